@@ -80,6 +80,8 @@ func newConfigCmd() *cobra.Command {
 	cmd.AddCommand(newConfigRmCmd(&stack))
 	cmd.AddCommand(newConfigSetCmd(&stack))
 	cmd.AddCommand(newConfigRefreshCmd(&stack))
+	cmd.AddCommand(newConfigExportCmd(&stack))
+	cmd.AddCommand(newConfigImportCmd(&stack))
 
 	return cmd
 }
@@ -355,6 +357,29 @@ func parseConfigKey(key string) (config.Key, error) {
 	return config.ParseKey(key)
 }
 
+// parseConfigOverrides parses an array of "key=value" pairs into a map of configuration overrides. Keys without a
+// namespace are treated as belonging to the current project, as with `pulumi config set`.
+func parseConfigOverrides(overrideArray []string) (map[config.Key]string, error) {
+	if len(overrideArray) == 0 {
+		return nil, nil
+	}
+
+	overrides := make(map[config.Key]string)
+	for _, o := range overrideArray {
+		kvp := strings.SplitN(o, "=", 2)
+		if len(kvp) != 2 {
+			return nil, errors.Errorf("configuration override %q must be of the form key=value", o)
+		}
+
+		key, err := parseConfigKey(kvp[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid configuration override key %q", kvp[0])
+		}
+		overrides[key] = kvp[1]
+	}
+	return overrides, nil
+}
+
 func prettyKey(k config.Key) string {
 	proj, err := workspace.DetectProject()
 	if err != nil {
@@ -540,6 +565,16 @@ func getStackConfiguration(stack backend.Stack, sm secrets.Manager) (backend.Sta
 		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
 	}
 
+	// The stack's configuration is layered over any configuration that it shares with the project's other stacks.
+	proj, _, err := readProject()
+	if err != nil {
+		return backend.StackConfiguration{}, err
+	}
+	layers, err := proj.StackConfigLayers(workspaceStack)
+	if err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
+
 	// If there are no secrets in the configuration, we should never use the decrypter, so it is safe to return
 	// one which panics if it is used. This provides for some nice UX in the common case (since, for example, building
 	// the correct decrypter for the local backend would involve prompting for a passphrase)
@@ -547,6 +582,7 @@ func getStackConfiguration(stack backend.Stack, sm secrets.Manager) (backend.Sta
 		return backend.StackConfiguration{
 			Config:    workspaceStack.Config,
 			Decrypter: config.NewPanicCrypter(),
+			Layers:    layers,
 		}, nil
 	}
 
@@ -558,5 +594,6 @@ func getStackConfiguration(stack backend.Stack, sm secrets.Manager) (backend.Sta
 	return backend.StackConfiguration{
 		Config:    workspaceStack.Config,
 		Decrypter: crypter,
+		Layers:    layers,
 	}, nil
 }
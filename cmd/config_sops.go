@@ -0,0 +1,248 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// sopsSecretsRegex is passed to SOPS as its --encrypted-regex when exporting configuration, so that only the values
+// beneath the document's "secrets" key are encrypted and plaintext configuration remains reviewable.
+const sopsSecretsRegex = "^secrets$"
+
+// sopsConfigDocument is the shape of the YAML document that stack configuration is exported to and imported from.
+// Plaintext values are stored under "config" and secret values under "secrets", each keyed by fully qualified
+// configuration key.
+type sopsConfigDocument struct {
+	Config  map[string]string `yaml:"config,omitempty"`
+	Secrets map[string]string `yaml:"secrets,omitempty"`
+}
+
+// makeSOPSConfigDocument builds the document for the given configuration, decrypting its secret values.
+func makeSOPSConfigDocument(cfg config.Map, dec config.Decrypter) (sopsConfigDocument, error) {
+	var doc sopsConfigDocument
+	for key, value := range cfg {
+		v, err := value.Value(dec)
+		if err != nil {
+			return sopsConfigDocument{}, errors.Wrap(err, "could not decrypt configuration value")
+		}
+
+		if value.Secure() {
+			if doc.Secrets == nil {
+				doc.Secrets = make(map[string]string)
+			}
+			doc.Secrets[key.String()] = v
+		} else {
+			if doc.Config == nil {
+				doc.Config = make(map[string]string)
+			}
+			doc.Config[key.String()] = v
+		}
+	}
+	return doc, nil
+}
+
+// applyTo adds the document's values to the given configuration, encrypting its secret values. Existing values with
+// the same keys are replaced.
+func (doc sopsConfigDocument) applyTo(cfg config.Map, enc config.Encrypter,
+	parseKey func(string) (config.Key, error)) error {
+
+	for k, v := range doc.Config {
+		key, err := parseKey(k)
+		if err != nil {
+			return errors.Wrapf(err, "invalid configuration key '%s'", k)
+		}
+		cfg[key] = config.NewValue(v)
+	}
+	for k, v := range doc.Secrets {
+		key, err := parseKey(k)
+		if err != nil {
+			return errors.Wrapf(err, "invalid configuration key '%s'", k)
+		}
+		ciphertext, err := enc.EncryptValue(v)
+		if err != nil {
+			return err
+		}
+		cfg[key] = config.NewSecureValue(ciphertext)
+	}
+	return nil
+}
+
+// runSOPS runs the sops command with the given arguments, returning its standard output.
+func runSOPS(args ...string) ([]byte, error) {
+	sopsBin, err := exec.LookPath("sops")
+	if err != nil {
+		return nil, errors.New("could not find the 'sops' command on your PATH; see https://github.com/mozilla/sops")
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(sopsBin, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, &stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return nil, errors.Wrap(err, "running sops")
+	}
+	return stdout.Bytes(), nil
+}
+
+// encryptWithSOPS encrypts the given YAML document with SOPS, using the creation rules that match the given path. The
+// plaintext is only ever written to a temporary file that is readable by the current user alone, and that is removed
+// before encryptWithSOPS returns.
+func encryptWithSOPS(plaintext []byte, path string) ([]byte, error) {
+	tmp, err := ioutil.TempFile("", "pulumi-config-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { contract.IgnoreError(os.Remove(tmp.Name())) }()
+
+	// TempFile creates files that only the current user can read, but make sure of it before writing any secrets.
+	if err = tmp.Chmod(0600); err == nil {
+		_, err = tmp.Write(plaintext)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "writing temporary file")
+	}
+
+	return runSOPS("--encrypt", "--filename-override", path, "--input-type", "yaml", "--output-type", "yaml",
+		"--encrypted-regex", sopsSecretsRegex, tmp.Name())
+}
+
+func newConfigExportCmd(stack *string) *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Export configuration to a SOPS-encrypted file",
+		Long: "Export configuration to a SOPS-encrypted file\n" +
+			"\n" +
+			"This command writes the stack's configuration to a YAML file and encrypts it with SOPS, so that it can\n" +
+			"be managed with existing SOPS key management and review workflows. Plaintext values are written under\n" +
+			"the 'config' key and remain readable; secret values are written under the 'secrets' key and are\n" +
+			"encrypted by SOPS using the keys selected by its usual creation rules.\n" +
+			"\n" +
+			"The 'sops' command, version 3.8 or later, must be on your PATH.",
+		Args: cmdutil.ExactArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(*stack, true, opts, true /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+			ps, err := loadProjectStack(s)
+			if err != nil {
+				return err
+			}
+
+			var dec config.Decrypter = config.NewPanicCrypter()
+			if ps.Config.HasSecureValue() {
+				if dec, err = getStackDencrypter(s); err != nil {
+					return errors.Wrap(err, "could not create a decrypter")
+				}
+			}
+			doc, err := makeSOPSConfigDocument(ps.Config, dec)
+			if err != nil {
+				return err
+			}
+			b, err := yaml.Marshal(doc)
+			contract.AssertNoError(err)
+
+			// SOPS selects keys by matching the file's path against its creation rules. Hand it the plaintext in a
+			// private temporary file outside of the destination's directory, tell it to match the destination's path
+			// instead, and write only its encrypted output to the destination.
+			path := args[0]
+			encrypted, err := encryptWithSOPS(b, path)
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(path, encrypted, 0600); err != nil {
+				return errors.Wrapf(err, "writing %s", path)
+			}
+
+			fmt.Printf("Exported configuration for stack '%s' to %s\n", s.Ref(), path)
+			return nil
+		}),
+	}
+
+	return exportCmd
+}
+
+func newConfigImportCmd(stack *string) *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import configuration from a SOPS-encrypted file",
+		Long: "Import configuration from a SOPS-encrypted file\n" +
+			"\n" +
+			"This command decrypts a YAML file with SOPS and sets the configuration values it contains on the stack.\n" +
+			"Values under the file's 'config' key are stored in plaintext, and values under its 'secrets' key are\n" +
+			"stored as secrets using the stack's own encryption. Configuration values that are not in the file are\n" +
+			"left untouched.\n" +
+			"\n" +
+			"The 'sops' command must be on your PATH.",
+		Args: cmdutil.ExactArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(*stack, true, opts, true /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			b, err := runSOPS("--decrypt", "--input-type", "yaml", "--output-type", "yaml", args[0])
+			if err != nil {
+				return err
+			}
+			var doc sopsConfigDocument
+			if err = yaml.UnmarshalStrict(b, &doc); err != nil {
+				return errors.Wrapf(err, "could not parse %s; expected only 'config' and 'secrets' keys", args[0])
+			}
+
+			var enc config.Encrypter = config.NewPanicCrypter()
+			if len(doc.Secrets) > 0 {
+				if enc, err = getStackEncrypter(s); err != nil {
+					return err
+				}
+			}
+
+			ps, err := loadProjectStack(s)
+			if err != nil {
+				return err
+			}
+			if err = doc.applyTo(ps.Config, enc, parseConfigKey); err != nil {
+				return err
+			}
+			return saveProjectStack(s, ps)
+		}),
+	}
+
+	return importCmd
+}
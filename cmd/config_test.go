@@ -15,6 +15,11 @@
 package cmd
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +46,71 @@ func TestSecretDetection(t *testing.T) {
 	// The key name does not match the, so even though this "looks like" a secret, we say it is not.
 	assert.False(t, looksLikeSecret(config.MustMakeKey("test", "okay"), "1415fc1f4eaeb5e096ee58c1480016638fff29bf"))
 }
+
+func TestSOPSConfigDocument(t *testing.T) {
+	crypter := config.NewSymmetricCrypter(make([]byte, 32))
+	ciphertext, err := crypter.EncryptValue("hunter2")
+	assert.NoError(t, err)
+
+	cfg := config.Map{
+		config.MustMakeKey("aws", "region"):      config.NewValue("us-west-2"),
+		config.MustMakeKey("test", "dbPassword"): config.NewSecureValue(ciphertext),
+	}
+
+	doc, err := makeSOPSConfigDocument(cfg, crypter)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"aws:region": "us-west-2"}, doc.Config)
+	assert.Equal(t, map[string]string{"test:dbPassword": "hunter2"}, doc.Secrets)
+
+	imported := config.Map{
+		config.MustMakeKey("test", "untouched"): config.NewValue("kept"),
+	}
+	assert.NoError(t, doc.applyTo(imported, crypter, config.ParseKey))
+	assert.Len(t, imported, 3)
+	assert.Equal(t, config.NewValue("us-west-2"), imported[config.MustMakeKey("aws", "region")])
+	assert.Equal(t, config.NewValue("kept"), imported[config.MustMakeKey("test", "untouched")])
+
+	secret := imported[config.MustMakeKey("test", "dbPassword")]
+	assert.True(t, secret.Secure())
+	plaintext, err := secret.Value(crypter)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+}
+
+func TestEncryptWithSOPS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops command is a shell script")
+	}
+
+	// Stand in for sops with a script that records the path it is asked to encrypt and its arguments.
+	bin, err := ioutil.TempDir("", "sops")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, os.RemoveAll(bin)) }()
+	script := "#!/bin/sh\n" +
+		"for arg; do last=\"$arg\"; done\n" +
+		"echo \"$@\" > \"" + filepath.Join(bin, "args") + "\"\n" +
+		"test \"$(stat -c %a \"$last\" 2>/dev/null || stat -f %Lp \"$last\")\" = 600 || exit 1\n" +
+		"echo encrypted\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(bin, "sops"), []byte(script), 0700))
+	defer func(path string) { assert.NoError(t, os.Setenv("PATH", path)) }(os.Getenv("PATH"))
+	assert.NoError(t, os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH")))
+
+	// SOPS matches its creation rules against the destination, but reads the plaintext from a private temporary file
+	// that is removed afterwards.
+	encrypted, err := encryptWithSOPS([]byte("secrets:\n  a: b\n"), "/some/dir/Pulumi.dev.sops.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "encrypted\n", string(encrypted))
+
+	b, err := ioutil.ReadFile(filepath.Join(bin, "args"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	args := strings.Fields(string(b))
+	assert.Contains(t, string(b), "--filename-override /some/dir/Pulumi.dev.sops.yaml")
+	tmp := args[len(args)-1]
+	assert.NotEqual(t, "/some/dir", filepath.Dir(tmp))
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -34,14 +34,21 @@ func newDestroyCmd() *cobra.Command {
 	var message string
 
 	// Flags for engine.UpdateOptions.
+	var allowProtected bool
 	var analyzers []string
 	var diffDisplay bool
+	var exclude []string
+	var excludeDependents bool
 	var parallel int
 	var refresh bool
+	var requireApproval bool
 	var showConfig bool
+	var showDeletionOrder bool
 	var showReplacementSteps bool
 	var showSames bool
 	var skipPreview bool
+	var takeoverStaleLock bool
+	var breakLock bool
 	var suppressOutputs bool
 	var yes bool
 
@@ -55,6 +62,9 @@ func newDestroyCmd() *cobra.Command {
 			"loaded from the associated state file in the workspace.  After running to completion,\n" +
 			"all of this stack's resources and associated state will be gone.\n" +
 			"\n" +
+			"Resources can be left in place with --exclude, e.g. to preserve stateful resources\n" +
+			"such as databases; the resources they depend on are left in place too.\n" +
+			"\n" +
 			"Warning: this command is generally irreversible and should be used with great care.",
 		Args: cmdutil.NoArgs,
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
@@ -67,6 +77,8 @@ func newDestroyCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(err)
 			}
+			opts.TakeoverStaleLock = takeoverStaleLock
+			opts.BreakLock = breakLock
 
 			var displayType = display.DisplayProgress
 			if diffDisplay {
@@ -93,6 +105,19 @@ func newDestroyCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
+			if err = applyUpdateDefaults(cmd, proj, s, updateDefaultFlags{
+				parallel:        &parallel,
+				refresh:         &refresh,
+				requireApproval: &requireApproval,
+				diff:            &diffDisplay,
+			}); err != nil {
+				return result.FromError(err)
+			}
+			opts.RequireApprovalForDestructive = requireApproval
+			if diffDisplay {
+				opts.Display.Type = display.DisplayDiff
+			}
+
 			m, err := getUpdateMetadata(message, root)
 			if err != nil {
 				return result.FromError(errors.Wrap(err, "gathering environment metadata"))
@@ -108,11 +133,19 @@ func newDestroyCmd() *cobra.Command {
 				return result.FromError(errors.Wrap(err, "getting stack configuration"))
 			}
 
-			opts.Engine = engine.UpdateOptions{
-				Analyzers: analyzers,
-				Parallel:  parallel,
-				Debug:     debug,
-				Refresh:   refresh,
+			opts.Engine, err = engine.NewUpdateOptionsBuilder().
+				AllowProtected(allowProtected).
+				Analyzers(analyzers...).
+				Parallel(parallel).
+				Debug(debug).
+				DiagnosticLimits(engine.DefaultDiagnosticLimits).
+				AssetCache(assetCache).
+				DestroyExclude(excludeDependents, exclude...).
+				ReportDeletionOrder(showDeletionOrder).
+				Refresh(refresh).
+				Build()
+			if err != nil {
+				return result.FromError(err)
 			}
 
 			_, res := s.Destroy(commandContext(), backend.UpdateOperation{
@@ -145,21 +178,37 @@ func newDestroyCmd() *cobra.Command {
 		"Optional message to associate with the destroy operation")
 
 	// Flags for engine.UpdateOptions.
+	cmd.PersistentFlags().BoolVar(
+		&allowProtected, "allow-protected", false,
+		"Allow this destroy to delete protected resources")
 	cmd.PersistentFlags().StringSliceVar(
 		&analyzers, "analyzer", []string{},
 		"Run one or more analyzers as part of this update")
 	cmd.PersistentFlags().BoolVar(
 		&diffDisplay, "diff", false,
 		"Display operation as a rich diff showing the overall change")
+	cmd.PersistentFlags().StringArrayVar(
+		&exclude, "exclude", []string{},
+		"Leave the resources whose URNs match a pattern in place, along with the resources they depend on, e.g. "+
+			"--exclude 'aws:rds/instance:Instance::*'. Patterns are URNs, type::name pairs, or names, with * wildcards")
+	cmd.PersistentFlags().BoolVar(
+		&excludeDependents, "exclude-dependents", false,
+		"Also leave in place the resources that depend on those selected by --exclude")
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
 	cmd.PersistentFlags().BoolVarP(
 		&refresh, "refresh", "r", false,
 		"Refresh the state of the stack's resources before this update")
+	cmd.PersistentFlags().BoolVar(
+		&requireApproval, "require-approval-for-destructive", false,
+		"Require approval of the destroy, even with --yes; it fails if approval cannot be given interactively")
 	cmd.PersistentFlags().BoolVar(
 		&showConfig, "show-config", false,
 		"Show configuration keys and variables")
+	cmd.PersistentFlags().BoolVar(
+		&showDeletionOrder, "show-deletion-order", false,
+		"Show the order in which resources will be deleted, in batches that are deleted in parallel")
 	cmd.PersistentFlags().BoolVar(
 		&showReplacementSteps, "show-replacement-steps", false,
 		"Show detailed resource replacement creates and deletes instead of a single step")
@@ -169,6 +218,13 @@ func newDestroyCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&skipPreview, "skip-preview", false,
 		"Do not perform a preview before performing the destroy")
+	cmd.PersistentFlags().BoolVar(
+		&takeoverStaleLock, "takeover-stale-lock", false,
+		"Take over the stack's lock if the update holding it has stopped sending heartbeats")
+	cmd.PersistentFlags().BoolVar(
+		&breakLock, "break-lock", false,
+		"Break the stack's lock even if the update holding it appears to be running. Only use this if you are "+
+			"certain that the other update has stopped, as two updates running at once may corrupt the stack's state")
 	cmd.PersistentFlags().BoolVar(
 		&suppressOutputs, "suppress-outputs", false,
 		"Suppress display of stack outputs (in case they contain sensitive values)")
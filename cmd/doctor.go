@@ -0,0 +1,134 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/backend/state"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var jsonOut bool
+	var stack string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment for common problems",
+		Long: "Check the environment for common problems.\n" +
+			"\n" +
+			"This command checks the health of the plugin directory, that the backend can be reached\n" +
+			"and accepts your credentials, whether the stack's state is locked by another update, that\n" +
+			"there is enough disk space for checkpoints, and that the language hosts on your $PATH\n" +
+			"belong to this CLI. Each problem found is reported along with a suggested fix.\n" +
+			"\n" +
+			"The command exits with an error if any check finds a problem that will cause updates to fail.",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			var doctorOpts engine.DoctorOptions
+			b, err := currentBackend(opts)
+			if err != nil {
+				doctorOpts.Checks = append(doctorOpts.Checks, func() []engine.DoctorFinding {
+					return []engine.DoctorFinding{{Check: "credentials", Status: engine.DoctorError,
+						Message:     fmt.Sprintf("could not log in to the current backend: %v", err),
+						Remediation: "run `pulumi login` to log in"}}
+				})
+			} else {
+				if strings.HasPrefix(b.URL(), filestate.FilePathPrefix) {
+					doctorOpts.StatePaths = append(doctorOpts.StatePaths,
+						strings.TrimPrefix(b.URL(), filestate.FilePathPrefix))
+				}
+				doctorOpts.Checks = append(doctorOpts.Checks,
+					backend.DoctorChecks(commandContext(), b, doctorStackReference(b, stack))...)
+			}
+
+			report := engine.Doctor(doctorOpts)
+			if jsonOut {
+				if err = printJSON(report); err != nil {
+					return err
+				}
+			} else {
+				printDoctorReport(report, opts)
+			}
+
+			if !report.Healthy() {
+				return errors.New("one or more checks found a problem")
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().BoolVarP(
+		&jsonOut, "json", "j", false,
+		"Emit the report as JSON")
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack whose state lock to check. Defaults to the current stack, if any")
+
+	return cmd
+}
+
+// doctorStackReference returns a reference to the stack with the given name, or to the current stack if the name is
+// empty. It returns nil if there is no such stack, as the stack is only needed to check its lock.
+func doctorStackReference(b backend.Backend, stackName string) backend.StackReference {
+	if stackName != "" {
+		ref, err := b.ParseStackReference(stackName)
+		if err != nil {
+			logging.V(5).Infof("not checking the state lock of %s: %v", stackName, err)
+			return nil
+		}
+		return ref
+	}
+
+	s, err := state.CurrentStack(commandContext(), b)
+	if err != nil || s == nil {
+		logging.V(5).Infof("not checking a state lock, as there is no current stack: %v", err)
+		return nil
+	}
+	return s.Ref()
+}
+
+func printDoctorReport(report engine.DoctorReport, opts display.Options) {
+	for _, f := range report.Findings {
+		var status string
+		switch f.Status {
+		case engine.DoctorOK:
+			status = colors.SpecCreate + "ok" + colors.Reset
+		case engine.DoctorWarning:
+			status = colors.SpecWarning + "warning" + colors.Reset
+		default:
+			status = colors.SpecError + "error" + colors.Reset
+		}
+		fmt.Println(opts.Color.Colorize(fmt.Sprintf("%s %s: %s", status, f.Check, f.Message)))
+		if f.Remediation != "" {
+			fmt.Printf("    %s\n", f.Remediation)
+		}
+	}
+}
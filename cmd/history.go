@@ -54,6 +54,11 @@ This command lists data about previous updates for a stack.`,
 				return err
 			}
 			b := s.Backend()
+			if err = backend.RequireCapability(b, "update history", func(c backend.Capabilities) bool {
+				return c.History
+			}); err != nil {
+				return err
+			}
 			updates, err := b.GetHistory(commandContext(), s.Ref())
 			if err != nil {
 				return errors.Wrap(err, "getting history")
@@ -156,9 +161,12 @@ func displayUpdatesConsole(updates []backend.UpdateInfo, opts display.Options) e
 	for _, update := range updates {
 
 		fmt.Printf("UpdateKind: %v\n", update.Kind)
-		if update.Result == "succeeded" {
+		switch update.Result {
+		case backend.SucceededResult:
 			fmt.Print(opts.Color.Colorize(fmt.Sprintf("%sStatus: %v%s\n", colors.Green, update.Result, colors.Reset)))
-		} else {
+		case backend.SucceededWithFailingChecksResult:
+			fmt.Print(opts.Color.Colorize(fmt.Sprintf("%sStatus: %v%s\n", colors.Yellow, update.Result, colors.Reset)))
+		default:
 			fmt.Print(opts.Color.Colorize(fmt.Sprintf("%sStatus: %v%s\n", colors.Red, update.Result, colors.Reset)))
 		}
 		fmt.Printf("Message: %v\n", update.Message)
@@ -121,7 +121,11 @@ func newLoginCmd() *cobra.Command {
 			var be backend.Backend
 			var err error
 			if filestate.IsFileStateBackendURL(cloudURL) {
-				be, err = filestate.Login(cmdutil.Diag(), cloudURL)
+				var localOpts filestate.Options
+				if localOpts, err = localBackendOptions(); err != nil {
+					return err
+				}
+				be, err = filestate.Login(cmdutil.Diag(), cloudURL, localOpts)
 			} else {
 				be, err = httpstate.Login(commandContext(), cmdutil.Diag(), cloudURL, displayOptions)
 			}
@@ -68,7 +68,11 @@ func newLogoutCmd() *cobra.Command {
 			var be backend.Backend
 			var err error
 			if filestate.IsFileStateBackendURL(cloudURL) {
-				be, err = filestate.New(cmdutil.Diag(), cloudURL)
+				var localOpts filestate.Options
+				if localOpts, err = localBackendOptions(); err != nil {
+					return err
+				}
+				be, err = filestate.New(cmdutil.Diag(), cloudURL, localOpts)
 			} else {
 				be, err = httpstate.New(cmdutil.Diag(), cloudURL)
 			}
@@ -168,7 +168,8 @@ func newLogsCmd() *cobra.Command {
 			"Defaults to returning the last 1 hour of logs.")
 	logsCmd.PersistentFlags().StringVarP(
 		&resource, "resource", "r", "",
-		"Only return logs for the requested resource ('name', 'type::name' or full URN).  Defaults to returning all logs.")
+		"Only return logs for the requested resource ('name', 'type::name' or full URN, any of which may contain "+
+			"'*' wildcards).  Defaults to returning all logs.")
 
 	return logsCmd
 }
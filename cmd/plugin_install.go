@@ -52,7 +52,12 @@ func newPluginInstallCmd() *cobra.Command {
 			"project.  VERSION cannot be a range: it must be a specific number.\n" +
 			"\n" +
 			"If you let Pulumi compute the set to download, it is conservative and may end up\n" +
-			"downloading more plugins than is strictly necessary.",
+			"downloading more plugins than is strictly necessary.\n" +
+			"\n" +
+			"Set PULUMI_PLUGIN_MIRRORS to a comma-separated list of URLs or local directories to\n" +
+			"download plugins only from those locations, and set PULUMI_PLUGIN_CHECKSUM_KEY to\n" +
+			"require that every downloaded plugin match the checksum listed for it in its\n" +
+			"location's signed pulumi-plugins.sha256 manifest.",
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			displayOpts := display.Options{
 				Color: cmdutil.GetGlobalColorization(),
@@ -115,6 +120,7 @@ func newPluginInstallCmd() *cobra.Command {
 			}
 
 			// Now for each kind, name, version pair, download it from the release website, and install it.
+			warned := false
 			for _, install := range installs {
 				label := fmt.Sprintf("[%s plugin %s]", install.Kind, install)
 				cmdutil.Diag().Infoerrf(
@@ -147,6 +153,10 @@ func newPluginInstallCmd() *cobra.Command {
 				var tarball io.ReadCloser
 				var err error
 				if file == "" {
+					if warning := workspace.PluginDownloadWarning(); warning != "" && !warned {
+						cmdutil.Diag().Warningf(diag.RawMessage("", warning))
+						warned = true
+					}
 					if verbose {
 						cmdutil.Diag().Infoerrf(
 							diag.Message("", "%s downloading from %s"), label, install.ServerURL)
@@ -21,8 +21,10 @@ import (
 	"github.com/pulumi/pulumi/pkg/backend"
 	"github.com/pulumi/pulumi/pkg/backend/display"
 	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
 func newPreviewCmd() *cobra.Command {
@@ -30,16 +32,31 @@ func newPreviewCmd() *cobra.Command {
 	var expectNop bool
 	var message string
 	var stack string
+	var configOverrides []string
 
 	// Flags for engine.UpdateOptions.
+	var allowProtected bool
 	var analyzers []string
+	var defaultTags []string
+	var diagFilters []string
+	var deterministicEvents bool
 	var diffDisplay bool
+	var explain bool
+	var htmlDisplay bool
 	var jsonDisplay bool
+	var terraformPlanJSON bool
 	var parallel int
+	var prune string
+	var pruneExempt []string
+	var planCache bool
+	var refreshPlanCache bool
 	var showConfig bool
+	var showDependencies bool
 	var showReplacementSteps bool
 	var showSames bool
 	var suppressOutputs bool
+	var validatePreview bool
+	var validateSnapshot bool
 
 	var cmd = &cobra.Command{
 		Use:        "preview",
@@ -59,16 +76,48 @@ func newPreviewCmd() *cobra.Command {
 			"`--cwd` flag to use a different directory.",
 		Args: cmdutil.NoArgs,
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			if htmlDisplay && (jsonDisplay || terraformPlanJSON) {
+				return result.Errorf("--html cannot be combined with --json or --terraform-plan-json")
+			}
+
 			var displayType = display.DisplayProgress
 			if diffDisplay {
 				displayType = display.DisplayDiff
 			}
 
+			overrides, err := parseConfigOverrides(configOverrides)
+			if err != nil {
+				return result.FromError(err)
+			}
+			tags, err := parseDefaultTags(defaultTags)
+			if err != nil {
+				return result.FromError(err)
+			}
+			filters, closeDiagFiles, err := parseDiagnosticFilters(diagFilters)
+			if err != nil {
+				return result.FromError(err)
+			}
+			defer closeDiagFiles()
+
 			opts := backend.UpdateOptions{
 				Engine: engine.UpdateOptions{
-					Analyzers: analyzers,
-					Parallel:  parallel,
-					Debug:     debug,
+					AllowProtected:      allowProtected,
+					Analyzers:           analyzers,
+					Parallel:            parallel,
+					Prune:               deploy.PruneMode(prune),
+					PruneExempt:         pruneExempt,
+					Debug:               debug,
+					DiagnosticLimits:    engine.DefaultDiagnosticLimits,
+					DiagnosticFilters:   filters,
+					ConfigOverrides:     overrides,
+					DefaultTags:         tags,
+					RefreshPlanCache:    refreshPlanCache,
+					ValidateSnapshot:    validateSnapshot,
+					ValidatePreview:     validatePreview,
+					ExpectNoChanges:     expectNop,
+					DeterministicEvents: deterministicEvents,
+					CheckCache:          newCheckCache(),
+					AssetCache:          assetCache,
 				},
 				Display: display.Options{
 					Color:                cmdutil.GetGlobalColorization(),
@@ -78,11 +127,23 @@ func newPreviewCmd() *cobra.Command {
 					SuppressOutputs:      suppressOutputs,
 					IsInteractive:        cmdutil.Interactive(),
 					Type:                 displayType,
-					JSONDisplay:          jsonDisplay,
+					JSONDisplay:          jsonDisplay || terraformPlanJSON || htmlDisplay,
+					TerraformPlanJSON:    terraformPlanJSON,
+					HTMLDisplay:          htmlDisplay,
+					ShowExplanation:      explain,
+					ShowDependencies:     showDependencies,
 					Debug:                debug,
 				},
 			}
 
+			if planCache || refreshPlanCache {
+				dir, err := workspace.GetPlanCacheDir()
+				if err != nil {
+					return result.FromError(errors.Wrap(err, "locating the plan cache"))
+				}
+				opts.Engine.PlanCache = engine.NewFilePlanCache(dir)
+			}
+
 			s, err := requireStack(stack, true, opts.Display, true /*setCurrent*/)
 			if err != nil {
 				return result.FromError(err)
@@ -93,6 +154,16 @@ func newPreviewCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
+			if err = applyUpdateDefaults(cmd, proj, s, updateDefaultFlags{
+				parallel: &opts.Engine.Parallel,
+				diff:     &diffDisplay,
+			}); err != nil {
+				return result.FromError(err)
+			}
+			if diffDisplay {
+				opts.Display.Type = display.DisplayDiff
+			}
+
 			m, err := getUpdateMetadata("", root)
 			if err != nil {
 				return result.FromError(errors.Wrap(err, "gathering environment metadata"))
@@ -141,24 +212,77 @@ func newPreviewCmd() *cobra.Command {
 	cmd.PersistentFlags().StringVar(
 		&stackConfigFile, "config-file", "",
 		"Use the configuration values in the specified file rather than detecting the file name")
+	cmd.PersistentFlags().StringArrayVar(
+		&configOverrides, "config-override", []string{},
+		"Override a config value for this preview only, without saving it to the stack, e.g. "+
+			"--config-override aws:region=us-west-2")
 
 	cmd.PersistentFlags().StringVarP(
 		&message, "message", "m", "",
 		"Optional message to associate with the preview operation")
 
 	// Flags for engine.UpdateOptions.
+	cmd.PersistentFlags().BoolVar(
+		&allowProtected, "allow-protected", false,
+		"Allow this preview to delete or replace protected resources")
 	cmd.PersistentFlags().StringSliceVar(
 		&analyzers, "analyzer", []string{},
 		"Run one or more analyzers as part of this update")
+	cmd.PersistentFlags().StringArrayVar(
+		&defaultTags, "default-tag", []string{},
+		"Apply a tag to every resource whose provider supports default tags, overriding the program's tag of the same "+
+			"name, e.g. --default-tag cost-center=1234")
+	cmd.PersistentFlags().BoolVar(
+		&deterministicEvents, "deterministic-events", false,
+		"Report the preview's steps in dependency order once it is complete rather than as they happen, so that "+
+			"the output of previews run with --parallel can be compared")
+	cmd.PersistentFlags().StringArrayVar(
+		&diagFilters, "diag", []string{},
+		"Set the least severe diagnostics shown from a source, and optionally append the rest to a file, e.g. "+
+			"--diag provider:aws=warning:aws.log. Sources are engine, language, provider:<package>, and * for all others")
 	cmd.PersistentFlags().BoolVar(
 		&diffDisplay, "diff", false,
 		"Display operation as a rich diff showing the overall change")
+	cmd.PersistentFlags().BoolVar(
+		&explain, "explain", false,
+		"Follow the preview with a prose explanation of which changes cause which replacements and updates")
+	cmd.PersistentFlags().BoolVar(
+		&showDependencies, "show-dependencies", false,
+		"Show why each resource waits on the resources before it (parent, provider, dependency, or input reference)")
+	cmd.Flags().BoolVar(
+		&htmlDisplay, "html", false,
+		"Render the preview as a self-contained HTML page, e.g. to archive it as a build artifact; secrets are masked")
 	cmd.Flags().BoolVarP(
 		&jsonDisplay, "json", "j", false,
 		"Serialize the preview diffs, operations, and overall output as JSON")
+	cmd.Flags().BoolVar(
+		&terraformPlanJSON, "terraform-plan-json", false,
+		"Serialize the preview as JSON that follows the structure of a Terraform plan")
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
+	cmd.PersistentFlags().StringVar(
+		&prune, "prune", "",
+		"Report resources that the program no longer declares as prunes instead of deleting them ('report'), "+
+			"or delete them as prunes ('delete')")
+	cmd.PersistentFlags().StringSliceVar(
+		&pruneExempt, "prune-exempt", []string{},
+		"Never prune the resources with these URNs or types, e.g. resources that are intentionally left unmanaged")
+	cmd.PersistentFlags().BoolVar(
+		&planCache, "plan-cache", false,
+		"Serve the preview from a cached plan if the program, configuration, and state are unchanged since it was "+
+			"computed, and cache the plan otherwise")
+	cmd.PersistentFlags().BoolVar(
+		&refreshPlanCache, "refresh-plan-cache", false,
+		"Compute a fresh plan even if a cached one is available, and cache it in its place (implies --plan-cache)")
+	cmd.PersistentFlags().BoolVar(
+		&validatePreview, "provider-dry-run", false,
+		"Ask providers that support it to validate each planned create and update with their cloud APIs, so that "+
+			"errors such as exhausted quotas are reported by the preview")
+	cmd.PersistentFlags().BoolVar(
+		&validateSnapshot, "validate-snapshot", false,
+		"Check the stored inputs of every resource against its provider before previewing, and fail if any are "+
+			"rejected, e.g. because a provider upgrade changed their shape")
 	cmd.PersistentFlags().BoolVar(
 		&showConfig, "show-config", false,
 		"Show configuration keys and variables")
@@ -40,6 +40,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/backend/httpstate/client"
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
@@ -111,6 +112,15 @@ func NewPulumiCmd() *cobra.Command {
 				}
 			}
 
+			// Cache the hashes of assets and the contents of archives across updates, unless asked not to.
+			if !cmdutil.IsTruthy(os.Getenv("PULUMI_DISABLE_ASSET_CACHE")) {
+				if dir, err := workspace.GetAssetCacheDir(); err == nil {
+					assetCache = resource.NewAssetCache(dir, 0)
+				} else {
+					logging.V(5).Infof("not caching assets: %v", err)
+				}
+			}
+
 			if cmdutil.IsTruthy(os.Getenv("PULUMI_SKIP_UPDATE_CHECK")) {
 				logging.Infof("skipping update check")
 			} else {
@@ -124,7 +134,7 @@ func NewPulumiCmd() *cobra.Command {
 			cmdutil.CloseTracing()
 
 			if profiling != "" {
-				if err := cmdutil.CloseProfiling(profiling); err != nil {
+				if err := cmdutil.CloseProfiling(profiling, assetCache); err != nil {
 					logging.Warningf("could not close profiling: %v", err)
 				}
 			}
@@ -138,6 +148,8 @@ func NewPulumiCmd() *cobra.Command {
 		"Enable emojis in the output")
 	cmd.PersistentFlags().BoolVar(&filestate.DisableIntegrityChecking, "disable-integrity-checking", false,
 		"Disable integrity checking of checkpoint files")
+	cmd.PersistentFlags().BoolVar(&allowModifiedState, "allow-modified-state", false,
+		"Load checkpoint files even if they were modified outside of Pulumi")
 	cmd.PersistentFlags().BoolVar(&logFlow, "logflow", false,
 		"Flow log settings to child processes (like plugins)")
 	cmd.PersistentFlags().BoolVar(&logToStderr, "logtostderr", false,
@@ -145,9 +157,10 @@ func NewPulumiCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(&cmdutil.DisableInteractive, "non-interactive", false,
 		"Disable interactive mode for all commands")
 	cmd.PersistentFlags().StringVar(&tracing, "tracing", "",
-		"Emit tracing to a Zipkin-compatible tracing endpoint")
+		"Emit tracing to a Zipkin-compatible tracing endpoint, or to an OTLP/HTTP endpoint whose path is /v1/traces")
 	cmd.PersistentFlags().StringVar(&profiling, "profiling", "",
-		"Emit CPU and memory profiles and an execution trace to '[filename].[pid].{cpu,mem,trace}', respectively")
+		"Emit CPU and memory profiles, an execution trace, backend round-trip counts, and asset cache statistics "+
+			"to '[filename].[pid].{cpu,mem,trace,backend,assets}', respectively")
 	cmd.PersistentFlags().IntVarP(&verbose, "verbose", "v", 0,
 		"Enable verbose logging (e.g., v=3); anything >3 is very verbose")
 	cmd.PersistentFlags().StringVar(
@@ -174,12 +187,14 @@ func NewPulumiCmd() *cobra.Command {
 	//     - Other Commands:
 	cmd.AddCommand(newLogsCmd())
 	cmd.AddCommand(newPluginCmd())
+	cmd.AddCommand(newDoctorCmd())
 	cmd.AddCommand(newVersionCmd())
 	cmd.AddCommand(newHistoryCmd())
 
 	// Less common, and thus hidden, commands:
 	cmd.AddCommand(newGenCompletionCmd(cmd))
 	cmd.AddCommand(newGenMarkdownCmd(cmd))
+	cmd.AddCommand(newReplayCmd())
 
 	// We have a set of options that are useful for developers of pulumi that we add when PULUMI_DEBUG_COMMANDS is
 	// set to true.
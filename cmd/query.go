@@ -59,6 +59,11 @@ func newQueryCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(err)
 			}
+			if err = backend.RequireCapability(s.Backend(), "querying over the state", func(c backend.Capabilities) bool {
+				return c.Query
+			}); err != nil {
+				return result.FromError(err)
+			}
 
 			proj, root, err := readProject()
 			if err != nil {
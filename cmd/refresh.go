@@ -37,10 +37,13 @@ func newRefreshCmd() *cobra.Command {
 	var analyzers []string
 	var diffDisplay bool
 	var parallel int
+	var readParallel int
 	var showConfig bool
 	var showReplacementSteps bool
 	var showSames bool
 	var skipPreview bool
+	var takeoverStaleLock bool
+	var breakLock bool
 	var suppressOutputs bool
 	var yes bool
 
@@ -67,6 +70,8 @@ func newRefreshCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(err)
 			}
+			opts.TakeoverStaleLock = takeoverStaleLock
+			opts.BreakLock = breakLock
 
 			var displayType = display.DisplayProgress
 			if diffDisplay {
@@ -94,6 +99,16 @@ func newRefreshCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
+			if err = applyUpdateDefaults(cmd, proj, s, updateDefaultFlags{
+				parallel: &parallel,
+				diff:     &diffDisplay,
+			}); err != nil {
+				return result.FromError(err)
+			}
+			if diffDisplay {
+				opts.Display.Type = display.DisplayDiff
+			}
+
 			m, err := getUpdateMetadata(message, root)
 			if err != nil {
 				return result.FromError(errors.Wrap(err, "gathering environment metadata"))
@@ -109,10 +124,16 @@ func newRefreshCmd() *cobra.Command {
 				return result.FromError(errors.Wrap(err, "getting stack configuration"))
 			}
 
-			opts.Engine = engine.UpdateOptions{
-				Analyzers: analyzers,
-				Parallel:  parallel,
-				Debug:     debug,
+			opts.Engine, err = engine.NewUpdateOptionsBuilder().
+				Analyzers(analyzers...).
+				Parallel(parallel).
+				RefreshParallel(readParallel).
+				Debug(debug).
+				DiagnosticLimits(engine.DefaultDiagnosticLimits).
+				AssetCache(assetCache).
+				Build()
+			if err != nil {
+				return result.FromError(err)
 			}
 
 			changes, res := s.Refresh(commandContext(), backend.UpdateOperation{
@@ -165,6 +186,9 @@ func newRefreshCmd() *cobra.Command {
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
+	cmd.PersistentFlags().IntVar(
+		&readParallel, "read-parallel", 0,
+		"Allow R resources to be read in parallel at once. Defaults to 64, or to --parallel if that is higher or 1")
 	cmd.PersistentFlags().BoolVar(
 		&showReplacementSteps, "show-replacement-steps", false,
 		"Show detailed resource replacement creates and deletes instead of a single step")
@@ -174,6 +198,13 @@ func newRefreshCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&skipPreview, "skip-preview", false,
 		"Do not perform a preview before performing the refresh")
+	cmd.PersistentFlags().BoolVar(
+		&takeoverStaleLock, "takeover-stale-lock", false,
+		"Take over the stack's lock if the update holding it has stopped sending heartbeats")
+	cmd.PersistentFlags().BoolVar(
+		&breakLock, "break-lock", false,
+		"Break the stack's lock even if the update holding it appears to be running. Only use this if you are "+
+			"certain that the other update has stopped, as two updates running at once may corrupt the stack's state")
 	cmd.PersistentFlags().BoolVar(
 		&suppressOutputs, "suppress-outputs", false,
 		"Suppress display of stack outputs (in case they contain sensitive values)")
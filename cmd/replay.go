@@ -0,0 +1,113 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+)
+
+// newReplayCmd returns a new command that renders the events recorded in a replay bundle. It is hidden by default
+// since it's mainly of use to those debugging the CLI's display.
+func newReplayCmd() *cobra.Command {
+	var realTime bool
+	var diffDisplay bool
+	var jsonDisplay bool
+	var showConfig bool
+	var showReplacementSteps bool
+	var showSames bool
+	var suppressOutputs bool
+
+	var cmd = &cobra.Command{
+		Use:   "replay <bundle>",
+		Args:  cmdutil.ExactArgs(1),
+		Short: "Replay the events recorded in a replay bundle",
+		Long: "Replay the events recorded in a replay bundle.\n" +
+			"\n" +
+			"A replay bundle is written by any update, preview, refresh, or destroy that is run with\n" +
+			"the " + backend.ReplayBundleEnvVar + " environment variable set to the path of the bundle.\n" +
+			"Replaying the bundle renders its events just as they were rendered when they were recorded,\n" +
+			"without running the program or contacting any resource providers.",
+		Hidden: true,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			bundle, err := engine.ReadReplayBundle(args[0])
+			if err != nil {
+				return err
+			}
+
+			displayType := display.DisplayProgress
+			if diffDisplay {
+				displayType = display.DisplayDiff
+			}
+			opts := display.Options{
+				Color:                cmdutil.GetGlobalColorization(),
+				ShowConfig:           showConfig,
+				ShowReplacementSteps: showReplacementSteps,
+				ShowSameResources:    showSames,
+				SuppressOutputs:      suppressOutputs,
+				IsInteractive:        cmdutil.Interactive(),
+				Type:                 displayType,
+				JSONDisplay:          jsonDisplay,
+			}
+
+			events := make(chan engine.Event)
+			done := make(chan bool)
+			go display.ShowEvents(
+				strings.ToLower(backend.ActionLabel(bundle.Kind, bundle.DryRun)), bundle.Kind, bundle.Stack,
+				bundle.Project, events, done, opts, bundle.DryRun)
+
+			engine.Replay(bundle, events, engine.ReplayOptions{RealTime: realTime})
+
+			// The display runs until it sees a cancellation event, which the engine emits at the end of every
+			// update. Supply one in case the recording stopped short of it.
+			if n := len(bundle.Events); n == 0 || bundle.Events[n-1].Event.Type != engine.CancelEvent {
+				events <- engine.Event{Type: engine.CancelEvent, Version: engine.EventSchemaVersion}
+			}
+			<-done
+			close(events)
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().BoolVar(
+		&realTime, "real-time", false,
+		"Replay events at the pace at which they were recorded")
+	cmd.PersistentFlags().BoolVar(
+		&diffDisplay, "diff", false,
+		"Display operation as a rich diff showing the overall change")
+	cmd.PersistentFlags().BoolVarP(
+		&jsonDisplay, "json", "j", false,
+		"Serialize the preview diffs, operations, and overall output as JSON")
+	cmd.PersistentFlags().BoolVar(
+		&showConfig, "show-config", false,
+		"Show configuration keys and variables")
+	cmd.PersistentFlags().BoolVar(
+		&showReplacementSteps, "show-replacement-steps", false,
+		"Show detailed resource replacement creates and deletes instead of a single step")
+	cmd.PersistentFlags().BoolVar(
+		&showSames, "show-sames", false,
+		"Show resources that don't need be updated because they haven't changed, alongside those that do")
+	cmd.PersistentFlags().BoolVar(
+		&suppressOutputs, "suppress-outputs", false,
+		"Suppress display of stack outputs (in case they contain sensitive values)")
+
+	return cmd
+}
@@ -18,10 +18,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 
+	"github.com/pulumi/pulumi/pkg/backend"
 	"github.com/pulumi/pulumi/pkg/backend/display"
 	"github.com/pulumi/pulumi/pkg/backend/httpstate"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
@@ -69,6 +71,13 @@ func newStackCmd() *cobra.Command {
 					fmt.Printf("    Owner: %s\n", cs.OrgName())
 				}
 			}
+			freeze, err := backend.ReadStackFreeze(commandContext(), be, s.Ref())
+			if err != nil {
+				return err
+			}
+			if freeze.Active(time.Now()) {
+				fmt.Printf("    Changes are suspended: %v\n", freeze)
+			}
 
 			if snap != nil {
 				if t := snap.Manifest.Time; t.IsZero() {
@@ -92,6 +101,9 @@ func newStackCmd() *cobra.Command {
 					}
 					fmt.Printf("    Plugin %s [%s] version: %s\n", plugin.Name, plugin.Kind, plugver)
 				}
+				for _, ref := range snap.StackReferences() {
+					fmt.Printf("    Reads outputs of stack: %s\n", ref.Stack)
+				}
 			} else {
 				fmt.Printf("    No updates yet; run 'pulumi up'\n")
 			}
@@ -161,15 +173,19 @@ func newStackCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&showSecrets, "show-secrets", false, "Display stack outputs which are marked as secret in plaintext")
 
+	cmd.AddCommand(newStackDepsCmd())
 	cmd.AddCommand(newStackExportCmd())
+	cmd.AddCommand(newStackFreezeCmd())
 	cmd.AddCommand(newStackGraphCmd())
 	cmd.AddCommand(newStackImportCmd())
 	cmd.AddCommand(newStackInitCmd())
 	cmd.AddCommand(newStackLsCmd())
 	cmd.AddCommand(newStackOutputCmd())
 	cmd.AddCommand(newStackRmCmd())
+	cmd.AddCommand(newStackSearchCmd())
 	cmd.AddCommand(newStackSelectCmd())
 	cmd.AddCommand(newStackTagCmd())
+	cmd.AddCommand(newStackThawCmd())
 	cmd.AddCommand(newStackRenameCmd())
 
 	return cmd
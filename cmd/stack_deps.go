@@ -0,0 +1,50 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+)
+
+func newStackDepsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps [root-directory]",
+		Args:  cmdutil.MaximumNArgs(1),
+		Short: "Print the order in which the projects in a repository should be deployed",
+		Long: "Print the order in which the projects in a repository should be deployed.\n" +
+			"\n" +
+			"This command finds every project beneath the given directory (or the current directory, if none\n" +
+			"is given) and orders them according to the `stackDependencies` declared in their project files.\n" +
+			"The result is printed as JSON so that CI systems can drive multi-stack deployments. Projects in\n" +
+			"the same wave do not depend on one another and may be deployed concurrently.",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			root := "."
+			if len(args) > 0 {
+				root = args[0]
+			}
+
+			plan, err := engine.PlanStackExecution(root)
+			if err != nil {
+				return err
+			}
+			return printJSON(plan)
+		}),
+	}
+
+	return cmd
+}
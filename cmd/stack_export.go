@@ -22,12 +22,14 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/resource/stack"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 )
 
 func newStackExportCmd() *cobra.Command {
 	var file string
 	var stackName string
+	var redact string
 
 	cmd := &cobra.Command{
 		Use:   "export",
@@ -38,7 +40,14 @@ func newStackExportCmd() *cobra.Command {
 			"The deployment can then be hand-edited and used to update the stack via\n" +
 			"`pulumi stack import`. This process may be used to correct inconsistencies\n" +
 			"in a stack's state due to failed deployments, manual changes to cloud\n" +
-			"resources, etc.",
+			"resources, etc.\n" +
+			"\n" +
+			"Pass --redact to instead export only the shape of the stack, for sharing with\n" +
+			"others when debugging: every resource's URN, type, and dependencies, and the names\n" +
+			"and kinds of its properties, but none of their values. With --redact=strip, all\n" +
+			"values are replaced by placeholders; with --redact=hash, strings are replaced by\n" +
+			"hashes so that equal values can still be recognized. A redacted deployment cannot\n" +
+			"be imported.",
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			opts := display.Options{
 				Color: cmdutil.GetGlobalColorization(),
@@ -54,6 +63,12 @@ func newStackExportCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if redact != "" {
+				deployment, err = stack.RedactUntypedDeployment(deployment, stack.RedactionMode(redact))
+				if err != nil {
+					return errors.Wrap(err, "could not redact deployment")
+				}
+			}
 
 			// Read from stdin or a specified file.
 			writer := os.Stdout
@@ -77,5 +92,8 @@ func newStackExportCmd() *cobra.Command {
 		&stackName, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
 	cmd.PersistentFlags().StringVarP(
 		&file, "file", "", "", "A filename to write stack output to")
+	cmd.PersistentFlags().StringVar(
+		&redact, "redact", "", "Redact all values from the export, either by removing them (strip) or hashing them (hash)")
+	cmd.PersistentFlags().Lookup("redact").NoOptDefVal = string(stack.RedactStrip)
 	return cmd
 }
@@ -0,0 +1,141 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+func newStackFreezeCmd() *cobra.Command {
+	var stack string
+	var reason string
+	var duration time.Duration
+	var until string
+
+	cmd := &cobra.Command{
+		Use:   "freeze",
+		Short: "Suspend changes to a stack",
+		Long: "Suspend changes to a stack\n" +
+			"\n" +
+			"While a stack is frozen, `pulumi up`, `pulumi destroy`, and imports into the stack are\n" +
+			"refused with the given reason, for everyone who works on the stack. Previews and refreshes\n" +
+			"are still allowed. The freeze lasts until it expires, if it has an expiry, or until it is\n" +
+			"lifted with `pulumi stack thaw`. Freezing a frozen stack replaces its freeze.\n",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			if reason == "" {
+				return errors.New("a reason for the freeze must be given with --reason")
+			}
+
+			now := time.Now()
+			var expires time.Time
+			switch {
+			case duration != 0 && until != "":
+				return errors.New("only one of --duration and --until may be given")
+			case duration < 0:
+				return errors.New("the freeze's duration must be positive")
+			case duration > 0:
+				expires = now.Add(duration)
+			case until != "":
+				t, err := time.Parse(time.RFC3339, until)
+				if err != nil {
+					return errors.Wrap(err, "parsing --until; the time must be in RFC 3339 format")
+				}
+				if !t.After(now) {
+					return errors.Errorf("the freeze would have expired at %s", until)
+				}
+				expires = t
+			}
+
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			s, err := requireStack(stack, false, opts, true /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			user, err := s.Backend().CurrentUser()
+			contract.IgnoreError(err) // the user is for information only.
+			freeze := &engine.StackFreeze{Reason: reason, User: user, Frozen: now, Expires: expires}
+			if err = backend.SetStackFreeze(commandContext(), s, freeze); err != nil {
+				return err
+			}
+
+			fmt.Printf("Stack %s is frozen\n", s.Ref())
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().StringVarP(
+		&reason, "reason", "r", "", "Why changes to the stack are suspended; shown to anyone whose update is refused")
+	cmd.PersistentFlags().DurationVar(
+		&duration, "duration", 0, "How long the freeze lasts, e.g. 4h (default: until the stack is thawed)")
+	cmd.PersistentFlags().StringVar(
+		&until, "until", "", "The time at which the freeze ends, in RFC 3339 format (e.g. 2019-07-01T09:00:00Z)")
+
+	return cmd
+}
+
+func newStackThawCmd() *cobra.Command {
+	var stack string
+
+	cmd := &cobra.Command{
+		Use:   "thaw",
+		Short: "Lift a stack's freeze, allowing changes to it again",
+		Args:  cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			s, err := requireStack(stack, false, opts, true /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			freeze, err := backend.GetStackFreeze(commandContext(), s)
+			if err != nil {
+				return err
+			}
+			if freeze == nil {
+				fmt.Printf("Stack %s is not frozen\n", s.Ref())
+				return nil
+			}
+			if err = backend.SetStackFreeze(commandContext(), s, nil); err != nil {
+				return err
+			}
+
+			fmt.Printf("Stack %s is thawed\n", s.Ref())
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+
+	return cmd
+}
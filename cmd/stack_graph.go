@@ -15,15 +15,15 @@
 package cmd
 
 import (
+	"encoding/json"
 	"os"
 
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
 	"github.com/pulumi/pulumi/pkg/backend/display"
-	"github.com/pulumi/pulumi/pkg/graph"
-	"github.com/pulumi/pulumi/pkg/graph/dotconv"
-	"github.com/pulumi/pulumi/pkg/resource"
-	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/engine"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
-	"github.com/spf13/cobra"
 )
 
 // Whether or not we should ignore parent edges when building up our graph.
@@ -32,14 +32,21 @@ var ignoreParentEdges bool
 // Whether or not we should ignore dependency edges when building up our graph.
 var ignoreDependencyEdges bool
 
+// Whether or not we should include provider edges when building up our graph.
+var includeProviderEdges bool
+
 // The color of dependency edges in the graph. Defaults to #246C60, a blush-green.
 var dependencyEdgeColor string
 
 // The color of parent edges in the graph. Defaults to #AA6639, an orange.
 var parentEdgeColor string
 
+// The color of provider edges in the graph. Defaults to #3F4F9B, a blue.
+var providerEdgeColor string
+
 func newStackGraphCmd() *cobra.Command {
 	var stackName string
+	var format string
 
 	cmd := &cobra.Command{
 		Use:   "graph",
@@ -48,13 +55,19 @@ func newStackGraphCmd() *cobra.Command {
 		Long: "Export a stack's dependency graph to a file.\n" +
 			"\n" +
 			"This command can be used to view the dependency graph that a Pulumi program\n" +
-			"admitted when it was ran. This graph is output in the DOT format. This command operates\n" +
+			"admitted when it was ran. The graph includes the dependencies between resources and\n" +
+			"the parent of each resource, and optionally the provider that manages each resource.\n" +
+			"This graph is output in the DOT format by default, or as JSON. This command operates\n" +
 			"on your stack's most recent deployment.",
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			opts := display.Options{
 				Color: cmdutil.GetGlobalColorization(),
 			}
 
+			if format != "dot" && format != "json" {
+				return errors.Errorf("unsupported graph format '%s'; expected 'dot' or 'json'", format)
+			}
+
 			s, err := requireStack(stackName, false, opts, true /*setCurrent*/)
 			if err != nil {
 				return err
@@ -64,13 +77,35 @@ func newStackGraphCmd() *cobra.Command {
 				return err
 			}
 
-			dg := makeDependencyGraph(snap)
+			var ignored []engine.GraphEdgeKind
+			if ignoreDependencyEdges {
+				ignored = append(ignored, engine.DependencyEdge)
+			}
+			if ignoreParentEdges {
+				ignored = append(ignored, engine.ParentEdge)
+			}
+			if !includeProviderEdges {
+				ignored = append(ignored, engine.ProviderEdge)
+			}
+			g := engine.NewSnapshotGraph(snap).Without(ignored...)
+
 			file, err := os.Create(args[0])
 			if err != nil {
 				return err
 			}
 
-			if err := dotconv.Print(dg, file); err != nil {
+			if format == "json" {
+				enc := json.NewEncoder(file)
+				enc.SetIndent("", "    ")
+				err = enc.Encode(g)
+			} else {
+				err = g.WriteDOT(file, map[engine.GraphEdgeKind]string{
+					engine.DependencyEdge: dependencyEdgeColor,
+					engine.ParentEdge:     parentEdgeColor,
+					engine.ProviderEdge:   providerEdgeColor,
+				})
+			}
+			if err != nil {
 				_ = file.Close()
 				return err
 			}
@@ -82,171 +117,19 @@ func newStackGraphCmd() *cobra.Command {
 	}
 	cmd.PersistentFlags().StringVarP(
 		&stackName, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().StringVar(&format, "format", "dot",
+		"The format of the graph: 'dot' or 'json'")
 	cmd.PersistentFlags().BoolVar(&ignoreParentEdges, "ignore-parent-edges", false,
 		"Ignores edges introduced by parent/child resource relationships")
 	cmd.PersistentFlags().BoolVar(&ignoreDependencyEdges, "ignore-dependency-edges", false,
 		"Ignores edges introduced by dependency resource relationships")
+	cmd.PersistentFlags().BoolVar(&includeProviderEdges, "include-provider-edges", false,
+		"Includes edges introduced by the providers that manage resources")
 	cmd.PersistentFlags().StringVar(&dependencyEdgeColor, "dependency-edge-color", "#246C60",
 		"Sets the color of dependency edges in the graph")
 	cmd.PersistentFlags().StringVar(&parentEdgeColor, "parent-edge-color", "#AA6639",
 		"Sets the color of parent edges in the graph")
+	cmd.PersistentFlags().StringVar(&providerEdgeColor, "provider-edge-color", "#3F4F9B",
+		"Sets the color of provider edges in the graph")
 	return cmd
 }
-
-// All of the types and code within this file are to provide implementations of the interfaces
-// in the `graph` package, so that we can use the `dotconv` package to output our graph in the
-// DOT format.
-//
-// `dependencyEdge` implements graph.Edge, `dependencyVertex` implements graph.Vertex, and
-// `dependencyGraph` implements `graph.Graph`.
-type dependencyEdge struct {
-	to   *dependencyVertex
-	from *dependencyVertex
-}
-
-// In this simple case, edges have no data.
-func (edge *dependencyEdge) Data() interface{} {
-	return nil
-}
-
-// In this simple case, edges have no label.
-func (edge *dependencyEdge) Label() string {
-	return ""
-}
-
-func (edge *dependencyEdge) To() graph.Vertex {
-	return edge.to
-}
-
-func (edge *dependencyEdge) From() graph.Vertex {
-	return edge.from
-}
-
-func (edge *dependencyEdge) Color() string {
-	return dependencyEdgeColor
-}
-
-// parentEdges represent edges in the parent-child graph, which
-// exists alongside the dependency graph. An edge exists from node
-// A to node B if node B is considered to be a parent of node A.
-type parentEdge struct {
-	to   *dependencyVertex
-	from *dependencyVertex
-}
-
-func (edge *parentEdge) Data() interface{} {
-	return nil
-}
-
-// In this simple case, edges have no label.
-func (edge *parentEdge) Label() string {
-	return ""
-}
-
-func (edge *parentEdge) To() graph.Vertex {
-	return edge.to
-}
-
-func (edge *parentEdge) From() graph.Vertex {
-	return edge.from
-}
-
-func (edge *parentEdge) Color() string {
-	return parentEdgeColor
-}
-
-// A dependencyVertex contains a reference to the graph to which it belongs
-// and to the resource state that it represents. Incoming and outgoing edges
-// are calculated on-demand using the combination of the graph and the state.
-type dependencyVertex struct {
-	graph         *dependencyGraph
-	resource      *resource.State
-	incomingEdges []graph.Edge
-	outgoingEdges []graph.Edge
-}
-
-func (vertex *dependencyVertex) Data() interface{} {
-	return vertex.resource
-}
-
-func (vertex *dependencyVertex) Label() string {
-	return string(vertex.resource.URN)
-}
-
-func (vertex *dependencyVertex) Ins() []graph.Edge {
-	return vertex.incomingEdges
-}
-
-// Outgoing edges are indirectly calculated by traversing the entire graph looking
-// for edges that point to this vertex. This is slow, but our graphs aren't big enough
-// for this to matter too much.
-func (vertex *dependencyVertex) Outs() []graph.Edge {
-	return vertex.outgoingEdges
-}
-
-// A dependencyGraph is a thin wrapper around a map of URNs to vertices in
-// the graph. It is constructed directly from a snapshot.
-type dependencyGraph struct {
-	vertices map[resource.URN]*dependencyVertex
-}
-
-// Roots are edges that point to the root set of our graph. In our case,
-// for simplicity, we define the root set of our dependency graph to be everything.
-func (dg *dependencyGraph) Roots() []graph.Edge {
-	rootEdges := []graph.Edge{}
-	for _, vertex := range dg.vertices {
-		edge := &dependencyEdge{
-			to:   vertex,
-			from: nil,
-		}
-
-		rootEdges = append(rootEdges, edge)
-	}
-
-	return rootEdges
-}
-
-// Makes a dependency graph from a deployment snapshot, allocating a vertex
-// for every resource in the graph.
-func makeDependencyGraph(snapshot *deploy.Snapshot) *dependencyGraph {
-	dg := &dependencyGraph{
-		vertices: make(map[resource.URN]*dependencyVertex),
-	}
-
-	for _, resource := range snapshot.Resources {
-		vertex := &dependencyVertex{
-			graph:    dg,
-			resource: resource,
-		}
-
-		dg.vertices[resource.URN] = vertex
-	}
-
-	for _, vertex := range dg.vertices {
-		if !ignoreDependencyEdges {
-			// Incoming edges are directly stored within the checkpoint file; they represent
-			// resources on which this vertex immediately depends upon.
-			for _, dep := range vertex.resource.Dependencies {
-				vertexWeDependOn := vertex.graph.vertices[dep]
-				edge := &dependencyEdge{to: vertex, from: vertexWeDependOn}
-				vertex.incomingEdges = append(vertex.incomingEdges, edge)
-				vertexWeDependOn.outgoingEdges = append(vertexWeDependOn.outgoingEdges, edge)
-			}
-		}
-
-		// alongside the dependency graph sits the resource parentage graph, which
-		// is also displayed as part of this graph, although with different colored
-		// edges.
-		if !ignoreParentEdges {
-			if parent := vertex.resource.Parent; parent != resource.URN("") {
-				parentVertex := dg.vertices[parent]
-				vertex.outgoingEdges = append(vertex.outgoingEdges, &parentEdge{
-					to:   parentVertex,
-					from: vertex,
-				})
-			}
-		}
-	}
-
-	return dg
-}
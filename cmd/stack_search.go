@@ -0,0 +1,135 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func newStackSearchCmd() *cobra.Command {
+	var allStacks bool
+	var jsonOut bool
+	var typ string
+	var where []string
+	cmd := &cobra.Command{
+		Use:   "search",
+		Short: "Search the resources of every stack for those that match a query",
+		Long: "Search the resources of every stack for those that match a query.\n" +
+			"\n" +
+			"Searches the latest state of each stack of the current project, or of every project with --all, for\n" +
+			"resources of the type given by --type whose properties satisfy every --where predicate. Each\n" +
+			"predicate is a property path followed by an optional comparison: `path=value`, `path!=value`, or\n" +
+			"`path~=value` (the string contains, or the array has an element equal to, the value). A path on its\n" +
+			"own matches resources that have a value at that path. Secret values never match.\n" +
+			"\n" +
+			"For example, to find every S3 bucket with a public ACL:\n" +
+			"\n" +
+			"    pulumi stack search --all --type aws:s3/bucket:Bucket --where acl=public-read\n" +
+			"\n" +
+			"Matches are printed as they are found. With --json, each match is printed as a JSON object on a line\n" +
+			"of its own.",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			query := backend.ResourceQuery{Type: tokens.Type(typ)}
+			for _, w := range where {
+				predicate, err := backend.ParsePropertyPredicate(w)
+				if err != nil {
+					return err
+				}
+				query.Predicates = append(query.Predicates, predicate)
+			}
+
+			if !allStacks {
+				// Ensure we are in a project; if not, we will fail.
+				projPath, err := workspace.DetectProjectPath()
+				if err != nil {
+					return errors.Wrapf(err, "could not detect current project")
+				} else if projPath == "" {
+					return errors.New("no Pulumi.yaml found; please run this command in a project directory")
+				}
+
+				proj, err := workspace.LoadProject(projPath)
+				if err != nil {
+					return errors.Wrap(err, "could not load current project")
+				}
+				query.Project = &proj.Name
+			}
+
+			b, err := currentBackend(display.Options{Color: cmdutil.GetGlobalColorization()})
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			failed := 0
+			err = backend.SearchResources(commandContext(), b, query, func(result backend.SearchResult) error {
+				if result.Err != nil {
+					failed++
+					cmdutil.Diag().Warningf(diag.RawMessage("", result.Err.Error()))
+					return nil
+				}
+				if jsonOut {
+					return encoder.Encode(stackSearchResultJSON{
+						Stack: result.Stack.String(),
+						URN:   string(result.Resource.URN),
+						ID:    string(result.Resource.ID),
+						Type:  string(result.Resource.Type),
+					})
+				}
+				fmt.Printf("%s\t%s\n", result.Stack, result.Resource.URN)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if failed > 0 {
+				return errors.Errorf("%d stacks could not be searched", failed)
+			}
+			return nil
+		}),
+	}
+	cmd.PersistentFlags().BoolVarP(
+		&allStacks, "all", "a", false, "Search all stacks instead of just stacks for the current project")
+	cmd.PersistentFlags().BoolVarP(
+		&jsonOut, "json", "j", false, "Emit output as JSON")
+	cmd.PersistentFlags().StringVarP(
+		&typ, "type", "t", "", "Only match resources of this type, e.g. aws:s3/bucket:Bucket")
+	cmd.PersistentFlags().StringArrayVarP(
+		&where, "where", "w", []string{}, "Only match resources whose properties satisfy this predicate; "+
+			"may be specified more than once")
+
+	return cmd
+}
+
+// stackSearchResultJSON is the shape of each line of the --json output of this command. While we can add fields to
+// this structure in the future, we should not change existing fields.
+type stackSearchResultJSON struct {
+	Stack string `json:"stack"`
+	URN   string `json:"urn"`
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type"`
+}
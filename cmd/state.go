@@ -49,6 +49,12 @@ troubleshooting a stack or when performing specific edits that otherwise would r
 
 	cmd.AddCommand(newStateDeleteCommand())
 	cmd.AddCommand(newStateUnprotectCommand())
+	cmd.AddCommand(newStateAnnotateCommand())
+	cmd.AddCommand(newStateRepairCommand())
+	cmd.AddCommand(newStateGCCommand())
+	cmd.AddCommand(newStateArtifactCommand())
+	cmd.AddCommand(newStateRenameCommand())
+	cmd.AddCommand(newStateCompactCommand())
 	return cmd
 }
 
@@ -0,0 +1,76 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/edit"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newStateAnnotateCommand() *cobra.Command {
+	var stack string
+
+	cmd := &cobra.Command{
+		Use:   "annotate <resource URN> <namespace:name>=<value>...",
+		Short: "Attach annotations to a resource in a stack's state",
+		Long: `Attach annotations to a resource in a stack's state
+
+This command attaches annotations to a resource, for use by external tools such as cost allocation or
+ownership mapping. Annotations are preserved by subsequent updates to the stack and are included in
+exported state and in engine events.
+
+Each annotation key must be of the form 'namespace:name'. The 'pulumi' namespace is reserved.
+An annotation is removed by giving it an empty value.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state annotate 'urn:pulumi:stage::demo::aws:s3/bucket:Bucket::logs' cost:center=1234 owner:team=`,
+		Args: cmdutil.MinimumNArgs(2),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			urn, err := resource.ParseURN(args[0])
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			annotations := make(map[string]string)
+			for _, arg := range args[1:] {
+				eq := strings.Index(arg, "=")
+				if eq < 0 {
+					return result.Errorf("expected an annotation of the form <namespace:name>=<value>, got %q", arg)
+				}
+				annotations[arg[:eq]] = arg[eq+1:]
+			}
+
+			if res := runStateEdit(stack, urn, edit.AnnotateResource(annotations)); res != nil {
+				return res
+			}
+			fmt.Println("Resource successfully annotated")
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	return cmd
+}
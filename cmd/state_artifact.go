@@ -0,0 +1,111 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newStateArtifactCommand() *cobra.Command {
+	var stack string
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "artifact <resource URN> [name]",
+		Short: "List or retrieve the artifacts attached to a resource",
+		Long: `List or retrieve the artifacts attached to a resource
+
+Providers may attach small files, such as a generated kubeconfig or certificate, to the resources they create
+and update. The stack's state records a reference to each artifact, and the backend stores its contents.
+
+With only a URN, this command lists the resource's artifacts. With a name, it writes the contents of that
+artifact to standard output, or to the file given by --output.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state artifact 'urn:pulumi:stage::demo::eks:index:Cluster::main' kubeconfig --output kubeconfig.json`,
+		Args: cmdutil.RangeArgs(1, 2),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+			s, err := requireStack(stack, false, opts, true /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+			snap, err := s.Snapshot(commandContext())
+			if err != nil {
+				return result.FromError(err)
+			}
+			urn, err := resource.ParseURN(args[0])
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			if len(args) == 1 {
+				res, err := locateStackResource(opts, snap, urn)
+				if err != nil {
+					return result.FromError(err)
+				}
+				if len(res.Artifacts) == 0 {
+					fmt.Println("The resource has no artifacts")
+					return nil
+				}
+				for _, a := range res.Artifacts {
+					fmt.Printf("%s\t%s\t%d bytes\tsha256:%s\n", a.Name, a.ContentType, a.Size, a.Digest)
+				}
+				return nil
+			}
+
+			b, ok := s.Backend().(backend.ArtifactBackend)
+			if !ok {
+				return result.Errorf("the %s backend does not store artifacts", s.Backend().Name())
+			}
+			_, contents, err := snap.ReadArtifact(b.ArtifactStore(s.Ref()), urn, args[1])
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			if output == "" {
+				_, err = os.Stdout.Write(contents)
+				return result.WrapIfNonNil(err)
+			}
+			if err = ioutil.WriteFile(output, contents, 0600); err != nil {
+				return result.FromError(errors.Wrapf(err, "writing artifact to %s", output))
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().StringVarP(
+		&output, "output", "o", "",
+		"Write the artifact to the given file rather than to standard output")
+	return cmd
+}
@@ -0,0 +1,102 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newStateCompactCommand() *cobra.Command {
+	var stack string
+	var historyRetention time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Remove dead entries from the stack's state",
+		Long: `Remove dead entries from the stack's state
+
+Over time, a stack's state accumulates entries that no longer serve a purpose: components and providers that
+are pending deletion but have nothing left to delete, references to providers that have been superseded by
+identical ones, and stale pending operations. This command rewrites the state without them. Resources that
+still have a cloud object to delete, and pending operations whose outcome is unknown, are kept.
+
+With --history-retention, entries in the stack's update history that are older than the given duration are
+removed as well. The most recent entry is always kept.`,
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			if historyRetention < 0 {
+				return result.Error("--history-retention must not be negative")
+			}
+
+			var report deploy.CompactionReport
+			res := runTotalStateEdit(stack, func(_ display.Options, snap *deploy.Snapshot) error {
+				report = snap.Compact()
+				return nil
+			})
+			if res != nil {
+				return res
+			}
+
+			if report.Empty() {
+				fmt.Println("The stack's state has no dead entries")
+			}
+			for _, tombstone := range report.Tombstones {
+				fmt.Printf("removed %s, which was pending deletion\n", tombstone.URN)
+			}
+			if report.ProviderReferences > 0 {
+				fmt.Printf("rewrote %d references to a superseded provider\n", report.ProviderReferences)
+			}
+			for _, op := range report.PendingOperations {
+				fmt.Printf("removed stale pending operation: %s (%s)\n", op.Resource.URN, op.Type)
+			}
+
+			if historyRetention == 0 {
+				return nil
+			}
+			s, err := requireStack(stack, false, display.Options{Color: cmdutil.GetGlobalColorization()}, false)
+			if err != nil {
+				return result.FromError(err)
+			}
+			pruner, ok := s.Backend().(backend.HistoryPruner)
+			if !ok {
+				return result.FromError(backend.UnsupportedError{Backend: s.Backend().Name(), Feature: "pruning history"})
+			}
+			pruned, err := pruner.PruneHistory(commandContext(), s.Ref(), time.Now().Add(-historyRetention))
+			if err != nil {
+				return result.FromError(errors.Wrap(err, "pruning history"))
+			}
+			fmt.Printf("removed %d entries from the stack's update history\n", pruned)
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().DurationVar(
+		&historyRetention, "history-retention", 0,
+		"Also remove update history older than the given duration (e.g. 720h)")
+	return cmd
+}
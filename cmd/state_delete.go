@@ -50,7 +50,10 @@ pulumi state delete 'urn:pulumi:stage::demo::eks:index:Cluster$pulumi:providers:
 `,
 		Args: cmdutil.ExactArgs(1),
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
-			urn := resource.URN(args[0])
+			urn, err := resource.ParseURN(args[0])
+			if err != nil {
+				return result.FromError(err)
+			}
 			res := runStateEdit(stack, urn, func(snap *deploy.Snapshot, res *resource.State) error {
 				if !force {
 					return edit.DeleteResource(snap, res)
@@ -0,0 +1,104 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newStateGCCommand() *cobra.Command {
+	var stack string
+	var attempts int
+	var backoff time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete the resources left pending deletion by earlier updates",
+		Long: `Delete the resources left pending deletion by earlier updates
+
+When a resource is replaced by creating its replacement before deleting it, and the delete fails, the stack's state
+records the old resource as pending deletion. This command asks each such resource's provider to delete it, retrying
+deletions that fail with transient errors, removes the resources that were deleted from the state, and prints a report
+of what it did. Resources that need manual intervention, e.g. because they are protected or because their deletion
+failed with an error that is not transient, are left pending and cause the command to fail.
+
+The same pass can be run at the start of each update instead.`,
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			if attempts < 1 {
+				return result.Error("--attempts must be at least 1")
+			}
+			policy := deploy.RetryPolicy{MaxAttempts: attempts, InitialBackoff: backoff}
+
+			var report []engine.PendingDeleteEntry
+			res := runTotalStateEdit(stack, func(_ display.Options, snap *deploy.Snapshot) error {
+				pwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				plugctx, err := plugin.NewContext(cmdutil.Diag(), cmdutil.Diag(), nil, nil, pwd, nil, nil)
+				if err != nil {
+					return err
+				}
+				defer contract.IgnoreClose(plugctx)
+
+				report, err = engine.CollectPendingDeletes(plugctx.Host, snap, policy, nil, nil)
+				return err
+			})
+			if res != nil {
+				return res
+			}
+
+			if len(report) == 0 {
+				fmt.Println("No resources are pending deletion")
+				return nil
+			}
+			manual := 0
+			for _, entry := range report {
+				fmt.Printf("%s: %s: %s\n", entry.Action, entry.URN, entry.Message)
+				if entry.Action == engine.PendingDeleteManual {
+					manual++
+				}
+			}
+			if manual > 0 {
+				return result.Errorf("%d resource(s) pending deletion need manual intervention", manual)
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().IntVar(
+		&attempts, "attempts", engine.DefaultPendingDeleteRetryPolicy.MaxAttempts,
+		"The number of times to attempt each deletion that fails with a transient error")
+	cmd.PersistentFlags().DurationVar(
+		&backoff, "backoff", engine.DefaultPendingDeleteRetryPolicy.InitialBackoff,
+		"The delay before the first retry of a deletion; each subsequent retry doubles it")
+	return cmd
+}
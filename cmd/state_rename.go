@@ -0,0 +1,93 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newStateRenameCommand() *cobra.Command {
+	var stack string
+	var parent string
+
+	cmd := &cobra.Command{
+		Use:   "rename <resource URN> [new name]",
+		Short: "Rename a resource in a stack's state",
+		Long: `Rename a resource in a stack's state
+
+This command gives a resource a new name, a new parent, or both, so that the resource's state is carried
+over when the program is refactored rather than the resource being replaced. The resource's URN and every
+reference to it in the state are rewritten, as are the URNs of its descendants if it is given a new parent.
+Each renamed resource records its old URN as an alias.
+
+Make sure that URNs are single-quoted to avoid having characters unexpectedly interpreted by the shell.
+
+Example:
+pulumi state rename 'urn:pulumi:stage::demo::aws:s3/bucket:Bucket::logs' access-logs`,
+		Args: cmdutil.RangeArgs(1, 2),
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			urn, err := resource.ParseURN(args[0])
+			if err != nil {
+				return result.FromError(err)
+			}
+			var name tokens.QName
+			if len(args) > 1 {
+				if !tokens.IsQName(args[1]) {
+					return result.Errorf("'%s' is not a valid resource name", args[1])
+				}
+				name = tokens.QName(args[1])
+			}
+			if name == "" && parent == "" {
+				return result.Errorf("expected a new name, a new parent, or both")
+			}
+			var parentURN resource.URN
+			if parent != "" {
+				if parentURN, err = resource.ParseURN(parent); err != nil {
+					return result.FromError(err)
+				}
+			}
+
+			var newURN resource.URN
+			res := runTotalStateEdit(stack, func(_ display.Options, snap *deploy.Snapshot) error {
+				var err error
+				newURN, err = engine.StateRename(snap, urn, name, parentURN)
+				return err
+			})
+			if res != nil {
+				return res
+			}
+			fmt.Printf("Resource successfully renamed to %s\n", newURN)
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	cmd.PersistentFlags().StringVar(
+		&parent, "parent", "",
+		"The URN of the resource's new parent")
+	return cmd
+}
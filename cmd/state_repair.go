@@ -0,0 +1,79 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newStateRepairCommand() *cobra.Command {
+	var stack string
+
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Reconcile operations left pending by an interrupted update",
+		Long: `Reconcile operations left pending by an interrupted update
+
+When an update dies in the middle of a step, the stack's state records the operations that were in flight. This
+command asks each affected resource's provider whether the resource exists, refreshes or removes the resource in the
+state accordingly, clears the pending operations, and prints a report of what it did.`,
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			var report []engine.RepairEntry
+			res := runTotalStateEdit(stack, func(_ display.Options, snap *deploy.Snapshot) error {
+				pwd, err := os.Getwd()
+				if err != nil {
+					return err
+				}
+				plugctx, err := plugin.NewContext(cmdutil.Diag(), cmdutil.Diag(), nil, nil, pwd, nil, nil)
+				if err != nil {
+					return err
+				}
+				defer contract.IgnoreClose(plugctx)
+
+				report, err = engine.Repair(plugctx.Host, snap)
+				return err
+			})
+			if res != nil {
+				return res
+			}
+
+			if len(report) == 0 {
+				fmt.Println("No pending operations to repair")
+				return nil
+			}
+			for _, entry := range report {
+				fmt.Printf("%s: %s (%s): %s\n", entry.Action, entry.URN, entry.Operation, entry.Message)
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&stack, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+	return cmd
+}
@@ -51,7 +51,10 @@ This command clears the 'protect' bit on one or more resources, allowing those r
 				return result.Error("must provide a URN corresponding to a resource")
 			}
 
-			urn := resource.URN(args[0])
+			urn, err := resource.ParseURN(args[0])
+			if err != nil {
+				return result.FromError(err)
+			}
 			return unprotectResource(stack, urn)
 		}),
 	}
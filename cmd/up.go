@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
@@ -50,22 +51,36 @@ func newUpCmd() *cobra.Command {
 	var message string
 	var stack string
 	var configArray []string
+	var configOverrides []string
 
 	// Flags for engine.UpdateOptions.
+	var allowProtected bool
 	var analyzers []string
+	var defaultTags []string
+	var diagFilters []string
 	var diffDisplay bool
+	var maxCreates int
+	var maxDuration time.Duration
 	var parallel int
+	var prune string
+	var pruneExempt []string
 	var refresh bool
+	var requireApproval bool
 	var showConfig bool
 	var showReplacementSteps bool
 	var showSames bool
+	var skipChecks bool
 	var skipPreview bool
+	var takeoverStaleLock bool
+	var breakLock bool
 	var suppressOutputs bool
+	var validatePreview bool
+	var validateSnapshot bool
 	var yes bool
 	var secretsProvider string
 
 	// up implementation used when the source of the Pulumi program is in the current working directory.
-	upWorkingDirectory := func(opts backend.UpdateOptions) result.Result {
+	upWorkingDirectory := func(cmd *cobra.Command, opts backend.UpdateOptions) result.Result {
 		s, err := requireStack(stack, true, opts.Display, true /*setCurrent*/)
 		if err != nil {
 			return result.FromError(err)
@@ -103,11 +118,60 @@ func newUpCmd() *cobra.Command {
 			return result.FromError(errors.Wrap(err, "getting stack configuration"))
 		}
 
-		opts.Engine = engine.UpdateOptions{
-			Analyzers: analyzers,
-			Parallel:  parallel,
-			Debug:     debug,
-			Refresh:   refresh,
+		if err = applyUpdateDefaults(cmd, proj, s, updateDefaultFlags{
+			parallel:        &parallel,
+			refresh:         &refresh,
+			requireApproval: &requireApproval,
+			diff:            &diffDisplay,
+		}); err != nil {
+			return result.FromError(err)
+		}
+		opts.RequireApprovalForDestructive = requireApproval
+		if diffDisplay {
+			opts.Display.Type = display.DisplayDiff
+		}
+
+		overrides, err := parseConfigOverrides(configOverrides)
+		if err != nil {
+			return result.FromError(err)
+		}
+		tags, err := parseDefaultTags(defaultTags)
+		if err != nil {
+			return result.FromError(err)
+		}
+		filters, closeDiagFiles, err := parseDiagnosticFilters(diagFilters)
+		if err != nil {
+			return result.FromError(err)
+		}
+		defer closeDiagFiles()
+
+		// Keep the plugins started by the preview for the update that follows it.
+		pool := newPluginPool()
+		if pool != nil {
+			defer contract.IgnoreClose(pool)
+		}
+
+		opts.Engine, err = engine.NewUpdateOptionsBuilder().
+			AllowProtected(allowProtected).
+			Analyzers(analyzers...).
+			Parallel(parallel).
+			Prune(deploy.PruneMode(prune), pruneExempt...).
+			Debug(debug).
+			DiagnosticLimits(engine.DefaultDiagnosticLimits).
+			DiagnosticFilters(filters...).
+			Refresh(refresh).
+			Budget(engine.UpdateBudget{MaxDuration: maxDuration, MaxCreates: maxCreates}).
+			ConfigOverrides(overrides).
+			DefaultTags(tags).
+			PluginPool(pool).
+			CheckCache(newCheckCache()).
+			AssetCache(assetCache).
+			ValidateSnapshot(validateSnapshot).
+			ValidatePreview(validatePreview).
+			SkipChecks(skipChecks).
+			Build()
+		if err != nil {
+			return result.FromError(err)
 		}
 
 		changes, res := s.Update(commandContext(), backend.UpdateOperation{
@@ -132,7 +196,9 @@ func newUpCmd() *cobra.Command {
 	}
 
 	// up implementation used when the source of the Pulumi program is a template name or a URL to a template.
-	upTemplateNameOrURL := func(templateNameOrURL string, opts backend.UpdateOptions) result.Result {
+	upTemplateNameOrURL := func(cmd *cobra.Command, templateNameOrURL string,
+		opts backend.UpdateOptions) result.Result {
+
 		// Retrieve the template repo.
 		repo, err := workspace.RetrieveTemplates(templateNameOrURL, false)
 		if err != nil {
@@ -260,11 +326,60 @@ func newUpCmd() *cobra.Command {
 			return result.FromError(errors.Wrap(err, "getting stack configuration"))
 		}
 
-		opts.Engine = engine.UpdateOptions{
-			Analyzers: analyzers,
-			Parallel:  parallel,
-			Debug:     debug,
-			Refresh:   refresh,
+		if err = applyUpdateDefaults(cmd, proj, s, updateDefaultFlags{
+			parallel:        &parallel,
+			refresh:         &refresh,
+			requireApproval: &requireApproval,
+			diff:            &diffDisplay,
+		}); err != nil {
+			return result.FromError(err)
+		}
+		opts.RequireApprovalForDestructive = requireApproval
+		if diffDisplay {
+			opts.Display.Type = display.DisplayDiff
+		}
+
+		overrides, err := parseConfigOverrides(configOverrides)
+		if err != nil {
+			return result.FromError(err)
+		}
+		tags, err := parseDefaultTags(defaultTags)
+		if err != nil {
+			return result.FromError(err)
+		}
+		filters, closeDiagFiles, err := parseDiagnosticFilters(diagFilters)
+		if err != nil {
+			return result.FromError(err)
+		}
+		defer closeDiagFiles()
+
+		// Keep the plugins started by the preview for the update that follows it.
+		pool := newPluginPool()
+		if pool != nil {
+			defer contract.IgnoreClose(pool)
+		}
+
+		opts.Engine, err = engine.NewUpdateOptionsBuilder().
+			AllowProtected(allowProtected).
+			Analyzers(analyzers...).
+			Parallel(parallel).
+			Prune(deploy.PruneMode(prune), pruneExempt...).
+			Debug(debug).
+			DiagnosticLimits(engine.DefaultDiagnosticLimits).
+			DiagnosticFilters(filters...).
+			Refresh(refresh).
+			Budget(engine.UpdateBudget{MaxDuration: maxDuration, MaxCreates: maxCreates}).
+			ConfigOverrides(overrides).
+			DefaultTags(tags).
+			PluginPool(pool).
+			CheckCache(newCheckCache()).
+			AssetCache(assetCache).
+			ValidateSnapshot(validateSnapshot).
+			ValidatePreview(validatePreview).
+			SkipChecks(skipChecks).
+			Build()
+		if err != nil {
+			return result.FromError(err)
 		}
 
 		// TODO for the URL case:
@@ -320,6 +435,8 @@ func newUpCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(err)
 			}
+			opts.TakeoverStaleLock = takeoverStaleLock
+			opts.BreakLock = breakLock
 
 			var displayType = display.DisplayProgress
 			if diffDisplay {
@@ -338,10 +455,10 @@ func newUpCmd() *cobra.Command {
 			}
 
 			if len(args) > 0 {
-				return upTemplateNameOrURL(args[0], opts)
+				return upTemplateNameOrURL(cmd, args[0], opts)
 			}
 
-			return upWorkingDirectory(opts)
+			return upWorkingDirectory(cmd, opts)
 		}),
 	}
 
@@ -360,6 +477,10 @@ func newUpCmd() *cobra.Command {
 	cmd.PersistentFlags().StringArrayVarP(
 		&configArray, "config", "c", []string{},
 		"Config to use during the update")
+	cmd.PersistentFlags().StringArrayVar(
+		&configOverrides, "config-override", []string{},
+		"Override a config value for this update only, without saving it to the stack, e.g. "+
+			"--config-override aws:region=us-west-2")
 	cmd.PersistentFlags().StringVar(
 		&secretsProvider, "secrets-provider", "default", "The type of the provider that should be used to encrypt and "+
 			"decrypt secrets (possible choices: default, passphrase). Only used when creating a new stack from "+
@@ -370,18 +491,54 @@ func newUpCmd() *cobra.Command {
 		"Optional message to associate with the update operation")
 
 	// Flags for engine.UpdateOptions.
+	cmd.PersistentFlags().BoolVar(
+		&allowProtected, "allow-protected", false,
+		"Allow this update to delete or replace protected resources")
 	cmd.PersistentFlags().StringSliceVar(
 		&analyzers, "analyzer", []string{},
 		"Run one or more analyzers as part of this update")
+	cmd.PersistentFlags().StringArrayVar(
+		&defaultTags, "default-tag", []string{},
+		"Apply a tag to every resource whose provider supports default tags, overriding the program's tag of the same "+
+			"name, e.g. --default-tag cost-center=1234")
+	cmd.PersistentFlags().StringArrayVar(
+		&diagFilters, "diag", []string{},
+		"Set the least severe diagnostics shown from a source, and optionally append the rest to a file, e.g. "+
+			"--diag provider:aws=warning:aws.log. Sources are engine, language, provider:<package>, and * for all others")
 	cmd.PersistentFlags().BoolVar(
 		&diffDisplay, "diff", false,
 		"Display operation as a rich diff showing the overall change")
+	cmd.PersistentFlags().IntVar(
+		&maxCreates, "max-creates", 0,
+		"Warn if this update creates more than this many resources (0 for no limit)")
+	cmd.PersistentFlags().DurationVar(
+		&maxDuration, "max-duration", 0,
+		"Warn if this update runs for longer than this duration, e.g. 30m (0 for no limit)")
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
+	cmd.PersistentFlags().StringVar(
+		&prune, "prune", "",
+		"Report resources that the program no longer declares as prunes instead of deleting them ('report'), "+
+			"or delete them as prunes ('delete')")
+	cmd.PersistentFlags().StringSliceVar(
+		&pruneExempt, "prune-exempt", []string{},
+		"Never prune the resources with these URNs or types, e.g. resources that are intentionally left unmanaged")
 	cmd.PersistentFlags().BoolVarP(
 		&refresh, "refresh", "r", false,
 		"Refresh the state of the stack's resources before this update")
+	cmd.PersistentFlags().BoolVar(
+		&requireApproval, "require-approval-for-destructive", false,
+		"Require approval of updates that delete or replace resources, even with --yes; such updates fail if "+
+			"approval cannot be given interactively")
+	cmd.PersistentFlags().BoolVar(
+		&validatePreview, "provider-dry-run", false,
+		"Ask providers that support it to validate each planned create and update with their cloud APIs during the "+
+			"preview, so that errors such as exhausted quotas are reported before any changes are made")
+	cmd.PersistentFlags().BoolVar(
+		&validateSnapshot, "validate-snapshot", false,
+		"Check the stored inputs of every resource against its provider before updating, and fail if any are "+
+			"rejected, e.g. because a provider upgrade changed their shape")
 	cmd.PersistentFlags().BoolVar(
 		&showConfig, "show-config", false,
 		"Show configuration keys and variables")
@@ -391,9 +548,19 @@ func newUpCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&showSames, "show-sames", false,
 		"Show resources that don't need be updated because they haven't changed, alongside those that do")
+	cmd.PersistentFlags().BoolVar(
+		&skipChecks, "skip-checks", false,
+		"Do not run the project's checks after the update is applied")
 	cmd.PersistentFlags().BoolVar(
 		&skipPreview, "skip-preview", false,
 		"Do not perform a preview before performing the update")
+	cmd.PersistentFlags().BoolVar(
+		&takeoverStaleLock, "takeover-stale-lock", false,
+		"Take over the stack's lock if the update holding it has stopped sending heartbeats")
+	cmd.PersistentFlags().BoolVar(
+		&breakLock, "break-lock", false,
+		"Break the stack's lock even if the update holding it appears to be running. Only use this if you are "+
+			"certain that the other update has stopped, as two updates running at once may corrupt the stack's state")
 	cmd.PersistentFlags().BoolVar(
 		&suppressOutputs, "suppress-outputs", false,
 		"Suppress display of stack outputs (in case they contain sensitive values)")
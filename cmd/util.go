@@ -31,6 +31,7 @@ import (
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
 	survey "gopkg.in/AlecAivazis/survey.v1"
 	surveycore "gopkg.in/AlecAivazis/survey.v1/core"
 	git "gopkg.in/src-d/go-git.v4"
@@ -40,13 +41,18 @@ import (
 	"github.com/pulumi/pulumi/pkg/backend/filestate"
 	"github.com/pulumi/pulumi/pkg/backend/httpstate"
 	"github.com/pulumi/pulumi/pkg/backend/state"
+	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/diag/colors"
 	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/util/cancel"
 	"github.com/pulumi/pulumi/pkg/util/ciutil"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/gitutil"
+	"github.com/pulumi/pulumi/pkg/util/logging"
 	"github.com/pulumi/pulumi/pkg/util/tracing"
 	"github.com/pulumi/pulumi/pkg/workspace"
 )
@@ -55,6 +61,33 @@ func hasDebugCommands() bool {
 	return cmdutil.IsTruthy(os.Getenv("PULUMI_DEBUG_COMMANDS"))
 }
 
+// newPluginPool returns a pool that keeps plugin processes alive from one engine operation to the next, e.g. from the
+// preview that precedes an update to the update itself, if PULUMI_REUSE_PLUGINS is set. It returns nil otherwise.
+func newPluginPool() *plugin.HostPool {
+	if !cmdutil.IsTruthy(os.Getenv("PULUMI_REUSE_PLUGINS")) {
+		return nil
+	}
+	return plugin.NewHostPool(plugin.DefaultHostPoolIdleTimeout)
+}
+
+// newCheckCache returns the cache of the checks made by previews, if the experimental check cache has been enabled by
+// setting PULUMI_EXPERIMENTAL_CHECK_CACHE.
+func newCheckCache() *deploy.CheckCache {
+	if !cmdutil.IsTruthy(os.Getenv("PULUMI_EXPERIMENTAL_CHECK_CACHE")) {
+		return nil
+	}
+	dir, err := workspace.GetCheckCacheDir()
+	if err != nil {
+		logging.V(5).Infof("not caching checks: %v", err)
+		return nil
+	}
+	return deploy.NewCheckCache(dir)
+}
+
+// assetCache is the cache of the hashes and translated contents of assets and archives used by this invocation's
+// operations, or nil if asset caching has been disabled by setting PULUMI_DISABLE_ASSET_CACHE.
+var assetCache *resource.AssetCache
+
 func currentBackend(opts display.Options) (backend.Backend, error) {
 	url, err := workspace.GetCurrentCloudURL()
 	if err != nil {
@@ -62,11 +95,29 @@ func currentBackend(opts display.Options) (backend.Backend, error) {
 	}
 
 	if filestate.IsFileStateBackendURL(url) {
-		return filestate.New(cmdutil.Diag(), url)
+		localOpts, err := localBackendOptions()
+		if err != nil {
+			return nil, err
+		}
+		return filestate.New(cmdutil.Diag(), url, localOpts)
 	}
 	return httpstate.Login(commandContext(), cmdutil.Diag(), url, opts)
 }
 
+// allowModifiedState is set by the --allow-modified-state flag, and lets local backends load checkpoints that were
+// modified outside of Pulumi.
+var allowModifiedState bool
+
+// localBackendOptions returns the options with which local backends are opened, including the checkpoint encryption key
+// configured by PULUMI_CHECKPOINT_KEY, if any.
+func localBackendOptions() (filestate.Options, error) {
+	sealer, err := filestate.OpenCheckpointSealer()
+	if err != nil {
+		return filestate.Options{}, errors.Wrap(err, "opening the checkpoint encryption key")
+	}
+	return filestate.Options{AllowModifiedCheckpoints: allowModifiedState, CheckpointSealer: sealer}, nil
+}
+
 // This is used to control the contents of the tracing header.
 var tracingHeader = os.Getenv("PULUMI_TRACING_HEADER")
 
@@ -552,7 +603,8 @@ func (cancellationScopeSource) NewScope(events chan<- engine.Event, isPreview bo
 						"and other inconsistencies.\n" + colors.Reset
 				}
 				events <- engine.Event{
-					Type: engine.StdoutColorEvent,
+					Type:    engine.StdoutColorEvent,
+					Version: engine.EventSchemaVersion,
 					Payload: engine.StdoutEventPayload{
 						Message: message,
 						Color:   colors.Always,
@@ -563,7 +615,8 @@ func (cancellationScopeSource) NewScope(events chan<- engine.Event, isPreview bo
 			} else {
 				message := colors.BrightRed + "^C received; terminating" + colors.Reset
 				events <- engine.Event{
-					Type: engine.StdoutColorEvent,
+					Type:    engine.StdoutColorEvent,
+					Version: engine.EventSchemaVersion,
 					Payload: engine.StdoutEventPayload{
 						Message: message,
 						Color:   colors.Always,
@@ -603,3 +656,101 @@ func updateFlagsToOptions(interactive, skipPreview, yes bool) (backend.UpdateOpt
 		SkipPreview: skipPreview,
 	}, nil
 }
+
+// parseDefaultTags parses the values of the --default-tag flag, each of the form name=value.
+func parseDefaultTags(tagArray []string) (map[string]string, error) {
+	if len(tagArray) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string)
+	for _, t := range tagArray {
+		kvp := strings.SplitN(t, "=", 2)
+		if len(kvp) != 2 || kvp[0] == "" {
+			return nil, errors.Errorf("default tag %q must be of the form name=value", t)
+		}
+		tags[kvp[0]] = kvp[1]
+	}
+	return tags, nil
+}
+
+// parseDiagnosticFilters parses filters of the form source=severity[:file], e.g. provider:aws=warning:aws.log, which
+// reports diagnostics from the AWS provider that are at least warnings, and appends the rest to aws.log. The source
+// "*" stands for every source without a filter of its own. The returned function closes the files that the filters
+// divert diagnostics to.
+func parseDiagnosticFilters(specs []string) ([]engine.DiagnosticFilter, func(), error) {
+	var filters []engine.DiagnosticFilter
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			contract.IgnoreClose(f)
+		}
+	}
+
+	for _, spec := range specs {
+		kvp := strings.SplitN(spec, "=", 2)
+		if len(kvp) != 2 || kvp[0] == "" || kvp[1] == "" {
+			closeFiles()
+			return nil, nil, errors.Errorf("diagnostic filter %q must be of the form source=severity[:file]", spec)
+		}
+
+		filter := engine.DiagnosticFilter{Source: diag.Source(kvp[0]), MinSeverity: diag.Severity(kvp[1])}
+		if filter.Source == "*" {
+			filter.Source = ""
+		}
+		if i := strings.Index(kvp[1], ":"); i != -1 {
+			filter.MinSeverity = diag.Severity(kvp[1][:i])
+			f, err := os.OpenFile(kvp[1][i+1:], os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+			if err != nil {
+				closeFiles()
+				return nil, nil, errors.Wrapf(err, "opening the file for diagnostic filter %q", spec)
+			}
+			files, filter.Divert = append(files, f), f
+		}
+		filters = append(filters, filter)
+	}
+	return filters, closeFiles, nil
+}
+
+// updateDefaultFlags holds the variables of the flags whose defaults may be declared by a project or stack. A nil field
+// is a flag that the command does not have.
+type updateDefaultFlags struct {
+	parallel        *int
+	refresh         *bool
+	requireApproval *bool
+	diff            *bool
+}
+
+// applyUpdateDefaults applies the update defaults declared by the project and by the stack's settings file to each of
+// the given flags that was not set explicitly on the command line.
+func applyUpdateDefaults(cmd *cobra.Command, proj *workspace.Project, s backend.Stack,
+	flags updateDefaultFlags) error {
+
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return errors.Wrap(err, "loading stack settings")
+	}
+	defaults := proj.UpdateDefaults.Merge(ps.UpdateDefaults)
+	if defaults == nil {
+		return nil
+	}
+	if err = defaults.Validate(); err != nil {
+		return err
+	}
+
+	explicit := cmd.Flags().Changed
+	if flags.parallel != nil && defaults.Parallel != nil && !explicit("parallel") {
+		*flags.parallel = *defaults.Parallel
+	}
+	if flags.refresh != nil && defaults.Refresh != nil && !explicit("refresh") {
+		*flags.refresh = *defaults.Refresh
+	}
+	if flags.requireApproval != nil && defaults.RequireApprovalForDestructive != nil &&
+		!explicit("require-approval-for-destructive") {
+		*flags.requireApproval = *defaults.RequireApprovalForDestructive
+	}
+	if flags.diff != nil && defaults.Diff != nil && !explicit("diff") {
+		*flags.diff = *defaults.Diff
+	}
+	return nil
+}
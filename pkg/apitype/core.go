@@ -49,6 +49,10 @@ const (
 type VersionedCheckpoint struct {
 	Version    int             `json:"version"`
 	Checkpoint json.RawMessage `json:"checkpoint"`
+	// Hash is the hex-encoded SHA-256 hash of the compacted Checkpoint document, recorded when it was written.
+	Hash string `json:"hash,omitempty"`
+	// Signature is an optional hex-encoded HMAC-SHA256 of Hash, present when a signing key was configured.
+	Signature string `json:"signature,omitempty"`
 }
 
 // CheckpointV1 is a serialized deployment target plus a record of the latest deployment.
@@ -285,6 +289,36 @@ type ResourceV3 struct {
 	AdditionalSecretOutputs []resource.PropertyKey `json:"additionalSecretOutputs,omitempty" yaml:"additionalSecretOutputs,omitempty"`
 	// Aliases is a list of previous URNs that this resource may have had in previous deployments
 	Aliases []resource.URN `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// Annotations are attached to the resource by external tools, keyed by "namespace:name". The engine preserves
+	// them across updates.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// CustomTimeouts bounds the time the provider may take to operate on this resource, and sets how often it polls.
+	CustomTimeouts *CustomTimeoutsV1 `json:"customTimeouts,omitempty" yaml:"customTimeouts,omitempty"`
+	// Artifacts refers to the files produced by the provider when creating or updating this resource.
+	Artifacts []ArtifactV1 `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+}
+
+// ArtifactV1 refers to a file produced by a provider when creating or updating a resource. Its contents are stored
+// apart from the checkpoint, addressed by their digest.
+type ArtifactV1 struct {
+	// Name is the artifact's name, unique among the resource's artifacts.
+	Name string `json:"name" yaml:"name"`
+	// ContentType is the artifact's MIME type, if known.
+	ContentType string `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+	// Size is the size of the artifact's contents, in bytes.
+	Size int64 `json:"size" yaml:"size"`
+	// Digest is the hex-encoded SHA-256 digest of the artifact's contents.
+	Digest string `json:"digest" yaml:"digest"`
+}
+
+// CustomTimeoutsV1 bounds the time a resource provider may take to create, update, or delete a resource, and sets how
+// often the provider polls for the completion of its long-running operations. Each setting is a duration such as
+// "10m0s"; empty timeouts are not enforced, and an empty poll interval leaves the interval up to the provider.
+type CustomTimeoutsV1 struct {
+	Create       string `json:"create,omitempty" yaml:"create,omitempty"`
+	Update       string `json:"update,omitempty" yaml:"update,omitempty"`
+	Delete       string `json:"delete,omitempty" yaml:"delete,omitempty"`
+	PollInterval string `json:"pollInterval,omitempty" yaml:"pollInterval,omitempty"`
 }
 
 // ManifestV1 captures meta-information about this checkpoint file, such as versions of binaries, etc.
@@ -297,6 +331,10 @@ type ManifestV1 struct {
 	Version string `json:"version" yaml:"version"`
 	// Plugins contains the binary version info of plug-ins used.
 	Plugins []PluginInfoV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	// Config contains the effective configuration of the update, with secret values in their encrypted form.
+	Config map[string]ConfigValue `json:"config,omitempty" yaml:"config,omitempty"`
+	// OmittedConfig contains the keys of the effective configuration whose values are not recorded in Config.
+	OmittedConfig []string `json:"omittedConfig,omitempty" yaml:"omittedConfig,omitempty"`
 }
 
 // PluginInfoV1 captures the version and information about a plugin.
@@ -350,6 +388,16 @@ const (
 	// VCSRepositoryKindTag is a tag that represents the kind of the cloud VCS that this stack
 	// may be associated with (inferred by the CLI based on the git remote info).
 	VCSRepositoryKindTag StackTagName = "vcs:kind"
+	// StackFreezeReasonTag is a tag that holds the reason that changes to the stack are suspended. The stack is frozen
+	// if and only if it has this tag.
+	StackFreezeReasonTag StackTagName = "pulumi:freeze:reason"
+	// StackFreezeUserTag is a tag that holds the user that froze the stack.
+	StackFreezeUserTag StackTagName = "pulumi:freeze:user"
+	// StackFreezeFrozenTag is a tag that holds the time, in RFC 3339 format, at which the stack was frozen.
+	StackFreezeFrozenTag StackTagName = "pulumi:freeze:frozen"
+	// StackFreezeExpiresTag is a tag that holds the time, in RFC 3339 format, at which the stack's freeze ends by
+	// itself, if it does.
+	StackFreezeExpiresTag StackTagName = "pulumi:freeze:expires"
 )
 
 // Stack describes a Stack running on a Pulumi Cloud.
@@ -18,8 +18,17 @@ package apitype
 // package. The duplication is intentional to insulate the Pulumi service from various kinds of
 // breaking changes.
 //
-// The types aren't versioned in the same manner as Resource, Deployment, and Checkpoint (see
-// apitype/migrate). So care must be taken if these are ever returned from the service to the CLI.
+// Each EngineEvent records the version of the schema it was written with. Events written with a
+// newer schema may be down-converted for older consumers using the functions in apitype/migrate.
+
+const (
+	// EngineEventSchemaVersionCurrent is the current version of the `EngineEvent` schema.
+	//
+	// Version 1 is the original schema. Version 2 adds the progressEvent, lifecycleEvent,
+	// confirmationRequiredEvent, and planCacheEvent kinds; the details of failed resource
+	// operations; the output changes of summary events; and the annotations of resource states.
+	EngineEventSchemaVersionCurrent = 2
+)
 
 // CancelEvent is emitted when the user initiates a cancellation of the update in progress, or
 // the update successfully completes.
@@ -74,6 +83,57 @@ type SummaryEvent struct {
 	// ResourceChanges contains the count for resource change by type. The keys are deploy.StepOp,
 	// which is not exported in this package.
 	ResourceChanges map[string]int `json:"resourceChanges"`
+	// OutputChanges describes how the update changed the stack's outputs.
+	OutputChanges *OutputChanges `json:"outputChanges,omitempty"`
+	// Deprecations counts the uses of resource types and input properties that their providers have deprecated.
+	Deprecations []DeprecatedUsage `json:"deprecations,omitempty"`
+	// Estimates are the estimates of the impact of a preview's plan, e.g. its cost.
+	Estimates []PlanEstimate `json:"estimates,omitempty"`
+}
+
+// PlanEstimate is an estimate of the impact of applying a preview's plan.
+type PlanEstimate struct {
+	// Estimator is the name of the estimator that made the estimate.
+	Estimator string `json:"estimator"`
+	// MonthlyCostDelta is the estimated change to the stack's monthly cost, in Currency, if it was estimated.
+	MonthlyCostDelta *float64 `json:"monthlyCostDelta,omitempty"`
+	// Currency is the ISO 4217 code of the currency in which costs are estimated.
+	Currency string `json:"currency,omitempty"`
+	// BlastRadius scores how much would be affected if the plan went wrong, if it was estimated. Its scale is up to
+	// the estimator.
+	BlastRadius *float64 `json:"blastRadius,omitempty"`
+	// Notes are human-readable remarks about the estimate.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// DeprecatedUsage counts the resources that use a resource type or input property that their provider has deprecated.
+type DeprecatedUsage struct {
+	// Type is the deprecated resource type, or the type whose input property is deprecated.
+	Type string `json:"type"`
+	// Property is the deprecated input property, or empty if the resource type is deprecated.
+	Property string `json:"property,omitempty"`
+	// Message says why the type or property is deprecated, if the provider says.
+	Message string `json:"message,omitempty"`
+	// Replacement is the type or property to use instead, if there is one.
+	Replacement string `json:"replacement,omitempty"`
+	// Count is the number of resources that use the type or property.
+	Count int `json:"count"`
+}
+
+// OutputChanges describes how an update changed a stack's outputs. Secret values are masked.
+type OutputChanges struct {
+	// Added maps the names of the outputs added by the update to their values.
+	Added map[string]interface{} `json:"added,omitempty"`
+	// Changed maps the names of the outputs changed by the update to their old and new values.
+	Changed map[string]OutputChange `json:"changed,omitempty"`
+	// Removed maps the names of the outputs removed by the update to their last values.
+	Removed map[string]interface{} `json:"removed,omitempty"`
+}
+
+// OutputChange describes the old and new values of a changed stack output.
+type OutputChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
 }
 
 // StepEventMetadata describes a "step" within the Pulumi engine, which is any concrete action
@@ -125,6 +185,8 @@ type StepEventStateMetadata struct {
 	Provider string `json:"provider"`
 	// InitErrors is the set of errors encountered in the process of initializing resource.
 	InitErrors []string `json:"initErrors,omitempty"`
+	// Annotations are the annotations attached to the resource by external tools.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // ResourcePreEvent is emitted before a resource is modified.
@@ -145,6 +207,186 @@ type ResOpFailedEvent struct {
 	Metadata StepEventMetadata `json:"metadata"`
 	Status   int               `json:"status"`
 	Steps    int               `json:"steps"`
+	// Details contains structured details about the failure, if the resource's provider supplied any.
+	Details *FailureDetails `json:"details,omitempty"`
+	// Kind classifies the failure: "provider", "precondition", "timeout", "canceled", or "bail".
+	Kind string `json:"kind,omitempty"`
+	// Code is the gRPC status code of the failure, e.g. "Unavailable"; for provider failures, the code that the
+	// provider returned.
+	Code string `json:"code,omitempty"`
+	// Retryable is true if the failure is one that the update's retry policy retries: a provider error with a
+	// retryable code that left the resource in a known state. Timeouts and cancellations are never retryable.
+	Retryable bool `json:"retryable,omitempty"`
+}
+
+// FailureDetails are structured details about a failed resource operation, as described by the resource's provider.
+type FailureDetails struct {
+	// Code is a provider-specific code that identifies the kind of failure.
+	Code string `json:"code,omitempty"`
+	// Retryable is true if the operation may succeed if it is attempted again.
+	Retryable bool `json:"retryable,omitempty"`
+	// Remediation lists suggested steps that may resolve the failure.
+	Remediation []string `json:"remediation,omitempty"`
+	// DocsURL is a link to documentation about the failure.
+	DocsURL string `json:"docsUrl,omitempty"`
+}
+
+// StepProgressEvent is emitted when a resource provider reports the progress of a long-running operation.
+type StepProgressEvent struct {
+	Metadata StepEventMetadata `json:"metadata"`
+	// Percent is the percentage of the operation that has completed, or negative if unknown.
+	Percent int `json:"percent"`
+	// Message is a short description of what the provider is currently doing.
+	Message string `json:"message,omitempty"`
+}
+
+// PluginLifecycleEvent is emitted when a resource provider plugin exits while applying a step and is restarted so that
+// the step can be retried.
+type PluginLifecycleEvent struct {
+	Metadata StepEventMetadata `json:"metadata"`
+	// Plugin is the name of the plugin that was restarted.
+	Plugin string `json:"plugin"`
+	// Message describes the error that the interrupted step failed with.
+	Message string `json:"message,omitempty"`
+}
+
+// ConfirmationRequiredEvent is emitted when an update is waiting for the caller to confirm a destructive step.
+type ConfirmationRequiredEvent struct {
+	Metadata StepEventMetadata `json:"metadata"`
+}
+
+// PlanCacheEvent is emitted at the start of a preview that may be served from a cached plan. If the preview is served
+// from the cache, the events that follow replay the cached plan.
+type PlanCacheEvent struct {
+	// Key is the key under which the plan is cached.
+	Key string `json:"key"`
+	// Hit is true if the preview was served from the cache.
+	Hit bool `json:"hit"`
+	// Forced is true if the cache was bypassed because a fresh plan was requested.
+	Forced bool `json:"forced,omitempty"`
+	// Created is a Unix timestamp (seconds) of when the cached plan was computed, or zero on a miss.
+	Created int64 `json:"created,omitempty"`
+}
+
+// StepDependency describes why a step is scheduled after one of its predecessors.
+type StepDependency struct {
+	// URN is the URN of the predecessor.
+	URN string `json:"urn"`
+	// Reason is why the step waits on the predecessor: "parent", "provider", "dependency" (an explicit dependency),
+	// or "property" (a reference to the predecessor's outputs from one of the resource's inputs).
+	Reason string `json:"reason"`
+	// Properties lists the inputs that reference the predecessor, for "property" dependencies.
+	Properties []string `json:"properties,omitempty"`
+}
+
+// StepDependenciesEvent is emitted during previews for each step that has predecessors, and describes why the step is
+// scheduled after each of them.
+type StepDependenciesEvent struct {
+	Metadata     StepEventMetadata `json:"metadata"`
+	Dependencies []StepDependency  `json:"dependencies"`
+}
+
+// CancellationEvent is emitted once a cancelled operation stops, and describes what state it left the stack in.
+type CancellationEvent struct {
+	// Phase is what the operation was doing when it was cancelled: "planning" (no step was executing), "executing"
+	// (one or more steps were executing), or "persisting" (the result of a step was being written to the checkpoint).
+	Phase string `json:"phase"`
+	// InFlight lists the URNs of the resources whose steps were executing when the operation was cancelled.
+	InFlight []string `json:"inFlight,omitempty"`
+	// Completed lists the URNs of the in-flight resources whose steps went on to succeed.
+	Completed []string `json:"completed,omitempty"`
+	// Terminated is true if the operation was terminated without waiting for its in-flight steps.
+	Terminated bool `json:"terminated,omitempty"`
+}
+
+// RefreshProgressEvent is emitted as the reads of a refresh complete, and describes how many of them have completed.
+type RefreshProgressEvent struct {
+	// Completed is the number of resources that have been read, successfully or not.
+	Completed int `json:"completed"`
+	// Failed is the number of resources whose reads failed.
+	Failed int `json:"failed,omitempty"`
+	// Total is the number of resources to read.
+	Total int `json:"total"`
+}
+
+// CheckResult is the result of a check that ran after an update was applied.
+type CheckResult struct {
+	// Name is the name of the check.
+	Name string `json:"name"`
+	// Passed is true if the check passed.
+	Passed bool `json:"passed"`
+	// Message explains why the check failed, if it did.
+	Message string `json:"message,omitempty"`
+	// DurationSeconds is the number of seconds the check ran.
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// CheckResultsEvent is emitted once the checks that run after an update is applied have completed. If any of them
+// failed, the update is recorded as having succeeded with failing checks.
+type CheckResultsEvent struct {
+	// Checks holds the results of the checks, in the order in which they ran.
+	Checks []CheckResult `json:"checks"`
+}
+
+// DeletionOrderEvent is emitted before the first of the deletes of an update begins, if the order of the deletes was
+// requested.
+type DeletionOrderEvent struct {
+	// Batches holds the URNs of the resources to delete, in batches that are deleted one after another. The resources
+	// of each batch are deleted in parallel.
+	Batches [][]string `json:"batches"`
+}
+
+// PausedEvent is emitted when an update pauses or resumes at the request of the operator.
+type PausedEvent struct {
+	// Paused is true if the update has paused, or false if it has resumed.
+	Paused bool `json:"paused"`
+}
+
+// InitErrorsEvent is emitted when the initialization errors of a resource are re-attempted or cleared.
+type InitErrorsEvent struct {
+	URN string `json:"urn"`
+	// Action is what was done about the errors: "resolved", "remain", "cleared", or "unresolved".
+	Action string `json:"action"`
+	// Errors holds the resource's initialization errors afterwards.
+	Errors  []string `json:"errors,omitempty"`
+	Message string   `json:"message"`
+}
+
+// AggregateProgressEvent is emitted in place of the events of steps that leave their resources unchanged by operations
+// that only report the steps that change something, and describes how many steps have completed.
+type AggregateProgressEvent struct {
+	// Processed is the number of steps that have completed, successfully or not.
+	Processed int `json:"processed"`
+	// Total is the number of resources in the stack before the operation, which estimates the number of steps.
+	Total int `json:"total"`
+	// Ops is the number of steps of each kind that have completed successfully. The keys are deploy.StepOp values.
+	Ops map[string]int `json:"ops,omitempty"`
+	// Done is true once all of the steps of the operation have completed.
+	Done bool `json:"done,omitempty"`
+}
+
+// PendingDeleteEvent is emitted when a garbage collection pass handles a resource that is pending deletion.
+type PendingDeleteEvent struct {
+	URN string `json:"urn"`
+	ID  string `json:"id,omitempty"`
+	// Action is what was done about the resource: "deleted", "retry", or "manual".
+	Action string `json:"action"`
+	// Attempts is the number of times the pass attempted to delete the resource.
+	Attempts int    `json:"attempts,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ProviderChangeEvent is emitted when a step that updates or replaces a provider because its configuration changed is
+// planned, before the step is executed.
+type ProviderChangeEvent struct {
+	URN string `json:"urn"`
+	// Op is "update" if the provider is updated in place, or "replace" if it is replaced.
+	Op string `json:"op"`
+	// Keys holds the configuration keys that change, and ReplaceKeys those whose changes force a replacement.
+	Keys        []string `json:"keys,omitempty"`
+	ReplaceKeys []string `json:"replaceKeys,omitempty"`
+	// Dependents holds the URNs of the resources that the provider manages, which its replacement may replace.
+	Dependents []string `json:"dependents,omitempty"`
 }
 
 // EngineEvent describes a Pulumi engine event, such as a change to a resource or diagnostic
@@ -163,13 +405,30 @@ type EngineEvent struct {
 	// Timestamp is a Unix timestamp (seconds) of when the event was emitted.
 	Timestamp int `json:"timestamp"`
 
-	CancelEvent      *CancelEvent       `json:"cancelEvent,omitempty"`
-	StdoutEvent      *StdoutEngineEvent `json:"stdoutEvent,omitempty"`
-	DiagnosticEvent  *DiagnosticEvent   `json:"diagnosticEvent,omitempty"`
-	PreludeEvent     *PreludeEvent      `json:"preludeEvent,omitempty"`
-	SummaryEvent     *SummaryEvent      `json:"summaryEvent,omitempty"`
-	ResourcePreEvent *ResourcePreEvent  `json:"resourcePreEvent,omitempty"`
-	ResOutputsEvent  *ResOutputsEvent   `json:"resOutputsEvent,omitempty"`
-	ResOpFailedEvent *ResOpFailedEvent  `json:"resOpFailedEvent,omitempty"`
-	PolicyEvent      *PolicyEvent       `json:"policyEvent,omitempty"`
+	// Version indicates the schema of the event. Events without a version use version 1.
+	Version int `json:"version,omitempty"`
+
+	CancelEvent               *CancelEvent               `json:"cancelEvent,omitempty"`
+	StdoutEvent               *StdoutEngineEvent         `json:"stdoutEvent,omitempty"`
+	DiagnosticEvent           *DiagnosticEvent           `json:"diagnosticEvent,omitempty"`
+	PreludeEvent              *PreludeEvent              `json:"preludeEvent,omitempty"`
+	SummaryEvent              *SummaryEvent              `json:"summaryEvent,omitempty"`
+	ResourcePreEvent          *ResourcePreEvent          `json:"resourcePreEvent,omitempty"`
+	ResOutputsEvent           *ResOutputsEvent           `json:"resOutputsEvent,omitempty"`
+	ResOpFailedEvent          *ResOpFailedEvent          `json:"resOpFailedEvent,omitempty"`
+	PolicyEvent               *PolicyEvent               `json:"policyEvent,omitempty"`
+	ProgressEvent             *StepProgressEvent         `json:"progressEvent,omitempty"`
+	LifecycleEvent            *PluginLifecycleEvent      `json:"lifecycleEvent,omitempty"`
+	ConfirmationRequiredEvent *ConfirmationRequiredEvent `json:"confirmationRequiredEvent,omitempty"`
+	PlanCacheEvent            *PlanCacheEvent            `json:"planCacheEvent,omitempty"`
+	StepDependenciesEvent     *StepDependenciesEvent     `json:"stepDependenciesEvent,omitempty"`
+	CancellationEvent         *CancellationEvent         `json:"cancellationEvent,omitempty"`
+	RefreshProgressEvent      *RefreshProgressEvent      `json:"refreshProgressEvent,omitempty"`
+	CheckResultsEvent         *CheckResultsEvent         `json:"checkResultsEvent,omitempty"`
+	DeletionOrderEvent        *DeletionOrderEvent        `json:"deletionOrderEvent,omitempty"`
+	PausedEvent               *PausedEvent               `json:"pausedEvent,omitempty"`
+	InitErrorsEvent           *InitErrorsEvent           `json:"initErrorsEvent,omitempty"`
+	ProviderChangeEvent       *ProviderChangeEvent       `json:"providerChangeEvent,omitempty"`
+	AggregateProgressEvent    *AggregateProgressEvent    `json:"aggregateProgressEvent,omitempty"`
+	PendingDeleteEvent        *PendingDeleteEvent        `json:"pendingDeleteEvent,omitempty"`
 }
@@ -48,6 +48,8 @@ const (
 	InProgressResult UpdateResult = "in-progress"
 	// SucceededResult is for updates that completed successfully.
 	SucceededResult UpdateResult = "succeeded"
+	// SucceededWithFailingChecksResult is for updates that completed successfully, but whose checks then failed.
+	SucceededWithFailingChecksResult UpdateResult = "succeeded-with-failing-checks"
 	// FailedResult is for updates that have failed.
 	FailedResult UpdateResult = "failed"
 )
@@ -15,12 +15,15 @@
 // Package migrate is responsible for converting to and from the various API
 // type versions that are in use in Pulumi. This package can migrate "up" for
 // every versioned API that needs to be migrated between versions. Today, there
-// are three versionable entities that can be migrated
+// are four versionable entities that can be migrated
 // with this package:
 //   * Checkpoint, the on-disk format for Fire-and-Forget stack state,
 //   * Deployment, the wire format for service-managed stacks,
 //   * Resource, the wire format for resources saved in deployments,
+//   * EngineEvent, the wire format for the events emitted by the engine.
 //
 // The migrations in this package are designed to preserve semantics between
 // versions. It is always safe to migrate an entity up from one version to another.
+// Engine events are instead migrated "down", so that consumers written against an
+// older version of the schema continue to work as new kinds of events are added.
 package migrate
@@ -0,0 +1,110 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+)
+
+// DownToEngineEvent converts an engine event to the given version of the EngineEvent schema so that it may be consumed
+// by clients that do not understand newer versions. The second result is false if the event has no equivalent in the
+// requested version, in which case it should be dropped.
+func DownToEngineEvent(e apitype.EngineEvent, version int) (apitype.EngineEvent, bool, error) {
+	current := e.Version
+	if current == 0 {
+		current = 1
+	}
+
+	switch {
+	case version < 1 || version > apitype.EngineEventSchemaVersionCurrent:
+		return apitype.EngineEvent{}, false, errors.Errorf("unsupported engine event version %d", version)
+	case current > apitype.EngineEventSchemaVersionCurrent:
+		return apitype.EngineEvent{}, false, errors.Errorf("engine event version %d is newer than this client "+
+			"supports (%d)", current, apitype.EngineEventSchemaVersionCurrent)
+	case version >= current:
+		return e, true, nil
+	}
+
+	// Only two versions exist today, so the only down-conversion is from V2 to V1.
+	return DownToEngineEventV1(e)
+}
+
+// DownToEngineEventV1 converts an engine event from version 2 of the EngineEvent schema to version 1. Event kinds that
+// were introduced in version 2 have no equivalent, so the second result is false for them; fields that were introduced
+// in version 2 are dropped.
+func DownToEngineEventV1(v2 apitype.EngineEvent) (apitype.EngineEvent, bool, error) {
+	if v2.ProgressEvent != nil || v2.LifecycleEvent != nil || v2.ConfirmationRequiredEvent != nil ||
+		v2.PlanCacheEvent != nil || v2.StepDependenciesEvent != nil || v2.CancellationEvent != nil ||
+		v2.RefreshProgressEvent != nil || v2.CheckResultsEvent != nil || v2.DeletionOrderEvent != nil ||
+		v2.PausedEvent != nil || v2.InitErrorsEvent != nil || v2.ProviderChangeEvent != nil ||
+		v2.AggregateProgressEvent != nil || v2.PendingDeleteEvent != nil {
+		return apitype.EngineEvent{}, false, nil
+	}
+
+	v1 := apitype.EngineEvent{
+		Sequence:        v2.Sequence,
+		Timestamp:       v2.Timestamp,
+		CancelEvent:     v2.CancelEvent,
+		StdoutEvent:     v2.StdoutEvent,
+		DiagnosticEvent: v2.DiagnosticEvent,
+		PreludeEvent:    v2.PreludeEvent,
+		PolicyEvent:     v2.PolicyEvent,
+	}
+	if e := v2.SummaryEvent; e != nil {
+		v1.SummaryEvent = &apitype.SummaryEvent{
+			MaybeCorrupt:    e.MaybeCorrupt,
+			DurationSeconds: e.DurationSeconds,
+			ResourceChanges: e.ResourceChanges,
+		}
+	}
+	if e := v2.ResourcePreEvent; e != nil {
+		v1.ResourcePreEvent = &apitype.ResourcePreEvent{
+			Metadata: downToStepEventMetadataV1(e.Metadata),
+			Planning: e.Planning,
+		}
+	}
+	if e := v2.ResOutputsEvent; e != nil {
+		v1.ResOutputsEvent = &apitype.ResOutputsEvent{
+			Metadata: downToStepEventMetadataV1(e.Metadata),
+			Planning: e.Planning,
+		}
+	}
+	if e := v2.ResOpFailedEvent; e != nil {
+		v1.ResOpFailedEvent = &apitype.ResOpFailedEvent{
+			Metadata: downToStepEventMetadataV1(e.Metadata),
+			Status:   e.Status,
+			Steps:    e.Steps,
+		}
+	}
+
+	return v1, true, nil
+}
+
+func downToStepEventMetadataV1(md apitype.StepEventMetadata) apitype.StepEventMetadata {
+	md.Old = downToStepEventStateMetadataV1(md.Old)
+	md.New = downToStepEventStateMetadataV1(md.New)
+	return md
+}
+
+func downToStepEventStateMetadataV1(md *apitype.StepEventStateMetadata) *apitype.StepEventStateMetadata {
+	if md == nil {
+		return nil
+	}
+	v1 := *md
+	v1.Annotations = nil
+	return &v1
+}
@@ -0,0 +1,70 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+)
+
+func TestDownToEngineEventV1(t *testing.T) {
+	state := &apitype.StepEventStateMetadata{
+		URN:         "urn:pulumi:stack::proj::pkg:mod:Typ::name",
+		Annotations: map[string]string{"owner": "team"},
+	}
+	v2 := apitype.EngineEvent{
+		Sequence: 7,
+		Version:  2,
+		ResOpFailedEvent: &apitype.ResOpFailedEvent{
+			Metadata: apitype.StepEventMetadata{Op: "create", Old: state, New: state},
+			Status:   1,
+			Steps:    3,
+			Details:  &apitype.FailureDetails{Code: "Throttled"},
+		},
+	}
+
+	v1, ok, err := DownToEngineEvent(v2, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0, v1.Version)
+	assert.Equal(t, 7, v1.Sequence)
+	assert.Nil(t, v1.ResOpFailedEvent.Details)
+	assert.Nil(t, v1.ResOpFailedEvent.Metadata.Old.Annotations)
+	assert.Equal(t, state.URN, v1.ResOpFailedEvent.Metadata.New.URN)
+
+	// The original event must not be modified.
+	assert.NotNil(t, v2.ResOpFailedEvent.Details)
+	assert.Equal(t, "team", state.Annotations["owner"])
+
+	// Kinds that were added in V2 have no V1 equivalent.
+	_, ok, err = DownToEngineEvent(apitype.EngineEvent{
+		Version:       2,
+		ProgressEvent: &apitype.StepProgressEvent{Percent: 50},
+	}, 1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Events that are already at or below the requested version are returned as-is.
+	same, ok, err := DownToEngineEvent(v2, 2)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, v2, same)
+
+	_, _, err = DownToEngineEvent(v2, apitype.EngineEventSchemaVersionCurrent+1)
+	assert.Error(t, err)
+}
@@ -95,6 +95,10 @@ type StartUpdateResponse struct {
 
 	// RequiredPolicies is a list of required Policy Packs to run during the update.
 	RequiredPolicies []RequiredPolicy `json:"requiredPolicies,omitempty"`
+
+	// RecommendedCLIVersion is the oldest version of the CLI that the service recommends for the update, if any. Older
+	// versions are warned that they should upgrade.
+	RecommendedCLIVersion string `json:"recommendedCLIVersion,omitempty"`
 }
 
 // UpdateEventKind is an enum for the type of update events.
@@ -184,6 +188,27 @@ type RenewUpdateLeaseResponse struct {
 	Token string `json:"token"`
 }
 
+// UpdateLock describes the update that currently holds a stack's update lock. It is returned by the stack update
+// lock endpoint of the service API.
+type UpdateLock struct {
+	// UpdateID is the identifier of the update that holds the lock.
+	UpdateID string `json:"updateID"`
+	// Kind is the kind of the update that holds the lock.
+	Kind UpdateKind `json:"kind"`
+	// RequestedBy is the name of the user that started the update that holds the lock.
+	RequestedBy string `json:"requestedBy"`
+	// LastHeartbeat is the time (in seconds since the Unix epoch) at which the holder of the lock last renewed its
+	// lease.
+	LastHeartbeat int64 `json:"lastHeartbeat"`
+}
+
+// TakeoverUpdateLockRequest defines the body of a request to take over a stale stack update lock.
+type TakeoverUpdateLockRequest struct {
+	// UpdateID is the identifier of the update whose stale lock should be released. The request fails if the lock
+	// is no longer held by this update.
+	UpdateID string `json:"updateID"`
+}
+
 const (
 	// UpdateStatusSucceeded indicates that an update completed successfully.
 	UpdateStatusSucceeded UpdateStatus = "succeeded"
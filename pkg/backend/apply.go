@@ -30,6 +30,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/diag/colors"
 	"github.com/pulumi/pulumi/pkg/engine"
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/result"
 )
@@ -116,11 +117,17 @@ func PreviewThenPrompt(ctx context.Context, kind apitype.UpdateKind, stack Stack
 		return changes, res
 	}
 
-	// If there are no changes, or we're auto-approving or just previewing, we can skip the confirmation prompt.
-	if op.Opts.AutoApprove || kind == apitype.PreviewUpdate {
+	// If there are no changes, or we're auto-approving or just previewing, we can skip the confirmation prompt. Changes
+	// that delete or replace resources may need approval regardless.
+	if kind == apitype.PreviewUpdate || (op.Opts.AutoApprove && !requiresApproval(changes, op.Opts)) {
 		close(eventsChannel)
 		return changes, nil
 	}
+	if op.Opts.AutoApprove && !op.Opts.Display.IsInteractive {
+		close(eventsChannel)
+		return changes, result.Errorf("this %s would delete or replace %d resources, which requires approval; "+
+			"run it interactively to approve it", kind, destructiveChanges(changes, op.Opts))
+	}
 
 	// Otherwise, ensure the user wants to proceed.
 	res = confirmBeforeUpdating(kind, stack, events, op.Opts)
@@ -128,6 +135,22 @@ func PreviewThenPrompt(ctx context.Context, kind apitype.UpdateKind, stack Stack
 	return changes, res
 }
 
+// destructiveChanges returns the number of resources that the given changes delete or replace. Prunes are counted
+// only if the update deletes the resources that it prunes.
+func destructiveChanges(changes engine.ResourceChanges, opts UpdateOptions) int {
+	n := changes[deploy.OpDelete] + changes[deploy.OpReplace]
+	if opts.Engine.Prune == deploy.PruneDelete {
+		n += changes[deploy.OpPrune]
+	}
+	return n
+}
+
+// requiresApproval returns true if the given changes must be approved by the user even if they would otherwise be
+// approved automatically.
+func requiresApproval(changes engine.ResourceChanges, opts UpdateOptions) bool {
+	return opts.RequireApprovalForDestructive && destructiveChanges(changes, opts) > 0
+}
+
 // confirmBeforeUpdating asks the user whether to proceed. A nil error means yes.
 func confirmBeforeUpdating(kind apitype.UpdateKind, stack Stack,
 	events []engine.Event, opts UpdateOptions) result.Result {
@@ -192,6 +215,11 @@ func PreviewThenPromptThenExecute(ctx context.Context, kind apitype.UpdateKind,
 	op UpdateOperation, apply Applier) (engine.ResourceChanges, result.Result) {
 	// Preview the operation to the user and ask them if they want to proceed.
 
+	// Skipping the preview would also skip the check for changes that need approval, so it is not allowed when
+	// approval is required.
+	if op.Opts.RequireApprovalForDestructive {
+		op.Opts.SkipPreview = false
+	}
 	if !op.Opts.SkipPreview {
 		changes, res := PreviewThenPrompt(ctx, kind, stack, op, apply)
 		if res != nil || kind == apitype.PreviewUpdate {
@@ -0,0 +1,26 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// ArtifactBackend is implemented by backends that store the contents of the artifacts that providers attach to the
+// resources of a stack. Backends that do not implement it drop such artifacts with a warning.
+type ArtifactBackend interface {
+	// ArtifactStore returns the store that holds the contents of the given stack's artifacts.
+	ArtifactStore(stackRef StackReference) deploy.ArtifactStore
+}
@@ -0,0 +1,45 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// AuditLogEnvVar names a file to which the engine appends an entry for every step it applies. See engine.AuditLog.
+const AuditLogEnvVar = "PULUMI_AUDIT_LOG"
+
+// OpenAuditLog opens the audit log named by PULUMI_AUDIT_LOG, if any, along with the principal to whom its entries
+// are attributed, which is the backend's current user. The log is nil if none is configured; otherwise, the caller
+// must close it once the update completes.
+func OpenAuditLog(b Backend) (*engine.AuditLog, string, error) {
+	path := os.Getenv(AuditLogEnvVar)
+	if path == "" {
+		return nil, "", nil
+	}
+
+	principal, err := b.CurrentUser()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "identifying the principal for the audit log")
+	}
+	log, err := engine.OpenAuditLog(path)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "opening audit log %s", path)
+	}
+	return log, principal, nil
+}
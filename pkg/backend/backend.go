@@ -81,6 +81,8 @@ type Backend interface {
 	Name() string
 	// URL returns a URL at which information about this backend may be seen.
 	URL() string
+	// Capabilities returns the optional features that this backend supports.
+	Capabilities() Capabilities
 
 	// ParseStackReference takes a string representation and parses it to a reference which may be used for other
 	// methods in this backend.
@@ -150,6 +152,10 @@ type UpdateOperation struct {
 type StackConfiguration struct {
 	Config    config.Map
 	Decrypter config.Decrypter
+
+	// Layers holds the configuration that the stack shares with other stacks, ordered from lowest to highest
+	// precedence. Config takes precedence over every layer.
+	Layers []config.Map
 }
 
 // UpdateOptions is the full set of update options, including backend and engine options.
@@ -163,6 +169,16 @@ type UpdateOptions struct {
 	AutoApprove bool
 	// SkipPreview, when true, causes the preview step to be skipped.
 	SkipPreview bool
+	// RequireApprovalForDestructive, when true, requires the user to approve any update that deletes or replaces
+	// resources, even if AutoApprove is set. An update that requires approval is always previewed first, and fails if
+	// the user cannot be prompted.
+	RequireApprovalForDestructive bool
+	// TakeoverStaleLock, when true, allows the update to take over a stack lock whose holder has stopped sending
+	// heartbeats.
+	TakeoverStaleLock bool
+	// BreakLock, when true, allows the update to proceed even if another update that appears to be live holds the
+	// stack's lock. This may corrupt the stack's state if the other update is in fact still running.
+	BreakLock bool
 }
 
 // CancellationScope provides a scoped source of cancellation and termination requests.
@@ -245,3 +261,33 @@ func (c *backendClient) GetStackResourceOutputs(
 	}
 	return pm, nil
 }
+
+// GetStackHistory returns the updates that have been made to the stack with the given name, most recent first.
+func (c *backendClient) GetStackHistory(ctx context.Context, name string) ([]apitype.UpdateInfo, error) {
+	ref, err := c.backend.ParseStackReference(name)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := c.backend.GetHistory(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]apitype.UpdateInfo, len(updates))
+	for i, update := range updates {
+		changes := make(map[apitype.OpType]int)
+		for op, count := range update.ResourceChanges {
+			changes[apitype.OpType(op)] = count
+		}
+		history[i] = apitype.UpdateInfo{
+			Kind:            update.Kind,
+			StartTime:       update.StartTime,
+			Message:         update.Message,
+			Environment:     update.Environment,
+			Result:          apitype.UpdateResult(update.Result),
+			EndTime:         update.EndTime,
+			ResourceChanges: changes,
+		}
+	}
+	return history, nil
+}
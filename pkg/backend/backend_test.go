@@ -101,6 +101,33 @@ func TestGetStackResourceOutputs(t *testing.T) {
 	assert.False(t, exists)
 }
 
+func TestStackTagsRequireCapability(t *testing.T) {
+	var capabilities Capabilities
+	tags := map[apitype.StackTagName]string{"owner": "alice"}
+	be := &mockBackend{
+		NameF:         func() string { return "mock" },
+		CapabilitiesF: func() Capabilities { return capabilities },
+		GetStackTagsF: func(context.Context, StackReference) (map[apitype.StackTagName]string, error) {
+			return tags, nil
+		},
+	}
+	s := &mockStack{
+		RefF:     func() StackReference { return nil },
+		BackendF: func() Backend { return be },
+	}
+
+	// A backend that does not store tags is not asked for them.
+	_, err := GetStackTags(context.Background(), s)
+	assert.Equal(t, UnsupportedError{Backend: "mock", Feature: "stack tags"}, err)
+	assert.EqualError(t, err, "the mock backend does not support stack tags")
+	assert.Error(t, UpdateStackTags(context.Background(), s, tags))
+
+	capabilities.Tags = true
+	actual, err := GetStackTags(context.Background(), s)
+	assert.NoError(t, err)
+	assert.Equal(t, tags, actual)
+}
+
 //
 // Helpers.
 //
@@ -128,6 +155,7 @@ func liveState(typ, name string, outs resource.PropertyMap) *resource.State {
 type mockBackend struct {
 	NameF                   func() string
 	URLF                    func() string
+	CapabilitiesF           func() Capabilities
 	ParseStackReferenceF    func(s string) (StackReference, error)
 	GetStackF               func(context.Context, StackReference) (Stack, error)
 	CreateStackF            func(context.Context, StackReference, interface{}) (Stack, error)
@@ -172,6 +200,13 @@ func (be *mockBackend) URL() string {
 	panic("not implemented")
 }
 
+func (be *mockBackend) Capabilities() Capabilities {
+	if be.CapabilitiesF != nil {
+		return be.CapabilitiesF()
+	}
+	panic("not implemented")
+}
+
 func (be *mockBackend) ParseStackReference(s string) (StackReference, error) {
 	if be.ParseStackReferenceF != nil {
 		return be.ParseStackReferenceF(s)
@@ -0,0 +1,50 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"fmt"
+)
+
+// Capabilities describes the optional features that a backend supports. Callers query them before starting an
+// operation that needs a feature, so that the operation can be skipped with an informative message rather than fail
+// partway through.
+type Capabilities struct {
+	History        bool // the backend records the history of each stack's updates.
+	Locking        bool // the backend locks a stack's state while an update writes it.
+	EventStreaming bool // the backend streams the events of each update to a service where they may be viewed later.
+	PolicyStorage  bool // the backend stores policy packs and enforces them on updates.
+	Tags           bool // the backend stores tags for each stack.
+	Query          bool // the backend can run query programs against a stack's state.
+}
+
+// UnsupportedError is returned when an operation needs a feature that a backend does not support.
+type UnsupportedError struct {
+	Backend string // the name of the backend.
+	Feature string // a description of the feature, e.g. "stack tags".
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("the %s backend does not support %s", e.Backend, e.Feature)
+}
+
+// RequireCapability returns an UnsupportedError for the given feature if the given backend lacks it, as reported by
+// the given function of its capabilities.
+func RequireCapability(b Backend, feature string, has func(Capabilities) bool) error {
+	if !has(b.Capabilities()) {
+		return UnsupportedError{Backend: b.Name(), Feature: feature}
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"math"
 	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize/english"
@@ -96,6 +97,8 @@ func RenderDiffEvent(action apitype.UpdateKind, event engine.Event,
 		// progress displays.
 	case engine.PreludeEvent:
 		return renderPreludeEvent(event.Payload.(engine.PreludeEventPayload), opts)
+	case engine.PlanCacheEvent:
+		return renderPlanCacheEvent(event.Payload.(engine.PlanCacheEventPayload), opts)
 	case engine.SummaryEvent:
 		return renderSummaryEvent(action, event.Payload.(engine.SummaryEventPayload), opts)
 	case engine.StdoutColorEvent:
@@ -114,6 +117,35 @@ func RenderDiffEvent(action apitype.UpdateKind, event engine.Event,
 		return renderDiffDiagEvent(event.Payload.(engine.DiagEventPayload), opts)
 	case engine.PolicyViolationEvent:
 		return renderDiffPolicyViolationEvent(event.Payload.(engine.PolicyViolationEventPayload), opts)
+	case engine.StepProgressEvent, engine.RefreshProgressEvent, engine.AggregateProgressEvent:
+		// Progress is transient, so the diff display, which only shows the result of each step, ignores it.
+		return ""
+	case engine.PluginLifecycleEvent:
+		return renderDiffDiagEvent(
+			pluginLifecycleDiagEventPayload(event.Payload.(engine.PluginLifecycleEventPayload)), opts)
+	case engine.ConfirmationRequiredEvent:
+		return renderDiffDiagEvent(
+			confirmationRequiredDiagEventPayload(event.Payload.(engine.ConfirmationRequiredEventPayload)), opts)
+	case engine.StepDependenciesEvent:
+		if !opts.ShowDependencies {
+			return ""
+		}
+		return renderDiffDiagEvent(
+			stepDependenciesDiagEventPayload(event.Payload.(engine.StepDependenciesEventPayload)), opts)
+	case engine.CancellationEvent:
+		return renderCancellationEvent(event.Payload.(engine.CancellationEventPayload), opts)
+	case engine.CheckResultsEvent:
+		return renderCheckResultsEvent(event.Payload.(engine.CheckResultsEventPayload), opts)
+	case engine.DeletionOrderEvent:
+		return renderDeletionOrderEvent(event.Payload.(engine.DeletionOrderEventPayload), opts)
+	case engine.PausedEvent:
+		return renderPausedEvent(event.Payload.(engine.PausedEventPayload), opts)
+	case engine.InitErrorsEvent:
+		return renderInitErrorsEvent(event.Payload.(engine.InitErrorsEventPayload), opts)
+	case engine.ProviderChangeEvent:
+		return renderProviderChangeEvent(event.Payload.(engine.ProviderChangeEventPayload), opts)
+	case engine.PendingDeleteEvent:
+		return renderPendingDeleteEvent(event.Payload.(engine.PendingDeleteEventPayload), opts)
 
 	default:
 		contract.Failf("unknown event type '%s'", event.Type)
@@ -193,6 +225,56 @@ func renderSummaryEvent(action apitype.UpdateKind, event engine.SummaryEventPayl
 		fprintfIgnoreError(out, "\n")
 	}
 
+	// For actual deploys, we print some additional summary information
+	if !event.IsPreview && event.OutputChanges.HasChanges() {
+		fprintIgnoreError(out, opts.Color.Colorize(
+			fmt.Sprintf("\n%sOutput changes:%s\n", colors.SpecHeadline, colors.Reset)))
+		for _, group := range []struct {
+			op      deploy.StepOp
+			changes []engine.OutputChange
+		}{
+			{deploy.OpCreate, event.OutputChanges.Added},
+			{deploy.OpUpdate, event.OutputChanges.Changed},
+			{deploy.OpDelete, event.OutputChanges.Removed},
+		} {
+			for _, c := range group.changes {
+				fprintIgnoreError(out, opts.Color.Colorize(
+					fmt.Sprintf("    %s%s%s\n", group.op.Prefix(), c.Key, colors.Reset)))
+			}
+		}
+	}
+
+	if len(event.Deprecations) > 0 {
+		fprintIgnoreError(out, opts.Color.Colorize(
+			fmt.Sprintf("\n%sDeprecations:%s\n", colors.SpecHeadline, colors.Reset)))
+		for _, u := range event.Deprecations {
+			what := string(u.Type)
+			if u.Property != "" {
+				what += "." + string(u.Property)
+			}
+			line := fmt.Sprintf("    %s%s%s used by %d %s", colors.SpecWarning, what, colors.Reset, u.Count,
+				english.PluralWord(u.Count, "resource", ""))
+			if u.Message != "" {
+				line += ": " + u.Message
+			}
+			if u.Replacement != "" {
+				line += fmt.Sprintf("; use %s instead", u.Replacement)
+			}
+			fprintIgnoreError(out, opts.Color.Colorize(line+"\n"))
+		}
+	}
+
+	if len(event.Estimates) > 0 {
+		fprintIgnoreError(out, opts.Color.Colorize(
+			fmt.Sprintf("\n%sEstimates:%s\n", colors.SpecHeadline, colors.Reset)))
+		for _, e := range event.Estimates {
+			fprintfIgnoreError(out, "    %s: %s\n", e.Estimator, formatPlanEstimate(e))
+			for _, note := range e.Notes {
+				fprintfIgnoreError(out, "        %s\n", note)
+			}
+		}
+	}
+
 	// For actual deploys, we print some additional summary information
 	if !event.IsPreview {
 		// Round up to the nearest second.  It's not useful to spit out time with 9 digits of
@@ -207,6 +289,26 @@ func renderSummaryEvent(action apitype.UpdateKind, event engine.SummaryEventPayl
 	return out.String()
 }
 
+// formatPlanEstimate formats the estimates that an estimator made of a plan's impact, e.g. "monthly cost +12.50 USD,
+// blast radius 3".
+func formatPlanEstimate(e engine.PlanEstimate) string {
+	var parts []string
+	if e.MonthlyCostDelta != nil {
+		cost := fmt.Sprintf("monthly cost %+.2f", *e.MonthlyCostDelta)
+		if e.Currency != "" {
+			cost += " " + e.Currency
+		}
+		parts = append(parts, cost)
+	}
+	if e.BlastRadius != nil {
+		parts = append(parts, fmt.Sprintf("blast radius %v", *e.BlastRadius))
+	}
+	if len(parts) == 0 {
+		return "no estimate"
+	}
+	return strings.Join(parts, ", ")
+}
+
 func renderPreludeEvent(event engine.PreludeEventPayload, opts Options) string {
 	// Only if we have been instructed to show configuration values will we print anything during the prelude.
 	if !opts.ShowConfig {
@@ -229,6 +331,168 @@ func renderPreludeEvent(event engine.PreludeEventPayload, opts Options) string {
 	return out.String()
 }
 
+func renderPlanCacheEvent(event engine.PlanCacheEventPayload, opts Options) string {
+	// Misses are uninteresting: the preview simply proceeds as it would without a cache.
+	if !event.Hit {
+		return ""
+	}
+
+	return opts.Color.Colorize(fmt.Sprintf(
+		"%sThe program, configuration, and state are unchanged; using the plan computed at %s.%s\n",
+		colors.SpecUnimportant, event.Created.Local().Format(time.RFC1123), colors.Reset))
+}
+
+func renderRefreshProgressEvent(event engine.RefreshProgressEventPayload, opts Options) string {
+	msg := fmt.Sprintf("Read %d of %d resources", event.Completed, event.Total)
+	if event.Failed > 0 {
+		msg += fmt.Sprintf(" (%s%d failed%s)", colors.SpecError, event.Failed, colors.Reset)
+	}
+	return opts.Color.Colorize(msg + "\n")
+}
+
+func renderAggregateProgressEvent(event engine.AggregateProgressEventPayload, opts Options) string {
+	msg := fmt.Sprintf("Processed %d resources", event.Processed)
+	if !event.Done && event.Total > 0 {
+		msg = fmt.Sprintf("Processed %d of about %d resources", event.Processed, event.Total)
+	}
+
+	var counts []string
+	for _, op := range deploy.StepOps {
+		switch count := event.Ops[op]; {
+		case count == 0:
+		case op == deploy.OpSame:
+			counts = append(counts, fmt.Sprintf("%d unchanged", count))
+		default:
+			counts = append(counts, fmt.Sprintf("%s%d %s%s", op.Color(), count, op, colors.Reset))
+		}
+	}
+	if len(counts) > 0 {
+		msg += " (" + strings.Join(counts, ", ") + ")"
+	}
+	return opts.Color.Colorize(msg + "\n")
+}
+
+func renderCheckResultsEvent(event engine.CheckResultsEventPayload, opts Options) string {
+	out := &bytes.Buffer{}
+	fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf("%sChecks:%s\n", colors.SpecHeadline, colors.Reset)))
+
+	failed := 0
+	for _, check := range event.Checks {
+		duration := check.Duration.Round(time.Millisecond)
+		if check.Passed {
+			fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf("    %spassed%s %s (%v)\n",
+				colors.SpecCreate, colors.Reset, check.Name, duration)))
+			continue
+		}
+		failed++
+		fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf("    %sfailed%s %s (%v): %s\n",
+			colors.SpecError, colors.Reset, check.Name, duration, check.Message)))
+	}
+	if failed > 0 {
+		fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf(
+			"%s%d of %d checks failed; the update succeeded with failing checks%s\n",
+			colors.SpecWarning, failed, len(event.Checks), colors.Reset)))
+	}
+	return out.String()
+}
+
+func renderDeletionOrderEvent(event engine.DeletionOrderEventPayload, opts Options) string {
+	out := &bytes.Buffer{}
+	fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf("%sDeletion order:%s\n", colors.SpecHeadline, colors.Reset)))
+	for i, batch := range event.Batches {
+		fprintIgnoreError(out, fmt.Sprintf("    %d. %s\n", i+1, describeURNs(batch)))
+	}
+	return out.String()
+}
+
+func renderPausedEvent(event engine.PausedEventPayload, opts Options) string {
+	if !event.Paused {
+		return opts.Color.Colorize(fmt.Sprintf("%sResumed.%s\n", colors.SpecInfo, colors.Reset))
+	}
+	return opts.Color.Colorize(fmt.Sprintf(
+		"%sPaused; no new steps will start until the update is resumed.%s\n", colors.SpecWarning, colors.Reset))
+}
+
+func renderInitErrorsEvent(event engine.InitErrorsEventPayload, opts Options) string {
+	color := colors.SpecInfo
+	switch event.Action {
+	case engine.InitErrorsRemain, engine.InitErrorsUnresolved:
+		color = colors.SpecWarning
+	}
+	out := &bytes.Buffer{}
+	fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf("%s%s: %s: %s%s\n",
+		color, event.Action, event.URN, event.Message, colors.Reset)))
+	for _, err := range event.Errors {
+		fprintIgnoreError(out, fmt.Sprintf("    - %s\n", err))
+	}
+	return out.String()
+}
+
+func renderPendingDeleteEvent(event engine.PendingDeleteEventPayload, opts Options) string {
+	color := colors.SpecInfo
+	switch event.Action {
+	case engine.PendingDeleteRetry:
+		color = colors.SpecWarning
+	case engine.PendingDeleteManual:
+		color = colors.SpecError
+	}
+	return opts.Color.Colorize(fmt.Sprintf("%s%s: %s: %s%s\n",
+		color, event.Action, event.URN, event.Message, colors.Reset))
+}
+
+func renderProviderChangeEvent(event engine.ProviderChangeEventPayload, opts Options) string {
+	var keys []string
+	for _, k := range event.Keys {
+		keys = append(keys, string(k))
+	}
+	changes := "changes"
+	if len(keys) > 0 {
+		changes = fmt.Sprintf("changes (%s)", joinWords(keys))
+	}
+
+	if event.Op != deploy.OpReplace {
+		return opts.Color.Colorize(fmt.Sprintf("%sThe configuration of %s %s; it will be updated in place.%s\n",
+			colors.SpecInfo, describeURN(event.URN), changes, colors.Reset))
+	}
+
+	out := &bytes.Buffer{}
+	fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf("%sThe configuration of %s %s; it will be replaced.%s\n",
+		colors.SpecWarning, describeURN(event.URN), changes, colors.Reset)))
+	if len(event.Dependents) > 0 {
+		fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf(
+			"%s    %d resources that it manages may be replaced too: %s%s\n",
+			colors.SpecWarning, len(event.Dependents), describeURNs(event.Dependents), colors.Reset)))
+	}
+	return out.String()
+}
+
+func renderCancellationEvent(event engine.CancellationEventPayload, opts Options) string {
+	out := &bytes.Buffer{}
+	fprintIgnoreError(out, opts.Color.Colorize(
+		fmt.Sprintf("%sCancelled while %s.%s\n", colors.SpecWarning, event.Phase, colors.Reset)))
+	if event.Terminated {
+		fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf(
+			"%sThe operation was terminated without waiting for its steps to finish.%s\n",
+			colors.SpecWarning, colors.Reset)))
+	}
+
+	// Steps that did not complete may have left their resources in an unknown state.
+	completed := make(map[resource.URN]bool)
+	for _, urn := range event.Completed {
+		completed[urn] = true
+	}
+	for _, urn := range event.InFlight {
+		if completed[urn] {
+			fprintIgnoreError(out, fmt.Sprintf("    %s: completed\n", urn))
+		} else {
+			fprintIgnoreError(out, opts.Color.Colorize(fmt.Sprintf(
+				"    %s: %sdid not complete; refresh the stack to learn its state%s\n",
+				urn, colors.SpecWarning, colors.Reset)))
+		}
+	}
+	return out.String()
+}
+
 func renderDiffResourceOperationFailedEvent(
 	payload engine.ResourceOperationFailedPayload, opts Options) string {
 
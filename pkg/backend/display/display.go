@@ -36,10 +36,23 @@ func ShowEvents(
 	if opts.JSONDisplay {
 		// TODO[pulumi/pulumi#2390]: enable JSON display for real deployments.
 		contract.Assertf(isPreview, "JSON display only available in preview mode")
+		if opts.TerraformPlanJSON {
+			ShowTerraformPlanEvents(events, done, opts)
+			return
+		}
+		if opts.HTMLDisplay {
+			ShowHTMLEvents(op, action, stack, proj, events, done, opts)
+			return
+		}
 		ShowJSONEvents(op, action, events, done, opts)
 		return
 	}
 
+	if opts.ShowExplanation && isPreview {
+		ShowExplainedEvents(op, action, stack, proj, events, done, opts)
+		return
+	}
+
 	switch opts.Type {
 	case DisplayDiff:
 		ShowDiffEvents(op, action, events, done, opts)
@@ -76,7 +89,7 @@ func shouldShow(step engine.StepEventMetadata, opts Options) bool {
 	// For certain operations, whether they are tracked is controlled by flags (to cut down on superfluous output).
 	if step.Op == deploy.OpSame {
 		// If the op is the same, it is possible that the resource's metadata changed.  In that case, still show it.
-		if step.Old.Protect != step.New.Protect {
+		if step.Old.Protect != step.New.Protect || step.Old.CustomTimeouts != step.New.CustomTimeouts {
 			return true
 		}
 		return opts.ShowSameResources
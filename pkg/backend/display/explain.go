@@ -0,0 +1,259 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// ShowExplainedEvents displays events using the display selected by the given options and, once the preview is
+// complete, prints a prose explanation of the plan: which changes are the root causes of the others, and which
+// replacements and updates follow from them.
+func ShowExplainedEvents(
+	op string, action apitype.UpdateKind, stack tokens.QName, proj tokens.PackageName,
+	events <-chan engine.Event, done chan<- bool, opts Options) {
+
+	// Hand the events off to the underlying display, noting each step as it goes by.
+	displayEvents, displayDone := make(chan engine.Event), make(chan bool)
+	displayOpts := opts
+	displayOpts.ShowExplanation = false
+	go ShowEvents(op, action, stack, proj, displayEvents, displayDone, displayOpts, true /*isPreview*/)
+
+	explainer := newPlanExplainer()
+	for event := range events {
+		if event.Type == engine.ResourcePreEvent {
+			explainer.record(event.Payload.(engine.ResourcePreEventPayload).Metadata)
+		}
+		displayEvents <- event
+		if event.Type == engine.CancelEvent {
+			break
+		}
+	}
+	<-displayDone
+
+	if paragraphs := explainer.explain(); len(paragraphs) > 0 {
+		out := &strings.Builder{}
+		fprintIgnoreError(out, opts.Color.Colorize(
+			fmt.Sprintf("%sExplanation:%s\n", colors.SpecHeadline, colors.Reset)))
+		for _, p := range paragraphs {
+			fprintfIgnoreError(out, "    %s\n", p)
+		}
+		fprintIgnoreError(out, "\n")
+		fprintIgnoreError(os.Stdout, out.String())
+	}
+	close(done)
+}
+
+// planExplainer accumulates the logical steps of a preview and explains them in prose.
+type planExplainer struct {
+	steps map[resource.URN]*explainedStep // the steps, keyed by URN.
+	order []*explainedStep                // the steps, in the order the engine produced them.
+}
+
+// explainedStep records what the explainer needs to know about a single step.
+type explainedStep struct {
+	urn      resource.URN
+	op       deploy.StepOp
+	keys     []resource.PropertyKey // the keys forcing replacement, if any.
+	diffs    []resource.PropertyKey // the keys that changed, if known.
+	upstream []resource.URN         // the resources (including the provider) that this resource depends upon.
+}
+
+func newPlanExplainer() *planExplainer {
+	return &planExplainer{steps: make(map[resource.URN]*explainedStep)}
+}
+
+// record notes a step of the preview. Only logical creates, updates, replacements, and deletes are explained.
+func (e *planExplainer) record(step engine.StepEventMetadata) {
+	if !step.Logical {
+		return
+	}
+	switch step.Op {
+	case deploy.OpCreate, deploy.OpUpdate, deploy.OpReplace, deploy.OpDelete:
+	default:
+		return
+	}
+
+	s := &explainedStep{urn: step.URN, op: step.Op, keys: step.Keys, diffs: step.Diffs}
+	if step.Res != nil && step.Res.State != nil {
+		state := step.Res.State
+		s.upstream = append(s.upstream, state.Dependencies...)
+		if state.Provider != "" {
+			if ref, err := providers.ParseReference(state.Provider); err == nil {
+				s.upstream = append(s.upstream, ref.URN())
+			}
+		}
+	}
+
+	if _, has := e.steps[s.urn]; !has {
+		e.order = append(e.order, s)
+	}
+	e.steps[s.urn] = s
+}
+
+// explain returns one sentence per root cause in the plan. A change is a root cause if it does not follow from an
+// earlier change; each sentence names the replacements and updates of the resources that depend, directly or
+// indirectly, upon the root cause.
+func (e *planExplainer) explain() []string {
+	// Index the changed resources that depend upon each changed resource. Deletions are explained on their own.
+	dependents := make(map[resource.URN][]*explainedStep)
+	for _, s := range e.order {
+		if s.op == deploy.OpDelete {
+			continue
+		}
+		for _, urn := range s.upstream {
+			if up, has := e.steps[urn]; has && up.op != deploy.OpDelete {
+				dependents[urn] = append(dependents[urn], s)
+			}
+		}
+	}
+
+	// The engine produces steps in dependency order, so the first unexplained step is always a root cause.
+	explained := make(map[resource.URN]bool)
+	var sentences []string
+	for _, s := range e.order {
+		if explained[s.urn] {
+			continue
+		}
+		explained[s.urn] = true
+
+		var replaced, updated []resource.URN
+		queue := append([]*explainedStep(nil), dependents[s.urn]...)
+		for len(queue) > 0 {
+			d := queue[0]
+			queue = queue[1:]
+			if explained[d.urn] {
+				continue
+			}
+			switch d.op {
+			case deploy.OpReplace:
+				replaced = append(replaced, d.urn)
+			case deploy.OpUpdate:
+				updated = append(updated, d.urn)
+			default:
+				// New resources are explained by the program, not by the resources they depend upon.
+				continue
+			}
+			explained[d.urn] = true
+			queue = append(queue, dependents[d.urn]...)
+		}
+
+		sentence := explainCause(s)
+		switch {
+		case len(replaced) > 0 && len(updated) > 0:
+			sentence += fmt.Sprintf(", forcing replacement of %s and causing %s to be updated",
+				describeURNs(replaced), describeURNs(updated))
+		case len(replaced) > 0:
+			sentence += fmt.Sprintf(", forcing replacement of %s", describeURNs(replaced))
+		case len(updated) > 0:
+			sentence += fmt.Sprintf(", causing %s to be updated", describeURNs(updated))
+		}
+		sentences = append(sentences, sentence+".")
+	}
+	return sentences
+}
+
+// explainCause describes a root cause step.
+func explainCause(s *explainedStep) string {
+	name := describeURN(s.urn)
+	switch s.op {
+	case deploy.OpCreate:
+		return fmt.Sprintf("%s will be created", name)
+	case deploy.OpDelete:
+		return fmt.Sprintf("%s will be deleted because the program no longer declares it", name)
+	}
+
+	verb, keys := "will be updated in place", s.diffs
+	if s.op == deploy.OpReplace {
+		verb = "must be replaced"
+		if len(s.keys) > 0 {
+			keys = s.keys
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Sprintf("%s %s", name, verb)
+	}
+
+	// The inputs of a default provider are the stack's configuration for its package, so describe them that way.
+	if providers.IsDefaultProvider(s.urn) {
+		pkg := providers.GetProviderPackage(s.urn.Type())
+		var config []string
+		for _, k := range keys {
+			if k != "version" {
+				config = append(config, fmt.Sprintf("%s:%s", pkg, k))
+			}
+		}
+		if len(config) == 0 {
+			return fmt.Sprintf("Because the version of the %s plugin changed, the default %s provider %s", pkg, pkg, verb)
+		}
+		return fmt.Sprintf("Because %s %s changed, the default %s provider %s",
+			plural("config key", len(config)), joinWords(config), pkg, verb)
+	}
+
+	var inputs []string
+	for _, k := range keys {
+		inputs = append(inputs, string(k))
+	}
+	return fmt.Sprintf("Because %s %s of %s changed, it %s",
+		plural("input", len(inputs)), joinWords(inputs), name, verb)
+}
+
+// describeURN returns a short, human-readable name for a resource: its type followed by its quoted name.
+func describeURN(urn resource.URN) string {
+	return fmt.Sprintf("%s %q", urn.Type(), urn.Name())
+}
+
+// describeURNs returns a human-readable list of the given resources.
+func describeURNs(urns []resource.URN) string {
+	var names []string
+	for _, urn := range urns {
+		names = append(names, describeURN(urn))
+	}
+	sort.Strings(names)
+	return joinWords(names)
+}
+
+// joinWords joins the given words into an English list: "a", "a and b", or "a, b, and c".
+func joinWords(words []string) string {
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		return words[0]
+	case 2:
+		return words[0] + " and " + words[1]
+	default:
+		return strings.Join(words[:len(words)-1], ", ") + ", and " + words[len(words)-1]
+	}
+}
+
+// plural returns the given noun, pluralized if n is not one.
+func plural(noun string, n int) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
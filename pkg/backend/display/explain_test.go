@@ -0,0 +1,90 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestExplainPlan(t *testing.T) {
+	urn := func(typ tokens.Type, name string) resource.URN {
+		return resource.NewURN("stack", "proj", "", typ, tokens.QName(name))
+	}
+	provURN := urn(providers.MakeProviderType("pkgA"), "default")
+	provRef, err := providers.NewReference(provURN, "provid")
+	assert.NoError(t, err)
+
+	step := func(op deploy.StepOp, urn resource.URN, keys, diffs []resource.PropertyKey,
+		deps ...resource.URN) engine.StepEventMetadata {
+
+		return engine.StepEventMetadata{
+			Op:      op,
+			URN:     urn,
+			Type:    urn.Type(),
+			Keys:    keys,
+			Diffs:   diffs,
+			Logical: true,
+			Res: &engine.StepEventStateMetadata{State: &resource.State{
+				URN:          urn,
+				Type:         urn.Type(),
+				Provider:     provRef.String(),
+				Dependencies: deps,
+			}},
+		}
+	}
+
+	resA, resB, resC := urn("pkgA:m:typA", "resA"), urn("pkgA:m:typA", "resB"), urn("pkgA:m:typA", "resC")
+	resD, resE := urn("pkgA:m:typA", "resD"), urn("pkgA:m:typA", "resE")
+	keys := []resource.PropertyKey{"region"}
+
+	explainer := newPlanExplainer()
+	explainer.record(step(deploy.OpReplace, provURN, keys, keys))
+	explainer.record(step(deploy.OpReplace, resA, []resource.PropertyKey{"foo"}, nil))
+	explainer.record(step(deploy.OpUpdate, resB, nil, []resource.PropertyKey{"bar"}, resA))
+	explainer.record(step(deploy.OpCreate, resC, nil, nil, resA))
+	explainer.record(step(deploy.OpSame, resE, nil, nil))
+	update := step(deploy.OpUpdate, resE, nil, []resource.PropertyKey{"baz", "qux"})
+	update.Res.State.Provider = ""
+	explainer.record(update)
+	explainer.record(step(deploy.OpDelete, resD, nil, nil))
+
+	// Non-logical steps are ignored.
+	replaced := step(deploy.OpDeleteReplaced, resA, nil, nil)
+	replaced.Logical = false
+	explainer.record(replaced)
+
+	assert.Equal(t, []string{
+		`Because config key pkgA:region changed, the default pkgA provider must be replaced, forcing replacement ` +
+			`of pkgA:m:typA "resA" and causing pkgA:m:typA "resB" to be updated.`,
+		`pkgA:m:typA "resC" will be created.`,
+		`Because inputs baz and qux of pkgA:m:typA "resE" changed, it will be updated in place.`,
+		`pkgA:m:typA "resD" will be deleted because the program no longer declares it.`,
+	}, explainer.explain())
+}
+
+func TestJoinWords(t *testing.T) {
+	assert.Equal(t, "", joinWords(nil))
+	assert.Equal(t, "a", joinWords([]string{"a"}))
+	assert.Equal(t, "a and b", joinWords([]string{"a", "b"}))
+	assert.Equal(t, "a, b, and c", joinWords([]string{"a", "b", "c"}))
+}
@@ -0,0 +1,235 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// htmlPreview is the data from which the HTML page of a preview is rendered. It is derived from the preview's digest,
+// so secrets are already masked.
+type htmlPreview struct {
+	Title            string
+	MaybeCorrupt     bool
+	Summary          []htmlOpCount
+	Steps            []htmlStep
+	Diagnostics      []previewDiagnostic
+	PolicyViolations []previewPolicyViolation
+}
+
+// htmlOpCount is the number of steps of a single kind that a preview plans.
+type htmlOpCount struct {
+	Op    deploy.StepOp
+	Count int
+}
+
+// htmlStep is a single step of a preview, rendered as a collapsible section.
+type htmlStep struct {
+	Op             deploy.StepOp
+	URN            resource.URN
+	Type           tokens.Type
+	Name           tokens.QName
+	Changed        bool // true if the step changes the resource, in which case its section starts expanded.
+	ReplaceReasons []resource.PropertyKey
+	Properties     []htmlProperty
+}
+
+// htmlProperty is a single property of a step's resource, or a single change to one.
+type htmlProperty struct {
+	Path string
+	Kind string // "add", "update", "delete", or "same".
+	Old  string
+	New  string
+}
+
+// newHTMLPreview converts the digest of a preview into the data from which its HTML page is rendered.
+func newHTMLPreview(title string, digest previewDigest) htmlPreview {
+	preview := htmlPreview{
+		Title:            title,
+		MaybeCorrupt:     digest.MaybeCorrupt,
+		Diagnostics:      digest.Diagnostics,
+		PolicyViolations: digest.PolicyViolations,
+	}
+	for _, op := range deploy.StepOps {
+		if count := digest.ChangeSummary[op]; count > 0 {
+			preview.Summary = append(preview.Summary, htmlOpCount{Op: op, Count: count})
+		}
+	}
+	for _, step := range digest.Steps {
+		if isRootURN(step.URN) {
+			continue
+		}
+		preview.Steps = append(preview.Steps, newHTMLStep(step))
+	}
+	return preview
+}
+
+// newHTMLStep converts a step of a preview's digest into a step of its HTML page. Steps with a detailed diff show
+// their changes; creates show their new inputs, deletes their old inputs, and all other steps their current inputs.
+func newHTMLStep(step *previewStep) htmlStep {
+	s := htmlStep{
+		Op:             step.Op,
+		URN:            step.URN,
+		Type:           step.URN.Type(),
+		Name:           step.URN.Name(),
+		Changed:        step.Op != deploy.OpSame && step.Op != deploy.OpRead,
+		ReplaceReasons: step.ReplaceReasons,
+	}
+
+	if len(step.DetailedDiff) > 0 {
+		for _, change := range step.DetailedDiff {
+			p := htmlProperty{Path: change.Path, Kind: string(change.Kind)}
+			if change.Kind != resource.PropertyAdded {
+				p.Old = formatHTMLValue(change.Old)
+			}
+			if change.Kind != resource.PropertyDeleted {
+				p.New = formatHTMLValue(change.New)
+			}
+			s.Properties = append(s.Properties, p)
+		}
+		return s
+	}
+
+	var inputs map[string]interface{}
+	kind := "same"
+	switch {
+	case step.Op == deploy.OpCreate && step.NewState != nil:
+		inputs, kind = step.NewState.Inputs, string(resource.PropertyAdded)
+	case (step.Op == deploy.OpDelete || step.Op == deploy.OpDeleteReplaced) && step.OldState != nil:
+		inputs, kind = step.OldState.Inputs, string(resource.PropertyDeleted)
+	case step.NewState != nil:
+		inputs = step.NewState.Inputs
+	case step.OldState != nil:
+		inputs = step.OldState.Inputs
+	}
+
+	keys := make([]string, 0, len(inputs))
+	for k := range inputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := htmlProperty{Path: k, Kind: kind}
+		if kind == string(resource.PropertyDeleted) {
+			p.Old = formatHTMLValue(inputs[k])
+		} else {
+			p.New = formatHTMLValue(inputs[k])
+		}
+		s.Properties = append(s.Properties, p)
+	}
+	return s
+}
+
+// formatHTMLValue formats a serialized property value for display.
+func formatHTMLValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// htmlPreviewTemplate renders an htmlPreview as a self-contained page: its styles are inline and it loads nothing, so
+// that it can be archived and opened anywhere.
+var htmlPreviewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+h1 { font-size: 1.5em; }
+ul.summary { list-style: none; padding: 0; }
+ul.summary li { display: inline-block; margin-right: 1.5em; }
+details { border: 1px solid #e1e4e8; border-radius: 4px; margin: 0.5em 0; padding: 0.5em; }
+summary { cursor: pointer; font-family: monospace; }
+table { border-collapse: collapse; margin-top: 0.5em; width: 100%; }
+td { border-top: 1px solid #e1e4e8; font-family: monospace; padding: 0.25em 0.5em; vertical-align: top; }
+pre { margin: 0; white-space: pre-wrap; }
+.op-create, .add { color: #22863a; }
+.op-delete, .op-delete-replaced, .delete { color: #cb2431; }
+.op-update, .update { color: #b08800; }
+.op-replace, .op-create-replacement { color: #6f42c1; }
+.op-same, .op-read, .same { color: #6a737d; }
+tr.add { background: #f0fff4; }
+tr.delete { background: #ffeef0; }
+tr.update { background: #fffbdd; }
+.warning { color: #b08800; }
+.error { color: #cb2431; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .MaybeCorrupt}}<p class="error">One or more resources may be corrupt.</p>{{end}}
+{{if .Summary}}<ul class="summary">
+{{range .Summary}}<li class="op-{{.Op}}">{{.Count}} to {{.Op}}</li>
+{{end}}</ul>{{else}}<p>No changes.</p>{{end}}
+{{range .Steps}}<details{{if .Changed}} open{{end}}>
+<summary><span class="op-{{.Op}}">{{.Op}}</span> {{.Type}} <strong>{{.Name}}</strong></summary>
+<div>{{.URN}}</div>
+{{if .ReplaceReasons}}<div class="op-replace">replaced because of changes to:
+{{- range .ReplaceReasons}} {{.}}{{end}}</div>{{end}}
+{{if .Properties}}<table>
+{{range .Properties}}<tr class="{{.Kind}}"><td>{{.Path}}</td>
+<td><pre>{{.Old}}</pre></td><td><pre>{{.New}}</pre></td></tr>
+{{end}}</table>{{end}}
+</details>
+{{end}}
+{{if .PolicyViolations}}<h2>Policy violations</h2>
+<ul>{{range .PolicyViolations}}<li class="{{if eq (print .EnforcementLevel) "mandatory"}}error{{else}}warning{{end}}">
+[{{.EnforcementLevel}}] {{.PolicyName}} ({{.PolicyPackName}}@{{.PolicyPackVersion}}){{if .URN}} on {{.URN}}{{end}}:
+<pre>{{.Message}}</pre></li>
+{{end}}</ul>{{end}}
+{{if .Diagnostics}}<h2>Diagnostics</h2>
+<ul>{{range .Diagnostics}}<li class="{{.Severity}}">{{if .URN}}{{.URN}}: {{end}}<pre>{{.Message}}</pre></li>
+{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+// renderHTMLPreview writes the HTML page of the given preview to the given writer.
+func renderHTMLPreview(w io.Writer, preview htmlPreview) error {
+	return htmlPreviewTemplate.Execute(w, preview)
+}
+
+// ShowHTMLEvents renders engine events from a preview into a self-contained HTML page, with a collapsible section for
+// each resource and colorized property diffs, so that previews can be archived as build artifacts and viewed in a
+// browser. Secrets are masked. Like ShowJSONEvents, this does not emit events incrementally.
+func ShowHTMLEvents(op string, action apitype.UpdateKind, stack tokens.QName, proj tokens.PackageName,
+	events <-chan engine.Event, done chan<- bool, opts Options) {
+
+	// Ensure we close the done channel before exiting.
+	defer func() { close(done) }()
+
+	digest := newPreviewDigest(events, opts)
+	title := fmt.Sprintf("Preview of %s of %s/%s", action, proj, stack)
+	err := renderHTMLPreview(os.Stdout, newHTMLPreview(title, digest))
+	contract.Assertf(err == nil, "unexpected HTML error: %v", err)
+}
@@ -0,0 +1,84 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestHTMLPreview(t *testing.T) {
+	typ := tokens.Type("pkgA:m:typA")
+	urn := resource.NewURN("stack", "proj", "", typ, "resA")
+
+	old := resource.NewState(typ, urn, true, false, "id", resource.PropertyMap{
+		"size":     resource.NewStringProperty("small"),
+		"password": resource.MakeSecret(resource.NewStringProperty("hunter2")),
+	}, nil, "", false, false, nil, nil, "", nil, false, nil, nil)
+	new := resource.NewState(typ, urn, true, false, "", resource.PropertyMap{
+		"size":     resource.NewStringProperty("<large>"),
+		"password": resource.MakeSecret(resource.NewStringProperty("correct horse")),
+	}, nil, "", false, false, nil, nil, "", nil, false, nil, nil)
+
+	events := make(chan engine.Event, 2)
+	events <- engine.Event{
+		Type: engine.ResourcePreEvent,
+		Payload: engine.ResourcePreEventPayload{Metadata: engine.StepEventMetadata{
+			Op:      deploy.OpUpdate,
+			URN:     urn,
+			Type:    typ,
+			Old:     &engine.StepEventStateMetadata{State: old, Inputs: old.Inputs},
+			New:     &engine.StepEventStateMetadata{State: new, Inputs: new.Inputs},
+			Res:     &engine.StepEventStateMetadata{State: new},
+			Diffs:   []resource.PropertyKey{"size", "password"},
+			Logical: true,
+		}},
+	}
+	events <- engine.Event{
+		Type: engine.SummaryEvent,
+		Payload: engine.SummaryEventPayload{
+			IsPreview:       true,
+			ResourceChanges: engine.ResourceChanges{deploy.OpUpdate: 1},
+		},
+	}
+	close(events)
+
+	preview := newHTMLPreview("Preview", newPreviewDigest(events, Options{}))
+	assert.Equal(t, []htmlOpCount{{Op: deploy.OpUpdate, Count: 1}}, preview.Summary)
+	if assert.Len(t, preview.Steps, 1) {
+		step := preview.Steps[0]
+		assert.True(t, step.Changed)
+		assert.Equal(t, tokens.QName("resA"), step.Name)
+		assert.ElementsMatch(t, []htmlProperty{
+			{Path: "size", Kind: "update", Old: "small", New: "<large>"},
+		}, step.Properties)
+	}
+
+	// The page escapes the values it shows, and never shows secrets.
+	var buf bytes.Buffer
+	assert.NoError(t, renderHTMLPreview(&buf, preview))
+	page := buf.String()
+	assert.Contains(t, page, "&lt;large&gt;")
+	assert.Contains(t, page, "<details open>")
+	assert.NotContains(t, page, "hunter2")
+	assert.NotContains(t, page, "correct horse")
+}
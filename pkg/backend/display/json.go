@@ -84,9 +84,58 @@ func stateForJSONOutput(s *resource.State, opts Options) *resource.State {
 		outputs = resource.PropertyMap{}
 	}
 
-	return resource.NewState(s.Type, s.URN, s.Custom, s.Delete, s.ID, inputs,
+	state := resource.NewState(s.Type, s.URN, s.Custom, s.Delete, s.ID, inputs,
 		outputs, s.Parent, s.Protect, s.External, s.Dependencies, s.InitErrors, s.Provider,
 		s.PropertyDependencies, s.PendingReplacement, s.AdditionalSecretOutputs, s.Aliases)
+	state.Annotations = s.Annotations
+	state.CustomTimeouts = s.CustomTimeouts
+	return state
+}
+
+// detailedDiffForJSONOutput returns the key-level changes between the old and new inputs of a step, restricted to the
+// top-level keys that the provider reported as different, if it reported any. Secret values are masked.
+func detailedDiffForJSONOutput(m engine.StepEventMetadata) []previewPropertyChange {
+	if m.Old == nil || m.New == nil || m.Old.Inputs == nil || m.New.Inputs == nil {
+		return nil
+	}
+	diff := MassageSecrets(m.Old.Inputs, false).Diff(MassageSecrets(m.New.Inputs, false))
+	if diff == nil {
+		return nil
+	}
+
+	var includeSet map[resource.PropertyKey]bool
+	if m.Diffs != nil {
+		includeSet = make(map[resource.PropertyKey]bool)
+		for _, k := range m.Diffs {
+			includeSet[k] = true
+		}
+	}
+
+	var changes []previewPropertyChange
+	for _, c := range diff.Changes() {
+		if includeSet != nil && !includeSet[c.Path[0]] {
+			continue
+		}
+
+		change := previewPropertyChange{Path: c.PathString(), Kind: c.Kind}
+		if c.Kind != resource.PropertyAdded {
+			change.Old = serializePropertyValueForJSONOutput(c.Old)
+		}
+		if c.Kind != resource.PropertyDeleted {
+			change.New = serializePropertyValueForJSONOutput(c.New)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func serializePropertyValueForJSONOutput(v resource.PropertyValue) interface{} {
+	result, err := stack.SerializePropertyValue(v, config.NewPanicCrypter())
+	if err != nil {
+		glog.V(7).Infof("not adding property value as there was an error serializing: %s", err)
+		return nil
+	}
+	return result
 }
 
 // ShowJSONEvents renders engine events from a preview into a well-formed JSON document. Note that this does not
@@ -97,7 +146,16 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 	// Ensure we close the done channel before exiting.
 	defer func() { close(done) }()
 
-	// Now loop and accumulate our digest until the event stream is closed, or we hit a cancellation.
+	// Now accumulate our digest and render it to stdout.
+	digest := newPreviewDigest(events, opts)
+	out, err := json.MarshalIndent(&digest, "", "    ")
+	contract.Assertf(err == nil, "unexpected JSON error: %v", err)
+	fmt.Println(string(out))
+}
+
+// newPreviewDigest accumulates the digest of the given preview events until the event stream is closed, or a
+// cancellation event arrives.
+func newPreviewDigest(events <-chan engine.Event, opts Options) previewDigest {
 	var digest previewDigest
 	for e := range events {
 		// In the event of cancelation, break out of the loop immediately.
@@ -111,6 +169,15 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 		case engine.PreludeEvent:
 			// Capture the config map from the prelude. Note that all secrets will remain blinded for safety.
 			digest.Config = e.Payload.(engine.PreludeEventPayload).Config
+		case engine.PlanCacheEvent:
+			// Record whether the steps that follow were replayed from a cached plan.
+			digest.CachedPlan = e.Payload.(engine.PlanCacheEventPayload).Hit
+
+		// Events ocurring when the preview is cancelled:
+		case engine.CancellationEvent:
+			if apiEvent, err := engine.ConvertEvent(e); err == nil {
+				digest.Cancellation = apiEvent.CancellationEvent
+			}
 
 		// Events throughout the execution:
 		case engine.DiagEvent:
@@ -130,6 +197,17 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 				Message:  colors.Never.Colorize(p.Message),
 				Severity: diag.Info,
 			})
+		case engine.PolicyViolationEvent:
+			// Record policy violations in their structured form so that tools can act on the enforcement level.
+			p := e.Payload.(engine.PolicyViolationEventPayload)
+			digest.PolicyViolations = append(digest.PolicyViolations, previewPolicyViolation{
+				URN:               p.ResourceURN,
+				PolicyName:        p.PolicyName,
+				PolicyPackName:    p.PolicyPackName,
+				PolicyPackVersion: p.PolicyPackVersion,
+				EnforcementLevel:  p.EnforcementLevel,
+				Message:           colors.Never.Colorize(p.Message),
+			})
 		case engine.ResourcePreEvent:
 			// Create the detailed metadata for this step and the initial state of its resource. Later,
 			// if new outputs arrive, we'll search for and swap in those new values.
@@ -140,6 +218,7 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 					Provider:       m.Provider,
 					DiffReasons:    m.Diffs,
 					ReplaceReasons: m.Keys,
+					DetailedDiff:   detailedDiffForJSONOutput(m),
 				}
 
 				if m.Old != nil {
@@ -163,7 +242,18 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 
 				digest.Steps = append(digest.Steps, step)
 			}
-		case engine.ResourceOutputsEvent, engine.ResourceOperationFailed:
+		case engine.StepDependenciesEvent:
+			// Attach the explanation of the step's predecessors to the step, which precedes it.
+			p := e.Payload.(engine.StepDependenciesEventPayload)
+			if n := len(digest.Steps); n > 0 && digest.Steps[n-1].URN == p.Metadata.URN {
+				if apiEvent, err := engine.ConvertEvent(e); err == nil {
+					digest.Steps[n-1].Dependencies = apiEvent.StepDependenciesEvent.Dependencies
+				}
+			}
+		case engine.ResourceOutputsEvent, engine.ResourceOperationFailed, engine.StepProgressEvent,
+			engine.PluginLifecycleEvent, engine.ConfirmationRequiredEvent, engine.RefreshProgressEvent,
+			engine.CheckResultsEvent, engine.DeletionOrderEvent, engine.PausedEvent, engine.InitErrorsEvent,
+			engine.ProviderChangeEvent, engine.AggregateProgressEvent, engine.PendingDeleteEvent:
 			// Because we are only JSON serializing previews, we don't need to worry about outputs
 			// resolving or operations failing. In the future, if we serialize actual deployments, we will
 			// need to come up with a scheme for matching the failure to the associated step.
@@ -175,15 +265,14 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 			digest.Duration = p.Duration
 			digest.ChangeSummary = p.ResourceChanges
 			digest.MaybeCorrupt = p.MaybeCorrupt
+			if apiEvent, err := engine.ConvertEvent(e); err == nil {
+				digest.Estimates = apiEvent.SummaryEvent.Estimates
+			}
 		default:
 			contract.Failf("unknown event type '%s'", e.Type)
 		}
 	}
-
-	// Finally, go ahead and render the JSON to stdout.
-	out, err := json.MarshalIndent(&digest, "", "    ")
-	contract.Assertf(err == nil, "unexpected JSON error: %v", err)
-	fmt.Println(string(out))
+	return digest
 }
 
 // previewDigest is a JSON-serializable overview of a preview operation.
@@ -196,6 +285,8 @@ type previewDigest struct {
 	// Diagnostics contains a record of all warnings/errors that took place during the preview. Note that
 	// ephemeral and debug messages are omitted from this list, as they are meant for display purposes only.
 	Diagnostics []previewDiagnostic `json:"diagnostics,omitempty"`
+	// PolicyViolations contains a record of all policy violations reported by analyzers during the preview.
+	PolicyViolations []previewPolicyViolation `json:"policyViolations,omitempty"`
 
 	// Duration records the amount of time it took to perform the preview.
 	Duration time.Duration `json:"duration,omitempty"`
@@ -203,6 +294,12 @@ type previewDigest struct {
 	ChangeSummary engine.ResourceChanges `json:"changeSummary,omitempty"`
 	// MaybeCorrupt indicates whether one or more resources may be corrupt.
 	MaybeCorrupt bool `json:"maybeCorrupt,omitempty"`
+	// Estimates contains the estimates of the impact of the preview's plan, e.g. its cost.
+	Estimates []apitype.PlanEstimate `json:"estimates,omitempty"`
+	// CachedPlan indicates whether the preview was served from a cached plan rather than by running the program.
+	CachedPlan bool `json:"cachedPlan,omitempty"`
+	// Cancellation describes the state in which the preview was left, if it was cancelled.
+	Cancellation *apitype.CancellationEvent `json:"cancellation,omitempty"`
 }
 
 // previewStep is a detailed overview of a step the engine intends to take.
@@ -221,6 +318,23 @@ type previewStep struct {
 	DiffReasons []resource.PropertyKey `json:"diffReasons,omitempty"`
 	// ReplaceReasons is a list of keys that are causing replacement (for replacement steps only).
 	ReplaceReasons []resource.PropertyKey `json:"replaceReasons,omitempty"`
+	// DetailedDiff lists the changes to the resource's inputs, with changes to maps broken down by key (for updating
+	// steps only).
+	DetailedDiff []previewPropertyChange `json:"detailedDiff,omitempty"`
+	// Dependencies explains why the step is scheduled after each of its predecessors.
+	Dependencies []apitype.StepDependency `json:"dependencies,omitempty"`
+}
+
+// previewPropertyChange is a single change to one of a resource's inputs.
+type previewPropertyChange struct {
+	// Path is the path to the changed property, e.g. `tags.env`.
+	Path string `json:"path"`
+	// Kind is the kind of change: "add", "update", or "delete".
+	Kind resource.PropertyChangeKind `json:"kind"`
+	// Old is the old value of the property, if it had one.
+	Old interface{} `json:"old,omitempty"`
+	// New is the new value of the property, if it has one.
+	New interface{} `json:"new,omitempty"`
 }
 
 // previewDiagnostic is a warning or error emitted during the execution of the preview.
@@ -230,3 +344,13 @@ type previewDiagnostic struct {
 	Message  string        `json:"message,omitempty"`
 	Severity diag.Severity `json:"severity,omitempty"`
 }
+
+// previewPolicyViolation is a policy violation reported by an analyzer during the execution of the preview.
+type previewPolicyViolation struct {
+	URN               resource.URN             `json:"urn,omitempty"`
+	PolicyName        string                   `json:"policyName"`
+	PolicyPackName    string                   `json:"policyPackName"`
+	PolicyPackVersion string                   `json:"policyPackVersion"`
+	EnforcementLevel  apitype.EnforcementLevel `json:"enforcementLevel"`
+	Message           string                   `json:"message"`
+}
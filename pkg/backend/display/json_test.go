@@ -0,0 +1,81 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestJSONPolicyViolations(t *testing.T) {
+	urn := resource.NewURN("stack", "proj", "", tokens.Type("pkgA:m:typA"), "resA")
+
+	events := make(chan engine.Event, 2)
+	events <- engine.Event{
+		Type: engine.PolicyViolationEvent,
+		Payload: engine.PolicyViolationEventPayload{
+			ResourceURN:       urn,
+			Message:           "<{%fg 1%}>buckets must not be public<{%reset%}>",
+			PolicyName:        "no-public-buckets",
+			PolicyPackName:    "security",
+			PolicyPackVersion: "1.2.0",
+			EnforcementLevel:  apitype.Mandatory,
+		},
+	}
+	events <- engine.Event{
+		Type: engine.PolicyViolationEvent,
+		Payload: engine.PolicyViolationEventPayload{
+			Message:           "stack has no owner tag",
+			PolicyName:        "owner-tag",
+			PolicyPackName:    "hygiene",
+			PolicyPackVersion: "0.1.0",
+			EnforcementLevel:  apitype.Warning,
+		},
+	}
+	close(events)
+
+	digest := newPreviewDigest(events, Options{})
+	out, err := json.Marshal(&digest)
+	assert.NoError(t, err)
+
+	var actual struct {
+		PolicyViolations []map[string]interface{} `json:"policyViolations"`
+	}
+	assert.NoError(t, json.Unmarshal(out, &actual))
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"urn":               string(urn),
+			"policyName":        "no-public-buckets",
+			"policyPackName":    "security",
+			"policyPackVersion": "1.2.0",
+			"enforcementLevel":  "mandatory",
+			"message":           "buckets must not be public",
+		},
+		{
+			"policyName":        "owner-tag",
+			"policyPackName":    "hygiene",
+			"policyPackVersion": "0.1.0",
+			"enforcementLevel":  "warning",
+			"message":           "stack has no owner tag",
+		},
+	}, actual.PolicyViolations)
+}
@@ -39,5 +39,9 @@ type Options struct {
 	IsInteractive        bool                // true if we should display things interactively.
 	Type                 Type                // type of display (rich diff, progress, or query).
 	JSONDisplay          bool                // true if we should emit the entire diff as JSON.
+	TerraformPlanJSON    bool                // true if JSON output should follow the Terraform plan format.
+	HTMLDisplay          bool                // true if JSON output should instead be rendered as an HTML page.
+	ShowExplanation      bool                // true to follow a preview with a prose explanation of its changes.
+	ShowDependencies     bool                // true to show why each step in a preview waits on its predecessors.
 	Debug                bool                // true to enable debug output.
 }
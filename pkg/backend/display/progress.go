@@ -186,6 +186,18 @@ func getEventUrnAndMetadata(event engine.Event) (resource.URN, *engine.StepEvent
 	} else if event.Type == engine.ResourceOperationFailed {
 		payload := event.Payload.(engine.ResourceOperationFailedPayload)
 		return payload.Metadata.URN, &payload.Metadata
+	} else if event.Type == engine.StepProgressEvent {
+		payload := event.Payload.(engine.StepProgressEventPayload)
+		return payload.Metadata.URN, &payload.Metadata
+	} else if event.Type == engine.PluginLifecycleEvent {
+		payload := event.Payload.(engine.PluginLifecycleEventPayload)
+		return payload.Metadata.URN, &payload.Metadata
+	} else if event.Type == engine.ConfirmationRequiredEvent {
+		payload := event.Payload.(engine.ConfirmationRequiredEventPayload)
+		return payload.Metadata.URN, &payload.Metadata
+	} else if event.Type == engine.StepDependenciesEvent {
+		payload := event.Payload.(engine.StepDependenciesEventPayload)
+		return payload.Metadata.URN, &payload.Metadata
 	} else if event.Type == engine.DiagEvent {
 		return event.Payload.(engine.DiagEventPayload).URN, nil
 	}
@@ -861,6 +873,52 @@ func (display *ProgressDisplay) processNormalEvent(event engine.Event) {
 		payload := event.Payload.(engine.PreludeEventPayload)
 		display.writeSimpleMessage(renderPreludeEvent(payload, display.opts))
 		return
+	case engine.PlanCacheEvent:
+		payload := event.Payload.(engine.PlanCacheEventPayload)
+		display.writeSimpleMessage(renderPlanCacheEvent(payload, display.opts))
+		return
+	case engine.CancellationEvent:
+		payload := event.Payload.(engine.CancellationEventPayload)
+		display.writeSimpleMessage(renderCancellationEvent(payload, display.opts))
+		return
+	case engine.CheckResultsEvent:
+		payload := event.Payload.(engine.CheckResultsEventPayload)
+		display.writeSimpleMessage(renderCheckResultsEvent(payload, display.opts))
+		return
+	case engine.DeletionOrderEvent:
+		payload := event.Payload.(engine.DeletionOrderEventPayload)
+		display.writeSimpleMessage(renderDeletionOrderEvent(payload, display.opts))
+		return
+	case engine.PausedEvent:
+		payload := event.Payload.(engine.PausedEventPayload)
+		display.writeSimpleMessage(renderPausedEvent(payload, display.opts))
+		return
+	case engine.InitErrorsEvent:
+		payload := event.Payload.(engine.InitErrorsEventPayload)
+		display.writeSimpleMessage(renderInitErrorsEvent(payload, display.opts))
+		return
+	case engine.ProviderChangeEvent:
+		payload := event.Payload.(engine.ProviderChangeEventPayload)
+		display.writeSimpleMessage(renderProviderChangeEvent(payload, display.opts))
+		return
+	case engine.PendingDeleteEvent:
+		payload := event.Payload.(engine.PendingDeleteEventPayload)
+		display.writeSimpleMessage(renderPendingDeleteEvent(payload, display.opts))
+		return
+	case engine.AggregateProgressEvent:
+		// In a terminal, the summary shows the number of steps of each kind once the operation completes.
+		if !display.isTerminal {
+			payload := event.Payload.(engine.AggregateProgressEventPayload)
+			display.writeSimpleMessage(renderAggregateProgressEvent(payload, display.opts))
+		}
+		return
+	case engine.RefreshProgressEvent:
+		// In a terminal, each resource's row already shows whether it has been read.
+		if !display.isTerminal {
+			payload := event.Payload.(engine.RefreshProgressEventPayload)
+			display.writeSimpleMessage(renderRefreshProgressEvent(payload, display.opts))
+		}
+		return
 	case engine.SummaryEvent:
 		// keep track of the summar event so that we can display it after all other
 		// resource-related events we receive.
@@ -875,6 +933,10 @@ func (display *ProgressDisplay) processNormalEvent(event engine.Event) {
 	case engine.StdoutColorEvent:
 		display.handleSystemEvent(event.Payload.(engine.StdoutEventPayload))
 		return
+	case engine.StepDependenciesEvent:
+		if !display.opts.ShowDependencies {
+			return
+		}
 	}
 
 	// At this point, all events should relate to resources.
@@ -926,6 +988,14 @@ func (display *ProgressDisplay) processNormalEvent(event engine.Event) {
 	} else if event.Type == engine.PolicyViolationEvent {
 		// also record this policy violation so we print it at the end.
 		row.RecordPolicyViolationEvent(event)
+	} else if event.Type == engine.StepProgressEvent {
+		row.RecordStepProgressEvent(event)
+	} else if event.Type == engine.PluginLifecycleEvent {
+		row.RecordPluginLifecycleEvent(event)
+	} else if event.Type == engine.ConfirmationRequiredEvent {
+		row.RecordConfirmationRequiredEvent(event)
+	} else if event.Type == engine.StepDependenciesEvent {
+		row.RecordStepDependenciesEvent(event)
 	} else {
 		contract.Failf("Unhandled event type '%s'", event.Type)
 	}
@@ -1037,6 +1107,8 @@ func (display *ProgressDisplay) getStepDoneDescription(step engine.StepEventMeta
 				return "failed"
 			case deploy.OpCreate, deploy.OpCreateReplacement:
 				return "creating failed"
+			case deploy.OpValidateReplacement:
+				return "validating failed"
 			case deploy.OpUpdate:
 				return "updating failed"
 			case deploy.OpDelete, deploy.OpDeleteReplaced:
@@ -1045,10 +1117,14 @@ func (display *ProgressDisplay) getStepDoneDescription(step engine.StepEventMeta
 				return "replacing failed"
 			case deploy.OpRead, deploy.OpReadReplacement:
 				return "reading failed"
+			case deploy.OpImport:
+				return "importing failed"
 			case deploy.OpRefresh:
 				return "refreshing failed"
 			case deploy.OpReadDiscard, deploy.OpDiscardReplaced:
 				return "discarding failed"
+			case deploy.OpPrune:
+				return "pruning failed"
 			}
 		} else {
 			switch op {
@@ -1064,6 +1140,8 @@ func (display *ProgressDisplay) getStepDoneDescription(step engine.StepEventMeta
 				return "replaced"
 			case deploy.OpCreateReplacement:
 				return "created replacement"
+			case deploy.OpValidateReplacement:
+				return "validated replacement"
 			case deploy.OpDeleteReplaced:
 				return "deleted original"
 			case deploy.OpRead:
@@ -1071,12 +1149,19 @@ func (display *ProgressDisplay) getStepDoneDescription(step engine.StepEventMeta
 				return "read"
 			case deploy.OpReadReplacement:
 				return "read for replacement"
+			case deploy.OpImport:
+				return "imported"
 			case deploy.OpRefresh:
 				return "refresh"
 			case deploy.OpReadDiscard:
 				return "discarded"
 			case deploy.OpDiscardReplaced:
 				return "discarded original"
+			case deploy.OpPrune:
+				if step.New != nil {
+					return "orphaned"
+				}
+				return "pruned"
 			}
 		}
 
@@ -1105,6 +1190,8 @@ func (display *ProgressDisplay) getPreviewText(step engine.StepEventMetadata) st
 		return "replace"
 	case deploy.OpCreateReplacement:
 		return "create replacement"
+	case deploy.OpValidateReplacement:
+		return "validate replacement"
 	case deploy.OpDeleteReplaced:
 		return "delete original"
 	case deploy.OpRead:
@@ -1112,12 +1199,19 @@ func (display *ProgressDisplay) getPreviewText(step engine.StepEventMetadata) st
 		return "read"
 	case deploy.OpReadReplacement:
 		return "read for replacement"
+	case deploy.OpImport:
+		return "import"
 	case deploy.OpRefresh:
 		return "refreshing"
 	case deploy.OpReadDiscard:
 		return "discard"
 	case deploy.OpDiscardReplaced:
 		return "discard origina;"
+	case deploy.OpPrune:
+		if step.New != nil {
+			return "orphaned"
+		}
+		return "prune"
 	}
 
 	contract.Failf("Unrecognized resource step op: %v", step.Op)
@@ -1136,16 +1230,23 @@ func (display *ProgressDisplay) getPreviewDoneText(step engine.StepEventMetadata
 		return "update"
 	case deploy.OpDelete:
 		return "delete"
-	case deploy.OpReplace, deploy.OpCreateReplacement, deploy.OpDeleteReplaced, deploy.OpReadReplacement,
-		deploy.OpDiscardReplaced:
+	case deploy.OpReplace, deploy.OpCreateReplacement, deploy.OpValidateReplacement, deploy.OpDeleteReplaced,
+		deploy.OpReadReplacement, deploy.OpDiscardReplaced:
 		return "replace"
 	case deploy.OpRead:
 		// nolint: goconst
 		return "read"
+	case deploy.OpImport:
+		return "import"
 	case deploy.OpRefresh:
 		return "refresh"
 	case deploy.OpReadDiscard:
 		return "discard"
+	case deploy.OpPrune:
+		if step.New != nil {
+			return "orphaned"
+		}
+		return "prune"
 	}
 
 	contract.Failf("Unrecognized resource step op: %v", step.Op)
@@ -1168,7 +1269,9 @@ func (display *ProgressDisplay) getStepOp(step engine.StepEventMetadata) deploy.
 		// Once done, show the steps for replacing as a single 'replaced' step.
 		// During update, we'll show these individual steps.
 		if display.isPreview || display.done {
-			if op == deploy.OpCreateReplacement || op == deploy.OpDeleteReplaced || op == deploy.OpDiscardReplaced {
+			switch op {
+			case deploy.OpCreateReplacement, deploy.OpValidateReplacement, deploy.OpDeleteReplaced,
+				deploy.OpDiscardReplaced:
 				return deploy.OpReplace
 			}
 		}
@@ -1208,18 +1311,27 @@ func (display *ProgressDisplay) getStepInProgressDescription(step engine.StepEve
 			return "replacing"
 		case deploy.OpCreateReplacement:
 			return "creating replacement"
+		case deploy.OpValidateReplacement:
+			return "validating replacement"
 		case deploy.OpDeleteReplaced:
 			return "deleting original"
 		case deploy.OpRead:
 			return "reading"
 		case deploy.OpReadReplacement:
 			return "reading for replacement"
+		case deploy.OpImport:
+			return "importing"
 		case deploy.OpRefresh:
 			return "refreshing"
 		case deploy.OpReadDiscard:
 			return "discarding"
 		case deploy.OpDiscardReplaced:
 			return "discarding original"
+		case deploy.OpPrune:
+			if step.New != nil {
+				return "orphaned"
+			}
+			return "pruning"
 		}
 
 		contract.Failf("Unrecognized resource step op: %v", op)
@@ -88,7 +88,11 @@ func renderQueryEvent(event engine.Event, opts Options) string {
 		return renderQueryDiagEvent(event.Payload.(engine.DiagEventPayload), opts)
 
 	case engine.PreludeEvent, engine.SummaryEvent, engine.ResourceOperationFailed,
-		engine.ResourceOutputsEvent, engine.ResourcePreEvent:
+		engine.ResourceOutputsEvent, engine.ResourcePreEvent, engine.StepProgressEvent,
+		engine.PluginLifecycleEvent, engine.ConfirmationRequiredEvent, engine.PlanCacheEvent,
+		engine.StepDependenciesEvent, engine.CancellationEvent, engine.RefreshProgressEvent,
+		engine.CheckResultsEvent, engine.DeletionOrderEvent, engine.PausedEvent, engine.InitErrorsEvent,
+		engine.ProviderChangeEvent, engine.AggregateProgressEvent, engine.PendingDeleteEvent:
 
 		contract.Failf("query mode does not support resource operations")
 		return ""
@@ -60,6 +60,10 @@ type ResourceRow interface {
 	DiagInfo() *DiagInfo
 	RecordDiagEvent(diagEvent engine.Event)
 	RecordPolicyViolationEvent(diagEvent engine.Event)
+	RecordStepProgressEvent(progressEvent engine.Event)
+	RecordPluginLifecycleEvent(lifecycleEvent engine.Event)
+	RecordConfirmationRequiredEvent(confirmationEvent engine.Event)
+	RecordStepDependenciesEvent(dependenciesEvent engine.Event)
 }
 
 // Implementation of a Row, used for the header of the grid.
@@ -261,6 +265,93 @@ func (data *resourceRowData) RecordPolicyViolationEvent(event engine.Event) {
 	data.recordDiagEventPayload(payload)
 }
 
+func (data *resourceRowData) RecordStepProgressEvent(event engine.Event) {
+	// As with policy violations, progress reports are converted to DiagEvents so they can be displayed. Progress is
+	// only interesting while the step is running, so the resulting diagnostic is ephemeral.
+	progress := event.Payload.(engine.StepProgressEventPayload)
+
+	message := progress.Message
+	if progress.Percent >= 0 {
+		message = strings.TrimSpace(fmt.Sprintf("%d%% %s", progress.Percent, message))
+	}
+
+	data.recordDiagEventPayload(engine.DiagEventPayload{
+		URN:       progress.Metadata.URN,
+		Message:   message,
+		Color:     colors.Raw,
+		Severity:  diag.Info,
+		Ephemeral: true,
+	})
+}
+
+func (data *resourceRowData) RecordPluginLifecycleEvent(event engine.Event) {
+	// Plugin restarts are reported as warnings so that they remain visible after the update completes.
+	data.recordDiagEventPayload(pluginLifecycleDiagEventPayload(event.Payload.(engine.PluginLifecycleEventPayload)))
+}
+
+// pluginLifecycleDiagEventPayload converts a plugin lifecycle event into a diagnostic that can be displayed.
+func pluginLifecycleDiagEventPayload(payload engine.PluginLifecycleEventPayload) engine.DiagEventPayload {
+	return engine.DiagEventPayload{
+		URN: payload.Metadata.URN,
+		Message: fmt.Sprintf("the %s provider exited unexpectedly and was restarted; retrying %s: %s\n",
+			payload.Plugin, payload.Metadata.Op, payload.Message),
+		Color:    colors.Raw,
+		Severity: diag.Warning,
+	}
+}
+
+func (data *resourceRowData) RecordConfirmationRequiredEvent(event engine.Event) {
+	// The request for confirmation is only interesting until it is answered, so it is displayed ephemerally.
+	data.recordDiagEventPayload(
+		confirmationRequiredDiagEventPayload(event.Payload.(engine.ConfirmationRequiredEventPayload)))
+}
+
+// confirmationRequiredDiagEventPayload converts a request for confirmation into a diagnostic that can be displayed.
+func confirmationRequiredDiagEventPayload(payload engine.ConfirmationRequiredEventPayload) engine.DiagEventPayload {
+	return engine.DiagEventPayload{
+		URN:       payload.Metadata.URN,
+		Message:   fmt.Sprintf("waiting for confirmation (%s %s)\n", payload.Metadata.Op, payload.Metadata.URN.Name()),
+		Color:     colors.Raw,
+		Severity:  diag.Info,
+		Ephemeral: true,
+	}
+}
+
+func (data *resourceRowData) RecordStepDependenciesEvent(event engine.Event) {
+	data.recordDiagEventPayload(stepDependenciesDiagEventPayload(event.Payload.(engine.StepDependenciesEventPayload)))
+}
+
+// stepDependenciesDiagEventPayload converts an explanation of a step's predecessors into a diagnostic that can be
+// displayed.
+func stepDependenciesDiagEventPayload(payload engine.StepDependenciesEventPayload) engine.DiagEventPayload {
+	var msg strings.Builder
+	for _, d := range payload.Dependencies {
+		var reason string
+		switch d.Reason {
+		case engine.StepDependencyParent:
+			reason = "parent"
+		case engine.StepDependencyProvider:
+			reason = "provider"
+		case engine.StepDependencyProperty:
+			quoted := make([]string, len(d.Properties))
+			for i, k := range d.Properties {
+				quoted[i] = fmt.Sprintf("'%s'", k)
+			}
+			reason = fmt.Sprintf("referenced by %s %s", english.PluralWord(len(quoted), "input", ""),
+				strings.Join(quoted, ", "))
+		default:
+			reason = "explicit dependency"
+		}
+		fprintfIgnoreError(&msg, "waiting on %s (%s)\n", d.URN, reason)
+	}
+	return engine.DiagEventPayload{
+		URN:      payload.Metadata.URN,
+		Message:  msg.String(),
+		Color:    colors.Raw,
+		Severity: diag.Info,
+	}
+}
+
 type column int
 
 const (
@@ -341,7 +432,8 @@ func (data *resourceRowData) ColorizedColumns() []string {
 
 func (data *resourceRowData) getInfoColumn() string {
 	step := data.step
-	if step.Op == deploy.OpCreateReplacement || step.Op == deploy.OpDeleteReplaced {
+	if step.Op == deploy.OpCreateReplacement || step.Op == deploy.OpValidateReplacement ||
+		step.Op == deploy.OpDeleteReplaced {
 		// if we're doing a replacement, see if we can find a replace step that contains useful
 		// information to display.
 		for _, outputStep := range data.outputSteps {
@@ -428,8 +520,8 @@ func (data *resourceRowData) getDiffInfo(step engine.StepEventMetadata) string {
 			diff = step.Old.Inputs.Diff(step.New.Inputs)
 		}
 
-		// Show a diff if either `provider` or `protect` changed; they might not show a diff via inputs or outputs, but
-		// it is still useful to show that these changed in output.
+		// Show a diff if `provider`, `protect`, or `customTimeouts` changed; they might not show a diff via inputs or
+		// outputs, but it is still useful to show that these changed in output.
 		recordMetadataDiff := func(name string, old, new resource.PropertyValue) {
 			if old != new {
 				if diff == nil {
@@ -449,41 +541,33 @@ func (data *resourceRowData) getDiffInfo(step engine.StepEventMetadata) string {
 			resource.NewStringProperty(step.Old.Provider), resource.NewStringProperty(step.New.Provider))
 		recordMetadataDiff("protect",
 			resource.NewBoolProperty(step.Old.Protect), resource.NewBoolProperty(step.New.Protect))
+		recordMetadataDiff("customTimeouts",
+			resource.NewStringProperty(step.Old.CustomTimeouts.String()),
+			resource.NewStringProperty(step.New.CustomTimeouts.String()))
 
 		if diff != nil {
 			writeString(changesBuf, "diff: ")
 
-			updates := make(resource.PropertyMap)
-			for k := range diff.Updates {
-				updates[k] = resource.PropertyValue{}
-			}
-
-			filteredKeys := func(m resource.PropertyMap) []string {
-				keys := make([]string, 0, len(m))
-				for k := range m {
-					keys = append(keys, string(k))
-				}
-				return keys
-			}
+			// Show changes to object-valued properties (e.g. tags) key by key, but only for the top-level properties
+			// that the provider reported as different, if it reported any.
+			var includeSet map[resource.PropertyKey]bool
 			if include := step.Diffs; include != nil {
-				includeSet := make(map[resource.PropertyKey]bool)
+				includeSet = make(map[resource.PropertyKey]bool)
 				for _, k := range include {
 					includeSet[k] = true
 				}
-				filteredKeys = func(m resource.PropertyMap) []string {
-					var filteredKeys []string
-					for k := range m {
-						if includeSet[k] {
-							filteredKeys = append(filteredKeys, string(k))
-						}
-					}
-					return filteredKeys
+			}
+
+			paths := make(map[resource.PropertyChangeKind][]string)
+			for _, change := range diff.Changes() {
+				if includeSet == nil || includeSet[change.Path[0]] {
+					paths[change.Kind] = append(paths[change.Kind], change.PathString())
 				}
 			}
 
-			writePropertyKeys(changesBuf, filteredKeys(diff.Adds), deploy.OpCreate)
-			writePropertyKeys(changesBuf, filteredKeys(diff.Deletes), deploy.OpDelete)
-			writePropertyKeys(changesBuf, filteredKeys(updates), deploy.OpUpdate)
+			writePropertyKeys(changesBuf, paths[resource.PropertyAdded], deploy.OpCreate)
+			writePropertyKeys(changesBuf, paths[resource.PropertyDeleted], deploy.OpDelete)
+			writePropertyKeys(changesBuf, paths[resource.PropertyUpdated], deploy.OpUpdate)
 		}
 	}
 
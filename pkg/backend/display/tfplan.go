@@ -0,0 +1,190 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// terraformPlanFormatVersion is the version of the Terraform JSON plan format that we emit.
+const terraformPlanFormatVersion = "0.1"
+
+// terraformPlan is a JSON-serializable subset of the Terraform plan representation (as produced by `terraform show
+// -json`). Only the pieces consumed by common plan-analysis tools (OPA policies, cost estimators, etc.) are populated.
+type terraformPlan struct {
+	// FormatVersion is the version of the plan format.
+	FormatVersion string `json:"format_version"`
+	// ResourceChanges contains an entry for each resource that the preview intends to operate upon.
+	ResourceChanges []terraformResourceChange `json:"resource_changes"`
+}
+
+// terraformResourceChange describes the change the plan intends to make to a single resource.
+type terraformResourceChange struct {
+	// Address is the absolute address of the resource. We use the resource's URN.
+	Address string `json:"address"`
+	// Mode is "managed" for resources whose lifecycle is owned by Pulumi and "data" for resources that are only read.
+	Mode string `json:"mode"`
+	// Type is the resource's type token.
+	Type string `json:"type"`
+	// Name is the resource's name.
+	Name string `json:"name"`
+	// ProviderName is the name of the package that provides this resource.
+	ProviderName string `json:"provider_name"`
+	// Change describes the change itself.
+	Change terraformChange `json:"change"`
+}
+
+// terraformChange describes the actions and the before/after values for a single resource change.
+type terraformChange struct {
+	// Actions is the list of actions that will be taken, in order (e.g. ["delete", "create"] for a replacement).
+	Actions []string `json:"actions"`
+	// Before is the value of the resource before the change, or nil if the resource is being created.
+	Before map[string]interface{} `json:"before"`
+	// After is the value of the resource after the change, or nil if the resource is being deleted. Values that are
+	// unknown until the change is applied are omitted from this map and recorded in AfterUnknown instead.
+	After map[string]interface{} `json:"after"`
+	// AfterUnknown records the top-level properties of After whose values are not known until apply time.
+	AfterUnknown map[string]bool `json:"after_unknown,omitempty"`
+}
+
+// terraformActions maps a step operation to the equivalent list of Terraform plan actions. The second return value is
+// false for operations that have no Terraform equivalent and should be omitted from the plan.
+func terraformActions(op deploy.StepOp, deleteBeforeReplace bool) ([]string, bool) {
+	switch op {
+	case deploy.OpSame:
+		return []string{"no-op"}, true
+	case deploy.OpCreate:
+		return []string{"create"}, true
+	case deploy.OpUpdate:
+		return []string{"update"}, true
+	case deploy.OpDelete, deploy.OpReadDiscard, deploy.OpPrune:
+		return []string{"delete"}, true
+	case deploy.OpReplace, deploy.OpReadReplacement:
+		if deleteBeforeReplace {
+			return []string{"delete", "create"}, true
+		}
+		return []string{"create", "delete"}, true
+	case deploy.OpRead, deploy.OpImport:
+		return []string{"read"}, true
+	default:
+		// The physical halves of a replacement (create-replacement, delete-replaced, etc.) are already represented
+		// by the logical replace step, and refreshes have no Terraform equivalent.
+		return nil, false
+	}
+}
+
+// terraformValues converts a property map into the plain JSON values used by a Terraform plan. Secrets are blinded
+// and unknown values are omitted from the result and reported in the returned set of unknown keys instead.
+func terraformValues(props resource.PropertyMap) (map[string]interface{}, map[string]bool) {
+	values := make(map[string]interface{})
+	unknowns := make(map[string]bool)
+	for k, v := range MassageSecrets(props, false) {
+		if v.ContainsUnknowns() {
+			unknowns[string(k)] = true
+			continue
+		}
+		values[string(k)] = v.Mappable()
+	}
+	return values, unknowns
+}
+
+// newTerraformResourceChange converts the metadata for a single step into a Terraform resource change. The second
+// return value is false if the step should not be included in the plan.
+func newTerraformResourceChange(m engine.StepEventMetadata) (terraformResourceChange, bool) {
+	// Providers are configuration, not resources, as far as Terraform is concerned.
+	if providers.IsProviderType(m.Type) {
+		return terraformResourceChange{}, false
+	}
+
+	// A replacement is "delete before replace" if the old resource is marked for deletion at the time of the step.
+	deleteBeforeReplace := m.Op == deploy.OpReplace && m.Old != nil && m.Old.State.PendingReplacement
+
+	// A pruned resource that is only reported is left unchanged.
+	op := m.Op
+	if op == deploy.OpPrune && m.New != nil {
+		op = deploy.OpSame
+	}
+	actions, ok := terraformActions(op, deleteBeforeReplace)
+	if !ok {
+		return terraformResourceChange{}, false
+	}
+
+	mode := "managed"
+	if m.Op == deploy.OpRead || (m.Res != nil && m.Res.State.External) {
+		mode = "data"
+	}
+
+	change := terraformResourceChange{
+		Address:      string(m.URN),
+		Mode:         mode,
+		Type:         string(m.Type),
+		Name:         string(m.URN.Name()),
+		ProviderName: string(m.Type.Package()),
+		Change:       terraformChange{Actions: actions},
+	}
+	if m.Old != nil && m.Op != deploy.OpCreate {
+		change.Change.Before, _ = terraformValues(m.Old.State.Outputs)
+	}
+	if m.New != nil && m.Op != deploy.OpDelete && m.Op != deploy.OpReadDiscard {
+		after, unknowns := terraformValues(m.New.State.Inputs)
+		change.Change.After = after
+		if len(unknowns) > 0 {
+			change.Change.AfterUnknown = unknowns
+		}
+	}
+	return change, true
+}
+
+// ShowTerraformPlanEvents renders engine events from a preview into a JSON document that follows the structure of a
+// Terraform plan. This allows tools that understand Terraform plans to consume Pulumi previews. Like ShowJSONEvents,
+// this does not emit events incrementally.
+func ShowTerraformPlanEvents(events <-chan engine.Event, done chan<- bool, opts Options) {
+	// Ensure we close the done channel before exiting.
+	defer func() { close(done) }()
+
+	plan := terraformPlan{
+		FormatVersion:   terraformPlanFormatVersion,
+		ResourceChanges: []terraformResourceChange{},
+	}
+	for e := range events {
+		// In the event of cancelation, break out of the loop immediately.
+		if e.Type == engine.CancelEvent {
+			break
+		}
+
+		// Only the pre-step events carry the information we need; everything else is ignored.
+		if e.Type != engine.ResourcePreEvent {
+			continue
+		}
+		m := e.Payload.(engine.ResourcePreEventPayload).Metadata
+		if !shouldShow(m, opts) || isRootStack(m) {
+			continue
+		}
+		if change, ok := newTerraformResourceChange(m); ok {
+			plan.ResourceChanges = append(plan.ResourceChanges, change)
+		}
+	}
+
+	out, err := json.MarshalIndent(&plan, "", "    ")
+	contract.Assertf(err == nil, "unexpected JSON error: %v", err)
+	fmt.Println(string(out))
+}
@@ -0,0 +1,85 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestTerraformActions(t *testing.T) {
+	cases := []struct {
+		op       deploy.StepOp
+		dbr      bool
+		expected []string
+	}{
+		{deploy.OpSame, false, []string{"no-op"}},
+		{deploy.OpCreate, false, []string{"create"}},
+		{deploy.OpUpdate, false, []string{"update"}},
+		{deploy.OpDelete, false, []string{"delete"}},
+		{deploy.OpReplace, false, []string{"create", "delete"}},
+		{deploy.OpReplace, true, []string{"delete", "create"}},
+		{deploy.OpRead, false, []string{"read"}},
+	}
+	for _, c := range cases {
+		actions, ok := terraformActions(c.op, c.dbr)
+		assert.True(t, ok)
+		assert.Equal(t, c.expected, actions)
+	}
+
+	for _, op := range []deploy.StepOp{deploy.OpCreateReplacement, deploy.OpDeleteReplaced, deploy.OpRefresh} {
+		_, ok := terraformActions(op, false)
+		assert.False(t, ok)
+	}
+}
+
+func TestTerraformResourceChange(t *testing.T) {
+	typ := tokens.Type("pkgA:m:typA")
+	urn := resource.NewURN("stack", "proj", "", typ, "resA")
+
+	old := resource.NewState(typ, urn, true, false, "id", resource.PropertyMap{},
+		resource.PropertyMap{
+			"foo":    resource.NewStringProperty("bar"),
+			"secret": resource.MakeSecret(resource.NewStringProperty("shh")),
+		}, "", false, false, nil, nil, "", nil, false, nil, nil)
+	new := resource.NewState(typ, urn, true, false, "", resource.PropertyMap{
+		"foo": resource.NewStringProperty("baz"),
+		"id":  resource.MakeComputed(resource.NewStringProperty("")),
+	}, nil, "", false, false, nil, nil, "", nil, false, nil, nil)
+
+	change, ok := newTerraformResourceChange(engine.StepEventMetadata{
+		Op:   deploy.OpUpdate,
+		URN:  urn,
+		Type: typ,
+		Old:  &engine.StepEventStateMetadata{State: old},
+		New:  &engine.StepEventStateMetadata{State: new},
+		Res:  &engine.StepEventStateMetadata{State: new},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, string(urn), change.Address)
+	assert.Equal(t, "managed", change.Mode)
+	assert.Equal(t, "resA", change.Name)
+	assert.Equal(t, "pkgA", change.ProviderName)
+	assert.Equal(t, []string{"update"}, change.Change.Actions)
+	assert.Equal(t, map[string]interface{}{"foo": "bar", "secret": "[secret]"}, change.Change.Before)
+	assert.Equal(t, map[string]interface{}{"foo": "baz"}, change.Change.After)
+	assert.Equal(t, map[string]bool{"id": true}, change.Change.AfterUnknown)
+}
@@ -0,0 +1,108 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// StateLockReader is implemented by backends that can report which update, if any, holds the lock on a stack's state.
+type StateLockReader interface {
+	// ReadStateLock returns the lease held on the given stack's state, or nil if the stack is not locked.
+	ReadStateLock(ctx context.Context, stackRef StackReference) (*StateLease, error)
+}
+
+// DoctorChecks returns diagnostic checks for engine.Doctor that the engine cannot perform itself: that the given
+// backend is reachable, that its credentials are valid, and, if stackRef is non-nil, whether the stack's state is
+// locked by another update.
+func DoctorChecks(ctx context.Context, b Backend, stackRef StackReference) []engine.DoctorCheck {
+	checks := []engine.DoctorCheck{
+		func() []engine.DoctorFinding { return checkBackend(ctx, b) },
+	}
+	if stackRef != nil {
+		checks = append(checks, func() []engine.DoctorFinding { return checkStateLock(ctx, b, stackRef) })
+	}
+	return checks
+}
+
+// checkBackend checks that the backend can be reached, and that it accepts the current credentials, by listing the
+// current user's stacks.
+func checkBackend(ctx context.Context, b Backend) []engine.DoctorFinding {
+	if _, err := b.ListStacks(ctx, nil); err != nil {
+		if errResp, ok := err.(*apitype.ErrorResponse); ok &&
+			(errResp.Code == http.StatusUnauthorized || errResp.Code == http.StatusForbidden) {
+			return []engine.DoctorFinding{{Check: "credentials", Status: engine.DoctorError,
+				Message:     fmt.Sprintf("%s rejected the current credentials: %v", b.URL(), err),
+				Remediation: fmt.Sprintf("run `pulumi login %s` to log in again", b.URL())}}
+		}
+		return []engine.DoctorFinding{{Check: "backend", Status: engine.DoctorError,
+			Message: fmt.Sprintf("could not reach %s: %v", b.URL(), err),
+			Remediation: "check your network connection and proxy settings, and that the backend URL is correct " +
+				"(see `pulumi whoami`)"}}
+	}
+
+	user, err := b.CurrentUser()
+	if err != nil {
+		return []engine.DoctorFinding{
+			{Check: "backend", Status: engine.DoctorOK, Message: fmt.Sprintf("reached %s", b.URL())},
+			{Check: "credentials", Status: engine.DoctorError,
+				Message:     fmt.Sprintf("could not identify the current user: %v", err),
+				Remediation: fmt.Sprintf("run `pulumi login %s` to log in again", b.URL())},
+		}
+	}
+	return []engine.DoctorFinding{
+		{Check: "backend", Status: engine.DoctorOK, Message: fmt.Sprintf("reached %s", b.URL())},
+		{Check: "credentials", Status: engine.DoctorOK, Message: fmt.Sprintf("logged in as %s", user)},
+	}
+}
+
+// checkStateLock reports whether another update holds the lock on the given stack's state.
+func checkStateLock(ctx context.Context, b Backend, stackRef StackReference) []engine.DoctorFinding {
+	const check = "state lock"
+
+	reader, ok := b.(StateLockReader)
+	if !ok {
+		return []engine.DoctorFinding{{Check: check, Status: engine.DoctorOK,
+			Message: fmt.Sprintf("%s does not report state locks", b.URL())}}
+	}
+	lease, err := reader.ReadStateLock(ctx, stackRef)
+	switch {
+	case err != nil:
+		return []engine.DoctorFinding{{Check: check, Status: engine.DoctorWarning,
+			Message: fmt.Sprintf("could not read the state lock of stack %s: %v", stackRef, err)}}
+	case lease == nil:
+		return []engine.DoctorFinding{{Check: check, Status: engine.DoctorOK,
+			Message: fmt.Sprintf("stack %s is not locked", stackRef)}}
+	}
+
+	locked := StateLockedError{Lease: *lease, Age: time.Since(lease.Renewed)}
+	if locked.Stale() {
+		return []engine.DoctorFinding{{Check: check, Status: engine.DoctorWarning,
+			Message: fmt.Sprintf("stack %s is locked by %s update %s, which has not renewed its lock for %s",
+				stackRef, lease.Kind, lease.ID, locked.Age.Round(time.Second)),
+			Remediation: "the update has most likely stopped; pass --takeover-stale-lock to the next update"}}
+	}
+	return []engine.DoctorFinding{{Check: check, Status: engine.DoctorWarning,
+		Message: fmt.Sprintf("stack %s is locked by %s update %s, which is still running", stackRef, lease.Kind,
+			lease.ID),
+		Remediation: "wait for the update to finish; if you are certain that it has stopped, pass --break-lock to " +
+			"the next update"}}
+}
@@ -0,0 +1,61 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"os"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// EventLogEnvVar names a file to which the events of each update are appended as newline-delimited JSON. Unlike a
+// replay bundle, the log accumulates the events of every update that runs, and is rotated as it grows. See
+// engine.EventLog.
+const EventLogEnvVar = "PULUMI_EVENT_LOG"
+
+// EventLogOptions are the options with which the event log named by PULUMI_EVENT_LOG is opened.
+var EventLogOptions = engine.EventLogOptions{
+	MaxSize:            64 * 1024 * 1024,
+	MaxFiles:           8,
+	MaxEventsPerSecond: 1000,
+}
+
+// OpenEventLog opens the event log named by PULUMI_EVENT_LOG, or returns nil if there is none.
+func OpenEventLog() (*engine.EventLog, error) {
+	path := os.Getenv(EventLogEnvVar)
+	if path == "" {
+		return nil, nil
+	}
+	return engine.NewEventLog(path, EventLogOptions)
+}
+
+// LogEvents subscribes the given event log to the events of the given mux. It returns a channel that is closed once
+// every event has been logged, which is after the mux is closed. It does nothing if the log is nil.
+func LogEvents(mux *engine.EventMux, log *engine.EventLog) <-chan bool {
+	done := make(chan bool)
+	if log == nil {
+		close(done)
+		return done
+	}
+
+	sub := mux.Subscribe(nil)
+	go func() {
+		for e := range sub.Events() {
+			log.Record(e)
+		}
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,39 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// ForwardEvents subscribes the given channel to the events of the given mux, so that a caller of an update can see its
+// events too. It returns a channel that is closed once every event has been forwarded, which is after the mux is
+// closed. It does nothing if the channel is nil.
+func ForwardEvents(mux *engine.EventMux, events chan<- engine.Event) <-chan bool {
+	done := make(chan bool)
+	if events == nil {
+		close(done)
+		return done
+	}
+
+	sub := mux.Subscribe(nil)
+	go func() {
+		for e := range sub.Events() {
+			events <- e
+		}
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,61 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestate
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// localArtifactStore stores the contents of a stack's artifacts in the backend's bucket, one file per digest.
+type localArtifactStore struct {
+	name    tokens.QName
+	backend *localBackend
+}
+
+var _ deploy.ArtifactStore = (*localArtifactStore)(nil)
+
+func (s *localArtifactStore) path(digest string) string {
+	return filepath.Join(s.backend.artifactDirectory(s.name), digest)
+}
+
+func (s *localArtifactStore) PutArtifact(digest string, contents []byte) error {
+	return s.backend.bucket.WriteAll(context.TODO(), s.path(digest), contents, nil)
+}
+
+func (s *localArtifactStore) GetArtifact(digest string) ([]byte, error) {
+	contents, err := s.backend.bucket.ReadAll(context.TODO(), s.path(digest))
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return nil, errors.Errorf("no artifact with digest %s is stored for stack %s", digest, s.name)
+	}
+	return contents, err
+}
+
+var _ backend.ArtifactBackend = (*localBackend)(nil)
+
+func (b *localBackend) ArtifactStore(stackRef backend.StackReference) deploy.ArtifactStore {
+	return b.newArtifactStore(stackRef.Name())
+}
+
+func (b *localBackend) newArtifactStore(stackName tokens.QName) *localArtifactStore {
+	return &localArtifactStore{name: stackName, backend: b}
+}
@@ -23,9 +23,12 @@ import (
 	"os/user"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"gocloud.dev/blob"
 	_ "gocloud.dev/blob/azureblob" // driver for azblob://
@@ -38,14 +41,15 @@ import (
 	"github.com/pulumi/pulumi/pkg/backend/display"
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/diag/colors"
-	"github.com/pulumi/pulumi/pkg/encoding"
 	"github.com/pulumi/pulumi/pkg/engine"
 	"github.com/pulumi/pulumi/pkg/operations"
 	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/edit"
 	"github.com/pulumi/pulumi/pkg/resource/stack"
+	"github.com/pulumi/pulumi/pkg/secrets/envelope"
 	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
 	"github.com/pulumi/pulumi/pkg/util/result"
@@ -60,9 +64,16 @@ type Backend interface {
 }
 
 type localBackend struct {
-	d      diag.Sink
-	url    string
-	bucket Bucket
+	d             diag.Sink
+	url           string
+	bucket        Bucket
+	checkpoints   stack.CheckpointEncoding // the encoding in which checkpoints are written.
+	sealer        *envelope.Sealer         // encrypts and decrypts checkpoints, or nil if they are not encrypted.
+	sharded       bool                     // true if checkpoints are written in shards.
+	allowModified bool                     // true if checkpoints modified outside of Pulumi may be loaded.
+
+	shardLock    sync.Mutex                    // guards shardWriters.
+	shardWriters map[tokens.QName]*shardWriter // the writers of the stacks' sharded checkpoints.
 }
 
 type localBackendReference struct {
@@ -88,7 +99,17 @@ func IsFileStateBackendURL(urlstr string) bool {
 
 const FilePathPrefix = "file://"
 
-func New(d diag.Sink, u string) (Backend, error) {
+// Options configures a local backend.
+type Options struct {
+	// AllowModifiedCheckpoints, if true, loads checkpoints whose contents no longer match the hash recorded when they
+	// were written, i.e. checkpoints that were edited outside of Pulumi.
+	AllowModifiedCheckpoints bool
+	// CheckpointSealer, if set, encrypts the checkpoints that the backend writes and decrypts those that it reads. If
+	// it is nil, checkpoints are written in plaintext, and encrypted checkpoints cannot be read.
+	CheckpointSealer *envelope.Sealer
+}
+
+func New(d diag.Sink, u string, opts Options) (Backend, error) {
 	if !IsFileStateBackendURL(u) {
 		return nil, errors.Errorf("local URL %s has an illegal prefix; expected one of: %s",
 			u, strings.Join(blob.DefaultURLMux().BucketSchemes(), ", "))
@@ -99,6 +120,11 @@ func New(d diag.Sink, u string) (Backend, error) {
 		return nil, err
 	}
 
+	checkpoints, err := stack.ParseCheckpointEncoding(os.Getenv(CheckpointFormatEnvVar))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", CheckpointFormatEnvVar)
+	}
+
 	bucket, err := blob.OpenBucket(context.TODO(), u)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to open bucket %s", u)
@@ -120,9 +146,13 @@ func New(d diag.Sink, u string) (Backend, error) {
 	}
 
 	return &localBackend{
-		d:      d,
-		url:    u,
-		bucket: &wrappedBucket{bucket: bucket},
+		d:             d,
+		url:           u,
+		bucket:        &wrappedBucket{bucket: bucket},
+		checkpoints:   checkpoints,
+		sealer:        opts.CheckpointSealer,
+		sharded:       cmdutil.IsTruthy(os.Getenv(ShardedCheckpointsEnvVar)),
+		allowModified: opts.AllowModifiedCheckpoints,
 	}, nil
 }
 
@@ -171,8 +201,8 @@ func massageBlobPath(path string) (string, error) {
 	return FilePathPrefix + path, nil
 }
 
-func Login(d diag.Sink, url string) (Backend, error) {
-	be, err := New(d, url)
+func Login(d diag.Sink, url string, opts Options) (Backend, error) {
+	be, err := New(d, url, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -194,10 +224,44 @@ func (b *localBackend) URL() string {
 	return b.url
 }
 
+func (b *localBackend) Capabilities() backend.Capabilities {
+	return backend.Capabilities{
+		History: true,
+		Locking: true,
+	}
+}
+
 func (b *localBackend) StateDir() string {
 	return workspace.BookkeepingDir
 }
 
+// localRoot returns the directory that holds the backend's bucket if the bucket is a directory on the local machine.
+func (b *localBackend) localRoot() (string, bool) {
+	if !strings.HasPrefix(b.url, FilePathPrefix) {
+		return "", false
+	}
+	root := strings.TrimPrefix(b.url, FilePathPrefix)
+	if runtime.GOOS == "windows" {
+		// massageBlobPath gives Windows paths a leading "/" before the drive letter.
+		root = strings.TrimPrefix(root, "/")
+	}
+	return filepath.FromSlash(root), true
+}
+
+// preflightOptions returns the options with which the engine checks that there is room for the given stack's
+// checkpoints before it updates the stack. Only buckets on the local machine are checked.
+func (b *localBackend) preflightOptions(stackName tokens.QName) engine.PreflightOptions {
+	root, ok := b.localRoot()
+	if !ok {
+		return engine.PreflightOptions{}
+	}
+	opts := engine.PreflightOptions{StatePaths: []string{root}}
+	if info, err := os.Stat(filepath.Join(root, b.stackPath(stackName))); err == nil {
+		opts.CheckpointSize = uint64(info.Size())
+	}
+	return opts
+}
+
 func (b *localBackend) ParseStackReference(stackRefName string) (backend.StackReference, error) {
 	return localBackendReference{name: tokens.QName(stackRefName)}, nil
 }
@@ -308,15 +372,31 @@ func (b *localBackend) RenameStack(ctx context.Context, stackRef backend.StackRe
 		return err
 	}
 
-	// To remove the old stack, just make a backup of the file and don't write out anything new.
-	file := b.stackPath(stackName)
-	backupTarget(b.bucket, file)
+	// To remove the old stack, just make a backup of its checkpoint and don't write out anything new.
+	if err = b.retireCheckpoint(stackName); err != nil {
+		return err
+	}
+
+	// Carry the stack's freeze over, if it has one.
+	if exists, existsErr := b.bucket.Exists(ctx, b.freezePath(stackName)); existsErr == nil && exists {
+		if err = renameObject(b.bucket, b.freezePath(stackName), b.freezePath(newName)); err != nil {
+			return err
+		}
+	}
 
 	// And move the history over as well.
 	oldHistoryDir := b.historyDirectory(stackName)
 	newHistoryDir := b.historyDirectory(newName)
+	if err = os.Rename(oldHistoryDir, newHistoryDir); err != nil {
+		return err
+	}
 
-	return os.Rename(oldHistoryDir, newHistoryDir)
+	// And the artifacts, if the stack has any.
+	err = os.Rename(b.artifactDirectory(stackName), b.artifactDirectory(newName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
 }
 
 func (b *localBackend) GetLatestConfiguration(ctx context.Context,
@@ -409,74 +489,168 @@ func (b *localBackend) apply(
 		return nil, result.FromError(err)
 	}
 
-	// Spawn a display loop to show events on the CLI.
-	displayEvents := make(chan engine.Event)
-	displayDone := make(chan bool)
-	go display.ShowEvents(
-		strings.ToLower(actionLabel), kind, stackName, op.Proj.Name,
-		displayEvents, displayDone, op.Opts.Display, opts.DryRun)
+	// The engine refuses to change a frozen stack, so look up the stack's freeze before the update begins.
+	var freeze *engine.StackFreeze
+	if !opts.DryRun {
+		if freeze, err = b.GetStackFreeze(ctx, stackRef); err != nil {
+			return nil, result.FromError(errors.Wrap(err, "reading stack freeze"))
+		}
+	}
 
-	// Create a separate event channel for engine events that we'll pipe to both listening streams.
-	engineEvents := make(chan engine.Event)
+	// Updates that write the stack's state hold a lease on it for as long as they run, so that two updates never write
+	// the same checkpoint at once.
+	var lease *backend.StateLeaseHolder
+	if !opts.DryRun {
+		user, userErr := b.CurrentUser()
+		contract.IgnoreError(userErr) // the user is for information only.
+		var lockErr error
+		lease, lockErr = backend.AcquireStateLease(b.newSnapshotPersister(stackName, op.SecretsManager), kind, user,
+			op.Opts.TakeoverStaleLock, op.Opts.BreakLock)
+		if lockErr != nil {
+			return nil, result.FromError(lockErr)
+		}
+		defer func() {
+			if releaseErr := lease.Release(); releaseErr != nil {
+				b.d.Warningf(diag.Message("" /*urn*/, "state lock: %v"), releaseErr)
+			}
+		}()
+	}
 
-	scope := op.Scopes.NewScope(engineEvents, opts.DryRun)
-	eventsDone := make(chan bool)
-	go func() {
-		// Pull in all events from the engine and send them to the two listeners.
-		for e := range engineEvents {
-			displayEvents <- e
+	// If a state mirror has been configured, open it before any work begins.
+	var mirror *backend.SnapshotMirror
+	if mirrorURL := os.Getenv(StateMirrorEnvVar); mirrorURL != "" {
+		if mirror, err = NewSnapshotMirror(b.d, mirrorURL, stackName, op.SecretsManager); err != nil {
+			return nil, result.FromError(err)
+		}
+	}
 
-			// If the caller also wants to see the events, stream them there also.
-			if events != nil {
-				events <- e
+	// Likewise the audit log, if one has been configured. Previews apply no steps, so they are never audited.
+	var auditLog *engine.AuditLog
+	var auditPrincipal string
+	if kind != apitype.PreviewUpdate && !opts.DryRun {
+		if auditLog, auditPrincipal, err = backend.OpenAuditLog(b); err != nil {
+			if mirror != nil {
+				contract.IgnoreError(mirror.Close())
 			}
+			return nil, result.FromError(err)
 		}
+	}
+
+	// Record the update's events for replay, if a replay bundle has been requested.
+	recorder := backend.NewReplayRecorder(kind, stackName, op.Proj.Name, opts.DryRun)
+
+	// Append the update's events to the event log, if one has been configured. The log is only a record of the
+	// events, so an update proceeds without it if it cannot be opened.
+	eventLog, err := backend.OpenEventLog()
+	if err != nil {
+		b.d.Warningf(diag.Message("" /*urn*/, "event log: %v"), err)
+	}
+
+	// Create a mux for the engine's events, and subscribe each of their consumers to it.
+	mux := engine.NewEventMux()
+	scope := op.Scopes.NewScope(mux.Events(), opts.DryRun)
 
-		close(eventsDone)
-	}()
+	// If another update breaks this update's lease, cancel this update so that it stops writing the stack's state.
+	cancelCtx := scope.Context()
+	if lease != nil {
+		var stopWatching func()
+		cancelCtx, stopWatching = lease.CancelOnLoss(cancelCtx)
+		defer stopWatching()
+	}
+
+	// Spawn a display loop to show events on the CLI.
+	displayEvents := mux.Subscribe(nil)
+	displayDone := make(chan bool)
+	go display.ShowEvents(
+		strings.ToLower(actionLabel), kind, stackName, op.Proj.Name,
+		displayEvents.Events(), displayDone, op.Opts.Display, opts.DryRun)
+
+	// Record the events and stream them to the caller, if either wants to see them.
+	recordDone := backend.RecordEvents(mux, recorder)
+	logDone := backend.LogEvents(mux, eventLog)
+	eventsDone := backend.ForwardEvents(mux, events)
 
 	// Create the management machinery.
 	persister := b.newSnapshotPersister(stackName, op.SecretsManager)
-	manager := backend.NewSnapshotManager(persister, update.GetTarget().Snapshot)
+	manager := backend.NewSnapshotManager(ctx, persister, update.GetTarget().Snapshot)
+	if mirror != nil {
+		manager.SetMirror(mirror)
+	}
 	engineCtx := &engine.Context{
-		Cancel:          scope.Context(),
-		Events:          engineEvents,
+		Cancel:          cancelCtx,
+		Events:          mux.Events(),
 		SnapshotManager: manager,
 		BackendClient:   backend.NewBackendClient(b),
+		AuditLog:        auditLog,
+		AuditPrincipal:  auditPrincipal,
+		Freeze:          freeze,
+		Artifacts:       b.newArtifactStore(stackName),
+	}
+	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+		engineCtx.ParentSpan = parentSpan.Context()
 	}
 
 	// Perform the update
+	engineOpts := op.Opts.Engine
+	if !engineOpts.Preflight.Skip {
+		engineOpts.Preflight = b.preflightOptions(stackName)
+	}
 	start := time.Now().Unix()
-	var changes engine.ResourceChanges
+	var updateResult *engine.UpdateResult
 	var updateRes result.Result
 	switch kind {
 	case apitype.PreviewUpdate:
-		changes, updateRes = engine.Update(update, engineCtx, op.Opts.Engine, true)
+		updateResult, updateRes = engine.Update(update, engineCtx, engineOpts, true)
 	case apitype.UpdateUpdate:
-		changes, updateRes = engine.Update(update, engineCtx, op.Opts.Engine, opts.DryRun)
+		updateResult, updateRes = engine.Update(update, engineCtx, engineOpts, opts.DryRun)
 	case apitype.RefreshUpdate:
-		changes, updateRes = engine.Refresh(update, engineCtx, op.Opts.Engine, opts.DryRun)
+		updateResult, updateRes = engine.Refresh(update, engineCtx, engineOpts, opts.DryRun)
 	case apitype.DestroyUpdate:
-		changes, updateRes = engine.Destroy(update, engineCtx, op.Opts.Engine, opts.DryRun)
+		updateResult, updateRes = engine.Destroy(update, engineCtx, engineOpts, opts.DryRun)
 	default:
 		contract.Failf("Unrecognized update kind: %s", kind)
 	}
+	var changes engine.ResourceChanges
+	if updateResult != nil {
+		changes = updateResult.Changes
+	}
 	end := time.Now().Unix()
 
 	// Wait for the display to finish showing all the events.
 	<-displayDone
+	displayEvents.Unsubscribe()
 	scope.Close() // Don't take any cancellations anymore, we're shutting down.
-	close(engineEvents)
+	mux.Close()
 	contract.IgnoreClose(manager)
+	if auditLog != nil {
+		contract.IgnoreClose(auditLog)
+	}
+	if mirror != nil {
+		if err = mirror.Close(); err != nil {
+			b.d.Warningf(diag.Message("" /*urn*/, "state mirror: %v"), err)
+		}
+	}
 
-	// Make sure the goroutine writing to displayEvents and events has exited before proceeding.
+	// Make sure every event has been recorded, logged, and streamed to the caller before proceeding.
+	<-recordDone
+	<-logDone
 	<-eventsDone
-	close(displayEvents)
+	if err = backend.SaveReplayBundle(recorder); err != nil {
+		b.d.Warningf(diag.Message("" /*urn*/, "replay bundle: %v"), err)
+	}
+	if eventLog != nil {
+		if err = eventLog.Close(); err != nil {
+			b.d.Warningf(diag.Message("" /*urn*/, "event log: %v"), err)
+		}
+	}
 
 	// Save update results.
 	backendUpdateResult := backend.SucceededResult
-	if updateRes != nil {
+	switch {
+	case updateRes != nil:
 		backendUpdateResult = backend.FailedResult
+	case updateResult.ChecksFailed():
+		backendUpdateResult = backend.SucceededWithFailingChecksResult
 	}
 	info := backend.UpdateInfo{
 		Kind:        kind,
@@ -543,7 +717,7 @@ func (b *localBackend) query(ctx context.Context, stack backend.Stack, op backen
 
 	// TODO: Consider implementing this for local backend. We left it out for the initial cut
 	// because we weren't sure we wanted to commit to it.
-	return result.Error("Local backend does not support querying over the state")
+	return result.FromError(backend.UnsupportedError{Backend: b.Name(), Feature: "querying over the state"})
 }
 
 func (b *localBackend) GetHistory(ctx context.Context, stackRef backend.StackReference) ([]backend.UpdateInfo, error) {
@@ -559,7 +733,7 @@ func (b *localBackend) GetLogs(ctx context.Context, stackRef backend.StackRefere
 	query operations.LogQuery) ([]operations.LogEntry, error) {
 
 	stackName := stackRef.Name()
-	target, err := b.getTarget(stackName, cfg.Config, cfg.Decrypter)
+	target, err := b.getTarget(stackName, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -572,7 +746,7 @@ func GetLogsForTarget(target *deploy.Target, query operations.LogQuery) ([]opera
 	contract.Assert(target != nil)
 	contract.Assert(target.Snapshot != nil)
 
-	config, err := target.Config.Decrypt(target.Decrypter)
+	config, err := target.LayeredConfig().Decrypt(target.Decrypter)
 	if err != nil {
 		return nil, err
 	}
@@ -656,6 +830,7 @@ func (b *localBackend) getLocalStacks() ([]tokens.QName, error) {
 		return nil, errors.Wrap(err, "error listing stacks")
 	}
 
+	seen := make(map[tokens.QName]bool)
 	for _, file := range files {
 		// Ignore directories.
 		if file.IsDir {
@@ -665,12 +840,18 @@ func (b *localBackend) getLocalStacks() ([]tokens.QName, error) {
 		// Skip files without valid extensions (e.g., *.bak files).
 		stackfn := objectName(file)
 		ext := filepath.Ext(stackfn)
-		if _, has := encoding.Marshalers[ext]; !has {
+		if _, has := stack.CheckpointEncodingForExt(ext); !has && ext != shardedCheckpointExt {
 			continue
 		}
 
-		// Read in this stack's information.
+		// A stack whose checkpoint is being converted to another encoding may briefly have checkpoints in both.
 		name := tokens.QName(stackfn[:len(stackfn)-len(ext)])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		// Read in this stack's information.
 		_, _, err := b.getStack(name)
 		if err != nil {
 			logging.V(5).Infof("error reading stack: %v (%v) skipping", name, err)
@@ -688,7 +869,7 @@ func (b *localBackend) GetStackTags(ctx context.Context,
 	stackRef backend.StackReference) (map[apitype.StackTagName]string, error) {
 
 	// The local backend does not currently persist tags.
-	return nil, errors.New("stack tags not supported in --local mode")
+	return nil, backend.UnsupportedError{Backend: b.Name(), Feature: "stack tags"}
 }
 
 // UpdateStackTags updates the stacks's tags, replacing all existing tags.
@@ -696,5 +877,5 @@ func (b *localBackend) UpdateStackTags(ctx context.Context,
 	stackRef backend.StackReference, tags map[apitype.StackTagName]string) error {
 
 	// The local backend does not currently persist tags.
-	return errors.New("stack tags not supported in --local mode")
+	return backend.UnsupportedError{Backend: b.Name(), Feature: "stack tags"}
 }
@@ -16,10 +16,13 @@ import (
 type Bucket interface {
 	Copy(ctx context.Context, dstKey, srcKey string, opts *blob.CopyOptions) (err error)
 	Delete(ctx context.Context, key string) (err error)
+	Exists(ctx context.Context, key string) (bool, error)
 	List(opts *blob.ListOptions) *blob.ListIterator
 	SignedURL(ctx context.Context, key string, opts *blob.SignedURLOptions) (string, error)
 	ReadAll(ctx context.Context, key string) (_ []byte, err error)
 	WriteAll(ctx context.Context, key string, p []byte, opts *blob.WriterOptions) (err error)
+	NewReader(ctx context.Context, key string, opts *blob.ReaderOptions) (_ *blob.Reader, err error)
+	NewWriter(ctx context.Context, key string, opts *blob.WriterOptions) (_ *blob.Writer, err error)
 }
 
 // wrappedBucket encapsulates a true gocloud blob.Bucket, but ensures that all paths we send to it
@@ -38,6 +41,10 @@ func (b *wrappedBucket) Delete(ctx context.Context, key string) (err error) {
 	return b.bucket.Delete(ctx, filepath.ToSlash(key))
 }
 
+func (b *wrappedBucket) Exists(ctx context.Context, key string) (bool, error) {
+	return b.bucket.Exists(ctx, filepath.ToSlash(key))
+}
+
 func (b *wrappedBucket) List(opts *blob.ListOptions) *blob.ListIterator {
 	return b.bucket.List(opts)
 }
@@ -54,6 +61,16 @@ func (b *wrappedBucket) WriteAll(ctx context.Context, key string, p []byte, opts
 	return b.bucket.WriteAll(ctx, filepath.ToSlash(key), p, opts)
 }
 
+func (b *wrappedBucket) NewReader(ctx context.Context, key string,
+	opts *blob.ReaderOptions) (_ *blob.Reader, err error) {
+	return b.bucket.NewReader(ctx, filepath.ToSlash(key), opts)
+}
+
+func (b *wrappedBucket) NewWriter(ctx context.Context, key string,
+	opts *blob.WriterOptions) (_ *blob.Writer, err error) {
+	return b.bucket.NewWriter(ctx, filepath.ToSlash(key), opts)
+}
+
 // listBucket returns a list of all files in the bucket within a given directory. go-cloud sorts the results by key
 func listBucket(bucket Bucket, dir string) ([]*blob.ListObject, error) {
 	bucketIter := bucket.List(&blob.ListOptions{
@@ -0,0 +1,70 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestate
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+var _ backend.StackFreezer = (*localBackend)(nil)
+
+// GetStackFreeze returns the freeze recorded for the given stack, or nil if it is not frozen.
+func (b *localBackend) GetStackFreeze(ctx context.Context,
+	stackRef backend.StackReference) (*engine.StackFreeze, error) {
+
+	bytes, err := b.bucket.ReadAll(ctx, b.freezePath(stackRef.Name()))
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var freeze engine.StackFreeze
+	if err = json.Unmarshal(bytes, &freeze); err != nil {
+		return nil, errors.Wrap(err, "decoding stack freeze")
+	}
+	return &freeze, nil
+}
+
+// SetStackFreeze records the given freeze for the given stack, or lifts its freeze if the given freeze is nil.
+func (b *localBackend) SetStackFreeze(ctx context.Context, stackRef backend.StackReference,
+	freeze *engine.StackFreeze) error {
+
+	if freeze == nil {
+		return b.deleteStackFreeze(stackRef.Name())
+	}
+	bytes, err := json.Marshal(freeze)
+	if err != nil {
+		return err
+	}
+	return b.bucket.WriteAll(ctx, b.freezePath(stackRef.Name()), bytes, nil)
+}
+
+// deleteStackFreeze removes the freeze recorded for the given stack, if any.
+func (b *localBackend) deleteStackFreeze(name tokens.QName) error {
+	err := b.bucket.Delete(context.TODO(), b.freezePath(name))
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return nil
+	}
+	return err
+}
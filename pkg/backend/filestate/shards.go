@@ -0,0 +1,421 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/stack"
+	"github.com/pulumi/pulumi/pkg/secrets"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/fsutil"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// ShardedCheckpointsEnvVar names an environment variable that, when truthy, stores checkpoints in shards: each resource
+// in a record of its own, and the rest of the checkpoint in a small manifest that lists the records in order. Records
+// are named by the hash of their contents and never change once written, so saving a checkpoint after a step rewrites
+// only the records of the resources that the step changed, and the manifest. Monolithic checkpoints are still read,
+// and are converted to shards the next time they are saved; likewise, sharded checkpoints are converted back the next
+// time they are saved once the variable is unset.
+const ShardedCheckpointsEnvVar = "PULUMI_SHARDED_CHECKPOINTS"
+
+// shardedCheckpointExt is the extension of the manifest of a sharded checkpoint.
+const shardedCheckpointExt = ".shards"
+
+// shardManifestVersion is the version of the manifests that this version of Pulumi writes.
+const shardManifestVersion = 1
+
+// shardReadParallelism is the number of records of a sharded checkpoint that are read at once.
+const shardReadParallelism = 16
+
+// shardManifest is the manifest of a sharded checkpoint.
+type shardManifest struct {
+	Version int          `json:"version"`
+	Stack   tokens.QName `json:"stack"`
+	// the latest deployment without its resources, or nil if there have been no deployments.
+	Latest *apitype.DeploymentV3 `json:"latest,omitempty"`
+	// the names of the records that hold the latest deployment's resources, in order.
+	Records []string `json:"records,omitempty"`
+}
+
+// shardWriter writes the sharded checkpoints of a single stack. It remembers the records that exist, so that each is
+// written only once, and deletes those that are no longer referenced by the checkpoint or its backup.
+type shardWriter struct {
+	lock sync.Mutex
+
+	existing map[string]bool   // the records known to exist.
+	backup   map[string]bool   // the records referenced by the backup of the checkpoint.
+	names    map[string]string // the names of the records of resources already written, by the hash of their plaintext.
+}
+
+// shardManifestPath returns the path of the manifest of the given stack's sharded checkpoint.
+func (b *localBackend) shardManifestPath(name tokens.QName) string {
+	return filepath.Join(b.StateDir(), workspace.StackDir, fsutil.QnamePath(name)+shardedCheckpointExt)
+}
+
+// shardDirectory returns the path of the directory that holds the records of the given stack's sharded checkpoint.
+func (b *localBackend) shardDirectory(stack tokens.QName) string {
+	contract.Require(stack != "", "stack")
+	return filepath.Join(b.StateDir(), workspace.ShardDir, fsutil.QnamePath(stack))
+}
+
+// shardRecordPath returns the path of the record with the given name.
+func (b *localBackend) shardRecordPath(stack tokens.QName, record string) string {
+	return path.Join(b.shardDirectory(stack), record+".json")
+}
+
+// isShardedCheckpoint returns true if the given path is that of the manifest of a sharded checkpoint.
+func isShardedCheckpoint(file string) bool {
+	return filepath.Ext(file) == shardedCheckpointExt
+}
+
+// readShardedCheckpoint reads the sharded checkpoint whose manifest is at the given path. Unless modified checkpoints
+// are allowed, each record is checked against the hash by which it is named.
+func (b *localBackend) readShardedCheckpoint(name tokens.QName, file string) (*apitype.CheckpointV3, int, error) {
+	manifest, n, err := b.readShardManifest(file)
+	if err != nil {
+		return nil, n, err
+	}
+
+	chk := &apitype.CheckpointV3{Stack: manifest.Stack, Latest: manifest.Latest}
+	if chk.Latest == nil {
+		return chk, n, nil
+	}
+
+	resources := make([]apitype.ResourceV3, len(manifest.Records))
+	sizes := make([]int, len(manifest.Records))
+	errs := make([]error, len(manifest.Records))
+	indices := make(chan int)
+	var workers sync.WaitGroup
+	for i := 0; i < shardReadParallelism; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indices {
+				sizes[index], errs[index] = b.readShardRecord(name, manifest.Records[index], &resources[index])
+			}
+		}()
+	}
+	for i := range manifest.Records {
+		indices <- i
+	}
+	close(indices)
+	workers.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, n, err
+		}
+		n += sizes[i]
+	}
+	chk.Latest.Resources = resources
+	return chk, n, nil
+}
+
+// readShardManifest reads and decodes the manifest at the given path, returning the number of bytes read.
+func (b *localBackend) readShardManifest(file string) (*shardManifest, int, error) {
+	data, err := b.bucket.ReadAll(context.TODO(), file)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := len(data)
+	if data, err = b.decryptCheckpoint(file, data); err != nil {
+		return nil, n, err
+	}
+
+	var manifest shardManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, n, errors.Wrapf(err, "decoding %s", file)
+	}
+	if manifest.Version > shardManifestVersion {
+		return nil, n, errors.Errorf("%s was written by a newer version of Pulumi", file)
+	}
+	return &manifest, n, nil
+}
+
+// readShardRecord reads and decodes the record with the given name into res, returning the number of bytes read.
+func (b *localBackend) readShardRecord(name tokens.QName, record string, res *apitype.ResourceV3) (int, error) {
+	file := b.shardRecordPath(name, record)
+	data, err := b.bucket.ReadAll(context.TODO(), file)
+	if err != nil {
+		return 0, errors.Wrapf(err, "reading %s", file)
+	}
+	n := len(data)
+	if data, err = b.decryptCheckpoint(file, data); err != nil {
+		return n, err
+	}
+
+	if !b.allowModified {
+		if actual := shardRecordName(data); actual != record {
+			err = &stack.CheckpointModifiedError{Expected: record, Actual: actual}
+			return n, errors.Wrapf(err, "%s (pass --allow-modified-state to load it anyway)", file)
+		}
+	}
+	if err = json.Unmarshal(data, res); err != nil {
+		return n, errors.Wrapf(err, "decoding %s", file)
+	}
+	return n, nil
+}
+
+// shardRecordName returns the name of the record with the given contents.
+func shardRecordName(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeShardedCheckpoint writes the sharded checkpoint for a snapshot, whose manifest is at the given path, returning
+// the number of bytes written. The records of resources that have not changed since they were last written are reused.
+// Once the manifest has been written, records that neither it nor the backup of the previous manifest reference are
+// deleted.
+func (b *localBackend) writeShardedCheckpoint(file string, name tokens.QName, snap *deploy.Snapshot,
+	sm secrets.Manager) (int, error) {
+
+	w, err := b.shardWriter(name, file)
+	if err != nil {
+		return 0, err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	manifest := shardManifest{Version: shardManifestVersion, Stack: name}
+	n := 0
+	if snap != nil {
+		// The deployment is serialized without its resources, each of which is serialized into a record of its own.
+		header := *snap
+		header.Resources = nil
+		if manifest.Latest, err = stack.SerializeDeployment(&header, sm); err != nil {
+			return 0, errors.Wrap(err, "serializing deployment")
+		}
+
+		if sm == nil {
+			sm = snap.SecretsManager
+		}
+		var enc config.Encrypter = config.NewPanicCrypter()
+		if sm != nil {
+			if enc, err = sm.Encrypter(); err != nil {
+				return 0, errors.Wrap(err, "getting encrypter for deployment")
+			}
+		}
+
+		// Records are only reused if their secrets were encrypted by the same secrets provider.
+		provider, err := json.Marshal(manifest.Latest.SecretsProviders)
+		if err != nil {
+			return 0, errors.Wrap(err, "serializing secrets provider")
+		}
+
+		for _, res := range snap.Resources {
+			record, written, err := b.writeShardRecord(w, name, res, enc, provider)
+			if err != nil {
+				return n, errors.Wrapf(err, "serializing %s", res.URN)
+			}
+			n += written
+			manifest.Records = append(manifest.Records, record)
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return n, errors.Wrap(err, "serializing checkpoint manifest")
+	}
+	written, err := b.writeShardObject(file, data)
+	n += written
+	if err != nil {
+		return n, err
+	}
+
+	b.sweepShardRecords(w, name, manifest.Records)
+	return n, nil
+}
+
+// writeShardRecord writes the record of the given resource, unless an identical record was already written with the
+// given secrets provider, and returns its name and the number of bytes written.
+func (b *localBackend) writeShardRecord(w *shardWriter, name tokens.QName, res *resource.State,
+	enc config.Encrypter, provider []byte) (string, int, error) {
+
+	// Secrets are encrypted afresh each time they are serialized, so a resource whose plaintext is unchanged would
+	// otherwise be written anew each time. The hash of its plaintext is only held in memory.
+	plain, err := stack.SerializeResource(res, config.NopEncrypter)
+	if err != nil {
+		return "", 0, err
+	}
+	plainData, err := json.Marshal(plain)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	_, err = fmt.Fprintf(h, "%s\n%s", provider, plainData)
+	contract.IgnoreError(err)
+	key := hex.EncodeToString(h.Sum(nil))
+	if record, ok := w.names[key]; ok && w.existing[record] {
+		return record, 0, nil
+	}
+
+	sres, err := stack.SerializeResource(res, enc)
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := json.Marshal(sres)
+	if err != nil {
+		return "", 0, err
+	}
+	record := shardRecordName(data)
+	n := 0
+	if !w.existing[record] {
+		if n, err = b.writeShardObject(b.shardRecordPath(name, record), data); err != nil {
+			return "", n, err
+		}
+		w.existing[record] = true
+	}
+	w.names[key] = record
+	return record, n, nil
+}
+
+// writeShardObject writes the given manifest or record to the given path, encrypting it if checkpoints are encrypted,
+// and returns the number of bytes written.
+func (b *localBackend) writeShardObject(file string, data []byte) (int, error) {
+	if b.sealer != nil {
+		sealed, err := b.sealer.Encrypt(context.TODO(), data)
+		if err != nil {
+			return 0, errors.Wrapf(err, "encrypting %s", file)
+		}
+		data = sealed
+	}
+	return len(data), b.bucket.WriteAll(context.TODO(), file, data, nil)
+}
+
+// sweepShardRecords deletes the records of the given stack that are referenced by neither the given records, which
+// are those of the manifest just written, nor the backup of the previous manifest. The records of the manifest just
+// written are those of the backup of the next one.
+func (b *localBackend) sweepShardRecords(w *shardWriter, name tokens.QName, records []string) {
+	keep := make(map[string]bool, len(records))
+	for _, record := range records {
+		keep[record] = true
+	}
+	for record := range w.existing {
+		if keep[record] || w.backup[record] {
+			continue
+		}
+		if err := b.bucket.Delete(context.TODO(), b.shardRecordPath(name, record)); err != nil &&
+			gcerrors.Code(err) != gcerrors.NotFound {
+			logging.V(5).Infof("failed to delete unreferenced record %s of stack %s: %v", record, name, err)
+			continue
+		}
+		delete(w.existing, record)
+	}
+	w.backup = keep
+}
+
+// shardWriter returns the writer of the given stack's sharded checkpoints, whose manifest is at the given path,
+// creating it if this is the first checkpoint that the backend writes for the stack. A new writer lists the records
+// that exist, and reads the backup of the manifest to find those that it references.
+func (b *localBackend) shardWriter(name tokens.QName, file string) (*shardWriter, error) {
+	b.shardLock.Lock()
+	defer b.shardLock.Unlock()
+
+	if w, ok := b.shardWriters[name]; ok {
+		return w, nil
+	}
+
+	w := &shardWriter{existing: make(map[string]bool), backup: make(map[string]bool), names: make(map[string]string)}
+	files, err := listBucket(b.bucket, b.shardDirectory(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if record := objectName(f); !f.IsDir && filepath.Ext(record) == ".json" {
+			w.existing[strings.TrimSuffix(record, ".json")] = true
+		}
+	}
+
+	bak := file + ".bak"
+	if exists, err := b.bucket.Exists(context.TODO(), bak); err == nil && exists {
+		manifest, _, err := b.readShardManifest(bak)
+		if err != nil {
+			// Without the backup's records, none can be swept safely.
+			logging.V(5).Infof("failed to read %s; unreferenced records will not be deleted: %v", bak, err)
+			for record := range w.existing {
+				w.backup[record] = true
+			}
+		} else {
+			for _, record := range manifest.Records {
+				w.backup[record] = true
+			}
+		}
+	}
+
+	if b.shardWriters == nil {
+		b.shardWriters = make(map[tokens.QName]*shardWriter)
+	}
+	b.shardWriters[name] = w
+	return w, nil
+}
+
+// shardedCheckpointBytes reads the sharded checkpoint whose manifest is at the given path, and returns it as a
+// monolithic JSON checkpoint, e.g. to be kept as a backup that does not depend on records that may later be deleted.
+func (b *localBackend) shardedCheckpointBytes(name tokens.QName, file string) ([]byte, error) {
+	chk, _, err := b.readShardedCheckpoint(name, file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(chk)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(apitype.VersionedCheckpoint{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Checkpoint: json.RawMessage(data),
+	}, "", "    ")
+}
+
+// retireShardedCheckpoint replaces the sharded checkpoint of the given stack, whose manifest is at the given path,
+// with a monolithic JSON backup of it, and deletes its manifest and records.
+func (b *localBackend) retireShardedCheckpoint(name tokens.QName, file string) error {
+	data, err := b.shardedCheckpointBytes(name, file)
+	if err != nil {
+		return err
+	}
+	bck := b.checkpointPath(name, stack.JSONCheckpoints) + ".bak"
+	if err = b.bucket.WriteAll(context.TODO(), bck, data, nil); err != nil {
+		return err
+	}
+	for _, f := range []string{file, file + ".bak"} {
+		if err = b.bucket.Delete(context.TODO(), f); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+			return err
+		}
+	}
+
+	b.shardLock.Lock()
+	delete(b.shardWriters, name)
+	b.shardLock.Unlock()
+	return removeAllByPrefix(b.bucket, b.shardDirectory(name))
+}
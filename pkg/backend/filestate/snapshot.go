@@ -15,11 +15,20 @@
 package filestate
 
 import (
+	"context"
+	"encoding/json"
 	"os"
+	"path/filepath"
 
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/secrets"
 	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
 )
 
 // localSnapshotManager is a simple SnapshotManager implementation that persists snapshots
@@ -35,16 +44,127 @@ func (sp *localSnapshotPersister) SecretsManager() secrets.Manager {
 }
 
 func (sp *localSnapshotPersister) Save(snapshot *deploy.Snapshot) error {
-	_, _, err := sp.backend.getStack(sp.name)
-	if err != nil && !os.IsNotExist(err) {
+	// Sharded checkpoints are not read back before each save, as that would read every record of the stack after
+	// each step.
+	if !sp.backend.sharded {
+		_, _, err := sp.backend.getStack(sp.name)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	_, err := sp.backend.saveStack(sp.name, snapshot, sp.sm)
+	return err
+
+}
+
+var _ backend.StateLocker = (*localSnapshotPersister)(nil)
+
+func (sp *localSnapshotPersister) ReadStateLease() (*backend.StateLease, error) {
+	bytes, err := sp.backend.bucket.ReadAll(context.TODO(), sp.backend.lockPath(sp.name))
+	if err != nil {
+		if gcerrors.Code(err) == gcerrors.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var lease backend.StateLease
+	if err = json.Unmarshal(bytes, &lease); err != nil {
+		return nil, errors.Wrap(err, "decoding state lock")
+	}
+	return &lease, nil
+}
+
+// CreateStateLease records the given lease if no lease is recorded. Leases in local directories are created exclusively,
+// so only one update can create them; other buckets offer no conditional writes, so the lease is written only after
+// finding that none exists, and two updates that race to create it may both succeed.
+func (sp *localSnapshotPersister) CreateStateLease(lease backend.StateLease) (bool, error) {
+	bytes, err := json.Marshal(lease)
+	if err != nil {
+		return false, err
+	}
+
+	if root, ok := sp.backend.localRoot(); ok {
+		path := filepath.Join(root, sp.backend.lockPath(sp.name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, err
+		}
+		f, openErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if openErr != nil {
+			if os.IsExist(openErr) {
+				return false, nil
+			}
+			return false, openErr
+		}
+		if _, err = f.Write(bytes); err != nil {
+			contract.IgnoreClose(f)
+			return false, err
+		}
+		return true, f.Close()
+	}
+
+	existing, err := sp.ReadStateLease()
+	if err != nil || existing != nil {
+		return false, err
+	}
+	return true, sp.backend.bucket.WriteAll(context.TODO(), sp.backend.lockPath(sp.name), bytes, nil)
+}
+
+func (sp *localSnapshotPersister) WriteStateLease(lease backend.StateLease) error {
+	bytes, err := json.Marshal(lease)
+	if err != nil {
 		return err
 	}
+	return sp.backend.bucket.WriteAll(context.TODO(), sp.backend.lockPath(sp.name), bytes, nil)
+}
 
-	_, err = sp.backend.saveStack(sp.name, snapshot, sp.sm)
+func (sp *localSnapshotPersister) DeleteStateLease() error {
+	err := sp.backend.bucket.Delete(context.TODO(), sp.backend.lockPath(sp.name))
+	if gcerrors.Code(err) == gcerrors.NotFound {
+		return nil
+	}
 	return err
+}
+
+var _ backend.StateLockReader = (*localBackend)(nil)
 
+func (b *localBackend) ReadStateLock(ctx context.Context,
+	stackRef backend.StackReference) (*backend.StateLease, error) {
+
+	return b.newSnapshotPersister(stackRef.Name(), nil).ReadStateLease()
 }
 
 func (b *localBackend) newSnapshotPersister(stackName tokens.QName, sm secrets.Manager) *localSnapshotPersister {
 	return &localSnapshotPersister{name: stackName, backend: b, sm: sm}
 }
+
+// StateMirrorEnvVar names an environment variable that, when set, holds the URL of a bucket or directory (in any form
+// accepted by `pulumi login`) to which every checkpoint written during an update is also copied.
+const StateMirrorEnvVar = "PULUMI_STATE_MIRROR_URL"
+
+// mirrorSnapshotPersister writes checkpoints to a secondary backend without consulting the stack it may already hold.
+type mirrorSnapshotPersister struct {
+	name    tokens.QName
+	backend *localBackend
+	sm      secrets.Manager
+}
+
+func (sp *mirrorSnapshotPersister) SecretsManager() secrets.Manager {
+	return sp.sm
+}
+
+func (sp *mirrorSnapshotPersister) Save(snapshot *deploy.Snapshot) error {
+	_, err := sp.backend.saveStack(sp.name, snapshot, sp.sm)
+	return err
+}
+
+// NewSnapshotMirror returns a mirror that copies the given stack's checkpoints to the backend at the given URL.
+func NewSnapshotMirror(d diag.Sink, url string, stackName tokens.QName,
+	sm secrets.Manager) (*backend.SnapshotMirror, error) {
+
+	be, err := New(d, url, Options{})
+	if err != nil {
+		return nil, errors.Wrap(err, "opening state mirror")
+	}
+	return backend.NewSnapshotMirror(&mirrorSnapshotPersister{name: stackName, backend: be.(*localBackend), sm: sm}), nil
+}
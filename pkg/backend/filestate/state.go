@@ -15,24 +15,30 @@
 package filestate
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
 
 	"github.com/pulumi/pulumi/pkg/apitype"
 	"github.com/pulumi/pulumi/pkg/backend"
-	"github.com/pulumi/pulumi/pkg/encoding"
-	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/backend/stats"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/stack"
 	"github.com/pulumi/pulumi/pkg/secrets"
+	"github.com/pulumi/pulumi/pkg/secrets/envelope"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 	"github.com/pulumi/pulumi/pkg/util/contract"
@@ -43,6 +49,21 @@ import (
 
 const DisableCheckpointBackupsEnvVar = "PULUMI_DISABLE_CHECKPOINT_BACKUPS"
 
+// CheckpointFormatEnvVar names an environment variable that selects the format in which checkpoints are written: json
+// (the default), msgpack, or protobuf. Checkpoints are read in whichever format they were written, and a stack's
+// checkpoint is converted to the selected format the next time it is saved.
+const CheckpointFormatEnvVar = "PULUMI_CHECKPOINT_FORMAT"
+
+// CheckpointKeyEnvVar names an environment variable that holds the URL of the key with which checkpoints are encrypted
+// (see envelope.OpenKeyProvider). If it is set, each checkpoint is encrypted in full with a data key wrapped by that
+// key; plaintext checkpoints are still read, and are encrypted the next time they are saved.
+const CheckpointKeyEnvVar = "PULUMI_CHECKPOINT_KEY"
+
+// CheckpointPreviousKeysEnvVar names an environment variable that holds a comma-separated list of the URLs of keys that
+// have been rotated out. Checkpoints encrypted with them are still read, and are re-encrypted with the current key the
+// next time they are saved.
+const CheckpointPreviousKeysEnvVar = "PULUMI_CHECKPOINT_PREVIOUS_KEYS"
+
 // DisableIntegrityChecking can be set to true to disable checkpoint state integrity verification.  This is not
 // recommended, because it could mean proceeding even in the face of a corrupted checkpoint state file, but can
 // be used as a last resort when a command absolutely must be run.
@@ -72,7 +93,7 @@ func (b *localBackend) newUpdate(stackName tokens.QName, op backend.UpdateOperat
 	contract.Require(stackName != "", "stackName")
 
 	// Construct the deployment target.
-	target, err := b.getTarget(stackName, op.StackConfiguration.Config, op.StackConfiguration.Decrypter)
+	target, err := b.getTarget(stackName, op.StackConfiguration)
 	if err != nil {
 		return nil, err
 	}
@@ -86,16 +107,17 @@ func (b *localBackend) newUpdate(stackName tokens.QName, op backend.UpdateOperat
 	}, nil
 }
 
-func (b *localBackend) getTarget(stackName tokens.QName, cfg config.Map, dec config.Decrypter) (*deploy.Target, error) {
+func (b *localBackend) getTarget(stackName tokens.QName, cfg backend.StackConfiguration) (*deploy.Target, error) {
 	snapshot, _, err := b.getStack(stackName)
 	if err != nil {
 		return nil, err
 	}
 	return &deploy.Target{
-		Name:      stackName,
-		Config:    cfg,
-		Decrypter: dec,
-		Snapshot:  snapshot,
+		Name:         stackName,
+		Config:       cfg.Config,
+		Decrypter:    cfg.Decrypter,
+		Snapshot:     snapshot,
+		ConfigLayers: cfg.Layers,
 	}, nil
 }
 
@@ -106,17 +128,11 @@ func (b *localBackend) getStack(name tokens.QName) (*deploy.Snapshot, string, er
 
 	file := b.stackPath(name)
 
-	chk, err := b.getCheckpoint(name)
+	snapshot, err := b.readSnapshot(name)
 	if err != nil {
 		return nil, file, errors.Wrap(err, "failed to load checkpoint")
 	}
 
-	// Materialize an actual snapshot object.
-	snapshot, err := stack.DeserializeCheckpoint(chk)
-	if err != nil {
-		return nil, "", err
-	}
-
 	// Ensure the snapshot passes verification before returning it, to catch bugs early.
 	if !DisableIntegrityChecking {
 		if verifyerr := snapshot.VerifyIntegrity(); verifyerr != nil {
@@ -128,49 +144,154 @@ func (b *localBackend) getStack(name tokens.QName) (*deploy.Snapshot, string, er
 	return snapshot, file, nil
 }
 
+// readSnapshot loads the snapshot for the given stack from its checkpoint file, in whichever encoding that was written.
+// JSON checkpoints are decoded one resource at a time, except that those written by older versions of Pulumi are read
+// in full instead.
+func (b *localBackend) readSnapshot(stackName tokens.QName) (*deploy.Snapshot, error) {
+	chkpath := b.stackPath(stackName)
+	start := time.Now()
+	if isShardedCheckpoint(chkpath) {
+		chk, n, err := b.readShardedCheckpoint(stackName, chkpath)
+		stats.Record("state read", time.Since(start), n)
+		if err != nil {
+			return nil, err
+		}
+		return stack.DeserializeCheckpoint(chk)
+	}
+
+	r, err := b.bucket.NewReader(context.TODO(), chkpath, nil)
+	if err != nil {
+		return nil, err
+	}
+	enc, ok := stack.CheckpointEncodingForExt(filepath.Ext(chkpath))
+	if !ok {
+		enc = stack.JSONCheckpoints
+	}
+
+	// Encrypted checkpoints are decrypted in full before they are decoded.
+	br := bufio.NewReader(r)
+	var in io.Reader = br
+	if magic, _ := br.Peek(envelope.MagicLength); envelope.IsEncrypted(magic) {
+		data, readErr := ioutil.ReadAll(br)
+		if readErr != nil {
+			contract.IgnoreClose(r)
+			return nil, readErr
+		}
+		plaintext, decryptErr := b.decryptCheckpoint(chkpath, data)
+		if decryptErr != nil {
+			contract.IgnoreClose(r)
+			return nil, decryptErr
+		}
+		in = bytes.NewReader(plaintext)
+	}
+	snapshot, err := enc.Decode(in, !b.allowModified)
+	stats.Record("state read", time.Since(start), int(r.Size()))
+	contract.IgnoreClose(r)
+
+	switch err.(type) {
+	case nil:
+		return snapshot, nil
+	case *stack.CheckpointModifiedError:
+		return nil, errors.Wrapf(err, "%s (pass --allow-modified-state to load it anyway)", chkpath)
+	}
+	if err != stack.ErrCheckpointNotStreamable {
+		return nil, err
+	}
+
+	chk, err := b.getCheckpoint(stackName)
+	if err != nil {
+		return nil, err
+	}
+	return stack.DeserializeCheckpoint(chk)
+}
+
 // GetCheckpoint loads a checkpoint file for the given stack in this project, from the current project workspace.
 func (b *localBackend) getCheckpoint(stackName tokens.QName) (*apitype.CheckpointV3, error) {
 	chkpath := b.stackPath(stackName)
+	start := time.Now()
+	if isShardedCheckpoint(chkpath) {
+		chk, n, err := b.readShardedCheckpoint(stackName, chkpath)
+		stats.Record("state read", time.Since(start), n)
+		return chk, err
+	}
+
 	bytes, err := b.bucket.ReadAll(context.TODO(), chkpath)
+	stats.Record("state read", time.Since(start), len(bytes))
 	if err != nil {
 		return nil, err
 	}
+	if bytes, err = b.decryptCheckpoint(chkpath, bytes); err != nil {
+		return nil, err
+	}
+
+	if !b.allowModified {
+		if err = stack.VerifyCheckpointIntegrity(bytes); err != nil {
+			if _, ok := err.(*stack.CheckpointModifiedError); ok {
+				return nil, errors.Wrapf(err, "%s (pass --allow-modified-state to load it anyway)", chkpath)
+			}
+			return nil, err
+		}
+	}
 
 	return stack.UnmarshalVersionedCheckpointToLatestCheckpoint(bytes)
 }
 
 func (b *localBackend) saveStack(name tokens.QName, snap *deploy.Snapshot, sm secrets.Manager) (string, error) {
-	// Make a serializable stack and then use the encoder to encode it.
-	file := b.stackPath(name)
-	m, ext := encoding.Detect(file)
-	if m == nil {
-		return "", errors.Errorf("resource serialization failed; illegal markup extension: '%v'", ext)
-	}
-	if filepath.Ext(file) == "" {
-		file = file + ext
-	}
-	chk, err := stack.SerializeCheckpoint(name, snap, sm)
-	if err != nil {
-		return "", errors.Wrap(err, "serializaing checkpoint")
-	}
-	byts, err := m.Marshal(chk)
-	if err != nil {
-		return "", errors.Wrap(err, "An IO error occurred during the current operation")
+	// Make a serializable stack and then use the backend's checkpoint encoding to encode it, or write it in shards.
+	file := b.checkpointPath(name, b.checkpoints)
+	if b.sharded {
+		file = b.shardManifestPath(name)
 	}
 
 	// Back up the existing file if it already exists.
 	bck := backupTarget(b.bucket, file)
 
 	// And now write out the new snapshot file, overwriting that location.
-	if err = b.bucket.WriteAll(context.TODO(), file, byts, nil); err != nil {
+	start := time.Now()
+	var n int
+	var err error
+	if b.sharded {
+		n, err = b.writeShardedCheckpoint(file, name, snap, sm)
+	} else {
+		n, err = b.writeCheckpoint(file, b.checkpoints, name, snap, sm)
+	}
+	stats.Record("state write", time.Since(start), n)
+	if err != nil {
 		return "", errors.Wrap(err, "An IO error occurred during the current operation")
 	}
 
+	// If the stack's checkpoint was last written in another encoding, or in shards, back that checkpoint up too, so
+	// that the one just written is the one that is read. Records of sharded checkpoints are kept for their backups.
+	others := []string{b.shardManifestPath(name)}
+	encs := b.checkpointEncodings()[1:]
+	if b.sharded {
+		others, encs = nil, b.checkpointEncodings()
+	}
+	for _, enc := range encs {
+		others = append(others, b.checkpointPath(name, enc))
+	}
+	for _, other := range others {
+		if exists, existsErr := b.bucket.Exists(context.TODO(), other); existsErr == nil && exists {
+			backupTarget(b.bucket, other)
+		}
+	}
+
 	logging.V(7).Infof("Saved stack %s checkpoint to: %s (backup=%s)", name, file, bck)
 
 	// And if we are retaining historical checkpoint information, write it out again
+	// And if we are retaining historical checkpoint information, write it out again. Sharded checkpoints are retained
+	// as monolithic JSON checkpoints, as their records are deleted once they are no longer referenced.
 	if cmdutil.IsTruthy(os.Getenv("PULUMI_RETAIN_CHECKPOINTS")) {
-		if err = b.bucket.WriteAll(context.TODO(), fmt.Sprintf("%v.%v", file, time.Now().UnixNano()), byts, nil); err != nil {
+		retained := fmt.Sprintf("%v.%v", file, time.Now().UnixNano())
+		if b.sharded {
+			var data []byte
+			if data, err = b.shardedCheckpointBytes(name, file); err == nil {
+				err = b.bucket.WriteAll(context.TODO(), retained+stack.JSONCheckpoints.Ext(), data, nil)
+			}
+		} else {
+			err = b.bucket.Copy(context.TODO(), retained, file, nil)
+		}
+		if err != nil {
 			return "", errors.Wrap(err, "An IO error occurred during the current operation")
 		}
 	}
@@ -189,16 +310,117 @@ func (b *localBackend) saveStack(name tokens.QName, snap *deploy.Snapshot, sm se
 	return file, nil
 }
 
+// writeCheckpoint writes the checkpoint for a snapshot to the given file in the given encoding, returning the number of
+// bytes written. JSON checkpoints are encoded one resource at a time as they are written.
+func (b *localBackend) writeCheckpoint(file string, enc stack.CheckpointEncoding, name tokens.QName,
+	snap *deploy.Snapshot, sm secrets.Manager) (int, error) {
+
+	// Canceling the context before closing the writer discards anything written so far, so that a failure partway
+	// through encoding never leaves a truncated checkpoint behind.
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	// Encrypted checkpoints are encoded in full before they are encrypted, always with the current key, which is how
+	// checkpoints encrypted with a key that has been rotated out are re-encrypted.
+	if b.sealer != nil {
+		var buf bytes.Buffer
+		if err := enc.Encode(&buf, name, snap, sm); err != nil {
+			return 0, errors.Wrap(err, "serializing checkpoint")
+		}
+		sealed, err := b.sealer.Encrypt(ctx, buf.Bytes())
+		if err != nil {
+			return 0, errors.Wrap(err, "encrypting checkpoint")
+		}
+		return len(sealed), b.bucket.WriteAll(ctx, file, sealed, nil)
+	}
+
+	w, err := b.bucket.NewWriter(ctx, file, nil)
+	if err != nil {
+		return 0, err
+	}
+	cw := &countingWriter{w: w}
+	if err = enc.Encode(cw, name, snap, sm); err != nil {
+		cancel()
+		contract.IgnoreClose(w)
+		return cw.n, errors.Wrap(err, "serializing checkpoint")
+	}
+	return cw.n, w.Close()
+}
+
+// decryptCheckpoint decrypts the given contents of the given checkpoint file, if they are encrypted.
+func (b *localBackend) decryptCheckpoint(file string, data []byte) ([]byte, error) {
+	if !envelope.IsEncrypted(data) {
+		return data, nil
+	}
+	if b.sealer == nil {
+		return nil, errors.Errorf("%s is encrypted; set %s to the URL of its key to read it", file, CheckpointKeyEnvVar)
+	}
+	plaintext, err := b.sealer.Decrypt(context.TODO(), data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypting %s", file)
+	}
+	return plaintext, nil
+}
+
+// OpenCheckpointSealer opens the sealer with which checkpoints are encrypted, as configured by CheckpointKeyEnvVar and
+// CheckpointPreviousKeysEnvVar, or returns nil if checkpoints are not encrypted. See Options.CheckpointSealer.
+func OpenCheckpointSealer() (*envelope.Sealer, error) {
+	current := os.Getenv(CheckpointKeyEnvVar)
+	var previous []string
+	for _, key := range strings.Split(os.Getenv(CheckpointPreviousKeysEnvVar), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			previous = append(previous, key)
+		}
+	}
+	if current == "" {
+		if len(previous) > 0 {
+			return nil, errors.Errorf("%s requires %s to be set", CheckpointPreviousKeysEnvVar, CheckpointKeyEnvVar)
+		}
+		return nil, nil
+	}
+	return envelope.OpenSealer(current, previous)
+}
+
+// countingWriter counts the bytes written through it, for reporting stats.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
 // removeStack removes information about a stack from the current workspace.
 func (b *localBackend) removeStack(name tokens.QName) error {
 	contract.Require(name != "", "name")
 
-	// Just make a backup of the file and don't write out anything new.
-	file := b.stackPath(name)
-	backupTarget(b.bucket, file)
+	// Just make a backup of the checkpoint and don't write out anything new.
+	if err := b.retireCheckpoint(name); err != nil {
+		return err
+	}
 
 	historyDir := b.historyDirectory(name)
-	return removeAllByPrefix(b.bucket, historyDir)
+	if err := removeAllByPrefix(b.bucket, historyDir); err != nil {
+		return err
+	}
+	if err := b.deleteStackFreeze(name); err != nil {
+		return err
+	}
+	return removeAllByPrefix(b.bucket, b.artifactDirectory(name))
+}
+
+// retireCheckpoint makes a backup of the given stack's checkpoint in place of the checkpoint itself. A sharded
+// checkpoint is backed up as a monolithic JSON checkpoint, and its manifest and records are deleted.
+func (b *localBackend) retireCheckpoint(name tokens.QName) error {
+	file := b.stackPath(name)
+	if isShardedCheckpoint(file) {
+		return b.retireShardedCheckpoint(name, file)
+	}
+	backupTarget(b.bucket, file)
+	return nil
 }
 
 // backupTarget makes a backup of an existing file, in preparation for writing a new one.  Instead of a copy, it
@@ -222,8 +444,7 @@ func (b *localBackend) backupStack(name tokens.QName) error {
 	}
 
 	// Read the current checkpoint file. (Assuming it aleady exists.)
-	stackPath := b.stackPath(name)
-	byts, err := b.bucket.ReadAll(context.TODO(), stackPath)
+	stackPath, byts, err := b.readCheckpointFile(name)
 	if err != nil {
 		return err
 	}
@@ -239,13 +460,70 @@ func (b *localBackend) backupStack(name tokens.QName) error {
 	return b.bucket.WriteAll(context.TODO(), filepath.Join(backupDir, backupFile), byts, nil)
 }
 
+// stackPath returns the path of the given stack's checkpoint: the path of its existing checkpoint, in whichever
+// encoding that was written, or else the path at which a checkpoint in this backend's encoding is written. If the stack
+// name is empty, the path of the directory that holds all stacks' checkpoints is returned.
 func (b *localBackend) stackPath(stack tokens.QName) string {
-	path := filepath.Join(b.StateDir(), workspace.StackDir)
-	if stack != "" {
-		path = filepath.Join(path, fsutil.QnamePath(stack)+".json")
+	if stack == "" {
+		return filepath.Join(b.StateDir(), workspace.StackDir)
 	}
 
-	return path
+	var paths []string
+	for _, enc := range b.checkpointEncodings() {
+		paths = append(paths, b.checkpointPath(stack, enc))
+	}
+	if b.sharded {
+		paths = append([]string{b.shardManifestPath(stack)}, paths...)
+	} else {
+		paths = append(paths, b.shardManifestPath(stack))
+	}
+	for _, path := range paths {
+		if exists, err := b.bucket.Exists(context.TODO(), path); err == nil && exists {
+			return path
+		}
+	}
+	return paths[0]
+}
+
+// readCheckpointFile reads the given stack's checkpoint file, returning its path and contents. A sharded checkpoint is
+// read as a monolithic JSON checkpoint, whose path is that of the stack's JSON checkpoint.
+func (b *localBackend) readCheckpointFile(name tokens.QName) (string, []byte, error) {
+	file := b.stackPath(name)
+	if isShardedCheckpoint(file) {
+		byts, err := b.shardedCheckpointBytes(name, file)
+		return b.checkpointPath(name, stack.JSONCheckpoints), byts, err
+	}
+	byts, err := b.bucket.ReadAll(context.TODO(), file)
+	return file, byts, err
+}
+
+// checkpointPath returns the path of the given stack's checkpoint in the given encoding.
+func (b *localBackend) checkpointPath(name tokens.QName, enc stack.CheckpointEncoding) string {
+	return filepath.Join(b.StateDir(), workspace.StackDir, fsutil.QnamePath(name)+enc.Ext())
+}
+
+// checkpointEncodings returns the encodings in which a stack's checkpoint may be stored, starting with the one in which
+// this backend writes checkpoints.
+func (b *localBackend) checkpointEncodings() []stack.CheckpointEncoding {
+	encs := []stack.CheckpointEncoding{b.checkpoints}
+	for _, enc := range stack.CheckpointEncodings {
+		if enc != b.checkpoints {
+			encs = append(encs, enc)
+		}
+	}
+	return encs
+}
+
+// lockPath returns the path of the file that records the lease on the given stack's state.
+func (b *localBackend) lockPath(stack tokens.QName) string {
+	contract.Require(stack != "", "stack")
+	return filepath.Join(b.StateDir(), workspace.LockDir, fsutil.QnamePath(stack)+".json")
+}
+
+// freezePath returns the path of the file that records the freeze on the given stack.
+func (b *localBackend) freezePath(stack tokens.QName) string {
+	contract.Require(stack != "", "stack")
+	return filepath.Join(b.StateDir(), workspace.FreezeDir, fsutil.QnamePath(stack)+".json")
 }
 
 func (b *localBackend) historyDirectory(stack tokens.QName) string {
@@ -253,6 +531,11 @@ func (b *localBackend) historyDirectory(stack tokens.QName) string {
 	return filepath.Join(b.StateDir(), workspace.HistoryDir, fsutil.QnamePath(stack))
 }
 
+func (b *localBackend) artifactDirectory(stack tokens.QName) string {
+	contract.Require(stack != "", "stack")
+	return filepath.Join(b.StateDir(), workspace.ArtifactDir, fsutil.QnamePath(stack))
+}
+
 func (b *localBackend) backupDirectory(stack tokens.QName) string {
 	contract.Require(stack != "", "stack")
 	return filepath.Join(b.StateDir(), workspace.BackupDir, fsutil.QnamePath(stack))
@@ -323,6 +606,65 @@ func (b *localBackend) addToHistory(name tokens.QName, update backend.UpdateInfo
 	}
 
 	// Make a copy of the checkpoint file. (Assuming it already exists.)
-	checkpointFile := fmt.Sprintf("%s.checkpoint.json", pathPrefix)
-	return b.bucket.Copy(context.TODO(), checkpointFile, b.stackPath(name), nil)
+	stackPath := b.stackPath(name)
+	if isShardedCheckpoint(stackPath) {
+		stackPath, byts, err = b.readCheckpointFile(name)
+		if err != nil {
+			return err
+		}
+		checkpointFile := fmt.Sprintf("%s.checkpoint%s", pathPrefix, filepath.Ext(stackPath))
+		return b.bucket.WriteAll(context.TODO(), checkpointFile, byts, nil)
+	}
+	checkpointFile := fmt.Sprintf("%s.checkpoint%s", pathPrefix, filepath.Ext(stackPath))
+	return b.bucket.Copy(context.TODO(), checkpointFile, stackPath, nil)
+}
+
+var _ backend.HistoryPruner = (*localBackend)(nil)
+
+// PruneHistory removes the entries of the given stack's update history that were recorded before the given time,
+// along with the checkpoints saved with them. The most recent entry is always kept.
+func (b *localBackend) PruneHistory(ctx context.Context, stackRef backend.StackReference,
+	before time.Time) (int, error) {
+
+	dir := b.historyDirectory(stackRef.Name())
+	allFiles, err := listBucket(b.bucket, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	// Each entry's files are named for the time at which it was recorded, so listBucket returns the oldest first.
+	var prefixes []string
+	for _, file := range allFiles {
+		if strings.HasSuffix(file.Key, ".history.json") {
+			prefixes = append(prefixes, strings.TrimSuffix(file.Key, ".history.json"))
+		}
+	}
+
+	pruned := 0
+	for i, prefix := range prefixes {
+		if i == len(prefixes)-1 {
+			break
+		}
+		recorded, err := strconv.ParseInt(prefix[strings.LastIndex(prefix, "-")+1:], 10, 64)
+		if err != nil {
+			logging.V(5).Infof("skipping history file with unexpected name: %s.history.json", prefix)
+			continue
+		}
+		if !time.Unix(0, recorded).Before(before) {
+			break
+		}
+
+		// Remove the checkpoint first, so that an entry is never left without its history file.
+		var files []string
+		for _, enc := range stack.CheckpointEncodings {
+			files = append(files, prefix+".checkpoint"+enc.Ext())
+		}
+		for _, file := range append(files, prefix+".history.json") {
+			if err := b.bucket.Delete(ctx, file); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+				return pruned, errors.Wrapf(err, "removing history file %s", file)
+			}
+		}
+		pruned++
+	}
+	return pruned, nil
 }
@@ -0,0 +1,62 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// StackFreezer is implemented by backends that can freeze stacks against changes. Freezes are stored by the backend
+// alongside the stack, so that they apply to everyone who works on it.
+type StackFreezer interface {
+	// GetStackFreeze returns the freeze recorded for the given stack, or nil if it is not frozen. The freeze may have
+	// expired.
+	GetStackFreeze(ctx context.Context, stackRef StackReference) (*engine.StackFreeze, error)
+	// SetStackFreeze records the given freeze for the given stack, replacing any existing freeze. A nil freeze lifts
+	// any existing freeze.
+	SetStackFreeze(ctx context.Context, stackRef StackReference, freeze *engine.StackFreeze) error
+}
+
+// GetStackFreeze returns the freeze recorded for the given stack, or nil if it is not frozen. It fails if the stack's
+// backend cannot freeze stacks.
+func GetStackFreeze(ctx context.Context, s Stack) (*engine.StackFreeze, error) {
+	freezer, ok := s.Backend().(StackFreezer)
+	if !ok {
+		return nil, UnsupportedError{Backend: s.Backend().Name(), Feature: "stack freezes"}
+	}
+	return freezer.GetStackFreeze(ctx, s.Ref())
+}
+
+// SetStackFreeze records the given freeze for the given stack, or lifts its freeze if the given freeze is nil. It
+// fails if the stack's backend cannot freeze stacks.
+func SetStackFreeze(ctx context.Context, s Stack, freeze *engine.StackFreeze) error {
+	freezer, ok := s.Backend().(StackFreezer)
+	if !ok {
+		return UnsupportedError{Backend: s.Backend().Name(), Feature: "stack freezes"}
+	}
+	return freezer.SetStackFreeze(ctx, s.Ref(), freeze)
+}
+
+// ReadStackFreeze returns the freeze recorded for the given stack by the given backend, so that it can be enforced by
+// the engine. It returns nil if the backend cannot freeze stacks.
+func ReadStackFreeze(ctx context.Context, b Backend, stackRef StackReference) (*engine.StackFreeze, error) {
+	freezer, ok := b.(StackFreezer)
+	if !ok {
+		return nil, nil
+	}
+	return freezer.GetStackFreeze(ctx, stackRef)
+}
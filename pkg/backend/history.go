@@ -0,0 +1,29 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// HistoryPruner is implemented by backends that can remove old entries from the update history of a stack, so that
+// the history does not grow without bound.
+type HistoryPruner interface {
+	// PruneHistory removes the entries of the given stack's update history that were recorded before the given time,
+	// along with any checkpoints saved with them. The most recent entry is always kept. It returns the number of
+	// entries removed.
+	PruneHistory(ctx context.Context, stackRef StackReference, before time.Time) (int, error)
+}
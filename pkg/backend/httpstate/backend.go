@@ -348,6 +348,17 @@ func (b *cloudBackend) URL() string {
 	return cloudConsoleURL(b.url, user)
 }
 
+func (b *cloudBackend) Capabilities() backend.Capabilities {
+	// The service can store policy packs, but this client does not yet publish or enforce them.
+	return backend.Capabilities{
+		History:        true,
+		Locking:        true,
+		EventStreaming: true,
+		Tags:           true,
+		Query:          true,
+	}
+}
+
 func (b *cloudBackend) CurrentUser() (string, error) {
 	return b.client.GetPulumiAccountName(context.Background())
 }
@@ -594,13 +605,13 @@ func (b *cloudBackend) Query(ctx context.Context, stackRef backend.StackReferenc
 
 func (b *cloudBackend) createAndStartUpdate(
 	ctx context.Context, action apitype.UpdateKind, stack backend.Stack,
-	op backend.UpdateOperation, dryRun bool) (client.UpdateIdentifier, int, string, error) {
+	op backend.UpdateOperation, dryRun bool) (client.UpdateIdentifier, apitype.StartUpdateResponse, error) {
 
 	stackRef := stack.Ref()
 
 	stackID, err := b.getCloudStackIdentifier(stackRef)
 	if err != nil {
-		return client.UpdateIdentifier{}, 0, "", err
+		return client.UpdateIdentifier{}, apitype.StartUpdateResponse{}, err
 	}
 	metadata := apitype.UpdateMetadata{
 		Message:     op.M.Message,
@@ -609,25 +620,25 @@ func (b *cloudBackend) createAndStartUpdate(
 	update, err := b.client.CreateUpdate(
 		ctx, action, stackID, op.Proj, op.StackConfiguration.Config, metadata, op.Opts.Engine, dryRun)
 	if err != nil {
-		return client.UpdateIdentifier{}, 0, "", err
+		return client.UpdateIdentifier{}, apitype.StartUpdateResponse{}, err
 	}
 
 	// Start the update. We use this opportunity to pass new tags to the service, to pick up any
 	// metadata changes.
 	tags, err := backend.GetMergedStackTags(ctx, stack)
 	if err != nil {
-		return client.UpdateIdentifier{}, 0, "", errors.Wrap(err, "getting stack tags")
+		return client.UpdateIdentifier{}, apitype.StartUpdateResponse{}, errors.Wrap(err, "getting stack tags")
 	}
-	version, token, err := b.client.StartUpdate(ctx, update, tags)
+	resp, err := b.startUpdateWithTakeover(ctx, update, tags, op.Opts.TakeoverStaleLock, op.Opts.BreakLock)
 	if err != nil {
-		return client.UpdateIdentifier{}, 0, "", err
+		return client.UpdateIdentifier{}, apitype.StartUpdateResponse{}, err
 	}
 	// Any non-preview update will be considered part of the stack's update history.
 	if action != apitype.PreviewUpdate {
-		logging.V(7).Infof("Stack %s being updated to version %d", stackRef, version)
+		logging.V(7).Infof("Stack %s being updated to version %d", stackRef, resp.Version)
 	}
 
-	return update, version, token, nil
+	return update, resp, nil
 }
 
 // apply actually performs the provided type of update on a stack hosted in the Pulumi Cloud.
@@ -645,10 +656,14 @@ func (b *cloudBackend) apply(
 	}
 
 	// Create an update object to persist results.
-	update, version, token, err := b.createAndStartUpdate(ctx, kind, stack, op, opts.DryRun)
+	update, started, err := b.createAndStartUpdate(ctx, kind, stack, op, opts.DryRun)
 	if err != nil {
 		return nil, result.FromError(err)
 	}
+	version, token := started.Version, started.Token
+
+	// The engine warns if this CLI is older than the version that the service recommends.
+	op.Opts.Engine.RecommendedVersion = started.RecommendedCLIVersion
 
 	if opts.ShowLink && !op.Opts.Display.JSONDisplay {
 		// Print a URL at the end of the update pointing to the Pulumi Service.
@@ -684,32 +699,22 @@ func (b *cloudBackend) query(
 		return result.FromError(err)
 	}
 
+	// The mux receives all events from the engine, which it then hands to each of their consumers for actual
+	// processing. (The display and callerEventsOpt.)
+	mux := engine.NewEventMux()
+
 	// Render query output to CLI.
-	displayEvents := make(chan engine.Event)
+	displayEvents := mux.Subscribe(nil)
 	displayDone := make(chan bool)
-	go display.ShowQueryEvents("running query", displayEvents, displayDone, op.Opts.Display)
-
-	// The engineEvents channel receives all events from the engine, which we then forward onto other
-	// channels for actual processing. (displayEvents and callerEventsOpt.)
-	engineEvents := make(chan engine.Event)
-	eventsDone := make(chan bool)
-	go func() {
-		for e := range engineEvents {
-			displayEvents <- e
-			if callerEventsOpt != nil {
-				callerEventsOpt <- e
-			}
-		}
-
-		close(eventsDone)
-	}()
+	go display.ShowQueryEvents("running query", displayEvents.Events(), displayDone, op.Opts.Display)
+	eventsDone := backend.ForwardEvents(mux, callerEventsOpt)
 
 	// Depending on the action, kick off the relevant engine activity.  Note that we don't immediately check and
 	// return error conditions, because we will do so below after waiting for the display channels to close.
-	cancellationScope := op.Scopes.NewScope(engineEvents, true /*dryRun*/)
+	cancellationScope := op.Scopes.NewScope(mux.Events(), true /*dryRun*/)
 	engineCtx := &engine.Context{
 		Cancel:        cancellationScope.Context(),
-		Events:        engineEvents,
+		Events:        mux.Events(),
 		BackendClient: httpstateBackendClient{backend: b},
 	}
 	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
@@ -718,15 +723,14 @@ func (b *cloudBackend) query(
 
 	res := engine.Query(engineCtx, q, op.Opts.Engine)
 
-	// Wait for dependent channels to finish processing engineEvents before closing.
+	// Wait for the display to finish processing the engine's events before closing the mux.
 	<-displayDone
+	displayEvents.Unsubscribe()
 	cancellationScope.Close() // Don't take any cancellations anymore, we're shutting down.
-	close(engineEvents)
+	mux.Close()
 
-	// Make sure that the goroutine writing to displayEvents and callerEventsOpt
-	// has exited before proceeding
+	// Make sure that every event has been forwarded to callerEventsOpt before proceeding.
 	<-eventsDone
-	close(displayEvents)
 
 	return res
 }
@@ -742,74 +746,133 @@ func (b *cloudBackend) runEngineAction(
 		return nil, result.FromError(err)
 	}
 
+	// The engine refuses to change a frozen stack, so look up the stack's freeze before the update begins.
+	var freeze *engine.StackFreeze
+	if kind != apitype.PreviewUpdate && !dryRun {
+		if freeze, err = b.GetStackFreeze(ctx, stackRef); err != nil {
+			return nil, result.FromError(errors.Wrap(err, "reading stack freeze"))
+		}
+	}
+
+	// If a state mirror has been configured, open it before any work begins.
+	var mirror *backend.SnapshotMirror
+	if mirrorURL := os.Getenv(filestate.StateMirrorEnvVar); mirrorURL != "" {
+		mirror, err = filestate.NewSnapshotMirror(b.d, mirrorURL, stackRef.Name(), op.SecretsManager)
+		if err != nil {
+			return nil, result.FromError(err)
+		}
+	}
+
+	// Likewise the audit log, if one has been configured. Previews apply no steps, so they are never audited.
+	var auditLog *engine.AuditLog
+	var auditPrincipal string
+	if kind != apitype.PreviewUpdate && !dryRun {
+		if auditLog, auditPrincipal, err = backend.OpenAuditLog(b); err != nil {
+			if mirror != nil {
+				contract.IgnoreError(mirror.Close())
+			}
+			return nil, result.FromError(err)
+		}
+	}
+
+	// Record the update's events for replay, if a replay bundle has been requested.
+	recorder := backend.NewReplayRecorder(kind, stackRef.Name(), op.Proj.Name, dryRun)
+
+	// Append the update's events to the event log, if one has been configured. The log is only a record of the
+	// events, so an update proceeds without it if it cannot be opened.
+	eventLog, err := backend.OpenEventLog()
+	if err != nil {
+		b.d.Warningf(diag.Message("" /*urn*/, "event log: %v"), err)
+	}
+
+	// The mux receives all events from the engine, which it then hands to each of their consumers for actual
+	// processing. (The display, the replay recorder, the event log, and callerEventsOpt.)
+	mux := engine.NewEventMux()
+
 	// displayEvents renders the event to the console and Pulumi service. The processor for the
 	// will signal all events have been proceed when a value is written to the displayDone channel.
-	displayEvents := make(chan engine.Event)
+	displayEvents := mux.Subscribe(nil)
 	displayDone := make(chan bool)
 	go u.RecordAndDisplayEvents(
 		backend.ActionLabel(kind, dryRun), kind, stackRef, op,
-		displayEvents, displayDone, op.Opts.Display, dryRun)
-
-	// The engineEvents channel receives all events from the engine, which we then forward onto other
-	// channels for actual processing. (displayEvents and callerEventsOpt.)
-	engineEvents := make(chan engine.Event)
-	eventsDone := make(chan bool)
-	go func() {
-		for e := range engineEvents {
-			displayEvents <- e
-			if callerEventsOpt != nil {
-				callerEventsOpt <- e
-			}
-		}
-
-		close(eventsDone)
-	}()
+		displayEvents.Events(), displayDone, op.Opts.Display, dryRun)
+	recordDone := backend.RecordEvents(mux, recorder)
+	logDone := backend.LogEvents(mux, eventLog)
+	eventsDone := backend.ForwardEvents(mux, callerEventsOpt)
 
 	// The backend.SnapshotManager and backend.SnapshotPersister will keep track of any changes to
 	// the Snapshot (checkpoint file) in the HTTP backend.
 	persister := b.newSnapshotPersister(ctx, u.update, u.tokenSource, op.SecretsManager)
-	snapshotManager := backend.NewSnapshotManager(persister, u.GetTarget().Snapshot)
+	snapshotManager := backend.NewSnapshotManager(ctx, persister, u.GetTarget().Snapshot)
+	if mirror != nil {
+		snapshotManager.SetMirror(mirror)
+	}
 
 	// Depending on the action, kick off the relevant engine activity.  Note that we don't immediately check and
 	// return error conditions, because we will do so below after waiting for the display channels to close.
-	cancellationScope := op.Scopes.NewScope(engineEvents, dryRun)
+	cancellationScope := op.Scopes.NewScope(mux.Events(), dryRun)
 	engineCtx := &engine.Context{
 		Cancel:          cancellationScope.Context(),
-		Events:          engineEvents,
+		Events:          mux.Events(),
 		SnapshotManager: snapshotManager,
 		BackendClient:   httpstateBackendClient{backend: b},
+		AuditLog:        auditLog,
+		AuditPrincipal:  auditPrincipal,
+		Freeze:          freeze,
 	}
 	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
 		engineCtx.ParentSpan = parentSpan.Context()
 	}
 
-	var changes engine.ResourceChanges
+	var updateResult *engine.UpdateResult
 	var res result.Result
 	switch kind {
 	case apitype.PreviewUpdate:
-		changes, res = engine.Update(u, engineCtx, op.Opts.Engine, true)
+		updateResult, res = engine.Update(u, engineCtx, op.Opts.Engine, true)
 	case apitype.UpdateUpdate:
-		changes, res = engine.Update(u, engineCtx, op.Opts.Engine, dryRun)
+		updateResult, res = engine.Update(u, engineCtx, op.Opts.Engine, dryRun)
 	case apitype.RefreshUpdate:
-		changes, res = engine.Refresh(u, engineCtx, op.Opts.Engine, dryRun)
+		updateResult, res = engine.Refresh(u, engineCtx, op.Opts.Engine, dryRun)
 	case apitype.DestroyUpdate:
-		changes, res = engine.Destroy(u, engineCtx, op.Opts.Engine, dryRun)
+		updateResult, res = engine.Destroy(u, engineCtx, op.Opts.Engine, dryRun)
 	default:
 		contract.Failf("Unrecognized update kind: %s", kind)
 	}
+	var changes engine.ResourceChanges
+	if updateResult != nil {
+		changes = updateResult.Changes
+	}
 
-	// Wait for dependent channels to finish processing engineEvents before closing.
+	// Wait for the display to finish processing the engine's events before closing the mux.
 	<-displayDone
+	displayEvents.Unsubscribe()
 	cancellationScope.Close() // Don't take any cancellations anymore, we're shutting down.
-	close(engineEvents)
+	mux.Close()
 	contract.IgnoreClose(snapshotManager)
+	if auditLog != nil {
+		contract.IgnoreClose(auditLog)
+	}
+	if mirror != nil {
+		if err = mirror.Close(); err != nil {
+			b.d.Warningf(diag.Message("" /*urn*/, "state mirror: %v"), err)
+		}
+	}
 
-	// Make sure that the goroutine writing to displayEvents and callerEventsOpt
-	// has exited before proceeding
+	// Make sure that every event has been recorded, logged, and forwarded to callerEventsOpt before proceeding.
+	<-recordDone
+	<-logDone
 	<-eventsDone
-	close(displayEvents)
+	if err = backend.SaveReplayBundle(recorder); err != nil {
+		b.d.Warningf(diag.Message("" /*urn*/, "replay bundle: %v"), err)
+	}
+	if eventLog != nil {
+		if err = eventLog.Close(); err != nil {
+			b.d.Warningf(diag.Message("" /*urn*/, "event log: %v"), err)
+		}
+	}
 
-	// Mark the update as complete.
+	// Mark the update as complete. The service has no status for updates whose checks failed, so such updates are
+	// recorded as having succeeded; the results of their checks are recorded by their events.
 	status := apitype.UpdateStatusSucceeded
 	if res != nil {
 		status = apitype.UpdateStatusFailed
@@ -938,7 +1001,7 @@ func (b *cloudBackend) GetLogs(ctx context.Context, stackRef backend.StackRefere
 		return nil, errors.New("stack not found")
 	}
 
-	target, targetErr := b.getTarget(ctx, stackRef, cfg.Config, cfg.Decrypter)
+	target, targetErr := b.getTarget(ctx, stackRef, cfg)
 	if targetErr != nil {
 		return nil, targetErr
 	}
@@ -1222,3 +1285,7 @@ func (c httpstateBackendClient) GetStackResourceOutputs(
 	ctx context.Context, name string) (resource.PropertyMap, error) {
 	return backend.NewBackendClient(c.backend).GetStackResourceOutputs(ctx, name)
 }
+
+func (c httpstateBackendClient) GetStackHistory(ctx context.Context, name string) ([]apitype.UpdateInfo, error) {
+	return backend.NewBackendClient(c.backend).GetStackHistory(ctx, name)
+}
@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/diag"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/backend/stats"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/httputil"
 	"github.com/pulumi/pulumi/pkg/util/logging"
@@ -272,6 +274,7 @@ func pulumiRESTCall(ctx context.Context, diag diag.Sink, cloudAPI, method, path
 	}
 
 	// Make API call
+	start := time.Now()
 	url, resp, err := pulumiAPICall(ctx, diag, cloudAPI, method, path+querystring, reqBody, tok, opts)
 	if err != nil {
 		return err
@@ -279,6 +282,7 @@ func pulumiRESTCall(ctx context.Context, diag diag.Sink, cloudAPI, method, path
 
 	// Read API response
 	respBody, err := readBody(resp)
+	stats.Record(method+" "+apiRoute(path), time.Since(start), len(reqBody)+len(respBody))
 	if err != nil {
 		return errors.Wrapf(err, "reading response from API")
 	}
@@ -295,6 +299,25 @@ func pulumiRESTCall(ctx context.Context, diag diag.Sink, cloudAPI, method, path
 	return nil
 }
 
+// apiRoute replaces the identifiers in an API path produced by getStackPath or getUpdatePath with placeholders, so that
+// calls to the same endpoint for different stacks and updates are counted together.
+func apiRoute(path string) string {
+	segments := strings.Split(path, "/")
+	if len(segments) < 6 || segments[1] != "api" || segments[2] != "stacks" {
+		return path
+	}
+	segments[3], segments[4], segments[5] = "{owner}", "{project}", "{stack}"
+
+	if len(segments) > 7 {
+		switch apitype.UpdateKind(segments[6]) {
+		case apitype.UpdateUpdate, apitype.PreviewUpdate, apitype.RefreshUpdate, apitype.DestroyUpdate,
+			apitype.ImportUpdate:
+			segments[7] = "{update}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
 // readBody reads the contents of an http.Response into a byte array, returning an error if one occurred while in the
 // process of doing so. readBody uses the Content-Encoding of the response to pick the correct reader to use.
 func readBody(resp *http.Response) ([]byte, error) {
@@ -393,14 +393,14 @@ func (pc *Client) RenameStack(ctx context.Context, stack StackIdentifier, newNam
 	return pc.restCall(ctx, "POST", getStackPath(stack, "rename"), nil, &req, &resp)
 }
 
-// StartUpdate starts the indicated update. It returns the new version of the update's target stack and the token used
-// to authenticate operations on the update if any. Replaces the stack's tags with the updated set.
+// StartUpdate starts the indicated update. Its response includes the new version of the update's target stack and the
+// token used to authenticate operations on the update if any. Replaces the stack's tags with the updated set.
 func (pc *Client) StartUpdate(ctx context.Context, update UpdateIdentifier,
-	tags map[apitype.StackTagName]string) (int, string, error) {
+	tags map[apitype.StackTagName]string) (apitype.StartUpdateResponse, error) {
 
 	// Validate names and tags.
 	if err := validation.ValidateStackProperties(update.StackIdentifier.Stack, tags); err != nil {
-		return 0, "", errors.Wrap(err, "validating stack properties")
+		return apitype.StartUpdateResponse{}, errors.Wrap(err, "validating stack properties")
 	}
 
 	req := apitype.StartUpdateRequest{
@@ -409,10 +409,10 @@ func (pc *Client) StartUpdate(ctx context.Context, update UpdateIdentifier,
 
 	var resp apitype.StartUpdateResponse
 	if err := pc.restCall(ctx, "POST", getUpdatePath(update), nil, req, &resp); err != nil {
-		return 0, "", err
+		return apitype.StartUpdateResponse{}, err
 	}
 
-	return resp.Version, resp.Token, nil
+	return resp, nil
 }
 
 // GetUpdateEvents returns all events, taking an optional continuation token from a previous call.
@@ -452,6 +452,24 @@ func (pc *Client) RenewUpdateLease(ctx context.Context, update UpdateIdentifier,
 	return resp.Token, nil
 }
 
+// GetStackUpdateLock returns information about the update that currently holds the indicated stack's update lock.
+func (pc *Client) GetStackUpdateLock(ctx context.Context, stack StackIdentifier) (apitype.UpdateLock, error) {
+	var lock apitype.UpdateLock
+	if err := pc.restCall(ctx, "GET", getStackPath(stack, "lock"), nil, nil, &lock); err != nil {
+		return apitype.UpdateLock{}, err
+	}
+	return lock, nil
+}
+
+// TakeoverStackUpdateLock releases the indicated stack's update lock if it is still held by the given update. This
+// is used to recover from updates whose lease has gone stale (e.g. because the CLI that started them crashed).
+func (pc *Client) TakeoverStackUpdateLock(ctx context.Context, stack StackIdentifier, updateID string) error {
+	req := apitype.TakeoverUpdateLockRequest{
+		UpdateID: updateID,
+	}
+	return pc.restCall(ctx, "POST", getStackPath(stack, "lock", "takeover"), nil, req, nil)
+}
+
 // InvalidateUpdateCheckpoint invalidates the checkpoint for the indicated update.
 func (pc *Client) InvalidateUpdateCheckpoint(ctx context.Context, update UpdateIdentifier, token string) error {
 	req := apitype.PatchUpdateCheckpointRequest{
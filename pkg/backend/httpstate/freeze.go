@@ -0,0 +1,92 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpstate
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/util/validation"
+)
+
+var _ backend.StackFreezer = (*cloudBackend)(nil)
+
+// GetStackFreeze returns the freeze recorded for the given stack, or nil if it is not frozen. The service has no
+// notion of freezes, so they are stored in the stack's tags.
+func (b *cloudBackend) GetStackFreeze(ctx context.Context,
+	stackRef backend.StackReference) (*engine.StackFreeze, error) {
+
+	tags, err := b.GetStackTags(ctx, stackRef)
+	if err != nil {
+		return nil, err
+	}
+	reason, ok := tags[apitype.StackFreezeReasonTag]
+	if !ok {
+		return nil, nil
+	}
+
+	freeze := &engine.StackFreeze{Reason: reason, User: tags[apitype.StackFreezeUserTag]}
+	if frozen, ok := tags[apitype.StackFreezeFrozenTag]; ok {
+		if freeze.Frozen, err = time.Parse(time.RFC3339, frozen); err != nil {
+			return nil, errors.Wrap(err, "decoding stack freeze")
+		}
+	}
+	if expires, ok := tags[apitype.StackFreezeExpiresTag]; ok {
+		if freeze.Expires, err = time.Parse(time.RFC3339, expires); err != nil {
+			return nil, errors.Wrap(err, "decoding stack freeze")
+		}
+	}
+	return freeze, nil
+}
+
+// SetStackFreeze records the given freeze for the given stack in its tags, or lifts its freeze if the given freeze is
+// nil. The stack's other tags are left as they are.
+func (b *cloudBackend) SetStackFreeze(ctx context.Context, stackRef backend.StackReference,
+	freeze *engine.StackFreeze) error {
+
+	tags, err := b.GetStackTags(ctx, stackRef)
+	if err != nil {
+		return err
+	}
+	updated := make(map[apitype.StackTagName]string, len(tags)+4)
+	for name, value := range tags {
+		switch name {
+		case apitype.StackFreezeReasonTag, apitype.StackFreezeUserTag, apitype.StackFreezeFrozenTag,
+			apitype.StackFreezeExpiresTag:
+			// Dropped, so that no part of an earlier freeze survives.
+		default:
+			updated[name] = value
+		}
+	}
+	if freeze != nil {
+		updated[apitype.StackFreezeReasonTag] = freeze.Reason
+		if freeze.User != "" {
+			updated[apitype.StackFreezeUserTag] = freeze.User
+		}
+		updated[apitype.StackFreezeFrozenTag] = freeze.Frozen.UTC().Format(time.RFC3339)
+		if !freeze.Expires.IsZero() {
+			updated[apitype.StackFreezeExpiresTag] = freeze.Expires.UTC().Format(time.RFC3339)
+		}
+		if err = validation.ValidateStackTags(updated); err != nil {
+			return errors.Wrap(err, "the freeze cannot be stored in the stack's tags")
+		}
+	}
+	return b.UpdateStackTags(ctx, stackRef, updated)
+}
@@ -0,0 +1,137 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpstate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/httpstate/client"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+const (
+	// updateLeaseDuration is the duration of the lease that an update holds on its stack. The lease is renewed (i.e.
+	// a heartbeat is sent) after half of this duration has elapsed.
+	updateLeaseDuration = 5 * time.Minute
+
+	// staleLeaseTimeout is the amount of time that must pass without a heartbeat before the lease held by an update
+	// is considered stale and may be taken over by another update.
+	staleLeaseTimeout = updateLeaseDuration
+)
+
+// UpdateLockedError is returned when an update cannot start because another update holds the stack's update lock.
+type UpdateLockedError struct {
+	Stack        client.StackIdentifier // the stack that is locked.
+	Lock         apitype.UpdateLock     // information about the update that holds the lock.
+	HeartbeatAge time.Duration          // the time since the lock holder last renewed its lease.
+}
+
+// Stale returns true if the lock holder has not renewed its lease within the stale lease timeout.
+func (e UpdateLockedError) Stale() bool {
+	return e.HeartbeatAge > staleLeaseTimeout
+}
+
+func (e UpdateLockedError) Error() string {
+	holder := e.Lock.RequestedBy
+	if holder == "" {
+		holder = "an unknown user"
+	}
+	msg := fmt.Sprintf("stack '%s/%s/%s' is locked by %s update %s started by %s; last heartbeat was %s ago",
+		e.Stack.Owner, e.Stack.Project, e.Stack.Stack, e.Lock.Kind, e.Lock.UpdateID, holder,
+		e.HeartbeatAge.Round(time.Second))
+	if e.Stale() {
+		return msg + "; the lock is stale and may be taken over by passing --takeover-stale-lock"
+	}
+	return msg + "; if you are certain that the update is no longer running, pass --break-lock to proceed anyway"
+}
+
+// isConflictError returns true if the given error is a 409 Conflict response from the service.
+func isConflictError(err error) bool {
+	errResp, ok := err.(*apitype.ErrorResponse)
+	return ok && errResp.Code == http.StatusConflict
+}
+
+// describeUpdateConflict fetches information about the update that holds the given stack's lock and returns an
+// UpdateLockedError that describes it. If the lock information cannot be fetched, the original error is returned.
+func (b *cloudBackend) describeUpdateConflict(ctx context.Context, stack client.StackIdentifier,
+	conflict error) error {
+
+	lock, err := b.client.GetStackUpdateLock(ctx, stack)
+	if err != nil {
+		logging.V(7).Infof("failed to fetch update lock for stack %s: %v", stack.Stack, err)
+		return conflict
+	}
+	return UpdateLockedError{
+		Stack:        stack,
+		Lock:         lock,
+		HeartbeatAge: time.Since(time.Unix(lock.LastHeartbeat, 0)),
+	}
+}
+
+// startUpdateWithTakeover starts the given update. If the stack is locked by another update whose lease has gone stale
+// and takeover is true, or by any other update and force is true, the lock is released and the update is started
+// again.
+func (b *cloudBackend) startUpdateWithTakeover(ctx context.Context, update client.UpdateIdentifier,
+	tags map[apitype.StackTagName]string, takeover, force bool) (apitype.StartUpdateResponse, error) {
+
+	resp, err := b.client.StartUpdate(ctx, update, tags)
+	if err == nil || !isConflictError(err) {
+		return resp, err
+	}
+
+	err = b.describeUpdateConflict(ctx, update.StackIdentifier, err)
+	locked, ok := err.(UpdateLockedError)
+	if !ok || !(force || takeover && locked.Stale()) {
+		return apitype.StartUpdateResponse{}, err
+	}
+
+	logging.V(7).Infof("taking over lock on stack %s held by update %s (last heartbeat %v ago)",
+		update.Stack, locked.Lock.UpdateID, locked.HeartbeatAge)
+	if err = b.client.TakeoverStackUpdateLock(ctx, update.StackIdentifier, locked.Lock.UpdateID); err != nil {
+		return apitype.StartUpdateResponse{}, err
+	}
+	return b.client.StartUpdate(ctx, update, tags)
+}
+
+var _ backend.StateLockReader = (*cloudBackend)(nil)
+
+// ReadStateLock returns a lease that describes the update holding the given stack's update lock, or nil if the stack
+// is not locked.
+func (b *cloudBackend) ReadStateLock(ctx context.Context,
+	stackRef backend.StackReference) (*backend.StateLease, error) {
+
+	stack, err := b.getCloudStackIdentifier(stackRef)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := b.client.GetStackUpdateLock(ctx, stack)
+	if err != nil {
+		if errResp, ok := err.(*apitype.ErrorResponse); ok && errResp.Code == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &backend.StateLease{
+		ID:      lock.UpdateID,
+		Kind:    lock.Kind,
+		User:    lock.RequestedBy,
+		Renewed: time.Unix(lock.LastHeartbeat, 0),
+	}, nil
+}
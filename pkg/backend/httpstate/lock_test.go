@@ -0,0 +1,181 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpstate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/backend/httpstate/client"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// fakeLockService is a fake of the parts of the Pulumi service's API that manage a stack's update lock. The stack is
+// locked by the update described by lock until that update's lock is taken over.
+type fakeLockService struct {
+	lock      apitype.UpdateLock // the update that holds the stack's lock.
+	locked    bool               // true if the stack is locked.
+	starts    int                // the number of attempts to start an update.
+	takeovers []string           // the IDs of the updates whose locks were taken over.
+}
+
+func (s *fakeLockService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const stackPath = "/api/stacks/owner/project/stack"
+
+	respond := func(code int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if body != nil {
+			contract.IgnoreError(json.NewEncoder(w).Encode(body))
+		}
+	}
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == stackPath+"/update/new-update":
+		s.starts++
+		if s.locked {
+			respond(http.StatusConflict, apitype.ErrorResponse{Code: http.StatusConflict, Message: "conflict"})
+			return
+		}
+		respond(http.StatusOK, apitype.StartUpdateResponse{Version: 2, Token: "token"})
+	case r.Method == "GET" && r.URL.Path == stackPath+"/lock":
+		if !s.locked {
+			respond(http.StatusNotFound, apitype.ErrorResponse{Code: http.StatusNotFound, Message: "not locked"})
+			return
+		}
+		respond(http.StatusOK, s.lock)
+	case r.Method == "POST" && r.URL.Path == stackPath+"/lock/takeover":
+		var req apitype.TakeoverUpdateLockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(http.StatusBadRequest, nil)
+			return
+		}
+		s.takeovers = append(s.takeovers, req.UpdateID)
+		if req.UpdateID == s.lock.UpdateID {
+			s.locked = false
+		}
+		respond(http.StatusOK, nil)
+	default:
+		respond(http.StatusNotFound, apitype.ErrorResponse{Code: http.StatusNotFound, Message: r.URL.Path})
+	}
+}
+
+// newLockTestBackend returns a backend that talks to a fake service whose stack is locked by an update that last
+// renewed its lease the given time ago.
+func newLockTestBackend(heartbeatAge time.Duration) (*cloudBackend, *fakeLockService, func()) {
+	service := &fakeLockService{
+		lock: apitype.UpdateLock{
+			UpdateID:      "old-update",
+			Kind:          apitype.UpdateUpdate,
+			RequestedBy:   "alice",
+			LastHeartbeat: time.Now().Add(-heartbeatAge).Unix(),
+		},
+		locked: true,
+	}
+	server := httptest.NewServer(service)
+	b := &cloudBackend{url: server.URL, client: client.NewClient(server.URL, "token", nil)}
+	return b, service, server.Close
+}
+
+var lockTestUpdate = client.UpdateIdentifier{
+	StackIdentifier: client.StackIdentifier{Owner: "owner", Project: "project", Stack: "stack"},
+	UpdateKind:      apitype.UpdateUpdate,
+	UpdateID:        "new-update",
+}
+
+func TestDescribeUpdateConflict(t *testing.T) {
+	b, _, done := newLockTestBackend(time.Minute)
+	defer done()
+
+	conflict := &apitype.ErrorResponse{Code: http.StatusConflict}
+	err := b.describeUpdateConflict(context.Background(), lockTestUpdate.StackIdentifier, conflict)
+	locked, ok := err.(UpdateLockedError)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "old-update", locked.Lock.UpdateID)
+	assert.Equal(t, "alice", locked.Lock.RequestedBy)
+	assert.False(t, locked.Stale())
+	assert.Contains(t, locked.Error(), "stack 'owner/project/stack' is locked by update update old-update")
+	assert.Contains(t, locked.Error(), "--break-lock")
+
+	// If the lock cannot be described, the original conflict is returned.
+	other := lockTestUpdate.StackIdentifier
+	other.Stack = "other"
+	assert.Equal(t, conflict, b.describeUpdateConflict(context.Background(), other, conflict))
+}
+
+func TestUpdateLockedErrorStale(t *testing.T) {
+	locked := UpdateLockedError{Stack: lockTestUpdate.StackIdentifier, HeartbeatAge: staleLeaseTimeout}
+	assert.False(t, locked.Stale())
+
+	locked.HeartbeatAge = staleLeaseTimeout + time.Second
+	assert.True(t, locked.Stale())
+	assert.Contains(t, locked.Error(), "--takeover-stale-lock")
+}
+
+func TestStartUpdateTakesOverStaleLock(t *testing.T) {
+	b, service, done := newLockTestBackend(2 * staleLeaseTimeout)
+	defer done()
+
+	resp, err := b.startUpdateWithTakeover(context.Background(), lockTestUpdate, nil,
+		true /*takeover*/, false /*force*/)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.Version)
+	assert.Equal(t, "token", resp.Token)
+	assert.Equal(t, []string{"old-update"}, service.takeovers)
+	assert.Equal(t, 2, service.starts)
+}
+
+func TestStartUpdateKeepsLiveLock(t *testing.T) {
+	b, service, done := newLockTestBackend(time.Minute)
+	defer done()
+
+	// A lock whose holder is still renewing its lease is not taken over.
+	_, err := b.startUpdateWithTakeover(context.Background(), lockTestUpdate, nil,
+		true /*takeover*/, false /*force*/)
+	locked, ok := err.(UpdateLockedError)
+	if assert.True(t, ok) {
+		assert.False(t, locked.Stale())
+	}
+	assert.Empty(t, service.takeovers)
+	assert.Equal(t, 1, service.starts)
+
+	// ...unless the lock is broken.
+	_, err = b.startUpdateWithTakeover(context.Background(), lockTestUpdate, nil,
+		false /*takeover*/, true /*force*/)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old-update"}, service.takeovers)
+}
+
+func TestStartUpdateIgnoresStaleLockWithoutTakeover(t *testing.T) {
+	b, service, done := newLockTestBackend(2 * staleLeaseTimeout)
+	defer done()
+
+	_, err := b.startUpdateWithTakeover(context.Background(), lockTestUpdate, nil,
+		false /*takeover*/, false /*force*/)
+	locked, ok := err.(UpdateLockedError)
+	if assert.True(t, ok) {
+		assert.True(t, locked.Stale())
+	}
+	assert.Empty(t, service.takeovers)
+}
@@ -29,7 +29,6 @@ import (
 	"github.com/pulumi/pulumi/pkg/backend/display"
 	"github.com/pulumi/pulumi/pkg/backend/httpstate/client"
 	"github.com/pulumi/pulumi/pkg/engine"
-	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/stack"
 	"github.com/pulumi/pulumi/pkg/workspace"
@@ -165,7 +164,7 @@ func (u *cloudUpdate) recordEngineEvent(event engine.Event, sequenceNumber int)
 		return err
 	}
 
-	apiEvent, convErr := convertEngineEvent(event)
+	apiEvent, convErr := engine.ConvertEvent(event)
 	if convErr != nil {
 		return errors.Wrap(convErr, "converting engine event")
 	}
@@ -247,7 +246,7 @@ func (u *cloudUpdate) RecordAndDisplayEvents(
 func (b *cloudBackend) newQuery(ctx context.Context, stackRef backend.StackReference,
 	op backend.UpdateOperation) (*cloudQuery, error) {
 	// Construct the query target.
-	target, err := b.getTarget(ctx, stackRef, op.StackConfiguration.Config, op.StackConfiguration.Decrypter)
+	target, err := b.getTarget(ctx, stackRef, op.StackConfiguration)
 	if err != nil {
 		return nil, err
 	}
@@ -261,7 +260,7 @@ func (b *cloudBackend) newUpdate(ctx context.Context, stackRef backend.StackRefe
 	// Create a token source for this update if necessary.
 	var tokenSource *tokenSource
 	if token != "" {
-		ts, err := newTokenSource(ctx, token, b, update, 5*time.Minute)
+		ts, err := newTokenSource(ctx, token, b, update, updateLeaseDuration)
 		if err != nil {
 			return nil, err
 		}
@@ -269,7 +268,7 @@ func (b *cloudBackend) newUpdate(ctx context.Context, stackRef backend.StackRefe
 	}
 
 	// Construct the deployment target.
-	target, err := b.getTarget(ctx, stackRef, op.StackConfiguration.Config, op.StackConfiguration.Decrypter)
+	target, err := b.getTarget(ctx, stackRef, op.StackConfiguration)
 	if err != nil {
 		return nil, err
 	}
@@ -301,7 +300,7 @@ func (b *cloudBackend) getSnapshot(ctx context.Context, stackRef backend.StackRe
 }
 
 func (b *cloudBackend) getTarget(ctx context.Context, stackRef backend.StackReference,
-	cfg config.Map, dec config.Decrypter) (*deploy.Target, error) {
+	cfg backend.StackConfiguration) (*deploy.Target, error) {
 	snapshot, err := b.getSnapshot(ctx, stackRef)
 	if err != nil {
 		switch err {
@@ -317,183 +316,10 @@ func (b *cloudBackend) getTarget(ctx context.Context, stackRef backend.StackRefe
 	}
 
 	return &deploy.Target{
-		Name:      stackRef.Name(),
-		Config:    cfg,
-		Decrypter: dec,
-		Snapshot:  snapshot,
+		Name:         stackRef.Name(),
+		Config:       cfg.Config,
+		Decrypter:    cfg.Decrypter,
+		Snapshot:     snapshot,
+		ConfigLayers: cfg.Layers,
 	}, nil
 }
-
-func convertStepEventMetadata(md engine.StepEventMetadata) apitype.StepEventMetadata {
-	keys := make([]string, len(md.Keys))
-	for i, v := range md.Keys {
-		keys[i] = string(v)
-	}
-	var diffs []string
-	for _, v := range md.Diffs {
-		diffs = append(diffs, string(v))
-	}
-
-	return apitype.StepEventMetadata{
-		Op:   string(md.Op),
-		URN:  string(md.URN),
-		Type: string(md.Type),
-
-		Old: convertStepEventStateMetadata(md.Old),
-		New: convertStepEventStateMetadata(md.New),
-
-		Keys:     keys,
-		Diffs:    diffs,
-		Logical:  md.Logical,
-		Provider: md.Provider,
-	}
-}
-
-func convertStepEventStateMetadata(md *engine.StepEventStateMetadata) *apitype.StepEventStateMetadata {
-	if md == nil {
-		return nil
-	}
-
-	inputs := make(map[string]interface{})
-	for k, v := range md.Inputs {
-		inputs[string(k)] = v
-	}
-	outputs := make(map[string]interface{})
-	for k, v := range md.Outputs {
-		outputs[string(k)] = v
-	}
-
-	return &apitype.StepEventStateMetadata{
-		Type: string(md.Type),
-		URN:  string(md.URN),
-
-		Custom:     md.Custom,
-		Delete:     md.Delete,
-		ID:         string(md.ID),
-		Parent:     string(md.Parent),
-		Protect:    md.Protect,
-		Inputs:     inputs,
-		Outputs:    outputs,
-		InitErrors: md.InitErrors,
-	}
-}
-
-// convertEngineEvent converts a raw engine.Event into an apitype.EngineEvent used in the Pulumi
-// REST API. Returns an error if the engine event is unknown or not in an expected format.
-// EngineEvent.{ Sequence, Timestamp } are expected to be set by the caller.
-func convertEngineEvent(e engine.Event) (apitype.EngineEvent, error) {
-	var apiEvent apitype.EngineEvent
-
-	// Error to return if the payload doesn't match expected.
-	eventTypePayloadMismatch := errors.Errorf("unexpected payload for event type %v", e.Type)
-
-	switch e.Type {
-	case engine.CancelEvent:
-		apiEvent.CancelEvent = &apitype.CancelEvent{}
-
-	case engine.StdoutColorEvent:
-		p, ok := e.Payload.(engine.StdoutEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		apiEvent.StdoutEvent = &apitype.StdoutEngineEvent{
-			Message: p.Message,
-			Color:   string(p.Color),
-		}
-
-	case engine.DiagEvent:
-		p, ok := e.Payload.(engine.DiagEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		apiEvent.DiagnosticEvent = &apitype.DiagnosticEvent{
-			URN:       string(p.URN),
-			Prefix:    p.Prefix,
-			Message:   p.Message,
-			Color:     string(p.Color),
-			Severity:  string(p.Severity),
-			Ephemeral: p.Ephemeral,
-		}
-
-	case engine.PolicyViolationEvent:
-		p, ok := e.Payload.(engine.PolicyViolationEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		apiEvent.PolicyEvent = &apitype.PolicyEvent{
-			ResourceURN:       string(p.ResourceURN),
-			Message:           p.Message,
-			Color:             string(p.Color),
-			PolicyName:        p.PolicyName,
-			PolicyPackName:    p.PolicyPackName,
-			PolicyPackVersion: p.PolicyPackVersion,
-			EnforcementLevel:  string(p.EnforcementLevel),
-		}
-
-	case engine.PreludeEvent:
-		p, ok := e.Payload.(engine.PreludeEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		// Convert the config bag.
-		cfg := make(map[string]string)
-		for k, v := range p.Config {
-			cfg[k] = v
-		}
-		apiEvent.PreludeEvent = &apitype.PreludeEvent{
-			Config: cfg,
-		}
-
-	case engine.SummaryEvent:
-		p, ok := e.Payload.(engine.SummaryEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		// Convert the resource changes.
-		changes := make(map[string]int)
-		for op, count := range p.ResourceChanges {
-			changes[string(op)] = count
-		}
-		apiEvent.SummaryEvent = &apitype.SummaryEvent{
-			MaybeCorrupt:    p.MaybeCorrupt,
-			DurationSeconds: int(p.Duration.Seconds()),
-			ResourceChanges: changes,
-		}
-
-	case engine.ResourcePreEvent:
-		p, ok := e.Payload.(engine.ResourcePreEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		apiEvent.ResourcePreEvent = &apitype.ResourcePreEvent{
-			Metadata: convertStepEventMetadata(p.Metadata),
-			Planning: p.Planning,
-		}
-
-	case engine.ResourceOutputsEvent:
-		p, ok := e.Payload.(engine.ResourceOutputsEventPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		apiEvent.ResOutputsEvent = &apitype.ResOutputsEvent{
-			Metadata: convertStepEventMetadata(p.Metadata),
-			Planning: p.Planning,
-		}
-
-	case engine.ResourceOperationFailed:
-		p, ok := e.Payload.(engine.ResourceOperationFailedPayload)
-		if !ok {
-			return apiEvent, eventTypePayloadMismatch
-		}
-		apiEvent.ResOpFailedEvent = &apitype.ResOpFailedEvent{
-			Metadata: convertStepEventMetadata(p.Metadata),
-			Status:   int(p.Status),
-			Steps:    p.Steps,
-		}
-
-	default:
-		return apiEvent, errors.Errorf("unknown event type %q", e.Type)
-	}
-
-	return apiEvent, nil
-}
@@ -0,0 +1,73 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// ReplayBundleEnvVar names a file to which the events of each update are written as a replay bundle. Commands that
+// run more than one update, such as a preview followed by an update, overwrite the bundle with each one, so the
+// bundle always holds the events of the last update that ran. See engine.ReplayBundle.
+const ReplayBundleEnvVar = "PULUMI_REPLAY_BUNDLE"
+
+// NewReplayRecorder returns a recorder for the events of an update of the given kind if PULUMI_REPLAY_BUNDLE names a
+// replay bundle to write, or nil otherwise.
+func NewReplayRecorder(kind apitype.UpdateKind, stack tokens.QName, proj tokens.PackageName,
+	dryRun bool) *engine.ReplayRecorder {
+
+	if os.Getenv(ReplayBundleEnvVar) == "" {
+		return nil
+	}
+	return engine.NewReplayRecorder(kind, stack, proj, dryRun)
+}
+
+// SaveReplayBundle writes the events recorded by the given recorder to the file named by PULUMI_REPLAY_BUNDLE. It
+// does nothing if the recorder is nil.
+func SaveReplayBundle(recorder *engine.ReplayRecorder) error {
+	if recorder == nil {
+		return nil
+	}
+	path := os.Getenv(ReplayBundleEnvVar)
+	if err := engine.WriteReplayBundle(path, recorder.Bundle()); err != nil {
+		return errors.Wrapf(err, "writing replay bundle %s", path)
+	}
+	return nil
+}
+
+// RecordEvents subscribes the given recorder to the events of the given mux. It returns a channel that is closed once
+// every event has been recorded, which is after the mux is closed. It does nothing if the recorder is nil.
+func RecordEvents(mux *engine.EventMux, recorder *engine.ReplayRecorder) <-chan bool {
+	done := make(chan bool)
+	if recorder == nil {
+		close(done)
+		return done
+	}
+
+	sub := mux.Subscribe(nil)
+	go func() {
+		for e := range sub.Events() {
+			recorder.Record(e)
+		}
+		close(done)
+	}()
+	return done
+}
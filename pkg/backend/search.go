@@ -0,0 +1,205 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/stack"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// ResourceQuery selects resources from the stacks of a backend.
+type ResourceQuery struct {
+	// Project, if non-nil, limits the search to the stacks of the given project.
+	Project *tokens.PackageName
+	// Type, if non-empty, limits the search to resources of the given type.
+	Type tokens.Type
+	// Predicates are conditions on the properties of a resource, all of which it must satisfy to match.
+	Predicates []PropertyPredicate
+}
+
+// PropertyPredicateOp is the comparison that a property predicate makes.
+type PropertyPredicateOp string
+
+const (
+	// PropertyExists matches resources that have a value, other than null, at the predicate's path.
+	PropertyExists PropertyPredicateOp = ""
+	// PropertyEquals matches resources whose value at the predicate's path is equal to the predicate's value.
+	PropertyEquals PropertyPredicateOp = "="
+	// PropertyNotEquals matches resources that have a value at the predicate's path that is not equal to the
+	// predicate's value.
+	PropertyNotEquals PropertyPredicateOp = "!="
+	// PropertyContains matches resources whose value at the predicate's path is a string that contains the
+	// predicate's value, or an array with an element equal to it.
+	PropertyContains PropertyPredicateOp = "~="
+)
+
+// PropertyPredicate is a condition on the value at a path within a resource's properties. The resource's outputs are
+// consulted first, followed by its inputs. Values are compared by their string forms, so that, e.g., `port=80` matches
+// the number 80. Secret values are never revealed by a search, and so never satisfy a predicate.
+type PropertyPredicate struct {
+	Path  resource.PropertyPath
+	Op    PropertyPredicateOp
+	Value string
+}
+
+// ParsePropertyPredicate parses a property predicate such as `acl=public-read`, `tags.env!=prod`,
+// `cidrBlocks~=0.0.0.0/0`, or `versioning.enabled`. See resource.ParsePropertyPath for the syntax of the path.
+func ParsePropertyPredicate(s string) (PropertyPredicate, error) {
+	// The comparison is at the first '=' outside of a quoted key; everything after it is the value.
+	path, op, value := s, PropertyExists, ""
+	for i, quoted := 0, false; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '=' && !quoted:
+			path, op, value = s[:i], PropertyEquals, s[i+1:]
+			if i > 0 && (s[i-1] == '!' || s[i-1] == '~') {
+				path, op = s[:i-1], PropertyPredicateOp(s[i-1:i+1])
+			}
+			i = len(s)
+		}
+	}
+
+	parsed, err := resource.ParsePropertyPath(strings.TrimSpace(path))
+	if err != nil {
+		return PropertyPredicate{}, errors.Wrapf(err, "invalid predicate %q", s)
+	}
+	return PropertyPredicate{Path: parsed, Op: op, Value: value}, nil
+}
+
+func (p PropertyPredicate) String() string {
+	return p.Path.String() + string(p.Op) + p.Value
+}
+
+// Matches returns true if the given resource satisfies the predicate.
+func (p PropertyPredicate) Matches(res *resource.State) bool {
+	v, ok := p.Path.Get(res.Outputs)
+	if !ok || v.IsNull() {
+		v, ok = p.Path.Get(res.Inputs)
+	}
+	if !ok || v.IsNull() || v.ContainsSecrets() || v.ContainsUnknowns() {
+		return false
+	}
+
+	switch p.Op {
+	case PropertyExists:
+		return true
+	case PropertyEquals:
+		return predicateString(v) == p.Value
+	case PropertyNotEquals:
+		return predicateString(v) != p.Value
+	case PropertyContains:
+		if v.IsArray() {
+			for _, elem := range v.ArrayValue() {
+				if predicateString(elem) == p.Value {
+					return true
+				}
+			}
+			return false
+		}
+		return v.IsString() && strings.Contains(v.StringValue(), p.Value)
+	default:
+		return false
+	}
+}
+
+// predicateString returns the string form of the given value to which a predicate's value is compared.
+func predicateString(v resource.PropertyValue) string {
+	switch {
+	case v.IsString():
+		return v.StringValue()
+	case v.IsNumber():
+		return strconv.FormatFloat(v.NumberValue(), 'f', -1, 64)
+	case v.IsBool():
+		return strconv.FormatBool(v.BoolValue())
+	default:
+		return fmt.Sprintf("%v", v.Mappable())
+	}
+}
+
+// Matches returns true if the given resource matches the query.
+func (q ResourceQuery) Matches(res *resource.State) bool {
+	if res.Delete || q.Type != "" && res.Type != q.Type {
+		return false
+	}
+	for _, p := range q.Predicates {
+		if !p.Matches(res) {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchResult is a single result of a search: either a resource that matched the query, or an error that prevented
+// a stack from being searched.
+type SearchResult struct {
+	Stack    StackReference  // the stack that holds the resource.
+	Resource *resource.State // the resource that matched; secret values are replaced by "[secret]".
+	Err      error           // the error that prevented the stack from being searched, if any.
+}
+
+// SearchResources searches the latest state of each stack of the given backend for resources that match the given
+// query, in order of stack name. The given function is called with each result as soon as it is found; if it returns
+// an error, the search stops and returns that error. Stacks whose state cannot be read are reported as results with an
+// error rather than stopping the search, so that a single damaged stack does not prevent an audit of the others.
+func SearchResources(ctx context.Context, b Backend, query ResourceQuery, fn func(SearchResult) error) error {
+	summaries, err := b.ListStacks(ctx, query.Project)
+	if err != nil {
+		return errors.Wrap(err, "listing stacks")
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name().String() < summaries[j].Name().String()
+	})
+
+	for _, summary := range summaries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		stackRef := summary.Name()
+		logging.V(7).Infof("SearchResources: searching stack %s", stackRef)
+		deployment, err := b.ExportDeployment(ctx, stackRef)
+		if err == nil && deployment != nil {
+			var resources []*resource.State
+			if resources, err = stack.DeserializeUntypedResources(deployment); err == nil {
+				for _, res := range resources {
+					if query.Matches(res) {
+						if err := fn(SearchResult{Stack: stackRef, Resource: res}); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		if err != nil {
+			if err := fn(SearchResult{Stack: stackRef, Err: errors.Wrapf(err, "reading stack %s", stackRef)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestParsePropertyPredicate(t *testing.T) {
+	cases := []struct {
+		input string
+		op    PropertyPredicateOp
+		path  string
+		value string
+	}{
+		{"acl=public-read", PropertyEquals, "acl", "public-read"},
+		{"tags.env!=prod", PropertyNotEquals, "tags.env", "prod"},
+		{"cidrBlocks~=0.0.0.0/0", PropertyContains, "cidrBlocks", "0.0.0.0/0"},
+		{"versioning.enabled", PropertyExists, "versioning.enabled", ""},
+		{"policy=a!=b", PropertyEquals, "policy", "a!=b"},
+		{`labels["a=b"]=c`, PropertyEquals, `labels["a=b"]`, "c"},
+	}
+	for _, c := range cases {
+		p, err := ParsePropertyPredicate(c.input)
+		if assert.NoError(t, err, c.input) {
+			assert.Equal(t, c.op, p.Op, c.input)
+			assert.Equal(t, c.path, p.Path.String(), c.input)
+			assert.Equal(t, c.value, p.Value, c.input)
+			assert.Equal(t, c.input, p.String())
+		}
+	}
+
+	_, err := ParsePropertyPredicate("tags[=prod")
+	assert.Error(t, err)
+}
+
+func TestResourceQueryMatches(t *testing.T) {
+	bucket := &resource.State{
+		Type: "aws:s3/bucket:Bucket",
+		Inputs: resource.NewPropertyMapFromMap(map[string]interface{}{
+			"acl":    "public-read",
+			"region": "us-west-2",
+		}),
+		Outputs: resource.NewPropertyMapFromMap(map[string]interface{}{
+			"acl":        "public-read",
+			"tags":       map[string]interface{}{"env": "dev"},
+			"cidrBlocks": []interface{}{"10.0.0.0/8", "0.0.0.0/0"},
+			"port":       80,
+			"versioning": map[string]interface{}{"enabled": true},
+		}),
+	}
+	bucket.Outputs["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+
+	query := func(typ tokens.Type, predicates ...string) ResourceQuery {
+		q := ResourceQuery{Type: typ}
+		for _, s := range predicates {
+			p, err := ParsePropertyPredicate(s)
+			assert.NoError(t, err)
+			q.Predicates = append(q.Predicates, p)
+		}
+		return q
+	}
+
+	assert.True(t, query("aws:s3/bucket:Bucket").Matches(bucket))
+	assert.True(t, query("", "acl=public-read").Matches(bucket))
+	assert.True(t, query("", "acl=public-read", "tags.env!=prod").Matches(bucket))
+	assert.True(t, query("", "port=80", "versioning.enabled=true").Matches(bucket))
+	assert.True(t, query("", "cidrBlocks~=0.0.0.0/0", "acl~=public").Matches(bucket))
+	assert.True(t, query("", "region=us-west-2").Matches(bucket), "inputs are consulted after outputs")
+	assert.True(t, query("", "versioning").Matches(bucket))
+
+	assert.False(t, query("aws:ec2/vpc:Vpc").Matches(bucket), "the type must match")
+	assert.False(t, query("", "acl=private").Matches(bucket))
+	assert.False(t, query("", "acl=public-read", "tags.env=prod").Matches(bucket))
+	assert.False(t, query("", "cidrBlocks~=0.0.0.0").Matches(bucket))
+	assert.False(t, query("", "missing").Matches(bucket))
+	assert.False(t, query("", "missing!=x").Matches(bucket), "a missing value is not unequal")
+	assert.False(t, query("", "password=hunter2").Matches(bucket), "secrets never match")
+	assert.False(t, query("", "password!=x").Matches(bucket), "secrets never match")
+
+	deleted := *bucket
+	deleted.Delete = true
+	assert.False(t, query("").Matches(&deleted), "resources pending deletion never match")
+}
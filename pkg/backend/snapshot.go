@@ -15,18 +15,24 @@
 package backend
 
 import (
+	"context"
 	"reflect"
 	"sort"
+	"sync/atomic"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi/pkg/engine"
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/secrets"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/tracing"
 	"github.com/pulumi/pulumi/pkg/version"
+	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
 // SnapshotPersister is an interface implemented by our backends that implements snapshot
@@ -56,24 +62,63 @@ type SnapshotPersister interface {
 // that it creates and expects those mutations to be persisted directly to the snapshot.
 type SnapshotManager struct {
 	persister        SnapshotPersister        // The persister responsible for invalidating and persisting the snapshot
+	mirror           *SnapshotMirror          // An optional mirror to which each persisted snapshot is copied
+	tracingCtx       context.Context          // The context whose span, if any, parents the spans of snapshot writes
 	baseSnapshot     *deploy.Snapshot         // The base snapshot for this plan
 	resources        []*resource.State        // The list of resources operated upon by this plan
 	operations       []resource.Operation     // The set of operations known to be outstanding in this plan
 	dones            map[*resource.State]bool // The set of resources that have been operated upon already by this plan
 	completeOps      map[*resource.State]bool // The set of resources that have completed their operation
+	config           config.Map               // The effective configuration of the update, if recorded
+	omittedConfig    []config.Key             // The keys whose values were omitted from the recorded configuration
+	plugins          []workspace.PluginInfo   // The provider plugins that served the update, if recorded
 	doVerify         bool                     // If true, verify the snapshot before persisting it
+	version          int32                    // The number of snapshots persisted so far; accessed atomically
 	mutationRequests chan<- mutationRequest   // The queue of mutation requests, to be retired serially by the manager
 	cancel           chan bool                // A channel used to request cancellation of any new mutation requests.
 	done             <-chan error             // A channel that sends a single result when the manager has shut down.
 }
 
 var _ engine.SnapshotManager = (*SnapshotManager)(nil)
+var _ engine.SnapshotVersioner = (*SnapshotManager)(nil)
+var _ engine.SnapshotReader = (*SnapshotManager)(nil)
 
 type mutationRequest struct {
 	mutator func() bool
+	read    bool // true if the request only reads the snapshot, so that it neither writes nor elides a write.
 	result  chan<- error
 }
 
+// SetMirror arranges for each snapshot persisted by the manager to also be written to the given mirror. It must be
+// called before the manager is handed to the engine. The caller remains responsible for closing the mirror once the
+// manager has been closed.
+func (sm *SnapshotManager) SetMirror(mirror *SnapshotMirror) {
+	sm.mirror = mirror
+}
+
+// SnapshotVersion returns the number of snapshots that the manager has persisted so far.
+func (sm *SnapshotManager) SnapshotVersion() int {
+	return int(atomic.LoadInt32(&sm.version))
+}
+
+// Snapshot returns the snapshot that the manager would persist now.
+func (sm *SnapshotManager) Snapshot() (*deploy.Snapshot, error) {
+	var snap *deploy.Snapshot
+	result := make(chan error)
+	reader := func() bool {
+		snap = sm.snap()
+		snap.NormalizeURNReferences()
+		return false
+	}
+	select {
+	case sm.mutationRequests <- mutationRequest{mutator: reader, read: true, result: result}:
+		err := <-result
+		return snap, err
+	case <-sm.cancel:
+		return nil, errors.New("snapshot manager closed")
+	}
+}
+
 func (sm *SnapshotManager) Close() error {
 	close(sm.cancel)
 	return <-sm.done
@@ -121,6 +166,26 @@ func (sm *SnapshotManager) RegisterResourceOutputs(step deploy.Step) error {
 	return sm.mutate(func() bool { return true })
 }
 
+// RecordConfig records the effective configuration of the update in the manifest of each snapshot that is
+// persisted from now on, along with the keys whose values were omitted from it. Recording it does not by itself
+// cause a write.
+func (sm *SnapshotManager) RecordConfig(cfg config.Map, omitted []config.Key) error {
+	return sm.mutate(func() bool {
+		sm.config, sm.omittedConfig = cfg, omitted
+		return false
+	})
+}
+
+// RecordPlugins records the provider plugins that served the update in the manifest of each snapshot that is
+// persisted from now on. Until they are recorded, snapshots keep the plugins recorded by the base snapshot. Recording
+// them does not by itself cause a write.
+func (sm *SnapshotManager) RecordPlugins(plugins []workspace.PluginInfo) error {
+	return sm.mutate(func() bool {
+		sm.plugins = plugins
+		return false
+	})
+}
+
 // BeginMutation signals to the SnapshotManager that the engine intends to mutate the global snapshot
 // by performing the given Step. This function gives the SnapshotManager a chance to record the
 // intent to mutate before the mutation occurs.
@@ -139,12 +204,19 @@ func (sm *SnapshotManager) BeginMutation(step deploy.Step) (engine.SnapshotMutat
 		return sm.doDelete(step)
 	case deploy.OpReplace:
 		return &replaceSnapshotMutation{sm}, nil
-	case deploy.OpRead, deploy.OpReadReplacement:
+	case deploy.OpValidateReplacement:
+		return &validateReplacementSnapshotMutation{sm}, nil
+	case deploy.OpRead, deploy.OpReadReplacement, deploy.OpImport:
 		return sm.doRead(step)
 	case deploy.OpRefresh:
 		return &refreshSnapshotMutation{sm}, nil
 	case deploy.OpRemovePendingReplace:
 		return &removePendingReplaceSnapshotMutation{sm}, nil
+	case deploy.OpPrune:
+		if step.New() == nil {
+			return sm.doDelete(step)
+		}
+		return &retainSnapshotMutation{sm}, nil
 	}
 
 	contract.Failf("unknown StepOp: %s", step.Op())
@@ -362,6 +434,21 @@ func (rsm *replaceSnapshotMutation) End(step deploy.Step, successful bool) error
 	return nil
 }
 
+type validateReplacementSnapshotMutation struct {
+	manager *SnapshotManager
+}
+
+func (vsm *validateReplacementSnapshotMutation) End(step deploy.Step, successful bool) error {
+	contract.Require(step != nil, "step != nil")
+	contract.Require(step.Op() == deploy.OpValidateReplacement, "step.Op() == deploy.OpValidateReplacement")
+	logging.V(9).Infof("SnapshotManager: validateReplacementSnapshotMutation.End(..., %v)", successful)
+	return vsm.manager.mutate(func() bool {
+		// Validation marks either the original resource (on success) or its replacement (on failure) for deletion.
+		// Both states are already present in the snapshot by reference, so we need only write the checkpoint.
+		return true
+	})
+}
+
 func (sm *SnapshotManager) doRead(step deploy.Step) (engine.SnapshotMutation, error) {
 	logging.V(9).Infof("SnapshotManager.doRead(%s)", step.URN())
 	err := sm.mutate(func() bool {
@@ -427,6 +514,20 @@ func (rsm *removePendingReplaceSnapshotMutation) End(step deploy.Step, successfu
 	})
 }
 
+type retainSnapshotMutation struct {
+	manager *SnapshotManager
+}
+
+func (rsm *retainSnapshotMutation) End(step deploy.Step, successful bool) error {
+	contract.Require(step != nil, "step != nil")
+	contract.Require(step.Op() == deploy.OpPrune, "step.Op() == deploy.OpPrune")
+	logging.V(9).Infof("SnapshotManager: retainSnapshotMutation.End(..., %v)", successful)
+	return rsm.manager.mutate(func() bool {
+		// A pruned resource that is left in place remains in the base snapshot, so there is nothing to write.
+		return false
+	})
+}
+
 // markDone marks a resource as having been processed. Resources that have been marked
 // in this manner won't be persisted in the snapshot.
 func (sm *SnapshotManager) markDone(state *resource.State) {
@@ -511,10 +612,18 @@ func (sm *SnapshotManager) snap() *deploy.Snapshot {
 		}
 	}
 
+	// Record the plugins that served the update, so that the next update can tell which providers were upgraded.
+	plugins := sm.plugins
+	if plugins == nil && sm.baseSnapshot != nil {
+		plugins = sm.baseSnapshot.Manifest.Plugins
+	}
+
 	manifest := deploy.Manifest{
-		Time:    time.Now(),
-		Version: version.Version,
-		// Plugins: sm.plugins, - Explicitly dropped, since we don't use the plugin list in the manifest anymore.
+		Time:          time.Now(),
+		Version:       version.Version,
+		Plugins:       plugins,
+		Config:        sm.config,
+		OmittedConfig: sm.omittedConfig,
 	}
 
 	manifest.Magic = manifest.NewMagic()
@@ -525,9 +634,19 @@ func (sm *SnapshotManager) snap() *deploy.Snapshot {
 func (sm *SnapshotManager) saveSnapshot() error {
 	snap := sm.snap()
 	snap.NormalizeURNReferences()
-	if err := sm.persister.Save(snap); err != nil {
+	span, _ := tracing.StartSpan(sm.tracingCtx, "pulumi-snapshot-write", opentracing.Tags{
+		tracing.OpTag:      "write",
+		"pulumi.resources": len(snap.Resources),
+	})
+	err := sm.persister.Save(snap)
+	span.End(err)
+	if err != nil {
 		return errors.Wrap(err, "failed to save snapshot")
 	}
+	atomic.AddInt32(&sm.version, 1)
+	if sm.mirror != nil {
+		sm.mirror.Mirror(snap)
+	}
 	if sm.doVerify {
 		if err := snap.VerifyIntegrity(); err != nil {
 			return errors.Wrapf(err, "failed to verify snapshot")
@@ -537,16 +656,17 @@ func (sm *SnapshotManager) saveSnapshot() error {
 }
 
 // NewSnapshotManager creates a new SnapshotManager for the given stack name, using the given persister
-// and base snapshot.
+// and base snapshot. Snapshot writes are traced as children of the span in the given context, if any.
 //
 // It is *very important* that the baseSnap pointer refers to the same Snapshot
 // given to the engine! The engine will mutate this object and correctness of the
 // SnapshotManager depends on being able to observe this mutation. (This is not ideal...)
-func NewSnapshotManager(persister SnapshotPersister, baseSnap *deploy.Snapshot) *SnapshotManager {
+func NewSnapshotManager(ctx context.Context, persister SnapshotPersister, baseSnap *deploy.Snapshot) *SnapshotManager {
 	mutationRequests, cancel, done := make(chan mutationRequest), make(chan bool), make(chan error)
 
 	manager := &SnapshotManager{
 		persister:        persister,
+		tracingCtx:       ctx,
 		baseSnapshot:     baseSnap,
 		dones:            make(map[*resource.State]bool),
 		completeOps:      make(map[*resource.State]bool),
@@ -566,6 +686,11 @@ func NewSnapshotManager(persister SnapshotPersister, baseSnap *deploy.Snapshot)
 			select {
 			case request := <-mutationRequests:
 				var err error
+				if request.read {
+					request.mutator()
+					request.result <- nil
+					continue
+				}
 				if request.mutator() {
 					err = manager.saveSnapshot()
 					hasElidedWrites = false
@@ -0,0 +1,170 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// MirrorStats describes how far a SnapshotMirror has fallen behind the primary persister.
+type MirrorStats struct {
+	Writes   int           // the number of snapshots written to the mirror.
+	Failures int           // the number of snapshots the mirror failed to write.
+	Skipped  int           // the number of snapshots superseded by a newer one before they could be written.
+	LastLag  time.Duration // the time between the primary and mirror writes of the most recently mirrored snapshot.
+	MaxLag   time.Duration // the largest lag observed so far.
+}
+
+// SnapshotMirror asynchronously tees the snapshots saved by a SnapshotManager to a secondary persister, providing a
+// disaster recovery copy of a stack's state. Mirror writes never block or fail the operation that produced them: if
+// the mirror falls behind, only the most recent snapshot is kept, and failures are reported when the mirror is closed.
+type SnapshotMirror struct {
+	persister SnapshotPersister // the persister for the secondary location.
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	pending *deploy.Snapshot // the next snapshot to write, if any.
+	saved   time.Time        // the time at which the pending snapshot was saved by the primary persister.
+	closed  bool             // true once Close has been called.
+	stats   MirrorStats      // the statistics gathered so far.
+	lastErr error            // the most recent write error, if any.
+	done    chan bool        // closed when the writer goroutine exits.
+}
+
+// NewSnapshotMirror creates a new mirror that writes snapshots using the given persister. The caller must call Close
+// once the last snapshot has been mirrored.
+func NewSnapshotMirror(persister SnapshotPersister) *SnapshotMirror {
+	m := &SnapshotMirror{persister: persister, done: make(chan bool)}
+	m.cond = sync.NewCond(&m.lock)
+	go m.run()
+	return m
+}
+
+// Mirror queues the given snapshot, which has just been saved by the primary persister, to be written to the mirror.
+// Any snapshot that is still waiting to be written is discarded in favor of this one.
+func (m *SnapshotMirror) Mirror(snap *deploy.Snapshot) {
+	// The engine continues to mutate the resources in the snapshot after it has been saved, so take a copy.
+	snap = copySnapshot(snap)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return
+	}
+	if m.pending != nil {
+		m.stats.Skipped++
+	}
+	m.pending, m.saved = snap, time.Now()
+	m.cond.Signal()
+}
+
+// Stats returns the statistics gathered by the mirror so far.
+func (m *SnapshotMirror) Stats() MirrorStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.stats
+}
+
+// Close waits for the most recently queued snapshot to be written and stops the mirror. It returns an error if any of
+// the mirror's writes failed.
+func (m *SnapshotMirror) Close() error {
+	m.lock.Lock()
+	m.closed = true
+	m.cond.Signal()
+	m.lock.Unlock()
+
+	<-m.done
+
+	s := m.Stats()
+	logging.V(4).Infof("SnapshotMirror: %d writes, %d failures, %d skipped, last lag %v, max lag %v",
+		s.Writes, s.Failures, s.Skipped, s.LastLag, s.MaxLag)
+	if s.Failures > 0 {
+		return errors.Wrapf(m.lastErr, "failed to mirror %d of %d snapshots", s.Failures, s.Writes+s.Failures)
+	}
+	return nil
+}
+
+func (m *SnapshotMirror) run() {
+	defer close(m.done)
+
+	for {
+		m.lock.Lock()
+		for m.pending == nil && !m.closed {
+			m.cond.Wait()
+		}
+		snap, saved := m.pending, m.saved
+		m.pending = nil
+		m.lock.Unlock()
+
+		if snap == nil {
+			return
+		}
+
+		err := m.persister.Save(snap)
+
+		m.lock.Lock()
+		if err != nil {
+			logging.V(4).Infof("SnapshotMirror: failed to write snapshot: %v", err)
+			m.stats.Failures++
+			m.lastErr = err
+		} else {
+			lag := time.Since(saved)
+			logging.V(9).Infof("SnapshotMirror: wrote snapshot with a lag of %v", lag)
+			m.stats.Writes++
+			m.stats.LastLag = lag
+			if lag > m.stats.MaxLag {
+				m.stats.MaxLag = lag
+			}
+		}
+		m.lock.Unlock()
+	}
+}
+
+// copySnapshot returns a copy of the given snapshot whose resource states may be serialized while the engine continues
+// to update the originals. The engine only ever mutates a state's fields and the top level of its property maps, so
+// the copy is otherwise shallow.
+func copySnapshot(snap *deploy.Snapshot) *deploy.Snapshot {
+	copyState := func(res *resource.State) *resource.State {
+		c := *res
+		c.Inputs, c.Outputs = res.Inputs.Copy(), res.Outputs.Copy()
+		return &c
+	}
+
+	copies := make(map[*resource.State]*resource.State, len(snap.Resources))
+	resources := make([]*resource.State, len(snap.Resources))
+	for i, res := range snap.Resources {
+		c := copyState(res)
+		copies[res], resources[i] = c, c
+	}
+
+	operations := make([]resource.Operation, len(snap.PendingOperations))
+	for i, op := range snap.PendingOperations {
+		if c, ok := copies[op.Resource]; ok {
+			op.Resource = c
+		} else {
+			op.Resource = copyState(op.Resource)
+		}
+		operations[i] = op
+	}
+
+	return deploy.NewSnapshot(snap.Manifest, snap.SecretsManager, resources, operations)
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// blockingPersister signals each save on started and then waits for a value on release before completing it.
+type blockingPersister struct {
+	MockStackPersister
+	started chan bool
+	release chan error
+}
+
+func (m *blockingPersister) Save(snap *deploy.Snapshot) error {
+	m.started <- true
+	if err := <-m.release; err != nil {
+		return err
+	}
+	return m.MockStackPersister.Save(snap)
+}
+
+func TestSnapshotMirrorCopiesSnapshot(t *testing.T) {
+	res := NewResource("a-unique-urn")
+	res.Outputs["foo"] = resource.NewStringProperty("bar")
+	snap := NewSnapshot([]*resource.State{res})
+
+	sp := &MockStackPersister{}
+	mirror := NewSnapshotMirror(sp)
+	mirror.Mirror(snap)
+
+	// Changes made by the engine after the snapshot was queued must not leak into the mirror.
+	res.Outputs["foo"] = resource.NewStringProperty("baz")
+
+	assert.NoError(t, mirror.Close())
+	if assert.Len(t, sp.SavedSnapshots, 1) && assert.Len(t, sp.LastSnap().Resources, 1) {
+		mirrored := sp.LastSnap().Resources[0]
+		assert.Equal(t, res.URN, mirrored.URN)
+		assert.Equal(t, resource.NewStringProperty("bar"), mirrored.Outputs["foo"])
+	}
+	assert.Equal(t, 1, mirror.Stats().Writes)
+}
+
+func TestSnapshotMirrorSkipsSupersededSnapshots(t *testing.T) {
+	sp := &blockingPersister{started: make(chan bool), release: make(chan error)}
+	mirror := NewSnapshotMirror(sp)
+
+	first := NewSnapshot([]*resource.State{NewResource("a")})
+	mirror.Mirror(first)
+	<-sp.started
+
+	// While the first write is outstanding, queue two more. Only the last of them should be written.
+	mirror.Mirror(NewSnapshot([]*resource.State{NewResource("a"), NewResource("b")}))
+	mirror.Mirror(NewSnapshot([]*resource.State{NewResource("a"), NewResource("b"), NewResource("c")}))
+	sp.release <- nil
+
+	<-sp.started
+	sp.release <- nil
+
+	assert.NoError(t, mirror.Close())
+	assert.Len(t, sp.SavedSnapshots, 2)
+	assert.Len(t, sp.LastSnap().Resources, 3)
+
+	stats := mirror.Stats()
+	assert.Equal(t, 2, stats.Writes)
+	assert.Equal(t, 1, stats.Skipped)
+	assert.True(t, stats.MaxLag >= stats.LastLag)
+}
+
+func TestSnapshotMirrorReportsFailures(t *testing.T) {
+	sp := &blockingPersister{started: make(chan bool), release: make(chan error)}
+	mirror := NewSnapshotMirror(sp)
+
+	mirror.Mirror(NewSnapshot([]*resource.State{NewResource("a")}))
+	<-sp.started
+	sp.release <- errors.New("bucket unavailable")
+
+	err := mirror.Close()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "bucket unavailable")
+	}
+	assert.Equal(t, 1, mirror.Stats().Failures)
+	assert.Empty(t, sp.SavedSnapshots)
+}
+
+func TestSnapshotManagerMirrorsWrites(t *testing.T) {
+	sameState := NewResource("a-unique-urn")
+	snap := NewSnapshot([]*resource.State{sameState})
+
+	manager, sp := MockSetup(t, snap)
+	mirrorSP := &MockStackPersister{}
+	mirror := NewSnapshotMirror(mirrorSP)
+	manager.SetMirror(mirror)
+
+	same := deploy.NewSameStep(nil, nil, sameState, NewResource(string(sameState.URN)))
+	mutation, err := manager.BeginMutation(same)
+	assert.NoError(t, err)
+	assert.NoError(t, mutation.End(same, true))
+
+	// Close flushes the elided write to both the primary persister and the mirror.
+	assert.NoError(t, manager.Close())
+	assert.NoError(t, mirror.Close())
+
+	assert.Len(t, sp.SavedSnapshots, 1)
+	if assert.Len(t, mirrorSP.SavedSnapshots, 1) && assert.Len(t, mirrorSP.LastSnap().Resources, 1) {
+		assert.Equal(t, sameState.URN, mirrorSP.LastSnap().Resources[0].URN)
+	}
+}
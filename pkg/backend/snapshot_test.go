@@ -15,6 +15,7 @@
 package backend
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -59,7 +60,7 @@ func MockSetup(t *testing.T, baseSnap *deploy.Snapshot) (*SnapshotManager, *Mock
 	}
 
 	sp := &MockStackPersister{}
-	return NewSnapshotManager(sp, baseSnap), sp
+	return NewSnapshotManager(context.Background(), sp, baseSnap), sp
 }
 
 func NewResource(name string, deps ...resource.URN) *resource.State {
@@ -103,11 +104,14 @@ func TestIdenticalSames(t *testing.T) {
 	// Identical sames do not cause a snapshot mutation as part of `End`.
 	assert.Empty(t, sp.SavedSnapshots)
 
+	assert.Equal(t, 0, manager.SnapshotVersion())
+
 	// Close must write the snapshot.
 	err = manager.Close()
 	assert.NoError(t, err)
 
 	assert.NotEmpty(t, sp.SavedSnapshots)
+	assert.Equal(t, len(sp.SavedSnapshots), manager.SnapshotVersion())
 	assert.NotEmpty(t, sp.SavedSnapshots[0].Resources)
 
 	// Our same resource should be the first entry in the snapshot list.
@@ -117,16 +117,25 @@ func ImportStackDeployment(ctx context.Context, s Stack, deployment *apitype.Unt
 	return s.Backend().ImportDeployment(ctx, s.Ref(), deployment)
 }
 
-// GetStackTags fetches the stack's existing tags.
+// GetStackTags fetches the stack's existing tags. It fails if the stack's backend does not store tags.
 func GetStackTags(ctx context.Context, s Stack) (map[apitype.StackTagName]string, error) {
+	if err := RequireCapability(s.Backend(), "stack tags", hasTags); err != nil {
+		return nil, err
+	}
 	return s.Backend().GetStackTags(ctx, s.Ref())
 }
 
-// UpdateStackTags updates the stacks's tags, replacing all existing tags.
+// UpdateStackTags updates the stacks's tags, replacing all existing tags. It fails if the stack's backend does not
+// store tags.
 func UpdateStackTags(ctx context.Context, s Stack, tags map[apitype.StackTagName]string) error {
+	if err := RequireCapability(s.Backend(), "stack tags", hasTags); err != nil {
+		return err
+	}
 	return s.Backend().UpdateStackTags(ctx, s.Ref(), tags)
 }
 
+func hasTags(c Capabilities) bool { return c.Tags }
+
 // GetMergedStackTags returns the stack's existing tags merged with fresh tags from the environment
 // and Pulumi.yaml file.
 func GetMergedStackTags(ctx context.Context, s Stack) (map[apitype.StackTagName]string, error) {
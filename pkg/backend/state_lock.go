@@ -0,0 +1,271 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// stateLeaseDuration is the duration of the lease that an update holds on its stack's state. The lease is renewed
+// after half of this duration has elapsed, and is considered stale, and may be taken over by another update, once
+// this duration has passed without it being renewed.
+var stateLeaseDuration = 5 * time.Minute
+
+// StateLease records the update that holds the lock on a stack's state.
+type StateLease struct {
+	// ID uniquely identifies the update that holds the lease.
+	ID string `json:"id"`
+	// Kind is the kind of the update that holds the lease.
+	Kind apitype.UpdateKind `json:"kind"`
+	// User is the user that started the update.
+	User string `json:"user,omitempty"`
+	// Host is the name of the machine on which the update is running.
+	Host string `json:"host,omitempty"`
+	// PID is the ID of the process that is running the update.
+	PID int `json:"pid,omitempty"`
+	// Acquired is the time at which the lease was acquired.
+	Acquired time.Time `json:"acquired"`
+	// Renewed is the time at which the lease was last renewed.
+	Renewed time.Time `json:"renewed"`
+}
+
+// StateLocker is implemented by snapshot persisters that can record a lease on their stack's state, so that only one
+// update at a time writes it. Persisters that do not implement it leave their stacks unlocked.
+//
+// The lock is only as strong as CreateStateLease: where the underlying storage cannot create the lease atomically,
+// two updates that start at the same moment may both believe that they hold it, and the lock is advisory. Taking
+// over a stale lease and breaking a live one are always advisory.
+type StateLocker interface {
+	// ReadStateLease returns the lease that is currently recorded for the stack, or nil if there is none.
+	ReadStateLease() (*StateLease, error)
+	// CreateStateLease records the given lease for the stack if no lease is recorded, returning false without
+	// recording anything if one is. It must check and record the lease in one atomic step if it can.
+	CreateStateLease(lease StateLease) (bool, error)
+	// WriteStateLease records the given lease for the stack, replacing any existing lease.
+	WriteStateLease(lease StateLease) error
+	// DeleteStateLease removes the lease recorded for the stack, if any.
+	DeleteStateLease() error
+}
+
+// StateLockedError is returned when an update cannot start because another update holds the lease on its stack's
+// state.
+type StateLockedError struct {
+	Lease StateLease    // the lease held by the other update.
+	Age   time.Duration // the time since the lease was last renewed.
+}
+
+// Stale returns true if the lease has not been renewed within the lease duration, which means that the update that
+// holds it has most likely been terminated.
+func (e StateLockedError) Stale() bool {
+	return e.Age > stateLeaseDuration
+}
+
+func (e StateLockedError) Error() string {
+	user := e.Lease.User
+	if user == "" {
+		user = "an unknown user"
+	}
+	msg := fmt.Sprintf("the stack's state is locked by %s update %s started by %s on %s (pid %d); "+
+		"the lock was last renewed %s ago", e.Lease.Kind, e.Lease.ID, user, e.Lease.Host, e.Lease.PID,
+		e.Age.Round(time.Second))
+	if e.Stale() {
+		return msg + "; the lock is stale and may be taken over by passing --takeover-stale-lock"
+	}
+	return msg + "; if you are certain that the update is no longer running, pass --break-lock to proceed anyway"
+}
+
+// errStateLeaseBroken is returned when an update's lease was broken by another update while the update was running.
+var errStateLeaseBroken = errors.New(
+	"the stack's state lock was broken by another update while this update was running")
+
+// StateLeaseHolder holds a lease on a stack's state, renewing it in the background until it is released.
+type StateLeaseHolder struct {
+	locker StateLocker
+	lease  StateLease
+	stop   chan bool
+	done   chan bool
+	lostCh chan struct{} // closed by the renewal loop if the lease was taken over by another update.
+	lost   error         // set by the renewal loop if the lease was taken over by another update.
+}
+
+// AcquireStateLease acquires a lease on a stack's state for an update of the given kind. If another update holds a
+// lease that has not gone stale, AcquireStateLease returns a StateLockedError unless force is true; if the lease has
+// gone stale, it is taken over if takeoverStale or force is true.
+func AcquireStateLease(locker StateLocker, kind apitype.UpdateKind, user string,
+	takeoverStale, force bool) (*StateLeaseHolder, error) {
+
+	existing, err := locker.ReadStateLease()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading state lock")
+	}
+	if existing != nil {
+		locked := StateLockedError{Lease: *existing, Age: time.Since(existing.Renewed)}
+		if !force && !(takeoverStale && locked.Stale()) {
+			return nil, locked
+		}
+		logging.V(7).Infof("breaking state lock held by update %s (last renewed %v ago)", existing.ID, locked.Age)
+	}
+
+	host, err := os.Hostname()
+	contract.IgnoreError(err) // the host is for information only.
+	now := time.Now()
+	lease := StateLease{
+		ID:       uuid.NewV4().String(),
+		Kind:     kind,
+		User:     user,
+		Host:     host,
+		PID:      os.Getpid(),
+		Acquired: now,
+		Renewed:  now,
+	}
+	if existing == nil {
+		// The stack is unlocked, so create the lease. If another update created one first, it wins.
+		created, createErr := locker.CreateStateLease(lease)
+		if createErr != nil {
+			return nil, errors.Wrap(createErr, "writing state lock")
+		}
+		if !created {
+			current, readErr := locker.ReadStateLease()
+			if readErr != nil {
+				return nil, errors.Wrap(readErr, "reading state lock")
+			}
+			if current == nil {
+				return nil, errors.New("the stack's state lock was removed while it was being acquired")
+			}
+			return nil, StateLockedError{Lease: *current, Age: time.Since(current.Renewed)}
+		}
+	} else if err = locker.WriteStateLease(lease); err != nil {
+		return nil, errors.Wrap(err, "writing state lock")
+	}
+
+	// Two updates may have taken over the same lease at once, or the locker may not be able to create leases
+	// atomically, in which case the last one to write its lease wins. Read the lease back to find out whether that
+	// was this one.
+	current, err := locker.ReadStateLease()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading state lock")
+	}
+	switch {
+	case current == nil:
+		return nil, errors.New("the stack's state lock was removed while it was being acquired")
+	case current.ID != lease.ID:
+		return nil, StateLockedError{Lease: *current, Age: time.Since(current.Renewed)}
+	}
+
+	h := &StateLeaseHolder{
+		locker: locker,
+		lease:  lease,
+		stop:   make(chan bool),
+		done:   make(chan bool),
+		lostCh: make(chan struct{}),
+	}
+	go h.renew()
+	return h, nil
+}
+
+// renew renews the lease every half lease duration until the holder is released or the lease is lost.
+func (h *StateLeaseHolder) renew() {
+	defer close(h.done)
+
+	ticker := time.NewTicker(stateLeaseDuration / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := h.locker.ReadStateLease()
+		if err != nil {
+			logging.V(4).Infof("failed to read state lock for renewal: %v", err)
+			continue
+		}
+		if current == nil || current.ID != h.lease.ID {
+			h.lost = errStateLeaseBroken
+			close(h.lostCh)
+			return
+		}
+
+		h.lease.Renewed = time.Now()
+		if err = h.locker.WriteStateLease(h.lease); err != nil {
+			logging.V(4).Infof("failed to renew state lock: %v", err)
+		}
+	}
+}
+
+// Lost returns a channel that is closed if the lease is taken over by another update while it is held. The update
+// that held the lease should stop writing the stack's state as soon as it can.
+func (h *StateLeaseHolder) Lost() <-chan struct{} {
+	return h.lostCh
+}
+
+// CancelOnLoss returns a cancellation context that is canceled and terminated along with the given context, and that
+// is also canceled if the lease is lost, so that an update that has lost its lease stops writing the stack's state.
+// The returned function stops watching for either and must be called once the context is no longer needed.
+func (h *StateLeaseHolder) CancelOnLoss(ctx *cancel.Context) (*cancel.Context, func()) {
+	leaseCtx, source := cancel.NewContext(context.Background())
+	done := make(chan struct{})
+	go func() {
+		canceled, lost := ctx.Canceled(), h.Lost()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Terminated():
+				source.Terminate()
+				return
+			case <-canceled:
+				source.Cancel()
+				canceled = nil
+			case <-lost:
+				logging.V(4).Infof("state lock lost; canceling the update")
+				source.Cancel()
+				lost = nil
+			}
+		}
+	}()
+	return leaseCtx, func() { close(done) }
+}
+
+// Release stops renewing the lease and removes it. If the lease was broken by another update while it was held,
+// Release leaves the other update's lease in place and returns an error.
+func (h *StateLeaseHolder) Release() error {
+	close(h.stop)
+	<-h.done
+	if h.lost != nil {
+		return h.lost
+	}
+
+	current, err := h.locker.ReadStateLease()
+	if err != nil {
+		return errors.Wrap(err, "reading state lock")
+	}
+	if current == nil || current.ID != h.lease.ID {
+		return errStateLeaseBroken
+	}
+	return h.locker.DeleteStateLease()
+}
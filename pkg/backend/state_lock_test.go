@@ -0,0 +1,228 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+)
+
+// mockStateLocker records a stack's lease in memory.
+type mockStateLocker struct {
+	lock  sync.Mutex
+	lease *StateLease
+}
+
+func (l *mockStateLocker) ReadStateLease() (*StateLease, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.lease == nil {
+		return nil, nil
+	}
+	lease := *l.lease
+	return &lease, nil
+}
+
+func (l *mockStateLocker) CreateStateLease(lease StateLease) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.lease != nil {
+		return false, nil
+	}
+	l.lease = &lease
+	return true, nil
+}
+
+func (l *mockStateLocker) WriteStateLease(lease StateLease) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lease = &lease
+	return nil
+}
+
+func (l *mockStateLocker) DeleteStateLease() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lease = nil
+	return nil
+}
+
+func TestStateLease(t *testing.T) {
+	locker := &mockStateLocker{}
+
+	holder, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+	assert.NoError(t, err)
+	lease, err := locker.ReadStateLease()
+	assert.NoError(t, err)
+	if assert.NotNil(t, lease) {
+		assert.Equal(t, apitype.UpdateUpdate, lease.Kind)
+		assert.Equal(t, "alice", lease.User)
+	}
+
+	// A second update is refused while the first holds the lease, even if it asks to take over a stale lock.
+	_, err = AcquireStateLease(locker, apitype.DestroyUpdate, "bob", true, false)
+	if assert.IsType(t, StateLockedError{}, err) {
+		assert.False(t, err.(StateLockedError).Stale())
+		assert.Equal(t, "alice", err.(StateLockedError).Lease.User)
+	}
+
+	// Once the first update releases the lease, the second may proceed.
+	assert.NoError(t, holder.Release())
+	lease, err = locker.ReadStateLease()
+	assert.NoError(t, err)
+	assert.Nil(t, lease)
+
+	holder, err = AcquireStateLease(locker, apitype.DestroyUpdate, "bob", false, false)
+	assert.NoError(t, err)
+	assert.NoError(t, holder.Release())
+}
+
+func TestStateLeaseBreak(t *testing.T) {
+	locker := &mockStateLocker{}
+
+	first, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+	assert.NoError(t, err)
+
+	// A live lease may be broken by force.
+	second, err := AcquireStateLease(locker, apitype.UpdateUpdate, "bob", false, true)
+	assert.NoError(t, err)
+
+	// The first update finds out that it lost its lease, and leaves the second update's lease in place.
+	assert.Error(t, first.Release())
+	lease, err := locker.ReadStateLease()
+	assert.NoError(t, err)
+	if assert.NotNil(t, lease) {
+		assert.Equal(t, "bob", lease.User)
+	}
+	assert.NoError(t, second.Release())
+}
+
+func TestStateLeaseStale(t *testing.T) {
+	locker := &mockStateLocker{}
+
+	// Record a lease that has not been renewed for longer than the lease duration.
+	then := time.Now().Add(-2 * stateLeaseDuration)
+	assert.NoError(t, locker.WriteStateLease(StateLease{ID: "old", Acquired: then, Renewed: then}))
+
+	_, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+	if assert.IsType(t, StateLockedError{}, err) {
+		assert.True(t, err.(StateLockedError).Stale())
+	}
+
+	holder, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", true, false)
+	assert.NoError(t, err)
+	assert.NoError(t, holder.Release())
+}
+
+func TestStateLeaseRenewal(t *testing.T) {
+	defer func(d time.Duration) { stateLeaseDuration = d }(stateLeaseDuration)
+	stateLeaseDuration = 100 * time.Millisecond
+
+	locker := &mockStateLocker{}
+	holder, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+	assert.NoError(t, err)
+
+	// While the lease is held it is renewed, so it never goes stale.
+	time.Sleep(3 * stateLeaseDuration)
+	_, err = AcquireStateLease(locker, apitype.UpdateUpdate, "bob", true, false)
+	if assert.IsType(t, StateLockedError{}, err) {
+		assert.False(t, err.(StateLockedError).Stale())
+	}
+	assert.NoError(t, holder.Release())
+}
+
+func TestStateLeaseConcurrentAcquire(t *testing.T) {
+	locker := &mockStateLocker{}
+
+	// Of many updates that find the stack unlocked at once, exactly one acquires the lease.
+	const updates = 16
+	holders := make(chan *StateLeaseHolder, updates)
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			holder, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+			if err != nil {
+				assert.IsType(t, StateLockedError{}, err)
+				return
+			}
+			holders <- holder
+		}()
+	}
+	wg.Wait()
+	close(holders)
+
+	var acquired []*StateLeaseHolder
+	for holder := range holders {
+		acquired = append(acquired, holder)
+	}
+	if assert.Len(t, acquired, 1) {
+		assert.NoError(t, acquired[0].Release())
+	}
+}
+
+func TestStateLeaseLostCancels(t *testing.T) {
+	defer func(d time.Duration) { stateLeaseDuration = d }(stateLeaseDuration)
+	stateLeaseDuration = 100 * time.Millisecond
+
+	locker := &mockStateLocker{}
+	first, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+	assert.NoError(t, err)
+
+	ctx, source := cancel.NewContext(context.Background())
+	leaseCtx, stop := first.CancelOnLoss(ctx)
+	defer stop()
+	defer source.Cancel()
+
+	// Breaking the lease cancels the first update at its next renewal.
+	second, err := AcquireStateLease(locker, apitype.UpdateUpdate, "bob", false, true)
+	assert.NoError(t, err)
+	select {
+	case <-leaseCtx.Canceled():
+	case <-time.After(10 * stateLeaseDuration):
+		assert.Fail(t, "the update was not canceled when its lease was broken")
+	}
+	assert.Nil(t, leaseCtx.TerminateErr())
+
+	assert.Error(t, first.Release())
+	assert.NoError(t, second.Release())
+}
+
+func TestStateLeaseCancelOnLossForwards(t *testing.T) {
+	locker := &mockStateLocker{}
+	holder, err := AcquireStateLease(locker, apitype.UpdateUpdate, "alice", false, false)
+	assert.NoError(t, err)
+
+	// Cancellation and termination of the update's own context pass through to the lease's context.
+	ctx, source := cancel.NewContext(context.Background())
+	leaseCtx, stop := holder.CancelOnLoss(ctx)
+	defer stop()
+
+	source.Terminate()
+	select {
+	case <-leaseCtx.Terminated():
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the update was not terminated")
+	}
+	assert.NoError(t, holder.Release())
+}
@@ -0,0 +1,106 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stats counts the round trips that the CLI makes to its state backend, so that time spent in the backend
+// protocol can be told apart from time spent performing the operation itself.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Counter accumulates the round trips of a single kind.
+type Counter struct {
+	Calls    int           // the number of round trips made.
+	Duration time.Duration // the total time spent waiting on those round trips.
+	Bytes    int64         // the total number of bytes sent and received.
+}
+
+var (
+	lock     sync.Mutex
+	counters = make(map[string]*Counter)
+)
+
+// Record records a single round trip of the given kind that took the given time and moved the given number of bytes.
+// Kinds are free-form labels such as "state write" or "PATCH /api/stacks/{owner}/{project}/{stack}/...".
+func Record(kind string, elapsed time.Duration, bytes int) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	c, ok := counters[kind]
+	if !ok {
+		c = &Counter{}
+		counters[kind] = c
+	}
+	c.Calls++
+	c.Duration += elapsed
+	c.Bytes += int64(bytes)
+}
+
+// Counters returns a copy of the counters recorded so far, keyed by kind.
+func Counters() map[string]Counter {
+	lock.Lock()
+	defer lock.Unlock()
+
+	result := make(map[string]Counter, len(counters))
+	for kind, c := range counters {
+		result[kind] = *c
+	}
+	return result
+}
+
+// Reset discards all counters recorded so far.
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	counters = make(map[string]*Counter)
+}
+
+// Write writes a table of the counters recorded so far to the given writer, ordered by kind, followed by a total.
+func Write(w io.Writer) error {
+	snapshot := Counters()
+
+	kinds := make([]string, 0, len(snapshot))
+	for kind := range snapshot {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var total Counter
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tCALLS\tTOTAL\tAVERAGE\tBYTES")
+	for _, kind := range kinds {
+		c := snapshot[kind]
+		writeRow(tw, kind, c)
+		total.Calls += c.Calls
+		total.Duration += c.Duration
+		total.Bytes += c.Bytes
+	}
+	writeRow(tw, "total", total)
+	return tw.Flush()
+}
+
+func writeRow(w io.Writer, kind string, c Counter) {
+	var average time.Duration
+	if c.Calls > 0 {
+		average = c.Duration / time.Duration(c.Calls)
+	}
+	fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%d\n", kind, c.Calls, c.Duration, average, c.Bytes)
+}
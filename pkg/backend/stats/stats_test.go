@@ -0,0 +1,45 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	Record("state write", 2*time.Second, 100)
+	Record("state write", 4*time.Second, 50)
+	Record("state read", time.Second, 10)
+
+	counters := Counters()
+	assert.Equal(t, Counter{Calls: 2, Duration: 6 * time.Second, Bytes: 150}, counters["state write"])
+	assert.Equal(t, Counter{Calls: 1, Duration: time.Second, Bytes: 10}, counters["state read"])
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[1], "state read"))
+	assert.True(t, strings.HasPrefix(lines[2], "state write"))
+	assert.Equal(t, []string{"total", "3", "7s", "2.333333333s", "160"}, strings.Fields(lines[3]))
+}
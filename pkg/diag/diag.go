@@ -16,11 +16,31 @@ package diag
 
 import (
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
 )
 
 // ID is a unique diagnostics identifier.
 type ID int
 
+// Source identifies the component that issued a diagnostic: the engine, the language host that runs the program, or
+// the plugin for a particular package.
+type Source string
+
+const (
+	EngineSource   Source = "engine"   // the engine itself.
+	LanguageSource Source = "language" // the language host and the program that it runs.
+)
+
+// ProviderSource returns the source of diagnostics issued by the resource provider for the given package.
+func ProviderSource(pkg tokens.Package) Source {
+	return Source("provider:" + string(pkg))
+}
+
+// AnalyzerSource returns the source of diagnostics issued by the given analyzer.
+func AnalyzerSource(name tokens.QName) Source {
+	return Source("analyzer:" + string(name))
+}
+
 // Diag is an instance of an error or warning generated by the compiler.
 type Diag struct {
 	URN     resource.URN // Resource this diagnostics is associated with.  Empty if not associated with any resource.
@@ -31,6 +51,17 @@ type Diag struct {
 	// An ID used to collate a stream of conceptually sequential messages.  0 means that the message
 	// is not part of any sequential message stream.
 	StreamID int32
+
+	// The component that issued this diagnostic.  Empty means the engine.
+	Source Source
+}
+
+// GetSource returns the component that issued this diagnostic.
+func (d *Diag) GetSource() Source {
+	if d.Source == "" {
+		return EngineSource
+	}
+	return d.Source
 }
 
 // Message returns an anonymous diagnostic message without any source or ID information.
@@ -26,7 +26,7 @@ func newError(urn resource.URN, id ID, message string) *Diag {
 // Plan and apply errors are in the [2000,3000) range.
 
 func GetPlanApplyFailedError(urn resource.URN) *Diag {
-	return newError(urn, 2000, "Plan apply failed: %v")
+	return newError(urn, 2000, "Plan apply failed (%v): %v")
 }
 
 func GetDuplicateResourceURNError(urn resource.URN) *Diag {
@@ -58,3 +58,10 @@ func GetDuplicateResourceAliasError(urn resource.URN) *Diag {
 		"Duplicate resource alias '%v' applied to resource with URN '%v' conflicting with resource with URN '%v'",
 	)
 }
+
+func GetResourceAliasConflictError(urn resource.URN) *Diag {
+	return newError(urn, 2009,
+		"Resource '%v' is claimed both by resource with URN '%v' and, through an alias, by resource with URN '%v'; "+
+			"remove the alias or give one of the resources a different name",
+	)
+}
@@ -0,0 +1,334 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// MsgPack marshals values using MessagePack (https://msgpack.org). Values are converted as they would be for JSON, so
+// any value that can be marshaled as JSON can be marshaled as MessagePack, but the result is smaller and faster to
+// read and write. Map keys are written in sorted order, so that the same value is always marshaled to the same bytes.
+var MsgPack Marshaler = &msgpackMarshaler{}
+
+type msgpackMarshaler struct {
+}
+
+func (m *msgpackMarshaler) IsJSONLike() bool {
+	return false
+}
+
+func (m *msgpackMarshaler) IsYAMLLike() bool {
+	return false
+}
+
+func (m *msgpackMarshaler) Marshal(v interface{}) ([]byte, error) {
+	plain, err := toPlain(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	var w msgpackWriter
+	if err = w.value(plain); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+func (m *msgpackMarshaler) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("cannot unmarshal into a non-pointer value of type %T", v)
+	}
+
+	r := msgpackReader{data: data}
+	plain, err := r.value()
+	if err != nil {
+		return err
+	}
+	if r.off != len(r.data) {
+		return errors.Errorf("unexpected data after MessagePack value at offset %d", r.off)
+	}
+	return fromPlain(plain, rv.Elem())
+}
+
+// msgpackWriter writes plain values in the MessagePack format, using the most compact representation of each.
+type msgpackWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *msgpackWriter) value(plain interface{}) error {
+	switch v := plain.(type) {
+	case nil:
+		w.buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			w.buf.WriteByte(0xc3)
+		} else {
+			w.buf.WriteByte(0xc2)
+		}
+	case int64:
+		w.int(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			w.int(int64(v))
+		} else {
+			w.buf.WriteByte(0xcf)
+			w.uint(v, 8)
+		}
+	case float64:
+		w.buf.WriteByte(0xcb)
+		w.uint(math.Float64bits(v), 8)
+	case string:
+		w.header(len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		w.buf.WriteString(v)
+	case []byte:
+		w.header(len(v), 0, 0, 0xc4, 0xc5, 0xc6)
+		w.buf.Write(v)
+	case []interface{}:
+		w.header(len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, elem := range v {
+			if err := w.value(elem); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		w.header(len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			if err := w.value(k); err != nil {
+				return err
+			}
+			if err := w.value(v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("cannot marshal value of type %T", plain)
+	}
+	return nil
+}
+
+// int writes a signed integer.
+func (w *msgpackWriter) int(v int64) {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		w.buf.WriteByte(byte(v)) // positive fixint
+	case v >= -32 && v < 0:
+		w.buf.WriteByte(byte(v)) // negative fixint
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		w.buf.WriteByte(0xd0)
+		w.uint(uint64(v), 1)
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		w.buf.WriteByte(0xd1)
+		w.uint(uint64(v), 2)
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		w.buf.WriteByte(0xd2)
+		w.uint(uint64(v), 4)
+	default:
+		w.buf.WriteByte(0xd3)
+		w.uint(uint64(v), 8)
+	}
+}
+
+// uint writes the low size bytes of v in big-endian order.
+func (w *msgpackWriter) uint(v uint64, size int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	w.buf.Write(b[8-size:])
+}
+
+// header writes the header of a string, binary, array, or map of the given length. The fix format, whose type byte
+// holds the length itself, is used for lengths less than fixMax if the type has one (that is, fixMax is non-zero);
+// otherwise, the smallest of the 8-, 16-, and 32-bit formats whose type byte is non-zero is used.
+func (w *msgpackWriter) header(n int, fix byte, fixMax int, type8, type16, type32 byte) {
+	switch {
+	case fixMax != 0 && n < fixMax:
+		w.buf.WriteByte(fix | byte(n))
+	case type8 != 0 && n <= math.MaxUint8:
+		w.buf.WriteByte(type8)
+		w.uint(uint64(n), 1)
+	case n <= math.MaxUint16:
+		w.buf.WriteByte(type16)
+		w.uint(uint64(n), 2)
+	default:
+		w.buf.WriteByte(type32)
+		w.uint(uint64(n), 4)
+	}
+}
+
+// msgpackReader reads plain values in the MessagePack format.
+type msgpackReader struct {
+	data []byte
+	off  int
+}
+
+func (r *msgpackReader) value() (interface{}, error) {
+	t, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return r.mapOf(int(t & 0x0f))
+	case t&0xf0 == 0x90:
+		return r.arrayOf(int(t & 0x0f))
+	case t&0xe0 == 0xa0:
+		return r.str(int(t & 0x1f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (t - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.bytes(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xca:
+		bits, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := r.uint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return r.uint(1 << (t - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (t - 0xd0)
+		v, err := r.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend the value from its encoded size.
+		shift := uint(64 - 8*size)
+		return int64(v<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (t - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.arrayOf(int(n))
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.mapOf(int(n))
+	default:
+		return nil, errors.Errorf("unsupported MessagePack type 0x%02x at offset %d", t, r.off-1)
+	}
+}
+
+func (r *msgpackReader) byte() (byte, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *msgpackReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.off {
+		return nil, errors.Errorf("unexpected end of MessagePack data at offset %d", r.off)
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of the given size in bytes.
+func (r *msgpackReader) uint(size int) (uint64, error) {
+	b, err := r.bytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (r *msgpackReader) str(n int) (string, error) {
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+func (r *msgpackReader) arrayOf(n int) ([]interface{}, error) {
+	// Every element takes at least a byte, which bounds the length that a well-formed array can claim.
+	if n > len(r.data)-r.off {
+		return nil, errors.Errorf("unexpected end of MessagePack data at offset %d", r.off)
+	}
+	arr := make([]interface{}, n)
+	for i := range arr {
+		elem, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = elem
+	}
+	return arr, nil
+}
+
+func (r *msgpackReader) mapOf(n int) (map[string]interface{}, error) {
+	if n > len(r.data)-r.off {
+		return nil, errors.Errorf("unexpected end of MessagePack data at offset %d", r.off)
+	}
+	obj := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, errors.Errorf("unsupported MessagePack map key of type %T at offset %d", key, r.off)
+		}
+		if obj[k], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
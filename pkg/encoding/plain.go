@@ -0,0 +1,525 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// The binary marshalers encode values in two passes: a value is first converted to a "plain" value, which is built
+// only from nil, bool, int64, uint64, float64, string, []byte, []interface{}, and map[string]interface{}, and the
+// plain value is then written in the marshaler's format. Unmarshaling reverses the process.
+//
+// Conversion to and from plain values follows the rules of encoding/json, so that any type that can be marshaled as
+// JSON, such as those in the apitype package, can be marshaled in a binary format without change: struct fields are
+// named and omitted according to their `json` tags, and types that implement json.Marshaler or
+// encoding.TextMarshaler are converted through their JSON or text forms. Unlike encoding/json, byte slices are kept as
+// bytes rather than being encoded as base64 strings.
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// toPlain converts the given value to a plain value.
+func toPlain(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+
+	if v.Type().Implements(jsonMarshalerType) {
+		b, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var plain interface{}
+		if err = json.Unmarshal(b, &plain); err != nil {
+			return nil, err
+		}
+		return plain, nil
+	}
+	if v.Kind() != reflect.Ptr && v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return toPlain(v.Elem())
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return append([]byte(nil), v.Bytes()...), nil
+		}
+		fallthrough
+	case reflect.Array:
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			elem, err := toPlain(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = elem
+		}
+		return arr, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		obj := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			k, err := plainKey(key)
+			if err != nil {
+				return nil, err
+			}
+			if obj[k], err = toPlain(v.MapIndex(key)); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case reflect.Struct:
+		obj := make(map[string]interface{})
+		for _, f := range plainFields(v.Type()) {
+			fv, ok := fieldByIndex(v, f.index, false)
+			if !ok || f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			value, err := toPlain(fv)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.name] = value
+		}
+		return obj, nil
+	default:
+		return nil, errors.Errorf("cannot marshal value of type %v", v.Type())
+	}
+}
+
+// plainKey converts the given map key to a string.
+func plainKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshalerType) {
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	default:
+		return "", errors.Errorf("cannot marshal map key of type %v", key.Type())
+	}
+}
+
+// fromPlain stores the given plain value in v, which must be settable.
+func fromPlain(plain interface{}, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if plain == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return fromPlain(plain, v.Elem())
+	}
+
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(jsonUnmarshalerType) {
+		b, err := json.Marshal(jsonValue(plain))
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(b)
+	}
+	if s, ok := plain.(string); ok && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if plain == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	mismatch := func() error {
+		return errors.Errorf("cannot unmarshal %T into a value of type %v", plain, v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return mismatch()
+		}
+		if jv := jsonValue(plain); jv != nil {
+			v.Set(reflect.ValueOf(jv))
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	case reflect.Bool:
+		b, ok := plain.(bool)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := plain.(type) {
+		case int64:
+			v.SetInt(n)
+		case uint64:
+			v.SetInt(int64(n))
+		case float64:
+			v.SetInt(int64(n))
+		default:
+			return mismatch()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch n := plain.(type) {
+		case int64:
+			v.SetUint(uint64(n))
+		case uint64:
+			v.SetUint(n)
+		case float64:
+			v.SetUint(uint64(n))
+		default:
+			return mismatch()
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := plain.(type) {
+		case int64:
+			v.SetFloat(float64(n))
+		case uint64:
+			v.SetFloat(float64(n))
+		case float64:
+			v.SetFloat(n)
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		s, ok := plain.(string)
+		if !ok {
+			return mismatch()
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			switch b := plain.(type) {
+			case []byte:
+				v.SetBytes(append([]byte(nil), b...))
+				return nil
+			case string:
+				// Formats without a bytes type, such as protobuf's Value, hold bytes as base64 strings.
+				decoded, err := base64.StdEncoding.DecodeString(b)
+				if err != nil {
+					return err
+				}
+				v.SetBytes(decoded)
+				return nil
+			}
+		}
+		arr, ok := plain.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+		for i, elem := range arr {
+			if err := fromPlain(elem, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		arr, ok := plain.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for i := 0; i < v.Len(); i++ {
+			var elem interface{}
+			if i < len(arr) {
+				elem = arr[i]
+			}
+			if err := fromPlain(elem, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		obj, ok := plain.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(obj))
+		for k, elem := range obj {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := fromPlainKey(k, key); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := fromPlain(elem, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		obj, ok := plain.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		fields := plainFields(v.Type())
+		for k, elem := range obj {
+			f, ok := findPlainField(fields, k)
+			if !ok {
+				continue
+			}
+			fv, ok := fieldByIndex(v, f.index, true)
+			if !ok {
+				continue
+			}
+			if err := fromPlain(elem, fv); err != nil {
+				return errors.Wrapf(err, "field %s", f.name)
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// fromPlainKey stores the given map key in key, which must be settable.
+func fromPlainKey(k string, key reflect.Value) error {
+	if key.Kind() == reflect.String && !reflect.PtrTo(key.Type()).Implements(textUnmarshalerType) {
+		key.SetString(k)
+		return nil
+	}
+	if reflect.PtrTo(key.Type()).Implements(textUnmarshalerType) {
+		return key.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(k))
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return err
+		}
+		key.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return err
+		}
+		key.SetUint(n)
+	default:
+		return errors.Errorf("cannot unmarshal map key into a value of type %v", key.Type())
+	}
+	return nil
+}
+
+// jsonValue converts the given plain value to the value that encoding/json would have produced when unmarshaling into
+// an empty interface: numbers become float64s and bytes become base64 strings.
+func jsonValue(plain interface{}) interface{} {
+	switch p := plain.(type) {
+	case int64:
+		return float64(p)
+	case uint64:
+		return float64(p)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(p)
+	case []interface{}:
+		arr := make([]interface{}, len(p))
+		for i, elem := range p {
+			arr[i] = jsonValue(elem)
+		}
+		return arr
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(p))
+		for k, elem := range p {
+			obj[k] = jsonValue(elem)
+		}
+		return obj
+	default:
+		return p
+	}
+}
+
+// plainField describes a struct field as it appears in a plain value.
+type plainField struct {
+	name      string // the field's name, from its `json` tag if it has one.
+	index     []int  // the field's index sequence, for use with reflect.Value.FieldByIndex.
+	omitEmpty bool   // true if the field is omitted when it holds its type's empty value.
+}
+
+var plainFieldCache sync.Map // map[reflect.Type][]plainField
+
+// plainFields returns the fields of the given struct type that appear in its plain values, following the rules of
+// encoding/json for naming, omitting, and embedding fields.
+func plainFields(t reflect.Type) []plainField {
+	if cached, ok := plainFieldCache.Load(t); ok {
+		return cached.([]plainField)
+	}
+
+	var fields []plainField
+	seen := make(map[string]int)
+	var visit func(t reflect.Type, index []int, depth int)
+	visit = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts := tag, ""
+			if comma := strings.Index(tag, ","); comma != -1 {
+				name, opts = tag[:comma], tag[comma+1:]
+			}
+
+			fieldIndex := append(append([]int(nil), index...), i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				visit(ft, fieldIndex, depth+1)
+				continue
+			}
+			if sf.PkgPath != "" {
+				continue // unexported.
+			}
+			if name == "" {
+				name = sf.Name
+			}
+
+			// As with encoding/json, a field hides any field of the same name that is embedded more deeply.
+			if prev, has := seen[name]; has {
+				if prev <= depth {
+					continue
+				}
+				for j := range fields {
+					if fields[j].name == name {
+						fields = append(fields[:j], fields[j+1:]...)
+						break
+					}
+				}
+			}
+			seen[name] = depth
+			fields = append(fields, plainField{
+				name:      name,
+				index:     fieldIndex,
+				omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+			})
+		}
+	}
+	visit(t, nil, 0)
+
+	sort.SliceStable(fields, func(i, j int) bool { return lessIndex(fields[i].index, fields[j].index) })
+	plainFieldCache.Store(t, fields)
+	return fields
+}
+
+// lessIndex orders field index sequences by their position in the struct.
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// findPlainField finds the field with the given name, preferring an exact match but, as with encoding/json, accepting
+// a case-insensitive one.
+func findPlainField(fields []plainField, name string) (plainField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return plainField{}, false
+}
+
+// fieldByIndex returns the field of v with the given index sequence. Nil embedded pointers are allocated if alloc is
+// true; otherwise, the field is reported as missing.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue returns true if the given value is omitted by an `omitempty` field.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
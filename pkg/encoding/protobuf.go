@@ -0,0 +1,142 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"encoding/base64"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/pkg/errors"
+)
+
+// Protobuf marshals values as a google.protobuf.Value message in the protocol buffers binary format. Values are
+// converted as they would be for JSON, so any value that can be marshaled as JSON can be marshaled this way, and the
+// result can be read by any protocol buffers implementation without a schema. As with JSON, numbers are doubles and
+// bytes are base64 strings. The encoding is deterministic, so the same value is always marshaled to the same bytes.
+var Protobuf Marshaler = &protobufMarshaler{}
+
+type protobufMarshaler struct {
+}
+
+func (m *protobufMarshaler) IsJSONLike() bool {
+	return false
+}
+
+func (m *protobufMarshaler) IsYAMLLike() bool {
+	return false
+}
+
+func (m *protobufMarshaler) Marshal(v interface{}) ([]byte, error) {
+	plain, err := toPlain(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	pv, err := plainToProtobuf(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	// Struct fields are maps, so the encoding must be made deterministic for the output to be stable.
+	var buf proto.Buffer
+	buf.SetDeterministic(true)
+	if err = buf.Marshal(pv); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *protobufMarshaler) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("cannot unmarshal into a non-pointer value of type %T", v)
+	}
+
+	var pv structpb.Value
+	if err := proto.Unmarshal(data, &pv); err != nil {
+		return err
+	}
+	return fromPlain(protobufToPlain(&pv), rv.Elem())
+}
+
+// plainToProtobuf converts a plain value to a protobuf Value.
+func plainToProtobuf(plain interface{}) (*structpb.Value, error) {
+	switch v := plain.(type) {
+	case nil:
+		return &structpb.Value{Kind: &structpb.Value_NullValue{NullValue: structpb.NullValue_NULL_VALUE}}, nil
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}, nil
+	case int64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: float64(v)}}, nil
+	case uint64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: float64(v)}}, nil
+	case float64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v}}, nil
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}, nil
+	case []byte:
+		s := base64.StdEncoding.EncodeToString(v)
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: s}}, nil
+	case []interface{}:
+		list := &structpb.ListValue{Values: make([]*structpb.Value, len(v))}
+		for i, elem := range v {
+			pv, err := plainToProtobuf(elem)
+			if err != nil {
+				return nil, err
+			}
+			list.Values[i] = pv
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: list}}, nil
+	case map[string]interface{}:
+		obj := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(v))}
+		for k, elem := range v {
+			pv, err := plainToProtobuf(elem)
+			if err != nil {
+				return nil, err
+			}
+			obj.Fields[k] = pv
+		}
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: obj}}, nil
+	default:
+		return nil, errors.Errorf("cannot marshal value of type %T", plain)
+	}
+}
+
+// protobufToPlain converts a protobuf Value to a plain value.
+func protobufToPlain(pv *structpb.Value) interface{} {
+	switch k := pv.GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		return k.BoolValue
+	case *structpb.Value_NumberValue:
+		return k.NumberValue
+	case *structpb.Value_StringValue:
+		return k.StringValue
+	case *structpb.Value_ListValue:
+		arr := make([]interface{}, len(k.ListValue.GetValues()))
+		for i, elem := range k.ListValue.GetValues() {
+			arr[i] = protobufToPlain(elem)
+		}
+		return arr
+	case *structpb.Value_StructValue:
+		obj := make(map[string]interface{}, len(k.StructValue.GetFields()))
+		for key, elem := range k.StructValue.GetFields() {
+			obj[key] = protobufToPlain(elem)
+		}
+		return obj
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,453 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func convertStepEventMetadata(md StepEventMetadata) apitype.StepEventMetadata {
+	keys := make([]string, len(md.Keys))
+	for i, v := range md.Keys {
+		keys[i] = string(v)
+	}
+	var diffs []string
+	for _, v := range md.Diffs {
+		diffs = append(diffs, string(v))
+	}
+
+	return apitype.StepEventMetadata{
+		Op:   string(md.Op),
+		URN:  string(md.URN),
+		Type: string(md.Type),
+
+		Old: convertStepEventStateMetadata(md.Old),
+		New: convertStepEventStateMetadata(md.New),
+
+		Keys:     keys,
+		Diffs:    diffs,
+		Logical:  md.Logical,
+		Provider: md.Provider,
+	}
+}
+
+func convertStepEventStateMetadata(md *StepEventStateMetadata) *apitype.StepEventStateMetadata {
+	if md == nil {
+		return nil
+	}
+
+	inputs := make(map[string]interface{})
+	for k, v := range md.Inputs {
+		inputs[string(k)] = v
+	}
+	outputs := make(map[string]interface{})
+	for k, v := range md.Outputs {
+		outputs[string(k)] = v
+	}
+
+	return &apitype.StepEventStateMetadata{
+		Type: string(md.Type),
+		URN:  string(md.URN),
+
+		Custom:     md.Custom,
+		Delete:     md.Delete,
+		ID:         string(md.ID),
+		Parent:     string(md.Parent),
+		Protect:    md.Protect,
+		Inputs:     inputs,
+		Outputs:    outputs,
+		InitErrors: md.InitErrors,
+
+		Annotations: md.Annotations,
+	}
+}
+
+func convertDeprecations(usages []deploy.DeprecatedUsage) []apitype.DeprecatedUsage {
+	var result []apitype.DeprecatedUsage
+	for _, u := range usages {
+		result = append(result, apitype.DeprecatedUsage{
+			Type:        string(u.Type),
+			Property:    string(u.Property),
+			Message:     u.Message,
+			Replacement: u.Replacement,
+			Count:       u.Count,
+		})
+	}
+	return result
+}
+
+func convertEstimates(estimates []PlanEstimate) []apitype.PlanEstimate {
+	var result []apitype.PlanEstimate
+	for _, e := range estimates {
+		result = append(result, apitype.PlanEstimate{
+			Estimator:        e.Estimator,
+			MonthlyCostDelta: e.MonthlyCostDelta,
+			Currency:         e.Currency,
+			BlastRadius:      e.BlastRadius,
+			Notes:            e.Notes,
+		})
+	}
+	return result
+}
+
+func convertOutputChanges(changes OutputChanges) *apitype.OutputChanges {
+	if !changes.HasChanges() {
+		return nil
+	}
+
+	result := &apitype.OutputChanges{}
+	for _, c := range changes.Added {
+		if result.Added == nil {
+			result.Added = make(map[string]interface{})
+		}
+		result.Added[string(c.Key)] = c.New.Mappable()
+	}
+	for _, c := range changes.Changed {
+		if result.Changed == nil {
+			result.Changed = make(map[string]apitype.OutputChange)
+		}
+		result.Changed[string(c.Key)] = apitype.OutputChange{Old: c.Old.Mappable(), New: c.New.Mappable()}
+	}
+	for _, c := range changes.Removed {
+		if result.Removed == nil {
+			result.Removed = make(map[string]interface{})
+		}
+		result.Removed[string(c.Key)] = c.Old.Mappable()
+	}
+	return result
+}
+
+// ConvertEvent converts a raw Event into an apitype.EngineEvent, the versioned, JSON-serializable form of the event
+// that is used in the Pulumi REST API. Returns an error if the event is unknown or not in an expected format.
+// EngineEvent.{ Sequence, Timestamp } are expected to be set by the caller.
+func ConvertEvent(e Event) (apitype.EngineEvent, error) {
+	apiEvent := apitype.EngineEvent{Version: e.Version}
+	if apiEvent.Version == 0 {
+		apiEvent.Version = EventSchemaVersion
+	}
+
+	// Error to return if the payload doesn't match expected.
+	eventTypePayloadMismatch := errors.Errorf("unexpected payload for event type %v", e.Type)
+
+	switch e.Type {
+	case CancelEvent:
+		apiEvent.CancelEvent = &apitype.CancelEvent{}
+
+	case StdoutColorEvent:
+		p, ok := e.Payload.(StdoutEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.StdoutEvent = &apitype.StdoutEngineEvent{
+			Message: p.Message,
+			Color:   string(p.Color),
+		}
+
+	case DiagEvent:
+		p, ok := e.Payload.(DiagEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.DiagnosticEvent = &apitype.DiagnosticEvent{
+			URN:       string(p.URN),
+			Prefix:    p.Prefix,
+			Message:   p.Message,
+			Color:     string(p.Color),
+			Severity:  string(p.Severity),
+			Ephemeral: p.Ephemeral,
+		}
+
+	case PolicyViolationEvent:
+		p, ok := e.Payload.(PolicyViolationEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.PolicyEvent = &apitype.PolicyEvent{
+			ResourceURN:       string(p.ResourceURN),
+			Message:           p.Message,
+			Color:             string(p.Color),
+			PolicyName:        p.PolicyName,
+			PolicyPackName:    p.PolicyPackName,
+			PolicyPackVersion: p.PolicyPackVersion,
+			EnforcementLevel:  string(p.EnforcementLevel),
+		}
+
+	case PreludeEvent:
+		p, ok := e.Payload.(PreludeEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		// Convert the config bag.
+		cfg := make(map[string]string)
+		for k, v := range p.Config {
+			cfg[k] = v
+		}
+		apiEvent.PreludeEvent = &apitype.PreludeEvent{
+			Config: cfg,
+		}
+
+	case SummaryEvent:
+		p, ok := e.Payload.(SummaryEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		// Convert the resource changes.
+		changes := make(map[string]int)
+		for op, count := range p.ResourceChanges {
+			changes[string(op)] = count
+		}
+		apiEvent.SummaryEvent = &apitype.SummaryEvent{
+			MaybeCorrupt:    p.MaybeCorrupt,
+			DurationSeconds: int(p.Duration.Seconds()),
+			ResourceChanges: changes,
+			OutputChanges:   convertOutputChanges(p.OutputChanges),
+			Deprecations:    convertDeprecations(p.Deprecations),
+			Estimates:       convertEstimates(p.Estimates),
+		}
+
+	case ResourcePreEvent:
+		p, ok := e.Payload.(ResourcePreEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.ResourcePreEvent = &apitype.ResourcePreEvent{
+			Metadata: convertStepEventMetadata(p.Metadata),
+			Planning: p.Planning,
+		}
+
+	case ResourceOutputsEvent:
+		p, ok := e.Payload.(ResourceOutputsEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.ResOutputsEvent = &apitype.ResOutputsEvent{
+			Metadata: convertStepEventMetadata(p.Metadata),
+			Planning: p.Planning,
+		}
+
+	case ResourceOperationFailed:
+		p, ok := e.Payload.(ResourceOperationFailedPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.ResOpFailedEvent = &apitype.ResOpFailedEvent{
+			Metadata:  convertStepEventMetadata(p.Metadata),
+			Status:    int(p.Status),
+			Steps:     p.Steps,
+			Kind:      string(p.Kind),
+			Code:      p.Code.String(),
+			Retryable: p.Retryable,
+		}
+		if d := p.Details; d != nil {
+			apiEvent.ResOpFailedEvent.Details = &apitype.FailureDetails{
+				Code:        d.Code,
+				Retryable:   d.Retryable,
+				Remediation: d.Remediation,
+				DocsURL:     d.DocsURL,
+			}
+		}
+
+	case StepProgressEvent:
+		p, ok := e.Payload.(StepProgressEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.ProgressEvent = &apitype.StepProgressEvent{
+			Metadata: convertStepEventMetadata(p.Metadata),
+			Percent:  p.Percent,
+			Message:  p.Message,
+		}
+
+	case PluginLifecycleEvent:
+		p, ok := e.Payload.(PluginLifecycleEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.LifecycleEvent = &apitype.PluginLifecycleEvent{
+			Metadata: convertStepEventMetadata(p.Metadata),
+			Plugin:   p.Plugin,
+			Message:  p.Message,
+		}
+
+	case ConfirmationRequiredEvent:
+		p, ok := e.Payload.(ConfirmationRequiredEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.ConfirmationRequiredEvent = &apitype.ConfirmationRequiredEvent{
+			Metadata: convertStepEventMetadata(p.Metadata),
+		}
+
+	case PlanCacheEvent:
+		p, ok := e.Payload.(PlanCacheEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.PlanCacheEvent = &apitype.PlanCacheEvent{
+			Key:    p.Key,
+			Hit:    p.Hit,
+			Forced: p.Forced,
+		}
+		if !p.Created.IsZero() {
+			apiEvent.PlanCacheEvent.Created = p.Created.Unix()
+		}
+
+	case StepDependenciesEvent:
+		p, ok := e.Payload.(StepDependenciesEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		dependencies := make([]apitype.StepDependency, len(p.Dependencies))
+		for i, d := range p.Dependencies {
+			dependencies[i] = apitype.StepDependency{URN: string(d.URN), Reason: string(d.Reason)}
+			for _, k := range d.Properties {
+				dependencies[i].Properties = append(dependencies[i].Properties, string(k))
+			}
+		}
+		apiEvent.StepDependenciesEvent = &apitype.StepDependenciesEvent{
+			Metadata:     convertStepEventMetadata(p.Metadata),
+			Dependencies: dependencies,
+		}
+
+	case CancellationEvent:
+		p, ok := e.Payload.(CancellationEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.CancellationEvent = &apitype.CancellationEvent{
+			Phase:      string(p.Phase),
+			Terminated: p.Terminated,
+		}
+		for _, urn := range p.InFlight {
+			apiEvent.CancellationEvent.InFlight = append(apiEvent.CancellationEvent.InFlight, string(urn))
+		}
+		for _, urn := range p.Completed {
+			apiEvent.CancellationEvent.Completed = append(apiEvent.CancellationEvent.Completed, string(urn))
+		}
+
+	case RefreshProgressEvent:
+		p, ok := e.Payload.(RefreshProgressEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.RefreshProgressEvent = &apitype.RefreshProgressEvent{
+			Completed: p.Completed,
+			Failed:    p.Failed,
+			Total:     p.Total,
+		}
+
+	case AggregateProgressEvent:
+		p, ok := e.Payload.(AggregateProgressEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		ops := make(map[string]int)
+		for op, count := range p.Ops {
+			ops[string(op)] = count
+		}
+		apiEvent.AggregateProgressEvent = &apitype.AggregateProgressEvent{
+			Processed: p.Processed,
+			Total:     p.Total,
+			Ops:       ops,
+			Done:      p.Done,
+		}
+
+	case DeletionOrderEvent:
+		p, ok := e.Payload.(DeletionOrderEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.DeletionOrderEvent = &apitype.DeletionOrderEvent{}
+		for _, batch := range p.Batches {
+			urns := make([]string, len(batch))
+			for i, urn := range batch {
+				urns[i] = string(urn)
+			}
+			apiEvent.DeletionOrderEvent.Batches = append(apiEvent.DeletionOrderEvent.Batches, urns)
+		}
+
+	case PausedEvent:
+		p, ok := e.Payload.(PausedEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.PausedEvent = &apitype.PausedEvent{Paused: p.Paused}
+
+	case InitErrorsEvent:
+		p, ok := e.Payload.(InitErrorsEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.InitErrorsEvent = &apitype.InitErrorsEvent{
+			URN:     string(p.URN),
+			Action:  string(p.Action),
+			Errors:  p.Errors,
+			Message: p.Message,
+		}
+
+	case PendingDeleteEvent:
+		p, ok := e.Payload.(PendingDeleteEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.PendingDeleteEvent = &apitype.PendingDeleteEvent{
+			URN:      string(p.URN),
+			ID:       string(p.ID),
+			Action:   string(p.Action),
+			Attempts: p.Attempts,
+			Message:  p.Message,
+		}
+
+	case ProviderChangeEvent:
+		p, ok := e.Payload.(ProviderChangeEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		change := &apitype.ProviderChangeEvent{URN: string(p.URN), Op: string(p.Op)}
+		for _, k := range p.Keys {
+			change.Keys = append(change.Keys, string(k))
+		}
+		for _, k := range p.ReplaceKeys {
+			change.ReplaceKeys = append(change.ReplaceKeys, string(k))
+		}
+		for _, urn := range p.Dependents {
+			change.Dependents = append(change.Dependents, string(urn))
+		}
+		apiEvent.ProviderChangeEvent = change
+
+	case CheckResultsEvent:
+		p, ok := e.Payload.(CheckResultsEventPayload)
+		if !ok {
+			return apiEvent, eventTypePayloadMismatch
+		}
+		apiEvent.CheckResultsEvent = &apitype.CheckResultsEvent{}
+		for _, check := range p.Checks {
+			apiEvent.CheckResultsEvent.Checks = append(apiEvent.CheckResultsEvent.Checks, apitype.CheckResult{
+				Name:            check.Name,
+				Passed:          check.Passed,
+				Message:         check.Message,
+				DurationSeconds: check.Duration.Seconds(),
+			})
+		}
+
+	default:
+		return apiEvent, errors.Errorf("unknown event type %q", e.Type)
+	}
+
+	return apiEvent, nil
+}
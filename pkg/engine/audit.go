@@ -0,0 +1,290 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// AuditResult is the outcome of an audited step.
+type AuditResult string
+
+const (
+	AuditSucceeded       AuditResult = "succeeded"        // the step was applied.
+	AuditPartiallyFailed AuditResult = "partially-failed" // the step failed, but some of its effects were applied.
+	AuditFailed          AuditResult = "failed"           // the step failed.
+)
+
+// AuditEntry records a single step applied by the engine. Rather than the properties of the step's resource, an entry
+// records hashes of them, so that changes can be detected without the log revealing any values, secret or otherwise.
+//
+// Each entry also records the hash of the entry before it, and its own hash covers every other field, so that no
+// entry can be altered, removed, or reordered without breaking the chain.
+type AuditEntry struct {
+	Sequence  int                `json:"sequence"`         // the entry's position in the log, starting at 1.
+	Time      time.Time          `json:"time"`             // the time at which the step completed.
+	Principal string             `json:"principal"`        // the identity on whose behalf the step was applied.
+	Project   tokens.PackageName `json:"project"`          // the project whose stack was updated.
+	Stack     tokens.QName       `json:"stack"`            // the stack that was updated.
+	URN       resource.URN       `json:"urn"`              // the resource affected by the step.
+	Op        deploy.StepOp      `json:"op"`               // the operation the step performed.
+	Result    AuditResult        `json:"result"`           // the outcome of the step.
+	Error     string             `json:"error,omitempty"`  // the error the step failed with, if any.
+	Before    string             `json:"before,omitempty"` // a hash of the resource's properties before the step.
+	After     string             `json:"after,omitempty"`  // a hash of the resource's properties after the step.
+	Previous  string             `json:"previous"`         // the hash of the preceding entry (empty for the first).
+	Hash      string             `json:"hash"`             // the hash of this entry.
+}
+
+// computeHash returns the hash of the entry, which covers every field but Hash itself.
+func (e AuditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditChainError is returned when an audit log's hash chain is broken, meaning that the log has been tampered with.
+type AuditChainError struct {
+	Sequence int    // the sequence number of the first entry that does not belong to the chain.
+	Reason   string // why the entry does not belong.
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit log entry %d is invalid: %s", e.Sequence, e.Reason)
+}
+
+// AuditFilter selects entries from an audit log. Zero-valued fields match every entry.
+type AuditFilter struct {
+	Principal string       // only entries for steps applied on behalf of this principal.
+	Stack     tokens.QName // only entries for this stack.
+	URN       resource.URN // only entries for this resource.
+	Since     time.Time    // only entries at or after this time.
+	Until     time.Time    // only entries before this time.
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	return (f.Principal == "" || e.Principal == f.Principal) &&
+		(f.Stack == "" || e.Stack == f.Stack) &&
+		(f.URN == "" || e.URN == f.URN) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until))
+}
+
+// AuditLog is an append-only log of the steps applied by the engine, stored as a file of hash-chained JSON entries,
+// one per line. It records steps independently of the event stream, so that it is complete no matter which consumers
+// are listening. An AuditLog may be shared by concurrent updates, but a log file must not be written by more than one
+// process at a time.
+type AuditLog struct {
+	path string
+	file *os.File
+	last AuditEntry // the last entry in the log, or the zero entry if the log is empty.
+	lock sync.Mutex
+}
+
+// OpenAuditLog opens the audit log at the given path for appending, creating it if it does not exist. The existing
+// entries are verified first, and an *AuditChainError is returned if their chain is broken: a log that has been
+// tampered with must not be extended.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	entries, err := readAuditEntries(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening audit log")
+	}
+
+	log := &AuditLog{path: path, file: file}
+	if len(entries) > 0 {
+		log.last = entries[len(entries)-1]
+	}
+	return log, nil
+}
+
+// Append adds an entry to the log, assigning its sequence number and chaining it to the entry before it. The entry
+// is flushed to disk before Append returns. The completed entry is returned.
+func (l *AuditLog) Append(entry AuditEntry) (AuditEntry, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	entry.Sequence, entry.Previous = l.last.Sequence+1, l.last.Hash
+	hash, err := entry.computeHash()
+	if err != nil {
+		return AuditEntry{}, errors.Wrap(err, "hashing audit log entry")
+	}
+	entry.Hash = hash
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return AuditEntry{}, errors.Wrap(err, "serializing audit log entry")
+	}
+	if _, err = l.file.Write(append(b, '\n')); err != nil {
+		return AuditEntry{}, errors.Wrap(err, "writing audit log entry")
+	}
+	if err = l.file.Sync(); err != nil {
+		return AuditEntry{}, errors.Wrap(err, "writing audit log entry")
+	}
+
+	l.last = entry
+	return entry, nil
+}
+
+// Close closes the log.
+func (l *AuditLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.file.Close()
+}
+
+// ReadAuditLog returns the entries in the audit log at the given path that match the given filter, in the order they
+// were appended. The whole log is verified, and an *AuditChainError is returned if its chain is broken.
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditEntry, error) {
+	entries, err := readAuditEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []AuditEntry
+	for _, e := range entries {
+		if filter.matches(e) {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
+
+// readAuditEntries reads and verifies every entry in the audit log at the given path.
+func readAuditEntries(path string) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(file)
+
+	var entries []AuditEntry
+	var last AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		sequence := last.Sequence + 1
+
+		var entry AuditEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, &AuditChainError{Sequence: sequence, Reason: err.Error()}
+		}
+		if entry.Sequence != sequence {
+			return nil, &AuditChainError{Sequence: sequence, Reason: fmt.Sprintf("found entry %d", entry.Sequence)}
+		}
+		if entry.Previous != last.Hash {
+			return nil, &AuditChainError{Sequence: sequence, Reason: "does not follow the entry before it"}
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return nil, err
+		}
+		if entry.Hash != hash {
+			return nil, &AuditChainError{Sequence: sequence, Reason: "has been modified"}
+		}
+
+		entries, last = append(entries, entry), entry
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading audit log")
+	}
+	return entries, nil
+}
+
+// auditStep appends an entry for the given applied step to the context's audit log.
+func auditStep(ctx *Context, u UpdateInfo, step deploy.Step, status resource.Status, stepErr error,
+	secrets *secretFilter) error {
+	entry := AuditEntry{
+		Time:      time.Now(),
+		Principal: ctx.AuditPrincipal,
+		Project:   u.GetProject().Name,
+		Stack:     u.GetTarget().Name,
+		URN:       step.URN(),
+		Op:        step.Op(),
+		Result:    AuditSucceeded,
+	}
+	switch {
+	case stepErr != nil && status == resource.StatusPartialFailure:
+		entry.Result, entry.Error = AuditPartiallyFailed, secrets.filter(stepErr.Error())
+	case stepErr != nil:
+		entry.Result, entry.Error = AuditFailed, secrets.filter(stepErr.Error())
+	}
+
+	var err error
+	if old := step.Old(); old != nil {
+		if entry.Before, err = hashAuditedState(old); err != nil {
+			return err
+		}
+	}
+	if new := step.New(); new != nil {
+		if entry.After, err = hashAuditedState(new); err != nil {
+			return err
+		}
+	}
+
+	_, err = ctx.AuditLog.Append(entry)
+	return err
+}
+
+// hashAuditedState hashes a resource's ID, inputs, and outputs. Secret values are hashed along with everything else,
+// so the hash changes when they do.
+func hashAuditedState(state *resource.State) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "id:%s\n", state.ID)
+	for _, props := range []resource.PropertyMap{state.Inputs, state.Outputs} {
+		s, err := plugin.MarshalProperties(props, plugin.MarshalOptions{
+			Label:        "audit",
+			KeepUnknowns: true,
+			KeepSecrets:  true,
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "hashing the state of %s", state.URN)
+		}
+
+		// Struct fields are maps, so the encoding must be made deterministic for the hash to be stable.
+		var buf proto.Buffer
+		buf.SetDeterministic(true)
+		if err = buf.Marshal(s); err != nil {
+			return "", errors.Wrapf(err, "hashing the state of %s", state.URN)
+		}
+		fmt.Fprintf(h, "props:%d\n", len(buf.Bytes()))
+		_, err = h.Write(buf.Bytes())
+		contract.IgnoreError(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
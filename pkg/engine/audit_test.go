@@ -0,0 +1,217 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+func tempAuditLogPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return filepath.Join(dir, "audit.log"), func() { assert.NoError(t, os.RemoveAll(dir)) }
+}
+
+func TestAuditLog(t *testing.T) {
+	path, cleanup := tempAuditLogPath(t)
+	defer cleanup()
+
+	log, err := OpenAuditLog(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	first, err := log.Append(AuditEntry{Principal: "alice", Stack: "dev", URN: "urn:a", Op: deploy.OpCreate})
+	assert.NoError(t, err)
+	second, err := log.Append(AuditEntry{Principal: "bob", Stack: "prod", URN: "urn:b", Op: deploy.OpUpdate})
+	assert.NoError(t, err)
+	assert.NoError(t, log.Close())
+
+	assert.Equal(t, 1, first.Sequence)
+	assert.Equal(t, "", first.Previous)
+	assert.Equal(t, 2, second.Sequence)
+	assert.Equal(t, first.Hash, second.Previous)
+
+	// Reopening the log continues its chain.
+	log, err = OpenAuditLog(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	third, err := log.Append(AuditEntry{
+		Principal: "alice", Stack: "dev", URN: "urn:a", Op: deploy.OpDelete, Time: time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, log.Close())
+	assert.Equal(t, 3, third.Sequence)
+	assert.Equal(t, second.Hash, third.Previous)
+
+	all, err := ReadAuditLog(path, AuditFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditEntry{first, second, third}, all)
+
+	byPrincipal, err := ReadAuditLog(path, AuditFilter{Principal: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditEntry{first, third}, byPrincipal)
+
+	byStack, err := ReadAuditLog(path, AuditFilter{Stack: "prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditEntry{second}, byStack)
+
+	since, err := ReadAuditLog(path, AuditFilter{URN: "urn:a", Since: time.Now().Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditEntry{third}, since)
+}
+
+func TestAuditLogTampering(t *testing.T) {
+	path, cleanup := tempAuditLogPath(t)
+	defer cleanup()
+
+	log, err := OpenAuditLog(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, urn := range []resource.URN{"urn:a", "urn:b", "urn:c"} {
+		_, err = log.Append(AuditEntry{Principal: "alice", URN: urn, Op: deploy.OpCreate})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, log.Close())
+
+	b, err := ioutil.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+
+	assertBroken := func(contents string, sequence int) {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+		_, err := ReadAuditLog(path, AuditFilter{})
+		if chainErr, ok := err.(*AuditChainError); assert.True(t, ok) {
+			assert.Equal(t, sequence, chainErr.Sequence)
+		}
+
+		// A broken log must not be extended.
+		_, err = OpenAuditLog(path)
+		assert.IsType(t, &AuditChainError{}, err)
+	}
+
+	// Editing an entry breaks the chain at that entry.
+	assertBroken(lines[0]+strings.Replace(lines[1], "urn:b", "urn:x", 1)+lines[2], 2)
+
+	// So does removing one.
+	assertBroken(lines[0]+lines[2], 2)
+
+	// And so does reordering them.
+	assertBroken(lines[1]+lines[0]+lines[2], 1)
+}
+
+func TestAuditUpdate(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	inputs := resource.PropertyMap{"foo": resource.NewStringProperty("bar")}
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", inputs,
+			nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	path, cleanup := tempAuditLogPath(t)
+	defer cleanup()
+
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+	urnA := p.NewURN("pkgA:m:typA", "resA", "")
+
+	update := func(snap *deploy.Snapshot) *deploy.Snapshot {
+		log, err := OpenAuditLog(path)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer contract.IgnoreClose(log)
+
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		events := make(chan Event)
+		go func() {
+			for range events {
+			}
+		}()
+		defer close(events)
+
+		journal := newJournal()
+		ctx := &Context{
+			Cancel:          cancelCtx,
+			Events:          events,
+			SnapshotManager: journal,
+			AuditLog:        log,
+			AuditPrincipal:  "alice",
+		}
+		_, res := Update(&updateInfo{project: p.GetProject(), target: p.GetTarget(snap)}, ctx, p.Options, false)
+		assert.Nil(t, res)
+		contract.IgnoreClose(journal)
+		return journal.Snap(snap)
+	}
+
+	// Creating the resource is audited.
+	snap := update(nil)
+	entries, err := ReadAuditLog(path, AuditFilter{URN: urnA})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, deploy.OpCreate, entries[0].Op)
+		assert.Equal(t, AuditSucceeded, entries[0].Result)
+		assert.Equal(t, "alice", entries[0].Principal)
+		assert.Equal(t, p.GetTarget(nil).Name, entries[0].Stack)
+		assert.Empty(t, entries[0].Before)
+		assert.NotEmpty(t, entries[0].After)
+	}
+
+	// An update that leaves the resource unchanged is not.
+	snap = update(snap)
+	entries, err = ReadAuditLog(path, AuditFilter{URN: urnA})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Changing the resource is, and its entry chains its before and after hashes to those of the create.
+	inputs = resource.PropertyMap{"foo": resource.NewStringProperty("baz")}
+	update(snap)
+	entries, err = ReadAuditLog(path, AuditFilter{URN: urnA})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, deploy.OpUpdate, entries[1].Op)
+		assert.Equal(t, entries[0].After, entries[1].Before)
+		assert.NotEqual(t, entries[1].Before, entries[1].After)
+	}
+}
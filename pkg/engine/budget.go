@@ -0,0 +1,168 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// BudgetLimit identifies one of the limits in an UpdateBudget.
+type BudgetLimit string
+
+const (
+	// DurationBudgetLimit is the limit on the duration of an update.
+	DurationBudgetLimit BudgetLimit = "duration"
+	// CreatesBudgetLimit is the limit on the number of resources created by an update.
+	CreatesBudgetLimit BudgetLimit = "creates"
+)
+
+// UpdateBudget configures soft limits on an update, so that runaway deployments are noticed before they finish. A limit
+// of zero is not enforced. Exceeding a limit does not itself stop the update: a warning is issued, and OnExceeded, if
+// set, decides whether the update may continue.
+type UpdateBudget struct {
+	// MaxDuration is the time after which an update is considered to have run for too long.
+	MaxDuration time.Duration
+	// MaxCreates is the number of resources an update may create, including replacements, before it is considered to
+	// have created too many.
+	MaxCreates int
+
+	// OnExceeded, if set, is called the first time each limit is exceeded. No new steps begin while it runs, so it may
+	// block to e.g. ask the user for confirmation. If it returns false, the update is stopped: steps that are already
+	// executing run to completion, but no further steps are started.
+	OnExceeded func(alarm BudgetAlarm) bool
+}
+
+// BudgetAlarm describes a limit in an UpdateBudget that has been exceeded.
+type BudgetAlarm struct {
+	Limit   BudgetLimit // the limit that was exceeded.
+	Message string      // a human-readable description of the alarm.
+}
+
+// budgetMonitor enforces an UpdateBudget over the course of an update.
+type budgetMonitor struct {
+	budget UpdateBudget
+	diag   diag.Sink
+
+	lock      sync.Mutex           // protects creates, alarmed, and stopped.
+	creates   int                  // the number of resources created so far.
+	alarmed   map[BudgetLimit]bool // the limits that have already raised an alarm.
+	stopped   bool                 // true if OnExceeded asked that the update be stopped.
+	gate      sync.RWMutex         // held for writing while OnExceeded runs, to keep new steps from starting.
+	done      chan struct{}        // closed when the update has finished.
+	timerDone chan struct{}        // closed when the duration timer has exited.
+}
+
+// newBudgetMonitor begins monitoring an update against the given budget. Callers must call close once the update has
+// finished.
+func newBudgetMonitor(budget UpdateBudget, sink diag.Sink) *budgetMonitor {
+	m := &budgetMonitor{
+		budget:    budget,
+		diag:      sink,
+		alarmed:   make(map[BudgetLimit]bool),
+		done:      make(chan struct{}),
+		timerDone: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(m.timerDone)
+		if budget.MaxDuration <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(budget.MaxDuration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			m.alarm(DurationBudgetLimit,
+				fmt.Sprintf("this update has been running for longer than its budget of %v", budget.MaxDuration))
+		case <-m.done:
+		}
+	}()
+
+	return m
+}
+
+// beforeStep is called before each step begins. It waits for any pending call to OnExceeded to return, and returns an
+// error if the update has been stopped.
+func (m *budgetMonitor) beforeStep() error {
+	m.gate.RLock()
+	m.gate.RUnlock()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.stopped {
+		return errors.New("the update was stopped because it exceeded its budget")
+	}
+	return nil
+}
+
+// afterStep is called after each step completes successfully.
+func (m *budgetMonitor) afterStep(step deploy.Step) {
+	if m.budget.MaxCreates <= 0 {
+		return
+	}
+	if op := step.Op(); op != deploy.OpCreate && op != deploy.OpCreateReplacement {
+		return
+	}
+
+	m.lock.Lock()
+	m.creates++
+	creates := m.creates
+	m.lock.Unlock()
+
+	if creates > m.budget.MaxCreates {
+		m.alarm(CreatesBudgetLimit,
+			fmt.Sprintf("this update has created more resources than its budget of %d", m.budget.MaxCreates))
+	}
+}
+
+// alarm issues a warning for the given limit, if it has not already done so, and calls OnExceeded.
+func (m *budgetMonitor) alarm(limit BudgetLimit, message string) {
+	m.lock.Lock()
+	if m.alarmed[limit] {
+		m.lock.Unlock()
+		return
+	}
+	m.alarmed[limit] = true
+	m.lock.Unlock()
+
+	m.diag.Warningf(diag.RawMessage("", message))
+	if m.budget.OnExceeded == nil {
+		return
+	}
+
+	m.gate.Lock()
+	proceed := m.budget.OnExceeded(BudgetAlarm{Limit: limit, Message: message})
+	m.gate.Unlock()
+
+	if !proceed {
+		m.lock.Lock()
+		m.stopped = true
+		m.lock.Unlock()
+	}
+}
+
+// close stops monitoring the update, waiting for any alarm raised by the duration timer to finish.
+func (m *budgetMonitor) close() {
+	close(m.done)
+	<-m.timerDone
+}
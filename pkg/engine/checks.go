@@ -0,0 +1,284 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// defaultCheckTimeout is how long a check may run if it does not set its own timeout.
+const defaultCheckTimeout = time.Minute
+
+// CheckResult is the result of a check that ran after an update was applied.
+type CheckResult struct {
+	Name     string        // the name of the check.
+	Passed   bool          // true if the check passed.
+	Message  string        // why the check failed, if it did.
+	Duration time.Duration // how long the check ran.
+}
+
+// ChecksFailed returns true if any of the checks that ran after the update was applied failed.
+func (r *UpdateResult) ChecksFailed() bool {
+	if r == nil {
+		return false
+	}
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// updateChecks returns the checks to run once an update of the given project has been applied: the project's own,
+// followed by any given in the update's options.
+func updateChecks(proj *workspace.Project, opts UpdateOptions) []workspace.ProjectCheck {
+	if opts.SkipChecks {
+		return nil
+	}
+	var checks []workspace.ProjectCheck
+	if proj != nil {
+		checks = append(checks, proj.Checks...)
+	}
+	return append(checks, opts.Checks...)
+}
+
+// runChecks runs the given checks, one at a time, against the stack as the given update actions left it.
+func runChecks(checks []workspace.ProjectCheck, plan *deploy.Plan, acts *updateActions,
+	outputs resource.PropertyMap) []CheckResult {
+
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		timeout := defaultCheckTimeout
+		if check.Timeout != "" {
+			t, err := time.ParseDuration(check.Timeout)
+			contract.Assertf(err == nil, "check timeouts are validated when the project is loaded")
+			timeout = t
+		}
+
+		start := time.Now()
+		err := runCheckWithTimeout(timeout, func() error {
+			switch {
+			case check.Invoke != nil:
+				return runInvokeCheck(check.Invoke, plan, acts)
+			case check.HTTP != nil:
+				return runHTTPCheck(check.HTTP, outputs, timeout)
+			default:
+				return runOutputCheck(check.Output, outputs)
+			}
+		})
+
+		result := CheckResult{Name: check.Name, Passed: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			result.Message = err.Error()
+		}
+		logging.V(7).Infof("runChecks(...): check %s passed=%v (%v)", check.Name, result.Passed, result.Message)
+		results = append(results, result)
+	}
+	return results
+}
+
+// runCheckWithTimeout runs the given check, failing it if it does not finish within the given timeout. A check that
+// times out is left to finish in the background.
+func runCheckWithTimeout(timeout time.Duration, check func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.Errorf("timed out after %v", timeout)
+	}
+}
+
+// runInvokeCheck calls the check's function through the default provider for its package, and compares the results
+// with those that the check expects.
+func runInvokeCheck(check *workspace.InvokeCheck, plan *deploy.Plan, acts *updateActions) error {
+	pkg := check.Function.Package()
+	prov, err := defaultProvider(pkg, plan, acts)
+	if err != nil {
+		return err
+	}
+
+	args := resource.NewPropertyMapFromMap(normalizeCheckMap(check.Args))
+	ret, failures, err := prov.Invoke(check.Function, args)
+	if err != nil {
+		return errors.Wrapf(err, "invoking %s", check.Function)
+	}
+	if len(failures) > 0 {
+		reasons := make([]string, len(failures))
+		for i, failure := range failures {
+			reasons[i] = failure.Reason
+		}
+		return errors.Errorf("invoking %s: invalid arguments: %s", check.Function, strings.Join(reasons, "; "))
+	}
+
+	keys := make([]string, 0, len(check.Expect))
+	for k := range check.Expect {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		expected := resource.NewPropertyValue(normalizeCheckValue(check.Expect[k]))
+		actual := ret[resource.PropertyKey(k)]
+		if actual.IsSecret() {
+			actual = actual.SecretValue().Element
+		}
+		if !actual.DeepEquals(expected) {
+			return errors.Errorf("%s returned %v for '%s', expected %v", check.Function, actual, k, expected)
+		}
+	}
+	return nil
+}
+
+// defaultProvider returns the default provider for the given package that the update used.
+func defaultProvider(pkg tokens.Package, plan *deploy.Plan, acts *updateActions) (plugin.Provider, error) {
+	typ := providers.MakeProviderType(pkg)
+
+	acts.MapLock.Lock()
+	var candidates []*resource.State
+	for urn, step := range acts.Seen {
+		if urn.Type() == typ && providers.IsDefaultProvider(urn) && step.New() != nil && !step.New().Delete {
+			candidates = append(candidates, step.New())
+		}
+	}
+	acts.MapLock.Unlock()
+	if len(candidates) == 0 {
+		return nil, errors.Errorf("the update did not use a default provider for package %s", pkg)
+	}
+
+	// If the update used more than one version of the package's provider, the choice is arbitrary but stable.
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].URN < candidates[j].URN })
+	ref, err := providers.NewReference(candidates[0].URN, candidates[0].ID)
+	if err != nil {
+		return nil, err
+	}
+	prov, ok := plan.GetProvider(ref)
+	if !ok {
+		return nil, errors.Errorf("the default provider for package %s is not loaded", pkg)
+	}
+	return prov, nil
+}
+
+// runHTTPCheck requests the check's URL and compares the status of the response with the one the check expects.
+func runHTTPCheck(check *workspace.HTTPCheck, outputs resource.PropertyMap, timeout time.Duration) error {
+	url := check.URL
+	if check.URLOutput != "" {
+		v, err := stackOutput(check.URLOutput, outputs)
+		if err != nil {
+			return err
+		}
+		if !v.IsString() {
+			return errors.Errorf("stack output '%s' is not a URL", check.URLOutput)
+		}
+		url = v.StringValue()
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+
+	expected := check.Status
+	if expected == 0 {
+		expected = http.StatusOK
+	}
+	if resp.StatusCode != expected {
+		return errors.Errorf("GET %s returned %s, expected %d", url, resp.Status, expected)
+	}
+	return nil
+}
+
+// runOutputCheck passes if the given stack output, which the program exports as the result of its own test function,
+// is true. If the output is a string, it is the reason that the test failed.
+func runOutputCheck(name string, outputs resource.PropertyMap) error {
+	v, err := stackOutput(name, outputs)
+	if err != nil {
+		return err
+	}
+	switch {
+	case v.IsBool() && v.BoolValue():
+		return nil
+	case v.IsString():
+		return errors.New(v.StringValue())
+	default:
+		return errors.Errorf("stack output '%s' is %v, not true", name, v)
+	}
+}
+
+// stackOutput returns the value of the given stack output, unwrapping it if it is secret.
+func stackOutput(name string, outputs resource.PropertyMap) (resource.PropertyValue, error) {
+	v, ok := outputs[resource.PropertyKey(name)]
+	if !ok {
+		return resource.PropertyValue{}, errors.Errorf("the stack has no output named '%s'", name)
+	}
+	if v.IsSecret() {
+		v = v.SecretValue().Element
+	}
+	return v, nil
+}
+
+// normalizeCheckMap converts the maps that YAML decodes, whose keys may be of any type, into the maps with string keys
+// from which property values are made.
+func normalizeCheckMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	normalized := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		normalized[k] = normalizeCheckValue(v)
+	}
+	return normalized
+}
+
+func normalizeCheckValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			normalized[fmt.Sprintf("%v", k)] = normalizeCheckValue(e)
+		}
+		return normalized
+	case map[string]interface{}:
+		return normalizeCheckMap(v)
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, e := range v {
+			normalized[i] = normalizeCheckValue(e)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func TestUpdateChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthy" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				InvokeF: func(tok tokens.ModuleMember,
+					args resource.PropertyMap) (resource.PropertyMap, []plugin.CheckFailure, error) {
+
+					return resource.PropertyMap{
+						"healthy": resource.NewBoolProperty(true),
+						"name":    args["name"],
+					}, nil, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", nil,
+			nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	checks := []workspace.ProjectCheck{
+		{Name: "invoke", Invoke: &workspace.InvokeCheck{
+			Function: "pkgA:m:getStatus",
+			Args:     map[string]interface{}{"name": "resA"},
+			Expect:   map[string]interface{}{"healthy": true, "name": "resA"},
+		}},
+		{Name: "unexpected", Invoke: &workspace.InvokeCheck{
+			Function: "pkgA:m:getStatus",
+			Expect:   map[string]interface{}{"healthy": false},
+		}},
+		{Name: "no-provider", Invoke: &workspace.InvokeCheck{Function: "pkgB:m:getStatus"}},
+		{Name: "http", HTTP: &workspace.HTTPCheck{URL: server.URL + "/healthy"}},
+		{Name: "http-down", HTTP: &workspace.HTTPCheck{URL: server.URL + "/down"}},
+		{Name: "http-expected-down", HTTP: &workspace.HTTPCheck{URL: server.URL + "/down", Status: 503}},
+		{Name: "output", Output: "tested"},
+	}
+	p := &TestPlan{Options: UpdateOptions{host: host, Checks: checks}}
+
+	run := func(opts UpdateOptions, dryRun bool) (*UpdateResult, []Event) {
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		events := make(chan Event)
+		done := make(chan []Event)
+		go func() {
+			var all []Event
+			for e := range events {
+				all = append(all, e)
+			}
+			done <- all
+		}()
+
+		journal := newJournal()
+		ctx := &Context{Cancel: cancelCtx, Events: events, SnapshotManager: journal}
+		updateResult, res := Update(&updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}, ctx, opts, dryRun)
+		assert.Nil(t, res)
+		contract.IgnoreClose(journal)
+		close(events)
+		return updateResult, <-done
+	}
+
+	// Checks run once the update has been applied, and failing checks do not fail it.
+	updateResult, events := run(p.Options, false)
+	assert.True(t, updateResult.ChecksFailed())
+	passed := make(map[string]bool)
+	for _, check := range updateResult.Checks {
+		passed[check.Name] = check.Passed
+	}
+	assert.Equal(t, map[string]bool{
+		"invoke":             true,
+		"unexpected":         false,
+		"no-provider":        false,
+		"http":               true,
+		"http-down":          false,
+		"http-expected-down": true,
+		"output":             false,
+	}, passed)
+	if assert.Len(t, updateResult.Checks, len(checks)) {
+		assert.Contains(t, updateResult.Checks[2].Message, "did not use a default provider for package pkgB")
+		assert.Contains(t, updateResult.Checks[6].Message, "no output named 'tested'")
+	}
+
+	var checkEvents []Event
+	for _, e := range events {
+		if e.Type == CheckResultsEvent {
+			checkEvents = append(checkEvents, e)
+		}
+	}
+	if assert.Len(t, checkEvents, 1) {
+		assert.Equal(t, updateResult.Checks, checkEvents[0].Payload.(CheckResultsEventPayload).Checks)
+	}
+
+	// Checks do not run for previews, or when they are skipped.
+	updateResult, _ = run(p.Options, true)
+	assert.False(t, updateResult.ChecksFailed())
+	opts := p.Options
+	opts.SkipChecks = true
+	updateResult, _ = run(opts, false)
+	if assert.NotNil(t, updateResult) {
+		assert.Empty(t, updateResult.Checks)
+	}
+}
+
+func TestOutputChecks(t *testing.T) {
+	outputs := resource.PropertyMap{
+		"passed": resource.NewBoolProperty(true),
+		"failed": resource.NewStringProperty("the widget is not frobbed"),
+		"secret": resource.MakeSecret(resource.NewBoolProperty(true)),
+		"number": resource.NewNumberProperty(42),
+	}
+
+	assert.NoError(t, runOutputCheck("passed", outputs))
+	assert.NoError(t, runOutputCheck("secret", outputs))
+	assert.EqualError(t, runOutputCheck("failed", outputs), "the widget is not frobbed")
+	assert.EqualError(t, runOutputCheck("number", outputs), "stack output 'number' is {42}, not true")
+	assert.EqualError(t, runOutputCheck("missing", outputs), "the stack has no output named 'missing'")
+
+	assert.EqualError(t, runHTTPCheck(&workspace.HTTPCheck{URLOutput: "number"}, outputs, defaultCheckTimeout),
+		"stack output 'number' is not a URL")
+}
+
+func TestNormalizeCheckValue(t *testing.T) {
+	// YAML decodes nested maps with keys of any type.
+	v := normalizeCheckValue(map[interface{}]interface{}{
+		"a": []interface{}{map[interface{}]interface{}{1: "b"}},
+	})
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{map[string]interface{}{"1": "b"}}}, v)
+}
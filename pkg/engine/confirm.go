@@ -0,0 +1,91 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+)
+
+// ConfirmationResponse is the caller's response to a ConfirmationRequiredEvent.
+type ConfirmationResponse struct {
+	URN      resource.URN // the resource whose step is being confirmed.
+	Approved bool         // true if the step may proceed.
+}
+
+// requiresConfirmation returns true if the given step deletes or replaces a resource, and so must be confirmed by the
+// caller before it is applied when confirmations are enabled.
+func requiresConfirmation(step deploy.Step) bool {
+	switch step.Op() {
+	case deploy.OpDelete, deploy.OpDeleteReplaced, deploy.OpCreateReplacement, deploy.OpReplace:
+		return true
+	case deploy.OpPrune:
+		return step.New() == nil
+	default:
+		return false
+	}
+}
+
+// replacesProvider returns true if the given step is part of the replacement of a provider.
+func replacesProvider(step deploy.Step) bool {
+	switch step.Op() {
+	case deploy.OpDeleteReplaced, deploy.OpCreateReplacement, deploy.OpReplace:
+		return providers.IsProviderType(step.Type())
+	default:
+		return false
+	}
+}
+
+// confirmStep asks the caller to confirm the given step, if necessary, and waits for their response. The steps that
+// make up the replacement of a resource are confirmed together, by the first of them to be applied. An error is
+// returned if the caller declines the step or the update is canceled while waiting.
+func (acts *updateActions) confirmStep(step deploy.Step) error {
+	destructive := acts.Opts.ConfirmDestructiveSteps && requiresConfirmation(step)
+	if !destructive && !(acts.Opts.ConfirmProviderReplacements && replacesProvider(step)) {
+		return nil
+	}
+
+	// Only one confirmation is outstanding at a time, so that each response answers the request that preceded it.
+	acts.confirmLock.Lock()
+	defer acts.confirmLock.Unlock()
+
+	urn := step.URN()
+	if acts.confirmed[urn] {
+		return nil
+	}
+
+	acts.Opts.Events.confirmationRequiredEvent(step, acts.Opts.Debug)
+	select {
+	case resp, ok := <-acts.Context.Confirmations:
+		if !ok {
+			return errors.Errorf("the confirmations channel was closed before the %s of '%s' was confirmed",
+				step.Op(), urn)
+		}
+		if resp.URN != urn {
+			return errors.Errorf("received a confirmation for '%s' while waiting for one for '%s'", resp.URN, urn)
+		}
+		if !resp.Approved {
+			return errors.Errorf("the %s of '%s' was not confirmed", step.Op(), urn)
+		}
+	case <-acts.Context.Cancel.Canceled():
+		return errors.Errorf("the update was canceled before the %s of '%s' was confirmed", step.Op(), urn)
+	}
+
+	acts.confirmed[urn] = true
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// ControlCommand steers an update while it executes. Commands are sent on a Context's Control channel.
+type ControlCommand string
+
+const (
+	// PauseCommand stops the update from starting new steps. Steps that are already executing run to completion.
+	PauseCommand ControlCommand = "pause"
+	// ResumeCommand lets a paused update start new steps again.
+	ResumeCommand ControlCommand = "resume"
+	// AbortCommand stops the update for good: steps that are already executing run to completion, but every step that
+	// has yet to start fails, and so the update fails.
+	AbortCommand ControlCommand = "abort"
+)
+
+// updateControl applies the commands sent on a Context's Control channel to an update.
+type updateControl struct {
+	events eventEmitter
+
+	lock    sync.Mutex // protects paused and aborted.
+	resumed *sync.Cond // signaled when the update resumes or is aborted.
+	paused  bool       // true if new steps must wait.
+	aborted bool       // true if new steps must fail.
+
+	done   chan struct{} // closed when the update has finished.
+	exited chan struct{} // closed when the goroutine that reads commands has exited.
+}
+
+// newUpdateControl begins applying the commands sent on the context's Control channel to an update, or returns nil if
+// the context has no Control channel. Callers must call close once the update has finished.
+func newUpdateControl(ctx *Context, events eventEmitter) *updateControl {
+	if ctx.Control == nil {
+		return nil
+	}
+
+	c := &updateControl{
+		events: events,
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	c.resumed = sync.NewCond(&c.lock)
+
+	go func() {
+		defer close(c.exited)
+		for {
+			select {
+			case cmd, ok := <-ctx.Control:
+				if !ok {
+					return
+				}
+				c.apply(cmd)
+			case <-ctx.Cancel.Canceled():
+				// A canceled update must not be held back, or it would never notice that it has been canceled.
+				c.apply(ResumeCommand)
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// apply applies a single command to the update. The event that reports a pause or resumption is emitted before any
+// step that waits on the update starts, so that it precedes the events of those steps.
+func (c *updateControl) apply(cmd ControlCommand) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var changed bool
+	switch cmd {
+	case PauseCommand:
+		// An aborted update has nothing left to pause.
+		if !c.aborted {
+			changed, c.paused = !c.paused, true
+		}
+	case ResumeCommand:
+		changed = c.paused
+		c.paused = false
+	case AbortCommand:
+		changed = c.paused
+		c.paused, c.aborted = false, true
+	default:
+		logging.V(7).Infof("ignoring unknown control command '%s'", cmd)
+	}
+	if changed {
+		logging.V(7).Infof("control: update paused=%v", c.paused)
+		c.events.pausedEvent(c.paused)
+	}
+	c.resumed.Broadcast()
+}
+
+// beforeStep is called before each step begins. It waits for the update to resume if it is paused, and returns an
+// error if the update has been aborted.
+func (c *updateControl) beforeStep() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for c.paused {
+		c.resumed.Wait()
+	}
+	if c.aborted {
+		return errors.New("the update was aborted")
+	}
+	return nil
+}
+
+// close stops applying commands to the update.
+func (c *updateControl) close() {
+	close(c.done)
+	<-c.exited
+}
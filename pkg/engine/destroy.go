@@ -15,6 +15,7 @@
 package engine
 
 import (
+	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/util/contract"
@@ -23,11 +24,23 @@ import (
 	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
-func Destroy(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (ResourceChanges, result.Result) {
+func Destroy(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
 	contract.Require(u != nil, "u")
 	contract.Require(ctx != nil, "ctx")
 
-	defer func() { ctx.Events <- cancelEvent() }()
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	// A destroy deletes every resource, so there is nothing to prune.
+	opts.Prune, opts.PruneExempt = deploy.PruneOff, nil
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+	exclude, err := opts.destroyExclude()
+	if err != nil {
+		return nil, result.FromError(err)
+	}
 
 	info, err := newPlanContext(u, "destroy", ctx.ParentSpan)
 	if err != nil {
@@ -35,7 +48,10 @@ func Destroy(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (Resou
 	}
 	defer info.Close()
 
-	emitter, err := makeEventEmitter(ctx.Events, u)
+	events, flushEvents := operationEvents(ctx, opts)
+	defer flushEvents()
+
+	emitter, err := makeEventEmitter(events, u)
 	if err != nil {
 		return nil, result.FromError(err)
 	}
@@ -43,11 +59,25 @@ func Destroy(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (Resou
 		UpdateOptions: opts,
 		SourceFunc:    newDestroySource,
 		Events:        emitter,
-		Diag:          newEventSink(emitter, false),
-		StatusDiag:    newEventSink(emitter, true),
+		Diag:          newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters),
+		StatusDiag:    newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters),
+		exclude:       exclude,
 	}, dryRun)
 }
 
+// destroyExclude parses the URN patterns of the resources that a destroy leaves in place.
+func (opts UpdateOptions) destroyExclude() ([]resource.URNPattern, error) {
+	var patterns []resource.URNPattern
+	for _, s := range opts.DestroyExclude {
+		pattern, err := resource.ParseURNPattern(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
 func newDestroySource(
 	client deploy.BackendClient, opts planOptions, proj *workspace.Project, pwd, main string,
 	target *deploy.Target, plugctx *plugin.Context, dryRun bool) (deploy.Source, error) {
@@ -61,7 +91,7 @@ func newDestroySource(
 	}
 
 	// Like Update, if we're missing plugins, attempt to download the missing plugins.
-	if err := ensurePluginsAreInstalled(plugins); err != nil {
+	if err := ensurePluginsAreInstalled(plugins, plugctx.Diag); err != nil {
 		logging.V(7).Infof("newDestroySource(): failed to install missing plugins: %v", err)
 	}
 
@@ -251,7 +251,8 @@ func GetResourceOutputsPropertiesString(
 	// the upshot is that either way we're ending up with outputs that are exactly accurate. If we are not sure that we
 	// are in one of the above states, we shouldn't try to print outputs.
 	if planning {
-		printOutputDuringPlanning := refresh || step.Op == deploy.OpRead || step.Op == deploy.OpReadReplacement
+		printOutputDuringPlanning := refresh || step.Op == deploy.OpRead || step.Op == deploy.OpReadReplacement ||
+			step.Op == deploy.OpImport
 		if !printOutputDuringPlanning {
 			return ""
 		}
@@ -321,7 +322,8 @@ func GetResourceOutputsPropertiesString(
 
 func considerSameIfNotCreateOrDelete(op deploy.StepOp) deploy.StepOp {
 	switch op {
-	case deploy.OpCreate, deploy.OpDelete, deploy.OpDeleteReplaced, deploy.OpReadDiscard, deploy.OpDiscardReplaced:
+	case deploy.OpCreate, deploy.OpDelete, deploy.OpDeleteReplaced, deploy.OpReadDiscard, deploy.OpDiscardReplaced,
+		deploy.OpPrune:
 		return op
 	default:
 		return deploy.OpSame
@@ -0,0 +1,34 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package engine performs the operations (preview, update, refresh, destroy, import, and query) that bring a stack's
+// resources up to date with its program.
+//
+// Any number of operations may run concurrently in one process, including operations for different stacks and
+// projects, provided that each operation has its own Context and UpdateInfo. The engine does not keep mutable state at
+// the package level that one operation could observe or disturb in another:
+//
+//   - Operations never change the process's working directory. Programs and plugins are started in their project's
+//     directory, and relative asset and archive paths are resolved against it explicitly.
+//   - Secrets found in a stack's state are masked per operation, so events emitted by one operation never mask, and
+//     so never reveal the presence of, another operation's secrets. Configuration secrets are registered with the
+//     logging package as well, so that they are masked in logs and in diagnostics written directly to a sink, which
+//     may be shared between operations.
+//   - Plugin hosts and their plugins belong to the operation that loaded them. They are shared only where the caller
+//     asks for it, as by UpdateMany and Watch.
+//   - Events are uncolored; each consumer decides how to colorize them for display.
+//
+// The snapshot of a single stack must not be modified by more than one operation at a time. Backends prevent this by
+// locking the stack; embedders that call the engine directly are responsible for doing the same.
+package engine
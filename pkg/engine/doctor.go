@@ -0,0 +1,276 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/version"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// DoctorStatus is the outcome of a diagnostic check.
+type DoctorStatus string
+
+const (
+	// DoctorOK means that the check found no problem.
+	DoctorOK DoctorStatus = "ok"
+	// DoctorWarning means that the check found something that may cause problems.
+	DoctorWarning DoctorStatus = "warning"
+	// DoctorError means that the check found something that will cause updates to fail.
+	DoctorError DoctorStatus = "error"
+)
+
+// DoctorFinding is a single result of a diagnostic check.
+type DoctorFinding struct {
+	Check       string       `json:"check"`                 // the name of the check, e.g. "plugins".
+	Status      DoctorStatus `json:"status"`                // the outcome of the check.
+	Message     string       `json:"message"`               // what the check found.
+	Remediation string       `json:"remediation,omitempty"` // how to fix the problem, if there is one.
+}
+
+// DoctorCheck performs a diagnostic check and returns its findings.
+type DoctorCheck func() []DoctorFinding
+
+// DoctorReport holds the findings of every check run by Doctor, in the order in which they were run.
+type DoctorReport struct {
+	Findings []DoctorFinding `json:"findings"`
+}
+
+// Healthy returns true if no check found an error. Warnings do not make a report unhealthy.
+func (r DoctorReport) Healthy() bool {
+	for _, f := range r.Findings {
+		if f.Status == DoctorError {
+			return false
+		}
+	}
+	return true
+}
+
+// DoctorOptions controls the checks run by Doctor.
+type DoctorOptions struct {
+	// Checks are run after the engine's own checks. The engine cannot reach the backend, so checks of its
+	// reachability, credentials, and locks are supplied here.
+	Checks []DoctorCheck
+	// StatePaths are additional directories in which checkpoints are written, e.g. that of a local backend. The free
+	// disk space of each, and of the user's bookkeeping directory, is checked.
+	StatePaths []string
+}
+
+var (
+	// DoctorMinFreeSpace is the free disk space below which Doctor reports an error, as writing a checkpoint may fail.
+	DoctorMinFreeSpace uint64 = 100 * 1024 * 1024
+	// DoctorLowFreeSpace is the free disk space below which Doctor reports a warning.
+	DoctorLowFreeSpace uint64 = 1024 * 1024 * 1024
+)
+
+// Doctor checks the environment for common problems that cause updates to fail: an unhealthy plugin directory, too
+// little disk space for checkpoints, language hosts that do not belong to the running CLI, and whatever else the
+// given options' checks look for. It returns a report of everything that was found, along with suggestions for
+// fixing any problems.
+func Doctor(opts DoctorOptions) DoctorReport {
+	checks := []DoctorCheck{
+		checkPluginDir,
+		checkLanguageHosts,
+		func() []DoctorFinding { return checkDiskSpace(opts.StatePaths) },
+	}
+	checks = append(checks, opts.Checks...)
+
+	var report DoctorReport
+	for _, check := range checks {
+		report.Findings = append(report.Findings, check()...)
+	}
+	return report
+}
+
+// checkPluginDir checks that the plugin directory is writable, that every plugin in it has its executable, and that
+// no plugin installations were left incomplete.
+func checkPluginDir() []DoctorFinding {
+	const check = "plugins"
+
+	dir, err := workspace.GetPluginDir()
+	if err != nil {
+		return []DoctorFinding{{Check: check, Status: DoctorError,
+			Message:     fmt.Sprintf("could not locate the plugin directory: %v", err),
+			Remediation: "make sure that the HOME environment variable refers to your home directory"}}
+	}
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		return []DoctorFinding{{Check: check, Status: DoctorOK,
+			Message: fmt.Sprintf("no plugins are installed yet; they will be installed into %s", dir)}}
+	case err != nil:
+		return []DoctorFinding{{Check: check, Status: DoctorError,
+			Message:     fmt.Sprintf("could not read the plugin directory %s: %v", dir, err),
+			Remediation: fmt.Sprintf("make sure that %s is readable by the current user", dir)}}
+	case !info.IsDir():
+		return []DoctorFinding{{Check: check, Status: DoctorError,
+			Message:     fmt.Sprintf("the plugin directory %s is not a directory", dir),
+			Remediation: fmt.Sprintf("move %s aside so that plugins can be installed", dir)}}
+	}
+
+	var findings []DoctorFinding
+	if probe, err := ioutil.TempFile(dir, ".doctor-"); err != nil {
+		findings = append(findings, DoctorFinding{Check: check, Status: DoctorError,
+			Message:     fmt.Sprintf("the plugin directory %s is not writable: %v", dir, err),
+			Remediation: fmt.Sprintf("make sure that %s is writable by the current user", dir)})
+	} else {
+		contract.IgnoreClose(probe)
+		contract.IgnoreError(os.Remove(probe.Name()))
+	}
+
+	// Installations that are interrupted leave their temporary directories behind.
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return append(findings, DoctorFinding{Check: check, Status: DoctorError,
+			Message: fmt.Sprintf("could not list the plugin directory %s: %v", dir, err)})
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.Contains(entry.Name(), ".tmp") {
+			path := filepath.Join(dir, entry.Name())
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorWarning,
+				Message:     fmt.Sprintf("%s was left behind by an incomplete plugin installation", path),
+				Remediation: fmt.Sprintf("delete %s", path)})
+		}
+	}
+
+	plugins, err := workspace.GetPlugins()
+	if err != nil {
+		return append(findings, DoctorFinding{Check: check, Status: DoctorError,
+			Message: fmt.Sprintf("could not list installed plugins: %v", err)})
+	}
+	broken := 0
+	for _, plug := range plugins {
+		path, err := plug.FilePath()
+		if err == nil {
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			broken++
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorError,
+				Message: fmt.Sprintf("%s plugin %s v%s is missing its executable: %v",
+					plug.Kind, plug.Name, plug.Version, err),
+				Remediation: fmt.Sprintf("run `pulumi plugin rm %s %s %s --yes`, then reinstall it with "+
+					"`pulumi plugin install %s %s %s`", plug.Kind, plug.Name, plug.Version,
+					plug.Kind, plug.Name, plug.Version)})
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, DoctorFinding{Check: check, Status: DoctorOK,
+			Message: fmt.Sprintf("%d plugins are installed in %s", len(plugins)-broken, dir)})
+	}
+	return findings
+}
+
+// checkLanguageHosts checks that the language hosts that the CLI will run are those that were installed alongside it.
+// Language hosts are found on the $PATH before they are found next to the CLI, so an older installation that comes
+// first on the $PATH pairs the CLI with language hosts of a different version.
+func checkLanguageHosts() []DoctorFinding {
+	const check = "language hosts"
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return []DoctorFinding{{Check: check, Status: DoctorWarning,
+			Message: fmt.Sprintf("could not locate the running CLI: %v", err)}}
+	}
+	cliDir := filepath.Dir(exe)
+
+	hosts, err := filepath.Glob(filepath.Join(cliDir, "pulumi-language-*"))
+	if err != nil || len(hosts) == 0 {
+		return []DoctorFinding{{Check: check, Status: DoctorOK,
+			Message: fmt.Sprintf("no language hosts are installed alongside the CLI in %s", cliDir)}}
+	}
+
+	var findings []DoctorFinding
+	for _, host := range hosts {
+		name := strings.TrimSuffix(filepath.Base(host), filepath.Ext(host))
+		found, err := exec.LookPath(name)
+		if err != nil {
+			continue // the CLI falls back to the language host next to it.
+		}
+		if resolved, err := filepath.EvalSymlinks(found); err == nil {
+			found = resolved
+		}
+		if filepath.Dir(found) != cliDir {
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorWarning,
+				Message: fmt.Sprintf("%s is run from %s, but the CLI (version %s) is installed in %s",
+					name, filepath.Dir(found), version.Version, cliDir),
+				Remediation: fmt.Sprintf("put %s before %s on your $PATH, or remove the other installation",
+					cliDir, filepath.Dir(found))})
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, DoctorFinding{Check: check, Status: DoctorOK,
+			Message: fmt.Sprintf("%d language hosts match the CLI in %s", len(hosts), cliDir)})
+	}
+	return findings
+}
+
+// checkDiskSpace checks that the bookkeeping directory and the given paths have enough free space for checkpoints.
+func checkDiskSpace(paths []string) []DoctorFinding {
+	const check = "disk space"
+
+	// The plugin directory lives in the bookkeeping directory, as does the state of the default local backend.
+	if pluginDir, err := workspace.GetPluginDir(); err == nil {
+		paths = append([]string{filepath.Dir(pluginDir)}, paths...)
+	}
+
+	var findings []DoctorFinding
+	for _, path := range paths {
+		free, err := freeDiskSpaceFor(path)
+		switch {
+		case err != nil:
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorWarning,
+				Message: fmt.Sprintf("could not determine the free space for %s: %v", path, err)})
+		case free < DoctorMinFreeSpace:
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorError,
+				Message:     fmt.Sprintf("only %s is free for %s; checkpoints may fail to save", humanize.IBytes(free), path),
+				Remediation: fmt.Sprintf("free up space on the volume that holds %s", path)})
+		case free < DoctorLowFreeSpace:
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorWarning,
+				Message:     fmt.Sprintf("only %s is free for %s", humanize.IBytes(free), path),
+				Remediation: fmt.Sprintf("free up space on the volume that holds %s", path)})
+		default:
+			findings = append(findings, DoctorFinding{Check: check, Status: DoctorOK,
+				Message: fmt.Sprintf("%s is free for %s", humanize.IBytes(free), path)})
+		}
+	}
+	return findings
+}
+
+// freeDiskSpaceFor returns the number of bytes available to the current user on the volume to which the given path
+// is, or will be, written. The path may not exist yet, in which case its nearest existing ancestor is the volume it
+// will be written to.
+func freeDiskSpaceFor(path string) (uint64, error) {
+	existing := path
+	for {
+		if _, err := os.Stat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	return freeDiskSpace(existing)
+}
@@ -0,0 +1,83 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctorRunsSuppliedChecks(t *testing.T) {
+	report := Doctor(DoctorOptions{Checks: []DoctorCheck{
+		func() []DoctorFinding {
+			return []DoctorFinding{{Check: "custom", Status: DoctorWarning, Message: "something is off"}}
+		},
+	}})
+
+	// Supplied checks run after the engine's own.
+	if assert.NotEmpty(t, report.Findings) {
+		assert.Equal(t, "custom", report.Findings[len(report.Findings)-1].Check)
+	}
+}
+
+func TestDoctorReportHealthy(t *testing.T) {
+	report := DoctorReport{Findings: []DoctorFinding{
+		{Check: "a", Status: DoctorOK},
+		{Check: "b", Status: DoctorWarning},
+	}}
+	assert.True(t, report.Healthy())
+
+	report.Findings = append(report.Findings, DoctorFinding{Check: "c", Status: DoctorError})
+	assert.False(t, report.Healthy())
+}
+
+func TestDoctorDiskSpace(t *testing.T) {
+	defer func(min, low uint64) {
+		DoctorMinFreeSpace, DoctorLowFreeSpace = min, low
+	}(DoctorMinFreeSpace, DoctorLowFreeSpace)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A path that does not exist yet is checked on the volume of its nearest existing ancestor.
+	path := filepath.Join(dir, "not", "yet", "created")
+	byPath := func(findings []DoctorFinding) DoctorFinding {
+		for _, f := range findings {
+			if strings.Contains(f.Message, path) {
+				return f
+			}
+		}
+		t.Fatalf("no finding for %s in %v", path, findings)
+		return DoctorFinding{}
+	}
+
+	DoctorMinFreeSpace, DoctorLowFreeSpace = 0, 0
+	assert.Equal(t, DoctorOK, byPath(checkDiskSpace([]string{path})).Status)
+
+	DoctorMinFreeSpace, DoctorLowFreeSpace = 0, math.MaxUint64
+	assert.Equal(t, DoctorWarning, byPath(checkDiskSpace([]string{path})).Status)
+
+	DoctorMinFreeSpace = math.MaxUint64
+	f := byPath(checkDiskSpace([]string{path}))
+	assert.Equal(t, DoctorError, f.Status)
+	assert.NotEmpty(t, f.Remediation)
+}
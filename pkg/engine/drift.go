@@ -0,0 +1,425 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// DriftPrepareFunc prepares an operation of a drift check. It returns the update to perform, which must reflect the
+// stack's current configuration and latest snapshot, along with the snapshot manager that will persist the
+// operation's results. CheckDrift calls it once for the preview that finds drift, and again before correcting any, as
+// the preview refreshes the snapshot that it is given in memory. The snapshot manager for the preview is closed
+// without being used.
+type DriftPrepareFunc func() (UpdateInfo, SnapshotManager, error)
+
+// DriftPolicy determines what CheckDrift does when it finds drift.
+type DriftPolicy struct {
+	// the types of resources whose drift is corrected by updating the stack. A correction is only made if every change
+	// that the update would make is to a drifted resource of one of these types, so that correcting drift never
+	// applies changes to the program that have not yet been deployed.
+	Correct []tokens.Type
+
+	// an optional function that is called with the report of each check that finds drift.
+	Notify func(report *DriftReport)
+
+	// an optional URL to which a JSON description of each check that finds drift is POSTed, e.g. to open a ticket.
+	Webhook string
+}
+
+// DriftedResource describes a resource whose actual state differs from the state recorded in its stack's snapshot.
+type DriftedResource struct {
+	URN     resource.URN
+	Type    tokens.Type
+	Deleted bool                   // true if the resource no longer exists.
+	Diffs   []resource.PropertyKey // the outputs that differ, if the resource still exists.
+}
+
+// DriftReport describes the result of a drift check.
+type DriftReport struct {
+	Stack     tokens.QName      // the stack that was checked.
+	Time      time.Time         // the time at which the check started.
+	Drifted   []DriftedResource // the resources that have drifted, in snapshot order.
+	Plan      *Plan             // the changes that updating the stack would make once it is refreshed.
+	Corrected bool              // true if the drift was corrected by updating the stack.
+}
+
+// driftWebhookClient is the client with which drift reports are posted to webhooks.
+var driftWebhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// CheckDrift looks for resources in the stack whose actual state has drifted from the state recorded in its snapshot,
+// by previewing an update of the stack that refreshes it first. The snapshot is not changed unless the policy allows
+// the drift to be corrected, in which case the stack is refreshed and updated. If drift is found, the policy's Notify
+// function is called and its webhook is posted to once any correction has been made.
+//
+// The events of the preview and of any correction are sent to the context's event channel, followed by a single
+// cancellation event. The context's snapshot manager is not used; see DriftPrepareFunc.
+func CheckDrift(ctx *Context, opts UpdateOptions, policy DriftPolicy,
+	prepare DriftPrepareFunc) (*DriftReport, result.Result) {
+
+	contract.Require(ctx != nil, "ctx")
+	contract.Require(prepare != nil, "prepare")
+
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+	opts.Refresh = true
+
+	u, manager, err := prepare()
+	if err != nil {
+		return nil, result.FromError(err)
+	}
+	contract.IgnoreClose(manager)
+
+	report := &DriftReport{Stack: u.GetTarget().Name, Time: time.Now()}
+	preview, res := Preview(u, driftOperationContext(ctx, nil), opts)
+	if res != nil {
+		return nil, res
+	}
+	report.Plan, report.Drifted = preview.Plan, driftedResources(preview.Plan)
+	if len(report.Drifted) == 0 {
+		logging.V(7).Infof("CheckDrift: stack %s has not drifted", report.Stack)
+		return report, nil
+	}
+
+	logging.V(7).Infof("CheckDrift: %d resources in stack %s have drifted", len(report.Drifted), report.Stack)
+	if policy.correctable(report) {
+		if u, manager, err = prepare(); err != nil {
+			return nil, result.FromError(err)
+		}
+		_, res = Update(u, driftOperationContext(ctx, manager), opts, false /*dryRun*/)
+		if closeErr := manager.Close(); closeErr != nil {
+			res = result.Merge(res, result.FromError(closeErr))
+		}
+		report.Corrected = res == nil
+	}
+
+	if policy.Notify != nil {
+		policy.Notify(report)
+	}
+	if policy.Webhook != "" {
+		if err = postDriftReport(policy.Webhook, report); err != nil {
+			res = result.Merge(res, result.FromError(err))
+		}
+	}
+	return report, res
+}
+
+// driftOperationContext returns a context for one of the operations of a drift check, which forwards the operation's
+// events to the check's event channel. The operation's cancellation event is dropped, as the check sends its own.
+func driftOperationContext(ctx *Context, manager SnapshotManager) *Context {
+	events := make(chan Event)
+	go func() {
+		for e := range events {
+			if e.Type != CancelEvent {
+				ctx.Events <- e
+				continue
+			}
+			// The cancellation event is the last event of the operation.
+			close(events)
+		}
+	}()
+
+	return &Context{
+		Cancel:          ctx.Cancel,
+		Events:          events,
+		SnapshotManager: manager,
+		BackendClient:   ctx.BackendClient,
+		ParentSpan:      ctx.ParentSpan,
+	}
+}
+
+// driftedResources returns the resources whose refreshes in the given plan found that they had drifted.
+func driftedResources(plan *Plan) []DriftedResource {
+	var drifted []DriftedResource
+	for _, step := range plan.Steps {
+		if step.Op != deploy.OpRefresh || step.Old == nil {
+			continue
+		}
+
+		if step.New == nil {
+			drifted = append(drifted, DriftedResource{URN: step.URN, Type: step.Type, Deleted: true})
+			continue
+		}
+		diff := step.Old.State.Outputs.Diff(step.New.State.Outputs)
+		if diff == nil {
+			continue
+		}
+		var keys []resource.PropertyKey
+		for _, k := range diff.Keys() {
+			if diff.Changed(k) {
+				keys = append(keys, k)
+			}
+		}
+		drifted = append(drifted, DriftedResource{URN: step.URN, Type: step.Type, Diffs: keys})
+	}
+	return drifted
+}
+
+// correctable returns true if the policy allows the drift in the given report to be corrected.
+func (policy DriftPolicy) correctable(report *DriftReport) bool {
+	if len(policy.Correct) == 0 {
+		return false
+	}
+
+	correct := make(map[tokens.Type]bool)
+	for _, t := range policy.Correct {
+		correct[t] = true
+	}
+	drifted := make(map[resource.URN]bool)
+	for _, r := range report.Drifted {
+		drifted[r.URN] = correct[r.Type]
+	}
+
+	for _, step := range report.Plan.Steps {
+		switch step.Op {
+		case deploy.OpSame, deploy.OpRead, deploy.OpRefresh:
+			continue
+		}
+		if !drifted[step.URN] {
+			logging.V(7).Infof("CheckDrift: not correcting drift, as %s of %s is not a correctable drift",
+				step.Op, step.URN)
+			return false
+		}
+	}
+	return true
+}
+
+// driftWebhookPayload is the JSON description of a drift report that is posted to a policy's webhook.
+type driftWebhookPayload struct {
+	Stack     string                 `json:"stack"`
+	Time      time.Time              `json:"time"`
+	Corrected bool                   `json:"corrected"`
+	Resources []driftWebhookResource `json:"resources"`
+	Changes   map[string]int         `json:"changes,omitempty"`
+}
+
+type driftWebhookResource struct {
+	URN     string   `json:"urn"`
+	Type    string   `json:"type"`
+	Deleted bool     `json:"deleted,omitempty"`
+	Diffs   []string `json:"diffs,omitempty"`
+}
+
+// postDriftReport posts a JSON description of the given report to the given URL.
+func postDriftReport(url string, report *DriftReport) error {
+	payload := driftWebhookPayload{
+		Stack:     string(report.Stack),
+		Time:      report.Time,
+		Corrected: report.Corrected,
+		Changes:   make(map[string]int),
+	}
+	for _, r := range report.Drifted {
+		res := driftWebhookResource{URN: string(r.URN), Type: string(r.Type), Deleted: r.Deleted}
+		for _, k := range r.Diffs {
+			res.Diffs = append(res.Diffs, string(k))
+		}
+		payload.Resources = append(payload.Resources, res)
+	}
+	for op, count := range report.Plan.Changes {
+		payload.Changes[string(op)] = count
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "encoding drift report")
+	}
+	resp, err := driftWebhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "posting drift report")
+	}
+	contract.IgnoreClose(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("posting drift report: %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// DriftSchedule describes the periodic drift checks of a stack.
+type DriftSchedule struct {
+	// the time between the start of one check and the start of the next.
+	Interval time.Duration
+
+	// the options for the check's operations.
+	Options UpdateOptions
+
+	// what to do when a check finds drift.
+	Policy DriftPolicy
+
+	// prepares each operation of each check.
+	Prepare DriftPrepareFunc
+
+	// an optional function that is called with the report and result of each check. The report is nil if the check
+	// failed before it could look for drift.
+	Done func(report *DriftReport, res result.Result)
+}
+
+// DriftScheduler runs periodic drift checks for any number of stacks on behalf of a long-running embedding of the
+// engine, such as an operator or agent. Checks of different stacks run concurrently; checks of the same stack never
+// overlap. The stacks share the scheduler's context: the events of each check are sent to its event channel tagged
+// with the name of the stack, and canceling it stops every schedule.
+type DriftScheduler struct {
+	ctx       *Context
+	endEvents func() // sends the final cancellation event; see beginEvents.
+
+	lock      sync.Mutex
+	schedules map[tokens.QName]*driftJob // the current schedule of each stack.
+	wg        sync.WaitGroup
+	closed    bool
+}
+
+// driftJob runs the checks of a single schedule.
+type driftJob struct {
+	stop chan struct{} // closed to stop the schedule.
+	done chan struct{} // closed once the schedule has stopped and its last check has finished.
+}
+
+// NewDriftScheduler creates a scheduler whose checks run in the given context. The context's snapshot manager is not
+// used; see DriftPrepareFunc. The scheduler must be closed once it is no longer needed.
+func NewDriftScheduler(ctx *Context) *DriftScheduler {
+	contract.Require(ctx != nil, "ctx")
+	ctx, endEvents := beginEvents(ctx)
+	return &DriftScheduler{ctx: ctx, endEvents: endEvents, schedules: make(map[tokens.QName]*driftJob)}
+}
+
+// Schedule checks the given stack for drift now and periodically hereafter, replacing any schedule that the stack
+// already has. A check that is already running is allowed to finish.
+func (s *DriftScheduler) Schedule(stack tokens.QName, schedule DriftSchedule) error {
+	contract.Require(schedule.Prepare != nil, "schedule.Prepare")
+	if schedule.Interval <= 0 {
+		return errors.Errorf("the drift check interval for stack %s must be positive", stack)
+	}
+	if err := schedule.Options.Validate(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return errors.New("the drift scheduler is closed")
+	}
+
+	prev := s.schedules[stack]
+	if prev != nil {
+		close(prev.stop)
+	}
+	job := &driftJob{stop: make(chan struct{}), done: make(chan struct{})}
+	s.schedules[stack] = job
+
+	s.wg.Add(1)
+	go s.run(stack, schedule, prev, job)
+	return nil
+}
+
+// Unschedule stops checking the given stack for drift. A check that is already running is allowed to finish.
+func (s *DriftScheduler) Unschedule(stack tokens.QName) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if job, has := s.schedules[stack]; has {
+		close(job.stop)
+		delete(s.schedules, stack)
+	}
+}
+
+// Close stops every schedule and waits for any running checks to finish, after which a single cancellation event is
+// sent to the context's event channel.
+func (s *DriftScheduler) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	for stack, job := range s.schedules {
+		close(job.stop)
+		delete(s.schedules, stack)
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+	s.endEvents()
+	return nil
+}
+
+// run checks the given stack for drift at the schedule's interval until the schedule is stopped or the scheduler's
+// context is canceled. If the stack had a previous schedule, run waits for its last check to finish first.
+func (s *DriftScheduler) run(stack tokens.QName, schedule DriftSchedule, prev, job *driftJob) {
+	defer s.wg.Done()
+	defer close(job.done)
+
+	if prev != nil {
+		<-prev.done
+	}
+
+	for {
+		select {
+		case <-job.stop:
+			return
+		case <-s.ctx.Cancel.Canceled():
+			return
+		default:
+		}
+
+		logging.V(7).Infof("DriftScheduler: checking stack %s for drift", stack)
+		report, res := CheckDrift(s.stackContext(stack), schedule.Options, schedule.Policy, schedule.Prepare)
+		if schedule.Done != nil {
+			schedule.Done(report, res)
+		}
+
+		select {
+		case <-job.stop:
+			return
+		case <-s.ctx.Cancel.Canceled():
+			return
+		case <-time.After(schedule.Interval):
+		}
+	}
+}
+
+// stackContext returns a context for a drift check of the given stack, which forwards the check's events to the
+// scheduler's event channel tagged with the name of the stack.
+func (s *DriftScheduler) stackContext(stack tokens.QName) *Context {
+	events := make(chan Event)
+	go func() {
+		for e := range events {
+			if e.Type != CancelEvent {
+				e.Stack = stack
+				s.ctx.Events <- e
+				continue
+			}
+			close(events)
+		}
+	}()
+
+	ctx := *s.ctx
+	ctx.Events = events
+	return &ctx
+}
@@ -0,0 +1,122 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+)
+
+func TestCheckDrift(t *testing.T) {
+	// Once drifted, the provider reports that the resource's value was changed out of band.
+	drifted := false
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ReadF: func(urn resource.URN, id resource.ID,
+					inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+					if drifted {
+						value := resource.PropertyMap{"value": resource.NewStringProperty("drifted")}
+						return plugin.ReadResult{Inputs: value, Outputs: value}, resource.StatusOK, nil
+					}
+					return plugin.ReadResult{Inputs: inputs, Outputs: state}, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"value": resource.NewStringProperty("a")}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+	project := p.GetProject()
+	snap, res := TestOp(Update).Run(project, p.GetTarget(nil), p.Options, false, nil, nil)
+	assert.Nil(t, res)
+	resURN := snap.Resources[1].URN
+
+	var journal *Journal
+	var target deploy.Target
+	prepares := 0
+	prepare := func() (UpdateInfo, SnapshotManager, error) {
+		prepares++
+		journal, target = newJournal(), p.GetTarget(CloneSnapshot(t, snap))
+		return &updateInfo{project: project, target: target}, journal, nil
+	}
+
+	var posted driftWebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+	}))
+	defer server.Close()
+
+	check := func(policy DriftPolicy) (*DriftReport, *deploy.Snapshot) {
+		events := make(chan Event)
+		go func() {
+			for range events {
+			}
+		}()
+		defer close(events)
+
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		prepares = 0
+		report, res := CheckDrift(&Context{Cancel: cancelCtx, Events: events}, p.Options, policy, prepare)
+		assert.Nil(t, res)
+		return report, journal.Snap(target.Snapshot)
+	}
+
+	// Nothing is reported until the resource drifts.
+	notified := 0
+	report, _ := check(DriftPolicy{Notify: func(*DriftReport) { notified++ }})
+	assert.Empty(t, report.Drifted)
+	assert.Equal(t, 0, notified)
+
+	// Drift of a type that may not be corrected is only reported.
+	drifted = true
+	report, _ = check(DriftPolicy{Notify: func(*DriftReport) { notified++ }, Webhook: server.URL})
+	assert.Equal(t, 1, notified)
+	assert.Equal(t, 1, prepares)
+	assert.False(t, report.Corrected)
+	if assert.Len(t, report.Drifted, 1) {
+		assert.Equal(t, resURN, report.Drifted[0].URN)
+		assert.Equal(t, []resource.PropertyKey{"value"}, report.Drifted[0].Diffs)
+	}
+	assert.Equal(t, string(resURN), posted.Resources[0].URN)
+	assert.False(t, posted.Corrected)
+
+	// Drift of a type that may be corrected is reverted by an update.
+	report, corrected := check(DriftPolicy{Correct: []tokens.Type{"pkgA:m:typA"}, Webhook: server.URL})
+	assert.Equal(t, 2, prepares)
+	assert.True(t, report.Corrected)
+	assert.True(t, posted.Corrected)
+	assert.NoError(t, corrected.VerifyIntegrity())
+	assert.Equal(t, resource.NewStringProperty("a"), corrected.Resources[1].Inputs["value"])
+}
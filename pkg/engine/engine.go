@@ -43,4 +43,34 @@ type Context struct {
 	SnapshotManager SnapshotManager
 	BackendClient   deploy.BackendClient
 	ParentSpan      opentracing.SpanContext
+
+	// Confirmations carries the caller's responses to ConfirmationRequiredEvents. It must be set for updates that
+	// confirm destructive steps or provider replacements (see UpdateOptions.ConfirmDestructiveSteps and
+	// UpdateOptions.ConfirmProviderReplacements).
+	Confirmations <-chan ConfirmationResponse
+
+	// Watch carries notifications that the program or its configuration have changed. It must be set for Watch.
+	Watch <-chan WatchEvent
+
+	// Control, if set, carries commands that pause, resume, or abort an update while it executes. See ControlCommand.
+	Control <-chan ControlCommand
+
+	// AuditLog, if set, receives an entry for every step the engine applies, attributed to AuditPrincipal.
+	AuditLog       *AuditLog
+	AuditPrincipal string
+
+	// Freeze, if set, suspends changes to the stack until it expires. See StackFreeze.
+	Freeze *StackFreeze
+
+	// Metrics, if set, collects operational metrics from the operation. See Metrics.
+	Metrics *Metrics
+
+	// Artifacts, if set, stores the contents of the artifacts that providers attach to resources. If it is not set,
+	// such artifacts are dropped with a warning.
+	Artifacts deploy.ArtifactStore
+
+	// EventBuffer, if set, places a bounded buffer between each operation and the consumer of its events, so that a
+	// slow consumer does not hold up the operation, and a consumer that stops accepting events does not deadlock it.
+	// Each operation waits until its buffered events have been delivered before it returns.
+	EventBuffer *EventBufferOptions
 }
@@ -0,0 +1,70 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// PlanEstimator estimates the impact of applying a plan, e.g. with a cost model, so that the estimate can be weighed
+// before the plan is applied. Estimators are given the plan computed by each preview; their estimates are attached to
+// the preview's summary event and result. Estimates are advisory: an estimator that fails is reported as a warning,
+// and the preview proceeds without its estimate.
+type PlanEstimator interface {
+	// Name returns the name of the estimator, by which its estimates are labeled.
+	Name() string
+	// EstimatePlan estimates the impact of applying the given plan to the given update's stack. The plan's states are
+	// those of its events, so secrets are masked; the stack's current state is the target's snapshot.
+	EstimatePlan(u UpdateInfo, plan *Plan) (PlanEstimate, error)
+}
+
+// PlanEstimate is the estimated impact of applying a plan. Each estimate is optional, and nil if the estimator does not
+// make it.
+type PlanEstimate struct {
+	// Estimator is the name of the estimator that made the estimate. It is set by the engine.
+	Estimator string
+	// MonthlyCostDelta is the estimated change to the stack's monthly cost, in Currency. It is negative if the plan
+	// would reduce the cost.
+	MonthlyCostDelta *float64
+	// Currency is the ISO 4217 code of the currency in which costs are estimated, e.g. "USD".
+	Currency string
+	// BlastRadius scores how much of the stack, or of what depends upon it, would be affected if the plan went wrong.
+	// Its scale is up to the estimator.
+	BlastRadius *float64
+	// Notes are human-readable remarks about the estimate, e.g. the resources whose costs could not be estimated.
+	Notes []string
+}
+
+// estimatePlan asks each of the given estimators to estimate the impact of the given plan, returning their estimates in
+// the order of the estimators.
+func estimatePlan(u UpdateInfo, plan *Plan, estimators []PlanEstimator, sink diag.Sink) []PlanEstimate {
+	contract.Require(plan != nil, "plan")
+
+	var estimates []PlanEstimate
+	for _, estimator := range estimators {
+		name := estimator.Name()
+		estimate, err := estimator.EstimatePlan(u, plan)
+		if err != nil {
+			sink.Warningf(diag.Message("" /*urn*/, "estimator %s could not estimate this plan: %v"), name, err)
+			continue
+		}
+		logging.V(7).Infof("estimatePlan: %s estimated %d steps", name, len(plan.Steps))
+		estimate.Estimator = name
+		estimates = append(estimates, estimate)
+	}
+	return estimates
+}
@@ -0,0 +1,296 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// DefaultOperationEventBufferSize is the number of events that an operation's event buffer holds when its options do
+// not specify a size.
+const DefaultOperationEventBufferSize = 1024
+
+// EventClass groups the types of events to which an event buffer applies the same overflow policy.
+type EventClass string
+
+const (
+	// ProgressEvents are the events that report the progress of steps and refreshes, which later events supersede.
+	ProgressEvents EventClass = "progress"
+	// DiagnosticEvents are diagnostics and the output of the program.
+	DiagnosticEvents EventClass = "diagnostic"
+	// ResourceEvents are the events that describe the steps of the operation and their outcomes.
+	ResourceEvents EventClass = "resource"
+	// ControlEvents are every other event, such as the prelude, the summary, confirmation requests, and the final
+	// cancellation event. They are never dropped.
+	ControlEvents EventClass = "control"
+)
+
+// EventOverflowPolicy determines what happens to an event of a particular class that is emitted while an event
+// buffer is full.
+type EventOverflowPolicy string
+
+const (
+	// EventBlock blocks the operation until the consumer has made room for the event. This is the default.
+	EventBlock EventOverflowPolicy = ""
+	// EventDrop drops the event, so that the operation is never held up by the consumer.
+	EventDrop EventOverflowPolicy = "drop"
+)
+
+// EventBufferOptions controls the buffer that decouples an operation from the consumer of its events. See
+// Context.EventBuffer.
+type EventBufferOptions struct {
+	// Size is the number of events the buffer holds before its overflow policies apply (<=0 for
+	// DefaultOperationEventBufferSize).
+	Size int
+
+	// Overflow is the policy for each class of events once the buffer is full. Classes that are not present block, as
+	// do ControlEvents regardless of their policy.
+	Overflow map[EventClass]EventOverflowPolicy
+
+	// StallTimeout is the time the consumer may take to accept a single event before it is considered unhealthy,
+	// e.g. because it panicked or is deadlocked (<=0 to wait forever). While the consumer is unhealthy, events that
+	// it is not ready to accept are discarded rather than delivered, so that the operation runs to completion instead
+	// of waiting for a consumer that may never return. ControlEvents are never discarded: they are held until the
+	// consumer accepts them, and the operation does not wait for them to be delivered once it has ended. The consumer
+	// is healthy again as soon as it accepts an event.
+	StallTimeout time.Duration
+}
+
+// eventClassOf returns the class of events of the given type.
+func eventClassOf(typ EventType) EventClass {
+	switch typ {
+	case StepProgressEvent, RefreshProgressEvent, AggregateProgressEvent:
+		return ProgressEvents
+	case DiagEvent, StdoutColorEvent:
+		return DiagnosticEvents
+	case ResourcePreEvent, ResourceOutputsEvent, ResourceOperationFailed, StepDependenciesEvent, PluginLifecycleEvent,
+		PolicyViolationEvent, ProviderChangeEvent, InitErrorsEvent, PendingDeleteEvent:
+		return ResourceEvents
+	default:
+		return ControlEvents
+	}
+}
+
+// eventBuffer is a bounded buffer between an operation and the consumer of its events. Events are accepted from the
+// operation by one goroutine and delivered to the consumer by another, so a consumer that is momentarily slow does
+// not hold up the operation until the buffer fills up; from then on, each event is dropped or blocks the operation
+// according to the policy for its class. An event that blocks holds up every later event, including those that would
+// be dropped, until there is room for it.
+type eventBuffer struct {
+	in       chan Event    // the channel to which the operation sends its events.
+	queue    chan Event    // the events that have been accepted but not yet delivered.
+	done     chan struct{} // closed once every accepted event has been delivered or discarded.
+	stalled  chan struct{} // signaled when delivery starts waiting for an unhealthy consumer to recover.
+	opts     EventBufferOptions
+	consumer chan<- Event
+
+	lock      sync.Mutex
+	dropped   int  // the number of events that were dropped because the buffer was full.
+	discarded int  // the number of events that were discarded because the consumer was unhealthy.
+	unhealthy bool // true while the consumer is unhealthy.
+	waiting   bool // true while a control event is held until the unhealthy consumer recovers.
+}
+
+// newEventBuffer creates a buffer that delivers the events sent to its input channel to the given consumer.
+func newEventBuffer(consumer chan<- Event, opts EventBufferOptions) *eventBuffer {
+	contract.Require(consumer != nil, "consumer")
+
+	size := opts.Size
+	if size <= 0 {
+		size = DefaultOperationEventBufferSize
+	}
+	b := &eventBuffer{
+		in:       make(chan Event),
+		queue:    make(chan Event, size),
+		done:     make(chan struct{}),
+		stalled:  make(chan struct{}, 1),
+		opts:     opts,
+		consumer: consumer,
+	}
+	go b.accept()
+	go b.deliver()
+	return b
+}
+
+// accept moves the events sent by the operation into the queue, applying the overflow policies once it is full.
+func (b *eventBuffer) accept() {
+	for e := range b.in {
+		class := eventClassOf(e.Type)
+		if class == ControlEvents || b.opts.Overflow[class] != EventDrop {
+			b.queue <- e
+			continue
+		}
+
+		select {
+		case b.queue <- e:
+		default:
+			b.lock.Lock()
+			b.dropped++
+			b.lock.Unlock()
+		}
+	}
+	close(b.queue)
+}
+
+// deliver sends the queued events to the consumer until the queue is closed and empty. If the consumer does not
+// accept an event within the stall timeout, it is marked unhealthy until it accepts another.
+func (b *eventBuffer) deliver() {
+	defer close(b.done)
+
+	for e := range b.queue {
+		if eventClassOf(e.Type) == ControlEvents {
+			b.deliverControl(e)
+		} else {
+			b.deliverOther(e)
+		}
+	}
+}
+
+// deliverOther delivers an event that may be discarded if the consumer is unhealthy.
+func (b *eventBuffer) deliverOther(e Event) {
+	if b.opts.StallTimeout <= 0 {
+		b.consumer <- e
+		return
+	}
+
+	// An unhealthy consumer is given the event only if it is ready for it, which shows that it has recovered.
+	if b.isUnhealthy() {
+		select {
+		case b.consumer <- e:
+			b.setUnhealthy(false)
+		default:
+			b.discard()
+		}
+		return
+	}
+
+	if !b.send(e) {
+		b.discard()
+	}
+}
+
+// deliverControl delivers an event that must not be discarded, waiting for as long as it takes the consumer to
+// accept it.
+func (b *eventBuffer) deliverControl(e Event) {
+	if b.opts.StallTimeout <= 0 {
+		b.consumer <- e
+		return
+	}
+	if !b.isUnhealthy() && b.send(e) {
+		return
+	}
+
+	b.lock.Lock()
+	b.waiting = true
+	b.lock.Unlock()
+	select {
+	case b.stalled <- struct{}{}:
+	default:
+	}
+
+	b.consumer <- e
+
+	b.lock.Lock()
+	b.waiting, b.unhealthy = false, false
+	b.lock.Unlock()
+}
+
+// send delivers the given event if the consumer accepts it within the stall timeout, and otherwise marks the consumer
+// unhealthy.
+func (b *eventBuffer) send(e Event) bool {
+	timer := time.NewTimer(b.opts.StallTimeout)
+	defer timer.Stop()
+	select {
+	case b.consumer <- e:
+		return true
+	case <-timer.C:
+		logging.Warningf("the consumer of engine events accepted no event for %v; discarding events until it recovers",
+			b.opts.StallTimeout)
+		b.setUnhealthy(true)
+		return false
+	}
+}
+
+func (b *eventBuffer) isUnhealthy() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.unhealthy
+}
+
+func (b *eventBuffer) setUnhealthy(unhealthy bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.unhealthy = unhealthy
+}
+
+func (b *eventBuffer) isWaiting() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.waiting
+}
+
+func (b *eventBuffer) discard() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.discarded++
+}
+
+// close stops accepting events and waits until every event that was accepted has been delivered to the consumer, or
+// discarded because the consumer is unhealthy. If a control event is held for an unhealthy consumer, close returns
+// without waiting for it, and the remaining events are delivered if the consumer recovers. No events may be sent to
+// the buffer once it has been closed.
+func (b *eventBuffer) close() {
+	close(b.in)
+	for {
+		select {
+		case <-b.done:
+		case <-b.stalled:
+			if !b.isWaiting() {
+				continue
+			}
+			logging.V(5).Infof("event buffer: the remaining events will be delivered if the consumer recovers")
+		}
+		break
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.dropped > 0 || b.discarded > 0 {
+		logging.V(5).Infof("event buffer: dropped %d events while full and discarded %d for an unhealthy consumer",
+			b.dropped, b.discarded)
+	}
+}
+
+// beginEvents prepares the given context for an operation. If the context's EventBuffer is set, the returned context
+// sends the operation's events to a buffer in front of the context's event channel. The returned function must be
+// deferred by the operation: it sends the operation's final cancellation event and, if the events are buffered, waits
+// until every event has been delivered.
+func beginEvents(ctx *Context) (*Context, func()) {
+	if ctx.EventBuffer == nil {
+		return ctx, func() { ctx.Events <- cancelEvent() }
+	}
+
+	b := newEventBuffer(ctx.Events, *ctx.EventBuffer)
+	buffered := *ctx
+	buffered.Events, buffered.EventBuffer = b.in, nil
+	return &buffered, func() {
+		b.in <- cancelEvent()
+		b.close()
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBufferFlush(t *testing.T) {
+	consumer := make(chan Event)
+	ctx, endEvents := beginEvents(&Context{Events: consumer, EventBuffer: &EventBufferOptions{Size: 2}})
+	assert.Nil(t, ctx.EventBuffer)
+
+	// The operation is not held up by a consumer that has yet to read anything until the buffer is full...
+	go func() {
+		for i := 0; i < 10; i++ {
+			ctx.Events <- Event{Type: ResourcePreEvent}
+		}
+		endEvents()
+		close(consumer)
+	}()
+
+	// ...and every event is delivered, in order, before the operation's events end.
+	var types []EventType
+	for e := range consumer {
+		types = append(types, e.Type)
+	}
+	if assert.Len(t, types, 11) {
+		assert.Equal(t, CancelEvent, types[10])
+	}
+}
+
+func TestEventBufferOverflow(t *testing.T) {
+	consumer := make(chan Event)
+	b := newEventBuffer(consumer, EventBufferOptions{
+		Size:     2,
+		Overflow: map[EventClass]EventOverflowPolicy{ProgressEvents: EventDrop, ControlEvents: EventDrop},
+	})
+
+	// Fill the buffer: one event waits to be delivered and two are queued. Once it is full, progress events are
+	// dropped rather than blocking.
+	for i := 0; i < 3; i++ {
+		b.in <- Event{Type: ResourcePreEvent}
+	}
+	for i := 0; i < 5; i++ {
+		b.in <- Event{Type: StepProgressEvent}
+	}
+
+	// Control events block, whatever their policy.
+	sent := make(chan struct{})
+	go func() {
+		b.in <- cancelEvent()
+		b.close()
+		close(sent)
+	}()
+
+	var types []EventType
+	for len(types) < 4 {
+		types = append(types, (<-consumer).Type)
+	}
+	<-sent
+	assert.Equal(t, []EventType{ResourcePreEvent, ResourcePreEvent, ResourcePreEvent, CancelEvent}, types)
+	assert.Equal(t, 5, b.dropped)
+	assert.Equal(t, 0, b.discarded)
+}
+
+func TestEventBufferUnhealthyConsumer(t *testing.T) {
+	// A consumer that stops reading its events does not deadlock the operation.
+	consumer := make(chan Event)
+	b := newEventBuffer(consumer, EventBufferOptions{Size: 1, StallTimeout: 10 * time.Millisecond})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			b.in <- Event{Type: ResourcePreEvent}
+		}
+		b.in <- cancelEvent()
+		b.close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "the operation is deadlocked on its consumer")
+	}
+
+	// The final cancellation event is held rather than discarded, and is delivered once the consumer recovers.
+	assert.Equal(t, CancelEvent, (<-consumer).Type)
+	<-b.done
+	assert.Equal(t, 5, b.discarded)
+	assert.False(t, b.isUnhealthy())
+}
+
+func TestEventBufferConsumerRecovers(t *testing.T) {
+	consumer := make(chan Event)
+	b := newEventBuffer(consumer, EventBufferOptions{StallTimeout: 100 * time.Millisecond})
+
+	// The consumer is unhealthy once it has failed to accept an event in time...
+	b.in <- Event{Type: ResourcePreEvent}
+	for !b.isUnhealthy() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// ...and healthy again once it accepts a control event, after which no more events are discarded.
+	var types []EventType
+	received := make(chan struct{})
+	go func() {
+		for e := range consumer {
+			types = append(types, e.Type)
+		}
+		close(received)
+	}()
+	b.in <- Event{Type: SummaryEvent}
+	b.in <- Event{Type: ResourcePreEvent}
+	b.in <- Event{Type: ResourceOutputsEvent}
+	b.in <- cancelEvent()
+	b.close()
+	close(consumer)
+	<-received
+
+	assert.Equal(t, []EventType{SummaryEvent, ResourcePreEvent, ResourceOutputsEvent, CancelEvent}, types)
+	assert.Equal(t, 1, b.discarded)
+}
+
+func TestEventClassOf(t *testing.T) {
+	assert.Equal(t, ProgressEvents, eventClassOf(StepProgressEvent))
+	assert.Equal(t, DiagnosticEvents, eventClassOf(DiagEvent))
+	assert.Equal(t, ResourceEvents, eventClassOf(ResourceOutputsEvent))
+	assert.Equal(t, ControlEvents, eventClassOf(SummaryEvent))
+	assert.Equal(t, ControlEvents, eventClassOf(ConfirmationRequiredEvent))
+}
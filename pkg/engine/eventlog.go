@@ -0,0 +1,304 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// DefaultEventLogBufferSize is the number of events an EventLog will queue if no buffer size is specified.
+const DefaultEventLogBufferSize = 1024
+
+// eventLogTimeFormat is the format of the timestamp suffix appended to the names of rotated event log files. It sorts
+// lexically in chronological order.
+const eventLogTimeFormat = "20060102T150405.000000000"
+
+// EventLogOptions controls how an EventLog writes and rotates its files.
+type EventLogOptions struct {
+	// MaxSize is the size in bytes at which the current file is rotated. Zero disables size-based rotation.
+	MaxSize int64
+	// MaxAge is the age at which the current file is rotated. Zero disables time-based rotation.
+	MaxAge time.Duration
+	// MaxFiles is the number of rotated files to retain. Zero retains all rotated files.
+	MaxFiles int
+	// BufferSize is the number of events that may be queued for writing before further events are dropped.
+	BufferSize int
+	// MaxEventsPerSecond is the sustained rate at which events are recorded. Once a burst of BufferSize events has
+	// been recorded, events that arrive faster than this are dropped. Zero disables rate limiting.
+	MaxEventsPerSecond float64
+}
+
+// EventLog writes a stream of engine events to a file as newline-delimited JSON, rotating the file as it grows. Events
+// are written asynchronously so that recording an event never blocks the engine: if the writer falls behind, or events
+// arrive faster than the log's rate limit, events are dropped rather than queued without bound. Each entry carries a
+// sequence number, so gaps in the sequence reveal where events were dropped.
+type EventLog struct {
+	path     string             // the path of the current file.
+	opts     EventLogOptions    // the options controlling rotation and buffering.
+	events   chan eventLogEntry // the queue of events waiting to be written.
+	done     chan struct{}      // closed when the writer has exited.
+	sequence int64              // the sequence number of the most recently recorded event.
+	dropped  int64              // the number of events dropped because the queue was full or the rate was exceeded.
+
+	limitLock sync.Mutex
+	tokens    float64   // the number of events that may be recorded before the rate limit applies.
+	refilled  time.Time // the time at which tokens was last replenished.
+
+	closeOnce sync.Once
+	file      *os.File      // the current file.
+	writer    *bufio.Writer // the buffered writer for the current file.
+	size      int64         // the size of the current file.
+	opened    time.Time     // the time at which the current file was opened.
+	err       error         // the first error encountered while writing, if any.
+}
+
+// eventLogEntry is the serialized form of an event in an event log.
+type eventLogEntry struct {
+	Sequence  int64        `json:"sequence"`
+	Timestamp int64        `json:"timestamp"`
+	Type      EventType    `json:"type"`
+	Stack     tokens.QName `json:"stack,omitempty"`
+	Payload   interface{}  `json:"payload,omitempty"`
+}
+
+// NewEventLog creates an event log that writes to the file at the given path, appending to it if it already exists.
+func NewEventLog(path string, opts EventLogOptions) (*EventLog, error) {
+	contract.Require(path != "", "path")
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultEventLogBufferSize
+	}
+
+	l := &EventLog{
+		path:     path,
+		opts:     opts,
+		events:   make(chan eventLogEntry, opts.BufferSize),
+		done:     make(chan struct{}),
+		tokens:   float64(opts.BufferSize),
+		refilled: time.Now(),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	go l.write()
+	return l, nil
+}
+
+// Record queues the given event to be written to the log. It never blocks; if the queue is full or the log's rate
+// limit has been exceeded, the event is dropped.
+func (l *EventLog) Record(e Event) {
+	entry := eventLogEntry{
+		Sequence:  atomic.AddInt64(&l.sequence, 1),
+		Timestamp: time.Now().Unix(),
+		Type:      e.Type,
+		Stack:     e.Stack,
+		Payload:   e.Payload,
+	}
+	if !l.allow() {
+		atomic.AddInt64(&l.dropped, 1)
+		return
+	}
+	select {
+	case l.events <- entry:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// allow returns true if an event may be recorded without exceeding the log's rate limit, and consumes its share of the
+// limit if so.
+func (l *EventLog) allow() bool {
+	if l.opts.MaxEventsPerSecond <= 0 {
+		return true
+	}
+
+	l.limitLock.Lock()
+	defer l.limitLock.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.refilled).Seconds() * l.opts.MaxEventsPerSecond
+	l.tokens = math.Min(l.tokens, float64(l.opts.BufferSize))
+	l.refilled = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Dropped returns the number of events that have been dropped because the log could not keep up with them.
+func (l *EventLog) Dropped() int64 {
+	return atomic.LoadInt64(&l.dropped)
+}
+
+// Close writes any queued events, closes the current file, and returns the first error encountered while writing, if
+// any. Events must not be recorded after the log has been closed.
+func (l *EventLog) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.events)
+		<-l.done
+		if dropped := l.Dropped(); dropped > 0 {
+			logging.V(3).Infof("EventLog(%s): dropped %d events", l.path, dropped)
+		}
+	})
+	return l.err
+}
+
+// write drains the queue of events, writing each to the current file. The file is flushed whenever the queue is
+// empty, so that readers see events promptly without a write per event.
+func (l *EventLog) write() {
+	defer close(l.done)
+	defer func() {
+		l.setErr(l.writer.Flush())
+		l.setErr(l.file.Close())
+	}()
+
+	for entry := range l.events {
+		l.writeEntry(entry)
+
+		// Write whatever else is already queued before flushing.
+		for drained := false; !drained; {
+			select {
+			case next, ok := <-l.events:
+				if !ok {
+					return
+				}
+				l.writeEntry(next)
+			default:
+				drained = true
+			}
+		}
+		l.setErr(l.writer.Flush())
+	}
+}
+
+// writeEntry writes a single entry to the log, rotating the current file first if necessary.
+func (l *EventLog) writeEntry(entry eventLogEntry) {
+	if l.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logging.V(5).Infof("EventLog(%s): skipping %s event that could not be serialized: %v", l.path, entry.Type, err)
+		return
+	}
+	line = append(line, '\n')
+
+	if l.shouldRotate(int64(len(line))) {
+		if err = l.rotate(); err != nil {
+			l.setErr(err)
+			return
+		}
+	}
+
+	n, err := l.writer.Write(line)
+	l.size += int64(n)
+	l.setErr(err)
+}
+
+// shouldRotate returns true if the current file should be rotated before writing the given number of bytes to it.
+func (l *EventLog) shouldRotate(n int64) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.opts.MaxSize > 0 && l.size+n > l.opts.MaxSize {
+		return true
+	}
+	return l.opts.MaxAge > 0 && time.Since(l.opened) >= l.opts.MaxAge
+}
+
+// open opens the file at the log's path for appending.
+func (l *EventLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "opening event log %s", l.path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		contract.IgnoreClose(file)
+		return errors.Wrapf(err, "opening event log %s", l.path)
+	}
+
+	l.file, l.writer, l.size, l.opened = file, bufio.NewWriter(file), info.Size(), time.Now()
+	return nil
+}
+
+// rotate closes the current file, renames it with a timestamp suffix, opens a new file in its place, and removes any
+// rotated files in excess of the log's retention limit.
+func (l *EventLog) rotate() error {
+	if err := l.writer.Flush(); err != nil {
+		return errors.Wrapf(err, "flushing event log %s", l.path)
+	}
+	if err := l.file.Close(); err != nil {
+		return errors.Wrapf(err, "closing event log %s", l.path)
+	}
+
+	rotated := l.path + "." + time.Now().UTC().Format(eventLogTimeFormat)
+	if err := os.Rename(l.path, rotated); err != nil {
+		return errors.Wrapf(err, "rotating event log %s", l.path)
+	}
+	logging.V(7).Infof("EventLog(%s): rotated to %s", l.path, rotated)
+
+	if err := l.open(); err != nil {
+		return err
+	}
+	return l.prune()
+}
+
+// prune removes the oldest rotated files until at most MaxFiles remain.
+func (l *EventLog) prune() error {
+	if l.opts.MaxFiles <= 0 {
+		return nil
+	}
+
+	rotated, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return errors.Wrapf(err, "listing rotated event logs for %s", l.path)
+	}
+	if len(rotated) <= l.opts.MaxFiles {
+		return nil
+	}
+
+	sort.Strings(rotated)
+	for _, path := range rotated[:len(rotated)-l.opts.MaxFiles] {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "removing rotated event log %s", path)
+		}
+	}
+	return nil
+}
+
+// setErr records the given error if it is the first error encountered while writing.
+func (l *EventLog) setErr(err error) {
+	if err != nil && l.err == nil {
+		logging.V(3).Infof("EventLog(%s): %v", l.path, err)
+		l.err = err
+	}
+}
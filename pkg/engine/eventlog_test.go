@@ -0,0 +1,116 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+)
+
+func readEventLog(t *testing.T, path string) []eventLogEntry {
+	f, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer f.Close()
+
+	var entries []eventLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry eventLogEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	assert.NoError(t, scanner.Err())
+	return entries
+}
+
+func TestEventLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.json")
+	log, err := NewEventLog(path, EventLogOptions{})
+	assert.NoError(t, err)
+
+	log.Record(Event{Type: DiagEvent, Payload: DiagEventPayload{Message: "hello", Severity: diag.Info}})
+	log.Record(cancelEvent())
+	assert.NoError(t, log.Close())
+	assert.Equal(t, int64(0), log.Dropped())
+
+	entries := readEventLog(t, path)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, int64(1), entries[0].Sequence)
+		assert.Equal(t, DiagEvent, entries[0].Type)
+		assert.Equal(t, "hello", entries[0].Payload.(map[string]interface{})["Message"])
+		assert.Equal(t, int64(2), entries[1].Sequence)
+		assert.Equal(t, CancelEvent, entries[1].Type)
+	}
+}
+
+func TestEventLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Each entry is larger than the maximum size, so every event after the first should rotate the file.
+	path := filepath.Join(dir, "events.json")
+	log, err := NewEventLog(path, EventLogOptions{MaxSize: 16, MaxFiles: 2})
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		log.Record(cancelEvent())
+	}
+	assert.NoError(t, log.Close())
+
+	rotated, err := filepath.Glob(path + ".*")
+	assert.NoError(t, err)
+	assert.Len(t, rotated, 2)
+
+	entries := readEventLog(t, path)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, int64(5), entries[0].Sequence)
+	}
+}
+
+func TestEventLogRateLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A burst of five events is allowed; the rest arrive far faster than one per minute and are dropped.
+	path := filepath.Join(dir, "events.json")
+	log, err := NewEventLog(path, EventLogOptions{BufferSize: 5, MaxEventsPerSecond: 1.0 / 60})
+	assert.NoError(t, err)
+
+	for i := 0; i < 8; i++ {
+		log.Record(cancelEvent())
+	}
+	assert.NoError(t, log.Close())
+	assert.Equal(t, int64(3), log.Dropped())
+
+	entries := readEventLog(t, path)
+	if assert.Len(t, entries, 5) {
+		assert.Equal(t, int64(5), entries[4].Sequence)
+	}
+}
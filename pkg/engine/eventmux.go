@@ -0,0 +1,265 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// DefaultEventBufferSize is the number of events that a subscriber's buffer holds if its options do not say otherwise.
+const DefaultEventBufferSize = 4096
+
+// OverflowPolicy determines what happens when an event is published to a subscriber whose buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock holds up the mux, and so the engine and every other subscriber, until the subscriber makes room
+	// for the event. No events are lost, so this is the policy for subscribers that need every event, such as the
+	// display and the replay recorder.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest event in the subscriber's buffer to make room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest discards the new event, keeping the subscriber's buffer as it is.
+	OverflowDropNewest
+)
+
+// SubscriptionOptions configures a subscriber to an EventMux.
+type SubscriptionOptions struct {
+	Filter     EventFilter    // selects the events that the subscriber receives (nil for every event).
+	BufferSize int            // the number of events the subscriber's buffer holds (<=0 for DefaultEventBufferSize).
+	Overflow   OverflowPolicy // what happens when an event is published to the subscriber while its buffer is full.
+}
+
+// EventFilter selects the events that a subscriber receives.
+type EventFilter func(e Event) bool
+
+// EventTypes returns a filter that selects events of the given types.
+func EventTypes(types ...EventType) EventFilter {
+	return func(e Event) bool {
+		for _, t := range types {
+			if e.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EventMux distributes the events of an operation to any number of independent subscribers, e.g. the display, a JSON
+// log, and a notifier. Events are published by sending them on the channel returned by Events, which is typically used
+// as a Context's Events. Each subscriber receives the events that its filter selects, in the order they were
+// published, through its own bounded buffer: a subscriber that falls behind by less than its buffer's size neither
+// blocks the engine nor delays or loses events for the others. Once a subscriber's buffer is full, its overflow policy
+// decides whether the mux waits for it or drops events for it; see OverflowPolicy.
+type EventMux struct {
+	events chan Event    // the channel on which events are published.
+	done   chan struct{} // closed once every published event has been handed to the subscribers.
+	once   sync.Once     // ensures that the mux is closed only once.
+	m      sync.Mutex    // guards subs.
+	subs   []*EventSubscription
+}
+
+// NewEventMux creates a new mux with no subscribers.
+func NewEventMux() *EventMux {
+	mux := &EventMux{
+		events: make(chan Event),
+		done:   make(chan struct{}),
+	}
+	go mux.run()
+	return mux
+}
+
+// Events returns the channel on which events are published. It must not be used once the mux is closed.
+func (mux *EventMux) Events() chan<- Event {
+	return mux.events
+}
+
+// Subscribe adds a subscriber that receives the events that the given filter selects, or every event if the filter is
+// nil. The subscriber has a buffer of DefaultEventBufferSize events and never loses events. It receives only the events
+// that are published after it subscribes.
+func (mux *EventMux) Subscribe(filter EventFilter) *EventSubscription {
+	return mux.SubscribeWithOptions(SubscriptionOptions{Filter: filter})
+}
+
+// SubscribeWithOptions adds a subscriber with the given options. The subscriber receives only the events that are
+// published after it subscribes.
+func (mux *EventMux) SubscribeWithOptions(opts SubscriptionOptions) *EventSubscription {
+	size := opts.BufferSize
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	sub := &EventSubscription{
+		filter:   opts.Filter,
+		size:     size,
+		overflow: opts.Overflow,
+		out:      make(chan Event),
+		quit:     make(chan struct{}),
+	}
+	sub.cond = sync.NewCond(&sub.m)
+	go sub.pump()
+
+	mux.m.Lock()
+	defer mux.m.Unlock()
+	select {
+	case <-mux.done:
+		// The mux is closed, so there is nothing more to receive.
+		sub.close()
+	default:
+		mux.subs = append(mux.subs, sub)
+	}
+	return sub
+}
+
+// Close stops the mux from accepting events. Each subscriber still receives the events that it has not yet received,
+// after which its channel is closed.
+func (mux *EventMux) Close() {
+	mux.once.Do(func() {
+		close(mux.events)
+		<-mux.done
+	})
+}
+
+// run hands each published event to the subscribers until the mux is closed.
+func (mux *EventMux) run() {
+	for e := range mux.events {
+		mux.m.Lock()
+		subs := mux.subs
+		mux.m.Unlock()
+
+		for _, sub := range subs {
+			sub.push(e)
+		}
+	}
+
+	mux.m.Lock()
+	defer mux.m.Unlock()
+	close(mux.done)
+	for _, sub := range mux.subs {
+		sub.close()
+	}
+	mux.subs = nil
+}
+
+// EventSubscription is a subscriber to an EventMux.
+type EventSubscription struct {
+	filter   EventFilter
+	size     int            // the maximum number of events in queue.
+	overflow OverflowPolicy // what push does when queue is full.
+	out      chan Event     // the channel on which the subscriber receives events.
+	quit     chan struct{}  // closed when the subscriber unsubscribes.
+
+	m       sync.Mutex // guards queue, dropped, closed, and unsubscribed.
+	cond    *sync.Cond // broadcast when queue, closed, or unsubscribed changes.
+	queue   []Event    // the events that have been published but not yet received.
+	dropped int        // the number of events dropped because queue was full.
+	closed  bool       // true once no more events will be published.
+
+	unsubscribed bool // true once the subscriber has unsubscribed.
+}
+
+// Events returns the channel on which the subscriber receives events. The channel is closed once the mux is closed
+// and every event has been received, or once the subscriber unsubscribes.
+func (sub *EventSubscription) Events() <-chan Event {
+	return sub.out
+}
+
+// Unsubscribe stops the delivery of events to the subscriber, discarding any that it has not yet received. This must
+// be called by subscribers that stop receiving events before the mux is closed and their channel is drained.
+func (sub *EventSubscription) Unsubscribe() {
+	sub.m.Lock()
+	defer sub.m.Unlock()
+	if !sub.unsubscribed {
+		sub.unsubscribed, sub.queue = true, nil
+		close(sub.quit)
+		sub.cond.Broadcast()
+	}
+}
+
+// Dropped returns the number of events that the subscriber's overflow policy has dropped so far.
+func (sub *EventSubscription) Dropped() int {
+	sub.m.Lock()
+	defer sub.m.Unlock()
+	return sub.dropped
+}
+
+// push queues an event for the subscriber if its filter selects it. If the subscriber's buffer is full, push applies
+// the subscriber's overflow policy.
+func (sub *EventSubscription) push(e Event) {
+	if sub.filter != nil && !sub.filter(e) {
+		return
+	}
+
+	sub.m.Lock()
+	defer sub.m.Unlock()
+	for len(sub.queue) >= sub.size && !sub.unsubscribed {
+		switch sub.overflow {
+		case OverflowDropOldest:
+			sub.drop(sub.queue[0])
+			sub.queue = sub.queue[1:]
+		case OverflowDropNewest:
+			sub.drop(e)
+			return
+		default:
+			sub.cond.Wait()
+		}
+	}
+	if !sub.unsubscribed {
+		sub.queue = append(sub.queue, e)
+		sub.cond.Broadcast()
+	}
+}
+
+// drop records that the given event was dropped. The caller must hold the subscriber's lock.
+func (sub *EventSubscription) drop(e Event) {
+	sub.dropped++
+	logging.V(5).Infof("EventMux: dropped %s event for a subscriber whose buffer of %d events is full (%d dropped)",
+		e.Type, sub.size, sub.dropped)
+}
+
+// close marks the end of the events that will be queued for the subscriber.
+func (sub *EventSubscription) close() {
+	sub.m.Lock()
+	defer sub.m.Unlock()
+	sub.closed = true
+	sub.cond.Broadcast()
+}
+
+// pump delivers the subscriber's queued events until there are no more or it unsubscribes.
+func (sub *EventSubscription) pump() {
+	defer close(sub.out)
+
+	for {
+		sub.m.Lock()
+		for len(sub.queue) == 0 && !sub.closed && !sub.unsubscribed {
+			sub.cond.Wait()
+		}
+		if sub.unsubscribed || len(sub.queue) == 0 {
+			sub.m.Unlock()
+			return
+		}
+		e := sub.queue[0]
+		sub.queue = sub.queue[1:]
+		sub.cond.Broadcast()
+		sub.m.Unlock()
+
+		select {
+		case sub.out <- e:
+		case <-sub.quit:
+			return
+		}
+	}
+}
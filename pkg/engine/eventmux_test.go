@@ -0,0 +1,112 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drain returns the types of the events received by the given subscriber until its channel is closed.
+func drain(sub *EventSubscription) <-chan []EventType {
+	result := make(chan []EventType, 1)
+	go func() {
+		var types []EventType
+		for e := range sub.Events() {
+			types = append(types, e.Type)
+		}
+		result <- types
+	}()
+	return result
+}
+
+func TestEventMuxDeliversToEverySubscriber(t *testing.T) {
+	mux := NewEventMux()
+	all := drain(mux.Subscribe(nil))
+	diags := drain(mux.Subscribe(EventTypes(DiagEvent)))
+
+	// A subscriber that never receives does not hold up the others.
+	stalled := mux.Subscribe(nil)
+
+	published := []EventType{PreludeEvent, DiagEvent, ResourcePreEvent, DiagEvent, SummaryEvent, CancelEvent}
+	for _, typ := range published {
+		mux.Events() <- Event{Type: typ}
+	}
+	mux.Close()
+
+	assert.Equal(t, published, <-all)
+	assert.Equal(t, []EventType{DiagEvent, DiagEvent}, <-diags)
+
+	stalled.Unsubscribe()
+	_, open := <-stalled.Events()
+	assert.False(t, open)
+}
+
+func TestEventMuxUnsubscribe(t *testing.T) {
+	mux := NewEventMux()
+	sub := mux.Subscribe(nil)
+	mux.Events() <- Event{Type: PreludeEvent}
+
+	e := <-sub.Events()
+	assert.Equal(t, PreludeEvent, e.Type)
+
+	sub.Unsubscribe()
+	mux.Events() <- Event{Type: SummaryEvent}
+	mux.Close()
+	for range sub.Events() {
+		assert.Fail(t, "received an event after unsubscribing")
+	}
+
+	// Subscribing after the mux is closed yields a closed channel.
+	late := mux.Subscribe(nil)
+	_, open := <-late.Events()
+	assert.False(t, open)
+}
+
+func TestEventMuxOverflow(t *testing.T) {
+	mux := NewEventMux()
+	oldest := mux.SubscribeWithOptions(SubscriptionOptions{BufferSize: 2, Overflow: OverflowDropOldest})
+	newest := mux.SubscribeWithOptions(SubscriptionOptions{BufferSize: 2, Overflow: OverflowDropNewest})
+	blocking := mux.SubscribeWithOptions(SubscriptionOptions{BufferSize: 2})
+
+	// Stall the dropping subscribers with one event each, so that they buffer the events that follow.
+	mux.Events() <- Event{Type: PreludeEvent}
+	assert.Equal(t, PreludeEvent, (<-oldest.Events()).Type)
+	assert.Equal(t, PreludeEvent, (<-newest.Events()).Type)
+	assert.Equal(t, PreludeEvent, (<-blocking.Events()).Type)
+
+	// The blocking subscriber is drained concurrently; it holds up the mux, but loses nothing.
+	received := drain(blocking)
+
+	published := []EventType{DiagEvent, ResourcePreEvent, ResourceOutputsEvent, SummaryEvent}
+	for _, typ := range published {
+		mux.Events() <- Event{Type: typ}
+	}
+	mux.Close()
+
+	// Each dropping subscriber has at most one event in flight and two buffered, so of the four published events at
+	// least one was dropped.
+	oldestTypes, newestTypes := <-drain(oldest), <-drain(newest)
+	assert.Equal(t, len(published), len(oldestTypes)+oldest.Dropped())
+	assert.Equal(t, len(published), len(newestTypes)+newest.Dropped())
+	assert.True(t, oldest.Dropped() > 0)
+	assert.True(t, newest.Dropped() > 0)
+	assert.Equal(t, SummaryEvent, oldestTypes[len(oldestTypes)-1])
+	assert.Equal(t, DiagEvent, newestTypes[0])
+
+	assert.Equal(t, published, <-received)
+	assert.Equal(t, 0, blocking.Dropped())
+}
@@ -0,0 +1,205 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+)
+
+// operationEvents returns the channel to which an operation with the given options sends its events, along with a
+// function that must be called once the operation is complete and before its final cancel event is sent. If the
+// options ask for events in a deterministic order, the channel buffers the operation's events, and the function sends
+// them to the context's channel in that order.
+func operationEvents(ctx *Context, opts UpdateOptions) (chan<- Event, func()) {
+	if !opts.DeterministicEvents {
+		return ctx.Events, func() {}
+	}
+
+	in, done := make(chan Event), make(chan []Event)
+	go func() {
+		var events []Event
+		for e := range in {
+			events = append(events, e)
+		}
+		done <- events
+	}()
+
+	return in, func() {
+		close(in)
+		for _, e := range orderEvents(<-done) {
+			ctx.Events <- e
+		}
+	}
+}
+
+// orderEvents sorts the events of an operation into an order that does not depend on the order in which its steps
+// happened to run. The prelude and the deletion order come first; then each resource's events, in the order in which
+// they were emitted, with the resources in dependency order and ties broken by URN; then the events that concern no
+// resource, such as diagnostics about the operation as a whole; and finally the summary.
+//
+// Progress events, pause notifications, and ephemeral diagnostics depend on how long things take or when the operator
+// intervened rather than on what happened, so they are dropped.
+func orderEvents(events []Event) []Event {
+	var head, general, tail []Event
+	byURN := make(map[resource.URN][]Event)
+	deps := make(map[resource.URN][]resource.URN)
+	for _, e := range events {
+		switch e.Type {
+		case PreludeEvent, PlanCacheEvent, DeletionOrderEvent:
+			head = append(head, e)
+			continue
+		case SummaryEvent, CancellationEvent, CheckResultsEvent:
+			tail = append(tail, e)
+			continue
+		case StepProgressEvent, RefreshProgressEvent, AggregateProgressEvent, PausedEvent:
+			continue
+		case DiagEvent:
+			if e.Payload.(DiagEventPayload).Ephemeral {
+				continue
+			}
+		}
+
+		urn, metadata := eventResource(e)
+		if urn == "" {
+			general = append(general, e)
+			continue
+		}
+		if _, has := byURN[urn]; !has {
+			deps[urn] = nil
+		}
+		byURN[urn] = append(byURN[urn], e)
+		if metadata != nil {
+			deps[urn] = append(deps[urn], metadataDependencies(metadata)...)
+		}
+	}
+
+	ordered := append([]Event(nil), head...)
+	for _, urn := range sortResources(deps) {
+		ordered = append(ordered, byURN[urn]...)
+	}
+	ordered = append(ordered, general...)
+	return append(ordered, tail...)
+}
+
+// eventResource returns the URN of the resource that the given event concerns, if any, and the metadata of the step
+// that it reports, if any.
+func eventResource(e Event) (resource.URN, *StepEventMetadata) {
+	switch p := e.Payload.(type) {
+	case ResourcePreEventPayload:
+		return p.Metadata.URN, &p.Metadata
+	case ResourceOutputsEventPayload:
+		return p.Metadata.URN, &p.Metadata
+	case ResourceOperationFailedPayload:
+		return p.Metadata.URN, &p.Metadata
+	case PluginLifecycleEventPayload:
+		return p.Metadata.URN, &p.Metadata
+	case ConfirmationRequiredEventPayload:
+		return p.Metadata.URN, &p.Metadata
+	case StepDependenciesEventPayload:
+		return p.Metadata.URN, &p.Metadata
+	case PolicyViolationEventPayload:
+		return p.ResourceURN, nil
+	case DiagEventPayload:
+		return p.URN, nil
+	case InitErrorsEventPayload:
+		return p.URN, nil
+	case ProviderChangeEventPayload:
+		return p.URN, nil
+	case PendingDeleteEventPayload:
+		return p.URN, nil
+	default:
+		return "", nil
+	}
+}
+
+// metadataDependencies returns the URNs of the resources on which the resource of the given step depends: its parent,
+// its provider, and its explicit dependencies.
+func metadataDependencies(metadata *StepEventMetadata) []resource.URN {
+	state := metadata.Res
+	if state == nil {
+		return nil
+	}
+
+	var deps []resource.URN
+	if state.Parent != "" {
+		deps = append(deps, state.Parent)
+	}
+	if state.Provider != "" {
+		if ref, err := providers.ParseReference(state.Provider); err == nil {
+			deps = append(deps, ref.URN())
+		}
+	}
+	if state.State != nil {
+		deps = append(deps, state.State.Dependencies...)
+	}
+	return deps
+}
+
+// sortResources returns the URNs in the given dependency graph in topological order, choosing the least URN whenever
+// more than one resource is ready. Dependencies on resources outside the graph are ignored, and resources in a cycle,
+// which a valid graph never has, follow the rest in URN order.
+func sortResources(deps map[resource.URN][]resource.URN) []resource.URN {
+	pending := make(map[resource.URN]int, len(deps))
+	dependents := make(map[resource.URN][]resource.URN)
+	for urn, urnDeps := range deps {
+		seen := make(map[resource.URN]bool)
+		for _, dep := range urnDeps {
+			if _, has := deps[dep]; has && dep != urn && !seen[dep] {
+				seen[dep] = true
+				pending[urn]++
+				dependents[dep] = append(dependents[dep], urn)
+			}
+		}
+	}
+
+	var ready []resource.URN
+	for urn := range deps {
+		if pending[urn] == 0 {
+			ready = append(ready, urn)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i] < ready[j] })
+
+	sorted := make([]resource.URN, 0, len(deps))
+	for len(ready) > 0 {
+		urn := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, urn)
+
+		for _, dependent := range dependents[urn] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				i := sort.Search(len(ready), func(i int) bool { return ready[i] >= dependent })
+				ready = append(ready, "")
+				copy(ready[i+1:], ready[i:])
+				ready[i] = dependent
+			}
+		}
+	}
+
+	if len(sorted) < len(deps) {
+		var cycle []resource.URN
+		for urn := range deps {
+			if pending[urn] > 0 {
+				cycle = append(cycle, urn)
+			}
+		}
+		sort.Slice(cycle, func(i, j int) bool { return cycle[i] < cycle[j] })
+		sorted = append(sorted, cycle...)
+	}
+	return sorted
+}
@@ -0,0 +1,94 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+func TestOrderEvents(t *testing.T) {
+	const (
+		stack  = resource.URN("urn:pulumi:test::test::pulumi:pulumi:Stack::test-test")
+		parent = resource.URN("urn:pulumi:test::test::pkgA:m:typA::z-parent")
+		child  = resource.URN("urn:pulumi:test::test::pkgA:m:typA::a-child")
+		other  = resource.URN("urn:pulumi:test::test::pkgA:m:typA::b-other")
+	)
+	pre := func(urn, parent resource.URN) Event {
+		return Event{Type: ResourcePreEvent, Payload: ResourcePreEventPayload{
+			Metadata: StepEventMetadata{URN: urn, Res: &StepEventStateMetadata{URN: urn, Parent: parent}},
+		}}
+	}
+	outputs := func(urn resource.URN) Event {
+		return Event{Type: ResourceOutputsEvent, Payload: ResourceOutputsEventPayload{
+			Metadata: StepEventMetadata{URN: urn},
+		}}
+	}
+	diagEvent := func(urn resource.URN, message string, ephemeral bool) Event {
+		return Event{Type: DiagEvent, Payload: DiagEventPayload{URN: urn, Message: message, Ephemeral: ephemeral}}
+	}
+
+	// Two runs whose steps interleave differently are ordered identically.
+	summary := Event{Type: SummaryEvent, Payload: SummaryEventPayload{}}
+	prelude := Event{Type: PreludeEvent, Payload: PreludeEventPayload{}}
+	first := []Event{
+		prelude,
+		pre(stack, ""),
+		pre(other, stack),
+		pre(parent, stack),
+		diagEvent("", "general", false),
+		pre(child, parent),
+		outputs(child),
+		diagEvent(other, "working...", true),
+		outputs(other),
+		outputs(parent),
+		outputs(stack),
+		summary,
+	}
+	second := []Event{
+		prelude,
+		pre(stack, ""),
+		pre(parent, stack),
+		pre(child, parent),
+		pre(other, stack),
+		outputs(other),
+		outputs(child),
+		diagEvent("", "general", false),
+		outputs(parent),
+		outputs(stack),
+		summary,
+	}
+
+	expected := []Event{
+		prelude,
+		pre(stack, ""),
+		outputs(stack),
+		// other and parent both depend only on the stack, and other has the lesser URN.
+		pre(other, stack),
+		outputs(other),
+		pre(parent, stack),
+		outputs(parent),
+		// child has the least URN of all, but depends on parent.
+		pre(child, parent),
+		outputs(child),
+		diagEvent("", "general", false),
+		summary,
+	}
+	assert.Equal(t, expected, orderEvents(first))
+	assert.Equal(t, expected, orderEvents(second))
+}
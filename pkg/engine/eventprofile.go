@@ -0,0 +1,127 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// EventProfile determines which events an operation emits.
+type EventProfile string
+
+const (
+	// FullEvents emits the events of every step. This is the default.
+	FullEvents EventProfile = ""
+	// SummaryEvents suppresses the events of steps that leave their resources unchanged, which make up most of the
+	// steps of a typical update of a large stack, and instead emits aggregate progress events that count the steps
+	// that have completed. The events of all other steps, diagnostics, and the summary are emitted as usual.
+	SummaryEvents EventProfile = "summary"
+)
+
+const (
+	// aggregateProgressSteps is the number of steps after which another aggregate progress event is emitted.
+	aggregateProgressSteps = 100
+	// aggregateProgressInterval is the time after which another aggregate progress event is emitted, if any steps
+	// have completed since the last one.
+	aggregateProgressInterval = 2 * time.Second
+)
+
+// progressAggregator counts the steps of an operation whose event profile is SummaryEvents.
+type progressAggregator struct {
+	lock      sync.Mutex
+	total     int             // the number of resources in the stack before the operation.
+	processed int             // the number of steps that have completed.
+	ops       ResourceChanges // the number of steps of each kind that have completed successfully.
+	reported  int             // the number of steps that had completed as of the last event.
+	last      time.Time       // the time of the last event.
+}
+
+// newProgressAggregator returns the aggregator for an operation with the given event profile on a stack with the given
+// snapshot, or nil if the profile does not aggregate progress.
+func newProgressAggregator(profile EventProfile, snap *deploy.Snapshot) *progressAggregator {
+	if profile != SummaryEvents {
+		return nil
+	}
+	total := 0
+	if snap != nil {
+		total = len(snap.Resources)
+	}
+	return &progressAggregator{total: total, ops: make(ResourceChanges), last: time.Now()}
+}
+
+// quiet returns true if the events of steps with the given operation are suppressed.
+func (e *eventEmitter) quiet(op deploy.StepOp) bool {
+	return e.progress != nil && op == deploy.OpSame
+}
+
+// stepProcessed counts a step that has completed, and emits an aggregate progress event if enough steps have
+// completed or enough time has passed since the last one. If counted is true, the step completed successfully and is
+// counted towards the steps of its kind.
+func (e *eventEmitter) stepProcessed(op deploy.StepOp, counted bool) {
+	contract.Requiref(e != nil, "e", "!= nil")
+	if e.progress == nil {
+		return
+	}
+
+	p := e.progress
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.processed++
+	if counted {
+		p.ops[op]++
+	}
+	if p.processed-p.reported >= aggregateProgressSteps || time.Since(p.last) >= aggregateProgressInterval {
+		e.aggregateProgressEvent(false)
+	}
+}
+
+// progressDone emits the final aggregate progress event of an operation, once all of its steps have completed.
+func (e *eventEmitter) progressDone() {
+	contract.Requiref(e != nil, "e", "!= nil")
+	if e.progress == nil {
+		return
+	}
+
+	e.progress.lock.Lock()
+	defer e.progress.lock.Unlock()
+	e.aggregateProgressEvent(true)
+}
+
+// aggregateProgressEvent emits an aggregate progress event. The caller must hold the aggregator's lock, so that the
+// events are emitted in the order in which the steps they count completed.
+func (e *eventEmitter) aggregateProgressEvent(done bool) {
+	p := e.progress
+	ops := make(ResourceChanges, len(p.ops))
+	for op, count := range p.ops {
+		ops[op] = count
+	}
+	p.reported, p.last = p.processed, time.Now()
+
+	e.Chan <- Event{
+		Type:    AggregateProgressEvent,
+		Version: EventSchemaVersion,
+		Payload: AggregateProgressEventPayload{
+			Processed: p.processed,
+			Total:     p.total,
+			Ops:       ops,
+			Done:      done,
+		},
+	}
+}
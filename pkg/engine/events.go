@@ -17,44 +17,70 @@ package engine
 import (
 	"bytes"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
+
 	"github.com/pulumi/pulumi/pkg/apitype"
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/diag/colors"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
 )
 
+// EventSchemaVersion is the version of the schema of the events generated by the engine. Events are converted to the
+// corresponding version of the apitype.EngineEvent schema by ConvertEvent, and may be down-converted from there for
+// older consumers using migrate.DownToEngineEvent.
+const EventSchemaVersion = apitype.EngineEventSchemaVersionCurrent
+
 // Event represents an event generated by the engine during an operation. The underlying
 // type for the `Payload` field will differ depending on the value of the `Type` field
 type Event struct {
 	Type    EventType
+	Version int // the version of the schema of the event's payload; see EventSchemaVersion.
 	Payload interface{}
+	Stack   tokens.QName // the stack whose operation generated this event; set only for UpdateMany and Rehearse.
 }
 
 // EventType is the kind of event being emitted.
 type EventType string
 
 const (
-	CancelEvent             EventType = "cancel"
-	StdoutColorEvent        EventType = "stdoutcolor"
-	DiagEvent               EventType = "diag"
-	PreludeEvent            EventType = "prelude"
-	SummaryEvent            EventType = "summary"
-	ResourcePreEvent        EventType = "resource-pre"
-	ResourceOutputsEvent    EventType = "resource-outputs"
-	ResourceOperationFailed EventType = "resource-operationfailed"
-	PolicyViolationEvent    EventType = "policy-violation"
+	CancelEvent               EventType = "cancel"
+	StdoutColorEvent          EventType = "stdoutcolor"
+	DiagEvent                 EventType = "diag"
+	PreludeEvent              EventType = "prelude"
+	SummaryEvent              EventType = "summary"
+	ResourcePreEvent          EventType = "resource-pre"
+	ResourceOutputsEvent      EventType = "resource-outputs"
+	ResourceOperationFailed   EventType = "resource-operationfailed"
+	PolicyViolationEvent      EventType = "policy-violation"
+	StepProgressEvent         EventType = "step-progress"
+	PluginLifecycleEvent      EventType = "plugin-lifecycle"
+	ConfirmationRequiredEvent EventType = "confirmation-required"
+	PlanCacheEvent            EventType = "plan-cache"
+	StepDependenciesEvent     EventType = "step-dependencies"
+	CancellationEvent         EventType = "cancellation"
+	RefreshProgressEvent      EventType = "refresh-progress"
+	CheckResultsEvent         EventType = "check-results"
+	DeletionOrderEvent        EventType = "deletion-order"
+	PausedEvent               EventType = "paused"
+	InitErrorsEvent           EventType = "init-errors"
+	ProviderChangeEvent       EventType = "provider-change"
+	AggregateProgressEvent    EventType = "aggregate-progress"
+	PendingDeleteEvent        EventType = "pending-delete"
 )
 
 func cancelEvent() Event {
-	return Event{Type: CancelEvent}
+	return Event{Type: CancelEvent, Version: EventSchemaVersion}
 }
 
 // DiagEventPayload is the payload for an event with type `diag`
@@ -95,12 +121,219 @@ type SummaryEventPayload struct {
 	MaybeCorrupt    bool            // true if one or more resources may be corrupt
 	Duration        time.Duration   // the duration of the entire update operation (zero values for previews)
 	ResourceChanges ResourceChanges // count of changed resources, useful for reporting
+	OutputChanges   OutputChanges   // the changes to the stack's outputs (always empty for previews)
+
+	// Deprecations counts the uses of resource types and input properties that their providers have deprecated.
+	Deprecations []deploy.DeprecatedUsage
+
+	// Estimates are the estimates of the impact of a preview's plan (always empty for updates). See PlanEstimator.
+	Estimates []PlanEstimate
+}
+
+// OutputChange describes a change to a single stack output. Secret values are masked.
+type OutputChange struct {
+	Key resource.PropertyKey   // the name of the output.
+	Old resource.PropertyValue // the output's value before the update, or null if the output was added.
+	New resource.PropertyValue // the output's value after the update, or null if the output was removed.
+}
+
+// OutputChanges describes how an update changed the stack's outputs. Each list is sorted by key.
+type OutputChanges struct {
+	Added   []OutputChange
+	Changed []OutputChange
+	Removed []OutputChange
+}
+
+// HasChanges returns true if any of the stack's outputs were added, changed, or removed.
+func (c OutputChanges) HasChanges() bool {
+	return len(c.Added) > 0 || len(c.Changed) > 0 || len(c.Removed) > 0
+}
+
+// diffStackOutputs computes the changes between the given old and new stack outputs, masking secret values.
+func diffStackOutputs(olds, news resource.PropertyMap, secrets *secretFilter, debug bool) OutputChanges {
+	maskedOlds, maskedNews := filterPropertyMap(olds, secrets, debug), filterPropertyMap(news, secrets, debug)
+
+	var changes OutputChanges
+	for _, k := range news.StableKeys() {
+		old, has := olds[k]
+		switch {
+		case !has:
+			changes.Added = append(changes.Added, OutputChange{
+				Key: k, Old: resource.NewNullProperty(), New: maskedNews[k]})
+		case !old.DeepEquals(news[k]):
+			changes.Changed = append(changes.Changed, OutputChange{Key: k, Old: maskedOlds[k], New: maskedNews[k]})
+		}
+	}
+	for _, k := range olds.StableKeys() {
+		if _, has := news[k]; !has {
+			changes.Removed = append(changes.Removed, OutputChange{
+				Key: k, Old: maskedOlds[k], New: resource.NewNullProperty()})
+		}
+	}
+	return changes
 }
 
 type ResourceOperationFailedPayload struct {
+	Metadata  StepEventMetadata
+	Status    resource.Status
+	Steps     int
+	Details   *plugin.FailureDetails // structured details about the failure, if the provider supplied any.
+	Kind      deploy.StepErrorKind   // the kind of failure.
+	Code      codes.Code             // the code of the failure; see deploy.StepError.
+	Retryable bool                   // true if the failure is one that the update's retry policy retries.
+}
+
+// StepProgressEventPayload is the payload for an event with type `step-progress`. It reports the progress of a
+// long-running provider operation, as reported by the provider itself.
+type StepProgressEventPayload struct {
+	Metadata StepEventMetadata
+	Percent  int    // the percentage of the operation that has completed, or negative if unknown.
+	Message  string // a short description of what the provider is currently doing.
+}
+
+// PluginLifecycleEventPayload is the payload for an event with type `plugin-lifecycle`. It reports that a provider
+// plugin exited while applying a step and was restarted so that the step could be retried.
+type PluginLifecycleEventPayload struct {
 	Metadata StepEventMetadata
-	Status   resource.Status
-	Steps    int
+	Plugin   string // the name of the plugin that was restarted.
+	Message  string // a description of the error that the interrupted step failed with.
+}
+
+// ConfirmationRequiredEventPayload is the payload for an event with type `confirmation-required`. It reports that the
+// update is waiting for the caller to confirm a destructive step, which it does by sending a ConfirmationResponse on
+// the Context's Confirmations channel.
+type ConfirmationRequiredEventPayload struct {
+	Metadata StepEventMetadata
+}
+
+// PlanCacheEventPayload is the payload for an event with type `plan-cache`. It reports whether a preview was served
+// from the plan cache; if it was, the events that follow replay the cached plan rather than a fresh one.
+type PlanCacheEventPayload struct {
+	Key     string    // the key under which the plan is cached.
+	Hit     bool      // true if the preview was served from the cache.
+	Forced  bool      // true if the cache was bypassed because a fresh plan was requested.
+	Created time.Time // the time at which the cached plan was computed (zero on a miss).
+}
+
+// StepDependencyReason describes why a step is scheduled after one of its predecessors.
+type StepDependencyReason string
+
+const (
+	// StepDependencyParent indicates that the predecessor is the resource's parent.
+	StepDependencyParent StepDependencyReason = "parent"
+	// StepDependencyProvider indicates that the predecessor is the resource's provider.
+	StepDependencyProvider StepDependencyReason = "provider"
+	// StepDependencyExplicit indicates that the resource was declared to depend on the predecessor.
+	StepDependencyExplicit StepDependencyReason = "dependency"
+	// StepDependencyProperty indicates that one or more of the resource's inputs refer to the predecessor's outputs.
+	StepDependencyProperty StepDependencyReason = "property"
+)
+
+// StepDependency describes why a step is scheduled after one of its predecessors.
+type StepDependency struct {
+	URN        resource.URN           // the predecessor.
+	Reason     StepDependencyReason   // why the step waits on the predecessor.
+	Properties []resource.PropertyKey // the inputs that refer to the predecessor, for property dependencies.
+}
+
+// StepDependenciesEventPayload is the payload for an event with type `step-dependencies`. It is emitted during previews
+// for each step that has predecessors, and describes why the step is scheduled after each of them.
+type StepDependenciesEventPayload struct {
+	Metadata     StepEventMetadata
+	Dependencies []StepDependency
+}
+
+// CancellationPhase describes what an operation was doing when it was cancelled.
+type CancellationPhase string
+
+const (
+	// CancellationPlanning indicates that no step was executing: the operation was a preview, or was waiting on the
+	// program to register its next resource.
+	CancellationPlanning CancellationPhase = "planning"
+	// CancellationExecuting indicates that one or more steps were executing.
+	CancellationExecuting CancellationPhase = "executing"
+	// CancellationPersisting indicates that the result of a step was being written to the stack's checkpoint.
+	CancellationPersisting CancellationPhase = "persisting"
+)
+
+// CancellationEventPayload is the payload for an event with type `cancellation`. It is emitted once a cancelled
+// operation stops, and describes what state the operation left the stack in. Note that every operation ends with an
+// event with type `cancel`, whether it was cancelled or not.
+type CancellationEventPayload struct {
+	Phase      CancellationPhase // what the operation was doing when it was cancelled.
+	InFlight   []resource.URN    // the resources whose steps were executing when the operation was cancelled.
+	Completed  []resource.URN    // the in-flight steps' resources whose steps went on to succeed.
+	Terminated bool              // true if the operation was terminated without waiting for its in-flight steps.
+}
+
+// RefreshProgressEventPayload is the payload for an event with type `refresh-progress`. It is emitted before the reads
+// of a refresh begin and each time another tenth of them has completed.
+type RefreshProgressEventPayload struct {
+	Completed int // the number of resources that have been read, successfully or not.
+	Failed    int // the number of resources whose reads failed.
+	Total     int // the number of resources to read.
+}
+
+// AggregateProgressEventPayload is the payload for an event with type `aggregate-progress`. It is emitted by operations
+// whose event profile is SummaryEvents, in place of the events of the steps that leave their resources unchanged: each
+// time another batch of steps has completed or some time has passed since the last one, and once all of the steps of
+// the operation have completed.
+type AggregateProgressEventPayload struct {
+	Processed int             // the number of steps that have completed, successfully or not.
+	Total     int             // the number of resources in the stack before the operation, which estimates the steps.
+	Ops       ResourceChanges // the number of steps of each kind that have completed successfully.
+	Done      bool            // true if all of the steps have completed.
+}
+
+// CheckResultsEventPayload is the payload for an event with type `check-results`. It is emitted once the checks that
+// run after an update is applied have completed.
+type CheckResultsEventPayload struct {
+	Checks []CheckResult // the results of the checks, in the order in which they ran.
+}
+
+// DeletionOrderEventPayload is the payload for an event with type `deletion-order`. It is emitted before the first of
+// the deletes of resources that the program no longer produces begins, if UpdateOptions.ReportDeletionOrder is set.
+type DeletionOrderEventPayload struct {
+	// Batches holds the URNs of the resources to delete, in batches that are deleted one after another, in reverse
+	// dependency order. The resources of each batch are deleted in parallel, and are sorted by URN.
+	Batches [][]resource.URN
+}
+
+// PausedEventPayload is the payload for an event with type `paused`. It is emitted when an update pauses or resumes in
+// response to a command sent on its context's Control channel. Steps that were executing when the update paused may
+// still complete after this event.
+type PausedEventPayload struct {
+	Paused bool // true if the update has paused, or false if it has resumed.
+}
+
+// InitErrorsEventPayload is the payload for an event with type `init-errors`. It is emitted by RetryInitialization and
+// ClearInitErrors for each resource whose initialization errors they handle.
+type InitErrorsEventPayload struct {
+	URN     resource.URN    // the resource whose errors were handled.
+	Action  InitErrorAction // what was done about them.
+	Errors  []string        // the resource's initialization errors afterwards.
+	Message string          // a human-readable explanation of the action.
+}
+
+// PendingDeleteEventPayload is the payload for an event with type `pending-delete`. It is emitted by
+// CollectPendingDeletes for each resource pending deletion, including by the pass that an update runs when
+// UpdateOptions.CollectPendingDeletes is set.
+type PendingDeleteEventPayload struct {
+	URN      resource.URN        // the resource that was pending deletion.
+	ID       resource.ID         // the resource's ID.
+	Action   PendingDeleteAction // what was done about it.
+	Attempts int                 // the number of times the pass attempted to delete the resource.
+	Message  string              // a human-readable explanation of the action.
+}
+
+// ProviderChangeEventPayload is the payload for an event with type `provider-change`. It is emitted when a step that
+// updates or replaces a provider because its configuration changed is planned, before the step is executed.
+type ProviderChangeEventPayload struct {
+	URN         resource.URN           // the provider whose configuration changes.
+	Op          deploy.StepOp          // OpUpdate if the provider is updated in place, or OpReplace if it is replaced.
+	Keys        []resource.PropertyKey // the configuration keys that change.
+	ReplaceKeys []resource.PropertyKey // the configuration keys whose changes force the provider to be replaced.
+	Dependents  []resource.URN         // the resources that the provider manages, which its replacement may replace.
 }
 
 type ResourceOutputsEventPayload struct {
@@ -162,6 +395,10 @@ type StepEventStateMetadata struct {
 	// InitErrors is the set of errors encountered in the process of initializing resource (i.e.,
 	// during create or update).
 	InitErrors []string
+	// Annotations are the annotations attached to the resource by external tools.
+	Annotations map[string]string
+	// CustomTimeouts are the resource's operation timeouts and poll interval.
+	CustomTimeouts resource.CustomTimeouts
 }
 
 func makeEventEmitter(events chan<- Event, update UpdateInfo) (eventEmitter, error) {
@@ -184,18 +421,51 @@ func makeEventEmitter(events chan<- Event, update UpdateInfo) (eventEmitter, err
 		}
 	}
 
-	logging.AddGlobalFilter(logging.CreateFilter(secrets, "[secret]"))
+	// Configuration secrets are also masked process-wide, so that they never appear in the logs or in diagnostics
+	// that are written by a sink rather than as engine events, such as the error that ends an update.
+	if len(secrets) > 0 {
+		logging.AddGlobalFilter(logging.CreateFilter(secrets, "[secret]"))
+	}
+
+	filter := newSecretFilter()
+	filter.addSecrets(secrets)
+	if target.Snapshot != nil {
+		filter.addStates(target.Snapshot.Resources...)
+	}
 
 	return eventEmitter{
-		Chan: events,
+		Chan:    events,
+		secrets: filter,
 	}, nil
 }
 
 type eventEmitter struct {
-	Chan chan<- Event
+	Chan     chan<- Event
+	secrets  *secretFilter       // the secrets known to the operation, which are masked in the events it emits.
+	diags    *diagRecorder       // if non-nil, records the diagnostics the operation emits.
+	progress *progressAggregator // if non-nil, aggregates the progress of steps whose events are suppressed.
+}
+
+// diagRecorder records the diagnostics emitted by an operation, including its program's output, so that they can be
+// replayed along with a cached plan.
+type diagRecorder struct {
+	lock  sync.Mutex
+	diags []DiagEventPayload
+}
+
+func (r *diagRecorder) record(d DiagEventPayload) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.diags = append(r.diags, d)
 }
 
-func makeStepEventMetadata(op deploy.StepOp, step deploy.Step, debug bool) StepEventMetadata {
+func (r *diagRecorder) recorded() []DiagEventPayload {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]DiagEventPayload(nil), r.diags...)
+}
+
+func makeStepEventMetadata(op deploy.StepOp, step deploy.Step, secrets *secretFilter, debug bool) StepEventMetadata {
 	contract.Assert(op == step.Op() || step.Op() == deploy.OpRefresh)
 
 	var keys, diffs []resource.PropertyKey
@@ -212,15 +482,15 @@ func makeStepEventMetadata(op deploy.StepOp, step deploy.Step, debug bool) StepE
 		Type:     step.Type(),
 		Keys:     keys,
 		Diffs:    diffs,
-		Old:      makeStepEventStateMetadata(step.Old(), debug),
-		New:      makeStepEventStateMetadata(step.New(), debug),
-		Res:      makeStepEventStateMetadata(step.Res(), debug),
+		Old:      makeStepEventStateMetadata(step.Old(), secrets, debug),
+		New:      makeStepEventStateMetadata(step.New(), secrets, debug),
+		Res:      makeStepEventStateMetadata(step.Res(), secrets, debug),
 		Logical:  step.Logical(),
 		Provider: step.Provider(),
 	}
 }
 
-func makeStepEventStateMetadata(state *resource.State, debug bool) *StepEventStateMetadata {
+func makeStepEventStateMetadata(state *resource.State, secrets *secretFilter, debug bool) *StepEventStateMetadata {
 	if state == nil {
 		return nil
 	}
@@ -234,14 +504,17 @@ func makeStepEventStateMetadata(state *resource.State, debug bool) *StepEventSta
 		ID:         state.ID,
 		Parent:     state.Parent,
 		Protect:    state.Protect,
-		Inputs:     filterPropertyMap(state.Inputs, debug),
-		Outputs:    filterPropertyMap(state.Outputs, debug),
+		Inputs:     filterPropertyMap(state.Inputs, secrets, debug),
+		Outputs:    filterPropertyMap(state.Outputs, secrets, debug),
 		Provider:   state.Provider,
 		InitErrors: state.InitErrors,
+
+		Annotations:    state.Annotations,
+		CustomTimeouts: state.CustomTimeouts,
 	}
 }
 
-func filterPropertyMap(propertyMap resource.PropertyMap, debug bool) resource.PropertyMap {
+func filterPropertyMap(propertyMap resource.PropertyMap, secrets *secretFilter, debug bool) resource.PropertyMap {
 	mappable := propertyMap.Mappable()
 
 	var filterValue func(v interface{}) interface{}
@@ -267,7 +540,7 @@ func filterPropertyMap(propertyMap resource.PropertyMap, debug bool) resource.Pr
 			return v
 		case string:
 			// have to ensure we filter out secrets.
-			return logging.FilterString(t)
+			return secrets.filter(t)
 		case *resource.Asset:
 			text := t.Text
 			if text != "" {
@@ -278,7 +551,7 @@ func filterPropertyMap(propertyMap resource.PropertyMap, debug bool) resource.Pr
 				// progress/diffs/etc.
 				if t.IsUserProgramCode() {
 					// also make sure we filter this in case there are any secrets in the code.
-					text = logging.FilterString(resource.MassageIfUserProgramCodeAsset(t, debug).Text)
+					text = secrets.filter(resource.MassageIfUserProgramCodeAsset(t, debug).Text)
 				} else {
 					// We need to have some string here so that we preserve that this is a
 					// text-asset
@@ -353,61 +626,283 @@ func filterPropertyMap(propertyMap resource.PropertyMap, debug bool) resource.Pr
 }
 
 func (e *eventEmitter) resourceOperationFailedEvent(
-	step deploy.Step, status resource.Status, steps int, debug bool) {
+	step deploy.Step, status resource.Status, err error, retry deploy.RetryPolicy, steps int, debug bool) {
 
 	contract.Requiref(e != nil, "e", "!= nil")
 
+	stepErr := deploy.AsStepError(err)
+	var details *plugin.FailureDetails
+	if explained, ok := stepErr.Err.(*deploy.ExplainedError); ok {
+		details = &explained.Details
+	}
+
 	e.Chan <- Event{
-		Type: ResourceOperationFailed,
+		Type:    ResourceOperationFailed,
+		Version: EventSchemaVersion,
 		Payload: ResourceOperationFailedPayload{
-			Metadata: makeStepEventMetadata(step.Op(), step, debug),
-			Status:   status,
-			Steps:    steps,
+			Metadata:  makeStepEventMetadata(step.Op(), step, e.secrets, debug),
+			Status:    status,
+			Steps:     steps,
+			Details:   details,
+			Kind:      stepErr.Kind,
+			Code:      stepErr.Code,
+			Retryable: stepErr.Retryable(retry, status),
 		},
 	}
 }
 
 func (e *eventEmitter) resourceOutputsEvent(op deploy.StepOp, step deploy.Step, planning bool, debug bool) {
 	contract.Requiref(e != nil, "e", "!= nil")
+	if e.quiet(step.Op()) {
+		return
+	}
 
 	e.Chan <- Event{
-		Type: ResourceOutputsEvent,
+		Type:    ResourceOutputsEvent,
+		Version: EventSchemaVersion,
 		Payload: ResourceOutputsEventPayload{
-			Metadata: makeStepEventMetadata(op, step, debug),
+			Metadata: makeStepEventMetadata(op, step, e.secrets, debug),
 			Planning: planning,
 			Debug:    debug,
 		},
 	}
 }
 
+func (e *eventEmitter) stepProgressEvent(step deploy.Step, percent int, message string, debug bool) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    StepProgressEvent,
+		Version: EventSchemaVersion,
+		Payload: StepProgressEventPayload{
+			Metadata: makeStepEventMetadata(step.Op(), step, e.secrets, debug),
+			Percent:  percent,
+			Message:  e.secrets.filter(message),
+		},
+	}
+}
+
+func (e *eventEmitter) pluginLifecycleEvent(step deploy.Step, err error, debug bool) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    PluginLifecycleEvent,
+		Version: EventSchemaVersion,
+		Payload: PluginLifecycleEventPayload{
+			Metadata: makeStepEventMetadata(step.Op(), step, e.secrets, debug),
+			Plugin:   string(step.Type().Package()),
+			Message:  e.secrets.filter(err.Error()),
+		},
+	}
+}
+
+func (e *eventEmitter) confirmationRequiredEvent(step deploy.Step, debug bool) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    ConfirmationRequiredEvent,
+		Version: EventSchemaVersion,
+		Payload: ConfirmationRequiredEventPayload{
+			Metadata: makeStepEventMetadata(step.Op(), step, e.secrets, debug),
+		},
+	}
+}
+
+func (e *eventEmitter) planCacheEvent(key string, hit bool, forced bool, created time.Time) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    PlanCacheEvent,
+		Version: EventSchemaVersion,
+		Payload: PlanCacheEventPayload{
+			Key:     key,
+			Hit:     hit,
+			Forced:  forced,
+			Created: created,
+		},
+	}
+}
+
+func (e *eventEmitter) stepDependenciesEvent(step deploy.Step, dependencies []StepDependency, debug bool) {
+	contract.Requiref(e != nil, "e", "!= nil")
+	if e.quiet(step.Op()) {
+		return
+	}
+
+	e.Chan <- Event{
+		Type:    StepDependenciesEvent,
+		Version: EventSchemaVersion,
+		Payload: StepDependenciesEventPayload{
+			Metadata:     makeStepEventMetadata(step.Op(), step, e.secrets, debug),
+			Dependencies: dependencies,
+		},
+	}
+}
+
+func (e *eventEmitter) cancellationEvent(payload CancellationEventPayload) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    CancellationEvent,
+		Version: EventSchemaVersion,
+		Payload: payload,
+	}
+}
+
+func (e *eventEmitter) refreshProgressEvent(progress deploy.RefreshProgress) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    RefreshProgressEvent,
+		Version: EventSchemaVersion,
+		Payload: RefreshProgressEventPayload{
+			Completed: progress.Completed,
+			Failed:    progress.Failed,
+			Total:     progress.Total,
+		},
+	}
+}
+
+func (e *eventEmitter) deletionOrderEvent(batches [][]deploy.Step, opts planOptions) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	var payload DeletionOrderEventPayload
+	for _, batch := range batches {
+		var urns []resource.URN
+		for _, step := range batch {
+			if shouldReportStep(step, opts) {
+				urns = append(urns, step.URN())
+			}
+		}
+		if len(urns) > 0 {
+			sort.Slice(urns, func(i, j int) bool { return urns[i] < urns[j] })
+			payload.Batches = append(payload.Batches, urns)
+		}
+	}
+	if len(payload.Batches) == 0 {
+		return
+	}
+
+	e.Chan <- Event{
+		Type:    DeletionOrderEvent,
+		Version: EventSchemaVersion,
+		Payload: payload,
+	}
+}
+
+func (e *eventEmitter) providerChangeEvent(change deploy.ProviderChange) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    ProviderChangeEvent,
+		Version: EventSchemaVersion,
+		Payload: ProviderChangeEventPayload{
+			URN:         change.URN,
+			Op:          change.Op,
+			Keys:        change.Keys,
+			ReplaceKeys: change.ReplaceKeys,
+			Dependents:  change.Dependents,
+		},
+	}
+}
+
+func (e *eventEmitter) pausedEvent(paused bool) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    PausedEvent,
+		Version: EventSchemaVersion,
+		Payload: PausedEventPayload{Paused: paused},
+	}
+}
+
+func (e *eventEmitter) checkResultsEvent(checks []CheckResult) {
+	contract.Requiref(e != nil, "e", "!= nil")
+
+	e.Chan <- Event{
+		Type:    CheckResultsEvent,
+		Version: EventSchemaVersion,
+		Payload: CheckResultsEventPayload{Checks: checks},
+	}
+}
+
+// stepDependencies returns the reasons that the step for the given resource is scheduled after each of its
+// predecessors: its parent, its provider, and each of its dependencies, which are explicit unless one of the
+// resource's inputs refers to them.
+func stepDependencies(state *resource.State) []StepDependency {
+	var dependencies []StepDependency
+	if state.Parent != "" {
+		dependencies = append(dependencies, StepDependency{URN: state.Parent, Reason: StepDependencyParent})
+	}
+	if state.Provider != "" {
+		if ref, err := providers.ParseReference(state.Provider); err == nil {
+			dependencies = append(dependencies, StepDependency{URN: ref.URN(), Reason: StepDependencyProvider})
+		}
+	}
+
+	keys := make([]resource.PropertyKey, 0, len(state.PropertyDependencies))
+	for k := range state.PropertyDependencies {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	referrers := make(map[resource.URN][]resource.PropertyKey)
+	for _, k := range keys {
+		for _, urn := range state.PropertyDependencies[k] {
+			referrers[urn] = append(referrers[urn], k)
+		}
+	}
+
+	for _, urn := range state.Dependencies {
+		dependency := StepDependency{URN: urn, Reason: StepDependencyExplicit}
+		if referring, ok := referrers[urn]; ok {
+			dependency.Reason, dependency.Properties = StepDependencyProperty, referring
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies
+}
+
 func (e *eventEmitter) resourcePreEvent(
 	step deploy.Step, planning bool, debug bool) {
 
 	contract.Requiref(e != nil, "e", "!= nil")
+	if e.quiet(step.Op()) {
+		return
+	}
 
 	e.Chan <- Event{
-		Type: ResourcePreEvent,
+		Type:    ResourcePreEvent,
+		Version: EventSchemaVersion,
 		Payload: ResourcePreEventPayload{
-			Metadata: makeStepEventMetadata(step.Op(), step, debug),
+			Metadata: makeStepEventMetadata(step.Op(), step, e.secrets, debug),
 			Planning: planning,
 			Debug:    debug,
 		},
 	}
 }
 
-func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.Map) {
+// preludeEvent reports the configuration of an operation. Secret values are blinded, as are the values of the given
+// keys, which are those of overrides of secret values.
+func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.Map, secretOverrides []config.Key) {
 	contract.Requiref(e != nil, "e", "!= nil")
 
-	configStringMap := make(map[string]string, len(cfg))
+	blinder := config.NewBlindingDecrypter()
+	configStringMap := make(map[string]string, len(cfg)+len(secretOverrides))
 	for k, v := range cfg {
 		keyString := k.String()
-		valueString, err := v.Value(config.NewBlindingDecrypter())
+		valueString, err := v.Value(blinder)
 		contract.AssertNoError(err)
 		configStringMap[keyString] = valueString
 	}
+	for _, k := range secretOverrides {
+		valueString, err := config.NewSecureValue("").Value(blinder)
+		contract.AssertNoError(err)
+		configStringMap[k.String()] = valueString
+	}
 
 	e.Chan <- Event{
-		Type: PreludeEvent,
+		Type:    PreludeEvent,
+		Version: EventSchemaVersion,
 		Payload: PreludeEventPayload{
 			IsPreview: isPreview,
 			Config:    configStringMap,
@@ -415,31 +910,39 @@ func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.Map) {
 	}
 }
 
-func (e *eventEmitter) previewSummaryEvent(resourceChanges ResourceChanges) {
+func (e *eventEmitter) previewSummaryEvent(resourceChanges ResourceChanges, deprecations []deploy.DeprecatedUsage,
+	estimates []PlanEstimate) {
+
 	contract.Requiref(e != nil, "e", "!= nil")
 
 	e.Chan <- Event{
-		Type: SummaryEvent,
+		Type:    SummaryEvent,
+		Version: EventSchemaVersion,
 		Payload: SummaryEventPayload{
 			IsPreview:       true,
 			MaybeCorrupt:    false,
 			Duration:        0,
 			ResourceChanges: resourceChanges,
+			Deprecations:    deprecations,
+			Estimates:       estimates,
 		},
 	}
 }
 
-func (e *eventEmitter) updateSummaryEvent(maybeCorrupt bool,
-	duration time.Duration, resourceChanges ResourceChanges) {
+func (e *eventEmitter) updateSummaryEvent(maybeCorrupt bool, duration time.Duration, resourceChanges ResourceChanges,
+	outputChanges OutputChanges, deprecations []deploy.DeprecatedUsage) {
 	contract.Requiref(e != nil, "e", "!= nil")
 
 	e.Chan <- Event{
-		Type: SummaryEvent,
+		Type:    SummaryEvent,
+		Version: EventSchemaVersion,
 		Payload: SummaryEventPayload{
 			IsPreview:       false,
 			MaybeCorrupt:    maybeCorrupt,
 			Duration:        duration,
 			ResourceChanges: resourceChanges,
+			OutputChanges:   outputChanges,
+			Deprecations:    deprecations,
 		},
 	}
 }
@@ -473,16 +976,17 @@ func (e *eventEmitter) policyViolationEvent(urn resource.URN, d plugin.AnalyzeDi
 	buffer.WriteRune('\n')
 
 	e.Chan <- Event{
-		Type: PolicyViolationEvent,
+		Type:    PolicyViolationEvent,
+		Version: EventSchemaVersion,
 		Payload: PolicyViolationEventPayload{
 			ResourceURN:       urn,
-			Message:           logging.FilterString(buffer.String()),
+			Message:           e.secrets.filter(buffer.String()),
 			Color:             colors.Raw,
 			PolicyName:        d.PolicyName,
 			PolicyPackName:    d.PolicyPackName,
 			PolicyPackVersion: d.PolicyPackVersion,
 			EnforcementLevel:  d.EnforcementLevel,
-			Prefix:            logging.FilterString(prefix.String()),
+			Prefix:            e.secrets.filter(prefix.String()),
 		},
 	}
 }
@@ -491,17 +995,22 @@ func diagEvent(e *eventEmitter, d *diag.Diag, prefix, msg string, sev diag.Sever
 	ephemeral bool) {
 	contract.Requiref(e != nil, "e", "!= nil")
 
+	payload := DiagEventPayload{
+		URN:       d.URN,
+		Prefix:    e.secrets.filter(prefix),
+		Message:   e.secrets.filter(msg),
+		Color:     colors.Raw,
+		Severity:  sev,
+		StreamID:  d.StreamID,
+		Ephemeral: ephemeral,
+	}
+	if e.diags != nil && !ephemeral {
+		e.diags.record(payload)
+	}
 	e.Chan <- Event{
-		Type: DiagEvent,
-		Payload: DiagEventPayload{
-			URN:       d.URN,
-			Prefix:    logging.FilterString(prefix),
-			Message:   logging.FilterString(msg),
-			Color:     colors.Raw,
-			Severity:  sev,
-			StreamID:  d.StreamID,
-			Ephemeral: ephemeral,
-		},
+		Type:    DiagEvent,
+		Version: EventSchemaVersion,
+		Payload: payload,
 	}
 }
 
@@ -0,0 +1,112 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+func TestDiffStackOutputs(t *testing.T) {
+	olds := resource.PropertyMap{
+		"same":    resource.NewStringProperty("a"),
+		"changed": resource.NewNumberProperty(1),
+		"removed": resource.NewStringProperty("gone"),
+		"secret":  resource.MakeSecret(resource.NewStringProperty("old")),
+	}
+	news := resource.PropertyMap{
+		"same":    resource.NewStringProperty("a"),
+		"changed": resource.NewNumberProperty(2),
+		"added":   resource.NewBoolProperty(true),
+		"secret":  resource.MakeSecret(resource.NewStringProperty("new")),
+	}
+
+	changes := diffStackOutputs(olds, news, nil, false)
+	assert.True(t, changes.HasChanges())
+	assert.Equal(t, []OutputChange{
+		{Key: "added", Old: resource.NewNullProperty(), New: resource.NewBoolProperty(true)},
+	}, changes.Added)
+	assert.Equal(t, []OutputChange{
+		{Key: "changed", Old: resource.NewNumberProperty(1), New: resource.NewNumberProperty(2)},
+		{Key: "secret", Old: resource.NewStringProperty("[secret]"), New: resource.NewStringProperty("[secret]")},
+	}, changes.Changed)
+	assert.Equal(t, []OutputChange{
+		{Key: "removed", Old: resource.NewStringProperty("gone"), New: resource.NewNullProperty()},
+	}, changes.Removed)
+
+	assert.False(t, diffStackOutputs(olds, olds, nil, false).HasChanges())
+	assert.Len(t, diffStackOutputs(nil, news, nil, false).Added, 4)
+}
+
+func TestConvertEvent(t *testing.T) {
+	e, err := ConvertEvent(Event{
+		Type:    DiagEvent,
+		Version: EventSchemaVersion,
+		Payload: DiagEventPayload{Message: "hello", Severity: diag.Warning},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, EventSchemaVersion, e.Version)
+	assert.Equal(t, "hello", e.DiagnosticEvent.Message)
+	assert.Equal(t, "warning", e.DiagnosticEvent.Severity)
+
+	bytes, err := json.Marshal(e)
+	assert.NoError(t, err)
+	var roundTripped apitype.EngineEvent
+	assert.NoError(t, json.Unmarshal(bytes, &roundTripped))
+	assert.Equal(t, e, roundTripped)
+
+	// Events that do not specify a version use the current one.
+	e, err = ConvertEvent(Event{Type: CancelEvent})
+	assert.NoError(t, err)
+	assert.Equal(t, EventSchemaVersion, e.Version)
+	assert.NotNil(t, e.CancelEvent)
+
+	// Payloads that do not match the event's type are rejected.
+	_, err = ConvertEvent(Event{Type: DiagEvent, Payload: StdoutEventPayload{Message: "hello"}})
+	assert.Error(t, err)
+
+	_, err = ConvertEvent(Event{Type: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestStepDependencies(t *testing.T) {
+	parent := resource.URN("urn:pulumi:test::test::my:component:Component::parent")
+	prov := resource.URN("urn:pulumi:test::test::pulumi:providers:pkgA::prov")
+	bucket := resource.URN("urn:pulumi:test::test::pkgA:m:Bucket::bucket")
+	role := resource.URN("urn:pulumi:test::test::pkgA:m:Role::role")
+	state := &resource.State{
+		Parent:       parent,
+		Provider:     string(prov) + "::b6e6f7b4",
+		Dependencies: []resource.URN{role, bucket},
+		PropertyDependencies: map[resource.PropertyKey][]resource.URN{
+			"bucketName": {bucket},
+			"bucketArn":  {bucket},
+		},
+	}
+
+	assert.Equal(t, []StepDependency{
+		{URN: parent, Reason: StepDependencyParent},
+		{URN: prov, Reason: StepDependencyProvider},
+		{URN: role, Reason: StepDependencyExplicit},
+		{URN: bucket, Reason: StepDependencyProperty, Properties: []resource.PropertyKey{"bucketArn", "bucketName"}},
+	}, stepDependencies(state))
+	assert.Empty(t, stepDependencies(&resource.State{}))
+}
@@ -17,25 +17,206 @@ package engine
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/diag"
 
 	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
 )
 
-func newEventSink(events eventEmitter, statusSink bool) diag.Sink {
-	return &eventSink{
+// DiagnosticLimits bounds the warnings that an operation reports, so that a provider that issues the same warning
+// thousands of times does not flood event consumers and terminals. Every warning is still written to the log at
+// verbosity 5 or higher. Errors and informational messages, which include the program's own output, are always
+// reported, as are all diagnostics when debugging output is enabled.
+type DiagnosticLimits struct {
+	// Deduplicate reports only the first of each identical warning for the same resource. The number of repeats of
+	// each is reported once the operation's steps have completed.
+	Deduplicate bool
+	// MaxPerSecond is the number of warnings reported per second, past which further warnings are counted rather than
+	// reported. The number that were not reported is reported once the operation's steps have completed. Zero is
+	// unlimited.
+	MaxPerSecond int
+}
+
+// DefaultDiagnosticLimits are the limits used by the CLI.
+var DefaultDiagnosticLimits = DiagnosticLimits{Deduplicate: true, MaxPerSecond: 50}
+
+// DiagnosticFilter sets the least severe diagnostics that an operation reports from a single source, so that, e.g.,
+// the debug output of one noisy provider can be kept out of the event stream, or written to a file of its own, while
+// that of the others is shown. Unlike DiagnosticLimits, filters apply whether or not debugging output is enabled.
+type DiagnosticFilter struct {
+	// Source is the source whose diagnostics are filtered. A filter without a source applies to the diagnostics of
+	// every source that does not have a filter of its own.
+	Source diag.Source
+	// MinSeverity is the least severe diagnostic that is reported, in the order debug, info, warning, error. Messages
+	// that the program writes to its standard error stream are as severe as those it writes to standard output.
+	MinSeverity diag.Severity
+	// Divert, if set, receives the diagnostics from the source that are less severe than MinSeverity, uncolored,
+	// rather than their being dropped. Each is written with a single call to Write, so that a writer such as an
+	// *os.File, which is safe for concurrent use, may be shared.
+	Divert io.Writer
+}
+
+// severityRank orders severities from least to most severe.
+func severityRank(sev diag.Severity) int {
+	switch sev {
+	case diag.Debug:
+		return 0
+	case diag.Info, diag.Infoerr:
+		return 1
+	case diag.Warning:
+		return 2
+	case diag.Error:
+		return 3
+	default:
+		return -1
+	}
+}
+
+func newEventSink(events eventEmitter, statusSink bool, limits DiagnosticLimits,
+	filters []DiagnosticFilter) diag.Sink {
+
+	s := &eventSink{
 		events:     events,
 		statusSink: statusSink,
+		limits:     limits,
+		repeats:    make(map[repeatKey]*repeatedDiag),
 	}
+	for i := range filters {
+		f := &filters[i]
+		if f.Source == "" {
+			s.defaultFilter = f
+		} else {
+			if s.filters == nil {
+				s.filters = make(map[diag.Source]*DiagnosticFilter)
+			}
+			s.filters[f.Source] = f
+		}
+	}
+	return s
 }
 
 // eventSink is a sink which writes all events to a channel
 type eventSink struct {
-	events     eventEmitter // the channel to emit events into.
-	statusSink bool         // whether this is an event sink for status messages.
+	events        eventEmitter                      // the channel to emit events into.
+	statusSink    bool                              // whether this is an event sink for status messages.
+	limits        DiagnosticLimits                  // the limits on the warnings that are emitted.
+	filters       map[diag.Source]*DiagnosticFilter // the filters for particular sources.
+	defaultFilter *DiagnosticFilter                 // the filter for every other source, if any.
+
+	m           sync.Mutex                  // guards the fields below.
+	repeats     map[repeatKey]*repeatedDiag // the warnings emitted so far, with the number of times each repeated.
+	order       []*repeatedDiag             // the warnings in repeats, in the order they were first emitted.
+	window      time.Time                   // the start of the current one-second rate limiting window.
+	windowCount int                         // the number of warnings emitted in the current window.
+	dropped     int                         // the number of warnings not emitted due to the rate limit.
+}
+
+// repeatKey identifies identical diagnostics.
+type repeatKey struct {
+	urn resource.URN
+	msg string
+}
+
+// repeatedDiag records a diagnostic that was emitted and the number of times it was repeated afterwards.
+type repeatedDiag struct {
+	d       *diag.Diag
+	prefix  string
+	msg     string
+	repeats int
+}
+
+// filter returns true if the given diagnostic should not be emitted due to the filter for its source. If the filter
+// diverts such diagnostics, the diagnostic is written to the filter's writer.
+func (s *eventSink) filter(sev diag.Severity, d *diag.Diag, prefix, msg string) bool {
+	f, has := s.filters[d.GetSource()]
+	if !has {
+		f = s.defaultFilter
+	}
+	if f == nil || f.MinSeverity == "" || severityRank(sev) >= severityRank(f.MinSeverity) {
+		return false
+	}
+
+	if f.Divert != nil {
+		line := colors.Never.Colorize(s.events.secrets.filter(prefix + msg))
+		if d.URN != "" {
+			line = fmt.Sprintf("%s: %s", d.URN, line)
+		}
+		if _, err := io.WriteString(f.Divert, line); err != nil {
+			logging.V(5).Infof("eventSink::filter: diverting diagnostic from %s: %v", d.GetSource(), err)
+		}
+	}
+	return true
+}
+
+// suppress returns true if the given warning should not be emitted due to the sink's limits.
+func (s *eventSink) suppress(d *diag.Diag, prefix, msg string) bool {
+	if !s.limits.Deduplicate && s.limits.MaxPerSecond <= 0 {
+		return false
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	key := repeatKey{urn: d.URN, msg: msg}
+	if s.limits.Deduplicate {
+		if r, has := s.repeats[key]; has {
+			r.repeats++
+			return true
+		}
+	}
+
+	if s.limits.MaxPerSecond > 0 {
+		if now := time.Now(); now.Sub(s.window) >= time.Second {
+			s.window, s.windowCount = now, 0
+		}
+		if s.windowCount >= s.limits.MaxPerSecond {
+			s.dropped++
+			return true
+		}
+		s.windowCount++
+	}
+
+	if s.limits.Deduplicate {
+		r := &repeatedDiag{d: d, prefix: prefix, msg: msg}
+		s.repeats[key], s.order = r, append(s.order, r)
+	}
+	return false
+}
+
+// flush reports the number of warnings that were suppressed due to the sink's limits since it was last flushed.
+func (s *eventSink) flush() {
+	s.m.Lock()
+	order, dropped := s.order, s.dropped
+	s.repeats, s.order, s.dropped = make(map[repeatKey]*repeatedDiag), nil, 0
+	s.m.Unlock()
+
+	for _, r := range order {
+		if r.repeats > 0 {
+			msg := strings.TrimSuffix(r.msg, "\n") + fmt.Sprintf(" (repeated %d more times)\n", r.repeats)
+			s.events.diagWarningEvent(r.d, r.prefix, msg, s.statusSink)
+		}
+	}
+	if dropped > 0 {
+		prefix, msg := s.Stringify(diag.Warning, diag.Message("" /*urn*/, "%d warnings were not shown as they were "+
+			"issued too quickly; pass --debug to show every diagnostic"), dropped)
+		s.events.diagWarningEvent(diag.Message("" /*urn*/, ""), prefix, msg, s.statusSink)
+	}
+}
+
+// flushDiagnostics reports the warnings that the given sinks suppressed due to their limits.
+func flushDiagnostics(sinks ...diag.Sink) {
+	for _, sink := range sinks {
+		if s, ok := sink.(*eventSink); ok {
+			s.flush()
+		}
+	}
 }
 
 func (s *eventSink) Logf(sev diag.Severity, d *diag.Diag, args ...interface{}) {
@@ -62,6 +243,9 @@ func (s *eventSink) Debugf(d *diag.Diag, args ...interface{}) {
 	if logging.V(9) {
 		logging.V(9).Infof("eventSink::Debug(%v)", msg[:len(msg)-1])
 	}
+	if s.filter(diag.Debug, d, prefix, msg) {
+		return
+	}
 	s.events.diagDebugEvent(d, prefix, msg, s.statusSink)
 }
 
@@ -70,6 +254,9 @@ func (s *eventSink) Infof(d *diag.Diag, args ...interface{}) {
 	if logging.V(5) {
 		logging.V(5).Infof("eventSink::Info(%v)", msg[:len(msg)-1])
 	}
+	if s.filter(diag.Info, d, prefix, msg) {
+		return
+	}
 	s.events.diagInfoEvent(d, prefix, msg, s.statusSink)
 }
 
@@ -78,6 +265,9 @@ func (s *eventSink) Infoerrf(d *diag.Diag, args ...interface{}) {
 	if logging.V(5) {
 		logging.V(5).Infof("eventSink::Infoerr(%v)", msg[:len(msg)-1])
 	}
+	if s.filter(diag.Infoerr, d, prefix, msg) {
+		return
+	}
 	s.events.diagInfoerrEvent(d, prefix, msg, s.statusSink)
 }
 
@@ -86,6 +276,9 @@ func (s *eventSink) Errorf(d *diag.Diag, args ...interface{}) {
 	if logging.V(5) {
 		logging.V(5).Infof("eventSink::Error(%v)", msg[:len(msg)-1])
 	}
+	if s.filter(diag.Error, d, prefix, msg) {
+		return
+	}
 	s.events.diagErrorEvent(d, prefix, msg, s.statusSink)
 }
 
@@ -94,6 +287,9 @@ func (s *eventSink) Warningf(d *diag.Diag, args ...interface{}) {
 	if logging.V(5) {
 		logging.V(5).Infof("eventSink::Warning(%v)", msg[:len(msg)-1])
 	}
+	if s.filter(diag.Warning, d, prefix, msg) || s.suppress(d, prefix, msg) {
+		return
+	}
 	s.events.diagWarningEvent(d, prefix, msg, s.statusSink)
 }
 
@@ -0,0 +1,129 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// collectDiags runs the given function against an event sink with the given limits, flushes the sink, and returns the
+// messages of the diagnostics it emitted.
+func collectDiags(limits DiagnosticLimits, f func(sink diag.Sink)) []string {
+	return collectFilteredDiags(limits, nil, f)
+}
+
+// collectFilteredDiags is like collectDiags, but with the given filters as well.
+func collectFilteredDiags(limits DiagnosticLimits, filters []DiagnosticFilter, f func(sink diag.Sink)) []string {
+	events := make(chan Event, 1000)
+	sink := newEventSink(eventEmitter{Chan: events, secrets: newSecretFilter()}, false, limits, filters)
+	f(sink)
+	flushDiagnostics(sink)
+	close(events)
+
+	var msgs []string
+	for e := range events {
+		msgs = append(msgs, strings.TrimSpace(e.Payload.(DiagEventPayload).Message))
+	}
+	return msgs
+}
+
+func TestEventSinkDeduplicatesWarnings(t *testing.T) {
+	urn := resource.URN("urn:pulumi:stack::proj::pkg:index:Res::res")
+	msgs := collectDiags(DiagnosticLimits{Deduplicate: true}, func(sink diag.Sink) {
+		for i := 0; i < 5; i++ {
+			sink.Warningf(diag.Message(urn, "deprecated"))
+			sink.Warningf(diag.Message("", "deprecated"))
+		}
+		sink.Warningf(diag.Message(urn, "only once"))
+		sink.Errorf(diag.Message(urn, "failed"))
+		sink.Errorf(diag.Message(urn, "failed"))
+	})
+	assert.Len(t, msgs, 7)
+	assert.Contains(t, msgs[0], "deprecated")
+	assert.Contains(t, msgs[2], "only once")
+	assert.Contains(t, msgs[3], "failed")
+	assert.Contains(t, msgs[4], "failed")
+	assert.Contains(t, msgs[5], "deprecated")
+	assert.Contains(t, msgs[5], "(repeated 4 more times)")
+	assert.Contains(t, msgs[6], "(repeated 4 more times)")
+}
+
+func TestEventSinkRateLimitsWarnings(t *testing.T) {
+	msgs := collectDiags(DiagnosticLimits{MaxPerSecond: 3}, func(sink diag.Sink) {
+		for i := 0; i < 10; i++ {
+			sink.Warningf(diag.Message("", "warning %d"), i)
+		}
+		sink.Errorf(diag.Message("", "failed"))
+	})
+	assert.Len(t, msgs, 5)
+	assert.Contains(t, msgs[2], "warning 2")
+	assert.Contains(t, msgs[3], "failed")
+	assert.Contains(t, msgs[4], "7 warnings were not shown")
+}
+
+func TestEventSinkWithoutLimits(t *testing.T) {
+	msgs := collectDiags(DiagnosticLimits{}, func(sink diag.Sink) {
+		for i := 0; i < 100; i++ {
+			sink.Warningf(diag.Message("", "deprecated"))
+		}
+	})
+	assert.Len(t, msgs, 100)
+}
+
+func TestEventSinkFilters(t *testing.T) {
+	urn := resource.URN("urn:pulumi:stack::proj::aws:s3/bucket:Bucket::res")
+	fromSource := func(source diag.Source, msg string) *diag.Diag {
+		d := diag.Message(urn, msg)
+		d.Source = source
+		return d
+	}
+
+	var aws bytes.Buffer
+	filters := []DiagnosticFilter{
+		{Source: diag.ProviderSource("aws"), MinSeverity: diag.Warning, Divert: &aws},
+		{Source: diag.EngineSource, MinSeverity: diag.Debug},
+		{MinSeverity: diag.Info},
+	}
+	msgs := collectFilteredDiags(DiagnosticLimits{}, filters, func(sink diag.Sink) {
+		sink.Debugf(fromSource(diag.ProviderSource("aws"), "aws debug"))
+		sink.Infof(fromSource(diag.ProviderSource("aws"), "aws info"))
+		sink.Warningf(fromSource(diag.ProviderSource("aws"), "aws warning"))
+		sink.Debugf(diag.Message("", "engine debug"))
+		sink.Debugf(fromSource(diag.LanguageSource, "language debug"))
+		sink.Infoerrf(fromSource(diag.LanguageSource, "language stderr"))
+		sink.Errorf(fromSource(diag.ProviderSource("gcp"), "gcp error"))
+	})
+	assert.Equal(t, []string{"aws warning", "engine debug", "language stderr", "gcp error"}, uncolored(msgs))
+
+	// The diagnostics filtered from the AWS provider are written to its file, without colors.
+	assert.Equal(t, string(urn)+": debug: aws debug\n"+string(urn)+": aws info\n", aws.String())
+}
+
+// uncolored removes the color codes from the given messages.
+func uncolored(msgs []string) []string {
+	trimmed := make([]string, len(msgs))
+	for i, msg := range msgs {
+		trimmed[i] = colors.Never.Colorize(msg)
+	}
+	return trimmed
+}
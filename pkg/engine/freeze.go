@@ -0,0 +1,63 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+)
+
+// StackFreeze records that changes to a stack are suspended, e.g. while an incident is under way. While a freeze is in
+// effect, the engine refuses to update, destroy, or import into the stack; previews and refreshes are still allowed.
+type StackFreeze struct {
+	// Reason explains why the stack is frozen. It is shown to anyone whose update is refused.
+	Reason string `json:"reason"`
+	// User is the user that froze the stack.
+	User string `json:"user,omitempty"`
+	// Frozen is the time at which the stack was frozen.
+	Frozen time.Time `json:"frozen"`
+	// Expires is the time at which the freeze ends by itself. If it is zero, the freeze lasts until it is lifted.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// Active returns true if the freeze is in effect at the given time.
+func (f *StackFreeze) Active(now time.Time) bool {
+	return f != nil && (f.Expires.IsZero() || now.Before(f.Expires))
+}
+
+func (f *StackFreeze) String() string {
+	user := f.User
+	if user == "" {
+		user = "an unknown user"
+	}
+	until := "until it is lifted"
+	if !f.Expires.IsZero() {
+		until = "until " + f.Expires.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("the stack was frozen by %s at %s %s: %s", user, f.Frozen.UTC().Format(time.RFC3339), until,
+		f.Reason)
+}
+
+// checkFreeze reports an error diagnostic and returns false if the given freeze is in effect.
+func checkFreeze(freeze *StackFreeze, d diag.Sink) bool {
+	if !freeze.Active(time.Now()) {
+		return true
+	}
+	d.Errorf(diag.RawMessage("" /*urn*/, fmt.Sprintf("refusing to change the stack: %v; "+
+		"run `pulumi stack thaw` once changes may resume\n", freeze)))
+	return false
+}
@@ -0,0 +1,122 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+func TestStackFreezeActive(t *testing.T) {
+	now := time.Now()
+
+	var none *StackFreeze
+	assert.False(t, none.Active(now))
+	assert.True(t, (&StackFreeze{Reason: "incident"}).Active(now))
+	assert.True(t, (&StackFreeze{Reason: "incident", Expires: now.Add(time.Hour)}).Active(now))
+	assert.False(t, (&StackFreeze{Reason: "incident", Expires: now.Add(-time.Hour)}).Active(now))
+}
+
+func TestStackFreeze(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", nil,
+			nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+
+	update := func(freeze *StackFreeze, refresh, dryRun bool) (bool, []string) {
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		events := make(chan Event)
+		var diags []string
+		done := make(chan bool)
+		go func() {
+			for e := range events {
+				if e.Type == DiagEvent {
+					diags = append(diags, e.Payload.(DiagEventPayload).Message)
+				}
+			}
+			close(done)
+		}()
+
+		journal := newJournal()
+		ctx := &Context{
+			Cancel:          cancelCtx,
+			Events:          events,
+			SnapshotManager: journal,
+			Freeze:          freeze,
+		}
+		info := &updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}
+		var bailed bool
+		if refresh {
+			_, res := Refresh(info, ctx, p.Options, dryRun)
+			bailed = res != nil && res.IsBail()
+		} else {
+			_, res := Update(info, ctx, p.Options, dryRun)
+			bailed = res != nil && res.IsBail()
+		}
+		contract.IgnoreClose(journal)
+		close(events)
+		<-done
+		return bailed, diags
+	}
+
+	refused := func(diags []string) bool {
+		for _, d := range diags {
+			if strings.Contains(d, "refusing to change the stack") && strings.Contains(d, "incident") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// An active freeze refuses updates.
+	freeze := &StackFreeze{Reason: "incident", User: "alice", Frozen: time.Now()}
+	bailed, diags := update(freeze, false, false)
+	assert.True(t, bailed)
+	assert.True(t, refused(diags))
+
+	// Previews and refreshes are still allowed.
+	bailed, diags = update(freeze, false, true)
+	assert.False(t, bailed)
+	assert.False(t, refused(diags))
+	bailed, diags = update(freeze, true, false)
+	assert.False(t, bailed)
+	assert.False(t, refused(diags))
+
+	// An expired freeze has no effect.
+	freeze.Expires = time.Now().Add(-time.Minute)
+	bailed, diags = update(freeze, false, false)
+	assert.False(t, bailed)
+	assert.False(t, refused(diags))
+}
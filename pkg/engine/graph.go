@@ -0,0 +1,227 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io"
+
+	"github.com/pulumi/pulumi/pkg/graph"
+	"github.com/pulumi/pulumi/pkg/graph/dotconv"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// GraphEdgeKind is the kind of relationship represented by an edge in a ResourceGraph.
+type GraphEdgeKind string
+
+const (
+	// DependencyEdge is an edge from a resource to a resource on which it depends.
+	DependencyEdge GraphEdgeKind = "dependency"
+	// ParentEdge is an edge from a resource to its parent.
+	ParentEdge GraphEdgeKind = "parent"
+	// ProviderEdge is an edge from a resource to the provider that manages it.
+	ProviderEdge GraphEdgeKind = "provider"
+)
+
+// ResourceGraph is the graph of relationships between the resources in a snapshot or plan. Each edge points from a
+// resource to a resource it refers to, so a resource can only be created after every resource it has an edge to.
+type ResourceGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a single resource in a ResourceGraph.
+type GraphNode struct {
+	URN  resource.URN `json:"urn"`
+	Type tokens.Type  `json:"type"`
+	// Op is the operation the plan will perform on the resource. It is empty for graphs of snapshots and for resources
+	// that are referred to by a plan but that the plan does not report, such as default providers.
+	Op deploy.StepOp `json:"op,omitempty"`
+}
+
+// GraphEdge is a single relationship in a ResourceGraph.
+type GraphEdge struct {
+	From resource.URN  `json:"from"`
+	To   resource.URN  `json:"to"`
+	Kind GraphEdgeKind `json:"kind"`
+}
+
+// NewSnapshotGraph returns the graph of the resources in the given snapshot.
+func NewSnapshotGraph(snap *deploy.Snapshot) *ResourceGraph {
+	b := newGraphBuilder()
+	if snap != nil {
+		for _, res := range snap.Resources {
+			b.addNode(res.URN, res.Type)
+		}
+		for _, res := range snap.Resources {
+			b.addEdges(res)
+		}
+	}
+	return b.graph
+}
+
+// NewPlanGraph returns the graph of the resources affected by the given plan, annotated with the operation the plan
+// will perform on each. If the plan performs several steps on a resource (e.g. to replace it), the node records the
+// operation of its logical step.
+func NewPlanGraph(plan *Plan) *ResourceGraph {
+	b := newGraphBuilder()
+	for _, step := range plan.Steps {
+		node := b.addNode(step.URN, step.Type)
+		if step.Logical {
+			node.Op = step.Op
+		}
+	}
+	for _, step := range plan.Steps {
+		if step.Res != nil && step.Res.State != nil {
+			b.addEdges(step.Res.State)
+		}
+	}
+	return b.graph
+}
+
+// Without returns a copy of the graph that omits edges of the given kinds.
+func (g *ResourceGraph) Without(kinds ...GraphEdgeKind) *ResourceGraph {
+	omit := make(map[GraphEdgeKind]bool)
+	for _, k := range kinds {
+		omit[k] = true
+	}
+
+	result := &ResourceGraph{Nodes: g.Nodes}
+	for _, e := range g.Edges {
+		if !omit[e.Kind] {
+			result.Edges = append(result.Edges, e)
+		}
+	}
+	return result
+}
+
+// WriteDOT writes the graph to the given writer in the DOT format. Edges of each kind are drawn in the color given for
+// that kind, if any. To match the graphs drawn by earlier versions of `pulumi stack graph`, dependency edges are drawn
+// from a resource to the resources that depend on it, while parent and provider edges are drawn from a resource to its
+// parent or provider.
+func (g *ResourceGraph) WriteDOT(w io.Writer, colors map[GraphEdgeKind]string) error {
+	dg := &dotGraph{}
+	vertices := make(map[resource.URN]*dotVertex)
+	for _, n := range g.Nodes {
+		v := &dotVertex{node: n}
+		vertices[n.URN] = v
+		dg.roots = append(dg.roots, &dotEdge{to: v})
+	}
+	for _, e := range g.Edges {
+		from, to := vertices[e.From], vertices[e.To]
+		contract.Assertf(from != nil && to != nil, "edge from %v to %v refers to an unknown node", e.From, e.To)
+		if e.Kind == DependencyEdge {
+			from, to = to, from
+		}
+
+		edge := &dotEdge{edge: e, from: from, to: to, color: colors[e.Kind]}
+		from.outs = append(from.outs, edge)
+		to.ins = append(to.ins, edge)
+	}
+	return dotconv.Print(dg, w)
+}
+
+// graphBuilder accumulates the nodes and edges of a ResourceGraph, adding a node for any resource that is referred to
+// before it is seen.
+type graphBuilder struct {
+	graph *ResourceGraph
+	nodes map[resource.URN]int // the index of each node in the graph.
+	edges map[GraphEdge]bool   // the set of edges already in the graph.
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{
+		graph: &ResourceGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}},
+		nodes: make(map[resource.URN]int),
+		edges: make(map[GraphEdge]bool),
+	}
+}
+
+// addNode returns the node for the given resource, adding it to the graph if necessary.
+func (b *graphBuilder) addNode(urn resource.URN, t tokens.Type) *GraphNode {
+	index, has := b.nodes[urn]
+	if !has {
+		index = len(b.graph.Nodes)
+		b.nodes[urn] = index
+		b.graph.Nodes = append(b.graph.Nodes, GraphNode{URN: urn, Type: t})
+	}
+	return &b.graph.Nodes[index]
+}
+
+func (b *graphBuilder) addEdge(from, to resource.URN, kind GraphEdgeKind) {
+	b.addNode(to, to.Type())
+
+	edge := GraphEdge{From: from, To: to, Kind: kind}
+	if !b.edges[edge] {
+		b.edges[edge] = true
+		b.graph.Edges = append(b.graph.Edges, edge)
+	}
+}
+
+// addEdges adds the edges for each of the relationships recorded in the given resource state.
+func (b *graphBuilder) addEdges(state *resource.State) {
+	for _, dep := range state.Dependencies {
+		b.addEdge(state.URN, dep, DependencyEdge)
+	}
+	if state.Parent != "" {
+		b.addEdge(state.URN, state.Parent, ParentEdge)
+	}
+	if state.Provider != "" {
+		if ref, err := providers.ParseReference(state.Provider); err == nil {
+			b.addEdge(state.URN, ref.URN(), ProviderEdge)
+		}
+	}
+}
+
+// The types below adapt a ResourceGraph to the interfaces in the graph package, so that it can be printed by the
+// dotconv package.
+
+type dotGraph struct {
+	roots []graph.Edge
+}
+
+func (g *dotGraph) Roots() []graph.Edge { return g.roots }
+
+type dotVertex struct {
+	node GraphNode
+	ins  []graph.Edge
+	outs []graph.Edge
+}
+
+func (v *dotVertex) Data() interface{}  { return v.node }
+func (v *dotVertex) Label() string      { return string(v.node.URN) }
+func (v *dotVertex) Ins() []graph.Edge  { return v.ins }
+func (v *dotVertex) Outs() []graph.Edge { return v.outs }
+
+type dotEdge struct {
+	edge  GraphEdge
+	from  *dotVertex
+	to    *dotVertex
+	color string
+}
+
+func (e *dotEdge) Data() interface{} { return e.edge }
+func (e *dotEdge) Label() string     { return string(e.edge.Kind) }
+func (e *dotEdge) To() graph.Vertex  { return e.to }
+func (e *dotEdge) From() graph.Vertex {
+	if e.from == nil {
+		return nil
+	}
+	return e.from
+}
+func (e *dotEdge) Color() string { return e.color }
@@ -0,0 +1,109 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func newGraphTestState(name string, t tokens.Type, parent resource.URN, provider string,
+	deps ...resource.URN) *resource.State {
+
+	urn := resource.NewURN("test", "test", "", t, tokens.QName(name))
+	return &resource.State{
+		URN:          urn,
+		Type:         t,
+		Custom:       true,
+		ID:           resource.ID(name),
+		Parent:       parent,
+		Provider:     provider,
+		Dependencies: deps,
+	}
+}
+
+func TestSnapshotGraph(t *testing.T) {
+	prov := newGraphTestState("prov", providers.MakeProviderType("pkgA"), "", "")
+	ref, err := providers.NewReference(prov.URN, prov.ID)
+	assert.NoError(t, err)
+
+	comp := newGraphTestState("comp", "test:m:comp", "", "")
+	comp.Custom, comp.ID = false, ""
+	resA := newGraphTestState("resA", "pkgA:m:typA", comp.URN, ref.String())
+	resB := newGraphTestState("resB", "pkgA:m:typA", comp.URN, ref.String(), resA.URN)
+
+	g := NewSnapshotGraph(&deploy.Snapshot{Resources: []*resource.State{prov, comp, resA, resB}})
+	assert.Len(t, g.Nodes, 4)
+	assert.Equal(t, []GraphEdge{
+		{From: resA.URN, To: comp.URN, Kind: ParentEdge},
+		{From: resA.URN, To: prov.URN, Kind: ProviderEdge},
+		{From: resB.URN, To: resA.URN, Kind: DependencyEdge},
+		{From: resB.URN, To: comp.URN, Kind: ParentEdge},
+		{From: resB.URN, To: prov.URN, Kind: ProviderEdge},
+	}, g.Edges)
+
+	assert.Equal(t, []GraphEdge{
+		{From: resB.URN, To: resA.URN, Kind: DependencyEdge},
+	}, g.Without(ParentEdge, ProviderEdge).Edges)
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteDOT(&buf, map[GraphEdgeKind]string{DependencyEdge: "#246C60"}))
+	assert.Contains(t, buf.String(), string(resB.URN))
+	assert.Contains(t, buf.String(), `[color="#246C60"]`)
+
+	// As they always have been, dependency edges are drawn from a dependency to its dependents, and parent edges from a
+	// child to its parent.
+	assert.Contains(t, buf.String(), `Resource2 [label="`+string(resA.URN)+`"]`)
+	assert.Contains(t, buf.String(), `Resource2 -> Resource3 [color="#246C60"]`)
+	assert.Contains(t, buf.String(), "Resource2 -> Resource1;")
+}
+
+func TestPlanGraph(t *testing.T) {
+	ref := "urn:pulumi:test::test::pulumi:providers:pkgA::default::id"
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", ref)
+	resB := newGraphTestState("resB", "pkgA:m:typA", "", ref, resA.URN)
+
+	step := func(op deploy.StepOp, state *resource.State, logical bool) PlanStep {
+		return PlanStep{StepEventMetadata: StepEventMetadata{
+			Op:      op,
+			URN:     state.URN,
+			Type:    state.Type,
+			Res:     &StepEventStateMetadata{State: state},
+			Logical: logical,
+		}}
+	}
+
+	g := NewPlanGraph(&Plan{Steps: []PlanStep{
+		step(deploy.OpSame, resA, true),
+		step(deploy.OpCreateReplacement, resB, false),
+		step(deploy.OpReplace, resB, true),
+	}})
+
+	// The default provider is not part of the plan, but is added as a node so that the provider edges have a target.
+	if assert.Len(t, g.Nodes, 3) {
+		assert.Equal(t, deploy.OpSame, g.Nodes[0].Op)
+		assert.Equal(t, deploy.OpReplace, g.Nodes[1].Op)
+		assert.Equal(t, resource.URN("urn:pulumi:test::test::pulumi:providers:pkgA::default"), g.Nodes[2].URN)
+		assert.Equal(t, deploy.StepOp(""), g.Nodes[2].Op)
+	}
+	assert.Len(t, g.Edges, 3)
+}
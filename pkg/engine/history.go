@@ -0,0 +1,79 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// UpdateSummary summarizes a single update that was made to a stack.
+type UpdateSummary struct {
+	Kind            apitype.UpdateKind   // the kind of update (update, refresh, destroy, etc.).
+	Result          apitype.UpdateResult // the result of the update.
+	Message         string               // the message associated with the update, if any.
+	Environment     map[string]string    // metadata about the environment, such as the git commit being deployed.
+	StartTime       time.Time            // the time at which the update started.
+	EndTime         time.Time            // the time at which the update completed.
+	ResourceChanges ResourceChanges      // the number of resources affected by each kind of operation.
+}
+
+// Duration returns the amount of time the update took to complete.
+func (s UpdateSummary) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// Succeeded returns true if the update completed successfully, even if its checks then failed.
+func (s UpdateSummary) Succeeded() bool {
+	return s.Result == apitype.SucceededResult || s.Result == apitype.SucceededWithFailingChecksResult
+}
+
+// GetHistory returns summaries of the updates that have been made to the named stack, most recent first, as recorded
+// by the given backend. If limit is positive, at most that many summaries are returned.
+func GetHistory(ctx context.Context, client deploy.BackendClient, stack string, limit int) ([]UpdateSummary, error) {
+	contract.Require(client != nil, "client")
+
+	updates, err := client.GetStackHistory(ctx, stack)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting history for stack %s", stack)
+	}
+	if limit > 0 && len(updates) > limit {
+		updates = updates[:limit]
+	}
+
+	history := make([]UpdateSummary, len(updates))
+	for i, update := range updates {
+		changes := make(ResourceChanges)
+		for op, count := range update.ResourceChanges {
+			changes[deploy.StepOp(op)] = count
+		}
+		history[i] = UpdateSummary{
+			Kind:            update.Kind,
+			Result:          update.Result,
+			Message:         update.Message,
+			Environment:     update.Environment,
+			StartTime:       time.Unix(update.StartTime, 0),
+			EndTime:         time.Unix(update.EndTime, 0),
+			ResourceChanges: changes,
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+)
+
+func TestGetHistory(t *testing.T) {
+	client := &deploytest.BackendClient{
+		GetStackHistoryF: func(ctx context.Context, name string) ([]apitype.UpdateInfo, error) {
+			assert.Equal(t, "dev", name)
+			return []apitype.UpdateInfo{
+				{
+					Kind:            apitype.UpdateUpdate,
+					Result:          apitype.FailedResult,
+					Environment:     map[string]string{"git.head": "abc123"},
+					StartTime:       100,
+					EndTime:         130,
+					ResourceChanges: map[apitype.OpType]int{apitype.OpCreate: 2},
+				},
+				{Kind: apitype.RefreshUpdate, Result: apitype.SucceededResult, StartTime: 10, EndTime: 20},
+			}, nil
+		},
+	}
+
+	history, err := GetHistory(context.Background(), client, "dev", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.False(t, history[0].Succeeded())
+		assert.Equal(t, 30*time.Second, history[0].Duration())
+		assert.Equal(t, "abc123", history[0].Environment["git.head"])
+		assert.Equal(t, ResourceChanges{deploy.OpCreate: 2}, history[0].ResourceChanges)
+		assert.True(t, history[1].Succeeded())
+	}
+
+	history, err = GetHistory(context.Background(), client, "dev", 1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+}
@@ -0,0 +1,171 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// Import reads each of the given resources from its provider and adds it to the stack as an external resource,
+// without creating or modifying the resource itself. Resources that are already present in the stack are left
+// untouched. Each import that does not name a provider uses the stack's default provider for the resource's package.
+// An import that names a parent path is placed beneath the components on that path, which are created as needed.
+func Import(u UpdateInfo, ctx *Context, opts UpdateOptions, imports []deploy.Import,
+	dryRun bool) (*UpdateResult, result.Result) {
+
+	contract.Require(u != nil, "u")
+	contract.Require(ctx != nil, "ctx")
+
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+
+	info, err := newPlanContext(u, "import", ctx.ParentSpan)
+	if err != nil {
+		return nil, result.FromError(err)
+	}
+	defer info.Close()
+
+	emitter, err := makeEventEmitter(ctx.Events, u)
+	if err != nil {
+		return nil, result.FromError(err)
+	}
+
+	return update(ctx, info, planOptions{
+		UpdateOptions: opts,
+		SourceFunc:    newImportSourceFunc(imports),
+		Events:        emitter,
+		Diag:          newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters),
+		StatusDiag:    newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters),
+		isImport:      true,
+	}, dryRun)
+}
+
+func newImportSourceFunc(imports []deploy.Import) planSourceFunc {
+	return func(client deploy.BackendClient, opts planOptions, proj *workspace.Project, pwd, main string,
+		target *deploy.Target, plugctx *plugin.Context, dryRun bool) (deploy.Source, error) {
+
+		// Like Refresh, we don't run the user's program, so the plugins we need are those described in the snapshot.
+		plugins, err := gatherPluginsFromSnapshot(plugctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if err := ensurePluginsAreInstalled(plugins, plugctx.Diag); err != nil {
+			logging.V(7).Infof("newImportSource(): failed to install missing plugins: %v", err)
+		}
+
+		resolved, err := resolveImportProviders(target, imports)
+		if err != nil {
+			return nil, err
+		}
+		components, resolved := resolveImportParents(proj.Name, target, resolved)
+		return deploy.NewImportSource(proj.Name, components, resolved), nil
+	}
+}
+
+// resolveImportParents places each import that names a parent path beneath the components on that path. The path
+// starts at the import's parent or, if it has none, at the stack's root resource. Components that are not already in
+// the target's snapshot are returned as goals to be registered before the imports, each at most once.
+func resolveImportParents(project tokens.PackageName, target *deploy.Target,
+	imports []deploy.Import) ([]*resource.Goal, []deploy.Import) {
+
+	existing := make(map[resource.URN]bool)
+	var root resource.URN
+	if target.Snapshot != nil {
+		for _, res := range target.Snapshot.Resources {
+			if res.Delete {
+				continue
+			}
+			existing[res.URN] = true
+			if res.Type == resource.RootStackType && res.Parent == "" {
+				root = res.URN
+			}
+		}
+	}
+
+	var components []*resource.Goal
+	resolved := make([]deploy.Import, len(imports))
+	for i, imp := range imports {
+		if len(imp.ParentPath) > 0 {
+			parent := imp.Parent
+			if parent == "" {
+				parent = root
+			}
+			for _, c := range imp.ParentPath {
+				// This mirrors the way the planner derives URNs, which skips the root stack type.
+				parentType := tokens.Type("")
+				if parent != "" && parent.Type() != resource.RootStackType {
+					parentType = parent.QualifiedType()
+				}
+				urn := resource.NewURN(target.Name, project, parentType, c.Type, c.Name)
+				if !existing[urn] {
+					existing[urn] = true
+					components = append(components, resource.NewGoal(c.Type, c.Name, false, resource.PropertyMap{},
+						parent, false, nil, "", nil, nil, false, nil, nil, nil))
+				}
+				parent = urn
+			}
+			imp.Parent, imp.ParentPath = parent, nil
+		}
+		resolved[i] = imp
+	}
+	return components, resolved
+}
+
+// resolveImportProviders fills in the provider reference of each import that does not specify one with a reference to
+// the default provider for the resource's package in the target's snapshot.
+func resolveImportProviders(target *deploy.Target, imports []deploy.Import) ([]deploy.Import, error) {
+	defaults := make(map[string]string)
+	if target.Snapshot != nil {
+		for _, res := range target.Snapshot.Resources {
+			if res.Delete || !providers.IsDefaultProvider(res.URN) {
+				continue
+			}
+			ref, err := providers.NewReference(res.URN, res.ID)
+			if err != nil {
+				return nil, err
+			}
+			defaults[string(providers.GetProviderPackage(res.Type))] = ref.String()
+		}
+	}
+
+	resolved := make([]deploy.Import, len(imports))
+	for i, imp := range imports {
+		if imp.Provider == "" {
+			pkg := string(imp.Type.Package())
+			ref, ok := defaults[pkg]
+			if !ok {
+				return nil, errors.Errorf("cannot import '%s': the stack has no default provider for package '%s'; "+
+					"specify a provider for the resource", imp.Name, pkg)
+			}
+			imp.Provider = ref
+		}
+		resolved[i] = imp
+	}
+	return resolved, nil
+}
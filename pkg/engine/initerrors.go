@@ -0,0 +1,218 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// InitErrorAction describes what was done about a resource's initialization errors.
+type InitErrorAction string
+
+const (
+	// InitErrorsResolved indicates that initialization was re-attempted and succeeded, so the resource's errors were
+	// cleared.
+	InitErrorsResolved InitErrorAction = "resolved"
+	// InitErrorsRemain indicates that initialization was re-attempted and failed again. The resource's errors were
+	// replaced with those of the new attempt.
+	InitErrorsRemain InitErrorAction = "remain"
+	// InitErrorsCleared indicates that the resource's errors were cleared without re-attempting initialization.
+	InitErrorsCleared InitErrorAction = "cleared"
+	// InitErrorsUnresolved indicates that initialization could not be re-attempted. The resource's errors are unchanged.
+	InitErrorsUnresolved InitErrorAction = "unresolved"
+)
+
+// InitErrorEntry reports what was done about the initialization errors of a single resource.
+type InitErrorEntry struct {
+	URN     resource.URN    // the resource whose errors were handled.
+	Action  InitErrorAction // what was done about them.
+	Errors  []string        // the resource's initialization errors afterwards.
+	Message string          // a human-readable explanation of the action.
+}
+
+// ListInitErrors returns the resources in the given snapshot that have initialization errors, i.e. that were created
+// or updated but did not finish initializing, in the order in which they appear in the snapshot.
+func ListInitErrors(snap *deploy.Snapshot) []*resource.State {
+	if snap == nil {
+		return nil
+	}
+	var failed []*resource.State
+	for _, res := range snap.Resources {
+		if len(res.InitErrors) > 0 && !res.Delete {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// RetryInitialization re-attempts the initialization of the resources with the given URNs, or of every resource with
+// initialization errors if none are given, by asking each resource's provider to read it again: providers report
+// resources that are still not initialized by failing the read with a plugin.InitError. Each resource's state is
+// refreshed from the read, and its errors are cleared or replaced accordingly. The snapshot is modified in place.
+//
+// An InitErrorsEvent is sent on the given channel, if it is not nil, for each resource. The returned entries describe
+// what was done for each resource.
+func RetryInitialization(host plugin.Host, snap *deploy.Snapshot, urns []resource.URN,
+	events chan<- Event) ([]InitErrorEntry, error) {
+
+	contract.Require(host != nil, "host")
+	contract.Require(snap != nil, "snap")
+
+	indices, err := initErrorIndices(snap, urns)
+	if err != nil || len(indices) == 0 {
+		return nil, err
+	}
+
+	reg, err := providers.NewRegistry(host, snap.Resources, false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading providers")
+	}
+
+	report := make([]InitErrorEntry, len(indices))
+	for i, index := range indices {
+		report[i] = retryInitialization(reg, snap, index)
+		sendInitErrorsEvent(events, report[i])
+	}
+	return report, nil
+}
+
+// ClearInitErrors clears the initialization errors of the resources with the given URNs, or of every resource with
+// initialization errors if none are given, once they have been resolved by other means. The snapshot is modified in
+// place. An InitErrorsEvent is sent on the given channel, if it is not nil, for each resource.
+func ClearInitErrors(snap *deploy.Snapshot, urns []resource.URN, events chan<- Event) ([]InitErrorEntry, error) {
+	contract.Require(snap != nil, "snap")
+
+	indices, err := initErrorIndices(snap, urns)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]InitErrorEntry, len(indices))
+	for i, index := range indices {
+		old := snap.Resources[index]
+		state := *old
+		state.InitErrors = nil
+		snap.Resources[index] = &state
+
+		report[i] = InitErrorEntry{
+			URN:     old.URN,
+			Action:  InitErrorsCleared,
+			Message: "the resource's initialization errors were cleared",
+		}
+		sendInitErrorsEvent(events, report[i])
+	}
+	return report, nil
+}
+
+// initErrorIndices returns the indices in the given snapshot of the resources with the given URNs, or of every
+// resource with initialization errors if none are given. It is an error for a given URN not to name a resource with
+// initialization errors.
+func initErrorIndices(snap *deploy.Snapshot, urns []resource.URN) ([]int, error) {
+	requested := make(map[resource.URN]bool, len(urns))
+	for _, urn := range urns {
+		requested[urn] = true
+	}
+
+	var indices []int
+	for i, res := range snap.Resources {
+		if len(res.InitErrors) == 0 || res.Delete {
+			continue
+		}
+		if len(urns) == 0 || requested[res.URN] {
+			indices = append(indices, i)
+			delete(requested, res.URN)
+		}
+	}
+	for _, urn := range urns {
+		if requested[urn] {
+			return nil, errors.Errorf("resource %s has no initialization errors", urn)
+		}
+	}
+	return indices, nil
+}
+
+// retryInitialization re-attempts the initialization of the resource at the given index in the snapshot.
+func retryInitialization(reg *providers.Registry, snap *deploy.Snapshot, index int) InitErrorEntry {
+	old := snap.Resources[index]
+	entry := InitErrorEntry{URN: old.URN, Errors: old.InitErrors}
+	unresolved := func(msg string) InitErrorEntry {
+		entry.Action, entry.Message = InitErrorsUnresolved, msg
+		return entry
+	}
+
+	if !old.Custom {
+		return unresolved("component resources have no provider to re-attempt initialization")
+	}
+	ref, err := providers.ParseReference(old.Provider)
+	if err != nil {
+		return unresolved(err.Error())
+	}
+	prov, ok := reg.GetProvider(ref)
+	if !ok {
+		return unresolved("the resource's provider could not be loaded")
+	}
+
+	var initErrors []string
+	refreshed, _, err := prov.Read(old.URN, old.ID, old.Inputs, old.Outputs)
+	if err != nil {
+		initErr, isInitErr := err.(*plugin.InitError)
+		if !isInitErr {
+			return unresolved(errors.Wrap(err, "reading the resource").Error())
+		}
+		initErrors = initErr.Reasons
+	}
+	if refreshed.Outputs == nil {
+		return unresolved("the resource no longer exists; refresh the stack to remove it")
+	}
+
+	state := *old
+	state.Outputs = refreshed.Outputs
+	if refreshed.Inputs != nil {
+		state.Inputs = refreshed.Inputs
+	}
+	state.InitErrors = initErrors
+	snap.Resources[index] = &state
+
+	entry.Errors = initErrors
+	if len(initErrors) > 0 {
+		entry.Action, entry.Message = InitErrorsRemain, "the resource is still not initialized"
+	} else {
+		entry.Action, entry.Message = InitErrorsResolved, "the resource is now initialized; its errors were cleared"
+	}
+	return entry
+}
+
+// sendInitErrorsEvent sends an event that reports the given entry on the given channel, if it is not nil.
+func sendInitErrorsEvent(events chan<- Event, entry InitErrorEntry) {
+	if events == nil {
+		return
+	}
+	events <- Event{
+		Type:    InitErrorsEvent,
+		Version: EventSchemaVersion,
+		Payload: InitErrorsEventPayload{
+			URN:     entry.URN,
+			Action:  entry.Action,
+			Errors:  entry.Errors,
+			Message: entry.Message,
+		},
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestInitErrors(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ReadF: func(urn resource.URN, id resource.ID,
+					inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+					result := plugin.ReadResult{
+						Outputs: resource.PropertyMap{"ready": resource.NewBoolProperty(id == "resA")},
+					}
+					if id == "resA" {
+						return result, resource.StatusOK, nil
+					}
+					return result, resource.StatusPartialFailure, &plugin.InitError{Reasons: []string{"still waiting"}}
+				},
+			}, nil
+		}),
+	}
+	host := deploytest.NewPluginHost(nil, nil, nil, loaders...)
+
+	prov := newGraphTestState("prov", providers.MakeProviderType("pkgA"), "", "")
+	ref, err := providers.NewReference(prov.URN, prov.ID)
+	assert.NoError(t, err)
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", ref.String())
+	resA.InitErrors = []string{"timed out"}
+	resB := newGraphTestState("resB", "pkgA:m:typA", "", ref.String())
+	resB.InitErrors = []string{"timed out"}
+	resC := newGraphTestState("resC", "pkgA:m:typA", "", ref.String())
+	snap := &deploy.Snapshot{Resources: []*resource.State{prov, resA, resB, resC}}
+
+	assert.Equal(t, []*resource.State{resA, resB}, ListInitErrors(snap))
+
+	// Only resources with initialization errors may be named.
+	_, err = RetryInitialization(host, snap, []resource.URN{resC.URN}, nil)
+	assert.Error(t, err)
+
+	// Re-attempting initialization clears the errors of resources that are now initialized, and replaces those of
+	// resources that are not.
+	events := make(chan Event, 2)
+	report, err := RetryInitialization(host, snap, nil, events)
+	assert.NoError(t, err)
+	if assert.Len(t, report, 2) {
+		assert.Equal(t, InitErrorsResolved, report[0].Action)
+		assert.Empty(t, report[0].Errors)
+		assert.Equal(t, InitErrorsRemain, report[1].Action)
+		assert.Equal(t, []string{"still waiting"}, report[1].Errors)
+	}
+	assert.Len(t, events, 2)
+	assert.Empty(t, snap.Resources[1].InitErrors)
+	assert.Equal(t, resource.NewBoolProperty(true), snap.Resources[1].Outputs["ready"])
+	assert.Equal(t, []string{"still waiting"}, snap.Resources[2].InitErrors)
+	assert.Equal(t, []*resource.State{snap.Resources[2]}, ListInitErrors(snap))
+
+	// Clearing the remaining errors leaves no resources with initialization errors.
+	report, err = ClearInitErrors(snap, []resource.URN{resB.URN}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, report, 1) {
+		assert.Equal(t, InitErrorsCleared, report[0].Action)
+	}
+	assert.Empty(t, ListInitErrors(snap))
+
+	// The snapshot's original states are not modified.
+	assert.Equal(t, []string{"timed out"}, resA.InitErrors)
+	assert.Equal(t, []string{"timed out"}, resB.InitErrors)
+}
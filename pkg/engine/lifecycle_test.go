@@ -16,12 +16,18 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/secrets"
 
@@ -46,6 +52,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/util/result"
 	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
 	"github.com/pulumi/pulumi/pkg/workspace"
+	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
 )
 
 type JournalEntryKind int
@@ -63,10 +70,13 @@ type JournalEntry struct {
 }
 
 type Journal struct {
-	Entries []JournalEntry
-	events  chan JournalEntry
-	cancel  chan bool
-	done    chan bool
+	Entries       []JournalEntry
+	Config        config.Map
+	OmittedConfig []config.Key
+	Plugins       []workspace.PluginInfo
+	events        chan JournalEntry
+	cancel        chan bool
+	done          chan bool
 }
 
 func (j *Journal) Close() error {
@@ -107,7 +117,13 @@ func (j *Journal) RegisterResourceOutputs(step deploy.Step) error {
 	}
 }
 
-func (j *Journal) RecordPlugin(plugin workspace.PluginInfo) error {
+func (j *Journal) RecordConfig(cfg config.Map, omitted []config.Key) error {
+	j.Config, j.OmittedConfig = cfg, omitted
+	return nil
+}
+
+func (j *Journal) RecordPlugins(plugins []workspace.PluginInfo) error {
+	j.Plugins = plugins
 	return nil
 }
 
@@ -127,7 +143,7 @@ func (j *Journal) Snap(base *deploy.Snapshot) *deploy.Snapshot {
 				ops = append(ops, resource.NewOperation(e.Step.New(), resource.OperationTypeCreating))
 			case deploy.OpDelete, deploy.OpDeleteReplaced, deploy.OpReadDiscard, deploy.OpDiscardReplaced:
 				ops = append(ops, resource.NewOperation(e.Step.Old(), resource.OperationTypeDeleting))
-			case deploy.OpRead, deploy.OpReadReplacement:
+			case deploy.OpRead, deploy.OpReadReplacement, deploy.OpImport:
 				ops = append(ops, resource.NewOperation(e.Step.New(), resource.OperationTypeReading))
 			case deploy.OpUpdate:
 				ops = append(ops, resource.NewOperation(e.Step.New(), resource.OperationTypeUpdating))
@@ -135,7 +151,8 @@ func (j *Journal) Snap(base *deploy.Snapshot) *deploy.Snapshot {
 		case JournalEntryFailure, JournalEntrySuccess:
 			switch e.Step.Op() {
 			// nolint: lll
-			case deploy.OpCreate, deploy.OpCreateReplacement, deploy.OpRead, deploy.OpReadReplacement, deploy.OpUpdate:
+			case deploy.OpCreate, deploy.OpCreateReplacement, deploy.OpRead, deploy.OpReadReplacement, deploy.OpUpdate,
+				deploy.OpImport:
 				doneOps[e.Step.New()] = true
 			case deploy.OpDelete, deploy.OpDeleteReplaced, deploy.OpReadDiscard, deploy.OpDiscardReplaced:
 				doneOps[e.Step.Old()] = true
@@ -157,15 +174,19 @@ func (j *Journal) Snap(base *deploy.Snapshot) *deploy.Snapshot {
 				if old := e.Step.Old(); !old.PendingReplacement {
 					dones[old] = true
 				}
-			case deploy.OpReplace:
+			case deploy.OpReplace, deploy.OpValidateReplacement:
 				// do nothing.
-			case deploy.OpRead, deploy.OpReadReplacement:
+			case deploy.OpRead, deploy.OpReadReplacement, deploy.OpImport:
 				resources = append(resources, e.Step.New())
 				if e.Step.Old() != nil {
 					dones[e.Step.Old()] = true
 				}
 			case deploy.OpRemovePendingReplace:
 				dones[e.Step.Old()] = true
+			case deploy.OpPrune:
+				if e.Step.New() == nil {
+					dones[e.Step.Old()] = true
+				}
 			}
 		}
 	}
@@ -194,7 +215,12 @@ func (j *Journal) Snap(base *deploy.Snapshot) *deploy.Snapshot {
 		secretsManager = base.SecretsManager
 	}
 
-	manifest := deploy.Manifest{}
+	plugins := j.Plugins
+	if plugins == nil && base != nil {
+		plugins = base.Manifest.Plugins
+	}
+
+	manifest := deploy.Manifest{Config: j.Config, OmittedConfig: j.OmittedConfig, Plugins: plugins}
 	manifest.Magic = manifest.NewMagic()
 	return deploy.NewSnapshot(manifest, secretsManager, resources, operations)
 }
@@ -248,12 +274,13 @@ func newJournal() *Journal {
 }
 
 type updateInfo struct {
+	root    string
 	project workspace.Project
 	target  deploy.Target
 }
 
 func (u *updateInfo) GetRoot() string {
-	return ""
+	return u.root
 }
 
 func (u *updateInfo) GetProject() *workspace.Project {
@@ -264,7 +291,7 @@ func (u *updateInfo) GetTarget() *deploy.Target {
 	return &u.target
 }
 
-type TestOp func(UpdateInfo, *Context, UpdateOptions, bool) (ResourceChanges, result.Result)
+type TestOp func(UpdateInfo, *Context, UpdateOptions, bool) (*UpdateResult, result.Result)
 type ValidateFunc func(project workspace.Project, target deploy.Target, j *Journal,
 	events []Event, res result.Result) result.Result
 
@@ -2401,6 +2428,87 @@ func TestStackReference(t *testing.T) {
 	p.Run(t, nil)
 }
 
+func TestStackReferenceOutputsChanged(t *testing.T) {
+	foo, seen := "bar", ""
+	program := deploytest.NewLanguageRuntime(func(info plugin.RunInfo, mon *deploytest.ResourceMonitor) error {
+		_, _, state, err := mon.RegisterResource("pulumi:pulumi:StackReference", "other", true, "", false, nil, "",
+			resource.NewPropertyMapFromMap(map[string]interface{}{
+				"name": "other",
+			}), nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		if !info.DryRun {
+			seen = state["outputs"].ObjectValue()["foo"].StringValue()
+		}
+		return nil
+	})
+	p := &TestPlan{
+		BackendClient: &deploytest.BackendClient{
+			GetStackOutputsF: func(ctx context.Context, name string) (resource.PropertyMap, error) {
+				return resource.NewPropertyMapFromMap(map[string]interface{}{"foo": foo}), nil
+			},
+		},
+		Options: UpdateOptions{host: deploytest.NewPluginHost(nil, nil, program)},
+	}
+	resURN := p.NewURN("pulumi:pulumi:StackReference", "other", "")
+
+	// validate checks whether the stack reference was updated, and warned about, by an update.
+	validate := func(updated bool) ValidateFunc {
+		return func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			assert.Nil(t, res)
+			var warnings []string
+			var ops []deploy.StepOp
+			for _, e := range evts {
+				switch e.Type {
+				case DiagEvent:
+					if payload := e.Payload.(DiagEventPayload); payload.Severity == diag.Warning {
+						warnings = append(warnings, payload.Message)
+					}
+				case ResourcePreEvent:
+					if m := e.Payload.(ResourcePreEventPayload).Metadata; m.URN == resURN && m.Op != deploy.OpSame {
+						ops = append(ops, m.Op)
+					}
+				}
+			}
+			if updated {
+				assert.Equal(t, []deploy.StepOp{deploy.OpUpdate}, ops)
+				if assert.Len(t, warnings, 1) {
+					assert.Contains(t, warnings[0], "the outputs of stack 'other' have changed")
+					assert.Contains(t, warnings[0], "foo")
+					assert.NotContains(t, warnings[0], foo)
+				}
+			} else {
+				assert.Empty(t, ops)
+				assert.Empty(t, warnings)
+			}
+			return res
+		}
+	}
+
+	p.Steps = []TestStep{{Op: Update}}
+	snap := p.Run(t, nil)
+	assert.Equal(t, "bar", seen)
+
+	// The dependency on the other stack is recorded in the snapshot.
+	assert.Equal(t, []deploy.StackReference{{URN: resURN, Stack: "other"}}, snap.StackReferences())
+
+	// If the other stack's outputs are unchanged, so is the reference.
+	p.Steps = []TestStep{{Op: Update, Validate: validate(false)}}
+	snap = p.Run(t, snap)
+
+	// If they have changed, the reference is updated and the change is reported.
+	foo = "baz"
+	p.Steps = []TestStep{{Op: Update, Validate: validate(true)}}
+	snap = p.Run(t, snap)
+	assert.Equal(t, "baz", seen)
+	for _, res := range snap.Resources {
+		if res.URN == resURN {
+			assert.Equal(t, "baz", res.Outputs["outputs"].ObjectValue()["foo"].StringValue())
+		}
+	}
+}
+
 type channelWriter struct {
 	channel chan []byte
 }
@@ -2902,6 +3010,83 @@ func TestExplicitDeleteBeforeReplace(t *testing.T) {
 	p.Run(t, snap)
 }
 
+func TestDeleteBeforeReplaceByType(t *testing.T) {
+	p := &TestPlan{}
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					if !olds["A"].DeepEquals(news["A"]) {
+						return plugin.DiffResult{ReplaceKeys: []resource.PropertyKey{"A"}}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	const resType = "pkgA:index:typ"
+
+	inputsA := resource.NewPropertyMapFromMap(map[string]interface{}{"A": "foo"})
+	inputsB := resource.NewPropertyMapFromMap(map[string]interface{}{"A": "foo"})
+
+	var urnA, urnB resource.URN
+	var err error
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnA, _, _, err = monitor.RegisterResource(resType, "resA", true, "", false, nil, "", inputsA,
+			nil, false, "", nil, nil)
+		assert.NoError(t, err)
+
+		inputDepsB := map[resource.PropertyKey][]resource.URN{"A": {urnA}}
+		urnB, _, _, err = monitor.RegisterResource(resType, "resB", true, "", false, []resource.URN{urnA}, "",
+			inputsB, inputDepsB, false, "", nil, nil)
+		assert.NoError(t, err)
+
+		return nil
+	})
+
+	p.Options.host = deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p.Steps = []TestStep{{Op: Update}}
+	snap := p.Run(t, nil)
+
+	// Require delete-before-replace for every resource of resA's type and change the value of resA.A. Although
+	// neither the program nor the provider requests it, both resA and its dependent resB should be replaced, and the
+	// replacements should be delete-before-replace.
+	p.Options.DeleteBeforeReplace = map[tokens.Type]bool{resType: true}
+	inputsA["A"] = resource.NewStringProperty("bar")
+	p.Steps = []TestStep{{
+		Op: Update,
+
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			assert.Nil(t, res)
+
+			// Ignore the steps for the default provider.
+			var steps []deploy.Step
+			for _, s := range j.SuccessfulSteps() {
+				if !providers.IsProviderType(s.Type()) {
+					steps = append(steps, s)
+				}
+			}
+			AssertSameSteps(t, []StepSummary{
+				{Op: deploy.OpDeleteReplaced, URN: urnB},
+				{Op: deploy.OpDeleteReplaced, URN: urnA},
+				{Op: deploy.OpReplace, URN: urnA},
+				{Op: deploy.OpCreateReplacement, URN: urnA},
+				{Op: deploy.OpReplace, URN: urnB},
+				{Op: deploy.OpCreateReplacement, URN: urnB},
+			}, steps)
+
+			return res
+		},
+	}}
+	p.Run(t, snap)
+}
+
 func TestSingleResourceIgnoreChanges(t *testing.T) {
 	loaders := []*deploytest.ProviderLoader{
 		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
@@ -2970,6 +3155,161 @@ func TestSingleResourceIgnoreChanges(t *testing.T) {
 	}, []string{"a"}, []deploy.StepOp{deploy.OpUpdate})
 }
 
+func TestNestedIgnoreChanges(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				// Report a replacement due to a server-side default whenever anything else changes.
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					return plugin.DiffResult{
+						Changes:     plugin.DiffSome,
+						ChangedKeys: []resource.PropertyKey{"tags", "defaulted"},
+						ReplaceKeys: []resource.PropertyKey{"defaulted"},
+					}, nil
+				},
+			}, nil
+		}),
+	}
+
+	tags := func(env, team string) resource.PropertyMap {
+		return resource.PropertyMap{
+			"tags": resource.NewObjectProperty(resource.PropertyMap{
+				"env":  resource.NewStringProperty(env),
+				"team": resource.NewStringProperty(team),
+			}),
+		}
+	}
+
+	updateProgramWithProps := func(snap *deploy.Snapshot, props resource.PropertyMap,
+		ignoreChanges []string, expectedOp deploy.StepOp) *deploy.Snapshot {
+
+		program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+				props, nil, false, "", ignoreChanges, nil)
+			assert.NoError(t, err)
+			return nil
+		})
+		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+		p := &TestPlan{
+			Options: UpdateOptions{host: host},
+			Steps: []TestStep{{
+				Op: Update,
+				Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+					events []Event, res result.Result) result.Result {
+					var ops []deploy.StepOp
+					for _, event := range events {
+						if event.Type == ResourcePreEvent {
+							ops = append(ops, event.Payload.(ResourcePreEventPayload).Metadata.Op)
+						}
+					}
+					assert.Contains(t, ops, expectedOp)
+					if expectedOp != deploy.OpReplace {
+						assert.NotContains(t, ops, deploy.OpReplace)
+					}
+					return res
+				},
+			}},
+		}
+		return p.Run(t, snap)
+	}
+
+	snap := updateProgramWithProps(nil, tags("dev", "a"), nil, deploy.OpCreate)
+
+	// A change to an ignored nested property results in an OpSame, and the old value is preserved in the state.
+	snap = updateProgramWithProps(snap, tags("prod", "a"), []string{"tags.env"}, deploy.OpSame)
+	assert.Len(t, snap.Resources, 2)
+	assert.Equal(t, tags("dev", "a"), snap.Resources[1].Inputs)
+
+	// A change to an un-ignored sibling results in an update that still preserves the ignored value. The replacement
+	// reported for the ignored "defaulted" key is suppressed.
+	snap = updateProgramWithProps(snap, tags("prod", "b"), []string{"tags.env", "defaulted"}, deploy.OpUpdate)
+	assert.Equal(t, tags("dev", "b"), snap.Resources[1].Inputs)
+
+	// Without ignoring "defaulted", the provider's replacement goes through.
+	_ = updateProgramWithProps(snap, tags("dev", "c"), nil, deploy.OpReplace)
+}
+
+func TestReplaceOnChanges(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				// Never ask for a replacement; any change is an in-place update.
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					if olds.DeepEquals(news) {
+						return plugin.DiffResult{Changes: plugin.DiffNone}, nil
+					}
+					return plugin.DiffResult{Changes: plugin.DiffSome}, nil
+				},
+			}, nil
+		}),
+	}
+
+	props := func(env, team string) resource.PropertyMap {
+		return resource.PropertyMap{
+			"tags": resource.NewObjectProperty(resource.PropertyMap{
+				"env":  resource.NewStringProperty(env),
+				"team": resource.NewStringProperty(team),
+			}),
+		}
+	}
+
+	updateProgramWithProps := func(snap *deploy.Snapshot, props resource.PropertyMap,
+		replaceOnChanges []string, expectedOp deploy.StepOp) *deploy.Snapshot {
+
+		program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+			object, err := plugin.MarshalProperties(props, plugin.MarshalOptions{KeepUnknowns: true})
+			assert.NoError(t, err)
+
+			_, _, _, err = monitor.RegisterResourceRaw(&pulumirpc.RegisterResourceRequest{
+				Type:             "pkgA:m:typA",
+				Name:             "resA",
+				Custom:           true,
+				Object:           object,
+				ReplaceOnChanges: replaceOnChanges,
+			})
+			assert.NoError(t, err)
+			return nil
+		})
+		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+		p := &TestPlan{
+			Options: UpdateOptions{host: host},
+			Steps: []TestStep{{
+				Op: Update,
+				Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+					events []Event, res result.Result) result.Result {
+					var ops []deploy.StepOp
+					for _, event := range events {
+						if event.Type == ResourcePreEvent {
+							ops = append(ops, event.Payload.(ResourcePreEventPayload).Metadata.Op)
+						}
+					}
+					assert.Contains(t, ops, expectedOp)
+					if expectedOp != deploy.OpReplace {
+						assert.NotContains(t, ops, deploy.OpReplace)
+					}
+					return res
+				},
+			}},
+		}
+		return p.Run(t, snap)
+	}
+
+	snap := updateProgramWithProps(nil, props("dev", "a"), []string{"tags.env"}, deploy.OpCreate)
+
+	// A change to a property that is not covered by replaceOnChanges is an in-place update.
+	snap = updateProgramWithProps(snap, props("dev", "b"), []string{"tags.env"}, deploy.OpUpdate)
+
+	// A change to a covered property forces a replacement even though the provider reported an update.
+	snap = updateProgramWithProps(snap, props("prod", "b"), []string{"tags.env"}, deploy.OpReplace)
+
+	// No changes at all remain a no-op.
+	_ = updateProgramWithProps(snap, props("prod", "b"), []string{"tags.env"}, deploy.OpSame)
+}
+
 // TestDefaultProviderDiff tests that the engine can gracefully recover whenever a resource's default provider changes
 // and there is no diff in the provider's inputs.
 func TestDefaultProviderDiff(t *testing.T) {
@@ -3176,69 +3516,251 @@ func TestDefaultProviderDiffReplacement(t *testing.T) {
 	}
 }
 
-// Resource is an abstract representation of a resource graph
-type Resource struct {
-	t                   tokens.Type
-	name                string
-	children            []Resource
-	props               resource.PropertyMap
-	aliases             []resource.URN
-	dependencies        []resource.URN
-	parent              resource.URN
-	deleteBeforeReplace bool
-}
-
-func registerResources(t *testing.T, monitor *deploytest.ResourceMonitor, resources []Resource) error {
-	for _, r := range resources {
-		_, _, _, err := monitor.RegisterResource(r.t, r.name, true, r.parent, false, r.dependencies, "",
-			r.props, nil, r.deleteBeforeReplace, "", nil, r.aliases)
-		if err != nil {
-			return err
-		}
-		err = registerResources(t, monitor, r.children)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func TestAliases(t *testing.T) {
+// TestDefaultProviderStateMigration tests that, when a resource moves to a newer version of its package's default
+// provider, the engine lets the new provider migrate the resource's old state before diffing it.
+func TestDefaultProviderStateMigration(t *testing.T) {
+	var migratedFrom *semver.Version
 	loaders := []*deploytest.ProviderLoader{
 		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
 			return &deploytest.Provider{
-				// The `forcesReplacement` key forces replacement and all other keys can update in place
-				DiffF: func(res resource.URN, id resource.ID, olds, news resource.PropertyMap) (plugin.DiffResult, error) {
-					replaceKeys := []resource.PropertyKey{}
-					old, hasOld := olds["forcesReplacement"]
-					new, hasNew := news["forcesReplacement"]
-					if hasOld && !hasNew || hasNew && !hasOld || hasOld && hasNew && old.Diff(new) != nil {
-						replaceKeys = append(replaceKeys, "forcesReplacement")
+				CreateF: func(urn resource.URN,
+					inputs resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					return "id", inputs, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("2.0.0"), func() (plugin.Provider, error) {
+			rename := func(props resource.PropertyMap) resource.PropertyMap {
+				result := resource.PropertyMap{}
+				for k, v := range props {
+					if k == "foo" {
+						k = "bar"
 					}
-					return plugin.DiffResult{ReplaceKeys: replaceKeys}, nil
+					result[k] = v
+				}
+				return result
+			}
+			return &deploytest.Provider{
+				MigrateStateF: func(urn resource.URN, id resource.ID, version semver.Version,
+					inputs, outputs resource.PropertyMap) (resource.PropertyMap, resource.PropertyMap, error) {
+					migratedFrom = &version
+					return rename(inputs), rename(outputs), nil
 				},
 			}, nil
 		}),
 	}
 
-	updateProgramWithResource := func(
-		snap *deploy.Snapshot, resources []Resource, allowedOps []deploy.StepOp) *deploy.Snapshot {
+	runProgram := func(base *deploy.Snapshot, version, key string, expectedStep deploy.StepOp) *deploy.Snapshot {
 		program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
-			err := registerResources(t, monitor, resources)
-			return err
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+				resource.PropertyMap{resource.PropertyKey(key): resource.NewStringProperty("x")}, nil, false,
+				version, nil, nil)
+			assert.NoError(t, err)
+			return nil
 		})
 		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
 		p := &TestPlan{
 			Options: UpdateOptions{host: host},
-			Steps: []TestStep{
-				{
-					Op: Update,
-					Validate: func(project workspace.Project, target deploy.Target, j *Journal,
-						events []Event, res result.Result) result.Result {
-						for _, event := range events {
-							if event.Type == ResourcePreEvent {
-								payload := event.Payload.(ResourcePreEventPayload)
-								assert.Subset(t, allowedOps, []deploy.StepOp{payload.Metadata.Op})
+			Steps: []TestStep{{
+				Op: Update,
+				Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+					events []Event, res result.Result) result.Result {
+					for _, entry := range j.Entries {
+						if entry.Kind == JournalEntrySuccess && entry.Step.URN().Name().String() == "resA" {
+							assert.Equal(t, expectedStep, entry.Step.Op())
+						}
+					}
+					return res
+				},
+			}},
+		}
+		return p.Run(t, base)
+	}
+
+	snap := runProgram(nil, "1.0.0", "foo", deploy.OpCreate)
+	assert.Nil(t, migratedFrom)
+
+	// Upgrading the default provider migrates "foo" to "bar", so the program's new inputs match the migrated state
+	// and the resource is left alone. The migrated outputs must nonetheless be saved.
+	snap = runProgram(snap, "2.0.0", "bar", deploy.OpSame)
+	if assert.NotNil(t, migratedFrom) {
+		assert.Equal(t, semver.MustParse("1.0.0"), *migratedFrom)
+	}
+	for _, res := range snap.Resources {
+		if res.URN.Name().String() == "resA" {
+			assert.Equal(t, resource.PropertyMap{"bar": resource.NewStringProperty("x")}, res.Inputs)
+			assert.Equal(t, resource.PropertyMap{"bar": resource.NewStringProperty("x")}, res.Outputs)
+		}
+	}
+}
+
+// TestProviderPluginUpgradeStateMigration tests that, when the plugin that serves a provider that does not ask for a
+// particular version is upgraded, the engine lets the new plugin migrate the old state of the provider's resources.
+func TestProviderPluginUpgradeStateMigration(t *testing.T) {
+	var migratedFrom []semver.Version
+	v1 := deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+		return &deploytest.Provider{
+			Version: semver.MustParse("1.0.0"),
+			CreateF: func(urn resource.URN,
+				inputs resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+				return "id", inputs, resource.StatusOK, nil
+			},
+		}, nil
+	})
+	v2 := deploytest.NewProviderLoader("pkgA", semver.MustParse("2.0.0"), func() (plugin.Provider, error) {
+		rename := func(props resource.PropertyMap) resource.PropertyMap {
+			result := resource.PropertyMap{}
+			for k, v := range props {
+				if k == "foo" {
+					k = "bar"
+				}
+				result[k] = v
+			}
+			return result
+		}
+		return &deploytest.Provider{
+			Version: semver.MustParse("2.0.0"),
+			MigrateStateF: func(urn resource.URN, id resource.ID, version semver.Version,
+				inputs, outputs resource.PropertyMap) (resource.PropertyMap, resource.PropertyMap, error) {
+				migratedFrom = append(migratedFrom, version)
+				return rename(inputs), rename(outputs), nil
+			},
+		}, nil
+	})
+
+	runProgram := func(base *deploy.Snapshot, key string, expectedStep deploy.StepOp,
+		loaders ...*deploytest.ProviderLoader) *deploy.Snapshot {
+
+		program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+			inputs := resource.PropertyMap{resource.PropertyKey(key): resource.NewStringProperty("x")}
+
+			// resA uses the default provider, and resB an explicit one. Neither asks for a particular version.
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", inputs, nil,
+				false, "", nil, nil)
+			assert.NoError(t, err)
+
+			provURN, provID, _, err := monitor.RegisterResource(providers.MakeProviderType("pkgA"), "provA", true, "",
+				false, nil, "", resource.PropertyMap{}, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+			if provID == "" {
+				provID = providers.UnknownID
+			}
+			provRef, err := providers.NewReference(provURN, provID)
+			assert.NoError(t, err)
+
+			_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, nil, provRef.String(),
+				inputs, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+			return nil
+		})
+		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+		p := &TestPlan{
+			Options: UpdateOptions{host: host},
+			Steps: []TestStep{{
+				Op: Update,
+				Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+					events []Event, res result.Result) result.Result {
+					for _, entry := range j.Entries {
+						if entry.Kind == JournalEntrySuccess && entry.Step.URN().Type() == "pkgA:m:typA" {
+							assert.Equal(t, expectedStep, entry.Step.Op())
+						}
+					}
+					return res
+				},
+			}},
+		}
+		return p.Run(t, base)
+	}
+
+	// The snapshot records the version of the plugin that served the providers.
+	snap := runProgram(nil, "foo", deploy.OpCreate, v1)
+	assert.Empty(t, migratedFrom)
+	if assert.Len(t, snap.Manifest.Plugins, 1) {
+		assert.Equal(t, "pkgA", snap.Manifest.Plugins[0].Name)
+		assert.Equal(t, semver.MustParse("1.0.0"), *snap.Manifest.Plugins[0].Version)
+	}
+
+	// Once a newer plugin is installed, it serves both providers, and migrates "foo" to "bar" for both resources.
+	snap = runProgram(snap, "bar", deploy.OpSame, v1, v2)
+	assert.Equal(t, []semver.Version{semver.MustParse("1.0.0"), semver.MustParse("1.0.0")}, migratedFrom)
+	for _, res := range snap.Resources {
+		if res.Type == "pkgA:m:typA" {
+			assert.Equal(t, resource.PropertyMap{"bar": resource.NewStringProperty("x")}, res.Inputs)
+		}
+	}
+	if assert.Len(t, snap.Manifest.Plugins, 1) {
+		assert.Equal(t, semver.MustParse("2.0.0"), *snap.Manifest.Plugins[0].Version)
+	}
+
+	// The state is only migrated once.
+	migratedFrom = nil
+	runProgram(snap, "bar", deploy.OpSame, v1, v2)
+	assert.Empty(t, migratedFrom)
+}
+
+// Resource is an abstract representation of a resource graph
+type Resource struct {
+	t                   tokens.Type
+	name                string
+	children            []Resource
+	props               resource.PropertyMap
+	aliases             []resource.URN
+	dependencies        []resource.URN
+	parent              resource.URN
+	deleteBeforeReplace bool
+}
+
+func registerResources(t *testing.T, monitor *deploytest.ResourceMonitor, resources []Resource) error {
+	for _, r := range resources {
+		_, _, _, err := monitor.RegisterResource(r.t, r.name, true, r.parent, false, r.dependencies, "",
+			r.props, nil, r.deleteBeforeReplace, "", nil, r.aliases)
+		if err != nil {
+			return err
+		}
+		err = registerResources(t, monitor, r.children)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAliases(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				// The `forcesReplacement` key forces replacement and all other keys can update in place
+				DiffF: func(res resource.URN, id resource.ID, olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+					replaceKeys := []resource.PropertyKey{}
+					old, hasOld := olds["forcesReplacement"]
+					new, hasNew := news["forcesReplacement"]
+					if hasOld && !hasNew || hasNew && !hasOld || hasOld && hasNew && old.Diff(new) != nil {
+						replaceKeys = append(replaceKeys, "forcesReplacement")
+					}
+					return plugin.DiffResult{ReplaceKeys: replaceKeys}, nil
+				},
+			}, nil
+		}),
+	}
+
+	updateProgramWithResource := func(
+		snap *deploy.Snapshot, resources []Resource, allowedOps []deploy.StepOp) *deploy.Snapshot {
+		program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+			err := registerResources(t, monitor, resources)
+			return err
+		})
+		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+		p := &TestPlan{
+			Options: UpdateOptions{host: host},
+			Steps: []TestStep{
+				{
+					Op: Update,
+					Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+						events []Event, res result.Result) result.Result {
+						for _, event := range events {
+							if event.Type == ResourcePreEvent {
+								payload := event.Payload.(ResourcePreEventPayload)
+								assert.Subset(t, allowedOps, []deploy.StepOp{payload.Metadata.Op})
 							}
 						}
 
@@ -3465,4 +3987,2828 @@ func TestAliases(t *testing.T) {
 		},
 	}}, []deploy.StepOp{deploy.OpSame, deploy.OpReplace, deploy.OpCreateReplacement, deploy.OpDeleteReplaced})
 
+	// Start again - this time with a parent, a child, and a grandchild
+	snap = updateProgramWithResource(nil, []Resource{{
+		t:    "pkgA:index:t1",
+		name: "n1",
+	}, {
+		t:      "pkgA:index:t2",
+		name:   "n2",
+		parent: resource.URN("urn:pulumi:test::test::pkgA:index:t1::n1"),
+	}, {
+		t:      "pkgA:index:t3",
+		name:   "n3",
+		parent: resource.URN("urn:pulumi:test::test::pkgA:index:t1$pkgA:index:t2::n2"),
+	}}, []deploy.StepOp{deploy.OpCreate})
+
+	// Ensure that descendants inherit their parent's alias, so that changing the parent's type produces Same
+	snap = updateProgramWithResource(snap, []Resource{{
+		t:    "pkgA:index:t1-new",
+		name: "n1",
+		aliases: []resource.URN{
+			"urn:pulumi:test::test::pkgA:index:t1::n1",
+		},
+	}, {
+		t:      "pkgA:index:t2",
+		name:   "n2",
+		parent: resource.URN("urn:pulumi:test::test::pkgA:index:t1-new::n1"),
+	}, {
+		t:      "pkgA:index:t3",
+		name:   "n3",
+		parent: resource.URN("urn:pulumi:test::test::pkgA:index:t1-new$pkgA:index:t2::n2"),
+	}}, []deploy.StepOp{deploy.OpSame})
+
+	// Ensure that the inherited aliases are no longer needed once the old names are gone from the snapshot
+	_ = updateProgramWithResource(snap, []Resource{{
+		t:    "pkgA:index:t1-new",
+		name: "n1",
+	}, {
+		t:      "pkgA:index:t2",
+		name:   "n2",
+		parent: resource.URN("urn:pulumi:test::test::pkgA:index:t1-new::n1"),
+	}, {
+		t:      "pkgA:index:t3",
+		name:   "n3",
+		parent: resource.URN("urn:pulumi:test::test::pkgA:index:t1-new$pkgA:index:t2::n2"),
+	}}, []deploy.StepOp{deploy.OpSame})
+}
+
+// Test that a plan fails if an old resource is claimed both by its URN and by another resource's alias.
+func TestAliasConflict(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	updateProgramWithResources := func(snap *deploy.Snapshot, resources []Resource, expectFailure bool) *deploy.Snapshot {
+		program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+			return registerResources(t, monitor, resources)
+		})
+		host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+		p := &TestPlan{
+			Options: UpdateOptions{host: host},
+			Steps: []TestStep{{
+				Op:            Update,
+				ExpectFailure: expectFailure,
+				SkipPreview:   expectFailure,
+				Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+					evts []Event, res result.Result) result.Result {
+
+					sawConflict := false
+					for _, evt := range evts {
+						if evt.Type == DiagEvent {
+							e := evt.Payload.(DiagEventPayload)
+							msg := colors.Never.Colorize(e.Message)
+							sawConflict = sawConflict || strings.Contains(msg, "is claimed both by resource")
+						}
+					}
+					assert.Equal(t, expectFailure, sawConflict)
+					return res
+				},
+			}},
+		}
+		return p.Run(t, snap)
+	}
+
+	snap := updateProgramWithResources(nil, []Resource{{
+		t:    "pkgA:index:t1",
+		name: "n1",
+	}}, false)
+
+	// The alias is used after the URN is claimed.
+	_ = updateProgramWithResources(snap, []Resource{{
+		t:    "pkgA:index:t1",
+		name: "n1",
+	}, {
+		t:    "pkgA:index:t1",
+		name: "n2",
+		aliases: []resource.URN{
+			"urn:pulumi:test::test::pkgA:index:t1::n1",
+		},
+	}}, true)
+
+	// The URN is claimed after the alias is used.
+	_ = updateProgramWithResources(snap, []Resource{{
+		t:    "pkgA:index:t1",
+		name: "n2",
+		aliases: []resource.URN{
+			"urn:pulumi:test::test::pkgA:index:t1::n1",
+		},
+	}, {
+		t:    "pkgA:index:t1",
+		name: "n1",
+	}}, true)
+}
+
+// Test that progress reported by a provider during a step is relayed as step progress events.
+func TestProviderProgressEvents(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			prov := &deploytest.Provider{}
+			prov.CreateF = func(urn resource.URN,
+				news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+				prov.ReportProgress(urn, 30, "creating nodes 3/10")
+				return "created-id", news, resource.StatusOK, nil
+			}
+			return prov, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps: []TestStep{{
+			Op:          Update,
+			SkipPreview: true,
+			Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+				evts []Event, res result.Result) result.Result {
+
+				var progress []StepProgressEventPayload
+				for _, evt := range evts {
+					if evt.Type == StepProgressEvent {
+						progress = append(progress, evt.Payload.(StepProgressEventPayload))
+					}
+				}
+
+				if assert.Len(t, progress, 1) {
+					assert.Equal(t, "resA", string(progress[0].Metadata.URN.Name()))
+					assert.Equal(t, deploy.OpCreate, progress[0].Metadata.Op)
+					assert.Equal(t, 30, progress[0].Percent)
+					assert.Equal(t, "creating nodes 3/10", progress[0].Message)
+				}
+				return res
+			},
+		}},
+	}
+	p.Run(t, nil)
+}
+
+// Test that the details a provider supplies about a failed operation are included in the failure event and diagnostic.
+func TestExplainFailure(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					return "", nil, resource.StatusOK, errors.New("AccessDenied")
+				},
+				ExplainFailureF: func(urn resource.URN, err error) (*plugin.FailureDetails, error) {
+					assert.EqualError(t, err, "AccessDenied")
+					return &plugin.FailureDetails{
+						Code:        "AccessDenied",
+						Remediation: []string{"grant the deployment role permission to create typA"},
+						DocsURL:     "https://example.com/errors/AccessDenied",
+					}, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.Error(t, err)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps: []TestStep{{
+			Op:            Update,
+			ExpectFailure: true,
+			SkipPreview:   true,
+			Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+				evts []Event, res result.Result) result.Result {
+
+				sawDiag := false
+				var failures []ResourceOperationFailedPayload
+				for _, evt := range evts {
+					switch evt.Type {
+					case DiagEvent:
+						e := evt.Payload.(DiagEventPayload)
+						msg := colors.Never.Colorize(e.Message)
+						if e.Severity == diag.Error && strings.Contains(msg, "AccessDenied") {
+							sawDiag = strings.Contains(msg, "hint: grant the deployment role permission to create typA")
+						}
+					case ResourceOperationFailed:
+						failures = append(failures, evt.Payload.(ResourceOperationFailedPayload))
+					}
+				}
+
+				assert.True(t, sawDiag)
+				if assert.Len(t, failures, 1) && assert.NotNil(t, failures[0].Details) {
+					assert.Equal(t, "AccessDenied", failures[0].Details.Code)
+					assert.Equal(t, "https://example.com/errors/AccessDenied", failures[0].Details.DocsURL)
+				}
+				return res
+			},
+		}},
+	}
+	p.Run(t, nil)
+}
+
+// Test that annotations attached to a resource by external tools survive updates and refreshes of the resource.
+func TestAnnotationsPreserved(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	inputs := resource.PropertyMap{"foo": resource.NewStringProperty("bar")}
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			inputs, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+
+	resURN := p.NewURN("pkgA:m:typA", "resA", "")
+	annotations := map[string]string{"cost:center": "1234"}
+	for _, res := range snap.Resources {
+		if res.URN == resURN {
+			res.Annotations = annotations
+		}
+	}
+
+	inputs = resource.PropertyMap{"foo": resource.NewStringProperty("baz")}
+	p.Steps = []TestStep{{Op: Update}, {Op: Refresh}}
+	snap = p.Run(t, snap)
+
+	found := false
+	for _, res := range snap.Resources {
+		if res.URN == resURN {
+			found = true
+			assert.Equal(t, annotations, res.Annotations)
+		}
+	}
+	assert.True(t, found)
+}
+
+// Test that importing a resource reads it from its provider and adds it to the stack without touching the resources
+// that are already present.
+func TestImport(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ReadF: func(urn resource.URN, id resource.ID,
+					inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+					if id != "existing-id" {
+						return plugin.ReadResult{}, resource.StatusOK, nil
+					}
+					return plugin.ReadResult{
+						Inputs:  resource.PropertyMap{"foo": resource.NewStringProperty("bar")},
+						Outputs: resource.PropertyMap{"foo": resource.NewStringProperty("bar")},
+					}, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	importOp := func(imports ...deploy.Import) TestOp {
+		return func(info UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
+			return Import(info, ctx, opts, imports, dryRun)
+		}
+	}
+
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+	p.Steps = []TestStep{{Op: Update}}
+	snap := p.Run(t, nil)
+
+	// Import a resource that exists.
+	p.Steps = []TestStep{{
+		Op: importOp(deploy.Import{Type: "pkgA:m:typA", Name: "resB", ID: "existing-id"}),
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			for _, entry := range j.Entries {
+				assert.Equal(t, deploy.OpImport, entry.Step.Op())
+				assert.Equal(t, p.NewURN("pkgA:m:typA", "resB", ""), entry.Step.URN())
+			}
+			return res
+		},
+	}}
+	snap = p.Run(t, snap)
+	assert.Len(t, snap.Resources, 3)
+	for _, res := range snap.Resources {
+		if res.URN == p.NewURN("pkgA:m:typA", "resB", "") {
+			assert.True(t, res.External)
+			assert.Equal(t, resource.ID("existing-id"), res.ID)
+			assert.Equal(t, resource.PropertyMap{"foo": resource.NewStringProperty("bar")}, res.Inputs)
+		}
+	}
+
+	// Importing a resource that is already in the stack or that does not exist fails.
+	p.Steps = []TestStep{
+		{
+			Op:            importOp(deploy.Import{Type: "pkgA:m:typA", Name: "resA", ID: "existing-id"}),
+			ExpectFailure: true,
+			SkipPreview:   true,
+		},
+		{
+			Op:            importOp(deploy.Import{Type: "pkgA:m:typA", Name: "resC", ID: "missing-id"}),
+			ExpectFailure: true,
+			SkipPreview:   true,
+		},
+	}
+	snap = p.Run(t, snap)
+	assert.Len(t, snap.Resources, 3)
+
+	// Import a resource beneath a chain of components that do not exist yet. The components are created, and the
+	// existing resources precede everything that was added.
+	stack, project, _ := p.getNames()
+	compA := p.NewURN("my:m:comp", "compA", "")
+	compB := p.NewURN("my:m:comp", "compB", compA)
+	resC := resource.NewURN(stack, project, compB.QualifiedType(), "pkgA:m:typA", "resC")
+	p.Steps = []TestStep{{Op: importOp(deploy.Import{
+		Type: "pkgA:m:typA", Name: "resC", ID: "existing-id",
+		ParentPath: []deploy.ImportComponent{{Type: "my:m:comp", Name: "compA"}, {Type: "my:m:comp", Name: "compB"}},
+	})}}
+	snap = p.Run(t, snap)
+	assert.Len(t, snap.Resources, 6)
+	assert.Equal(t, compA, snap.Resources[3].URN)
+	assert.Equal(t, compB, snap.Resources[4].URN)
+	assert.Equal(t, compA, snap.Resources[4].Parent)
+	assert.Equal(t, resC, snap.Resources[5].URN)
+	assert.Equal(t, compB, snap.Resources[5].Parent)
+
+	// Importing beneath an existing component attaches to it rather than creating another.
+	resD := p.NewURN("pkgA:m:typA", "resD", compA)
+	p.Steps = []TestStep{{Op: importOp(deploy.Import{
+		Type: "pkgA:m:typA", Name: "resD", ID: "existing-id",
+		ParentPath: []deploy.ImportComponent{{Type: "my:m:comp", Name: "compA"}},
+	})}}
+	snap = p.Run(t, snap)
+	assert.Len(t, snap.Resources, 7)
+	assert.Equal(t, resD, snap.Resources[6].URN)
+	assert.Equal(t, compA, snap.Resources[6].Parent)
+}
+
+// Test that a step whose provider exits mid-operation is retried once the provider has been restarted, and that the
+// restart is reported.
+func TestProviderRestart(t *testing.T) {
+	crashed := false
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+					if !crashed {
+						crashed = true
+						return "", nil, resource.StatusOK, errors.New("transport is closing")
+					}
+					return "created-id", news, resource.StatusOK, nil
+				},
+				RestartF: func() (bool, error) {
+					return crashed, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps: []TestStep{{
+			Op:          Update,
+			SkipPreview: true,
+			Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+				evts []Event, res result.Result) result.Result {
+
+				var restarts []PluginLifecycleEventPayload
+				for _, evt := range evts {
+					if evt.Type == PluginLifecycleEvent {
+						restarts = append(restarts, evt.Payload.(PluginLifecycleEventPayload))
+					}
+				}
+
+				if assert.Len(t, restarts, 1) {
+					assert.Equal(t, "pkgA", restarts[0].Plugin)
+					assert.Equal(t, "transport is closing", restarts[0].Message)
+				}
+				return res
+			},
+		}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, snap.Resources, 2)
+}
+
+// Test that a replacement registered with validateReplacement is validated before dependents are moved to it, and that
+// a replacement that fails validation is condemned while the original resource is left in place.
+func TestValidateReplacement(t *testing.T) {
+	var healthErr error
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					if !olds["A"].DeepEquals(news["A"]) {
+						return plugin.DiffResult{ReplaceKeys: []resource.PropertyKey{"A"}}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+				CheckHealthF: func(urn resource.URN, id resource.ID, state resource.PropertyMap) error {
+					return healthErr
+				},
+			}, nil
+		}),
+	}
+
+	inputsA := resource.NewPropertyMapFromMap(map[string]interface{}{"A": "foo"})
+
+	var urnA, urnB resource.URN
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		object, err := plugin.MarshalProperties(inputsA, plugin.MarshalOptions{KeepUnknowns: true})
+		assert.NoError(t, err)
+
+		urnA, _, _, err = monitor.RegisterResourceRaw(&pulumirpc.RegisterResourceRequest{
+			Type:                "pkgA:m:typA",
+			Name:                "resA",
+			Custom:              true,
+			Object:              object,
+			ValidateReplacement: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		urnB, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, []resource.URN{urnA}, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		return err
+	})
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: deploytest.NewPluginHost(nil, nil, program, loaders...)},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+
+	// Change the value of resA.A. The replacement should be validated before resB is processed.
+	inputsA["A"] = resource.NewStringProperty("bar")
+	p.Steps = []TestStep{{
+		Op: Update,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			assert.Nil(t, res)
+
+			var ops []deploy.StepOp
+			for _, step := range j.SuccessfulSteps() {
+				if step.URN() == urnA || step.URN() == urnB {
+					ops = append(ops, step.Op())
+				}
+			}
+			assert.Equal(t, []deploy.StepOp{
+				deploy.OpCreateReplacement,
+				deploy.OpValidateReplacement,
+				deploy.OpReplace,
+				deploy.OpSame,
+				deploy.OpDeleteReplaced,
+			}, ops)
+			return res
+		},
+	}}
+	snap = p.Run(t, snap)
+
+	// Change the value again, but fail validation. The update should fail, the original resource should remain live,
+	// and the unhealthy replacement should be marked for deletion.
+	healthErr = errors.New("health check failed")
+	inputsA["A"] = resource.NewStringProperty("baz")
+	p.Steps = []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true}}
+	snap = p.Run(t, snap)
+
+	var live, condemned []*resource.State
+	for _, res := range snap.Resources {
+		if res.URN != urnA {
+			continue
+		}
+		if res.Delete {
+			condemned = append(condemned, res)
+		} else {
+			live = append(live, res)
+		}
+	}
+	if assert.Len(t, live, 1) && assert.Len(t, condemned, 1) {
+		assert.Equal(t, "bar", live[0].Inputs["A"].StringValue())
+		assert.Equal(t, "baz", condemned[0].Inputs["A"].StringValue())
+	}
+
+	// The next successful update should clean up the condemned replacement and replace the original.
+	healthErr = nil
+	p.Steps = []TestStep{{Op: Update}}
+	snap = p.Run(t, snap)
+	for _, res := range snap.Resources {
+		assert.False(t, res.Delete)
+	}
+}
+
+func TestPreviewPlan(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		urnA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"foo": resource.NewStringProperty("bar")}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, []resource.URN{urnA}, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+
+	var plan *Plan
+	previewOp := func(info UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
+		preview, res := Preview(info, ctx, opts)
+		if preview != nil {
+			plan = preview.Plan
+		}
+		return preview, res
+	}
+
+	_, res := TestOp(previewOp).Run(p.GetProject(), p.GetTarget(nil), p.Options, true, nil, nil)
+	assert.Nil(t, res)
+	if !assert.NotNil(t, plan) {
+		return
+	}
+
+	urnA, urnB := p.NewURN("pkgA:m:typA", "resA", ""), p.NewURN("pkgA:m:typA", "resB", "")
+	assert.Equal(t, 2, plan.Changes[deploy.OpCreate])
+
+	stepA, ok := plan.Step(urnA)
+	if assert.True(t, ok) {
+		assert.Equal(t, deploy.OpCreate, stepA.Op)
+		assert.Equal(t, resource.NewStringProperty("bar"), stepA.New.Inputs["foo"])
+		assert.Empty(t, stepA.Dependencies)
+	}
+	stepB, ok := plan.Step(urnB)
+	if assert.True(t, ok) {
+		assert.Equal(t, deploy.OpCreate, stepB.Op)
+		assert.Equal(t, []resource.URN{urnA}, stepB.Dependencies)
+	}
+
+	// Default provider steps are not reported, so the plan contains exactly the two resources, in dependency order.
+	if assert.Len(t, plan.Steps, 2) {
+		assert.Equal(t, urnA, plan.Steps[0].URN)
+		assert.Equal(t, urnB, plan.Steps[1].URN)
+	}
+}
+
+func TestUpdateMany(t *testing.T) {
+	var lock sync.Mutex
+	inflight, maxInflight := 0, 0
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					lock.Lock()
+					inflight++
+					if inflight > maxInflight {
+						maxInflight = inflight
+					}
+					lock.Unlock()
+
+					time.Sleep(10 * time.Millisecond)
+
+					lock.Lock()
+					inflight--
+					lock.Unlock()
+					return "created-id", news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				_, _, _, err := monitor.RegisterResource("pkgA:m:typA", name, true, "", false, nil, "",
+					resource.PropertyMap{}, nil, false, "", nil, nil)
+				assert.NoError(t, err)
+			}(fmt.Sprintf("res%d", i))
+		}
+		wg.Wait()
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	var updates []StackUpdate
+	var journals []*Journal
+	for _, stack := range []string{"stackA", "stackB"} {
+		p := &TestPlan{Stack: stack}
+		journal := newJournal()
+		journals = append(journals, journal)
+		updates = append(updates, StackUpdate{
+			Info:            &updateInfo{project: p.GetProject(), target: p.GetTarget(nil)},
+			SnapshotManager: journal,
+		})
+	}
+
+	events := make(chan Event)
+	seen := make(map[tokens.QName]int)
+	var cancels int
+	drained := make(chan bool)
+	go func() {
+		for e := range events {
+			if e.Type == CancelEvent {
+				cancels++
+			} else {
+				seen[e.Stack]++
+			}
+		}
+		close(drained)
+	}()
+
+	cancelCtx, _ := cancel.NewContext(context.Background())
+	ctx := &Context{Cancel: cancelCtx, Events: events}
+	results, res := UpdateMany(updates, ctx, UpdateOptions{Parallel: 2, host: host}, false)
+	close(events)
+	<-drained
+	assert.Nil(t, res)
+
+	// The parallelism budget is shared by both stacks.
+	assert.True(t, maxInflight <= 2, "at most two creates may run at once; saw %d", maxInflight)
+
+	// Every event is tagged with its stack, and the updates' individual cancellation events are collapsed into one.
+	assert.Equal(t, 1, cancels)
+	assert.Len(t, seen, 2)
+	assert.NotZero(t, seen["stackA"])
+	assert.NotZero(t, seen["stackB"])
+
+	for i, stack := range []tokens.QName{"stackA", "stackB"} {
+		assert.Equal(t, 3, results[stack].Changes[deploy.OpCreate])
+
+		contract.IgnoreClose(journals[i])
+		snap := journals[i].Snap(nil)
+		assert.Len(t, snap.Resources, 4)
+		for _, r := range snap.Resources {
+			assert.Equal(t, stack, r.URN.Stack())
+		}
+	}
+}
+
+func TestUpdateManyRequiresOneProjectRoot(t *testing.T) {
+	var updates []StackUpdate
+	for _, root := range []string{"rootA", "rootB"} {
+		p := &TestPlan{Stack: root}
+		updates = append(updates, StackUpdate{
+			Info:            &updateInfo{root: root, project: p.GetProject(), target: p.GetTarget(nil)},
+			SnapshotManager: newJournal(),
+		})
+	}
+
+	cancelCtx, _ := cancel.NewContext(context.Background())
+	ctx := &Context{Cancel: cancelCtx, Events: make(chan Event, 1)}
+	results, res := UpdateMany(updates, ctx, UpdateOptions{}, false)
+	assert.Nil(t, results)
+	assert.NotNil(t, res)
+	assert.Error(t, res.Error())
+}
+
+func TestProviderParallelismIsolation(t *testing.T) {
+	released := make(chan struct{})
+	var createdB sync.WaitGroup
+	createdB.Add(2)
+	go func() {
+		createdB.Wait()
+		close(released)
+	}()
+
+	var lock sync.Mutex
+	inflightA, maxInflightA := 0, 0
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				MaxParallelismF: func() int { return 1 },
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					lock.Lock()
+					inflightA++
+					if inflightA > maxInflightA {
+						maxInflightA = inflightA
+					}
+					lock.Unlock()
+					defer func() {
+						lock.Lock()
+						inflightA--
+						lock.Unlock()
+					}()
+
+					// Block until pkgB's resources have been created. If pkgA's steps occupied the workers that
+					// pkgB needs, this would never happen.
+					select {
+					case <-released:
+						return "created-id", news, resource.StatusOK, nil
+					case <-time.After(10 * time.Second):
+						return "", nil, resource.StatusOK, errors.New("pkgB's resources were starved")
+					}
+				},
+			}, nil
+		}),
+		deploytest.NewProviderLoader("pkgB", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					createdB.Done()
+					return "created-id", news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		var wg sync.WaitGroup
+		register := func(typ tokens.Type, name string) {
+			defer wg.Done()
+			_, _, _, err := monitor.RegisterResource(typ, name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+		}
+
+		wg.Add(4)
+		go register("pkgA:m:typA", "resA1")
+		go register("pkgA:m:typA", "resA2")
+		go register("pkgB:m:typB", "resB1")
+		go register("pkgB:m:typB", "resB2")
+		wg.Wait()
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Parallel: 2, host: host},
+		Steps:   []TestStep{{Op: Update, SkipPreview: true}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, snap.Resources, 6)
+
+	// pkgA's hint limits it to one operation at a time.
+	assert.Equal(t, 1, maxInflightA)
+}
+
+func TestProviderParallelismGlobalCap(t *testing.T) {
+	var lock sync.Mutex
+	inflight, maxInflight := 0, 0
+	create := func(urn resource.URN,
+		news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+		lock.Lock()
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		inflight--
+		lock.Unlock()
+		return "created-id", news, resource.StatusOK, nil
+	}
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{CreateF: create}, nil
+		}),
+		deploytest.NewProviderLoader("pkgB", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{CreateF: create}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			for _, typ := range []tokens.Type{"pkgA:m:typA", "pkgB:m:typB"} {
+				wg.Add(1)
+				go func(typ tokens.Type, name string) {
+					defer wg.Done()
+					_, _, _, err := monitor.RegisterResource(typ, name, true, "", false, nil, "",
+						resource.PropertyMap{}, nil, false, "", nil, nil)
+					assert.NoError(t, err)
+				}(typ, fmt.Sprintf("res-%s-%d", typ.Name(), i))
+			}
+		}
+		wg.Wait()
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// Each provider has its own pool of workers, but together they run no more operations than --parallel allows.
+	p := &TestPlan{
+		Options: UpdateOptions{Parallel: 2, host: host},
+		Steps:   []TestStep{{Op: Update, SkipPreview: true}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, snap.Resources, 10)
+	assert.True(t, maxInflight <= 2, "%d operations ran at once", maxInflight)
+}
+
+func TestProviderShareReservation(t *testing.T) {
+	released := make(chan struct{})
+	var createdB sync.WaitGroup
+	createdB.Add(2)
+	go func() {
+		createdB.Wait()
+		close(released)
+	}()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					// Block until pkgB's resources have been created. If pkgA's steps occupied every slot in the
+					// plan's budget, this would never happen.
+					select {
+					case <-released:
+						return "created-id", news, resource.StatusOK, nil
+					case <-time.After(10 * time.Second):
+						return "", nil, resource.StatusOK, errors.New("pkgB's resources were starved")
+					}
+				},
+			}, nil
+		}),
+		deploytest.NewProviderLoader("pkgB", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					createdB.Done()
+					return "created-id", news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		// Register both providers before any of their resources, so that the plan divides its budget between them.
+		provider := func(pkg tokens.Package, name string) string {
+			urn, id, _, err := monitor.RegisterResource(providers.MakeProviderType(pkg), name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+			ref, err := providers.NewReference(urn, id)
+			assert.NoError(t, err)
+			return ref.String()
+		}
+		provA, provB := provider("pkgA", "provA"), provider("pkgB", "provB")
+
+		var wg sync.WaitGroup
+		register := func(typ tokens.Type, name, prov string) {
+			defer wg.Done()
+			_, _, _, err := monitor.RegisterResource(typ, name, true, "", false, nil, prov,
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+		}
+
+		wg.Add(5)
+		for i := 0; i < 3; i++ {
+			go register("pkgA:m:typA", fmt.Sprintf("resA%d", i), provA)
+		}
+		for i := 0; i < 2; i++ {
+			go register("pkgB:m:typB", fmt.Sprintf("resB%d", i), provB)
+		}
+		wg.Wait()
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// pkgA's blocked operations may only occupy its share of the two slots, leaving the other to pkgB.
+	p := &TestPlan{
+		Options: UpdateOptions{Parallel: 2, host: host},
+		Steps:   []TestStep{{Op: Update, SkipPreview: true}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, snap.Resources, 7)
+}
+
+func TestProtectedResources(t *testing.T) {
+	var creates int
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					if !olds["foo"].DeepEquals(news["foo"]) {
+						return plugin.DiffResult{
+							Changes:     plugin.DiffSome,
+							ReplaceKeys: []resource.PropertyKey{"foo"},
+						}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					creates++
+					return resource.ID(fmt.Sprintf("created-id-%d", creates)), news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	foo := "bar"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", true, nil, "",
+			resource.PropertyMap{"foo": resource.NewStringProperty(foo)}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+	assert.Equal(t, 1, creates)
+
+	// Replacing the protected resource fails before the replacement is created, both in preview and in an update.
+	foo = "baz"
+	p.Steps = []TestStep{
+		{Op: Update, ExpectFailure: true},
+		{Op: Update, ExpectFailure: true, SkipPreview: true},
+	}
+	p.Run(t, snap)
+	assert.Equal(t, 1, creates)
+
+	// Destroying the stack fails, too.
+	p.Steps = []TestStep{{Op: Destroy, ExpectFailure: true, SkipPreview: true}}
+	p.Run(t, snap)
+
+	// Both succeed if changes to protected resources are allowed.
+	p.Options.AllowProtected = true
+	p.Steps = []TestStep{{Op: Update}}
+	snap = p.Run(t, snap)
+	assert.Equal(t, 2, creates)
+
+	p.Steps = []TestStep{{Op: Destroy}}
+	snap = p.Run(t, snap)
+	assert.Len(t, snap.Resources, 0)
+}
+
+func TestPruneOrphans(t *testing.T) {
+	var deletes []resource.URN
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap) (resource.Status, error) {
+					deletes = append(deletes, urn)
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	types := map[string]tokens.Type{"resA": "pkgA:m:typA", "resB": "pkgA:m:typA", "resC": "pkgA:m:typB"}
+	names := []string{"resA", "resB", "resC"}
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		for _, name := range names {
+			_, _, _, err := monitor.RegisterResource(types[name], name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+
+	// prunes returns the URNs of the resources pruned by the given journal, and whether or not each was deleted.
+	prunes := func(j *Journal) map[resource.URN]bool {
+		pruned := make(map[resource.URN]bool)
+		for _, e := range j.Entries {
+			if e.Kind == JournalEntrySuccess && e.Step.Op() == deploy.OpPrune {
+				pruned[e.Step.URN()] = e.Step.New() == nil
+			}
+		}
+		return pruned
+	}
+	resB, resC := p.NewURN(types["resB"], "resB", ""), p.NewURN(types["resC"], "resC", "")
+
+	// Reporting the orphans leaves them in place.
+	names = []string{"resA"}
+	p.Options.Prune = deploy.PruneReport
+	p.Steps = []TestStep{{
+		Op: Update,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			_ []Event, res result.Result) result.Result {
+
+			assert.Equal(t, map[resource.URN]bool{resB: false, resC: false}, prunes(j))
+			return res
+		},
+	}}
+	snap = p.Run(t, snap)
+	assert.Empty(t, deletes)
+	assert.Len(t, snap.Resources, 4)
+
+	// Deleting them spares the exempt type.
+	p.Options.Prune, p.Options.PruneExempt = deploy.PruneDelete, []string{string(types["resC"])}
+	p.Steps = []TestStep{{
+		Op: Update,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			_ []Event, res result.Result) result.Result {
+
+			assert.Equal(t, map[resource.URN]bool{resB: true}, prunes(j))
+			return res
+		},
+	}}
+	snap = p.Run(t, snap)
+	assert.Equal(t, []resource.URN{resB}, deletes)
+	assert.Len(t, snap.Resources, 3)
+
+	// An exempt list without a prune mode is rejected.
+	p.Options.Prune = deploy.PruneOff
+	p.Steps = []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true}}
+	p.Run(t, snap)
+}
+
+func TestDestroyExclude(t *testing.T) {
+	var deletes []resource.URN
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap) (resource.Status, error) {
+					deletes = append(deletes, urn)
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	// The database depends on the network, and the app on the database; the cache stands alone.
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		net, _, _, err := monitor.RegisterResource("pkgA:m:typA", "net", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		db, _, _, err := monitor.RegisterResource("pkgA:m:typB", "db", true, "", false, []resource.URN{net}, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "app", true, "", false, []resource.URN{db}, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "cache", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+
+	app, cache := p.NewURN("pkgA:m:typA", "app", ""), p.NewURN("pkgA:m:typA", "cache", "")
+	names := func(snap *deploy.Snapshot) []string {
+		var names []string
+		for _, res := range snap.Resources {
+			if !providers.IsProviderType(res.Type) {
+				names = append(names, string(res.URN.Name()))
+			}
+		}
+		return names
+	}
+
+	// Excluding the database leaves it and the network in place, and the order of the deletes is reported first.
+	p.Options.DestroyExclude = []string{"pkgA:m:typB::*"}
+	p.Options.ReportDeletionOrder = true
+	p.Steps = []TestStep{{
+		Op: Destroy,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			events []Event, res result.Result) result.Result {
+
+			var order []resource.URN
+			for _, e := range events {
+				if e.Type == DeletionOrderEvent {
+					for _, batch := range e.Payload.(DeletionOrderEventPayload).Batches {
+						order = append(order, batch...)
+					}
+				}
+			}
+			assert.ElementsMatch(t, []resource.URN{app, cache}, order)
+			return res
+		},
+	}}
+	excluded := p.Run(t, CloneSnapshot(t, snap))
+	assert.ElementsMatch(t, []resource.URN{app, cache}, deletes)
+	assert.Equal(t, []string{"net", "db"}, names(excluded))
+
+	// Excluding its dependents, too, leaves the app in place as well.
+	deletes = nil
+	p.Options.DestroyExcludeDependents = true
+	p.Steps = []TestStep{{Op: Destroy}}
+	excluded = p.Run(t, CloneSnapshot(t, snap))
+	assert.Equal(t, []resource.URN{cache}, deletes)
+	assert.Equal(t, []string{"net", "db", "app"}, names(excluded))
+
+	// Invalid patterns are rejected.
+	p.Options.DestroyExclude = []string{"urn:bad"}
+	p.Steps = []TestStep{{Op: Destroy, ExpectFailure: true, SkipPreview: true}}
+	p.Run(t, snap)
+}
+
+func TestDeletesDoNotWaitOnUnrelatedResources(t *testing.T) {
+	netDeleted := make(chan struct{})
+	var cacheWaited bool
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap) (resource.Status, error) {
+					switch urn.Name() {
+					case "net":
+						close(netDeleted)
+					case "cache":
+						// The cache is deleted alongside the app, but the database and the network need not wait for
+						// it to be gone.
+						select {
+						case <-netDeleted:
+						case <-time.After(10 * time.Second):
+							cacheWaited = true
+						}
+					}
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	// The database depends on the network, and the app on the database; the cache stands alone.
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		net, _, _, err := monitor.RegisterResource("pkgA:m:typA", "net", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		db, _, _, err := monitor.RegisterResource("pkgA:m:typA", "db", true, "", false, []resource.URN{net}, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "app", true, "", false, []resource.URN{db}, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "cache", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{Parallel: 4, host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+
+	p.Steps = []TestStep{{Op: Destroy, SkipPreview: true}}
+	snap = p.Run(t, snap)
+	assert.Len(t, snap.Resources, 0)
+	assert.False(t, cacheWaited)
+}
+
+func TestCheckCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checks")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer func() { contract.IgnoreError(os.RemoveAll(dir)) }()
+
+	checks := make(map[string]int)
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CheckF: func(urn resource.URN,
+					olds, news resource.PropertyMap) (resource.PropertyMap, []plugin.CheckFailure, error) {
+
+					checks[string(urn.Name())]++
+					checked := news.Copy()
+					checked["checked"] = resource.NewBoolProperty(true)
+					return checked, nil, nil
+				},
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					// Inputs served from the cache are those that the provider returned.
+					assert.True(t, news.HasValue("checked"))
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	// resB depends on resA.
+	inputs := map[string]string{"resA": "a", "resB": "b"}
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		resA, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"value": resource.NewStringProperty(inputs["resA"])}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, []resource.URN{resA}, "",
+			resource.PropertyMap{"value": resource.NewStringProperty(inputs["resB"])}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	cache := deploy.NewCheckCache(dir)
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, CheckCache: cache},
+		Steps:   []TestStep{{Op: Update, SkipPreview: true}},
+	}
+	snap := p.Run(t, nil)
+
+	preview := func() map[string]int {
+		checks = make(map[string]int)
+		_, res := Update.Run(p.GetProject(), p.GetTarget(CloneSnapshot(t, snap)), p.Options, true, nil, nil)
+		assert.Nil(t, res)
+		return checks
+	}
+
+	// Updates are never served from the cache; the first preview populates it, and the second is served from it.
+	assert.Equal(t, map[string]int{"resA": 1, "resB": 1}, preview())
+	assert.Equal(t, map[string]int{}, preview())
+	assert.Equal(t, deploy.CheckCacheStats{Hits: 2, Misses: 2}, cache.Stats())
+
+	// A change to a resource's inputs only affects the resource and its dependents.
+	inputs["resB"] = "b2"
+	assert.Equal(t, map[string]int{"resB": 1}, preview())
+	inputs["resA"] = "a2"
+	assert.Equal(t, map[string]int{"resA": 1, "resB": 1}, preview())
+
+	// Entries that the last preview did not use are swept away.
+	partitions, err := ioutil.ReadDir(dir)
+	if assert.NoError(t, err) && assert.Len(t, partitions, 1) {
+		entries, err := ioutil.ReadDir(filepath.Join(dir, partitions[0].Name()))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+	}
+}
+
+func TestDefaultTags(t *testing.T) {
+	created := make(map[resource.URN]resource.PropertyMap)
+	var updated []resource.URN
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				TagsPropertyF: func(t tokens.Type) resource.PropertyKey {
+					if t == "pkgA:m:typA" {
+						return "tags"
+					}
+					return ""
+				},
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					created[urn] = news
+					return resource.ID(urn.Name()), news, resource.StatusOK, nil
+				},
+				UpdateF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (resource.PropertyMap, resource.Status, error) {
+
+					updated = append(updated, urn)
+					return news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.NewPropertyMapFromMap(map[string]interface{}{
+				"tags": map[string]interface{}{"team": "infra", "cost-center": "0000"},
+			}), nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typB", "resB", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, DefaultTags: map[string]string{"cost-center": "1234"}},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	resA, resB := p.NewURN("pkgA:m:typA", "resA", ""), p.NewURN("pkgA:m:typB", "resB", "")
+
+	// The default tags override the program's tags on taggable resources, and are recorded in their state.
+	snap := p.Run(t, nil)
+	assert.Equal(t, resource.NewPropertyMapFromMap(map[string]interface{}{
+		"team": "infra", "cost-center": "1234",
+	}), created[resA]["tags"].ObjectValue())
+	assert.NotContains(t, created[resB], resource.PropertyKey("tags"))
+	for _, res := range snap.Resources {
+		if res.URN == resA {
+			assert.Equal(t, resource.NewStringProperty("1234"), res.Inputs["tags"].ObjectValue()["cost-center"])
+		}
+	}
+
+	// Changing a default tag updates the resources that carry it.
+	p.Options.DefaultTags["cost-center"] = "5678"
+	p.Run(t, snap)
+	assert.Equal(t, []resource.URN{resA}, updated)
+}
+
+func TestValidatePreview(t *testing.T) {
+	var validations int
+	validate := func(news resource.PropertyMap) error {
+		validations++
+		if news["size"].StringValue() == "huge" {
+			return errors.New("quota exceeded")
+		}
+		return nil
+	}
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				PreviewCreateF: func(urn resource.URN, news resource.PropertyMap) error {
+					return validate(news)
+				},
+				PreviewUpdateF: func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap) error {
+					return validate(news)
+				},
+			}, nil
+		}),
+	}
+
+	size := "small"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"size": resource.NewStringProperty(size)}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// Only the preview's create is validated.
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, ValidatePreview: true},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+	assert.Equal(t, 1, validations)
+
+	// An update that the provider rejects fails the preview.
+	size = "huge"
+	p.Steps = []TestStep{{Op: Update, ExpectFailure: true}}
+	p.Run(t, snap)
+	assert.Equal(t, 2, validations)
+
+	// Without validation, the preview is none the wiser.
+	p.Options.ValidatePreview = false
+	p.Steps = []TestStep{{Op: Update}}
+	p.Run(t, snap)
+	assert.Equal(t, 2, validations)
+}
+
+func TestDeprecations(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ResourceDeprecationF: func(t tokens.Type) *plugin.Deprecation {
+					if t == "pkgA:m:typA" {
+						return &plugin.Deprecation{Message: "typA will be removed in 2.0", Replacement: "pkgA:m:typB"}
+					}
+					return nil
+				},
+				PropertyDeprecationsF: func(t tokens.Type) map[resource.PropertyKey]plugin.Deprecation {
+					return map[resource.PropertyKey]plugin.Deprecation{"oldProp": {Replacement: "newProp"}}
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		for _, name := range []string{"resA", "resB"} {
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typB", "resC", true, "", false, nil, "",
+			resource.NewPropertyMapFromMap(map[string]interface{}{"oldProp": "value"}), nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// Each use of a deprecated type or property is warned about, and the uses are counted in the summary.
+	validate := func(project workspace.Project, target deploy.Target, j *Journal,
+		evts []Event, res result.Result) result.Result {
+
+		assert.Nil(t, res)
+		var warnings []string
+		var summary *SummaryEventPayload
+		for _, e := range evts {
+			switch e.Type {
+			case DiagEvent:
+				if payload := e.Payload.(DiagEventPayload); payload.Severity == diag.Warning {
+					warnings = append(warnings, payload.Message)
+				}
+			case SummaryEvent:
+				payload := e.Payload.(SummaryEventPayload)
+				summary = &payload
+			}
+		}
+		assert.Len(t, warnings, 3)
+		if assert.NotNil(t, summary) {
+			assert.Equal(t, []deploy.DeprecatedUsage{
+				{Type: "pkgA:m:typA", Message: "typA will be removed in 2.0", Replacement: "pkgA:m:typB", Count: 2},
+				{Type: "pkgA:m:typB", Property: "oldProp", Replacement: "newProp", Count: 1},
+			}, summary.Deprecations)
+		}
+		return res
+	}
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update, Validate: validate}},
+	}
+	p.Run(t, nil)
+}
+
+type testEstimator struct {
+	name     string
+	estimate func(plan *Plan) (PlanEstimate, error)
+}
+
+func (e *testEstimator) Name() string {
+	return e.name
+}
+
+func (e *testEstimator) EstimatePlan(u UpdateInfo, plan *Plan) (PlanEstimate, error) {
+	return e.estimate(plan)
+}
+
+func TestPlanEstimators(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		for _, name := range []string{"resA", "resB"} {
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// Each resource created costs 5 USD a month.
+	cost := &testEstimator{name: "cost", estimate: func(plan *Plan) (PlanEstimate, error) {
+		delta := 5 * float64(plan.Changes[deploy.OpCreate])
+		return PlanEstimate{MonthlyCostDelta: &delta, Currency: "USD"}, nil
+	}}
+	broken := &testEstimator{name: "broken", estimate: func(plan *Plan) (PlanEstimate, error) {
+		return PlanEstimate{}, errors.New("no cost model")
+	}}
+
+	// The estimates are attached to the summary of the preview, but not to that of the update. An estimator that fails
+	// is warned about.
+	validate := func(project workspace.Project, target deploy.Target, j *Journal,
+		evts []Event, res result.Result) result.Result {
+
+		assert.Nil(t, res)
+		var warnings []string
+		var summary *SummaryEventPayload
+		for _, e := range evts {
+			switch e.Type {
+			case DiagEvent:
+				if payload := e.Payload.(DiagEventPayload); payload.Severity == diag.Warning {
+					warnings = append(warnings, payload.Message)
+				}
+			case SummaryEvent:
+				payload := e.Payload.(SummaryEventPayload)
+				summary = &payload
+			}
+		}
+		if !assert.NotNil(t, summary) {
+			return res
+		}
+		if summary.IsPreview {
+			delta := 10.0
+			assert.Equal(t, []PlanEstimate{
+				{Estimator: "cost", MonthlyCostDelta: &delta, Currency: "USD"},
+			}, summary.Estimates)
+			if assert.Len(t, warnings, 1) {
+				assert.Contains(t, warnings[0], "estimator broken could not estimate this plan: no cost model")
+			}
+		} else {
+			assert.Empty(t, summary.Estimates)
+		}
+		return res
+	}
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, PlanEstimators: []PlanEstimator{cost, broken}},
+		Steps:   []TestStep{{Op: Update, Validate: validate}},
+	}
+	p.Run(t, nil)
+}
+
+type testArtifactStore map[string][]byte
+
+func (s testArtifactStore) PutArtifact(digest string, contents []byte) error {
+	s[digest] = contents
+	return nil
+}
+
+func (s testArtifactStore) GetArtifact(digest string) ([]byte, error) {
+	contents, has := s[digest]
+	if !has {
+		return nil, errors.Errorf("no artifact %s", digest)
+	}
+	return contents, nil
+}
+
+func TestArtifacts(t *testing.T) {
+	var pending []plugin.ArtifactFile
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					pending = []plugin.ArtifactFile{
+						{Name: "kubeconfig", ContentType: "application/json", Contents: []byte(`{"v":1}`)},
+						{Name: "ca.pem", Contents: []byte("ca")},
+					}
+					return "created-id", news, resource.StatusOK, nil
+				},
+				UpdateF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (resource.PropertyMap, resource.Status, error) {
+
+					pending = []plugin.ArtifactFile{
+						{Name: "kubeconfig", ContentType: "application/json", Contents: []byte(`{"v":2}`)},
+					}
+					return news, resource.StatusOK, nil
+				},
+				TakeArtifactsF: func(urn resource.URN) []plugin.ArtifactFile {
+					files := pending
+					pending = nil
+					return files
+				},
+			}, nil
+		}),
+	}
+
+	size := 1.0
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"size": resource.NewNumberProperty(size)}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{}
+	resA := p.NewURN("pkgA:m:typA", "resA", "")
+	store := testArtifactStore{}
+	run := func(snap *deploy.Snapshot) *deploy.Snapshot {
+		events := make(chan Event)
+		go func() {
+			for range events {
+			}
+		}()
+		journal := newJournal()
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		ctx := &Context{Cancel: cancelCtx, Events: events, SnapshotManager: journal, Artifacts: store}
+		info := &updateInfo{project: p.GetProject(), target: p.GetTarget(snap)}
+		_, res := Update(info, ctx, UpdateOptions{host: host}, false)
+		close(events)
+		contract.IgnoreClose(journal)
+		assert.Nil(t, res)
+		return journal.Snap(snap)
+	}
+	read := func(snap *deploy.Snapshot, name string) string {
+		_, contents, err := snap.ReadArtifact(store, resA, name)
+		assert.NoError(t, err)
+		return string(contents)
+	}
+
+	// The artifacts produced by the create are stored, and referenced by the resource's state.
+	snap := run(nil)
+	assert.Equal(t, `{"v":1}`, read(snap, "kubeconfig"))
+	assert.Equal(t, "ca", read(snap, "ca.pem"))
+
+	// An update replaces the artifacts it produces, and keeps the others.
+	size = 2
+	snap = run(snap)
+	assert.Equal(t, `{"v":2}`, read(snap, "kubeconfig"))
+	assert.Equal(t, "ca", read(snap, "ca.pem"))
+
+	// A step that produces no artifacts keeps the resource's artifacts.
+	snap = run(snap)
+	assert.Equal(t, `{"v":2}`, read(snap, "kubeconfig"))
+
+	_, _, err := snap.ReadArtifact(store, resA, "missing")
+	assert.Error(t, err)
+}
+
+func TestUpdateBudget(t *testing.T) {
+	var creates int
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					creates++
+					return resource.ID(fmt.Sprintf("created-id-%d", creates)), news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		for _, name := range []string{"resA", "resB", "resC"} {
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// Exceeding the budget stops the update if the alarm handler says so.
+	var alarms []BudgetAlarm
+	p := &TestPlan{
+		Options: UpdateOptions{
+			host: host,
+			Budget: UpdateBudget{
+				MaxCreates: 1,
+				OnExceeded: func(alarm BudgetAlarm) bool {
+					alarms = append(alarms, alarm)
+					return false
+				},
+			},
+		},
+		Steps: []TestStep{{
+			Op:            Update,
+			ExpectFailure: true,
+			SkipPreview:   true,
+			Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+				evts []Event, res result.Result) result.Result {
+
+				sawWarning := false
+				for _, evt := range evts {
+					if evt.Type == DiagEvent {
+						e := evt.Payload.(DiagEventPayload)
+						msg := colors.Never.Colorize(e.Message)
+						sawWarning = sawWarning ||
+							e.Severity == diag.Warning && strings.Contains(msg, "budget of 1")
+					}
+				}
+
+				assert.True(t, sawWarning)
+				return res
+			},
+		}},
+	}
+	p.Run(t, nil)
+	assert.Equal(t, 2, creates)
+	if assert.Len(t, alarms, 1) {
+		assert.Equal(t, CreatesBudgetLimit, alarms[0].Limit)
+	}
+
+	// Otherwise, the update only warns.
+	creates, alarms = 0, nil
+	p.Options.Budget.OnExceeded = nil
+	p.Steps = []TestStep{{Op: Update}}
+	snap := p.Run(t, nil)
+	assert.Equal(t, 3, creates)
+	assert.Len(t, snap.Resources, 4)
+}
+
+func TestConfirmDestructiveSteps(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					if !olds["foo"].DeepEquals(news["foo"]) {
+						return plugin.DiffResult{
+							Changes:     plugin.DiffSome,
+							ReplaceKeys: []resource.PropertyKey{"foo"},
+						}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	foo, createB := "bar", true
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"foo": resource.NewStringProperty(foo)}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+
+		if createB {
+			_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// confirmingUpdate runs an update that answers each request for confirmation using the given function.
+	var requests []resource.URN
+	confirmingUpdate := func(approve func(urn resource.URN) bool) TestOp {
+		return func(info UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
+			events, confirmations := make(chan Event), make(chan ConfirmationResponse)
+			forwarded := make(chan struct{})
+			go func() {
+				defer close(forwarded)
+				for e := range events {
+					ctx.Events <- e
+					if e.Type == ConfirmationRequiredEvent {
+						urn := e.Payload.(ConfirmationRequiredEventPayload).Metadata.URN
+						requests = append(requests, urn)
+						confirmations <- ConfirmationResponse{URN: urn, Approved: approve(urn)}
+					}
+				}
+			}()
+
+			confirmingCtx := *ctx
+			confirmingCtx.Events, confirmingCtx.Confirmations = events, confirmations
+			updateResult, res := Update(info, &confirmingCtx, opts, dryRun)
+			close(events)
+			<-forwarded
+			return updateResult, res
+		}
+	}
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, ConfirmDestructiveSteps: true},
+		Steps:   []TestStep{{Op: confirmingUpdate(func(resource.URN) bool { return true })}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, requests, 0)
+
+	// Replacing resA and deleting resB each require confirmation. Declining the deletion of resB fails the update and
+	// leaves resB in place.
+	foo, createB = "baz", false
+	p.Steps = []TestStep{{
+		Op:            confirmingUpdate(func(urn resource.URN) bool { return urn.Name() == "resA" }),
+		ExpectFailure: true,
+		SkipPreview:   true,
+	}}
+	snap = p.Run(t, snap)
+	assert.Len(t, requests, 2)
+
+	var names []string
+	for _, res := range snap.Resources {
+		names = append(names, string(res.URN.Name()))
+	}
+	assert.Contains(t, names, "resB")
+
+	// Once the deletion is confirmed, the update succeeds.
+	requests = nil
+	p.Steps = []TestStep{{Op: confirmingUpdate(func(resource.URN) bool { return true })}}
+	snap = p.Run(t, snap)
+	var requested []string
+	for _, urn := range requests {
+		requested = append(requested, string(urn.Name()))
+	}
+	assert.Contains(t, requested, "resB")
+	for _, res := range snap.Resources {
+		assert.NotEqual(t, "resB", string(res.URN.Name()))
+	}
+
+	// Updates that confirm destructive steps must be given a channel for the confirmations.
+	p.Steps = []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true}}
+	p.Run(t, snap)
+}
+
+func TestConfirmProviderReplacements(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffConfigF: func(urn resource.URN, olds, news resource.PropertyMap,
+					allowUnknowns bool) (plugin.DiffResult, error) {
+
+					// Changing the region replaces the provider.
+					if !olds["region"].DeepEquals(news["region"]) {
+						return plugin.DiffResult{
+							Changes:     plugin.DiffSome,
+							ReplaceKeys: []resource.PropertyKey{"region"},
+						}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	region := "us-west-2"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		provURN, provID, _, err := monitor.RegisterResource(providers.MakeProviderType("pkgA"), "provA", true, "",
+			false, nil, "", resource.PropertyMap{"region": resource.NewStringProperty(region)}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+
+		if provID == "" {
+			provID = providers.UnknownID
+		}
+		provRef, err := providers.NewReference(provURN, provID)
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, provRef.String(),
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// confirmingUpdate runs an update that answers each request for confirmation using the given answer.
+	var requests []resource.URN
+	confirmingUpdate := func(approve bool) TestOp {
+		return func(info UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
+			events, confirmations := make(chan Event), make(chan ConfirmationResponse)
+			forwarded := make(chan struct{})
+			go func() {
+				defer close(forwarded)
+				for e := range events {
+					ctx.Events <- e
+					if e.Type == ConfirmationRequiredEvent {
+						urn := e.Payload.(ConfirmationRequiredEventPayload).Metadata.URN
+						requests = append(requests, urn)
+						confirmations <- ConfirmationResponse{URN: urn, Approved: approve}
+					}
+				}
+			}()
+
+			confirmingCtx := *ctx
+			confirmingCtx.Events, confirmingCtx.Confirmations = events, confirmations
+			updateResult, res := Update(info, &confirmingCtx, opts, dryRun)
+			close(events)
+			<-forwarded
+			return updateResult, res
+		}
+	}
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, ConfirmProviderReplacements: true},
+		Steps:   []TestStep{{Op: confirmingUpdate(true)}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, requests, 0)
+
+	provURN := p.NewProviderURN("pkgA", "provA", "")
+	resURN := p.NewURN("pkgA:m:typA", "resA", "")
+
+	// Changing the region reports the change, along with the resource that the provider manages. Declining the
+	// replacement of the provider fails the update before anything is replaced.
+	region = "us-east-1"
+	p.Steps = []TestStep{{
+		Op:            confirmingUpdate(false),
+		ExpectFailure: true,
+		SkipPreview:   true,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			events []Event, res result.Result) result.Result {
+
+			var changes []ProviderChangeEventPayload
+			for _, e := range events {
+				if e.Type == ProviderChangeEvent {
+					changes = append(changes, e.Payload.(ProviderChangeEventPayload))
+				}
+			}
+			if assert.Len(t, changes, 1) {
+				assert.Equal(t, provURN, changes[0].URN)
+				assert.Equal(t, deploy.OpReplace, changes[0].Op)
+				assert.Equal(t, []resource.PropertyKey{"region"}, changes[0].ReplaceKeys)
+				assert.Equal(t, []resource.URN{resURN}, changes[0].Dependents)
+			}
+
+			for _, entry := range j.Entries {
+				if entry.Kind == JournalEntrySuccess {
+					assert.NotEqual(t, deploy.OpDeleteReplaced, entry.Step.Op())
+					assert.NotEqual(t, deploy.OpCreateReplacement, entry.Step.Op())
+				}
+			}
+			return res
+		},
+	}}
+	snap = p.Run(t, snap)
+	assert.Equal(t, []resource.URN{provURN}, requests)
+
+	// Once the replacement is confirmed, the provider and the resource that it manages are both replaced. The
+	// replacement of the resource itself does not need to be confirmed.
+	requests = nil
+	p.Steps = []TestStep{{Op: confirmingUpdate(true), SkipPreview: true}}
+	p.Run(t, snap)
+	assert.Equal(t, []resource.URN{provURN}, requests)
+}
+
+func TestControl(t *testing.T) {
+	var created []string
+	var onCreateA func()
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					created = append(created, string(urn.Name()))
+					if urn.Name() == "resA" {
+						onCreateA()
+					}
+					return "id", news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	// resB is not registered until resA has been created.
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		for _, name := range []string{"resA", "resB"} {
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", name, true, "", false, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// controlledUpdate runs an update whose context has the given control channel, recording the order in which resB
+	// starts and the update pauses and resumes. A paused update is resumed after a little while.
+	var seen []string
+	var paused chan struct{}
+	controlledUpdate := func(control chan ControlCommand) TestOp {
+		return func(info UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
+			events := make(chan Event)
+			forwarded := make(chan struct{})
+			go func() {
+				defer close(forwarded)
+				for e := range events {
+					ctx.Events <- e
+					switch e.Type {
+					case ResourcePreEvent:
+						if e.Payload.(ResourcePreEventPayload).Metadata.URN.Name() == "resB" {
+							seen = append(seen, "resB")
+						}
+					case PausedEvent:
+						if e.Payload.(PausedEventPayload).Paused {
+							seen = append(seen, "paused")
+							close(paused)
+							go func() {
+								time.Sleep(100 * time.Millisecond)
+								control <- ResumeCommand
+							}()
+						} else {
+							seen = append(seen, "resumed")
+						}
+					}
+				}
+			}()
+
+			controlledCtx := *ctx
+			controlledCtx.Events, controlledCtx.Control = events, control
+			updateResult, res := Update(info, &controlledCtx, opts, dryRun)
+			close(events)
+			<-forwarded
+			return updateResult, res
+		}
+	}
+
+	// Pausing the update while resA is being created holds resB back until the update is resumed.
+	control := make(chan ControlCommand)
+	paused = make(chan struct{})
+	onCreateA = func() {
+		control <- PauseCommand
+		<-paused
+	}
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: controlledUpdate(control), SkipPreview: true}},
+	}
+	snap := p.Run(t, nil)
+	assert.Equal(t, []string{"resA", "resB"}, created)
+	assert.Equal(t, []string{"paused", "resumed", "resB"}, seen)
+	assert.Len(t, snap.Resources, 3)
+
+	// Aborting the update while resA is being created fails the update before resB is created. The command that
+	// follows the abort is not received until the abort has been applied, and is ignored.
+	created, seen = nil, nil
+	control = make(chan ControlCommand)
+	onCreateA = func() {
+		control <- AbortCommand
+		control <- PauseCommand
+	}
+	p.Steps = []TestStep{{Op: controlledUpdate(control), SkipPreview: true, ExpectFailure: true}}
+	snap = p.Run(t, nil)
+	assert.Equal(t, []string{"resA"}, created)
+	assert.Empty(t, seen)
+	for _, res := range snap.Resources {
+		assert.NotEqual(t, "resB", string(res.URN.Name()))
+	}
+}
+
+func TestCustomTimeouts(t *testing.T) {
+	var canceled []resource.URN
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			// Creates hang until they are canceled.
+			cancel := make(chan struct{}, 1)
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					<-cancel
+					return "", nil, resource.StatusOK, errors.New("canceled")
+				},
+				CancelOperationF: func(urn resource.URN) {
+					canceled = append(canceled, urn)
+					cancel <- struct{}{}
+				},
+			}, nil
+		}),
+	}
+
+	var timeouts *pulumirpc.RegisterResourceRequest_CustomTimeouts
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		object, err := plugin.MarshalProperties(resource.PropertyMap{}, plugin.MarshalOptions{KeepUnknowns: true})
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResourceRaw(&pulumirpc.RegisterResourceRequest{
+			Type:           "pkgA:m:typA",
+			Name:           "resA",
+			Custom:         true,
+			Object:         object,
+			CustomTimeouts: timeouts,
+		})
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	validate := func(project workspace.Project, target deploy.Target, j *Journal,
+		evts []Event, res result.Result) result.Result {
+
+		sawTimeout := false
+		for _, evt := range evts {
+			if evt.Type == DiagEvent {
+				e := evt.Payload.(DiagEventPayload)
+				msg := colors.Never.Colorize(e.Message)
+				sawTimeout = sawTimeout || e.Severity == diag.Error && strings.Contains(msg, "timed out after 100ms")
+			}
+		}
+		assert.True(t, sawTimeout)
+		return res
+	}
+
+	// A timeout set on the resource cancels its creation.
+	timeouts = &pulumirpc.RegisterResourceRequest_CustomTimeouts{Create: "100ms"}
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true, Validate: validate}},
+	}
+	p.Run(t, nil)
+	if assert.Len(t, canceled, 1) {
+		assert.Equal(t, "resA", string(canceled[0].Name()))
+	}
+
+	// So does a timeout set for the resource's type.
+	timeouts = nil
+	p.Options.CustomTimeouts = map[tokens.Type]resource.CustomTimeouts{
+		"pkgA:m:typA": {Create: 100 * time.Millisecond},
+	}
+	p.Run(t, nil)
+	assert.Len(t, canceled, 2)
+
+	// Invalid timeouts are rejected.
+	timeouts = &pulumirpc.RegisterResourceRequest_CustomTimeouts{Create: "soon"}
+	p.Steps = []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true}}
+	p.Run(t, nil)
+	assert.Len(t, canceled, 2)
+}
+
+func TestCustomTimeoutUnknownOutcome(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			// Creates hang until they are canceled, and cannot tell whether they took effect.
+			cancel := make(chan struct{}, 1)
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					<-cancel
+					return "", nil, resource.StatusUnknown, errors.New("canceled")
+				},
+				CancelOperationF: func(urn resource.URN) {
+					cancel <- struct{}{}
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		object, err := plugin.MarshalProperties(resource.PropertyMap{}, plugin.MarshalOptions{KeepUnknowns: true})
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResourceRaw(&pulumirpc.RegisterResourceRequest{
+			Type:           "pkgA:m:typA",
+			Name:           "resA",
+			Custom:         true,
+			Object:         object,
+			CustomTimeouts: &pulumirpc.RegisterResourceRequest_CustomTimeouts{Create: "100ms"},
+		})
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// The creation is left pending, so that the next update will not run until it has been resolved.
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update, ExpectFailure: true, SkipPreview: true}},
+	}
+	snap := p.Run(t, nil)
+	assert.Len(t, snap.Resources, 1) // the default provider
+	if assert.Len(t, snap.PendingOperations, 1) {
+		assert.Equal(t, resource.OperationTypeCreating, snap.PendingOperations[0].Type)
+		assert.Equal(t, "resA", string(snap.PendingOperations[0].Resource.URN.Name()))
+	}
+}
+
+func TestOperationSettings(t *testing.T) {
+	var lock sync.Mutex
+	settings := make(map[resource.URN]plugin.OperationSettings)
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ConfigureOperationF: func(urn resource.URN, s plugin.OperationSettings) {
+					lock.Lock()
+					defer lock.Unlock()
+					settings[urn] = s
+				},
+			}, nil
+		}),
+	}
+
+	register := true
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		if !register {
+			return nil
+		}
+		object, err := plugin.MarshalProperties(resource.PropertyMap{}, plugin.MarshalOptions{KeepUnknowns: true})
+		assert.NoError(t, err)
+
+		_, _, _, err = monitor.RegisterResourceRaw(&pulumirpc.RegisterResourceRequest{
+			Type:   "pkgA:m:typA",
+			Name:   "resA",
+			Custom: true,
+			Object: object,
+			CustomTimeouts: &pulumirpc.RegisterResourceRequest_CustomTimeouts{
+				Create:       "10m",
+				PollInterval: "30s",
+			},
+		})
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	urn := p.NewURN("pkgA:m:typA", "resA", "")
+
+	// The create is told the resource's settings, which are persisted in its state.
+	snap := p.Run(t, nil)
+	assert.Equal(t, plugin.OperationSettings{Timeout: 10 * time.Minute, PollInterval: 30 * time.Second}, settings[urn])
+	if assert.Len(t, snap.Resources, 2) {
+		assert.Equal(t, resource.CustomTimeouts{Create: 10 * time.Minute, PollInterval: 30 * time.Second},
+			snap.Resources[1].CustomTimeouts)
+	}
+
+	// Once the program no longer registers the resource, its delete is still told the poll interval from its state,
+	// along with the delete timeout configured for its type.
+	register = false
+	p.Options.CustomTimeouts = map[tokens.Type]resource.CustomTimeouts{"pkgA:m:typA": {Delete: time.Minute}}
+	p.Run(t, snap)
+	assert.Equal(t, plugin.OperationSettings{Timeout: time.Minute, PollInterval: 30 * time.Second}, settings[urn])
+}
+
+func TestPropertyChangeGuards(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	var props resource.PropertyMap
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", props, nil, false,
+			"", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// No one may turn off deletion protection, and changes to tags are worth a second look.
+	var changes []deploy.PropertyChange
+	guard := func(change deploy.PropertyChange) (deploy.PropertyChangeVerdict, string) {
+		if change.URN.Name() != "resA" {
+			return deploy.PropertyChangeAllowed, ""
+		}
+		changes = append(changes, change)
+		switch {
+		case change.Path.String() == "deletionProtection" && change.Old.IsBool() && change.Old.BoolValue():
+			return deploy.PropertyChangeVetoed, "deletion protection may not be disabled"
+		case change.Path[0] == "tags":
+			return deploy.PropertyChangeFlagged, "tags changed"
+		default:
+			return deploy.PropertyChangeAllowed, ""
+		}
+	}
+
+	diagnostics := func(severity diag.Severity) func(project workspace.Project, target deploy.Target, j *Journal,
+		evts []Event, res result.Result) result.Result {
+
+		return func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			saw := false
+			for _, evt := range evts {
+				if evt.Type == DiagEvent && evt.Payload.(DiagEventPayload).Severity == severity {
+					saw = true
+				}
+			}
+			assert.True(t, saw)
+			return res
+		}
+	}
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, PropertyChangeGuards: []deploy.PropertyChangeGuard{guard}},
+	}
+
+	// Creating the resource reports each of its properties as a change from null.
+	props = resource.PropertyMap{
+		"deletionProtection": resource.NewBoolProperty(true),
+		"tags": resource.NewObjectProperty(resource.PropertyMap{
+			"env": resource.NewStringProperty("prod"),
+		}),
+	}
+	p.Steps = []TestStep{{Op: Update, SkipPreview: true, Validate: diagnostics(diag.Warning)}}
+	snap := p.Run(t, nil)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "deletionProtection", changes[0].Path.String())
+		assert.True(t, changes[0].Old.IsNull())
+		assert.Equal(t, "tags.env", changes[1].Path.String())
+	}
+
+	// Changing only a nested tag reports just that tag.
+	changes = nil
+	props["tags"] = resource.NewObjectProperty(resource.PropertyMap{"env": resource.NewStringProperty("staging")})
+	snap = p.Run(t, snap)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "tags.env", changes[0].Path.String())
+		assert.Equal(t, resource.NewStringProperty("prod"), changes[0].Old)
+		assert.Equal(t, resource.NewStringProperty("staging"), changes[0].New)
+	}
+
+	// Turning off deletion protection is vetoed, in the preview as well as the update.
+	props["deletionProtection"] = resource.NewBoolProperty(false)
+	p.Steps = []TestStep{
+		{Op: Update, ExpectFailure: true, Validate: diagnostics(diag.Error)},
+		{Op: Update, ExpectFailure: true, SkipPreview: true, Validate: diagnostics(diag.Error)},
+	}
+	p.Run(t, snap)
+}
+
+func TestExpectNoChanges(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	size := "small"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"size": resource.NewStringProperty(size)}, nil, false, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+	p.Steps = []TestStep{{Op: Update, SkipPreview: true}}
+	snap := p.Run(t, nil)
+
+	// A plan with no changes succeeds.
+	p.Options.ExpectNoChanges = true
+	p.Steps = []TestStep{{Op: Update}}
+	snap = p.Run(t, snap)
+
+	// A plan with a change fails at that change, before planning the resources that follow it, and reports the
+	// property that would change.
+	size = "large"
+	p.Steps = []TestStep{{
+		Op:            Update,
+		ExpectFailure: true,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			reported := false
+			for _, evt := range evts {
+				switch payload := evt.Payload.(type) {
+				case DiagEventPayload:
+					if payload.Severity == diag.Error && payload.URN.Name() == "resA" {
+						reported = true
+						assert.Contains(t, payload.Message, "size")
+					}
+				case ResourcePreEventPayload:
+					assert.NotEqual(t, tokens.QName("resB"), payload.Metadata.URN.Name())
+				}
+			}
+			assert.True(t, reported)
+			return res
+		},
+	}}
+	p.Run(t, snap)
+}
+
+func TestUpdateResult(t *testing.T) {
+	attempts := 0
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					news resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					if urn.Name() == "resB" {
+						return "", nil, resource.StatusOK, errors.New("AccessDenied")
+					}
+					if attempts++; attempts == 1 {
+						return "", nil, resource.StatusOK, rpcerror.New(codes.Unavailable, "throttled")
+					}
+					return "created-id", news, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		_, _, _, err = monitor.RegisterResource("pkgA:m:typA", "resB", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.Error(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	var updateResult *UpdateResult
+	updateOp := func(info UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
+		var res result.Result
+		updateResult, res = Update(info, ctx, opts, dryRun)
+		return updateResult, res
+	}
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host, Retry: deploy.RetryPolicy{MaxAttempts: 2}},
+		Steps:   []TestStep{{Op: updateOp, ExpectFailure: true, SkipPreview: true}},
+	}
+	p.Run(t, nil)
+	if !assert.NotNil(t, updateResult) {
+		return
+	}
+
+	// The result records the outcome of each reported step, including the retry of resA and the failure of resB.
+	urnA, urnB := p.NewURN("pkgA:m:typA", "resA", ""), p.NewURN("pkgA:m:typA", "resB", "")
+	assert.Equal(t, 1, updateResult.Changes[deploy.OpCreate])
+	if assert.Len(t, updateResult.Resources, 2) {
+		outcomeA, outcomeB := updateResult.Resources[0], updateResult.Resources[1]
+		assert.Equal(t, urnA, outcomeA.URN)
+		assert.Equal(t, deploy.OpCreate, outcomeA.Op)
+		assert.Equal(t, 1, outcomeA.Retries)
+		assert.NoError(t, outcomeA.Error)
+
+		assert.Equal(t, urnB, outcomeB.URN)
+		assert.Equal(t, deploy.OpCreate, outcomeB.Op)
+		assert.Zero(t, outcomeB.Retries)
+		assert.Error(t, outcomeB.Error)
+	}
+	if failed := updateResult.Failed(); assert.Len(t, failed, 1) {
+		assert.Equal(t, urnB, failed[0].URN)
+	}
+}
+
+func TestConfigLayering(t *testing.T) {
+	key := func(name string) config.Key { return config.MustMakeKey("test", name) }
+
+	program := deploytest.NewLanguageRuntime(func(info plugin.RunInfo, _ *deploytest.ResourceMonitor) error {
+		assert.Equal(t, map[config.Key]string{
+			key("base"):     "base",
+			key("env"):      "env",
+			key("stack"):    "stack",
+			key("override"): "override",
+		}, info.Config)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program)
+
+	p := &TestPlan{Config: config.Map{
+		key("stack"):    config.NewValue("stack"),
+		key("override"): config.NewValue("stack"),
+	}}
+	target := p.GetTarget(nil)
+	target.ConfigLayers = []config.Map{
+		{
+			key("base"):  config.NewValue("base"),
+			key("env"):   config.NewValue("base"),
+			key("stack"): config.NewValue("base"),
+		},
+		{
+			key("env"):   config.NewValue("env"),
+			key("stack"): config.NewValue("env"),
+		},
+	}
+	opts := UpdateOptions{
+		host:            host,
+		ConfigOverrides: map[config.Key]string{key("override"): "override"},
+	}
+
+	// The effective configuration is reported in the prelude and recorded in the snapshot.
+	expected := config.Map{
+		key("base"):     config.NewValue("base"),
+		key("env"):      config.NewValue("env"),
+		key("stack"):    config.NewValue("stack"),
+		key("override"): config.NewValue("override"),
+	}
+	snap, res := TestOp(Update).Run(p.GetProject(), target, opts, false, nil,
+		func(_ workspace.Project, _ deploy.Target, _ *Journal, events []Event, res result.Result) result.Result {
+			for _, e := range events {
+				if e.Type == PreludeEvent {
+					assert.Equal(t, map[string]string{
+						"test:base":     "base",
+						"test:env":      "env",
+						"test:stack":    "stack",
+						"test:override": "override",
+					}, e.Payload.(PreludeEventPayload).Config)
+				}
+			}
+			return res
+		})
+	assert.Nil(t, res)
+	assert.Equal(t, expected, snap.Manifest.Config)
+
+	// The target's own configuration is left as it was.
+	assert.Equal(t, p.Config, target.Config)
+}
+
+func TestRelativeAssetPathsOutsideProjectDirectory(t *testing.T) {
+	root, err := ioutil.TempDir("", "pulumi-engine-test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, os.RemoveAll(root)) }()
+	contents := []byte("<h1>hello</h1>")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "index.html"), contents, 0600))
+
+	// The operation must not depend on the process's working directory, which is not the project's.
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NotEqual(t, root, cwd)
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		page := &resource.Asset{Sig: resource.AssetSig, Path: "index.html"}
+		site := &resource.Archive{Sig: resource.ArchiveSig, Assets: map[string]interface{}{
+			"index.html": &resource.Asset{Sig: resource.AssetSig, Path: "index.html"},
+		}}
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{
+				"page": resource.NewAssetProperty(page),
+				"site": resource.NewArchiveProperty(site),
+			}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	events := make(chan Event)
+	go func() {
+		for range events {
+		}
+	}()
+	journal := newJournal()
+	cancelCtx, _ := cancel.NewContext(context.Background())
+	ctx := &Context{Cancel: cancelCtx, Events: events, SnapshotManager: journal}
+	p := &TestPlan{}
+	info := &updateInfo{root: root, project: p.GetProject(), target: p.GetTarget(nil)}
+	_, res := Update(info, ctx, UpdateOptions{host: host}, false)
+	close(events)
+	contract.IgnoreClose(journal)
+	assert.Nil(t, res)
+
+	// The hashes of the relative paths were computed against the project's directory.
+	hash := sha256.Sum256(contents)
+	snap := journal.Snap(nil)
+	for _, res := range snap.Resources {
+		if res.URN.Name() != "resA" {
+			continue
+		}
+		page := res.Inputs["page"]
+		if assert.True(t, page.IsAsset()) {
+			assert.Equal(t, hex.EncodeToString(hash[:]), page.AssetValue().Hash)
+			assert.Equal(t, "index.html", page.AssetValue().Path)
+		}
+		site := res.Inputs["site"]
+		if assert.True(t, site.IsArchive()) {
+			assert.NotEmpty(t, site.ArchiveValue().Hash)
+		}
+	}
+}
+
+// Test that a refresh reads every resource at once, regardless of the dependencies between them, and reports its
+// progress as the reads complete.
+func TestRefreshReadsInParallel(t *testing.T) {
+	const reads = 20
+
+	var m sync.Mutex
+	started := 0
+	all := make(chan struct{})
+	read := func(urn resource.URN, id resource.ID,
+		inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+		m.Lock()
+		if started++; started == reads {
+			close(all)
+		}
+		m.Unlock()
+
+		// Wait for every read to be in flight, which is only possible if the reads ignore the dependency chain.
+		select {
+		case <-all:
+		case <-time.After(10 * time.Second):
+		}
+		return plugin.ReadResult{Inputs: inputs, Outputs: state}, resource.StatusOK, nil
+	}
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{ReadF: read}, nil
+		}),
+		deploytest.NewProviderLoader("pkgB", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{ReadF: read}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		var deps []resource.URN
+		for i := 0; i < reads; i++ {
+			typ := tokens.Type("pkgA:m:typA")
+			if i%2 == 1 {
+				typ = "pkgB:m:typB"
+			}
+			urn, _, _, err := monitor.RegisterResource(typ, fmt.Sprintf("res%d", i), true, "", false, deps, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+			deps = []resource.URN{urn}
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{Parallel: 2, host: host}}
+
+	snap, res := TestOp(Update).Run(p.GetProject(), p.GetTarget(nil), p.Options, false, nil, nil)
+	assert.Nil(t, res)
+
+	var progress []RefreshProgressEventPayload
+	_, res = TestOp(Refresh).Run(p.GetProject(), p.GetTarget(snap), p.Options, false, nil,
+		func(_ workspace.Project, _ deploy.Target, _ *Journal, evts []Event, res result.Result) result.Result {
+			for _, evt := range evts {
+				if evt.Type == RefreshProgressEvent {
+					progress = append(progress, evt.Payload.(RefreshProgressEventPayload))
+				}
+			}
+			return res
+		})
+	assert.Nil(t, res)
+
+	select {
+	case <-all:
+	default:
+		t.Error("the reads were not all in flight at once")
+	}
+	if assert.NotEmpty(t, progress) {
+		assert.Equal(t, RefreshProgressEventPayload{Total: reads}, progress[0])
+		assert.Equal(t, RefreshProgressEventPayload{Completed: reads, Total: reads}, progress[len(progress)-1])
+		assert.True(t, len(progress) <= 11)
+	}
+}
+
+// Test that the summary event profile suppresses the events of unchanged resources in favor of aggregate progress.
+func TestSummaryEvents(t *testing.T) {
+	const resources = 150
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	foo := "bar"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		for i := 0; i < resources; i++ {
+			inputs := resource.PropertyMap{}
+			if i == 0 {
+				inputs["foo"] = resource.NewStringProperty(foo)
+			}
+			_, _, _, err := monitor.RegisterResource("pkgA:m:typA", fmt.Sprintf("res%d", i), true, "", false, nil, "",
+				inputs, nil, false, "", nil, nil)
+			assert.NoError(t, err)
+		}
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+
+	snap, res := TestOp(Update).Run(p.GetProject(), p.GetTarget(nil), p.Options, false, nil, nil)
+	assert.Nil(t, res)
+
+	foo = "baz"
+	p.Options.EventProfile = SummaryEvents
+	for _, dryRun := range []bool{true, false} {
+		var progress []AggregateProgressEventPayload
+		var reported []deploy.StepOp
+		_, res = TestOp(Update).Run(p.GetProject(), p.GetTarget(snap), p.Options, dryRun, nil,
+			func(_ workspace.Project, _ deploy.Target, _ *Journal, evts []Event, res result.Result) result.Result {
+				for _, evt := range evts {
+					switch evt.Type {
+					case ResourcePreEvent:
+						reported = append(reported, evt.Payload.(ResourcePreEventPayload).Metadata.Op)
+					case AggregateProgressEvent:
+						progress = append(progress, evt.Payload.(AggregateProgressEventPayload))
+					}
+				}
+				return res
+			})
+		assert.Nil(t, res)
+
+		// Only the update is reported on its own.
+		assert.Equal(t, []deploy.StepOp{deploy.OpUpdate}, reported)
+
+		// Progress is reported after each batch of steps, and once all of them have completed.
+		if assert.True(t, len(progress) >= 2) {
+			first, last := progress[0], progress[len(progress)-1]
+			assert.False(t, first.Done)
+			assert.True(t, first.Processed <= aggregateProgressSteps)
+			assert.True(t, last.Done)
+			assert.True(t, last.Processed >= resources)
+			assert.Equal(t, len(snap.Resources), last.Total)
+			assert.Equal(t, 1, last.Ops[deploy.OpUpdate])
+			assert.Equal(t, last.Processed-1, last.Ops[deploy.OpSame])
+		}
+	}
+}
+
+// Test that transformations rewrite the goal states of resources before they are planned, and may not change their
+// URNs.
+func TestTransformations(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"name": resource.NewStringProperty("resA")}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	// Inject a tag and prefix the name of each resource of type typA.
+	tag := func(urn resource.URN, goal *resource.Goal) error {
+		if goal.Type != "pkgA:m:typA" {
+			return nil
+		}
+		props := goal.Properties.Copy()
+		props["tags"] = resource.NewObjectProperty(resource.PropertyMap{
+			"team": resource.NewStringProperty("platform"),
+		})
+		goal.Properties = props
+		return nil
+	}
+	prefix := func(urn resource.URN, goal *resource.Goal) error {
+		if name, ok := goal.Properties["name"]; ok && name.IsString() {
+			props := goal.Properties.Copy()
+			props["name"] = resource.NewStringProperty("prod-" + name.StringValue())
+			goal.Properties = props
+		}
+		return nil
+	}
+
+	p := &TestPlan{Options: UpdateOptions{host: host, Transformations: []deploy.ResourceTransformation{tag, prefix}}}
+	snap, res := TestOp(Update).Run(p.GetProject(), p.GetTarget(nil), p.Options, false, nil, nil)
+	assert.Nil(t, res)
+	var found bool
+	for _, r := range snap.Resources {
+		if r.Type == "pkgA:m:typA" {
+			found = true
+			assert.Equal(t, resource.NewStringProperty("prod-resA"), r.Inputs["name"])
+			assert.Equal(t, resource.NewObjectProperty(resource.PropertyMap{
+				"team": resource.NewStringProperty("platform"),
+			}), r.Inputs["tags"])
+		}
+	}
+	assert.True(t, found)
+
+	// A transformation that renames a resource is rejected.
+	rename := func(urn resource.URN, goal *resource.Goal) error {
+		goal.Name = "renamed"
+		return nil
+	}
+	p.Options.Transformations = []deploy.ResourceTransformation{rename}
+	_, res = TestOp(Update).Run(p.GetProject(), p.GetTarget(snap), p.Options, false, nil, nil)
+	assert.NotNil(t, res)
+
+	// So is a resource whose transformation fails.
+	fail := func(urn resource.URN, goal *resource.Goal) error {
+		return errors.New("resources must be tagged")
+	}
+	p.Options.Transformations = []deploy.ResourceTransformation{fail}
+	_, res = TestOp(Update).Run(p.GetProject(), p.GetTarget(snap), p.Options, false, nil, nil)
+	assert.NotNil(t, res)
 }
@@ -0,0 +1,309 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// MetricsContentType is the content type of the metrics written by Metrics: version 0.0.4 of the Prometheus text
+// exposition format.
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// providerCallBuckets are the upper bounds, in seconds, of the buckets of the provider call latency histogram.
+	// Provider operations range from milliseconds for a diff to many minutes for the creation of e.g. a database.
+	providerCallBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 900}
+	// snapshotWriteBuckets are the upper bounds, in seconds, of the buckets of the snapshot write duration histogram.
+	snapshotWriteBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// Metrics collects operational metrics from the engine for services that embed it: the steps that it executes, the
+// latency of the calls it makes to providers, the time it takes to write snapshots, the provider plugins it restarts,
+// and the number of updates in progress. A single Metrics is typically shared by every operation in a process by
+// setting it as the Metrics of each operation's Context; it is safe for concurrent use, and a nil Metrics collects
+// nothing.
+//
+// Metrics is an http.Handler that serves the metrics in the Prometheus text exposition format, so it can be mounted
+// next to promhttp.Handler() (e.g. at /metrics/engine) or scraped on its own.
+type Metrics struct {
+	m              sync.Mutex
+	steps          map[stepMetricKey]uint64             // executed steps, by operation and result.
+	providerCalls  map[providerCallMetricKey]*histogram // provider call latencies, by provider and method.
+	snapshotWrites *histogram                           // snapshot write durations.
+	restarts       map[tokens.Package]uint64            // provider plugin restarts, by provider.
+	activeUpdates  int                                  // updates in progress.
+}
+
+type stepMetricKey struct {
+	op     deploy.StepOp
+	result string
+}
+
+type providerCallMetricKey struct {
+	provider tokens.Package
+	method   string
+}
+
+// NewMetrics creates an empty set of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		steps:          make(map[stepMetricKey]uint64),
+		providerCalls:  make(map[providerCallMetricKey]*histogram),
+		snapshotWrites: newHistogram(snapshotWriteBuckets),
+		restarts:       make(map[tokens.Package]uint64),
+	}
+}
+
+// beginUpdate records the start of an update, returning a function that records its end.
+func (m *Metrics) beginUpdate() func() {
+	if m == nil {
+		return func() {}
+	}
+	m.m.Lock()
+	m.activeUpdates++
+	m.m.Unlock()
+	return func() {
+		m.m.Lock()
+		m.activeUpdates--
+		m.m.Unlock()
+	}
+}
+
+// stepExecuted records the execution of a step with the given operation.
+func (m *Metrics) stepExecuted(op deploy.StepOp, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.m.Lock()
+	m.steps[stepMetricKey{op: op, result: result}]++
+	m.m.Unlock()
+}
+
+// observeProviderCall records the duration of a call to a provider method. It is a plugin.CallObserver.
+func (m *Metrics) observeProviderCall(pkg tokens.Package, method string, duration time.Duration, err error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	key := providerCallMetricKey{provider: pkg, method: method}
+	h, ok := m.providerCalls[key]
+	if !ok {
+		h = newHistogram(providerCallBuckets)
+		m.providerCalls[key] = h
+	}
+	h.observe(duration)
+}
+
+// callObserver returns the function to which a plugin context reports calls to its providers, or nil if m is nil.
+func (m *Metrics) callObserver() plugin.CallObserver {
+	if m == nil {
+		return nil
+	}
+	return m.observeProviderCall
+}
+
+// timeSnapshotWrite calls the given function, which writes a snapshot, and records how long it took.
+func (m *Metrics) timeSnapshotWrite(write func() error) error {
+	if m == nil {
+		return write()
+	}
+	start := time.Now()
+	err := write()
+	m.m.Lock()
+	m.snapshotWrites.observe(time.Since(start))
+	m.m.Unlock()
+	return err
+}
+
+// pluginRestarted records the restart of the plugin for the given provider.
+func (m *Metrics) pluginRestarted(pkg tokens.Package) {
+	if m == nil {
+		return
+	}
+	m.m.Lock()
+	m.restarts[pkg]++
+	m.m.Unlock()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", MetricsContentType)
+	if _, err := m.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format. Metrics and their samples are written in a
+// fixed order, so the same metrics are always written the same way.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	mw := metricsWriter{w: cw}
+
+	mw.header("pulumi_engine_steps_total", "counter", "The number of steps executed by the engine.")
+	steps := make([]stepMetricKey, 0, len(m.steps))
+	for key := range m.steps {
+		steps = append(steps, key)
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		if steps[i].op != steps[j].op {
+			return steps[i].op < steps[j].op
+		}
+		return steps[i].result < steps[j].result
+	})
+	for _, key := range steps {
+		mw.sample("pulumi_engine_steps_total", []string{"op", string(key.op), "result", key.result},
+			float64(m.steps[key]))
+	}
+
+	mw.header("pulumi_engine_provider_call_duration_seconds", "histogram",
+		"The duration of calls to the Check, Diff, Create, Read, Update, and Delete methods of providers.")
+	calls := make([]providerCallMetricKey, 0, len(m.providerCalls))
+	for key := range m.providerCalls {
+		calls = append(calls, key)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].provider != calls[j].provider {
+			return calls[i].provider < calls[j].provider
+		}
+		return calls[i].method < calls[j].method
+	})
+	for _, key := range calls {
+		mw.histogram("pulumi_engine_provider_call_duration_seconds",
+			[]string{"provider", string(key.provider), "method", key.method}, m.providerCalls[key])
+	}
+
+	mw.header("pulumi_engine_snapshot_write_duration_seconds", "histogram",
+		"The duration of writes of the snapshot to the backend.")
+	mw.histogram("pulumi_engine_snapshot_write_duration_seconds", nil, m.snapshotWrites)
+
+	mw.header("pulumi_engine_plugin_restarts_total", "counter",
+		"The number of times a provider plugin exited unexpectedly and was restarted.")
+	restarts := make([]string, 0, len(m.restarts))
+	for pkg := range m.restarts {
+		restarts = append(restarts, string(pkg))
+	}
+	sort.Strings(restarts)
+	for _, pkg := range restarts {
+		mw.sample("pulumi_engine_plugin_restarts_total", []string{"provider", pkg},
+			float64(m.restarts[tokens.Package(pkg)]))
+	}
+
+	mw.header("pulumi_engine_active_updates", "gauge",
+		"The number of updates, refreshes, destroys, and imports in progress.")
+	mw.sample("pulumi_engine_active_updates", nil, float64(m.activeUpdates))
+
+	if mw.err == nil {
+		mw.err = cw.w.Flush()
+	}
+	return cw.n, mw.err
+}
+
+// histogram is a Prometheus histogram of durations, measured in seconds.
+type histogram struct {
+	bounds []float64 // the upper bounds of the buckets, in increasing order.
+	counts []uint64  // the number of observations in each bucket (not cumulative).
+	count  uint64    // the total number of observations.
+	sum    float64   // the sum of the observations.
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	if i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// metricsWriter writes metrics in the Prometheus text exposition format, remembering the first error it encounters.
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (mw *metricsWriter) printf(format string, args ...interface{}) {
+	if mw.err == nil {
+		_, mw.err = fmt.Fprintf(mw.w, format, args...)
+	}
+}
+
+func (mw *metricsWriter) header(name, typ, help string) {
+	mw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (mw *metricsWriter) sample(name string, labels []string, value float64) {
+	mw.printf("%s%s %s\n", name, formatLabels(labels), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (mw *metricsWriter) histogram(name string, labels []string, h *histogram) {
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		le := append(append([]string(nil), labels...), "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		mw.sample(name+"_bucket", le, float64(cumulative))
+	}
+	mw.sample(name+"_bucket", append(append([]string(nil), labels...), "le", "+Inf"), float64(h.count))
+	mw.sample(name+"_sum", labels, h.sum)
+	mw.sample(name+"_count", labels, float64(h.count))
+}
+
+// formatLabels formats the given label name/value pairs as a label set, e.g. {op="create",result="success"}.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"=\""+labelValueEscaper.Replace(labels[i+1])+"\"")
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelValueEscaper escapes label values as the text exposition format requires.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
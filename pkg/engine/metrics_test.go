@@ -0,0 +1,140 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	done := m.beginUpdate()
+	m.beginUpdate()
+	m.stepExecuted(deploy.OpCreate, nil)
+	m.stepExecuted(deploy.OpCreate, nil)
+	m.stepExecuted(deploy.OpDelete, errors.New("oops"))
+	m.callObserver()("aws", "Create", 2*time.Second, nil)
+	m.callObserver()("aws", "Create", 20*time.Millisecond, nil)
+	assert.NoError(t, m.timeSnapshotWrite(func() error { return nil }))
+	m.pluginRestarted("aws")
+	done()
+
+	var buf bytes.Buffer
+	n, err := m.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	text := buf.String()
+
+	assert.Contains(t, text, "# TYPE pulumi_engine_steps_total counter\n")
+	assert.Contains(t, text, `pulumi_engine_steps_total{op="create",result="success"} 2`+"\n")
+	assert.Contains(t, text, `pulumi_engine_steps_total{op="delete",result="failure"} 1`+"\n")
+	assert.Contains(t, text, "# TYPE pulumi_engine_provider_call_duration_seconds histogram\n")
+	assert.Contains(t, text,
+		`pulumi_engine_provider_call_duration_seconds_bucket{provider="aws",method="Create",le="0.01"} 0`+"\n")
+	assert.Contains(t, text,
+		`pulumi_engine_provider_call_duration_seconds_bucket{provider="aws",method="Create",le="0.05"} 1`+"\n")
+	assert.Contains(t, text,
+		`pulumi_engine_provider_call_duration_seconds_bucket{provider="aws",method="Create",le="2.5"} 2`+"\n")
+	assert.Contains(t, text,
+		`pulumi_engine_provider_call_duration_seconds_bucket{provider="aws",method="Create",le="+Inf"} 2`+"\n")
+	assert.Contains(t, text, `pulumi_engine_provider_call_duration_seconds_count{provider="aws",method="Create"} 2`+"\n")
+	assert.Contains(t, text, "pulumi_engine_snapshot_write_duration_seconds_count 1\n")
+	assert.Contains(t, text, `pulumi_engine_plugin_restarts_total{provider="aws"} 1`+"\n")
+	assert.Contains(t, text, "pulumi_engine_active_updates 1\n")
+
+	// The same metrics are always written the same way.
+	var again bytes.Buffer
+	_, err = m.WriteTo(&again)
+	assert.NoError(t, err)
+	assert.Equal(t, text, again.String())
+
+	// Metrics are served over HTTP.
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, MetricsContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, text, rec.Body.String())
+
+	// Label values are escaped.
+	assert.Equal(t, `{provider="a\"b\\c\nd"}`, formatLabels([]string{"provider", "a\"b\\c\nd"}))
+
+	// A nil Metrics collects nothing.
+	var none *Metrics
+	none.beginUpdate()()
+	none.stepExecuted(deploy.OpCreate, nil)
+	none.pluginRestarted("aws")
+	assert.Nil(t, none.callObserver())
+	assert.NoError(t, none.timeSnapshotWrite(func() error { return nil }))
+}
+
+func TestMetricsUpdate(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", nil,
+			nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+
+	cancelCtx, _ := cancel.NewContext(context.Background())
+	events := make(chan Event)
+	go func() {
+		for range events {
+		}
+	}()
+	defer close(events)
+
+	metrics := NewMetrics()
+	journal := newJournal()
+	ctx := &Context{
+		Cancel:          cancelCtx,
+		Events:          events,
+		SnapshotManager: journal,
+		Metrics:         metrics,
+	}
+	_, res := Update(&updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}, ctx, p.Options, false)
+	assert.Nil(t, res)
+	contract.IgnoreClose(journal)
+
+	var buf bytes.Buffer
+	_, err := metrics.WriteTo(&buf)
+	assert.NoError(t, err)
+	text := buf.String()
+
+	// Both the resource and its default provider are created, and each step writes the snapshot.
+	assert.Contains(t, text, `pulumi_engine_steps_total{op="create",result="success"} 2`+"\n")
+	assert.NotContains(t, text, "pulumi_engine_snapshot_write_duration_seconds_count 0\n")
+	assert.Contains(t, text, "pulumi_engine_active_updates 0\n")
+}
@@ -0,0 +1,512 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// OptionError describes a single invalid update option.
+type OptionError struct {
+	Option string // the name of the offending option, e.g. "Parallel" or "CustomTimeouts[aws:s3/bucket:Bucket]".
+	Reason string // why the option's value is invalid.
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Option, e.Reason)
+}
+
+// OptionsError is returned when a set of update options is invalid. It describes every invalid option, not just the
+// first.
+type OptionsError struct {
+	Errors []OptionError
+}
+
+func (e *OptionsError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "invalid update options: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks that the options are consistent and in range, returning an *OptionsError that describes every
+// problem if they are not.
+func (opts UpdateOptions) Validate() error {
+	var errs []OptionError
+	invalid := func(option, format string, args ...interface{}) {
+		errs = append(errs, OptionError{Option: option, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if opts.Parallel < 0 {
+		invalid("Parallel", "%d is negative (use 0 or 1 for serial execution)", opts.Parallel)
+	}
+	for _, pkg := range sortedPackages(opts.ProviderParallel) {
+		if n := opts.ProviderParallel[pkg]; n < 0 {
+			invalid(fmt.Sprintf("ProviderParallel[%s]", pkg), "%d is negative", n)
+		}
+	}
+	if opts.RefreshParallel < 0 {
+		invalid("RefreshParallel", "%d is negative (use 0 for the default)", opts.RefreshParallel)
+	}
+	for i, a := range opts.Analyzers {
+		if !tokens.IsQName(a) {
+			invalid(fmt.Sprintf("Analyzers[%d]", i), "%q is not a valid analyzer name", a)
+		}
+	}
+
+	if opts.Retry.MaxAttempts < 0 {
+		invalid("Retry.MaxAttempts", "%d is negative", opts.Retry.MaxAttempts)
+	}
+	if opts.Retry.InitialBackoff < 0 {
+		invalid("Retry.InitialBackoff", "%v is negative", opts.Retry.InitialBackoff)
+	}
+	if opts.Retry.MaxBackoff > 0 && opts.Retry.MaxBackoff < opts.Retry.InitialBackoff {
+		invalid("Retry.MaxBackoff", "%v is less than the initial backoff of %v",
+			opts.Retry.MaxBackoff, opts.Retry.InitialBackoff)
+	}
+
+	for _, typ := range sortedTimeoutTypes(opts.CustomTimeouts) {
+		option := fmt.Sprintf("CustomTimeouts[%s]", typ)
+		if !isResourceType(typ) {
+			invalid(option, "%q is not a resource type", typ)
+		}
+		if t := opts.CustomTimeouts[typ]; t.Create < 0 || t.Update < 0 || t.Delete < 0 || t.PollInterval < 0 {
+			invalid(option, "timeouts and poll intervals must not be negative")
+		}
+	}
+	for _, typ := range sortedTypes(opts.DeleteBeforeReplace) {
+		if !isResourceType(typ) {
+			invalid(fmt.Sprintf("DeleteBeforeReplace[%s]", typ), "%q is not a resource type", typ)
+		}
+	}
+
+	switch opts.Prune {
+	case deploy.PruneOff:
+		if len(opts.PruneExempt) > 0 {
+			invalid("PruneExempt", "requires a Prune mode")
+		}
+	case deploy.PruneReport, deploy.PruneDelete:
+	default:
+		invalid("Prune", "%q is not a prune mode (use %q or %q)", opts.Prune, deploy.PruneReport, deploy.PruneDelete)
+	}
+
+	for i, pattern := range opts.DestroyExclude {
+		if _, err := resource.ParseURNPattern(pattern); err != nil {
+			invalid(fmt.Sprintf("DestroyExclude[%d]", i), "%v", err)
+		}
+	}
+	if opts.DestroyExcludeDependents && len(opts.DestroyExclude) == 0 {
+		invalid("DestroyExcludeDependents", "requires DestroyExclude")
+	}
+
+	if _, has := opts.DefaultTags[""]; has {
+		invalid("DefaultTags", "tag names must not be empty")
+	}
+
+	if opts.Budget.MaxDuration < 0 {
+		invalid("Budget.MaxDuration", "%v is negative", opts.Budget.MaxDuration)
+	}
+	if opts.Budget.MaxCreates < 0 {
+		invalid("Budget.MaxCreates", "%d is negative", opts.Budget.MaxCreates)
+	}
+
+	if opts.DiagnosticLimits.MaxPerSecond < 0 {
+		invalid("DiagnosticLimits.MaxPerSecond", "%d is negative (use 0 for no limit)", opts.DiagnosticLimits.MaxPerSecond)
+	}
+	filtered := make(map[diag.Source]bool)
+	for i, f := range opts.DiagnosticFilters {
+		option := fmt.Sprintf("DiagnosticFilters[%d]", i)
+		if filtered[f.Source] {
+			invalid(option, "the source %q has more than one filter", f.Source)
+		}
+		filtered[f.Source] = true
+		if f.MinSeverity != "" && severityRank(f.MinSeverity) < 0 {
+			invalid(option, "%q is not a severity (use %q, %q, %q, or %q)", f.MinSeverity,
+				diag.Debug, diag.Info, diag.Warning, diag.Error)
+		}
+	}
+
+	for i, check := range opts.Checks {
+		if err := check.Validate(); err != nil {
+			invalid(fmt.Sprintf("Checks[%d]", i), "%v", err)
+		}
+	}
+
+	for i, hook := range opts.StepHooks {
+		if err := hook.validate(); err != nil {
+			invalid(fmt.Sprintf("StepHooks[%d]", i), "%v", err)
+		}
+	}
+
+	if opts.DeterministicEvents && opts.ConfirmDestructiveSteps {
+		invalid("DeterministicEvents", "conflicts with ConfirmDestructiveSteps, as confirmations cannot wait for the "+
+			"operation to complete")
+	}
+	if opts.DeterministicEvents && opts.ConfirmProviderReplacements {
+		invalid("DeterministicEvents", "conflicts with ConfirmProviderReplacements, as confirmations cannot wait for "+
+			"the operation to complete")
+	}
+
+	switch opts.EventProfile {
+	case FullEvents, SummaryEvents:
+	default:
+		invalid("EventProfile", "%q is not an event profile (leave it empty for every event, or use %q)",
+			opts.EventProfile, SummaryEvents)
+	}
+
+	if opts.RefreshPlanCache && opts.PlanCache == nil {
+		invalid("RefreshPlanCache", "requires a PlanCache")
+	}
+	if opts.RefreshPlanCache && opts.Refresh {
+		invalid("RefreshPlanCache", "conflicts with Refresh, as previews that refresh are never cached")
+	}
+
+	if len(errs) > 0 {
+		return &OptionsError{Errors: errs}
+	}
+	return nil
+}
+
+func sortedPackages(m map[tokens.Package]int) []tokens.Package {
+	pkgs := make([]tokens.Package, 0, len(m))
+	for pkg := range m {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i] < pkgs[j] })
+	return pkgs
+}
+
+func sortedTimeoutTypes(m map[tokens.Type]resource.CustomTimeouts) []tokens.Type {
+	types := make([]tokens.Type, 0, len(m))
+	for typ := range m {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func sortedTypes(m map[tokens.Type]bool) []tokens.Type {
+	types := make([]tokens.Type, 0, len(m))
+	for typ := range m {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// isResourceType returns true if the given type is fully qualified, i.e. of the form "package:module:type".
+func isResourceType(typ tokens.Type) bool {
+	tok := tokens.Token(typ)
+	if tok.Delimiters() != 2 {
+		return false
+	}
+	for _, part := range strings.Split(string(tok), tokens.TokenDelimiter) {
+		if part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateOptionsBuilder assembles UpdateOptions, which are validated as a whole when they are built. Each method
+// returns the builder so that calls may be chained, e.g. NewUpdateOptionsBuilder().Parallel(10).Build().
+type UpdateOptionsBuilder struct {
+	opts UpdateOptions
+}
+
+// NewUpdateOptionsBuilder returns a builder whose options are initially the defaults.
+func NewUpdateOptionsBuilder() *UpdateOptionsBuilder {
+	return &UpdateOptionsBuilder{}
+}
+
+// Analyzers adds analyzers to run as part of the update.
+func (b *UpdateOptionsBuilder) Analyzers(analyzers ...string) *UpdateOptionsBuilder {
+	b.opts.Analyzers = append(b.opts.Analyzers, analyzers...)
+	return b
+}
+
+// Parallel sets the degree of parallelism for resource operations (0 or 1 for serial).
+func (b *UpdateOptionsBuilder) Parallel(parallel int) *UpdateOptionsBuilder {
+	b.opts.Parallel = parallel
+	return b
+}
+
+// ProviderParallel sets the degree of parallelism for the operations of the given package's provider.
+func (b *UpdateOptionsBuilder) ProviderParallel(pkg tokens.Package, parallel int) *UpdateOptionsBuilder {
+	if b.opts.ProviderParallel == nil {
+		b.opts.ProviderParallel = make(map[tokens.Package]int)
+	}
+	b.opts.ProviderParallel[pkg] = parallel
+	return b
+}
+
+// RefreshParallel sets the degree of parallelism for the reads of a refresh (0 for the default).
+func (b *UpdateOptionsBuilder) RefreshParallel(parallel int) *UpdateOptionsBuilder {
+	b.opts.RefreshParallel = parallel
+	return b
+}
+
+// Debug enables debugging output.
+func (b *UpdateOptionsBuilder) Debug(debug bool) *UpdateOptionsBuilder {
+	b.opts.Debug = debug
+	return b
+}
+
+// Refresh causes the update to refresh the stack's resources before it begins.
+func (b *UpdateOptionsBuilder) Refresh(refresh bool) *UpdateOptionsBuilder {
+	b.opts.Refresh = refresh
+	return b
+}
+
+// ConfigOverrides overrides the values of the given configuration keys for this update only. The program and default
+// providers see the values as if they were part of the stack's configuration, but they are never saved to the stack.
+func (b *UpdateOptionsBuilder) ConfigOverrides(overrides map[config.Key]string) *UpdateOptionsBuilder {
+	if b.opts.ConfigOverrides == nil && len(overrides) > 0 {
+		b.opts.ConfigOverrides = make(map[config.Key]string)
+	}
+	for k, v := range overrides {
+		b.opts.ConfigOverrides[k] = v
+	}
+	return b
+}
+
+// Retry sets the policy for retrying steps that fail with transient provider errors.
+func (b *UpdateOptionsBuilder) Retry(policy deploy.RetryPolicy) *UpdateOptionsBuilder {
+	b.opts.Retry = policy
+	return b
+}
+
+// AllowProtected permits the update to delete or replace protected resources.
+func (b *UpdateOptionsBuilder) AllowProtected(allow bool) *UpdateOptionsBuilder {
+	b.opts.AllowProtected = allow
+	return b
+}
+
+// CustomTimeouts sets the time providers may take to create, update, or delete resources of the given type.
+func (b *UpdateOptionsBuilder) CustomTimeouts(typ tokens.Type, timeouts resource.CustomTimeouts) *UpdateOptionsBuilder {
+	if b.opts.CustomTimeouts == nil {
+		b.opts.CustomTimeouts = make(map[tokens.Type]resource.CustomTimeouts)
+	}
+	b.opts.CustomTimeouts[typ] = timeouts
+	return b
+}
+
+// DeleteBeforeReplace requires resources of the given types to be deleted before their replacements are created.
+func (b *UpdateOptionsBuilder) DeleteBeforeReplace(types ...tokens.Type) *UpdateOptionsBuilder {
+	if b.opts.DeleteBeforeReplace == nil {
+		b.opts.DeleteBeforeReplace = make(map[tokens.Type]bool)
+	}
+	for _, typ := range types {
+		b.opts.DeleteBeforeReplace[typ] = true
+	}
+	return b
+}
+
+// Prune reports resources that are no longer produced by the program, deleting them if the mode is
+// deploy.PruneDelete, except for those whose URNs or types are exempt.
+func (b *UpdateOptionsBuilder) Prune(mode deploy.PruneMode, exempt ...string) *UpdateOptionsBuilder {
+	b.opts.Prune = mode
+	b.opts.PruneExempt = append(b.opts.PruneExempt, exempt...)
+	return b
+}
+
+// DestroyExclude leaves the resources that match the given URN patterns in place during a destroy, along with the
+// resources upon which they depend, and, if dependents is set, the resources that depend upon them.
+func (b *UpdateOptionsBuilder) DestroyExclude(dependents bool, patterns ...string) *UpdateOptionsBuilder {
+	b.opts.DestroyExclude = append(b.opts.DestroyExclude, patterns...)
+	b.opts.DestroyExcludeDependents = dependents
+	return b
+}
+
+// ReportDeletionOrder reports the order in which the operation deletes resources before the deletes begin.
+func (b *UpdateOptionsBuilder) ReportDeletionOrder(report bool) *UpdateOptionsBuilder {
+	b.opts.ReportDeletionOrder = report
+	return b
+}
+
+// DefaultTags applies the given tags to every resource whose provider supports default tags.
+func (b *UpdateOptionsBuilder) DefaultTags(tags map[string]string) *UpdateOptionsBuilder {
+	for k, v := range tags {
+		if b.opts.DefaultTags == nil {
+			b.opts.DefaultTags = make(map[string]string)
+		}
+		b.opts.DefaultTags[k] = v
+	}
+	return b
+}
+
+// Budget sets soft limits on the duration and size of the update.
+func (b *UpdateOptionsBuilder) Budget(budget UpdateBudget) *UpdateOptionsBuilder {
+	b.opts.Budget = budget
+	return b
+}
+
+// CollectPendingDeletes garbage collects the resources left pending deletion by earlier updates before the update is
+// planned.
+func (b *UpdateOptionsBuilder) CollectPendingDeletes(collect bool) *UpdateOptionsBuilder {
+	b.opts.CollectPendingDeletes = collect
+	return b
+}
+
+// ConfirmDestructiveSteps requires each delete or replacement to be confirmed before it is applied.
+func (b *UpdateOptionsBuilder) ConfirmDestructiveSteps(confirm bool) *UpdateOptionsBuilder {
+	b.opts.ConfirmDestructiveSteps = confirm
+	return b
+}
+
+// ConfirmProviderReplacements requires each replacement of a provider to be confirmed before it is applied.
+func (b *UpdateOptionsBuilder) ConfirmProviderReplacements(confirm bool) *UpdateOptionsBuilder {
+	b.opts.ConfirmProviderReplacements = confirm
+	return b
+}
+
+// EventProfile sets the profile that determines which events the operation emits.
+func (b *UpdateOptionsBuilder) EventProfile(profile EventProfile) *UpdateOptionsBuilder {
+	b.opts.EventProfile = profile
+	return b
+}
+
+// PropertyChangeGuards adds guards that inspect each planned change to a resource's input properties, and may flag
+// or veto it.
+func (b *UpdateOptionsBuilder) PropertyChangeGuards(guards ...deploy.PropertyChangeGuard) *UpdateOptionsBuilder {
+	b.opts.PropertyChangeGuards = append(b.opts.PropertyChangeGuards, guards...)
+	return b
+}
+
+// Transformations appends transformations to the pipeline that rewrites the goal state of each resource the program
+// registers before it is planned.
+func (b *UpdateOptionsBuilder) Transformations(transformations ...deploy.ResourceTransformation) *UpdateOptionsBuilder {
+	b.opts.Transformations = append(b.opts.Transformations, transformations...)
+	return b
+}
+
+// PlanEstimators adds estimators that estimate the impact of the plan computed by each preview.
+func (b *UpdateOptionsBuilder) PlanEstimators(estimators ...PlanEstimator) *UpdateOptionsBuilder {
+	b.opts.PlanEstimators = append(b.opts.PlanEstimators, estimators...)
+	return b
+}
+
+// Checks adds checks to run after the update is applied, in addition to those of the project.
+func (b *UpdateOptionsBuilder) Checks(checks ...workspace.ProjectCheck) *UpdateOptionsBuilder {
+	b.opts.Checks = append(b.opts.Checks, checks...)
+	return b
+}
+
+// SkipChecks disables the checks that would otherwise run after the update is applied.
+func (b *UpdateOptionsBuilder) SkipChecks(skip bool) *UpdateOptionsBuilder {
+	b.opts.SkipChecks = skip
+	return b
+}
+
+// PlanCache sets the cache from which previews of unchanged programs are served.
+func (b *UpdateOptionsBuilder) PlanCache(cache PlanCache) *UpdateOptionsBuilder {
+	b.opts.PlanCache = cache
+	return b
+}
+
+// RefreshPlanCache causes previews to compute a fresh plan even if a cached one is available.
+func (b *UpdateOptionsBuilder) RefreshPlanCache(refresh bool) *UpdateOptionsBuilder {
+	b.opts.RefreshPlanCache = refresh
+	return b
+}
+
+// ValidateSnapshot causes the stored inputs of the resources in the snapshot to be checked by their providers before
+// the update begins.
+func (b *UpdateOptionsBuilder) ValidateSnapshot(validate bool) *UpdateOptionsBuilder {
+	b.opts.ValidateSnapshot = validate
+	return b
+}
+
+// ValidatePreview causes previews to ask providers to validate each planned create and update without performing it.
+func (b *UpdateOptionsBuilder) ValidatePreview(validate bool) *UpdateOptionsBuilder {
+	b.opts.ValidatePreview = validate
+	return b
+}
+
+// ExpectNoChanges causes plans to fail as soon as they generate a step that changes a resource.
+func (b *UpdateOptionsBuilder) ExpectNoChanges(expect bool) *UpdateOptionsBuilder {
+	b.opts.ExpectNoChanges = expect
+	return b
+}
+
+// RecommendedVersion sets the oldest version of the CLI that the backend recommends.
+func (b *UpdateOptionsBuilder) RecommendedVersion(version string) *UpdateOptionsBuilder {
+	b.opts.RecommendedVersion = version
+	return b
+}
+
+// VerifySnapshot causes the referential integrity of the snapshot that the update leaves behind to be verified once
+// it completes.
+func (b *UpdateOptionsBuilder) VerifySnapshot(verify bool) *UpdateOptionsBuilder {
+	b.opts.VerifySnapshot = verify
+	return b
+}
+
+// DiagnosticLimits sets the limits on the warnings that the update reports.
+func (b *UpdateOptionsBuilder) DiagnosticLimits(limits DiagnosticLimits) *UpdateOptionsBuilder {
+	b.opts.DiagnosticLimits = limits
+	return b
+}
+
+// DiagnosticFilters adds filters that set the least severe diagnostics that the update reports from particular
+// sources.
+func (b *UpdateOptionsBuilder) DiagnosticFilters(filters ...DiagnosticFilter) *UpdateOptionsBuilder {
+	b.opts.DiagnosticFilters = append(b.opts.DiagnosticFilters, filters...)
+	return b
+}
+
+// CheckCache sets the cache of the checks made by previews.
+func (b *UpdateOptionsBuilder) CheckCache(cache *deploy.CheckCache) *UpdateOptionsBuilder {
+	b.opts.CheckCache = cache
+	return b
+}
+
+// AssetCache sets the cache of the hashes and translated contents of the update's assets and archives.
+func (b *UpdateOptionsBuilder) AssetCache(cache *resource.AssetCache) *UpdateOptionsBuilder {
+	b.opts.AssetCache = cache
+	return b
+}
+
+// PluginPool sets the pool from which the update's plugin host is taken.
+func (b *UpdateOptionsBuilder) PluginPool(pool *plugin.HostPool) *UpdateOptionsBuilder {
+	b.opts.PluginPool = pool
+	return b
+}
+
+// StepHooks adds webhooks or local commands that are invoked before and after each step that changes a resource.
+func (b *UpdateOptionsBuilder) StepHooks(hooks ...StepHook) *UpdateOptionsBuilder {
+	b.opts.StepHooks = append(b.opts.StepHooks, hooks...)
+	return b
+}
+
+// Build validates and returns the assembled options. If they are invalid, the returned error is an *OptionsError
+// describing every problem.
+func (b *UpdateOptionsBuilder) Build() (UpdateOptions, error) {
+	if err := b.opts.Validate(); err != nil {
+		return UpdateOptions{}, err
+	}
+	return b.opts, nil
+}
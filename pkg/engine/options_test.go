@@ -0,0 +1,89 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestBuildValidOptions(t *testing.T) {
+	opts, err := NewUpdateOptionsBuilder().
+		Parallel(10).
+		ProviderParallel("aws", 2).
+		Analyzers("policy").
+		CustomTimeouts("aws:s3/bucket:Bucket", resource.CustomTimeouts{Create: 60}).
+		DeleteBeforeReplace("aws:s3/bucket:Bucket").
+		Retry(deploy.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Minute}).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, opts.Parallel)
+	assert.Equal(t, 2, opts.ProviderParallel["aws"])
+	assert.Equal(t, []string{"policy"}, opts.Analyzers)
+	assert.True(t, opts.DeleteBeforeReplace["aws:s3/bucket:Bucket"])
+
+	// The zero value is valid.
+	assert.NoError(t, UpdateOptions{}.Validate())
+}
+
+func TestBuildInvalidOptions(t *testing.T) {
+	_, err := NewUpdateOptionsBuilder().
+		Parallel(-1).
+		ProviderParallel("aws", -2).
+		CustomTimeouts("Bucket", resource.CustomTimeouts{Delete: -1}).
+		DeleteBeforeReplace("aws::Bucket").
+		DestroyExclude(false, "urn:bad").
+		DefaultTags(map[string]string{"": "1234"}).
+		DiagnosticFilters(
+			DiagnosticFilter{Source: "provider:aws", MinSeverity: "verbose"},
+			DiagnosticFilter{Source: "provider:aws", MinSeverity: diag.Warning}).
+		Retry(deploy.RetryPolicy{InitialBackoff: time.Minute, MaxBackoff: time.Second}).
+		EventProfile("quiet").
+		RefreshPlanCache(true).
+		Refresh(true).
+		Build()
+	assert.Error(t, err)
+
+	optsErr, ok := err.(*OptionsError)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	var options []string
+	for _, e := range optsErr.Errors {
+		options = append(options, e.Option)
+	}
+	assert.Equal(t, []string{
+		"Parallel",
+		"ProviderParallel[aws]",
+		"Retry.MaxBackoff",
+		"CustomTimeouts[Bucket]",
+		"CustomTimeouts[Bucket]",
+		"DeleteBeforeReplace[aws::Bucket]",
+		"DestroyExclude[0]",
+		"DefaultTags",
+		"DiagnosticFilters[0]",
+		"DiagnosticFilters[1]",
+		"EventProfile",
+		"RefreshPlanCache",
+		"RefreshPlanCache",
+	}, options)
+}
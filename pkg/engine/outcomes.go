@@ -0,0 +1,238 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+// ResourceOutcome is the outcome of a single step of an update.
+type ResourceOutcome struct {
+	URN      resource.URN  // the resource the step operated upon.
+	Op       deploy.StepOp // the operation the step performed.
+	Duration time.Duration // the time taken to apply the step, including any retries.
+	Retries  int           // the number of times the step was retried after failing with a transient error.
+	Error    error         // the error that failed the step, if any.
+}
+
+// PolicyViolation is a violation of a policy that was reported while an update ran.
+type PolicyViolation struct {
+	URN resource.URN // the resource that violated the policy.
+	plugin.AnalyzeDiagnostic
+}
+
+// UpdateResult is the result of an update, refresh, destroy, or import. It holds everything that would otherwise have
+// to be reconstructed from the event stream by callers that drive the engine programmatically.
+type UpdateResult struct {
+	Changes ResourceChanges // the aggregate resource changes, by operation type.
+
+	// ChangesDetected is true if the update changed, or for a dry run would change, any resources. Steps that leave a
+	// resource as it is do not count, so this is what `--expect-no-changes` checks.
+	ChangesDetected bool
+
+	// Resources holds the outcome of each step, in the order in which the steps completed. Steps that are not
+	// reported, such as those of default providers, are omitted. For a dry run, the outcomes are those of the steps
+	// that would be performed.
+	Resources []ResourceOutcome
+
+	// Outputs holds the outputs of the stack once the update is complete. It is nil for a dry run, or if the stack
+	// resource was deleted.
+	Outputs resource.PropertyMap
+
+	// Plan holds the steps that a dry run of an update would perform. It is nil for any other operation.
+	Plan *Plan
+
+	// PolicyViolations holds the policy violations that were reported, in the order in which they were reported.
+	PolicyViolations []PolicyViolation
+
+	// Checks holds the results of the checks that ran after the update was applied, in the order in which they ran.
+	// Checks run only after updates that succeed, and failing checks do not fail the update.
+	Checks []CheckResult
+
+	// Deprecations counts the uses of resource types and input properties that their providers have deprecated, as
+	// reported in the update's summary.
+	Deprecations []deploy.DeprecatedUsage
+
+	// Estimates holds the estimates of the impact of a dry run's plan made by the update's PlanEstimators, as reported
+	// in its summary. It is nil for any other operation.
+	Estimates []PlanEstimate
+
+	// SnapshotVersion is the number of snapshots of the stack's state that the update had persisted when it returned,
+	// if its context's SnapshotManager counts them; it is zero otherwise, and for a dry run.
+	SnapshotVersion int
+}
+
+// newUpdateResult returns the result of an update that made the given changes and recorded the given outcomes.
+func newUpdateResult(changes ResourceChanges, outcomes *outcomeRecorder) *UpdateResult {
+	return &UpdateResult{
+		Changes:          changes,
+		ChangesDetected:  changes.HasChanges(),
+		Resources:        outcomes.list(),
+		PolicyViolations: outcomes.policyViolations(),
+	}
+}
+
+// Failed returns the outcomes of the steps that failed.
+func (r *UpdateResult) Failed() []ResourceOutcome {
+	if r == nil {
+		return nil
+	}
+	var failed []ResourceOutcome
+	for _, outcome := range r.Resources {
+		if outcome.Error != nil {
+			failed = append(failed, outcome)
+		}
+	}
+	return failed
+}
+
+// outcomeRecorder records the outcomes of the steps of an update as they complete. It is safe for concurrent use.
+type outcomeRecorder struct {
+	lock     sync.Mutex
+	starts   map[deploy.Step]time.Time
+	retries  map[deploy.Step]int
+	outcomes []ResourceOutcome
+
+	violations []PolicyViolation
+
+	writes       int                       // the number of checkpoint writes in progress.
+	cancellation *CancellationEventPayload // what the update was doing when it was cancelled, if it was.
+	inFlight     map[deploy.Step]bool      // the steps that were executing when the update was cancelled.
+}
+
+func newOutcomeRecorder() *outcomeRecorder {
+	return &outcomeRecorder{
+		starts:  make(map[deploy.Step]time.Time),
+		retries: make(map[deploy.Step]int),
+	}
+}
+
+// begin records that the given step is about to be applied.
+func (r *outcomeRecorder) begin(step deploy.Step) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.starts[step] = time.Now()
+}
+
+// retry records that the given step is being retried.
+func (r *outcomeRecorder) retry(step deploy.Step) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.retries[step]++
+}
+
+// end records the outcome of the given step, which performed the given operation.
+func (r *outcomeRecorder) end(step deploy.Step, op deploy.StepOp, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	outcome := ResourceOutcome{URN: step.URN(), Op: op, Retries: r.retries[step], Error: err}
+	if start, has := r.starts[step]; has {
+		outcome.Duration = time.Since(start)
+	}
+	delete(r.starts, step)
+	delete(r.retries, step)
+	r.outcomes = append(r.outcomes, outcome)
+
+	if r.inFlight[step] && err == nil {
+		r.cancellation.Completed = append(r.cancellation.Completed, step.URN())
+	}
+}
+
+// persist records that a checkpoint is being written while the given function runs, and returns its error.
+func (r *outcomeRecorder) persist(write func() error) error {
+	r.lock.Lock()
+	r.writes++
+	r.lock.Unlock()
+
+	defer func() {
+		r.lock.Lock()
+		r.writes--
+		r.lock.Unlock()
+	}()
+	return write()
+}
+
+// cancel records that the update was cancelled, noting what it was doing at the time. Steps that are executing at the
+// time of cancellation and that go on to succeed are recorded as having completed after it.
+func (r *outcomeRecorder) cancel(preview bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.cancellation != nil {
+		return
+	}
+
+	// A preview never changes anything, so it is always planning. Otherwise, a checkpoint write in progress means
+	// that the update is persisting the result of a step, and a step in progress that it is executing one; if neither,
+	// the update is waiting on the program to register its next resource.
+	phase := CancellationPlanning
+	switch {
+	case preview:
+	case r.writes > 0:
+		phase = CancellationPersisting
+	case len(r.starts) > 0:
+		phase = CancellationExecuting
+	}
+
+	r.cancellation = &CancellationEventPayload{Phase: phase}
+	r.inFlight = make(map[deploy.Step]bool)
+	for step := range r.starts {
+		r.inFlight[step] = true
+		r.cancellation.InFlight = append(r.cancellation.InFlight, step.URN())
+	}
+	sort.Slice(r.cancellation.InFlight, func(i, j int) bool {
+		return r.cancellation.InFlight[i] < r.cancellation.InFlight[j]
+	})
+}
+
+// cancelled returns what the update was doing when it was cancelled, and false if it was not cancelled.
+func (r *outcomeRecorder) cancelled() (CancellationEventPayload, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.cancellation == nil {
+		return CancellationEventPayload{}, false
+	}
+	payload := *r.cancellation
+	payload.InFlight = append([]resource.URN(nil), payload.InFlight...)
+	payload.Completed = append([]resource.URN(nil), payload.Completed...)
+	return payload, true
+}
+
+// list returns the outcomes recorded so far.
+func (r *outcomeRecorder) list() []ResourceOutcome {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]ResourceOutcome(nil), r.outcomes...)
+}
+
+// violation records that the given resource violated a policy.
+func (r *outcomeRecorder) violation(urn resource.URN, d plugin.AnalyzeDiagnostic) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.violations = append(r.violations, PolicyViolation{URN: urn, AnalyzeDiagnostic: d})
+}
+
+// policyViolations returns the policy violations recorded so far.
+func (r *outcomeRecorder) policyViolations() []PolicyViolation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]PolicyViolation(nil), r.violations...)
+}
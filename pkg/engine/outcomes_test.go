@@ -0,0 +1,96 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestNewUpdateResult(t *testing.T) {
+	outcomes := newOutcomeRecorder()
+
+	// Steps that leave resources as they are are not changes.
+	result := newUpdateResult(ResourceChanges{deploy.OpSame: 3}, outcomes)
+	assert.False(t, result.ChangesDetected)
+	assert.Empty(t, result.PolicyViolations)
+
+	urn := resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA")
+	d := plugin.AnalyzeDiagnostic{
+		PolicyName:       "no-public-buckets",
+		PolicyPackName:   "security",
+		Message:          "buckets must not be public",
+		EnforcementLevel: apitype.Mandatory,
+	}
+	outcomes.violation(urn, d)
+
+	result = newUpdateResult(ResourceChanges{deploy.OpSame: 3, deploy.OpUpdate: 1}, outcomes)
+	assert.True(t, result.ChangesDetected)
+	assert.Equal(t, []PolicyViolation{{URN: urn, AnalyzeDiagnostic: d}}, result.PolicyViolations)
+}
+
+func TestOutcomeRecorderCancellation(t *testing.T) {
+	newStep := func(name string) deploy.Step {
+		state := &resource.State{URN: resource.URN("urn:pulumi:test::test::pkgA:m:typA::" + name)}
+		return deploy.NewSameStep(nil, nil, state, &resource.State{URN: state.URN})
+	}
+
+	// A preview is always planning.
+	outcomes := newOutcomeRecorder()
+	outcomes.begin(newStep("a"))
+	outcomes.cancel(true /*preview*/)
+	cancellation, ok := outcomes.cancelled()
+	assert.True(t, ok)
+	assert.Equal(t, CancellationPlanning, cancellation.Phase)
+
+	// An update with no steps in progress is planning too.
+	outcomes = newOutcomeRecorder()
+	_, ok = outcomes.cancelled()
+	assert.False(t, ok)
+	outcomes.cancel(false /*preview*/)
+	cancellation, _ = outcomes.cancelled()
+	assert.Equal(t, CancellationPlanning, cancellation.Phase)
+
+	// Steps in progress are in flight, and are completed if they go on to succeed.
+	outcomes = newOutcomeRecorder()
+	a, b, c := newStep("a"), newStep("b"), newStep("c")
+	outcomes.begin(a)
+	outcomes.begin(b)
+	outcomes.cancel(false /*preview*/)
+	outcomes.end(a, deploy.OpSame, nil)
+	outcomes.end(b, deploy.OpSame, errors.New("failed"))
+	outcomes.end(c, deploy.OpSame, nil)
+	cancellation, _ = outcomes.cancelled()
+	assert.Equal(t, CancellationExecuting, cancellation.Phase)
+	assert.Equal(t, []resource.URN{a.URN(), b.URN()}, cancellation.InFlight)
+	assert.Equal(t, []resource.URN{a.URN()}, cancellation.Completed)
+
+	// A checkpoint write in progress is persisting.
+	outcomes = newOutcomeRecorder()
+	err := outcomes.persist(func() error {
+		outcomes.cancel(false /*preview*/)
+		return nil
+	})
+	assert.NoError(t, err)
+	cancellation, _ = outcomes.cancelled()
+	assert.Equal(t, CancellationPersisting, cancellation.Phase)
+}
@@ -0,0 +1,335 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+)
+
+// PendingDeleteAction describes what a garbage collection pass did about a pending delete.
+type PendingDeleteAction string
+
+const (
+	// PendingDeleteDeleted indicates that the resource was deleted and removed from the snapshot.
+	PendingDeleteDeleted PendingDeleteAction = "deleted"
+	// PendingDeleteRetry indicates that the resource could not be deleted yet, because its deletion failed with a
+	// transient error or because resources that depend on it remain. It is still pending, and the next pass retries it.
+	PendingDeleteRetry PendingDeleteAction = "retry"
+	// PendingDeleteManual indicates that the resource cannot be deleted without manual intervention: e.g. it is
+	// protected, its provider could not be loaded, or its deletion failed with an error that is not transient. It is
+	// still pending.
+	PendingDeleteManual PendingDeleteAction = "manual"
+)
+
+// DefaultPendingDeleteRetryPolicy is the policy with which an update retries the deletions of its pending deletes
+// when UpdateOptions.Retry does not set a number of attempts.
+var DefaultPendingDeleteRetryPolicy = deploy.RetryPolicy{MaxAttempts: 3, InitialBackoff: 5 * time.Second}
+
+// pendingDeleteProviders looks up the providers of pending deletes; both provider registries and plans do.
+type pendingDeleteProviders interface {
+	GetProvider(ref providers.Reference) (plugin.Provider, bool)
+}
+
+// PendingDeleteEntry reports what a garbage collection pass did about a single pending delete.
+type PendingDeleteEntry struct {
+	URN      resource.URN        // the resource that was pending deletion.
+	ID       resource.ID         // the resource's ID.
+	Action   PendingDeleteAction // what was done about it.
+	Attempts int                 // the number of times the pass attempted to delete the resource.
+	Message  string              // a human-readable explanation of the action.
+}
+
+// ListPendingDeletes returns the resources in the given snapshot that are pending deletion, i.e. that were replaced
+// but whose deletion has not yet succeeded, in the order in which they appear in the snapshot.
+func ListPendingDeletes(snap *deploy.Snapshot) []*resource.State {
+	if snap == nil {
+		return nil
+	}
+	var pending []*resource.State
+	for _, res := range snap.Resources {
+		if res.Delete {
+			pending = append(pending, res)
+		}
+	}
+	return pending
+}
+
+// CollectPendingDeletes runs a garbage collection pass over the resources in the given snapshot that are pending
+// deletion: create-before-delete replacements whose replacements were created, but whose own deletion failed. Each is
+// deleted by its provider, in the reverse of the snapshot's order so that dependents are deleted before the resources
+// upon which they depend, and removed from the snapshot, which is modified in place. Deletions that fail with
+// transient errors are retried with backoff according to the given policy; closing cancel abandons the retries.
+//
+// Resources that cannot be deleted remain pending. Those that the next pass may be able to delete are reported with
+// PendingDeleteRetry, and those that need manual intervention with PendingDeleteManual. A PendingDeleteEvent is sent on
+// the given channel, if it is not nil, for each pending delete. The returned entries describe what was done for each.
+//
+// Only the providers of the pending deletes are loaded.
+func CollectPendingDeletes(host plugin.Host, snap *deploy.Snapshot, policy deploy.RetryPolicy, cancel <-chan struct{},
+	events chan<- Event) ([]PendingDeleteEntry, error) {
+
+	contract.Require(host != nil, "host")
+	contract.Require(snap != nil, "snap")
+
+	if len(ListPendingDeletes(snap)) == 0 {
+		return nil, nil
+	}
+
+	reg, err := providers.NewRegistry(host, pendingDeleteProviderStates(snap), false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading providers")
+	}
+	return collectPendingDeletes(reg, snap, policy, cancel, events), nil
+}
+
+// collectPendingDeletes runs a garbage collection pass with the given providers; see CollectPendingDeletes.
+func collectPendingDeletes(provs pendingDeleteProviders, snap *deploy.Snapshot, policy deploy.RetryPolicy,
+	cancel <-chan struct{}, events chan<- Event) []PendingDeleteEntry {
+
+	var report []PendingDeleteEntry
+	deleted := make(map[*resource.State]bool)
+	for i := len(snap.Resources) - 1; i >= 0; i-- {
+		res := snap.Resources[i]
+		if !res.Delete {
+			continue
+		}
+		entry := collectPendingDelete(provs, snap, res, deleted, policy, cancel)
+		if entry.Action == PendingDeleteDeleted {
+			deleted[res] = true
+		}
+		logging.V(7).Infof("CollectPendingDeletes: %s (%s): %s", entry.URN, entry.Action, entry.Message)
+		report = append(report, entry)
+		sendPendingDeleteEvent(events, entry)
+	}
+
+	remaining := snap.Resources[:0]
+	for _, res := range snap.Resources {
+		if !deleted[res] {
+			remaining = append(remaining, res)
+		}
+	}
+	snap.Resources = remaining
+	return report
+}
+
+// pendingDeleteProviderStates returns the providers in the given snapshot that the pending deletes refer to.
+func pendingDeleteProviderStates(snap *deploy.Snapshot) []*resource.State {
+	refs := make(map[string]bool)
+	for _, res := range ListPendingDeletes(snap) {
+		if res.Custom && res.Provider != "" {
+			refs[res.Provider] = true
+		}
+	}
+
+	var provs []*resource.State
+	for _, res := range snap.Resources {
+		if !providers.IsProviderType(res.Type) || res.Delete {
+			continue
+		}
+		if ref, err := providers.NewReference(res.URN, res.ID); err == nil && refs[ref.String()] {
+			provs = append(provs, res)
+		}
+	}
+	return provs
+}
+
+// collectPendingDelete attempts to delete the given pending delete, unless a resource that remains in the snapshot
+// depends on it. The resources that the pass has already deleted are given.
+func collectPendingDelete(provs pendingDeleteProviders, snap *deploy.Snapshot, res *resource.State,
+	deleted map[*resource.State]bool, policy deploy.RetryPolicy, cancel <-chan struct{}) PendingDeleteEntry {
+
+	entry := PendingDeleteEntry{URN: res.URN, ID: res.ID}
+	result := func(action PendingDeleteAction, msg string) PendingDeleteEntry {
+		entry.Action, entry.Message = action, msg
+		return entry
+	}
+
+	if dependent := pendingDeleteDependent(snap, res, deleted); dependent != nil {
+		return result(PendingDeleteRetry, fmt.Sprintf("%s depends on the resource and must be deleted first", dependent.URN))
+	}
+	switch {
+	case res.Protect:
+		return result(PendingDeleteManual, "the resource is protected; unprotect it to allow it to be deleted")
+	case !res.Custom:
+		return result(PendingDeleteDeleted, "component resources are removed without being deleted by a provider")
+	case providers.IsProviderType(res.Type):
+		return result(PendingDeleteDeleted, "providers are removed once no resource refers to them")
+	case res.External:
+		return result(PendingDeleteDeleted, "the resource is not managed by Pulumi, so it was removed without being "+
+			"deleted")
+	case res.ID == "":
+		return result(PendingDeleteManual, "the resource has no ID, so its provider cannot delete it")
+	}
+
+	ref, err := providers.ParseReference(res.Provider)
+	if err != nil {
+		return result(PendingDeleteManual, err.Error())
+	}
+	prov, ok := provs.GetProvider(ref)
+	if !ok {
+		return result(PendingDeleteManual, "the resource's provider could not be loaded")
+	}
+
+	for attempt := 1; ; attempt++ {
+		entry.Attempts = attempt
+		status, err := prov.Delete(res.URN, res.ID, res.Outputs)
+		if err == nil {
+			return result(PendingDeleteDeleted, "the resource was deleted")
+		}
+		if !policy.ShouldRetry(attempt, status, err) {
+			if isTransientError(policy, status, err) {
+				return result(PendingDeleteRetry, fmt.Sprintf("deleting the resource failed with a transient error: %v", err))
+			}
+			return result(PendingDeleteManual, fmt.Sprintf("deleting the resource failed: %v", err))
+		}
+
+		select {
+		case <-time.After(policy.Backoff(attempt + 1)):
+		case <-cancel:
+			return result(PendingDeleteRetry, fmt.Sprintf("deleting the resource was abandoned after it failed: %v", err))
+		}
+	}
+}
+
+// pendingDeleteDependent returns a resource that remains in the snapshot and depends on the given pending delete, if
+// any. Resources that are not pending deletion may refer to the URN of a pending delete that has been replaced, in
+// which case they depend on its replacement instead; they may only depend on a pending delete that is a provider, as
+// references to providers include their IDs.
+func pendingDeleteDependent(snap *deploy.Snapshot, res *resource.State,
+	deleted map[*resource.State]bool) *resource.State {
+
+	replaced := false
+	for _, s := range snap.Resources {
+		if s.URN == res.URN && !s.Delete {
+			replaced = true
+		}
+	}
+	var ref string
+	if providers.IsProviderType(res.Type) {
+		if r, err := providers.NewReference(res.URN, res.ID); err == nil {
+			ref = r.String()
+		}
+	}
+
+	for _, s := range snap.Resources {
+		if s == res || deleted[s] {
+			continue
+		}
+		if ref != "" && s.Provider == ref {
+			return s
+		}
+		if replaced && !s.Delete {
+			continue
+		}
+		if s.Parent == res.URN {
+			return s
+		}
+		for _, dep := range s.Dependencies {
+			if dep == res.URN {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// isTransientError returns true if the given failure of a provider operation is one that the given policy considers
+// retryable, regardless of the number of attempts that have been made.
+func isTransientError(policy deploy.RetryPolicy, status resource.Status, err error) bool {
+	rpcErr, ok := rpcerror.FromError(err)
+	return ok && status == resource.StatusOK && policy.RetryableCode(rpcErr.Code())
+}
+
+// collectPendingDeletesBeforeUpdate runs a garbage collection pass over the pending deletes of the target's snapshot
+// at the start of an update, and returns the snapshot from which the update should proceed. The pass runs within a
+// plan of its own, whose snapshot holds only the providers of the pending deletes, and each deletion is persisted by
+// the context's snapshot manager as that plan's deletion of a replaced resource. Deletions are retried according to
+// the update's retry policy, or DefaultPendingDeleteRetryPolicy if it does not set a number of attempts. Pending
+// deletes that could not be deleted are left in place, so the update retries them once it has completed, as it
+// always has.
+func collectPendingDeletesBeforeUpdate(ctx *Context, opts planOptions, plugctx *plugin.Context,
+	target *deploy.Target) (*deploy.Snapshot, error) {
+
+	snap := target.Snapshot
+	if len(ListPendingDeletes(snap)) == 0 {
+		return snap, nil
+	}
+
+	provs := &deploy.Snapshot{Manifest: snap.Manifest, Resources: pendingDeleteProviderStates(snap)}
+	plan, err := deploy.NewPlan(plugctx, target, provs, deploy.NullSource, nil, false, ctx.BackendClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading providers")
+	}
+
+	policy := opts.Retry
+	if policy.MaxAttempts == 0 {
+		policy = DefaultPendingDeleteRetryPolicy
+	}
+	collected := *snap
+	collected.Resources = append([]*resource.State(nil), snap.Resources...)
+	var cancel <-chan struct{}
+	if ctx.Cancel != nil {
+		cancel = ctx.Cancel.Canceled()
+	}
+	collectPendingDeletes(plan, &collected, policy, cancel, opts.Events.Chan)
+
+	remaining := make(map[*resource.State]bool, len(collected.Resources))
+	for _, res := range collected.Resources {
+		remaining[res] = true
+	}
+	for _, res := range snap.Resources {
+		if remaining[res] {
+			continue
+		}
+		step := deploy.NewDeleteReplacementStep(plan, res, false)
+		mutation, err := ctx.SnapshotManager.BeginMutation(step)
+		if err != nil {
+			return nil, err
+		}
+		if err = mutation.End(step, true); err != nil {
+			return nil, err
+		}
+	}
+	return &collected, nil
+}
+
+// sendPendingDeleteEvent sends an event that reports the given entry on the given channel, if it is not nil.
+func sendPendingDeleteEvent(events chan<- Event, entry PendingDeleteEntry) {
+	if events == nil {
+		return
+	}
+	events <- Event{
+		Type:    PendingDeleteEvent,
+		Version: EventSchemaVersion,
+		Payload: PendingDeleteEventPayload{
+			URN:      entry.URN,
+			ID:       entry.ID,
+			Action:   entry.Action,
+			Attempts: entry.Attempts,
+			Message:  entry.Message,
+		},
+	}
+}
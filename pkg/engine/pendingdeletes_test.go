@@ -0,0 +1,201 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func TestCollectPendingDeletes(t *testing.T) {
+	attempts := make(map[resource.ID]int)
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap) (resource.Status, error) {
+					attempts[id]++
+					switch id {
+					case "resB-old":
+						return resource.StatusOK, rpcerror.New(codes.Unavailable, "throttled")
+					case "resC-old":
+						return resource.StatusOK, errors.New("AccessDenied")
+					}
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+	host := deploytest.NewPluginHost(nil, nil, nil, loaders...)
+
+	prov := newGraphTestState("prov", providers.MakeProviderType("pkgA"), "", "")
+	ref, err := providers.NewReference(prov.URN, prov.ID)
+	assert.NoError(t, err)
+	pending := func(name string, parent resource.URN) *resource.State {
+		state := newGraphTestState(name, "pkgA:m:typA", parent, ref.String())
+		state.ID, state.Delete = resource.ID(name+"-old"), true
+		return state
+	}
+
+	comp := newGraphTestState("comp", "pkgA:m:comp", "", "")
+	comp.Custom, comp.ID, comp.Provider, comp.Delete = false, "", "", true
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", ref.String())
+	resAOld := pending("resA", comp.URN)
+	resBOld := pending("resB", "")
+	resCOld := pending("resC", "")
+	resDOld := pending("resD", "")
+	resDOld.Protect = true
+	snap := &deploy.Snapshot{Resources: []*resource.State{prov, comp, resA, resAOld, resBOld, resCOld, resDOld}}
+
+	assert.Equal(t, []*resource.State{comp, resAOld, resBOld, resCOld, resDOld}, ListPendingDeletes(snap))
+
+	// Dependents are collected first, transient failures are retried, and resources that need manual intervention are
+	// left alone.
+	events := make(chan Event, 5)
+	report, err := CollectPendingDeletes(host, snap, deploy.RetryPolicy{MaxAttempts: 2}, nil, events)
+	assert.NoError(t, err)
+	if assert.Len(t, report, 5) {
+		assert.Equal(t, PendingDeleteEntry{URN: resDOld.URN, ID: "resD-old", Action: PendingDeleteManual,
+			Message: "the resource is protected; unprotect it to allow it to be deleted"}, report[0])
+		assert.Equal(t, PendingDeleteManual, report[1].Action)
+		assert.Equal(t, 1, report[1].Attempts)
+		assert.Equal(t, PendingDeleteRetry, report[2].Action)
+		assert.Equal(t, 2, report[2].Attempts)
+		assert.Equal(t, PendingDeleteDeleted, report[3].Action)
+		assert.Equal(t, resAOld.URN, report[3].URN)
+		assert.Equal(t, PendingDeleteDeleted, report[4].Action)
+		assert.Equal(t, comp.URN, report[4].URN)
+	}
+	assert.Len(t, events, 5)
+	assert.Equal(t, map[resource.ID]int{"resA-old": 1, "resB-old": 2, "resC-old": 1}, attempts)
+	assert.Equal(t, []*resource.State{prov, resA, resBOld, resCOld, resDOld}, snap.Resources)
+
+	// A pending delete that is a parent of another is not collected until its child is.
+	comp2 := newGraphTestState("comp2", "pkgA:m:comp", "", "")
+	comp2.Custom, comp2.ID, comp2.Provider, comp2.Delete = false, "", "", true
+	resBOld.Parent = comp2.URN
+	snap.Resources = append([]*resource.State{comp2}, snap.Resources...)
+	report, err = CollectPendingDeletes(host, snap, deploy.RetryPolicy{}, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, report, 4) {
+		assert.Equal(t, PendingDeleteRetry, report[3].Action)
+		assert.Equal(t, comp2.URN, report[3].URN)
+	}
+	assert.Len(t, snap.Resources, 6)
+}
+
+func TestCollectPendingDeletesLoadsOnlyTheirProviders(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+		deploytest.NewProviderLoader("pkgB", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return nil, errors.New("pkgB has no pending deletes and must not be loaded")
+		}),
+	}
+	host := deploytest.NewPluginHost(nil, nil, nil, loaders...)
+
+	provA := newGraphTestState("provA", providers.MakeProviderType("pkgA"), "", "")
+	provB := newGraphTestState("provB", providers.MakeProviderType("pkgB"), "", "")
+	refA, err := providers.NewReference(provA.URN, provA.ID)
+	assert.NoError(t, err)
+	refB, err := providers.NewReference(provB.URN, provB.ID)
+	assert.NoError(t, err)
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", refA.String())
+	resA.ID, resA.Delete = "resA-old", true
+	resB := newGraphTestState("resB", "pkgB:m:typB", "", refB.String())
+	snap := &deploy.Snapshot{Resources: []*resource.State{provA, provB, resA, resB}}
+
+	report, err := CollectPendingDeletes(host, snap, deploy.RetryPolicy{}, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, report, 1) {
+		assert.Equal(t, PendingDeleteDeleted, report[0].Action)
+	}
+	assert.Equal(t, []*resource.State{provA, provB, resB}, snap.Resources)
+}
+
+func TestCollectPendingDeletesBeforeUpdate(t *testing.T) {
+	var deletes []resource.ID
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap) (resource.Status, error) {
+					deletes = append(deletes, id)
+					return resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+
+	snap, res := TestOp(Update).Run(p.GetProject(), p.GetTarget(nil), p.Options, false, nil, nil)
+	assert.Nil(t, res)
+
+	// Leave behind an old copy of resA, as if the delete of a create-before-delete replacement had failed.
+	resA := snap.Resources[len(snap.Resources)-1]
+	old := *resA
+	old.ID, old.Delete = "resA-old", true
+	snap.Resources = append(snap.Resources, &old)
+
+	p.Options.CollectPendingDeletes = true
+	var reported []PendingDeleteEventPayload
+	snap, res = TestOp(Update).Run(p.GetProject(), p.GetTarget(snap), p.Options, false, nil,
+		func(_ workspace.Project, _ deploy.Target, j *Journal, evts []Event, res result.Result) result.Result {
+			for _, evt := range evts {
+				if evt.Type == PendingDeleteEvent {
+					reported = append(reported, evt.Payload.(PendingDeleteEventPayload))
+				}
+			}
+
+			// The deletion is journaled before the update's own steps.
+			var replaced []resource.ID
+			for _, e := range j.Entries {
+				if e.Kind == JournalEntrySuccess && e.Step.Op() == deploy.OpDeleteReplaced {
+					replaced = append(replaced, e.Step.Old().ID)
+				}
+			}
+			assert.Equal(t, []resource.ID{"resA-old"}, replaced)
+			return res
+		})
+	assert.Nil(t, res)
+
+	if assert.Len(t, reported, 1) {
+		assert.Equal(t, PendingDeleteDeleted, reported[0].Action)
+		assert.Equal(t, resA.URN, reported[0].URN)
+	}
+	assert.Equal(t, []resource.ID{"resA-old"}, deletes)
+	assert.Empty(t, ListPendingDeletes(snap))
+	assert.Len(t, snap.Resources, 2)
+}
@@ -16,8 +16,10 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
+	"github.com/dustin/go-humanize"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
@@ -26,13 +28,20 @@ import (
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
-	"github.com/pulumi/pulumi/pkg/util/fsutil"
 	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/version"
 	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
 // ProjectInfoContext returns information about the current project, including its pwd, main, and plugin context.
 func ProjectInfoContext(projinfo *Projinfo, host plugin.Host, config plugin.ConfigSource,
+	diag, statusDiag diag.Sink, tracingSpan opentracing.Span) (string, string, *plugin.Context, error) {
+	return projectInfoContext(projinfo, host, nil, config, diag, statusDiag, tracingSpan)
+}
+
+// projectInfoContext is like ProjectInfoContext, but takes the context's host from the given pool if there is one and
+// no host is supplied.
+func projectInfoContext(projinfo *Projinfo, host plugin.Host, pool *plugin.HostPool, config plugin.ConfigSource,
 	diag, statusDiag diag.Sink, tracingSpan opentracing.Span) (string, string, *plugin.Context, error) {
 	contract.Require(projinfo != nil, "projinfo")
 
@@ -43,8 +52,13 @@ func ProjectInfoContext(projinfo *Projinfo, host plugin.Host, config plugin.Conf
 	}
 
 	// Create a context for plugins.
-	ctx, err := plugin.NewContext(diag, statusDiag, host, config, pwd,
-		projinfo.Proj.Runtime.Options(), tracingSpan)
+	var ctx *plugin.Context
+	if host == nil && pool != nil {
+		ctx, err = pool.NewContext(diag, statusDiag, pwd, projinfo.Proj.Runtime.Options(), tracingSpan)
+	} else {
+		ctx, err = plugin.NewContext(diag, statusDiag, host, config, pwd,
+			projinfo.Proj.Runtime.Options(), tracingSpan)
+	}
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -98,6 +112,18 @@ type planOptions struct {
 	// true if we're planning a refresh.
 	isRefresh bool
 
+	// true if we're planning an import.
+	isImport bool
+
+	// true if the update's checks run once it has been applied.
+	runChecks bool
+
+	// the resources that are never deleted for want of being produced by the program. Set only by Destroy.
+	exclude []resource.URNPattern
+
+	// if non-nil, the plan to which each reported step of a preview is recorded.
+	previewPlan *Plan
+
 	// true if we should trust the dependency graph reported by the language host. Not all Pulumi-supported languages
 	// correctly report their dependencies, in which case this will be false.
 	trustDependencies bool
@@ -119,12 +145,26 @@ func plan(ctx *Context, info *planContext, opts planOptions, dryRun bool) (*plan
 	proj, target := info.Update.GetProject(), info.Update.GetTarget()
 	contract.Assert(proj != nil)
 	contract.Assert(target != nil)
+	if err := checkPulumiVersion(proj, version.Version, opts.RecommendedVersion, opts.Diag); err != nil {
+		return nil, err
+	}
 	projinfo := &Projinfo{Proj: proj, Root: info.Update.GetRoot()}
-	pwd, main, plugctx, err := ProjectInfoContext(projinfo, opts.host, target,
+	pwd, main, plugctx, err := projectInfoContext(projinfo, opts.host, opts.PluginPool, target,
 		opts.Diag, opts.StatusDiag, info.TracingSpan)
 	if err != nil {
 		return nil, err
 	}
+	plugctx.SetCallObserver(ctx.Metrics.callObserver())
+	plugctx.AssetCache = opts.AssetCache
+
+	// Garbage collect the stack's pending deletes first, if requested, so that the plan starts from what they leave.
+	prev := target.Snapshot
+	if !dryRun && opts.CollectPendingDeletes && !opts.isRefresh && !opts.isImport {
+		if prev, err = collectPendingDeletesBeforeUpdate(ctx, opts, plugctx, target); err != nil {
+			contract.IgnoreClose(plugctx)
+			return nil, err
+		}
+	}
 
 	opts.trustDependencies = proj.TrustResourceDependencies()
 	// Now create the state source.  This may issue an error if it can't create the source.  This entails,
@@ -149,7 +189,7 @@ func plan(ctx *Context, info *planContext, opts planOptions, dryRun bool) (*plan
 	}
 
 	// Generate a plan; this API handles all interesting cases (create, update, delete).
-	plan, err := deploy.NewPlan(plugctx, target, target.Snapshot, source, analyzers, dryRun, ctx.BackendClient)
+	plan, err := deploy.NewPlan(plugctx, target, prev, source, analyzers, dryRun, ctx.BackendClient)
 	if err != nil {
 		contract.IgnoreClose(plugctx)
 		return nil, err
@@ -169,27 +209,53 @@ type planResult struct {
 	Options planOptions     // the options used during planning.
 }
 
-// Chdir changes the directory so that all operations from now on are relative to the project we are working with.
-// It returns a function that, when run, restores the old working directory.
-func (planResult *planResult) Chdir() (func(), error) {
-	return fsutil.Chdir(planResult.Plugctx.Pwd)
-}
-
 // Walk enumerates all steps in the plan, calling out to the provided action at each step.  It returns four things: the
 // resulting Snapshot, no matter whether an error occurs or not; an error, if something went wrong; the step that
 // failed, if the error is non-nil; and finally the state of the resource modified in the failing step.
-func (planResult *planResult) Walk(cancelCtx *Context, events deploy.Events, preview bool) result.Result {
+//
+// The given recorder must be the one to which the actions report the outcomes of their steps. If the walk is
+// cancelled, a cancellation event describing the steps that were executing at the time is emitted once it stops.
+func (planResult *planResult) Walk(cancelCtx *Context, events deploy.Events, outcomes *outcomeRecorder,
+	preview bool) result.Result {
+
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
+	// Report the progress of any remote assets or archives fetched while the plan executes as status messages.
+	planResult.Plugctx.SetAssetTransferObserver(func(progress resource.AssetTransferProgress) {
+		planResult.Options.StatusDiag.Infof(diag.RawMessage("" /*urn*/, formatAssetTransferProgress(progress)))
+	})
+	defer planResult.Plugctx.SetAssetTransferObserver(nil)
+
 	done := make(chan bool)
 	var walkResult result.Result
 	go func() {
 		opts := deploy.Options{
-			Events:            events,
-			Parallel:          planResult.Options.Parallel,
-			Refresh:           planResult.Options.Refresh,
-			RefreshOnly:       planResult.Options.isRefresh,
-			TrustDependencies: planResult.Options.trustDependencies,
+			Events:               events,
+			Parallel:             planResult.Options.Parallel,
+			Refresh:              planResult.Options.Refresh,
+			RefreshOnly:          planResult.Options.isRefresh,
+			ImportOnly:           planResult.Options.isImport,
+			TrustDependencies:    planResult.Options.trustDependencies,
+			Retry:                planResult.Options.Retry,
+			AllowProtected:       planResult.Options.AllowProtected,
+			ProviderParallel:     planResult.Options.ProviderParallel,
+			RefreshParallel:      planResult.Options.RefreshParallel,
+			CustomTimeouts:       planResult.Options.CustomTimeouts,
+			Budget:               planResult.Options.budget,
+			DeleteBeforeReplace:  planResult.Options.DeleteBeforeReplace,
+			PropertyChangeGuards: planResult.Options.PropertyChangeGuards,
+			Transformations:      planResult.Options.Transformations,
+			Prune:                planResult.Options.Prune,
+			PruneExempt:          planResult.Options.PruneExempt,
+			Exclude:              planResult.Options.exclude,
+			ExcludeDependents:    planResult.Options.DestroyExcludeDependents,
+			CheckCache:           planResult.Options.CheckCache,
+			DefaultTags:          planResult.Options.DefaultTags,
+			Artifacts:            cancelCtx.Artifacts,
+			ValidatePreview:      planResult.Options.ValidatePreview,
+			ExpectNoChanges:      planResult.Options.ExpectNoChanges,
+			HotReload:            planResult.Options.hotReload,
+			ChangedPaths:         planResult.Options.changedPaths,
 		}
 		walkResult = planResult.Plan.Execute(ctx, opts, preview)
 		close(done)
@@ -200,6 +266,7 @@ func (planResult *planResult) Walk(cancelCtx *Context, events deploy.Events, pre
 		select {
 		case <-cancelCtx.Cancel.Canceled():
 			// Cancel the plan's execution context, so it begins to shut down.
+			outcomes.cancel(preview)
 			cancelFunc()
 		case <-done:
 			return
@@ -208,24 +275,57 @@ func (planResult *planResult) Walk(cancelCtx *Context, events deploy.Events, pre
 
 	select {
 	case <-cancelCtx.Cancel.Terminated():
+		// Termination follows cancellation, which may not have been recorded yet.
+		outcomes.cancel(preview)
+		if cancellation, ok := outcomes.cancelled(); ok {
+			cancellation.Terminated = true
+			planResult.Options.Events.cancellationEvent(cancellation)
+		}
 		return result.WrapIfNonNil(cancelCtx.Cancel.TerminateErr())
 
 	case <-done:
+		planResult.Options.Events.progressDone()
+		if cancellation, ok := outcomes.cancelled(); ok {
+			planResult.Options.Events.cancellationEvent(cancellation)
+		}
 		return walkResult
 	}
 }
 
+// formatAssetTransferProgress formats a status message describing the progress of an asset transfer.
+func formatAssetTransferProgress(progress resource.AssetTransferProgress) string {
+	switch {
+	case progress.Done:
+		return fmt.Sprintf("fetched %s (%s)", progress.URI, humanize.IBytes(uint64(progress.Transferred)))
+	case progress.Total < 0:
+		return fmt.Sprintf("fetching %s: %s", progress.URI, humanize.IBytes(uint64(progress.Transferred)))
+	default:
+		return fmt.Sprintf("fetching %s: %s of %s", progress.URI,
+			humanize.IBytes(uint64(progress.Transferred)), humanize.IBytes(uint64(progress.Total)))
+	}
+}
+
 func (planResult *planResult) Close() error {
 	return planResult.Plugctx.Close()
 }
 
 // printPlan prints the plan's result to the plan's Options.Events stream.
-func printPlan(ctx *Context, planResult *planResult, dryRun bool) (ResourceChanges, result.Result) {
-	planResult.Options.Events.preludeEvent(dryRun, planResult.Ctx.Update.GetTarget().Config)
+func printPlan(ctx *Context, planResult *planResult, dryRun bool) (*UpdateResult, result.Result) {
+	cfg, secretOverrides := planResult.Ctx.Update.GetTarget().EffectiveConfig(planResult.Options.ConfigOverrides)
+	planResult.Options.Events.preludeEvent(dryRun, cfg, secretOverrides)
+
+	// Estimators are given the plan's steps, so record them even if the caller has not asked for them.
+	plan := planResult.Options.previewPlan
+	if plan == nil && len(planResult.Options.PlanEstimators) != 0 {
+		plan = &Plan{}
+		planResult.Options.previewPlan = plan
+	}
 
 	// Walk the plan's steps and and pretty-print them out.
 	actions := newPlanActions(planResult.Options)
-	if res := planResult.Walk(ctx, actions, true); res != nil {
+	res := planResult.Walk(ctx, actions, actions.Outcomes, true)
+	flushDiagnostics(planResult.Options.Diag, planResult.Options.StatusDiag)
+	if res != nil {
 		if res.IsBail() {
 			return nil, res
 		}
@@ -235,15 +335,26 @@ func printPlan(ctx *Context, planResult *planResult, dryRun bool) (ResourceChang
 
 	// Emit an event with a summary of operation counts.
 	changes := ResourceChanges(actions.Ops)
-	planResult.Options.Events.previewSummaryEvent(changes)
-	return changes, nil
+	deprecations := planResult.Plan.DeprecatedUsages()
+	var estimates []PlanEstimate
+	if len(planResult.Options.PlanEstimators) != 0 {
+		plan.Changes, plan.Deprecations = changes, deprecations
+		estimates = estimatePlan(planResult.Ctx.Update, plan, planResult.Options.PlanEstimators,
+			planResult.Options.Diag)
+	}
+	planResult.Options.Events.previewSummaryEvent(changes, deprecations, estimates)
+	updateResult := newUpdateResult(changes, actions.Outcomes)
+	updateResult.Deprecations = deprecations
+	updateResult.Estimates = estimates
+	return updateResult, nil
 }
 
 type planActions struct {
-	Ops     map[deploy.StepOp]int
-	Opts    planOptions
-	Seen    map[resource.URN]deploy.Step
-	MapLock sync.Mutex
+	Ops      map[deploy.StepOp]int
+	Opts     planOptions
+	Seen     map[resource.URN]deploy.Step
+	MapLock  sync.Mutex
+	Outcomes *outcomeRecorder
 }
 
 func shouldReportStep(step deploy.Step, opts planOptions) bool {
@@ -253,9 +364,10 @@ func shouldReportStep(step deploy.Step, opts planOptions) bool {
 
 func newPlanActions(opts planOptions) *planActions {
 	return &planActions{
-		Ops:  make(map[deploy.StepOp]int),
-		Opts: opts,
-		Seen: make(map[resource.URN]deploy.Step),
+		Ops:      make(map[deploy.StepOp]int),
+		Opts:     opts,
+		Seen:     make(map[resource.URN]deploy.Step),
+		Outcomes: newOutcomeRecorder(),
 	}
 }
 
@@ -264,13 +376,24 @@ func (acts *planActions) OnResourceStepPre(step deploy.Step) (interface{}, error
 	acts.Seen[step.URN()] = step
 	acts.MapLock.Unlock()
 
+	// Mask any secret inputs in case the provider echoes them back in its diagnostics.
+	acts.Opts.Events.secrets.addStates(step.New())
+
 	// Skip reporting if necessary.
 	if !shouldReportStep(step, acts.Opts) {
 		return nil, nil
 	}
 
+	acts.Outcomes.begin(step)
 	acts.Opts.Events.resourcePreEvent(step, true /*planning*/, acts.Opts.Debug)
 
+	// Explain why the step is scheduled after its predecessors, if it has any.
+	if step.New() != nil {
+		if dependencies := stepDependencies(step.New()); len(dependencies) > 0 {
+			acts.Opts.Events.stepDependenciesEvent(step, dependencies, acts.Opts.Debug)
+		}
+	}
+
 	return nil, nil
 }
 
@@ -291,6 +414,10 @@ func (acts *planActions) OnResourceStepPost(ctx interface{},
 		}
 
 		acts.Opts.Diag.Errorf(diag.GetPreviewFailedError(reportedURN), err)
+		if reportStep {
+			acts.Outcomes.end(step, step.Op(), err)
+			acts.Opts.Events.stepProcessed(step.Op(), false /*counted*/)
+		}
 	} else if reportStep {
 		op, record := step.Op(), step.Logical()
 		if acts.Opts.isRefresh && op == deploy.OpRefresh {
@@ -308,6 +435,12 @@ func (acts *planActions) OnResourceStepPost(ctx interface{},
 			acts.Ops[op]++
 			acts.MapLock.Unlock()
 		}
+		acts.Outcomes.end(step, op, nil)
+		acts.Opts.Events.stepProcessed(op, record)
+
+		if acts.Opts.previewPlan != nil {
+			acts.Opts.previewPlan.record(op, step, acts.Opts.Events.secrets, acts.Opts.Debug)
+		}
 
 		acts.Opts.Events.resourceOutputsEvent(op, step, true /*planning*/, acts.Opts.Debug)
 	}
@@ -328,6 +461,34 @@ func ShouldRecordReadStep(step deploy.Step) bool {
 		step.Old().Outputs.Diff(step.New().Outputs) != nil
 }
 
+func (acts *planActions) OnResourceStepProgress(step deploy.Step, percent int, message string) {
+	// Providers do not perform any long-running operations during a preview, so there is no progress to report.
+}
+
+func (acts *planActions) OnRefreshProgress(progress deploy.RefreshProgress) {
+	acts.Opts.Events.refreshProgressEvent(progress)
+}
+
+func (acts *planActions) OnDeletesScheduled(batches [][]deploy.Step) {
+	if acts.Opts.ReportDeletionOrder {
+		acts.Opts.Events.deletionOrderEvent(batches, acts.Opts)
+	}
+}
+
+func (acts *planActions) OnProviderChange(change deploy.ProviderChange) {
+	acts.Opts.Events.providerChangeEvent(change)
+}
+
+func (acts *planActions) OnProviderRestart(step deploy.Step, err error) {
+	acts.Opts.Events.pluginLifecycleEvent(step, err, acts.Opts.Debug)
+}
+
+func (acts *planActions) OnResourceStepRetry(step deploy.Step, attempt int, err error) {
+	if shouldReportStep(step, acts.Opts) {
+		acts.Outcomes.retry(step)
+	}
+}
+
 func (acts *planActions) OnResourceOutputs(step deploy.Step) error {
 	acts.MapLock.Lock()
 	assertSeen(acts.Seen, step)
@@ -345,6 +506,7 @@ func (acts *planActions) OnResourceOutputs(step deploy.Step) error {
 }
 
 func (acts *planActions) OnPolicyViolation(urn resource.URN, d plugin.AnalyzeDiagnostic) {
+	acts.Outcomes.violation(urn, d)
 	acts.Opts.Events.policyViolationEvent(urn, d)
 }
 
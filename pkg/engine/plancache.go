@@ -0,0 +1,581 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/version"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// PlanCache stores the plans computed by previews so that a later preview of the same stack can be served without
+// running its program, provided that the program's source, the stack's configuration, and the stack's last snapshot
+// are unchanged. Plans are keyed by a hash of all three, so a cache may be shared freely between stacks.
+//
+// A preview served from the cache replays the steps of the cached plan, followed by the diagnostics and program output
+// that were reported while the plan was computed.
+type PlanCache interface {
+	// Get returns the plan cached under the given key and the time at which it was computed, or nil if there is none.
+	Get(key string) (*Plan, time.Time, error)
+	// Put caches the given plan under the given key, replacing any plan already cached there.
+	Put(key string, plan *Plan) error
+}
+
+// planCacheIgnoredDirs are the directories beneath a project's root that are not hashed as part of its program source.
+// Installed dependencies are covered by the project's lock files, and the rest are bookkeeping or build output.
+var planCacheIgnoredDirs = map[string]bool{
+	workspace.GitDir:         true,
+	workspace.BookkeepingDir: true,
+	"node_modules":           true,
+	"__pycache__":            true,
+	"venv":                   true,
+}
+
+// usePlanCache returns true if a preview of the given update run with the given options may be served from and recorded
+// in the plan cache. Previews that refresh first depend on the live state of the stack's resources, so they are never
+// cached; nor are previews that run analyzers, property change guards, transformations, or snapshot or provider
+// validation, which a cached plan would bypass, or that expect no changes, which must stop at the first change they
+// find. (The result of an analyzer also depends on the version of its policy pack, which the key cannot see.)
+func usePlanCache(u UpdateInfo, opts UpdateOptions) bool {
+	if opts.PlanCache == nil || opts.Refresh || opts.ValidateSnapshot || opts.ValidatePreview || opts.ExpectNoChanges ||
+		len(opts.PropertyChangeGuards) != 0 || len(opts.Transformations) != 0 {
+		return false
+	}
+	if len(opts.Analyzers) != 0 {
+		return false
+	}
+	proj := u.GetProject()
+	return proj == nil || proj.Analyzers == nil || len(*proj.Analyzers) == 0
+}
+
+// previewWithCache serves a preview from the plan cache if a plan was cached for the same program, configuration, and
+// snapshot. Otherwise, it runs the preview and caches the resulting plan.
+func previewWithCache(ctx *Context, info *planContext, opts planOptions) (*UpdateResult, result.Result) {
+	key, err := planCacheKey(info.Update, opts.UpdateOptions)
+	if err != nil {
+		// Without a key we can neither consult nor populate the cache, but nothing stops the preview itself.
+		logging.V(5).Infof("plan cache: not caching preview: %v", err)
+		return previewPlan(ctx, info, opts)
+	}
+
+	if !opts.RefreshPlanCache {
+		plan, created, err := opts.PlanCache.Get(key)
+		if err != nil {
+			logging.V(5).Infof("plan cache: ignoring unreadable plan %s: %v", key, err)
+		} else if plan != nil {
+			logging.V(5).Infof("plan cache: serving preview from plan %s computed at %v", key, created)
+			opts.Events.planCacheEvent(key, true /*hit*/, false /*forced*/, created)
+			cfg, secretOverrides := info.Update.GetTarget().EffectiveConfig(opts.ConfigOverrides)
+			var estimates []PlanEstimate
+			if len(opts.PlanEstimators) != 0 {
+				estimates = estimatePlan(info.Update, plan, opts.PlanEstimators, opts.Diag)
+			}
+			opts.Events.progress = newProgressAggregator(opts.EventProfile, info.Update.GetTarget().Snapshot)
+			opts.Events.replayPlan(plan, cfg, secretOverrides, estimates, opts.Debug)
+			updateResult := plan.updateResult()
+			updateResult.Estimates = estimates
+			return updateResult, nil
+		}
+	}
+	opts.Events.planCacheEvent(key, false /*hit*/, opts.RefreshPlanCache, time.Time{})
+
+	updateResult, res := previewPlan(ctx, info, opts)
+	if res != nil {
+		return nil, res
+	}
+	plan := updateResult.Plan
+	if opts.Events.diags != nil {
+		plan.Diagnostics = opts.Events.diags.recorded()
+	}
+	if err = opts.PlanCache.Put(key, plan); err != nil {
+		opts.Diag.Warningf(diag.Message("" /*urn*/, "could not cache the plan for this preview: %v"), err)
+	}
+	return updateResult, nil
+}
+
+// replayPlan emits the events for a cached plan in the same order as the preview that computed it. The plan's estimates
+// are not cached, as the estimators may have changed since, so they are made afresh and passed in.
+func (e *eventEmitter) replayPlan(plan *Plan, cfg config.Map, secretOverrides []config.Key, estimates []PlanEstimate,
+	debug bool) {
+
+	e.preludeEvent(true /*isPreview*/, cfg, secretOverrides)
+	for _, step := range plan.Steps {
+		e.stepProcessed(step.Op, step.Logical)
+		if e.quiet(step.Op) {
+			continue
+		}
+		e.Chan <- Event{
+			Type:    ResourcePreEvent,
+			Version: EventSchemaVersion,
+			Payload: ResourcePreEventPayload{
+				Metadata: step.StepEventMetadata,
+				Planning: true,
+				Debug:    debug,
+			},
+		}
+		e.Chan <- Event{
+			Type:    ResourceOutputsEvent,
+			Version: EventSchemaVersion,
+			Payload: ResourceOutputsEventPayload{
+				Metadata: step.StepEventMetadata,
+				Planning: true,
+				Debug:    debug,
+			},
+		}
+	}
+	for _, d := range plan.Diagnostics {
+		e.Chan <- Event{
+			Type:    DiagEvent,
+			Version: EventSchemaVersion,
+			Payload: d,
+		}
+	}
+	e.progressDone()
+	e.previewSummaryEvent(plan.Changes, plan.Deprecations, estimates)
+}
+
+// planCacheKey computes the key under which the plan for a preview of the given update is cached. The key covers the
+// version of the engine, the program's source, the stack's configuration and last snapshot, and the options that
+// affect which steps the preview reports.
+func planCacheKey(u UpdateInfo, opts UpdateOptions) (string, error) {
+	h := sha256.New()
+	proj, target := u.GetProject(), u.GetTarget()
+	fmt.Fprintf(h, "version:%s\nproject:%s\nstack:%s\n", version.Version, proj.Name, target.Name)
+	fmt.Fprintf(h, "options:%v %v %v %v\n",
+		opts.AllowProtected, opts.Debug, opts.reportDefaultProviderSteps, opts.DeleteBeforeReplace)
+
+	if err := hashProgramSource(h, u.GetRoot()); err != nil {
+		return "", errors.Wrap(err, "hashing program source")
+	}
+
+	// Secret configuration values are hashed in their encrypted form, so the key never depends on plaintext secrets.
+	cfg, err := json.Marshal(target.LayeredConfig())
+	if err != nil {
+		return "", errors.Wrap(err, "hashing configuration")
+	}
+	fmt.Fprintf(h, "config:%s\n", cfg)
+
+	// Overrides are only ever held in memory, so they are hashed as given.
+	overrides := make([]string, 0, len(opts.ConfigOverrides))
+	for k, v := range opts.ConfigOverrides {
+		overrides = append(overrides, fmt.Sprintf("override:%s=%q\n", k, v))
+	}
+	sort.Strings(overrides)
+	for _, o := range overrides {
+		fmt.Fprint(h, o)
+	}
+
+	// Every write of a snapshot stamps it with a new time, which serves as the snapshot's serial number. The resources
+	// are hashed as well, in case the snapshot was edited by hand without updating its manifest.
+	if snap := target.Snapshot; snap != nil {
+		fmt.Fprintf(h, "snapshot:%d %s %d\n", snap.Manifest.Time.UnixNano(), snap.Manifest.Magic,
+			len(snap.PendingOperations))
+		for _, res := range snap.Resources {
+			fmt.Fprintf(h, "resource:%s %s %v\n", res.URN, res.ID, res.Delete)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashProgramSource hashes the paths and contents of the files beneath the given project root. If the update has no
+// root on disk, there is no source to hash.
+func hashProgramSource(h hash.Hash, root string) error {
+	if root == "" {
+		return nil
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && planCacheIgnoredDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file:%s\n", filepath.ToSlash(rel))
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "link:%s\n", target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			contract.IgnoreClose(f)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// filePlanCache is a PlanCache that stores each plan as a JSON file in a directory.
+type filePlanCache struct {
+	dir string
+}
+
+// NewFilePlanCache creates a PlanCache that stores plans in the given directory, which is created if necessary.
+func NewFilePlanCache(dir string) PlanCache {
+	return &filePlanCache{dir: dir}
+}
+
+func (c *filePlanCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *filePlanCache) Get(key string) (*Plan, time.Time, error) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, err
+	}
+
+	var cached cachedPlan
+	if err = json.Unmarshal(b, &cached); err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "reading cached plan %s", key)
+	}
+	plan, err := cached.plan()
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "reading cached plan %s", key)
+	}
+	return plan, cached.Created, nil
+}
+
+func (c *filePlanCache) Put(key string, plan *Plan) error {
+	cached, err := newCachedPlan(plan, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "serializing plan")
+	}
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return errors.Wrap(err, "serializing plan")
+	}
+
+	if err = os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+
+	// Write the plan to a temporary file first so that concurrent previews never read a partially-written plan.
+	tmp, err := ioutil.TempFile(c.dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		contract.IgnoreError(os.Remove(tmp.Name()))
+	}
+	return err
+}
+
+// cachedPlan is the serialized form of a Plan.
+type cachedPlan struct {
+	Created      time.Time                `json:"created"`
+	Steps        []cachedPlanStep         `json:"steps"`
+	Changes      map[string]int           `json:"changes"`
+	Diagnostics  []cachedDiagnostic       `json:"diagnostics,omitempty"`
+	Deprecations []deploy.DeprecatedUsage `json:"deprecations,omitempty"`
+}
+
+// cachedDiagnostic is the serialized form of a diagnostic reported while a plan was computed. Its text was already
+// stripped of secrets when it was reported.
+type cachedDiagnostic struct {
+	URN      resource.URN        `json:"urn,omitempty"`
+	Prefix   string              `json:"prefix,omitempty"`
+	Message  string              `json:"message"`
+	Color    colors.Colorization `json:"color"`
+	Severity diag.Severity       `json:"severity"`
+	StreamID int32               `json:"streamID,omitempty"`
+}
+
+// cachedPlanStep is the serialized form of a PlanStep.
+type cachedPlanStep struct {
+	Op           deploy.StepOp          `json:"op"`
+	URN          resource.URN           `json:"urn"`
+	Type         tokens.Type            `json:"type"`
+	Old          *cachedState           `json:"old,omitempty"`
+	New          *cachedState           `json:"new,omitempty"`
+	Res          *cachedState           `json:"res,omitempty"`
+	Keys         []resource.PropertyKey `json:"keys,omitempty"`
+	Diffs        []resource.PropertyKey `json:"diffs,omitempty"`
+	Logical      bool                   `json:"logical,omitempty"`
+	Provider     string                 `json:"provider,omitempty"`
+	Dependencies []resource.URN         `json:"dependencies,omitempty"`
+}
+
+// cachedState is the serialized form of a StepEventStateMetadata. Only the filtered inputs and outputs are stored, so
+// a cached plan never contains secret values. Because the properties of a planned resource may be unknown, they are
+// marshaled as they are for RPC rather than as they are in a checkpoint.
+type cachedState struct {
+	Type               tokens.Type       `json:"type"`
+	URN                resource.URN      `json:"urn"`
+	Custom             bool              `json:"custom,omitempty"`
+	Delete             bool              `json:"delete,omitempty"`
+	ID                 resource.ID       `json:"id,omitempty"`
+	Parent             resource.URN      `json:"parent,omitempty"`
+	Protect            bool              `json:"protect,omitempty"`
+	External           bool              `json:"external,omitempty"`
+	PendingReplacement bool              `json:"pendingReplacement,omitempty"`
+	Dependencies       []resource.URN    `json:"dependencies,omitempty"`
+	Inputs             []byte            `json:"inputs,omitempty"`
+	Outputs            []byte            `json:"outputs,omitempty"`
+	Provider           string            `json:"provider,omitempty"`
+	InitErrors         []string          `json:"initErrors,omitempty"`
+	Annotations        map[string]string `json:"annotations,omitempty"`
+}
+
+var cachedPropertiesOptions = plugin.MarshalOptions{
+	Label:        "plan-cache",
+	KeepUnknowns: true,
+	KeepSecrets:  true,
+}
+
+func newCachedPlan(plan *Plan, created time.Time) (*cachedPlan, error) {
+	plan.lock.Lock()
+	defer plan.lock.Unlock()
+
+	cached := &cachedPlan{Created: created, Changes: make(map[string]int), Deprecations: plan.Deprecations}
+	for op, count := range plan.Changes {
+		cached.Changes[string(op)] = count
+	}
+	for _, d := range plan.Diagnostics {
+		cached.Diagnostics = append(cached.Diagnostics, cachedDiagnostic{
+			URN:      d.URN,
+			Prefix:   d.Prefix,
+			Message:  d.Message,
+			Color:    d.Color,
+			Severity: d.Severity,
+			StreamID: d.StreamID,
+		})
+	}
+	for _, step := range plan.Steps {
+		oldState, err := newCachedState(step.Old)
+		if err != nil {
+			return nil, err
+		}
+		newState, err := newCachedState(step.New)
+		if err != nil {
+			return nil, err
+		}
+		resState, err := newCachedState(step.Res)
+		if err != nil {
+			return nil, err
+		}
+
+		cached.Steps = append(cached.Steps, cachedPlanStep{
+			Op:           step.Op,
+			URN:          step.URN,
+			Type:         step.Type,
+			Old:          oldState,
+			New:          newState,
+			Res:          resState,
+			Keys:         step.Keys,
+			Diffs:        step.Diffs,
+			Logical:      step.Logical,
+			Provider:     step.Provider,
+			Dependencies: step.Dependencies,
+		})
+	}
+	return cached, nil
+}
+
+func newCachedState(m *StepEventStateMetadata) (*cachedState, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	inputs, err := marshalCachedProperties(m.Inputs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "serializing inputs of %s", m.URN)
+	}
+	outputs, err := marshalCachedProperties(m.Outputs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "serializing outputs of %s", m.URN)
+	}
+
+	cached := &cachedState{
+		Type:        m.Type,
+		URN:         m.URN,
+		Custom:      m.Custom,
+		Delete:      m.Delete,
+		ID:          m.ID,
+		Parent:      m.Parent,
+		Protect:     m.Protect,
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Provider:    m.Provider,
+		InitErrors:  m.InitErrors,
+		Annotations: m.Annotations,
+	}
+	if m.State != nil {
+		cached.External = m.State.External
+		cached.PendingReplacement = m.State.PendingReplacement
+		cached.Dependencies = m.State.Dependencies
+	}
+	return cached, nil
+}
+
+func (c *cachedPlan) plan() (*Plan, error) {
+	plan := &Plan{Changes: make(ResourceChanges), Deprecations: c.Deprecations}
+	for op, count := range c.Changes {
+		plan.Changes[deploy.StepOp(op)] = count
+	}
+	for _, d := range c.Diagnostics {
+		plan.Diagnostics = append(plan.Diagnostics, DiagEventPayload{
+			URN:      d.URN,
+			Prefix:   d.Prefix,
+			Message:  d.Message,
+			Color:    d.Color,
+			Severity: d.Severity,
+			StreamID: d.StreamID,
+		})
+	}
+	for _, step := range c.Steps {
+		oldState, err := step.Old.metadata()
+		if err != nil {
+			return nil, err
+		}
+		newState, err := step.New.metadata()
+		if err != nil {
+			return nil, err
+		}
+		resState, err := step.Res.metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Steps = append(plan.Steps, PlanStep{
+			StepEventMetadata: StepEventMetadata{
+				Op:       step.Op,
+				URN:      step.URN,
+				Type:     step.Type,
+				Old:      oldState,
+				New:      newState,
+				Res:      resState,
+				Keys:     step.Keys,
+				Diffs:    step.Diffs,
+				Logical:  step.Logical,
+				Provider: step.Provider,
+			},
+			Dependencies: step.Dependencies,
+		})
+	}
+	return plan, nil
+}
+
+// metadata rebuilds the StepEventStateMetadata from which the cached state was created. Its raw State is reconstructed
+// from the filtered properties, as the raw properties were never cached.
+func (c *cachedState) metadata() (*StepEventStateMetadata, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	inputs, err := unmarshalCachedProperties(c.Inputs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deserializing inputs of %s", c.URN)
+	}
+	outputs, err := unmarshalCachedProperties(c.Outputs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deserializing outputs of %s", c.URN)
+	}
+
+	state := resource.NewState(c.Type, c.URN, c.Custom, c.Delete, c.ID, inputs, outputs, c.Parent, c.Protect,
+		c.External, c.Dependencies, c.InitErrors, c.Provider, nil, c.PendingReplacement, nil, nil)
+	state.Annotations = c.Annotations
+
+	return &StepEventStateMetadata{
+		State:       state,
+		Type:        c.Type,
+		URN:         c.URN,
+		Custom:      c.Custom,
+		Delete:      c.Delete,
+		ID:          c.ID,
+		Parent:      c.Parent,
+		Protect:     c.Protect,
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Provider:    c.Provider,
+		InitErrors:  c.InitErrors,
+		Annotations: c.Annotations,
+	}, nil
+}
+
+func marshalCachedProperties(props resource.PropertyMap) ([]byte, error) {
+	if props == nil {
+		return nil, nil
+	}
+	s, err := plugin.MarshalProperties(props, cachedPropertiesOptions)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(s)
+}
+
+func unmarshalCachedProperties(b []byte) (resource.PropertyMap, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var s structpb.Struct
+	if err := proto.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return plugin.UnmarshalProperties(&s, cachedPropertiesOptions)
+}
@@ -0,0 +1,243 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// previewEvents runs a preview of the given plan and returns its result along with the events it emitted.
+func previewEvents(p *TestPlan, opts UpdateOptions) (*Plan, []Event, result.Result) {
+	info := &updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}
+	cancelCtx, _ := cancel.NewContext(context.Background())
+
+	events := make(chan Event)
+	done := make(chan []Event)
+	go func() {
+		var fired []Event
+		for e := range events {
+			fired = append(fired, e)
+		}
+		done <- fired
+	}()
+
+	preview, res := Preview(info, &Context{Cancel: cancelCtx, Events: events}, opts)
+	close(events)
+	if preview == nil {
+		return nil, <-done, res
+	}
+	return preview.Plan, <-done, res
+}
+
+func planCachePayload(t *testing.T, events []Event) PlanCacheEventPayload {
+	for _, e := range events {
+		if e.Type == PlanCacheEvent {
+			return e.Payload.(PlanCacheEventPayload)
+		}
+	}
+	assert.Fail(t, "expected a plan-cache event")
+	return PlanCacheEventPayload{}
+}
+
+func TestPlanCache(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	runs := 0
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		runs++
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{
+				"foo": resource.NewStringProperty("bar"),
+				"baz": resource.MakeComputed(resource.NewStringProperty("")),
+			}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	dir, err := ioutil.TempDir("", "plancache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, os.RemoveAll(dir)) }()
+
+	p := &TestPlan{}
+	opts := UpdateOptions{host: host, PlanCache: NewFilePlanCache(dir)}
+	urnA := p.NewURN("pkgA:m:typA", "resA", "")
+
+	// The first preview runs the program and caches its plan.
+	computed, events, res := previewEvents(p, opts)
+	assert.Nil(t, res)
+	assert.Equal(t, 1, runs)
+	assert.False(t, planCachePayload(t, events).Hit)
+
+	// The second is served from the cache without running the program, and reports the same steps.
+	cached, events, res := previewEvents(p, opts)
+	assert.Nil(t, res)
+	assert.Equal(t, 1, runs)
+	payload := planCachePayload(t, events)
+	assert.True(t, payload.Hit)
+	assert.False(t, payload.Created.IsZero())
+	if assert.NotNil(t, computed) && assert.NotNil(t, cached) {
+		assert.Equal(t, computed.Changes, cached.Changes)
+		assert.Equal(t, 1, cached.Changes[deploy.OpCreate])
+		step, ok := cached.Step(urnA)
+		if assert.True(t, ok) {
+			assert.Equal(t, deploy.OpCreate, step.Op)
+			assert.Equal(t, resource.NewStringProperty("bar"), step.New.Inputs["foo"])
+			assert.True(t, step.New.Inputs["baz"].IsComputed())
+			assert.Equal(t, step.New.Inputs, step.New.State.Inputs)
+		}
+	}
+
+	var replayed []resource.URN
+	for _, e := range events {
+		if e.Type == ResourcePreEvent {
+			replayed = append(replayed, e.Payload.(ResourcePreEventPayload).Metadata.URN)
+		}
+	}
+	assert.Equal(t, []resource.URN{urnA}, replayed)
+
+	// Forcing a refresh of the cache runs the program again.
+	opts.RefreshPlanCache = true
+	_, events, res = previewEvents(p, opts)
+	assert.Nil(t, res)
+	assert.Equal(t, 2, runs)
+	payload = planCachePayload(t, events)
+	assert.False(t, payload.Hit)
+	assert.True(t, payload.Forced)
+
+	// So does changing the stack's configuration.
+	opts.RefreshPlanCache = false
+	p.Config = config.Map{config.MustMakeKey("pkgA", "foo"): config.NewValue("bar")}
+	_, events, res = previewEvents(p, opts)
+	assert.Nil(t, res)
+	assert.Equal(t, 3, runs)
+	assert.False(t, planCachePayload(t, events).Hit)
+
+	// Previews that refresh first never consult the cache.
+	opts.Refresh = true
+	_, events, res = previewEvents(p, opts)
+	assert.Nil(t, res)
+	assert.Equal(t, 4, runs)
+	for _, e := range events {
+		assert.NotEqual(t, PlanCacheEvent, e.Type)
+	}
+}
+
+func TestPlanCacheKey(t *testing.T) {
+	p := &TestPlan{}
+	info := &updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}
+
+	key, err := planCacheKey(info, UpdateOptions{})
+	assert.NoError(t, err)
+	same, err := planCacheKey(info, UpdateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	// Options that change which steps are reported change the key.
+	debug, err := planCacheKey(info, UpdateOptions{Debug: true})
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, debug)
+
+	// So does any change to the stack's snapshot.
+	info.target.Snapshot = deploy.NewSnapshot(deploy.Manifest{}, nil, nil, nil)
+	empty, err := planCacheKey(info, UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, empty)
+
+	info.target.Snapshot = deploy.NewSnapshot(deploy.Manifest{}, nil, []*resource.State{
+		{Type: "pkgA:m:typA", URN: p.NewURN("pkgA:m:typA", "resA", ""), Custom: true, ID: "id"},
+	}, nil)
+	nonEmpty, err := planCacheKey(info, UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, empty, nonEmpty)
+}
+
+func TestPlanCacheSkipsAnalyzers(t *testing.T) {
+	p := &TestPlan{}
+	info := &updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}
+	opts := UpdateOptions{PlanCache: NewFilePlanCache("")}
+	assert.True(t, usePlanCache(info, opts))
+
+	// A cached plan would bypass analyzers, whether they are requested by the update or by the project.
+	assert.False(t, usePlanCache(info, UpdateOptions{PlanCache: opts.PlanCache, Analyzers: []string{"policy"}}))
+	info.project.Analyzers = &workspace.Analyzers{"policy"}
+	assert.False(t, usePlanCache(info, opts))
+}
+
+func TestPlanCacheReplaysDiagnostics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plancache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, os.RemoveAll(dir)) }()
+
+	// Diagnostics emitted while a plan is computed are recorded, except for ephemeral status messages.
+	events := make(chan Event, 8)
+	emitter := eventEmitter{Chan: events, secrets: newSecretFilter(), diags: &diagRecorder{}}
+	urn := resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA")
+	emitter.diagInfoEvent(&diag.Diag{URN: urn, StreamID: 7}, "", "hello from the program\n", false)
+	emitter.diagWarningEvent(&diag.Diag{URN: urn}, "warning: ", "watch out\n", false)
+	emitter.diagInfoEvent(&diag.Diag{URN: urn}, "", "working...\n", true)
+	plan := &Plan{Changes: ResourceChanges{}, Diagnostics: emitter.diags.recorded()}
+	assert.Len(t, plan.Diagnostics, 2)
+
+	// They survive the cache, and are replayed along with the plan.
+	cache := NewFilePlanCache(dir)
+	assert.NoError(t, cache.Put("key", plan))
+	cached, _, err := cache.Get("key")
+	if !assert.NoError(t, err) || !assert.NotNil(t, cached) {
+		return
+	}
+	assert.Equal(t, plan.Diagnostics, cached.Diagnostics)
+
+	replayEvents := make(chan Event, 8)
+	replayer := eventEmitter{Chan: replayEvents, secrets: newSecretFilter()}
+	replayer.replayPlan(cached, nil, nil, nil, false)
+	close(replayEvents)
+
+	var replayed []DiagEventPayload
+	for e := range replayEvents {
+		if e.Type == DiagEvent {
+			replayed = append(replayed, e.Payload.(DiagEventPayload))
+		}
+	}
+	if assert.Len(t, replayed, 2) {
+		assert.Equal(t, "hello from the program\n", replayed[0].Message)
+		assert.Equal(t, int32(7), replayed[0].StreamID)
+		assert.Equal(t, diag.Warning, replayed[1].Severity)
+	}
+}
@@ -15,11 +15,14 @@
 package engine
 
 import (
+	"io/ioutil"
 	"sort"
 
 	"github.com/blang/semver"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
@@ -118,12 +121,75 @@ func gatherPluginsFromSnapshot(plugctx *plugin.Context, target *deploy.Target) (
 	return set, nil
 }
 
+// ListRequiredPlugins returns the plugins that an update of the given program and stack would need, sorted by kind,
+// name, and version. These are the plugins that the program's language host reports, along with those that the
+// providers recorded in the stack's current snapshot are bound to.
+//
+// Neither the program nor any resource provider is run, and no plugins are installed, so this may be used to
+// pre-populate a plugin cache or to review which providers an update would execute before performing it.
+func ListRequiredPlugins(u UpdateInfo) ([]workspace.PluginInfo, error) {
+	return listRequiredPlugins(u, nil)
+}
+
+func listRequiredPlugins(u UpdateInfo, host plugin.Host) ([]workspace.PluginInfo, error) {
+	contract.Require(u != nil, "u")
+
+	proj, target := u.GetProject(), u.GetTarget()
+	contract.Assert(proj != nil)
+	contract.Assert(target != nil)
+
+	// The language host is only asked about the program, so there are no diagnostics worth reporting; any failure is
+	// returned as an error.
+	sink := diag.DefaultSink(ioutil.Discard, ioutil.Discard, diag.FormatOptions{Color: colors.Never})
+	pwd, main, plugctx, err := ProjectInfoContext(&Projinfo{Proj: proj, Root: u.GetRoot()}, host, target,
+		sink, sink, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(plugctx)
+
+	env, err := languageEnv(proj)
+	if err != nil {
+		return nil, err
+	}
+	plugctx.LanguageEnv = env
+
+	languagePlugins, err := gatherPluginsFromProgram(plugctx, plugin.ProgInfo{
+		Proj:    proj,
+		Pwd:     pwd,
+		Program: main,
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshotPlugins, err := gatherPluginsFromSnapshot(plugctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := languagePlugins.Union(snapshotPlugins).Values()
+	sort.SliceStable(plugins, func(i, j int) bool {
+		pi, pj := plugins[i], plugins[j]
+		switch {
+		case pi.Kind != pj.Kind:
+			return pi.Kind < pj.Kind
+		case pi.Name != pj.Name:
+			return pi.Name < pj.Name
+		default:
+			return workspace.SortedPluginInfo(plugins).Less(i, j)
+		}
+	})
+	return plugins, nil
+}
+
 // ensurePluginsAreInstalled inspects all plugins in the plugin set and, if any plugins are not currently installed,
 // uses the given backend client to install them. Installations are processed in parallel, though
-// ensurePluginsAreInstalled does not return until all installations are completed.
-func ensurePluginsAreInstalled(plugins pluginSet) error {
+// ensurePluginsAreInstalled does not return until all installations are completed. If plugins are downloaded without
+// being verified, or fail verification, a warning is issued to the given sink.
+func ensurePluginsAreInstalled(plugins pluginSet, d diag.Sink) error {
 	logging.V(preparePluginLog).Infof("ensurePluginsAreInstalled(): beginning")
 	var installTasks errgroup.Group
+	warned := false
 	for _, plug := range plugins.Values() {
 		_, path, err := workspace.GetPluginPath(plug.Kind, plug.Name, plug.Version)
 		if err == nil && path != "" {
@@ -132,12 +198,24 @@ func ensurePluginsAreInstalled(plugins pluginSet) error {
 			continue
 		}
 
+		// Language plugins are never downloaded, so only warn about the download of a resource or analyzer plugin.
+		if !warned && plug.Kind != workspace.LanguagePlugin {
+			if warning := workspace.PluginDownloadWarning(); warning != "" {
+				d.Warningf(diag.RawMessage("", warning))
+			}
+			warned = true
+		}
+
 		// Launch an install task asynchronously and add it to the current error group.
 		info := plug // don't close over the loop induction variable
 		installTasks.Go(func() error {
 			logging.V(preparePluginLog).Infof(
 				"ensurePluginsAreInstalled(): plugin %s %s not installed, doing install", info.Name, info.Version)
-			return installPlugin(info)
+			err := installPlugin(info)
+			if verr, ok := err.(*workspace.PluginVerificationError); ok {
+				d.Warningf(diag.Message("", "%v"), verr)
+			}
+			return err
 		})
 	}
 
@@ -20,6 +20,9 @@ import (
 	"github.com/blang/semver"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/logging"
 	"github.com/pulumi/pulumi/pkg/workspace"
@@ -127,3 +130,34 @@ func TestDefaultProvidersSnapshotOverrides(t *testing.T) {
 	assert.NotNil(t, awsVer)
 	assert.Equal(t, "0.17.0", awsVer.String())
 }
+
+func TestListRequiredPlugins(t *testing.T) {
+	program := deploytest.NewLanguageRuntime(nil,
+		workspace.PluginInfo{Name: "pkgA", Kind: workspace.ResourcePlugin, Version: mustMakeVersion("1.0.0")},
+		workspace.PluginInfo{Name: "test", Kind: workspace.LanguagePlugin})
+	host := deploytest.NewPluginHost(nil, nil, program)
+
+	// The stack's snapshot holds a provider for another package, which any update must also load.
+	provURN := resource.NewURN("test", "test", "", "pulumi:providers:pkgB", "prov")
+	prov := resource.NewState(provURN.Type(), provURN, true, false, "id",
+		resource.PropertyMap{"version": resource.NewStringProperty("2.0.0")}, nil, "", false, false, nil, nil, "",
+		nil, false, nil, nil)
+
+	info := &updateInfo{
+		project: workspace.Project{Name: "test", Runtime: workspace.NewProjectRuntimeInfo("test", nil)},
+		target: deploy.Target{
+			Name:     "test",
+			Snapshot: deploy.NewSnapshot(deploy.Manifest{}, nil, []*resource.State{prov}, nil),
+		},
+	}
+	plugins, err := listRequiredPlugins(info, host)
+	assert.NoError(t, err)
+	if assert.Len(t, plugins, 3) {
+		assert.Equal(t, workspace.LanguagePlugin, plugins[0].Kind)
+		assert.Equal(t, "test", plugins[0].Name)
+		assert.Equal(t, "pkgA", plugins[1].Name)
+		assert.Equal(t, "1.0.0", plugins[1].Version.String())
+		assert.Equal(t, "pkgB", plugins[2].Name)
+		assert.Equal(t, "2.0.0", plugins[2].Version.String())
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// PreflightOptions describes where an update writes its state, so that the update can check that there is room for
+// it before any resources are changed.
+type PreflightOptions struct {
+	// StatePaths are the local directories to which the update writes its checkpoints and their backups. The free
+	// disk space of each is checked. Backends whose state is not stored locally leave this empty.
+	StatePaths []string
+	// CheckpointSize is the size in bytes of the stack's current checkpoint, from which the size of the checkpoints
+	// that the update writes is estimated.
+	CheckpointSize uint64
+	// Skip disables the checks.
+	Skip bool
+}
+
+const (
+	// preflightCheckpointCopies is the number of copies of a checkpoint that must fit on a state volume: the
+	// checkpoint itself, the temporary file that replaces it, and its backup.
+	preflightCheckpointCopies = 3
+
+	// preflightBaseFiles is the number of file descriptors that the CLI uses for itself, e.g. for its standard
+	// streams, its logs, and its connections to the backend.
+	preflightBaseFiles = 64
+	// preflightFilesPerPlugin is the number of file descriptors that the CLI holds for each plugin process: a pipe for
+	// each of its standard streams and the connection over which it is called.
+	preflightFilesPerPlugin = 8
+	// preflightFilesPerStep is the number of file descriptors that each step executing in parallel may hold, e.g. to
+	// read the assets and archives that it passes to its provider.
+	preflightFilesPerStep = 4
+)
+
+// preflight checks that an update of the given target with the given options will not run out of disk space for its
+// checkpoints or of file descriptors for its plugins part of the way through. A shortage of disk space is an error;
+// an update that may exceed the file descriptor limit is warned about, as the estimate of what it needs is rough.
+func preflight(target *deploy.Target, opts UpdateOptions, d diag.Sink) error {
+	if opts.Preflight.Skip {
+		return nil
+	}
+
+	// Every checkpoint that the update writes is about as large as the stack's current one, except that new resources
+	// make it larger. Require room for a few copies, and never less than what Doctor considers the minimum.
+	need := DoctorMinFreeSpace + preflightCheckpointCopies*opts.Preflight.CheckpointSize
+	for _, path := range opts.Preflight.StatePaths {
+		free, err := freeDiskSpaceFor(path)
+		if err != nil {
+			logging.V(7).Infof("preflight: could not determine the free space for %s: %v", path, err)
+			continue
+		}
+		if free < need {
+			return errors.Errorf("only %s is free for the stack's state in %s, but the update may need %s to save its "+
+				"checkpoints; free up space on that volume before running the update again, or the update may fail "+
+				"part of the way through", humanize.IBytes(free), path, humanize.IBytes(need))
+		}
+	}
+
+	if limit, ok := openFileLimit(); ok {
+		if need := preflightFiles(target, opts); need > limit {
+			d.Warningf(diag.RawMessage("", fmt.Sprintf("the update may need up to %d open files for its plugins "+
+				"and its %d parallel steps, but this process may only open %d; if the update fails with "+
+				"\"too many open files\", raise the limit (e.g. with `ulimit -n %d`) or lower --parallel",
+				need, opts.Parallel, limit, need)))
+		}
+	}
+	return nil
+}
+
+// preflightFiles estimates the number of file descriptors that an update of the given target may need at once: those
+// for the CLI itself, for a language host and a plugin for each provider in the target's snapshot, and for each step
+// that may execute in parallel.
+func preflightFiles(target *deploy.Target, opts UpdateOptions) uint64 {
+	plugins := 1 // the language host.
+	if target != nil && target.Snapshot != nil {
+		for _, res := range target.Snapshot.Resources {
+			if !res.Delete && providers.IsProviderType(res.Type) {
+				plugins++
+			}
+		}
+	}
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	return uint64(preflightBaseFiles + preflightFilesPerPlugin*plugins + preflightFilesPerStep*parallel)
+}
@@ -0,0 +1,71 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestPreflightFiles(t *testing.T) {
+	// With no snapshot, only the language host is counted.
+	assert.Equal(t, uint64(preflightBaseFiles+preflightFilesPerPlugin+preflightFilesPerStep),
+		preflightFiles(&deploy.Target{}, UpdateOptions{}))
+
+	target := &deploy.Target{Snapshot: &deploy.Snapshot{Resources: []*resource.State{
+		{Type: "pulumi:providers:aws", URN: "urn:pulumi:stack::proj::pulumi:providers:aws::default"},
+		{Type: "pulumi:providers:gcp", URN: "urn:pulumi:stack::proj::pulumi:providers:gcp::default"},
+		{Type: "pulumi:providers:gcp", URN: "urn:pulumi:stack::proj::pulumi:providers:gcp::old", Delete: true},
+		{Type: "aws:s3/bucket:Bucket", URN: "urn:pulumi:stack::proj::aws:s3/bucket:Bucket::bucket"},
+	}}}
+	assert.Equal(t, uint64(preflightBaseFiles+3*preflightFilesPerPlugin+10*preflightFilesPerStep),
+		preflightFiles(target, UpdateOptions{Parallel: 10}))
+}
+
+func TestPreflightDiskSpace(t *testing.T) {
+	defer func(min uint64) { DoctorMinFreeSpace = min }(DoctorMinFreeSpace)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	sink := diag.DefaultSink(&stdout, &stderr, diag.FormatOptions{Color: colors.Never})
+	opts := UpdateOptions{Preflight: PreflightOptions{StatePaths: []string{dir}}}
+
+	DoctorMinFreeSpace = 0
+	assert.NoError(t, preflight(&deploy.Target{}, opts, sink))
+
+	DoctorMinFreeSpace = math.MaxUint64
+	err = preflight(&deploy.Target{}, opts, sink)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), dir)
+		assert.Contains(t, err.Error(), "free up space")
+	}
+
+	// Skipped checks never fail.
+	opts.Preflight.Skip = true
+	assert.NoError(t, preflight(&deploy.Target{}, opts, sink))
+}
@@ -0,0 +1,154 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// Plan is the structured result of a preview: the steps the engine would perform to bring the stack up to date with
+// its program, in an order that respects the dependencies between them.
+type Plan struct {
+	Steps   []PlanStep      // the steps that would be performed, in the order the engine produced them.
+	Changes ResourceChanges // the aggregate resource changes by operation type.
+
+	// Diagnostics are the diagnostics, including the program's output, that were reported while the plan was computed.
+	// They are only recorded for plans that are cached.
+	Diagnostics []DiagEventPayload
+
+	// Deprecations counts the uses of resource types and input properties that their providers have deprecated.
+	Deprecations []deploy.DeprecatedUsage
+
+	lock sync.Mutex
+}
+
+// PlanStep describes a single step in a Plan.
+type PlanStep struct {
+	StepEventMetadata
+
+	// Dependencies are the URNs of the resources on which this step's resource depends. Together with the parent and
+	// provider references in the step's state, these form the dependency graph of the plan's resources.
+	Dependencies []resource.URN
+}
+
+// Step returns the step in the plan for the resource with the given URN, if any. If the resource has more than one
+// step (e.g. a replacement), the last is returned.
+func (p *Plan) Step(urn resource.URN) (PlanStep, bool) {
+	for i := len(p.Steps) - 1; i >= 0; i-- {
+		if p.Steps[i].URN == urn {
+			return p.Steps[i], true
+		}
+	}
+	return PlanStep{}, false
+}
+
+// record appends the given step to the plan.
+func (p *Plan) record(op deploy.StepOp, step deploy.Step, secrets *secretFilter, debug bool) {
+	var deps []resource.URN
+	if state := step.Res(); state != nil {
+		deps = append(deps, state.Dependencies...)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.Steps = append(p.Steps, PlanStep{
+		StepEventMetadata: makeStepEventMetadata(op, step, secrets, debug),
+		Dependencies:      deps,
+	})
+}
+
+// updateResult returns the result of a dry run of an update whose plan is the given plan.
+func (p *Plan) updateResult() *UpdateResult {
+	outcomes := make([]ResourceOutcome, len(p.Steps))
+	for i, step := range p.Steps {
+		outcomes[i] = ResourceOutcome{URN: step.URN, Op: step.Op}
+	}
+	return &UpdateResult{
+		Changes:         p.Changes,
+		ChangesDetected: p.Changes.HasChanges(),
+		Resources:       outcomes,
+		Plan:            p,
+		Deprecations:    p.Deprecations,
+	}
+}
+
+// Preview computes the steps necessary to bring the stack up to date with its program without performing them. It
+// returns the same result as a preview run with Update, whose Plan holds the steps. Events are emitted to the
+// context's event channel exactly as they are for a preview run with Update, so callers must continue to drain that
+// channel.
+func Preview(u UpdateInfo, ctx *Context, opts UpdateOptions) (*UpdateResult, result.Result) {
+	contract.Require(u != nil, "update")
+	contract.Require(ctx != nil, "ctx")
+
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+
+	info, err := newPlanContext(u, "preview", ctx.ParentSpan)
+	if err != nil {
+		return nil, result.FromError(err)
+	}
+	defer info.Close()
+
+	events, flushEvents := operationEvents(ctx, opts)
+	defer flushEvents()
+
+	emitter, err := makeEventEmitter(events, u)
+	if err != nil {
+		return nil, result.FromError(err)
+	}
+
+	cached := usePlanCache(u, opts)
+	if cached {
+		emitter.diags = &diagRecorder{}
+	}
+	planOpts := planOptions{
+		UpdateOptions: opts,
+		SourceFunc:    newUpdateSource,
+		Events:        emitter,
+		Diag:          newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters),
+		StatusDiag:    newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters),
+	}
+	if cached {
+		return previewWithCache(ctx, info, planOpts)
+	}
+	return previewPlan(ctx, info, planOpts)
+}
+
+// previewPlan runs a preview, recording its steps into a new Plan that is returned as part of its result.
+func previewPlan(ctx *Context, info *planContext, opts planOptions) (*UpdateResult, result.Result) {
+	plan := &Plan{}
+	opts.previewPlan = plan
+	updateResult, res := update(ctx, info, opts, true /*dryRun*/)
+	if res != nil {
+		return nil, res
+	}
+
+	if updateResult == nil {
+		updateResult = &UpdateResult{}
+	}
+	plan.Changes = updateResult.Changes
+	plan.Deprecations = updateResult.Deprecations
+	updateResult.Plan = plan
+	return updateResult, nil
+}
@@ -19,10 +19,10 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/util/contract"
-	"github.com/pulumi/pulumi/pkg/util/fsutil"
 	"github.com/pulumi/pulumi/pkg/util/logging"
 	"github.com/pulumi/pulumi/pkg/util/result"
 )
@@ -34,13 +34,16 @@ type QueryOptions struct {
 	host       plugin.Host  // the plugin host to use for this query.
 	pwd, main  string
 	plugctx    *plugin.Context
+
+	configOverrides map[config.Key]string // configuration values that override the stack's for this query only.
 }
 
 func Query(ctx *Context, u UpdateInfo, opts UpdateOptions) result.Result {
 	contract.Require(u != nil, "update")
 	contract.Require(ctx != nil, "ctx")
 
-	defer func() { ctx.Events <- cancelEvent() }()
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
 
 	tracingSpan := func(opName string, parentSpan opentracing.SpanContext) opentracing.Span {
 		// Create a root span for the operation
@@ -62,15 +65,15 @@ func Query(ctx *Context, u UpdateInfo, opts UpdateOptions) result.Result {
 
 	// First, load the package metadata and the deployment target in preparation for executing the package's program
 	// and creating resources.  This includes fetching its pwd and main overrides.
-	diag := newEventSink(emitter, false)
-	statusDiag := newEventSink(emitter, true)
+	diag := newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters)
+	statusDiag := newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters)
 
 	proj, target := u.GetProject(), u.GetTarget()
 	contract.Assert(proj != nil)
 	contract.Assert(target != nil)
 
-	pwd, main, plugctx, err := ProjectInfoContext(&Projinfo{Proj: proj, Root: u.GetRoot()}, opts.host,
-		target, diag, statusDiag, tracingSpan)
+	pwd, main, plugctx, err := projectInfoContext(&Projinfo{Proj: proj, Root: u.GetRoot()}, opts.host,
+		opts.PluginPool, target, diag, statusDiag, tracingSpan)
 	if err != nil {
 		return result.FromError(err)
 	}
@@ -83,6 +86,8 @@ func Query(ctx *Context, u UpdateInfo, opts UpdateOptions) result.Result {
 		pwd:        pwd,
 		main:       main,
 		plugctx:    plugctx,
+
+		configOverrides: opts.ConfigOverrides,
 	})
 }
 
@@ -105,24 +110,19 @@ func newQuerySource(cancel context.Context, client deploy.BackendClient, u Updat
 	}
 
 	// If that succeeded, create a new source that will perform interpretation of the compiled program.
-	// TODO[pulumi/pulumi#88]: we are passing `nil` as the arguments map; we need to allow a way to pass these.
 	return deploy.NewQuerySource(cancel, opts.plugctx, client, &deploy.EvalRunInfo{
-		Proj:    u.GetProject(),
-		Pwd:     opts.pwd,
-		Program: opts.main,
-		Target:  u.GetTarget(),
+		Proj:            u.GetProject(),
+		Pwd:             opts.pwd,
+		Program:         opts.main,
+		Target:          u.GetTarget(),
+		ConfigOverrides: opts.configOverrides,
 	})
 }
 
 func query(ctx *Context, u UpdateInfo, opts QueryOptions) result.Result {
-	// Make the current working directory the same as the program's, and restore it upon exit.
-	done, chErr := fsutil.Chdir(opts.plugctx.Pwd)
-	if chErr != nil {
-		return result.FromError(chErr)
-	}
-	defer done()
-
-	if res := runQuery(ctx, u, opts); res != nil {
+	res := runQuery(ctx, u, opts)
+	flushDiagnostics(opts.Diag, opts.StatusDiag)
+	if res != nil {
 		if res.IsBail() {
 			return res
 		}
@@ -23,11 +23,16 @@ import (
 	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
-func Refresh(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (ResourceChanges, result.Result) {
+func Refresh(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
 	contract.Require(u != nil, "u")
 	contract.Require(ctx != nil, "ctx")
 
-	defer func() { ctx.Events <- cancelEvent() }()
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
 
 	info, err := newPlanContext(u, "refresh", ctx.ParentSpan)
 	if err != nil {
@@ -35,7 +40,10 @@ func Refresh(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (Resou
 	}
 	defer info.Close()
 
-	emitter, err := makeEventEmitter(ctx.Events, u)
+	events, flushEvents := operationEvents(ctx, opts)
+	defer flushEvents()
+
+	emitter, err := makeEventEmitter(events, u)
 	if err != nil {
 		return nil, result.FromError(err)
 	}
@@ -47,8 +55,8 @@ func Refresh(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (Resou
 		UpdateOptions: opts,
 		SourceFunc:    newRefreshSource,
 		Events:        emitter,
-		Diag:          newEventSink(emitter, false),
-		StatusDiag:    newEventSink(emitter, true),
+		Diag:          newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters),
+		StatusDiag:    newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters),
 		isRefresh:     true,
 	}, dryRun)
 }
@@ -65,7 +73,7 @@ func newRefreshSource(client deploy.BackendClient, opts planOptions, proj *works
 	}
 
 	// Like Update, if we're missing plugins, attempt to download the missing plugins.
-	if err := ensurePluginsAreInstalled(plugins); err != nil {
+	if err := ensurePluginsAreInstalled(plugins, plugctx.Diag); err != nil {
 		logging.V(7).Infof("newRefreshSource(): failed to install missing plugins: %v", err)
 	}
 
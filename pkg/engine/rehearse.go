@@ -0,0 +1,238 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// RehearsalOptions controls a rehearsal. See Rehearse.
+type RehearsalOptions struct {
+	// Stack is the name of the shadow stack. If it is empty, a unique name is made from the name of the stack being
+	// rehearsed.
+	Stack tokens.QName
+
+	// NamePrefix, if set, is prepended to the string "name" input of each resource the program registers, so that
+	// resources whose physical names are set by the program do not collide with those of the stack being rehearsed.
+	// Resources whose names are chosen by their providers are distinct already, as the shadow stack's URNs differ.
+	NamePrefix string
+
+	// State creates the snapshot manager that persists the shadow stack's state, given the snapshot that the state
+	// starts from. It is called once for the deployment and once for the destroy. The snapshot managers that it creates
+	// must implement SnapshotReader, so that the destroy can start from the state that the deployment left behind.
+	State func(base *deploy.Snapshot) SnapshotManager
+}
+
+// RehearsalResult is the result of a rehearsal.
+type RehearsalResult struct {
+	Stack   tokens.QName  // the name of the shadow stack.
+	Update  *UpdateResult // the result of deploying the program into the shadow stack.
+	Destroy *UpdateResult // the result of destroying the shadow stack, or nil if the deployment left nothing behind.
+
+	// Leftovers are the resources that remain in the shadow stack's state because the destroy failed. They must be
+	// cleaned up by hand.
+	Leftovers []resource.URN
+}
+
+// rehearsalInfo is the UpdateInfo of a shadow stack: that of the stack being rehearsed, with its own target.
+type rehearsalInfo struct {
+	UpdateInfo
+	target *deploy.Target
+}
+
+func (u *rehearsalInfo) GetTarget() *deploy.Target {
+	return u.target
+}
+
+// Rehearse deploys the program of the given update into a temporary shadow stack, checks the result, and then
+// destroys the shadow stack, so that risky changes can be tried out before they are applied to the stack itself.
+//
+// The shadow stack has the stack's configuration but none of its state: the program is deployed from scratch, and its
+// state is kept by the snapshot managers that the options create rather than the context's, which is not used. The
+// program sees the shadow stack's name as that of its stack. Once the program has been deployed, the referential
+// integrity of the shadow stack's state is verified, and the checks of the project and the options are run (unless
+// they are skipped). The shadow stack is destroyed whether or not the deployment and the checks succeed.
+//
+// The events of both operations are sent to the context's event channel, tagged with the name of the shadow stack,
+// followed by a single cancellation event. If either operation fails, or any check fails, the rehearsal fails.
+func Rehearse(u UpdateInfo, ctx *Context, opts UpdateOptions,
+	rehearsal RehearsalOptions) (*RehearsalResult, result.Result) {
+
+	contract.Require(u != nil, "u")
+	contract.Require(ctx != nil, "ctx")
+	contract.Require(rehearsal.State != nil, "rehearsal.State")
+
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+
+	target := u.GetTarget()
+	stack := rehearsal.Stack
+	if stack == "" {
+		name, err := shadowStackName(target.Name)
+		if err != nil {
+			return nil, result.FromError(err)
+		}
+		stack = name
+	}
+	if stack == target.Name {
+		return nil, result.Errorf("the shadow stack must not be the stack being rehearsed")
+	}
+
+	shadow := *target
+	shadow.Name, shadow.Snapshot = stack, nil
+	info := &rehearsalInfo{UpdateInfo: u, target: &shadow}
+
+	// The shadow stack's state is verified before the checks are run, as a deployment whose state is damaged would
+	// otherwise be destroyed without anyone finding out.
+	updateOpts := opts
+	updateOpts.VerifySnapshot = true
+	if rehearsal.NamePrefix != "" {
+		updateOpts.Transformations = append(append([]deploy.ResourceTransformation(nil), opts.Transformations...),
+			prefixNameTransformation(rehearsal.NamePrefix))
+	}
+
+	rehearsalResult := &RehearsalResult{Stack: stack}
+	logging.V(7).Infof("Rehearse: deploying %s into shadow stack %s", target.Name, stack)
+	updateManager := rehearsal.State(nil)
+	updateResult, res := Update(info, rehearsalContext(ctx, stack, updateManager), updateOpts, false)
+	rehearsalResult.Update = updateResult
+	if updateResult.ChecksFailed() {
+		res = result.Merge(res, result.Bail())
+	}
+
+	// Destroy whatever the deployment left behind, even if it failed.
+	snap, err := readShadowState(updateManager)
+	if err != nil {
+		return rehearsalResult, result.Merge(res, result.FromError(err))
+	}
+	if snap == nil || len(snap.Resources) == 0 {
+		return rehearsalResult, res
+	}
+
+	logging.V(7).Infof("Rehearse: destroying shadow stack %s", stack)
+	destroyTarget := shadow
+	destroyTarget.Snapshot = snap
+	destroyInfo := &rehearsalInfo{UpdateInfo: u, target: &destroyTarget}
+	destroyOpts := opts
+	destroyOpts.Transformations = nil
+	destroyOpts.DestroyExclude, destroyOpts.DestroyExcludeDependents = nil, false
+	destroyManager := rehearsal.State(snap)
+	destroyResult, destroyRes := Destroy(destroyInfo, rehearsalContext(ctx, stack, destroyManager), destroyOpts, false)
+	rehearsalResult.Destroy = destroyResult
+	res = result.Merge(res, destroyRes)
+
+	if leftover, err := readShadowState(destroyManager); err != nil {
+		res = result.Merge(res, result.FromError(err))
+	} else if leftover != nil {
+		for _, state := range leftover.Resources {
+			if !providers.IsProviderType(state.Type) && state.Type != resource.RootStackType {
+				rehearsalResult.Leftovers = append(rehearsalResult.Leftovers, state.URN)
+			}
+		}
+	}
+	return rehearsalResult, res
+}
+
+// shadowStackName returns a unique name for a shadow stack of the given stack.
+func shadowStackName(stack tokens.QName) (tokens.QName, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "naming the shadow stack")
+	}
+	return tokens.QName(fmt.Sprintf("%s-rehearsal-%s", stack, hex.EncodeToString(b))), nil
+}
+
+// rehearsalContext returns a context for one of the operations of a rehearsal, whose state is persisted by the given
+// snapshot manager. Its events are forwarded to the rehearsal's context, tagged with the name of the shadow stack;
+// the cancellation event that each operation sends when it finishes is dropped in favor of the rehearsal's own.
+func rehearsalContext(ctx *Context, stack tokens.QName, manager SnapshotManager) *Context {
+	events := make(chan Event)
+	go func() {
+		for e := range events {
+			if e.Type == CancelEvent {
+				// The operation sends its cancellation event last, so there is nothing more to forward.
+				return
+			}
+			e.Stack = stack
+			ctx.Events <- e
+		}
+	}()
+
+	return &Context{
+		Cancel:          ctx.Cancel,
+		Events:          events,
+		SnapshotManager: manager,
+		BackendClient:   ctx.BackendClient,
+		ParentSpan:      ctx.ParentSpan,
+		Confirmations:   ctx.Confirmations,
+		Control:         ctx.Control,
+		AuditLog:        ctx.AuditLog,
+		AuditPrincipal:  ctx.AuditPrincipal,
+		Metrics:         ctx.Metrics,
+		Artifacts:       ctx.Artifacts,
+	}
+}
+
+// readShadowState returns the state that the given snapshot manager persisted, and closes it.
+func readShadowState(manager SnapshotManager) (*deploy.Snapshot, error) {
+	reader, ok := manager.(SnapshotReader)
+	if !ok {
+		contract.IgnoreClose(manager)
+		return nil, errors.New("the shadow stack's state cannot be read, so it cannot be destroyed")
+	}
+	snap, err := reader.Snapshot()
+	if err != nil {
+		contract.IgnoreClose(manager)
+		return nil, errors.Wrap(err, "reading the shadow stack's state")
+	}
+	if err = manager.Close(); err != nil {
+		return nil, errors.Wrap(err, "saving the shadow stack's state")
+	}
+	return snap, nil
+}
+
+// prefixNameTransformation returns a transformation that prepends the given prefix to the string "name" input of
+// each resource other than a provider.
+func prefixNameTransformation(prefix string) deploy.ResourceTransformation {
+	return func(urn resource.URN, goal *resource.Goal) error {
+		if providers.IsProviderType(goal.Type) {
+			return nil
+		}
+		name, ok := goal.Properties["name"]
+		if !ok || !name.IsString() {
+			return nil
+		}
+		props := goal.Properties.Copy()
+		props["name"] = resource.NewStringProperty(prefix + name.StringValue())
+		goal.Properties = props
+		return nil
+	}
+}
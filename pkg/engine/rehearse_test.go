@@ -0,0 +1,140 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// readableJournal is a Journal that can return the snapshot that it would persist.
+type readableJournal struct {
+	*Journal
+	base *deploy.Snapshot
+}
+
+func (j *readableJournal) Snapshot() (*deploy.Snapshot, error) {
+	return j.Snap(j.base), nil
+}
+
+func TestRehearse(t *testing.T) {
+	var lock sync.Mutex
+	var created, deleted []string
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CreateF: func(urn resource.URN,
+					inputs resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
+
+					lock.Lock()
+					defer lock.Unlock()
+					created = append(created, string(urn.Stack())+"/"+inputs["name"].StringValue())
+					return "id", inputs, resource.StatusOK, nil
+				},
+				DeleteF: func(urn resource.URN, id resource.ID, olds resource.PropertyMap) (resource.Status, error) {
+					lock.Lock()
+					defer lock.Unlock()
+					deleted = append(deleted, string(urn.Stack())+"/"+olds["name"].StringValue())
+					return resource.StatusOK, nil
+				},
+				InvokeF: func(tok tokens.ModuleMember,
+					args resource.PropertyMap) (resource.PropertyMap, []plugin.CheckFailure, error) {
+					return resource.PropertyMap{"healthy": resource.NewBoolProperty(true)}, nil, nil
+				},
+			}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"name": resource.NewStringProperty("bucket")}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{host: host}}
+
+	run := func(opts UpdateOptions, rehearsal RehearsalOptions) (*RehearsalResult, []Event, bool) {
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		events := make(chan Event)
+		done := make(chan []Event)
+		go func() {
+			var all []Event
+			for e := range events {
+				all = append(all, e)
+			}
+			done <- all
+		}()
+
+		rehearsal.State = func(base *deploy.Snapshot) SnapshotManager {
+			return &readableJournal{Journal: newJournal(), base: base}
+		}
+		ctx := &Context{Cancel: cancelCtx, Events: events}
+		info := &updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}
+		rehearsalResult, res := Rehearse(info, ctx, opts, rehearsal)
+		close(events)
+		return rehearsalResult, <-done, res == nil
+	}
+
+	// The program is deployed into a shadow stack with a name of its own, and then destroyed.
+	rehearsalResult, events, ok := run(p.Options, RehearsalOptions{NamePrefix: "rehearsal-"})
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(string(rehearsalResult.Stack), "test-rehearsal-"))
+	shadowBucket := string(rehearsalResult.Stack) + "/rehearsal-bucket"
+	assert.Equal(t, []string{shadowBucket}, created)
+	assert.Equal(t, []string{shadowBucket}, deleted)
+	assert.NotNil(t, rehearsalResult.Update)
+	assert.NotNil(t, rehearsalResult.Destroy)
+	assert.Empty(t, rehearsalResult.Leftovers)
+
+	// Every event is tagged with the shadow stack's name, and a single cancellation event comes last.
+	if assert.NotEmpty(t, events) {
+		for _, e := range events[:len(events)-1] {
+			assert.NotEqual(t, CancelEvent, e.Type)
+			assert.Equal(t, rehearsalResult.Stack, e.Stack)
+		}
+		assert.Equal(t, CancelEvent, events[len(events)-1].Type)
+	}
+
+	// A failing check fails the rehearsal, but the shadow stack is destroyed all the same.
+	created, deleted = nil, nil
+	opts := p.Options
+	opts.Checks = []workspace.ProjectCheck{{Name: "unhealthy", Invoke: &workspace.InvokeCheck{
+		Function: "pkgA:m:getStatus",
+		Expect:   map[string]interface{}{"healthy": false},
+	}}}
+	rehearsalResult, _, ok = run(opts, RehearsalOptions{Stack: "shadow"})
+	assert.False(t, ok)
+	assert.Equal(t, tokens.QName("shadow"), rehearsalResult.Stack)
+	assert.True(t, rehearsalResult.Update.ChecksFailed())
+	assert.Equal(t, []string{"shadow/bucket"}, created)
+	assert.Equal(t, []string{"shadow/bucket"}, deleted)
+
+	// The stack being rehearsed cannot be its own shadow.
+	_, _, ok = run(p.Options, RehearsalOptions{Stack: "test"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,161 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// StateRename renames the resource with the given URN in the given snapshot, so that the state of a resource whose
+// name or parent was changed by a refactoring of the program can be carried over without replacing the resource. An
+// empty name or parent leaves the resource's name or parent as it is.
+//
+// The resource's URN is rewritten, as are the URNs of any of its descendants that embed its type, and every reference
+// to the rewritten URNs: parents, dependencies, property dependencies, and provider references. Each rewritten
+// resource records its old URN as an alias. The snapshot is modified in place, and only if the rename is valid: the
+// new parent must precede the resource in the snapshot, and the new URNs must not already be in use. StateRename
+// returns the resource's new URN.
+func StateRename(snap *deploy.Snapshot, urn resource.URN, name tokens.QName,
+	parent resource.URN) (resource.URN, error) {
+
+	contract.Require(snap != nil, "snap")
+
+	if err := snap.VerifyIntegrity(); err != nil {
+		return "", errors.Wrap(err, "the snapshot is invalid")
+	}
+
+	// Find the resource to rename.
+	index := -1
+	for i, res := range snap.Resources {
+		if res.URN != urn {
+			continue
+		}
+		if res.Delete {
+			return "", errors.Errorf("'%s' has a replacement that is pending deletion; run an update first", urn)
+		}
+		index = i
+	}
+	if index == -1 {
+		return "", errors.Errorf("no resource with URN '%s' exists in the snapshot", urn)
+	}
+	res := snap.Resources[index]
+	switch {
+	case res.Type == resource.RootStackType:
+		return "", errors.New("the stack's root resource cannot be renamed")
+	case providers.IsDefaultProvider(urn):
+		return "", errors.New("default providers are named by the engine and cannot be renamed")
+	}
+
+	if name == "" {
+		name = urn.Name()
+	}
+	if parent == "" {
+		parent = res.Parent
+	}
+	if parent != res.Parent {
+		// The new parent must precede the resource in the snapshot. This also keeps the resource from becoming its
+		// own ancestor, as a resource's descendants follow it.
+		parentIndex := -1
+		for i, r := range snap.Resources[:index] {
+			if r.URN == parent && !r.Delete {
+				parentIndex = i
+			}
+		}
+		if parentIndex == -1 {
+			return "", errors.Errorf("the new parent '%s' does not precede '%s' in the snapshot", parent, urn)
+		}
+	}
+
+	// Compute the new URNs. A resource's URN embeds the type of its parent, so a new parent changes the URNs of the
+	// resource's descendants as well.
+	renames := map[resource.URN]resource.URN{urn: renamedURN(urn, parent, res.Type, name)}
+	if renames[urn] == urn {
+		return "", errors.Errorf("'%s' already has the given name and parent", urn)
+	}
+	for _, r := range snap.Resources[index+1:] {
+		if newParent, has := renames[r.Parent]; has && newParent.QualifiedType() != r.Parent.QualifiedType() {
+			renames[r.URN] = renamedURN(r.URN, newParent, r.Type, r.URN.Name())
+		}
+	}
+	for _, r := range snap.Resources {
+		if _, renamed := renames[r.URN]; renamed {
+			continue
+		}
+		for old, new := range renames {
+			if r.URN == new {
+				return "", errors.Errorf("cannot rename '%s' to '%s': a resource with that URN already exists", old, new)
+			}
+		}
+	}
+
+	// Rewrite the resources, and those recorded by any pending operations.
+	for _, r := range snap.Resources {
+		rewriteRenamedState(r, renames)
+	}
+	for _, op := range snap.PendingOperations {
+		rewriteRenamedState(op.Resource, renames)
+	}
+	contract.AssertNoErrorf(snap.VerifyIntegrity(), "renaming produced an invalid snapshot")
+	return renames[urn], nil
+}
+
+// renamedURN returns the URN of the resource with the given URN, type, and name once it is a child of the given parent.
+func renamedURN(urn resource.URN, parent resource.URN, t tokens.Type, name tokens.QName) resource.URN {
+	parentType := tokens.Type("")
+	if parent != "" && parent.Type() != resource.RootStackType {
+		parentType = parent.QualifiedType()
+	}
+	return resource.NewURN(urn.Stack(), urn.Project(), parentType, t, name)
+}
+
+// rewriteRenamedState rewrites the URN of the given resource and its references to other resources according to the
+// given renames, recording the resource's old URN as an alias if it is renamed.
+func rewriteRenamedState(res *resource.State, renames map[resource.URN]resource.URN) {
+	rewrite := func(urn resource.URN) resource.URN {
+		if new, has := renames[urn]; has {
+			return new
+		}
+		return urn
+	}
+
+	if new, has := renames[res.URN]; has {
+		res.Aliases = append(res.Aliases, res.URN)
+		res.URN = new
+	}
+	res.Parent = rewrite(res.Parent)
+	for i, dep := range res.Dependencies {
+		res.Dependencies[i] = rewrite(dep)
+	}
+	for _, deps := range res.PropertyDependencies {
+		for i, dep := range deps {
+			deps[i] = rewrite(dep)
+		}
+	}
+	if res.Provider != "" {
+		ref, err := providers.ParseReference(res.Provider)
+		contract.AssertNoErrorf(err, "failed to parse provider reference from validated snapshot")
+		if new, has := renames[ref.URN()]; has {
+			ref, err = providers.NewReference(new, ref.ID())
+			contract.AssertNoErrorf(err, "failed to generate provider reference from valid reference")
+			res.Provider = ref.String()
+		}
+	}
+}
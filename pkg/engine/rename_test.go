@@ -0,0 +1,87 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+)
+
+func TestStateRename(t *testing.T) {
+	newSnap := func() (*deploy.Snapshot, map[string]*resource.State) {
+		prov := newGraphTestState("prov", providers.MakeProviderType("pkgA"), "", "")
+		ref, err := providers.NewReference(prov.URN, prov.ID)
+		assert.NoError(t, err)
+
+		compA := newGraphTestState("compA", "test:m:comp", "", "")
+		compA.Custom, compA.ID = false, ""
+		compB := newGraphTestState("compB", "test:m:other", "", "")
+		compB.Custom, compB.ID = false, ""
+		child := newGraphTestState("child", "pkgA:m:typA", compA.URN, ref.String())
+		child.URN = resource.NewURN("test", "test", compA.URN.QualifiedType(), child.Type, "child")
+		dependent := newGraphTestState("dependent", "pkgA:m:typA", "", ref.String(), child.URN)
+		dependent.PropertyDependencies = map[resource.PropertyKey][]resource.URN{"foo": {child.URN}}
+
+		return &deploy.Snapshot{Resources: []*resource.State{prov, compB, compA, child, dependent}},
+			map[string]*resource.State{"prov": prov, "compA": compA, "compB": compB, "child": child, "dependent": dependent}
+	}
+
+	// Renaming a resource rewrites its URN and every reference to it, and records its old URN as an alias.
+	snap, res := newSnap()
+	oldURN := res["child"].URN
+	newURN, err := StateRename(snap, oldURN, "renamed", "")
+	assert.NoError(t, err)
+	assert.Equal(t, resource.NewURN("test", "test", "test:m:comp", "pkgA:m:typA", "renamed"), newURN)
+	assert.Equal(t, newURN, res["child"].URN)
+	assert.Equal(t, []resource.URN{oldURN}, res["child"].Aliases)
+	assert.Equal(t, []resource.URN{newURN}, res["dependent"].Dependencies)
+	assert.Equal(t, []resource.URN{newURN}, res["dependent"].PropertyDependencies["foo"])
+
+	// Moving a resource to a new parent rewrites the URNs of its descendants, which embed its type.
+	snap, res = newSnap()
+	childURN := res["child"].URN
+	newURN, err = StateRename(snap, res["compA"].URN, "", res["compB"].URN)
+	assert.NoError(t, err)
+	assert.Equal(t, resource.NewURN("test", "test", "test:m:other", "test:m:comp", "compA"), newURN)
+	assert.Equal(t, newURN, res["child"].Parent)
+	assert.Equal(t, resource.NewURN("test", "test", "test:m:other$test:m:comp", "pkgA:m:typA", "child"),
+		res["child"].URN)
+	assert.Equal(t, []resource.URN{childURN}, res["child"].Aliases)
+	assert.Equal(t, []resource.URN{res["child"].URN}, res["dependent"].Dependencies)
+
+	// Renaming a provider rewrites the references to it.
+	snap, res = newSnap()
+	newURN, err = StateRename(snap, res["prov"].URN, "renamed", "")
+	assert.NoError(t, err)
+	ref, err := providers.ParseReference(res["child"].Provider)
+	assert.NoError(t, err)
+	assert.Equal(t, newURN, ref.URN())
+
+	// Renames that would produce an invalid snapshot are rejected, and leave the snapshot untouched.
+	snap, res = newSnap()
+	_, err = StateRename(snap, res["dependent"].URN, "child", res["compA"].URN)
+	assert.Error(t, err)
+	_, err = StateRename(snap, res["compB"].URN, "", res["compA"].URN)
+	assert.Error(t, err)
+	_, err = StateRename(snap, "urn:pulumi:test::test::pkgA:m:typA::missing", "other", "")
+	assert.Error(t, err)
+	assert.Equal(t, oldURN, res["child"].URN)
+	assert.Nil(t, res["child"].Aliases)
+}
@@ -0,0 +1,165 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/edit"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// RepairAction describes how Repair reconciled a single pending operation with the snapshot.
+type RepairAction string
+
+const (
+	// RepairRefreshed indicates that the resource exists and its state in the snapshot was refreshed from its provider.
+	RepairRefreshed RepairAction = "refreshed"
+	// RepairRemoved indicates that the resource no longer exists and was removed from the snapshot.
+	RepairRemoved RepairAction = "removed"
+	// RepairDiscarded indicates that the operation had no lasting effect and was simply dropped.
+	RepairDiscarded RepairAction = "discarded"
+	// RepairUnresolved indicates that the outcome of the operation could not be determined. The operation is dropped,
+	// but the resource should be checked by hand.
+	RepairUnresolved RepairAction = "unresolved"
+)
+
+// RepairEntry reports how Repair reconciled a single pending operation.
+type RepairEntry struct {
+	URN       resource.URN           // the URN of the resource the operation was acting upon.
+	Operation resource.OperationType // the kind of operation that was pending.
+	Action    RepairAction           // what Repair did about it.
+	Message   string                 // a human-readable explanation of the action.
+}
+
+// Repair reconciles the pending operations left in a snapshot by a deployment that died mid-step. For each pending
+// operation, the resource's provider is asked for the resource's live state, and the snapshot is fixed up to match:
+// resources that exist are refreshed and resources that are gone are removed. The snapshot is modified in place and
+// is left with no pending operations. The returned entries describe what was done for each operation.
+func Repair(host plugin.Host, snap *deploy.Snapshot) ([]RepairEntry, error) {
+	contract.Require(host != nil, "host")
+	contract.Require(snap != nil, "snap")
+
+	if len(snap.PendingOperations) == 0 {
+		return nil, nil
+	}
+
+	reg, err := providers.NewRegistry(host, snap.Resources, false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading providers")
+	}
+
+	report := make([]RepairEntry, len(snap.PendingOperations))
+	for i, op := range snap.PendingOperations {
+		report[i] = repairOperation(reg, snap, op)
+	}
+	snap.PendingOperations = nil
+
+	return report, nil
+}
+
+// repairOperation reconciles a single pending operation with the given snapshot.
+func repairOperation(reg *providers.Registry, snap *deploy.Snapshot, op resource.Operation) RepairEntry {
+	entry := RepairEntry{URN: op.Resource.URN, Operation: op.Type}
+	unresolved := func(msg string) RepairEntry {
+		entry.Action, entry.Message = RepairUnresolved, msg
+		return entry
+	}
+
+	// Interrupted creates never recorded an ID, so there is nothing we can ask the provider about.
+	if op.Type == resource.OperationTypeCreating {
+		if !op.Resource.Custom {
+			entry.Action, entry.Message = RepairDiscarded, "component resources have no provider state"
+			return entry
+		}
+		return unresolved("the create was interrupted before the provider returned an ID; " +
+			"if the resource exists, import it into the stack")
+	}
+
+	// Find the resource's entry in the snapshot. The state recorded for a pending update does not carry the resource's
+	// ID, so updates are matched on URN alone; everything else is matched on URN and ID.
+	index := -1
+	for i, res := range snap.Resources {
+		if res.URN != op.Resource.URN {
+			continue
+		}
+		if (op.Type == resource.OperationTypeUpdating && !res.Delete) || res.ID == op.Resource.ID {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		if op.Type == resource.OperationTypeReading {
+			entry.Action, entry.Message = RepairDiscarded, "reads have no side effects; the next update will read again"
+			return entry
+		}
+		return unresolved("the resource is not present in the snapshot")
+	}
+
+	old := snap.Resources[index]
+	if !old.Custom || providers.IsProviderType(old.Type) {
+		entry.Action, entry.Message = RepairDiscarded, "the resource has no state to refresh"
+		return entry
+	}
+
+	ref, err := providers.ParseReference(old.Provider)
+	if err != nil {
+		return unresolved(err.Error())
+	}
+	prov, ok := reg.GetProvider(ref)
+	if !ok {
+		return unresolved("the resource's provider could not be loaded")
+	}
+
+	initErrors := old.InitErrors
+	refreshed, _, err := prov.Read(old.URN, old.ID, old.Inputs, old.Outputs)
+	if err != nil {
+		initErr, isInitErr := err.(*plugin.InitError)
+		if !isInitErr {
+			return unresolved(errors.Wrap(err, "reading the resource").Error())
+		}
+		initErrors = initErr.Reasons
+	}
+
+	if refreshed.Outputs == nil {
+		if err = edit.DeleteResource(snap, old); err != nil {
+			return unresolved(errors.Wrap(err, "the resource no longer exists, but cannot be removed").Error())
+		}
+		entry.Action, entry.Message = RepairRemoved, "the resource no longer exists"
+		return entry
+	}
+
+	inputs := old.Inputs
+	if refreshed.Inputs != nil {
+		inputs = refreshed.Inputs
+	}
+	state := resource.NewState(old.Type, old.URN, old.Custom, old.Delete, old.ID, inputs, refreshed.Outputs,
+		old.Parent, old.Protect, old.External, old.Dependencies, initErrors, old.Provider,
+		old.PropertyDependencies, old.PendingReplacement, old.AdditionalSecretOutputs, old.Aliases)
+	state.Annotations = old.Annotations
+	state.CustomTimeouts = old.CustomTimeouts
+	state.Artifacts = old.Artifacts
+	snap.Resources[index] = state
+
+	entry.Action, entry.Message = RepairRefreshed, "the resource exists; its state was refreshed"
+	if op.Type == resource.OperationTypeDeleting {
+		entry.Message = "the delete did not complete; the resource still exists and its state was refreshed"
+	}
+	return entry
+}
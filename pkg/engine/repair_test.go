@@ -0,0 +1,82 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestRepair(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ReadF: func(urn resource.URN, id resource.ID,
+					inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+					if id != "resA" {
+						return plugin.ReadResult{}, resource.StatusOK, nil
+					}
+					return plugin.ReadResult{
+						Outputs: resource.PropertyMap{"foo": resource.NewStringProperty("bar")},
+					}, resource.StatusOK, nil
+				},
+			}, nil
+		}),
+	}
+	host := deploytest.NewPluginHost(nil, nil, nil, loaders...)
+
+	prov := newGraphTestState("prov", providers.MakeProviderType("pkgA"), "", "")
+	ref, err := providers.NewReference(prov.URN, prov.ID)
+	assert.NoError(t, err)
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", ref.String())
+	resB := newGraphTestState("resB", "pkgA:m:typA", "", ref.String())
+	resC := newGraphTestState("resC", "pkgA:m:typA", "", ref.String())
+	resC.ID = ""
+
+	// The state recorded for a pending update does not carry the resource's ID.
+	updating := *resA
+	updating.ID = ""
+
+	snap := &deploy.Snapshot{
+		Resources: []*resource.State{prov, resA, resB},
+		PendingOperations: []resource.Operation{
+			resource.NewOperation(&updating, resource.OperationTypeUpdating),
+			resource.NewOperation(resB, resource.OperationTypeDeleting),
+			resource.NewOperation(resC, resource.OperationTypeCreating),
+		},
+	}
+
+	report, err := Repair(host, snap)
+	assert.NoError(t, err)
+	assert.Len(t, report, 3)
+	assert.Equal(t, RepairRefreshed, report[0].Action)
+	assert.Equal(t, RepairRemoved, report[1].Action)
+	assert.Equal(t, RepairUnresolved, report[2].Action)
+
+	assert.Nil(t, snap.PendingOperations)
+	assert.Len(t, snap.Resources, 2)
+	assert.Equal(t, resA.URN, snap.Resources[1].URN)
+	assert.Equal(t, resource.NewStringProperty("bar"), snap.Resources[1].Outputs["foo"])
+	assert.NoError(t, snap.VerifyIntegrity())
+}
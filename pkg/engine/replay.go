@@ -0,0 +1,189 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"encoding/gob"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// ReplayBundleVersion is the version of the replay bundle format written by WriteReplayBundle.
+const ReplayBundleVersion = 1
+
+func init() {
+	// Event payloads, and the property values within them, are interfaces, so their concrete types must be registered
+	// before bundles can be encoded or decoded.
+	for _, v := range []interface{}{
+		AggregateProgressEventPayload{},
+		CancellationEventPayload{},
+		CheckResultsEventPayload{},
+		ConfirmationRequiredEventPayload{},
+		DeletionOrderEventPayload{},
+		DiagEventPayload{},
+		InitErrorsEventPayload{},
+		PausedEventPayload{},
+		PendingDeleteEventPayload{},
+		PlanCacheEventPayload{},
+		PluginLifecycleEventPayload{},
+		PolicyViolationEventPayload{},
+		PreludeEventPayload{},
+		ProviderChangeEventPayload{},
+		RefreshProgressEventPayload{},
+		ResourceOperationFailedPayload{},
+		ResourceOutputsEventPayload{},
+		ResourcePreEventPayload{},
+		StdoutEventPayload{},
+		StepDependenciesEventPayload{},
+		StepProgressEventPayload{},
+		SummaryEventPayload{},
+		[]resource.PropertyValue{},
+		resource.PropertyMap{},
+		resource.Computed{},
+		resource.Output{},
+		resource.Secret{},
+		&resource.Asset{},
+		&resource.Archive{},
+	} {
+		gob.Register(v)
+	}
+}
+
+// ReplayBundle records the stream of events emitted by an update, along with the time at which each was emitted, so
+// that the stream can be replayed later without running the update's program or contacting its providers. Because
+// step events carry the old and new states of the resources they affect, a bundle also records the inputs of each
+// step, subject to the masking of secrets that the engine applies to all events.
+type ReplayBundle struct {
+	Version int                 // the version of the bundle's format.
+	Kind    apitype.UpdateKind  // the kind of the update whose events were recorded.
+	Stack   tokens.QName        // the stack that was updated.
+	Project tokens.PackageName  // the project to which the stack belongs.
+	DryRun  bool                // true if the update was a preview.
+	Started time.Time           // the time at which recording started.
+	Events  []ReplayBundleEvent // the recorded events, in the order in which they were emitted.
+}
+
+// ReplayBundleEvent is an event recorded in a replay bundle.
+type ReplayBundleEvent struct {
+	Offset time.Duration // the time at which the event was emitted, relative to the start of the recording.
+	Event  Event         // the event itself.
+}
+
+// ReplayRecorder records the events of an update into a replay bundle. It is safe for concurrent use.
+type ReplayRecorder struct {
+	lock   sync.Mutex
+	bundle ReplayBundle
+}
+
+// NewReplayRecorder creates a recorder for the events of an update of the given kind to the given stack.
+func NewReplayRecorder(kind apitype.UpdateKind, stack tokens.QName, proj tokens.PackageName,
+	dryRun bool) *ReplayRecorder {
+
+	return &ReplayRecorder{
+		bundle: ReplayBundle{
+			Version: ReplayBundleVersion,
+			Kind:    kind,
+			Stack:   stack,
+			Project: proj,
+			DryRun:  dryRun,
+			Started: time.Now(),
+		},
+	}
+}
+
+// Record appends the given event to the bundle.
+func (r *ReplayRecorder) Record(e Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.bundle.Events = append(r.bundle.Events, ReplayBundleEvent{
+		Offset: time.Since(r.bundle.Started),
+		Event:  e,
+	})
+}
+
+// Bundle returns the bundle of the events recorded so far.
+func (r *ReplayRecorder) Bundle() *ReplayBundle {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	bundle := r.bundle
+	bundle.Events = append([]ReplayBundleEvent(nil), r.bundle.Events...)
+	return &bundle
+}
+
+// WriteReplayBundle writes the given bundle to the file at the given path, replacing the file if it exists.
+func WriteReplayBundle(path string, bundle *ReplayBundle) error {
+	contract.Require(bundle != nil, "bundle")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "creating replay bundle")
+	}
+	if err = gob.NewEncoder(file).Encode(bundle); err != nil {
+		contract.IgnoreClose(file)
+		return errors.Wrap(err, "encoding replay bundle")
+	}
+	return file.Close()
+}
+
+// ReadReplayBundle reads the bundle in the file at the given path.
+func ReadReplayBundle(path string) (*ReplayBundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening replay bundle")
+	}
+	defer contract.IgnoreClose(file)
+
+	var bundle ReplayBundle
+	if err = gob.NewDecoder(file).Decode(&bundle); err != nil {
+		return nil, errors.Wrap(err, "decoding replay bundle")
+	}
+	if bundle.Version != ReplayBundleVersion {
+		return nil, errors.Errorf("unsupported replay bundle version %d; this version of the CLI reads version %d",
+			bundle.Version, ReplayBundleVersion)
+	}
+	return &bundle, nil
+}
+
+// ReplayOptions controls how Replay re-emits a bundle's events.
+type ReplayOptions struct {
+	// RealTime, when true, re-emits each event at the same offset from the start of the replay as it was emitted from
+	// the start of the recording. Otherwise, events are re-emitted as quickly as they are received.
+	RealTime bool
+}
+
+// Replay re-emits the events recorded in the given bundle on the given channel, in the order in which they were
+// recorded. No program is run and no providers are loaded, so replaying a bundle has no effect beyond the events it
+// emits. Replay does not close the channel.
+func Replay(bundle *ReplayBundle, events chan<- Event, opts ReplayOptions) {
+	contract.Require(bundle != nil, "bundle")
+
+	start := time.Now()
+	for _, e := range bundle.Events {
+		if opts.RealTime {
+			if wait := time.Until(start.Add(e.Offset)); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		events <- e.Event
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestReplayBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	urn := resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA")
+	inputs := resource.PropertyMap{
+		"name":   resource.NewStringProperty("a"),
+		"id":     resource.MakeComputed(resource.NewStringProperty("")),
+		"tags":   resource.NewObjectProperty(resource.PropertyMap{"n": resource.NewNumberProperty(1)}),
+		"list":   resource.NewArrayProperty([]resource.PropertyValue{resource.NewBoolProperty(true)}),
+		"secret": resource.MakeSecret(resource.NewStringProperty("[secret]")),
+		"code":   resource.NewAssetProperty(&resource.Asset{Text: "hello"}),
+	}
+	state := &resource.State{Type: urn.Type(), URN: urn, Custom: true, Inputs: inputs}
+	pre := Event{
+		Type: ResourcePreEvent,
+		Payload: ResourcePreEventPayload{
+			Metadata: StepEventMetadata{
+				Op:   deploy.OpCreate,
+				URN:  urn,
+				Type: urn.Type(),
+				New:  &StepEventStateMetadata{State: state, URN: urn, Type: urn.Type(), Inputs: inputs},
+				Res:  &StepEventStateMetadata{State: state, URN: urn, Type: urn.Type(), Inputs: inputs},
+			},
+			Planning: true,
+		},
+	}
+	diagnostic := Event{Type: DiagEvent, Payload: DiagEventPayload{URN: urn, Message: "hi", Severity: diag.Warning}}
+
+	recorder := NewReplayRecorder(apitype.UpdateUpdate, "test", "test", true)
+	recorder.Record(pre)
+	time.Sleep(50 * time.Millisecond)
+	recorder.Record(diagnostic)
+	recorder.Record(cancelEvent())
+
+	path := filepath.Join(dir, "bundle")
+	assert.NoError(t, WriteReplayBundle(path, recorder.Bundle()))
+	bundle, err := ReadReplayBundle(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, apitype.UpdateUpdate, bundle.Kind)
+	assert.True(t, bundle.DryRun)
+	assert.Len(t, bundle.Events, 3)
+
+	// Replaying the bundle re-emits the recorded events, property values and all.
+	events := make(chan Event, len(bundle.Events))
+	start := time.Now()
+	Replay(bundle, events, ReplayOptions{RealTime: true})
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	close(events)
+
+	var replayed []Event
+	for e := range events {
+		replayed = append(replayed, e)
+	}
+	if !assert.Len(t, replayed, 3) {
+		return
+	}
+	payload, ok := replayed[0].Payload.(ResourcePreEventPayload)
+	if assert.True(t, ok) {
+		assert.True(t, payload.Planning)
+		assert.Equal(t, deploy.OpCreate, payload.Metadata.Op)
+		assert.True(t, inputs.DeepEquals(payload.Metadata.New.State.Inputs))
+		assert.True(t, payload.Metadata.New.Inputs["id"].IsComputed())
+		assert.True(t, payload.Metadata.New.Inputs["secret"].IsSecret())
+		assert.True(t, payload.Metadata.New.Inputs["code"].IsAsset())
+	}
+	assert.Equal(t, diagnostic, replayed[1])
+	assert.Equal(t, CancelEvent, replayed[2].Type)
+}
@@ -0,0 +1,133 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// secretFilter masks the plaintext of the secrets known to a single engine operation wherever they appear in
+// free-form text, such as diagnostics reported by providers. Each operation has its own filter, so that the events of
+// one operation never mask (and so never reveal the presence of) the secrets that another operation discovers in its
+// resources' state. The process-wide filters registered with the logging package apply as well; these include the
+// filters for credentials, and for the configuration secrets of every operation.
+type secretFilter struct {
+	lock    sync.RWMutex
+	seen    map[string]bool  // the secrets that have already been added to the filter.
+	filters []logging.Filter // the filters that mask this operation's secrets.
+}
+
+func newSecretFilter() *secretFilter {
+	return &secretFilter{seen: make(map[string]bool)}
+}
+
+// addSecrets arranges for the given plaintext secrets to be masked.
+func (f *secretFilter) addSecrets(secrets []string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var added []string
+	for _, s := range secrets {
+		if !f.seen[s] {
+			f.seen[s] = true
+			added = append(added, s)
+		}
+	}
+	if len(added) > 0 {
+		f.filters = append(f.filters, logging.CreateFilter(added, "[secret]"))
+	}
+}
+
+// merge arranges for the secrets masked by the given filter to be masked by this one as well.
+func (f *secretFilter) merge(other *secretFilter) {
+	other.lock.RLock()
+	secrets := make([]string, 0, len(other.seen))
+	for s := range other.seen {
+		secrets = append(secrets, s)
+	}
+	other.lock.RUnlock()
+
+	f.addSecrets(secrets)
+}
+
+// addStates arranges for the plaintext of any secret property values in the given states to be masked. Secret values
+// within property maps are masked separately when events are constructed; this covers the cases where a secret leaks
+// into a message.
+func (f *secretFilter) addStates(states ...*resource.State) {
+	var secrets []string
+	for _, state := range states {
+		if state != nil {
+			secrets = append(secrets, secretStrings(state.Inputs, state.Outputs)...)
+		}
+	}
+	if len(secrets) > 0 {
+		f.addSecrets(secrets)
+	}
+}
+
+// filter masks the secrets known to the filter in the given string. A nil filter masks only those secrets registered
+// with the logging package.
+func (f *secretFilter) filter(msg string) string {
+	msg = logging.FilterString(msg)
+	if f == nil {
+		return msg
+	}
+
+	f.lock.RLock()
+	filters := f.filters
+	f.lock.RUnlock()
+
+	for _, filter := range filters {
+		msg = filter.Filter(msg)
+	}
+	return msg
+}
+
+// secretStrings returns the string values that are contained within secrets in the given property maps.
+func secretStrings(maps ...resource.PropertyMap) []string {
+	var strs []string
+	var walk func(v resource.PropertyValue, secret bool)
+	walk = func(v resource.PropertyValue, secret bool) {
+		switch {
+		case v.IsSecret():
+			walk(v.SecretValue().Element, true)
+		case v.IsString():
+			if secret {
+				strs = append(strs, v.StringValue())
+			}
+		case v.IsArray():
+			for _, e := range v.ArrayValue() {
+				walk(e, secret)
+			}
+		case v.IsObject():
+			for _, e := range v.ObjectValue() {
+				walk(e, secret)
+			}
+		case v.IsComputed():
+			walk(v.Input().Element, secret)
+		case v.IsOutput():
+			walk(v.OutputValue().Element, secret)
+		}
+	}
+	for _, m := range maps {
+		for _, v := range m {
+			walk(v, false)
+		}
+	}
+	return strs
+}
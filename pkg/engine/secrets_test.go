@@ -0,0 +1,91 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+func TestSecretStrings(t *testing.T) {
+	props := resource.PropertyMap{
+		"plain":    resource.NewStringProperty("visible"),
+		"password": resource.MakeSecret(resource.NewStringProperty("hunter2")),
+		"nested": resource.NewObjectProperty(resource.PropertyMap{
+			"keys": resource.MakeSecret(resource.NewArrayProperty([]resource.PropertyValue{
+				resource.NewStringProperty("key-one"),
+				resource.NewNumberProperty(42),
+			})),
+		}),
+	}
+
+	strs := secretStrings(props)
+	sort.Strings(strs)
+	assert.Equal(t, []string{"hunter2", "key-one"}, strs)
+}
+
+func TestSecretFilter(t *testing.T) {
+	state := &resource.State{
+		Outputs: resource.PropertyMap{
+			"connectionString": resource.MakeSecret(resource.NewStringProperty("s3cr3t-connection-string")),
+		},
+	}
+	secrets := newSecretFilter()
+	secrets.addStates(state)
+	assert.Equal(t, "failed to connect using [secret]",
+		secrets.filter("failed to connect using s3cr3t-connection-string"))
+
+	// The secrets of one operation are not masked by another's filter, nor by the process-wide filters.
+	other := newSecretFilter()
+	assert.Equal(t, "failed to connect using s3cr3t-connection-string",
+		other.filter("failed to connect using s3cr3t-connection-string"))
+	assert.Equal(t, "failed to connect using s3cr3t-connection-string",
+		logging.FilterString("failed to connect using s3cr3t-connection-string"))
+
+	// A nil filter masks only the process-wide secrets.
+	var none *secretFilter
+	assert.Equal(t, "hello", none.filter("hello"))
+}
+
+func TestConfigSecretsMaskedInSinks(t *testing.T) {
+	crypter := config.NewSymmetricCrypter(make([]byte, 32))
+	ciphertext, err := crypter.EncryptValue("c0nfig-s3cr3t-value")
+	assert.NoError(t, err)
+
+	info := &updateInfo{target: deploy.Target{
+		Config:    config.Map{config.MustMakeKey("test", "password"): config.NewSecureValue(ciphertext)},
+		Decrypter: crypter,
+	}}
+	_, err = makeEventEmitter(make(chan Event), info)
+	assert.NoError(t, err)
+
+	// Diagnostics written directly to a sink, such as the error that ends an update, mask the stack's config secrets.
+	var stdout, stderr bytes.Buffer
+	sink := diag.DefaultSink(&stdout, &stderr, diag.FormatOptions{Color: colors.Never})
+	sink.Errorf(diag.Message("", "update failed: invalid password %s"), "c0nfig-s3cr3t-value")
+	assert.Contains(t, stderr.String(), "update failed: invalid password [secret]")
+	assert.NotContains(t, stderr.String(), "c0nfig-s3cr3t-value")
+	assert.Equal(t, "[secret]", logging.FilterString("c0nfig-s3cr3t-value"))
+}
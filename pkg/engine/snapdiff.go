@@ -0,0 +1,184 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"reflect"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// ResourceDiffKind is the kind of difference between the states of a resource in two snapshots.
+type ResourceDiffKind string
+
+const (
+	// ResourceAdded means that the resource is only in the new snapshot.
+	ResourceAdded ResourceDiffKind = "add"
+	// ResourceDeleted means that the resource is only in the old snapshot.
+	ResourceDeleted ResourceDiffKind = "delete"
+	// ResourceUpdated means that the resource is in both snapshots, with different states.
+	ResourceUpdated ResourceDiffKind = "update"
+	// ResourceReplaced means that the resource is in both snapshots, with different IDs.
+	ResourceReplaced ResourceDiffKind = "replace"
+)
+
+// ResourceDiff describes how the state of a single resource differs between two snapshots.
+type ResourceDiff struct {
+	URN  resource.URN
+	Type tokens.Type
+	Kind ResourceDiffKind
+	Old  *resource.State // the resource's state in the old snapshot, or nil if it was added.
+	New  *resource.State // the resource's state in the new snapshot, or nil if it was deleted.
+
+	// the changes to the resource's inputs and outputs, if it is in both snapshots. Changes to object-valued
+	// properties are expanded into changes to their keys.
+	Inputs  []resource.PropertyChange
+	Outputs []resource.PropertyChange
+
+	// the other fields of the resource's state that differ, if it is in both snapshots: any of "id", "parent",
+	// "provider", "protect", and "dependencies".
+	Fields []string
+}
+
+// SnapshotDiff describes the differences between two snapshots.
+type SnapshotDiff struct {
+	// the resources whose states differ: those in the new snapshot in its order, followed by those that were deleted
+	// in the order of the old snapshot.
+	Resources []ResourceDiff
+}
+
+// HasChanges returns true if the snapshots differ.
+func (d *SnapshotDiff) HasChanges() bool {
+	return d != nil && len(d.Resources) > 0
+}
+
+// Summary counts the resources that differ by the operation that would take the old snapshot to the new one.
+func (d *SnapshotDiff) Summary() ResourceChanges {
+	changes := make(ResourceChanges)
+	if d == nil {
+		return changes
+	}
+	for _, diff := range d.Resources {
+		switch diff.Kind {
+		case ResourceAdded:
+			changes[deploy.OpCreate]++
+		case ResourceDeleted:
+			changes[deploy.OpDelete]++
+		case ResourceUpdated:
+			changes[deploy.OpUpdate]++
+		case ResourceReplaced:
+			changes[deploy.OpReplace]++
+		}
+	}
+	return changes
+}
+
+// DiffSnapshots compares two snapshots of a stack, e.g. checkpoints taken at different times or of the same program
+// deployed to different environments, without running the program or loading any plugins. Resources are matched by
+// URN; resources that are pending deletion are ignored, as are internal properties. A nil snapshot is treated as an
+// empty one.
+//
+// Property values are compared as they are recorded, so secrets are neither decrypted nor masked: callers that render
+// the changes should take care not to reveal the values of secrets.
+func DiffSnapshots(old, new *deploy.Snapshot) *SnapshotDiff {
+	olds, oldOrder := liveResources(old)
+	news, newOrder := liveResources(new)
+
+	diff := &SnapshotDiff{}
+	for _, urn := range newOrder {
+		n := news[urn]
+		o, has := olds[urn]
+		if !has {
+			diff.Resources = append(diff.Resources, ResourceDiff{URN: urn, Type: n.Type, Kind: ResourceAdded, New: n})
+			continue
+		}
+		if d, changed := diffResourceStates(o, n); changed {
+			diff.Resources = append(diff.Resources, d)
+		}
+	}
+	for _, urn := range oldOrder {
+		if _, has := news[urn]; !has {
+			o := olds[urn]
+			diff.Resources = append(diff.Resources, ResourceDiff{URN: urn, Type: o.Type, Kind: ResourceDeleted, Old: o})
+		}
+	}
+	return diff
+}
+
+// liveResources returns the resources of the given snapshot that are not pending deletion, by URN and in order.
+func liveResources(snap *deploy.Snapshot) (map[resource.URN]*resource.State, []resource.URN) {
+	states := make(map[resource.URN]*resource.State)
+	if snap == nil {
+		return states, nil
+	}
+	var order []resource.URN
+	for _, res := range snap.Resources {
+		if res.Delete {
+			continue
+		}
+		if _, has := states[res.URN]; !has {
+			order = append(order, res.URN)
+		}
+		states[res.URN] = res
+	}
+	return states, order
+}
+
+// diffResourceStates compares the states of a resource in two snapshots. It returns false if they do not differ.
+func diffResourceStates(old, new *resource.State) (ResourceDiff, bool) {
+	diff := ResourceDiff{URN: new.URN, Type: new.Type, Kind: ResourceUpdated, Old: old, New: new}
+	if d := old.Inputs.Diff(new.Inputs, IsInternalPropertyKey); d != nil {
+		diff.Inputs = d.Changes()
+	}
+	if d := old.Outputs.Diff(new.Outputs, IsInternalPropertyKey); d != nil {
+		diff.Outputs = d.Changes()
+	}
+
+	if old.ID != new.ID {
+		diff.Fields = append(diff.Fields, "id")
+		if old.ID != "" && new.ID != "" {
+			diff.Kind = ResourceReplaced
+		}
+	}
+	if old.Parent != new.Parent {
+		diff.Fields = append(diff.Fields, "parent")
+	}
+	if old.Provider != new.Provider {
+		diff.Fields = append(diff.Fields, "provider")
+	}
+	if old.Protect != new.Protect {
+		diff.Fields = append(diff.Fields, "protect")
+	}
+	if !sameURNSet(old.Dependencies, new.Dependencies) {
+		diff.Fields = append(diff.Fields, "dependencies")
+	}
+
+	changed := len(diff.Inputs) > 0 || len(diff.Outputs) > 0 || len(diff.Fields) > 0
+	return diff, changed
+}
+
+// sameURNSet returns true if the given lists hold the same URNs, regardless of their order.
+func sameURNSet(a, b []resource.URN) bool {
+	as, bs := make(map[resource.URN]bool), make(map[resource.URN]bool)
+	for _, urn := range a {
+		as[urn] = true
+	}
+	for _, urn := range b {
+		bs[urn] = true
+	}
+	return reflect.DeepEqual(as, bs)
+}
@@ -0,0 +1,85 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	same := newGraphTestState("same", "pkgA:m:typA", "", "")
+	deleted := newGraphTestState("deleted", "pkgA:m:typA", "", "")
+	added := newGraphTestState("added", "pkgA:m:typA", "", "")
+
+	oldUpdated := newGraphTestState("updated", "pkgA:m:typA", "", "")
+	oldUpdated.Inputs = resource.PropertyMap{
+		"size": resource.NewStringProperty("small"),
+		"tags": resource.NewObjectProperty(resource.PropertyMap{"env": resource.NewStringProperty("dev")}),
+	}
+	oldUpdated.Outputs = resource.PropertyMap{"arn": resource.NewStringProperty("arn:1")}
+	newUpdated := newGraphTestState("updated", "pkgA:m:typA", "", "", same.URN)
+	newUpdated.Inputs = resource.PropertyMap{
+		"size": resource.NewStringProperty("small"),
+		"tags": resource.NewObjectProperty(resource.PropertyMap{"env": resource.NewStringProperty("prod")}),
+	}
+	newUpdated.Outputs = resource.PropertyMap{"arn": resource.NewStringProperty("arn:1")}
+
+	oldReplaced := newGraphTestState("replaced", "pkgA:m:typA", "", "")
+	newReplaced := newGraphTestState("replaced", "pkgA:m:typA", "", "")
+	newReplaced.ID = "replaced-2"
+
+	// Resources that are pending deletion are ignored.
+	pending := newGraphTestState("replaced", "pkgA:m:typA", "", "")
+	pending.Delete = true
+
+	old := &deploy.Snapshot{Resources: []*resource.State{same, deleted, oldUpdated, oldReplaced}}
+	new := &deploy.Snapshot{Resources: []*resource.State{same, added, newUpdated, pending, newReplaced}}
+	diff := DiffSnapshots(old, new)
+	assert.True(t, diff.HasChanges())
+
+	var kinds []ResourceDiffKind
+	for _, d := range diff.Resources {
+		kinds = append(kinds, d.Kind)
+	}
+	assert.Equal(t, []ResourceDiffKind{ResourceAdded, ResourceUpdated, ResourceReplaced, ResourceDeleted}, kinds)
+	assert.Equal(t, ResourceChanges{
+		deploy.OpCreate:  1,
+		deploy.OpUpdate:  1,
+		deploy.OpReplace: 1,
+		deploy.OpDelete:  1,
+	}, diff.Summary())
+
+	updated := diff.Resources[1]
+	assert.Equal(t, newUpdated.URN, updated.URN)
+	if assert.Len(t, updated.Inputs, 1) {
+		assert.Equal(t, "tags.env", updated.Inputs[0].PathString())
+		assert.Equal(t, resource.PropertyUpdated, updated.Inputs[0].Kind)
+	}
+	assert.Empty(t, updated.Outputs)
+	assert.Equal(t, []string{"dependencies"}, updated.Fields)
+
+	assert.Equal(t, []string{"id"}, diff.Resources[2].Fields)
+	assert.Equal(t, deleted.URN, diff.Resources[3].URN)
+
+	// Identical snapshots do not differ, and a nil snapshot is an empty one.
+	assert.False(t, DiffSnapshots(old, old).HasChanges())
+	assert.False(t, DiffSnapshots(nil, &deploy.Snapshot{}).HasChanges())
+	assert.Equal(t, ResourceChanges{deploy.OpCreate: 4}, DiffSnapshots(nil, old).Summary())
+}
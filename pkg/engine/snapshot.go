@@ -17,7 +17,9 @@ package engine
 import (
 	"io"
 
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
 // SnapshotManager is responsible for maintaining the in-memory representation
@@ -34,6 +36,28 @@ type SnapshotManager interface {
 	// RegisterResourceOutputs registers the set of resource outputs generated by performing the
 	// given step. These outputs are persisted in the snapshot.
 	RegisterResourceOutputs(step deploy.Step) error
+
+	// RecordConfig records the effective configuration of the update in the manifest of each snapshot
+	// that is persisted from now on, along with the keys whose values were omitted from it.
+	RecordConfig(cfg config.Map, omitted []config.Key) error
+
+	// RecordPlugins records the provider plugins that served the update in the manifest of each snapshot that is
+	// persisted from now on, so that the next update can tell which of the stack's providers have been upgraded.
+	RecordPlugins(plugins []workspace.PluginInfo) error
+}
+
+// SnapshotVersioner is implemented by SnapshotManagers that count the snapshots that they persist, so that the result
+// of an update can report the version of the stack's state that it left behind.
+type SnapshotVersioner interface {
+	// SnapshotVersion returns the number of snapshots that have been persisted so far.
+	SnapshotVersion() int
+}
+
+// SnapshotReader is implemented by SnapshotManagers that can return the snapshot that they would persist, so that the
+// engine can verify the state that an update leaves behind.
+type SnapshotReader interface {
+	// Snapshot returns the snapshot as it stands.
+	Snapshot() (*deploy.Snapshot, error)
 }
 
 // SnapshotMutation represents an outstanding mutation that is yet to be completed. When the engine completes
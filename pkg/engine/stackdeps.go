@@ -0,0 +1,144 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// StackExecutionPlan is an ordered plan for deploying the projects within a repository, derived from the stack
+// dependencies declared in their project files. It is intended to be serialized as JSON and consumed by CI systems
+// that drive multi-stack deployments.
+type StackExecutionPlan struct {
+	// Projects lists every project in an order that respects the declared dependencies: each project appears after
+	// all of the projects it depends upon.
+	Projects []StackExecutionEntry `json:"projects"`
+}
+
+// StackExecutionEntry describes a single project in a StackExecutionPlan.
+type StackExecutionEntry struct {
+	// Name is the name of the project.
+	Name tokens.PackageName `json:"name"`
+	// Path is the path of the directory containing the project file, relative to the root of the search.
+	Path string `json:"path"`
+	// Wave is the position of the project in the plan. All projects within a wave are independent of one another and
+	// may be deployed concurrently once every project in earlier waves has been deployed.
+	Wave int `json:"wave"`
+	// DependsOn lists the stack dependencies declared by the project.
+	DependsOn []workspace.StackDependency `json:"dependsOn,omitempty"`
+}
+
+// PlanStackExecution finds every project beneath the given root directory and returns a plan that orders them such
+// that each project is deployed after the projects its stacks depend upon. An error is returned if two projects share
+// a name, if a project depends upon a project that does not exist beneath the root, or if the dependencies are cyclic.
+func PlanStackExecution(root string) (*StackExecutionPlan, error) {
+	paths, err := workspace.FindProjects(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "searching for projects")
+	}
+
+	// Load each project and index it by name.
+	entries := make(map[tokens.PackageName]*StackExecutionEntry)
+	for _, path := range paths {
+		proj, err := workspace.LoadProject(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading project %s", path)
+		}
+
+		dir, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		if prior, has := entries[proj.Name]; has {
+			return nil, errors.Errorf("project '%s' is defined in both %s and %s", proj.Name, prior.Path, dir)
+		}
+		entries[proj.Name] = &StackExecutionEntry{
+			Name:      proj.Name,
+			Path:      filepath.ToSlash(dir),
+			DependsOn: proj.StackDependencies,
+		}
+	}
+
+	return orderStackExecution(entries)
+}
+
+// orderStackExecution topologically sorts the given projects by their stack dependencies, assigning each project to
+// the earliest wave that follows all of its dependencies. Within a wave, projects are sorted by name so that the
+// resulting plan is deterministic.
+func orderStackExecution(entries map[tokens.PackageName]*StackExecutionEntry) (*StackExecutionPlan, error) {
+	// Compute the set of distinct projects each project depends upon, and the inverse.
+	remaining := make(map[tokens.PackageName]int)
+	dependents := make(map[tokens.PackageName][]tokens.PackageName)
+	for name, entry := range entries {
+		seen := make(map[tokens.PackageName]bool)
+		for _, dep := range entry.DependsOn {
+			if _, has := entries[dep.Project]; !has {
+				return nil, errors.Errorf("project '%s' depends on unknown project '%s'", name, dep.Project)
+			}
+			if !seen[dep.Project] {
+				seen[dep.Project] = true
+				dependents[dep.Project] = append(dependents[dep.Project], name)
+			}
+		}
+		remaining[name] = len(seen)
+	}
+
+	var wave []tokens.PackageName
+	for name, count := range remaining {
+		if count == 0 {
+			wave = append(wave, name)
+		}
+	}
+
+	plan := &StackExecutionPlan{Projects: []StackExecutionEntry{}}
+	for w := 0; len(wave) > 0; w++ {
+		sort.Slice(wave, func(i, j int) bool { return wave[i] < wave[j] })
+
+		var next []tokens.PackageName
+		for _, name := range wave {
+			entry := entries[name]
+			entry.Wave = w
+			plan.Projects = append(plan.Projects, *entry)
+
+			for _, dependent := range dependents[name] {
+				if remaining[dependent]--; remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		wave = next
+	}
+
+	// Any project that was never scheduled is part of (or depends upon) a cycle.
+	if len(plan.Projects) != len(entries) {
+		var cyclic []string
+		for name, count := range remaining {
+			if count > 0 {
+				cyclic = append(cyclic, string(name))
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, errors.Errorf("stack dependencies form a cycle among projects %s", strings.Join(cyclic, ", "))
+	}
+
+	return plan, nil
+}
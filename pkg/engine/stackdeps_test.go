@@ -0,0 +1,86 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func writeTestProject(t *testing.T, root, dir, contents string) {
+	path := filepath.Join(root, dir)
+	assert.NoError(t, os.MkdirAll(path, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "Pulumi.yaml"), []byte(contents), 0600))
+}
+
+func TestPlanStackExecution(t *testing.T) {
+	root, err := ioutil.TempDir("", "stackdeps")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	writeTestProject(t, root, "app", `name: app
+runtime: nodejs
+stackDependencies:
+- project: network
+- project: database
+`)
+	writeTestProject(t, root, "infra/database", `name: database
+runtime: nodejs
+stackDependencies:
+- project: network
+  stack: shared
+`)
+	writeTestProject(t, root, "infra/network", "name: network\nruntime: nodejs\n")
+	writeTestProject(t, root, "monitoring", "name: monitoring\nruntime: python\n")
+	writeTestProject(t, root, "node_modules/ignored", "name: ignored\nruntime: nodejs\n")
+
+	plan, err := PlanStackExecution(root)
+	assert.NoError(t, err)
+
+	var names []tokens.PackageName
+	var waves []int
+	for _, p := range plan.Projects {
+		names = append(names, p.Name)
+		waves = append(waves, p.Wave)
+	}
+	assert.Equal(t, []tokens.PackageName{"monitoring", "network", "database", "app"}, names)
+	assert.Equal(t, []int{0, 0, 1, 2}, waves)
+	assert.Equal(t, "infra/database", plan.Projects[2].Path)
+	assert.Equal(t, []workspace.StackDependency{{Project: "network", Stack: "shared"}}, plan.Projects[2].DependsOn)
+}
+
+func TestOrderStackExecutionErrors(t *testing.T) {
+	// Unknown dependencies are reported.
+	_, err := orderStackExecution(map[tokens.PackageName]*StackExecutionEntry{
+		"a": {Name: "a", DependsOn: []workspace.StackDependency{{Project: "missing"}}},
+	})
+	assert.EqualError(t, err, "project 'a' depends on unknown project 'missing'")
+
+	// Cycles are reported along with the projects that could not be scheduled.
+	_, err = orderStackExecution(map[tokens.PackageName]*StackExecutionEntry{
+		"a": {Name: "a", DependsOn: []workspace.StackDependency{{Project: "b"}}},
+		"b": {Name: "b", DependsOn: []workspace.StackDependency{{Project: "a"}}},
+		"c": {Name: "c", DependsOn: []workspace.StackDependency{{Project: "b"}}},
+		"d": {Name: "d"},
+	})
+	assert.EqualError(t, err, "stack dependencies form a cycle among projects a, b, c")
+}
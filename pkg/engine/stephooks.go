@@ -0,0 +1,284 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// defaultStepHookTimeout is how long a step hook may run if it does not set its own timeout.
+const defaultStepHookTimeout = 30 * time.Second
+
+// StepHookPolicy says what becomes of a step when one of its hooks fails.
+type StepHookPolicy string
+
+const (
+	// StepHookWarn reports a warning when the hook fails, and carries on as if it had succeeded. This is the default.
+	StepHookWarn StepHookPolicy = "warn"
+	// StepHookBlock fails the step when the hook fails. A hook that runs before a step blocks it from being applied; a
+	// hook that runs after a step fails the update once the step's result has been saved.
+	StepHookBlock StepHookPolicy = "block"
+)
+
+// StepHookPhase says whether a hook is running before or after a step.
+type StepHookPhase string
+
+const (
+	StepHookPre  StepHookPhase = "pre"  // the step is about to be applied.
+	StepHookPost StepHookPhase = "post" // the step has been applied, or has failed.
+)
+
+// StepHook is a webhook, or a local command, that is invoked before and after each step of an update that changes a
+// resource, e.g. to open and close change tickets or to keep a CMDB up to date. It is sent a StepHookPayload as JSON:
+// webhooks are sent it in the body of a POST, and commands on their standard input. A webhook fails if it does not
+// respond with a 2xx status, and a command if it exits with a non-zero status.
+type StepHook struct {
+	// the name of the hook, which is used in diagnostics. If it is empty, the hook's URL or command is used.
+	Name string
+	// the URL to which the payload is posted.
+	URL string
+	// the command, and its arguments, that is run with the payload on its standard input.
+	Command []string
+	// what becomes of the step if the hook fails.
+	Policy StepHookPolicy
+	// how long the hook may run before it fails (0 for the default of 30 seconds).
+	Timeout time.Duration
+}
+
+// StepHookPayload is the JSON document that is sent to a step hook.
+type StepHookPayload struct {
+	Phase   StepHookPhase      `json:"phase"`
+	Project tokens.PackageName `json:"project"`
+	Stack   tokens.QName       `json:"stack"`
+	URN     resource.URN       `json:"urn"`
+	Op      deploy.StepOp      `json:"op"`
+	// a hash of the inputs that the step applies, or of the inputs of the resource that it deletes.
+	InputsHash string `json:"inputsHash,omitempty"`
+	// the result of the step, which is only sent after the step.
+	Result AuditResult `json:"result,omitempty"`
+	// the error with which the step failed, if any. Secrets are masked.
+	Error string `json:"error,omitempty"`
+}
+
+// validate returns an error if the hook is not well formed.
+func (h StepHook) validate() error {
+	switch {
+	case h.URL == "" && len(h.Command) == 0:
+		return errors.New("requires a URL or a Command")
+	case h.URL != "" && len(h.Command) > 0:
+		return errors.New("must not have both a URL and a Command")
+	case len(h.Command) > 0 && h.Command[0] == "":
+		return errors.New("the command must not be empty")
+	}
+	if h.URL != "" {
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.Errorf("%q is not an HTTP or HTTPS URL", h.URL)
+		}
+	}
+	switch h.Policy {
+	case "", StepHookWarn, StepHookBlock:
+	default:
+		return errors.Errorf("%q is not a policy (use %q or %q)", h.Policy, StepHookWarn, StepHookBlock)
+	}
+	if h.Timeout < 0 {
+		return errors.Errorf("the timeout of %v is negative", h.Timeout)
+	}
+	return nil
+}
+
+// String returns the name of the hook that is used in diagnostics.
+func (h StepHook) String() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.URL != "":
+		return h.URL
+	default:
+		return strings.Join(h.Command, " ")
+	}
+}
+
+// stepHooks invokes the step hooks of an update. A nil *stepHooks invokes none.
+type stepHooks struct {
+	hooks   []StepHook
+	project tokens.PackageName
+	stack   tokens.QName
+	diag    diag.Sink
+	secrets *secretFilter
+}
+
+// newStepHooks returns the step hooks of the given update, or nil if it has none.
+func newStepHooks(u UpdateInfo, opts planOptions) *stepHooks {
+	if len(opts.StepHooks) == 0 {
+		return nil
+	}
+	return &stepHooks{
+		hooks:   opts.StepHooks,
+		project: u.GetProject().Name,
+		stack:   u.GetTarget().Name,
+		diag:    opts.Diag,
+		secrets: opts.Events.secrets,
+	}
+}
+
+// beforeStep invokes the hooks before the given step is applied. It returns an error if a blocking hook fails, in which
+// case the step must not be applied.
+func (sh *stepHooks) beforeStep(step deploy.Step) error {
+	if sh == nil || step.Op() == deploy.OpSame {
+		return nil
+	}
+	return sh.invoke(step, StepHookPayload{Phase: StepHookPre})
+}
+
+// afterStep invokes the hooks after the given step has been applied, or has failed with the given error. It returns an
+// error if a blocking hook fails.
+func (sh *stepHooks) afterStep(step deploy.Step, status resource.Status, stepErr error) error {
+	if sh == nil || step.Op() == deploy.OpSame {
+		return nil
+	}
+	payload := StepHookPayload{Phase: StepHookPost, Result: AuditSucceeded}
+	switch {
+	case stepErr != nil && status == resource.StatusPartialFailure:
+		payload.Result, payload.Error = AuditPartiallyFailed, sh.secrets.filter(stepErr.Error())
+	case stepErr != nil:
+		payload.Result, payload.Error = AuditFailed, sh.secrets.filter(stepErr.Error())
+	}
+	return sh.invoke(step, payload)
+}
+
+// invoke sends the given payload, completed with the details of the given step, to each hook in turn. A hook whose
+// policy is to warn has its failure reported as a warning; the first blocking hook to fail stops the rest.
+func (sh *stepHooks) invoke(step deploy.Step, payload StepHookPayload) error {
+	payload.Project, payload.Stack, payload.URN, payload.Op = sh.project, sh.stack, step.URN(), step.Op()
+
+	inputs := step.New()
+	if inputs == nil {
+		inputs = step.Old()
+	}
+	if inputs != nil {
+		hash, err := hashStepInputs(inputs.Inputs)
+		if err != nil {
+			return errors.Wrapf(err, "hashing the inputs of %s", step.URN())
+		}
+		payload.InputsHash = hash
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "encoding step hook payload")
+	}
+
+	for _, hook := range sh.hooks {
+		err := runStepHook(hook, body)
+		if err == nil {
+			continue
+		}
+		logging.V(7).Infof("stepHooks.invoke(%s): %s hook %s failed: %v", step.URN(), payload.Phase, hook, err)
+		message := fmt.Sprintf("the %s-step hook %s failed: %s", payload.Phase, hook, sh.secrets.filter(err.Error()))
+		if hook.Policy == StepHookBlock {
+			return errors.New(message)
+		}
+		sh.diag.Warningf(diag.RawMessage(step.URN(), message))
+	}
+	return nil
+}
+
+// runStepHook sends the given payload to the given hook, and returns an error if the hook fails.
+func runStepHook(hook StepHook, body []byte) error {
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = defaultStepHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if hook.URL != "" {
+		req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		contract.IgnoreClose(resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return errors.Errorf("POST %s returned %s", hook.URL, resp.Status)
+		}
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdin, cmd.Stderr = bytes.NewReader(body), &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return errors.Errorf("timed out after %v", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// hashStepInputs hashes the given inputs. Secret values are hashed along with everything else, so the hash changes
+// when they do.
+func hashStepInputs(inputs resource.PropertyMap) (string, error) {
+	s, err := plugin.MarshalProperties(inputs, plugin.MarshalOptions{
+		Label:        "step-hook",
+		KeepUnknowns: true,
+		KeepSecrets:  true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Struct fields are maps, so the encoding must be made deterministic for the hash to be stable.
+	var buf proto.Buffer
+	buf.SetDeterministic(true)
+	if err = buf.Marshal(s); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func TestStepHooks(t *testing.T) {
+	var lock sync.Mutex
+	var payloads []StepHookPayload
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload StepHookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		lock.Lock()
+		defer lock.Unlock()
+		if payload.URN.Type() == "pkgA:m:typA" {
+			payloads = append(payloads, payload)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	name := "resA"
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", name, true, "", false, nil, "",
+			resource.PropertyMap{"size": resource.NewStringProperty("small")}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	hook := StepHook{URL: server.URL}
+	p := &TestPlan{Options: UpdateOptions{host: host, StepHooks: []StepHook{hook}}}
+
+	// The hook is told about the create before and after it is applied, but not about previews.
+	p.Steps = []TestStep{{Op: Update}}
+	snap := p.Run(t, nil)
+	if assert.Len(t, payloads, 2) {
+		pre, post := payloads[0], payloads[1]
+		assert.Equal(t, StepHookPre, pre.Phase)
+		assert.Equal(t, deploy.OpCreate, pre.Op)
+		assert.Equal(t, tokens.QName("resA"), pre.URN.Name())
+		assert.NotEmpty(t, pre.InputsHash)
+		assert.Empty(t, pre.Result)
+		assert.Equal(t, StepHookPost, post.Phase)
+		assert.Equal(t, pre.InputsHash, post.InputsHash)
+		assert.Equal(t, AuditSucceeded, post.Result)
+	}
+
+	// Steps that leave their resource unchanged are not hooked.
+	payloads = nil
+	snap = p.Run(t, snap)
+	assert.Empty(t, payloads)
+
+	// A hook that fails with the default policy only warns.
+	name, status, payloads = "resB", http.StatusInternalServerError, nil
+	snap = p.Run(t, snap)
+	assert.Len(t, payloads, 4)
+
+	// A blocking hook that fails before a step keeps it from being applied.
+	hook.Policy = StepHookBlock
+	p.Options.StepHooks = []StepHook{hook}
+	name, payloads = "resC", nil
+	p.Steps = []TestStep{{
+		Op:            Update,
+		SkipPreview:   true,
+		ExpectFailure: true,
+		Validate: func(project workspace.Project, target deploy.Target, j *Journal,
+			evts []Event, res result.Result) result.Result {
+
+			for _, entry := range j.Entries {
+				assert.NotEqual(t, tokens.QName("resC"), entry.Step.URN().Name())
+			}
+			return res
+		},
+	}}
+	p.Run(t, snap)
+	if assert.Len(t, payloads, 1) {
+		assert.Equal(t, StepHookPre, payloads[0].Phase)
+	}
+}
+
+func TestStepHookValidation(t *testing.T) {
+	assert.NoError(t, StepHook{URL: "https://example.com/hook"}.validate())
+	assert.NoError(t, StepHook{Command: []string{"notify", "--ticket"}, Policy: StepHookBlock}.validate())
+	assert.Error(t, StepHook{}.validate())
+	assert.Error(t, StepHook{URL: "https://example.com/hook", Command: []string{"notify"}}.validate())
+	assert.Error(t, StepHook{URL: "ftp://example.com/hook"}.validate())
+	assert.Error(t, StepHook{URL: "https://example.com/hook", Policy: "ignore"}.validate())
+}
@@ -15,12 +15,16 @@
 package engine
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/blang/semver"
+	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
@@ -48,11 +52,182 @@ type UpdateOptions struct {
 	// true if the plan should refresh before executing.
 	Refresh bool
 
+	// configuration values that override the stack's configuration for this run only. They are passed to the program
+	// and to default providers, but are never saved to the stack.
+	ConfigOverrides map[config.Key]string
+
+	// the policy for retrying steps that fail with transient provider errors (e.g. throttling).
+	Retry deploy.RetryPolicy
+
+	// true if protected resources may be deleted or replaced.
+	AllowProtected bool
+
+	// the degree of parallelism for the operations of each provider, by package. Providers whose packages are not
+	// present are limited by their own hints or by Parallel. Parallel also bounds the operations of all providers
+	// together, so settings above it have no effect.
+	ProviderParallel map[tokens.Package]int
+
+	// the degree of parallelism for the reads of a refresh. Reads have no side effects, so by default they use the
+	// greater of Parallel and deploy.DefaultRefreshParallel, unless Parallel asks for serial execution.
+	RefreshParallel int
+
+	// the time providers may take to create, update, or delete resources, and how often they poll for the completion of
+	// those operations, by type. Settings on individual resources take precedence.
+	CustomTimeouts map[tokens.Type]resource.CustomTimeouts
+
+	// the types of resources that must be deleted before their replacements are created, even if neither the program
+	// nor the provider requests it. Useful for resources that cannot exist twice at once, e.g. due to unique names.
+	DeleteBeforeReplace map[tokens.Type]bool
+
+	// how resources that are in the snapshot but no longer produced by the program are handled: by default they are
+	// deleted; if set, they are reported as prunes, and deleted only if the mode is deploy.PruneDelete.
+	Prune deploy.PruneMode
+
+	// the URNs and types of resources that are intentionally left unmanaged by the program, and so are never pruned.
+	PruneExempt []string
+
+	// URN patterns (see resource.URNPattern) that select resources a destroy leaves in place, along with the resources
+	// upon which they depend. Other operations ignore them.
+	DestroyExclude []string
+
+	// true if a destroy also leaves in place the resources that depend on those that DestroyExclude selects.
+	DestroyExcludeDependents bool
+
+	// true if the order in which the operation deletes resources is reported in a deletion-order event before the
+	// first of the deletes begins.
+	ReportDeletionOrder bool
+
+	// tags that are applied to every resource whose provider supports default tags, taking precedence over the tags
+	// set by the program. They are recorded in the resources' inputs, so changes to them are diffed like any other.
+	DefaultTags map[string]string
+
+	// soft limits on the duration and size of the update, past which warnings are issued.
+	Budget UpdateBudget
+
+	// true if the resources left pending deletion by earlier updates, e.g. because the delete of a create-before-delete
+	// replacement failed, are garbage collected before the update is planned (see CollectPendingDeletes). Deletions
+	// that fail with transient errors are retried according to Retry, or DefaultPendingDeleteRetryPolicy if it does
+	// not set a number of attempts; those that still fail remain pending.
+	CollectPendingDeletes bool
+
+	// true if each delete or replacement must be confirmed via the context's Confirmations channel before it is
+	// applied.
+	ConfirmDestructiveSteps bool
+
+	// true if each replacement of a provider must be confirmed via the context's Confirmations channel before it is
+	// applied, and so before it can cascade into replacements of the resources that the provider manages.
+	ConfirmProviderReplacements bool
+
+	// true if the operation's events are emitted in an order that does not depend on the order in which its steps
+	// happen to run, so that the events of the same operation on the same stack can be compared across runs. The
+	// events are buffered until the operation completes, and progress events and ephemeral diagnostics are dropped.
+	DeterministicEvents bool
+
+	// determines which events the operation emits. SummaryEvents replaces the events of steps that leave their
+	// resources unchanged with aggregate progress events, which greatly reduces the events of large, mostly unchanged
+	// stacks.
+	EventProfile EventProfile
+
+	// an optional set of guards that inspect each planned change to a resource's input properties, and may flag or veto
+	// it. Previews served from a plan cache would bypass the guards, so the cache is not used if any are set.
+	PropertyChangeGuards []deploy.PropertyChangeGuard
+
+	// an optional pipeline of transformations that rewrite the goal state of each resource the program registers, in
+	// order, before it is planned: e.g. to inject tags, enforce naming conventions, or redirect resources to other
+	// providers. They apply to programs in every language alike. The cache key of a plan cannot see them, so the plan
+	// cache is not used if any are set.
+	Transformations []deploy.ResourceTransformation
+
+	// an optional set of estimators that estimate the impact of the plan computed by each preview, e.g. its cost. Their
+	// estimates are attached to the preview's summary.
+	PlanEstimators []PlanEstimator
+
+	// an optional set of checks to run after the update is applied, in addition to those of the project.
+	Checks []workspace.ProjectCheck
+
+	// true if no checks are run after the update is applied, including those of the project.
+	SkipChecks bool
+
+	// an optional cache of plans from which previews of unchanged programs are served. See PlanCache.
+	PlanCache PlanCache
+
+	// true if previews must compute a fresh plan even if a cached one is available. The fresh plan replaces it.
+	RefreshPlanCache bool
+
+	// true if the stored inputs of every resource in the snapshot are checked by the providers that the program
+	// requires before the update begins, so that resources whose shapes no longer match their providers' schemas are
+	// reported up front. The update fails if any are found.
+	ValidateSnapshot bool
+
+	// true if previews ask the providers that support it to validate each planned create and update without performing
+	// it, e.g. via a dry-run request to the cloud API, so that errors such as quota exhaustion or invalid parameters
+	// are reported by the preview rather than partway through the update. See plugin.PreviewValidator.
+	ValidatePreview bool
+
+	// true if the plan fails as soon as it generates a step that changes a resource, with an error diagnostic for each
+	// resource that would change that lists the properties that would change. This lets drift detection over many
+	// stacks stop planning a stack once it is known to have drifted.
+	ExpectNoChanges bool
+
+	// the oldest version of the CLI that the backend recommends, if any. If this build of the engine is older, each
+	// operation begins with a warning that it should be upgraded.
+	RecommendedVersion string
+
+	// true if the referential integrity of the snapshot that the update leaves behind is verified once it completes,
+	// even if it failed, if the context's SnapshotManager can return it. See VerifySnapshot. Each problem is reported
+	// as an error, and fails the update.
+	VerifySnapshot bool
+
+	// limits on the warnings reported by the update, so that repeated or high-volume warnings do not flood event
+	// consumers. Ignored if Debug is set.
+	DiagnosticLimits DiagnosticLimits
+
+	// filters that set the least severe diagnostics reported from particular sources, such as a single noisy
+	// provider, and optionally divert the rest to a writer of their own. See DiagnosticFilter.
+	DiagnosticFilters []DiagnosticFilter
+
+	// an optional cache of the checks made by previews, from which resources whose dependency closures are unchanged
+	// since an earlier preview are served rather than checked by their providers again. Experimental; see
+	// deploy.CheckCache.
+	CheckCache *deploy.CheckCache
+
+	// an optional cache of the hashes of the update's assets and archives, and of the contents of its archives
+	// translated into other formats. See resource.AssetCache.
+	AssetCache *resource.AssetCache
+
+	// an optional pool from which the plugin host is taken, so that plugin processes started by an earlier operation
+	// are reused rather than started anew. Ignored if a host is supplied.
+	PluginPool *plugin.HostPool
+
+	// where the update writes its state, so that it can check for enough disk space before it starts. Updates that
+	// are not dry runs also warn if they may need more open files than the process's limit allows.
+	Preflight PreflightOptions
+
+	// an optional set of webhooks or local commands that are invoked before and after each step of an update that
+	// changes a resource, each with a policy that says whether its failure blocks the step. Previews do not invoke them.
+	StepHooks []StepHook
+
 	// true if we should report events for steps that involve default providers.
 	reportDefaultProviderSteps bool
 
 	// the plugin host to use for this update
 	host plugin.Host
+
+	// an optional semaphore shared by concurrent updates that bounds the number of steps executing across all of them.
+	budget chan struct{}
+
+	// true if the program may be run again by the same language host, as it is by each iteration of a watch, and the
+	// paths of the program's files that changed since it was last run.
+	hotReload    bool
+	changedPaths []string
+}
+
+// diagnosticLimits returns the limits on the warnings reported by the update, if any.
+func (opts UpdateOptions) diagnosticLimits() DiagnosticLimits {
+	if opts.Debug {
+		return DiagnosticLimits{}
+	}
+	return opts.DiagnosticLimits
 }
 
 // ResourceChanges contains the aggregate resource changes by operation type.
@@ -69,11 +244,16 @@ func (changes ResourceChanges) HasChanges() bool {
 	return c > 0
 }
 
-func Update(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (ResourceChanges, result.Result) {
+func Update(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (*UpdateResult, result.Result) {
 	contract.Require(u != nil, "update")
 	contract.Require(ctx != nil, "ctx")
 
-	defer func() { ctx.Events <- cancelEvent() }()
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
 
 	info, err := newPlanContext(u, "update", ctx.ParentSpan)
 	if err != nil {
@@ -81,17 +261,33 @@ func Update(u UpdateInfo, ctx *Context, opts UpdateOptions, dryRun bool) (Resour
 	}
 	defer info.Close()
 
-	emitter, err := makeEventEmitter(ctx.Events, u)
+	events, flushEvents := operationEvents(ctx, opts)
+	defer flushEvents()
+
+	emitter, err := makeEventEmitter(events, u)
 	if err != nil {
 		return nil, result.FromError(err)
 	}
-	return update(ctx, info, planOptions{
+	cached := dryRun && usePlanCache(u, opts)
+	if cached {
+		emitter.diags = &diagRecorder{}
+	}
+	planOpts := planOptions{
 		UpdateOptions: opts,
 		SourceFunc:    newUpdateSource,
 		Events:        emitter,
-		Diag:          newEventSink(emitter, false),
-		StatusDiag:    newEventSink(emitter, true),
-	}, dryRun)
+		Diag:          newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters),
+		StatusDiag:    newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters),
+		runChecks:     true,
+	}
+	switch {
+	case cached:
+		return previewWithCache(ctx, info, planOpts)
+	case dryRun:
+		return previewPlan(ctx, info, planOpts)
+	default:
+		return update(ctx, info, planOpts, dryRun)
+	}
 }
 
 func installPlugins(
@@ -129,7 +325,7 @@ func installPlugins(
 	//
 	// Note that this is purely a best-effort thing. If we can't install missing plugins, just proceed; we'll fail later
 	// with an error message indicating exactly what plugins are missing.
-	if err := ensurePluginsAreInstalled(allPlugins); err != nil {
+	if err := ensurePluginsAreInstalled(allPlugins, plugctx.Diag); err != nil {
 		logging.V(7).Infof("newUpdateSource(): failed to install missing plugins: %v", err)
 	}
 
@@ -139,10 +335,32 @@ func installPlugins(
 	return allPlugins, defaultProviderVersions, nil
 }
 
+// languageEnv returns any additions to the environment of the project's language host. If the project constrains the
+// version of its language toolchain, this makes sure that a matching toolchain is installed and points the language
+// host at it.
+func languageEnv(proj *workspace.Project) ([]string, error) {
+	constraint := proj.Runtime.Version()
+	if constraint == "" {
+		return nil, nil
+	}
+	toolchain, err := plugin.ResolveToolchain(proj.Runtime.Name(), constraint)
+	if err != nil {
+		return nil, err
+	}
+	return toolchain.Env(), nil
+}
+
 func newUpdateSource(
 	client deploy.BackendClient, opts planOptions, proj *workspace.Project, pwd, main string,
 	target *deploy.Target, plugctx *plugin.Context, dryRun bool) (deploy.Source, error) {
 
+	// Point the language host at the toolchain the project asks for, if any.
+	env, err := languageEnv(proj)
+	if err != nil {
+		return nil, err
+	}
+	plugctx.LanguageEnv = env
+
 	allPlugins, defaultProviderVersions, err := installPlugins(proj, pwd, main, target,
 		plugctx)
 	if err != nil {
@@ -157,55 +375,150 @@ func newUpdateSource(
 		return nil, err
 	}
 
+	// If asked to, make sure that the providers will accept the resources in the snapshot before going any further.
+	if opts.ValidateSnapshot {
+		if err := validateSnapshot(plugctx, target.Snapshot, defaultProviderVersions); err != nil {
+			return nil, err
+		}
+	}
+
 	// If that succeeded, create a new source that will perform interpretation of the compiled program.
-	// TODO[pulumi/pulumi#88]: we are passing `nil` as the arguments map; we need to allow a way to pass these.
 	return deploy.NewEvalSource(plugctx, &deploy.EvalRunInfo{
-		Proj:    proj,
-		Pwd:     pwd,
-		Program: main,
-		Target:  target,
+		Proj:            proj,
+		Pwd:             pwd,
+		Program:         main,
+		Target:          target,
+		ConfigOverrides: opts.ConfigOverrides,
 	}, defaultProviderVersions, dryRun), nil
 }
 
-func update(ctx *Context, info *planContext, opts planOptions, dryRun bool) (ResourceChanges, result.Result) {
+// validateSnapshot asks the providers at the given versions to check the stored inputs of the resources in the given
+// snapshot, reporting each resource whose inputs are rejected and returning an error if there are any.
+func validateSnapshot(plugctx *plugin.Context, snap *deploy.Snapshot,
+	versions map[tokens.Package]*semver.Version) error {
+
+	mismatches, err := snap.ValidateInputs(plugctx.Host, versions)
+	if err != nil {
+		return errors.Wrap(err, "validating snapshot")
+	}
+	for _, m := range mismatches {
+		version := "an unknown version"
+		if m.Version != nil {
+			version = "v" + m.Version.String()
+		}
+		var reasons []string
+		for _, f := range m.Failures {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", f.Property, f.Reason))
+		}
+		plugctx.Diag.Errorf(diag.Message(m.URN, "stored inputs are rejected by the %s provider at %s: %s"),
+			m.Package, version, strings.Join(reasons, "; "))
+	}
+	if len(mismatches) > 0 {
+		return errors.Errorf("%d resources have stored inputs that their providers no longer accept, and may show "+
+			"unexpected diffs or fail to update", len(mismatches))
+	}
+	return nil
+}
+
+func update(ctx *Context, info *planContext, opts planOptions, dryRun bool) (*UpdateResult, result.Result) {
+	if !dryRun && opts.ConfirmDestructiveSteps && ctx.Confirmations == nil {
+		return nil, result.Error("confirming destructive steps requires a Confirmations channel on the context")
+	}
+	if !dryRun && opts.ConfirmProviderReplacements && ctx.Confirmations == nil {
+		return nil, result.Error("confirming provider replacements requires a Confirmations channel on the context")
+	}
+	if !dryRun && !opts.isRefresh && !checkFreeze(ctx.Freeze, opts.Diag) {
+		return nil, result.Bail()
+	}
+	if !dryRun {
+		defer ctx.Metrics.beginUpdate()()
+		if err := preflight(info.Update.GetTarget(), opts.UpdateOptions, opts.Diag); err != nil {
+			return nil, result.FromError(err)
+		}
+	}
+
+	opts.Events.progress = newProgressAggregator(opts.EventProfile, info.Update.GetTarget().Snapshot)
 	planResult, err := plan(ctx, info, opts, dryRun)
 	if err != nil {
 		return nil, result.FromError(err)
 	}
 
-	var resourceChanges ResourceChanges
+	var updateResult *UpdateResult
 	var res result.Result
 	if planResult != nil {
 		defer contract.IgnoreClose(planResult)
 
-		// Make the current working directory the same as the program's, and restore it upon exit.
-		done, chErr := planResult.Chdir()
-		if chErr != nil {
-			return nil, result.FromError(chErr)
-		}
-		defer done()
-
 		if dryRun {
 			// If a dry run, just print the plan, don't actually carry out the deployment.
-			resourceChanges, res = printPlan(ctx, planResult, dryRun)
+			updateResult, res = printPlan(ctx, planResult, dryRun)
 		} else {
-			// Otherwise, we will actually deploy the latest bits.
-			opts.Events.preludeEvent(dryRun, planResult.Ctx.Update.GetTarget().Config)
+			// Otherwise, we will actually deploy the latest bits. The configuration that the update runs with is
+			// recorded in the snapshots that it writes, so that the update can be reproduced later.
+			cfg, secretOverrides := planResult.Ctx.Update.GetTarget().EffectiveConfig(opts.ConfigOverrides)
+			opts.Events.preludeEvent(dryRun, cfg, secretOverrides)
+			if err := ctx.SnapshotManager.RecordConfig(cfg, secretOverrides); err != nil {
+				return nil, result.FromError(err)
+			}
+
+			// Remember the stack's outputs so that we can report how the update changed them.
+			oldOutputs := stackOutputs(info.Update.GetTarget().Snapshot)
 
 			// Walk the plan, reporting progress and executing the actual operations as we go.
 			start := time.Now()
 			actions := newUpdateActions(ctx, info.Update, opts)
-
-			res = planResult.Walk(ctx, actions, false)
-			resourceChanges = ResourceChanges(actions.Ops)
+			actions.Budget = newBudgetMonitor(opts.Budget, opts.Diag)
+			actions.Control = newUpdateControl(ctx, opts.Events)
+			actions.Hooks = newStepHooks(info.Update, opts)
+
+			res = planResult.Walk(ctx, actions, actions.Outcomes, false)
+			actions.Budget.close()
+			if actions.Control != nil {
+				actions.Control.close()
+			}
+			recordPlugins(ctx, planResult.Plan)
+			flushDiagnostics(opts.Diag, opts.StatusDiag)
+			resourceChanges := ResourceChanges(actions.Ops)
+			newOutputs := actions.StackOutputs()
+			updateResult = newUpdateResult(resourceChanges, actions.Outcomes)
+			updateResult.Outputs = newOutputs
+			updateResult.Deprecations = planResult.Plan.DeprecatedUsages()
+			if versioner, ok := ctx.SnapshotManager.(SnapshotVersioner); ok {
+				updateResult.SnapshotVersion = versioner.SnapshotVersion()
+			}
 
 			if len(resourceChanges) != 0 {
 				// Print out the total number of steps performed (and their kinds), the duration, and any summary info.
-				opts.Events.updateSummaryEvent(actions.MaybeCorrupt, time.Since(start), resourceChanges)
+				outputChanges := diffStackOutputs(oldOutputs, newOutputs, opts.Events.secrets, opts.Debug)
+				opts.Events.updateSummaryEvent(actions.MaybeCorrupt, time.Since(start), resourceChanges, outputChanges,
+					updateResult.Deprecations)
+			}
+
+			if opts.VerifySnapshot && !verifyUpdatedSnapshot(ctx, opts.Diag) {
+				res = result.Merge(res, result.Bail())
+			}
+
+			// Once the update has been applied, verify that the stack works by running its checks.
+			if res == nil && opts.runChecks {
+				if checks := updateChecks(info.Update.GetProject(), opts.UpdateOptions); len(checks) != 0 {
+					updateResult.Checks = runChecks(checks, planResult.Plan, actions, newOutputs)
+					opts.Events.checkResultsEvent(updateResult.Checks)
+				}
 			}
 		}
 	}
-	return resourceChanges, res
+	return updateResult, res
+}
+
+// recordPlugins records the provider plugins that served the given plan in the snapshot, so that the next update can
+// tell which of the stack's providers have been upgraded and migrate their resources' state.
+func recordPlugins(ctx *Context, plan *deploy.Plan) {
+	plugins, err := plan.ProviderPlugins()
+	if err == nil {
+		err = ctx.SnapshotManager.RecordPlugins(plugins)
+	}
+	if err != nil {
+		logging.V(7).Infof("failed to record the update's provider plugins: %v", err)
+	}
 }
 
 // updateActions pretty-prints the plan application process as it goes.
@@ -218,31 +531,101 @@ type updateActions struct {
 	MaybeCorrupt bool
 	Update       UpdateInfo
 	Opts         planOptions
+	Budget       *budgetMonitor
+	Control      *updateControl
+	Hooks        *stepHooks
+	Outcomes     *outcomeRecorder
+
+	confirmLock sync.Mutex            // serializes requests for confirmation.
+	confirmed   map[resource.URN]bool // the resources whose destructive steps have been confirmed.
+}
+
+// stackOutputs returns the outputs of the root stack resource in the given snapshot, if any.
+func stackOutputs(snap *deploy.Snapshot) resource.PropertyMap {
+	if snap == nil {
+		return nil
+	}
+	for _, res := range snap.Resources {
+		if res.Type == resource.RootStackType && !res.Delete {
+			return res.Outputs
+		}
+	}
+	return nil
+}
+
+// StackOutputs returns the outputs of the root stack resource after the steps applied so far, or nil if the stack
+// resource has been deleted or has not been operated upon.
+func (acts *updateActions) StackOutputs() resource.PropertyMap {
+	acts.MapLock.Lock()
+	defer acts.MapLock.Unlock()
+	for urn, step := range acts.Seen {
+		if urn.Type() == resource.RootStackType && step.New() != nil && !step.New().Delete {
+			return step.New().Outputs
+		}
+	}
+	return nil
 }
 
 func newUpdateActions(context *Context, u UpdateInfo, opts planOptions) *updateActions {
 	return &updateActions{
-		Context: context,
-		Ops:     make(map[deploy.StepOp]int),
-		Seen:    make(map[resource.URN]deploy.Step),
-		Update:  u,
-		Opts:    opts,
+		Context:  context,
+		Ops:      make(map[deploy.StepOp]int),
+		Seen:     make(map[resource.URN]deploy.Step),
+		Update:   u,
+		Opts:     opts,
+		Outcomes: newOutcomeRecorder(),
+
+		confirmed: make(map[resource.URN]bool),
 	}
 }
 
 func (acts *updateActions) OnResourceStepPre(step deploy.Step) (interface{}, error) {
+	// Hold the step back while a budget alarm is pending, and refuse it if the alarm stopped the update.
+	if acts.Budget != nil {
+		if err := acts.Budget.beforeStep(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Hold the step back while the update is paused, and refuse it if the update has been aborted.
+	if acts.Control != nil {
+		if err := acts.Control.beforeStep(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Wait for the step to be confirmed if it must be, and refuse it if it is declined.
+	if err := acts.confirmStep(step); err != nil {
+		return nil, err
+	}
+
+	// Let the step hooks know that the step is about to be applied, and refuse it if a blocking hook fails.
+	if err := acts.Hooks.beforeStep(step); err != nil {
+		return nil, err
+	}
+
 	// Ensure we've marked this step as observed.
 	acts.MapLock.Lock()
 	acts.Seen[step.URN()] = step
 	acts.MapLock.Unlock()
 
+	// Mask any secret inputs in case the provider echoes them back in its diagnostics.
+	acts.Opts.Events.secrets.addStates(step.New())
+
 	// Skip reporting if necessary.
 	if shouldReportStep(step, acts.Opts) {
+		acts.Outcomes.begin(step)
 		acts.Opts.Events.resourcePreEvent(step, false /*planning*/, acts.Opts.Debug)
 	}
 
 	// Inform the snapshot service that we are about to perform a step.
-	return acts.Context.SnapshotManager.BeginMutation(step)
+	var mutation SnapshotMutation
+	err := acts.persist(func() error {
+		var err error
+		mutation, err = acts.Context.SnapshotManager.BeginMutation(step)
+		return err
+	})
+	return mutation, err
 }
 
 func (acts *updateActions) OnResourceStepPost(
@@ -253,6 +636,8 @@ func (acts *updateActions) OnResourceStepPost(
 	assertSeen(acts.Seen, step)
 	acts.MapLock.Unlock()
 
+	acts.Context.Metrics.stepExecuted(step.Op(), err)
+
 	// If we've already been terminated, exit without writing the checkpoint. We explicitly want to leave the
 	// checkpoint in an inconsistent state in this event.
 	if acts.Context.Cancel.TerminateErr() != nil {
@@ -260,6 +645,7 @@ func (acts *updateActions) OnResourceStepPost(
 	}
 
 	reportStep := shouldReportStep(step, acts.Opts)
+	acts.Opts.Events.secrets.addStates(step.New())
 
 	// Report the result of the step.
 	if err != nil {
@@ -273,9 +659,13 @@ func (acts *updateActions) OnResourceStepPost(
 		}
 
 		// Issue a true, bonafide error.
-		acts.Opts.Diag.Errorf(diag.GetPlanApplyFailedError(errorURN), err)
+		summary := deploy.AsStepError(err).Summary(acts.Opts.Retry, status)
+		acts.Opts.Diag.Errorf(diag.GetPlanApplyFailedError(errorURN), summary, err)
 		if reportStep {
-			acts.Opts.Events.resourceOperationFailedEvent(step, status, acts.Steps, acts.Opts.Debug)
+			acts.Outcomes.end(step, step.Op(), err)
+			acts.Opts.Events.stepProcessed(step.Op(), false /*counted*/)
+			acts.Opts.Events.resourceOperationFailedEvent(
+				step, status, err, acts.Opts.Retry, acts.Steps, acts.Opts.Debug)
 		}
 	} else if reportStep {
 		op, record := step.Op(), step.Logical()
@@ -287,6 +677,11 @@ func (acts *updateActions) OnResourceStepPost(
 		if step.Op() == deploy.OpRead {
 			record = ShouldRecordReadStep(step)
 		}
+		if op == deploy.OpPrune && step.New() != nil {
+			// An orphan that is only reported is left unchanged by the update, though its preview counts it as a prune.
+			op = deploy.OpSame
+		}
+		acts.Outcomes.end(step, op, nil)
 
 		if record {
 			// Increment the counters.
@@ -295,6 +690,10 @@ func (acts *updateActions) OnResourceStepPost(
 			acts.Ops[op]++
 			acts.MapLock.Unlock()
 		}
+		acts.Opts.Events.stepProcessed(op, record)
+		if acts.Budget != nil {
+			acts.Budget.afterStep(step)
+		}
 
 		// Also show outputs here for custom resources, since there might be some from the initial registration. We do
 		// not show outputs for component resources at this point: any that exist must be from a previous execution of
@@ -329,10 +728,79 @@ func (acts *updateActions) OnResourceStepPost(
 		}
 	}
 
+	// Record the step in the audit log, if any. Steps that leave their resource unchanged are not audited.
+	var auditErr error
+	if acts.Context.AuditLog != nil && step.Op() != deploy.OpSame {
+		auditErr = auditStep(acts.Context, acts.Update, step, status, err, acts.Opts.Events.secrets)
+	}
+
+	// A provider operation that timed out may still take effect after it has been abandoned, so its outcome is
+	// unknown. Leave it pending in the snapshot rather than ending the mutation, so that the next update refuses to
+	// proceed until the resource has been checked and the operation resolved.
+	if err != nil && status == resource.StatusUnknown && deploy.AsStepError(err).Kind == deploy.StepErrorTimeout {
+		logging.V(7).Infof("OnResourceStepPost(%s): leaving timed-out %s pending", step.URN(), step.Op())
+		if hookErr := acts.Hooks.afterStep(step, status, err); hookErr != nil {
+			return hookErr
+		}
+		return errors.Wrap(auditErr, "recording step in audit log")
+	}
+
 	// Write out the current snapshot. Note that even if a failure has occurred, we should still have a
 	// safe checkpoint.  Note that any error that occurs when writing the checkpoint trumps the error
 	// reported above.
-	return ctx.(SnapshotMutation).End(step, err == nil || status == resource.StatusPartialFailure)
+	endErr := acts.persist(func() error {
+		return ctx.(SnapshotMutation).End(step, err == nil || status == resource.StatusPartialFailure)
+	})
+	if endErr != nil {
+		return endErr
+	}
+
+	// Let the step hooks know the step's result once it has been saved, so that a blocking hook that fails does not
+	// leave the snapshot without it.
+	if hookErr := acts.Hooks.afterStep(step, status, err); hookErr != nil {
+		return hookErr
+	}
+	return errors.Wrap(auditErr, "recording step in audit log")
+}
+
+// persist writes a snapshot with the given function, recording the write in the update's outcomes and metrics.
+func (acts *updateActions) persist(write func() error) error {
+	return acts.Outcomes.persist(func() error {
+		return acts.Context.Metrics.timeSnapshotWrite(write)
+	})
+}
+
+func (acts *updateActions) OnResourceStepProgress(step deploy.Step, percent int, message string) {
+	if shouldReportStep(step, acts.Opts) {
+		acts.Opts.Events.stepProgressEvent(step, percent, message, acts.Opts.Debug)
+	}
+}
+
+func (acts *updateActions) OnRefreshProgress(progress deploy.RefreshProgress) {
+	acts.Opts.Events.refreshProgressEvent(progress)
+}
+
+func (acts *updateActions) OnDeletesScheduled(batches [][]deploy.Step) {
+	if acts.Opts.ReportDeletionOrder {
+		acts.Opts.Events.deletionOrderEvent(batches, acts.Opts)
+	}
+}
+
+func (acts *updateActions) OnProviderChange(change deploy.ProviderChange) {
+	acts.Opts.Events.providerChangeEvent(change)
+}
+
+func (acts *updateActions) OnProviderRestart(step deploy.Step, err error) {
+	// Restarts are always reported, even for steps that are otherwise hidden, since they affect the whole update.
+	acts.Context.Metrics.pluginRestarted(step.Type().Package())
+	acts.Opts.Events.pluginLifecycleEvent(step, err, acts.Opts.Debug)
+}
+
+func (acts *updateActions) OnResourceStepRetry(step deploy.Step, attempt int, err error) {
+	// The retry itself is reported as a warning by the step executor.
+	if shouldReportStep(step, acts.Opts) {
+		acts.Outcomes.retry(step)
+	}
 }
 
 func (acts *updateActions) OnResourceOutputs(step deploy.Step) error {
@@ -340,6 +808,8 @@ func (acts *updateActions) OnResourceOutputs(step deploy.Step) error {
 	assertSeen(acts.Seen, step)
 	acts.MapLock.Unlock()
 
+	acts.Opts.Events.secrets.addStates(step.New())
+
 	// Skip reporting if necessary.
 	if shouldReportStep(step, acts.Opts) {
 		acts.Opts.Events.resourceOutputsEvent(step.Op(), step, false /*planning*/, acts.Opts.Debug)
@@ -347,9 +817,12 @@ func (acts *updateActions) OnResourceOutputs(step deploy.Step) error {
 
 	// There's a chance there are new outputs that weren't written out last time.
 	// We need to perform another snapshot write to ensure they get written out.
-	return acts.Context.SnapshotManager.RegisterResourceOutputs(step)
+	return acts.persist(func() error {
+		return acts.Context.SnapshotManager.RegisterResourceOutputs(step)
+	})
 }
 
 func (acts *updateActions) OnPolicyViolation(urn resource.URN, d plugin.AnalyzeDiagnostic) {
+	acts.Outcomes.violation(urn, d)
 	acts.Opts.Events.policyViolationEvent(urn, d)
 }
@@ -0,0 +1,232 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// StackUpdate describes the update of a single stack as part of a call to UpdateMany.
+type StackUpdate struct {
+	Info            UpdateInfo      // the update to perform.
+	SnapshotManager SnapshotManager // the snapshot manager that persists the stack's state.
+}
+
+// UpdateMany updates a number of stacks concurrently. The stacks share the context's cancellation source, backend
+// client, and event channel; each event sent to the channel is tagged with the name of the stack that generated it,
+// and a single cancellation event is sent once every update has finished. The context's snapshot manager is not used:
+// each stack's state is persisted by its own snapshot manager.
+//
+// The degree of parallelism in the options is a budget shared by all of the stacks rather than a limit for each, so
+// that driving many stacks at once does not multiply the number of concurrent provider operations.
+//
+// The stacks also share a single plugin host, so that each plugin binary is launched from one place rather than once
+// per stack; it is taken from the options' plugin pool if there is one. Each stack still configures its own provider
+// instances. As the host's plugins run in a single working directory, every stack must belong to the same project
+// root. Diagnostics reported by the shared host rather than by a particular stack's update are sent untagged.
+//
+// If the context has a Confirmations channel, each response sent on it is routed to the update of the stack named by
+// the response's URN.
+//
+// The result of each stack's update is returned keyed by stack name. If any update fails, the failures are merged into
+// the returned result; the remaining updates run to completion regardless.
+func UpdateMany(updates []StackUpdate, ctx *Context, opts UpdateOptions,
+	dryRun bool) (map[tokens.QName]*UpdateResult, result.Result) {
+
+	contract.Require(ctx != nil, "ctx")
+
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+	for _, u := range updates {
+		contract.Require(u.Info != nil, "updates")
+		if root := updates[0].Info.GetRoot(); u.Info.GetRoot() != root {
+			return nil, result.FromError(errors.Errorf("stack %s is not in the project root %s of stack %s",
+				u.Info.GetTarget().Name, root, updates[0].Info.GetTarget().Name))
+		}
+	}
+
+	if len(updates) > 0 {
+		host, err := newUpdateManyHost(ctx, updates, opts)
+		if err != nil {
+			return nil, result.FromError(err)
+		}
+		defer contract.IgnoreClose(host)
+		opts.host = sharedHost{host}
+	}
+
+	if parallel := (deploy.Options{Parallel: opts.Parallel}); !parallel.InfiniteParallelism() {
+		opts.budget = make(chan struct{}, parallel.DegreeOfParallelism())
+	}
+
+	var confirmations map[tokens.QName]chan ConfirmationResponse
+	if ctx.Confirmations != nil {
+		confirmations = make(map[tokens.QName]chan ConfirmationResponse)
+		for _, u := range updates {
+			confirmations[u.Info.GetTarget().Name] = make(chan ConfirmationResponse)
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		go routeConfirmations(ctx.Confirmations, confirmations, done)
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[tokens.QName]*UpdateResult)
+	var res result.Result
+	for _, u := range updates {
+		stack := u.Info.GetTarget().Name
+		events := make(chan Event)
+		stackCtx := &Context{
+			Cancel:          ctx.Cancel,
+			Events:          events,
+			SnapshotManager: u.SnapshotManager,
+			BackendClient:   ctx.BackendClient,
+			ParentSpan:      ctx.ParentSpan,
+		}
+		if confirmations != nil {
+			stackCtx.Confirmations = confirmations[stack]
+		}
+
+		// Forward the stack's events to the shared channel, tagged with the stack's name. Each update sends its own
+		// cancellation event when it finishes; these are dropped in favor of the one sent once all updates are done.
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			for e := range events {
+				if e.Type != CancelEvent {
+					e.Stack = stack
+					ctx.Events <- e
+				}
+			}
+		}()
+
+		wg.Add(1)
+		go func(info UpdateInfo) {
+			defer wg.Done()
+
+			logging.V(7).Infof("UpdateMany: updating stack %s", stack)
+			stackResult, stackRes := Update(info, stackCtx, opts, dryRun)
+			close(events)
+			<-forwarded
+
+			lock.Lock()
+			defer lock.Unlock()
+			results[stack] = stackResult
+			if stackRes != nil {
+				logging.V(7).Infof("UpdateMany: update of stack %s failed", stack)
+				res = result.Merge(res, stackRes)
+			}
+		}(u.Info)
+	}
+	wg.Wait()
+
+	return results, res
+}
+
+// newUpdateManyHost returns the plugin host shared by the given updates: the host supplied in the options if there is
+// one, and a new one, loaded for the first update's project, otherwise. Closing the returned host shuts it down.
+func newUpdateManyHost(ctx *Context, updates []StackUpdate, opts UpdateOptions) (plugin.Host, error) {
+	if opts.host != nil {
+		return opts.host, nil
+	}
+
+	emitter, err := makeSharedEventEmitter(ctx.Events, updates)
+	if err != nil {
+		return nil, err
+	}
+	diagSink := newEventSink(emitter, false, opts.diagnosticLimits(), opts.DiagnosticFilters)
+	statusSink := newEventSink(emitter, true, DiagnosticLimits{}, opts.DiagnosticFilters)
+
+	u := updates[0].Info
+	proj := u.GetProject()
+	_, _, hostCtx, err := projectInfoContext(&Projinfo{Proj: proj, Root: u.GetRoot()}, nil, opts.PluginPool, nil,
+		diagSink, statusSink, nil)
+	if err != nil {
+		return nil, err
+	}
+	env, err := languageEnv(proj)
+	if err != nil {
+		contract.IgnoreClose(hostCtx)
+		return nil, err
+	}
+	hostCtx.LanguageEnv, hostCtx.AssetCache = env, opts.AssetCache
+	return &contextHost{Host: hostCtx.Host, ctx: hostCtx}, nil
+}
+
+// makeSharedEventEmitter returns an emitter for the events of an UpdateMany that belong to no single stack, which masks
+// the secrets of every stack.
+func makeSharedEventEmitter(events chan<- Event, updates []StackUpdate) (eventEmitter, error) {
+	filter := newSecretFilter()
+	for _, u := range updates {
+		emitter, err := makeEventEmitter(events, u.Info)
+		if err != nil {
+			return eventEmitter{}, err
+		}
+		filter.merge(emitter.secrets)
+	}
+	return eventEmitter{Chan: events, secrets: filter}, nil
+}
+
+// contextHost is a plugin host that is closed by closing the context that owns it, which returns it to its pool if it
+// was taken from one.
+type contextHost struct {
+	plugin.Host
+	ctx *plugin.Context
+}
+
+func (h *contextHost) Close() error {
+	return h.ctx.Close()
+}
+
+// routeConfirmations forwards each response received from the caller to the channel of the stack it confirms a step
+// for, until the caller's channel is closed or done is closed.
+func routeConfirmations(in <-chan ConfirmationResponse, out map[tokens.QName]chan ConfirmationResponse,
+	done <-chan struct{}) {
+
+	for {
+		select {
+		case resp, ok := <-in:
+			if !ok {
+				return
+			}
+			ch, has := out[resp.URN.Stack()]
+			if !has {
+				logging.V(7).Infof("UpdateMany: dropping confirmation for %s, which is not in any stack", resp.URN)
+				continue
+			}
+			select {
+			case ch <- resp:
+			case <-done:
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
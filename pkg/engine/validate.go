@@ -0,0 +1,207 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// ValidateOptions controls the checks that Validate makes of a plan.
+type ValidateOptions struct {
+	UpdateOptions
+
+	// NoReplace lists the types of resources that the plan must not replace, e.g. databases whose replacement would
+	// lose data.
+	NoReplace []tokens.Type
+}
+
+// ValidationKind classifies a finding of Validate.
+type ValidationKind string
+
+const (
+	// ValidationPolicy is a policy violation reported by an analyzer.
+	ValidationPolicy ValidationKind = "policy"
+	// ValidationProtected is a delete or replacement of a protected resource that the options do not allow.
+	ValidationProtected ValidationKind = "protected"
+	// ValidationReplace is a replacement of a resource whose type the options forbid replacing.
+	ValidationReplace ValidationKind = "replace"
+	// ValidationError is an error reported while computing the plan, e.g. by the program or a provider.
+	ValidationError ValidationKind = "error"
+)
+
+// ValidationFinding is a single problem that Validate found with a plan.
+type ValidationFinding struct {
+	Kind    ValidationKind // the kind of problem.
+	URN     resource.URN   // the resource concerned, if any.
+	Message string         // a human-readable description of the problem.
+	Policy  string         // for policy violations, the policy pack and policy that reported it.
+
+	// Blocking is true if the finding fails validation. Policy violations that are only advisory are not blocking.
+	Blocking bool
+}
+
+// Verdict is the result of Validate.
+type Verdict struct {
+	Passed   bool                // true if no finding is blocking.
+	Changes  ResourceChanges     // the aggregate resource changes of the plan, by operation type.
+	Steps    []PlanStep          // the steps of the plan.
+	Findings []ValidationFinding // the problems found with the plan, in the order they were found.
+}
+
+// Validate computes the plan to bring the stack up to date with its program, runs the configured analyzers against
+// it, and checks it against the given constraints, without performing any of its steps. Unlike Preview, it emits no
+// events: the context's event channel is not used, and everything that would be reported is instead returned as a
+// structured verdict, which makes Validate suitable for checks such as a pre-merge gate in CI.
+//
+// The plan may not delete or replace protected resources unless the options allow it, nor replace resources of the
+// types the options list. Policy violations are blocking if their enforcement level is mandatory, and errors are
+// always blocking. A result is returned only if the plan could not be validated at all.
+func Validate(u UpdateInfo, ctx *Context, opts ValidateOptions) (*Verdict, result.Result) {
+	contract.Require(u != nil, "update")
+	contract.Require(ctx != nil, "ctx")
+
+	if err := opts.Validate(); err != nil {
+		return nil, result.FromError(err)
+	}
+
+	info, err := newPlanContext(u, "validate", ctx.ParentSpan)
+	if err != nil {
+		return nil, result.FromError(err)
+	}
+	defer info.Close()
+
+	// Gather the findings that would otherwise be reported as events.
+	verdict := &Verdict{}
+	events := make(chan Event)
+	done := make(chan bool)
+	go func() {
+		for e := range events {
+			if finding, ok := validationFindingOfEvent(e); ok {
+				verdict.Findings = append(verdict.Findings, finding)
+			}
+		}
+		close(done)
+	}()
+
+	emitter, err := makeEventEmitter(events, u)
+	if err != nil {
+		close(events)
+		return nil, result.FromError(err)
+	}
+
+	// Deletes and replacements of protected resources would stop the plan, so they are allowed while planning and
+	// checked once the plan is complete.
+	updateOpts := opts.UpdateOptions
+	updateOpts.AllowProtected = true
+	planOpts := planOptions{
+		UpdateOptions: updateOpts,
+		SourceFunc:    newUpdateSource,
+		Events:        emitter,
+		Diag:          newEventSink(emitter, false, DiagnosticLimits{}, nil),
+		StatusDiag:    newEventSink(emitter, true, DiagnosticLimits{}, nil),
+	}
+
+	vctx := *ctx
+	vctx.Events = events
+	updateResult, res := previewPlan(&vctx, info, planOpts)
+	close(events)
+	<-done
+	if res != nil && !res.IsBail() {
+		return nil, res
+	}
+
+	if updateResult != nil {
+		plan := updateResult.Plan
+		verdict.Changes, verdict.Steps = plan.Changes, plan.Steps
+		verdict.Findings = append(verdict.Findings, validatePlanSteps(plan.Steps, opts)...)
+	}
+
+	verdict.Passed = res == nil
+	for _, finding := range verdict.Findings {
+		if finding.Blocking {
+			verdict.Passed = false
+		}
+	}
+	return verdict, nil
+}
+
+// validationFindingOfEvent returns the finding reported by the given event, if any.
+func validationFindingOfEvent(e Event) (ValidationFinding, bool) {
+	switch e.Type {
+	case DiagEvent:
+		payload := e.Payload.(DiagEventPayload)
+		if payload.Severity != diag.Error {
+			return ValidationFinding{}, false
+		}
+		return ValidationFinding{
+			Kind:     ValidationError,
+			URN:      payload.URN,
+			Message:  colors.Never.Colorize(payload.Message),
+			Blocking: true,
+		}, true
+	case PolicyViolationEvent:
+		payload := e.Payload.(PolicyViolationEventPayload)
+		return ValidationFinding{
+			Kind:     ValidationPolicy,
+			URN:      payload.ResourceURN,
+			Message:  colors.Never.Colorize(payload.Message),
+			Policy:   fmt.Sprintf("%s/%s", payload.PolicyPackName, payload.PolicyName),
+			Blocking: payload.EnforcementLevel == apitype.Mandatory,
+		}, true
+	default:
+		return ValidationFinding{}, false
+	}
+}
+
+// validatePlanSteps checks the given steps against the constraints of the given options.
+func validatePlanSteps(steps []PlanStep, opts ValidateOptions) []ValidationFinding {
+	noReplace := make(map[tokens.Type]bool)
+	for _, t := range opts.NoReplace {
+		noReplace[t] = true
+	}
+
+	var findings []ValidationFinding
+	for _, step := range steps {
+		deletes := step.Op == deploy.OpDelete || (step.Op == deploy.OpPrune && step.New == nil)
+		replaces := step.Op == deploy.OpReplace
+
+		if (deletes || replaces) && !opts.AllowProtected && step.Old != nil && step.Old.Protect {
+			findings = append(findings, ValidationFinding{
+				Kind:     ValidationProtected,
+				URN:      step.URN,
+				Message:  fmt.Sprintf("the plan would %s protected resource '%s'", step.Op, step.URN),
+				Blocking: true,
+			})
+		}
+		if replaces && noReplace[step.Type] {
+			findings = append(findings, ValidationFinding{
+				Kind:     ValidationReplace,
+				URN:      step.URN,
+				Message:  fmt.Sprintf("resources of type %s must not be replaced", step.Type),
+				Blocking: true,
+			})
+		}
+	}
+	return findings
+}
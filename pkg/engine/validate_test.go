@@ -0,0 +1,104 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+)
+
+func TestValidate(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				DiffF: func(urn resource.URN, id resource.ID,
+					olds, news resource.PropertyMap) (plugin.DiffResult, error) {
+
+					if !olds["engine"].DeepEquals(news["engine"]) {
+						return plugin.DiffResult{ReplaceKeys: []resource.PropertyKey{"engine"}}, nil
+					}
+					return plugin.DiffResult{}, nil
+				},
+			}, nil
+		}),
+	}
+
+	dbEngine, keep := "postgres", true
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		if keep {
+			_, _, _, err := monitor.RegisterResource("pkgA:m:bucket", "logs", true, "", true, nil, "",
+				resource.PropertyMap{}, nil, false, "", nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		_, _, _, err := monitor.RegisterResource("pkgA:m:db", "main", true, "", false, nil, "",
+			resource.PropertyMap{"engine": resource.NewStringProperty(dbEngine)}, nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+
+	p := &TestPlan{
+		Options: UpdateOptions{host: host},
+		Steps:   []TestStep{{Op: Update}},
+	}
+	snap := p.Run(t, nil)
+
+	validate := func(opts ValidateOptions) *Verdict {
+		events := make(chan Event)
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		ctx := &Context{Cancel: cancelCtx, Events: events}
+		info := &updateInfo{project: p.GetProject(), target: p.GetTarget(snap)}
+		verdict, res := Validate(info, ctx, opts)
+		assert.Nil(t, res)
+		return verdict
+	}
+
+	// An unchanged program passes.
+	verdict := validate(ValidateOptions{UpdateOptions: UpdateOptions{host: host}})
+	assert.True(t, verdict.Passed)
+	assert.Empty(t, verdict.Findings)
+
+	// Deleting a protected resource and replacing a flagged type fail, without emitting any events.
+	dbEngine, keep = "mysql", false
+	verdict = validate(ValidateOptions{
+		UpdateOptions: UpdateOptions{host: host},
+		NoReplace:     []tokens.Type{"pkgA:m:db"},
+	})
+	assert.False(t, verdict.Passed)
+	assert.Equal(t, 1, verdict.Changes[deploy.OpDelete])
+	assert.Equal(t, 1, verdict.Changes[deploy.OpReplace])
+	if assert.Len(t, verdict.Findings, 2) {
+		assert.Equal(t, ValidationReplace, verdict.Findings[0].Kind)
+		assert.Equal(t, p.NewURN("pkgA:m:db", "main", ""), verdict.Findings[0].URN)
+		assert.Equal(t, ValidationProtected, verdict.Findings[1].Kind)
+		assert.Equal(t, p.NewURN("pkgA:m:bucket", "logs", ""), verdict.Findings[1].URN)
+	}
+
+	// The same plan passes if the options allow it.
+	verdict = validate(ValidateOptions{UpdateOptions: UpdateOptions{host: host, AllowProtected: true}})
+	assert.True(t, verdict.Passed)
+	assert.Empty(t, verdict.Findings)
+}
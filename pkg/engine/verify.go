@@ -0,0 +1,179 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+)
+
+// SnapshotIssue identifies a kind of problem that VerifySnapshot finds in a snapshot.
+type SnapshotIssue string
+
+const (
+	// SnapshotMagicMismatch means that the snapshot's magic cookie does not match its contents, so it may have been
+	// tampered with or corrupted.
+	SnapshotMagicMismatch SnapshotIssue = "magic-mismatch"
+	// SnapshotDuplicateURN means that more than one resource that is not marked for deletion has the same URN.
+	SnapshotDuplicateURN SnapshotIssue = "duplicate-urn"
+	// SnapshotMissingParent means that a resource's parent is not in the snapshot.
+	SnapshotMissingParent SnapshotIssue = "missing-parent"
+	// SnapshotParentOutOfOrder means that a resource's parent comes after it in the snapshot.
+	SnapshotParentOutOfOrder SnapshotIssue = "parent-out-of-order"
+	// SnapshotMissingDependency means that one of a resource's dependencies is not in the snapshot.
+	SnapshotMissingDependency SnapshotIssue = "missing-dependency"
+	// SnapshotDependencyOutOfOrder means that one of a resource's dependencies comes after it in the snapshot.
+	SnapshotDependencyOutOfOrder SnapshotIssue = "dependency-out-of-order"
+	// SnapshotInvalidProvider means that a resource's provider reference cannot be parsed, or that a provider
+	// resource cannot be referred to.
+	SnapshotInvalidProvider SnapshotIssue = "invalid-provider"
+	// SnapshotMissingProvider means that a resource refers to a provider that is not in the snapshot.
+	SnapshotMissingProvider SnapshotIssue = "missing-provider"
+	// SnapshotProviderOutOfOrder means that a resource's provider comes after it in the snapshot.
+	SnapshotProviderOutOfOrder SnapshotIssue = "provider-out-of-order"
+)
+
+// SnapshotFinding describes a single problem with the referential integrity of a snapshot.
+type SnapshotFinding struct {
+	Issue   SnapshotIssue // the kind of problem.
+	URN     resource.URN  // the resource with the problem, or empty if the problem is with the snapshot as a whole.
+	Related string        // the parent, dependency, or provider reference at fault, if any.
+	Message string        // a human-readable description of the problem.
+}
+
+func (f SnapshotFinding) String() string {
+	return f.Message
+}
+
+// VerifySnapshot checks the referential integrity of the given snapshot: that each resource's parent, dependencies,
+// and provider exist and come before it, that provider references are valid, and that no two live resources share a
+// URN. Unlike deploy.Snapshot.VerifyIntegrity, which stops at the first problem, it returns every problem that it
+// finds, in the order of the resources in the snapshot. A nil snapshot has no problems.
+func VerifySnapshot(snap *deploy.Snapshot) []SnapshotFinding {
+	if snap == nil {
+		return nil
+	}
+
+	var findings []SnapshotFinding
+	report := func(issue SnapshotIssue, urn resource.URN, related string, format string, args ...interface{}) {
+		findings = append(findings, SnapshotFinding{
+			Issue:   issue,
+			URN:     urn,
+			Related: related,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if snap.Manifest.Magic != snap.Manifest.NewMagic() {
+		report(SnapshotMagicMismatch, "", "", "magic cookie mismatch; possible tampering/corruption detected")
+	}
+
+	// Note every resource and provider in the snapshot, so that references to those that come later can be told apart
+	// from references to those that are missing entirely.
+	all := make(map[resource.URN]bool)
+	allProviders := make(map[providers.Reference]bool)
+	for _, state := range snap.Resources {
+		all[state.URN] = true
+		if providers.IsProviderType(state.Type) {
+			if ref, err := providers.NewReference(state.URN, state.ID); err == nil {
+				allProviders[ref] = true
+			}
+		}
+	}
+
+	urns := make(map[resource.URN]bool)
+	provs := make(map[providers.Reference]bool)
+	for _, state := range snap.Resources {
+		urn := state.URN
+
+		if providers.IsProviderType(state.Type) {
+			ref, err := providers.NewReference(urn, state.ID)
+			if err != nil {
+				report(SnapshotInvalidProvider, urn, "", "provider %s is not referenceable: %v", urn, err)
+			} else {
+				provs[ref] = true
+			}
+		}
+		if provider := state.Provider; provider != "" {
+			ref, err := providers.ParseReference(provider)
+			switch {
+			case err != nil:
+				report(SnapshotInvalidProvider, urn, provider,
+					"failed to parse provider reference for resource %s: %v", urn, err)
+			case provs[ref]:
+			case allProviders[ref]:
+				report(SnapshotProviderOutOfOrder, urn, provider, "resource %s's provider %s comes after it", urn, ref)
+			default:
+				report(SnapshotMissingProvider, urn, provider, "resource %s refers to unknown provider %s", urn, ref)
+			}
+		}
+
+		if par := state.Parent; par != "" && !urns[par] {
+			if all[par] {
+				report(SnapshotParentOutOfOrder, urn, string(par), "child resource %s's parent %s comes after it",
+					urn, par)
+			} else {
+				report(SnapshotMissingParent, urn, string(par), "child resource %s refers to missing parent %s",
+					urn, par)
+			}
+		}
+
+		for _, dep := range state.Dependencies {
+			if urns[dep] {
+				continue
+			}
+			if all[dep] {
+				report(SnapshotDependencyOutOfOrder, urn, string(dep), "resource %s's dependency %s comes after it",
+					urn, dep)
+			} else {
+				report(SnapshotMissingDependency, urn, string(dep),
+					"resource %s dependency %s refers to missing resource", urn, dep)
+			}
+		}
+
+		// The only time we should have duplicate URNs is when all but one of them are marked for deletion.
+		if urns[urn] && !state.Delete {
+			report(SnapshotDuplicateURN, urn, "", "duplicate resource %s (not marked for deletion)", urn)
+		}
+
+		urns[urn] = true
+	}
+
+	return findings
+}
+
+// verifyUpdatedSnapshot verifies the snapshot that an update left behind, if the context's SnapshotManager can
+// return it, reporting each problem that it finds as an error. It returns false if it finds any problems.
+func verifyUpdatedSnapshot(ctx *Context, d diag.Sink) bool {
+	reader, ok := ctx.SnapshotManager.(SnapshotReader)
+	if !ok {
+		return true
+	}
+	snap, err := reader.Snapshot()
+	if err != nil {
+		d.Errorf(diag.RawMessage("" /*urn*/, fmt.Sprintf("could not read the stack's state to verify it: %v", err)))
+		return false
+	}
+
+	findings := VerifySnapshot(snap)
+	for _, f := range findings {
+		d.Errorf(diag.RawMessage(f.URN, fmt.Sprintf("the stack's state failed verification: %s", f.Message)))
+	}
+	return len(findings) == 0
+}
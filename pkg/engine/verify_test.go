@@ -0,0 +1,150 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+func TestVerifySnapshot(t *testing.T) {
+	prov := newGraphTestState("prov", providers.MakeProviderType("pkgA"), "", "")
+	ref, err := providers.NewReference(prov.URN, prov.ID)
+	assert.NoError(t, err)
+	later := newGraphTestState("later", providers.MakeProviderType("pkgB"), "", "")
+	laterRef, err := providers.NewReference(later.URN, later.ID)
+	assert.NoError(t, err)
+
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", ref.String())
+	resB := newGraphTestState("resB", "pkgA:m:typA", resA.URN, ref.String(), resA.URN)
+	assert.Empty(t, VerifySnapshot(&deploy.Snapshot{Resources: []*resource.State{prov, resA, resB}}))
+	assert.Empty(t, VerifySnapshot(nil))
+
+	// Every problem is found, not just the first.
+	missing := resource.NewURN("test", "test", "", "pkgA:m:typA", "missing")
+	orphan := newGraphTestState("orphan", "pkgA:m:typA", missing, laterRef.String(), missing, resB.URN)
+	unknown := newGraphTestState("unknown", "pkgA:m:typA", resB.URN, "not-a-reference")
+	unprovided := newGraphTestState("unprovided", "pkgA:m:typA", "",
+		"urn:pulumi:test::test::pulumi:providers:pkgC::p::id")
+	duplicate := newGraphTestState("resA", "pkgA:m:typA", "", ref.String())
+	snap := &deploy.Snapshot{
+		Manifest:  deploy.Manifest{Version: "1.0.0", Magic: "bogus"},
+		Resources: []*resource.State{prov, resA, orphan, resB, unknown, unprovided, duplicate, later},
+	}
+
+	var issues []SnapshotIssue
+	for _, f := range VerifySnapshot(snap) {
+		issues = append(issues, f.Issue)
+	}
+	assert.Equal(t, []SnapshotIssue{
+		SnapshotMagicMismatch,
+		SnapshotProviderOutOfOrder,
+		SnapshotMissingParent,
+		SnapshotMissingDependency,
+		SnapshotDependencyOutOfOrder,
+		SnapshotInvalidProvider,
+		SnapshotMissingProvider,
+		SnapshotDuplicateURN,
+	}, issues)
+
+	if findings := VerifySnapshot(snap); assert.Len(t, findings, 8) {
+		assert.Equal(t, orphan.URN, findings[2].URN)
+		assert.Equal(t, string(missing), findings[2].Related)
+		assert.Equal(t, "child resource "+string(orphan.URN)+" refers to missing parent "+string(missing),
+			findings[2].Message)
+	}
+
+	// A resource that is marked for deletion may share its URN with a live one.
+	duplicate.Delete = true
+	assert.Len(t, VerifySnapshot(snap), 7)
+}
+
+// verifiedJournal is a Journal that returns a fixed snapshot for verification.
+type verifiedJournal struct {
+	*Journal
+	snap *deploy.Snapshot
+}
+
+func (j *verifiedJournal) Snapshot() (*deploy.Snapshot, error) {
+	return j.snap, nil
+}
+
+func TestVerifySnapshotAfterUpdate(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	program := deploytest.NewLanguageRuntime(func(_ plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "", nil,
+			nil, false, "", nil, nil)
+		return err
+	})
+	host := deploytest.NewPluginHost(nil, nil, program, loaders...)
+	p := &TestPlan{Options: UpdateOptions{host: host, VerifySnapshot: true}}
+
+	run := func(snap *deploy.Snapshot) ([]string, bool) {
+		cancelCtx, _ := cancel.NewContext(context.Background())
+		events := make(chan Event)
+		done := make(chan []string)
+		go func() {
+			var errs []string
+			for e := range events {
+				if e.Type == DiagEvent {
+					if payload := e.Payload.(DiagEventPayload); payload.Severity == diag.Error {
+						errs = append(errs, payload.Message)
+					}
+				}
+			}
+			done <- errs
+		}()
+
+		journal := &verifiedJournal{Journal: newJournal(), snap: snap}
+		ctx := &Context{Cancel: cancelCtx, Events: events, SnapshotManager: journal}
+		_, res := Update(&updateInfo{project: p.GetProject(), target: p.GetTarget(nil)}, ctx, p.Options, false)
+		contract.IgnoreClose(journal)
+		close(events)
+		return <-done, res == nil
+	}
+
+	resA := newGraphTestState("resA", "pkgA:m:typA", "", "")
+	errs, ok := run(&deploy.Snapshot{Resources: []*resource.State{resA}})
+	assert.True(t, ok)
+	assert.Empty(t, errs)
+
+	// Each problem with the stack's state is reported, and fails the update.
+	missing := resource.NewURN("test", "test", "", "pkgA:m:typA", "missing")
+	resB := newGraphTestState("resB", "pkgA:m:typA", missing, "", missing)
+	errs, ok = run(&deploy.Snapshot{Resources: []*resource.State{resA, resB}})
+	assert.False(t, ok)
+	if assert.Len(t, errs, 2) {
+		for _, msg := range errs {
+			assert.Contains(t, msg, "the stack's state failed verification")
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// upgradeInstructions tells users whose CLI is too old where to find a newer one.
+const upgradeInstructions = "visit https://pulumi.io/install for instructions on upgrading"
+
+// checkPulumiVersion checks the given version of this build of the engine against the minimum version that the project
+// requires, if any, and the version that the backend recommends, if any. It fails with upgrade instructions if the
+// build is older than the project's minimum, and warns if it is older than the backend's recommendation. Builds whose
+// versions are unknown, such as those made without setting version.Version, are not checked.
+func checkPulumiVersion(proj *workspace.Project, current, recommended string, d diag.Sink) error {
+	if current == "" {
+		return nil
+	}
+	cur, err := semver.ParseTolerant(current)
+	if err != nil {
+		logging.V(7).Infof("not checking the engine's version, which is not a valid version: %v", err)
+		return nil
+	}
+
+	if proj.MinimumPulumiVersion != "" {
+		min, err := semver.ParseTolerant(proj.MinimumPulumiVersion)
+		if err != nil {
+			return errors.Wrapf(err, "project '%s' has an invalid 'minimumPulumiVersion'", proj.Name)
+		}
+		if cur.LT(min) {
+			return errors.Errorf("project '%s' requires version %s or later of the Pulumi CLI, but this is version %s; %s",
+				proj.Name, min, cur, upgradeInstructions)
+		}
+	}
+
+	if recommended != "" && d != nil {
+		rec, err := semver.ParseTolerant(recommended)
+		if err != nil {
+			logging.V(7).Infof("ignoring the recommended version %q, which is not a valid version: %v", recommended, err)
+		} else if cur.LT(rec) {
+			d.Warningf(diag.RawMessage("", fmt.Sprintf(
+				"version %s of the Pulumi CLI or later is recommended, but this is version %s; %s",
+				rec, cur, upgradeInstructions)))
+		}
+	}
+	return nil
+}
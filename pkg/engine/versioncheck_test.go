@@ -0,0 +1,53 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+func TestCheckPulumiVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	sink := diag.DefaultSink(&stdout, &stderr, diag.FormatOptions{Color: colors.Never})
+	proj := &workspace.Project{Name: "proj", MinimumPulumiVersion: "0.17.18"}
+
+	// A build older than the project's minimum is rejected.
+	err := checkPulumiVersion(proj, "v0.17.17", "", sink)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "requires version 0.17.18 or later")
+		assert.Contains(t, err.Error(), "https://pulumi.io/install")
+	}
+
+	// Builds at or past the minimum, and builds whose versions are unknown, are not.
+	assert.NoError(t, checkPulumiVersion(proj, "v0.17.18", "", sink))
+	assert.NoError(t, checkPulumiVersion(proj, "v1.0.0", "", sink))
+	assert.NoError(t, checkPulumiVersion(proj, "", "", sink))
+	assert.Empty(t, stderr.String())
+
+	// A build older than the backend's recommendation is only warned.
+	assert.NoError(t, checkPulumiVersion(proj, "v0.17.18", "0.17.20", sink))
+	assert.Contains(t, stderr.String(), "version 0.17.20 of the Pulumi CLI or later is recommended")
+
+	stderr.Reset()
+	assert.NoError(t, checkPulumiVersion(proj, "v0.17.20", "0.17.20", sink))
+	assert.Empty(t, stderr.String())
+}
@@ -0,0 +1,243 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// WatchEvent notifies Watch that files belonging to the program or its configuration have changed.
+type WatchEvent struct {
+	Paths []string // the paths of the files that changed.
+}
+
+// WatchIterationFunc prepares a single iteration of Watch. It returns the update to perform, which must reflect the
+// stack's current configuration and latest snapshot, along with the snapshot manager that will persist the update's
+// results. Watch closes the snapshot manager once the iteration completes and before it prepares the next one.
+type WatchIterationFunc func() (UpdateInfo, SnapshotManager, error)
+
+// watchQuietPeriod is how long Watch waits for further changes after being notified of one before it starts an update,
+// so that a burst of changes (e.g. saving several files at once) results in a single update.
+var watchQuietPeriod = 250 * time.Millisecond
+
+// Watch updates the stack, and then updates it again each time a change is reported on ctx.Watch, until that channel
+// is closed or the operation is canceled. The plugins loaded by one iteration, including any provider processes and
+// their connections, are kept for the next, so that each iteration only pays for evaluating the program and applying
+// its changes. Plugins are reloaded whenever the stack's configuration changes, as providers are configured when they
+// are loaded. Language hosts that support hot reloading are asked to keep the program's interpreter alive, too, and to
+// re-evaluate the program within it on the next iteration after evicting the files that changed from their module
+// caches. An update that fails is reported and Watch carries on waiting for changes; Watch only returns an error if an
+// iteration cannot be prepared.
+func Watch(ctx *Context, opts UpdateOptions, prepare WatchIterationFunc) result.Result {
+	contract.Require(ctx != nil, "ctx")
+	contract.Require(ctx.Watch != nil, "ctx.Watch")
+	contract.Require(prepare != nil, "prepare")
+
+	ctx, endEvents := beginEvents(ctx)
+	defer endEvents()
+
+	if err := opts.Validate(); err != nil {
+		return result.FromError(err)
+	}
+
+	w := &watcher{ctx: ctx, opts: opts, prepare: prepare, provided: opts.host}
+	defer w.close()
+
+	var changed []string
+	for {
+		// Failed updates have already been reported, and are returned as bails. Any other error is fatal.
+		if res := w.iterate(changed); res != nil && !res.IsBail() {
+			return res
+		}
+		var ok bool
+		if changed, ok = w.waitForChanges(); !ok {
+			return nil
+		}
+	}
+}
+
+// watcher holds the state that Watch carries from one iteration to the next.
+type watcher struct {
+	ctx      *Context
+	opts     UpdateOptions
+	prepare  WatchIterationFunc
+	provided plugin.Host     // the host supplied by the caller, if any.
+	hostCtx  *plugin.Context // the context that owns the host that Watch loaded, if any.
+	config   config.Map      // the configuration with which the host in hostCtx was loaded.
+}
+
+// iterate performs a single update of the stack, after the files at the given paths changed. Failures of the update
+// itself are reported through the event stream.
+func (w *watcher) iterate(changed []string) result.Result {
+	u, manager, err := w.prepare()
+	if err != nil {
+		return result.FromError(err)
+	}
+
+	emitter, err := makeEventEmitter(w.ctx.Events, u)
+	if err != nil {
+		contract.IgnoreClose(manager)
+		return result.FromError(err)
+	}
+	diagSink := newEventSink(emitter, false, w.opts.diagnosticLimits(), w.opts.DiagnosticFilters)
+	statusSink := newEventSink(emitter, true, DiagnosticLimits{}, w.opts.DiagnosticFilters)
+
+	host, err := w.host(u, diagSink, statusSink)
+	if err != nil {
+		contract.IgnoreClose(manager)
+		diagSink.Errorf(diag.Message("" /*urn*/, "%v"), err)
+		return result.Bail()
+	}
+
+	info, err := newPlanContext(u, "watch", w.ctx.ParentSpan)
+	if err != nil {
+		contract.IgnoreClose(manager)
+		return result.FromError(err)
+	}
+	defer info.Close()
+
+	// Each iteration persists its results through its own snapshot manager.
+	iterCtx := *w.ctx
+	iterCtx.SnapshotManager = manager
+
+	opts := w.opts
+	opts.host = host
+	opts.hotReload, opts.changedPaths = true, changed
+	_, res := update(&iterCtx, info, planOptions{
+		UpdateOptions: opts,
+		SourceFunc:    newUpdateSource,
+		Events:        emitter,
+		Diag:          diagSink,
+		StatusDiag:    statusSink,
+	}, false)
+
+	if closeErr := manager.Close(); closeErr != nil {
+		res = result.Merge(res, result.FromError(closeErr))
+	}
+	if res != nil && res.Error() != nil {
+		diagSink.Errorf(diag.Message("" /*urn*/, "%v"), res.Error())
+		return result.Bail()
+	}
+	return res
+}
+
+// host returns the plugin host for an iteration that performs the given update, loading a new one if this is the first
+// iteration or the configuration has changed. If the options name a plugin pool, no host is returned, as each iteration
+// takes its host from the pool, which only relaunches the providers whose configuration has changed.
+func (w *watcher) host(u UpdateInfo, d, statusD diag.Sink) (plugin.Host, error) {
+	if w.provided != nil {
+		return sharedHost{w.provided}, nil
+	}
+	if w.opts.PluginPool != nil {
+		return nil, nil
+	}
+
+	target := u.GetTarget()
+	cfg := target.LayeredConfig()
+	if w.hostCtx != nil {
+		if reflect.DeepEqual(w.config, cfg) {
+			return sharedHost{w.hostCtx.Host}, nil
+		}
+		logging.V(7).Infof("Watch: configuration changed; reloading plugins")
+		contract.IgnoreClose(w.hostCtx)
+		w.hostCtx = nil
+	}
+
+	proj := u.GetProject()
+	_, _, hostCtx, err := ProjectInfoContext(&Projinfo{Proj: proj, Root: u.GetRoot()}, nil, target, d, statusD, nil)
+	if err != nil {
+		return nil, err
+	}
+	env, err := languageEnv(proj)
+	if err != nil {
+		contract.IgnoreClose(hostCtx)
+		return nil, err
+	}
+	hostCtx.LanguageEnv = env
+
+	w.hostCtx, w.config = hostCtx, make(config.Map, len(cfg))
+	for k, v := range cfg {
+		w.config[k] = v
+	}
+	return sharedHost{hostCtx.Host}, nil
+}
+
+// waitForChanges blocks until a change is reported and the changes have settled, returning the paths that changed, or
+// false if the watch is over.
+func (w *watcher) waitForChanges() ([]string, bool) {
+	seen := make(map[string]bool)
+	var changed []string
+	record := func(event WatchEvent) {
+		for _, path := range event.Paths {
+			if !seen[path] {
+				seen[path] = true
+				changed = append(changed, path)
+			}
+		}
+	}
+
+	select {
+	case event, ok := <-w.ctx.Watch:
+		if !ok {
+			return nil, false
+		}
+		record(event)
+	case <-w.ctx.Cancel.Canceled():
+		return nil, false
+	}
+
+	for {
+		select {
+		case event, ok := <-w.ctx.Watch:
+			if !ok {
+				return nil, false
+			}
+			logging.V(7).Infof("Watch: changed: %v", event.Paths)
+			record(event)
+		case <-w.ctx.Cancel.Canceled():
+			return nil, false
+		case <-time.After(watchQuietPeriod):
+			return changed, true
+		}
+	}
+}
+
+// close shuts down the plugins loaded by the watch.
+func (w *watcher) close() {
+	if w.hostCtx != nil {
+		contract.IgnoreClose(w.hostCtx)
+	}
+	if w.provided != nil {
+		contract.IgnoreClose(w.provided)
+	}
+}
+
+// sharedHost keeps a plugin host open across the updates that share it, as by the iterations of a watch or the stacks
+// of an UpdateMany, each of which would otherwise close it when it finishes.
+type sharedHost struct {
+	plugin.Host
+}
+
+func (h sharedHost) Close() error {
+	return nil
+}
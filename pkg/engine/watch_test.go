@@ -0,0 +1,160 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/cancel"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// closeCountingHost counts the number of times the host it wraps is closed.
+type closeCountingHost struct {
+	plugin.Host
+	closes int
+}
+
+func (h *closeCountingHost) Close() error {
+	h.closes++
+	return h.Host.Close()
+}
+
+// watchJournal signals each time one of the watch's snapshot managers is closed.
+type watchJournal struct {
+	*Journal
+	closed chan<- bool
+}
+
+func (j watchJournal) Close() error {
+	err := j.Journal.Close()
+	j.closed <- true
+	return err
+}
+
+func TestWatch(t *testing.T) {
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{}, nil
+		}),
+	}
+
+	// Each iteration registers a resource with the next of these values. An empty value makes the program fail.
+	values := []string{"a", "", "b"}
+	var value string
+	var runs []plugin.RunInfo
+	program := deploytest.NewLanguageRuntime(func(info plugin.RunInfo, monitor *deploytest.ResourceMonitor) error {
+		runs = append(runs, info)
+		if value == "" {
+			return errors.New("program failed")
+		}
+		_, _, _, err := monitor.RegisterResource("pkgA:m:typA", "resA", true, "", false, nil, "",
+			resource.PropertyMap{"value": resource.NewStringProperty(value)}, nil, false, "", nil, nil)
+		assert.NoError(t, err)
+		return nil
+	})
+	host := &closeCountingHost{Host: deploytest.NewPluginHost(nil, nil, program, loaders...)}
+
+	p := &TestPlan{}
+	project := p.GetProject()
+
+	var snap *deploy.Snapshot
+	var journal *Journal
+	var target deploy.Target
+	closed := make(chan bool, len(values))
+	prepare := func() (UpdateInfo, SnapshotManager, error) {
+		if journal != nil {
+			snap = journal.Snap(target.Snapshot)
+		}
+		value, values = values[0], values[1:]
+		journal, target = newJournal(), p.GetTarget(snap)
+		return &updateInfo{project: project, target: target}, watchJournal{Journal: journal, closed: closed}, nil
+	}
+
+	events, changes := make(chan Event), make(chan WatchEvent)
+	var firedEvents []Event
+	eventsDone := make(chan bool)
+	go func() {
+		for e := range events {
+			firedEvents = append(firedEvents, e)
+		}
+		close(eventsDone)
+	}()
+
+	cancelCtx, _ := cancel.NewContext(context.Background())
+	ctx := &Context{Cancel: cancelCtx, Events: events, Watch: changes}
+
+	oldQuietPeriod := watchQuietPeriod
+	watchQuietPeriod = 0
+	defer func() { watchQuietPeriod = oldQuietPeriod }()
+
+	done := make(chan bool)
+	var res result.Result
+	go func() {
+		res = Watch(ctx, UpdateOptions{host: host}, prepare)
+		close(done)
+	}()
+
+	// The first update runs immediately. Each change after that triggers another, and a failed update does not end
+	// the watch.
+	<-closed
+	changes <- WatchEvent{Paths: []string{"index.ts"}}
+	<-closed
+	changes <- WatchEvent{Paths: []string{"index.ts", "util.ts"}}
+	<-closed
+	close(changes)
+	<-done
+	close(events)
+	<-eventsDone
+
+	assert.Nil(t, res)
+
+	// The host is shared by all of the iterations and closed once the watch ends.
+	assert.Equal(t, 1, host.closes)
+
+	// Each run of the program may be hot reloaded, and is told which files changed since the last.
+	if assert.Len(t, runs, 3) {
+		for _, run := range runs {
+			assert.True(t, run.HotReload)
+		}
+		assert.Empty(t, runs[0].ChangedPaths)
+		assert.Equal(t, []string{"index.ts"}, runs[1].ChangedPaths)
+		assert.Equal(t, []string{"index.ts", "util.ts"}, runs[2].ChangedPaths)
+	}
+
+	snap = journal.Snap(target.Snapshot)
+	assert.NoError(t, snap.VerifyIntegrity())
+	assert.Len(t, snap.Resources, 2)
+	assert.Equal(t, resource.NewStringProperty("b"), snap.Resources[1].Inputs["value"])
+
+	// The failed update is reported as an error diagnostic.
+	var errorMessages int
+	for _, e := range firedEvents {
+		if e.Type == DiagEvent && e.Payload.(DiagEventPayload).Severity == diag.Error {
+			errorMessages++
+		}
+	}
+	assert.NotZero(t, errorMessages)
+}
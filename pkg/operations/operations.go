@@ -30,6 +30,7 @@ type LogEntry struct {
 // - Full URN: "<namespace>::<alloc>::<type>::<name>"
 // - Type + Name: "<type>::<name>"
 // - Name: "<name>"
+// Each may contain wildcards; see resource.URNPattern for the details of how filters are matched.
 type ResourceFilter string
 
 // LogQuery represents the parameters to a log query operation. All fields are
@@ -229,20 +229,11 @@ func (ops *resourceOperations) matchesResourceFilter(filter *ResourceFilter) boo
 	if ops.resource == nil || ops.resource.State == nil {
 		return false
 	}
-	urn := ops.resource.State.URN
-	if resource.URN(*filter) == urn {
-		// The filter matched the full URN
-		return true
-	}
-	if string(*filter) == string(urn.Type())+"::"+string(urn.Name()) {
-		// The filter matched the '<type>::<name>' part of the URN
-		return true
-	}
-	if tokens.QName(*filter) == urn.Name() {
-		// The filter matched the '<name>' part of the URN
-		return true
+	pattern, err := resource.ParseURNPattern(string(*filter))
+	if err != nil {
+		return false
 	}
-	return false
+	return pattern.Matches(ops.resource.State.URN)
 }
 
 func (ops *resourceOperations) getOperationsProvider() (Provider, error) {
@@ -24,7 +24,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -37,10 +36,36 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/util/contract"
-	"github.com/pulumi/pulumi/pkg/util/httputil"
 	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
+// AssetOptions controls how the contents of assets and archives are read on behalf of an operation. The zero value
+// reads them with the defaults.
+type AssetOptions struct {
+	// WorkingDirectory is the directory against which relative paths are resolved, e.g. the project's working
+	// directory. If it is empty, they are resolved against the process's working directory, and are never cached,
+	// as that directory may be shared by operations on different projects.
+	WorkingDirectory string
+	// Cache, if set, caches the hashes of assets and archives and the translated contents of archives. See
+	// AssetCache.
+	Cache *AssetCache
+	// TransferChunkSize is the number of bytes requested at a time when fetching remote contents (<=0 for
+	// DefaultAssetTransferChunkSize). Servers that support range requests are asked for one chunk at a time, so that
+	// a transfer interrupted by a network error resumes from the end of the last chunk received rather than starting
+	// over.
+	TransferChunkSize int64
+	// TransferObserver, if set, is called with the progress of each transfer of remote contents.
+	TransferObserver AssetTransferObserver
+}
+
+// resolvePath resolves the given path against the working directory, if it is relative and there is one.
+func (opts AssetOptions) resolvePath(path string) string {
+	if opts.WorkingDirectory == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(opts.WorkingDirectory, path)
+}
+
 // Asset is a serialized asset reference.  It is a union: thus, only one of its fields will be non-nil.  Several helper
 // routines exist as members in order to easily interact with the assets referenced by an instance of this type.
 type Asset struct {
@@ -287,12 +312,17 @@ func (a *Asset) Bytes() ([]byte, error) {
 
 // Read begins reading an asset.
 func (a *Asset) Read() (*Blob, error) {
+	return a.ReadWithOptions(AssetOptions{})
+}
+
+// ReadWithOptions begins reading an asset, fetching any remote contents according to the given options.
+func (a *Asset) ReadWithOptions(opts AssetOptions) (*Blob, error) {
 	if a.IsText() {
 		return a.readText()
 	} else if a.IsPath() {
-		return a.readPath()
+		return a.readPath(opts)
 	} else if a.IsURI() {
-		return a.readURI()
+		return a.readURI(opts)
 	}
 	return nil, errors.New("unrecognized asset type")
 }
@@ -303,9 +333,10 @@ func (a *Asset) readText() (*Blob, error) {
 	return NewByteBlob([]byte(text)), nil
 }
 
-func (a *Asset) readPath() (*Blob, error) {
+func (a *Asset) readPath(opts AssetOptions) (*Blob, error) {
 	path, ispath := a.GetPath()
 	contract.Assertf(ispath, "Expected a path-based asset")
+	path = opts.resolvePath(path)
 
 	file, err := os.Open(path)
 	if err != nil {
@@ -330,7 +361,7 @@ func (a *Asset) readPath() (*Blob, error) {
 	return blob, nil
 }
 
-func (a *Asset) readURI() (*Blob, error) {
+func (a *Asset) readURI(opts AssetOptions) (*Blob, error) {
 	url, isurl, err := a.GetURIURL()
 	if err != nil {
 		return nil, err
@@ -338,11 +369,11 @@ func (a *Asset) readURI() (*Blob, error) {
 	contract.Assertf(isurl, "Expected a URI-based asset")
 	switch s := url.Scheme; s {
 	case "http", "https":
-		resp, err := httputil.GetWithRetry(url.String(), http.DefaultClient)
+		rd, sz, err := fetchRemoteContents(url.String(), a.Hash, opts)
 		if err != nil {
 			return nil, err
 		}
-		return NewReadCloserBlob(resp.Body)
+		return &Blob{rd: rd, sz: sz}, nil
 	case "file":
 		contract.Assert(url.User == nil)
 		contract.Assert(url.RawQuery == "")
@@ -362,23 +393,39 @@ func (a *Asset) readURI() (*Blob, error) {
 
 // EnsureHash computes the SHA256 hash of the asset's contents and stores it on the object.
 func (a *Asset) EnsureHash() error {
-	if a.Hash == "" {
-		blob, err := a.Read()
-		if err != nil {
-			return err
-		}
-		defer contract.IgnoreClose(blob)
+	return a.EnsureHashWithOptions(AssetOptions{})
+}
 
-		hash := sha256.New()
-		_, err = io.Copy(hash, blob)
+// EnsureHashWithOptions computes the SHA256 hash of the asset's contents, reading them according to the given
+// options, and stores it on the object.
+func (a *Asset) EnsureHashWithOptions(opts AssetOptions) error {
+	if a.Hash == "" {
+		compute := func() (string, error) { return a.computeHash(opts) }
+		hash, err := cachedHash(opts, func() (string, bool) { return assetFingerprint(a, opts) }, compute)
 		if err != nil {
 			return err
 		}
-		a.Hash = hex.EncodeToString(hash.Sum(nil))
+		a.Hash = hash
 	}
 	return nil
 }
 
+// computeHash reads the asset's contents and returns their SHA256 hash.
+func (a *Asset) computeHash(opts AssetOptions) (string, error) {
+	blob, err := a.ReadWithOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	defer contract.IgnoreClose(blob)
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, blob)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // Blob is a blob that implements ReadCloser and offers Len functionality.
 type Blob struct {
 	rd io.ReadCloser // an underlying reader.
@@ -637,13 +684,19 @@ type ArchiveReader interface {
 
 // Open returns an ArchiveReader that can be used to iterate over the named blobs that comprise the archive.
 func (a *Archive) Open() (ArchiveReader, error) {
+	return a.OpenWithOptions(AssetOptions{})
+}
+
+// OpenWithOptions returns an ArchiveReader that can be used to iterate over the named blobs that comprise the archive,
+// fetching any remote contents according to the given options.
+func (a *Archive) OpenWithOptions(opts AssetOptions) (ArchiveReader, error) {
 	contract.Assertf(a.HasContents(), "cannot read an archive that has no contents")
 	if a.IsAssets() {
-		return a.readAssets()
+		return a.readAssets(opts)
 	} else if a.IsPath() {
-		return a.readPath()
+		return a.readPath(opts)
 	} else if a.IsURI() {
-		return a.readURI()
+		return a.readURI(opts)
 	}
 	return nil, errors.New("unrecognized archive type")
 }
@@ -654,6 +707,7 @@ type assetsArchiveReader struct {
 	keys        []string
 	archive     ArchiveReader
 	archiveRoot string
+	opts        AssetOptions
 }
 
 func (r *assetsArchiveReader) Next() (string, *Blob, error) {
@@ -688,14 +742,14 @@ func (r *assetsArchiveReader) Next() (string, *Blob, error) {
 		switch t := asset.(type) {
 		case *Asset:
 			// An asset can be produced directly.
-			blob, err := t.Read()
+			blob, err := t.ReadWithOptions(r.opts)
 			if err != nil {
 				return "", nil, errors.Wrapf(err, "failed to expand archive asset '%v'", name)
 			}
 			return name, blob, nil
 		case *Archive:
 			// An archive must be flattened into its constituent blobs. Open the archive for reading and loop.
-			archive, err := t.Open()
+			archive, err := t.OpenWithOptions(r.opts)
 			if err != nil {
 				return "", nil, errors.Wrapf(err, "failed to expand sub-archive '%v'", name)
 			}
@@ -712,7 +766,7 @@ func (r *assetsArchiveReader) Close() error {
 	return nil
 }
 
-func (a *Archive) readAssets() (ArchiveReader, error) {
+func (a *Archive) readAssets(opts AssetOptions) (ArchiveReader, error) {
 	// To read a map-based archive, just produce a map from each asset to its associated reader.
 	m, isassets := a.GetAssets()
 	contract.Assertf(isassets, "Expected an asset map-based archive")
@@ -727,6 +781,7 @@ func (a *Archive) readAssets() (ArchiveReader, error) {
 	r := &assetsArchiveReader{
 		assets: m,
 		keys:   keys,
+		opts:   opts,
 	}
 	return r, nil
 }
@@ -766,10 +821,11 @@ func (r *directoryArchiveReader) Close() error {
 	return nil
 }
 
-func (a *Archive) readPath() (ArchiveReader, error) {
+func (a *Archive) readPath(opts AssetOptions) (ArchiveReader, error) {
 	// To read a path-based archive, read that file and use its extension to ascertain what format to use.
 	path, ispath := a.GetPath()
 	contract.Assertf(ispath, "Expected a path-based asset")
+	path = opts.resolvePath(path)
 
 	format := detectArchiveFormat(path)
 
@@ -840,7 +896,7 @@ func (a *Archive) readPath() (ArchiveReader, error) {
 	return readArchive(file, format)
 }
 
-func (a *Archive) readURI() (ArchiveReader, error) {
+func (a *Archive) readURI(opts AssetOptions) (ArchiveReader, error) {
 	// To read a URI-based archive, fetch the contents remotely and use the extension to pick the format to use.
 	url, isurl, err := a.GetURIURL()
 	if err != nil {
@@ -854,21 +910,18 @@ func (a *Archive) readURI() (ArchiveReader, error) {
 		return nil, errors.Errorf("file at URL '%v' is not a recognized archive format", url)
 	}
 
-	ar, err := a.openURLStream(url)
+	ar, err := a.openURLStream(url, opts)
 	if err != nil {
 		return nil, err
 	}
 	return readArchive(ar, format)
 }
 
-func (a *Archive) openURLStream(url *url.URL) (io.ReadCloser, error) {
+func (a *Archive) openURLStream(url *url.URL, opts AssetOptions) (io.ReadCloser, error) {
 	switch s := url.Scheme; s {
 	case "http", "https":
-		resp, err := httputil.GetWithRetry(url.String(), http.DefaultClient)
-		if err != nil {
-			return nil, err
-		}
-		return resp.Body, nil
+		rd, _, err := fetchRemoteContents(url.String(), a.Hash, opts)
+		return rd, err
 	case "file":
 		contract.Assert(url.Host == "")
 		contract.Assert(url.User == nil)
@@ -885,7 +938,7 @@ func (a *Archive) openURLStream(url *url.URL) (io.ReadCloser, error) {
 // APIs that demand []bytes.
 func (a *Archive) Bytes(format ArchiveFormat) ([]byte, error) {
 	var data bytes.Buffer
-	if err := a.Archive(format, &data); err != nil {
+	if err := a.ArchiveWithOptions(format, &data, AssetOptions{}); err != nil {
 		return nil, err
 	}
 	return data.Bytes(), nil
@@ -894,8 +947,14 @@ func (a *Archive) Bytes(format ArchiveFormat) ([]byte, error) {
 // Archive produces a single archive stream in the desired format.  It prefers to return the archive with as little
 // copying as is feasible, however if the desired format is different from the source, it will need to translate.
 func (a *Archive) Archive(format ArchiveFormat, w io.Writer) error {
+	return a.ArchiveWithOptions(format, w, AssetOptions{})
+}
+
+// ArchiveWithOptions produces a single archive stream in the desired format, like Archive, reading its contents and
+// caching any translation according to the given options.
+func (a *Archive) ArchiveWithOptions(format ArchiveFormat, w io.Writer, opts AssetOptions) error {
 	// If the source format is the same, just return that.
-	if sf, ss, err := a.ReadSourceArchive(); sf != NotArchive && sf == format {
+	if sf, ss, err := a.readSourceArchive(opts); sf != NotArchive && sf == format {
 		if err != nil {
 			return err
 		}
@@ -903,13 +962,30 @@ func (a *Archive) Archive(format ArchiveFormat, w io.Writer) error {
 		return err
 	}
 
+	// Otherwise, the archive must be translated. If it is unchanged since it was last translated into this format,
+	// serve the translation from the cache instead. Note that the archive's hash is always recomputed from its
+	// fingerprint rather than trusting a.Hash, which may describe the contents as they were in a prior update.
+	if cache := opts.Cache; cache != nil {
+		if fp, ok := archiveFingerprint(a, opts); ok {
+			hash, err := cache.hash(fp, func() (string, error) { return a.computeHash(opts) })
+			if err != nil {
+				return err
+			}
+			return cache.archive(hash, format, w, func(w io.Writer) error { return a.archive(format, w, opts) })
+		}
+	}
+	return a.archive(format, w, opts)
+}
+
+// archive translates the archive into the desired format.
+func (a *Archive) archive(format ArchiveFormat, w io.Writer, opts AssetOptions) error {
 	switch format {
 	case TarArchive:
-		return a.archiveTar(w)
+		return a.archiveTar(w, opts)
 	case TarGZIPArchive:
-		return a.archiveTarGZIP(w)
+		return a.archiveTarGZIP(w, opts)
 	case ZIPArchive:
-		return a.archiveZIP(w)
+		return a.archiveZIP(w, opts)
 	default:
 		contract.Failf("Illegal archive type: %v", format)
 		return nil
@@ -948,9 +1024,9 @@ func addNextFileToTar(r ArchiveReader, tw *tar.Writer, seenFiles map[string]bool
 	return err
 }
 
-func (a *Archive) archiveTar(w io.Writer) error {
+func (a *Archive) archiveTar(w io.Writer, opts AssetOptions) error {
 	// Open the archive.
-	reader, err := a.Open()
+	reader, err := a.OpenWithOptions(opts)
 	if err != nil {
 		return err
 	}
@@ -969,9 +1045,9 @@ func (a *Archive) archiveTar(w io.Writer) error {
 	return tw.Close()
 }
 
-func (a *Archive) archiveTarGZIP(w io.Writer) error {
+func (a *Archive) archiveTarGZIP(w io.Writer, opts AssetOptions) error {
 	z := gzip.NewWriter(w)
-	return a.archiveTar(z)
+	return a.archiveTar(z, opts)
 }
 
 // addNextFileToZIP adds the next file in the given archive to the given ZIP file. Returns io.EOF if the archive
@@ -1012,9 +1088,9 @@ func addNextFileToZIP(r ArchiveReader, zw *zip.Writer, seenFiles map[string]bool
 	return err
 }
 
-func (a *Archive) archiveZIP(w io.Writer) error {
+func (a *Archive) archiveZIP(w io.Writer, opts AssetOptions) error {
 	// Open the archive.
-	reader, err := a.Open()
+	reader, err := a.OpenWithOptions(opts)
 	if err != nil {
 		return err
 	}
@@ -1035,14 +1111,20 @@ func (a *Archive) archiveZIP(w io.Writer) error {
 
 // ReadSourceArchive returns a stream to the underlying archive, if there is one.
 func (a *Archive) ReadSourceArchive() (ArchiveFormat, io.ReadCloser, error) {
+	return a.readSourceArchive(AssetOptions{})
+}
+
+// readSourceArchive returns a stream to the underlying archive, if there is one, fetching any remote contents
+// according to the given options.
+func (a *Archive) readSourceArchive(opts AssetOptions) (ArchiveFormat, io.ReadCloser, error) {
 	if path, ispath := a.GetPath(); ispath {
 		if format := detectArchiveFormat(path); format != NotArchive {
-			f, err := os.Open(path)
+			f, err := os.Open(opts.resolvePath(path))
 			return format, f, err
 		}
 	} else if url, isurl, urlerr := a.GetURIURL(); urlerr == nil && isurl {
 		if format := detectArchiveFormat(url.Path); format != NotArchive {
-			s, err := a.openURLStream(url)
+			s, err := a.openURLStream(url, opts)
 			return format, s, err
 		}
 	}
@@ -1051,36 +1133,53 @@ func (a *Archive) ReadSourceArchive() (ArchiveFormat, io.ReadCloser, error) {
 
 // EnsureHash computes the SHA256 hash of the archive's contents and stores it on the object.
 func (a *Archive) EnsureHash() error {
-	if a.Hash == "" {
-		hash := sha256.New()
+	return a.EnsureHashWithOptions(AssetOptions{})
+}
 
-		// Attempt to compute the hash in the most efficient way.  First try to open the archive directly and copy it
-		// to the hash.  This avoids traversing any of the contents and just treats it as a byte stream.
-		f, r, err := a.ReadSourceArchive()
+// EnsureHashWithOptions computes the SHA256 hash of the archive's contents, reading them according to the given
+// options, and stores it on the object.
+func (a *Archive) EnsureHashWithOptions(opts AssetOptions) error {
+	if a.Hash == "" {
+		compute := func() (string, error) { return a.computeHash(opts) }
+		hash, err := cachedHash(opts, func() (string, bool) { return archiveFingerprint(a, opts) }, compute)
 		if err != nil {
 			return err
 		}
-		if f != NotArchive && r != nil {
-			defer contract.IgnoreClose(r)
-			_, err = io.Copy(hash, r)
-			if err != nil {
-				return err
-			}
-		} else {
-			// Otherwise, it's not an archive; we'll need to transform it into one.  Pick tar since it avoids
-			// any superfluous compression which doesn't actually help us in this situation.
-			err := a.Archive(TarArchive, hash)
-			if err != nil {
-				return err
-			}
-		}
-
-		// Finally, encode the resulting hash as a string and we're done.
-		a.Hash = hex.EncodeToString(hash.Sum(nil))
+		a.Hash = hash
 	}
 	return nil
 }
 
+// computeHash reads the archive's contents and returns their SHA256 hash.
+func (a *Archive) computeHash(opts AssetOptions) (string, error) {
+	hash := sha256.New()
+
+	// Attempt to compute the hash in the most efficient way.  First try to open the archive directly and copy it
+	// to the hash.  This avoids traversing any of the contents and just treats it as a byte stream.
+	f, r, err := a.readSourceArchive(opts)
+	if err != nil {
+		return "", err
+	}
+	if f != NotArchive && r != nil {
+		defer contract.IgnoreClose(r)
+		_, err = io.Copy(hash, r)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// Otherwise, it's not an archive; we'll need to transform it into one.  Pick tar since it avoids
+		// any superfluous compression which doesn't actually help us in this situation.  Note that this
+		// bypasses the cache, which is itself keyed by the hash being computed.
+		err := a.archive(TarArchive, hash, opts)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Finally, encode the resulting hash as a string and we're done.
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // ArchiveFormat indicates what archive and/or compression format an archive uses.
 type ArchiveFormat int
 
@@ -1119,11 +1218,15 @@ func readArchive(ar io.ReadCloser, format ArchiveFormat) (ArchiveReader, error)
 	case TarGZIPArchive:
 		return readTarGZIPArchive(ar)
 	case ZIPArchive:
-		// Unfortunately, the ZIP archive reader requires ReaderAt functionality.  If it's a file, we can recover this
-		// with a simple stat.  Otherwise, we will need to go ahead and make a copy in memory.
+		// Unfortunately, the ZIP archive reader requires ReaderAt functionality.  If it's a file (including the
+		// temporary file holding a remote archive), we can recover this with a simple stat.  Otherwise, we will need
+		// to go ahead and make a copy in memory.
 		var ra io.ReaderAt
 		var sz int64
-		if f, isf := ar.(*os.File); isf {
+		if f, isf := ar.(interface {
+			io.ReaderAt
+			Stat() (os.FileInfo, error)
+		}); isf {
 			stat, err := f.Stat()
 			if err != nil {
 				return nil, err
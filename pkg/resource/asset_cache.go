@@ -0,0 +1,347 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// DefaultAssetCacheMaxSize is the total size of the archive contents that an asset cache retains unless it is created
+// with a size of its own.
+const DefaultAssetCacheMaxSize = 1024 * 1024 * 1024
+
+// assetFingerprintVersion is mixed into every fingerprint, so that changes to the way that hashes or archive contents
+// are computed invalidate the entries cached before them.
+const assetFingerprintVersion = "v1"
+
+// AssetCache caches the hashes of assets and archives, and the contents of archives translated into other formats,
+// across updates. Hashes are keyed by a fingerprint of the files that an asset or archive reads (their paths, sizes,
+// modification times, and modes), so that an unchanged asset need not be read to learn its hash; translated archive
+// contents are keyed by the archive's hash, so that an unchanged archive need not be read and translated again. Only
+// assets and archives that are read from the local filesystem or from text are cached.
+//
+// A cache is used by the operations whose AssetOptions include it. An asset cache may be shared by any number of
+// operations and processes.
+type AssetCache struct {
+	dir     string          // the directory in which the cache is stored.
+	maxSize int64           // the total size of the archive contents that the cache retains.
+	stats   AssetCacheStats // the cache's statistics, which are updated atomically.
+}
+
+// AssetCacheStats counts the lookups made in an asset cache.
+type AssetCacheStats struct {
+	HashHits      int64 // the number of hashes found in the cache.
+	HashMisses    int64 // the number of hashes that had to be computed.
+	ContentHits   int64 // the number of archives whose translated contents were found in the cache.
+	ContentMisses int64 // the number of archives that had to be translated.
+	BytesServed   int64 // the number of bytes of archive contents served from the cache.
+	Evictions     int64 // the number of translated archives evicted from the cache.
+}
+
+// NewAssetCache creates an asset cache that is stored in the given directory. Once the archive contents that it retains
+// exceed the given size (<=0 for DefaultAssetCacheMaxSize), the contents that were least recently used are evicted.
+func NewAssetCache(dir string, maxSize int64) *AssetCache {
+	contract.Require(dir != "", "dir")
+	if maxSize <= 0 {
+		maxSize = DefaultAssetCacheMaxSize
+	}
+	return &AssetCache{dir: dir, maxSize: maxSize}
+}
+
+// Stats returns the statistics for the lookups made in the cache by this process.
+func (c *AssetCache) Stats() AssetCacheStats {
+	return AssetCacheStats{
+		HashHits:      atomic.LoadInt64(&c.stats.HashHits),
+		HashMisses:    atomic.LoadInt64(&c.stats.HashMisses),
+		ContentHits:   atomic.LoadInt64(&c.stats.ContentHits),
+		ContentMisses: atomic.LoadInt64(&c.stats.ContentMisses),
+		BytesServed:   atomic.LoadInt64(&c.stats.BytesServed),
+		Evictions:     atomic.LoadInt64(&c.stats.Evictions),
+	}
+}
+
+// Write writes a table of the statistics to the given writer.
+func (s AssetCacheStats) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tHITS\tMISSES")
+	fmt.Fprintf(tw, "hash\t%d\t%d\n", s.HashHits, s.HashMisses)
+	fmt.Fprintf(tw, "archive contents\t%d\t%d\n", s.ContentHits, s.ContentMisses)
+	fmt.Fprintf(tw, "\nbytes served from cache\t%d\n", s.BytesServed)
+	fmt.Fprintf(tw, "archives evicted\t%d\n", s.Evictions)
+	return tw.Flush()
+}
+
+// hash returns the hash cached under the given fingerprint, calling compute to compute and cache it if there is none.
+func (c *AssetCache) hash(fingerprint string, compute func() (string, error)) (string, error) {
+	path := filepath.Join(c.dir, "hashes", fingerprint)
+	if b, err := ioutil.ReadFile(path); err == nil && len(b) > 0 {
+		atomic.AddInt64(&c.stats.HashHits, 1)
+		return string(b), nil
+	}
+
+	atomic.AddInt64(&c.stats.HashMisses, 1)
+	hash, err := compute()
+	if err != nil {
+		return "", err
+	}
+	if err = c.writeFile(path, []byte(hash)); err != nil {
+		logging.V(5).Infof("failed to cache asset hash: %v", err)
+	}
+	return hash, nil
+}
+
+// archive writes the contents of the archive with the given hash, translated into the given format, to the given
+// writer. If the translated contents are not in the cache, translate is called to produce them.
+func (c *AssetCache) archive(hash string, format ArchiveFormat, w io.Writer, translate func(io.Writer) error) error {
+	path := filepath.Join(c.dir, "contents", fmt.Sprintf("%s.%d", hash, format))
+	if f, err := os.Open(path); err == nil {
+		defer contract.IgnoreClose(f)
+		atomic.AddInt64(&c.stats.ContentHits, 1)
+
+		// Note that the contents were used, so that they are evicted after contents that were not.
+		now := time.Now()
+		contract.IgnoreError(os.Chtimes(path, now, now))
+
+		n, err := io.Copy(w, f)
+		atomic.AddInt64(&c.stats.BytesServed, n)
+		return err
+	}
+
+	atomic.AddInt64(&c.stats.ContentMisses, 1)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		logging.V(5).Infof("failed to create asset cache directory: %v", err)
+		return translate(w)
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		logging.V(5).Infof("failed to create asset cache entry: %v", err)
+		return translate(w)
+	}
+
+	err = translate(io.MultiWriter(w, temp))
+	if closeErr := temp.Close(); err == nil && closeErr == nil {
+		if err = os.Rename(temp.Name(), path); err == nil {
+			c.prune()
+			return nil
+		}
+		logging.V(5).Infof("failed to cache archive contents: %v", err)
+		err = nil
+	}
+	contract.IgnoreError(os.Remove(temp.Name()))
+	return err
+}
+
+// writeFile atomically writes the given data to the given path within the cache.
+func (c *AssetCache) writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		contract.IgnoreError(os.Remove(temp.Name()))
+	}
+	return err
+}
+
+// prune evicts the least recently used archive contents until their total size is within the cache's maximum size.
+func (c *AssetCache) prune() {
+	infos, err := ioutil.ReadDir(filepath.Join(c.dir, "contents"))
+	if err != nil {
+		logging.V(5).Infof("failed to read asset cache: %v", err)
+		return
+	}
+
+	var total int64
+	entries := infos[:0]
+	for _, info := range infos {
+		if !info.IsDir() && !strings.HasPrefix(info.Name(), ".tmp-") {
+			total += info.Size()
+			entries = append(entries, info)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime().Before(entries[j].ModTime()) })
+	for _, info := range entries {
+		if total <= c.maxSize {
+			return
+		}
+		if err := os.Remove(filepath.Join(c.dir, "contents", info.Name())); err != nil {
+			logging.V(5).Infof("failed to evict %s from asset cache: %v", info.Name(), err)
+			continue
+		}
+		total -= info.Size()
+		atomic.AddInt64(&c.stats.Evictions, 1)
+	}
+}
+
+// cachedHash returns the hash of an asset or archive, looking it up in the options' cache under its fingerprint if
+// there is a cache and the asset or archive can be fingerprinted, or calling compute otherwise.
+func cachedHash(opts AssetOptions, fingerprint func() (string, bool), compute func() (string, error)) (string, error) {
+	if cache := opts.Cache; cache != nil {
+		if fp, ok := fingerprint(); ok {
+			return cache.hash(fp, compute)
+		}
+	}
+	return compute()
+}
+
+// assetFingerprint returns a fingerprint of the contents of the given asset, and true, if the asset's contents can be
+// fingerprinted without reading them. Relative paths are resolved against the options' working directory.
+func assetFingerprint(a *Asset, opts AssetOptions) (string, bool) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00asset\n", assetFingerprintVersion)
+	if !fingerprintAsset(h, a, opts) {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// archiveFingerprint returns a fingerprint of the contents of the given archive, and true, if the archive's contents
+// can be fingerprinted without reading them. Relative paths are resolved against the options' working directory.
+func archiveFingerprint(a *Archive, opts AssetOptions) (string, bool) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00archive\n", assetFingerprintVersion)
+	if !fingerprintArchive(h, a, opts) {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func fingerprintAsset(h hash.Hash, a *Asset, opts AssetOptions) bool {
+	if text, istext := a.GetText(); istext {
+		sum := sha256.Sum256([]byte(text))
+		fmt.Fprintf(h, "text\x00%x\n", sum)
+		return true
+	}
+	if path, ispath := a.GetPath(); ispath {
+		return fingerprintFile(h, path, opts)
+	}
+	return false
+}
+
+func fingerprintArchive(h hash.Hash, a *Archive, opts AssetOptions) bool {
+	if assets, isassets := a.GetAssets(); isassets {
+		names := make([]string, 0, len(assets))
+		for name := range assets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(h, "member\x00%s\n", name)
+			switch member := assets[name].(type) {
+			case *Asset:
+				if !fingerprintAsset(h, member, opts) {
+					return false
+				}
+			case *Archive:
+				if !fingerprintArchive(h, member, opts) {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+		return true
+	}
+
+	path, ispath := a.GetPath()
+	if !ispath {
+		return false
+	}
+	if path = opts.resolvePath(path); !filepath.IsAbs(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return fingerprintFile(h, path, opts)
+	}
+
+	// Walk the directory just as readPath does, but stat its files rather than reading them.
+	err = filepath.Walk(path, func(filePath string, f os.FileInfo, fileerr error) error {
+		if fileerr != nil {
+			return fileerr
+		}
+		if f.Name() == workspace.BookkeepingDir {
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if f.IsDir() {
+			return nil
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			target, statErr := os.Stat(filePath)
+			if statErr != nil {
+				return statErr
+			}
+			if target.IsDir() {
+				return nil
+			}
+			f = target
+		}
+		rel, relErr := filepath.Rel(path, filePath)
+		if relErr != nil {
+			return relErr
+		}
+		fmt.Fprintf(h, "file\x00%s\x00%d\x00%d\x00%v\n", filepath.ToSlash(rel), f.Size(), f.ModTime().UnixNano(), f.Mode())
+		return nil
+	})
+	return err == nil
+}
+
+// fingerprintFile writes a fingerprint of the file at the given path to the given hash, and returns true, if the path
+// is absolute or can be resolved against the options' working directory. Paths that are relative to the process's
+// working directory are not fingerprinted, as the same path may name different files in different operations.
+func fingerprintFile(h hash.Hash, path string, opts AssetOptions) bool {
+	abs := opts.resolvePath(path)
+	if !filepath.IsAbs(abs) {
+		return false
+	}
+	info, err := os.Stat(abs)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	fmt.Fprintf(h, "file\x00%s\x00%d\x00%d\x00%v\n", abs, info.Size(), info.ModTime().UnixNano(), info.Mode())
+	return true
+}
@@ -0,0 +1,141 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetCache(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	dirName, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dirName)
+
+	cache := NewAssetCache(cacheDir, 0)
+	opts := AssetOptions{Cache: cache}
+
+	file := filepath.Join(dirName, "file.txt")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("hello"), 0600))
+
+	// The first hash is computed; the second is served from the cache.
+	asset := &Asset{Path: file}
+	assert.NoError(t, asset.EnsureHashWithOptions(opts))
+	archive := &Archive{Path: dirName}
+	assert.NoError(t, archive.EnsureHashWithOptions(opts))
+	first, err := archiveBytes(archive, opts)
+	assert.NoError(t, err)
+
+	asset2 := &Asset{Path: file}
+	assert.NoError(t, asset2.EnsureHashWithOptions(opts))
+	archive2 := &Archive{Path: dirName}
+	assert.NoError(t, archive2.EnsureHashWithOptions(opts))
+	second, err := archiveBytes(archive2, opts)
+	assert.NoError(t, err)
+
+	assert.Equal(t, asset.Hash, asset2.Hash)
+	assert.Equal(t, archive.Hash, archive2.Hash)
+	assert.Equal(t, first, second)
+	stats := cache.Stats()
+	assert.Equal(t, int64(4), stats.HashHits)
+	assert.Equal(t, int64(1), stats.ContentHits)
+	assert.Equal(t, int64(1), stats.ContentMisses)
+	assert.Equal(t, int64(len(second)), stats.BytesServed)
+
+	// Changing the file changes its fingerprint, so nothing stale is served.
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, ioutil.WriteFile(file, []byte("goodbye"), 0600))
+	assert.NoError(t, os.Chtimes(file, later, later))
+
+	asset3 := &Asset{Path: file}
+	assert.NoError(t, asset3.EnsureHashWithOptions(opts))
+	uncached, err := asset3.computeHash(AssetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, uncached, asset3.Hash)
+	assert.NotEqual(t, asset.Hash, asset3.Hash)
+
+	archive3 := &Archive{Path: dirName}
+	assert.NoError(t, archive3.EnsureHashWithOptions(opts))
+	third, err := archiveBytes(archive3, opts)
+	assert.NoError(t, err)
+	assert.NotEqual(t, archive.Hash, archive3.Hash)
+	assert.NotEqual(t, first, third)
+}
+
+func TestAssetCacheEviction(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	// A cache of one byte holds nothing.
+	cache := NewAssetCache(cacheDir, 1)
+
+	text, err := NewTextAsset("a")
+	assert.NoError(t, err)
+	archive, err := NewAssetArchive(map[string]interface{}{"a.txt": text})
+	assert.NoError(t, err)
+	_, err = archiveBytes(archive, AssetOptions{Cache: cache})
+	assert.NoError(t, err)
+
+	// Nothing fits in the cache, so the contents were evicted as soon as they were stored.
+	assert.Equal(t, int64(1), cache.Stats().Evictions)
+	infos, err := ioutil.ReadDir(filepath.Join(cacheDir, "contents"))
+	assert.NoError(t, err)
+	assert.Empty(t, infos)
+}
+
+func TestAssetCacheResolvesRelativePaths(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	dirA, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dirA)
+	dirB, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dirB)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dirA, "file.txt"), []byte("hello"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dirB, "file.txt"), []byte("goodbye"), 0600))
+
+	// The same relative path names different files in different working directories, so neither operation is served
+	// the other's hash.
+	cache := NewAssetCache(cacheDir, 0)
+	assetA := &Asset{Path: "file.txt"}
+	assert.NoError(t, assetA.EnsureHashWithOptions(AssetOptions{WorkingDirectory: dirA, Cache: cache}))
+	assetB := &Asset{Path: "file.txt"}
+	assert.NoError(t, assetB.EnsureHashWithOptions(AssetOptions{WorkingDirectory: dirB, Cache: cache}))
+	assert.NotEqual(t, assetA.Hash, assetB.Hash)
+	assert.Equal(t, int64(0), cache.Stats().HashHits)
+
+	// Without a working directory, relative paths are not fingerprinted at all.
+	_, ok := assetFingerprint(&Asset{Path: "file.txt"}, AssetOptions{Cache: cache})
+	assert.False(t, ok)
+}
+
+func archiveBytes(a *Archive, opts AssetOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	err := a.ArchiveWithOptions(ZIPArchive, &buf, opts)
+	return buf.Bytes(), err
+}
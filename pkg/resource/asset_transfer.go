@@ -0,0 +1,273 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// DefaultAssetTransferChunkSize is the number of bytes requested at a time when fetching the contents of a remote
+// asset or archive, unless the AssetOptions say otherwise.
+const DefaultAssetTransferChunkSize = 8 * 1024 * 1024
+
+// assetTransferMaxAttempts is the number of consecutive failed requests after which a transfer is abandoned.
+const assetTransferMaxAttempts = 5
+
+// assetTransferRetryDelay is the delay before the first retry of a failed request. Each subsequent retry waits for
+// an additional delay.
+var assetTransferRetryDelay = time.Second
+
+// AssetTransferProgress reports the progress of the transfer of a remote asset's or archive's contents.
+type AssetTransferProgress struct {
+	URI         string // the URI whose contents are being transferred.
+	Transferred int64  // the number of bytes transferred so far.
+	Total       int64  // the total number of bytes to transfer, or -1 if unknown.
+	Done        bool   // true once the transfer has completed and its contents have been verified.
+}
+
+// AssetTransferObserver is called with the progress of each remote asset or archive transfer, after each chunk is
+// received. Observers may be called concurrently.
+type AssetTransferObserver func(progress AssetTransferProgress)
+
+// chunkSize returns the number of bytes to request at a time when fetching remote contents.
+func (opts AssetOptions) chunkSize() int64 {
+	if opts.TransferChunkSize <= 0 {
+		return DefaultAssetTransferChunkSize
+	}
+	return opts.TransferChunkSize
+}
+
+// observeTransfer reports the given progress to the observer, if any.
+func (opts AssetOptions) observeTransfer(progress AssetTransferProgress) {
+	if opts.TransferObserver != nil {
+		opts.TransferObserver(progress)
+	}
+}
+
+// assetTransferError is a failed request that is not worth retrying.
+type assetTransferError struct {
+	error
+}
+
+// transferFile is a temporary file holding the contents of a remote asset or archive. Closing it removes the file.
+type transferFile struct {
+	*os.File
+}
+
+func (f transferFile) Close() error {
+	err := f.File.Close()
+	contract.IgnoreError(os.Remove(f.Name()))
+	return err
+}
+
+// fetchRemoteContents fetches the contents at the given HTTP or HTTPS URI into a temporary file, which is removed when
+// the returned reader is closed. The contents are fetched in chunks, resuming after the last chunk received if a
+// request fails, and are verified against the given SHA256 hash, if it is non-empty.
+func fetchRemoteContents(uri string, expectedHash string, opts AssetOptions) (io.ReadCloser, int64, error) {
+	file, err := ioutil.TempFile("", "pulumi-asset-")
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "creating temporary file")
+	}
+	result := transferFile{file}
+
+	t := &assetTransfer{uri: uri, file: file, hash: sha256.New(), total: -1, opts: opts}
+	if err = t.run(); err != nil {
+		contract.IgnoreClose(result)
+		return nil, 0, err
+	}
+	if actual := hex.EncodeToString(t.hash.Sum(nil)); expectedHash != "" && actual != expectedHash {
+		contract.IgnoreClose(result)
+		return nil, 0, errors.Errorf("contents of %s failed verification: expected hash %s, got %s",
+			uri, expectedHash, actual)
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		contract.IgnoreClose(result)
+		return nil, 0, err
+	}
+
+	opts.observeTransfer(AssetTransferProgress{URI: uri, Transferred: t.offset, Total: t.offset, Done: true})
+	return result, t.offset, nil
+}
+
+// assetTransfer tracks the state of a chunked transfer.
+type assetTransfer struct {
+	uri    string    // the URI whose contents are being transferred.
+	file   *os.File  // the file to which the contents are written.
+	hash   hash.Hash // the hash of the contents written so far.
+	offset int64     // the number of bytes written so far.
+	total  int64     // the total number of bytes, or -1 if unknown.
+	etag   string    // the entity tag of the contents, used to detect changes between chunks.
+	opts   AssetOptions
+}
+
+// run fetches chunks until the transfer completes, retrying failed requests.
+func (t *assetTransfer) run() error {
+	attempts := 0
+	for {
+		done, err := t.fetchChunk()
+		if err == nil {
+			if done {
+				return nil
+			}
+			attempts = 0
+			continue
+		}
+
+		attempts++
+		if _, permanent := err.(assetTransferError); permanent || attempts >= assetTransferMaxAttempts {
+			return errors.Wrapf(err, "fetching %s", t.uri)
+		}
+		logging.V(5).Infof("fetching %s failed at byte %d (attempt %d): %v", t.uri, t.offset, attempts, err)
+		time.Sleep(time.Duration(attempts) * assetTransferRetryDelay)
+	}
+}
+
+// fetchChunk requests the next chunk of the contents, writing whatever it receives. It returns true once the
+// transfer is complete.
+func (t *assetTransfer) fetchChunk() (bool, error) {
+	req, err := http.NewRequest("GET", t.uri, nil)
+	if err != nil {
+		return false, assetTransferError{err}
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", t.offset, t.offset+t.opts.chunkSize()-1))
+	if t.etag != "" {
+		// If the contents have changed since the last chunk, the server ignores the range and sends them all.
+		req.Header.Set("If-Range", t.etag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		start, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return false, assetTransferError{err}
+		}
+		if start != t.offset {
+			return false, assetTransferError{
+				errors.Errorf("server returned bytes from %d when bytes from %d were requested", start, t.offset)}
+		}
+		if etag := resp.Header.Get("ETag"); !strings.HasPrefix(etag, "W/") {
+			t.etag = etag
+		}
+		t.total = total
+
+		n, err := t.write(resp.Body)
+		if err != nil {
+			return false, err
+		}
+		if t.total >= 0 {
+			return t.offset >= t.total, nil
+		}
+		return n < t.opts.chunkSize(), nil
+	case resp.StatusCode == http.StatusOK:
+		// The server does not support range requests, or the contents have changed, so the response holds the
+		// contents in their entirety. Start over.
+		if err = t.reset(); err != nil {
+			return false, assetTransferError{err}
+		}
+		t.total = resp.ContentLength
+		if _, err = t.write(resp.Body); err != nil {
+			return false, err
+		}
+		return true, nil
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The requested range starts at or after the end of the contents, which is expected only if they are empty
+		// or were exactly consumed by the previous chunk.
+		if _, total, err := parseContentRange(resp.Header.Get("Content-Range")); err == nil && total == t.offset {
+			return true, nil
+		}
+		return false, assetTransferError{errors.Errorf("server rejected the range starting at byte %d", t.offset)}
+	case resp.StatusCode >= 500:
+		return false, errors.Errorf("server returned %s", resp.Status)
+	default:
+		return false, assetTransferError{errors.Errorf("server returned %s", resp.Status)}
+	}
+}
+
+// write appends the given response body to the transfer's contents. Whatever is received before an error occurs is
+// kept, so that the next request can resume after it.
+func (t *assetTransfer) write(body io.Reader) (int64, error) {
+	n, err := io.Copy(io.MultiWriter(t.file, t.hash), body)
+	t.offset += n
+	t.opts.observeTransfer(AssetTransferProgress{URI: t.uri, Transferred: t.offset, Total: t.total})
+	return n, err
+}
+
+// reset discards the contents received so far.
+func (t *assetTransfer) reset() error {
+	if t.offset == 0 {
+		return nil
+	}
+	if err := t.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	t.hash.Reset()
+	t.offset, t.etag = 0, ""
+	return nil
+}
+
+// parseContentRange parses a Content-Range header of the form "bytes start-end/total" or "bytes */total", returning
+// the start of the range (or -1 if there is none) and the total size (or -1 if it is unknown).
+func parseContentRange(header string) (int64, int64, error) {
+	malformed := errors.Errorf("malformed Content-Range header %q", header)
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, malformed
+	}
+	parts := strings.SplitN(strings.TrimPrefix(header, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, malformed
+	}
+
+	start, total := int64(-1), int64(-1)
+	if parts[0] != "*" {
+		bounds := strings.SplitN(parts[0], "-", 2)
+		s, err := strconv.ParseInt(bounds[0], 10, 64)
+		if err != nil || len(bounds) != 2 {
+			return 0, 0, malformed
+		}
+		start = s
+	}
+	if parts[1] != "*" {
+		t, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, malformed
+		}
+		total = t
+	}
+	return start, total, nil
+}
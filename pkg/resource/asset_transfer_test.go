@@ -0,0 +1,144 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFlakyServer serves the given contents, honoring range requests, but cuts off every other response halfway
+// through its body.
+func newFlakyServer(contents []byte) (*httptest.Server, func() int) {
+	var lock sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests++
+		flaky := requests%2 == 1
+		lock.Unlock()
+
+		if !flaky {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+			return
+		}
+
+		// Claim a full chunk, but send only half of it before dropping the connection.
+		rec := httptest.NewRecorder()
+		http.ServeContent(rec, r, "", time.Time{}, bytes.NewReader(contents))
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		body := rec.Body.Bytes()
+		_, _ = w.Write(body[:len(body)/2])
+		if hj, ok := w.(http.Hijacker); ok {
+			conn, _, err := hj.Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+		}
+	}))
+	return server, func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return requests
+	}
+}
+
+func TestAssetTransferResumes(t *testing.T) {
+	defer func(delay time.Duration) { assetTransferRetryDelay = delay }(assetTransferRetryDelay)
+	assetTransferRetryDelay = time.Millisecond
+
+	contents := make([]byte, 3000)
+	for i := range contents {
+		contents[i] = byte(i)
+	}
+	sum := sha256.Sum256(contents)
+	hash := hex.EncodeToString(sum[:])
+
+	server, requests := newFlakyServer(contents)
+	defer server.Close()
+
+	var lock sync.Mutex
+	var progress []AssetTransferProgress
+	opts := AssetOptions{
+		TransferChunkSize: 1024,
+		TransferObserver: func(p AssetTransferProgress) {
+			lock.Lock()
+			defer lock.Unlock()
+			progress = append(progress, p)
+		},
+	}
+
+	asset, err := NewURIAsset(server.URL + "/file.bin")
+	assert.NoError(t, err)
+	asset.Hash = hash
+	blob, err := asset.ReadWithOptions(opts)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := ioutil.ReadAll(blob)
+	assert.NoError(t, err)
+	assert.NoError(t, blob.Close())
+	assert.Equal(t, contents, data)
+	assert.Equal(t, int64(len(contents)), blob.Size())
+
+	// Every other response was cut off halfway, and each following request resumed where it was cut off: 512 bytes,
+	// then bytes 512-1535, then 512 more, then the remaining 952.
+	assert.Equal(t, 4, requests())
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.NotEmpty(t, progress) {
+		last := progress[len(progress)-1]
+		assert.True(t, last.Done)
+		assert.Equal(t, int64(len(contents)), last.Transferred)
+	}
+
+	// Contents that do not match the expected hash are rejected.
+	asset.Hash = "0000"
+	_, err = asset.ReadWithOptions(opts)
+	assert.Error(t, err)
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, total, err := parseContentRange("bytes 1024-2047/3000")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), start)
+	assert.Equal(t, int64(3000), total)
+
+	start, total, err = parseContentRange("bytes 0-1023/*")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(-1), total)
+
+	start, total, err = parseContentRange("bytes */3000")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), start)
+	assert.Equal(t, int64(3000), total)
+
+	_, _, err = parseContentRange("items 0-1/2")
+	assert.Error(t, err)
+}
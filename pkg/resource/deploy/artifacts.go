@@ -0,0 +1,131 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+// ArtifactStore holds the contents of the artifacts that providers attach to resources, addressed by the hex-encoded
+// SHA-256 digests of their contents. The snapshot records only references to artifacts; their contents live here.
+type ArtifactStore interface {
+	// PutArtifact stores the given contents under the given digest. Storing contents that are already present is not
+	// an error.
+	PutArtifact(digest string, contents []byte) error
+	// GetArtifact returns the contents stored under the given digest.
+	GetArtifact(digest string) ([]byte, error)
+}
+
+// ArtifactDigest returns the digest under which the given contents are stored.
+func ArtifactDigest(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// storeArtifacts takes the artifacts that the given provider produced for the given resource, stores their contents,
+// and returns the resource's artifact references with those of the new artifacts added. A new artifact replaces any
+// existing artifact of the same name. Failing to store an artifact does not fail the step that produced it: the
+// artifact is dropped with a warning, as the resource itself was created or updated.
+func (p *Plan) storeArtifacts(urn resource.URN, prov plugin.Provider,
+	artifacts []resource.Artifact) []resource.Artifact {
+
+	producer, ok := prov.(plugin.ArtifactProducer)
+	if !ok {
+		return artifacts
+	}
+	files := producer.TakeArtifacts(urn)
+	if len(files) == 0 {
+		return artifacts
+	}
+
+	warn := func(name string, err error) {
+		p.Diag().Warningf(diag.RawMessage(urn, fmt.Sprintf("dropping artifact '%s': %v", name, err)))
+	}
+
+	result := append([]resource.Artifact(nil), artifacts...)
+	for _, f := range files {
+		if f.Name == "" {
+			warn(f.Name, errors.New("artifacts must be named"))
+			continue
+		}
+		if p.artifacts == nil {
+			warn(f.Name, errors.New("this backend does not store artifacts"))
+			continue
+		}
+
+		digest := ArtifactDigest(f.Contents)
+		if err := p.artifacts.PutArtifact(digest, f.Contents); err != nil {
+			warn(f.Name, err)
+			continue
+		}
+
+		ref := resource.Artifact{
+			Name:        f.Name,
+			ContentType: f.ContentType,
+			Size:        int64(len(f.Contents)),
+			Digest:      digest,
+		}
+		replaced := false
+		for i := range result {
+			if result[i].Name == ref.Name {
+				result[i], replaced = ref, true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// ReadArtifact returns the reference to and the contents of the named artifact of the given resource, as held by the
+// given store. The contents are checked against the digest recorded in the snapshot.
+func (snap *Snapshot) ReadArtifact(store ArtifactStore, urn resource.URN,
+	name string) (resource.Artifact, []byte, error) {
+
+	if snap != nil {
+		for _, res := range snap.Resources {
+			if res.URN != urn || res.Delete {
+				continue
+			}
+			for _, a := range res.Artifacts {
+				if a.Name != name {
+					continue
+				}
+				if store == nil {
+					return a, nil, errors.New("this backend does not store artifacts")
+				}
+				contents, err := store.GetArtifact(a.Digest)
+				if err != nil {
+					return a, nil, errors.Wrapf(err, "reading artifact '%s' of '%s'", name, urn)
+				}
+				if ArtifactDigest(contents) != a.Digest {
+					return a, nil, errors.Errorf("the contents of artifact '%s' of '%s' do not match its digest", name, urn)
+				}
+				return a, contents, nil
+			}
+		}
+	}
+	return resource.Artifact{}, nil, errors.Errorf("resource '%s' has no artifact named '%s'", urn, name)
+}
@@ -3,10 +3,14 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 
+	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
@@ -16,16 +20,22 @@ import (
 
 type builtinProvider struct {
 	backendClient BackendClient
+	diag          diag.Sink
 	context       context.Context
 	cancel        context.CancelFunc
+
+	stackOutputsLock sync.Mutex
+	stackOutputs     map[string]resource.PropertyMap // the outputs of each referenced stack, read at most once.
 }
 
-func newBuiltinProvider(backendClient BackendClient) *builtinProvider {
+func newBuiltinProvider(backendClient BackendClient, d diag.Sink) *builtinProvider {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &builtinProvider{
 		backendClient: backendClient,
+		diag:          d,
 		context:       ctx,
 		cancel:        cancel,
+		stackOutputs:  make(map[string]resource.PropertyMap),
 	}
 }
 
@@ -61,6 +71,9 @@ func (p *builtinProvider) Check(urn resource.URN, state, inputs resource.Propert
 
 	typ := urn.Type()
 	if typ != stackReferenceType {
+		if builtin, ok := builtinResources[typ]; ok {
+			return checkBuiltinInputs(builtin, inputs)
+		}
 		return nil, nil, errors.Errorf("unrecognized resource type '%v'", urn.Type())
 	}
 
@@ -84,6 +97,10 @@ func (p *builtinProvider) Check(urn resource.URN, state, inputs resource.Propert
 func (p *builtinProvider) Diff(urn resource.URN, id resource.ID, state, inputs resource.PropertyMap,
 	allowUnknowns bool) (plugin.DiffResult, error) {
 
+	if builtin, ok := builtinResources[urn.Type()]; ok {
+		return diffBuiltinInputs(builtin, state, inputs), nil
+	}
+
 	contract.Assert(urn.Type() == stackReferenceType)
 
 	if !inputs["name"].DeepEquals(state["name"]) {
@@ -93,12 +110,69 @@ func (p *builtinProvider) Diff(urn resource.URN, id resource.ID, state, inputs r
 		}, nil
 	}
 
+	// If the referenced stack's outputs have changed since this stack was last deployed, the reference is updated so
+	// that the resources that use them see the new values.
+	if changed, err := p.stackOutputsChanged(urn, state); err != nil {
+		return plugin.DiffResult{}, err
+	} else if changed {
+		return plugin.DiffResult{Changes: plugin.DiffSome}, nil
+	}
+
 	return plugin.DiffResult{Changes: plugin.DiffNone}, nil
 }
 
+// stackOutputsChanged returns true if the current outputs of the stack referenced by the given state differ from
+// those recorded in it, and warns about the outputs that changed. The warning names the changed outputs but not their
+// values, which may be secret.
+func (p *builtinProvider) stackOutputsChanged(urn resource.URN, state resource.PropertyMap) (bool, error) {
+	name := state["name"]
+	if !name.IsString() || p.backendClient == nil {
+		return false, nil
+	}
+
+	outputs, err := p.getStackOutputs(name.StringValue())
+	if err != nil {
+		return false, err
+	}
+	var olds resource.PropertyMap
+	if old := state["outputs"]; old.IsObject() {
+		olds = old.ObjectValue()
+	}
+	diff := olds.Diff(outputs)
+	if diff == nil {
+		return false, nil
+	}
+
+	var keys []string
+	for k := range diff.Adds {
+		keys = append(keys, string(k))
+	}
+	for k := range diff.Deletes {
+		keys = append(keys, string(k))
+	}
+	for k := range diff.Updates {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	if p.diag != nil {
+		p.diag.Warningf(diag.RawMessage(urn, fmt.Sprintf(
+			"the outputs of stack '%s' have changed since this stack was last deployed: %s",
+			name.StringValue(), strings.Join(keys, ", "))))
+	}
+	return true, nil
+}
+
 func (p *builtinProvider) Create(urn resource.URN,
 	inputs resource.PropertyMap) (resource.ID, resource.PropertyMap, resource.Status, error) {
 
+	if builtin, ok := builtinResources[urn.Type()]; ok {
+		state, err := builtin.create(inputs)
+		if err != nil {
+			return "", nil, resource.StatusUnknown, err
+		}
+		return resource.ID(uuid.NewV4().String()), state, resource.StatusOK, nil
+	}
+
 	contract.Assert(urn.Type() == stackReferenceType)
 
 	state, err := p.readStackReference(inputs)
@@ -112,16 +186,24 @@ func (p *builtinProvider) Create(urn resource.URN,
 func (p *builtinProvider) Update(urn resource.URN, id resource.ID, state,
 	inputs resource.PropertyMap) (resource.PropertyMap, resource.Status, error) {
 
-	contract.Failf("unexpected update for builtin resource %v", urn)
-	contract.Assert(urn.Type() == stackReferenceType)
+	if urn.Type() != stackReferenceType {
+		contract.Failf("unexpected update for builtin resource %v", urn)
+		return state, resource.StatusOK, errors.New("unexpected update for builtin resource")
+	}
 
-	return state, resource.StatusOK, errors.New("unexpected update for builtin resource")
+	// A stack reference is only updated when the referenced stack's outputs have changed, so read them again.
+	outputs, err := p.readStackReference(inputs)
+	if err != nil {
+		return state, resource.StatusUnknown, err
+	}
+	return outputs, resource.StatusOK, nil
 }
 
 func (p *builtinProvider) Delete(urn resource.URN, id resource.ID,
 	state resource.PropertyMap) (resource.Status, error) {
 
-	contract.Assert(urn.Type() == stackReferenceType)
+	_, ok := builtinResources[urn.Type()]
+	contract.Assert(ok || urn.Type() == stackReferenceType)
 
 	return resource.StatusOK, nil
 }
@@ -129,6 +211,11 @@ func (p *builtinProvider) Delete(urn resource.URN, id resource.ID,
 func (p *builtinProvider) Read(urn resource.URN, id resource.ID,
 	inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
 
+	// The values of the engine's own resources live only in the checkpoint, so refreshing them changes nothing.
+	if _, ok := builtinResources[urn.Type()]; ok {
+		return plugin.ReadResult{Inputs: inputs, Outputs: state}, resource.StatusOK, nil
+	}
+
 	contract.Assert(urn.Type() == stackReferenceType)
 
 	outputs, err := p.readStackReference(state)
@@ -173,11 +260,7 @@ func (p *builtinProvider) readStackReference(inputs resource.PropertyMap) (resou
 	contract.Assert(ok)
 	contract.Assert(name.IsString())
 
-	if p.backendClient == nil {
-		return nil, errors.New("no backend client is available")
-	}
-
-	outputs, err := p.backendClient.GetStackOutputs(p.context, name.StringValue())
+	outputs, err := p.getStackOutputs(name.StringValue())
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +271,26 @@ func (p *builtinProvider) readStackReference(inputs resource.PropertyMap) (resou
 	}, nil
 }
 
+// getStackOutputs returns the outputs of the given stack. Each stack's outputs are read from the backend at most once,
+// so that every resource and step of a deployment sees the same outputs.
+func (p *builtinProvider) getStackOutputs(name string) (resource.PropertyMap, error) {
+	if p.backendClient == nil {
+		return nil, errors.New("no backend client is available")
+	}
+
+	p.stackOutputsLock.Lock()
+	defer p.stackOutputsLock.Unlock()
+	if outputs, ok := p.stackOutputs[name]; ok {
+		return outputs, nil
+	}
+	outputs, err := p.backendClient.GetStackOutputs(p.context, name)
+	if err != nil {
+		return nil, err
+	}
+	p.stackOutputs[name] = outputs
+	return outputs, nil
+}
+
 func (p *builtinProvider) readStackResourceOutputs(inputs resource.PropertyMap) (resource.PropertyMap, error) {
 	name, ok := inputs["stackName"]
 	contract.Assert(ok)
@@ -0,0 +1,189 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+const (
+	randomPasswordType = "pulumi:random:RandomPassword"
+	randomPetType      = "pulumi:random:RandomPet"
+	randomIDType       = "pulumi:random:RandomId"
+)
+
+const (
+	passwordLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	passwordSpecial = "!@#$%&*()-_=+[]{}<>:?"
+)
+
+// randomPassword generates a random password of a given length. The password is stored as a secret.
+var randomPassword = &builtinResource{
+	inputs: map[resource.PropertyKey]builtinInput{
+		"length":   {kind: builtinNumber, required: true},
+		"special":  {kind: builtinBool, def: builtinDefault(resource.NewBoolProperty(true))},
+		"triggers": builtinTriggers,
+	},
+	check: func(inputs resource.PropertyMap) []plugin.CheckFailure {
+		return checkPositiveInteger(inputs, "length")
+	},
+	create: func(inputs resource.PropertyMap) (resource.PropertyMap, error) {
+		charset := passwordLetters
+		if builtinValue(inputs, "special").BoolValue() {
+			charset += passwordSpecial
+		}
+		password, err := randomString(charset, int(builtinValue(inputs, "length").NumberValue()))
+		if err != nil {
+			return nil, err
+		}
+
+		state := inputs.Copy()
+		state["result"] = resource.MakeSecret(resource.NewStringProperty(password))
+		return state, nil
+	},
+}
+
+// randomPet generates a random, human-readable name made of adjectives followed by the name of an animal, such as
+// "brave-curious-otter".
+var randomPet = &builtinResource{
+	inputs: map[resource.PropertyKey]builtinInput{
+		"length":    {kind: builtinNumber, def: builtinDefault(resource.NewNumberProperty(2))},
+		"separator": {kind: builtinString, def: builtinDefault(resource.NewStringProperty("-"))},
+		"prefix":    {kind: builtinString},
+		"triggers":  builtinTriggers,
+	},
+	check: func(inputs resource.PropertyMap) []plugin.CheckFailure {
+		return checkPositiveInteger(inputs, "length")
+	},
+	create: func(inputs resource.PropertyMap) (resource.PropertyMap, error) {
+		length := int(builtinValue(inputs, "length").NumberValue())
+
+		var words []string
+		if prefix := builtinValue(inputs, "prefix"); prefix.IsString() && prefix.StringValue() != "" {
+			words = append(words, prefix.StringValue())
+		}
+		for i := 0; i < length; i++ {
+			list := petAdjectives
+			if i == length-1 {
+				list = petAnimals
+			}
+			n, err := randomInt(len(list))
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, list[n])
+		}
+
+		state := inputs.Copy()
+		state["id"] = resource.NewStringProperty(strings.Join(words, builtinValue(inputs, "separator").StringValue()))
+		return state, nil
+	},
+}
+
+// randomID generates a given number of random bytes, and reports them in several encodings that are suitable for use
+// in resource names.
+var randomID = &builtinResource{
+	inputs: map[resource.PropertyKey]builtinInput{
+		"byteLength": {kind: builtinNumber, required: true},
+		"prefix":     {kind: builtinString},
+		"triggers":   builtinTriggers,
+	},
+	check: func(inputs resource.PropertyMap) []plugin.CheckFailure {
+		return checkPositiveInteger(inputs, "byteLength")
+	},
+	create: func(inputs resource.PropertyMap) (resource.PropertyMap, error) {
+		bytes := make([]byte, int(builtinValue(inputs, "byteLength").NumberValue()))
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, errors.Wrap(err, "generating random bytes")
+		}
+
+		var prefix string
+		if p := builtinValue(inputs, "prefix"); p.IsString() {
+			prefix = p.StringValue()
+		}
+
+		state := inputs.Copy()
+		state["hex"] = resource.NewStringProperty(prefix + hex.EncodeToString(bytes))
+		state["b64Url"] = resource.NewStringProperty(prefix + base64.RawURLEncoding.EncodeToString(bytes))
+		state["dec"] = resource.NewStringProperty(prefix + new(big.Int).SetBytes(bytes).String())
+		return state, nil
+	},
+}
+
+// checkPositiveInteger checks that the given input is a whole number greater than zero.
+func checkPositiveInteger(inputs resource.PropertyMap, k resource.PropertyKey) []plugin.CheckFailure {
+	n := builtinValue(inputs, k).NumberValue()
+	if n < 1 || n != float64(int(n)) {
+		reason := fmt.Sprintf("property \"%v\" must be a positive integer", k)
+		return []plugin.CheckFailure{{Property: k, Reason: reason}}
+	}
+	return nil
+}
+
+// builtinValue returns the value of a builtin resource's input, looking through any secret that wraps it.
+func builtinValue(inputs resource.PropertyMap, k resource.PropertyKey) resource.PropertyValue {
+	v := inputs[k]
+	if v.IsSecret() {
+		return v.SecretValue().Element
+	}
+	return v
+}
+
+// randomInt returns a uniformly distributed random integer in [0, n).
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, errors.Wrap(err, "generating a random number")
+	}
+	return int(i.Int64()), nil
+}
+
+// randomString returns a string of the given length made of characters chosen uniformly from the given set.
+func randomString(charset string, length int) (string, error) {
+	result := make([]byte, length)
+	for i := range result {
+		n, err := randomInt(len(charset))
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[n]
+	}
+	return string(result), nil
+}
+
+var petAdjectives = []string{
+	"able", "amused", "brave", "bright", "calm", "careful", "clever", "cool", "curious", "daring", "eager", "fair",
+	"fancy", "fast", "fine", "gentle", "glad", "golden", "happy", "helpful", "honest", "humble", "jolly", "keen",
+	"kind", "lively", "loyal", "lucky", "merry", "mighty", "modest", "neat", "nice", "noble", "patient", "polite",
+	"proud", "quick", "quiet", "rapid", "ready", "sharp", "shy", "smart", "steady", "sunny", "swift", "tidy", "witty",
+}
+
+var petAnimals = []string{
+	"badger", "bat", "bear", "beaver", "bison", "buffalo", "camel", "cat", "cheetah", "crab", "crane", "deer", "dog",
+	"dolphin", "dove", "duck", "eagle", "elk", "falcon", "ferret", "finch", "fox", "frog", "gecko", "goat", "goose",
+	"hare", "hawk", "heron", "horse", "ibis", "jackal", "koala", "lemur", "lion", "llama", "lynx", "mole", "moose",
+	"newt", "otter", "owl", "panda", "parrot", "pig", "puma", "quail", "rabbit", "raven", "seal", "sheep", "sloth",
+	"snail", "swan", "tiger", "toad", "trout", "turtle", "walrus", "whale", "wolf", "wombat", "yak", "zebra",
+}
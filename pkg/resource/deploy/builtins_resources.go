@@ -0,0 +1,159 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// builtinResource describes a resource type whose values are generated and stored by the engine itself rather than by
+// an external provider. Any change to such a resource's inputs replaces it, so its values stay stable until one of
+// its inputs (typically its "triggers") changes.
+type builtinResource struct {
+	// inputs describes the properties that the resource accepts.
+	inputs map[resource.PropertyKey]builtinInput
+	// check validates the resource's inputs once their types are known to be correct. It may be nil.
+	check func(inputs resource.PropertyMap) []plugin.CheckFailure
+	// create generates the resource's state from its checked inputs.
+	create func(inputs resource.PropertyMap) (resource.PropertyMap, error)
+}
+
+// builtinInputKind is the kind of value a builtin resource's input accepts.
+type builtinInputKind string
+
+const (
+	builtinBool   builtinInputKind = "boolean"
+	builtinNumber builtinInputKind = "number"
+	builtinString builtinInputKind = "string"
+	builtinObject builtinInputKind = "object"
+)
+
+// builtinInput describes a single input property of a builtin resource.
+type builtinInput struct {
+	kind     builtinInputKind        // the kind of value the property accepts.
+	required bool                    // true if the property must be set.
+	def      *resource.PropertyValue // the property's default value, if any.
+}
+
+// builtinResources holds the resource types managed by the engine, keyed by type token.
+var builtinResources = map[tokens.Type]*builtinResource{
+	randomPasswordType: randomPassword,
+	randomPetType:      randomPet,
+	randomIDType:       randomID,
+	timestampType:      timestamp,
+}
+
+// builtinTriggers is the input that every builtin resource accepts so that users can force the resource's values to
+// be regenerated: any change to its contents replaces the resource.
+var builtinTriggers = builtinInput{kind: builtinObject}
+
+// checkBuiltinInputs validates a builtin resource's inputs and fills in any defaults.
+func checkBuiltinInputs(builtin *builtinResource,
+	inputs resource.PropertyMap) (resource.PropertyMap, []plugin.CheckFailure, error) {
+
+	var failures []plugin.CheckFailure
+	for _, k := range inputs.StableKeys() {
+		if _, ok := builtin.inputs[k]; !ok {
+			failures = append(failures, plugin.CheckFailure{
+				Property: k,
+				Reason:   fmt.Sprintf("unknown property \"%v\"", k),
+			})
+		}
+	}
+
+	checked := inputs.Copy()
+	for _, k := range builtinInputKeys(builtin) {
+		input := builtin.inputs[k]
+
+		v, ok := checked[k]
+		if !ok || v.IsNull() {
+			switch {
+			case input.def != nil:
+				checked[k] = *input.def
+			case input.required:
+				failures = append(failures, plugin.CheckFailure{
+					Property: k,
+					Reason:   fmt.Sprintf("missing required property \"%v\"", k),
+				})
+			}
+			continue
+		}
+
+		if v.IsSecret() {
+			v = v.SecretValue().Element
+		}
+		if v.IsComputed() || v.IsOutput() {
+			continue
+		}
+		var valid bool
+		switch input.kind {
+		case builtinBool:
+			valid = v.IsBool()
+		case builtinNumber:
+			valid = v.IsNumber()
+		case builtinString:
+			valid = v.IsString()
+		case builtinObject:
+			valid = v.IsObject()
+		}
+		if !valid {
+			failures = append(failures, plugin.CheckFailure{
+				Property: k,
+				Reason:   fmt.Sprintf("property \"%v\" must be a %s", k, input.kind),
+			})
+		}
+	}
+
+	if len(failures) == 0 && builtin.check != nil && !checked.ContainsUnknowns() {
+		failures = builtin.check(checked)
+	}
+	if len(failures) != 0 {
+		return nil, failures, nil
+	}
+	return checked, nil, nil
+}
+
+// diffBuiltinInputs compares a builtin resource's new inputs with its state. Every changed input requires that the
+// resource be replaced.
+func diffBuiltinInputs(builtin *builtinResource, state, inputs resource.PropertyMap) plugin.DiffResult {
+	var replaceKeys []resource.PropertyKey
+	for _, k := range builtinInputKeys(builtin) {
+		if !inputs[k].DeepEquals(state[k]) {
+			replaceKeys = append(replaceKeys, k)
+		}
+	}
+	if len(replaceKeys) == 0 {
+		return plugin.DiffResult{Changes: plugin.DiffNone}
+	}
+	return plugin.DiffResult{Changes: plugin.DiffSome, ReplaceKeys: replaceKeys}
+}
+
+// builtinInputKeys returns the names of a builtin resource's inputs in a stable order.
+func builtinInputKeys(builtin *builtinResource) []resource.PropertyKey {
+	keys := make(resource.PropertyMap, len(builtin.inputs))
+	for k := range builtin.inputs {
+		keys[k] = resource.PropertyValue{}
+	}
+	return keys.StableKeys()
+}
+
+// builtinDefault returns a pointer to the given default value for a builtin resource's input.
+func builtinDefault(v resource.PropertyValue) *resource.PropertyValue {
+	return &v
+}
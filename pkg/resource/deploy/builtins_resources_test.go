@@ -0,0 +1,140 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func builtinURN(typ tokens.Type) resource.URN {
+	return resource.NewURN("test", "test", "", typ, "res")
+}
+
+func TestBuiltinResourceCheck(t *testing.T) {
+	p := newBuiltinProvider(nil, nil)
+
+	// Defaults are filled in.
+	inputs, failures, err := p.Check(builtinURN(randomPasswordType), nil,
+		resource.PropertyMap{"length": resource.NewNumberProperty(16)}, false)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.Equal(t, resource.NewBoolProperty(true), inputs["special"])
+
+	// Missing, unknown, mistyped, and invalid properties are reported.
+	_, failures, err = p.Check(builtinURN(randomPasswordType), nil, resource.PropertyMap{}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []plugin.CheckFailure{{Property: "length", Reason: `missing required property "length"`}}, failures)
+
+	_, failures, err = p.Check(builtinURN(randomPetType), nil, resource.PropertyMap{
+		"name":   resource.NewStringProperty("x"),
+		"length": resource.NewStringProperty("2"),
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []plugin.CheckFailure{
+		{Property: "name", Reason: `unknown property "name"`},
+		{Property: "length", Reason: `property "length" must be a number`},
+	}, failures)
+
+	_, failures, err = p.Check(builtinURN(randomIDType), nil,
+		resource.PropertyMap{"byteLength": resource.NewNumberProperty(1.5)}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []plugin.CheckFailure{
+		{Property: "byteLength", Reason: `property "byteLength" must be a positive integer`},
+	}, failures)
+
+	// Unknown values are accepted.
+	_, failures, err = p.Check(builtinURN(randomIDType), nil,
+		resource.PropertyMap{"byteLength": resource.MakeComputed(resource.NewStringProperty(""))}, true)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+func TestBuiltinResourceCreate(t *testing.T) {
+	p := newBuiltinProvider(nil, nil)
+
+	create := func(typ tokens.Type, props resource.PropertyMap) resource.PropertyMap {
+		inputs, failures, err := p.Check(builtinURN(typ), nil, props, false)
+		assert.NoError(t, err)
+		assert.Empty(t, failures)
+		_, state, _, err := p.Create(builtinURN(typ), inputs)
+		assert.NoError(t, err)
+		return state
+	}
+
+	state := create(randomPasswordType, resource.PropertyMap{
+		"length":  resource.NewNumberProperty(24),
+		"special": resource.NewBoolProperty(false),
+	})
+	if assert.True(t, state["result"].IsSecret()) {
+		password := state["result"].SecretValue().Element.StringValue()
+		assert.Len(t, password, 24)
+		assert.Equal(t, "", strings.Trim(password, passwordLetters))
+	}
+
+	state = create(randomPetType, resource.PropertyMap{
+		"length": resource.NewNumberProperty(3),
+		"prefix": resource.NewStringProperty("dev"),
+	})
+	words := strings.Split(state["id"].StringValue(), "-")
+	assert.Len(t, words, 4)
+	assert.Equal(t, "dev", words[0])
+	assert.Contains(t, petAnimals, words[3])
+
+	state = create(randomIDType, resource.PropertyMap{"byteLength": resource.NewNumberProperty(4)})
+	assert.Len(t, state["hex"].StringValue(), 8)
+	assert.Len(t, state["b64Url"].StringValue(), 6)
+
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Unix(1560000000, 0) }
+	state = create(timestampType, resource.PropertyMap{})
+	assert.Equal(t, "2019-06-08T13:20:00Z", state["rfc3339"].StringValue())
+	assert.Equal(t, resource.NewNumberProperty(1560000000), state["unix"])
+}
+
+func TestBuiltinResourceDiff(t *testing.T) {
+	p := newBuiltinProvider(nil, nil)
+
+	triggers := func(v string) resource.PropertyMap {
+		return resource.PropertyMap{
+			"triggers": resource.NewObjectProperty(resource.PropertyMap{"image": resource.NewStringProperty(v)}),
+		}
+	}
+	_, state, _, err := p.Create(builtinURN(timestampType), triggers("v1"))
+	assert.NoError(t, err)
+
+	// The generated values are stable for as long as the inputs are.
+	diff, err := p.Diff(builtinURN(timestampType), "id", state, triggers("v1"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, plugin.DiffNone, diff.Changes)
+
+	// Changing the triggers replaces the resource.
+	diff, err = p.Diff(builtinURN(timestampType), "id", state, triggers("v2"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, plugin.DiffSome, diff.Changes)
+	assert.Equal(t, []resource.PropertyKey{"triggers"}, diff.ReplaceKeys)
+
+	// Refreshing leaves the generated values alone.
+	read, _, err := p.Read(builtinURN(timestampType), "id", triggers("v1"), state)
+	assert.NoError(t, err)
+	assert.Equal(t, state, read.Outputs)
+}
@@ -0,0 +1,42 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+const timestampType = "pulumi:time:Timestamp"
+
+// timeNow returns the current time. It is a variable so that tests can control the clock.
+var timeNow = time.Now
+
+// timestamp records the time at which it was created. The time is kept until the resource's triggers change, at which
+// point the resource is replaced and the time is recorded anew.
+var timestamp = &builtinResource{
+	inputs: map[resource.PropertyKey]builtinInput{
+		"triggers": builtinTriggers,
+	},
+	create: func(inputs resource.PropertyMap) (resource.PropertyMap, error) {
+		now := timeNow().UTC()
+
+		state := inputs.Copy()
+		state["rfc3339"] = resource.NewStringProperty(now.Format(time.RFC3339))
+		state["unix"] = resource.NewNumberProperty(float64(now.Unix()))
+		return state, nil
+	},
+}
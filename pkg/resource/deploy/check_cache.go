@@ -0,0 +1,248 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// checkCacheVersion is mixed into every key, so that changes to the way that keys are computed invalidate the entries
+// cached before them.
+const checkCacheVersion = "v1"
+
+// checkCacheOptions marshal the properties that are hashed into keys and stored in entries. Asset hashes are computed,
+// so that a changed asset changes the key of each resource that refers to it.
+var checkCacheOptions = plugin.MarshalOptions{
+	Label:              "check-cache",
+	KeepUnknowns:       true,
+	KeepSecrets:        true,
+	ComputeAssetHashes: true,
+}
+
+// CheckCache is an experimental cache of the inputs that providers return when previews check resources. Each entry is
+// keyed by a hash of the resource's dependency closure: its URN, the inputs and old inputs passed to Check, and the
+// closures of its parent, its provider, and its dependencies. If a resource and everything it depends on is unchanged
+// since an earlier preview, the preview reuses the checked inputs rather than calling its provider's Check again, which
+// makes previews of large, mostly unchanged programs much faster.
+//
+// Only checks that succeed are cached, so that failures are always reported, and providers themselves are always
+// checked, as checking a provider loads it. Entries are partitioned by stack; once a preview completes, the entries of
+// its stack that it did not use are removed. A check cache may be shared by any number of processes.
+type CheckCache struct {
+	dir   string          // the directory in which the cache is stored.
+	stats CheckCacheStats // the cache's statistics, which are updated atomically.
+
+	m    sync.Mutex
+	used map[string]map[string]bool // the entries used by this process, by partition.
+}
+
+// CheckCacheStats counts the lookups made in a check cache.
+type CheckCacheStats struct {
+	Hits   int64 // the number of checks served from the cache.
+	Misses int64 // the number of checks that had to be made.
+}
+
+// NewCheckCache creates a check cache that is stored in the given directory.
+func NewCheckCache(dir string) *CheckCache {
+	contract.Require(dir != "", "dir")
+	return &CheckCache{dir: dir, used: make(map[string]map[string]bool)}
+}
+
+// Stats returns the statistics for the lookups made in the cache by this process.
+func (c *CheckCache) Stats() CheckCacheStats {
+	return CheckCacheStats{
+		Hits:   atomic.LoadInt64(&c.stats.Hits),
+		Misses: atomic.LoadInt64(&c.stats.Misses),
+	}
+}
+
+// checkCacheKey returns the key under which the result of checking the given resource is cached, which is also the
+// hash of the resource's dependency closure. The closures of the resources upon which it depends are looked up in
+// closures; a resource that was not registered by the plan is represented by its URN alone.
+func checkCacheKey(urn resource.URN, goal *resource.Goal, olds, news resource.PropertyMap,
+	closures map[resource.URN]string, pwd string) (string, error) {
+
+	h := sha256.New()
+	fmt.Fprintf(h, "version:%s\nurn:%s\ncustom:%v\nprovider:%s\n", checkCacheVersion, urn, goal.Custom, goal.Provider)
+
+	deps := append([]resource.URN(nil), goal.Dependencies...)
+	if goal.Parent != "" {
+		deps = append(deps, goal.Parent)
+	}
+	if goal.Provider != "" {
+		if ref, err := providers.ParseReference(goal.Provider); err == nil {
+			deps = append(deps, ref.URN())
+		}
+	}
+	for _, dep := range deps {
+		fmt.Fprintf(h, "dep:%s %s\n", dep, closures[dep])
+	}
+
+	for _, props := range []resource.PropertyMap{olds, news} {
+		if props == nil {
+			fmt.Fprint(h, "props:none\n")
+			continue
+		}
+		opts := checkCacheOptions
+		opts.WorkingDirectory = pwd
+		s, err := plugin.MarshalProperties(props, opts)
+		if err != nil {
+			return "", err
+		}
+		b, err := marshalDeterministic(s)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "props:%d\n", len(b))
+		_, err = h.Write(b)
+		contract.IgnoreError(err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// get returns the checked inputs cached for the given resource under the given key, if any.
+func (c *CheckCache) get(urn resource.URN, key string) (resource.PropertyMap, bool) {
+	partition := checkCachePartition(urn)
+	b, err := ioutil.ReadFile(filepath.Join(c.dir, partition, key))
+	if err != nil {
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, false
+	}
+	var s structpb.Struct
+	if err = proto.Unmarshal(b, &s); err != nil {
+		logging.V(5).Infof("check cache: ignoring unreadable entry %s: %v", key, err)
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, false
+	}
+	inputs, err := plugin.UnmarshalProperties(&s, checkCacheOptions)
+	if err != nil {
+		logging.V(5).Infof("check cache: ignoring unreadable entry %s: %v", key, err)
+		atomic.AddInt64(&c.stats.Misses, 1)
+		return nil, false
+	}
+
+	atomic.AddInt64(&c.stats.Hits, 1)
+	c.use(partition, key)
+	return inputs, true
+}
+
+// put caches the given checked inputs for the given resource under the given key.
+func (c *CheckCache) put(urn resource.URN, key string, inputs resource.PropertyMap) {
+	s, err := plugin.MarshalProperties(inputs, checkCacheOptions)
+	if err == nil {
+		var b []byte
+		if b, err = marshalDeterministic(s); err == nil {
+			err = writeCacheFile(filepath.Join(c.dir, checkCachePartition(urn), key), b)
+		}
+	}
+	if err != nil {
+		logging.V(5).Infof("check cache: failed to cache the inputs of %s: %v", urn, err)
+		return
+	}
+	c.use(checkCachePartition(urn), key)
+}
+
+// use records that the given entry was used by this process.
+func (c *CheckCache) use(partition, key string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.used[partition] == nil {
+		c.used[partition] = make(map[string]bool)
+	}
+	c.used[partition][key] = true
+}
+
+// sweep removes the entries of each partition used by this process that this process did not use, as they belong to
+// resources, or versions of resources, that no longer exist.
+func (c *CheckCache) sweep() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for partition, used := range c.used {
+		infos, err := ioutil.ReadDir(filepath.Join(c.dir, partition))
+		if err != nil {
+			logging.V(5).Infof("check cache: failed to read partition %s: %v", partition, err)
+			continue
+		}
+		for _, info := range infos {
+			if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") || used[info.Name()] {
+				continue
+			}
+			if err := os.Remove(filepath.Join(c.dir, partition, info.Name())); err != nil {
+				logging.V(5).Infof("check cache: failed to remove entry %s: %v", info.Name(), err)
+			}
+		}
+	}
+	c.used = make(map[string]map[string]bool)
+
+	stats := c.Stats()
+	logging.V(5).Infof("check cache: %d hits, %d misses", stats.Hits, stats.Misses)
+}
+
+// checkCachePartition returns the name of the partition of the cache that holds the entries of the given resource's
+// stack.
+func checkCachePartition(urn resource.URN) string {
+	sum := sha256.Sum256([]byte(string(urn.Project()) + "::" + string(urn.Stack())))
+	return hex.EncodeToString(sum[:8])
+}
+
+// marshalDeterministic marshals the given properties to bytes that are the same for equal properties.
+func marshalDeterministic(s *structpb.Struct) ([]byte, error) {
+	var buf proto.Buffer
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCacheFile atomically writes the given data to the given path.
+func writeCacheFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		contract.IgnoreError(os.Remove(temp.Name()))
+	}
+	return err
+}
@@ -17,6 +17,7 @@ package deploytest
 import (
 	"context"
 
+	"github.com/pulumi/pulumi/pkg/apitype"
 	"github.com/pulumi/pulumi/pkg/resource"
 )
 
@@ -24,6 +25,7 @@ import (
 type BackendClient struct {
 	GetStackOutputsF         func(ctx context.Context, name string) (resource.PropertyMap, error)
 	GetStackResourceOutputsF func(ctx context.Context, name string) (resource.PropertyMap, error)
+	GetStackHistoryF         func(ctx context.Context, name string) ([]apitype.UpdateInfo, error)
 }
 
 // GetStackOutputs returns the outputs (if any) for the named stack or an error if the stack cannot be found.
@@ -40,3 +42,9 @@ func (b *BackendClient) GetStackResourceOutputs(
 	ctx context.Context, name string) (resource.PropertyMap, error) {
 	return b.GetStackResourceOutputsF(ctx, name)
 }
+
+// GetStackHistory returns the updates that have been made to the named stack, most recent first, or an error if the
+// stack cannot be found.
+func (b *BackendClient) GetStackHistory(ctx context.Context, name string) ([]apitype.UpdateInfo, error) {
+	return b.GetStackHistoryF(ctx, name)
+}
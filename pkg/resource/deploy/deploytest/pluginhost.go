@@ -169,6 +169,12 @@ func (host *pluginHost) CloseProvider(provider plugin.Provider) error {
 	delete(host.providers, provider)
 	return nil
 }
+func (host *pluginHost) RestartProvider(provider plugin.Provider) (bool, error) {
+	if r, ok := provider.(plugin.Restartable); ok {
+		return r.Restart()
+	}
+	return false, nil
+}
 func (host *pluginHost) ListPlugins() []workspace.PluginInfo {
 	return nil
 }
@@ -177,5 +183,8 @@ func (host *pluginHost) EnsurePlugins(plugins []workspace.PluginInfo, kinds plug
 }
 func (host *pluginHost) GetRequiredPlugins(info plugin.ProgInfo,
 	kinds plugin.Flags) ([]workspace.PluginInfo, error) {
-	return nil, nil
+	if host.languageRuntime == nil || kinds&plugin.LanguagePlugins == 0 {
+		return nil, nil
+	}
+	return host.languageRuntime.GetRequiredPlugins(info)
 }
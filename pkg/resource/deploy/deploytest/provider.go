@@ -15,6 +15,8 @@
 package deploytest
 
 import (
+	"sync"
+
 	"github.com/blang/semver"
 	uuid "github.com/satori/go.uuid"
 
@@ -52,6 +54,165 @@ type Provider struct {
 		inputs resource.PropertyMap) (resource.PropertyMap, []plugin.CheckFailure, error)
 
 	CancelF func() error
+
+	ExplainFailureF func(urn resource.URN, err error) (*plugin.FailureDetails, error)
+	CheckHealthF    func(urn resource.URN, id resource.ID, state resource.PropertyMap) error
+
+	// MigrateStateF, if set, migrates the state of resources last managed by an older version of the provider.
+	MigrateStateF func(urn resource.URN, id resource.ID, version semver.Version,
+		inputs, outputs resource.PropertyMap) (resource.PropertyMap, resource.PropertyMap, error)
+
+	// RestartF is called when the engine asks the provider to restart after a failed operation. It should return true
+	// if the provider simulated a restart.
+	RestartF func() (bool, error)
+
+	// MaxParallelismF, if set, supplies the provider's parallelism hint.
+	MaxParallelismF func() int
+
+	// CancelOperationF is called when the engine cancels an in-flight operation, e.g. because it timed out.
+	CancelOperationF func(urn resource.URN)
+
+	// ConfigureOperationF is called with the settings of each resource before the engine operates on it.
+	ConfigureOperationF func(urn resource.URN, settings plugin.OperationSettings)
+
+	// TagsPropertyF, if set, names the property that holds the tags of resources of the given type.
+	TagsPropertyF func(t tokens.Type) resource.PropertyKey
+
+	// TakeArtifactsF, if set, supplies the artifacts produced by the last create or update of the given resource.
+	TakeArtifactsF func(urn resource.URN) []plugin.ArtifactFile
+
+	// ResourceDeprecationF, if set, supplies the deprecation of the given resource type.
+	ResourceDeprecationF func(t tokens.Type) *plugin.Deprecation
+	// PropertyDeprecationsF, if set, supplies the deprecated input properties of resources of the given type.
+	PropertyDeprecationsF func(t tokens.Type) map[resource.PropertyKey]plugin.Deprecation
+
+	// PreviewCreateF and PreviewUpdateF, if set, validate the creates and updates of previews that ask for it.
+	PreviewCreateF func(urn resource.URN, news resource.PropertyMap) error
+	PreviewUpdateF func(urn resource.URN, id resource.ID, olds, news resource.PropertyMap) error
+
+	progressLock sync.Mutex
+	progressF    plugin.ProgressFunc
+
+	restartLock sync.Mutex
+	restarts    int
+}
+
+func (prov *Provider) SetProgressFunc(f plugin.ProgressFunc) {
+	prov.progressLock.Lock()
+	defer prov.progressLock.Unlock()
+	prov.progressF = f
+}
+
+// ReportProgress reports the progress of an operation on the given resource to the engine, if it is listening.
+func (prov *Provider) ReportProgress(urn resource.URN, percent int, message string) {
+	prov.progressLock.Lock()
+	f := prov.progressF
+	prov.progressLock.Unlock()
+	if f != nil {
+		f(urn, percent, message)
+	}
+}
+
+func (prov *Provider) ExplainFailure(urn resource.URN, err error) (*plugin.FailureDetails, error) {
+	if prov.ExplainFailureF == nil {
+		return nil, nil
+	}
+	return prov.ExplainFailureF(urn, err)
+}
+
+func (prov *Provider) CheckHealth(urn resource.URN, id resource.ID, state resource.PropertyMap) error {
+	if prov.CheckHealthF == nil {
+		return plugin.ErrHealthCheckUnsupported
+	}
+	return prov.CheckHealthF(urn, id, state)
+}
+
+func (prov *Provider) MigrateState(urn resource.URN, id resource.ID, version semver.Version,
+	inputs, outputs resource.PropertyMap) (resource.PropertyMap, resource.PropertyMap, error) {
+	if prov.MigrateStateF == nil {
+		return inputs, outputs, nil
+	}
+	return prov.MigrateStateF(urn, id, version, inputs, outputs)
+}
+
+func (prov *Provider) Restart() (bool, error) {
+	if prov.RestartF == nil {
+		return false, nil
+	}
+	restarted, err := prov.RestartF()
+	if restarted {
+		prov.restartLock.Lock()
+		prov.restarts++
+		prov.restartLock.Unlock()
+	}
+	return restarted, err
+}
+
+func (prov *Provider) Restarts() int {
+	prov.restartLock.Lock()
+	defer prov.restartLock.Unlock()
+	return prov.restarts
+}
+
+func (prov *Provider) MaxParallelism() int {
+	if prov.MaxParallelismF == nil {
+		return 0
+	}
+	return prov.MaxParallelismF()
+}
+
+func (prov *Provider) CancelOperation(urn resource.URN) {
+	if prov.CancelOperationF != nil {
+		prov.CancelOperationF(urn)
+	}
+}
+
+func (prov *Provider) ConfigureOperation(urn resource.URN, settings plugin.OperationSettings) {
+	if prov.ConfigureOperationF != nil {
+		prov.ConfigureOperationF(urn, settings)
+	}
+}
+
+func (prov *Provider) TagsProperty(t tokens.Type) resource.PropertyKey {
+	if prov.TagsPropertyF == nil {
+		return ""
+	}
+	return prov.TagsPropertyF(t)
+}
+
+func (prov *Provider) ResourceDeprecation(t tokens.Type) *plugin.Deprecation {
+	if prov.ResourceDeprecationF == nil {
+		return nil
+	}
+	return prov.ResourceDeprecationF(t)
+}
+
+func (prov *Provider) PropertyDeprecations(t tokens.Type) map[resource.PropertyKey]plugin.Deprecation {
+	if prov.PropertyDeprecationsF == nil {
+		return nil
+	}
+	return prov.PropertyDeprecationsF(t)
+}
+
+func (prov *Provider) TakeArtifacts(urn resource.URN) []plugin.ArtifactFile {
+	if prov.TakeArtifactsF == nil {
+		return nil
+	}
+	return prov.TakeArtifactsF(urn)
+}
+
+func (prov *Provider) PreviewCreate(urn resource.URN, news resource.PropertyMap) error {
+	if prov.PreviewCreateF == nil {
+		return nil
+	}
+	return prov.PreviewCreateF(urn, news)
+}
+
+func (prov *Provider) PreviewUpdate(urn resource.URN, id resource.ID, olds, news resource.PropertyMap) error {
+	if prov.PreviewUpdateF == nil {
+		return nil
+	}
+	return prov.PreviewUpdateF(urn, id, olds, news)
 }
 
 func (prov *Provider) SignalCancellation() error {
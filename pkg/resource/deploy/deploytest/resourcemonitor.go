@@ -63,7 +63,7 @@ func (rm *ResourceMonitor) RegisterResource(t tokens.Type, name string, custom b
 	}
 
 	// submit request
-	resp, err := rm.resmon.RegisterResource(context.Background(), &pulumirpc.RegisterResourceRequest{
+	return rm.RegisterResourceRaw(&pulumirpc.RegisterResourceRequest{
 		Type:                 string(t),
 		Name:                 name,
 		Custom:               custom,
@@ -78,6 +78,14 @@ func (rm *ResourceMonitor) RegisterResource(t tokens.Type, name string, custom b
 		Version:              version,
 		Aliases:              aliasStrings,
 	})
+}
+
+// RegisterResourceRaw submits the given registration request as-is. This allows tests to exercise resource options
+// that are not exposed by RegisterResource.
+func (rm *ResourceMonitor) RegisterResourceRaw(
+	req *pulumirpc.RegisterResourceRequest) (resource.URN, resource.ID, resource.PropertyMap, error) {
+
+	resp, err := rm.resmon.RegisterResource(context.Background(), req)
 	if err != nil {
 		return "", "", nil, err
 	}
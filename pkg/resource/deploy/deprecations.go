@@ -0,0 +1,121 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// DeprecatedUsage counts the resources of a plan that use a resource type or input property that their provider has
+// deprecated.
+type DeprecatedUsage struct {
+	Type        tokens.Type          // the deprecated resource type, or the type whose input property is deprecated.
+	Property    resource.PropertyKey // the deprecated input property, or "" if the resource type is deprecated.
+	Message     string               // why the type or property is deprecated, if the provider says.
+	Replacement string               // the type or property to use instead, if there is one.
+	Count       int                  // the number of resources that use the type or property.
+}
+
+// deprecationKey identifies a deprecated resource type or input property.
+type deprecationKey struct {
+	typ      tokens.Type
+	property resource.PropertyKey
+}
+
+// DeprecatedUsages returns the deprecated resource types and input properties used by the plan's resources so far,
+// sorted by type and then by property.
+func (p *Plan) DeprecatedUsages() []DeprecatedUsage {
+	p.deprecationsLock.Lock()
+	defer p.deprecationsLock.Unlock()
+
+	var usages []DeprecatedUsage
+	for _, u := range p.deprecations {
+		usages = append(usages, *u)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Type != usages[j].Type {
+			return usages[i].Type < usages[j].Type
+		}
+		return usages[i].Property < usages[j].Property
+	})
+	return usages
+}
+
+// recordDeprecatedUsage counts a use of the given deprecated resource type or input property.
+func (p *Plan) recordDeprecatedUsage(t tokens.Type, property resource.PropertyKey, d plugin.Deprecation) {
+	p.deprecationsLock.Lock()
+	defer p.deprecationsLock.Unlock()
+
+	if p.deprecations == nil {
+		p.deprecations = make(map[deprecationKey]*DeprecatedUsage)
+	}
+	key := deprecationKey{typ: t, property: property}
+	u, ok := p.deprecations[key]
+	if !ok {
+		u = &DeprecatedUsage{Type: t, Property: property, Message: d.Message, Replacement: d.Replacement}
+		p.deprecations[key] = u
+	}
+	u.Count++
+}
+
+// checkDeprecations warns if the given resource is of a type that its provider has deprecated, or sets any input
+// properties that its provider has deprecated, and records each such use in the plan.
+func (sg *stepGenerator) checkDeprecations(urn resource.URN, prov plugin.Provider, t tokens.Type,
+	inputs resource.PropertyMap) {
+
+	reporter, ok := prov.(plugin.DeprecationReporter)
+	if !ok {
+		return
+	}
+
+	if d := reporter.ResourceDeprecation(t); d != nil {
+		sg.plan.Diag().Warningf(diag.RawMessage(urn,
+			describeDeprecation(fmt.Sprintf("resource type '%s'", t), *d)))
+		sg.plan.recordDeprecatedUsage(t, "", *d)
+	}
+
+	deprecated := reporter.PropertyDeprecations(t)
+	var keys []resource.PropertyKey
+	for k := range deprecated {
+		if v, has := inputs[k]; has && !v.IsNull() {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		d := deprecated[k]
+		sg.plan.Diag().Warningf(diag.RawMessage(urn,
+			describeDeprecation(fmt.Sprintf("property '%s' of resource type '%s'", k, t), d)))
+		sg.plan.recordDeprecatedUsage(t, k, d)
+	}
+}
+
+// describeDeprecation returns a message saying that the given type or property is deprecated.
+func describeDeprecation(what string, d plugin.Deprecation) string {
+	msg := what + " is deprecated"
+	if d.Message != "" {
+		msg += ": " + d.Message
+	}
+	if d.Replacement != "" {
+		msg += fmt.Sprintf("; use '%s' instead", d.Replacement)
+	}
+	return msg
+}
@@ -17,11 +17,13 @@ package deploy
 import (
 	"context"
 	"math"
+	"sync"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 
+	"github.com/pulumi/pulumi/pkg/apitype"
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
@@ -30,6 +32,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/result"
+	"github.com/pulumi/pulumi/pkg/workspace"
 )
 
 // BackendClient provides an interface for retrieving information about other stacks.
@@ -43,15 +46,133 @@ type BackendClient interface {
 	// `Propertymap` with members `type` (containing the Pulumi type ID for the resource) and
 	// `outputs` (containing the resource outputs themselves).
 	GetStackResourceOutputs(ctx context.Context, stackName string) (resource.PropertyMap, error)
+
+	// GetStackHistory returns the updates that have been made to the named stack, most recent first, or an error if
+	// the stack cannot be found. The configuration and deployment of each update are not included.
+	GetStackHistory(ctx context.Context, name string) ([]apitype.UpdateInfo, error)
 }
 
 // Options controls the planning and deployment process.
 type Options struct {
-	Events            Events // an optional events callback interface.
-	Parallel          int    // the degree of parallelism for resource operations (<=1 for serial).
-	Refresh           bool   // whether or not to refresh before executing the plan.
-	RefreshOnly       bool   // whether or not to exit after refreshing.
-	TrustDependencies bool   // whether or not to trust the resource dependency graph.
+	Events            Events      // an optional events callback interface.
+	Parallel          int         // the degree of parallelism for resource operations (<=1 for serial).
+	Refresh           bool        // whether or not to refresh before executing the plan.
+	RefreshOnly       bool        // whether or not to exit after refreshing.
+	ImportOnly        bool        // whether or not this plan only imports resources, leaving all others untouched.
+	TrustDependencies bool        // whether or not to trust the resource dependency graph.
+	Retry             RetryPolicy // the policy for retrying steps that fail with transient provider errors.
+	AllowProtected    bool        // whether or not protected resources may be deleted or replaced.
+
+	// ProviderParallel lowers the degree of parallelism for the operations of the providers of particular packages.
+	// Each provider's operations are executed by its own pool of workers so that a slow provider cannot starve the
+	// others; by default, each pool is sized to the plan's degree of parallelism, which also bounds the number of
+	// operations executing at once across all of the pools.
+	ProviderParallel map[tokens.Package]int
+
+	// RefreshParallel is the degree of parallelism for the reads of a refresh. Reads have no side effects and do not
+	// depend on one another, so they are executed without regard to the dependency graph, in batches by provider. If
+	// zero, reads use the greater of Parallel and DefaultRefreshParallel, unless Parallel asks for serial execution.
+	RefreshParallel int
+
+	// CustomTimeouts bounds the time providers may take to create, update, or delete resources of particular types, and
+	// sets how often they poll for the completion of those operations. The settings of an individual resource take
+	// precedence over these.
+	CustomTimeouts map[tokens.Type]resource.CustomTimeouts
+
+	// DeleteBeforeReplace lists the types of resources that must be deleted before their replacements are created,
+	// as if each such resource had requested it. Resources that depend on a replaced resource and would themselves
+	// be replaced are deleted first.
+	DeleteBeforeReplace map[tokens.Type]bool
+
+	// PropertyChangeGuards inspect each planned change to a resource's input properties, and may flag or veto it.
+	PropertyChangeGuards []PropertyChangeGuard
+
+	// Transformations rewrite the goal state of each resource that the program registers, in order, before the
+	// resource is planned.
+	Transformations []ResourceTransformation
+
+	// Budget, if non-nil, is a semaphore shared with other plans that bounds the number of steps executing at once
+	// across all of them. A step holds a slot in the budget for as long as it is executing.
+	Budget chan struct{}
+
+	// Prune selects how resources that are in the snapshot but no longer produced by the program are handled. By
+	// default they are deleted like any other resource that the program removes.
+	Prune PruneMode
+
+	// PruneExempt lists the URNs and types of resources that are intentionally left unmanaged by the program, and so
+	// are neither reported nor deleted when pruning.
+	PruneExempt []string
+
+	// Exclude selects resources that are never deleted for want of being produced by the program: they, and the
+	// resources upon which they depend, are left in place. This lets a destroy delete most of a stack while preserving
+	// stateful resources such as databases.
+	Exclude []resource.URNPattern
+
+	// ExcludeDependents, if set, also leaves in place the resources that depend on those that Exclude selects, whether
+	// directly or indirectly, and whether by their dependencies, parents, or providers.
+	ExcludeDependents bool
+
+	// CheckCache, if set, caches the results of the checks that previews make, so that resources whose dependency
+	// closures are unchanged since an earlier preview are not checked again. Experimental; see CheckCache.
+	CheckCache *CheckCache
+
+	// DefaultTags are applied to the inputs of every resource whose provider supports default tags (see
+	// plugin.DefaultTagger), and so are recorded in the resource's state and diffed like any other input.
+	DefaultTags map[string]string
+
+	// Artifacts stores the contents of the artifacts that providers attach to the resources they create and update.
+	// If nil, such artifacts are dropped with a warning.
+	Artifacts ArtifactStore
+
+	// ValidatePreview, if set, asks the providers that support it (see plugin.PreviewValidator) to validate each create
+	// and update that a preview plans, so that errors the providers' APIs would report are surfaced by the preview.
+	// Creates and updates whose inputs are not yet known cannot be validated, and are skipped.
+	ValidatePreview bool
+
+	// ExpectNoChanges, if set, fails the plan as soon as it generates a step that changes a resource, reporting the
+	// resource and the properties that would change, rather than planning the remaining resources. It is meant for
+	// previews that detect drift, which only need to know whether anything has changed.
+	ExpectNoChanges bool
+
+	// HotReload, if set, tells the language host that the program may be run again, e.g. by the next iteration of a
+	// watch, so that a host that supports hot reloading can keep the program's interpreter alive and re-evaluate the
+	// program within it next time. The language host is left running once the program completes, for the plugin host
+	// to close. ChangedPaths holds the paths of the program's files that changed since the previous such run.
+	HotReload    bool
+	ChangedPaths []string
+}
+
+// PruneMode selects how a plan handles resources that are no longer produced by the program.
+type PruneMode string
+
+const (
+	// PruneOff deletes resources that are no longer produced by the program with ordinary delete steps.
+	PruneOff PruneMode = ""
+	// PruneReport reports resources that are no longer produced by the program with prune steps, but leaves them in
+	// place and in the snapshot.
+	PruneReport PruneMode = "report"
+	// PruneDelete deletes resources that are no longer produced by the program with prune steps.
+	PruneDelete PruneMode = "delete"
+)
+
+// isPruneExempt returns true if the given resource is exempt from pruning by its URN or its type.
+func (o Options) isPruneExempt(res *resource.State) bool {
+	for _, exempt := range o.PruneExempt {
+		if exempt == string(res.URN) || exempt == string(res.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcluded returns true if the given resource matches one of the Exclude patterns.
+func (o Options) isExcluded(res *resource.State) bool {
+	for _, pattern := range o.Exclude {
+		if pattern.Matches(res.URN) {
+			return true
+		}
+	}
+	return false
 }
 
 // DegreeOfParallelism returns the degree of parallelism that should be used during the
@@ -68,11 +189,26 @@ func (o Options) InfiniteParallelism() bool {
 	return o.Parallel == math.MaxInt32
 }
 
+// RefreshDegreeOfParallelism returns the degree of parallelism that should be used for the reads of a refresh.
+func (o Options) RefreshDegreeOfParallelism() int {
+	switch {
+	case o.RefreshParallel > 0:
+		return o.RefreshParallel
+	case o.InfiniteParallelism() || o.DegreeOfParallelism() <= 1 || o.Parallel >= DefaultRefreshParallel:
+		return o.DegreeOfParallelism()
+	default:
+		return DefaultRefreshParallel
+	}
+}
+
 // StepExecutorEvents is an interface that can be used to hook resource lifecycle events.
 type StepExecutorEvents interface {
 	OnResourceStepPre(step Step) (interface{}, error)
 	OnResourceStepPost(ctx interface{}, step Step, status resource.Status, err error) error
+	OnResourceStepProgress(step Step, percent int, message string)
 	OnResourceOutputs(step Step) error
+	OnProviderRestart(step Step, err error)
+	OnResourceStepRetry(step Step, attempt int, err error)
 }
 
 // PolicyEvents is an interface that can be used to hook policy violation events.
@@ -80,10 +216,31 @@ type PolicyEvents interface {
 	OnPolicyViolation(resource.URN, plugin.AnalyzeDiagnostic)
 }
 
+// RefreshEvents is an interface that can be used to hook the progress of a refresh.
+type RefreshEvents interface {
+	OnRefreshProgress(progress RefreshProgress)
+}
+
+// DeleteEvents is an interface that can be used to hook the scheduling of deletes.
+type DeleteEvents interface {
+	// OnDeletesScheduled is called with the batches of steps that delete resources the program no longer produces,
+	// in the order in which they execute, before any of them begins. The steps of each batch run in parallel.
+	OnDeletesScheduled(batches [][]Step)
+}
+
+// ProviderEvents is an interface that can be used to hook planned changes to providers' configuration.
+type ProviderEvents interface {
+	// OnProviderChange is called when a step that updates or replaces a provider is planned, before it is executed.
+	OnProviderChange(change ProviderChange)
+}
+
 // Events is an interface that can be used to hook interesting engine/planning events.
 type Events interface {
 	StepExecutorEvents
 	PolicyEvents
+	RefreshEvents
+	DeleteEvents
+	ProviderEvents
 }
 
 // PlanPendingOperationsError is an error returned from `NewPlan` if there exist pending operations in the
@@ -111,6 +268,18 @@ type Plan struct {
 	preview   bool                             // true if this plan is to be previewed rather than applied.
 	depGraph  *graph.DependencyGraph           // the dependency graph of the old snapshot
 	providers *providers.Registry              // the provider registry for this plan.
+
+	allowProtected bool          // true if protected resources may be deleted or replaced.
+	artifacts      ArtifactStore // the store for the artifacts that providers produce, if any.
+
+	// execCtx is canceled when the plan's current execution stops, and typeTimeouts holds the custom timeouts of each
+	// type for that execution. Steps that wait on their own, e.g. for resources to become ready, consult both. They
+	// are set by the step executor before any steps are applied.
+	execCtx      context.Context
+	typeTimeouts map[tokens.Type]resource.CustomTimeouts
+
+	deprecationsLock sync.Mutex                          // protects deprecations.
+	deprecations     map[deprecationKey]*DeprecatedUsage // the deprecated types and properties used by the plan.
 }
 
 // addDefaultProviders adds any necessary default provider definitions and references to the given snapshot. Version
@@ -238,7 +407,7 @@ func NewPlan(ctx *plugin.Context, target *Target, prev *Snapshot, source Source,
 	}
 
 	// Create a new builtin provider. This provider implements features such as `getStack`.
-	builtins := newBuiltinProvider(backendClient)
+	builtins := newBuiltinProvider(backendClient, ctx.Diag)
 
 	// Create a new provider registry. Although we really only need to pass in any providers that were present in the
 	// old resource list, the registry itself will filter out other sorts of resources when processing the prior state,
@@ -268,6 +437,11 @@ func (p *Plan) Prev() *Snapshot                        { return p.prev }
 func (p *Plan) Olds() map[resource.URN]*resource.State { return p.olds }
 func (p *Plan) Source() Source                         { return p.source }
 
+// ProviderPlugins returns the name and version of the plugin that serves each of the plan's configured providers.
+func (p *Plan) ProviderPlugins() ([]workspace.PluginInfo, error) {
+	return p.providers.Plugins()
+}
+
 func (p *Plan) GetProvider(ref providers.Reference) (plugin.Provider, bool) {
 	return p.providers.GetProvider(ref)
 }
@@ -310,6 +484,9 @@ func (p *Plan) generateEventURN(event SourceEvent) resource.URN {
 // Execute executes a plan to completion, using the given cancellation context and running a preview
 // or update.
 func (p *Plan) Execute(ctx context.Context, opts Options, preview bool) result.Result {
+	p.allowProtected = opts.AllowProtected
+	p.artifacts = opts.Artifacts
+
 	planExec := &planExecutor{plan: p}
 	return planExec.Execute(ctx, opts, preview)
 }
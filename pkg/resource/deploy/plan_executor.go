@@ -104,6 +104,14 @@ func (pe *planExecutor) Execute(callerCtx context.Context, opts Options, preview
 	// Set up a step generator and executor for this plan.
 	pe.stepExec = newStepExecutor(ctx, cancel, pe.plan, opts, preview, false)
 
+	// An import leaves the existing resources untouched, but imported resources may use them as providers or parents.
+	// Carry them over before importing anything so that they precede the imported resources in the new snapshot.
+	if opts.ImportOnly {
+		if sames := pe.stepGen.GenerateCarryOverSteps(); len(sames) > 0 {
+			pe.stepExec.ExecuteSerial(sames).Wait(ctx)
+		}
+	}
+
 	// We iterate the source in its own goroutine because iteration is blocking and we want the main loop to be able to
 	// respond to cancellation requests promptly.
 	type nextEvent struct {
@@ -152,28 +160,38 @@ func (pe *planExecutor) Execute(callerCtx context.Context, opts Options, preview
 				}
 
 				if event.Event == nil {
-					deleteSteps := pe.stepGen.GenerateDeletes()
+					// An import only adds resources to the stack, so none of the existing resources are deleted.
+					var deleteSteps []Step
+					if !opts.ImportOnly {
+						var res result.Result
+						if deleteSteps, res = pe.stepGen.GenerateDeletes(); res != nil {
+							logging.V(4).Infof("planExecutor.Execute(...): failed to generate deletes")
+							cancel()
+							return false, result.Bail()
+						}
+					}
+					if pe.checkNoChanges(opts, deleteSteps) {
+						cancel()
+						return false, result.Bail()
+					}
 					deletes := pe.stepGen.ScheduleDeletes(deleteSteps)
-
-					// ScheduleDeletes gives us a list of lists of steps. Each list of steps can safely be executed in
-					// parallel, but each list must execute completes before the next list can safely begin executing.
-					//
-					// This is not "true" delete parallelism, since there may be resources that could safely begin
-					// deleting but we won't until the previous set of deletes fully completes. This approximation is
-					// conservative, but correct.
-					for _, antichain := range deletes {
-						logging.V(4).Infof("planExecutor.Execute(...): beginning delete antichain")
-						tok := pe.stepExec.ExecuteParallel(antichain)
-						tok.Wait(ctx)
-						logging.V(4).Infof("planExecutor.Execute(...): antichain complete")
+					if opts.Events != nil && len(deletes) > 0 {
+						opts.Events.OnDeletesScheduled(deletes)
 					}
 
+					// Each delete may begin as soon as the deletes of the resources that depend on it have completed,
+					// rather than waiting for the whole of the antichain that ScheduleDeletes put it in.
+					logging.V(4).Infof("planExecutor.Execute(...): beginning %d deletes", len(deleteSteps))
+					tok := pe.stepExec.ExecuteDAG(deleteSteps, pe.stepGen.DeleteDependencies(deleteSteps))
+					tok.Wait(ctx)
+					logging.V(4).Infof("planExecutor.Execute(...): deletes complete")
+
 					// We're done here - signal completion so that the step executor knows to terminate.
 					pe.stepExec.SignalCompletion()
 					return false, nil
 				}
 
-				if res := pe.handleSingleEvent(event.Event); res != nil {
+				if res := pe.handleSingleEvent(opts, event.Event); res != nil {
 					if resErr := res.Error(); resErr != nil {
 						logging.V(4).Infof("planExecutor.Execute(...): error handling event: %v", resErr)
 						pe.reportError(pe.plan.generateEventURN(event.Event), resErr)
@@ -209,12 +227,17 @@ func (pe *planExecutor) Execute(callerCtx context.Context, opts Options, preview
 		return result.Bail()
 	}
 
+	// A preview that ran to completion used every check cache entry that its program still needs.
+	if preview && opts.CheckCache != nil {
+		opts.CheckCache.sweep()
+	}
+
 	return res
 }
 
 // handleSingleEvent handles a single source event. For all incoming events, it produces a chain that needs
 // to be executed and schedules the chain for execution.
-func (pe *planExecutor) handleSingleEvent(event SourceEvent) result.Result {
+func (pe *planExecutor) handleSingleEvent(opts Options, event SourceEvent) result.Result {
 	contract.Require(event != nil, "event != nil")
 
 	var steps []Step
@@ -236,6 +259,12 @@ func (pe *planExecutor) handleSingleEvent(event SourceEvent) result.Result {
 		return res
 	}
 
+	// A plan that expects no changes fails before executing a step that would make one.
+	if pe.checkNoChanges(opts, steps) {
+		return result.Bail()
+	}
+
+	pe.reportProviderChanges(opts, steps)
 	pe.stepExec.ExecuteSerial(steps)
 	return nil
 }
@@ -256,17 +285,14 @@ func (pe *planExecutor) retirePendingDeletes(callerCtx context.Context, opts Opt
 	ctx, cancel := context.WithCancel(callerCtx)
 
 	stepExec := newStepExecutor(ctx, cancel, pe.plan, opts, preview, false)
-	antichains := pe.stepGen.ScheduleDeletes(steps)
-	// Submit the deletes for execution and wait for them all to retire.
-	for _, antichain := range antichains {
-		for _, step := range antichain {
-			pe.plan.Ctx().StatusDiag.Infof(diag.RawMessage(step.URN(), "completing deletion from previous update"))
-		}
-
-		tok := stepExec.ExecuteParallel(antichain)
-		tok.Wait(ctx)
+	for _, step := range steps {
+		pe.plan.Ctx().StatusDiag.Infof(diag.RawMessage(step.URN(), "completing deletion from previous update"))
 	}
 
+	// Submit the deletes for execution and wait for them all to retire.
+	tok := stepExec.ExecuteDAG(steps, pe.stepGen.DeleteDependencies(steps))
+	tok.Wait(ctx)
+
 	stepExec.SignalCompletion()
 	stepExec.WaitForCompletion()
 
@@ -296,10 +322,19 @@ func (pe *planExecutor) refresh(callerCtx context.Context, opts Options, preview
 		steps[i] = NewRefreshStep(pe.plan, prev.Resources[i], nil)
 	}
 
-	// Fire up a worker pool and issue each refresh in turn.
+	// Fire up a worker pool and issue every read at once, in batches by provider. Reads have no side effects and do
+	// not depend on one another, so they may run with a higher degree of parallelism than other operations.
+	refreshOpts := opts
+	refreshOpts.Parallel = opts.RefreshDegreeOfParallelism()
+	if opts.Events != nil {
+		refreshOpts.Events = newRefreshProgressEvents(opts.Events, steps)
+	}
 	ctx, cancel := context.WithCancel(callerCtx)
-	stepExec := newStepExecutor(ctx, cancel, pe.plan, opts, preview, true)
-	stepExec.ExecuteParallel(steps)
+	stepExec := newStepExecutor(ctx, cancel, pe.plan, refreshOpts, preview, true)
+	for _, batch := range batchRefreshSteps(steps) {
+		logging.V(7).Infof("planExecutor.refresh(...): issuing a batch of %d refresh steps", len(batch))
+		stepExec.ExecuteParallel(batch)
+	}
 	stepExec.SignalCompletion()
 	stepExec.WaitForCompletion()
 
@@ -0,0 +1,123 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// PropertyChange describes a planned change to the value of one of a resource's input properties.
+type PropertyChange struct {
+	URN  resource.URN           // the resource whose property changes.
+	Path resource.PropertyPath  // the path to the property that changes.
+	Old  resource.PropertyValue // the property's old value, or null if the property is being added.
+	New  resource.PropertyValue // the property's new value (unknown during previews, if not yet computed), or null.
+}
+
+// PropertyChangeVerdict is a PropertyChangeGuard's judgement of a planned property change.
+type PropertyChangeVerdict int
+
+const (
+	// PropertyChangeAllowed permits the change without comment.
+	PropertyChangeAllowed PropertyChangeVerdict = iota
+	// PropertyChangeFlagged permits the change, but issues a warning about it.
+	PropertyChangeFlagged
+	// PropertyChangeVetoed forbids the change, which causes the plan to fail.
+	PropertyChangeVetoed
+)
+
+// PropertyChangeGuard inspects a planned property change and returns its verdict, along with a message that explains
+// any verdict other than PropertyChangeAllowed. Guards are called for every property that a create, update, or
+// replacement would change, including those nested within objects and arrays, and are called during previews as well
+// as updates. Guards may be called concurrently.
+type PropertyChangeGuard func(change PropertyChange) (PropertyChangeVerdict, string)
+
+// propertyChanges returns the changes between the given old and new property maps, at the deepest paths at which
+// they differ. Arrays whose lengths differ are reported as a whole.
+func propertyChanges(urn resource.URN, olds, news resource.PropertyMap) []PropertyChange {
+	var changes []PropertyChange
+	var visit func(path resource.PropertyPath, old, new resource.PropertyValue)
+	visit = func(path resource.PropertyPath, old, new resource.PropertyValue) {
+		switch {
+		case old.DeepEquals(new):
+			return
+		case old.IsObject() && new.IsObject():
+			visitObject(path, old.ObjectValue(), new.ObjectValue(), visit)
+			return
+		case old.IsArray() && new.IsArray() && len(old.ArrayValue()) == len(new.ArrayValue()):
+			for i := range old.ArrayValue() {
+				visit(appendPropertyPath(path, i), old.ArrayValue()[i], new.ArrayValue()[i])
+			}
+			return
+		}
+		changes = append(changes, PropertyChange{URN: urn, Path: path, Old: old, New: new})
+	}
+	visitObject(nil, olds, news, visit)
+	return changes
+}
+
+// visitObject calls visit for each key of the given old and new objects, in a stable order.
+func visitObject(path resource.PropertyPath, olds, news resource.PropertyMap,
+	visit func(path resource.PropertyPath, old, new resource.PropertyValue)) {
+
+	keys := make(resource.PropertyMap, len(olds)+len(news))
+	for k := range olds {
+		keys[k] = resource.NewNullProperty()
+	}
+	for k := range news {
+		keys[k] = resource.NewNullProperty()
+	}
+	for _, k := range keys.StableKeys() {
+		old, new := olds[k], news[k]
+		if old.V == nil {
+			old = resource.NewNullProperty()
+		}
+		if new.V == nil {
+			new = resource.NewNullProperty()
+		}
+		visit(appendPropertyPath(path, string(k)), old, new)
+	}
+}
+
+// appendPropertyPath returns a new path that extends the given path with the given key or index.
+func appendPropertyPath(path resource.PropertyPath, elem interface{}) resource.PropertyPath {
+	result := make(resource.PropertyPath, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, elem)
+}
+
+// checkPropertyChangeGuards runs the plan's property change guards over the changes between the given old and new
+// inputs, warning about flagged changes and reporting vetoed ones as errors. It returns true if any change was vetoed.
+func (sg *stepGenerator) checkPropertyChangeGuards(urn resource.URN, olds, news resource.PropertyMap) bool {
+	if len(sg.opts.PropertyChangeGuards) == 0 {
+		return false
+	}
+
+	vetoed := false
+	for _, change := range propertyChanges(urn, olds, news) {
+		for _, guard := range sg.opts.PropertyChangeGuards {
+			verdict, message := guard(change)
+			switch verdict {
+			case PropertyChangeFlagged:
+				sg.plan.Diag().Warningf(diag.Message(urn, "change to %s: %s"), change.Path, message)
+			case PropertyChangeVetoed:
+				sg.plan.Diag().Errorf(diag.Message(urn, "change to %s is not allowed: %s"), change.Path, message)
+				vetoed = true
+			}
+		}
+	}
+	return vetoed
+}
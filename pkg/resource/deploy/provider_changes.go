@@ -0,0 +1,86 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+)
+
+// ProviderChange describes a planned change to the configuration of a provider, e.g. to its credentials or region.
+type ProviderChange struct {
+	URN resource.URN // the provider whose configuration changes.
+	Op  StepOp       // OpUpdate if the provider is updated in place, or OpReplace if it is replaced.
+	// the configuration keys that change, and those whose changes force the provider to be replaced.
+	Keys        []resource.PropertyKey
+	ReplaceKeys []resource.PropertyKey
+	// the resources that the provider managed as of the last deployment. If the provider is replaced, each of them is
+	// replaced as well, unless the old and new providers are both default providers and the new one reports that it
+	// can still manage them.
+	Dependents []resource.URN
+}
+
+// Replaced returns true if the change replaces the provider, and so may cascade into replacements of its dependents.
+func (c ProviderChange) Replaced() bool {
+	return c.Op == OpReplace
+}
+
+// providerChanges returns the changes to providers' configuration made by those of the given steps that update or
+// replace a provider. The dependents of each provider are found among the given old resources.
+func providerChanges(steps []Step, olds []*resource.State) []ProviderChange {
+	var changes []ProviderChange
+	for _, step := range steps {
+		if !providers.IsProviderType(step.Type()) || step.Old() == nil {
+			continue
+		}
+
+		change := ProviderChange{URN: step.URN(), Op: step.Op()}
+		switch s := step.(type) {
+		case *UpdateStep:
+			change.Keys = s.Diffs()
+		case *ReplaceStep:
+			change.Keys, change.ReplaceKeys = s.Diffs(), s.Keys()
+		default:
+			continue
+		}
+
+		ref, err := providers.NewReference(step.Old().URN, step.Old().ID)
+		if err != nil {
+			continue
+		}
+		for _, res := range olds {
+			if res.Provider == ref.String() && !res.Delete {
+				change.Dependents = append(change.Dependents, res.URN)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// reportProviderChanges reports the changes to providers' configuration made by the given steps, before the steps
+// are executed.
+func (pe *planExecutor) reportProviderChanges(opts Options, steps []Step) {
+	if opts.Events == nil {
+		return
+	}
+	var olds []*resource.State
+	if pe.plan.prev != nil {
+		olds = pe.plan.prev.Resources
+	}
+	for _, change := range providerChanges(steps, olds) {
+		opts.Events.OnProviderChange(change)
+	}
+}
@@ -16,6 +16,7 @@ package providers
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/blang/semver"
@@ -149,6 +150,57 @@ func (r *Registry) GetProvider(ref Reference) (plugin.Provider, bool) {
 	return provider, ok
 }
 
+// Len returns the number of distinct providers in the registry. A provider registered under more than one reference,
+// e.g. before and after it is created, is counted once.
+func (r *Registry) Len() int {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	urns := make(map[resource.URN]bool)
+	for ref := range r.providers {
+		urns[ref.URN()] = true
+	}
+	return len(urns)
+}
+
+// Plugins returns the name and version of the plugin that serves each configured provider in the registry, sorted by
+// name and version and without duplicates.
+func (r *Registry) Plugins() ([]workspace.PluginInfo, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	seen := make(map[string]bool)
+	var plugins []workspace.PluginInfo
+	for ref, provider := range r.providers {
+		if ref.ID() == UnknownID {
+			continue
+		}
+		info, err := provider.GetPluginInfo()
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting the plugin info of provider '%v'", ref)
+		}
+		if info.Version == nil {
+			continue
+		}
+		name := string(GetProviderPackage(ref.URN().Type()))
+		if key := name + "@" + info.Version.String(); !seen[key] {
+			seen[key] = true
+			plugins = append(plugins, workspace.PluginInfo{
+				Name:    name,
+				Kind:    workspace.ResourcePlugin,
+				Version: info.Version,
+			})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		if plugins[i].Name != plugins[j].Name {
+			return plugins[i].Name < plugins[j].Name
+		}
+		return plugins[i].Version.LT(*plugins[j].Version)
+	})
+	return plugins, nil
+}
+
 func (r *Registry) setProvider(ref Reference, provider plugin.Provider) {
 	r.m.Lock()
 	defer r.m.Unlock()
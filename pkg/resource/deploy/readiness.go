@@ -0,0 +1,240 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// readinessPollInterval is the time to wait between checks of a resource's readiness conditions.
+var readinessPollInterval = 5 * time.Second
+
+// readinessTimeout is the time a resource has to satisfy its readiness conditions once it has been created or updated,
+// unless its custom timeout for the operation, or that of its type, says otherwise.
+var readinessTimeout = 10 * time.Minute
+
+// healthCondition is the readiness condition that is satisfied once the resource's provider reports it as healthy.
+const healthCondition = "health"
+
+// readinessCondition is a condition that a resource must satisfy after it has been created or updated before the
+// step that did so completes and the resource's dependents are allowed to use it. A condition is either a health
+// check, written `health`, which asks the resource's provider whether the resource is healthy, or a predicate over one
+// of the resource's output properties. A predicate is either a property path such as `status`, which is satisfied if
+// the output is present and is not null, false, or empty, or a comparison of a property path with a JSON value such as
+// `status == "ACTIVE"` or `status != "PENDING"`.
+type readinessCondition struct {
+	text  string                 // the condition as written.
+	path  resource.PropertyPath  // the output property to inspect, or nil for a health check.
+	op    string                 // the comparison to perform: "", "==", or "!=".
+	value resource.PropertyValue // the value to compare the output against.
+}
+
+// parseReadinessCondition parses a single readiness condition.
+func parseReadinessCondition(text string) (readinessCondition, error) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == healthCondition {
+		return readinessCondition{text: text}, nil
+	}
+
+	// Split the condition at the first comparison operator, if any.
+	cond, lhs, at := readinessCondition{text: text}, trimmed, -1
+	for _, op := range []string{"==", "!="} {
+		if i := strings.Index(trimmed, op); i != -1 && (at == -1 || i < at) {
+			cond.op, at = op, i
+		}
+	}
+	if at != -1 {
+		lhs = strings.TrimSpace(trimmed[:at])
+
+		var v interface{}
+		rhs := strings.TrimSpace(trimmed[at+len(cond.op):])
+		if err := json.Unmarshal([]byte(rhs), &v); err != nil {
+			return readinessCondition{}, errors.Errorf("invalid value %q in condition %q: expected a JSON value",
+				rhs, text)
+		}
+		cond.value = resource.NewPropertyValue(v)
+	}
+
+	path, err := resource.ParsePropertyPath(lhs)
+	if err != nil {
+		return readinessCondition{}, errors.Wrapf(err, "invalid condition %q", text)
+	}
+	cond.path = path
+	return cond, nil
+}
+
+// parseReadinessConditions parses each of the given readiness conditions.
+func parseReadinessConditions(texts []string) ([]readinessCondition, error) {
+	conds := make([]readinessCondition, len(texts))
+	for i, text := range texts {
+		cond, err := parseReadinessCondition(text)
+		if err != nil {
+			return nil, err
+		}
+		conds[i] = cond
+	}
+	return conds, nil
+}
+
+// satisfiedBy returns true if the given outputs satisfy this condition. Health checks are not evaluated here.
+func (c readinessCondition) satisfiedBy(outputs resource.PropertyMap) bool {
+	v, ok := c.path.Get(outputs)
+	if !ok || v.IsComputed() || v.IsOutput() {
+		return false
+	}
+	if v.IsSecret() {
+		v = v.SecretValue().Element
+	}
+
+	switch c.op {
+	case "==":
+		return v.DeepEquals(c.value)
+	case "!=":
+		return !v.IsNull() && !v.DeepEquals(c.value)
+	default:
+		switch {
+		case v.IsNull():
+			return false
+		case v.IsBool():
+			return v.BoolValue()
+		case v.IsString():
+			return v.StringValue() != ""
+		default:
+			return true
+		}
+	}
+}
+
+// awaitReadiness waits for the given resource, newly created or updated by the given operation, to satisfy the
+// readiness conditions of the registration that produced it, if any. The wait is bounded by the resource's custom
+// timeout for the operation, and ends early if the plan is canceled. The resource's outputs are updated with those
+// that satisfied the conditions. If the conditions are not satisfied, the resource records the failure as an
+// initialization error, so that the next update will try again.
+func awaitReadiness(plan *Plan, prov plugin.Provider, reg RegisterResourceEvent, new *resource.State,
+	op StepOp) error {
+
+	if reg == nil || reg.Goal() == nil || len(reg.Goal().WaitFor) == 0 {
+		return nil
+	}
+	conds, err := parseReadinessConditions(reg.Goal().WaitFor)
+	if err != nil {
+		return err
+	}
+
+	ctx, timeout := context.Background(), readinessTimeout
+	if plan != nil {
+		if plan.execCtx != nil {
+			ctx = plan.execCtx
+		}
+		timeouts := new.CustomTimeouts.WithDefaults(plan.typeTimeouts[new.Type])
+		if t := timeouts.Create; op == OpCreate && t > 0 {
+			timeout = t
+		} else if t := timeouts.Update; op == OpUpdate && t > 0 {
+			timeout = t
+		}
+	}
+
+	outputs, err := waitForReadiness(ctx, prov, new.URN, new.ID, new.Inputs, new.Outputs, conds, timeout)
+	new.Outputs = outputs
+	if err != nil {
+		new.InitErrors = append(new.InitErrors, err.Error())
+		return err
+	}
+	return nil
+}
+
+// waitForReadiness waits for the given resource to satisfy all of the given readiness conditions, refreshing its
+// outputs from its provider between checks. It returns the outputs that satisfied the conditions, or an error if they
+// were not satisfied within the given timeout or before the given context was canceled.
+func waitForReadiness(ctx context.Context, prov plugin.Provider, urn resource.URN, id resource.ID,
+	inputs, outputs resource.PropertyMap, conds []readinessCondition,
+	timeout time.Duration) (resource.PropertyMap, error) {
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		unmet, err := unmetReadinessConditions(prov, urn, id, outputs, conds)
+		if err != nil {
+			return outputs, err
+		}
+		if len(unmet) == 0 {
+			return outputs, nil
+		}
+		logging.V(7).Infof("waitForReadiness(%s): waiting for %v", urn, unmet)
+
+		poll := time.NewTimer(readinessPollInterval)
+		select {
+		case <-poll.C:
+		case <-deadline.C:
+			poll.Stop()
+			return outputs, errors.Errorf("resource did not become ready within %v; waiting for %s", timeout,
+				strings.Join(unmet, ", "))
+		case <-ctx.Done():
+			poll.Stop()
+			return outputs, errors.Errorf("canceled while waiting for the resource to become ready; waiting for %s",
+				strings.Join(unmet, ", "))
+		}
+
+		result, _, err := prov.Read(urn, id, inputs, outputs)
+		if err != nil {
+			return outputs, errors.Wrap(err, "refreshing resource while waiting for it to become ready")
+		}
+		if result.Outputs == nil {
+			return outputs, errors.New("resource no longer exists")
+		}
+		outputs = result.Outputs
+	}
+}
+
+// unmetReadinessConditions returns the text of each of the given conditions that the resource does not yet satisfy. It
+// returns an error if a condition can never be satisfied, i.e. if a health check is required of a provider that is
+// unable to check the health of its resources.
+func unmetReadinessConditions(prov plugin.Provider, urn resource.URN, id resource.ID, outputs resource.PropertyMap,
+	conds []readinessCondition) ([]string, error) {
+
+	var unmet []string
+	for _, c := range conds {
+		if c.path != nil {
+			if !c.satisfiedBy(outputs) {
+				unmet = append(unmet, c.text)
+			}
+			continue
+		}
+
+		checker, ok := prov.(plugin.HealthChecker)
+		if !ok {
+			return nil, errors.Errorf("condition %q cannot be satisfied: %v", c.text, plugin.ErrHealthCheckUnsupported)
+		}
+		err := checker.CheckHealth(urn, id, outputs)
+		if err == plugin.ErrHealthCheckUnsupported {
+			return nil, errors.Errorf("condition %q cannot be satisfied: %v", c.text, err)
+		}
+		if err != nil {
+			logging.V(7).Infof("waitForReadiness(%s): health check failed: %v", urn, err)
+			unmet = append(unmet, fmt.Sprintf("%s (%v)", c.text, err))
+		}
+	}
+	return unmet, nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestParseReadinessCondition(t *testing.T) {
+	cond, err := parseReadinessCondition("health")
+	assert.NoError(t, err)
+	assert.Nil(t, cond.path)
+
+	cond, err = parseReadinessCondition("status.phase")
+	assert.NoError(t, err)
+	assert.Equal(t, resource.PropertyPath{"status", "phase"}, cond.path)
+	assert.Equal(t, "", cond.op)
+
+	cond, err = parseReadinessCondition(`status != "=="`)
+	assert.NoError(t, err)
+	assert.Equal(t, resource.PropertyPath{"status"}, cond.path)
+	assert.Equal(t, "!=", cond.op)
+	assert.Equal(t, resource.NewStringProperty("=="), cond.value)
+
+	cond, err = parseReadinessCondition("replicas[0] == 3")
+	assert.NoError(t, err)
+	assert.Equal(t, resource.PropertyPath{"replicas", 0}, cond.path)
+	assert.Equal(t, resource.NewNumberProperty(3), cond.value)
+
+	_, err = parseReadinessCondition("status == ACTIVE")
+	assert.Error(t, err)
+	_, err = parseReadinessCondition("status[ == 1")
+	assert.Error(t, err)
+}
+
+func TestReadinessConditionSatisfiedBy(t *testing.T) {
+	outputs := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"status":  "ACTIVE",
+		"empty":   "",
+		"ready":   false,
+		"count":   2,
+		"nothing": nil,
+	})
+	outputs["secret"] = resource.MakeSecret(resource.NewStringProperty("ACTIVE"))
+
+	cases := map[string]bool{
+		"status":               true,
+		"empty":                false,
+		"ready":                false,
+		"count":                true,
+		"nothing":              false,
+		"missing":              false,
+		`status == "ACTIVE"`:   true,
+		`status != "ACTIVE"`:   false,
+		`status != "PENDING"`:  true,
+		`missing != "PENDING"`: false,
+		"count == 2":           true,
+		`secret == "ACTIVE"`:   true,
+	}
+	for text, expected := range cases {
+		cond, err := parseReadinessCondition(text)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, cond.satisfiedBy(outputs), text)
+	}
+}
+
+func TestWaitForReadiness(t *testing.T) {
+	oldInterval := readinessPollInterval
+	readinessPollInterval = 0
+	defer func() { readinessPollInterval = oldInterval }()
+
+	reads, checks := 0, 0
+	prov := &deploytest.Provider{
+		ReadF: func(urn resource.URN, id resource.ID,
+			inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+			reads++
+			status := "PENDING"
+			if reads >= 2 {
+				status = "ACTIVE"
+			}
+			return plugin.ReadResult{
+				Outputs: resource.PropertyMap{"status": resource.NewStringProperty(status)},
+			}, resource.StatusOK, nil
+		},
+		CheckHealthF: func(urn resource.URN, id resource.ID, state resource.PropertyMap) error {
+			checks++
+			if checks < 4 {
+				return errors.New("not yet")
+			}
+			return nil
+		},
+	}
+
+	conds, err := parseReadinessConditions([]string{`status == "ACTIVE"`, "health"})
+	assert.NoError(t, err)
+
+	outputs := resource.PropertyMap{"status": resource.NewStringProperty("CREATING")}
+	outputs, err = waitForReadiness(context.Background(), prov, "urn", "id", resource.PropertyMap{}, outputs, conds,
+		readinessTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, resource.NewStringProperty("ACTIVE"), outputs["status"])
+	assert.Equal(t, 3, reads)
+	assert.Equal(t, 4, checks)
+}
+
+func TestWaitForReadinessTimeout(t *testing.T) {
+	oldInterval := readinessPollInterval
+	readinessPollInterval = time.Millisecond
+	defer func() { readinessPollInterval = oldInterval }()
+
+	prov := &deploytest.Provider{
+		ReadF: func(urn resource.URN, id resource.ID,
+			inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+			return plugin.ReadResult{Outputs: state}, resource.StatusOK, nil
+		},
+	}
+
+	conds, err := parseReadinessConditions([]string{`status == "ACTIVE"`})
+	assert.NoError(t, err)
+
+	outputs := resource.PropertyMap{"status": resource.NewStringProperty("PENDING")}
+	_, err = waitForReadiness(context.Background(), prov, "urn", "id", resource.PropertyMap{}, outputs, conds,
+		10*time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "within 10ms")
+		assert.Contains(t, err.Error(), `status == "ACTIVE"`)
+	}
+}
+
+func TestWaitForReadinessCanceled(t *testing.T) {
+	prov := &deploytest.Provider{
+		ReadF: func(urn resource.URN, id resource.ID,
+			inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+			return plugin.ReadResult{Outputs: state}, resource.StatusOK, nil
+		},
+	}
+
+	conds, err := parseReadinessConditions([]string{`status == "ACTIVE"`})
+	assert.NoError(t, err)
+
+	// The wait must end as soon as the context is canceled, rather than once the poll interval or timeout elapses.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	outputs := resource.PropertyMap{"status": resource.NewStringProperty("PENDING")}
+	_, err = waitForReadiness(ctx, prov, "urn", "id", resource.PropertyMap{}, outputs, conds, readinessTimeout)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "canceled")
+	}
+}
+
+func TestWaitForReadinessHealthUnsupported(t *testing.T) {
+	conds, err := parseReadinessConditions([]string{"health"})
+	assert.NoError(t, err)
+
+	// A provider that cannot check the health of its resources can never satisfy the condition.
+	_, err = waitForReadiness(context.Background(), &deploytest.Provider{}, "urn", "id", resource.PropertyMap{},
+		resource.PropertyMap{}, conds, readinessTimeout)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), plugin.ErrHealthCheckUnsupported.Error())
+	}
+}
+
+func TestAwaitReadinessCustomTimeout(t *testing.T) {
+	oldInterval := readinessPollInterval
+	readinessPollInterval = time.Millisecond
+	defer func() { readinessPollInterval = oldInterval }()
+
+	prov := &deploytest.Provider{
+		ReadF: func(urn resource.URN, id resource.ID,
+			inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+			return plugin.ReadResult{Outputs: state}, resource.StatusOK, nil
+		},
+	}
+
+	// The resource's create timeout bounds the wait in place of the default.
+	goal := &resource.Goal{Type: "pkgA:m:typA", WaitFor: []string{`status == "ACTIVE"`}}
+	reg := &testRegEvent{goal: goal}
+	plan := &Plan{execCtx: context.Background()}
+	new := &resource.State{
+		Type:           "pkgA:m:typA",
+		URN:            "urn",
+		ID:             "id",
+		Outputs:        resource.PropertyMap{"status": resource.NewStringProperty("PENDING")},
+		CustomTimeouts: resource.CustomTimeouts{Create: 10 * time.Millisecond},
+	}
+	err := awaitReadiness(plan, prov, reg, new, OpCreate)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "within 10ms")
+	}
+	assert.Len(t, new.InitErrors, 1)
+}
@@ -0,0 +1,125 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+)
+
+// DefaultRefreshParallel is the degree of parallelism used for the reads of a refresh when RefreshParallel is not set
+// and the plan's own degree of parallelism is lower. Reads have no side effects, so they can safely be issued far more
+// aggressively than other operations; providers that cannot keep up are still limited by ProviderParallel and by
+// their own parallelism hints.
+const DefaultRefreshParallel = 64
+
+// RefreshProgress describes how far the reads of a refresh have progressed.
+type RefreshProgress struct {
+	Completed int // the number of resources that have been read, successfully or not.
+	Failed    int // the number of resources whose reads failed.
+	Total     int // the number of resources to read.
+}
+
+// refreshProgressSteps is the number of times the progress of a refresh is reported as its reads complete, in addition
+// to once before they begin.
+const refreshProgressSteps = 10
+
+// isRefreshRead returns true if refreshing the given resource calls its provider's Read method. Components, providers,
+// and resources that are pending replacement are carried over as they are.
+func isRefreshRead(old *resource.State) bool {
+	return old.Custom && !providers.IsProviderType(old.Type) && !old.PendingReplacement
+}
+
+// batchRefreshSteps groups the given refresh steps into batches that may be submitted for execution in any order:
+// first the steps that do not read anything, which complete immediately, and then the reads of each provider, in the
+// order in which each provider's first resource appears. Reads have no side effects and do not depend on one another,
+// so they need not follow the order of the dependency graph, and submitting each provider's reads together lets that
+// provider's pool of workers start on all of them at once.
+func batchRefreshSteps(steps []Step) []antichain {
+	var carried antichain
+	var order []string
+	reads := make(map[string]antichain)
+	for _, step := range steps {
+		if !isRefreshRead(step.Old()) {
+			carried = append(carried, step)
+			continue
+		}
+		ref := step.Provider()
+		if _, has := reads[ref]; !has {
+			order = append(order, ref)
+		}
+		reads[ref] = append(reads[ref], step)
+	}
+
+	var batches []antichain
+	if len(carried) > 0 {
+		batches = append(batches, carried)
+	}
+	for _, ref := range order {
+		batches = append(batches, reads[ref])
+	}
+	return batches
+}
+
+// refreshProgressEvents relays the events of a refresh's steps to the plan's event handlers, counting the reads as
+// they complete and reporting the refresh's progress each time another tenth of them has completed.
+type refreshProgressEvents struct {
+	Events
+
+	m        sync.Mutex
+	progress RefreshProgress
+	reported int // the number of tenths of the reads whose completion has been reported.
+}
+
+func newRefreshProgressEvents(events Events, steps []Step) *refreshProgressEvents {
+	e := &refreshProgressEvents{Events: events}
+	for _, step := range steps {
+		if isRefreshRead(step.Old()) {
+			e.progress.Total++
+		}
+	}
+	if e.progress.Total > 0 {
+		events.OnRefreshProgress(e.progress)
+	}
+	return e
+}
+
+func (e *refreshProgressEvents) OnResourceStepPost(ctx interface{}, step Step, status resource.Status,
+	err error) error {
+
+	postErr := e.Events.OnResourceStepPost(ctx, step, status, err)
+	if !isRefreshRead(step.Old()) {
+		return postErr
+	}
+
+	e.m.Lock()
+	e.progress.Completed++
+	if err != nil {
+		e.progress.Failed++
+	}
+	progress, report := e.progress, false
+	if tenths := progress.Completed * refreshProgressSteps / progress.Total; tenths > e.reported {
+		e.reported, report = tenths, true
+	}
+	// Reports are made while holding the lock so that they are delivered in order.
+	if report {
+		e.Events.OnRefreshProgress(progress)
+	}
+	e.m.Unlock()
+
+	return postErr
+}
@@ -0,0 +1,61 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestRefreshDegreeOfParallelism(t *testing.T) {
+	assert.Equal(t, 1, Options{}.RefreshDegreeOfParallelism())
+	assert.Equal(t, 1, Options{Parallel: 1}.RefreshDegreeOfParallelism())
+	assert.Equal(t, DefaultRefreshParallel, Options{Parallel: 8}.RefreshDegreeOfParallelism())
+	assert.Equal(t, 100, Options{Parallel: 100}.RefreshDegreeOfParallelism())
+	assert.Equal(t, math.MaxInt32, Options{Parallel: math.MaxInt32}.RefreshDegreeOfParallelism())
+	assert.Equal(t, 4, Options{Parallel: 8, RefreshParallel: 4}.RefreshDegreeOfParallelism())
+}
+
+func TestBatchRefreshSteps(t *testing.T) {
+	const provA = "urn:pulumi:test::test::pulumi:providers:pkgA::default::a"
+	const provB = "urn:pulumi:test::test::pulumi:providers:pkgB::default::b"
+
+	state := func(name string, custom bool, provider string) *resource.State {
+		urn := resource.NewURN("test", "test", "", "pkgA:m:typA", tokens.QName(name))
+		return &resource.State{Type: "pkgA:m:typA", URN: urn, Custom: custom, Provider: provider}
+	}
+	olds := []*resource.State{
+		state("comp", false, ""),
+		state("a1", true, provA),
+		state("b1", true, provB),
+		state("a2", true, provA),
+	}
+	var steps []Step
+	for _, old := range olds {
+		steps = append(steps, NewRefreshStep(nil, old, nil))
+	}
+
+	batches := batchRefreshSteps(steps)
+	if assert.Len(t, batches, 3) {
+		assert.Equal(t, []Step{steps[0]}, batches[0])
+		assert.Equal(t, []Step{steps[1], steps[3]}, batches[1])
+		assert.Equal(t, []Step{steps[2]}, batches[2])
+	}
+}
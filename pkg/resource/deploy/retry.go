@@ -0,0 +1,84 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+)
+
+// DefaultRetryableCodes are the provider error codes that are retried when a RetryPolicy does not specify its own.
+// These codes indicate transient conditions such as throttling or a resource that is not yet consistent.
+var DefaultRetryableCodes = []codes.Code{
+	codes.Unavailable,
+	codes.ResourceExhausted,
+	codes.Aborted,
+	codes.DeadlineExceeded,
+}
+
+// RetryPolicy controls how the step executor retries steps whose provider operations fail with transient errors. The
+// zero value disables retries.
+type RetryPolicy struct {
+	MaxAttempts    int           // the maximum number of times a step is attempted (<=1 disables retries).
+	InitialBackoff time.Duration // the delay before the first retry; each subsequent retry doubles the delay.
+	MaxBackoff     time.Duration // the upper bound on the delay between retries (<=0 for unbounded).
+	RetryableCodes []codes.Code  // the provider error codes that are retryable (nil for DefaultRetryableCodes).
+}
+
+// ShouldRetry returns true if a step that has been attempted the given number of times and that failed with the given
+// status and error should be attempted again. Only failures that left the resource in a known state are retried, as
+// a failure with an unknown or partial status may have already had side effects.
+func (p RetryPolicy) ShouldRetry(attempts int, status resource.Status, err error) bool {
+	if err == nil || attempts >= p.MaxAttempts || status != resource.StatusOK {
+		return false
+	}
+
+	// Errors that have not yet been converted from gRPC statuses, e.g. those of in-process providers, are retryable too.
+	rpcErr, ok := rpcerror.FromError(err)
+	return ok && p.RetryableCode(rpcErr.Code())
+}
+
+// RetryableCode returns true if a provider error with the given code is retryable under this policy.
+func (p RetryPolicy) RetryableCode(code codes.Code) bool {
+	retryable := p.RetryableCodes
+	if retryable == nil {
+		retryable = DefaultRetryableCodes
+	}
+	for _, c := range retryable {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay to wait before making the given attempt (where the first retry is attempt 2).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 2; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,65 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+)
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	throttled := rpcerror.New(codes.ResourceExhausted, "rate exceeded")
+
+	assert.True(t, policy.ShouldRetry(1, resource.StatusOK, throttled))
+	assert.True(t, policy.ShouldRetry(2, resource.StatusOK, throttled))
+	assert.False(t, policy.ShouldRetry(3, resource.StatusOK, throttled))
+
+	// Failures that may have had side effects are never retried.
+	assert.False(t, policy.ShouldRetry(1, resource.StatusUnknown, throttled))
+	assert.False(t, policy.ShouldRetry(1, resource.StatusPartialFailure, throttled))
+
+	// Non-retryable codes and non-RPC errors are not retried.
+	assert.False(t, policy.ShouldRetry(1, resource.StatusOK, rpcerror.New(codes.InvalidArgument, "bad input")))
+	assert.False(t, policy.ShouldRetry(1, resource.StatusOK, errors.New("boom")))
+	assert.False(t, policy.ShouldRetry(1, resource.StatusOK, nil))
+
+	// Custom codes replace the defaults.
+	policy.RetryableCodes = []codes.Code{codes.FailedPrecondition}
+	assert.False(t, policy.ShouldRetry(1, resource.StatusOK, throttled))
+	assert.True(t, policy.ShouldRetry(1, resource.StatusOK, rpcerror.New(codes.FailedPrecondition, "not ready")))
+
+	// The zero value never retries.
+	assert.False(t, RetryPolicy{}.ShouldRetry(1, resource.StatusOK, throttled))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 6, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.Backoff(2))
+	assert.Equal(t, 2*time.Second, policy.Backoff(3))
+	assert.Equal(t, 4*time.Second, policy.Backoff(4))
+	assert.Equal(t, 5*time.Second, policy.Backoff(5))
+	assert.Equal(t, 5*time.Second, policy.Backoff(6))
+
+	policy.MaxBackoff = 0
+	assert.Equal(t, 16*time.Second, policy.Backoff(6))
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/secrets"
 	"github.com/pulumi/pulumi/pkg/util/contract"
@@ -44,6 +45,11 @@ type Manifest struct {
 	Magic   string                 // a magic cookie.
 	Version string                 // the pulumi command version.
 	Plugins []workspace.PluginInfo // the plugin versions also loaded.
+	Config  config.Map             // the effective configuration of the update that took this snapshot, if known.
+
+	// OmittedConfig holds the keys of the effective configuration whose values are not recorded in Config, because
+	// they were secret values overridden for the update by plaintext values that could not be encrypted.
+	OmittedConfig []config.Key
 }
 
 // NewMagic creates a magic cookie out of a manifest; this can be used to check for tampering.  This ignores
@@ -68,6 +74,30 @@ func NewSnapshot(manifest Manifest, secretsManager secrets.Manager,
 	}
 }
 
+// StackReference records that a stack reads the outputs of another stack through a stack reference resource.
+type StackReference struct {
+	URN   resource.URN // the URN of the stack reference resource.
+	Stack string       // the name of the referenced stack.
+}
+
+// StackReferences returns the stacks whose outputs are read by the snapshot's stack, in the order in which their
+// stack references appear in the snapshot.
+func (snap *Snapshot) StackReferences() []StackReference {
+	if snap == nil {
+		return nil
+	}
+	var refs []StackReference
+	for _, res := range snap.Resources {
+		if res.Type != stackReferenceType || res.Delete {
+			continue
+		}
+		if name := res.Inputs["name"]; name.IsString() {
+			refs = append(refs, StackReference{URN: res.URN, Stack: name.StringValue()})
+		}
+	}
+	return refs
+}
+
 // NormalizeURNReferences fixes up all URN references in a snapshot to use the new URNs instead of potentially-aliased
 // URNs.  This will affect resources that are "old", and which would be expected to be updated to refer to the new names
 // later in the deployment.  But until they are, we still want to ensure that any serialization of the snapshot uses URN
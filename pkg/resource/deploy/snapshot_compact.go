@@ -0,0 +1,200 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// CompactionReport describes what Compact removed from or rewrote in a snapshot.
+type CompactionReport struct {
+	Tombstones         []*resource.State    // the dead resources pending deletion that were removed.
+	PendingOperations  []resource.Operation // the stale pending operations that were removed.
+	ProviderReferences int                  // the number of provider references rewritten to a live provider.
+}
+
+// Empty returns true if compaction left the snapshot unchanged.
+func (r CompactionReport) Empty() bool {
+	return len(r.Tombstones) == 0 && len(r.PendingOperations) == 0 && r.ProviderReferences == 0
+}
+
+// Compact rewrites the snapshot in place to remove the dead entries that accumulate in it over time:
+//
+//  1. Resources that are pending deletion but that have no corresponding object in the cloud: component resources,
+//     providers, and custom resources that were never assigned an ID. Deleting such a resource is a no-op, so it may
+//     be dropped as long as nothing in the snapshot still refers to it.
+//  2. References to a provider that is pending deletion, where a live provider with the same URN and inputs exists.
+//     These are rewritten to refer to the live provider, which frees the old provider to be dropped.
+//  3. Pending operations that are duplicates of an earlier operation, and pending deletes of resources that are no
+//     longer in the snapshot.
+//
+// Resources with a cloud object that is pending deletion are never removed, nor are pending operations whose outcome
+// is unknown; these are left for an update or for Repair to resolve. A snapshot that is valid before compaction is
+// valid after it.
+func (snap *Snapshot) Compact() CompactionReport {
+	var report CompactionReport
+	if snap == nil {
+		return report
+	}
+
+	report.ProviderReferences = snap.dedupeProviderReferences()
+	report.Tombstones = snap.removeTombstones()
+	report.PendingOperations = snap.removeStaleOperations()
+	return report
+}
+
+// isTombstone returns true if the given resource is pending deletion and has no cloud object to delete.
+func isTombstone(res *resource.State) bool {
+	return res.Delete && (!res.Custom || providers.IsProviderType(res.Type) || res.ID == "")
+}
+
+// dedupeProviderReferences rewrites references to providers that are pending deletion to refer to a preceding live
+// provider with the same URN and inputs, if there is one. It returns the number of references rewritten.
+func (snap *Snapshot) dedupeProviderReferences() int {
+	dead := make(map[providers.Reference]*resource.State)
+	live := make(map[resource.URN]*resource.State)
+
+	rewritten := 0
+	for _, res := range snap.Resources {
+		if res.Provider != "" {
+			ref, err := providers.ParseReference(res.Provider)
+			contract.AssertNoErrorf(err, "failed to parse provider reference %s", res.Provider)
+			if old, isDead := dead[ref]; isDead {
+				if prov, has := live[ref.URN()]; has && prov.Inputs.DeepEquals(old.Inputs) {
+					newRef, err := providers.NewReference(prov.URN, prov.ID)
+					contract.AssertNoError(err)
+					res.Provider = newRef.String()
+					rewritten++
+				}
+			}
+		}
+
+		if providers.IsProviderType(res.Type) {
+			ref, err := providers.NewReference(res.URN, res.ID)
+			if err != nil {
+				continue
+			}
+			if res.Delete {
+				dead[ref] = res
+			} else {
+				live[res.URN] = res
+			}
+		}
+	}
+	return rewritten
+}
+
+// removeTombstones removes the tombstones to which nothing in the snapshot refers, and returns them.
+func (snap *Snapshot) removeTombstones() []*resource.State {
+	removed := make(map[*resource.State]bool)
+	for _, res := range snap.Resources {
+		if isTombstone(res) {
+			removed[res] = true
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	// A removed tombstone may have been all that satisfied a reference from a later resource. Restore any tombstone
+	// that is needed in this way, and repeat until every reference is satisfied by a resource that is kept.
+	for restored := true; restored; {
+		restored = false
+
+		urns := make(map[resource.URN]bool)
+		provs := make(map[providers.Reference]bool)
+		restore := func(i int, needed func(*resource.State) bool) {
+			for _, candidate := range snap.Resources[:i] {
+				if removed[candidate] && needed(candidate) {
+					delete(removed, candidate)
+					restored = true
+				}
+			}
+		}
+
+		for i, res := range snap.Resources {
+			refs := append([]resource.URN{res.Parent}, res.Dependencies...)
+			for _, urn := range refs {
+				if urn != "" && !urns[urn] {
+					restore(i, func(candidate *resource.State) bool { return candidate.URN == urn })
+				}
+			}
+			if res.Provider != "" {
+				ref, err := providers.ParseReference(res.Provider)
+				contract.AssertNoErrorf(err, "failed to parse provider reference %s", res.Provider)
+				if !provs[ref] {
+					restore(i, func(candidate *resource.State) bool {
+						return candidate.URN == ref.URN() && candidate.ID == ref.ID()
+					})
+				}
+			}
+
+			if removed[res] {
+				continue
+			}
+			urns[res.URN] = true
+			if providers.IsProviderType(res.Type) {
+				if ref, err := providers.NewReference(res.URN, res.ID); err == nil {
+					provs[ref] = true
+				}
+			}
+		}
+	}
+
+	var kept, tombstones []*resource.State
+	for _, res := range snap.Resources {
+		if removed[res] {
+			tombstones = append(tombstones, res)
+		} else {
+			kept = append(kept, res)
+		}
+	}
+	snap.Resources = kept
+	return tombstones
+}
+
+// removeStaleOperations removes the pending operations that are duplicates of an earlier operation, and the pending
+// deletes of resources that are no longer in the snapshot, and returns them.
+func (snap *Snapshot) removeStaleOperations() []resource.Operation {
+	type opKey struct {
+		Type resource.OperationType
+		URN  resource.URN
+		ID   resource.ID
+	}
+
+	present := make(map[opKey]bool)
+	for _, res := range snap.Resources {
+		present[opKey{resource.OperationTypeDeleting, res.URN, res.ID}] = true
+	}
+
+	seen := make(map[opKey]bool)
+	var kept, stale []resource.Operation
+	for _, op := range snap.PendingOperations {
+		key := opKey{op.Type, op.Resource.URN, op.Resource.ID}
+		switch {
+		case seen[key]:
+			stale = append(stale, op)
+		case op.Type == resource.OperationTypeDeleting && !present[key]:
+			stale = append(stale, op)
+		default:
+			kept = append(kept, op)
+		}
+		seen[key] = true
+	}
+	snap.PendingOperations = kept
+	return stale
+}
@@ -0,0 +1,84 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestSnapshotCompact(t *testing.T) {
+	newURN := func(t tokens.Type, name tokens.QName) resource.URN {
+		return resource.NewURN("test", "test", "", t, name)
+	}
+
+	provURN := newURN(providers.MakeProviderType("pkgA"), "default")
+	provInputs := resource.PropertyMap{"region": resource.NewStringProperty("us-west-2")}
+	provOld := &resource.State{Type: provURN.Type(), URN: provURN, ID: "0", Custom: true, Delete: true,
+		Inputs: provInputs}
+	provNew := &resource.State{Type: provURN.Type(), URN: provURN, ID: "1", Custom: true, Inputs: provInputs}
+	oldRef, newRef := string(provURN)+"::0", string(provURN)+"::1"
+
+	compURN := newURN("my:mod:Component", "comp")
+	compOld := &resource.State{Type: compURN.Type(), URN: compURN, Delete: true}
+	compNew := &resource.State{Type: compURN.Type(), URN: compURN}
+
+	// A dead component that is still the parent of a live resource must be kept.
+	orphanURN := newURN("my:mod:Component", "orphan")
+	orphanParent := &resource.State{Type: orphanURN.Type(), URN: orphanURN, Delete: true}
+	orphan := &resource.State{Type: "pkgA:m:typA", URN: newURN("pkgA:m:typA", "orphan"), ID: "orphan-id",
+		Custom: true, Parent: orphanURN, Provider: newRef}
+
+	resA := &resource.State{Type: "pkgA:m:typA", URN: newURN("pkgA:m:typA", "resA"), ID: "a-id", Custom: true,
+		Parent: compURN, Provider: oldRef}
+
+	// A replaced custom resource still has a cloud object to delete, so it must be kept.
+	resB := &resource.State{Type: "pkgA:m:typA", URN: newURN("pkgA:m:typA", "resB"), ID: "b-old", Custom: true,
+		Delete: true, Provider: newRef}
+
+	gone := &resource.State{Type: "pkgA:m:typA", URN: newURN("pkgA:m:typA", "gone"), ID: "gone-id", Custom: true}
+	creating := &resource.State{Type: "pkgA:m:typA", URN: newURN("pkgA:m:typA", "resC"), Custom: true}
+	ops := []resource.Operation{
+		resource.NewOperation(resB, resource.OperationTypeDeleting),
+		resource.NewOperation(resB, resource.OperationTypeDeleting),
+		resource.NewOperation(gone, resource.OperationTypeDeleting),
+		resource.NewOperation(creating, resource.OperationTypeCreating),
+	}
+
+	snap := NewSnapshot(Manifest{}, nil, []*resource.State{
+		provOld, provNew, compOld, compNew, orphanParent, orphan, resA, resB,
+	}, ops)
+	snap.Manifest.Magic = snap.Manifest.NewMagic()
+	assert.NoError(t, snap.VerifyIntegrity())
+
+	report := snap.Compact()
+	assert.False(t, report.Empty())
+	assert.Equal(t, []*resource.State{provOld, compOld}, report.Tombstones)
+	assert.Equal(t, 1, report.ProviderReferences)
+	assert.Equal(t, []resource.Operation{ops[1], ops[2]}, report.PendingOperations)
+
+	assert.Equal(t, []*resource.State{provNew, compNew, orphanParent, orphan, resA, resB}, snap.Resources)
+	assert.Equal(t, []resource.Operation{ops[0], ops[3]}, snap.PendingOperations)
+	assert.Equal(t, newRef, resA.Provider)
+	assert.NoError(t, snap.VerifyIntegrity())
+
+	// Compaction is idempotent.
+	assert.True(t, snap.Compact().Empty())
+}
@@ -0,0 +1,136 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// InputMismatch describes a resource whose stored inputs are rejected by its provider.
+type InputMismatch struct {
+	URN      resource.URN          // the resource whose inputs were rejected.
+	Package  tokens.Package        // the package of the provider that rejected them.
+	Version  *semver.Version       // the version of the provider that rejected them, if known.
+	Failures []plugin.CheckFailure // the provider's reasons for rejecting them.
+}
+
+// ValidateInputs asks the provider of each custom resource in the snapshot to check the resource's stored inputs, and
+// returns the resources whose inputs are rejected. This finds resources whose shapes no longer match their providers'
+// schemas, e.g. after an upgrade to a provider that renamed or retyped properties, before they cause confusing diffs or
+// failures in the middle of an update. Resource outputs are not checked, as providers do not validate them.
+//
+// Each provider is configured as recorded in the snapshot, and is loaded at the version given for its package in
+// versions, if any, which is typically the version that the program requires, and at the version recorded in the
+// snapshot otherwise.
+func (snap *Snapshot) ValidateInputs(host plugin.Host,
+	versions map[tokens.Package]*semver.Version) ([]InputMismatch, error) {
+
+	if snap == nil {
+		return nil, nil
+	}
+
+	// Group the resources to check by their providers, checking each provider's resources in snapshot order.
+	provs := make(map[string]*resource.State)
+	resources := make(map[string][]*resource.State)
+	var refs []string
+	for _, res := range snap.Resources {
+		switch {
+		case res.Delete:
+			continue
+		case providers.IsProviderType(res.Type):
+			if ref, err := providers.NewReference(res.URN, res.ID); err == nil {
+				provs[ref.String()] = res
+			}
+		case res.Custom && !res.External && res.Provider != "":
+			if _, has := resources[res.Provider]; !has {
+				refs = append(refs, res.Provider)
+			}
+			resources[res.Provider] = append(resources[res.Provider], res)
+		}
+	}
+
+	var mismatches []InputMismatch
+	for _, ref := range refs {
+		prov, has := provs[ref]
+		if !has {
+			// VerifyIntegrity reports references to missing providers.
+			logging.V(7).Infof("ValidateInputs: skipping the resources of unknown provider %v", ref)
+			continue
+		}
+		pkg := providers.GetProviderPackage(prov.Type)
+		if pkg == "pulumi" {
+			// The builtin provider's resources have no schema to speak of.
+			continue
+		}
+		version, err := providers.GetProviderVersion(prov.Inputs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse version for %v provider '%v'", pkg, prov.URN)
+		}
+		if v, has := versions[pkg]; has && v != nil {
+			version = v
+		}
+
+		ms, err := validateProviderInputs(host, pkg, version, prov, resources[ref])
+		if err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, ms...)
+	}
+	return mismatches, nil
+}
+
+// validateProviderInputs loads and configures the given provider and asks it to check the stored inputs of each of
+// the given resources.
+func validateProviderInputs(host plugin.Host, pkg tokens.Package, version *semver.Version, prov *resource.State,
+	resources []*resource.State) ([]InputMismatch, error) {
+
+	p, err := host.Provider(pkg, version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load plugin for %v provider '%v'", pkg, prov.URN)
+	}
+	if p == nil {
+		return nil, errors.Errorf("could not find plugin for %v provider '%v' at version %v", pkg, prov.URN, version)
+	}
+	defer func() { contract.IgnoreError(host.CloseProvider(p)) }()
+
+	if err := p.Configure(prov.Inputs); err != nil {
+		return nil, errors.Wrapf(err, "could not configure provider '%v'", prov.URN)
+	}
+
+	var mismatches []InputMismatch
+	for _, res := range resources {
+		_, failures, err := p.Check(res.URN, res.Inputs, res.Inputs, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not check the inputs of '%v'", res.URN)
+		}
+		if len(failures) > 0 {
+			mismatches = append(mismatches, InputMismatch{
+				URN:      res.URN,
+				Package:  pkg,
+				Version:  version,
+				Failures: failures,
+			})
+		}
+	}
+	return mismatches, nil
+}
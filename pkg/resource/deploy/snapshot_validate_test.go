@@ -0,0 +1,86 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func TestSnapshotValidateInputs(t *testing.T) {
+	// Version 2 of the provider renamed the "size" property to "capacity".
+	check := func(renamed bool) deploytest.LoadProviderFunc {
+		return func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				CheckF: func(urn resource.URN,
+					olds, news resource.PropertyMap) (resource.PropertyMap, []plugin.CheckFailure, error) {
+					if _, has := news["size"]; has && renamed {
+						return nil, []plugin.CheckFailure{{Property: "size", Reason: "unknown property"}}, nil
+					}
+					return news, nil, nil
+				},
+			}, nil
+		}
+	}
+	host := deploytest.NewPluginHost(nil, nil, nil,
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), check(false)),
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("2.0.0"), check(true)))
+
+	provURN := resource.NewURN("test", "test", "", providers.MakeProviderType("pkgA"), "default")
+	prov := &resource.State{
+		Type:   provURN.Type(),
+		URN:    provURN,
+		ID:     "0",
+		Custom: true,
+		Inputs: resource.PropertyMap{"version": resource.NewStringProperty("1.0.0")},
+	}
+	ref := string(provURN) + "::0"
+
+	newRes := func(name tokens.QName, inputs resource.PropertyMap) *resource.State {
+		return &resource.State{
+			Type:     "pkgA:m:typA",
+			URN:      resource.NewURN("test", "test", "", "pkgA:m:typA", name),
+			Custom:   true,
+			Provider: ref,
+			Inputs:   inputs,
+		}
+	}
+	old := newRes("old", resource.PropertyMap{"size": resource.NewNumberProperty(1)})
+	current := newRes("current", resource.PropertyMap{"capacity": resource.NewNumberProperty(1)})
+	snap := newSnapshot([]*resource.State{prov, old, current}, nil)
+
+	// The version recorded in the snapshot accepts every resource.
+	mismatches, err := snap.ValidateInputs(host, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	// The version that the program requires rejects the resource stored with the old shape.
+	v2 := semver.MustParse("2.0.0")
+	mismatches, err = snap.ValidateInputs(host, map[tokens.Package]*semver.Version{"pkgA": &v2})
+	assert.NoError(t, err)
+	if assert.Len(t, mismatches, 1) {
+		assert.Equal(t, old.URN, mismatches[0].URN)
+		assert.Equal(t, tokens.Package("pkgA"), mismatches[0].Package)
+		assert.Equal(t, resource.PropertyKey("size"), mismatches[0].Failures[0].Property)
+	}
+}
@@ -17,6 +17,7 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/blang/semver"
 	pbempty "github.com/golang/protobuf/ptypes/empty"
@@ -25,6 +26,7 @@ import (
 	"google.golang.org/grpc/codes"
 
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
@@ -44,8 +46,47 @@ type EvalRunInfo struct {
 	Program string             `json:"program" yaml:"program"`                   // the path to the program.
 	Args    []string           `json:"args,omitempty" yaml:"args,omitempty"`     // any arguments to pass to the package.
 	Target  *Target            `json:"target,omitempty" yaml:"target,omitempty"` // the target being deployed into.
+
+	// ConfigOverrides holds configuration values that take precedence over the target's for this run only. They are
+	// seen by the program and by default providers exactly as if they were part of the target's configuration, but
+	// are never written back to it.
+	ConfigOverrides map[config.Key]string `json:"configOverrides,omitempty" yaml:"configOverrides,omitempty"`
+}
+
+// GetConfig returns the decrypted configuration for the run: the target's layered configuration, overlaid with any
+// overrides.
+func (info *EvalRunInfo) GetConfig() (map[config.Key]string, error) {
+	cfg, err := info.Target.LayeredConfig().Decrypt(info.Target.Decrypter)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range info.ConfigOverrides {
+		cfg[k] = v
+	}
+	return cfg, nil
+}
+
+// GetPackageConfig returns the set of configuration parameters for the indicated package, if any, overlaid with any
+// overrides for that package.
+func (info *EvalRunInfo) GetPackageConfig(pkg tokens.Package) (map[config.Key]string, error) {
+	cfg, err := info.Target.GetPackageConfig(pkg)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range info.ConfigOverrides {
+		if tokens.Package(k.Namespace()) != pkg {
+			continue
+		}
+		if cfg == nil {
+			cfg = make(map[config.Key]string)
+		}
+		cfg[k] = v
+	}
+	return cfg, nil
 }
 
+var _ plugin.ConfigSource = (*EvalRunInfo)(nil)
+
 // NewEvalSource returns a planning source that fetches resources by evaluating a package with a set of args and
 // a confgiuration map.  This evaluation is performed using the given plugin context and may optionally use the
 // given plugin host (or the default, if this is nil).  Note that closing the eval source also closes the host.
@@ -183,11 +224,14 @@ func (iter *evalSourceIterator) forkRun(opts Options) {
 			}
 			contract.Assertf(langhost != nil, "expected non-nil language host %s", rt)
 
-			// Make sure to clean up before exiting.
-			defer contract.IgnoreClose(langhost)
+			// Make sure to clean up before exiting, unless the language host is to be kept for the program's next run,
+			// in which case the plugin host closes it.
+			if !opts.HotReload {
+				defer contract.IgnoreClose(langhost)
+			}
 
-			// Decrypt the configuration.
-			config, err := iter.src.runinfo.Target.Config.Decrypt(iter.src.runinfo.Target.Decrypter)
+			// Decrypt the configuration and apply any overrides.
+			config, err := iter.src.runinfo.GetConfig()
 			if err != nil {
 				return result.FromError(err)
 			}
@@ -203,6 +247,8 @@ func (iter *evalSourceIterator) forkRun(opts Options) {
 				Config:         config,
 				DryRun:         iter.src.dryRun,
 				Parallel:       opts.Parallel,
+				HotReload:      opts.HotReload,
+				ChangedPaths:   opts.ChangedPaths,
 			})
 
 			// Check if we were asked to Bail.  This a special random constant used for that
@@ -394,6 +440,8 @@ type resmon struct {
 	regChan          chan *registerResourceEvent        // the channel to send resource registrations to.
 	regOutChan       chan *registerResourceOutputsEvent // the channel to send resource output registrations to.
 	regReadChan      chan *readResourceEvent            // the channel to send resource reads to.
+	pwd              string                             // the directory against which asset paths are resolved.
+	plugctx          *plugin.Context                    // the plugin context, which controls how assets are read.
 	addr             string                             // the address the host is listening on.
 	cancel           chan bool                          // a channel that can cancel the server.
 	done             chan error                         // a channel that resolves when the server completes.
@@ -412,7 +460,7 @@ func newResourceMonitor(src *evalSource, provs ProviderSource, regChan chan *reg
 	d := &defaultProviders{
 		defaultVersions: src.defaultProviderVersions,
 		providers:       make(map[string]providers.Reference),
-		config:          src.runinfo.Target,
+		config:          src.runinfo,
 		requests:        make(chan defaultProviderRequest),
 		regChan:         regChan,
 		cancel:          cancel,
@@ -425,6 +473,8 @@ func newResourceMonitor(src *evalSource, provs ProviderSource, regChan chan *reg
 		regChan:          regChan,
 		regOutChan:       regOutChan,
 		regReadChan:      regReadChan,
+		pwd:              src.runinfo.Pwd,
+		plugctx:          src.plugctx,
 		cancel:           cancel,
 	}
 
@@ -673,7 +723,10 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 	parent := resource.URN(req.GetParent())
 	protect := req.GetProtect()
 	deleteBeforeReplace := req.GetDeleteBeforeReplace()
+	validateReplacement := req.GetValidateReplacement()
 	ignoreChanges := req.GetIgnoreChanges()
+	replaceOnChanges := req.GetReplaceOnChanges()
+	waitFor := req.GetWaitFor()
 	var t tokens.Type
 
 	// Custom resources must have a three-part type so that we can 1) identify if they are providers and 2) retrieve the
@@ -708,6 +761,11 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 		aliases = append(aliases, resource.URN(aliasURN))
 	}
 
+	customTimeouts, err := parseCustomTimeouts(req.GetCustomTimeouts())
+	if err != nil {
+		return nil, rpcerror.New(codes.InvalidArgument, err.Error())
+	}
+
 	dependencies := []resource.URN{}
 	for _, dependingURN := range req.GetDependencies() {
 		dependencies = append(dependencies, resource.URN(dependingURN))
@@ -719,6 +777,8 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 			KeepUnknowns:       true,
 			ComputeAssetHashes: true,
 			KeepSecrets:        true,
+			WorkingDirectory:   rm.pwd,
+			AssetOptions:       rm.plugctx.AssetOptions(),
 		})
 	if err != nil {
 		return nil, err
@@ -749,13 +809,20 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 
 	logging.V(5).Infof(
 		"ResourceMonitor.RegisterResource received: t=%v, name=%v, custom=%v, #props=%v, parent=%v, protect=%v, "+
-			"provider=%v, deps=%v, deleteBeforeReplace=%v, ignoreChanges=%v",
-		t, name, custom, len(props), parent, protect, provider, dependencies, deleteBeforeReplace, ignoreChanges)
+			"provider=%v, deps=%v, deleteBeforeReplace=%v, validateReplacement=%v, ignoreChanges=%v, "+
+			"replaceOnChanges=%v, waitFor=%v",
+		t, name, custom, len(props), parent, protect, provider, dependencies, deleteBeforeReplace, validateReplacement,
+		ignoreChanges, replaceOnChanges, waitFor)
 
 	// Send the goal state to the engine.
+	goal := resource.NewGoal(t, name, custom, props, parent, protect, dependencies, provider, nil,
+		propertyDependencies, deleteBeforeReplace, ignoreChanges, additionalSecretOutputs, aliases)
+	goal.ValidateReplacement = validateReplacement
+	goal.CustomTimeouts = customTimeouts
+	goal.ReplaceOnChanges = replaceOnChanges
+	goal.WaitFor = waitFor
 	step := &registerResourceEvent{
-		goal: resource.NewGoal(t, name, custom, props, parent, protect, dependencies, provider, nil,
-			propertyDependencies, deleteBeforeReplace, ignoreChanges, additionalSecretOutputs, aliases),
+		goal: goal,
 		done: make(chan *RegisterResult),
 	}
 
@@ -804,6 +871,36 @@ func (rm *resmon) RegisterResource(ctx context.Context,
 	}, nil
 }
 
+// parseCustomTimeouts parses the timeouts and poll interval in a resource registration, each of which is a duration
+// such as "10m".
+func parseCustomTimeouts(timeouts *pulumirpc.RegisterResourceRequest_CustomTimeouts) (resource.CustomTimeouts, error) {
+	var result resource.CustomTimeouts
+	if timeouts == nil {
+		return result, nil
+	}
+
+	for _, t := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"create timeout", timeouts.GetCreate(), &result.Create},
+		{"update timeout", timeouts.GetUpdate(), &result.Update},
+		{"delete timeout", timeouts.GetDelete(), &result.Delete},
+		{"poll interval", timeouts.GetPollInterval(), &result.PollInterval},
+	} {
+		if t.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(t.value)
+		if err != nil {
+			return resource.CustomTimeouts{}, errors.Wrapf(err, "invalid %s %q", t.name, t.value)
+		}
+		*t.dest = d
+	}
+	return result, nil
+}
+
 // RegisterResourceOutputs records some new output properties for a resource that have arrived after its initial
 // provisioning.  These will make their way into the eventual checkpoint state file for that resource.
 func (rm *resmon) RegisterResourceOutputs(ctx context.Context,
@@ -821,6 +918,8 @@ func (rm *resmon) RegisterResourceOutputs(ctx context.Context,
 			KeepUnknowns:       true,
 			ComputeAssetHashes: true,
 			KeepSecrets:        true,
+			WorkingDirectory:   rm.pwd,
+			AssetOptions:       rm.plugctx.AssetOptions(),
 		})
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot unmarshal output properties")
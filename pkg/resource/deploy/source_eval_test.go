@@ -23,6 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
 	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
@@ -299,6 +300,36 @@ func TestRegisterDefaultProviders(t *testing.T) {
 	assert.Equal(t, len(steps)+len(defaults), processed)
 }
 
+func TestEvalRunInfoConfigOverrides(t *testing.T) {
+	region, bucket := config.MustMakeKey("aws", "region"), config.MustMakeKey("test", "bucket")
+	runInfo := &EvalRunInfo{
+		Proj: &workspace.Project{Name: "test"},
+		Target: &Target{Name: "test", Config: config.Map{
+			region: config.NewValue("us-east-1"),
+			bucket: config.NewValue("logs"),
+		}},
+		ConfigOverrides: map[config.Key]string{region: "us-west-2"},
+	}
+
+	// The program sees the overridden value alongside the rest of the configuration.
+	cfg, err := runInfo.GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[config.Key]string{region: "us-west-2", bucket: "logs"}, cfg)
+
+	// So do default providers, but only for their own packages.
+	awsConfig, err := runInfo.GetPackageConfig("aws")
+	assert.NoError(t, err)
+	assert.Equal(t, map[config.Key]string{region: "us-west-2"}, awsConfig)
+	testConfig, err := runInfo.GetPackageConfig("test")
+	assert.NoError(t, err)
+	assert.Equal(t, map[config.Key]string{bucket: "logs"}, testConfig)
+
+	// The target's own configuration is untouched.
+	v, err := runInfo.Target.Config[region].Value(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "us-east-1", v)
+}
+
 func TestReadInvokeNoDefaultProviders(t *testing.T) {
 	runInfo := &EvalRunInfo{
 		Proj:   &workspace.Project{Name: "test"},
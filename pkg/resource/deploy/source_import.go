@@ -0,0 +1,136 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"context"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// Import describes an existing resource that is to be imported into a stack.
+type Import struct {
+	Type       tokens.Type       // the type token of the resource.
+	Name       tokens.QName      // the name of the resource.
+	ID         resource.ID       // the ID of the resource, as understood by its provider.
+	Parent     resource.URN      // an optional parent for the resource.
+	ParentPath []ImportComponent // an optional chain of components, outermost first, to place the resource beneath.
+	Provider   string            // a reference to the provider instance that manages the resource.
+}
+
+// ImportComponent names a component resource along the path from an import's parent to the imported resource. The
+// component is reused if it is already part of the stack, and created otherwise.
+type ImportComponent struct {
+	Type tokens.Type  // the type token of the component.
+	Name tokens.QName // the name of the component.
+}
+
+// NewImportSource returns a planning source that reads each of the given resources from its provider and imports it
+// into the stack. The given component goals are registered first, in order, so that imports may be parented to them.
+// This source must be used with ImportOnly planning options so that the resources that are already present in the
+// stack are left untouched.
+func NewImportSource(project tokens.PackageName, components []*resource.Goal, imports []Import) Source {
+	return &importSource{project: project, components: components, imports: imports}
+}
+
+// An importSource produces a registration event for each component that is to be created and a read event for each
+// resource that is to be imported.
+type importSource struct {
+	project    tokens.PackageName
+	components []*resource.Goal
+	imports    []Import
+}
+
+func (src *importSource) Close() error                { return nil }
+func (src *importSource) Project() tokens.PackageName { return src.project }
+func (src *importSource) Info() interface{}           { return nil }
+
+func (src *importSource) Iterate(
+	ctx context.Context, opts Options, providers ProviderSource) (SourceIterator, result.Result) {
+
+	return &importSourceIterator{ctx: ctx, src: src, current: -1}, nil
+}
+
+// importSourceIterator returns a registration event for each component and then a read event for each import in turn.
+type importSourceIterator struct {
+	ctx     context.Context
+	src     *importSource
+	current int
+	pending chan struct{} // closed once the most recently registered component is done.
+}
+
+func (iter *importSourceIterator) Close() error {
+	return nil // nothing to do.
+}
+
+func (iter *importSourceIterator) Next() (SourceEvent, result.Result) {
+	// Components must be registered before anything that is parented to them, so wait for the previous one to finish.
+	if iter.pending != nil {
+		select {
+		case <-iter.pending:
+			iter.pending = nil
+		case <-iter.ctx.Done():
+			return nil, result.Bail()
+		}
+	}
+
+	iter.current++
+	if iter.current < len(iter.src.components) {
+		iter.pending = make(chan struct{})
+		return &importComponentEvent{goal: iter.src.components[iter.current], done: iter.pending}, nil
+	}
+	if i := iter.current - len(iter.src.components); i < len(iter.src.imports) {
+		return &importResourceEvent{imp: iter.src.imports[i]}, nil
+	}
+	return nil, nil
+}
+
+// importComponentEvent is the registration event for a component that holds imported resources.
+type importComponentEvent struct {
+	goal *resource.Goal
+	done chan struct{}
+}
+
+var _ RegisterResourceEvent = (*importComponentEvent)(nil)
+
+func (g *importComponentEvent) event() {}
+
+func (g *importComponentEvent) Goal() *resource.Goal { return g.goal }
+
+func (g *importComponentEvent) Done(result *RegisterResult) {
+	close(g.done)
+}
+
+// importResourceEvent is the read event for a single import. No program is waiting on the result of the read, so
+// completion is a no-op.
+type importResourceEvent struct {
+	imp Import
+}
+
+var _ ReadResourceEvent = (*importResourceEvent)(nil)
+
+func (g *importResourceEvent) event() {}
+
+func (g *importResourceEvent) ID() resource.ID                                 { return g.imp.ID }
+func (g *importResourceEvent) Name() tokens.QName                              { return g.imp.Name }
+func (g *importResourceEvent) Type() tokens.Type                               { return g.imp.Type }
+func (g *importResourceEvent) Provider() string                                { return g.imp.Provider }
+func (g *importResourceEvent) Parent() resource.URN                            { return g.imp.Parent }
+func (g *importResourceEvent) Properties() resource.PropertyMap                { return resource.PropertyMap{} }
+func (g *importResourceEvent) Dependencies() []resource.URN                    { return nil }
+func (g *importResourceEvent) AdditionalSecretOutputs() []resource.PropertyKey { return nil }
+func (g *importResourceEvent) Done(result *ReadResult)                         {}
@@ -43,7 +43,7 @@ func NewQuerySource(cancel context.Context, plugctx *plugin.Context, client Back
 	runinfo *EvalRunInfo) (QuerySource, error) {
 
 	// Create a new builtin provider. This provider implements features such as `getStack`.
-	builtins := newBuiltinProvider(client)
+	builtins := newBuiltinProvider(client, plugctx.Diag)
 
 	// First, fire up a resource monitor that will disallow all resource operations, as well as
 	// service calls for things like resource ouptuts of state snapshots.
@@ -132,8 +132,8 @@ func runLangPlugin(src *querySource) result.Result {
 	// Make sure to clean up before exiting.
 	defer contract.IgnoreClose(langhost)
 
-	// Decrypt the configuration.
-	config, err := src.runinfo.Target.Config.Decrypt(src.runinfo.Target.Decrypter)
+	// Decrypt the configuration and apply any overrides.
+	config, err := src.runinfo.GetConfig()
 	if err != nil {
 		return result.FromError(err)
 	}
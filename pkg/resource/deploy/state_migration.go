@@ -0,0 +1,182 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/workspace"
+)
+
+// migrateState gives a resource's provider the chance to migrate the resource's old state when the resource moves from
+// one version of its package's default provider to a newer one, or when the plugin that serves its provider has been
+// upgraded since the last update. It returns the old inputs and outputs to use in place of those recorded in the old
+// state, and true if the provider changed them.
+//
+// The migration is not recorded anywhere by itself: the migrated state is checked and diffed against the resource's
+// goal as usual, so any changes it causes are previewed, and it is only written to the checkpoint once the resulting
+// step is applied.
+func (sg *stepGenerator) migrateState(urn resource.URN, old *resource.State,
+	newProvider string) (resource.PropertyMap, resource.PropertyMap, bool, error) {
+
+	from, prov, err := sg.providerUpgrade(old.Provider, newProvider)
+	if err != nil || from == nil {
+		return old.Inputs, old.Outputs, false, err
+	}
+	migrator, ok := prov.(plugin.StateMigrator)
+	if !ok {
+		return old.Inputs, old.Outputs, false, nil
+	}
+
+	inputs, outputs, err := migrator.MigrateState(urn, old.ID, *from, old.Inputs, old.Outputs)
+	if err != nil {
+		return nil, nil, false, errors.Wrapf(err, "migrating the state of %s from version %s of its provider", urn, from)
+	}
+
+	var changes []string
+	for _, props := range []struct {
+		name      string
+		old, news resource.PropertyMap
+	}{{"inputs", old.Inputs, inputs}, {"outputs", old.Outputs, outputs}} {
+		if diff := props.old.Diff(props.news); diff != nil {
+			for _, c := range diff.Changes() {
+				path := c.PathString()
+				if !strings.HasPrefix(path, "[") {
+					path = "." + path
+				}
+				changes = append(changes, fmt.Sprintf("%s%s%s", migrationChangeSymbol(c.Kind), props.name, path))
+			}
+		}
+	}
+	if len(changes) == 0 {
+		logging.V(7).Infof("Planner found no state to migrate for '%v' from provider version %s", urn, from)
+		return old.Inputs, old.Outputs, false, nil
+	}
+
+	sg.plan.Diag().Infof(diag.Message(urn, "migrated state from version %s of its provider: %s"),
+		from, strings.Join(changes, ", "))
+	return inputs, outputs, true, nil
+}
+
+// migrationChangeSymbol returns the symbol used to describe a property changed by a state migration.
+func migrationChangeSymbol(kind resource.PropertyChangeKind) string {
+	switch kind {
+	case resource.PropertyAdded:
+		return "+"
+	case resource.PropertyDeleted:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// providerUpgrade determines whether moving a resource from its old provider to its new one upgrades its provider:
+// either the resource moves from one default provider for its package to another, or it stays with the same provider,
+// default or explicit, whose version or plugin has changed. If so, it returns the version being upgraded from and the
+// new provider; otherwise, it returns a nil version. Results are cached, since every resource managed by a provider
+// shares them.
+func (sg *stepGenerator) providerUpgrade(oldProvider, newProvider string) (*semver.Version, plugin.Provider, error) {
+	key := oldProvider + "\x00" + newProvider
+	if upgrade, ok := sg.upgrades[key]; ok {
+		return upgrade.from, upgrade.prov, nil
+	}
+
+	from, prov, err := sg.computeProviderUpgrade(oldProvider, newProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	sg.upgrades[key] = defaultProviderUpgrade{from: from, prov: prov}
+	return from, prov, nil
+}
+
+func (sg *stepGenerator) computeProviderUpgrade(oldProvider,
+	newProvider string) (*semver.Version, plugin.Provider, error) {
+
+	if oldProvider == "" || newProvider == "" {
+		return nil, nil, nil
+	}
+	oldRef, err := providers.ParseReference(oldProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	newRef, err := providers.ParseReference(newProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+	sameProvider := oldRef.URN() == newRef.URN()
+	if !sameProvider && (!providers.IsDefaultProvider(oldRef.URN()) || !providers.IsDefaultProvider(newRef.URN())) {
+		return nil, nil, nil
+	}
+
+	newProv, ok := sg.plan.providers.GetProvider(newRef)
+	if !ok {
+		return nil, nil, errors.Errorf("failed to resolve provider reference: %q", newRef.String())
+	}
+
+	// The old provider's version is the one it was configured with, or, if it did not ask for a particular version,
+	// the version of the plugin that the last update used. A provider that does not ask for a version is served by the
+	// newest plugin available, so if the last update used several versions of the plugin, it is the newest of them.
+	pkg := providers.GetProviderPackage(oldRef.URN().Type())
+	var from *semver.Version
+	if oldRes, ok := sg.plan.olds[oldRef.URN()]; ok {
+		if from, err = providers.GetProviderVersion(oldRes.Inputs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if from == nil && sg.plan.prev != nil {
+		for _, plug := range sg.plan.prev.Manifest.Plugins {
+			if plug.Kind == workspace.ResourcePlugin && plug.Name == string(pkg) && plug.Version != nil &&
+				(from == nil || plug.Version.GT(*from)) {
+				from = plug.Version
+			}
+		}
+	}
+
+	// Likewise, the new provider's version is the one it asked for, or else that of the plugin serving it.
+	var to *semver.Version
+	if newRes, ok := sg.providers[newRef.URN()]; ok {
+		if to, err = providers.GetProviderVersion(newRes.Inputs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if to == nil {
+		info, err := newProv.GetPluginInfo()
+		if err != nil {
+			return nil, nil, err
+		}
+		to = info.Version
+	}
+
+	if from == nil || to == nil || !to.GT(*from) {
+		return nil, nil, nil
+	}
+	logging.V(7).Infof("Planner observed an upgrade of the %s provider from %s to %s", pkg, from, to)
+	return from, newProv, nil
+}
+
+// defaultProviderUpgrade records whether moving between two providers upgrades a default provider.
+type defaultProviderUpgrade struct {
+	from *semver.Version // the version being upgraded from, or nil if this is not an upgrade.
+	prov plugin.Provider // the new provider.
+}
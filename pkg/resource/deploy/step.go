@@ -56,10 +56,11 @@ type Step interface {
 
 // SameStep is a mutating step that does nothing.
 type SameStep struct {
-	plan *Plan                 // the current plan.
-	reg  RegisterResourceEvent // the registration intent to convey a URN back to.
-	old  *resource.State       // the state of the resource before this step.
-	new  *resource.State       // the state of the resource after this step.
+	plan     *Plan                 // the current plan.
+	reg      RegisterResourceEvent // the registration intent to convey a URN back to.
+	old      *resource.State       // the state of the resource before this step.
+	new      *resource.State       // the state of the resource after this step.
+	migrated resource.PropertyMap  // the old outputs as migrated by a newer provider, if any.
 }
 
 var _ Step = (*SameStep)(nil)
@@ -97,10 +98,35 @@ func (s *SameStep) Apply(preview bool) (resource.Status, StepCompleteFunc, error
 	// Retain the ID, and outputs:
 	s.new.ID = s.old.ID
 	s.new.Outputs = s.old.Outputs
-	complete := func() { s.reg.Done(&RegisterResult{State: s.new, Stable: true}) }
+	if s.migrated != nil {
+		s.new.Outputs = s.migrated
+	}
+	complete := func() {
+		// Same steps for resources carried over by an import have no registration to complete.
+		if s.reg != nil {
+			s.reg.Done(&RegisterResult{State: s.new, Stable: true})
+		}
+	}
 	return resource.StatusOK, complete, nil
 }
 
+// withMigratedOutputs arranges for a same or update step to use the given outputs, which a newer version of the
+// resource's provider migrated from its old outputs, in place of the old outputs when the step is applied.
+func withMigratedOutputs(step Step, migrated bool, outputs resource.PropertyMap) Step {
+	if !migrated {
+		return step
+	}
+	switch s := step.(type) {
+	case *SameStep:
+		s.migrated = outputs
+	case *UpdateStep:
+		s.migrated = outputs
+	default:
+		contract.Failf("unexpected step type %T", step)
+	}
+	return step
+}
+
 // CreateStep is a mutating step that creates an entirely new resource.
 type CreateStep struct {
 	plan          *Plan                  // the current plan.
@@ -111,6 +137,7 @@ type CreateStep struct {
 	diffs         []resource.PropertyKey // the keys causing a diff (only for replacements).
 	replacing     bool                   // true if this is a create due to a replacement.
 	pendingDelete bool                   // true if this replacement should create a pending delete.
+	validating    bool                   // true if a validation step will complete this replacement.
 }
 
 var _ Step = (*CreateStep)(nil)
@@ -201,9 +228,23 @@ func (s *CreateStep) Apply(preview bool) (resource.Status, StepCompleteFunc, err
 			// Copy any of the default and output properties on the live object state.
 			s.new.ID = id
 			s.new.Outputs = outs
+			s.new.Artifacts = s.plan.storeArtifacts(s.URN(), prov, s.new.Artifacts)
+
+			// Hold the resource back from its dependents until it is ready for use.
+			if resourceError == nil {
+				if resourceError = awaitReadiness(s.plan, prov, s.reg, s.new, OpCreate); resourceError != nil {
+					resourceStatus = resource.StatusPartialFailure
+				}
+			}
 		}
 	}
 
+	// If the replacement must be validated before it is put into use, the validation step is responsible for
+	// condemning the old resource and completing the registration.
+	if s.validating {
+		return resourceStatus, nil, resourceError
+	}
+
 	// Mark the old resource as pending deletion if necessary.
 	if s.replacing && s.pendingDelete {
 		s.old.Delete = true
@@ -216,6 +257,24 @@ func (s *CreateStep) Apply(preview bool) (resource.Status, StepCompleteFunc, err
 	return resourceStatus, complete, resourceError
 }
 
+// detach returns a copy of the step that applies itself to private copies of the step's states, along with a function
+// that moves the copy's results into the step's own states.
+func (s *CreateStep) detach() (Step, func()) {
+	c := *s
+	new := *s.new
+	c.new = &new
+	if s.old != nil {
+		old := *s.old
+		c.old = &old
+	}
+	return &c, func() {
+		*s.new, c.new = *c.new, s.new
+		if s.old != nil {
+			*s.old, c.old = *c.old, s.old
+		}
+	}
+}
+
 // DeleteStep is a mutating step that deletes an existing resource. If `old` is marked "External",
 // DeleteStep is a no-op.
 type DeleteStep struct {
@@ -287,10 +346,10 @@ func (s *DeleteStep) Res() *resource.State { return s.old }
 func (s *DeleteStep) Logical() bool        { return !s.replacing }
 
 func (s *DeleteStep) Apply(preview bool) (resource.Status, StepCompleteFunc, error) {
-	// Refuse to delete protected resources.
-	if s.old.Protect {
+	// Refuse to delete protected resources unless the plan allows it.
+	if s.old.Protect && !s.plan.allowProtected {
 		return resource.StatusOK, nil,
-			errors.Errorf("refusing to delete protected resource '%s'", s.old.URN)
+			preconditionError(errors.Errorf("refusing to delete protected resource '%s'", s.old.URN))
 	}
 
 	// Deleting an External resource is a no-op, since Pulumi does not own the lifecycle.
@@ -340,14 +399,67 @@ func (s *RemovePendingReplaceStep) Apply(preview bool) (resource.Status, StepCom
 	return resource.StatusOK, nil, nil
 }
 
+// PruneStep is a step that handles a resource that is in the snapshot but is no longer produced by the program, when
+// the plan prunes such resources. Depending on the plan's prune mode, the resource is either deleted or only reported
+// and left in place.
+type PruneStep struct {
+	plan   *Plan           // the current plan.
+	old    *resource.State // the state of the existing resource.
+	delete bool            // true if the resource is deleted rather than only reported.
+}
+
+var _ Step = (*PruneStep)(nil)
+
+func NewPruneStep(plan *Plan, old *resource.State, delete bool) Step {
+	contract.Assert(old != nil)
+	contract.Assert(old.URN != "")
+	contract.Assert(old.ID != "" || !old.Custom)
+	contract.Assert(!old.Custom || old.Provider != "" || providers.IsProviderType(old.Type))
+	contract.Assert(!old.Delete)
+	return &PruneStep{
+		plan:   plan,
+		old:    old,
+		delete: delete,
+	}
+}
+
+func (s *PruneStep) Op() StepOp           { return OpPrune }
+func (s *PruneStep) Plan() *Plan          { return s.plan }
+func (s *PruneStep) Type() tokens.Type    { return s.old.Type }
+func (s *PruneStep) Provider() string     { return s.old.Provider }
+func (s *PruneStep) URN() resource.URN    { return s.old.URN }
+func (s *PruneStep) Old() *resource.State { return s.old }
+func (s *PruneStep) Res() *resource.State { return s.old }
+func (s *PruneStep) Logical() bool        { return true }
+
+// New returns nil if the resource is deleted, and its unchanged state if it is left in place.
+func (s *PruneStep) New() *resource.State {
+	if s.delete {
+		return nil
+	}
+	return s.old
+}
+
+// Deletes returns true if the resource is deleted rather than only reported.
+func (s *PruneStep) Deletes() bool { return s.delete }
+
+func (s *PruneStep) Apply(preview bool) (resource.Status, StepCompleteFunc, error) {
+	if !s.delete {
+		return resource.StatusOK, func() {}, nil
+	}
+	del := &DeleteStep{plan: s.plan, old: s.old}
+	return del.Apply(preview)
+}
+
 // UpdateStep is a mutating step that updates an existing resource's state.
 type UpdateStep struct {
-	plan    *Plan                  // the current plan.
-	reg     RegisterResourceEvent  // the registration intent to convey a URN back to.
-	old     *resource.State        // the state of the existing resource.
-	new     *resource.State        // the newly computed state of the resource after updating.
-	stables []resource.PropertyKey // an optional list of properties that won't change during this update.
-	diffs   []resource.PropertyKey // the keys causing a diff.
+	plan     *Plan                  // the current plan.
+	reg      RegisterResourceEvent  // the registration intent to convey a URN back to.
+	old      *resource.State        // the state of the existing resource.
+	new      *resource.State        // the newly computed state of the resource after updating.
+	stables  []resource.PropertyKey // an optional list of properties that won't change during this update.
+	diffs    []resource.PropertyKey // the keys causing a diff.
+	migrated resource.PropertyMap   // the old outputs as migrated by a newer provider, if any.
 }
 
 var _ Step = (*UpdateStep)(nil)
@@ -402,7 +514,11 @@ func (s *UpdateStep) Apply(preview bool) (resource.Status, StepCompleteFunc, err
 			}
 
 			// Update to the combination of the old "all" state, but overwritten with new inputs.
-			outs, rst, upderr := prov.Update(s.URN(), s.old.ID, s.old.Outputs, s.new.Inputs)
+			olds := s.old.Outputs
+			if s.migrated != nil {
+				olds = s.migrated
+			}
+			outs, rst, upderr := prov.Update(s.URN(), s.old.ID, olds, s.new.Inputs)
 			if upderr != nil {
 				if rst != resource.StatusPartialFailure {
 					return rst, nil, upderr
@@ -418,6 +534,14 @@ func (s *UpdateStep) Apply(preview bool) (resource.Status, StepCompleteFunc, err
 
 			// Now copy any output state back in case the update triggered cascading updates to other properties.
 			s.new.Outputs = outs
+			s.new.Artifacts = s.plan.storeArtifacts(s.URN(), prov, s.new.Artifacts)
+
+			// Hold the resource back from its dependents until it is ready for use.
+			if resourceError == nil {
+				if resourceError = awaitReadiness(s.plan, prov, s.reg, s.new, OpUpdate); resourceError != nil {
+					resourceStatus = resource.StatusPartialFailure
+				}
+			}
 		}
 	}
 
@@ -429,6 +553,17 @@ func (s *UpdateStep) Apply(preview bool) (resource.Status, StepCompleteFunc, err
 	return resourceStatus, complete, resourceError
 }
 
+// detach returns a copy of the step that applies itself to a private copy of the step's new state, along with a
+// function that moves the copy's results into the step's own new state.
+func (s *UpdateStep) detach() (Step, func()) {
+	c := *s
+	new := *s.new
+	c.new = &new
+	return &c, func() {
+		*s.new, c.new = *c.new, s.new
+	}
+}
+
 // ReplaceStep is a logical step indicating a resource will be replaced.  This is comprised of three physical steps:
 // a creation of the new resource, any number of intervening updates of dependents to the new resource, and then
 // a deletion of the now-replaced old resource.  This logical step is primarily here for tools and visualization.
@@ -481,6 +616,88 @@ func (s *ReplaceStep) Apply(preview bool) (resource.Status, StepCompleteFunc, er
 	return resource.StatusOK, func() {}, nil
 }
 
+// ValidateReplacementStep is a step that checks that a newly created replacement is healthy before any dependents are
+// moved to it and the resource it replaces is condemned. If validation fails, the replacement is marked for deletion
+// and the original resource is left in place.
+type ValidateReplacementStep struct {
+	plan          *Plan                 // the current plan.
+	reg           RegisterResourceEvent // the registration intent to convey a URN back to.
+	old           *resource.State       // the state of the resource being replaced.
+	new           *resource.State       // the state of the replacement.
+	pendingDelete bool                  // true if the old resource should be marked for deletion.
+}
+
+var _ Step = (*ValidateReplacementStep)(nil)
+
+// NewValidateReplacementStep creates a step that validates the replacement created by the given create-replacement
+// step. The registration for the resource is completed by the new step rather than by the create step.
+func NewValidateReplacementStep(plan *Plan, create Step) Step {
+	c, ok := create.(*CreateStep)
+	contract.Assertf(ok && c.replacing, "validated replacements must begin with a create-replacement step")
+	c.validating = true
+	return &ValidateReplacementStep{
+		plan:          plan,
+		reg:           c.reg,
+		old:           c.old,
+		new:           c.new,
+		pendingDelete: c.pendingDelete,
+	}
+}
+
+func (s *ValidateReplacementStep) Op() StepOp           { return OpValidateReplacement }
+func (s *ValidateReplacementStep) Plan() *Plan          { return s.plan }
+func (s *ValidateReplacementStep) Type() tokens.Type    { return s.new.Type }
+func (s *ValidateReplacementStep) Provider() string     { return s.new.Provider }
+func (s *ValidateReplacementStep) URN() resource.URN    { return s.new.URN }
+func (s *ValidateReplacementStep) Old() *resource.State { return s.old }
+func (s *ValidateReplacementStep) New() *resource.State { return s.new }
+func (s *ValidateReplacementStep) Res() *resource.State { return s.new }
+func (s *ValidateReplacementStep) Logical() bool        { return false }
+
+func (s *ValidateReplacementStep) Apply(preview bool) (resource.Status, StepCompleteFunc, error) {
+	var err error
+	if !preview {
+		err = s.checkHealth()
+	}
+
+	// If the replacement is unhealthy, leave the original resource in place and condemn the replacement instead.
+	s.new.Delete = err != nil
+	if err != nil {
+		return resource.StatusOK, nil, err
+	}
+
+	if s.pendingDelete {
+		s.old.Delete = true
+	}
+	complete := func() { s.reg.Done(&RegisterResult{State: s.new}) }
+	return resource.StatusOK, complete, nil
+}
+
+// checkHealth asks the replacement's provider whether the replacement is healthy. Providers that are unable to check
+// the health of their resources are assumed to have produced a healthy replacement.
+func (s *ValidateReplacementStep) checkHealth() error {
+	if !s.new.Custom {
+		return nil
+	}
+	prov, err := getProvider(s)
+	if err != nil {
+		return err
+	}
+	checker, ok := prov.(plugin.HealthChecker)
+	if !ok {
+		return nil
+	}
+	err = checker.CheckHealth(s.URN(), s.new.ID, s.new.Outputs)
+	if err == plugin.ErrHealthCheckUnsupported {
+		return nil
+	}
+	if err != nil {
+		return preconditionError(
+			errors.Wrapf(err, "replacement failed validation; the original resource has been left in place"))
+	}
+	return nil
+}
+
 // ReadStep is a step indicating that an existing resources will be "read" and projected into the Pulumi object
 // model. Resources that are read are marked with the "External" bit which indicates to the engine that it does
 // not own this resource's lifeycle.
@@ -496,6 +713,7 @@ type ReadStep struct {
 	old       *resource.State   // the old resource state, if one exists for this urn
 	new       *resource.State   // the new resource state, to be used to query the provider
 	replacing bool              // whether or not the new resource is replacing the old resource
+	importing bool              // whether or not the new resource is being imported into the stack
 }
 
 // NewReadStep creates a new Read step.
@@ -536,10 +754,27 @@ func NewReadReplacementStep(plan *Plan, event ReadResourceEvent, old *resource.S
 	}
 }
 
+// NewImportStep creates a new Read step with the `importing` flag set. When executed, it reads the state of an
+// existing resource that is not yet present in the stack and adds it to the stack as an external resource.
+func NewImportStep(plan *Plan, event ReadResourceEvent, new *resource.State) Step {
+	contract.Assert(new != nil)
+	contract.Assertf(new.External, "target of Import step must be marked External")
+	contract.Assertf(new.Custom, "target of Import step must be Custom")
+	return &ReadStep{
+		plan:      plan,
+		event:     event,
+		new:       new,
+		importing: true,
+	}
+}
+
 func (s *ReadStep) Op() StepOp {
 	if s.replacing {
 		return OpReadReplacement
 	}
+	if s.importing {
+		return OpImport
+	}
 
 	return OpRead
 }
@@ -584,6 +819,16 @@ func (s *ReadStep) Apply(preview bool) (resource.Status, StepCompleteFunc, error
 		}
 
 		s.new.Outputs = result.Outputs
+
+		// An imported resource must exist, and its inputs are those reported by the provider.
+		if s.importing {
+			if result.Outputs == nil {
+				return resource.StatusOK, nil, preconditionError(errors.Errorf("resource '%v' does not exist", id))
+			}
+			if result.Inputs != nil {
+				s.new.Inputs = result.Inputs
+			}
+		}
 	}
 
 	// If we were asked to replace an existing, non-External resource, pend the
@@ -692,6 +937,9 @@ func (s *RefreshStep) Apply(preview bool) (resource.Status, StepCompleteFunc, er
 		s.new = resource.NewState(s.old.Type, s.old.URN, s.old.Custom, s.old.Delete, s.old.ID, inputs, outputs,
 			s.old.Parent, s.old.Protect, s.old.External, s.old.Dependencies, initErrors, s.old.Provider,
 			s.old.PropertyDependencies, s.old.PendingReplacement, s.old.AdditionalSecretOutputs, s.old.Aliases)
+		s.new.Annotations = s.old.Annotations
+		s.new.CustomTimeouts = s.old.CustomTimeouts
+		s.new.Artifacts = s.old.Artifacts
 	} else {
 		s.new = nil
 	}
@@ -709,13 +957,16 @@ const (
 	OpDelete               StepOp = "delete"                 // deleting an existing resource.
 	OpReplace              StepOp = "replace"                // replacing a resource with a new one.
 	OpCreateReplacement    StepOp = "create-replacement"     // creating a new resource for a replacement.
+	OpValidateReplacement  StepOp = "validate-replacement"   // validating a new resource for a replacement.
 	OpDeleteReplaced       StepOp = "delete-replaced"        // deleting an existing resource after replacement.
 	OpRead                 StepOp = "read"                   // reading an existing resource.
 	OpReadReplacement      StepOp = "read-replacement"       // reading an existing resource for a replacement.
+	OpImport               StepOp = "import"                 // importing an existing resource into the stack.
 	OpRefresh              StepOp = "refresh"                // refreshing an existing resource.
 	OpReadDiscard          StepOp = "discard"                // removing a resource that was read.
 	OpDiscardReplaced      StepOp = "discard-replaced"       // discarding a read resource that was replaced.
 	OpRemovePendingReplace StepOp = "remove-pending-replace" // removing a pending replace resource.
+	OpPrune                StepOp = "prune"                  // handling a resource the program no longer produces.
 )
 
 // StepOps contains the full set of step operation types.
@@ -726,13 +977,16 @@ var StepOps = []StepOp{
 	OpDelete,
 	OpReplace,
 	OpCreateReplacement,
+	OpValidateReplacement,
 	OpDeleteReplaced,
 	OpRead,
 	OpReadReplacement,
+	OpImport,
 	OpRefresh,
 	OpReadDiscard,
 	OpDiscardReplaced,
 	OpRemovePendingReplace,
+	OpPrune,
 }
 
 // Color returns a suggested color for lines of this op type.
@@ -748,17 +1002,17 @@ func (op StepOp) Color() string {
 		return colors.SpecUpdate
 	case OpReplace:
 		return colors.SpecReplace
-	case OpCreateReplacement:
+	case OpCreateReplacement, OpValidateReplacement:
 		return colors.SpecCreateReplacement
 	case OpDeleteReplaced:
 		return colors.SpecDeleteReplaced
-	case OpRead:
+	case OpRead, OpImport:
 		return colors.SpecCreate
 	case OpReadReplacement:
 		return colors.SpecReplace
 	case OpRefresh:
 		return colors.SpecUpdate
-	case OpReadDiscard, OpDiscardReplaced:
+	case OpReadDiscard, OpDiscardReplaced, OpPrune:
 		return colors.SpecDelete
 	default:
 		contract.Failf("Unrecognized resource step op: '%v'", op)
@@ -786,18 +1040,24 @@ func (op StepOp) RawPrefix() string {
 		return "+-"
 	case OpCreateReplacement:
 		return "++"
+	case OpValidateReplacement:
+		return "+?"
 	case OpDeleteReplaced:
 		return "--"
 	case OpRead:
 		return "> "
 	case OpReadReplacement:
 		return ">>"
+	case OpImport:
+		return "= "
 	case OpRefresh:
 		return "~ "
 	case OpReadDiscard:
 		return "< "
 	case OpDiscardReplaced:
 		return "<<"
+	case OpPrune:
+		return "x "
 	default:
 		contract.Failf("Unrecognized resource step op: %v", op)
 		return ""
@@ -812,8 +1072,14 @@ func (op StepOp) PastTense() string {
 		return "refreshed"
 	case OpRead:
 		return "read"
+	case OpImport:
+		return "imported"
+	case OpValidateReplacement:
+		return "validated"
 	case OpReadDiscard, OpDiscardReplaced:
 		return "discarded"
+	case OpPrune:
+		return "pruned"
 	default:
 		contract.Failf("Unexpected resource step op: %v", op)
 		return ""
@@ -835,11 +1101,12 @@ func getProvider(s Step) (plugin.Provider, error) {
 	}
 	ref, err := providers.ParseReference(s.Provider())
 	if err != nil {
-		return nil, errors.Errorf("bad provider reference '%v' for resource %v: %v", s.Provider(), s.URN(), err)
+		return nil, preconditionError(
+			errors.Errorf("bad provider reference '%v' for resource %v: %v", s.Provider(), s.URN(), err))
 	}
 	provider, ok := s.Plan().GetProvider(ref)
 	if !ok {
-		return nil, errors.Errorf("unknown provider '%v' for resource %v", s.Provider(), s.URN())
+		return nil, preconditionError(errors.Errorf("unknown provider '%v' for resource %v", s.Provider(), s.URN()))
 	}
 	return provider, nil
 }
@@ -0,0 +1,113 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+)
+
+// StepErrorKind classifies the failure of a step.
+type StepErrorKind string
+
+const (
+	// StepErrorProvider is a failure reported by the provider operation that applied the step.
+	StepErrorProvider StepErrorKind = "provider"
+	// StepErrorPrecondition is a refusal to apply the step, e.g. because it would delete a protected resource.
+	StepErrorPrecondition StepErrorKind = "precondition"
+	// StepErrorTimeout is a provider operation that did not finish within the step's timeout.
+	StepErrorTimeout StepErrorKind = "timeout"
+	// StepErrorCanceled is a provider operation that was canceled.
+	StepErrorCanceled StepErrorKind = "canceled"
+	// StepErrorBail is a step that was abandoned because the operation stopped after another failure.
+	StepErrorBail StepErrorKind = "bail"
+)
+
+// StepError is the error with which a step failed, as passed to Events.OnResourceStepPost. It classifies the failure
+// so that its reports can tell failures that may succeed if the operation is run again from those that will not.
+type StepError struct {
+	Kind StepErrorKind // the kind of failure.
+	Code codes.Code    // the code of the failure; for provider failures, the code that the provider returned.
+	Err  error         // the underlying error.
+}
+
+func (e *StepError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the underlying error.
+func (e *StepError) Cause() error {
+	return e.Err
+}
+
+// Retryable returns true if the failure, which left its resource with the given status, is one that the given retry
+// policy would retry: a provider error with one of the policy's retryable codes that left the resource in a known
+// state. This is independent of the policy's attempt limit, so it is true of failures that exhausted their attempts.
+// Timeouts and cancellations are never retryable, as the provider operation may still have had side effects.
+func (e *StepError) Retryable(policy RetryPolicy, status resource.Status) bool {
+	return e.Kind == StepErrorProvider && status == resource.StatusOK && policy.RetryableCode(e.Code)
+}
+
+// Summary returns a short description of the failure's classification under the given retry policy, e.g.
+// "provider Unavailable, retryable".
+func (e *StepError) Summary(policy RetryPolicy, status resource.Status) string {
+	retryable := "fatal"
+	if e.Retryable(policy, status) {
+		retryable = "retryable"
+	}
+	if e.Kind == StepErrorProvider {
+		return fmt.Sprintf("%s %s, %s", e.Kind, e.Code, retryable)
+	}
+	return fmt.Sprintf("%s, %s", e.Kind, retryable)
+}
+
+// newStepError returns a step error of the given kind and code that wraps the given error.
+func newStepError(kind StepErrorKind, code codes.Code, err error) *StepError {
+	return &StepError{Kind: kind, Code: code, Err: err}
+}
+
+// preconditionError returns a step error for a refusal to apply a step for the reason given by the given error.
+func preconditionError(err error) error {
+	return newStepError(StepErrorPrecondition, codes.FailedPrecondition, err)
+}
+
+// AsStepError returns the given error as a step error. An error that is not already a step error, nor caused by one,
+// is classified by its provider error code, if any, and as a provider failure with an unknown code otherwise.
+func AsStepError(err error) *StepError {
+	if err == nil {
+		return nil
+	}
+
+	for cause := err; cause != nil; {
+		switch e := cause.(type) {
+		case *StepError:
+			return e
+		case *rpcerror.Error:
+			if e.Code() == codes.Canceled {
+				return newStepError(StepErrorCanceled, codes.Canceled, err)
+			}
+			return newStepError(StepErrorProvider, e.Code(), err)
+		case interface{ Cause() error }:
+			cause = e.Cause()
+		default:
+			cause = nil
+		}
+	}
+	return newStepError(StepErrorProvider, codes.Unknown, err)
+}
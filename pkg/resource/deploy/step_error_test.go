@@ -0,0 +1,77 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+)
+
+func TestAsStepError(t *testing.T) {
+	policy, ok := RetryPolicy{}, resource.StatusOK
+	assert.Nil(t, AsStepError(nil))
+
+	// Provider errors are classified by their codes, even when wrapped.
+	throttled := AsStepError(errors.Wrap(rpcerror.New(codes.ResourceExhausted, "rate exceeded"), "creating"))
+	assert.Equal(t, StepErrorProvider, throttled.Kind)
+	assert.Equal(t, codes.ResourceExhausted, throttled.Code)
+	assert.True(t, throttled.Retryable(policy, ok))
+	assert.Equal(t, "creating: rate exceeded", throttled.Error())
+	assert.Equal(t, "provider ResourceExhausted, retryable", throttled.Summary(policy, ok))
+
+	invalid := AsStepError(rpcerror.New(codes.InvalidArgument, "bad input"))
+	assert.Equal(t, StepErrorProvider, invalid.Kind)
+	assert.False(t, invalid.Retryable(policy, ok))
+
+	canceled := AsStepError(rpcerror.New(codes.Canceled, "canceled"))
+	assert.Equal(t, StepErrorCanceled, canceled.Kind)
+	assert.False(t, canceled.Retryable(policy, ok))
+
+	// Errors without codes are fatal provider failures.
+	unknown := AsStepError(errors.New("boom"))
+	assert.Equal(t, StepErrorProvider, unknown.Kind)
+	assert.Equal(t, codes.Unknown, unknown.Code)
+	assert.False(t, unknown.Retryable(policy, ok))
+
+	// Errors that are already classified keep their classification.
+	precondition := AsStepError(preconditionError(errors.New("refusing to delete protected resource")))
+	assert.Equal(t, StepErrorPrecondition, precondition.Kind)
+	assert.Equal(t, codes.FailedPrecondition, precondition.Code)
+	assert.False(t, precondition.Retryable(policy, ok))
+	assert.Equal(t, "precondition, fatal", precondition.Summary(policy, ok))
+
+	timeout := newStepError(StepErrorTimeout, codes.DeadlineExceeded, errors.New("timed out"))
+	assert.True(t, AsStepError(timeout) == timeout)
+	assert.False(t, timeout.Retryable(policy, resource.StatusUnknown))
+	assert.Equal(t, "timeout, fatal", timeout.Summary(policy, resource.StatusUnknown))
+
+	bail := newStepError(StepErrorBail, codes.Aborted, rpcerror.New(codes.Unavailable, "unavailable"))
+	assert.False(t, bail.Retryable(policy, ok))
+
+	// Failures that may have had side effects are not retryable, whatever their codes.
+	assert.False(t, throttled.Retryable(policy, resource.StatusPartialFailure))
+
+	// The policy's codes replace the defaults.
+	policy.RetryableCodes = []codes.Code{codes.InvalidArgument}
+	assert.False(t, throttled.Retryable(policy, ok))
+	assert.True(t, invalid.Retryable(policy, ok))
+	assert.Equal(t, "provider InvalidArgument, retryable", invalid.Summary(policy, ok))
+}
@@ -19,21 +19,35 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+
 	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
+	"github.com/pulumi/pulumi/pkg/util/tracing"
 )
 
 const (
 	// Dummy workerID for synchronous operations.
 	synchronousWorkerID = -1
-	infiniteWorkerID    = -2
 
 	// Utility constant for easy debugging.
 	stepExecutorLogLevel = 4
+
+	// maxProviderRestarts is the number of times a single step will be retried after its provider's plugin process
+	// has been restarted.
+	maxProviderRestarts = 2
+
+	// timeoutCancelGracePeriod is how long a step that has timed out waits for its canceled provider operation to
+	// return before abandoning it.
+	timeoutCancelGracePeriod = 10 * time.Second
 )
 
 var (
@@ -43,10 +57,31 @@ var (
 	errStepApplyFailed = errors.New("step application failed")
 )
 
+// ExplainedError is the error reported for a failed step when the resource's provider was able to describe the
+// failure in more detail than the error its operation returned.
+type ExplainedError struct {
+	Err     error                 // the error returned by the provider operation.
+	Details plugin.FailureDetails // the provider's description of the failure.
+}
+
+func (e *ExplainedError) Error() string {
+	details := e.Details.String()
+	if details == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v\n%s", e.Err, details)
+}
+
+// Cause returns the error returned by the provider operation.
+func (e *ExplainedError) Cause() error {
+	return e.Err
+}
+
 // The step executor operates in terms of "chains" and "antichains". A chain is set of steps that are totally ordered
 // when ordered by dependency; each step in a chain depends directly on the step that comes before it. An antichain
 // is a set of steps that is completely incomparable when ordered by dependency. The step executor is aware that chains
-// must be executed serially and antichains can be executed concurrently.
+// must be executed serially and antichains can be executed concurrently. Sets of steps with arbitrary dependencies
+// between them, such as the deletes of a plan, are executed as a DAG: see ExecuteDAG.
 //
 // See https://en.wikipedia.org/wiki/Antichain for more complete definitions. The below type aliases are useful for
 // documentation purposes.
@@ -90,9 +125,20 @@ type stepExecutor struct {
 	preview         bool     // Whether or not we are doing a preview.
 	pendingNews     sync.Map // Resources that have been created but are pending a RegisterResourceOutputs.
 	continueOnError bool     // True if we want to continue the plan after a step error.
+	inflight        sync.Map // Steps whose provider operations may report progress, keyed by URN.
+	reporters       sync.Map // In-process providers that have been told to report progress to this executor.
 
-	workers        sync.WaitGroup     // WaitGroup tracking the worker goroutines that are owned by this step executor.
-	incomingChains chan incomingChain // Incoming chains that we are to execute
+	workers sync.WaitGroup // WaitGroup tracking the worker goroutines that are owned by this step executor.
+	pool    *chainPool     // The pool that executes chains not bound for a provider's pool, if parallelism is bounded.
+
+	poolLock      sync.Mutex            // Lock protecting providerPools and nextWorkerID.
+	providerPools map[string]*chainPool // The pools that execute each provider's chains, keyed by reference.
+	nextWorkerID  int                   // The ID of the next worker to be launched.
+	slots         chan struct{}         // Bounds the steps executing at once across all pools, if non-nil.
+
+	shareLock  sync.Mutex     // Lock protecting shares and shareFreed.
+	shares     map[string]int // The number of steps executing for each provider, keyed by reference.
+	shareFreed chan struct{}  // Closed, and replaced, whenever a step releases its provider's share.
 
 	ctx      context.Context    // cancellation context for the current plan.
 	cancel   context.CancelFunc // CancelFunc that cancels the above context.
@@ -106,18 +152,35 @@ type stepExecutor struct {
 //
 
 // Execute submits a Chain for asynchronous execution. The execution of the chain will begin as soon as there
-// is a worker available to execute it.
+// is a worker available to execute it. Submitting a chain never blocks, even if every worker is busy.
 func (se *stepExecutor) ExecuteSerial(chain chain) completionToken {
-	// The select here is to avoid blocking on a send to se.incomingChains if a cancellation is pending.
-	// If one is pending, we should exit early - we will shortly be tearing down the engine and exiting.
-
 	completion := make(chan bool)
-	select {
-	case se.incomingChains <- incomingChain{Chain: chain, CompletionChan: completion}:
-	case <-se.ctx.Done():
+	request := incomingChain{Chain: chain, CompletionChan: completion}
+
+	// If a cancellation is pending, we should exit early - we will shortly be tearing down the engine and exiting.
+	if se.ctx.Err() != nil {
 		close(completion)
+		return completionToken{channel: completion}
+	}
+
+	// If we're being asked to run as parallel as possible, each chain is executed by a oneshot worker of its own.
+	if se.opts.InfiniteParallelism() {
+		se.poolLock.Lock()
+		workerID := se.nextWorkerID
+		se.nextWorkerID++
+		se.workers.Add(1)
+		se.poolLock.Unlock()
+
+		go func() {
+			defer se.workers.Done()
+			se.log(workerID, "launching oneshot worker")
+			se.executeChain(workerID, request.Chain)
+			close(request.CompletionChan)
+		}()
+		return completionToken{channel: completion}
 	}
 
+	se.poolFor(chain).submit(request)
 	return completionToken{channel: completion}
 }
 
@@ -190,7 +253,15 @@ func (se *stepExecutor) Errored() bool {
 // SignalCompletion signals to the stepExecutor that there are no more chains left to execute. All worker
 // threads will terminate as soon as they retire all of the work they are currently executing.
 func (se *stepExecutor) SignalCompletion() {
-	close(se.incomingChains)
+	se.poolLock.Lock()
+	defer se.poolLock.Unlock()
+
+	if se.pool != nil {
+		se.pool.close()
+	}
+	for _, pool := range se.providerPools {
+		pool.close()
+	}
 }
 
 // WaitForCompletion blocks the calling goroutine until the step executor completes execution of all in-flight
@@ -202,11 +273,181 @@ func (se *stepExecutor) WaitForCompletion() {
 }
 
 //
-// As calls to `Execute` submit chains for execution, some number of worker goroutines will continuously
-// read from `incomingChains` and execute any chains that are received. The core execution logic is in
-// the next few functions.
+// As calls to `Execute` submit chains for execution, the workers of a pool (see chainPool) take them from
+// the pool and execute them. The core execution logic is in the next few functions.
 //
 
+// poolFor returns the pool to which the given chain should be submitted. When running with a bounded degree of
+// parallelism greater than one, each provider's chains are executed by that provider's own pool of workers, so that a
+// slow or throttled provider occupies only its own workers rather than starving the others. A provider's pool is
+// launched the first time one of its chains is submitted. However many pools there are, no more steps than the plan's
+// degree of parallelism execute at once, and each provider's steps are limited to its share of that degree (see
+// providerShare).
+func (se *stepExecutor) poolFor(chain chain) *chainPool {
+	if se.opts.DegreeOfParallelism() <= 1 || len(chain) == 0 {
+		return se.pool
+	}
+	ref := chain[0].Provider()
+	if ref == "" {
+		return se.pool
+	}
+
+	se.poolLock.Lock()
+	defer se.poolLock.Unlock()
+
+	pool, ok := se.providerPools[ref]
+	if !ok {
+		size := se.providerPoolSize(ref)
+		se.log(synchronousWorkerID, "launching %d workers for provider %s", size, ref)
+
+		pool = se.newChainPool(size)
+		se.providerPools[ref] = pool
+	}
+	return pool
+}
+
+// providerPoolSize returns the number of workers to launch for the provider with the given reference. An explicit
+// setting for the provider's package takes precedence; otherwise, the provider's own hint is used. Either is only used
+// if it is lower than the plan's degree of parallelism, which bounds every pool.
+func (se *stepExecutor) providerPoolSize(ref string) int {
+	size := se.opts.DegreeOfParallelism()
+
+	providerRef, err := providers.ParseReference(ref)
+	if err != nil {
+		return size
+	}
+	if n := se.opts.ProviderParallel[providers.GetProviderPackage(providerRef.URN().Type())]; n > 0 {
+		if n < size {
+			return n
+		}
+		return size
+	}
+	if provider, ok := se.plan.GetProvider(providerRef); ok {
+		if hinter, ok := provider.(plugin.ParallelismHinter); ok {
+			if n := hinter.MaxParallelism(); n > 0 && n < size {
+				return n
+			}
+		}
+	}
+	return size
+}
+
+// acquireBudget waits for the step's provider to have a free share (see acquireShare), then for a slot in the plan's
+// own parallelism budget, and then for one in the parallelism budget it shares with other plans, if it has one. It
+// returns false if the plan was canceled before slots became available.
+func (se *stepExecutor) acquireBudget(workerID int, step Step) bool {
+	if !se.acquireShare(workerID, step.Provider()) {
+		return false
+	}
+	if !se.acquireSlot(workerID, se.slots, "the plan's parallelism budget") {
+		se.releaseShare(step.Provider())
+		return false
+	}
+	if !se.acquireSlot(workerID, se.opts.Budget, "the shared parallelism budget") {
+		releaseSlot(se.slots)
+		se.releaseShare(step.Provider())
+		return false
+	}
+	return true
+}
+
+// releaseBudget returns the slots acquired by acquireBudget for the given step.
+func (se *stepExecutor) releaseBudget(step Step) {
+	releaseSlot(se.opts.Budget)
+	releaseSlot(se.slots)
+	se.releaseShare(step.Provider())
+}
+
+// providerShare returns the number of steps each provider may execute at once: the plan's degree of parallelism
+// divided evenly among the providers the plan has loaded, but at least one. Reserving each provider a share of the
+// budget keeps a slow provider from occupying every slot while the steps of other providers wait. Providers that are
+// loaded later, e.g. default providers, shrink the shares of the others as their steps complete.
+func (se *stepExecutor) providerShare() int {
+	dop := se.opts.DegreeOfParallelism()
+	if se.plan == nil || se.plan.providers == nil {
+		return dop
+	}
+	if n := se.plan.providers.Len(); n > 1 {
+		dop /= n
+	}
+	if dop < 1 {
+		return 1
+	}
+	return dop
+}
+
+// acquireShare waits until the provider with the given reference is executing fewer steps than its share, if the
+// plan's parallelism is bounded and the step has a provider. It returns false if the plan was canceled first.
+func (se *stepExecutor) acquireShare(workerID int, ref string) bool {
+	if se.shares == nil || ref == "" {
+		return true
+	}
+
+	logged := false
+	for {
+		se.shareLock.Lock()
+		if se.shares[ref] < se.providerShare() {
+			se.shares[ref]++
+			se.shareLock.Unlock()
+			return true
+		}
+		freed := se.shareFreed
+		se.shareLock.Unlock()
+
+		if !logged {
+			se.log(workerID, "waiting for a slot in the share of provider %s", ref)
+			logged = true
+		}
+		select {
+		case <-freed:
+		case <-se.ctx.Done():
+			return false
+		}
+	}
+}
+
+// releaseShare returns a slot acquired by acquireShare to the share of the provider with the given reference.
+func (se *stepExecutor) releaseShare(ref string) {
+	if se.shares == nil || ref == "" {
+		return
+	}
+
+	se.shareLock.Lock()
+	defer se.shareLock.Unlock()
+	se.shares[ref]--
+	close(se.shareFreed)
+	se.shareFreed = make(chan struct{})
+}
+
+// acquireSlot waits for a slot in the given semaphore, if it is non-nil. It returns false if the plan was canceled
+// before a slot became available.
+func (se *stepExecutor) acquireSlot(workerID int, slots chan struct{}, name string) bool {
+	if slots == nil {
+		return true
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	se.log(workerID, "waiting for a slot in %s", name)
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-se.ctx.Done():
+		return false
+	}
+}
+
+// releaseSlot returns a slot acquired by acquireSlot to the given semaphore, if it is non-nil.
+func releaseSlot(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
 // executeChain executes a chain, one step at a time. If any step in the chain fails to execute, or if the
 // context is canceled, the chain stops execution.
 func (se *stepExecutor) executeChain(workerID int, chain chain) {
@@ -218,7 +459,13 @@ func (se *stepExecutor) executeChain(workerID int, chain chain) {
 		default:
 		}
 
-		if err := se.executeStep(workerID, step); err != nil {
+		if !se.acquireBudget(workerID, step) {
+			se.log(workerID, "step %v on %v canceled while waiting for budget", step.Op(), step.URN())
+			return
+		}
+		err := se.executeStep(workerID, step)
+		se.releaseBudget(step)
+		if err != nil {
 			se.log(workerID, "step %v on %v failed, signalling cancellation", step.Op(), step.URN())
 			se.cancelDueToError()
 			if err != errStepApplyFailed {
@@ -253,6 +500,317 @@ func (se *stepExecutor) cancelDueToError() {
 // verbatim to the post-step event.
 //
 
+// watchProgress arranges for any progress reported by the provider of the given step's resource to be relayed to the
+// plan's event handlers. Provider plugins report progress over the engine RPC, whose reports the plan's plugin context
+// relays to the step executor; in-process providers that implement plugin.ProgressReporter are told where to report
+// the first time one of their steps is applied. It returns true if the step was registered to receive progress
+// reports.
+func (se *stepExecutor) watchProgress(step Step) bool {
+	if se.preview || se.opts.Events == nil {
+		return false
+	}
+
+	prov, err := getProvider(step)
+	if err != nil {
+		return false
+	}
+	if reporter, ok := prov.(plugin.ProgressReporter); ok {
+		if _, seen := se.reporters.LoadOrStore(reporter, true); !seen {
+			reporter.SetProgressFunc(se.reportProgress)
+		}
+	}
+
+	se.inflight.Store(step.URN(), step)
+	return true
+}
+
+// reportProgress relays a progress report from a provider to the plan's event handlers.
+func (se *stepExecutor) reportProgress(urn resource.URN, percent int, message string) {
+	step, ok := se.inflight.Load(urn)
+	if !ok {
+		logging.V(stepExecutorLogLevel).Infof("StepExecutor: ignoring progress for %v, which has no step in flight", urn)
+		return
+	}
+	se.opts.Events.OnResourceStepProgress(step.(Step), percent, message)
+}
+
+// applyStep applies the given step, retrying it according to the plan's retry policy if it fails with a transient
+// provider error. Each retry is reported as a warning. If the step fails because its provider's plugin process has
+// exited, the plugin is restarted and the step is retried.
+func (se *stepExecutor) applyStep(workerID int, step Step) (resource.Status, StepCompleteFunc, error) {
+	if se.watchProgress(step) {
+		defer se.inflight.Delete(step.URN())
+	}
+
+	if se.preview && se.opts.ValidatePreview {
+		if err := se.validatePreview(workerID, step); err != nil {
+			return resource.StatusOK, nil, err
+		}
+	}
+
+	se.configureOperation(step)
+
+	policy := se.opts.Retry
+	restarts := 0
+	for attempt := 1; ; attempt++ {
+		se.log(workerID, "applying step %v on %v (preview %v, attempt %d)", step.Op(), step.URN(), se.preview, attempt)
+		generation := se.providerRestarts(step)
+		status, stepComplete, err := se.applyWithTimeout(workerID, step)
+		if err != nil && status == resource.StatusOK && restarts < maxProviderRestarts &&
+			se.restartProvider(workerID, step, generation, err) {
+			restarts++
+			continue
+		}
+		if !policy.ShouldRetry(attempt, status, err) {
+			return status, stepComplete, err
+		}
+
+		delay := policy.Backoff(attempt + 1)
+		se.log(workerID, "step %v on %v failed with a retryable error, retrying in %v: %v",
+			step.Op(), step.URN(), delay, err)
+		se.plan.Diag().Warningf(diag.RawMessage(step.URN(), fmt.Sprintf(
+			"%s failed with a transient error (attempt %d of %d); retrying in %v: %v",
+			step.Op(), attempt, policy.MaxAttempts, delay, err)))
+		if se.opts.Events != nil {
+			se.opts.Events.OnResourceStepRetry(step, attempt, err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-se.ctx.Done():
+			// The operation is stopping after another failure, so give up on the step.
+			return status, stepComplete, newStepError(StepErrorBail, codes.Aborted, err)
+		}
+	}
+}
+
+// validatePreview asks the provider of the resource that the given step creates or updates to validate the operation
+// without performing it, if the provider supports it. Other steps, and those whose inputs are not yet known, are not
+// validated.
+func (se *stepExecutor) validatePreview(workerID int, step Step) error {
+	var validate func(plugin.PreviewValidator) error
+	switch s := step.(type) {
+	case *CreateStep:
+		if !s.new.Custom || s.new.Inputs.ContainsUnknowns() {
+			return nil
+		}
+		validate = func(v plugin.PreviewValidator) error { return v.PreviewCreate(s.URN(), s.new.Inputs) }
+	case *UpdateStep:
+		if !s.new.Custom || s.new.Inputs.ContainsUnknowns() {
+			return nil
+		}
+		olds := s.old.Outputs
+		if s.migrated != nil {
+			olds = s.migrated
+		}
+		validate = func(v plugin.PreviewValidator) error { return v.PreviewUpdate(s.URN(), s.old.ID, olds, s.new.Inputs) }
+	default:
+		return nil
+	}
+
+	prov, err := getProvider(step)
+	if err != nil {
+		return err
+	}
+	validator, ok := prov.(plugin.PreviewValidator)
+	if !ok {
+		return nil
+	}
+
+	se.log(workerID, "validating step %v on %v with its provider", step.Op(), step.URN())
+	if err = validate(validator); err != nil {
+		return errors.Wrapf(err, "the provider rejected this %s", step.Op())
+	}
+	return nil
+}
+
+// stepTimeout returns the time the provider may take to apply the given step, or zero if the step is not bounded. A
+// resource's own timeouts take precedence over those configured for its type.
+func (se *stepExecutor) stepTimeout(step Step) time.Duration {
+	var res *resource.State
+	switch step.Op() {
+	case OpCreate, OpCreateReplacement, OpUpdate:
+		res = step.New()
+	case OpDelete, OpDeleteReplaced, OpPrune:
+		res = step.Old()
+	}
+	if res == nil {
+		return 0
+	}
+
+	timeouts := res.CustomTimeouts.WithDefaults(se.opts.CustomTimeouts[res.Type])
+	switch step.Op() {
+	case OpCreate, OpCreateReplacement:
+		return timeouts.Create
+	case OpUpdate:
+		return timeouts.Update
+	default:
+		return timeouts.Delete
+	}
+}
+
+// configureOperation tells the provider of the given step's resource the settings for the step's operation, if the
+// provider implements plugin.OperationConfigurer. The settings come from the resource's state, with defaults taken from
+// those configured for its type, so an update that resumes after an interruption honors the same constraints.
+func (se *stepExecutor) configureOperation(step Step) {
+	var res *resource.State
+	switch step.Op() {
+	case OpCreate, OpCreateReplacement, OpUpdate, OpRead, OpReadReplacement, OpImport:
+		res = step.New()
+	case OpDelete, OpDeleteReplaced, OpPrune, OpRefresh:
+		res = step.Old()
+	}
+	if res == nil || !res.Custom {
+		return
+	}
+
+	prov, err := getProvider(step)
+	if err != nil {
+		return
+	}
+	if configurer, ok := prov.(plugin.OperationConfigurer); ok {
+		timeouts := res.CustomTimeouts.WithDefaults(se.opts.CustomTimeouts[res.Type])
+		configurer.ConfigureOperation(step.URN(), plugin.OperationSettings{
+			Timeout:      se.stepTimeout(step),
+			PollInterval: timeouts.PollInterval,
+		})
+	}
+}
+
+// detachableStep is implemented by steps that write to their states as they are applied. A step that times out may be
+// abandoned while its provider operation is still running, so such steps are applied to private copies of their states
+// that are only moved into the plan's states if the step finishes in time. Steps that only read their states, such as
+// deletes, need not implement it.
+type detachableStep interface {
+	Step
+	// detach returns a copy of the step that applies itself to private copies of the step's states, along with a
+	// function that moves the copy's results into the step's own states.
+	detach() (Step, func())
+}
+
+// applyWithTimeout applies the given step, failing it if it does not finish within the step's timeout. The timeout
+// covers the whole of the step, including any wait for the resource to become ready after its provider operation
+// returns. If the provider implements plugin.OperationCanceler, the operation is canceled; if it does not return
+// promptly once canceled, or cannot be canceled, it is abandoned and its result ignored. The outcome of a step that
+// timed out is unknown unless the canceled operation reports otherwise.
+func (se *stepExecutor) applyWithTimeout(workerID int, step Step) (resource.Status, StepCompleteFunc, error) {
+	timeout := se.stepTimeout(step)
+	if se.preview || timeout <= 0 {
+		return step.Apply(se.preview)
+	}
+
+	// Apply a detachable step to copies of its states, so that an abandoned operation cannot race with the plan.
+	applied, attach := step, func() {}
+	if d, ok := step.(detachableStep); ok {
+		applied, attach = d.detach()
+	}
+
+	type applyResult struct {
+		status   resource.Status
+		complete StepCompleteFunc
+		err      error
+	}
+	done := make(chan applyResult, 1)
+	go func() {
+		status, complete, err := applied.Apply(se.preview)
+		done <- applyResult{status: status, complete: complete, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		attach()
+		return r.status, r.complete, r.err
+	case <-timer.C:
+	}
+
+	se.log(workerID, "step %v on %v timed out after %v", step.Op(), step.URN(), timeout)
+	timeoutErr := newStepError(StepErrorTimeout, codes.DeadlineExceeded,
+		errors.Errorf("%s of '%s' timed out after %v", step.Op(), step.URN(), timeout))
+
+	// Unless the canceled operation reports otherwise, we cannot know whether it took effect.
+	status := resource.StatusUnknown
+	if prov, err := getProvider(step); err == nil {
+		if canceler, ok := prov.(plugin.OperationCanceler); ok {
+			canceler.CancelOperation(step.URN())
+			select {
+			case r := <-done:
+				attach()
+				if r.err == nil {
+					// The operation finished before it could be canceled.
+					return r.status, r.complete, nil
+				}
+				status = r.status
+			case <-time.After(timeoutCancelGracePeriod):
+			}
+		}
+	}
+	return status, nil, timeoutErr
+}
+
+// providerRestarts returns the number of times the plugin process for the provider of the given step's resource has
+// been restarted, or zero if the provider cannot be restarted.
+func (se *stepExecutor) providerRestarts(step Step) int {
+	prov, err := getProvider(step)
+	if err != nil {
+		return 0
+	}
+	if r, ok := prov.(plugin.Restartable); ok {
+		return r.Restarts()
+	}
+	return 0
+}
+
+// restartProvider restarts the plugin process for the provider of the given step's resource if it has exited. It
+// returns true if the step should be retried: that is, if the provider was restarted since the step was applied,
+// either by this call or by another worker whose step failed at the same time.
+func (se *stepExecutor) restartProvider(workerID int, step Step, generation int, err error) bool {
+	prov, provErr := getProvider(step)
+	if provErr != nil {
+		return false
+	}
+
+	restarted, restartErr := se.plan.ctx.Host.RestartProvider(prov)
+	if restartErr != nil {
+		se.log(workerID, "failed to restart provider for step %v on %v: %v", step.Op(), step.URN(), restartErr)
+		se.plan.Diag().Warningf(diag.RawMessage(step.URN(), fmt.Sprintf(
+			"the provider for %s exited unexpectedly and could not be restarted: %v", step.URN(), restartErr)))
+		return false
+	}
+	if restarted {
+		se.log(workerID, "restarted provider for step %v on %v after error: %v", step.Op(), step.URN(), err)
+		if se.opts.Events != nil {
+			se.opts.Events.OnProviderRestart(step, err)
+		}
+		return true
+	}
+	return se.providerRestarts(step) > generation
+}
+
+// explainFailure asks the provider of the given step's resource to describe the error that failed the step. If the
+// provider is able to do so, the error is wrapped in an ExplainedError; otherwise, it is returned as-is.
+func (se *stepExecutor) explainFailure(workerID int, step Step, err error) error {
+	prov, provErr := getProvider(step)
+	if provErr != nil {
+		return err
+	}
+	explainer, ok := prov.(plugin.FailureExplainer)
+	if !ok {
+		return err
+	}
+
+	details, explainErr := explainer.ExplainFailure(step.URN(), err)
+	if explainErr != nil {
+		se.log(workerID, "failed to explain failure of step %v on %v: %v", step.Op(), step.URN(), explainErr)
+		return err
+	}
+	if details == nil {
+		return err
+	}
+	return &ExplainedError{Err: err, Details: *details}
+}
+
 // executeStep executes a single step, returning true if the step execution was successful and
 // false if it was not.
 func (se *stepExecutor) executeStep(workerID int, step Step) error {
@@ -267,8 +825,23 @@ func (se *stepExecutor) executeStep(workerID int, step Step) error {
 		}
 	}
 
-	se.log(workerID, "applying step %v on %v (preview %v)", step.Op(), step.URN(), se.preview)
-	status, stepComplete, err := step.Apply(se.preview)
+	// Trace the application of the step. Provider calls made on behalf of the step's resource are parented to the
+	// step's span.
+	span, _ := tracing.StartSpan(se.plan.ctx.Request(), "pulumi-step", opentracing.Tags{
+		tracing.URNTag: string(step.URN()),
+		tracing.OpTag:  string(step.Op()),
+		"pulumi.type":  string(step.Type()),
+	})
+	se.plan.ctx.SetResourceSpan(step.URN(), span)
+	status, stepComplete, err := se.applyStep(workerID, step)
+	se.plan.ctx.SetResourceSpan(step.URN(), nil)
+	if err != nil {
+		// Classify the failure, and ask the provider to explain the error that caused it.
+		stepErr := AsStepError(err)
+		stepErr.Err = se.explainFailure(workerID, step, stepErr.Err)
+		err = stepErr
+	}
+	span.End(err)
 
 	if err == nil {
 		// If we have a state object, and this is a create or update, remember it, as we may need to update it later.
@@ -327,59 +900,17 @@ func (se *stepExecutor) log(workerID int, msg string, args ...interface{}) {
 // executing steps. By default, as we ease into the waters of parallelism, there is at most one worker
 // active.
 //
-// Workers continuously pull from se.incomingChains, executing chains as they are provided to the executor.
+// Workers continuously take chains from their pool (se.pool, or their provider's pool in se.providerPools),
+// executing chains as they are provided to the executor. Each worker prefers the chains queued on its own
+// deque, and steals from the other workers of its pool once its own deque is empty.
 // There are two reasons why a worker would exit:
 //
 //  1. A worker exits if se.ctx is canceled. There are two ways that se.ctx gets canceled: first, if there is
 //     a step error in another worker, it will cancel the context. Second, if the plan executor experiences an
 //     error when generating steps or doing pre or post-step events, it will cancel the context.
-//  2. A worker exits if it experiences an error when running a step.
+//  2. A worker exits once its pool is closed and there are no more chains for it to execute.
 //
 
-// worker is the base function for all step executor worker goroutines. It continuously polls for new chains
-// and executes any that it gets from the channel. If `launchAsync` is true, worker launches a new goroutine
-// that will execute the chain so that the execution continues asynchronously and this worker can proceed to
-// the next chain.
-func (se *stepExecutor) worker(workerID int, launchAsync bool) {
-	se.log(workerID, "worker coming online")
-	defer se.workers.Done()
-
-	oneshotWorkerID := 0
-	for {
-		se.log(workerID, "worker waiting for incoming chains")
-		select {
-		case request := <-se.incomingChains:
-			if request.Chain == nil {
-				se.log(workerID, "worker received nil chain, exiting")
-				return
-			}
-
-			se.log(workerID, "worker received chain for execution")
-			if !launchAsync {
-				se.executeChain(workerID, request.Chain)
-				close(request.CompletionChan)
-				continue
-			}
-
-			// If we're launching asynchronously, make up a new worker ID for this new oneshot worker and record its
-			// launch with our worker wait group.
-			se.workers.Add(1)
-			newWorkerID := oneshotWorkerID
-			go func() {
-				defer se.workers.Done()
-				se.log(newWorkerID, "launching oneshot worker")
-				se.executeChain(newWorkerID, request.Chain)
-				close(request.CompletionChan)
-			}()
-
-			oneshotWorkerID++
-		case <-se.ctx.Done():
-			se.log(workerID, "worker exiting due to cancellation")
-			return
-		}
-	}
-}
-
 func newStepExecutor(ctx context.Context, cancel context.CancelFunc, plan *Plan, opts Options,
 	preview, continueOnError bool) *stepExecutor {
 	exec := &stepExecutor{
@@ -387,26 +918,34 @@ func newStepExecutor(ctx context.Context, cancel context.CancelFunc, plan *Plan,
 		opts:            opts,
 		preview:         preview,
 		continueOnError: continueOnError,
-		incomingChains:  make(chan incomingChain),
+		providerPools:   make(map[string]*chainPool),
 		ctx:             ctx,
 		cancel:          cancel,
 	}
 
 	exec.sawError.Store(false)
 
-	// If we're being asked to run as parallel as possible, spawn a single worker that launches chain executions
-	// asynchronously.
+	// Steps that wait on their own stop when the plan is canceled, and wait no longer than their timeouts allow.
+	plan.execCtx, plan.typeTimeouts = ctx, opts.CustomTimeouts
+
+	// Provider plugins report progress to the plan's plugin context, which relays their reports to the executor.
+	if !preview && opts.Events != nil && plan.ctx != nil {
+		plan.ctx.SetProgressFunc(exec.reportProgress)
+	}
+
+	// If we're being asked to run as parallel as possible, each chain is executed by a oneshot worker of its own
+	// (see ExecuteSerial), so there is no pool.
 	if opts.InfiniteParallelism() {
-		exec.workers.Add(1)
-		go exec.worker(infiniteWorkerID, true /*launchAsync*/)
 		return exec
 	}
 
-	// Otherwise, launch a worker goroutine for each degree of parallelism.
-	fanout := opts.DegreeOfParallelism()
-	for i := 0; i < fanout; i++ {
-		exec.workers.Add(1)
-		go exec.worker(i, false /*launchAsync*/)
+	// Otherwise, launch a pool with a worker goroutine for each degree of parallelism. Providers' pools may launch
+	// more workers, so steps also take a slot in the plan's own budget, which bounds them to the same degree.
+	exec.pool = exec.newChainPool(opts.DegreeOfParallelism())
+	if opts.DegreeOfParallelism() > 1 {
+		exec.slots = make(chan struct{}, opts.DegreeOfParallelism())
+		exec.shares = make(map[string]int)
+		exec.shareFreed = make(chan struct{})
 	}
 
 	return exec
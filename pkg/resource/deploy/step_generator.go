@@ -15,6 +15,9 @@
 package deploy
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi/pkg/apitype"
 
@@ -51,6 +54,10 @@ type stepGenerator struct {
 	dependentReplaceKeys map[resource.URN][]resource.PropertyKey
 	// a map from old names (aliased URNs) to the new URN that aliased to them.
 	aliased map[resource.URN]resource.URN
+	// a cache of the default provider upgrades observed in this plan, keyed by old and new provider reference.
+	upgrades map[string]defaultProviderUpgrade
+	// the hashes of the dependency closures of the resources registered so far, if the plan uses a check cache.
+	closures map[resource.URN]string
 }
 
 // GenerateReadSteps is responsible for producing one or more steps required to service
@@ -76,6 +83,19 @@ func (sg *stepGenerator) GenerateReadSteps(event ReadResourceEvent) ([]Step, res
 		nil, /* aliases */
 	)
 	old, hasOld := sg.plan.Olds()[urn]
+	if hasOld {
+		newState.Annotations = old.Annotations
+		newState.Artifacts = old.Artifacts
+	}
+
+	// If we are importing resources, the resource must not already be part of the stack.
+	if sg.opts.ImportOnly {
+		if hasOld {
+			return nil, result.Errorf("resource '%v' already exists in the stack", urn)
+		}
+		sg.reads[urn] = true
+		return []Step{NewImportStep(sg.plan, event, newState)}, nil
+	}
 
 	// If the snapshot has an old resource for this URN and it's not external, we're going
 	// to have to delete the old resource and conceptually replace it with the resource we
@@ -119,6 +139,12 @@ func (sg *stepGenerator) GenerateReadSteps(event ReadResourceEvent) ([]Step, res
 func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, result.Result) {
 	var invalid bool // will be set to true if this object fails validation.
 
+	// Let the plan's transformations rewrite the resource's goal state before anything else looks at it.
+	event, res := sg.transform(event)
+	if res != nil {
+		return nil, res
+	}
+
 	goal := event.Goal()
 	// generate an URN for this new resource.
 	urn := sg.plan.generateURN(goal.Parent, goal.Type, goal.Name)
@@ -130,13 +156,15 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 	sg.urns[urn] = true
 
 	// Check for an old resource so that we can figure out if this is a create, delete, etc., and/or to diff.  We look
-	// up first by URN and then by any provided aliases.  If it is found using an alias, record that alias so that we do
-	// not delete the aliased resource later.
+	// up first by URN, then by any provided aliases, and then by any aliases inherited from the resource's parent.  If
+	// it is found using an alias, record that alias so that we do not delete the aliased resource later.
 	var oldInputs resource.PropertyMap
 	var oldOutputs resource.PropertyMap
 	var old *resource.State
 	var hasOld bool
-	for _, urnOrAlias := range append([]resource.URN{urn}, goal.Aliases...) {
+	aliases := goal.Aliases
+	candidates := append(append([]resource.URN{urn}, goal.Aliases...), sg.inheritedAliases(goal)...)
+	for i, urnOrAlias := range candidates {
 		old, hasOld = sg.plan.Olds()[urnOrAlias]
 		if hasOld {
 			oldInputs = old.Inputs
@@ -145,25 +173,96 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 				if previousAliasURN, alreadyAliased := sg.aliased[urnOrAlias]; alreadyAliased {
 					invalid = true
 					sg.plan.Diag().Errorf(diag.GetDuplicateResourceAliasError(urn), urnOrAlias, urn, previousAliasURN)
+				} else if sg.urns[urnOrAlias] {
+					// A resource registered earlier in this plan already has the alias as its URN, and so already
+					// claimed the old resource.
+					invalid = true
+					sg.plan.Diag().Errorf(diag.GetResourceAliasConflictError(urn), urnOrAlias, urnOrAlias, urn)
 				}
 				sg.aliased[urnOrAlias] = urn
+
+				// Record an inherited alias in the new state, as if the program had declared it, so that references
+				// to the old URN are fixed up in the snapshot.
+				if i > len(goal.Aliases) {
+					aliases = append(append([]resource.URN(nil), goal.Aliases...), urnOrAlias)
+				}
+			} else if aliasedBy, aliased := sg.aliased[urn]; aliased {
+				// A resource registered earlier in this plan already claimed the old resource through an alias.
+				invalid = true
+				sg.plan.Diag().Errorf(diag.GetResourceAliasConflictError(urn), urn, urn, aliasedBy)
 			}
 			break
 		}
 	}
 
+	// If this resource's provider has been upgraded, give the new provider a chance to migrate the old state before it
+	// is checked and diffed against the goal.
+	var migrated bool
+	if hasOld && goal.Custom && !old.External {
+		if _, recreating := sg.deletes[urn]; !recreating {
+			var err error
+			oldInputs, oldOutputs, migrated, err = sg.migrateState(urn, old, goal.Provider)
+			if err != nil {
+				return nil, result.FromError(err)
+			}
+		}
+	}
+
+	// Parse the paths of any properties whose changes are to be ignored.
+	ignoreChanges := make([]resource.PropertyPath, 0, len(goal.IgnoreChanges))
+	for _, ignoreChange := range goal.IgnoreChanges {
+		path, err := resource.ParsePropertyPath(ignoreChange)
+		if err != nil {
+			invalid = true
+			sg.plan.Diag().Errorf(diag.GetResourceInvalidError(urn), goal.Type, urn.Name(),
+				fmt.Sprintf("invalid ignoreChanges entry: %v", err))
+			continue
+		}
+		ignoreChanges = append(ignoreChanges, path)
+	}
+
+	// Parse the paths of any properties whose changes must force a replacement.
+	replaceOnChanges := make([]resource.PropertyPath, 0, len(goal.ReplaceOnChanges))
+	for _, replaceOnChange := range goal.ReplaceOnChanges {
+		path, err := resource.ParsePropertyPath(replaceOnChange)
+		if err != nil {
+			invalid = true
+			sg.plan.Diag().Errorf(diag.GetResourceInvalidError(urn), goal.Type, urn.Name(),
+				fmt.Sprintf("invalid replaceOnChanges entry: %v", err))
+			continue
+		}
+		replaceOnChanges = append(replaceOnChanges, path)
+	}
+
+	// Make sure that any readiness conditions are well-formed.
+	for _, waitFor := range goal.WaitFor {
+		if _, err := parseReadinessCondition(waitFor); err != nil {
+			invalid = true
+			sg.plan.Diag().Errorf(diag.GetResourceInvalidError(urn), goal.Type, urn.Name(),
+				fmt.Sprintf("invalid waitFor entry: %v", err))
+		}
+	}
+
 	// Create the desired inputs from the goal state
 	inputs := goal.Properties
 	if hasOld {
 		// Set inputs back to their old values (if any) for any "ignored" properties
-		inputs = sg.processIgnoreChanges(inputs, oldInputs, goal.IgnoreChanges)
+		inputs = sg.processIgnoreChanges(inputs, oldInputs, ignoreChanges)
 	}
 
 	// Produce a new state object that we'll build up as operations are performed.  Ultimately, this is what will
 	// get serialized into the checkpoint file.
 	new := resource.NewState(goal.Type, urn, goal.Custom, false, "", inputs, nil, goal.Parent, goal.Protect, false,
 		goal.Dependencies, goal.InitErrors, goal.Provider, goal.PropertyDependencies, false,
-		goal.AdditionalSecretOutputs, goal.Aliases)
+		goal.AdditionalSecretOutputs, aliases)
+	new.CustomTimeouts = goal.CustomTimeouts
+
+	// Annotations are attached by external tools rather than by the program, and artifacts were produced by earlier
+	// operations, so carry both over from the old state.
+	if hasOld {
+		new.Annotations = old.Annotations
+		new.Artifacts = old.Artifacts
+	}
 
 	// Is this thing a provider resource? If so, stash it - we might need it later when calculating replacement
 	// of resources that use this provider.
@@ -177,6 +276,14 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 		return nil, res
 	}
 
+	// Apply the default tags to the resource's inputs, including the goal's inputs that are checked afresh when the
+	// resource is created anew, if its provider supports them.
+	goalInputs := goal.Properties
+	if prov != nil {
+		inputs = sg.applyDefaultTags(urn, prov, goal.Type, inputs)
+		goalInputs = sg.applyDefaultTags(urn, prov, goal.Type, goalInputs)
+	}
+
 	// We only allow unknown property values to be exposed to the provider if we are performing an update preview.
 	allowUnknowns := sg.plan.preview
 
@@ -186,18 +293,31 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 	// We may be creating this resource if it previously existed in the snapshot as an External resource
 	wasExternal := hasOld && old.External
 
+	// If we are re-creating this resource because it was deleted earlier, the old inputs are now invalid (they got
+	// deleted) so don't consider them. Similarly, if the old resource was External, don't consider those inputs since
+	// Pulumi does not own them.
+	checkOlds, checkNews := oldInputs, inputs
+	if recreating || wasExternal {
+		checkOlds, checkNews = nil, goalInputs
+	}
+	checkKey := sg.checkCacheKey(urn, goal, checkOlds, checkNews)
+
 	// Ensure the provider is okay with this resource and fetch the inputs to pass to subsequent methods.
 	var err error
 	if prov != nil {
 		var failures []plugin.CheckFailure
 
-		// If we are re-creating this resource because it was deleted earlier, the old inputs are now
-		// invalid (they got deleted) so don't consider them. Similarly, if the old resource was External,
-		// don't consider those inputs since Pulumi does not own them.
-		if recreating || wasExternal {
-			inputs, failures, err = prov.Check(urn, nil, goal.Properties, allowUnknowns)
+		// If the resource and everything it depends on are unchanged since an earlier preview checked them, reuse the
+		// inputs that its provider returned then. Providers are always checked, as checking them loads them.
+		cacheable := checkKey != "" && !providers.IsProviderType(goal.Type)
+		if cached, ok := sg.cachedCheck(cacheable, urn, checkKey); ok {
+			logging.V(7).Infof("Planner reusing the cached check of '%v'", urn)
+			inputs = cached
 		} else {
-			inputs, failures, err = prov.Check(urn, oldInputs, inputs, allowUnknowns)
+			inputs, failures, err = prov.Check(urn, checkOlds, checkNews, allowUnknowns)
+			if cacheable && err == nil && len(failures) == 0 {
+				sg.opts.CheckCache.put(urn, checkKey, inputs)
+			}
 		}
 
 		if err != nil {
@@ -206,6 +326,19 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 			invalid = true
 		}
 		new.Inputs = inputs
+
+		// Warn about any deprecated resource type or input property that the program uses.
+		sg.checkDeprecations(urn, prov, goal.Type, goal.Properties)
+	}
+
+	// Give each property change guard a chance to inspect the changes to the resource's inputs. Resources that are
+	// being created, or re-created, have no old inputs to compare against.
+	guardOlds := oldInputs
+	if !hasOld || recreating || wasExternal {
+		guardOlds = nil
+	}
+	if sg.checkPropertyChangeGuards(urn, guardOlds, inputs) {
+		invalid = true
 	}
 
 	// Next, give each analyzer -- if any -- a chance to inspect the resource too.
@@ -297,7 +430,7 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 	//    be replaced, we do so. If it does not, we update the resource in place.
 	if hasOld {
 		contract.Assert(old != nil)
-		diff, err := sg.diff(urn, old, new, oldInputs, oldOutputs, inputs, prov, allowUnknowns)
+		diff, err := sg.diff(urn, old, new, oldInputs, oldOutputs, inputs, prov, allowUnknowns, ignoreChanges)
 		if err != nil {
 			// If the plugin indicated that the diff is unavailable, assume that the resource will be updated and
 			// report the message contained in the error.
@@ -310,6 +443,9 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 
 		}
 
+		// Regardless of what the provider said, changes to any replaceOnChanges properties force a replacement.
+		diff = applyReplaceOnChanges(diff, oldInputs, inputs, replaceOnChanges)
+
 		// Ensure that we received a sensible response.
 		if diff.Changes != plugin.DiffNone && diff.Changes != plugin.DiffSome {
 			return nil, result.Errorf(
@@ -319,13 +455,16 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 		// If there were changes, check for a replacement vs. an in-place update.
 		if diff.Changes == plugin.DiffSome {
 			if diff.Replace() {
+				if sg.isProtected(old, "replace") {
+					return nil, result.Bail()
+				}
 				sg.replaces[urn] = true
 
 				// If we are going to perform a replacement, we need to recompute the default values.  The above logic
 				// had assumed that we were going to carry them over from the old resource, which is no longer true.
 				if prov != nil {
 					var failures []plugin.CheckFailure
-					inputs, failures, err = prov.Check(urn, nil, goal.Properties, allowUnknowns)
+					inputs, failures, err = prov.Check(urn, nil, goalInputs, allowUnknowns)
 					if err != nil {
 						return nil, result.FromError(err)
 					} else if sg.issueCheckErrors(new, urn, failures) {
@@ -351,9 +490,10 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 				//       until pulumi/pulumi#624 is resolved, we cannot safely perform this operation on resources
 				//       that have dependent resources (we try to delete the resource while they refer to it).
 				//
-				// The provider is responsible for requesting which of these two modes to use.
+				// The provider may request the latter mode, as may the program (for an individual resource) or the
+				// plan's options (for every resource of a type).
 
-				if diff.DeleteBeforeReplace || goal.DeleteBeforeReplace {
+				if diff.DeleteBeforeReplace || goal.DeleteBeforeReplace || sg.opts.DeleteBeforeReplace[new.Type] {
 					logging.V(7).Infof("Planner decided to delete-before-replacement for resource '%v'", urn)
 					contract.Assert(sg.plan.depGraph != nil)
 
@@ -379,6 +519,9 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 							if sg.deletes[dependentResource.URN] {
 								continue
 							}
+							if sg.isProtected(dependentResource, "replace") {
+								return nil, result.Bail()
+							}
 
 							sg.dependentReplaceKeys[dependentResource.URN] = toReplace[i].keys
 
@@ -390,6 +533,12 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 							// or not we're going to be replacing this resource.
 							sg.deletes[dependentResource.URN] = true
 						}
+					} else if len(sg.plan.depGraph.DependingOn(old)) > 0 {
+						// Without trustworthy dependencies we cannot tell which dependents must be cascaded, so
+						// they are left referring to the deleted resource until the program updates them.
+						sg.plan.Diag().Warningf(diag.Message(urn, "%v will be deleted before its replacement is "+
+							"created, but resources that depend on it will not be deleted first because this "+
+							"project does not trust resource dependencies"), urn)
 					}
 
 					return append(steps,
@@ -399,11 +548,19 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 					), nil
 				}
 
-				return []Step{
+				// If the replacement must be validated, do so before its registration completes and dependents are
+				// moved to the new resource.
+				steps := []Step{
 					NewCreateReplacementStep(sg.plan, event, old, new, diff.ReplaceKeys, diff.ChangedKeys, true),
+				}
+				if goal.ValidateReplacement {
+					logging.V(7).Infof("Planner decided to validate the replacement for resource '%v'", urn)
+					steps = append(steps, NewValidateReplacementStep(sg.plan, steps[0]))
+				}
+				return append(steps,
 					NewReplaceStep(sg.plan, old, new, diff.ReplaceKeys, diff.ChangedKeys, true),
 					// note that the delete step is generated "later" on, after all creates/updates finish.
-				}, nil
+				), nil
 			}
 
 			// If we fell through, it's an update.
@@ -411,14 +568,16 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 			if logging.V(7) {
 				logging.V(7).Infof("Planner decided to update '%v' (oldprops=%v inputs=%v", urn, oldInputs, new.Inputs)
 			}
-			return []Step{NewUpdateStep(sg.plan, event, old, new, diff.StableKeys, diff.ChangedKeys)}, nil
+			step := NewUpdateStep(sg.plan, event, old, new, diff.StableKeys, diff.ChangedKeys)
+			return []Step{withMigratedOutputs(step, migrated, oldOutputs)}, nil
 		}
 
 		// If resource was unchanged, but there were initialization errors, generate an empty update
 		// step to attempt to "continue" awaiting initialization.
 		if len(old.InitErrors) > 0 {
 			sg.updates[urn] = true
-			return []Step{NewUpdateStep(sg.plan, event, old, new, diff.StableKeys, nil)}, nil
+			step := NewUpdateStep(sg.plan, event, old, new, diff.StableKeys, nil)
+			return []Step{withMigratedOutputs(step, migrated, oldOutputs)}, nil
 		}
 
 		// No need to update anything, the properties didn't change.
@@ -426,7 +585,7 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 		if logging.V(7) {
 			logging.V(7).Infof("Planner decided not to update '%v' (same) (inputs=%v)", urn, new.Inputs)
 		}
-		return []Step{NewSameStep(sg.plan, event, old, new)}, nil
+		return []Step{withMigratedOutputs(NewSameStep(sg.plan, event, old, new), migrated, oldOutputs)}, nil
 	}
 
 	// Case 4: Not Case 1, 2, or 3
@@ -437,12 +596,62 @@ func (sg *stepGenerator) GenerateSteps(event RegisterResourceEvent) ([]Step, res
 	return []Step{NewCreateStep(sg.plan, event, new)}, nil
 }
 
-func (sg *stepGenerator) GenerateDeletes() []Step {
+// inheritedAliases returns the URNs by which a resource with the given goal state would have been known as a child
+// of each old resource that its parent was found under by alias. Children cannot always declare aliases for their
+// parents' old names, so this keeps a refactored parent from replacing its children.
+func (sg *stepGenerator) inheritedAliases(goal *resource.Goal) []resource.URN {
+	if goal.Parent == "" {
+		return nil
+	}
+
+	var aliases []resource.URN
+	for oldParent, newParent := range sg.aliased {
+		if newParent == goal.Parent {
+			aliases = append(aliases, sg.plan.generateURN(oldParent, goal.Type, goal.Name))
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i] < aliases[j] })
+	return aliases
+}
+
+// GenerateCarryOverSteps produces a same step for each live resource in the old snapshot. An import runs these steps
+// before importing anything: the existing resources are left untouched, but imported resources may use them as
+// providers or parents, so they must precede the imported resources in the new snapshot.
+func (sg *stepGenerator) GenerateCarryOverSteps() []Step {
+	if sg.plan.prev == nil {
+		return nil
+	}
+
+	var steps []Step
+	for _, old := range sg.plan.prev.Resources {
+		// Resources that are pending deletion or that predate provider references are simply left where they are.
+		if old.Delete || (old.Custom && old.Provider == "" && !providers.IsProviderType(old.Type)) {
+			continue
+		}
+
+		new := resource.NewState(old.Type, old.URN, old.Custom, false, "", old.Inputs, nil, old.Parent, old.Protect,
+			old.External, old.Dependencies, old.InitErrors, old.Provider, old.PropertyDependencies,
+			old.PendingReplacement, old.AdditionalSecretOutputs, old.Aliases)
+		new.Annotations = old.Annotations
+		new.CustomTimeouts = old.CustomTimeouts
+		new.Artifacts = old.Artifacts
+
+		sg.urns[old.URN] = true
+		sg.sames[old.URN] = true
+		steps = append(steps, NewSameStep(sg.plan, nil, old, new))
+	}
+	return steps
+}
+
+func (sg *stepGenerator) GenerateDeletes() ([]Step, result.Result) {
 	// To compute the deletion list, we must walk the list of old resources *backwards*.  This is because the list is
 	// stored in dependency order, and earlier elements are possibly leaf nodes for later elements.  We must not delete
 	// dependencies prior to their dependent nodes.
 	var dels []Step
+	var protected bool
+	retained := make(map[resource.URN]bool) // the resources needed by those that are left in place.
 	if prev := sg.plan.prev; prev != nil {
+		excluded := sg.excludedResources(prev)
 		for i := len(prev.Resources) - 1; i >= 0; i-- {
 			// If this resource is explicitly marked for deletion or wasn't seen at all, delete it.
 			res := prev.Resources[i]
@@ -475,13 +684,39 @@ func (sg *stepGenerator) GenerateDeletes() []Step {
 				}
 
 				logging.V(7).Infof("Planner decided to delete '%v' due to replacement", res.URN)
+				if sg.isProtected(res, "delete") {
+					protected = true
+					continue
+				}
 				sg.deletes[res.URN] = true
 				dels = append(dels, NewDeleteReplacementStep(sg.plan, res, false))
 			} else if _, aliased := sg.aliased[res.URN]; !sg.sames[res.URN] && !sg.updates[res.URN] && !sg.replaces[res.URN] &&
 				!sg.reads[res.URN] && !aliased {
+				// Resources that are excluded from deletion are left in place, along with everything they need.
+				if excluded[res.URN] || (sg.opts.Prune == PruneOff && retained[res.URN]) {
+					logging.V(7).Infof("Planner decided not to delete excluded '%v'", res.URN)
+					retainDependencies(res, retained)
+					continue
+				}
+
+				// If the plan prunes resources that the program no longer produces, do so instead of deleting them.
+				if sg.opts.Prune != PruneOff && !res.PendingReplacement {
+					step, ok := sg.generatePrune(res, retained)
+					if !ok {
+						protected = true
+					} else if step != nil {
+						dels = append(dels, step)
+					}
+					continue
+				}
+
 				// NOTE: we deliberately do not check sg.deletes here, as it is possible for us to issue multiple
 				// delete steps for the same URN if the old checkpoint contained pending deletes.
 				logging.V(7).Infof("Planner decided to delete '%v'", res.URN)
+				if sg.isProtected(res, "delete") {
+					protected = true
+					continue
+				}
 				sg.deletes[res.URN] = true
 				if !res.PendingReplacement {
 					dels = append(dels, NewDeleteStep(sg.plan, res))
@@ -491,7 +726,125 @@ func (sg *stepGenerator) GenerateDeletes() []Step {
 			}
 		}
 	}
-	return dels
+
+	// Report every protected resource that would be deleted before failing, so that they can all be dealt with at once.
+	if protected {
+		return nil, result.Bail()
+	}
+	return dels, nil
+}
+
+// generatePrune returns the step that prunes the given resource, which the program no longer produces, or nil if the
+// resource is exempt from pruning. Because old resources are visited in reverse dependency order, the resources upon
+// which an orphan that is left in place depends are recorded in retained before they are visited, and are left in place
+// too so that the snapshot remains consistent. The second result is false if the resource is protected.
+func (sg *stepGenerator) generatePrune(res *resource.State, retained map[resource.URN]bool) (Step, bool) {
+	switch {
+	case sg.opts.isPruneExempt(res):
+		logging.V(7).Infof("Planner decided not to prune exempt '%v'", res.URN)
+		retainDependencies(res, retained)
+		return nil, true
+	case sg.opts.Prune == PruneReport || retained[res.URN]:
+		logging.V(7).Infof("Planner decided to report orphaned '%v'", res.URN)
+		retainDependencies(res, retained)
+		return NewPruneStep(sg.plan, res, false), true
+	}
+
+	logging.V(7).Infof("Planner decided to prune '%v'", res.URN)
+	if sg.isProtected(res, "prune") {
+		return nil, false
+	}
+	sg.deletes[res.URN] = true
+	return NewPruneStep(sg.plan, res, true), true
+}
+
+// excludedResources returns the set of old resources that the plan's options exclude from deletion: those that match
+// one of the Exclude patterns and, if ExcludeDependents is set, those that depend upon them. Old resources are stored
+// in dependency order, so each resource's dependencies are visited before it.
+func (sg *stepGenerator) excludedResources(prev *Snapshot) map[resource.URN]bool {
+	excluded := make(map[resource.URN]bool)
+	if len(sg.opts.Exclude) == 0 {
+		return excluded
+	}
+	for _, res := range prev.Resources {
+		if res.Delete {
+			continue
+		}
+		if sg.opts.isExcluded(res) {
+			excluded[res.URN] = true
+			continue
+		}
+		if sg.opts.ExcludeDependents {
+			for _, dep := range dependencyURNs(res) {
+				if excluded[dep] {
+					logging.V(7).Infof("Planner excluding '%v', which depends on excluded '%v'", res.URN, dep)
+					excluded[res.URN] = true
+					break
+				}
+			}
+		}
+	}
+	return excluded
+}
+
+// retainDependencies records in retained the resources upon which the given resource, which is left in place,
+// depends, so that they are left in place too and the snapshot remains consistent.
+func retainDependencies(res *resource.State, retained map[resource.URN]bool) {
+	for _, dep := range dependencyURNs(res) {
+		retained[dep] = true
+	}
+}
+
+// dependencyURNs returns the URNs of the resources upon which the given resource depends: its explicit dependencies,
+// its parent, and its provider.
+func dependencyURNs(res *resource.State) []resource.URN {
+	deps := append([]resource.URN(nil), res.Dependencies...)
+	if res.Parent != "" {
+		deps = append(deps, res.Parent)
+	}
+	if res.Provider != "" {
+		if ref, err := providers.ParseReference(res.Provider); err == nil {
+			deps = append(deps, ref.URN())
+		}
+	}
+	return deps
+}
+
+// checkCacheKey returns the key under which the result of checking the given resource is cached, and records it as the
+// hash of the resource's dependency closure, if the plan is a preview that uses a check cache. Otherwise, or if the key
+// cannot be computed, it returns the empty string.
+func (sg *stepGenerator) checkCacheKey(urn resource.URN, goal *resource.Goal, olds, news resource.PropertyMap) string {
+	if sg.opts.CheckCache == nil || !sg.plan.preview {
+		return ""
+	}
+	key, err := checkCacheKey(urn, goal, olds, news, sg.closures, sg.plan.ctx.Pwd)
+	if err != nil {
+		logging.V(7).Infof("Planner cannot cache the check of '%v': %v", urn, err)
+		return ""
+	}
+	sg.closures[urn] = key
+	return key
+}
+
+// cachedCheck returns the inputs cached by an earlier check of the given resource, if the check is cacheable.
+func (sg *stepGenerator) cachedCheck(cacheable bool, urn resource.URN, key string) (resource.PropertyMap, bool) {
+	if !cacheable {
+		return nil, false
+	}
+	return sg.opts.CheckCache.get(urn, key)
+}
+
+// isProtected returns true and issues an error diagnostic if the given resource is protected and the plan is not
+// allowed to perform the given action (a delete, prune, or replace) on protected resources.
+func (sg *stepGenerator) isProtected(res *resource.State, action string) bool {
+	if !res.Protect || sg.opts.AllowProtected {
+		return false
+	}
+
+	sg.plan.Diag().Errorf(diag.RawMessage(res.URN, fmt.Sprintf(
+		"refusing to %s protected resource '%s'; remove its protect option first, or allow changes to protected "+
+			"resources with --allow-protected", action, res.URN)))
+	return true
 }
 
 // GeneratePendingDeletes generates delete steps for all resources that are pending deletion. This function should be
@@ -600,6 +953,36 @@ func (sg *stepGenerator) ScheduleDeletes(deleteSteps []Step) []antichain {
 	return antichains
 }
 
+// DeleteDependencies returns, for each of the given delete steps, the delete steps that must complete before it may
+// begin: those that delete resources that depend on the resource that it deletes. Unlike the antichains returned by
+// ScheduleDeletes, this lets each delete begin as soon as the resources that depend on its resource are gone, rather
+// than once every delete of an earlier antichain has completed.
+//
+// If the plan does not trust the dependency graph, each step must wait for the step before it, so that the deletes
+// execute serially in the given order.
+func (sg *stepGenerator) DeleteDependencies(deleteSteps []Step) map[Step][]Step {
+	waitsOn := make(map[Step][]Step)
+	if !sg.opts.TrustDependencies {
+		for i := 1; i < len(deleteSteps); i++ {
+			waitsOn[deleteSteps[i]] = []Step{deleteSteps[i-1]}
+		}
+		return waitsOn
+	}
+
+	stepMap := make(map[*resource.State]Step) // a map from resource states to the steps that delete them.
+	for _, step := range deleteSteps {
+		stepMap[step.Res()] = step
+	}
+	for _, step := range deleteSteps {
+		for dep := range sg.plan.depGraph.DependenciesOf(step.Res()) {
+			if depStep, ok := stepMap[dep]; ok {
+				waitsOn[depStep] = append(waitsOn[depStep], step)
+			}
+		}
+	}
+	return waitsOn
+}
+
 // providerChanged diffs the Provider field of old and new resources, returning true if the rest of the step generator
 // should consider there to be a diff between these two resources.
 func (sg *stepGenerator) providerChanged(urn resource.URN, old, new *resource.State) (bool, error) {
@@ -679,7 +1062,8 @@ func (sg *stepGenerator) providerChanged(urn resource.URN, old, new *resource.St
 
 // diff returns a DiffResult for the given resource.
 func (sg *stepGenerator) diff(urn resource.URN, old, new *resource.State, oldInputs, oldOutputs,
-	newInputs resource.PropertyMap, prov plugin.Provider, allowUnknowns bool) (plugin.DiffResult, error) {
+	newInputs resource.PropertyMap, prov plugin.Provider, allowUnknowns bool,
+	ignoreChanges []resource.PropertyPath) (plugin.DiffResult, error) {
 
 	// Before diffing the resource, diff the provider field. If the provider field changes, we may or may
 	// not need to replace the resource.
@@ -716,7 +1100,77 @@ func (sg *stepGenerator) diff(urn resource.URN, old, new *resource.State, oldInp
 	if diff.Changes == plugin.DiffUnknown {
 		diff.Changes = plugin.DiffSome
 	}
-	return diff, nil
+	return filterIgnoredChanges(diff, oldInputs, newInputs, ignoreChanges), nil
+}
+
+// filterIgnoredChanges removes any keys covered by ignoreChanges from the changed and replaced keys reported by a
+// provider's diff. A key is covered if it is ignored outright, or if only paths nested within it are ignored and its
+// old and new inputs are otherwise identical. This silences diffs that a provider reports for values it fills in on the
+// server side. If the provider reported changed keys and all of them were removed, the diff reports no changes.
+func filterIgnoredChanges(diff plugin.DiffResult, oldInputs, newInputs resource.PropertyMap,
+	ignoreChanges []resource.PropertyPath) plugin.DiffResult {
+
+	if len(ignoreChanges) == 0 || diff.Changes != plugin.DiffSome {
+		return diff
+	}
+
+	ignored := func(k resource.PropertyKey) bool {
+		for _, path := range ignoreChanges {
+			if resource.PropertyKey(path[0].(string)) != k {
+				continue
+			}
+			if len(path) == 1 || oldInputs[k].DeepEquals(newInputs[k]) {
+				return true
+			}
+		}
+		return false
+	}
+	filter := func(keys []resource.PropertyKey) []resource.PropertyKey {
+		var result []resource.PropertyKey
+		for _, k := range keys {
+			if !ignored(k) {
+				result = append(result, k)
+			}
+		}
+		return result
+	}
+
+	hadKeys := len(diff.ChangedKeys) > 0 || len(diff.ReplaceKeys) > 0
+	diff.ChangedKeys, diff.ReplaceKeys = filter(diff.ChangedKeys), filter(diff.ReplaceKeys)
+	if hadKeys && len(diff.ChangedKeys) == 0 && len(diff.ReplaceKeys) == 0 {
+		diff.Changes = plugin.DiffNone
+	}
+	return diff
+}
+
+// applyReplaceOnChanges marks the diff as requiring a replacement if the value of any of the given property paths
+// differs between the old and new inputs. The top-level key of each such path is added to the diff's changed and
+// replaced keys.
+func applyReplaceOnChanges(diff plugin.DiffResult, oldInputs, newInputs resource.PropertyMap,
+	replaceOnChanges []resource.PropertyPath) plugin.DiffResult {
+
+	addKey := func(keys []resource.PropertyKey, k resource.PropertyKey) []resource.PropertyKey {
+		for _, key := range keys {
+			if key == k {
+				return keys
+			}
+		}
+		return append(keys, k)
+	}
+
+	for _, path := range replaceOnChanges {
+		oldValue, hasOld := path.Get(oldInputs)
+		newValue, hasNew := path.Get(newInputs)
+		if hasOld == hasNew && oldValue.DeepEquals(newValue) {
+			continue
+		}
+
+		k := resource.PropertyKey(path[0].(string))
+		diff.Changes = plugin.DiffSome
+		diff.ChangedKeys = addKey(diff.ChangedKeys, k)
+		diff.ReplaceKeys = addKey(diff.ReplaceKeys, k)
+	}
+	return diff
 }
 
 // issueCheckErrors prints any check errors to the diagnostics sink.
@@ -739,22 +1193,58 @@ func (sg *stepGenerator) issueCheckErrors(new *resource.State, urn resource.URN,
 }
 
 // processIgnoreChanges sets the value for each ignoreChanges property in inputs to the value from oldInputs.  This has
-// the effect of ensuring that no changes will be made for the corresponding property.
+// the effect of ensuring that no changes will be made for the corresponding property. Nested paths are only honored
+// where the objects and arrays that contain them are present in the new inputs.
 func (sg *stepGenerator) processIgnoreChanges(inputs, oldInputs resource.PropertyMap,
-	ignoreChanges []string) resource.PropertyMap {
+	ignoreChanges []resource.PropertyPath) resource.PropertyMap {
 
 	ignoredInputs := inputs.Copy()
 	for _, ignoreChange := range ignoreChanges {
-		ignoreChangePropertyKey := resource.PropertyKey(ignoreChange)
-		if oldValue, has := oldInputs[ignoreChangePropertyKey]; has {
-			ignoredInputs[ignoreChangePropertyKey] = oldValue
+		if oldValue, has := ignoreChange.Get(oldInputs); has {
+			ignoredInputs, _ = ignoreChange.Set(ignoredInputs, oldValue)
 		} else {
-			delete(ignoredInputs, ignoreChangePropertyKey)
+			ignoredInputs, _ = ignoreChange.Delete(ignoredInputs)
 		}
 	}
 	return ignoredInputs
 }
 
+// applyDefaultTags returns the given inputs of the resource with the given URN and type with the plan's default tags
+// applied, if the resource's provider supports default tags and resources of its type can be tagged. Default tags take
+// precedence over tags of the same name set by the program, so that they can be enforced centrally.
+func (sg *stepGenerator) applyDefaultTags(urn resource.URN, prov plugin.Provider, typ tokens.Type,
+	inputs resource.PropertyMap) resource.PropertyMap {
+
+	if len(sg.opts.DefaultTags) == 0 {
+		return inputs
+	}
+	tagger, ok := prov.(plugin.DefaultTagger)
+	if !ok {
+		return inputs
+	}
+	key := tagger.TagsProperty(typ)
+	if key == "" {
+		return inputs
+	}
+
+	tags := make(resource.PropertyMap)
+	if v, has := inputs[key]; has && !v.IsNull() {
+		if !v.IsObject() {
+			// The tags are unknown, secret, or malformed, so there is nothing to merge with; leave them to the provider.
+			logging.V(7).Infof("Planner not applying default tags to '%v': its %v are not an object", urn, key)
+			return inputs
+		}
+		tags = v.ObjectValue().Copy()
+	}
+	for k, v := range sg.opts.DefaultTags {
+		tags[resource.PropertyKey(k)] = resource.NewStringProperty(v)
+	}
+
+	tagged := inputs.Copy()
+	tagged[key] = resource.NewObjectProperty(tags)
+	return tagged
+}
+
 func (sg *stepGenerator) loadResourceProvider(
 	urn resource.URN, custom bool, provider string, typ tokens.Type) (plugin.Provider, result.Result) {
 
@@ -895,5 +1385,7 @@ func newStepGenerator(plan *Plan, opts Options) *stepGenerator {
 		providers:            make(map[resource.URN]*resource.State),
 		dependentReplaceKeys: make(map[resource.URN][]resource.PropertyKey),
 		aliased:              make(map[resource.URN]resource.URN),
+		upgrades:             make(map[string]defaultProviderUpgrade),
+		closures:             make(map[resource.URN]string),
 	}
 }
@@ -0,0 +1,250 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// chainPool is a work-stealing pool of workers that execute the chains submitted to it. Each worker has a deque of its
+// own: submitted chains are spread across the deques in turn, and each worker takes chains from the front of its own
+// deque until it is empty, at which point it steals from the back of another worker's. Submitting a chain never
+// blocks, so a scheduler may dispatch every step whose dependencies are satisfied without waiting for a worker to
+// become free, and no worker sits idle while chains are queued for one of its peers.
+type chainPool struct {
+	se *stepExecutor // the step executor that owns the pool.
+
+	lock     sync.Mutex
+	ready    *sync.Cond        // signaled when a chain is queued, or the pool is closed or canceled.
+	deques   [][]incomingChain // the chains queued for each worker.
+	next     int               // the index of the deque to which the next chain is submitted.
+	closed   bool              // true once no more chains will be submitted.
+	canceled bool              // true once the step executor's context is canceled.
+	done     chan struct{}     // closed once every worker has exited.
+}
+
+// newChainPool launches a pool with the given number of workers. Callers must hold poolLock unless the step executor
+// is still being constructed.
+func (se *stepExecutor) newChainPool(size int) *chainPool {
+	contract.Assert(size > 0)
+
+	pool := &chainPool{
+		se:     se,
+		deques: make([][]incomingChain, size),
+		done:   make(chan struct{}),
+	}
+	pool.ready = sync.NewCond(&pool.lock)
+
+	var workers sync.WaitGroup
+	for i := 0; i < size; i++ {
+		workers.Add(1)
+		se.workers.Add(1)
+		go func(index, workerID int) {
+			defer se.workers.Done()
+			defer workers.Done()
+			pool.work(index, workerID)
+		}(i, se.nextWorkerID)
+		se.nextWorkerID++
+	}
+
+	// Queued chains are completed without being executed if the plan is canceled, so that no one waits on them
+	// forever.
+	go func() {
+		workers.Wait()
+		close(pool.done)
+	}()
+	go func() {
+		select {
+		case <-se.ctx.Done():
+			pool.cancel()
+		case <-pool.done:
+		}
+	}()
+
+	return pool
+}
+
+// submit queues the given chain for execution by one of the pool's workers. If the pool has been closed or canceled,
+// the chain is completed immediately without being executed.
+func (pool *chainPool) submit(request incomingChain) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if pool.closed || pool.canceled || pool.se.ctx.Err() != nil {
+		close(request.CompletionChan)
+		return
+	}
+
+	pool.deques[pool.next] = append(pool.deques[pool.next], request)
+	pool.next = (pool.next + 1) % len(pool.deques)
+	pool.ready.Signal()
+}
+
+// take returns the next chain that the worker with the given index should execute, waiting until one is queued. It
+// returns false once the pool has been canceled, or has been closed and has no chains left to execute.
+func (pool *chainPool) take(index int) (incomingChain, bool) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for {
+		if pool.canceled {
+			return incomingChain{}, false
+		}
+
+		// Prefer the front of our own deque...
+		if own := pool.deques[index]; len(own) > 0 {
+			request := own[0]
+			pool.deques[index] = own[1:]
+			return request, true
+		}
+
+		// ...and otherwise steal from the back of another worker's.
+		for i := 1; i < len(pool.deques); i++ {
+			victim := (index + i) % len(pool.deques)
+			if theirs := pool.deques[victim]; len(theirs) > 0 {
+				request := theirs[len(theirs)-1]
+				pool.deques[victim] = theirs[:len(theirs)-1]
+				pool.se.log(synchronousWorkerID, "worker %d stole a chain from worker %d", index, victim)
+				return request, true
+			}
+		}
+
+		if pool.closed {
+			return incomingChain{}, false
+		}
+		pool.ready.Wait()
+	}
+}
+
+// work is the base function for each of the pool's worker goroutines. It executes chains until the pool has been
+// closed and drained, or canceled.
+func (pool *chainPool) work(index, workerID int) {
+	se := pool.se
+	se.log(workerID, "worker coming online")
+	for {
+		request, ok := pool.take(index)
+		if !ok {
+			se.log(workerID, "worker exiting")
+			return
+		}
+
+		se.log(workerID, "worker received chain for execution")
+		se.executeChain(workerID, request.Chain)
+		close(request.CompletionChan)
+	}
+}
+
+// close signals that no more chains will be submitted to the pool. Its workers exit once they have executed the
+// chains that are already queued.
+func (pool *chainPool) close() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.closed = true
+	pool.ready.Broadcast()
+}
+
+// cancel completes every queued chain without executing it, and signals the pool's workers to exit.
+func (pool *chainPool) cancel() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for i, deque := range pool.deques {
+		for _, request := range deque {
+			close(request.CompletionChan)
+		}
+		pool.deques[i] = nil
+	}
+	pool.canceled = true
+	pool.ready.Broadcast()
+}
+
+// ExecuteDAG executes the given steps, each of which may begin as soon as the steps upon which it waits have
+// completed. waitsOn maps each step to the steps that must complete before it may begin; steps that are not in the
+// given set are ignored. Unlike a sequence of antichains, in which every step of one antichain must complete before
+// any step of the next may begin, no step waits on steps that it does not depend upon, so wide graphs keep every
+// worker busy. The returned token completes once every step has completed, or once the plan has been canceled and
+// the steps that were executing have completed.
+func (se *stepExecutor) ExecuteDAG(steps []Step, waitsOn map[Step][]Step) completionToken {
+	done := make(chan bool)
+	if len(steps) == 0 {
+		close(done)
+		return completionToken{channel: done}
+	}
+
+	inSet := make(map[Step]bool, len(steps))
+	for _, step := range steps {
+		inSet[step] = true
+	}
+
+	remaining := make(map[Step]int, len(steps))
+	dependents := make(map[Step][]Step)
+	for _, step := range steps {
+		for _, dep := range waitsOn[step] {
+			if inSet[dep] && dep != step {
+				remaining[step]++
+				dependents[dep] = append(dependents[dep], step)
+			}
+		}
+	}
+
+	var lock sync.Mutex
+	dispatched, unfinished := 0, len(steps)
+
+	var dispatch func(step Step)
+	dispatch = func(step Step) {
+		dispatched++
+		token := se.ExecuteSerial(chain{step})
+		go func() {
+			// The step's completion channel is closed even if the plan is canceled, once the step is no longer
+			// executing.
+			<-token.channel
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			unfinished--
+			if se.ctx.Err() == nil {
+				for _, dependent := range dependents[step] {
+					if remaining[dependent]--; remaining[dependent] == 0 {
+						dispatch(dependent)
+					}
+				}
+			}
+
+			// Once the plan is canceled, no more steps are dispatched, so we are done once those that were
+			// dispatched have completed.
+			if unfinished == 0 || (se.ctx.Err() != nil && unfinished == len(steps)-dispatched) {
+				close(done)
+			}
+		}()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, step := range steps {
+		if remaining[step] == 0 {
+			dispatch(step)
+		}
+	}
+	if dispatched == 0 {
+		// Every step waits on another, so none of them can ever begin.
+		close(done)
+	}
+
+	return completionToken{channel: done}
+}
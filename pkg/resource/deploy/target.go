@@ -15,6 +15,8 @@
 package deploy
 
 import (
+	"sort"
+
 	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/tokens"
 )
@@ -25,12 +27,61 @@ type Target struct {
 	Config    config.Map       // optional configuration key/value pairs.
 	Decrypter config.Decrypter // decrypter for secret configuration values.
 	Snapshot  *Snapshot        // the last snapshot deployed to the target.
+
+	// ConfigLayers holds configuration that the target shares with other targets, ordered from lowest to highest
+	// precedence. The target's own Config takes precedence over every layer.
+	ConfigLayers []config.Map
+}
+
+// LayeredConfig returns the target's configuration layered over its shared configuration.
+func (t *Target) LayeredConfig() config.Map {
+	if len(t.ConfigLayers) == 0 {
+		return t.Config
+	}
+
+	cfg := make(config.Map)
+	for _, layer := range t.ConfigLayers {
+		for k, v := range layer {
+			cfg[k] = v
+		}
+	}
+	for k, v := range t.Config {
+		cfg[k] = v
+	}
+	return cfg
+}
+
+// EffectiveConfig returns the configuration in effect for a run against the target with the given overrides: its
+// layered configuration, overlaid with the overrides. It is the configuration that is reported and recorded for the
+// run. Overrides are held in plaintext and cannot be encrypted, so an override of a secret value is left out of the
+// configuration rather than reported or recorded in the clear; the keys of such overrides are returned, in order.
+func (t *Target) EffectiveConfig(overrides map[config.Key]string) (config.Map, []config.Key) {
+	layered := t.LayeredConfig()
+	if len(overrides) == 0 {
+		return layered, nil
+	}
+
+	cfg := make(config.Map, len(layered)+len(overrides))
+	for k, v := range layered {
+		cfg[k] = v
+	}
+	var omitted []config.Key
+	for k, v := range overrides {
+		if cfg[k].Secure() {
+			delete(cfg, k)
+			omitted = append(omitted, k)
+			continue
+		}
+		cfg[k] = config.NewValue(v)
+	}
+	sort.Slice(omitted, func(i, j int) bool { return omitted[i].String() < omitted[j].String() })
+	return cfg, omitted
 }
 
 // GetPackageConfig returns the set of configuration parameters for the indicated package, if any.
 func (t *Target) GetPackageConfig(pkg tokens.Package) (map[config.Key]string, error) {
 	var result map[config.Key]string
-	for k, c := range t.Config {
+	for k, c := range t.LayeredConfig() {
 		if tokens.Package(k.Namespace()) != pkg {
 			continue
 		}
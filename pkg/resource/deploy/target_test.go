@@ -0,0 +1,59 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource/config"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	key := func(name string) config.Key { return config.MustMakeKey("test", name) }
+
+	target := &Target{
+		Config: config.Map{
+			key("stack"):    config.NewValue("stack"),
+			key("password"): config.NewSecureValue("c2VjcmV0"),
+		},
+		ConfigLayers: []config.Map{
+			{key("base"): config.NewValue("base"), key("stack"): config.NewValue("base")},
+		},
+	}
+
+	cfg, omitted := target.EffectiveConfig(nil)
+	assert.Equal(t, config.Map{
+		key("base"):     config.NewValue("base"),
+		key("stack"):    config.NewValue("stack"),
+		key("password"): config.NewSecureValue("c2VjcmV0"),
+	}, cfg)
+	assert.Empty(t, omitted)
+
+	// An override of a secret value cannot be encrypted, so it is omitted rather than reported in the clear.
+	cfg, omitted = target.EffectiveConfig(map[config.Key]string{
+		key("base"):     "override",
+		key("password"): "hunter2",
+	})
+	assert.Equal(t, config.Map{
+		key("base"):  config.NewValue("override"),
+		key("stack"): config.NewValue("stack"),
+	}, cfg)
+	assert.Equal(t, []config.Key{key("password")}, omitted)
+
+	// The target's own configuration is left as it was.
+	assert.Len(t, target.Config, 2)
+}
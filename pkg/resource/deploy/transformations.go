@@ -0,0 +1,73 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+// ResourceTransformation rewrites the goal state of a resource as the program registers it, before the resource is
+// planned. Because transformations are applied by the engine, they apply uniformly to programs written in any
+// language, which makes them suitable for enforcing standards: injecting tags, enforcing naming conventions for the
+// resource's inputs, redirecting the resource to a different provider, and so on.
+//
+// A transformation is given the resource's URN and a copy of its goal, whose fields it may reassign. The maps and
+// slices that the goal refers to belong to the program, and must be copied rather than modified in place. A
+// transformation must not change the resource's type, name, parent, or whether it is custom, since these determine
+// its URN. If a transformation returns an error, the resource is not registered and the plan fails.
+type ResourceTransformation func(urn resource.URN, goal *resource.Goal) error
+
+// transformedRegisterResourceEvent is a registration whose goal state has been rewritten by the plan's
+// transformations.
+type transformedRegisterResourceEvent struct {
+	RegisterResourceEvent
+	goal *resource.Goal
+}
+
+func (e *transformedRegisterResourceEvent) Goal() *resource.Goal {
+	return e.goal
+}
+
+// transform applies the plan's transformations, in order, to the goal state of the given registration, and returns a
+// registration with the resulting goal. If a transformation fails, or changes the resource's URN, an error is
+// reported and the plan bails.
+func (sg *stepGenerator) transform(event RegisterResourceEvent) (RegisterResourceEvent, result.Result) {
+	if len(sg.opts.Transformations) == 0 {
+		return event, nil
+	}
+
+	original := event.Goal()
+	urn := sg.plan.generateURN(original.Parent, original.Type, original.Name)
+	goal := *original
+	for i, transformation := range sg.opts.Transformations {
+		if err := transformation(urn, &goal); err != nil {
+			sg.plan.Diag().Errorf(diag.GetResourceInvalidError(urn), original.Type, original.Name,
+				fmt.Sprintf("transformation %d failed: %v", i, err))
+			return nil, result.Bail()
+		}
+	}
+
+	if goal.Type != original.Type || goal.Name != original.Name || goal.Parent != original.Parent ||
+		goal.Custom != original.Custom {
+		sg.plan.Diag().Errorf(diag.GetResourceInvalidError(urn), original.Type, original.Name,
+			"transformations must not change a resource's type, name, parent, or whether it is custom")
+		return nil, result.Bail()
+	}
+	return &transformedRegisterResourceEvent{RegisterResourceEvent: event, goal: &goal}, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// unexpectedChange describes a step that changes a resource, generated by a plan that expected no changes.
+type unexpectedChange struct {
+	URN   resource.URN            // the resource that the step changes.
+	Op    StepOp                  // the step's operation.
+	Paths []resource.PropertyPath // the input properties that the step changes, if it changes an existing resource.
+}
+
+// unexpectedChanges returns the changes made by those of the given steps that do not leave their resources as they
+// are. Only logical steps are considered, so that a replacement is reported once rather than once for each of its
+// steps. The properties that an update or replacement changes are those whose inputs differ.
+func unexpectedChanges(steps []Step) []unexpectedChange {
+	var changes []unexpectedChange
+	for _, step := range steps {
+		if step.Op() == OpSame || !step.Logical() {
+			continue
+		}
+		change := unexpectedChange{URN: step.URN(), Op: step.Op()}
+		if old, new := step.Old(), step.New(); old != nil && new != nil {
+			for _, pc := range propertyChanges(step.URN(), old.Inputs, new.Inputs) {
+				change.Paths = append(change.Paths, pc.Path)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// checkNoChanges reports an error for each of the given steps that changes a resource if the plan expects no changes,
+// and returns true if there were any.
+func (pe *planExecutor) checkNoChanges(opts Options, steps []Step) bool {
+	if !opts.ExpectNoChanges {
+		return false
+	}
+
+	changes := unexpectedChanges(steps)
+	for _, change := range changes {
+		if len(change.Paths) == 0 {
+			pe.plan.Diag().Errorf(diag.Message(change.URN, "no changes were expected, but the plan would %s this resource"),
+				change.Op)
+			continue
+		}
+		paths := make([]string, len(change.Paths))
+		for i, path := range change.Paths {
+			paths[i] = path.String()
+		}
+		pe.plan.Diag().Errorf(
+			diag.Message(change.URN, "no changes were expected, but the plan would %s this resource, changing: %s"),
+			change.Op, strings.Join(paths, ", "))
+	}
+	return len(changes) > 0
+}
@@ -39,3 +39,13 @@ type ResourceProtectedError struct {
 func (ResourceProtectedError) Error() string {
 	return "Can't delete protected resource"
 }
+
+// InvalidAnnotationKeyError is returned by AnnotateResource if an annotation key is not valid.
+type InvalidAnnotationKeyError struct {
+	Key    string
+	Reason string
+}
+
+func (e InvalidAnnotationKeyError) Error() string {
+	return fmt.Sprintf("Invalid annotation key %q: %s", e.Key, e.Reason)
+}
@@ -15,6 +15,8 @@
 package edit
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/resource"
@@ -79,6 +81,47 @@ func UnprotectResource(_ *deploy.Snapshot, res *resource.State) error {
 	return nil
 }
 
+// AnnotateResource returns an OperationFunc that sets the given annotations on a resource. Each key must be of the form
+// "namespace:name"; an annotation with an empty value is removed from the resource.
+func AnnotateResource(annotations map[string]string) OperationFunc {
+	return func(_ *deploy.Snapshot, res *resource.State) error {
+		for key := range annotations {
+			if err := ValidateAnnotationKey(key); err != nil {
+				return err
+			}
+		}
+
+		for key, value := range annotations {
+			if value == "" {
+				delete(res.Annotations, key)
+				continue
+			}
+			if res.Annotations == nil {
+				res.Annotations = make(map[string]string)
+			}
+			res.Annotations[key] = value
+		}
+		if len(res.Annotations) == 0 {
+			res.Annotations = nil
+		}
+		return nil
+	}
+}
+
+// ValidateAnnotationKey returns an error if the given key is not a valid annotation key. Annotation keys are
+// namespaced so that the annotations attached by different tools do not collide: each key is of the form
+// "namespace:name", where neither part is empty. The "pulumi" namespace is reserved.
+func ValidateAnnotationKey(key string) error {
+	colon := strings.Index(key, ":")
+	if colon <= 0 || colon == len(key)-1 {
+		return InvalidAnnotationKeyError{Key: key, Reason: "keys must be of the form 'namespace:name'"}
+	}
+	if key[:colon] == "pulumi" {
+		return InvalidAnnotationKeyError{Key: key, Reason: "the 'pulumi' namespace is reserved"}
+	}
+	return nil
+}
+
 // LocateResource returns all resources in the given shapshot that have the given URN.
 func LocateResource(snap *deploy.Snapshot, urn resource.URN) []*resource.State {
 	contract.Require(snap != nil, "snap")
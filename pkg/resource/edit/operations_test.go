@@ -205,6 +205,31 @@ func TestUnprotectResource(t *testing.T) {
 	assert.False(t, a.Protect)
 }
 
+func TestAnnotateResource(t *testing.T) {
+	pA := NewProviderResource("a", "p1", "0")
+	a := NewResource("a", pA)
+	a.Annotations = map[string]string{"cost:center": "1234", "owner:team": "infra"}
+	snap := NewSnapshot([]*resource.State{
+		pA,
+		a,
+	})
+
+	err := AnnotateResource(map[string]string{"cost:center": "5678", "owner:team": ""})(snap, a)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cost:center": "5678"}, a.Annotations)
+
+	err = AnnotateResource(map[string]string{"cost:center": ""})(snap, a)
+	assert.NoError(t, err)
+	assert.Nil(t, a.Annotations)
+
+	// Invalid keys are rejected without modifying the resource.
+	for _, key := range []string{"center", ":center", "cost:", "pulumi:center"} {
+		err = AnnotateResource(map[string]string{"ok:key": "value", key: "value"})(snap, a)
+		assert.IsType(t, InvalidAnnotationKeyError{}, err)
+		assert.Nil(t, a.Annotations)
+	}
+}
+
 func TestLocateResourceNotFound(t *testing.T) {
 	pA := NewProviderResource("a", "p1", "0")
 	a := NewResource("a", pA)
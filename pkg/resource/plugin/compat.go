@@ -0,0 +1,162 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// StrictPluginProtocolEnvVar is the environment variable that, when truthy, causes responses from resource providers
+// that contain fields unknown to this version of the engine to be rejected rather than ignored.
+const StrictPluginProtocolEnvVar = "PULUMI_STRICT_PLUGIN_PROTOCOL"
+
+// IsStrictPluginProtocol returns true if unknown fields in provider responses should be treated as errors.
+func IsStrictPluginProtocol() bool {
+	return cmdutil.IsTruthy(os.Getenv(StrictPluginProtocolEnvVar))
+}
+
+// UnknownFieldsError is returned in strict protocol mode when a provider responds with fields that this version of
+// the engine does not understand, typically because the provider was built against a newer version of the protocol.
+type UnknownFieldsError struct {
+	Plugin  string  // the name of the plugin that sent the response.
+	Version string  // the version of the plugin, if known.
+	Message string  // the name of the response message.
+	Fields  []int32 // the numbers of the unknown fields.
+}
+
+func (e *UnknownFieldsError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = fmt.Sprintf("%d", f)
+	}
+	version := e.Version
+	if version == "" {
+		version = "unknown version"
+	}
+	return fmt.Sprintf("plugin '%s' (%s) sent unknown field(s) %s in %s; the plugin may require a newer version "+
+		"of the CLI (unset %s to ignore unknown fields)", e.Plugin, version, strings.Join(fields, ", "), e.Message,
+		StrictPluginProtocolEnvVar)
+}
+
+// checkUnknownFields inspects a response from the provider for fields this engine does not understand. In lenient
+// mode (the default), such fields are logged and ignored; in strict mode, an UnknownFieldsError is returned.
+func (p *provider) checkUnknownFields(label string, resp interface{}) error {
+	fields := unknownFields(resp)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	message := reflect.Indirect(reflect.ValueOf(resp)).Type().Name()
+	if !IsStrictPluginProtocol() {
+		logging.V(7).Infof("%s ignoring unknown field(s) %v in %s", label, fields, message)
+		return nil
+	}
+
+	err := &UnknownFieldsError{
+		Plugin:  string(p.pkg),
+		Version: p.pluginVersion(),
+		Message: message,
+		Fields:  fields,
+	}
+	logging.V(7).Infof("%s failed: %v", label, err)
+	return err
+}
+
+// warnUnknownFields is used in place of checkUnknownFields for responses to operations that have already taken effect
+// by the time the response is received. Failing such an operation would leave the engine unaware of changes the
+// provider has made, so in strict mode the unknown fields are reported as a warning instead.
+func (p *provider) warnUnknownFields(label string, resp interface{}) {
+	if err := p.checkUnknownFields(label, resp); err != nil {
+		p.ctx.Diag.Warningf(diag.Message("", "%v"), err)
+	}
+}
+
+// pluginVersion returns the version reported by the provider, or the empty string if it cannot be determined.
+func (p *provider) pluginVersion() string {
+	resp, err := p.raw().GetPluginInfo(p.ctx.Request(), &pbempty.Empty{})
+	if err != nil {
+		return ""
+	}
+	return resp.GetVersion()
+}
+
+// unknownFields returns the numbers of the fields preserved in a generated protobuf message's XXX_unrecognized buffer,
+// in the order they were received. Each field number is reported once.
+func unknownFields(msg interface{}) []int32 {
+	v := reflect.Indirect(reflect.ValueOf(msg))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("XXX_unrecognized")
+	if !f.IsValid() || f.Kind() != reflect.Slice || f.Type().Elem().Kind() != reflect.Uint8 {
+		return nil
+	}
+	buf := f.Bytes()
+
+	var fields []int32
+	seen := make(map[int32]bool)
+	for len(buf) > 0 {
+		tag, n := binary.Uvarint(buf)
+		if n <= 0 {
+			break
+		}
+		buf = buf[n:]
+
+		field, wire := int32(tag>>3), tag&0x7
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+
+		// Skip over the field's value so that we can find the next tag.
+		switch wire {
+		case 0: // varint
+			_, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return fields
+			}
+			buf = buf[n:]
+		case 1: // 64-bit
+			if len(buf) < 8 {
+				return fields
+			}
+			buf = buf[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < l {
+				return fields
+			}
+			buf = buf[n+int(l):]
+		case 5: // 32-bit
+			if len(buf) < 4 {
+				return fields
+			}
+			buf = buf[4:]
+		default: // groups are deprecated and never produced by our providers; stop scanning.
+			return fields
+		}
+	}
+	return fields
+}
@@ -0,0 +1,51 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
+)
+
+func TestUnknownFields(t *testing.T) {
+	// A message with no unknown fields reports none.
+	assert.Empty(t, unknownFields(&pulumirpc.CreateResponse{Id: "id"}))
+
+	// Each kind of field is skipped correctly, and repeated fields are reported once.
+	resp := &pulumirpc.CreateResponse{
+		Id: "id",
+		XXX_unrecognized: []byte{
+			0x38, 0x96, 0x01, // field 7, varint 150
+			0x4a, 0x03, 'a', 'b', 'c', // field 9, 3 bytes
+			0x38, 0x01, // field 7, varint 1
+			0xa5, 0x01, 0x00, 0x00, 0x80, 0x3f, // field 20, fixed32
+			0x59, 0, 0, 0, 0, 0, 0, 0, 0, // field 11, fixed64
+		},
+	}
+	assert.Equal(t, []int32{7, 9, 20, 11}, unknownFields(resp))
+
+	// Truncated input reports the fields seen so far.
+	resp.XXX_unrecognized = []byte{0x38, 0x96, 0x01, 0x4a, 0x05, 'a'}
+	assert.Equal(t, []int32{7, 9}, unknownFields(resp))
+}
+
+func TestUnknownFieldsError(t *testing.T) {
+	err := &UnknownFieldsError{Plugin: "aws", Version: "v1.2.3", Message: "CreateResponse", Fields: []int32{7, 9}}
+	assert.EqualError(t, err, "plugin 'aws' (v1.2.3) sent unknown field(s) 7, 9 in CreateResponse; the plugin may "+
+		"require a newer version of the CLI (unset PULUMI_STRICT_PLUGIN_PROTOCOL to ignore unknown fields)")
+}
@@ -16,10 +16,15 @@ package plugin
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 
 	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/rpcutil"
 )
 
@@ -31,7 +36,22 @@ type Context struct {
 	Host       Host      // the host that can be used to fetch providers.
 	Pwd        string    // the working directory to spawn all plugins in.
 
-	tracingSpan opentracing.Span // the OpenTracing span to parent requests within.
+	// LanguageEnv holds extra environment variables (in KEY=VALUE form) for language plugins, e.g. to point them at a
+	// particular toolchain.
+	LanguageEnv []string
+
+	// AssetTransferChunkSize is the number of bytes requested at a time when the engine fetches the contents of remote
+	// assets and archives on behalf of the context (<=0 for resource.DefaultAssetTransferChunkSize).
+	AssetTransferChunkSize int64
+	// AssetCache, if set, caches the hashes and translated contents of the assets and archives that the engine reads on
+	// behalf of the context.
+	AssetCache *resource.AssetCache
+
+	tracingSpan      opentracing.Span // the OpenTracing span to parent requests within.
+	resourceSpans    sync.Map         // the spans of in-flight resource operations, keyed by URN.
+	progressFunc     atomic.Value     // the ProgressFunc to which plugins' progress reports are relayed, if any.
+	callObserver     atomic.Value     // the CallObserver to which calls to the context's providers are reported, if any.
+	transferObserver atomic.Value     // the AssetTransferObserver to which asset transfers are reported, if any.
 }
 
 // NewContext allocates a new context with a given sink and host.  Note that the host is "owned" by this context from
@@ -61,6 +81,71 @@ func (ctx *Context) Request() context.Context {
 	return opentracing.ContextWithSpan(context.Background(), ctx.tracingSpan)
 }
 
+// SetResourceSpan records the span of the operation in flight on the given resource, so that requests made on the
+// resource's behalf are parented to it. Passing a nil span clears the record.
+func (ctx *Context) SetResourceSpan(urn resource.URN, span opentracing.Span) {
+	if span == nil {
+		ctx.resourceSpans.Delete(urn)
+	} else {
+		ctx.resourceSpans.Store(urn, span)
+	}
+}
+
+// ResourceRequest allocates a request sub-context for an operation on the given resource. The request is parented to
+// the resource's span, if one has been recorded with SetResourceSpan, and to the context's span otherwise.
+func (ctx *Context) ResourceRequest(urn resource.URN) context.Context {
+	if span, has := ctx.resourceSpans.Load(urn); has {
+		return opentracing.ContextWithSpan(context.Background(), span.(opentracing.Span))
+	}
+	return ctx.Request()
+}
+
+// SetProgressFunc registers the function to which progress reported by the context's plugins over the engine's
+// ReportProgress RPC is relayed. The engine RPC is shared by all of the context's plugins, so the function receives the
+// reports of every one of them; it is registered once, when the plan that uses the context begins to execute.
+func (ctx *Context) SetProgressFunc(f ProgressFunc) {
+	ctx.progressFunc.Store(f)
+}
+
+// reportProgress relays a progress report from one of the context's plugins to the registered function, if any.
+func (ctx *Context) reportProgress(urn resource.URN, percent int, message string) {
+	if f, ok := ctx.progressFunc.Load().(ProgressFunc); ok && f != nil {
+		f(urn, percent, message)
+	}
+}
+
+// SetCallObserver registers the function to which calls to the context's provider plugins are reported. Like the
+// progress function, it is shared by all of the context's plugins. Passing nil stops the reports.
+func (ctx *Context) SetCallObserver(f CallObserver) {
+	ctx.callObserver.Store(f)
+}
+
+// observeCall reports a call to one of the context's provider plugins to the registered observer, if any.
+func (ctx *Context) observeCall(pkg tokens.Package, method string, duration time.Duration, err error) {
+	if f, ok := ctx.callObserver.Load().(CallObserver); ok && f != nil {
+		f(pkg, method, duration, err)
+	}
+}
+
+// SetAssetTransferObserver registers the function to which the progress of the context's transfers of remote asset
+// and archive contents is reported. Passing nil stops the reports.
+func (ctx *Context) SetAssetTransferObserver(f resource.AssetTransferObserver) {
+	ctx.transferObserver.Store(f)
+}
+
+// AssetOptions returns the options with which the contents of assets and archives are read on behalf of the context.
+func (ctx *Context) AssetOptions() resource.AssetOptions {
+	opts := resource.AssetOptions{
+		WorkingDirectory:  ctx.Pwd,
+		Cache:             ctx.AssetCache,
+		TransferChunkSize: ctx.AssetTransferChunkSize,
+	}
+	if f, ok := ctx.transferObserver.Load().(resource.AssetTransferObserver); ok && f != nil {
+		opts.TransferObserver = f
+	}
+	return opts
+}
+
 // Close reclaims all resources associated with this context.
 func (ctx *Context) Close() error {
 	if ctx.tracingSpan != nil {
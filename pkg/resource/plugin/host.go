@@ -16,6 +16,7 @@ package plugin
 
 import (
 	"os"
+	"reflect"
 
 	"github.com/blang/semver"
 	"github.com/hashicorp/go-multierror"
@@ -52,6 +53,9 @@ type Host interface {
 	Provider(pkg tokens.Package, version *semver.Version) (Provider, error)
 	// CloseProvider closes the given provider plugin and deregisters it from this host.
 	CloseProvider(provider Provider) error
+	// RestartProvider checks that the given provider plugin is still alive and, if it is not, relaunches and
+	// reconfigures it. It returns true if the provider was restarted.
+	RestartProvider(provider Provider) (bool, error)
 	// LanguageRuntime fetches the language runtime plugin for a given language, lazily allocating if necessary.  If
 	// an implementation of this language runtime wasn't found, on an error occurs, a non-nil error is returned.
 	LanguageRuntime(runtime string) (LanguageRuntime, error)
@@ -134,6 +138,7 @@ type analyzerPlugin struct {
 type languagePlugin struct {
 	Plugin LanguageRuntime
 	Info   workspace.PluginInfo
+	Env    []string // the extra environment variables with which the plugin was launched.
 }
 
 type resourcePlugin struct {
@@ -146,11 +151,23 @@ func (host *defaultHost) ServerAddr() string {
 }
 
 func (host *defaultHost) Log(sev diag.Severity, urn resource.URN, msg string, streamID int32) {
-	host.ctx.Diag.Logf(sev, diag.StreamMessage(urn, msg, streamID))
+	host.ctx.Diag.Logf(sev, logMessage(urn, msg, streamID))
 }
 
 func (host *defaultHost) LogStatus(sev diag.Severity, urn resource.URN, msg string, streamID int32) {
-	host.ctx.StatusDiag.Logf(sev, diag.StreamMessage(urn, msg, streamID))
+	host.ctx.StatusDiag.Logf(sev, logMessage(urn, msg, streamID))
+}
+
+// logMessage returns the diagnostic for a message that a plugin or the program logged through the host. The host
+// cannot tell its clients apart, so a message about a resource is attributed to the provider for the resource's
+// package, which logs most such messages, and any other message to the language host.
+func logMessage(urn resource.URN, msg string, streamID int32) *diag.Diag {
+	d := diag.StreamMessage(urn, msg, streamID)
+	d.Source = diag.LanguageSource
+	if urn.IsValid() {
+		d.Source = diag.ProviderSource(urn.Type().Package())
+	}
+	return d
 }
 
 // loadPlugin sends an appropriate load request to the plugin loader and returns the loaded plugin (if any) and error.
@@ -247,10 +264,17 @@ func (host *defaultHost) Provider(pkg tokens.Package, version *semver.Version) (
 
 func (host *defaultHost) LanguageRuntime(runtime string) (LanguageRuntime, error) {
 	plugin, err := host.loadPlugin(func() (interface{}, error) {
-		// First see if we already loaded this plugin.
+		// First see if we already loaded this plugin. A plugin that was launched with a different environment, e.g. by
+		// an earlier operation on a pooled host, is replaced.
 		if plug, has := host.languagePlugins[runtime]; has {
 			contract.Assert(plug != nil)
-			return plug.Plugin, nil
+			if reflect.DeepEqual(plug.Env, host.ctx.LanguageEnv) {
+				return plug.Plugin, nil
+			}
+			if err := plug.Plugin.Close(); err != nil {
+				logging.Infof("Error closing '%s' language plugin for relaunch; ignoring: %v", plug.Info.Name, err)
+			}
+			delete(host.languagePlugins, runtime)
 		}
 
 		// If not, allocate a new one.
@@ -263,7 +287,7 @@ func (host *defaultHost) LanguageRuntime(runtime string) (LanguageRuntime, error
 
 			// Memoize the result.
 			host.plugins = append(host.plugins, info)
-			host.languagePlugins[runtime] = &languagePlugin{Plugin: plug, Info: info}
+			host.languagePlugins[runtime] = &languagePlugin{Plugin: plug, Info: info, Env: host.ctx.LanguageEnv}
 		}
 
 		return plug, err
@@ -388,6 +412,19 @@ func (host *defaultHost) CloseProvider(provider Provider) error {
 	return err
 }
 
+func (host *defaultHost) RestartProvider(provider Provider) (bool, error) {
+	r, ok := provider.(Restartable)
+	if !ok {
+		return false, nil
+	}
+	restarted, err := r.Restart()
+	if restarted {
+		logging.V(7).Infof("restarted resource plugin for package '%v' (restarts=%d, err=%v)",
+			provider.Pkg(), r.Restarts(), err)
+	}
+	return restarted, err
+}
+
 func (host *defaultHost) Close() error {
 	// Close all plugins.
 	for _, plug := range host.analyzerPlugins {
@@ -103,6 +103,17 @@ func (eng *hostServer) Log(ctx context.Context, req *lumirpc.LogRequest) (*pbemp
 	return &pbempty.Empty{}, nil
 }
 
+// ReportProgress relays a plugin's report of the progress of a long-running operation on a resource to the engine.
+func (eng *hostServer) ReportProgress(ctx context.Context,
+	req *lumirpc.ReportProgressRequest) (*pbempty.Empty, error) {
+	percent := int(req.GetPercent())
+	if percent > 100 {
+		percent = 100
+	}
+	eng.ctx.reportProgress(resource.URN(req.GetUrn()), percent, req.GetMessage())
+	return &pbempty.Empty{}, nil
+}
+
 // GetRootResource returns the current root resource's URN, which will serve as the parent of resources that are
 // otherwise left unparented.
 func (eng *hostServer) GetRootResource(ctx context.Context,
@@ -59,4 +59,10 @@ type RunInfo struct {
 	DryRun         bool                  // true if we are performing a dry-run (preview).
 	QueryMode      bool                  // true if we're only doing a query.
 	Parallel       int                   // the degree of parallelism for resource operations (<=1 for serial).
+
+	// HotReload is true if the program may be run again by the same language host, which may then keep the program's
+	// interpreter alive between runs and re-evaluate the program within it (see pulumirpc.RunRequest).
+	HotReload bool
+	// ChangedPaths holds the paths of the program's files that changed since the previous run that set HotReload.
+	ChangedPaths []string
 }
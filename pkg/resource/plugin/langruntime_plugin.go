@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
 
+	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
@@ -61,7 +62,7 @@ func NewLanguageRuntime(host Host, ctx *Context, runtime string,
 	}
 	args = append(args, host.ServerAddr())
 
-	plug, err := newPlugin(ctx, path, runtime, args)
+	plug, err := newPlugin(ctx, path, runtime, diag.LanguageSource, args, ctx.LanguageEnv)
 	if err != nil {
 		return nil, err
 	}
@@ -150,6 +151,8 @@ func (h *langhost) Run(info RunInfo) (string, bool, error) {
 		DryRun:         info.DryRun,
 		QueryMode:      info.QueryMode,
 		Parallel:       int32(info.Parallel),
+		HotReload:      info.HotReload,
+		ChangedPaths:   info.ChangedPaths,
 	})
 	if err != nil {
 		rpcError := rpcerror.Convert(err)
@@ -42,6 +42,7 @@ import (
 type plugin struct {
 	stdoutDone <-chan bool
 	stderrDone <-chan bool
+	exited     <-chan bool // closed when the plugin process exits.
 
 	Bin    string
 	Args   []string
@@ -60,7 +61,11 @@ var pluginRPCConnectionTimeout = time.Second * 10
 // time.
 var nextStreamID int32
 
-func newPlugin(ctx *Context, bin string, prefix string, args []string) (*plugin, error) {
+// newPlugin launches the plugin at the given path. Anything that it writes to its standard output and error streams
+// once it has started is reported as diagnostics issued by the given source.
+func newPlugin(ctx *Context, bin string, prefix string, source diag.Source, args []string,
+	env []string) (*plugin, error) {
+
 	if logging.V(9) {
 		var argstr string
 		for i, arg := range args {
@@ -73,7 +78,7 @@ func newPlugin(ctx *Context, bin string, prefix string, args []string) (*plugin,
 	}
 
 	// Try to execute the binary.
-	plug, err := execPlugin(bin, args, ctx.Pwd)
+	plug, err := execPlugin(bin, args, ctx.Pwd, env)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to load plugin %s", bin)
 	}
@@ -101,9 +106,13 @@ func newPlugin(ctx *Context, bin string, prefix string, args []string) (*plugin,
 
 			if strings.TrimSpace(msg) != "" {
 				if stderr {
-					ctx.Diag.Infoerrf(diag.StreamMessage("" /*urn*/, msg, errStreamID))
+					d := diag.StreamMessage("" /*urn*/, msg, errStreamID)
+					d.Source = source
+					ctx.Diag.Infoerrf(d)
 				} else {
-					ctx.Diag.Infof(diag.StreamMessage("" /*urn*/, msg, outStreamID))
+					d := diag.StreamMessage("" /*urn*/, msg, outStreamID)
+					d.Source = source
+					ctx.Diag.Infof(d)
 				}
 			}
 		}
@@ -204,7 +213,7 @@ func newPlugin(ctx *Context, bin string, prefix string, args []string) (*plugin,
 	return plug, nil
 }
 
-func execPlugin(bin string, pluginArgs []string, pwd string) (*plugin, error) {
+func execPlugin(bin string, pluginArgs []string, pwd string, env []string) (*plugin, error) {
 	var args []string
 	// Flow the logging information if set.
 	if logging.LogFlow {
@@ -224,6 +233,9 @@ func execPlugin(bin string, pluginArgs []string, pwd string) (*plugin, error) {
 	cmd := exec.Command(bin, args...)
 	cmdutil.RegisterProcessGroup(cmd)
 	cmd.Dir = pwd
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	in, _ := cmd.StdinPipe()
 	out, _ := cmd.StdoutPipe()
 	err, _ := cmd.StderrPipe()
@@ -231,7 +243,16 @@ func execPlugin(bin string, pluginArgs []string, pwd string) (*plugin, error) {
 		return nil, err
 	}
 
+	// Watch for the process to exit so that we can tell whether a plugin that stops responding has crashed.
+	exited := make(chan bool)
+	go func() {
+		_, waiterr := cmd.Process.Wait()
+		contract.IgnoreError(waiterr)
+		close(exited)
+	}()
+
 	return &plugin{
+		exited: exited,
 		Bin:    bin,
 		Args:   args,
 		Proc:   cmd.Process,
@@ -241,6 +262,16 @@ func execPlugin(bin string, pluginArgs []string, pwd string) (*plugin, error) {
 	}, nil
 }
 
+// Exited returns true if the plugin process has exited.
+func (p *plugin) Exited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *plugin) Close() error {
 	if p.Conn != nil {
 		closerr := p.Conn.Close()
@@ -257,8 +288,10 @@ func (p *plugin) Close() error {
 	}
 
 	// IDEA: consider a more graceful termination than just SIGKILL.
-	if err := p.Proc.Kill(); err != nil {
-		result = multierror.Append(result, err)
+	if !p.Exited() {
+		if err := p.Proc.Kill(); err != nil {
+			result = multierror.Append(result, err)
+		}
 	}
 
 	// Wait for stdout and stderr to drain.
@@ -0,0 +1,331 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// DefaultHostPoolIdleTimeout is how long a HostPool keeps an unused host, and its plugin processes, alive by default.
+const DefaultHostPoolIdleTimeout = 5 * time.Minute
+
+// HostPool keeps plugin hosts, along with their language, analyzer, and provider plugin processes, alive across
+// consecutive operations in the same process, e.g. a preview followed by an update, so that each operation does not
+// pay to start its plugins anew. A host is lent to one operation at a time; closing the context returned by NewContext
+// returns the host to the pool rather than shutting it down. Hosts that go unused for the pool's idle timeout are shut
+// down.
+//
+// Providers that an operation closes are kept and handed out again when a provider for the same package and version is
+// next loaded. A provider is configured once per process, so one that is loaded with a different configuration than
+// before is transparently relaunched.
+type HostPool struct {
+	idleTimeout time.Duration
+
+	m      sync.Mutex
+	idle   map[string][]*pooledHost // the hosts that are not in use, by the key of their settings.
+	closed bool                     // true once the pool has been closed.
+}
+
+// NewHostPool creates a new, empty pool that shuts down hosts that have been unused for the given duration. A
+// non-positive duration uses DefaultHostPoolIdleTimeout.
+func NewHostPool(idleTimeout time.Duration) *HostPool {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultHostPoolIdleTimeout
+	}
+	return &HostPool{
+		idleTimeout: idleTimeout,
+		idle:        make(map[string][]*pooledHost),
+	}
+}
+
+// NewContext allocates a new plugin context, much like the package's NewContext, whose host is taken from the pool if
+// one with the same working directory and runtime options is idle. Diagnostics from the host's plugins are reported to
+// the given sinks until the context is closed, at which point the host is returned to the pool.
+func (pool *HostPool) NewContext(d, statusD diag.Sink, pwd string, runtimeOptions map[string]interface{},
+	parentSpan opentracing.Span) (*Context, error) {
+
+	key := fmt.Sprintf("%s\x00%v", pwd, runtimeOptions)
+
+	pool.m.Lock()
+	var host *pooledHost
+	if hosts := pool.idle[key]; len(hosts) > 0 {
+		host = hosts[len(hosts)-1]
+		pool.idle[key] = hosts[:len(hosts)-1]
+		host.evict.Stop()
+	}
+	pool.m.Unlock()
+
+	if host == nil {
+		logging.V(7).Infof("HostPool: starting a new host for %s", pwd)
+		sink, statusSink := &switchSink{}, &switchSink{}
+		ctx := &Context{Diag: sink, StatusDiag: statusSink, Pwd: pwd}
+		h, err := NewDefaultHost(ctx, nil, runtimeOptions)
+		if err != nil {
+			return nil, err
+		}
+		host = &pooledHost{
+			defaultHost: h.(*defaultHost),
+			pool:        pool,
+			key:         key,
+			sink:        sink,
+			statusSink:  statusSink,
+			versions:    make(map[Provider]string),
+			parked:      make(map[string][]*provider),
+		}
+		ctx.Host = host
+	} else {
+		logging.V(7).Infof("HostPool: reusing an idle host for %s", pwd)
+	}
+
+	// The context is shared by every operation that uses the host, as its plugins hold on to it.
+	host.sink.set(d)
+	host.statusSink.set(statusD)
+	host.ctx.LanguageEnv = nil
+	host.ctx.tracingSpan = parentSpan
+	return host.ctx, nil
+}
+
+// release returns a host to the pool once an operation is done with it.
+func (pool *HostPool) release(host *pooledHost) error {
+	host.sink.set(nil)
+	host.statusSink.set(nil)
+	host.ctx.tracingSpan = nil
+
+	if host.canceled || !host.parkAll() {
+		return host.shutdown()
+	}
+
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	if pool.closed {
+		return host.shutdown()
+	}
+	pool.idle[host.key] = append(pool.idle[host.key], host)
+	host.evict = time.AfterFunc(pool.idleTimeout, func() { pool.evict(host) })
+	return nil
+}
+
+// evict shuts down a host that has been idle for the pool's idle timeout, unless it has since been reused.
+func (pool *HostPool) evict(host *pooledHost) {
+	pool.m.Lock()
+	hosts := pool.idle[host.key]
+	found := false
+	for i, h := range hosts {
+		if h == host {
+			pool.idle[host.key] = append(hosts[:i], hosts[i+1:]...)
+			found = true
+			break
+		}
+	}
+	pool.m.Unlock()
+
+	if found {
+		logging.V(7).Infof("HostPool: shutting down a host for %s after %v idle", host.ctx.Pwd, pool.idleTimeout)
+		contract.IgnoreError(host.shutdown())
+	}
+}
+
+// Close shuts down every idle host in the pool. Hosts that are in use are shut down when they are released.
+func (pool *HostPool) Close() error {
+	pool.m.Lock()
+	idle := pool.idle
+	pool.idle, pool.closed = make(map[string][]*pooledHost), true
+	pool.m.Unlock()
+
+	for _, hosts := range idle {
+		for _, host := range hosts {
+			host.evict.Stop()
+			contract.IgnoreError(host.shutdown())
+		}
+	}
+	return nil
+}
+
+// pooledHost is a host that belongs to a HostPool. Closing it returns it to the pool.
+type pooledHost struct {
+	*defaultHost
+
+	pool       *HostPool
+	key        string      // the key of the host's settings in the pool.
+	sink       *switchSink // the sink for the current operation's diagnostics.
+	statusSink *switchSink // the sink for the current operation's status messages.
+	evict      *time.Timer // shuts the host down once it has been idle for too long.
+	canceled   bool        // true if cancellation was signaled, in which case the host is not reused.
+
+	m        sync.Mutex
+	versions map[Provider]string    // the key of each loaded provider's package and requested version.
+	parked   map[string][]*provider // providers that are ready for reuse, by package and requested version.
+}
+
+var _ Host = (*pooledHost)(nil)
+
+func providerKey(pkg tokens.Package, version *semver.Version) string {
+	if version == nil {
+		return string(pkg)
+	}
+	return string(pkg) + "@" + version.String()
+}
+
+// Provider returns a parked provider for the given package and version if there is one, and loads a new one otherwise.
+func (host *pooledHost) Provider(pkg tokens.Package, version *semver.Version) (Provider, error) {
+	key := providerKey(pkg, version)
+
+	host.m.Lock()
+	for len(host.parked[key]) > 0 {
+		parked := host.parked[key]
+		p := parked[len(parked)-1]
+		host.parked[key] = parked[:len(parked)-1]
+		if !p.currentPlugin().Exited() {
+			host.m.Unlock()
+			logging.V(7).Infof("HostPool: reusing resource plugin for package '%v'", pkg)
+			return p, nil
+		}
+		delete(host.versions, p)
+		contract.IgnoreError(host.defaultHost.CloseProvider(p))
+	}
+	host.m.Unlock()
+
+	p, err := host.defaultHost.Provider(pkg, version)
+	if err != nil {
+		return nil, err
+	}
+
+	host.m.Lock()
+	host.versions[p] = key
+	host.m.Unlock()
+	return p, nil
+}
+
+// CloseProvider parks the given provider for reuse if it can be reused, and closes it otherwise.
+func (host *pooledHost) CloseProvider(provider Provider) error {
+	if host.park(provider) {
+		return nil
+	}
+	return host.defaultHost.CloseProvider(provider)
+}
+
+// park parks a provider for reuse, returning false if it cannot be reused.
+func (host *pooledHost) park(plug Provider) bool {
+	host.m.Lock()
+	defer host.m.Unlock()
+
+	key, has := host.versions[plug]
+	p, ok := plug.(*provider)
+	if !has || !ok {
+		return false
+	}
+	for _, parked := range host.parked[key] {
+		if parked == p {
+			return true
+		}
+	}
+	if !p.park() {
+		delete(host.versions, plug)
+		return false
+	}
+	host.parked[key] = append(host.parked[key], p)
+	return true
+}
+
+// parkAll parks every provider that the last operation left open, closing those that cannot be reused. It returns
+// false if the host's providers could not be enumerated, in which case the host must not be reused.
+func (host *pooledHost) parkAll() bool {
+	var open []Provider
+	_, err := host.loadPlugin(func() (interface{}, error) {
+		for p := range host.resourcePlugins {
+			open = append(open, p)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return false
+	}
+	for _, p := range open {
+		if !host.park(p) {
+			contract.IgnoreError(host.defaultHost.CloseProvider(p))
+		}
+	}
+	return true
+}
+
+// SignalCancellation signals cancellation to the host's providers. A host whose providers have been canceled is shut
+// down rather than reused.
+func (host *pooledHost) SignalCancellation() error {
+	host.canceled = true
+	return host.defaultHost.SignalCancellation()
+}
+
+// Close returns the host to its pool.
+func (host *pooledHost) Close() error {
+	return host.pool.release(host)
+}
+
+// shutdown shuts down the host and all of its plugins.
+func (host *pooledHost) shutdown() error {
+	host.m.Lock()
+	host.versions, host.parked = make(map[Provider]string), make(map[string][]*provider)
+	host.m.Unlock()
+	return host.defaultHost.Close()
+}
+
+// switchSink is a diagnostics sink that forwards to another that may be changed at any time. Messages are discarded
+// while there is no sink to forward to.
+type switchSink struct {
+	m    sync.RWMutex
+	sink diag.Sink
+}
+
+var discardSink = diag.DefaultSink(ioutil.Discard, ioutil.Discard, diag.FormatOptions{Color: colors.Never})
+
+func (s *switchSink) set(sink diag.Sink) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.sink = sink
+}
+
+func (s *switchSink) target() diag.Sink {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	if s.sink == nil {
+		return discardSink
+	}
+	return s.sink
+}
+
+func (s *switchSink) Logf(sev diag.Severity, d *diag.Diag, args ...interface{}) {
+	s.target().Logf(sev, d, args...)
+}
+
+func (s *switchSink) Debugf(d *diag.Diag, args ...interface{})   { s.target().Debugf(d, args...) }
+func (s *switchSink) Infof(d *diag.Diag, args ...interface{})    { s.target().Infof(d, args...) }
+func (s *switchSink) Infoerrf(d *diag.Diag, args ...interface{}) { s.target().Infoerrf(d, args...) }
+func (s *switchSink) Errorf(d *diag.Diag, args ...interface{})   { s.target().Errorf(d, args...) }
+func (s *switchSink) Warningf(d *diag.Diag, args ...interface{}) { s.target().Warningf(d, args...) }
+
+func (s *switchSink) Stringify(sev diag.Severity, d *diag.Diag, args ...interface{}) (string, string) {
+	return s.target().Stringify(sev, d, args...)
+}
@@ -0,0 +1,79 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostPoolReusesHosts(t *testing.T) {
+	pool := NewHostPool(time.Minute)
+	defer func() { assert.NoError(t, pool.Close()) }()
+
+	first, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Close())
+
+	// A context for the same project gets the same host back.
+	second, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, first.Host == second.Host)
+
+	// While that host is in use, or for a different project, a new host is started.
+	third, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.False(t, second.Host == third.Host)
+	other, err := pool.NewContext(discardSink, discardSink, "/other", nil, nil)
+	assert.NoError(t, err)
+	assert.False(t, second.Host == other.Host)
+
+	assert.NoError(t, second.Close())
+	assert.NoError(t, third.Close())
+	assert.NoError(t, other.Close())
+}
+
+func TestHostPoolEvictsIdleHosts(t *testing.T) {
+	pool := NewHostPool(10 * time.Millisecond)
+	defer func() { assert.NoError(t, pool.Close()) }()
+
+	first, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Close())
+
+	time.Sleep(100 * time.Millisecond)
+
+	second, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.False(t, first.Host == second.Host)
+	assert.NoError(t, second.Close())
+}
+
+func TestHostPoolDiscardsCanceledHosts(t *testing.T) {
+	pool := NewHostPool(time.Minute)
+	defer func() { assert.NoError(t, pool.Close()) }()
+
+	first, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Host.SignalCancellation())
+	assert.NoError(t, first.Close())
+
+	second, err := pool.NewContext(discardSink, discardSink, "/project", nil, nil)
+	assert.NoError(t, err)
+	assert.False(t, first.Host == second.Host)
+	assert.NoError(t, second.Close())
+}
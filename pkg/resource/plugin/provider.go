@@ -15,7 +15,13 @@
 package plugin
 
 import (
+	"fmt"
 	"io"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/tokens"
@@ -78,6 +84,171 @@ type Provider interface {
 	SignalCancellation() error
 }
 
+// ProgressFunc is called by a provider to report the progress of a long-running operation on the resource with the
+// given URN. Percent is in the range [0, 100], or is negative if the provider cannot estimate its progress.
+type ProgressFunc func(urn resource.URN, percent int, message string)
+
+// CallObserver is called with the duration and result of each call that the engine makes to a provider's Check, Diff,
+// Create, Read, Update, or Delete method.
+type CallObserver func(pkg tokens.Package, method string, duration time.Duration, err error)
+
+// ProgressReporter is an optional interface implemented by providers that are able to report the progress of
+// long-running operations such as Create, Update, and Delete.
+type ProgressReporter interface {
+	// SetProgressFunc registers the function to which the provider reports progress.
+	SetProgressFunc(f ProgressFunc)
+}
+
+// FailureDetails are structured details about a failed provider operation, intended to turn an opaque error from the
+// underlying cloud into an actionable message.
+type FailureDetails struct {
+	Code        string   // a provider-specific code that identifies the kind of failure.
+	Retryable   bool     // true if the operation may succeed if it is attempted again.
+	Remediation []string // suggested steps that may resolve the failure.
+	DocsURL     string   // a link to documentation about the failure.
+}
+
+// String formats the details for display beneath the error they describe.
+func (d FailureDetails) String() string {
+	var lines []string
+	if d.Code != "" {
+		lines = append(lines, fmt.Sprintf("error code: %s", d.Code))
+	}
+	if d.Retryable {
+		lines = append(lines, "this error is transient; retrying the operation may succeed")
+	}
+	for _, r := range d.Remediation {
+		lines = append(lines, fmt.Sprintf("hint: %s", r))
+	}
+	if d.DocsURL != "" {
+		lines = append(lines, fmt.Sprintf("see %s for more information", d.DocsURL))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FailureExplainer is an optional interface implemented by providers that are able to describe the failure of an
+// operation in more detail than the error it returned.
+type FailureExplainer interface {
+	// ExplainFailure returns structured details about the given error, which was returned by an operation on the
+	// resource with the given URN. If the provider has nothing to add, it returns nil.
+	ExplainFailure(urn resource.URN, err error) (*FailureDetails, error)
+}
+
+// HealthChecker is an optional interface implemented by providers that are able to check that a newly created resource
+// is ready to serve traffic. It is used to validate replacements before dependents are moved to them.
+type HealthChecker interface {
+	// CheckHealth returns nil if the resource with the given URN, ID, and state is healthy, or an error describing why
+	// it is not. Implementations may block while waiting for the resource to become healthy. If the provider is unable
+	// to check the resource's health, it returns ErrHealthCheckUnsupported.
+	CheckHealth(urn resource.URN, id resource.ID, state resource.PropertyMap) error
+}
+
+// ErrHealthCheckUnsupported is returned by CheckHealth when the provider turns out to be unable to check the health of
+// the resource, e.g. because its plugin predates health checks.
+var ErrHealthCheckUnsupported = errors.New("the provider is unable to check the health of this resource")
+
+// Restartable is an optional interface implemented by providers whose underlying plugin process can be relaunched if
+// it exits unexpectedly.
+type Restartable interface {
+	// Restart relaunches the provider's plugin process if it is no longer running, and configures the new process
+	// as the old one was configured. It returns true if the plugin process was restarted.
+	Restart() (bool, error)
+	// Restarts returns the number of times the provider's plugin process has been restarted.
+	Restarts() int
+}
+
+// ParallelismHinter is an optional interface implemented by providers that know how many operations they can usefully
+// perform at once, e.g. because their underlying API is rate limited.
+type ParallelismHinter interface {
+	// MaxParallelism returns the maximum number of resource operations the engine should issue to this provider
+	// concurrently, or zero if the provider has no preference.
+	MaxParallelism() int
+}
+
+// OperationCanceler is an optional interface implemented by providers that can cancel a single in-flight create,
+// update, or delete, e.g. because it has exceeded its timeout.
+type OperationCanceler interface {
+	// CancelOperation cancels the in-flight operation on the given resource, if there is one.
+	CancelOperation(urn resource.URN)
+}
+
+// OperationSettings govern a provider's operations on a single resource. They are persisted in the resource's state,
+// so that an update that resumes after an interruption operates under the same constraints as the one interrupted.
+type OperationSettings struct {
+	Timeout      time.Duration // how long the operation may take, or zero if it is not bounded.
+	PollInterval time.Duration // how often to poll for the completion of the operation, or zero for the default.
+}
+
+// OperationConfigurer is an optional interface implemented by providers that honor per-resource operation settings,
+// such as the interval at which they poll for the completion of long-running operations.
+type OperationConfigurer interface {
+	// ConfigureOperation sets the settings for the next create, read, update, or delete of the resource with the given
+	// URN. The engine calls it before each such operation.
+	ConfigureOperation(urn resource.URN, settings OperationSettings)
+}
+
+// StateMigrator is an optional interface implemented by providers that are able to upgrade the state of resources last
+// managed by an older version of the provider, e.g. across a major version that renamed or restructured properties.
+type StateMigrator interface {
+	// MigrateState converts the inputs and outputs of the resource with the given URN and ID, as recorded by the given
+	// older version of this provider, into the form this version expects. Providers that need not change a resource's
+	// state return its inputs and outputs unmodified.
+	MigrateState(urn resource.URN, id resource.ID, version semver.Version,
+		inputs, outputs resource.PropertyMap) (resource.PropertyMap, resource.PropertyMap, error)
+}
+
+// DefaultTagger is an optional interface implemented by providers that support default tags, i.e. tags that the engine
+// applies to every taggable resource that the provider manages, e.g. to enforce cost-center tagging across a stack.
+type DefaultTagger interface {
+	// TagsProperty returns the input property of resources of the given type that holds their tags as an object of
+	// strings, or "" if resources of the type cannot be tagged.
+	TagsProperty(t tokens.Type) resource.PropertyKey
+}
+
+// Deprecation describes a resource type or input property that a provider has deprecated.
+type Deprecation struct {
+	Message     string // why the type or property is deprecated, if the provider says.
+	Replacement string // the type or property to use instead, if there is one.
+}
+
+// DeprecationReporter is an optional interface implemented by providers that declare deprecated resource types and
+// input properties, so that the engine can warn about their use before a major version of the provider removes them.
+type DeprecationReporter interface {
+	// ResourceDeprecation returns the deprecation of the given resource type, or nil if the type is not deprecated.
+	ResourceDeprecation(t tokens.Type) *Deprecation
+	// PropertyDeprecations returns the deprecated input properties of resources of the given type, if any.
+	PropertyDeprecations(t tokens.Type) map[resource.PropertyKey]Deprecation
+}
+
+// ArtifactFile is a small file produced by a provider when creating or updating a resource, e.g. a generated kubeconfig
+// or certificate.
+type ArtifactFile struct {
+	Name        string // the artifact's name, unique among the resource's artifacts.
+	ContentType string // the artifact's MIME type, if known.
+	Contents    []byte // the artifact's contents.
+}
+
+// ArtifactProducer is an optional interface implemented by providers that attach artifacts to the resources they create
+// and update, so that deployment byproducts are kept alongside the stack rather than in ad-hoc outputs.
+type ArtifactProducer interface {
+	// TakeArtifacts returns the artifacts produced by the most recent create or update of the resource with the given
+	// URN, and forgets them. It returns nil if the operation produced none.
+	TakeArtifacts(urn resource.URN) []ArtifactFile
+}
+
+// PreviewValidator is an optional interface implemented by providers whose underlying APIs can validate a create or
+// update without performing it, e.g. by way of a dry-run request. Previews that ask for it call the validator for each
+// planned create and update, so that errors such as quota exhaustion or invalid parameters are reported during the
+// preview rather than partway through the update.
+type PreviewValidator interface {
+	// PreviewCreate validates the creation of the resource with the given URN and inputs, returning an error that
+	// describes why the create would fail, or nil if it would succeed.
+	PreviewCreate(urn resource.URN, news resource.PropertyMap) error
+	// PreviewUpdate validates the update of the resource with the given URN and ID from the given old state to the given
+	// new inputs, returning an error that describes why the update would fail, or nil if it would succeed.
+	PreviewUpdate(urn resource.URN, id resource.ID, olds, news resource.PropertyMap) error
+}
+
 // CheckFailure indicates that a call to check failed; it contains the property and reason for the failure.
 type CheckFailure struct {
 	Property resource.PropertyKey // the property that failed checking.
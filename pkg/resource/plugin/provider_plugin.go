@@ -15,21 +15,29 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blang/semver"
 	pbempty "github.com/golang/protobuf/ptypes/empty"
 	_struct "github.com/golang/protobuf/ptypes/struct"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/pulumi/pulumi/pkg/util/logging"
 	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+	"github.com/pulumi/pulumi/pkg/util/tracing"
 	"github.com/pulumi/pulumi/pkg/workspace"
 	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
 )
@@ -48,16 +56,34 @@ const nodejsDynamicProviderType = "pulumi:providers:pulumi-nodejs"
 // This is needed because we have to handle some buggy behavior that previous versions of this provider implemented.
 const kubernetesProviderType = "pulumi:providers:kubernetes"
 
+// The gRPC metadata keys under which a resource's operation settings are sent to provider plugins with each create,
+// read, update, and delete, as durations such as "10m0s". A key is omitted if its setting is zero.
+const (
+	OperationTimeoutMetadataKey = "pulumi-operation-timeout"
+	PollIntervalMetadataKey     = "pulumi-poll-interval"
+)
+
 // provider reflects a resource plugin, loaded dynamically for a single package.
 type provider struct {
-	ctx           *Context                         // a plugin context for caching, etc.
-	pkg           tokens.Package                   // the Pulumi package containing this provider's resources.
-	plug          *plugin                          // the actual plugin process wrapper.
-	clientRaw     pulumirpc.ResourceProviderClient // the raw provider client; usually unsafe to use directly.
-	cfgerr        error                            // non-nil if a configure call fails.
-	cfgknown      bool                             // true if all configuration values are known.
-	cfgdone       chan bool                        // closed when configuration has completed.
-	acceptSecrets bool                             // true if this provider plugin can consume strongly typed secret.
+	ctx           *Context                           // a plugin context for caching, etc.
+	pkg           tokens.Package                     // the Pulumi package containing this provider's resources.
+	launch        func() (*plugin, error)            // launches a new plugin process for this provider.
+	plugLock      sync.RWMutex                       // guards plug, clientRaw, and restarts.
+	plug          *plugin                            // the actual plugin process wrapper.
+	clientRaw     pulumirpc.ResourceProviderClient   // the raw provider client; usually unsafe to use directly.
+	restarts      int                                // the number of times the plugin process has been restarted.
+	cfgerr        error                              // non-nil if a configure call fails.
+	cfgknown      bool                               // true if all configuration values are known.
+	cfgdone       chan bool                          // closed when configuration has completed.
+	cfgvars       map[string]string                  // the configuration last sent to the plugin, if any.
+	parkedcfg     map[string]string                  // the configuration of a parked plugin process, if any.
+	acceptSecrets bool                               // true if this provider plugin can consume strongly typed secret.
+	opLock        sync.Mutex                         // guards operations.
+	operations    map[resource.URN]func()            // cancels the in-flight operation on each resource.
+	artLock       sync.Mutex                         // guards artifacts.
+	artifacts     map[resource.URN][]ArtifactFile    // the artifacts produced by each resource's last operation.
+	settingsLock  sync.Mutex                         // guards settings.
+	settings      map[resource.URN]OperationSettings // the settings for the next operation on each resource.
 }
 
 // NewProvider attempts to bind to a given package's resource plugin and then creates a gRPC connection to it.  If the
@@ -76,7 +102,11 @@ func NewProvider(host Host, ctx *Context, pkg tokens.Package, version *semver.Ve
 		})
 	}
 
-	plug, err := newPlugin(ctx, path, fmt.Sprintf("%v (resource)", pkg), []string{host.ServerAddr()})
+	launch := func() (*plugin, error) {
+		return newPlugin(ctx, path, fmt.Sprintf("%v (resource)", pkg), diag.ProviderSource(pkg),
+			[]string{host.ServerAddr()}, nil)
+	}
+	plug, err := launch()
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +115,7 @@ func NewProvider(host Host, ctx *Context, pkg tokens.Package, version *semver.Ve
 	return &provider{
 		ctx:       ctx,
 		pkg:       pkg,
+		launch:    launch,
 		plug:      plug,
 		clientRaw: pulumirpc.NewResourceProviderClient(plug.Conn),
 		cfgdone:   make(chan bool),
@@ -165,7 +196,7 @@ func (p *provider) CheckConfig(urn resource.URN, olds,
 		return nil, nil, err
 	}
 
-	resp, err := p.clientRaw.CheckConfig(p.ctx.Request(), &pulumirpc.CheckRequest{
+	resp, err := p.raw().CheckConfig(p.ctx.Request(), &pulumirpc.CheckRequest{
 		Urn:  string(urn),
 		Olds: molds,
 		News: mnews,
@@ -182,14 +213,19 @@ func (p *provider) CheckConfig(urn resource.URN, olds,
 			rpcError.Message())
 		return nil, nil, err
 	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return nil, nil, err
+	}
 
 	// Unmarshal the provider inputs.
 	var inputs resource.PropertyMap
 	if ins := resp.GetInputs(); ins != nil {
 		inputs, err = UnmarshalProperties(ins, MarshalOptions{
-			Label:          fmt.Sprintf("%s.inputs", label),
-			KeepUnknowns:   allowUnknowns,
-			RejectUnknowns: !allowUnknowns,
+			Label:            fmt.Sprintf("%s.inputs", label),
+			KeepUnknowns:     allowUnknowns,
+			RejectUnknowns:   !allowUnknowns,
+			WorkingDirectory: p.ctx.Pwd,
+			AssetOptions:     p.ctx.AssetOptions(),
 		})
 		if err != nil {
 			return nil, nil, err
@@ -223,7 +259,7 @@ func (p *provider) DiffConfig(urn resource.URN, olds, news resource.PropertyMap,
 		return DiffResult{}, err
 	}
 
-	resp, err := p.clientRaw.DiffConfig(p.ctx.Request(), &pulumirpc.DiffRequest{
+	resp, err := p.raw().DiffConfig(p.ctx.Request(), &pulumirpc.DiffRequest{
 		Urn:  string(urn),
 		Olds: molds,
 		News: mnews,
@@ -252,6 +288,9 @@ func (p *provider) DiffConfig(urn resource.URN, olds, news resource.PropertyMap,
 			rpcError.Message())
 		return DiffResult{}, nil
 	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return DiffResult{}, err
+	}
 
 	var replaces []resource.PropertyKey
 	for _, replace := range resp.GetReplaces() {
@@ -286,7 +325,14 @@ func (p *provider) getClient() (pulumirpc.ResourceProviderClient, error) {
 	if err := p.ensureConfigured(); err != nil {
 		return nil, err
 	}
-	return p.clientRaw, nil
+	return p.raw(), nil
+}
+
+// raw returns the raw client for the current plugin process.
+func (p *provider) raw() pulumirpc.ResourceProviderClient {
+	p.plugLock.RLock()
+	defer p.plugLock.RUnlock()
+	return p.clientRaw
 }
 
 // ensureConfigured blocks waiting for the plugin to be configured.  To improve parallelism, all Configure RPCs
@@ -361,10 +407,29 @@ func (p *provider) Configure(inputs resource.PropertyMap) error {
 		}
 	}
 
+	// If this provider was parked by a host pool, its plugin process has already been configured. If the
+	// configuration is unchanged, there is nothing to do; otherwise, the process must be replaced, as providers
+	// cannot be configured twice.
+	if parked := p.parkedcfg; parked != nil {
+		p.parkedcfg = nil
+		if reflect.DeepEqual(parked, config) {
+			logging.V(7).Infof("%s reusing configured plugin", label)
+			p.cfgvars, p.cfgknown = config, true
+			close(p.cfgdone)
+			return nil
+		}
+		if err := p.relaunch(); err != nil {
+			p.cfgerr = err
+			close(p.cfgdone)
+			return err
+		}
+	}
+
 	// Spawn the configure to happen in parallel.  This ensures that we remain responsive elsewhere that might
 	// want to make forward progress, even as the configure call is happening.
+	p.cfgvars = config
 	go func() {
-		resp, err := p.clientRaw.Configure(p.ctx.Request(), &pulumirpc.ConfigureRequest{
+		resp, err := p.raw().Configure(p.ctx.Request(), &pulumirpc.ConfigureRequest{
 			AcceptSecrets: true,
 			Variables:     config,
 		})
@@ -372,6 +437,8 @@ func (p *provider) Configure(inputs resource.PropertyMap) error {
 			rpcError := rpcerror.Convert(err)
 			logging.V(7).Infof("%s failed: err=%v", label, rpcError.Message())
 			err = createConfigureError(rpcError)
+		} else {
+			err = p.checkUnknownFields(label, resp)
 		}
 		// Acquire the lock, publish the results, and notify any waiters.
 		p.cfgknown, p.acceptSecrets, p.cfgerr = true, resp.GetAcceptSecrets(), err
@@ -416,25 +483,32 @@ func (p *provider) Check(urn resource.URN,
 		return nil, nil, err
 	}
 
-	resp, err := client.Check(p.ctx.Request(), &pulumirpc.CheckRequest{
+	span, reqctx := p.startSpan(p.ctx.ResourceRequest(urn), "Check", urn)
+	resp, err := client.Check(reqctx, &pulumirpc.CheckRequest{
 		Urn:  string(urn),
 		Olds: molds,
 		News: mnews,
 	})
+	span.End(err)
 	if err != nil {
 		rpcError := rpcerror.Convert(err)
 		logging.V(7).Infof("%s failed: err=%v", label, rpcError.Message())
 		return nil, nil, rpcError
 	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return nil, nil, err
+	}
 
 	// Unmarshal the provider inputs.
 	var inputs resource.PropertyMap
 	if ins := resp.GetInputs(); ins != nil {
 		inputs, err = UnmarshalProperties(ins, MarshalOptions{
-			Label:          fmt.Sprintf("%s.inputs", label),
-			KeepUnknowns:   allowUnknowns,
-			RejectUnknowns: !allowUnknowns,
-			KeepSecrets:    true,
+			Label:            fmt.Sprintf("%s.inputs", label),
+			KeepUnknowns:     allowUnknowns,
+			RejectUnknowns:   !allowUnknowns,
+			KeepSecrets:      true,
+			WorkingDirectory: p.ctx.Pwd,
+			AssetOptions:     p.ctx.AssetOptions(),
 		})
 		if err != nil {
 			return nil, nil, err
@@ -503,17 +577,22 @@ func (p *provider) Diff(urn resource.URN, id resource.ID,
 		return DiffResult{}, err
 	}
 
-	resp, err := client.Diff(p.ctx.Request(), &pulumirpc.DiffRequest{
+	span, reqctx := p.startSpan(p.ctx.ResourceRequest(urn), "Diff", urn)
+	resp, err := client.Diff(reqctx, &pulumirpc.DiffRequest{
 		Id:   string(id),
 		Urn:  string(urn),
 		Olds: molds,
 		News: mnews,
 	})
+	span.End(err)
 	if err != nil {
 		rpcError := rpcerror.Convert(err)
 		logging.V(7).Infof("%s failed: %v", label, rpcError.Message())
 		return DiffResult{}, rpcError
 	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return DiffResult{}, err
+	}
 
 	var replaces []resource.PropertyKey
 	for _, replace := range resp.GetReplaces() {
@@ -572,10 +651,14 @@ func (p *provider) Create(urn resource.URN, props resource.PropertyMap) (resourc
 	var liveObject *_struct.Struct
 	var resourceError error
 	var resourceStatus = resource.StatusOK
-	resp, err := client.Create(p.ctx.Request(), &pulumirpc.CreateRequest{
+	opctx, done := p.operationContext(urn)
+	defer done()
+	span, opctx := p.startSpan(opctx, "Create", urn)
+	resp, err := client.Create(opctx, &pulumirpc.CreateRequest{
 		Urn:        string(urn),
 		Properties: mprops,
 	})
+	span.End(err)
 	if err != nil {
 		resourceStatus, id, liveObject, _, resourceError = parseError(err)
 		logging.V(7).Infof("%s failed: %v", label, resourceError)
@@ -587,6 +670,8 @@ func (p *provider) Create(urn resource.URN, props resource.PropertyMap) (resourc
 	} else {
 		id = resource.ID(resp.GetId())
 		liveObject = resp.GetProperties()
+		p.warnUnknownFields(label, resp)
+		p.putArtifacts(urn, resp.GetArtifacts())
 	}
 
 	if id == "" {
@@ -595,9 +680,11 @@ func (p *provider) Create(urn resource.URN, props resource.PropertyMap) (resourc
 	}
 
 	outs, err := UnmarshalProperties(liveObject, MarshalOptions{
-		Label:          fmt.Sprintf("%s.outputs", label),
-		RejectUnknowns: true,
-		KeepSecrets:    true,
+		Label:            fmt.Sprintf("%s.outputs", label),
+		RejectUnknowns:   true,
+		KeepSecrets:      true,
+		WorkingDirectory: p.ctx.Pwd,
+		AssetOptions:     p.ctx.AssetOptions(),
 	})
 	if err != nil {
 		return "", nil, resourceStatus, err
@@ -670,12 +757,14 @@ func (p *provider) Read(urn resource.URN, id resource.ID,
 	var liveInputs *_struct.Struct
 	var resourceError error
 	var resourceStatus = resource.StatusOK
-	resp, err := client.Read(p.ctx.Request(), &pulumirpc.ReadRequest{
+	span, reqctx := p.startSpan(p.withOperationSettings(p.ctx.ResourceRequest(urn), urn), "Read", urn)
+	resp, err := client.Read(reqctx, &pulumirpc.ReadRequest{
 		Id:         string(id),
 		Urn:        string(urn),
 		Properties: mstate,
 		Inputs:     minputs,
 	})
+	span.End(err)
 	if err != nil {
 		resourceStatus, readID, liveObject, liveInputs, resourceError = parseError(err)
 		logging.V(7).Infof("%s failed: %v", label, err)
@@ -685,6 +774,9 @@ func (p *provider) Read(urn resource.URN, id resource.ID,
 		}
 		// Else it's a `StatusPartialFailure`.
 	} else {
+		if err = p.checkUnknownFields(label, resp); err != nil {
+			return ReadResult{}, resource.StatusOK, err
+		}
 		readID = resource.ID(resp.GetId())
 		liveObject = resp.GetProperties()
 		liveInputs = resp.GetInputs()
@@ -700,9 +792,11 @@ func (p *provider) Read(urn resource.URN, id resource.ID,
 
 	// Finally, unmarshal the resulting state properties and return them.
 	newState, err := UnmarshalProperties(liveObject, MarshalOptions{
-		Label:          fmt.Sprintf("%s.outputs", label),
-		RejectUnknowns: true,
-		KeepSecrets:    true,
+		Label:            fmt.Sprintf("%s.outputs", label),
+		RejectUnknowns:   true,
+		KeepSecrets:      true,
+		WorkingDirectory: p.ctx.Pwd,
+		AssetOptions:     p.ctx.AssetOptions(),
 	})
 	if err != nil {
 		return ReadResult{}, resourceStatus, err
@@ -711,9 +805,11 @@ func (p *provider) Read(urn resource.URN, id resource.ID,
 	var newInputs resource.PropertyMap
 	if liveInputs != nil {
 		newInputs, err = UnmarshalProperties(liveInputs, MarshalOptions{
-			Label:          label + ".inputs",
-			RejectUnknowns: true,
-			KeepSecrets:    true,
+			Label:            label + ".inputs",
+			RejectUnknowns:   true,
+			KeepSecrets:      true,
+			WorkingDirectory: p.ctx.Pwd,
+			AssetOptions:     p.ctx.AssetOptions(),
 		})
 		if err != nil {
 			return ReadResult{}, resourceStatus, err
@@ -774,12 +870,16 @@ func (p *provider) Update(urn resource.URN, id resource.ID,
 	var liveObject *_struct.Struct
 	var resourceError error
 	var resourceStatus = resource.StatusOK
-	resp, err := client.Update(p.ctx.Request(), &pulumirpc.UpdateRequest{
+	opctx, done := p.operationContext(urn)
+	defer done()
+	span, opctx := p.startSpan(opctx, "Update", urn)
+	resp, err := client.Update(opctx, &pulumirpc.UpdateRequest{
 		Id:   string(id),
 		Urn:  string(urn),
 		Olds: molds,
 		News: mnews,
 	})
+	span.End(err)
 	if err != nil {
 		resourceStatus, _, liveObject, _, resourceError = parseError(err)
 		logging.V(7).Infof("%s failed: %v", label, resourceError)
@@ -790,12 +890,16 @@ func (p *provider) Update(urn resource.URN, id resource.ID,
 		// Else it's a `StatusPartialFailure`.
 	} else {
 		liveObject = resp.GetProperties()
+		p.warnUnknownFields(label, resp)
+		p.putArtifacts(urn, resp.GetArtifacts())
 	}
 
 	outs, err := UnmarshalProperties(liveObject, MarshalOptions{
-		Label:          fmt.Sprintf("%s.outputs", label),
-		RejectUnknowns: true,
-		KeepSecrets:    true,
+		Label:            fmt.Sprintf("%s.outputs", label),
+		RejectUnknowns:   true,
+		KeepSecrets:      true,
+		WorkingDirectory: p.ctx.Pwd,
+		AssetOptions:     p.ctx.AssetOptions(),
 	})
 	if err != nil {
 		return nil, resourceStatus, err
@@ -841,11 +945,16 @@ func (p *provider) Delete(urn resource.URN, id resource.ID, props resource.Prope
 	// We should only be calling {Create,Update,Delete} if the provider is fully configured.
 	contract.Assert(p.cfgknown)
 
-	if _, err := client.Delete(p.ctx.Request(), &pulumirpc.DeleteRequest{
+	opctx, done := p.operationContext(urn)
+	defer done()
+	span, opctx := p.startSpan(opctx, "Delete", urn)
+	_, err = client.Delete(opctx, &pulumirpc.DeleteRequest{
 		Id:         string(id),
 		Urn:        string(urn),
 		Properties: mprops,
-	}); err != nil {
+	})
+	span.End(err)
+	if err != nil {
 		resourceStatus, rpcErr := resourceStateAndError(err)
 		logging.V(7).Infof("%s failed: %v", label, rpcErr)
 		return resourceStatus, rpcErr
@@ -888,12 +997,17 @@ func (p *provider) Invoke(tok tokens.ModuleMember, args resource.PropertyMap) (r
 		logging.V(7).Infof("%s failed: %v", label, rpcError.Message())
 		return nil, nil, rpcError
 	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return nil, nil, err
+	}
 
 	// Unmarshal any return values.
 	ret, err := UnmarshalProperties(resp.GetReturn(), MarshalOptions{
-		Label:          fmt.Sprintf("%s.returns", label),
-		RejectUnknowns: true,
-		KeepSecrets:    true,
+		Label:            fmt.Sprintf("%s.returns", label),
+		RejectUnknowns:   true,
+		KeepSecrets:      true,
+		WorkingDirectory: p.ctx.Pwd,
+		AssetOptions:     p.ctx.AssetOptions(),
 	})
 	if err != nil {
 		return nil, nil, err
@@ -909,19 +1023,209 @@ func (p *provider) Invoke(tok tokens.ModuleMember, args resource.PropertyMap) (r
 	return ret, failures, nil
 }
 
+// MigrateState upgrades the state of a resource last managed by the given older version of this provider. Providers
+// that do not implement the MigrateState RPC leave the state unmodified.
+func (p *provider) MigrateState(urn resource.URN, id resource.ID, version semver.Version,
+	inputs, outputs resource.PropertyMap) (resource.PropertyMap, resource.PropertyMap, error) {
+	contract.Assert(urn != "")
+	contract.Assert(id != "")
+
+	label := fmt.Sprintf("%s.MigrateState(%s,%s,%s)", p.label(), urn, id, version)
+	logging.V(7).Infof("%s executing (#inputs=%d,#outputs=%d)", label, len(inputs), len(outputs))
+
+	minputs, err := MarshalProperties(inputs, MarshalOptions{
+		Label:              fmt.Sprintf("%s.inputs", label),
+		ElideAssetContents: true,
+		KeepSecrets:        p.acceptSecrets,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	moutputs, err := MarshalProperties(outputs, MarshalOptions{
+		Label:              fmt.Sprintf("%s.outputs", label),
+		ElideAssetContents: true,
+		KeepSecrets:        p.acceptSecrets,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get the RPC client and ensure it's configured.
+	client, err := p.getClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := client.MigrateState(p.ctx.Request(), &pulumirpc.MigrateStateRequest{
+		Urn:     string(urn),
+		Id:      string(id),
+		Version: version.String(),
+		Inputs:  minputs,
+		Outputs: moutputs,
+	})
+	if err != nil {
+		rpcError := rpcerror.Convert(err)
+		if rpcError.Code() == codes.Unimplemented {
+			// For backwards compatibility, providers that cannot migrate state leave it as-is.
+			logging.V(7).Infof("%s unimplemented rpc: returning state as is", label)
+			return inputs, outputs, nil
+		}
+		logging.V(7).Infof("%s failed: %v", label, rpcError.Message())
+		return nil, nil, rpcError
+	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return nil, nil, err
+	}
+
+	newInputs, err := UnmarshalProperties(resp.GetInputs(), MarshalOptions{
+		Label:            fmt.Sprintf("%s.newInputs", label),
+		RejectUnknowns:   true,
+		KeepSecrets:      true,
+		WorkingDirectory: p.ctx.Pwd,
+		AssetOptions:     p.ctx.AssetOptions(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	newOutputs, err := UnmarshalProperties(resp.GetOutputs(), MarshalOptions{
+		Label:            fmt.Sprintf("%s.newOutputs", label),
+		RejectUnknowns:   true,
+		KeepSecrets:      true,
+		WorkingDirectory: p.ctx.Pwd,
+		AssetOptions:     p.ctx.AssetOptions(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// If we could not pass secrets to the provider, retain the secret bit on any property with the same name.
+	if !p.acceptSecrets {
+		annotateSecrets(newInputs, inputs)
+		annotateSecrets(newOutputs, outputs)
+	}
+
+	logging.V(7).Infof("%s success (#inputs=%d,#outputs=%d)", label, len(newInputs), len(newOutputs))
+	return newInputs, newOutputs, nil
+}
+
+// ExplainFailure asks the provider for structured details about an error returned by an operation on a resource.
+// Providers that do not implement the RPC have nothing to add, so nil details are returned for them.
+func (p *provider) ExplainFailure(urn resource.URN, failure error) (*FailureDetails, error) {
+	contract.Assert(urn != "")
+	contract.Assert(failure != nil)
+
+	label := fmt.Sprintf("%s.ExplainFailure(%s)", p.label(), urn)
+	logging.V(7).Infof("%s executing", label)
+
+	// Get the RPC client and ensure it's configured.
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.ExplainFailure(p.ctx.Request(), &pulumirpc.ExplainFailureRequest{
+		Urn:   string(urn),
+		Error: failure.Error(),
+	})
+	if err != nil {
+		rpcError := rpcerror.Convert(err)
+		if rpcError.Code() == codes.Unimplemented {
+			logging.V(7).Infof("%s unimplemented rpc: no explanation", label)
+			return nil, nil
+		}
+		logging.V(7).Infof("%s failed: %v", label, rpcError.Message())
+		return nil, rpcError
+	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return nil, err
+	}
+
+	details := FailureDetails{
+		Code:        resp.GetCode(),
+		Retryable:   resp.GetRetryable(),
+		Remediation: resp.GetRemediation(),
+		DocsURL:     resp.GetDocsUrl(),
+	}
+	if details.Code == "" && !details.Retryable && len(details.Remediation) == 0 && details.DocsURL == "" {
+		logging.V(7).Infof("%s success: no explanation", label)
+		return nil, nil
+	}
+
+	logging.V(7).Infof("%s success (code=%s)", label, details.Code)
+	return &details, nil
+}
+
+// CheckHealth asks the provider whether the given resource is healthy. Providers that do not implement the RPC are
+// unable to check the health of their resources, so ErrHealthCheckUnsupported is returned for them.
+func (p *provider) CheckHealth(urn resource.URN, id resource.ID, state resource.PropertyMap) error {
+	contract.Assert(urn != "")
+	contract.Assert(id != "")
+
+	label := fmt.Sprintf("%s.CheckHealth(%s,%s)", p.label(), urn, id)
+	logging.V(7).Infof("%s executing (#props=%d)", label, len(state))
+
+	mprops, err := MarshalProperties(state, MarshalOptions{
+		Label:              label,
+		ElideAssetContents: true,
+		KeepSecrets:        p.acceptSecrets,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Get the RPC client and ensure it's configured.
+	client, err := p.getClient()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.CheckHealth(p.ctx.Request(), &pulumirpc.CheckHealthRequest{
+		Urn:        string(urn),
+		Id:         string(id),
+		Properties: mprops,
+	})
+	if err != nil {
+		rpcError := rpcerror.Convert(err)
+		if rpcError.Code() == codes.Unimplemented {
+			logging.V(7).Infof("%s unimplemented rpc: health checks unsupported", label)
+			return ErrHealthCheckUnsupported
+		}
+		logging.V(7).Infof("%s failed: %v", label, rpcError.Message())
+		return rpcError
+	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return err
+	}
+
+	if !resp.GetHealthy() {
+		reason := resp.GetReason()
+		if reason == "" {
+			reason = "the resource is unhealthy"
+		}
+		logging.V(7).Infof("%s success: unhealthy (%s)", label, reason)
+		return errors.New(reason)
+	}
+
+	logging.V(7).Infof("%s success: healthy", label)
+	return nil
+}
+
 // GetPluginInfo returns this plugin's information.
 func (p *provider) GetPluginInfo() (workspace.PluginInfo, error) {
 	label := fmt.Sprintf("%s.GetPluginInfo()", p.label())
 	logging.V(7).Infof("%s executing", label)
 
 	// Calling GetPluginInfo happens immediately after loading, and does not require configuration to proceed.
-	// Thus, we access the clientRaw property, rather than calling getClient.
-	resp, err := p.clientRaw.GetPluginInfo(p.ctx.Request(), &pbempty.Empty{})
+	// Thus, we access the raw client, rather than calling getClient.
+	resp, err := p.raw().GetPluginInfo(p.ctx.Request(), &pbempty.Empty{})
 	if err != nil {
 		rpcError := rpcerror.Convert(err)
 		logging.V(7).Infof("%s failed: err=%v", label, rpcError.Message())
 		return workspace.PluginInfo{}, rpcError
 	}
+	if err = p.checkUnknownFields(label, resp); err != nil {
+		return workspace.PluginInfo{}, err
+	}
 
 	var version *semver.Version
 	if v := resp.Version; v != "" {
@@ -934,14 +1238,14 @@ func (p *provider) GetPluginInfo() (workspace.PluginInfo, error) {
 
 	return workspace.PluginInfo{
 		Name:    string(p.pkg),
-		Path:    p.plug.Bin,
+		Path:    p.currentPlugin().Bin,
 		Kind:    workspace.ResourcePlugin,
 		Version: version,
 	}, nil
 }
 
 func (p *provider) SignalCancellation() error {
-	_, err := p.clientRaw.Cancel(p.ctx.Request(), &pbempty.Empty{})
+	_, err := p.raw().Cancel(p.ctx.Request(), &pbempty.Empty{})
 	if err != nil {
 		rpcError := rpcerror.Convert(err)
 		logging.V(8).Infof("provider received rpc error `%s`: `%s`", rpcError.Code(),
@@ -956,9 +1260,228 @@ func (p *provider) SignalCancellation() error {
 	return err
 }
 
+// Restart relaunches the provider's plugin process if it has exited, reconnects to the new process, and configures it
+// with the configuration that was sent to the original process. It returns true if the plugin was restarted.
+func (p *provider) Restart() (bool, error) {
+	label := fmt.Sprintf("%s.Restart()", p.label())
+
+	// Wait for any configuration in flight so that we know what to send to the new process.
+	<-p.cfgdone
+
+	p.plugLock.Lock()
+	defer p.plugLock.Unlock()
+
+	if !p.plug.Exited() {
+		return false, nil
+	}
+	logging.V(7).Infof("%s plugin process exited; restarting", label)
+	if err := p.relaunchLocked(); err != nil {
+		return false, errors.Wrapf(err, "restarting plugin for package '%v'", p.pkg)
+	}
+	p.restarts++
+
+	if p.cfgvars != nil {
+		_, err := p.clientRaw.Configure(p.ctx.Request(), &pulumirpc.ConfigureRequest{
+			AcceptSecrets: true,
+			Variables:     p.cfgvars,
+		})
+		if err != nil {
+			rpcError := rpcerror.Convert(err)
+			logging.V(7).Infof("%s failed to reconfigure: err=%v", label, rpcError.Message())
+			return true, createConfigureError(rpcError)
+		}
+	}
+
+	logging.V(7).Infof("%s success", label)
+	return true, nil
+}
+
+// relaunch replaces the provider's plugin process with a new, unconfigured one.
+func (p *provider) relaunch() error {
+	p.plugLock.Lock()
+	defer p.plugLock.Unlock()
+	return p.relaunchLocked()
+}
+
+// relaunchLocked replaces the provider's plugin process with a new, unconfigured one. The caller must hold plugLock.
+func (p *provider) relaunchLocked() error {
+	contract.IgnoreError(p.plug.Close())
+
+	plug, err := p.launch()
+	if err != nil {
+		return err
+	}
+	p.plug, p.clientRaw = plug, pulumirpc.NewResourceProviderClient(plug.Conn)
+	return nil
+}
+
+// park prepares the provider to be handed out again by a host pool once its current user is done with it. The next
+// call to Configure reuses the plugin process if the configuration is unchanged, and replaces it otherwise. park
+// returns false if the plugin process cannot be reused, e.g. because it has exited or failed to configure.
+func (p *provider) park() bool {
+	// A provider that was never configured, or whose configuration is still in flight, is not worth keeping.
+	select {
+	case <-p.cfgdone:
+	default:
+		return false
+	}
+
+	if p.cfgerr != nil || p.currentPlugin().Exited() {
+		return false
+	}
+
+	p.opLock.Lock()
+	busy := len(p.operations) > 0
+	p.opLock.Unlock()
+	if busy {
+		return false
+	}
+
+	// A process whose configuration was unknown was never configured, so it may be configured as if it were new. A
+	// parked process that was then left unconfigured is simply replaced.
+	if p.cfgknown {
+		p.parkedcfg = p.cfgvars
+	} else if p.parkedcfg != nil {
+		return false
+	}
+	p.cfgerr, p.cfgknown, p.cfgvars, p.cfgdone = nil, false, nil, make(chan bool)
+	return true
+}
+
+// Restarts returns the number of times the provider's plugin process has been restarted.
+func (p *provider) Restarts() int {
+	p.plugLock.RLock()
+	defer p.plugLock.RUnlock()
+	return p.restarts
+}
+
+// operationContext returns the context for a create, update, or delete of the given resource, which CancelOperation
+// cancels. The returned function must be called once the operation has completed.
+func (p *provider) operationContext(urn resource.URN) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(p.withOperationSettings(p.ctx.ResourceRequest(urn), urn))
+
+	p.opLock.Lock()
+	defer p.opLock.Unlock()
+	if p.operations == nil {
+		p.operations = make(map[resource.URN]func())
+	}
+	p.operations[urn] = cancel
+
+	return ctx, func() {
+		p.opLock.Lock()
+		defer p.opLock.Unlock()
+		delete(p.operations, urn)
+		cancel()
+	}
+}
+
+// ConfigureOperation sets the settings that are sent to the plugin with the next operation on the given resource.
+func (p *provider) ConfigureOperation(urn resource.URN, settings OperationSettings) {
+	p.settingsLock.Lock()
+	defer p.settingsLock.Unlock()
+	if settings == (OperationSettings{}) {
+		delete(p.settings, urn)
+		return
+	}
+	if p.settings == nil {
+		p.settings = make(map[resource.URN]OperationSettings)
+	}
+	p.settings[urn] = settings
+}
+
+// withOperationSettings returns the given context with the operation settings of the given resource, if it has any,
+// attached as outgoing gRPC metadata.
+func (p *provider) withOperationSettings(ctx context.Context, urn resource.URN) context.Context {
+	p.settingsLock.Lock()
+	settings, has := p.settings[urn]
+	p.settingsLock.Unlock()
+	if !has {
+		return ctx
+	}
+
+	var kvs []string
+	if settings.Timeout != 0 {
+		kvs = append(kvs, OperationTimeoutMetadataKey, settings.Timeout.String())
+	}
+	if settings.PollInterval != 0 {
+		kvs = append(kvs, PollIntervalMetadataKey, settings.PollInterval.String())
+	}
+	return metadata.AppendToOutgoingContext(ctx, kvs...)
+}
+
+// providerCall is a call to a provider method that is traced and timed.
+type providerCall struct {
+	p      *provider
+	method string
+	start  time.Time
+	span   *tracing.Span
+}
+
+// End finishes the call's span and reports its duration to the context's call observer, if any.
+func (c *providerCall) End(err error) {
+	c.span.End(err)
+	c.p.ctx.observeCall(c.p.pkg, c.method, time.Since(c.start), err)
+}
+
+// startSpan starts a span for a call to the given provider method on behalf of the given resource.
+func (p *provider) startSpan(ctx context.Context, method string, urn resource.URN) (*providerCall, context.Context) {
+	span, ctx := tracing.StartSpan(ctx, "pulumi-provider-"+strings.ToLower(method), opentracing.Tags{
+		tracing.URNTag:    string(urn),
+		tracing.OpTag:     method,
+		"pulumi.provider": string(p.pkg),
+	})
+	return &providerCall{p: p, method: method, start: time.Now(), span: span}, ctx
+}
+
+// CancelOperation cancels the in-flight create, update, or delete of the given resource, if there is one.
+func (p *provider) CancelOperation(urn resource.URN) {
+	p.opLock.Lock()
+	defer p.opLock.Unlock()
+	if cancel, has := p.operations[urn]; has {
+		logging.V(7).Infof("%s.CancelOperation(%s): canceling", p.label(), urn)
+		cancel()
+	}
+}
+
+// putArtifacts records the artifacts that the plugin returned from an operation on the given resource, to be taken by
+// the engine once the operation completes.
+func (p *provider) putArtifacts(urn resource.URN, artifacts []*pulumirpc.Artifact) {
+	if len(artifacts) == 0 {
+		return
+	}
+
+	files := make([]ArtifactFile, len(artifacts))
+	for i, a := range artifacts {
+		files[i] = ArtifactFile{Name: a.GetName(), ContentType: a.GetContentType(), Contents: a.GetContents()}
+	}
+
+	p.artLock.Lock()
+	defer p.artLock.Unlock()
+	if p.artifacts == nil {
+		p.artifacts = make(map[resource.URN][]ArtifactFile)
+	}
+	p.artifacts[urn] = files
+}
+
+// TakeArtifacts returns and forgets the artifacts produced by the last create or update of the given resource.
+func (p *provider) TakeArtifacts(urn resource.URN) []ArtifactFile {
+	p.artLock.Lock()
+	defer p.artLock.Unlock()
+	files := p.artifacts[urn]
+	delete(p.artifacts, urn)
+	return files
+}
+
+// currentPlugin returns the current plugin process.
+func (p *provider) currentPlugin() *plugin {
+	p.plugLock.RLock()
+	defer p.plugLock.RUnlock()
+	return p.plug
+}
+
 // Close tears down the underlying plugin RPC connection and process.
 func (p *provider) Close() error {
-	return p.plug.Close()
+	return p.currentPlugin().Close()
 }
 
 // createConfigureError creates a nice error message from an RPC error that
@@ -1,12 +1,20 @@
 package plugin
 
 import (
+	"context"
+	"net"
 	"reflect"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	lumirpc "github.com/pulumi/pulumi/sdk/proto/go"
 )
 
 func TestAnnotateSecrets(t *testing.T) {
@@ -217,3 +225,197 @@ func TestNestedSecret(t *testing.T) {
 
 	assert.Truef(t, reflect.DeepEqual(to, expected), "did not match expected after annotation")
 }
+
+func TestReportProgress(t *testing.T) {
+	ctx := &Context{}
+	eng := &hostServer{ctx: ctx}
+	urn := resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA")
+
+	// Reports made before a function is registered are dropped.
+	_, err := eng.ReportProgress(context.Background(), &lumirpc.ReportProgressRequest{Urn: string(urn), Percent: 10})
+	assert.NoError(t, err)
+
+	type report struct {
+		urn     resource.URN
+		percent int
+		message string
+	}
+	var reports []report
+	ctx.SetProgressFunc(func(urn resource.URN, percent int, message string) {
+		reports = append(reports, report{urn, percent, message})
+	})
+
+	for _, req := range []*lumirpc.ReportProgressRequest{
+		{Urn: string(urn), Percent: 50, Message: "waiting for nodes"},
+		{Urn: string(urn), Percent: -1, Message: "draining"},
+		{Urn: string(urn), Percent: 250, Message: "done"},
+	} {
+		_, err = eng.ReportProgress(context.Background(), req)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []report{
+		{urn, 50, "waiting for nodes"},
+		{urn, -1, "draining"},
+		{urn, 100, "done"},
+	}, reports)
+}
+
+// testProviderServer is a gRPC provider server whose optional RPCs are implemented by the functions it holds. RPCs
+// whose function is nil are unimplemented. Calls to any other RPC panic.
+type testProviderServer struct {
+	lumirpc.ResourceProviderServer
+
+	explainFailure func(req *lumirpc.ExplainFailureRequest) (*lumirpc.ExplainFailureResponse, error)
+	checkHealth    func(req *lumirpc.CheckHealthRequest) (*lumirpc.CheckHealthResponse, error)
+}
+
+func (s *testProviderServer) ExplainFailure(ctx context.Context,
+	req *lumirpc.ExplainFailureRequest) (*lumirpc.ExplainFailureResponse, error) {
+	if s.explainFailure == nil {
+		return nil, status.Error(codes.Unimplemented, "ExplainFailure is not yet implemented")
+	}
+	return s.explainFailure(req)
+}
+
+func (s *testProviderServer) CheckHealth(ctx context.Context,
+	req *lumirpc.CheckHealthRequest) (*lumirpc.CheckHealthResponse, error) {
+	if s.checkHealth == nil {
+		return nil, status.Error(codes.Unimplemented, "CheckHealth is not yet implemented")
+	}
+	return s.checkHealth(req)
+}
+
+// newGRPCProvider serves the given server over a local gRPC connection and returns a configured provider that talks
+// to it, along with a function that tears both down.
+func newGRPCProvider(t *testing.T, server lumirpc.ResourceProviderServer) (*provider, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv := grpc.NewServer()
+	lumirpc.RegisterResourceProviderServer(srv, server)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	cfgdone := make(chan bool)
+	close(cfgdone)
+	p := &provider{
+		ctx:       &Context{},
+		pkg:       "pkgA",
+		clientRaw: lumirpc.NewResourceProviderClient(conn),
+		cfgknown:  true,
+		cfgdone:   cfgdone,
+	}
+	return p, func() {
+		contract.IgnoreClose(conn)
+		srv.Stop()
+	}
+}
+
+func TestExplainFailure(t *testing.T) {
+	urn := resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA")
+	failure := errors.New("quota exceeded")
+
+	p, done := newGRPCProvider(t, &testProviderServer{
+		explainFailure: func(req *lumirpc.ExplainFailureRequest) (*lumirpc.ExplainFailureResponse, error) {
+			switch {
+			case req.GetUrn() != string(urn):
+				return nil, status.Errorf(codes.InvalidArgument, "unexpected URN %s", req.GetUrn())
+			case req.GetError() == "boom":
+				return nil, status.Error(codes.Internal, "boom")
+			case req.GetError() != "quota exceeded":
+				return &lumirpc.ExplainFailureResponse{}, nil
+			}
+			return &lumirpc.ExplainFailureResponse{
+				Code:        "QuotaExceeded",
+				Retryable:   true,
+				Remediation: []string{"request a quota increase"},
+				DocsUrl:     "https://example.com/quotas",
+			}, nil
+		},
+	})
+	defer done()
+
+	details, err := p.ExplainFailure(urn, failure)
+	assert.NoError(t, err)
+	assert.Equal(t, &FailureDetails{
+		Code:        "QuotaExceeded",
+		Retryable:   true,
+		Remediation: []string{"request a quota increase"},
+		DocsURL:     "https://example.com/quotas",
+	}, details)
+
+	// A provider that has nothing to add returns no details.
+	details, err = p.ExplainFailure(urn, errors.New("something else"))
+	assert.NoError(t, err)
+	assert.Nil(t, details)
+
+	// Errors other than Unimplemented are reported.
+	_, err = p.ExplainFailure(urn, errors.New("boom"))
+	assert.Error(t, err)
+}
+
+func TestExplainFailureUnimplemented(t *testing.T) {
+	p, done := newGRPCProvider(t, &testProviderServer{})
+	defer done()
+
+	details, err := p.ExplainFailure(resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA"), errors.New("failed"))
+	assert.NoError(t, err)
+	assert.Nil(t, details)
+}
+
+func TestCheckHealth(t *testing.T) {
+	urn := resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA")
+
+	p, done := newGRPCProvider(t, &testProviderServer{
+		checkHealth: func(req *lumirpc.CheckHealthRequest) (*lumirpc.CheckHealthResponse, error) {
+			if req.GetUrn() != string(urn) {
+				return nil, status.Errorf(codes.InvalidArgument, "unexpected URN %s", req.GetUrn())
+			}
+			switch req.GetProperties().GetFields()["status"].GetStringValue() {
+			case "ACTIVE":
+				return &lumirpc.CheckHealthResponse{Healthy: true}, nil
+			case "PENDING":
+				return &lumirpc.CheckHealthResponse{Reason: "the load balancer has no healthy targets"}, nil
+			case "":
+				return &lumirpc.CheckHealthResponse{}, nil
+			default:
+				return nil, status.Error(codes.Internal, "boom")
+			}
+		},
+	})
+	defer done()
+
+	state := func(s string) resource.PropertyMap {
+		if s == "" {
+			return resource.PropertyMap{}
+		}
+		return resource.PropertyMap{"status": resource.NewStringProperty(s)}
+	}
+
+	assert.NoError(t, p.CheckHealth(urn, "id", state("ACTIVE")))
+
+	err := p.CheckHealth(urn, "id", state("PENDING"))
+	assert.EqualError(t, err, "the load balancer has no healthy targets")
+
+	err = p.CheckHealth(urn, "id", state(""))
+	assert.EqualError(t, err, "the resource is unhealthy")
+
+	err = p.CheckHealth(urn, "id", state("FAILED"))
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrHealthCheckUnsupported, err)
+}
+
+func TestCheckHealthUnimplemented(t *testing.T) {
+	p, done := newGRPCProvider(t, &testProviderServer{})
+	defer done()
+
+	err := p.CheckHealth(resource.URN("urn:pulumi:test::test::pkgA:m:typA::resA"), "id", resource.PropertyMap{})
+	assert.Equal(t, ErrHealthCheckUnsupported, err)
+}
@@ -15,6 +15,7 @@
 package plugin
 
 import (
+	"path/filepath"
 	"reflect"
 	"sort"
 
@@ -35,6 +36,43 @@ type MarshalOptions struct {
 	ElideAssetContents bool   // true if we are eliding the contents of assets.
 	ComputeAssetHashes bool   // true if we are computing missing asset hashes on the fly.
 	KeepSecrets        bool   // true if we are keeping secrets (otherwise we replace them with their underlying value).
+	WorkingDirectory   string // the directory against which relative asset paths are resolved when computing hashes.
+
+	// AssetOptions controls how the contents of assets and archives are read when computing hashes.
+	AssetOptions resource.AssetOptions
+}
+
+// hashAsset computes the missing hash of the given asset if the options ask for it. If they do not, but the asset
+// refers to a relative path and the options give the directory against which to resolve it, the hash is computed
+// anyway where possible, so that the path never needs to be resolved later against the process's working directory,
+// which may be shared by other operations.
+func (opts MarshalOptions) hashAsset(asset *resource.Asset) error {
+	switch {
+	case asset.Hash != "":
+		return nil
+	case opts.ComputeAssetHashes:
+		return ensureAssetHash(asset, opts.WorkingDirectory, opts.AssetOptions)
+	case opts.WorkingDirectory != "" && hasRelativeAssetPath(asset):
+		if err := ensureAssetHash(asset, opts.WorkingDirectory, opts.AssetOptions); err != nil {
+			logging.V(5).Infof("%s: not computing hash of asset %s: %v", opts.Label, asset.Path, err)
+		}
+	}
+	return nil
+}
+
+// hashArchive computes the missing hash of the given archive if the options ask for it. See hashAsset.
+func (opts MarshalOptions) hashArchive(archive *resource.Archive) error {
+	switch {
+	case archive.Hash != "":
+		return nil
+	case opts.ComputeAssetHashes:
+		return ensureArchiveHash(archive, opts.WorkingDirectory, opts.AssetOptions)
+	case opts.WorkingDirectory != "" && hasRelativeArchivePath(archive):
+		if err := ensureArchiveHash(archive, opts.WorkingDirectory, opts.AssetOptions); err != nil {
+			logging.V(5).Infof("%s: not computing hash of archive %s: %v", opts.Label, archive.Path, err)
+		}
+	}
+	return nil
 }
 
 const (
@@ -292,10 +330,8 @@ func UnmarshalPropertyValue(v *structpb.Value, opts MarshalOptions) (*resource.P
 				return nil, err
 			}
 			contract.Assert(isasset)
-			if opts.ComputeAssetHashes {
-				if err = asset.EnsureHash(); err != nil {
-					return nil, errors.Wrapf(err, "failed to compute asset hash")
-				}
+			if err = opts.hashAsset(asset); err != nil {
+				return nil, errors.Wrapf(err, "failed to compute asset hash")
 			}
 			m := resource.NewAssetProperty(asset)
 			return &m, nil
@@ -305,10 +341,8 @@ func UnmarshalPropertyValue(v *structpb.Value, opts MarshalOptions) (*resource.P
 				return nil, err
 			}
 			contract.Assert(isarchive)
-			if opts.ComputeAssetHashes {
-				if err = archive.EnsureHash(); err != nil {
-					return nil, errors.Wrapf(err, "failed to compute archive hash")
-				}
+			if err = opts.hashArchive(archive); err != nil {
+				return nil, errors.Wrapf(err, "failed to compute archive hash")
 			}
 			m := resource.NewArchiveProperty(archive)
 			return &m, nil
@@ -394,10 +428,8 @@ func MarshalAsset(v *resource.Asset, opts MarshalOptions) (*structpb.Value, erro
 		v = &resource.Asset{Hash: v.Hash}
 	} else {
 		// Ensure a hash is present if needed.
-		if v.Hash == "" && opts.ComputeAssetHashes {
-			if err := v.EnsureHash(); err != nil {
-				return nil, errors.Wrapf(err, "failed to compute asset hash")
-			}
+		if err := opts.hashAsset(v); err != nil {
+			return nil, errors.Wrapf(err, "failed to compute asset hash")
 		}
 	}
 
@@ -407,6 +439,94 @@ func MarshalAsset(v *resource.Asset, opts MarshalOptions) (*structpb.Value, erro
 	return MarshalPropertyValue(resource.NewObjectProperty(serap), opts)
 }
 
+// ensureAssetHash computes the hash of the given asset if it does not already have one. If the asset refers to a
+// relative path, the path is resolved against the given directory rather than the process's working directory, which
+// may be shared by other operations; the asset itself retains the relative path. Its contents are read according to
+// the given options.
+func ensureAssetHash(asset *resource.Asset, dir string, assetOpts resource.AssetOptions) error {
+	if asset.Hash != "" || dir == "" {
+		return asset.EnsureHashWithOptions(assetOpts)
+	}
+	rebased := rebaseAsset(asset, dir)
+	if err := rebased.EnsureHashWithOptions(assetOpts); err != nil {
+		return err
+	}
+	asset.Hash = rebased.Hash
+	return nil
+}
+
+// ensureArchiveHash computes the hash of the given archive if it does not already have one, resolving relative paths
+// against the given directory. See ensureAssetHash.
+func ensureArchiveHash(archive *resource.Archive, dir string, assetOpts resource.AssetOptions) error {
+	if archive.Hash != "" || dir == "" {
+		return archive.EnsureHashWithOptions(assetOpts)
+	}
+	rebased := rebaseArchive(archive, dir)
+	if err := rebased.EnsureHashWithOptions(assetOpts); err != nil {
+		return err
+	}
+	archive.Hash = rebased.Hash
+	return nil
+}
+
+// hasRelativeAssetPath returns true if the given asset refers to a relative path.
+func hasRelativeAssetPath(asset *resource.Asset) bool {
+	return asset.Path != "" && !filepath.IsAbs(asset.Path)
+}
+
+// hasRelativeArchivePath returns true if the given archive, or any asset or archive within it, refers to a relative
+// path.
+func hasRelativeArchivePath(archive *resource.Archive) bool {
+	if archive.Path != "" && !filepath.IsAbs(archive.Path) {
+		return true
+	}
+	for _, a := range archive.Assets {
+		switch a := a.(type) {
+		case *resource.Asset:
+			if hasRelativeAssetPath(a) {
+				return true
+			}
+		case *resource.Archive:
+			if hasRelativeArchivePath(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rebaseAsset returns a copy of the given asset whose path, if it is relative, is joined to the given directory.
+func rebaseAsset(asset *resource.Asset, dir string) *resource.Asset {
+	rebased := *asset
+	if rebased.Path != "" && !filepath.IsAbs(rebased.Path) {
+		rebased.Path = filepath.Join(dir, rebased.Path)
+	}
+	return &rebased
+}
+
+// rebaseArchive returns a copy of the given archive whose path and the paths of whose assets, if they are relative, are
+// joined to the given directory.
+func rebaseArchive(archive *resource.Archive, dir string) *resource.Archive {
+	rebased := *archive
+	if rebased.Path != "" && !filepath.IsAbs(rebased.Path) {
+		rebased.Path = filepath.Join(dir, rebased.Path)
+	}
+	if archive.Assets != nil {
+		rebased.Assets = make(map[string]interface{}, len(archive.Assets))
+		for name, a := range archive.Assets {
+			switch a := a.(type) {
+			case *resource.Asset:
+				rebased.Assets[name] = rebaseAsset(a, dir)
+			case *resource.Archive:
+				rebased.Assets[name] = rebaseArchive(a, dir)
+			default:
+				rebased.Assets[name] = a
+			}
+		}
+	}
+	return &rebased
+}
+
 // MarshalArchive marshals an archive into its wire form for resource provider plugins.
 func MarshalArchive(v *resource.Archive, opts MarshalOptions) (*structpb.Value, error) {
 	// If we are not providing access to an asset's contents, we simply need to record the fact that this asset existed.
@@ -415,10 +535,8 @@ func MarshalArchive(v *resource.Archive, opts MarshalOptions) (*structpb.Value,
 		v = &resource.Archive{Hash: v.Hash}
 	} else {
 		// Ensure a hash is present if needed.
-		if v.Hash == "" && opts.ComputeAssetHashes {
-			if err := v.EnsureHash(); err != nil {
-				return nil, errors.Wrapf(err, "failed to compute archive hash")
-			}
+		if err := opts.hashArchive(v); err != nil {
+			return nil, errors.Wrapf(err, "failed to compute archive hash")
 		}
 	}
 
@@ -16,6 +16,9 @@ package plugin
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
@@ -70,6 +73,57 @@ func TestAssetSerialize(t *testing.T) {
 	}
 }
 
+func TestAssetHashWorkingDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "asset.txt"), []byte("a test asset"), 0600))
+
+	// Relative paths are resolved against the working directory rather than the process's current directory, but are
+	// otherwise left as-is.
+	opts := MarshalOptions{ComputeAssetHashes: true, WorkingDirectory: dir}
+	asset := &resource.Asset{Sig: resource.AssetSig, Path: "asset.txt"}
+	assetProps, err := MarshalPropertyValue(resource.NewAssetProperty(asset), opts)
+	assert.NoError(t, err)
+	assetValue, err := UnmarshalPropertyValue(assetProps, MarshalOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "asset.txt", assetValue.AssetValue().Path)
+	assert.Equal(t, "e34c74529110661faae4e121e57165ff4cb4dbdde1ef9770098aa3695e6b6704", assetValue.AssetValue().Hash)
+
+	// The same applies to the assets within archives.
+	absolute, err := resource.NewPathAsset(filepath.Join(dir, "asset.txt"))
+	assert.NoError(t, err)
+	expected, err := resource.NewAssetArchive(map[string]interface{}{"foo": absolute})
+	assert.NoError(t, err)
+
+	arch := &resource.Archive{Sig: resource.ArchiveSig, Assets: map[string]interface{}{
+		"foo": &resource.Asset{Sig: resource.AssetSig, Path: "asset.txt"},
+	}}
+	archProps, err := MarshalPropertyValue(resource.NewArchiveProperty(arch), MarshalOptions{})
+	assert.NoError(t, err)
+	archValue, err := UnmarshalPropertyValue(archProps, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Hash, archValue.ArchiveValue().Hash)
+	assert.Equal(t, "asset.txt", archValue.ArchiveValue().Assets["foo"].(*resource.Asset).Path)
+
+	// Given only a working directory, as when unmarshaling a provider's response, the hashes of relative paths are
+	// still computed, so that they are never resolved later against the process's current directory. Other assets
+	// are left alone.
+	opts = MarshalOptions{WorkingDirectory: dir}
+	unmarshal := func(a *resource.Asset) *resource.Asset {
+		props, err := MarshalPropertyValue(resource.NewAssetProperty(a), MarshalOptions{})
+		assert.NoError(t, err)
+		value, err := UnmarshalPropertyValue(props, opts)
+		assert.NoError(t, err)
+		return value.AssetValue()
+	}
+	assert.Equal(t, asset.Hash, unmarshal(&resource.Asset{Sig: resource.AssetSig, Path: "asset.txt"}).Hash)
+	assert.Empty(t, unmarshal(&resource.Asset{Sig: resource.AssetSig, URI: "https://example.com/asset.txt"}).Hash)
+
+	// A relative path that cannot be read is not an error, since the hash was not asked for.
+	assert.Empty(t, unmarshal(&resource.Asset{Sig: resource.AssetSig, Path: "missing.txt"}).Hash)
+}
+
 func TestComputedSerialize(t *testing.T) {
 	// Ensure that computed properties survive round trips.
 	opts := MarshalOptions{KeepUnknowns: true}
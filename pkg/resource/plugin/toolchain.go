@@ -0,0 +1,235 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/util/logging"
+)
+
+// toolchainInfo describes how to find and interrogate the toolchain a language runtime plugin depends upon.
+type toolchainInfo struct {
+	commands    []string // the executables to look for, in order of preference.
+	versionArgs []string // the arguments that make an executable print its version.
+	managers    []toolchainManager
+}
+
+// toolchainManager describes a version manager that installs side-by-side copies of a toolchain.
+type toolchainManager struct {
+	name    string // the name of the version manager (e.g. nvm).
+	rootEnv string // the environment variable that overrides the manager's root directory.
+	root    string // the default root directory, relative to the user's home directory.
+	install string // the command a user runs to install a particular version; %s is replaced with the version.
+}
+
+// versionRegexp extracts a version number from the output of a toolchain's version command.
+var versionRegexp = regexp.MustCompile(`v?(\d+\.\d+(\.\d+)?)`)
+
+// toolchains maps language runtime names to their toolchains.
+var toolchains = map[string]toolchainInfo{
+	"nodejs": {
+		commands:    []string{"node"},
+		versionArgs: []string{"--version"},
+		managers: []toolchainManager{
+			{name: "nvm", rootEnv: "NVM_DIR", root: filepath.Join(".nvm", "versions", "node"),
+				install: "nvm install %s"},
+			{name: "nodenv", rootEnv: "NODENV_ROOT", root: filepath.Join(".nodenv", "versions"),
+				install: "nodenv install %s"},
+		},
+	},
+	"python": {
+		commands:    []string{"python3", "python"},
+		versionArgs: []string{"--version"},
+		managers: []toolchainManager{
+			{name: "pyenv", rootEnv: "PYENV_ROOT", root: filepath.Join(".pyenv", "versions"),
+				install: "pyenv install %s"},
+		},
+	},
+	"go": {
+		commands:    []string{"go"},
+		versionArgs: []string{"version"},
+	},
+	"dotnet": {
+		commands:    []string{"dotnet"},
+		versionArgs: []string{"--version"},
+	},
+}
+
+// lookPath and toolchainVersionOutput are variables so that tests can replace them.
+var lookPath = exec.LookPath
+var toolchainVersionOutput = func(path string, args []string) (string, error) {
+	out, err := exec.Command(path, args...).CombinedOutput()
+	return string(out), err
+}
+
+// Toolchain is a language toolchain that satisfies a project's version requirements.
+type Toolchain struct {
+	Runtime string         // the language runtime the toolchain belongs to.
+	Version semver.Version // the version of the toolchain.
+	Dir     string         // the directory containing the toolchain's executables, if not the one found on the PATH.
+}
+
+// Env returns the environment variables a language plugin needs in order to use this toolchain.
+func (tc *Toolchain) Env() []string {
+	if tc.Dir == "" {
+		return nil
+	}
+	return []string{"PATH=" + tc.Dir + string(os.PathListSeparator) + os.Getenv("PATH")}
+}
+
+// MissingToolchainError is returned when no installed toolchain satisfies a project's version requirements.
+type MissingToolchainError struct {
+	Runtime  string // the language runtime.
+	Required string // the version range the project requires.
+	Found    string // the version found on the PATH, if any.
+	Hint     string // a suggestion for how to install a matching toolchain, if any.
+}
+
+func (e *MissingToolchainError) Error() string {
+	found := "no toolchain was found on the PATH"
+	if e.Found != "" {
+		found = fmt.Sprintf("version %s was found on the PATH", e.Found)
+	}
+	hint := "install a matching version"
+	if e.Hint != "" {
+		hint = e.Hint
+	}
+	return fmt.Sprintf("project requires %s %s, but %s; %s or update runtime.version in Pulumi.yaml",
+		e.Runtime, e.Required, found, hint)
+}
+
+// ResolveToolchain finds an installed toolchain for the given language runtime whose version satisfies the given
+// semver range. The toolchain on the PATH is preferred; failing that, the highest matching version installed by a
+// known version manager (nvm, nodenv, or pyenv) is used. If nothing matches, a *MissingToolchainError is returned.
+func ResolveToolchain(runtime string, constraint string) (*Toolchain, error) {
+	info, ok := toolchains[runtime]
+	if !ok {
+		return nil, errors.Errorf("runtime.version is not supported for the %s runtime", runtime)
+	}
+	rng, err := semver.ParseRange(constraint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid runtime.version %q", constraint)
+	}
+
+	var found string
+	for _, command := range info.commands {
+		path, err := lookPath(command)
+		if err != nil {
+			continue
+		}
+		out, err := toolchainVersionOutput(path, info.versionArgs)
+		if err != nil {
+			logging.V(7).Infof("ResolveToolchain(%s): %s %v failed: %v", runtime, path, info.versionArgs, err)
+			continue
+		}
+		v, ok := parseToolchainVersion(out)
+		if !ok {
+			logging.V(7).Infof("ResolveToolchain(%s): could not parse version from %q", runtime, out)
+			continue
+		}
+		if rng(v) {
+			logging.V(7).Infof("ResolveToolchain(%s): using %s (%s) from the PATH", runtime, path, v)
+			return &Toolchain{Runtime: runtime, Version: v}, nil
+		}
+		if found == "" {
+			found = v.String()
+		}
+	}
+
+	var best *Toolchain
+	var hint string
+	for _, mgr := range info.managers {
+		if hint == "" {
+			hint = fmt.Sprintf("install a matching version (e.g. `%s`)", fmt.Sprintf(mgr.install, constraint))
+		}
+		for _, tc := range managedToolchains(runtime, mgr, info.commands) {
+			if rng(tc.Version) && (best == nil || tc.Version.GT(best.Version)) {
+				best = tc
+			}
+		}
+	}
+	if best != nil {
+		logging.V(7).Infof("ResolveToolchain(%s): using %s from %s", runtime, best.Version, best.Dir)
+		return best, nil
+	}
+
+	return nil, &MissingToolchainError{Runtime: runtime, Required: constraint, Found: found, Hint: hint}
+}
+
+// managedToolchains returns the toolchains installed by a version manager. Each version lives in its own directory
+// beneath the manager's root, named after the version, with the executables in a bin subdirectory.
+func managedToolchains(runtime string, mgr toolchainManager, commands []string) []*Toolchain {
+	root := os.Getenv(mgr.rootEnv)
+	if root != "" && mgr.name == "nvm" {
+		root = filepath.Join(root, "versions", "node")
+	} else if root != "" {
+		root = filepath.Join(root, "versions")
+	} else {
+		usr, err := user.Current()
+		if err != nil {
+			return nil
+		}
+		root = filepath.Join(usr.HomeDir, mgr.root)
+	}
+
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+
+	var result []*Toolchain
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		v, err := semver.ParseTolerant(strings.TrimPrefix(entry.Name(), "v"))
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name(), "bin")
+		for _, command := range commands {
+			if _, err := os.Stat(filepath.Join(dir, command)); err == nil {
+				result = append(result, &Toolchain{Runtime: runtime, Version: v, Dir: dir})
+				break
+			}
+		}
+	}
+	return result
+}
+
+// parseToolchainVersion extracts the version from the output of a toolchain's version command, e.g. "v10.16.0",
+// "Python 3.7.3", or "go version go1.12.5 linux/amd64".
+func parseToolchainVersion(out string) (semver.Version, bool) {
+	m := versionRegexp.FindStringSubmatch(out)
+	if m == nil {
+		return semver.Version{}, false
+	}
+	v, err := semver.ParseTolerant(m[1])
+	if err != nil {
+		return semver.Version{}, false
+	}
+	return v, true
+}
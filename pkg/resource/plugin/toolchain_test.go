@@ -0,0 +1,101 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseToolchainVersion(t *testing.T) {
+	cases := map[string]string{
+		"v10.16.0\n":                        "10.16.0",
+		"Python 3.7.3\n":                    "3.7.3",
+		"go version go1.12.5 linux/amd64\n": "1.12.5",
+		"go version go1.12 darwin/amd64\n":  "1.12.0",
+		"2.2.300\n":                         "2.2.300",
+	}
+	for out, expected := range cases {
+		v, ok := parseToolchainVersion(out)
+		assert.True(t, ok, out)
+		assert.Equal(t, expected, v.String(), out)
+	}
+
+	_, ok := parseToolchainVersion("command not found")
+	assert.False(t, ok)
+}
+
+func TestResolveToolchain(t *testing.T) {
+	oldLookPath, oldVersionOutput := lookPath, toolchainVersionOutput
+	defer func() {
+		lookPath, toolchainVersionOutput = oldLookPath, oldVersionOutput
+	}()
+	lookPath = func(file string) (string, error) {
+		if file == "node" {
+			return "/usr/bin/node", nil
+		}
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	toolchainVersionOutput = func(path string, args []string) (string, error) {
+		return "v10.16.0\n", nil
+	}
+
+	nvmDir, err := ioutil.TempDir("", "nvm")
+	assert.NoError(t, err)
+	defer os.RemoveAll(nvmDir)
+	for _, v := range []string{"v8.16.0", "v12.4.0", "v12.10.0"} {
+		bin := filepath.Join(nvmDir, "versions", "node", v, "bin")
+		assert.NoError(t, os.MkdirAll(bin, 0700))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(bin, "node"), nil, 0700))
+	}
+	oldNVMDir := os.Getenv("NVM_DIR")
+	defer os.Setenv("NVM_DIR", oldNVMDir)
+	assert.NoError(t, os.Setenv("NVM_DIR", nvmDir))
+
+	// The toolchain on the PATH is used if it matches.
+	tc, err := ResolveToolchain("nodejs", ">=10.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.16.0", tc.Version.String())
+	assert.Empty(t, tc.Dir)
+	assert.Nil(t, tc.Env())
+
+	// Otherwise, the highest matching version installed by nvm is used.
+	tc, err = ResolveToolchain("nodejs", ">=12.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "12.10.0", tc.Version.String())
+	assert.Equal(t, filepath.Join(nvmDir, "versions", "node", "v12.10.0", "bin"), tc.Dir)
+	assert.Len(t, tc.Env(), 1)
+
+	tc, err = ResolveToolchain("nodejs", ">=8.0.0 <9.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "8.16.0", tc.Version.String())
+
+	// If nothing matches, the error says what was found and how to fix it.
+	_, err = ResolveToolchain("nodejs", ">=13.0.0")
+	missing, ok := err.(*MissingToolchainError)
+	assert.True(t, ok)
+	assert.Equal(t, "10.16.0", missing.Found)
+	assert.Contains(t, err.Error(), "nvm install >=13.0.0")
+
+	_, err = ResolveToolchain("nodejs", "13")
+	assert.Error(t, err)
+	_, err = ResolveToolchain("cobol", ">=1.0.0")
+	assert.Error(t, err)
+}
@@ -15,7 +15,9 @@
 package resource
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 )
 
 // ObjectDiff holds the results of diffing two object property maps.
@@ -74,6 +76,88 @@ func (diff *ObjectDiff) Keys() []PropertyKey {
 	return ks
 }
 
+// PropertyChangeKind is the kind of change made to a single property.
+type PropertyChangeKind string
+
+const (
+	// PropertyAdded indicates that a property was added.
+	PropertyAdded PropertyChangeKind = "add"
+	// PropertyUpdated indicates that the value of a property was changed.
+	PropertyUpdated PropertyChangeKind = "update"
+	// PropertyDeleted indicates that a property was deleted.
+	PropertyDeleted PropertyChangeKind = "delete"
+)
+
+// PropertyChange is a single change to a property, which may be nested within object-valued properties.
+type PropertyChange struct {
+	Path []PropertyKey      // the keys leading from the top-level object to the changed property.
+	Kind PropertyChangeKind // the kind of change.
+	Old  PropertyValue      // the old value (for updates and deletes).
+	New  PropertyValue      // the new value (for adds and updates).
+}
+
+// PathString renders the change's path, e.g. `tags.env` or `labels["app.kubernetes.io/name"]`.
+func (c PropertyChange) PathString() string {
+	var b strings.Builder
+	for i, k := range c.Path {
+		switch {
+		case !isSimplePathKey(k):
+			fmt.Fprintf(&b, "[%q]", string(k))
+		case i > 0:
+			b.WriteString(".")
+			b.WriteString(string(k))
+		default:
+			b.WriteString(string(k))
+		}
+	}
+	return b.String()
+}
+
+// isSimplePathKey returns true if the given key can be written in a path without quoting.
+func isSimplePathKey(k PropertyKey) bool {
+	if k == "" {
+		return false
+	}
+	for i, c := range k {
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case i > 0 && (c == '-' || c >= '0' && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Changes flattens this diff into a list of individual property changes, in stable order. Updates to object-valued
+// properties are expanded into changes to their keys, recursively, so that e.g. changing one entry in a map of tags is
+// reported as an update to that entry rather than to the whole map.
+func (diff *ObjectDiff) Changes() []PropertyChange {
+	return diff.appendChanges(nil, nil)
+}
+
+func (diff *ObjectDiff) appendChanges(changes []PropertyChange, prefix []PropertyKey) []PropertyChange {
+	for _, k := range diff.Keys() {
+		path := make([]PropertyKey, len(prefix)+1)
+		copy(path, prefix)
+		path[len(prefix)] = k
+
+		if add, isadd := diff.Adds[k]; isadd {
+			changes = append(changes, PropertyChange{Path: path, Kind: PropertyAdded, New: add})
+		} else if del, isdelete := diff.Deletes[k]; isdelete {
+			changes = append(changes, PropertyChange{Path: path, Kind: PropertyDeleted, Old: del})
+		} else if update, isupdate := diff.Updates[k]; isupdate {
+			if update.Object != nil {
+				changes = update.Object.appendChanges(changes, path)
+			} else {
+				changes = append(changes, PropertyChange{
+					Path: path, Kind: PropertyUpdated, Old: update.Old, New: update.New})
+			}
+		}
+	}
+	return changes
+}
+
 // ValueDiff holds the results of diffing two property values.
 type ValueDiff struct {
 	Old    PropertyValue // the old value.
@@ -351,3 +351,45 @@ func TestMismatchedPropertyValueDiff(t *testing.T) {
 	assert.True(t, s2.DeepEquals(s1))
 	assert.True(t, s1.DeepEquals(s2))
 }
+
+func TestObjectDiffChanges(t *testing.T) {
+	t.Parallel()
+
+	olds := PropertyMap{
+		"name": NewStringProperty("a"),
+		"tags": NewObjectProperty(PropertyMap{
+			"env":   NewStringProperty("dev"),
+			"owner": NewStringProperty("me"),
+			"nested": NewObjectProperty(PropertyMap{
+				"app.kubernetes.io/name": NewStringProperty("web"),
+			}),
+		}),
+	}
+	news := PropertyMap{
+		"name": NewStringProperty("b"),
+		"tags": NewObjectProperty(PropertyMap{
+			"env":  NewStringProperty("prod"),
+			"team": NewStringProperty("infra"),
+			"nested": NewObjectProperty(PropertyMap{
+				"app.kubernetes.io/name": NewStringProperty("api"),
+			}),
+		}),
+	}
+
+	changes := olds.Diff(news).Changes()
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, string(c.Kind)+" "+c.PathString())
+	}
+	assert.Equal(t, []string{
+		"update name",
+		"update tags.env",
+		`update tags.nested["app.kubernetes.io/name"]`,
+		"delete tags.owner",
+		"add tags.team",
+	}, paths)
+
+	assert.Equal(t, []PropertyKey{"tags", "env"}, changes[1].Path)
+	assert.Equal(t, "dev", changes[1].Old.StringValue())
+	assert.Equal(t, "prod", changes[1].New.StringValue())
+}
@@ -0,0 +1,223 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PropertyPath identifies a value nested within a property map. Each element is either a string, which names a key
+// within an object, or an int, which indexes into an array. The first element always names a top-level key.
+type PropertyPath []interface{}
+
+// ParsePropertyPath parses a property path such as `tags.env`, `rules[0].port`, or `labels["app.kubernetes.io/name"]`.
+// Keys that contain characters other than letters, digits, underscores, and dashes must be quoted within brackets.
+func ParsePropertyPath(path string) (PropertyPath, error) {
+	var result PropertyPath
+	for i, bare := 0, true; ; {
+		if i < len(path) && path[i] == '[' && (!bare || len(result) == 0) {
+			// A bracketed element is either a quoted key or an array index.
+			i++
+			if i < len(path) && path[i] == '"' {
+				j := i + 1
+				for ; j < len(path) && path[j] != '"'; j++ {
+					if path[j] == '\\' {
+						j++
+					}
+				}
+				if j >= len(path) {
+					return nil, errors.Errorf("unterminated string in property path %q", path)
+				}
+				key, err := strconv.Unquote(path[i : j+1])
+				if err != nil {
+					return nil, errors.Errorf("invalid key in property path %q: %v", path, err)
+				}
+				result, i = append(result, key), j+1
+			} else {
+				j := strings.IndexByte(path[i:], ']')
+				if j == -1 {
+					return nil, errors.Errorf("unterminated index in property path %q", path)
+				}
+				index, err := strconv.Atoi(path[i : i+j])
+				if err != nil || index < 0 {
+					return nil, errors.Errorf("invalid index %q in property path %q", path[i:i+j], path)
+				}
+				result, i = append(result, index), i+j
+			}
+			if i >= len(path) || path[i] != ']' {
+				return nil, errors.Errorf("missing ']' in property path %q", path)
+			}
+			i, bare = i+1, false
+		} else {
+			// Otherwise, this element is a bare key that runs until the next separator.
+			j := i
+			for j < len(path) && path[j] != '.' && path[j] != '[' {
+				j++
+			}
+			if j == i {
+				return nil, errors.Errorf("missing key in property path %q", path)
+			}
+			result, i, bare = append(result, path[i:j]), j, false
+		}
+
+		switch {
+		case i == len(path):
+			if _, ok := result[0].(string); !ok {
+				return nil, errors.Errorf("property path %q must begin with a key", path)
+			}
+			return result, nil
+		case path[i] == '.':
+			i, bare = i+1, true
+		case path[i] != '[':
+			return nil, errors.Errorf("unexpected %q in property path %q", path[i], path)
+		}
+	}
+}
+
+// String returns the path in the form accepted by ParsePropertyPath.
+func (p PropertyPath) String() string {
+	var b strings.Builder
+	for i, elem := range p {
+		switch key := elem.(type) {
+		case string:
+			if !isBarePropertyKey(key) {
+				b.WriteString("[" + strconv.Quote(key) + "]")
+				continue
+			}
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(key)
+		case int:
+			b.WriteString("[" + strconv.Itoa(key) + "]")
+		}
+	}
+	return b.String()
+}
+
+// isBarePropertyKey returns true if the given key may appear in a property path without quotes.
+func isBarePropertyKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the value at this path within the given map, if there is one.
+func (p PropertyPath) Get(m PropertyMap) (PropertyValue, bool) {
+	v := NewObjectProperty(m)
+	for _, elem := range p {
+		switch key := elem.(type) {
+		case string:
+			if !v.IsObject() {
+				return PropertyValue{}, false
+			}
+			child, ok := v.ObjectValue()[PropertyKey(key)]
+			if !ok {
+				return PropertyValue{}, false
+			}
+			v = child
+		case int:
+			if !v.IsArray() || key >= len(v.ArrayValue()) {
+				return PropertyValue{}, false
+			}
+			v = v.ArrayValue()[key]
+		}
+	}
+	return v, true
+}
+
+// Set returns a copy of the given map in which the value at this path has been replaced with v. Only the objects and
+// arrays along the path are copied; the given map is not modified. Set returns false if any object or array along the
+// path does not exist.
+func (p PropertyPath) Set(m PropertyMap, v PropertyValue) (PropertyMap, bool) {
+	return p.update(m, &v)
+}
+
+// Delete returns a copy of the given map in which the value at this path has been removed. Only the objects and arrays
+// along the path are copied; the given map is not modified. Delete returns false if there is no value at the path.
+func (p PropertyPath) Delete(m PropertyMap) (PropertyMap, bool) {
+	if _, ok := p.Get(m); !ok {
+		return m, false
+	}
+	return p.update(m, nil)
+}
+
+func (p PropertyPath) update(m PropertyMap, v *PropertyValue) (PropertyMap, bool) {
+	if len(p) == 0 {
+		return m, false
+	}
+	result, ok := updatePath(NewObjectProperty(m), p, v)
+	if !ok {
+		return m, false
+	}
+	return result.ObjectValue(), true
+}
+
+// updatePath returns a copy of container with the value at path replaced by v, or removed if v is nil.
+func updatePath(container PropertyValue, path PropertyPath, v *PropertyValue) (PropertyValue, bool) {
+	switch key := path[0].(type) {
+	case string:
+		if !container.IsObject() {
+			return container, false
+		}
+		obj := container.ObjectValue().Copy()
+		k := PropertyKey(key)
+		if len(path) == 1 {
+			if v == nil {
+				delete(obj, k)
+			} else {
+				obj[k] = *v
+			}
+			return NewObjectProperty(obj), true
+		}
+		child, ok := obj[k]
+		if !ok {
+			return container, false
+		}
+		if obj[k], ok = updatePath(child, path[1:], v); !ok {
+			return container, false
+		}
+		return NewObjectProperty(obj), true
+	case int:
+		if !container.IsArray() || key >= len(container.ArrayValue()) {
+			return container, false
+		}
+		arr := append([]PropertyValue(nil), container.ArrayValue()...)
+		if len(path) == 1 {
+			if v == nil {
+				arr = append(arr[:key], arr[key+1:]...)
+			} else {
+				arr[key] = *v
+			}
+			return NewArrayProperty(arr), true
+		}
+		var ok bool
+		if arr[key], ok = updatePath(arr[key], path[1:], v); !ok {
+			return container, false
+		}
+		return NewArrayProperty(arr), true
+	default:
+		return container, false
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePropertyPath(t *testing.T) {
+	cases := map[string]PropertyPath{
+		"root":                           {"root"},
+		"root.nested":                    {"root", "nested"},
+		"root[1].nested":                 {"root", 1, "nested"},
+		`root["key with spaces"][0]`:     {"root", "key with spaces", 0},
+		`["app.kubernetes.io/name"].foo`: {"app.kubernetes.io/name", "foo"},
+	}
+	for path, expected := range cases {
+		actual, err := ParsePropertyPath(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, expected, actual, path)
+	}
+
+	for _, path := range []string{"", "root.", ".root", "root..nested", "root.[0]", "[0]", "root[x]", "root[0]x",
+		`root["unterminated]`, "root[1"} {
+		_, err := ParsePropertyPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestPropertyPathString(t *testing.T) {
+	cases := map[string]PropertyPath{
+		"root":                           {"root"},
+		"root.nested":                    {"root", "nested"},
+		"root[1].nested":                 {"root", 1, "nested"},
+		`root["key with spaces"][0]`:     {"root", "key with spaces", 0},
+		`["app.kubernetes.io/name"].foo`: {"app.kubernetes.io/name", "foo"},
+	}
+	for expected, path := range cases {
+		assert.Equal(t, expected, path.String())
+		parsed, err := ParsePropertyPath(path.String())
+		assert.NoError(t, err, expected)
+		assert.Equal(t, path, parsed)
+	}
+}
+
+func TestPropertyPathSetAndDelete(t *testing.T) {
+	m := PropertyMap{
+		"tags": NewObjectProperty(PropertyMap{"env": NewStringProperty("dev")}),
+		"list": NewArrayProperty([]PropertyValue{NewNumberProperty(1), NewNumberProperty(2)}),
+	}
+
+	path, err := ParsePropertyPath("tags.env")
+	assert.NoError(t, err)
+	v, ok := path.Get(m)
+	assert.True(t, ok)
+	assert.Equal(t, NewStringProperty("dev"), v)
+
+	updated, ok := path.Set(m, NewStringProperty("prod"))
+	assert.True(t, ok)
+	assert.Equal(t, NewStringProperty("prod"), updated["tags"].ObjectValue()["env"])
+	assert.Equal(t, NewStringProperty("dev"), m["tags"].ObjectValue()["env"])
+
+	deleted, ok := path.Delete(m)
+	assert.True(t, ok)
+	assert.Empty(t, deleted["tags"].ObjectValue())
+	assert.Len(t, m["tags"].ObjectValue(), 1)
+
+	path, err = ParsePropertyPath("list[0]")
+	assert.NoError(t, err)
+	deleted, ok = path.Delete(m)
+	assert.True(t, ok)
+	assert.Equal(t, []PropertyValue{NewNumberProperty(2)}, deleted["list"].ArrayValue())
+
+	path, err = ParsePropertyPath("missing.key")
+	assert.NoError(t, err)
+	_, ok = path.Set(m, NewStringProperty("x"))
+	assert.False(t, ok)
+}
@@ -89,3 +89,16 @@ func (host *HostClient) LogStatus(
 ) error {
 	return host.log(context, sev, urn, msg, true)
 }
+
+// ReportProgress reports the progress of a long-running operation on the resource with the given URN. Percent is in
+// the range [0, 100], or is negative if the provider cannot estimate its progress.
+func (host *HostClient) ReportProgress(
+	context context.Context, urn resource.URN, percent int, msg string,
+) error {
+	_, err := host.client.ReportProgress(context, &lumirpc.ReportProgressRequest{
+		Urn:     string(urn),
+		Percent: int32(percent),
+		Message: msg,
+	})
+	return err
+}
@@ -15,6 +15,10 @@
 package resource
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/pulumi/pulumi/pkg/tokens"
 )
 
@@ -35,6 +39,10 @@ type Goal struct {
 	IgnoreChanges           []string              // a list of property names to ignore during changes.
 	AdditionalSecretOutputs []PropertyKey         // outputs that should always be treated as secrets.
 	Aliases                 []URN                 // additional URNs that should be aliased to this resource.
+	ValidateReplacement     bool                  // true if a replacement must be validated before it is put into use.
+	CustomTimeouts          CustomTimeouts        // the timeouts for the provider's operations on this resource.
+	ReplaceOnChanges        []string              // property paths whose changes force a replacement.
+	WaitFor                 []string              // conditions that must hold before the resource is ready for use.
 }
 
 // NewGoal allocates a new resource goal state.
@@ -60,3 +68,49 @@ func NewGoal(t tokens.Type, name tokens.QName, custom bool, props PropertyMap,
 		Aliases:                 aliases,
 	}
 }
+
+// CustomTimeouts bounds the time a resource provider may take to create, update, or delete a resource, and sets how
+// often the provider polls for the completion of its long-running operations. A zero timeout is not enforced, and a
+// zero poll interval leaves the interval up to the provider.
+type CustomTimeouts struct {
+	Create       time.Duration // the timeout for creating the resource.
+	Update       time.Duration // the timeout for updating the resource.
+	Delete       time.Duration // the timeout for deleting the resource.
+	PollInterval time.Duration // how often the provider polls for the completion of operations on the resource.
+}
+
+// WithDefaults returns these timeouts, with any that are not set taken from the given defaults.
+func (t CustomTimeouts) WithDefaults(defaults CustomTimeouts) CustomTimeouts {
+	if t.Create == 0 {
+		t.Create = defaults.Create
+	}
+	if t.Update == 0 {
+		t.Update = defaults.Update
+	}
+	if t.Delete == 0 {
+		t.Delete = defaults.Delete
+	}
+	if t.PollInterval == 0 {
+		t.PollInterval = defaults.PollInterval
+	}
+	return t
+}
+
+// String formats the settings that are set, e.g. "create=10m0s, poll=30s", or returns "" if none are.
+func (t CustomTimeouts) String() string {
+	var parts []string
+	for _, s := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"create", t.Create},
+		{"update", t.Update},
+		{"delete", t.Delete},
+		{"poll", t.PollInterval},
+	} {
+		if s.value != 0 {
+			parts = append(parts, fmt.Sprintf("%s=%v", s.name, s.value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -41,6 +41,19 @@ type State struct {
 	PendingReplacement      bool                  // true if this resource was deleted and is awaiting replacement.
 	AdditionalSecretOutputs []PropertyKey         // an additional set of outputs that should be treated as secrets.
 	Aliases                 []URN                 // TODO
+	Annotations             map[string]string     // annotations attached by external tools, keyed by "namespace:name".
+	CustomTimeouts          CustomTimeouts        // the timeouts for the provider's operations on this resource.
+	Artifacts               []Artifact            // the artifacts produced by the provider's operations on this resource.
+}
+
+// Artifact refers to a small file produced by a provider when creating or updating a resource, e.g. a generated
+// kubeconfig or certificate. The snapshot records only the reference; the contents are kept in an artifact store,
+// addressed by their digest.
+type Artifact struct {
+	Name        string // the artifact's name, unique among the resource's artifacts.
+	ContentType string // the artifact's MIME type, if known.
+	Size        int64  // the size of the artifact's contents, in bytes.
+	Digest      string // the hex-encoded SHA-256 digest of the artifact's contents.
 }
 
 // NewState creates a new resource value from existing resource state information.
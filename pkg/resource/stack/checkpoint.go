@@ -17,7 +17,13 @@
 package stack
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"os"
 
 	"github.com/pkg/errors"
 
@@ -30,6 +36,91 @@ import (
 	"github.com/pulumi/pulumi/pkg/util/contract"
 )
 
+// CheckpointSigningKeyEnvVar is the environment variable that, when set, holds a key used to sign checkpoints as they
+// are written and to verify those signatures as they are read.
+const CheckpointSigningKeyEnvVar = "PULUMI_CHECKPOINT_SIGNING_KEY"
+
+// CheckpointModifiedError is returned when a checkpoint's contents no longer match the hash or signature that was
+// recorded when Pulumi wrote it, which means the state was edited by some other means.
+type CheckpointModifiedError struct {
+	Expected string // the hash (or signature) recorded in the checkpoint, if any.
+	Actual   string // the hash (or signature) computed from the checkpoint's current contents.
+}
+
+func (e *CheckpointModifiedError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("state was modified outside Pulumi: it has no hash or signature, but %s is set",
+			CheckpointSigningKeyEnvVar)
+	}
+	return fmt.Sprintf("state was modified outside Pulumi: expected hash %s, got %s", e.Expected, e.Actual)
+}
+
+// checkpointHash computes the hex-encoded SHA-256 hash of a checkpoint document. The document is compacted first so
+// that the hash does not depend on how the enclosing file was indented.
+func checkpointHash(checkpoint json.RawMessage) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, checkpoint); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkpointSignature signs a checkpoint hash with the given key using HMAC-SHA256.
+func checkpointSignature(hash string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, err := mac.Write([]byte(hash))
+	contract.IgnoreError(err)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCheckpointIntegrity checks that the checkpoint in the given bytes matches the hash, and signature if a signing
+// key is configured, that were recorded when it was written. Checkpoints written before hashes were recorded are
+// accepted as-is unless a signing key is configured. If the contents do not match, a *CheckpointModifiedError is
+// returned.
+func VerifyCheckpointIntegrity(bytes []byte) error {
+	var versionedCheckpoint apitype.VersionedCheckpoint
+	if err := json.Unmarshal(bytes, &versionedCheckpoint); err != nil {
+		return err
+	}
+	if versionedCheckpoint.Hash == "" {
+		// There is no hash to compare against, so there is no need to compute one.
+		return verifyCheckpointHash("", "", versionedCheckpoint.Signature)
+	}
+
+	actual, err := checkpointHash(versionedCheckpoint.Checkpoint)
+	if err != nil {
+		return errors.Wrap(err, "hashing checkpoint")
+	}
+	return verifyCheckpointHash(actual, versionedCheckpoint.Hash, versionedCheckpoint.Signature)
+}
+
+// verifyCheckpointHash compares a checkpoint's computed hash against the hash and signature recorded in it.
+func verifyCheckpointHash(actual, expected, expectedSignature string) error {
+	key := os.Getenv(CheckpointSigningKeyEnvVar)
+	if expected == "" {
+		// Checkpoints written before hashes were recorded carry none. They are only accepted if no signing key is
+		// configured, as otherwise stripping the hash and signature would be enough to bypass verification.
+		if key != "" {
+			return &CheckpointModifiedError{Actual: actual}
+		}
+		return nil
+	}
+
+	if actual != expected {
+		return &CheckpointModifiedError{Expected: expected, Actual: actual}
+	}
+
+	if key != "" {
+		signature := checkpointSignature(actual, []byte(key))
+		if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+			return &CheckpointModifiedError{Expected: expectedSignature, Actual: signature}
+		}
+	}
+
+	return nil
+}
+
 func UnmarshalVersionedCheckpointToLatestCheckpoint(bytes []byte) (*apitype.CheckpointV3, error) {
 	var versionedCheckpoint apitype.VersionedCheckpoint
 	if err := json.Unmarshal(bytes, &versionedCheckpoint); err != nil {
@@ -100,9 +191,20 @@ func SerializeCheckpoint(stack tokens.QName, snap *deploy.Snapshot,
 		return nil, errors.Wrap(err, "marshalling checkpoint")
 	}
 
+	hash, err := checkpointHash(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "hashing checkpoint")
+	}
+	var signature string
+	if key := os.Getenv(CheckpointSigningKeyEnvVar); key != "" {
+		signature = checkpointSignature(hash, []byte(key))
+	}
+
 	return &apitype.VersionedCheckpoint{
 		Version:    apitype.DeploymentSchemaVersionCurrent,
 		Checkpoint: json.RawMessage(b),
+		Hash:       hash,
+		Signature:  signature,
 	}, nil
 }
 
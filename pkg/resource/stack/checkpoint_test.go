@@ -15,7 +15,10 @@
 package stack
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,3 +43,57 @@ func TestLoadV1Checkpoint(t *testing.T) {
 	assert.NotNil(t, chk.Latest)
 	assert.Len(t, chk.Latest.Resources, 30)
 }
+
+func TestCheckpointIntegrity(t *testing.T) {
+	chk, err := SerializeCheckpoint("stack", nil, nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, chk.Hash)
+	assert.Empty(t, chk.Signature)
+
+	// Indentation applied when writing the file must not affect the hash.
+	bytes, err := json.MarshalIndent(chk, "", "    ")
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyCheckpointIntegrity(bytes))
+
+	// Editing the checkpoint must be detected.
+	modified := []byte(strings.Replace(string(bytes), `"stack": "stack"`, `"stack": "other"`, 1))
+	err = VerifyCheckpointIntegrity(modified)
+	assert.IsType(t, &CheckpointModifiedError{}, err)
+	assert.Equal(t, chk.Hash, err.(*CheckpointModifiedError).Expected)
+
+	// Checkpoints written without a hash are accepted.
+	chk.Hash = ""
+	bytes, err = json.Marshal(chk)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyCheckpointIntegrity(bytes))
+}
+
+func TestCheckpointSignature(t *testing.T) {
+	os.Setenv(CheckpointSigningKeyEnvVar, "secret")
+	defer os.Unsetenv(CheckpointSigningKeyEnvVar)
+
+	chk, err := SerializeCheckpoint("stack", nil, nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, chk.Signature)
+	bytes, err := json.Marshal(chk)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyCheckpointIntegrity(bytes))
+
+	// A checkpoint whose hash was recomputed without the key must be rejected.
+	chk.Signature = ""
+	bytes, err = json.Marshal(chk)
+	assert.NoError(t, err)
+	assert.IsType(t, &CheckpointModifiedError{}, VerifyCheckpointIntegrity(bytes))
+
+	// Stripping the hash as well must not bypass verification while a key is configured.
+	chk.Hash = ""
+	bytes, err = json.Marshal(chk)
+	assert.NoError(t, err)
+	assert.IsType(t, &CheckpointModifiedError{}, VerifyCheckpointIntegrity(bytes))
+	_, err = DecodeCheckpoint(strings.NewReader(string(bytes)), true)
+	assert.IsType(t, &CheckpointModifiedError{}, err)
+
+	// Without a key, the same checkpoint is accepted as one written before hashes were recorded.
+	os.Unsetenv(CheckpointSigningKeyEnvVar)
+	assert.NoError(t, VerifyCheckpointIntegrity(bytes))
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/secrets/service"
 
@@ -58,38 +59,16 @@ func SerializeDeployment(snap *deploy.Snapshot, sm secrets.Manager) (*apitype.De
 	contract.Require(snap != nil, "snap")
 
 	// Capture the version information into a manifest.
-	manifest := apitype.ManifestV1{
-		Time:    snap.Manifest.Time,
-		Magic:   snap.Manifest.Magic,
-		Version: snap.Manifest.Version,
-	}
-	for _, plug := range snap.Manifest.Plugins {
-		var version string
-		if plug.Version != nil {
-			version = plug.Version.String()
-		}
-		manifest.Plugins = append(manifest.Plugins, apitype.PluginInfoV1{
-			Name:    plug.Name,
-			Path:    plug.Path,
-			Type:    plug.Kind,
-			Version: version,
-		})
-	}
+	manifest := serializeManifest(snap.Manifest)
 
 	// If a specific secrets manager was not provided, use the one in the snapshot, if present.
 	if sm == nil {
 		sm = snap.SecretsManager
 	}
 
-	var enc config.Encrypter
-	if sm != nil {
-		e, err := sm.Encrypter()
-		if err != nil {
-			return nil, errors.Wrap(err, "getting encrypter for deployment")
-		}
-		enc = e
-	} else {
-		enc = config.NewPanicCrypter()
+	enc, err := deploymentEncrypter(sm)
+	if err != nil {
+		return nil, err
 	}
 
 	// Serialize all vertices and only include a vertex section if non-empty.
@@ -111,18 +90,9 @@ func SerializeDeployment(snap *deploy.Snapshot, sm secrets.Manager) (*apitype.De
 		operations = append(operations, sop)
 	}
 
-	var secretsProvider *apitype.SecretsProvidersV1
-	if sm != nil {
-		secretsProvider = &apitype.SecretsProvidersV1{
-			Type: sm.Type(),
-		}
-		if state := sm.State(); state != nil {
-			rm, err := json.Marshal(state)
-			if err != nil {
-				return nil, err
-			}
-			secretsProvider.State = rm
-		}
+	secretsProvider, err := serializeSecretsProvider(sm)
+	if err != nil {
+		return nil, err
 	}
 
 	return &apitype.DeploymentV3{
@@ -133,10 +103,123 @@ func SerializeDeployment(snap *deploy.Snapshot, sm secrets.Manager) (*apitype.De
 	}, nil
 }
 
+// serializeManifest turns a deployment manifest into a structure suitable for serialization.
+func serializeManifest(m deploy.Manifest) apitype.ManifestV1 {
+	manifest := apitype.ManifestV1{
+		Time:    m.Time,
+		Magic:   m.Magic,
+		Version: m.Version,
+	}
+	for _, plug := range m.Plugins {
+		var version string
+		if plug.Version != nil {
+			version = plug.Version.String()
+		}
+		manifest.Plugins = append(manifest.Plugins, apitype.PluginInfoV1{
+			Name:    plug.Name,
+			Path:    plug.Path,
+			Type:    plug.Kind,
+			Version: version,
+		})
+	}
+	if len(m.Config) > 0 {
+		manifest.Config = make(map[string]apitype.ConfigValue, len(m.Config))
+		for k, v := range m.Config {
+			// Secret values are recorded as they are held, in their encrypted form.
+			value, err := v.Value(config.NopDecrypter)
+			contract.AssertNoError(err)
+			manifest.Config[k.String()] = apitype.ConfigValue{String: value, Secret: v.Secure()}
+		}
+	}
+	for _, k := range m.OmittedConfig {
+		manifest.OmittedConfig = append(manifest.OmittedConfig, k.String())
+	}
+	return manifest
+}
+
+// serializeSecretsProvider records the type and state of a secrets manager so that it can be recreated when the
+// deployment is read back. Returns nil if sm is nil.
+func serializeSecretsProvider(sm secrets.Manager) (*apitype.SecretsProvidersV1, error) {
+	if sm == nil {
+		return nil, nil
+	}
+
+	secretsProvider := &apitype.SecretsProvidersV1{
+		Type: sm.Type(),
+	}
+	if state := sm.State(); state != nil {
+		rm, err := json.Marshal(state)
+		if err != nil {
+			return nil, err
+		}
+		secretsProvider.State = rm
+	}
+	return secretsProvider, nil
+}
+
+// deploymentEncrypter returns the encrypter used to serialize secrets with the given manager. If there is no manager,
+// the returned encrypter panics if any secret is encountered.
+func deploymentEncrypter(sm secrets.Manager) (config.Encrypter, error) {
+	if sm == nil {
+		return config.NewPanicCrypter(), nil
+	}
+	enc, err := sm.Encrypter()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting encrypter for deployment")
+	}
+	return enc, nil
+}
+
+// deploymentDecrypter returns the decrypter used to deserialize secrets with the given manager. If there is no
+// manager, the returned decrypter panics if any secret is encountered.
+func deploymentDecrypter(sm secrets.Manager) (config.Decrypter, error) {
+	if sm == nil {
+		return config.NewPanicCrypter(), nil
+	}
+	return sm.Decrypter()
+}
+
 // DeserializeUntypedDeployment deserializes an untyped deployment and produces a `deploy.Snapshot`
 // from it. DeserializeDeployment will return an error if the untyped deployment's version is
 // not within the range `DeploymentSchemaVersionCurrent` and `DeploymentSchemaVersionOldestSupported`.
 func DeserializeUntypedDeployment(deployment *apitype.UntypedDeployment) (*deploy.Snapshot, error) {
+	v3deployment, err := untypedDeploymentToV3(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return DeserializeDeploymentV3(*v3deployment)
+}
+
+// DeserializeUntypedResources deserializes the resources of an untyped deployment without decrypting its secrets, so
+// that the resources of a stack can be inspected without access to its secrets provider. The value of each secret is
+// replaced by the string "[secret]".
+func DeserializeUntypedResources(deployment *apitype.UntypedDeployment) ([]*resource.State, error) {
+	v3deployment, err := untypedDeploymentToV3(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []*resource.State
+	for _, res := range v3deployment.Resources {
+		desres, err := DeserializeResource(res, blindingJSONDecrypter{})
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, desres)
+	}
+	return resources, nil
+}
+
+// blindingJSONDecrypter is a config.Decrypter that decrypts every secret property value to the string "[secret]". The
+// plaintext of a secret property value is JSON, so unlike config.NewBlindingDecrypter its result is quoted.
+type blindingJSONDecrypter struct{}
+
+func (blindingJSONDecrypter) DecryptValue(ciphertext string) (string, error) {
+	return `"[secret]"`, nil
+}
+
+// untypedDeploymentToV3 decodes an untyped deployment, migrating it to the current schema version if necessary.
+func untypedDeploymentToV3(deployment *apitype.UntypedDeployment) (*apitype.DeploymentV3, error) {
 	contract.Require(deployment != nil, "deployment")
 	switch {
 	case deployment.Version > apitype.DeploymentSchemaVersionCurrent:
@@ -168,64 +251,25 @@ func DeserializeUntypedDeployment(deployment *apitype.UntypedDeployment) (*deplo
 		contract.Failf("unrecognized version: %d", deployment.Version)
 	}
 
-	return DeserializeDeploymentV3(v3deployment)
+	return &v3deployment, nil
 }
 
 // DeserializeDeploymentV3 deserializes a typed DeploymentV3 into a `deploy.Snapshot`.
 func DeserializeDeploymentV3(deployment apitype.DeploymentV3) (*deploy.Snapshot, error) {
 	// Unpack the versions.
-	manifest := deploy.Manifest{
-		Time:    deployment.Manifest.Time,
-		Magic:   deployment.Manifest.Magic,
-		Version: deployment.Manifest.Version,
-	}
-	for _, plug := range deployment.Manifest.Plugins {
-		var version *semver.Version
-		if v := plug.Version; v != "" {
-			sv, err := semver.ParseTolerant(v)
-			if err != nil {
-				return nil, err
-			}
-			version = &sv
-		}
-		manifest.Plugins = append(manifest.Plugins, workspace.PluginInfo{
-			Name:    plug.Name,
-			Kind:    plug.Type,
-			Version: version,
-		})
+	manifest, err := deserializeManifest(deployment.Manifest)
+	if err != nil {
+		return nil, err
 	}
 
-	var secretsManager secrets.Manager
-	if deployment.SecretsProviders != nil && deployment.SecretsProviders.Type != "" {
-		var provider secrets.ManagerProvider
-
-		switch deployment.SecretsProviders.Type {
-		case b64.Type:
-			provider = b64.NewProvider()
-		case passphrase.Type:
-			provider = passphrase.NewProvider()
-		case service.Type:
-			provider = service.NewProvider()
-		default:
-			return nil, errors.Errorf("unknown secrets provider type %s", deployment.SecretsProviders.Type)
-		}
-
-		sm, err := provider.FromState(deployment.SecretsProviders.State)
-		if err != nil {
-			return nil, errors.Wrap(err, "creating secrets manager from existing state")
-		}
-		secretsManager = sm
+	secretsManager, err := deserializeSecretsManager(deployment.SecretsProviders)
+	if err != nil {
+		return nil, err
 	}
 
-	var dec config.Decrypter
-	if secretsManager == nil {
-		dec = config.NewPanicCrypter()
-	} else {
-		d, err := secretsManager.Decrypter()
-		if err != nil {
-			return nil, err
-		}
-		dec = d
+	dec, err := deploymentDecrypter(secretsManager)
+	if err != nil {
+		return nil, err
 	}
 
 	// For every serialized resource vertex, create a ResourceDeployment out of it.
@@ -250,6 +294,78 @@ func DeserializeDeploymentV3(deployment apitype.DeploymentV3) (*deploy.Snapshot,
 	return deploy.NewSnapshot(manifest, secretsManager, resources, ops), nil
 }
 
+// deserializeManifest turns a serialized manifest back into a deployment manifest.
+func deserializeManifest(m apitype.ManifestV1) (deploy.Manifest, error) {
+	manifest := deploy.Manifest{
+		Time:    m.Time,
+		Magic:   m.Magic,
+		Version: m.Version,
+	}
+	for _, plug := range m.Plugins {
+		var version *semver.Version
+		if v := plug.Version; v != "" {
+			sv, err := semver.ParseTolerant(v)
+			if err != nil {
+				return deploy.Manifest{}, err
+			}
+			version = &sv
+		}
+		manifest.Plugins = append(manifest.Plugins, workspace.PluginInfo{
+			Name:    plug.Name,
+			Kind:    plug.Type,
+			Version: version,
+		})
+	}
+	if len(m.Config) > 0 {
+		manifest.Config = make(config.Map, len(m.Config))
+		for rawKey, v := range m.Config {
+			k, err := config.ParseKey(rawKey)
+			if err != nil {
+				return deploy.Manifest{}, err
+			}
+			if v.Secret {
+				manifest.Config[k] = config.NewSecureValue(v.String)
+			} else {
+				manifest.Config[k] = config.NewValue(v.String)
+			}
+		}
+	}
+	for _, rawKey := range m.OmittedConfig {
+		k, err := config.ParseKey(rawKey)
+		if err != nil {
+			return deploy.Manifest{}, err
+		}
+		manifest.OmittedConfig = append(manifest.OmittedConfig, k)
+	}
+	return manifest, nil
+}
+
+// deserializeSecretsManager recreates the secrets manager recorded in a deployment. Returns nil if no secrets
+// provider was recorded.
+func deserializeSecretsManager(providers *apitype.SecretsProvidersV1) (secrets.Manager, error) {
+	if providers == nil || providers.Type == "" {
+		return nil, nil
+	}
+
+	var provider secrets.ManagerProvider
+	switch providers.Type {
+	case b64.Type:
+		provider = b64.NewProvider()
+	case passphrase.Type:
+		provider = passphrase.NewProvider()
+	case service.Type:
+		provider = service.NewProvider()
+	default:
+		return nil, errors.Errorf("unknown secrets provider type %s", providers.Type)
+	}
+
+	sm, err := provider.FromState(providers.State)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating secrets manager from existing state")
+	}
+	return sm, nil
+}
+
 // SerializeResource turns a resource into a structure suitable for serialization.
 func SerializeResource(res *resource.State, enc config.Encrypter) (apitype.ResourceV3, error) {
 	contract.Assert(res != nil)
@@ -291,9 +407,75 @@ func SerializeResource(res *resource.State, enc config.Encrypter) (apitype.Resou
 		PendingReplacement:      res.PendingReplacement,
 		AdditionalSecretOutputs: res.AdditionalSecretOutputs,
 		Aliases:                 res.Aliases,
+		Annotations:             res.Annotations,
+		CustomTimeouts:          serializeCustomTimeouts(res.CustomTimeouts),
+		Artifacts:               serializeArtifacts(res.Artifacts),
 	}, nil
 }
 
+// serializeArtifacts serializes a resource's artifact references, returning nil if there are none.
+func serializeArtifacts(artifacts []resource.Artifact) []apitype.ArtifactV1 {
+	var result []apitype.ArtifactV1
+	for _, a := range artifacts {
+		result = append(result, apitype.ArtifactV1{
+			Name:        a.Name,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			Digest:      a.Digest,
+		})
+	}
+	return result
+}
+
+// serializeCustomTimeouts serializes a resource's timeouts and poll interval, returning nil if none are set.
+func serializeCustomTimeouts(timeouts resource.CustomTimeouts) *apitype.CustomTimeoutsV1 {
+	if timeouts == (resource.CustomTimeouts{}) {
+		return nil
+	}
+
+	format := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+	return &apitype.CustomTimeoutsV1{
+		Create:       format(timeouts.Create),
+		Update:       format(timeouts.Update),
+		Delete:       format(timeouts.Delete),
+		PollInterval: format(timeouts.PollInterval),
+	}
+}
+
+// deserializeCustomTimeouts deserializes a resource's timeouts and poll interval.
+func deserializeCustomTimeouts(timeouts *apitype.CustomTimeoutsV1) (resource.CustomTimeouts, error) {
+	var result resource.CustomTimeouts
+	if timeouts == nil {
+		return result, nil
+	}
+
+	parse := func(s string) (time.Duration, error) {
+		if s == "" {
+			return 0, nil
+		}
+		return time.ParseDuration(s)
+	}
+	var err error
+	if result.Create, err = parse(timeouts.Create); err != nil {
+		return result, errors.Wrap(err, "parsing create timeout")
+	}
+	if result.Update, err = parse(timeouts.Update); err != nil {
+		return result, errors.Wrap(err, "parsing update timeout")
+	}
+	if result.Delete, err = parse(timeouts.Delete); err != nil {
+		return result, errors.Wrap(err, "parsing delete timeout")
+	}
+	if result.PollInterval, err = parse(timeouts.PollInterval); err != nil {
+		return result, errors.Wrap(err, "parsing poll interval")
+	}
+	return result, nil
+}
+
 func SerializeOperation(op resource.Operation, enc config.Encrypter) (apitype.OperationV2, error) {
 	res, err := SerializeResource(op.Resource, enc)
 	if err != nil {
@@ -393,10 +575,23 @@ func DeserializeResource(res apitype.ResourceV3, dec config.Decrypter) (*resourc
 		return nil, err
 	}
 
-	return resource.NewState(
+	state := resource.NewState(
 		res.Type, res.URN, res.Custom, res.Delete, res.ID,
 		inputs, outputs, res.Parent, res.Protect, res.External, res.Dependencies, res.InitErrors, res.Provider,
-		res.PropertyDependencies, res.PendingReplacement, res.AdditionalSecretOutputs, res.Aliases), nil
+		res.PropertyDependencies, res.PendingReplacement, res.AdditionalSecretOutputs, res.Aliases)
+	state.Annotations = res.Annotations
+	for _, a := range res.Artifacts {
+		state.Artifacts = append(state.Artifacts, resource.Artifact{
+			Name:        a.Name,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			Digest:      a.Digest,
+		})
+	}
+	if state.CustomTimeouts, err = deserializeCustomTimeouts(res.CustomTimeouts); err != nil {
+		return nil, errors.Wrapf(err, "deserializing resource %s", res.URN)
+	}
+	return state, nil
 }
 
 func DeserializeOperation(op apitype.OperationV2, dec config.Decrypter) (resource.Operation, error) {
@@ -16,12 +16,14 @@ package stack
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/pulumi/pulumi/pkg/apitype"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/tokens"
 )
 
@@ -190,3 +192,55 @@ func TestUnknownSig(t *testing.T) {
 	_, err := DeserializePropertyValue(rawProp, config.NewPanicCrypter())
 	assert.Error(t, err)
 }
+
+func TestCustomTimeoutsSerialization(t *testing.T) {
+	res := resource.NewState("pkgA:m:typA", "urn:pulumi:test::test::pkgA:m:typA::resA", true, false, "id",
+		resource.PropertyMap{}, resource.PropertyMap{}, "", false, false, nil, nil, "", nil, false, nil, nil)
+
+	// Resources without timeouts serialize without them.
+	dep, err := SerializeResource(res, config.NewPanicCrypter())
+	assert.NoError(t, err)
+	assert.Nil(t, dep.CustomTimeouts)
+
+	res.CustomTimeouts = resource.CustomTimeouts{
+		Create:       10 * time.Minute,
+		Delete:       90 * time.Second,
+		PollInterval: 15 * time.Second,
+	}
+	dep, err = SerializeResource(res, config.NewPanicCrypter())
+	assert.NoError(t, err)
+	assert.Equal(t, &apitype.CustomTimeoutsV1{Create: "10m0s", Delete: "1m30s", PollInterval: "15s"},
+		dep.CustomTimeouts)
+
+	state, err := DeserializeResource(dep, config.NewPanicCrypter())
+	assert.NoError(t, err)
+	assert.Equal(t, res.CustomTimeouts, state.CustomTimeouts)
+
+	dep.CustomTimeouts.Update = "soon"
+	_, err = DeserializeResource(dep, config.NewPanicCrypter())
+	assert.Error(t, err)
+}
+
+func TestManifestConfigSerialization(t *testing.T) {
+	m := deploy.Manifest{Version: "1.0.0", Config: config.Map{
+		config.MustMakeKey("aws", "region"):    config.NewValue("us-west-2"),
+		config.MustMakeKey("proj", "password"): config.NewSecureValue("c2VjcmV0"),
+	}, OmittedConfig: []config.Key{config.MustMakeKey("proj", "token")}}
+
+	// Secret values are recorded in their encrypted form.
+	serialized := serializeManifest(m)
+	assert.Equal(t, map[string]apitype.ConfigValue{
+		"aws:region":    {String: "us-west-2"},
+		"proj:password": {String: "c2VjcmV0", Secret: true},
+	}, serialized.Config)
+	assert.Equal(t, []string{"proj:token"}, serialized.OmittedConfig)
+
+	deserialized, err := deserializeManifest(serialized)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Config, deserialized.Config)
+	assert.Equal(t, m.OmittedConfig, deserialized.OmittedConfig)
+
+	serialized.Config["not-a-key"] = apitype.ConfigValue{String: "x"}
+	_, err = deserializeManifest(serialized)
+	assert.Error(t, err)
+}
@@ -0,0 +1,308 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/encoding"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/secrets"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// CheckpointEncoding is a format in which checkpoints are stored.
+type CheckpointEncoding interface {
+	// Name returns the name by which the encoding is selected, e.g. "json".
+	Name() string
+	// Ext returns the file extension of checkpoints in this encoding, e.g. ".json".
+	Ext() string
+	// Encode writes the checkpoint for the given stack and snapshot to w.
+	Encode(w io.Writer, stack tokens.QName, snap *deploy.Snapshot, sm secrets.Manager) error
+	// Decode reads a checkpoint from r and returns its associated snapshot, or nil if there have been no deployments
+	// performed on it. If verify is true, the checkpoint's hash and signature are checked as in
+	// VerifyCheckpointIntegrity. JSON checkpoints written by older versions of Pulumi cannot be decoded from a
+	// stream; for these, ErrCheckpointNotStreamable is returned and the caller should read the checkpoint in full with
+	// UnmarshalVersionedCheckpointToLatestCheckpoint.
+	Decode(r io.Reader, verify bool) (*deploy.Snapshot, error)
+}
+
+var (
+	// JSONCheckpoints stores checkpoints as JSON. This is the default encoding, and the one in which checkpoints are
+	// exported and imported.
+	JSONCheckpoints CheckpointEncoding = jsonCheckpointEncoding{}
+	// MsgPackCheckpoints stores checkpoints as MessagePack, which is smaller and faster to read and write than JSON.
+	MsgPackCheckpoints CheckpointEncoding = &binaryCheckpointEncoding{
+		name:              "msgpack",
+		ext:               ".msgpack",
+		m:                 encoding.MsgPack,
+		marshalEnvelope:   marshalMsgPackEnvelope,
+		unmarshalEnvelope: unmarshalMsgPackEnvelope,
+	}
+	// ProtobufCheckpoints stores checkpoints as protocol buffers, as a google.protobuf.Value message.
+	ProtobufCheckpoints CheckpointEncoding = &binaryCheckpointEncoding{
+		name:              "protobuf",
+		ext:               ".pb",
+		m:                 encoding.Protobuf,
+		marshalEnvelope:   marshalProtobufEnvelope,
+		unmarshalEnvelope: unmarshalProtobufEnvelope,
+	}
+)
+
+// CheckpointEncodings lists the encodings in which checkpoints may be stored, starting with the default.
+var CheckpointEncodings = []CheckpointEncoding{JSONCheckpoints, MsgPackCheckpoints, ProtobufCheckpoints}
+
+// ParseCheckpointEncoding returns the checkpoint encoding with the given name. The empty name selects the default.
+func ParseCheckpointEncoding(name string) (CheckpointEncoding, error) {
+	if name == "" {
+		return JSONCheckpoints, nil
+	}
+	for _, enc := range CheckpointEncodings {
+		if enc.Name() == name {
+			return enc, nil
+		}
+	}
+	return nil, errors.Errorf("unknown checkpoint format %q; the supported formats are json, msgpack, and protobuf",
+		name)
+}
+
+// CheckpointEncodingForExt returns the checkpoint encoding whose files have the given extension, if any.
+func CheckpointEncodingForExt(ext string) (CheckpointEncoding, bool) {
+	for _, enc := range CheckpointEncodings {
+		if enc.Ext() == ext {
+			return enc, true
+		}
+	}
+	return nil, false
+}
+
+type jsonCheckpointEncoding struct{}
+
+func (jsonCheckpointEncoding) Name() string { return "json" }
+func (jsonCheckpointEncoding) Ext() string  { return ".json" }
+
+func (jsonCheckpointEncoding) Encode(w io.Writer, stack tokens.QName, snap *deploy.Snapshot,
+	sm secrets.Manager) error {
+	return EncodeCheckpoint(w, stack, snap, sm)
+}
+
+func (jsonCheckpointEncoding) Decode(r io.Reader, verify bool) (*deploy.Snapshot, error) {
+	return DecodeCheckpoint(r, verify)
+}
+
+// binaryCheckpoint is the envelope in which checkpoints are stored in binary encodings. It plays the part of
+// apitype.VersionedCheckpoint, except that the checkpoint is held in the envelope's own encoding, and its hash is that
+// of those bytes. Binary checkpoints are only ever written in the current schema version.
+type binaryCheckpoint struct {
+	Version    int    `json:"version"`
+	Checkpoint []byte `json:"checkpoint"`
+	Hash       string `json:"hash,omitempty"`
+	Signature  string `json:"signature,omitempty"`
+}
+
+// binaryCheckpointEncoding stores checkpoints in a binary format, marshaling apitype.CheckpointV3 directly rather than
+// by way of JSON.
+type binaryCheckpointEncoding struct {
+	name              string
+	ext               string
+	m                 encoding.Marshaler // the marshaler for the checkpoint itself.
+	marshalEnvelope   func(envelope binaryCheckpoint) ([]byte, error)
+	unmarshalEnvelope func(data []byte) (binaryCheckpoint, error)
+}
+
+func (e *binaryCheckpointEncoding) Name() string { return e.name }
+func (e *binaryCheckpointEncoding) Ext() string  { return e.ext }
+
+func (e *binaryCheckpointEncoding) Encode(w io.Writer, stack tokens.QName, snap *deploy.Snapshot,
+	sm secrets.Manager) error {
+
+	var latest *apitype.DeploymentV3
+	if snap != nil {
+		dep, err := SerializeDeployment(snap, sm)
+		if err != nil {
+			return errors.Wrap(err, "serializing deployment")
+		}
+		latest = dep
+	}
+
+	b, err := e.m.Marshal(apitype.CheckpointV3{Stack: stack, Latest: latest})
+	if err != nil {
+		return errors.Wrap(err, "marshalling checkpoint")
+	}
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+	var signature string
+	if key := os.Getenv(CheckpointSigningKeyEnvVar); key != "" {
+		signature = checkpointSignature(hash, []byte(key))
+	}
+
+	envelope, err := e.marshalEnvelope(binaryCheckpoint{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Checkpoint: b,
+		Hash:       hash,
+		Signature:  signature,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshalling checkpoint")
+	}
+	_, err = w.Write(envelope)
+	return err
+}
+
+func (e *binaryCheckpointEncoding) Decode(r io.Reader, verify bool) (*deploy.Snapshot, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := e.unmarshalEnvelope(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling %s checkpoint", e.name)
+	}
+	if envelope.Version != apitype.DeploymentSchemaVersionCurrent {
+		return nil, errors.Errorf("unsupported %s checkpoint version %d", e.name, envelope.Version)
+	}
+	if verify {
+		sum := sha256.Sum256(envelope.Checkpoint)
+		if err = verifyCheckpointHash(hex.EncodeToString(sum[:]), envelope.Hash, envelope.Signature); err != nil {
+			return nil, err
+		}
+	}
+
+	var chk apitype.CheckpointV3
+	if err = e.m.Unmarshal(envelope.Checkpoint, &chk); err != nil {
+		return nil, errors.Wrapf(err, "unmarshalling %s checkpoint", e.name)
+	}
+	return DeserializeCheckpoint(&chk)
+}
+
+func marshalMsgPackEnvelope(envelope binaryCheckpoint) ([]byte, error) {
+	return encoding.MsgPack.Marshal(envelope)
+}
+
+func unmarshalMsgPackEnvelope(data []byte) (binaryCheckpoint, error) {
+	var envelope binaryCheckpoint
+	err := encoding.MsgPack.Unmarshal(data, &envelope)
+	return envelope, err
+}
+
+// The envelope of a protobuf checkpoint is the message
+//
+//     message Checkpoint {
+//         int64 version = 1;
+//         bytes checkpoint = 2;
+//         string hash = 3;
+//         string signature = 4;
+//     }
+//
+// which is encoded by hand, as it is too simple to warrant generated code. A google.protobuf.Value, in which the
+// checkpoint itself is held, has no type for bytes, so the envelope cannot be one.
+
+const (
+	protobufVarint          = 0 // the wire type of integers.
+	protobufFixed64         = 1 // the wire type of 64-bit fixed-size values.
+	protobufLengthDelimited = 2 // the wire type of strings and bytes.
+	protobufFixed32         = 5 // the wire type of 32-bit fixed-size values.
+)
+
+func marshalProtobufEnvelope(envelope binaryCheckpoint) ([]byte, error) {
+	var b []byte
+	appendVarint := func(v uint64) {
+		var buf [binary.MaxVarintLen64]byte
+		b = append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+	}
+	appendBytes := func(field uint64, v []byte) {
+		if len(v) > 0 {
+			appendVarint(field<<3 | protobufLengthDelimited)
+			appendVarint(uint64(len(v)))
+			b = append(b, v...)
+		}
+	}
+
+	appendVarint(1<<3 | protobufVarint)
+	appendVarint(uint64(envelope.Version))
+	appendBytes(2, envelope.Checkpoint)
+	appendBytes(3, []byte(envelope.Hash))
+	appendBytes(4, []byte(envelope.Signature))
+	return b, nil
+}
+
+func unmarshalProtobufEnvelope(data []byte) (binaryCheckpoint, error) {
+	var envelope binaryCheckpoint
+	readVarint := func() (uint64, error) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, errors.New("malformed protobuf checkpoint")
+		}
+		data = data[n:]
+		return v, nil
+	}
+	skip := func(n uint64) ([]byte, error) {
+		if n > uint64(len(data)) {
+			return nil, errors.New("malformed protobuf checkpoint")
+		}
+		v := data[:n]
+		data = data[n:]
+		return v, nil
+	}
+
+	for len(data) > 0 {
+		key, err := readVarint()
+		if err != nil {
+			return envelope, err
+		}
+
+		// Fields other than those of the envelope are skipped, so that fields can be added to it in the future.
+		var value []byte
+		var number uint64
+		switch key & 7 {
+		case protobufVarint:
+			number, err = readVarint()
+		case protobufFixed64:
+			_, err = skip(8)
+		case protobufLengthDelimited:
+			if number, err = readVarint(); err == nil {
+				value, err = skip(number)
+			}
+		case protobufFixed32:
+			_, err = skip(4)
+		default:
+			err = errors.Errorf("malformed protobuf checkpoint: unsupported wire type %d", key&7)
+		}
+		if err != nil {
+			return envelope, err
+		}
+
+		switch key {
+		case 1<<3 | protobufVarint:
+			envelope.Version = int(number)
+		case 2<<3 | protobufLengthDelimited:
+			envelope.Checkpoint = value
+		case 3<<3 | protobufLengthDelimited:
+			envelope.Hash = string(value)
+		case 4<<3 | protobufLengthDelimited:
+			envelope.Signature = string(value)
+		}
+	}
+	return envelope, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/encoding"
+)
+
+func TestCheckpointEncodings(t *testing.T) {
+	snap := newStreamTestSnapshot()
+
+	for _, enc := range CheckpointEncodings {
+		t.Run(enc.Name(), func(t *testing.T) {
+			parsed, err := ParseCheckpointEncoding(enc.Name())
+			assert.NoError(t, err)
+			assert.Equal(t, enc, parsed)
+			byExt, ok := CheckpointEncodingForExt(enc.Ext())
+			assert.True(t, ok)
+			assert.Equal(t, enc, byExt)
+
+			var buf bytes.Buffer
+			assert.NoError(t, enc.Encode(&buf, "stack", snap, nil))
+
+			// Encoding is deterministic.
+			var again bytes.Buffer
+			assert.NoError(t, enc.Encode(&again, "stack", snap, nil))
+			assert.Equal(t, buf.Bytes(), again.Bytes())
+
+			decoded, err := enc.Decode(bytes.NewReader(buf.Bytes()), true)
+			assert.NoError(t, err)
+			if !assert.NotNil(t, decoded) {
+				return
+			}
+			assert.Equal(t, snap.Manifest, decoded.Manifest)
+			assert.Len(t, decoded.Resources, len(snap.Resources))
+			for i, res := range decoded.Resources {
+				assert.Equal(t, snap.Resources[i].URN, res.URN)
+				assert.Equal(t, snap.Resources[i].ID, res.ID)
+				assert.Equal(t, snap.Resources[i].Inputs, res.Inputs)
+				assert.Equal(t, snap.Resources[i].Outputs, res.Outputs)
+			}
+
+			// Edits are detected unless verification is disabled.
+			modified := bytes.Replace(buf.Bytes(), []byte("1.0.0"), []byte("2.0.0"), 1)
+			_, err = enc.Decode(bytes.NewReader(modified), true)
+			assert.IsType(t, &CheckpointModifiedError{}, err)
+			decoded, err = enc.Decode(bytes.NewReader(modified), false)
+			assert.NoError(t, err)
+			assert.Equal(t, "2.0.0", decoded.Manifest.Version)
+
+			// Empty checkpoints have no snapshot.
+			buf.Reset()
+			assert.NoError(t, enc.Encode(&buf, "stack", nil, nil))
+			decoded, err = enc.Decode(&buf, true)
+			assert.NoError(t, err)
+			assert.Nil(t, decoded)
+		})
+	}
+
+	enc, err := ParseCheckpointEncoding("")
+	assert.NoError(t, err)
+	assert.Equal(t, JSONCheckpoints, enc)
+	_, err = ParseCheckpointEncoding("xml")
+	assert.Error(t, err)
+	_, ok := CheckpointEncodingForExt(".yaml")
+	assert.False(t, ok)
+}
+
+func TestBinaryMarshalers(t *testing.T) {
+	type value struct {
+		Ints     []int64                `json:"ints"`
+		Uint     uint64                 `json:"uint"`
+		Float    float64                `json:"float"`
+		Bytes    []byte                 `json:"bytes,omitempty"`
+		Omitted  string                 `json:"omitted,omitempty"`
+		Pointer  *string                `json:"pointer"`
+		Object   map[string]interface{} `json:"object"`
+		Renamed  bool                   `json:"other"`
+		Ignored  string                 `json:"-"`
+		Resource apitype.ResourceV3     `json:"resource"`
+	}
+
+	long := string(bytes.Repeat([]byte("x"), 70000))
+	v := value{
+		Ints:    []int64{0, 1, -1, 127, 128, -32, -33, 255, -129, 65536, -40000, math.MaxInt64, math.MinInt64},
+		Uint:    math.MaxUint64,
+		Float:   1.5,
+		Bytes:   []byte{0, 1, 2},
+		Pointer: &long,
+		Object: map[string]interface{}{
+			"nested": []interface{}{"a", true, nil, 2.5},
+		},
+		Renamed:  true,
+		Ignored:  "ignored",
+		Resource: apitype.ResourceV3{URN: "urn", Type: "pkg:index:typ", Custom: true},
+	}
+
+	b, err := encoding.MsgPack.Marshal(v)
+	assert.NoError(t, err)
+	var decoded value
+	assert.NoError(t, encoding.MsgPack.Unmarshal(b, &decoded))
+	v.Ignored = ""
+	assert.Equal(t, v, decoded)
+	assert.Error(t, encoding.MsgPack.Unmarshal(b[:len(b)/2], &decoded))
+
+	// Values decoded into interfaces look just as they would if decoded from JSON.
+	var plain map[string]interface{}
+	assert.NoError(t, encoding.MsgPack.Unmarshal(b, &plain))
+	assert.Equal(t, 1.5, plain["float"])
+	assert.Equal(t, "AAEC", plain["bytes"])
+	assert.Equal(t, true, plain["other"])
+	assert.NotContains(t, plain, "omitted")
+
+	// Protobuf numbers are doubles, so integers survive only up to 2^53.
+	v.Ints, v.Uint = []int64{0, -1, 1 << 53}, 1<<53
+	b, err = encoding.Protobuf.Marshal(v)
+	assert.NoError(t, err)
+	decoded = value{}
+	assert.NoError(t, encoding.Protobuf.Unmarshal(b, &decoded))
+	assert.Equal(t, v, decoded)
+}
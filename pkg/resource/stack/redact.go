@@ -0,0 +1,184 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// RedactionMode determines how RedactUntypedDeployment replaces the values in a deployment.
+type RedactionMode string
+
+const (
+	// RedactStrip replaces each string with "[redacted]", each number with 0, and each boolean with false.
+	RedactStrip RedactionMode = "strip"
+	// RedactHash replaces each string with a hash of its value, so that equal strings can still be recognized, and
+	// otherwise behaves like RedactStrip. The hashes are keyed with a secret that is chosen anew each time a deployment
+	// is redacted, so they cannot be compared across redactions or used to guess the original values.
+	RedactHash RedactionMode = "hash"
+)
+
+// redactedString replaces strings removed by RedactStrip.
+const redactedString = "[redacted]"
+
+// RedactUntypedDeployment returns a copy of a deployment from which all data has been removed, for sharing with
+// others, such as support engineers, who need to understand the stack's shape but must not see its contents.
+//
+// The copy keeps every resource's URN, type, parent, provider, and dependencies, and the shape of its inputs and
+// outputs: the property names, the nesting of objects and arrays, and the kind of each value. Property values,
+// resource IDs, initialization errors, and annotations are redacted according to the given mode, and the secrets
+// provider's state is dropped. The redacted deployment is meant for inspection only and cannot be imported.
+func RedactUntypedDeployment(deployment *apitype.UntypedDeployment,
+	mode RedactionMode) (*apitype.UntypedDeployment, error) {
+
+	r, err := newRedactor(mode)
+	if err != nil {
+		return nil, err
+	}
+	v3deployment, err := untypedDeploymentToV3(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	v3deployment.SecretsProviders = nil
+	for i := range v3deployment.Resources {
+		r.resource(&v3deployment.Resources[i])
+	}
+	for i := range v3deployment.PendingOperations {
+		r.resource(&v3deployment.PendingOperations[i].Resource)
+	}
+
+	bytes, err := json.Marshal(v3deployment)
+	if err != nil {
+		return nil, err
+	}
+	return &apitype.UntypedDeployment{
+		Version:    apitype.DeploymentSchemaVersionCurrent,
+		Deployment: json.RawMessage(bytes),
+	}, nil
+}
+
+// redactor redacts the values in a deployment.
+type redactor struct {
+	mode RedactionMode
+	key  []byte // the key for RedactHash's hashes.
+}
+
+func newRedactor(mode RedactionMode) (*redactor, error) {
+	switch mode {
+	case RedactStrip:
+		return &redactor{mode: mode}, nil
+	case RedactHash:
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, errors.Wrap(err, "generating redaction key")
+		}
+		return &redactor{mode: mode, key: key}, nil
+	default:
+		return nil, errors.Errorf("unknown redaction mode %q: expected %q or %q", mode, RedactStrip, RedactHash)
+	}
+}
+
+func (r *redactor) resource(res *apitype.ResourceV3) {
+	isProvider := providers.IsProviderType(res.Type)
+
+	// Provider IDs are chosen by the engine rather than by the cloud, and are referred to by every resource that the
+	// provider manages, so they are left as they are.
+	if res.ID != "" && !isProvider {
+		res.ID = resource.ID(r.string(string(res.ID)))
+	}
+
+	// The version of a provider is part of the stack's shape, so it is kept.
+	var version interface{}
+	if isProvider {
+		version = res.Inputs["version"]
+	}
+	res.Inputs = r.object(res.Inputs)
+	res.Outputs = r.object(res.Outputs)
+	if version != nil {
+		res.Inputs["version"] = version
+		if _, has := res.Outputs["version"]; has {
+			res.Outputs["version"] = version
+		}
+	}
+
+	for i, e := range res.InitErrors {
+		res.InitErrors[i] = r.string(e)
+	}
+	for k, v := range res.Annotations {
+		res.Annotations[k] = r.string(v)
+	}
+}
+
+func (r *redactor) object(obj map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if k == resource.SigKey {
+			// Signatures identify secrets, assets, and archives, and are part of the value's kind.
+			result[k] = v
+			continue
+		}
+		result[k] = r.value(v)
+	}
+	return result
+}
+
+func (r *redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		return false
+	case float64:
+		return float64(0)
+	case string:
+		return r.string(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, e := range v {
+			result[i] = r.value(e)
+		}
+		return result
+	case map[string]interface{}:
+		return r.object(v)
+	default:
+		// Deployments are decoded from JSON, so no other kinds of values are possible.
+		return nil
+	}
+}
+
+func (r *redactor) string(s string) string {
+	if r.mode != RedactHash {
+		return redactedString
+	}
+	mac := hmac.New(sha256.New, r.key)
+	_, err := mac.Write([]byte(s))
+	contract.IgnoreError(err) // hash.Hash.Write never fails.
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+func newRedactTestDeployment(t *testing.T) *apitype.UntypedDeployment {
+	provURN := resource.URN("urn:pulumi:test::test::pulumi:providers:aws::default")
+	deployment := apitype.DeploymentV3{
+		SecretsProviders: &apitype.SecretsProvidersV1{Type: "passphrase", State: json.RawMessage(`{"salt":"x"}`)},
+		Resources: []apitype.ResourceV3{
+			{
+				URN:    provURN,
+				Custom: true,
+				ID:     "b6e6f7b4-1b3c-4c1d-8c3a-3bdf7f2f6f39",
+				Type:   "pulumi:providers:aws",
+				Inputs: map[string]interface{}{"region": "us-west-2", "version": "0.18.0"},
+			},
+			{
+				URN:      "urn:pulumi:test::test::aws:s3/bucket:Bucket::a",
+				Custom:   true,
+				ID:       "my-secret-bucket",
+				Type:     "aws:s3/bucket:Bucket",
+				Provider: string(provURN) + "::b6e6f7b4-1b3c-4c1d-8c3a-3bdf7f2f6f39",
+				Inputs: map[string]interface{}{
+					"bucket": "my-secret-bucket",
+					"tags":   map[string]interface{}{"owner": "alice", "count": float64(3)},
+					"acl":    []interface{}{"private", true},
+				},
+				Outputs: map[string]interface{}{
+					"bucket": "my-secret-bucket",
+					"key": map[string]interface{}{
+						resource.SigKey: resource.SecretSig,
+						"ciphertext":    "c2VjcmV0",
+					},
+				},
+				Dependencies: []resource.URN{provURN},
+				InitErrors:   []string{"bucket my-secret-bucket is not ready"},
+			},
+			{
+				URN:    "urn:pulumi:test::test::aws:s3/bucket:Bucket::b",
+				Custom: true,
+				ID:     "my-other-bucket",
+				Type:   "aws:s3/bucket:Bucket",
+				Inputs: map[string]interface{}{"bucket": "my-secret-bucket"},
+			},
+		},
+	}
+	bytes, err := json.Marshal(deployment)
+	assert.NoError(t, err)
+	return &apitype.UntypedDeployment{Version: 3, Deployment: json.RawMessage(bytes)}
+}
+
+func redactTestDeployment(t *testing.T, mode RedactionMode) apitype.DeploymentV3 {
+	redacted, err := RedactUntypedDeployment(newRedactTestDeployment(t), mode)
+	assert.NoError(t, err)
+
+	var deployment apitype.DeploymentV3
+	assert.NoError(t, json.Unmarshal(redacted.Deployment, &deployment))
+	assert.NotContains(t, string(redacted.Deployment), "my-secret-bucket")
+	assert.NotContains(t, string(redacted.Deployment), "alice")
+	assert.NotContains(t, string(redacted.Deployment), "us-west-2")
+	assert.NotContains(t, string(redacted.Deployment), "c2VjcmV0")
+	return deployment
+}
+
+func TestRedactDeploymentStrip(t *testing.T) {
+	deployment := redactTestDeployment(t, RedactStrip)
+	assert.Nil(t, deployment.SecretsProviders)
+
+	// Providers keep their IDs and versions, so that references to them still make sense.
+	prov := deployment.Resources[0]
+	assert.Equal(t, resource.ID("b6e6f7b4-1b3c-4c1d-8c3a-3bdf7f2f6f39"), prov.ID)
+	assert.Equal(t, map[string]interface{}{"region": "[redacted]", "version": "0.18.0"}, prov.Inputs)
+
+	// Everything else keeps its structure, but not its values.
+	res := deployment.Resources[1]
+	assert.Equal(t, resource.URN("urn:pulumi:test::test::aws:s3/bucket:Bucket::a"), res.URN)
+	assert.Equal(t, resource.ID("[redacted]"), res.ID)
+	assert.Equal(t, "urn:pulumi:test::test::pulumi:providers:aws::default::b6e6f7b4-1b3c-4c1d-8c3a-3bdf7f2f6f39",
+		res.Provider)
+	assert.Equal(t, []resource.URN{prov.URN}, res.Dependencies)
+	assert.Equal(t, map[string]interface{}{
+		"bucket": "[redacted]",
+		"tags":   map[string]interface{}{"owner": "[redacted]", "count": float64(0)},
+		"acl":    []interface{}{"[redacted]", false},
+	}, res.Inputs)
+	assert.Equal(t, map[string]interface{}{
+		resource.SigKey: resource.SecretSig,
+		"ciphertext":    "[redacted]",
+	}, res.Outputs["key"])
+	assert.Equal(t, []string{"[redacted]"}, res.InitErrors)
+}
+
+func TestRedactDeploymentHash(t *testing.T) {
+	deployment := redactTestDeployment(t, RedactHash)
+
+	// Equal values hash to equal strings, and different values to different ones.
+	a, b := deployment.Resources[1], deployment.Resources[2]
+	assert.Equal(t, a.Inputs["bucket"], b.Inputs["bucket"])
+	assert.Equal(t, string(a.ID), a.Inputs["bucket"])
+	assert.NotEqual(t, a.ID, b.ID)
+	assert.Regexp(t, "^sha256:[0-9a-f]{16}$", a.ID)
+
+	_, err := RedactUntypedDeployment(newRedactTestDeployment(t), "scramble")
+	assert.Error(t, err)
+}
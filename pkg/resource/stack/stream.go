@@ -0,0 +1,498 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/secrets"
+	"github.com/pulumi/pulumi/pkg/tokens"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// ErrCheckpointNotStreamable is returned by DecodeCheckpoint when a checkpoint is not laid out the way
+// EncodeCheckpoint writes it, e.g. because it predates the current checkpoint version. Such checkpoints must be read
+// in full and passed to UnmarshalVersionedCheckpointToLatestCheckpoint instead.
+var ErrCheckpointNotStreamable = errors.New("checkpoint cannot be decoded incrementally")
+
+// checkpointIndent is the indentation used for each level of a checkpoint file.
+const checkpointIndent = "    "
+
+// checkpointWriter writes a checkpoint file one piece at a time. Each piece is written indented to the output and
+// compacted to the hash, so that the recorded hash matches the one SerializeCheckpoint would compute.
+type checkpointWriter struct {
+	out  *bufio.Writer
+	hash hash.Hash
+	buf  bytes.Buffer
+	err  error
+}
+
+// write emits literal text: the indented form to the output and the compact form to the hash.
+func (cw *checkpointWriter) write(indented, compact string) {
+	if cw.err != nil {
+		return
+	}
+	if _, cw.err = cw.out.WriteString(indented); cw.err != nil {
+		return
+	}
+	_, cw.err = cw.hash.Write([]byte(compact))
+}
+
+// value marshals v and emits it, indented to the given depth in the output and compacted in the hash.
+func (cw *checkpointWriter) value(v interface{}, depth int) {
+	if cw.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		cw.err = err
+		return
+	}
+	if _, cw.err = cw.hash.Write(b); cw.err != nil {
+		return
+	}
+
+	cw.buf.Reset()
+	if cw.err = json.Indent(&cw.buf, b, strings.Repeat(checkpointIndent, depth), checkpointIndent); cw.err != nil {
+		return
+	}
+	_, cw.err = cw.buf.WriteTo(cw.out)
+}
+
+// field emits the key of an object field at the given depth, preceded by a comma unless it is the first field.
+func (cw *checkpointWriter) field(key string, depth int, first bool) {
+	sep := ","
+	if first {
+		sep = ""
+	}
+	quoted := `"` + key + `":`
+	cw.write(sep+"\n"+strings.Repeat(checkpointIndent, depth)+quoted+" ", sep+quoted)
+}
+
+// EncodeCheckpoint writes the checkpoint for a snapshot to w. The result is the same document that marshaling the
+// output of SerializeCheckpoint would produce, but resources and pending operations are serialized and written one at
+// a time, so memory use is bounded by the largest resource rather than by the size of the stack.
+func EncodeCheckpoint(w io.Writer, stack tokens.QName, snap *deploy.Snapshot, sm secrets.Manager) error {
+	cw := &checkpointWriter{out: bufio.NewWriter(w), hash: sha256.New()}
+
+	// The version is written to the output only; the hash covers just the checkpoint itself.
+	version := strconv.Itoa(apitype.DeploymentSchemaVersionCurrent)
+	cw.write("{\n"+checkpointIndent+`"version": `+version+",\n"+checkpointIndent+`"checkpoint": {`, "{")
+
+	cw.field("stack", 2, true)
+	cw.value(stack, 2)
+
+	if snap != nil {
+		if err := encodeDeployment(cw, snap, sm); err != nil {
+			return err
+		}
+	}
+	cw.write("\n"+checkpointIndent+"}", "}")
+	if cw.err != nil {
+		return errors.Wrap(cw.err, "writing checkpoint")
+	}
+
+	// Now that the checkpoint has been written in its entirety, record its hash and signature.
+	sum := hex.EncodeToString(cw.hash.Sum(nil))
+	cw.write(",\n"+checkpointIndent+`"hash": "`+sum+`"`, "")
+	if key := os.Getenv(CheckpointSigningKeyEnvVar); key != "" {
+		cw.write(",\n"+checkpointIndent+`"signature": "`+checkpointSignature(sum, []byte(key))+`"`, "")
+	}
+	cw.write("\n}", "")
+	if cw.err != nil {
+		return errors.Wrap(cw.err, "writing checkpoint")
+	}
+	return cw.out.Flush()
+}
+
+// encodeDeployment writes the "latest" field of a checkpoint, serializing the snapshot's resources and pending
+// operations as it goes.
+func encodeDeployment(cw *checkpointWriter, snap *deploy.Snapshot, sm secrets.Manager) error {
+	// If a specific secrets manager was not provided, use the one in the snapshot, if present.
+	if sm == nil {
+		sm = snap.SecretsManager
+	}
+	enc, err := deploymentEncrypter(sm)
+	if err != nil {
+		return err
+	}
+	secretsProvider, err := serializeSecretsProvider(sm)
+	if err != nil {
+		return err
+	}
+
+	cw.field("latest", 2, false)
+	cw.write("{", "{")
+
+	cw.field("manifest", 3, true)
+	cw.value(serializeManifest(snap.Manifest), 3)
+
+	if secretsProvider != nil {
+		cw.field("secrets_providers", 3, false)
+		cw.value(secretsProvider, 3)
+	}
+
+	if len(snap.Resources) > 0 {
+		cw.field("resources", 3, false)
+		cw.write("[", "[")
+		for i, res := range snap.Resources {
+			sres, err := SerializeResource(res, enc)
+			if err != nil {
+				return errors.Wrap(err, "serializing resources")
+			}
+			cw.element(i == 0, 4)
+			cw.value(sres, 4)
+		}
+		cw.write("\n"+strings.Repeat(checkpointIndent, 3)+"]", "]")
+	}
+
+	if len(snap.PendingOperations) > 0 {
+		cw.field("pending_operations", 3, false)
+		cw.write("[", "[")
+		for i, op := range snap.PendingOperations {
+			sop, err := SerializeOperation(op, enc)
+			if err != nil {
+				return err
+			}
+			cw.element(i == 0, 4)
+			cw.value(sop, 4)
+		}
+		cw.write("\n"+strings.Repeat(checkpointIndent, 3)+"]", "]")
+	}
+
+	cw.write("\n"+strings.Repeat(checkpointIndent, 2)+"}", "}")
+	return nil
+}
+
+// element starts an array element at the given depth, preceded by a comma unless it is the first element.
+func (cw *checkpointWriter) element(first bool, depth int) {
+	sep := ","
+	if first {
+		sep = ""
+	}
+	cw.write(sep+"\n"+strings.Repeat(checkpointIndent, depth), sep)
+}
+
+// checkpointReader reads a checkpoint file one piece at a time, compacting each piece into a hash as it goes so that
+// the checkpoint's integrity can be verified without holding the whole document in memory.
+type checkpointReader struct {
+	dec  *json.Decoder
+	hash hash.Hash
+	buf  bytes.Buffer
+}
+
+// raw decodes the next value as raw JSON and adds its compact form to the hash.
+func (cr *checkpointReader) raw() (json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := cr.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	cr.buf.Reset()
+	if err := json.Compact(&cr.buf, raw); err != nil {
+		return nil, err
+	}
+	if _, err := cr.buf.WriteTo(cr.hash); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// key reads the next object key and, unless it is the first key in its object, the comma that precedes it.
+func (cr *checkpointReader) key(first bool) (string, error) {
+	tok, err := cr.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", errors.Errorf("expected an object key, got %v", tok)
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	if !first {
+		cr.write(",")
+	}
+	cr.write(string(b) + ":")
+	return key, nil
+}
+
+// write adds structural text to the hash.
+func (cr *checkpointReader) write(s string) {
+	_, err := cr.hash.Write([]byte(s))
+	contract.IgnoreError(err)
+}
+
+// delim reads the next token and returns true if it is the given delimiter, or false if it is null.
+func (cr *checkpointReader) delim(d json.Delim) (bool, error) {
+	tok, err := cr.dec.Token()
+	if err != nil {
+		return false, err
+	}
+	switch tok {
+	case d:
+		cr.write(d.String())
+		return true, nil
+	case nil:
+		cr.write("null")
+		return false, nil
+	default:
+		return false, errors.Errorf("expected %v, got %v", d, tok)
+	}
+}
+
+// end reads the closing delimiter of the current object or array.
+func (cr *checkpointReader) end(d json.Delim) error {
+	tok, err := cr.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != d {
+		return errors.Errorf("expected %v, got %v", d, tok)
+	}
+	cr.write(d.String())
+	return nil
+}
+
+// DecodeCheckpoint reads a checkpoint from r and returns its associated snapshot, deserializing resources and pending
+// operations one at a time so that memory use is bounded by the snapshot itself rather than by its serialized form.
+// Returns nil if there have been no deployments performed on this checkpoint.
+//
+// If verify is true, the checkpoint's hash and signature are checked as in VerifyCheckpointIntegrity. If the
+// checkpoint is not laid out as EncodeCheckpoint writes it, ErrCheckpointNotStreamable is returned and the caller
+// should fall back to reading the checkpoint in full.
+func DecodeCheckpoint(r io.Reader, verify bool) (*deploy.Snapshot, error) {
+	cr := &checkpointReader{dec: json.NewDecoder(r), hash: sha256.New()}
+
+	tok, err := cr.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, ErrCheckpointNotStreamable
+	}
+
+	var version int
+	var expected, signature string
+	var snap *deploy.Snapshot
+	sawCheckpoint := false
+	for cr.dec.More() {
+		tok, err := cr.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok {
+		case "version":
+			if err = cr.dec.Decode(&version); err != nil {
+				return nil, err
+			}
+		case "checkpoint":
+			// Only the current checkpoint version can be decoded incrementally, and we must know the version
+			// before the checkpoint itself is read.
+			if version != apitype.DeploymentSchemaVersionCurrent {
+				return nil, ErrCheckpointNotStreamable
+			}
+			if snap, err = cr.checkpoint(); err != nil {
+				return nil, err
+			}
+			sawCheckpoint = true
+		case "hash":
+			if err = cr.dec.Decode(&expected); err != nil {
+				return nil, err
+			}
+		case "signature":
+			if err = cr.dec.Decode(&signature); err != nil {
+				return nil, err
+			}
+		default:
+			var skip json.RawMessage
+			if err = cr.dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !sawCheckpoint {
+		return nil, ErrCheckpointNotStreamable
+	}
+
+	if verify {
+		actual := hex.EncodeToString(cr.hash.Sum(nil))
+		if err := verifyCheckpointHash(actual, expected, signature); err != nil {
+			return nil, err
+		}
+	}
+
+	return snap, nil
+}
+
+// checkpoint reads a CheckpointV3 and returns the snapshot of its latest deployment, if any.
+func (cr *checkpointReader) checkpoint() (*deploy.Snapshot, error) {
+	if ok, err := cr.delim('{'); err != nil || !ok {
+		return nil, err
+	}
+
+	var snap *deploy.Snapshot
+	for first := true; cr.dec.More(); first = false {
+		key, err := cr.key(first)
+		if err != nil {
+			return nil, err
+		}
+		if key == "latest" {
+			if snap, err = cr.deployment(); err != nil {
+				return nil, err
+			}
+		} else if _, err = cr.raw(); err != nil {
+			return nil, err
+		}
+	}
+
+	return snap, cr.end('}')
+}
+
+// deployment reads a DeploymentV3 and returns its snapshot, or nil if the deployment is null.
+func (cr *checkpointReader) deployment() (*deploy.Snapshot, error) {
+	ok, err := cr.delim('{')
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var manifest deploy.Manifest
+	var sm secrets.Manager
+	var dec config.Decrypter
+	var resources []*resource.State
+	var ops []resource.Operation
+	for first := true; cr.dec.More(); first = false {
+		key, err := cr.key(first)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "manifest":
+			var m apitype.ManifestV1
+			if err = cr.unmarshal(&m); err != nil {
+				return nil, err
+			}
+			if manifest, err = deserializeManifest(m); err != nil {
+				return nil, err
+			}
+		case "secrets_providers":
+			// Secrets must be decrypted as resources are read, so the provider must precede them.
+			if dec != nil {
+				return nil, ErrCheckpointNotStreamable
+			}
+			var providers *apitype.SecretsProvidersV1
+			if err = cr.unmarshal(&providers); err != nil {
+				return nil, err
+			}
+			if sm, err = deserializeSecretsManager(providers); err != nil {
+				return nil, err
+			}
+		case "resources":
+			if dec == nil {
+				if dec, err = deploymentDecrypter(sm); err != nil {
+					return nil, err
+				}
+			}
+			err = cr.array(func() error {
+				var res apitype.ResourceV3
+				if err := cr.unmarshal(&res); err != nil {
+					return err
+				}
+				desres, err := DeserializeResource(res, dec)
+				if err != nil {
+					return err
+				}
+				resources = append(resources, desres)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		case "pending_operations":
+			if dec == nil {
+				if dec, err = deploymentDecrypter(sm); err != nil {
+					return nil, err
+				}
+			}
+			err = cr.array(func() error {
+				var op apitype.OperationV2
+				if err := cr.unmarshal(&op); err != nil {
+					return err
+				}
+				desop, err := DeserializeOperation(op, dec)
+				if err != nil {
+					return err
+				}
+				ops = append(ops, desop)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if _, err = cr.raw(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err = cr.end('}'); err != nil {
+		return nil, err
+	}
+
+	return deploy.NewSnapshot(manifest, sm, resources, ops), nil
+}
+
+// array reads a JSON array, calling elem to read each of its elements in turn.
+func (cr *checkpointReader) array(elem func() error) error {
+	ok, err := cr.delim('[')
+	if err != nil || !ok {
+		return err
+	}
+	for first := true; cr.dec.More(); first = false {
+		if !first {
+			cr.write(",")
+		}
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	return cr.end(']')
+}
+
+// unmarshal reads the next value into v, adding it to the hash.
+func (cr *checkpointReader) unmarshal(v interface{}) error {
+	raw, err := cr.raw()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,122 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/secrets/b64"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func newStreamTestSnapshot() *deploy.Snapshot {
+	var resources []*resource.State
+	for _, name := range []string{"a", "b", "c"} {
+		urn := resource.NewURN("test", "test", "", "pkg:index:typ", tokens.QName(name))
+		resources = append(resources, resource.NewState("pkg:index:typ", urn, true, false, resource.ID(name),
+			resource.PropertyMap{
+				"name":   resource.NewStringProperty(name),
+				"secret": resource.MakeSecret(resource.NewStringProperty("<" + name + ">")),
+			},
+			resource.PropertyMap{"id": resource.NewStringProperty(name)},
+			"", false, false, nil, nil, "", nil, false, nil, nil))
+	}
+	manifest := deploy.Manifest{Time: time.Unix(0, 0).UTC(), Magic: "magic", Version: "1.0.0"}
+	return deploy.NewSnapshot(manifest, b64.NewBase64SecretsManager(), resources, nil)
+}
+
+func TestEncodeCheckpoint(t *testing.T) {
+	snap := newStreamTestSnapshot()
+
+	chk, err := SerializeCheckpoint("stack", snap, nil)
+	assert.NoError(t, err)
+	expected, err := json.MarshalIndent(chk, "", "    ")
+	assert.NoError(t, err)
+
+	// Streaming the checkpoint must produce exactly the document that marshaling it in full does.
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeCheckpoint(&buf, "stack", snap, nil))
+	assert.Equal(t, string(expected), buf.String())
+	assert.NoError(t, VerifyCheckpointIntegrity(buf.Bytes()))
+
+	// As must streaming an empty checkpoint.
+	chk, err = SerializeCheckpoint("stack", nil, nil)
+	assert.NoError(t, err)
+	expected, err = json.MarshalIndent(chk, "", "    ")
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, EncodeCheckpoint(&buf, "stack", nil, nil))
+	assert.Equal(t, string(expected), buf.String())
+}
+
+func TestDecodeCheckpoint(t *testing.T) {
+	snap := newStreamTestSnapshot()
+
+	var buf bytes.Buffer
+	assert.NoError(t, EncodeCheckpoint(&buf, "stack", snap, nil))
+
+	decoded, err := DecodeCheckpoint(bytes.NewReader(buf.Bytes()), true)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, decoded) {
+		return
+	}
+	assert.Equal(t, snap.Manifest, decoded.Manifest)
+	assert.Len(t, decoded.Resources, len(snap.Resources))
+	for i, res := range decoded.Resources {
+		assert.Equal(t, snap.Resources[i].URN, res.URN)
+		assert.Equal(t, snap.Resources[i].Inputs, res.Inputs)
+		assert.Equal(t, snap.Resources[i].Outputs, res.Outputs)
+	}
+
+	// Compact checkpoints decode just the same.
+	chk, err := SerializeCheckpoint("stack", snap, nil)
+	assert.NoError(t, err)
+	compact, err := json.Marshal(chk)
+	assert.NoError(t, err)
+	decoded, err = DecodeCheckpoint(bytes.NewReader(compact), true)
+	assert.NoError(t, err)
+	assert.Len(t, decoded.Resources, len(snap.Resources))
+
+	// Edits are detected unless verification is disabled.
+	modified := strings.Replace(buf.String(), `"magic": "magic"`, `"magic": "other"`, 1)
+	_, err = DecodeCheckpoint(strings.NewReader(modified), true)
+	assert.IsType(t, &CheckpointModifiedError{}, err)
+	_, err = DecodeCheckpoint(strings.NewReader(modified), false)
+	assert.NoError(t, err)
+
+	// Empty checkpoints have no snapshot.
+	buf.Reset()
+	assert.NoError(t, EncodeCheckpoint(&buf, "stack", nil, nil))
+	decoded, err = DecodeCheckpoint(&buf, true)
+	assert.NoError(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestDecodeOldCheckpoint(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/checkpoint-v1.json")
+	assert.NoError(t, err)
+
+	_, err = DecodeCheckpoint(strings.NewReader(string(bytes)), true)
+	assert.Equal(t, ErrCheckpointNotStreamable, err)
+}
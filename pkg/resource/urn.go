@@ -17,6 +17,8 @@ package resource
 import (
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 )
@@ -68,6 +70,37 @@ func NewURN(stack tokens.QName, proj tokens.PackageName, parentType, baseType to
 	)
 }
 
+// ParseURN parses the given string as a URN, returning an error if it is not of the form described above. Tools that
+// accept URNs from their users should parse them with ParseURN rather than converting them, since the accessors of
+// an invalid URN panic.
+func ParseURN(s string) (URN, error) {
+	if !strings.HasPrefix(s, URNPrefix) {
+		return "", errors.Errorf("invalid URN '%s': URNs must begin with '%s'", s, URNPrefix)
+	}
+	parts := strings.SplitN(s[len(URNPrefix):], URNNameDelimiter, 4)
+	if len(parts) != 4 {
+		return "", errors.Errorf("invalid URN '%s': expected '%s<stack>::<project>::<type>::<name>'", s, URNPrefix)
+	}
+	if parts[2] == "" {
+		return "", errors.Errorf("invalid URN '%s': the type is empty", s)
+	}
+	for _, typ := range strings.Split(parts[2], URNTypeDelimiter) {
+		if typ == "" {
+			return "", errors.Errorf("invalid URN '%s': the type '%s' has an empty component", s, parts[2])
+		}
+	}
+	if parts[3] == "" {
+		return "", errors.Errorf("invalid URN '%s': the name is empty", s)
+	}
+	return URN(s), nil
+}
+
+// IsValid returns true if the URN is well-formed; that is, if ParseURN would accept it.
+func (urn URN) IsValid() bool {
+	_, err := ParseURN(string(urn))
+	return err == nil
+}
+
 // URNName returns the URN name part of a URN (i.e., strips off the prefix).
 func (urn URN) URNName() string {
 	s := string(urn)
@@ -75,24 +108,42 @@ func (urn URN) URNName() string {
 	return s[len(URNPrefix):]
 }
 
+// components returns the stack, project, qualified type, and name parts of a URN. Because the name is the last part,
+// it may itself contain the name delimiter.
+func (urn URN) components() []string {
+	parts := strings.SplitN(urn.URNName(), URNNameDelimiter, 4)
+	contract.Assertf(len(parts) == 4, "Urn is: '%s'", string(urn))
+	return parts
+}
+
 // Stack returns the resource stack part of a URN.
 func (urn URN) Stack() tokens.QName {
-	return tokens.QName(strings.Split(urn.URNName(), URNNameDelimiter)[0])
+	return tokens.QName(urn.components()[0])
 }
 
 // Project returns the project name part of a URN.
 func (urn URN) Project() tokens.PackageName {
-	return tokens.PackageName(strings.Split(urn.URNName(), URNNameDelimiter)[1])
+	return tokens.PackageName(urn.components()[1])
 }
 
 // QualifiedType returns the resource type part of a URN including the parent type
 func (urn URN) QualifiedType() tokens.Type {
-	return tokens.Type(strings.Split(urn.URNName(), URNNameDelimiter)[2])
+	return tokens.Type(urn.components()[2])
+}
+
+// ParentType returns the parent type part of a URN; that is, the qualified type without the resource's own type. It
+// is empty if the resource has no parent, or if its parent is the stack's root resource.
+func (urn URN) ParentType() tokens.Type {
+	qualifiedType := urn.components()[2]
+	if i := strings.LastIndex(qualifiedType, URNTypeDelimiter); i != -1 {
+		return tokens.Type(qualifiedType[:i])
+	}
+	return ""
 }
 
 // Type returns the resource type part of a URN
 func (urn URN) Type() tokens.Type {
-	qualifiedType := strings.Split(urn.URNName(), URNNameDelimiter)[2]
+	qualifiedType := urn.components()[2]
 	types := strings.Split(qualifiedType, URNTypeDelimiter)
 	lastType := types[len(types)-1]
 	return tokens.Type(lastType)
@@ -100,5 +151,5 @@ func (urn URN) Type() tokens.Type {
 
 // Name returns the resource name part of a URN.
 func (urn URN) Name() tokens.QName {
-	return tokens.QName(strings.Split(urn.URNName(), URNNameDelimiter)[3])
+	return tokens.QName(urn.components()[3])
 }
@@ -0,0 +1,96 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// URNPattern selects resources by their URNs. A pattern that begins with "urn:pulumi:" is matched against the whole
+// URN; one that contains "::" is matched against the resource's "<type>::<name>"; and any other is matched against
+// the resource's name alone.
+//
+// In each form, `*` matches any run of characters that does not contain the `::` delimiter, and `**` matches any run
+// of characters at all. A pattern without wildcards matches only the URN, type and name, or name that it spells out.
+// Every tool and engine option that selects resources by URN should use URNPattern, so that they all agree on which
+// resources a pattern selects.
+type URNPattern struct {
+	pattern string
+}
+
+// ParseURNPattern parses the given string as a URN pattern.
+func ParseURNPattern(s string) (URNPattern, error) {
+	switch {
+	case s == "":
+		return URNPattern{}, errors.New("a URN pattern must not be empty")
+	case strings.HasPrefix(s, "urn:") && !strings.HasPrefix(s, URNPrefix):
+		return URNPattern{}, errors.Errorf("invalid URN pattern '%s': URNs must begin with '%s'", s, URNPrefix)
+	}
+	return URNPattern{pattern: s}, nil
+}
+
+func (p URNPattern) String() string {
+	return p.pattern
+}
+
+// Matches returns true if the given URN matches the pattern. Invalid URNs match no pattern.
+func (p URNPattern) Matches(urn URN) bool {
+	if p.pattern == "" || !urn.IsValid() {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(p.pattern, URNPrefix):
+		return globMatch(p.pattern, string(urn))
+	case strings.Contains(p.pattern, URNNameDelimiter):
+		return globMatch(p.pattern, string(urn.Type())+URNNameDelimiter+string(urn.Name()))
+	default:
+		return globMatch(p.pattern, string(urn.Name()))
+	}
+}
+
+// globMatch returns true if the given string matches the given pattern, in which `*` matches any run of characters
+// that does not contain the URN name delimiter, and `**` matches any run of characters.
+func globMatch(pattern, s string) bool {
+	for pattern != "" {
+		switch {
+		case strings.HasPrefix(pattern, "**"):
+			rest := pattern[2:]
+			for i := 0; i <= len(s); i++ {
+				if globMatch(rest, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case pattern[0] == '*':
+			rest := pattern[1:]
+			for i := 0; i <= len(s); i++ {
+				if i >= len(URNNameDelimiter) && s[i-len(URNNameDelimiter):i] == URNNameDelimiter {
+					return false
+				}
+				if globMatch(rest, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case s == "" || pattern[0] != s[0]:
+			return false
+		default:
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
@@ -0,0 +1,58 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURNPattern(t *testing.T) {
+	urn := NewURN("dev", "proj", "my:mod:Comp", "aws:s3/bucket:Bucket", "logs")
+
+	matches := func(pattern string) bool {
+		p, err := ParseURNPattern(pattern)
+		assert.NoError(t, err, pattern)
+		return p.Matches(urn)
+	}
+
+	assert.True(t, matches(string(urn)))
+	assert.True(t, matches("aws:s3/bucket:Bucket::logs"))
+	assert.True(t, matches("logs"))
+	assert.True(t, matches("l*"))
+	assert.True(t, matches("aws:*::logs"))
+	assert.True(t, matches("urn:pulumi:dev::proj::*::logs"))
+	assert.True(t, matches("urn:pulumi:**::logs"))
+	assert.True(t, matches("urn:pulumi:dev::**"))
+
+	assert.False(t, matches("log"))
+	assert.False(t, matches("aws:s3/bucket:Bucket"))
+	assert.False(t, matches("gcp:*::logs"))
+	assert.False(t, matches("urn:pulumi:prod::proj::*::logs"))
+
+	// A single wildcard does not cross the delimiter between the parts of a URN.
+	assert.False(t, matches("urn:pulumi:*::logs"))
+	assert.False(t, matches("urn:pulumi:dev::*"))
+
+	p, err := ParseURNPattern("*")
+	assert.NoError(t, err)
+	assert.False(t, p.Matches("not-a-urn"))
+
+	_, err = ParseURNPattern("")
+	assert.Error(t, err)
+	_, err = ParseURNPattern("urn:other:dev::proj::typ::name")
+	assert.Error(t, err)
+}
@@ -49,3 +49,27 @@ func TestURNRoundTripping2(t *testing.T) {
 	assert.Equal(t, typ, urn.Type())
 	assert.Equal(t, name, urn.Name())
 }
+
+func TestParseURN(t *testing.T) {
+	urn, err := ParseURN("urn:pulumi:stck::proj::parent$type$pkg:m:typ::a::b")
+	assert.NoError(t, err)
+	assert.True(t, urn.IsValid())
+	assert.Equal(t, tokens.Type("parent$type"), urn.ParentType())
+	assert.Equal(t, tokens.Type("pkg:m:typ"), urn.Type())
+	assert.Equal(t, tokens.QName("a::b"), urn.Name())
+
+	assert.Equal(t, tokens.Type(""), NewURN("stck", "proj", "", "pkg:m:typ", "a").ParentType())
+
+	for _, s := range []string{
+		"",
+		"pkg:m:typ::a",
+		"urn:pulumi:stck::proj::pkg:m:typ",
+		"urn:pulumi:stck::proj::::a",
+		"urn:pulumi:stck::proj::parent$$pkg:m:typ::a",
+		"urn:pulumi:stck::proj::pkg:m:typ::",
+	} {
+		_, err := ParseURN(s)
+		assert.Error(t, err, s)
+		assert.False(t, URN(s).IsValid(), s)
+	}
+}
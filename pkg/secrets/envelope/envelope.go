@@ -0,0 +1,180 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envelope implements envelope encryption of whole checkpoints. Each checkpoint is encrypted with a random data
+// key, which is itself encrypted ("wrapped") by a key provider such as a passphrase or a cloud key management service,
+// and stored alongside the ciphertext. Rotating the key provider only requires re-wrapping the data key, which happens
+// the next time each checkpoint is written.
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource/config"
+)
+
+// envelopeVersion is the version of the envelope format.
+const envelopeVersion = 1
+
+// envelopeMagic begins every envelope, so that encrypted checkpoints can be told apart from plaintext ones without
+// parsing them.
+const envelopeMagic = `{"pulumiEnvelope":`
+
+// KeyProvider wraps and unwraps the data keys with which checkpoints are encrypted.
+type KeyProvider interface {
+	// KeyID identifies the key with which the provider wraps data keys. It is stored in each envelope, so that the
+	// envelope can be opened by a provider with the same ID after the current key has been rotated.
+	KeyID() string
+	// WrapKey encrypts the given data key.
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key that was encrypted by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// envelope is the serialized form of an encrypted checkpoint.
+type envelope struct {
+	Version    int    `json:"pulumiEnvelope"`
+	KeyID      string `json:"keyID"`
+	WrappedKey []byte `json:"wrappedKey"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// IsEncrypted returns true if the given data is an envelope.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(envelopeMagic))
+}
+
+// MagicLength is the number of bytes at the start of some data that IsEncrypted needs to see.
+const MagicLength = len(envelopeMagic)
+
+// Sealer encrypts checkpoints with its current key provider and decrypts checkpoints encrypted by any of its key
+// providers. Data keys are wrapped and unwrapped at most once per sealer, as key management services are slow and may
+// charge for each request.
+type Sealer struct {
+	current  KeyProvider   // the provider that wraps the data keys of newly encrypted checkpoints.
+	previous []KeyProvider // providers whose keys have been rotated out, but may still have encrypted checkpoints.
+
+	m          sync.Mutex
+	dataKey    []byte            // the data key of newly encrypted checkpoints, or nil if none has been made yet.
+	wrappedKey []byte            // dataKey, wrapped by the current provider.
+	unwrapped  map[string][]byte // data keys that have been unwrapped, by key ID and wrapped key.
+}
+
+// NewSealer creates a sealer that encrypts checkpoints with the given current key provider, and that also decrypts
+// checkpoints encrypted with any of the given previous ones.
+func NewSealer(current KeyProvider, previous ...KeyProvider) *Sealer {
+	return &Sealer{current: current, previous: previous, unwrapped: make(map[string][]byte)}
+}
+
+// Encrypt encrypts the given checkpoint with the sealer's current key provider.
+func (s *Sealer) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	dataKey, wrappedKey, err := s.currentKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := config.NewSymmetricCrypter(dataKey).EncryptValue(string(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{
+		Version:    envelopeVersion,
+		KeyID:      s.current.KeyID(),
+		WrappedKey: wrappedKey,
+		Ciphertext: ciphertext,
+	})
+}
+
+// Decrypt decrypts the given checkpoint. Checkpoints that are not encrypted are returned as-is, so that stacks can be
+// migrated to encrypted state by writing them again.
+func (s *Sealer) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, errors.Wrap(err, "malformed encrypted checkpoint")
+	}
+	if env.Version != envelopeVersion {
+		return nil, errors.Errorf("unsupported encrypted checkpoint version %d", env.Version)
+	}
+	dataKey, err := s.unwrapKey(ctx, env.KeyID, env.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := config.NewSymmetricCrypter(dataKey).DecryptValue(env.Ciphertext)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting checkpoint")
+	}
+	return []byte(plaintext), nil
+}
+
+// currentKey returns the data key of newly encrypted checkpoints and its wrapped form, making one if necessary.
+func (s *Sealer) currentKey(ctx context.Context) ([]byte, []byte, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.dataKey == nil {
+		dataKey := make([]byte, config.SymmetricCrypterKeyBytes)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, nil, errors.Wrap(err, "generating a data key")
+		}
+		wrappedKey, err := s.current.WrapKey(ctx, dataKey)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "wrapping a data key with %s", s.current.KeyID())
+		}
+		s.dataKey, s.wrappedKey = dataKey, wrappedKey
+	}
+	return s.dataKey, s.wrappedKey, nil
+}
+
+// unwrapKey returns the data key that the provider with the given ID wrapped. If several providers have that ID, as
+// passphrases do, each is tried in turn.
+func (s *Sealer) unwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	cacheKey := keyID + "\x00" + string(wrappedKey)
+	if dataKey, ok := s.unwrapped[cacheKey]; ok {
+		return dataKey, nil
+	}
+
+	var lastErr error
+	for _, p := range append([]KeyProvider{s.current}, s.previous...) {
+		if p.KeyID() != keyID {
+			continue
+		}
+		dataKey, err := p.UnwrapKey(ctx, wrappedKey)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(dataKey) != config.SymmetricCrypterKeyBytes {
+			lastErr = errors.New("unwrapped data key has the wrong length")
+			continue
+		}
+		s.unwrapped[cacheKey] = dataKey
+		return dataKey, nil
+	}
+	if lastErr != nil {
+		return nil, errors.Wrapf(lastErr, "unwrapping the checkpoint's data key with %s", keyID)
+	}
+	return nil, errors.Errorf("the checkpoint was encrypted with %s, which is not a configured key", keyID)
+}
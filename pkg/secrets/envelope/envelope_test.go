@@ -0,0 +1,130 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envelope
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// xorKeyProvider is a key provider for tests that "wraps" data keys by XORing them with a single byte, and counts the
+// keys it wraps and unwraps.
+type xorKeyProvider struct {
+	id                 string
+	mask               byte
+	wrapped, unwrapped int
+}
+
+func (p *xorKeyProvider) KeyID() string {
+	return p.id
+}
+
+func (p *xorKeyProvider) xor(key []byte) []byte {
+	result := make([]byte, len(key))
+	for i, b := range key {
+		result[i] = b ^ p.mask
+	}
+	return result
+}
+
+func (p *xorKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	p.wrapped++
+	return p.xor(key), nil
+}
+
+func (p *xorKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	p.unwrapped++
+	if len(wrapped) == 0 {
+		return nil, errors.New("empty key")
+	}
+	return p.xor(wrapped), nil
+}
+
+func TestSealerRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	key := &xorKeyProvider{id: "test://a", mask: 0x5a}
+	sealer := NewSealer(key)
+
+	plaintext := []byte(`{"version":3,"checkpoint":{"stack":"dev"}}`)
+	sealed, err := sealer.Encrypt(ctx, plaintext)
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.False(t, IsEncrypted(plaintext))
+	assert.NotContains(t, string(sealed), "checkpoint")
+
+	opened, err := sealer.Decrypt(ctx, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// The data key is wrapped once, and the envelope opened without unwrapping it again.
+	_, err = sealer.Encrypt(ctx, plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, key.wrapped)
+
+	// Plaintext passes through, so that unencrypted checkpoints can still be read.
+	opened, err = sealer.Decrypt(ctx, plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+}
+
+func TestSealerRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey := &xorKeyProvider{id: "test://old", mask: 0x11}
+	newKey := &xorKeyProvider{id: "test://new", mask: 0x22}
+
+	plaintext := []byte(`{"version":3}`)
+	sealed, err := NewSealer(oldKey).Encrypt(ctx, plaintext)
+	assert.NoError(t, err)
+
+	// A sealer whose current key is the new one still opens checkpoints sealed with the old one...
+	rotated := NewSealer(newKey, oldKey)
+	opened, err := rotated.Decrypt(ctx, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// ...but seals them again with the new one.
+	resealed, err := rotated.Encrypt(ctx, opened)
+	assert.NoError(t, err)
+	assert.Contains(t, string(resealed), "test://new")
+	opened, err = NewSealer(newKey).Decrypt(ctx, resealed)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// Once the old key is no longer configured, its checkpoints cannot be opened.
+	_, err = NewSealer(newKey).Decrypt(ctx, sealed)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "test://old"))
+	}
+}
+
+func TestPassphraseKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	plaintext := []byte(`{"version":3}`)
+	sealed, err := NewSealer(NewPassphraseKeyProvider("old phrase")).Encrypt(ctx, plaintext)
+	assert.NoError(t, err)
+
+	// Passphrases share a key ID, so each configured passphrase is tried in turn.
+	opened, err := NewSealer(NewPassphraseKeyProvider("new phrase"), NewPassphraseKeyProvider("old phrase")).
+		Decrypt(ctx, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	_, err = NewSealer(NewPassphraseKeyProvider("wrong phrase")).Decrypt(ctx, sealed)
+	assert.Error(t, err)
+}
@@ -0,0 +1,256 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/secrets"
+	_ "gocloud.dev/secrets/awskms" // registers the awskms:// scheme.
+	_ "gocloud.dev/secrets/gcpkms" // registers the gcpkms:// scheme.
+
+	"github.com/pulumi/pulumi/pkg/resource/config"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// PassphraseEnvVar names the environment variable from which passphrase key providers read their passphrase, unless
+// their URL names another.
+const PassphraseEnvVar = "PULUMI_CONFIG_PASSPHRASE"
+
+// OpenKeyProvider opens the key provider with the given URL:
+//
+//	passphrase://[?env=VAR]                 a passphrase read from VAR, or PULUMI_CONFIG_PASSPHRASE by default
+//	awskms://<key ID or alias>[?region=R]   an AWS KMS key
+//	gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>   a GCP KMS key
+//	hashivault://<key>[?mount=M]            a key of the Vault transit engine at VAULT_ADDR, mounted at M or transit
+//
+// Vault is authenticated with the token in VAULT_TOKEN; the cloud key management services use their SDKs' default
+// credentials.
+func OpenKeyProvider(rawurl string) (KeyProvider, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key URL %q", rawurl)
+	}
+
+	switch u.Scheme {
+	case "passphrase":
+		env := u.Query().Get("env")
+		if env == "" {
+			env = PassphraseEnvVar
+		}
+		phrase := os.Getenv(env)
+		if phrase == "" {
+			return nil, errors.Errorf("%s must be set to use the key %s", env, rawurl)
+		}
+		return NewPassphraseKeyProvider(phrase), nil
+	case "awskms", "gcpkms":
+		return &keeperKeyProvider{url: rawurl}, nil
+	case "hashivault":
+		addr := os.Getenv("VAULT_ADDR")
+		if addr == "" {
+			return nil, errors.Errorf("VAULT_ADDR must be set to use the key %s", rawurl)
+		}
+		mount := u.Query().Get("mount")
+		if mount == "" {
+			mount = "transit"
+		}
+		return &vaultKeyProvider{
+			id:    rawurl,
+			addr:  strings.TrimRight(addr, "/"),
+			token: os.Getenv("VAULT_TOKEN"),
+			mount: mount,
+			key:   u.Host + u.Path,
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown key URL scheme %q; expected one of: passphrase, awskms, gcpkms, hashivault",
+			u.Scheme)
+	}
+}
+
+// NewPassphraseKeyProvider returns a key provider that wraps data keys with a key derived from the given passphrase.
+// All passphrase providers have the same key ID, so a checkpoint encrypted with a passphrase that has been rotated out
+// is opened by trying each configured passphrase in turn.
+func NewPassphraseKeyProvider(phrase string) KeyProvider {
+	return passphraseKeyProvider{phrase: phrase}
+}
+
+type passphraseKeyProvider struct {
+	phrase string
+}
+
+func (p passphraseKeyProvider) KeyID() string {
+	return "passphrase"
+}
+
+// WrapKey encrypts the data key with a key derived from the passphrase and a fresh salt, which is stored with it as
+// `<salt>:<encrypted key>`.
+func (p passphraseKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	crypter := config.NewSymmetricCrypterFromPassphrase(p.phrase, salt)
+	wrapped, err := crypter.EncryptValue(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(salt) + ":" + wrapped), nil
+}
+
+func (p passphraseKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	parts := strings.SplitN(string(wrapped), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("malformed wrapped key")
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed wrapped key")
+	}
+	encoded, err := config.NewSymmetricCrypterFromPassphrase(p.phrase, salt).DecryptValue(parts[1])
+	if err != nil {
+		return nil, errors.New("incorrect passphrase")
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// keeperKeyProvider wraps data keys with a cloud key management service, by way of a Go CDK keeper. The keeper is
+// opened when it is first used.
+type keeperKeyProvider struct {
+	url string
+
+	once   sync.Once
+	keeper *secrets.Keeper
+	err    error
+}
+
+func (p *keeperKeyProvider) KeyID() string {
+	return p.url
+}
+
+func (p *keeperKeyProvider) open(ctx context.Context) (*secrets.Keeper, error) {
+	p.once.Do(func() {
+		p.keeper, p.err = secrets.OpenKeeper(ctx, p.url)
+	})
+	return p.keeper, p.err
+}
+
+func (p *keeperKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	keeper, err := p.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keeper.Encrypt(ctx, key)
+}
+
+func (p *keeperKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	keeper, err := p.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keeper.Decrypt(ctx, wrapped)
+}
+
+// vaultKeyProvider wraps data keys with a key of HashiCorp Vault's transit secrets engine, which it calls over HTTP.
+type vaultKeyProvider struct {
+	id    string // the URL from which the provider was opened.
+	addr  string // the address of the Vault server.
+	token string // the token with which requests are authenticated.
+	mount string // the path at which the transit engine is mounted.
+	key   string // the name of the transit key.
+}
+
+func (p *vaultKeyProvider) KeyID() string {
+	return p.id
+}
+
+func (p *vaultKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	data, err := p.transit(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data["ciphertext"]), nil
+}
+
+func (p *vaultKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	data, err := p.transit(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)})
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(data["plaintext"])
+}
+
+// transit makes a request of the given operation to the transit engine, and returns the data of its response.
+func (p *vaultKeyProvider) transit(ctx context.Context, op string, body map[string]string) (map[string]string, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", p.addr, p.mount, op, p.key)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "calling Vault at %s", p.addr)
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	var result struct {
+		Data   map[string]string `json:"data"`
+		Errors []string          `json:"errors"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, errors.Wrap(err, "malformed response from Vault")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Vault %s failed with status %d: %s", op, resp.StatusCode,
+			strings.Join(result.Errors, "; "))
+	}
+	return result.Data, nil
+}
+
+// OpenSealer opens a sealer whose current key provider has the given URL, and that can also decrypt checkpoints
+// encrypted by the key providers with the given previous URLs.
+func OpenSealer(current string, previous []string) (*Sealer, error) {
+	cur, err := OpenKeyProvider(current)
+	if err != nil {
+		return nil, err
+	}
+	var prev []KeyProvider
+	for _, rawurl := range previous {
+		var p KeyProvider
+		if p, err = OpenKeyProvider(rawurl); err != nil {
+			return nil, err
+		}
+		prev = append(prev, p)
+	}
+	return NewSealer(cur, prev...), nil
+}
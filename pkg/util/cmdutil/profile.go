@@ -23,6 +23,8 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/pulumi/pulumi/pkg/backend/stats"
+	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 )
 
@@ -46,7 +48,7 @@ func InitProfiling(prefix string) error {
 	return nil
 }
 
-func CloseProfiling(prefix string) error {
+func CloseProfiling(prefix string, assets *resource.AssetCache) error {
 	pprof.StopCPUProfile()
 	trace.Stop()
 
@@ -61,5 +63,30 @@ func CloseProfiling(prefix string) error {
 		return errors.Wrap(err, "could not write memory profile")
 	}
 
+	// Also record how many round trips were made to the state backend, and how long they took, so that backend
+	// latency can be told apart from time spent elsewhere.
+	be, err := os.Create(fmt.Sprintf("%s.%v.backend", prefix, os.Getpid()))
+	if err != nil {
+		return errors.Wrap(err, "could not create backend statistics")
+	}
+	defer contract.IgnoreClose(be)
+
+	if err = stats.Write(be); err != nil {
+		return errors.Wrap(err, "could not write backend statistics")
+	}
+
+	// And record how effective the asset cache was, if there is one.
+	if assets != nil {
+		f, err := os.Create(fmt.Sprintf("%s.%v.assets", prefix, os.Getpid()))
+		if err != nil {
+			return errors.Wrap(err, "could not create asset cache statistics")
+		}
+		defer contract.IgnoreClose(f)
+
+		if err = assets.Stats().Write(f); err != nil {
+			return errors.Wrap(err, "could not write asset cache statistics")
+		}
+	}
+
 	return nil
 }
@@ -24,7 +24,8 @@ import (
 	"github.com/uber/jaeger-client-go/transport/zipkin"
 )
 
-// TracingEndpoint is the Zipkin-compatible tracing endpoint where tracing data will be sent.
+// TracingEndpoint is the tracing endpoint where tracing data will be sent. Endpoints whose path is /v1/traces are
+// sent spans using OTLP/HTTP; all others are sent spans using the Zipkin protocol.
 var TracingEndpoint string
 var TracingRootSpan opentracing.Span
 
@@ -44,15 +45,21 @@ func InitTracing(name, rootSpanName, tracingEndpoint string) {
 	// Store the tracing endpoint
 	TracingEndpoint = tracingEndpoint
 
-	// Jaeger tracer can be initialized with a transport that will
-	// report tracing Spans to a Zipkin backend
-	transport, err := zipkin.NewHTTPTransport(
-		tracingEndpoint,
-		zipkin.HTTPBatchSize(1),
-		zipkin.HTTPLogger(jaeger.StdLogger),
-	)
-	if err != nil {
-		log.Fatalf("Cannot initialize HTTP transport: %v", err)
+	// Jaeger tracer can be initialized with a transport that will report tracing Spans to an OTLP collector, if the
+	// endpoint is an OTLP/HTTP traces endpoint, or to a Zipkin backend otherwise.
+	var transport jaeger.Transport
+	if isOTLPEndpoint(tracingEndpoint) {
+		transport = newOTLPTransport(name, tracingEndpoint)
+	} else {
+		zipkinTransport, err := zipkin.NewHTTPTransport(
+			tracingEndpoint,
+			zipkin.HTTPBatchSize(1),
+			zipkin.HTTPLogger(jaeger.StdLogger),
+		)
+		if err != nil {
+			log.Fatalf("Cannot initialize HTTP transport: %v", err)
+		}
+		transport = zipkinTransport
 	}
 
 	// create Jaeger tracer
@@ -0,0 +1,250 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	jaeger "github.com/uber/jaeger-client-go"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// otlpTracesPath is the path at which OTLP/HTTP collectors, such as the OpenTelemetry Collector, Jaeger, and Tempo,
+// receive traces. Tracing endpoints with this path are sent spans using OTLP rather than Zipkin.
+const otlpTracesPath = "/v1/traces"
+
+// otlpBatchSize is the number of spans that an OTLP transport buffers before sending them. The reporter also flushes
+// the buffer once a second, so spans are never held for long.
+const otlpBatchSize = 100
+
+// isOTLPEndpoint returns true if the given tracing endpoint is an OTLP/HTTP traces endpoint.
+func isOTLPEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	return err == nil && strings.TrimSuffix(u.Path, "/") == otlpTracesPath
+}
+
+// otlpTransport is a jaeger.Transport that sends spans to an OTLP/HTTP endpoint using the JSON encoding of the OTLP
+// protocol, so that spans can be sent to any OpenTelemetry collector without a Zipkin receiver.
+type otlpTransport struct {
+	service  string       // the name of the service that reports the spans.
+	endpoint string       // the OTLP/HTTP traces endpoint.
+	client   *http.Client // the client with which to send spans.
+
+	m     sync.Mutex
+	spans []otlpSpan // the spans that have yet to be sent.
+}
+
+var _ jaeger.Transport = (*otlpTransport)(nil)
+
+func newOTLPTransport(service, endpoint string) *otlpTransport {
+	return &otlpTransport{
+		service:  service,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Append buffers the given span, sending the buffer if it is full.
+func (t *otlpTransport) Append(span *jaeger.Span) (int, error) {
+	t.m.Lock()
+	t.spans = append(t.spans, makeOTLPSpan(span))
+	full := len(t.spans) >= otlpBatchSize
+	t.m.Unlock()
+
+	if full {
+		return t.Flush()
+	}
+	return 0, nil
+}
+
+// Flush sends any buffered spans, returning the number of spans that were sent.
+func (t *otlpTransport) Flush() (int, error) {
+	t.m.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.m.Unlock()
+
+	if len(spans) == 0 {
+		return 0, nil
+	}
+
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{makeOTLPAttribute("service.name", t.service)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/pulumi/pulumi"},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Wrapf(err, "sending %d spans to %s", len(spans), t.endpoint)
+	}
+	defer contract.IgnoreClose(resp.Body)
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	contract.IgnoreError(err)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, errors.Errorf("sending %d spans to %s: %s", len(spans), t.endpoint, resp.Status)
+	}
+	return len(spans), nil
+}
+
+// Close sends any buffered spans.
+func (t *otlpTransport) Close() error {
+	_, err := t.Flush()
+	return err
+}
+
+// The types below are the subset of the OTLP trace protocol's JSON encoding that is needed to send spans. Note that
+// OTLP encodes trace and span IDs as hex strings and 64-bit integers as decimal strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code"`
+}
+
+const (
+	otlpSpanKindInternal = 1 // SPAN_KIND_INTERNAL
+	otlpStatusCodeError  = 2 // STATUS_CODE_ERROR
+)
+
+// makeOTLPSpan converts the given finished Jaeger span to an OTLP span. A span tagged with `error: true` is given an
+// error status.
+func makeOTLPSpan(span *jaeger.Span) otlpSpan {
+	ctx := span.Context().(jaeger.SpanContext)
+	start := span.StartTime()
+	end := start.Add(span.Duration())
+
+	s := otlpSpan{
+		TraceID:           fmt.Sprintf("%016x%016x", ctx.TraceID().High, ctx.TraceID().Low),
+		SpanID:            fmt.Sprintf("%016x", uint64(ctx.SpanID())),
+		Name:              span.OperationName(),
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+	}
+	if parent := ctx.ParentID(); parent != 0 {
+		s.ParentSpanID = fmt.Sprintf("%016x", uint64(parent))
+	}
+	for key, value := range span.Tags() {
+		if key == "error" {
+			if isErr, ok := value.(bool); ok && isErr {
+				s.Status = &otlpStatus{Code: otlpStatusCodeError}
+			}
+		}
+		s.Attributes = append(s.Attributes, makeOTLPAttribute(key, value))
+	}
+	return s
+}
+
+// makeOTLPAttribute converts the given span tag to an OTLP attribute. Values of types that OTLP cannot represent
+// directly are formatted as strings.
+func makeOTLPAttribute(key string, value interface{}) otlpAttribute {
+	var v otlpAnyValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		i := strconv.FormatInt(int64(value), 10)
+		v.IntValue = &i
+	case int32:
+		i := strconv.FormatInt(int64(value), 10)
+		v.IntValue = &i
+	case int64:
+		i := strconv.FormatInt(value, 10)
+		v.IntValue = &i
+	case uint16:
+		i := strconv.FormatUint(uint64(value), 10)
+		v.IntValue = &i
+	case uint32:
+		i := strconv.FormatUint(uint64(value), 10)
+		v.IntValue = &i
+	case float32:
+		f := float64(value)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &value
+	default:
+		str := fmt.Sprintf("%v", value)
+		v.StringValue = &str
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
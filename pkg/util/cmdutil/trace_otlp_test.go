@@ -0,0 +1,109 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmdutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+func TestIsOTLPEndpoint(t *testing.T) {
+	assert.True(t, isOTLPEndpoint("http://localhost:4318/v1/traces"))
+	assert.True(t, isOTLPEndpoint("https://collector.example.com/v1/traces/"))
+	assert.False(t, isOTLPEndpoint("http://localhost:9411/api/v2/spans"))
+	assert.False(t, isOTLPEndpoint("http://localhost:9411"))
+}
+
+func TestOTLPTransport(t *testing.T) {
+	var m sync.Mutex
+	var requests []otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req otlpRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		m.Lock()
+		requests = append(requests, req)
+		m.Unlock()
+	}))
+	defer server.Close()
+
+	tracer, closer := jaeger.NewTracer("pulumi-cli", jaeger.NewConstSampler(true),
+		jaeger.NewRemoteReporter(newOTLPTransport("pulumi-cli", server.URL+otlpTracesPath)))
+
+	parent := tracer.StartSpan("parent")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()), opentracing.Tags{
+		"pulumi.urn":         "urn:pulumi:stack::proj::pkg:m:typ::res",
+		"pulumi.duration_ms": 1.5,
+		"error":              true,
+	})
+	child.Finish()
+	parent.Finish()
+
+	// Closing the tracer flushes the spans.
+	assert.NoError(t, closer.Close())
+
+	m.Lock()
+	defer m.Unlock()
+	var spans []otlpSpan
+	for _, req := range requests {
+		if assert.Len(t, req.ResourceSpans, 1) {
+			rs := req.ResourceSpans[0]
+			assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+			assert.Equal(t, "pulumi-cli", *rs.Resource.Attributes[0].Value.StringValue)
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+
+	assert.Equal(t, "child", childSpan.Name)
+	assert.Len(t, childSpan.TraceID, 32)
+	assert.Len(t, childSpan.SpanID, 16)
+	assert.Equal(t, parentSpan.TraceID, childSpan.TraceID)
+	assert.Equal(t, parentSpan.SpanID, childSpan.ParentSpanID)
+	assert.Equal(t, "", parentSpan.ParentSpanID)
+	assert.NotEqual(t, "", childSpan.StartTimeUnixNano)
+	if assert.NotNil(t, childSpan.Status) {
+		assert.Equal(t, otlpStatusCodeError, childSpan.Status.Code)
+	}
+	assert.Nil(t, parentSpan.Status)
+
+	attrs := map[string]otlpAnyValue{}
+	for _, attr := range childSpan.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if assert.NotNil(t, attrs["pulumi.urn"].StringValue) {
+		assert.Equal(t, "urn:pulumi:stack::proj::pkg:m:typ::res", *attrs["pulumi.urn"].StringValue)
+	}
+	if assert.NotNil(t, attrs["pulumi.duration_ms"].DoubleValue) {
+		assert.Equal(t, 1.5, *attrs["pulumi.duration_ms"].DoubleValue)
+	}
+	if assert.NotNil(t, attrs["error"].BoolValue) {
+		assert.True(t, *attrs["error"].BoolValue)
+	}
+}
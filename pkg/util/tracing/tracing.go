@@ -14,7 +14,24 @@
 
 package tracing
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Tags attached to the spans the engine creates for steps, provider calls, and snapshot writes.
+const (
+	// URNTag is the URN of the resource being operated upon.
+	URNTag = "pulumi.urn"
+	// OpTag is the step operation or provider method being performed.
+	OpTag = "pulumi.op"
+	// DurationTag is the time the operation took, in milliseconds.
+	DurationTag = "pulumi.duration_ms"
+)
 
 // tracingOptionsKey is the value used as the context key for TracingOptions.
 var tracingOptionsKey struct{}
@@ -39,3 +56,28 @@ func OptionsFromContext(ctx context.Context) Options {
 	opts, _ := ctx.Value(tracingOptionsKey).(Options)
 	return opts
 }
+
+// Span is an OpenTracing span that records its duration as a tag when it ends, so that operations can be filtered
+// and aggregated by tracing backends that only index tags.
+type Span struct {
+	opentracing.Span
+	start time.Time
+}
+
+// StartSpan starts a span with the given operation name and tags as a child of the span in ctx, if any, and returns
+// the span along with a context that carries it.
+func StartSpan(ctx context.Context, operationName string, tags opentracing.Tags) (*Span, context.Context) {
+	start := time.Now()
+	span, ctx := opentracing.StartSpanFromContext(ctx, operationName, tags, opentracing.StartTime(start))
+	return &Span{Span: span, start: start}, ctx
+}
+
+// End records the span's duration and the error that ended the operation, if any, and finishes the span.
+func (s *Span) End(err error) {
+	s.SetTag(DurationTag, float64(time.Since(s.start))/float64(time.Millisecond))
+	if err != nil {
+		ext.Error.Set(s.Span, true)
+		s.LogFields(log.Error(err))
+	}
+	s.Finish()
+}
@@ -0,0 +1,54 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpans(t *testing.T) {
+	tracer := mocktracer.New()
+	oldTracer := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(oldTracer)
+
+	parent, ctx := StartSpan(context.Background(), "parent", nil)
+	child, _ := StartSpan(ctx, "child", opentracing.Tags{
+		URNTag: "urn:pulumi:stack::proj::pkg:m:typ::res",
+		OpTag:  "create",
+	})
+	child.End(errors.New("oops"))
+	parent.End(nil)
+
+	spans := tracer.FinishedSpans()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	assert.Equal(t, "child", childSpan.OperationName)
+	assert.Equal(t, parentSpan.SpanContext.SpanID, childSpan.ParentID)
+	assert.Equal(t, "urn:pulumi:stack::proj::pkg:m:typ::res", childSpan.Tag(URNTag))
+	assert.Equal(t, "create", childSpan.Tag(OpTag))
+	assert.IsType(t, float64(0), childSpan.Tag(DurationTag))
+	assert.Equal(t, true, childSpan.Tag("error"))
+	assert.Nil(t, parentSpan.Tag("error"))
+	assert.NotNil(t, parentSpan.Tag(DurationTag))
+}
@@ -29,18 +29,32 @@ import (
 )
 
 const (
+	// ArtifactDir is the name of the directory containing the contents of the artifacts attached to resources.
+	ArtifactDir = "artifacts"
+	// AssetCacheDir is the name of the directory containing cached asset hashes and archive contents.
+	AssetCacheDir = "assets"
 	// BackupDir is the name of the folder where backup stack information is stored.
 	BackupDir = "backups"
 	// BookkeepingDir is the name of our bookeeping folder, we store state here (like .git for git).
 	BookkeepingDir = ".pulumi"
+	// CheckCacheDir is the name of the directory containing the cached results of the checks made by previews.
+	CheckCacheDir = "checks"
 	// ConfigDir is the name of the folder that holds local configuration information.
 	ConfigDir = "config"
+	// FreezeDir is the name of the directory that holds the freezes on stacks.
+	FreezeDir = "freezes"
 	// GitDir is the name of the folder git uses to store information.
 	GitDir = ".git"
 	// HistoryDir is the name of the directory that holds historical information for projects.
 	HistoryDir = "history"
+	// LockDir is the name of the directory that holds the locks on stacks' state.
+	LockDir = "locks"
+	// PlanCacheDir is the name of the directory containing cached preview plans.
+	PlanCacheDir = "plans"
 	// PluginDir is the name of the directory containing plugins.
 	PluginDir = "plugins"
+	// ShardDir is the name of the directory that holds the resource records of sharded checkpoints.
+	ShardDir = "shards"
 	// StackDir is the name of the directory that holds stack information for projects.
 	StackDir = "stacks"
 	// TemplateDir is the name of the directory containing templates.
@@ -141,6 +155,32 @@ func SaveProjectStack(stackName tokens.QName, stack *ProjectStack) error {
 	return stack.Save(path)
 }
 
+// FindProjects walks the directory tree rooted at the given path and returns the paths of all project files found
+// within it. Hidden directories and directories containing installed dependencies (e.g. node_modules) are skipped.
+func FindProjects(root string) ([]string, error) {
+	var projects []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isProject(path) {
+			projects = append(projects, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
 // isProject returns true if the path references what appears to be a valid project.  If problems are detected -- like
 // an incorrect extension -- they are logged to the provided diag.Sink (if non-nil).
 func isProject(path string) bool {
@@ -182,3 +222,30 @@ func GetCachedVersionFilePath() (string, error) {
 
 	return filepath.Join(user.HomeDir, BookkeepingDir, CachedVersionFile), nil
 }
+
+// GetPlanCacheDir returns the directory in which the CLI caches the plans computed by previews.
+func GetPlanCacheDir() (string, error) {
+	u, err := user.Current()
+	if u == nil || err != nil {
+		return "", errors.Wrapf(err, "getting user home directory")
+	}
+	return filepath.Join(u.HomeDir, BookkeepingDir, PlanCacheDir), nil
+}
+
+// GetCheckCacheDir returns the directory in which the CLI caches the results of the checks made by previews.
+func GetCheckCacheDir() (string, error) {
+	u, err := user.Current()
+	if u == nil || err != nil {
+		return "", errors.Wrapf(err, "getting user home directory")
+	}
+	return filepath.Join(u.HomeDir, BookkeepingDir, CheckCacheDir), nil
+}
+
+// GetAssetCacheDir returns the directory in which the CLI caches the hashes of assets and the contents of archives.
+func GetAssetCacheDir() (string, error) {
+	u, err := user.Current()
+	if u == nil || err != nil {
+		return "", errors.Wrapf(err, "getting user home directory")
+	}
+	return filepath.Join(u.HomeDir, BookkeepingDir, AssetCacheDir), nil
+}
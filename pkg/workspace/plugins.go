@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"os/exec"
 	"os/user"
@@ -32,12 +31,11 @@ import (
 
 	"github.com/blang/semver"
 	"github.com/djherbis/times"
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/util/contract"
-	"github.com/pulumi/pulumi/pkg/util/httputil"
 	"github.com/pulumi/pulumi/pkg/util/logging"
-	"github.com/pulumi/pulumi/pkg/version"
 )
 
 const (
@@ -168,6 +166,12 @@ func (info *PluginInfo) SetFileMetadata(path string) error {
 }
 
 // Download fetches an io.ReadCloser for this plugin and also returns the size of the response (if known).
+//
+// The plugin is downloaded from the first of its sources that has it: the mirrors named by PULUMI_PLUGIN_MIRRORS if
+// that is set, or else the plugin's server. If PULUMI_PLUGIN_CHECKSUM_KEY is set, the download is verified against its
+// source's signed checksum manifest; otherwise, if mirrors are set, it is verified against its mirror's manifest,
+// which need not be signed. A *PluginVerificationError is returned if verification fails. If neither is set, the
+// download is not verified; see PluginDownloadWarning.
 func (info PluginInfo) Download() (io.ReadCloser, int64, error) {
 	// Figure out the OS/ARCH pair for the download URL.
 	var os string
@@ -185,32 +189,37 @@ func (info PluginInfo) Download() (io.ReadCloser, int64, error) {
 		return nil, -1, errors.Errorf("unsupported plugin architecture: %s", runtime.GOARCH)
 	}
 
-	// If the plugin has a server, associated with it, download from there.  Otherwise use the "default" location, which
-	// is hosted by Pulumi.
-	serverURL := info.ServerURL
-	if serverURL == "" {
-		serverURL = "https://api.pulumi.com/releases/plugins"
-	}
-
-	endpoint := fmt.Sprintf("%s/pulumi-%s-%s-v%s-%s-%s.tar.gz", serverURL, info.Kind, info.Name, info.Version, os, arch)
-	req, err := http.NewRequest("GET", endpoint, nil)
+	sources, err := getPluginSources(info)
 	if err != nil {
 		return nil, -1, err
 	}
-
-	userAgent := fmt.Sprintf("pulumi-cli/1 (%s; %s)", version.Version, runtime.GOOS)
-	req.Header.Set("User-Agent", userAgent)
-
-	resp, err := httputil.DoWithRetry(req, http.DefaultClient)
+	key, err := getPluginChecksumKey()
 	if err != nil {
 		return nil, -1, err
 	}
+	verify := key != nil || pluginMirrorsConfigured()
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, -1, errors.Errorf("%d HTTP error fetching plugin from %s", resp.StatusCode, endpoint)
-	}
+	file := fmt.Sprintf("pulumi-%s-%s-v%s-%s-%s.tar.gz", info.Kind, info.Name, info.Version, os, arch)
+	var result error
+	for _, source := range sources {
+		tarball, size, err := source.open(file)
+		if err != nil {
+			if err == errPluginNotFound {
+				err = errors.Errorf("%s not found", file)
+			}
+			logging.V(7).Infof("failed to download plugin %s from %s: %v", file, source, err)
+			result = multierror.Append(result, errors.Wrapf(err, "downloading from %s", source))
+			continue
+		}
 
-	return resp.Body, resp.ContentLength, nil
+		// A plugin that fails verification is never installed, even if another source might have a good copy: the
+		// failure means that a source the user trusts is serving something unexpected.
+		if verify {
+			return verifyPluginTarball(source, file, tarball, key)
+		}
+		return tarball, size, nil
+	}
+	return nil, -1, errors.Wrapf(result, "failed to download plugin %s", info)
 }
 
 // Install installs a plugin's tarball into the cache.  It validates that plugin names are in the expected format.
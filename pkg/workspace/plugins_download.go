@@ -0,0 +1,327 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/httputil"
+	"github.com/pulumi/pulumi/pkg/version"
+)
+
+const (
+	// PluginMirrorsEnvVar names the environment variable that lists the locations from which plugins are downloaded.
+	// The value is a comma-separated list of HTTP(S) URLs and local directories (either paths or file:// URLs), which
+	// are tried in order. When it is set, plugins are never downloaded from any other location, and every downloaded
+	// plugin must match the checksum recorded for it in its mirror's checksum manifest.
+	PluginMirrorsEnvVar = "PULUMI_PLUGIN_MIRRORS"
+
+	// PluginChecksumKeyEnvVar names the environment variable that holds the base64-encoded Ed25519 public key that
+	// signs plugin checksum manifests. When it is set, every downloaded plugin must match the checksum recorded for it
+	// in its source's manifest, whose signature must also be valid, and is not installed otherwise.
+	PluginChecksumKeyEnvVar = "PULUMI_PLUGIN_CHECKSUM_KEY"
+
+	// PluginChecksumManifest is the name of the file, alongside a source's plugin tarballs, that records their SHA-256
+	// checksums. Each line holds a checksum and a tarball name, in the format written by `sha256sum`.
+	PluginChecksumManifest = "pulumi-plugins.sha256"
+	// PluginChecksumSignature is the name of the file that holds the base64-encoded Ed25519 signature of the
+	// checksum manifest.
+	PluginChecksumSignature = PluginChecksumManifest + ".sig"
+
+	defaultPluginServerURL = "https://api.pulumi.com/releases/plugins"
+)
+
+// errPluginNotFound is returned by a plugin source that does not have the requested file.
+var errPluginNotFound = errors.New("not found")
+
+// PluginVerificationError is returned when a downloaded plugin does not match its signed checksum, or when its
+// checksum cannot be verified at all.
+type PluginVerificationError struct {
+	Tarball string // the name of the plugin tarball.
+	Source  string // the location that the tarball was downloaded from.
+	Reason  string // why verification failed.
+}
+
+func (err *PluginVerificationError) Error() string {
+	return fmt.Sprintf("refusing to install plugin %s from %s: %s", err.Tarball, err.Source, err.Reason)
+}
+
+// pluginSource is a location from which plugin tarballs may be downloaded.
+type pluginSource interface {
+	// open opens the named file, returning its contents and size (or -1 if the size is unknown). If the file does not
+	// exist, open returns errPluginNotFound.
+	open(file string) (io.ReadCloser, int64, error)
+	// String returns a description of the source for use in messages.
+	String() string
+}
+
+// pluginMirrorsConfigured returns true if plugins are downloaded only from the mirrors named by PULUMI_PLUGIN_MIRRORS.
+func pluginMirrorsConfigured() bool {
+	return os.Getenv(PluginMirrorsEnvVar) != ""
+}
+
+// getPluginSources returns the locations from which the given plugin may be downloaded, in the order they should be
+// tried.
+func getPluginSources(info PluginInfo) ([]pluginSource, error) {
+	if !pluginMirrorsConfigured() {
+		// If the plugin has a server associated with it, download from there. Otherwise use the "default" location,
+		// which is hosted by Pulumi.
+		serverURL := info.ServerURL
+		if serverURL == "" {
+			serverURL = defaultPluginServerURL
+		}
+		return []pluginSource{httpPluginSource(serverURL)}, nil
+	}
+
+	var sources []pluginSource
+	for _, mirror := range strings.Split(os.Getenv(PluginMirrorsEnvVar), ",") {
+		mirror = strings.TrimSpace(mirror)
+		if mirror == "" {
+			continue
+		}
+		source, err := parsePluginSource(mirror)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", PluginMirrorsEnvVar)
+		}
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return nil, errors.Errorf("%s does not name any plugin mirrors", PluginMirrorsEnvVar)
+	}
+	return sources, nil
+}
+
+// parsePluginSource parses a plugin mirror, which is either an HTTP(S) URL or a local directory.
+func parsePluginSource(mirror string) (pluginSource, error) {
+	u, err := url.Parse(mirror)
+	if err != nil || u.Scheme == "" || filepath.VolumeName(mirror) != "" {
+		return dirPluginSource(mirror), nil
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return httpPluginSource(strings.TrimSuffix(mirror, "/")), nil
+	case "file":
+		return dirPluginSource(filepath.FromSlash(u.Path)), nil
+	default:
+		return nil, errors.Errorf("unsupported plugin mirror %q: expected an http, https, or file URL", mirror)
+	}
+}
+
+// httpPluginSource downloads plugins from a web server.
+type httpPluginSource string
+
+func (s httpPluginSource) open(file string) (io.ReadCloser, int64, error) {
+	endpoint := fmt.Sprintf("%s/%s", s, file)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	userAgent := fmt.Sprintf("pulumi-cli/1 (%s; %s)", version.Version, runtime.GOOS)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httputil.DoWithRetry(req, http.DefaultClient)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		contract.IgnoreClose(resp.Body)
+		return nil, -1, errPluginNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		contract.IgnoreClose(resp.Body)
+		return nil, -1, errors.Errorf("%d HTTP error fetching plugin from %s", resp.StatusCode, endpoint)
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+func (s httpPluginSource) String() string {
+	return string(s)
+}
+
+// dirPluginSource reads plugins from a local directory, such as a copy of a plugin server kept for air-gapped use.
+type dirPluginSource string
+
+func (s dirPluginSource) open(file string) (io.ReadCloser, int64, error) {
+	f, err := os.Open(filepath.Join(string(s), file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, -1, errPluginNotFound
+		}
+		return nil, -1, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		contract.IgnoreClose(f)
+		return nil, -1, err
+	}
+	return f, stat.Size(), nil
+}
+
+func (s dirPluginSource) String() string {
+	return string(s)
+}
+
+// PluginDownloadWarning returns a warning to show before plugins are downloaded if they will be installed without
+// being verified, which is the case when neither PULUMI_PLUGIN_MIRRORS nor PULUMI_PLUGIN_CHECKSUM_KEY is set. It
+// returns the empty string if downloaded plugins are verified.
+func PluginDownloadWarning() string {
+	if pluginMirrorsConfigured() || os.Getenv(PluginChecksumKeyEnvVar) != "" {
+		return ""
+	}
+	return fmt.Sprintf("plugins are downloaded without verifying their checksums; set %s to require that they match "+
+		"a signed checksum manifest, or %s to download them only from mirrors that list their checksums",
+		PluginChecksumKeyEnvVar, PluginMirrorsEnvVar)
+}
+
+// getPluginChecksumKey returns the public key that signs plugin checksum manifests, or nil if manifests need not be
+// signed.
+func getPluginChecksumKey() (ed25519.PublicKey, error) {
+	encoded := os.Getenv(PluginChecksumKeyEnvVar)
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("%s must hold a base64-encoded Ed25519 public key", PluginChecksumKeyEnvVar)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// readPluginChecksums fetches a source's checksum manifest, checks its signature if key is not nil, and returns the
+// checksums it records, keyed by tarball name.
+func readPluginChecksums(source pluginSource, key ed25519.PublicKey) (map[string]string, error) {
+	readAll := func(file string) ([]byte, error) {
+		r, _, err := source.open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer contract.IgnoreClose(r)
+		return ioutil.ReadAll(r)
+	}
+
+	manifest, err := readAll(PluginChecksumManifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", PluginChecksumManifest)
+	}
+	if key != nil {
+		encodedSignature, err := readAll(PluginChecksumSignature)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", PluginChecksumSignature)
+		}
+		signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s", PluginChecksumSignature)
+		}
+		if !ed25519.Verify(key, manifest, signature) {
+			return nil, errors.Errorf("the signature of %s is not valid", PluginChecksumManifest)
+		}
+	}
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, errors.Errorf("malformed line in %s: %q", PluginChecksumManifest, scanner.Text())
+		}
+		// `sha256sum` marks files that it read in binary mode with a leading '*'.
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return checksums, scanner.Err()
+}
+
+// verifyPluginTarball checks a plugin tarball against the checksum recorded for it in its source's manifest, which must
+// be signed by key if key is not nil.
+// Because the tarball must be read in full before it can be verified, it is spooled to a temporary file; the returned
+// reader reads that file and removes it when closed.
+func verifyPluginTarball(source pluginSource, file string, tarball io.ReadCloser,
+	key ed25519.PublicKey) (io.ReadCloser, int64, error) {
+
+	defer contract.IgnoreClose(tarball)
+
+	fail := func(format string, args ...interface{}) (io.ReadCloser, int64, error) {
+		return nil, -1, &PluginVerificationError{
+			Tarball: file,
+			Source:  source.String(),
+			Reason:  fmt.Sprintf(format, args...),
+		}
+	}
+
+	checksums, err := readPluginChecksums(source, key)
+	if err != nil {
+		return fail("%v", err)
+	}
+	expected, ok := checksums[file]
+	if !ok {
+		return fail("%s does not list a checksum for it", PluginChecksumManifest)
+	}
+
+	temp, err := ioutil.TempFile("", file)
+	if err != nil {
+		return nil, -1, errors.Wrap(err, "creating temporary file for plugin download")
+	}
+	spooled := &tempFileReadCloser{File: temp}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), tarball)
+	if err == nil {
+		_, err = temp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		contract.IgnoreClose(spooled)
+		return nil, -1, errors.Wrapf(err, "downloading plugin %s from %s", file, source)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		contract.IgnoreClose(spooled)
+		return fail("its SHA-256 checksum is %s, but %s lists %s", actual, PluginChecksumManifest, expected)
+	}
+	return spooled, size, nil
+}
+
+// tempFileReadCloser is a temporary file that is removed when it is closed.
+type tempFileReadCloser struct {
+	*os.File
+}
+
+func (f *tempFileReadCloser) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+// newPluginMirror creates a directory that serves a single plugin tarball with the given contents. If checksum is not
+// empty, the directory also holds a checksum manifest that lists it for the tarball, which is signed with key if key is
+// not nil.
+func newPluginMirror(t *testing.T, info PluginInfo, contents, checksum string,
+	key ed25519.PrivateKey) string {
+
+	dir, err := ioutil.TempDir("", "plugin-mirror")
+	assert.NoError(t, err)
+
+	file := fmt.Sprintf("pulumi-%s-%s-v%s-%s-%s.tar.gz", info.Kind, info.Name, info.Version, runtime.GOOS,
+		runtime.GOARCH)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(contents), 0600))
+
+	if checksum != "" {
+		manifest := []byte(fmt.Sprintf("%s  %s\n", checksum, file))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, PluginChecksumManifest), manifest, 0600))
+		if key != nil {
+			signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifest))
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, PluginChecksumSignature), []byte(signature), 0600))
+		}
+	}
+	return dir
+}
+
+func setenv(t *testing.T, key, value string) func() {
+	old, had := os.LookupEnv(key)
+	assert.NoError(t, os.Setenv(key, value))
+	return func() {
+		if had {
+			assert.NoError(t, os.Setenv(key, old))
+		} else {
+			assert.NoError(t, os.Unsetenv(key))
+		}
+	}
+}
+
+func TestPluginDownloadFromMirrors(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("plugins are only published for amd64")
+	}
+
+	v := semver.MustParse("1.0.0")
+	info := PluginInfo{Name: "myplugin", Kind: ResourcePlugin, Version: &v}
+
+	sum := sha256.Sum256([]byte("contents"))
+	mirror := newPluginMirror(t, info, "contents", hex.EncodeToString(sum[:]), nil)
+	defer os.RemoveAll(mirror)
+	empty, err := ioutil.TempDir("", "plugin-mirror")
+	assert.NoError(t, err)
+	defer os.RemoveAll(empty)
+
+	// Mirrors are tried in order until one has the plugin.
+	defer setenv(t, PluginMirrorsEnvVar, empty+", "+mirror)()
+	tarball, size, err := info.Download()
+	if assert.NoError(t, err) {
+		contents, err := ioutil.ReadAll(tarball)
+		assert.NoError(t, err)
+		assert.NoError(t, tarball.Close())
+		assert.Equal(t, "contents", string(contents))
+		assert.Equal(t, int64(len("contents")), size)
+	}
+
+	// If no mirror has the plugin, the download fails rather than falling back to the plugin's server.
+	defer setenv(t, PluginMirrorsEnvVar, empty)()
+	_, _, err = info.Download()
+	assert.Error(t, err)
+
+	// Without a checksum key, a mirror's manifest need not be signed, but the plugin must still match it...
+	tampered := newPluginMirror(t, info, "tampered", hex.EncodeToString(sum[:]), nil)
+	defer os.RemoveAll(tampered)
+	defer setenv(t, PluginMirrorsEnvVar, tampered)()
+	_, _, err = info.Download()
+	assert.IsType(t, &PluginVerificationError{}, err)
+
+	// ...and a mirror without a manifest is refused.
+	unlisted := newPluginMirror(t, info, "contents", "", nil)
+	defer os.RemoveAll(unlisted)
+	defer setenv(t, PluginMirrorsEnvVar, unlisted)()
+	_, _, err = info.Download()
+	assert.IsType(t, &PluginVerificationError{}, err)
+}
+
+func TestPluginDownloadWarning(t *testing.T) {
+	defer setenv(t, PluginMirrorsEnvVar, "")()
+	defer setenv(t, PluginChecksumKeyEnvVar, "")()
+	assert.Contains(t, PluginDownloadWarning(), "without verifying their checksums")
+
+	defer setenv(t, PluginMirrorsEnvVar, "/plugins")()
+	assert.Equal(t, "", PluginDownloadWarning())
+
+	defer setenv(t, PluginMirrorsEnvVar, "")()
+	defer setenv(t, PluginChecksumKeyEnvVar, "key")()
+	assert.Equal(t, "", PluginDownloadWarning())
+}
+
+func TestPluginDownloadVerification(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("plugins are only published for amd64")
+	}
+
+	v := semver.MustParse("1.0.0")
+	info := PluginInfo{Name: "myplugin", Kind: ResourcePlugin, Version: &v}
+
+	public, private, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	defer setenv(t, PluginChecksumKeyEnvVar, base64.StdEncoding.EncodeToString(public))()
+
+	sum := sha256.Sum256([]byte("contents"))
+	checksum := hex.EncodeToString(sum[:])
+
+	// A tarball that matches its signed checksum is installed.
+	good := newPluginMirror(t, info, "contents", checksum, private)
+	defer os.RemoveAll(good)
+	defer setenv(t, PluginMirrorsEnvVar, good)()
+	tarball, _, err := info.Download()
+	if assert.NoError(t, err) {
+		contents, err := ioutil.ReadAll(tarball)
+		assert.NoError(t, err)
+		assert.Equal(t, "contents", string(contents))
+		assert.NoError(t, tarball.Close())
+	}
+
+	// A tarball that does not match is rejected, as is one whose manifest was signed by another key, whose manifest is
+	// not signed, or that has no manifest at all.
+	tampered := newPluginMirror(t, info, "tampered", checksum, private)
+	defer os.RemoveAll(tampered)
+	_, other, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	forged := newPluginMirror(t, info, "contents", checksum, other)
+	defer os.RemoveAll(forged)
+	unsigned := newPluginMirror(t, info, "contents", checksum, nil)
+	defer os.RemoveAll(unsigned)
+	unlisted := newPluginMirror(t, info, "contents", "", nil)
+	defer os.RemoveAll(unlisted)
+
+	for _, mirror := range []string{tampered, forged, unsigned, unlisted} {
+		defer setenv(t, PluginMirrorsEnvVar, mirror)()
+		_, _, err = info.Download()
+		assert.IsType(t, &PluginVerificationError{}, err)
+	}
+}
@@ -19,10 +19,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 
+	"github.com/blang/semver"
 	"github.com/pkg/errors"
 
 	"github.com/pulumi/pulumi/pkg/encoding"
@@ -52,12 +54,145 @@ type ProjectTemplateConfigValue struct {
 	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
 }
 
+// StackDependency declares that the stacks of a project depend on the stacks of another project, and must therefore be
+// deployed after them.
+type StackDependency struct {
+	// Project is the name of the project that is depended upon.
+	Project tokens.PackageName `json:"project" yaml:"project"`
+	// Stack is an optional name of the stack that is depended upon. If empty, the stack with the same name as the
+	// dependent stack is used.
+	Stack string `json:"stack,omitempty" yaml:"stack,omitempty"`
+}
+
 // ProjectBackend is a configuration for backend used by project
 type ProjectBackend struct {
 	// URL is optional field to explicitly set backend url
 	URL string `json:"url,omitempty" yaml:"url,omitempty"`
 }
 
+// UpdateDefaults holds default options for the updates, previews, refreshes, and destroys of a project's stacks, so
+// that the options that suit each environment can be kept in source control. Each option that is not set leaves the
+// CLI's own default in place, and options given explicitly on the command line always take precedence.
+// nolint: lll
+type UpdateDefaults struct {
+	// Parallel is the number of resource operations that may run at once.
+	Parallel *int `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	// Refresh, when true, refreshes the stack's state before each update.
+	Refresh *bool `json:"refresh,omitempty" yaml:"refresh,omitempty"`
+	// RequireApprovalForDestructive, when true, requires interactive approval of any update that deletes or replaces
+	// resources, even when changes are otherwise approved automatically.
+	RequireApprovalForDestructive *bool `json:"requireApprovalForDestructive,omitempty" yaml:"requireApprovalForDestructive,omitempty"`
+	// Diff, when true, displays a detailed diff of the changes rather than their progress.
+	Diff *bool `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+// Validate returns an error if these defaults are not valid. The defaults may be nil.
+func (d *UpdateDefaults) Validate() error {
+	if d != nil && d.Parallel != nil && *d.Parallel < 1 {
+		return errors.New("'updateDefaults.parallel' must be at least 1")
+	}
+	return nil
+}
+
+// Merge returns the defaults that result from applying the options set in overrides on top of these. Either may be
+// nil.
+func (d *UpdateDefaults) Merge(overrides *UpdateDefaults) *UpdateDefaults {
+	if d == nil {
+		return overrides
+	}
+	if overrides == nil {
+		return d
+	}
+
+	merged := *d
+	if overrides.Parallel != nil {
+		merged.Parallel = overrides.Parallel
+	}
+	if overrides.Refresh != nil {
+		merged.Refresh = overrides.Refresh
+	}
+	if overrides.RequireApprovalForDestructive != nil {
+		merged.RequireApprovalForDestructive = overrides.RequireApprovalForDestructive
+	}
+	if overrides.Diff != nil {
+		merged.Diff = overrides.Diff
+	}
+	return &merged
+}
+
+// ProjectCheck is a check that the engine runs after it applies an update to one of a project's stacks, to verify
+// that the stack's resources work. Exactly one of Invoke, HTTP, and Output must be set. When a check fails, the update
+// still succeeds, but it is recorded as having succeeded with failing checks.
+type ProjectCheck struct {
+	// Name identifies the check in the update's display and history.
+	Name string `json:"name" yaml:"name"`
+	// Invoke, if set, calls a provider function and compares its results to those expected.
+	Invoke *InvokeCheck `json:"invoke,omitempty" yaml:"invoke,omitempty"`
+	// HTTP, if set, makes an HTTP GET request and checks the status of the response.
+	HTTP *HTTPCheck `json:"http,omitempty" yaml:"http,omitempty"`
+	// Output, if set, names a stack output that the program exports as the result of its own test function. The
+	// check passes if the output is true; if it is a string, the check fails with the string as its message.
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+	// Timeout is an optional limit on how long the check may run, e.g. 30s. It defaults to one minute.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// InvokeCheck calls a provider function once an update has been applied. The function is called through the default
+// provider for its package.
+type InvokeCheck struct {
+	// Function is the token of the function to call, e.g. aws:ec2/getInstance:getInstance.
+	Function tokens.ModuleMember `json:"function" yaml:"function"`
+	// Args are the arguments to pass to the function.
+	Args map[string]interface{} `json:"args,omitempty" yaml:"args,omitempty"`
+	// Expect holds the results that the function must return. Results that are not named here are ignored.
+	Expect map[string]interface{} `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// HTTPCheck makes an HTTP GET request once an update has been applied. Exactly one of URL and URLOutput must be set.
+type HTTPCheck struct {
+	// URL is the URL to request.
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// URLOutput names a stack output whose value is the URL to request.
+	URLOutput string `json:"urlOutput,omitempty" yaml:"urlOutput,omitempty"`
+	// Status is the expected status code of the response. It defaults to 200.
+	Status int `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// Validate returns an error if this check is not valid.
+func (c ProjectCheck) Validate() error {
+	if c.Name == "" {
+		return errors.New("check is missing a 'name' attribute")
+	}
+
+	kinds := 0
+	if c.Invoke != nil {
+		kinds++
+		if c.Invoke.Function == "" {
+			return errors.Errorf("check '%s' is missing an 'invoke.function' attribute", c.Name)
+		}
+	}
+	if c.HTTP != nil {
+		kinds++
+		if (c.HTTP.URL == "") == (c.HTTP.URLOutput == "") {
+			return errors.Errorf("check '%s' must have exactly one of 'http.url' and 'http.urlOutput'", c.Name)
+		}
+	}
+	if c.Output != "" {
+		kinds++
+	}
+	if kinds != 1 {
+		return errors.Errorf("check '%s' must have exactly one of 'invoke', 'http', and 'output'", c.Name)
+	}
+
+	if c.Timeout != "" {
+		if timeout, err := time.ParseDuration(c.Timeout); err != nil || timeout <= 0 {
+			return errors.Errorf("check '%s' has an invalid 'timeout'; it must be a positive duration, e.g. 30s",
+				c.Name)
+		}
+	}
+	return nil
+}
+
 // Project is a Pulumi project manifest.
 //
 // We explicitly add yaml tags (instead of using the default behavior from https://github.com/ghodss/yaml which works
@@ -93,6 +228,26 @@ type Project struct {
 
 	// Backend is an optional backend configuration
 	Backend *ProjectBackend `json:"backend,omitempty" yaml:"backend,omitempty"`
+
+	// StackDependencies is an optional list of other projects whose stacks must be deployed before this project's.
+	StackDependencies []StackDependency `json:"stackDependencies,omitempty" yaml:"stackDependencies,omitempty"`
+
+	// UpdateDefaults is an optional set of default options for operations on this project's stacks.
+	UpdateDefaults *UpdateDefaults `json:"updateDefaults,omitempty" yaml:"updateDefaults,omitempty"`
+
+	// BaseConfig is an optional config bag shared by all of this project's stacks. Each stack's own config takes
+	// precedence over it.
+	BaseConfig config.Map `json:"baseConfig,omitempty" yaml:"baseConfig,omitempty"`
+	// Environments is an optional set of named config bags, each of which may be shared by the stacks that select it.
+	// An environment's config takes precedence over the base config, and a stack's own config over both.
+	Environments map[string]config.Map `json:"environments,omitempty" yaml:"environments,omitempty"`
+
+	// Checks is an optional list of checks to run after each update to one of this project's stacks is applied.
+	Checks []ProjectCheck `json:"checks,omitempty" yaml:"checks,omitempty"`
+
+	// MinimumPulumiVersion is the optional oldest version of the Pulumi CLI that may operate on this project's stacks,
+	// so that everyone on a team uses an engine that behaves the same way.
+	MinimumPulumiVersion string `json:"minimumPulumiVersion,omitempty" yaml:"minimumPulumiVersion,omitempty"`
 }
 
 func (proj *Project) Validate() error {
@@ -102,10 +257,68 @@ func (proj *Project) Validate() error {
 	if proj.Runtime.Name() == "" {
 		return errors.New("project is missing a 'runtime' attribute")
 	}
+	if err := proj.UpdateDefaults.Validate(); err != nil {
+		return err
+	}
+	for _, dep := range proj.StackDependencies {
+		if dep.Project == "" {
+			return errors.New("stack dependency is missing a 'project' attribute")
+		}
+		if dep.Project == proj.Name {
+			return errors.Errorf("project '%s' cannot depend on its own stacks", proj.Name)
+		}
+	}
+
+	// Secret values are encrypted with a key that belongs to a single stack, so they cannot be shared between stacks.
+	if proj.BaseConfig.HasSecureValue() {
+		return errors.New("project 'baseConfig' cannot contain secret values; set them in each stack's config instead")
+	}
+	for name, env := range proj.Environments {
+		if env.HasSecureValue() {
+			return errors.Errorf("environment '%s' cannot contain secret values; set them in each stack's config "+
+				"instead", name)
+		}
+	}
+
+	if proj.MinimumPulumiVersion != "" {
+		if _, err := semver.ParseTolerant(proj.MinimumPulumiVersion); err != nil {
+			return errors.Errorf("project 'minimumPulumiVersion' is not a valid version: %v", err)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, check := range proj.Checks {
+		if err := check.Validate(); err != nil {
+			return err
+		}
+		if names[check.Name] {
+			return errors.Errorf("more than one check is named '%s'", check.Name)
+		}
+		names[check.Name] = true
+	}
 
 	return nil
 }
 
+// StackConfigLayers returns the config bags that the given stack's own config is layered over, ordered from lowest to
+// highest precedence: the project's base config, followed by that of the stack's environment, if it selects one.
+func (proj *Project) StackConfigLayers(ps *ProjectStack) ([]config.Map, error) {
+	contract.Require(ps != nil, "ps")
+
+	var layers []config.Map
+	if len(proj.BaseConfig) > 0 {
+		layers = append(layers, proj.BaseConfig)
+	}
+	if ps.Environment != "" {
+		env, has := proj.Environments[ps.Environment]
+		if !has {
+			return nil, errors.Errorf("environment '%s' is not defined by project '%s'", ps.Environment, proj.Name)
+		}
+		layers = append(layers, env)
+	}
+	return layers, nil
+}
+
 // TrustResourceDependencies returns whether or not this project's runtime can be trusted to accurately report
 // dependencies. All languages supported by Pulumi today do this correctly. This option remains useful when bringing
 // up new Pulumi languages.
@@ -138,6 +351,12 @@ type ProjectStack struct {
 	EncryptionSalt string `json:"encryptionsalt,omitempty" yaml:"encryptionsalt,omitempty"`
 	// Config is an optional config bag.
 	Config config.Map `json:"config,omitempty" yaml:"config,omitempty"`
+	// UpdateDefaults is an optional set of default options for operations on this stack, which take precedence over
+	// the project's.
+	UpdateDefaults *UpdateDefaults `json:"updateDefaults,omitempty" yaml:"updateDefaults,omitempty"`
+	// Environment optionally names one of the project's environments, whose config this stack's config is layered
+	// over.
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
 }
 
 // Save writes a project definition to a file.
@@ -164,6 +383,7 @@ func (ps *ProjectStack) Save(path string) error {
 
 type ProjectRuntimeInfo struct {
 	name    string
+	version string
 	options map[string]interface{}
 }
 
@@ -178,30 +398,46 @@ func (info *ProjectRuntimeInfo) Name() string {
 	return info.name
 }
 
+// Version returns the semver range, if any, that the project requires of its language toolchain (e.g. ">=10.0.0").
+func (info *ProjectRuntimeInfo) Version() string {
+	return info.version
+}
+
+// SetVersion sets the semver range that the project requires of its language toolchain.
+func (info *ProjectRuntimeInfo) SetVersion(version string) {
+	info.version = version
+}
+
 func (info *ProjectRuntimeInfo) Options() map[string]interface{} {
 	return info.options
 }
 
+// payload returns the object form of this runtime section, omitting any attributes that are unset.
+func (info ProjectRuntimeInfo) payload() map[string]interface{} {
+	payload := map[string]interface{}{"name": info.name}
+	if info.version != "" {
+		payload["version"] = info.version
+	}
+	if len(info.options) != 0 {
+		payload["options"] = info.options
+	}
+	return payload
+}
+
 func (info ProjectRuntimeInfo) MarshalYAML() (interface{}, error) {
-	if info.options == nil || len(info.options) == 0 {
+	if info.version == "" && len(info.options) == 0 {
 		return info.name, nil
 	}
 
-	return map[string]interface{}{
-		"name":    info.name,
-		"options": info.options,
-	}, nil
+	return info.payload(), nil
 }
 
 func (info ProjectRuntimeInfo) MarshalJSON() ([]byte, error) {
-	if info.options == nil || len(info.options) == 0 {
+	if info.version == "" && len(info.options) == 0 {
 		return json.Marshal(info.name)
 	}
 
-	return json.Marshal(map[string]interface{}{
-		"name":    info.name,
-		"options": info.options,
-	})
+	return json.Marshal(info.payload())
 }
 
 func (info *ProjectRuntimeInfo) UnmarshalJSON(data []byte) error {
@@ -211,16 +447,18 @@ func (info *ProjectRuntimeInfo) UnmarshalJSON(data []byte) error {
 
 	var payload struct {
 		Name    string                 `json:"name"`
+		Version string                 `json:"version"`
 		Options map[string]interface{} `json:"options"`
 	}
 
 	if err := json.Unmarshal(data, &payload); err == nil {
 		info.name = payload.Name
+		info.version = payload.Version
 		info.options = payload.Options
 		return nil
 	}
 
-	return errors.New("runtime section must be a string or an object with name and options attributes")
+	return errors.New("runtime section must be a string or an object with name, version, and options attributes")
 }
 
 func (info *ProjectRuntimeInfo) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -230,16 +468,18 @@ func (info *ProjectRuntimeInfo) UnmarshalYAML(unmarshal func(interface{}) error)
 
 	var payload struct {
 		Name    string                 `yaml:"name"`
+		Version string                 `yaml:"version"`
 		Options map[string]interface{} `yaml:"options"`
 	}
 
 	if err := unmarshal(&payload); err == nil {
 		info.name = payload.Name
+		info.version = payload.Version
 		info.options = payload.Options
 		return nil
 	}
 
-	return errors.New("runtime section must be a string or an object with name and options attributes")
+	return errors.New("runtime section must be a string or an object with name, version, and options attributes")
 }
 
 // LoadProject reads a project definition from a file.
@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
+
+	"github.com/pulumi/pulumi/pkg/resource/config"
 )
 
 func TestProjectRuntimeInfoRoundtripYAML(t *testing.T) {
@@ -31,8 +33,120 @@ func TestProjectRuntimeInfoRoundtripYAML(t *testing.T) {
 		assert.Equal(t, "nodejs", riRountrip.Name())
 		assert.Equal(t, true, riRountrip.Options()["typescript"])
 		assert.Equal(t, "hello", riRountrip.Options()["stringOption"])
+		assert.Equal(t, "", riRountrip.Version())
+
+		ri = NewProjectRuntimeInfo("python", nil)
+		ri.SetVersion(">=3.6.0")
+		byts, err = marshal(ri)
+		assert.NoError(t, err)
+		riRountrip = ProjectRuntimeInfo{}
+		err = unmarshal(byts, &riRountrip)
+		assert.NoError(t, err)
+		assert.Equal(t, "python", riRountrip.Name())
+		assert.Equal(t, ">=3.6.0", riRountrip.Version())
+		assert.Nil(t, riRountrip.Options())
 	}
 
 	doTest(yaml.Marshal, yaml.Unmarshal)
 	doTest(json.Marshal, json.Unmarshal)
 }
+
+func TestUpdateDefaultsMerge(t *testing.T) {
+	var project, stack UpdateDefaults
+	assert.NoError(t, yaml.Unmarshal([]byte("parallel: 4\nrefresh: true\ndiff: true\n"), &project))
+	assert.NoError(t, yaml.Unmarshal([]byte("refresh: false\nrequireApprovalForDestructive: true\n"), &stack))
+
+	merged := project.Merge(&stack)
+	if assert.NotNil(t, merged.Parallel) {
+		assert.Equal(t, 4, *merged.Parallel)
+	}
+	if assert.NotNil(t, merged.Refresh) {
+		assert.False(t, *merged.Refresh)
+	}
+	if assert.NotNil(t, merged.RequireApprovalForDestructive) {
+		assert.True(t, *merged.RequireApprovalForDestructive)
+	}
+	if assert.NotNil(t, merged.Diff) {
+		assert.True(t, *merged.Diff)
+	}
+
+	// The receiver is left as it was.
+	assert.True(t, *project.Refresh)
+	assert.Nil(t, project.RequireApprovalForDestructive)
+
+	// Either side may be missing.
+	var none *UpdateDefaults
+	assert.Equal(t, &stack, none.Merge(&stack))
+	assert.Equal(t, &project, project.Merge(nil))
+	assert.Nil(t, none.Merge(nil))
+
+	zero := 0
+	assert.Error(t, (&UpdateDefaults{Parallel: &zero}).Validate())
+	assert.NoError(t, none.Validate())
+}
+
+func TestStackConfigLayers(t *testing.T) {
+	var proj Project
+	assert.NoError(t, yaml.Unmarshal([]byte(`name: proj
+runtime: nodejs
+baseConfig:
+  aws:region: us-west-2
+  proj:size: small
+environments:
+  prod:
+    proj:size: large
+  secret:
+    proj:password:
+      secure: c2VjcmV0
+`), &proj))
+	assert.Error(t, proj.Validate())
+	delete(proj.Environments, "secret")
+	assert.NoError(t, proj.Validate())
+
+	layers, err := proj.StackConfigLayers(&ProjectStack{Environment: "prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, []config.Map{proj.BaseConfig, proj.Environments["prod"]}, layers)
+
+	layers, err = proj.StackConfigLayers(&ProjectStack{})
+	assert.NoError(t, err)
+	assert.Equal(t, []config.Map{proj.BaseConfig}, layers)
+
+	_, err = proj.StackConfigLayers(&ProjectStack{Environment: "staging"})
+	assert.Error(t, err)
+}
+
+func TestProjectChecks(t *testing.T) {
+	var proj Project
+	assert.NoError(t, yaml.Unmarshal([]byte(`name: proj
+runtime: nodejs
+checks:
+- name: api
+  http:
+    urlOutput: url
+  timeout: 30s
+- name: bucket
+  invoke:
+    function: aws:s3/getBucket:getBucket
+    args:
+      bucket: my-bucket
+    expect:
+      region: us-west-2
+- name: tests
+  output: testsPassed
+`), &proj))
+	assert.NoError(t, proj.Validate())
+	if assert.Len(t, proj.Checks, 3) {
+		assert.Equal(t, "url", proj.Checks[0].HTTP.URLOutput)
+		assert.Equal(t, "us-west-2", proj.Checks[1].Invoke.Expect["region"])
+		assert.Equal(t, "testsPassed", proj.Checks[2].Output)
+	}
+
+	assert.Error(t, ProjectCheck{Name: "none"}.Validate())
+	assert.Error(t, ProjectCheck{Name: "both", Output: "ok", HTTP: &HTTPCheck{URL: "http://localhost"}}.Validate())
+	assert.Error(t, ProjectCheck{Name: "url", HTTP: &HTTPCheck{}}.Validate())
+	assert.Error(t, ProjectCheck{Name: "timeout", Output: "ok", Timeout: "soon"}.Validate())
+	assert.Error(t, ProjectCheck{Output: "ok"}.Validate())
+
+	proj.Checks = append(proj.Checks, proj.Checks[0])
+	assert.Error(t, proj.Validate())
+}
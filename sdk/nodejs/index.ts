@@ -29,8 +29,10 @@ import * as asset from "./asset";
 import * as dynamic from "./dynamic";
 import * as iterable from "./iterable";
 import * as log from "./log";
+import * as random from "./random";
 import * as runtime from "./runtime";
-export { asset, dynamic, iterable, log, runtime };
+import * as time from "./time";
+export { asset, dynamic, iterable, log, random, runtime, time };
 
 // @pulumi is a deployment-only module.  If someone tries to capture it, and we fail for some reason
 // we want to give a good message about what the problem likely is.  Note that capturing a
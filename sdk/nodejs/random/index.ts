@@ -0,0 +1,211 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import { Input, Output } from "../output";
+import { CustomResource, CustomResourceOptions } from "../resource";
+
+/**
+ * RandomPassword generates a random password that is stored, as a secret, in the stack's state. The password is kept
+ * until one of the resource's inputs changes, at which point a new password is generated.
+ *
+ * RandomPassword is managed by the Pulumi engine itself and does not require a resource provider plugin.
+ */
+export class RandomPassword extends CustomResource {
+    /**
+     * The number of characters in the password.
+     */
+    public readonly length: Output<number>;
+    /**
+     * Whether the password may contain special characters.
+     */
+    public readonly special: Output<boolean>;
+    /**
+     * Arbitrary values that, when changed, cause a new password to be generated.
+     */
+    public readonly triggers: Output<{[key: string]: any} | undefined>;
+    /**
+     * The generated password.
+     */
+    public readonly result: Output<string>;
+
+    /**
+     * Create a RandomPassword resource with the given unique name, arguments, and options.
+     *
+     * @param name The _unique_ name of the resource.
+     * @param args The arguments to use to populate this resource's properties.
+     * @param opts A bag of options that control this resource's behavior.
+     */
+    constructor(name: string, args: RandomPasswordArgs, opts?: CustomResourceOptions) {
+        super("pulumi:random:RandomPassword", name, {
+            length: args.length,
+            special: args.special,
+            triggers: args.triggers,
+            result: undefined,
+        }, { ...opts, additionalSecretOutputs: ["result"] });
+    }
+}
+
+/**
+ * The set of arguments for constructing a RandomPassword resource.
+ */
+export interface RandomPasswordArgs {
+    /**
+     * The number of characters in the password.
+     */
+    readonly length: Input<number>;
+    /**
+     * Whether the password may contain special characters. Defaults to true.
+     */
+    readonly special?: Input<boolean>;
+    /**
+     * Arbitrary values that, when changed, cause a new password to be generated.
+     */
+    readonly triggers?: Input<{[key: string]: Input<any>}>;
+}
+
+/**
+ * RandomPet generates a random, human-readable name, such as "brave-curious-otter", that is stored in the stack's
+ * state. The name is kept until one of the resource's inputs changes, at which point a new name is generated.
+ *
+ * RandomPet is managed by the Pulumi engine itself and does not require a resource provider plugin.
+ */
+export class RandomPet extends CustomResource {
+    /**
+     * The number of words in the name, not counting the prefix.
+     */
+    public readonly length: Output<number>;
+    /**
+     * The string that separates the words of the name.
+     */
+    public readonly separator: Output<string>;
+    /**
+     * The word that begins the name, if any.
+     */
+    public readonly prefix: Output<string | undefined>;
+    /**
+     * Arbitrary values that, when changed, cause a new name to be generated.
+     */
+    public readonly triggers: Output<{[key: string]: any} | undefined>;
+
+    /**
+     * Create a RandomPet resource with the given unique name, arguments, and options. The generated name is available
+     * as the resource's `id`.
+     *
+     * @param name The _unique_ name of the resource.
+     * @param args The arguments to use to populate this resource's properties.
+     * @param opts A bag of options that control this resource's behavior.
+     */
+    constructor(name: string, args?: RandomPetArgs, opts?: CustomResourceOptions) {
+        args = args || {};
+
+        super("pulumi:random:RandomPet", name, {
+            length: args.length,
+            separator: args.separator,
+            prefix: args.prefix,
+            triggers: args.triggers,
+        }, opts);
+    }
+}
+
+/**
+ * The set of arguments for constructing a RandomPet resource.
+ */
+export interface RandomPetArgs {
+    /**
+     * The number of words in the name, not counting the prefix. Defaults to 2.
+     */
+    readonly length?: Input<number>;
+    /**
+     * The string that separates the words of the name. Defaults to "-".
+     */
+    readonly separator?: Input<string>;
+    /**
+     * A word with which to begin the name.
+     */
+    readonly prefix?: Input<string>;
+    /**
+     * Arbitrary values that, when changed, cause a new name to be generated.
+     */
+    readonly triggers?: Input<{[key: string]: Input<any>}>;
+}
+
+/**
+ * RandomId generates random bytes that are stored in the stack's state, and reports them in several encodings that
+ * are suitable for use in resource names. The bytes are kept until one of the resource's inputs changes, at which
+ * point new bytes are generated.
+ *
+ * RandomId is managed by the Pulumi engine itself and does not require a resource provider plugin.
+ */
+export class RandomId extends CustomResource {
+    /**
+     * The number of random bytes.
+     */
+    public readonly byteLength: Output<number>;
+    /**
+     * The string that prefixes each encoding of the bytes, if any.
+     */
+    public readonly prefix: Output<string | undefined>;
+    /**
+     * Arbitrary values that, when changed, cause new bytes to be generated.
+     */
+    public readonly triggers: Output<{[key: string]: any} | undefined>;
+    /**
+     * The bytes, encoded as hexadecimal digits.
+     */
+    public readonly hex: Output<string>;
+    /**
+     * The bytes, encoded as URL-safe base64 without padding.
+     */
+    public readonly b64Url: Output<string>;
+    /**
+     * The bytes, encoded as a decimal number.
+     */
+    public readonly dec: Output<string>;
+
+    /**
+     * Create a RandomId resource with the given unique name, arguments, and options.
+     *
+     * @param name The _unique_ name of the resource.
+     * @param args The arguments to use to populate this resource's properties.
+     * @param opts A bag of options that control this resource's behavior.
+     */
+    constructor(name: string, args: RandomIdArgs, opts?: CustomResourceOptions) {
+        super("pulumi:random:RandomId", name, {
+            byteLength: args.byteLength,
+            prefix: args.prefix,
+            triggers: args.triggers,
+            hex: undefined,
+            b64Url: undefined,
+            dec: undefined,
+        }, opts);
+    }
+}
+
+/**
+ * The set of arguments for constructing a RandomId resource.
+ */
+export interface RandomIdArgs {
+    /**
+     * The number of random bytes to generate.
+     */
+    readonly byteLength: Input<number>;
+    /**
+     * A string with which to prefix each encoding of the bytes.
+     */
+    readonly prefix?: Input<string>;
+    /**
+     * Arbitrary values that, when changed, cause new bytes to be generated.
+     */
+    readonly triggers?: Input<{[key: string]: Input<any>}>;
+}
@@ -0,0 +1,65 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import { Input, Output } from "../output";
+import { CustomResource, CustomResourceOptions } from "../resource";
+
+/**
+ * Timestamp records the time at which it was created in the stack's state. The time is kept until the resource's
+ * triggers change, at which point the time is recorded anew. This makes it possible to, for example, rotate a
+ * credential whenever a particular input changes.
+ *
+ * Timestamp is managed by the Pulumi engine itself and does not require a resource provider plugin.
+ */
+export class Timestamp extends CustomResource {
+    /**
+     * Arbitrary values that, when changed, cause the time to be recorded anew.
+     */
+    public readonly triggers: Output<{[key: string]: any} | undefined>;
+    /**
+     * The recorded time, formatted according to RFC 3339.
+     */
+    public readonly rfc3339: Output<string>;
+    /**
+     * The recorded time, as the number of seconds since the Unix epoch.
+     */
+    public readonly unix: Output<number>;
+
+    /**
+     * Create a Timestamp resource with the given unique name, arguments, and options.
+     *
+     * @param name The _unique_ name of the resource.
+     * @param args The arguments to use to populate this resource's properties.
+     * @param opts A bag of options that control this resource's behavior.
+     */
+    constructor(name: string, args?: TimestampArgs, opts?: CustomResourceOptions) {
+        args = args || {};
+
+        super("pulumi:time:Timestamp", name, {
+            triggers: args.triggers,
+            rfc3339: undefined,
+            unix: undefined,
+        }, opts);
+    }
+}
+
+/**
+ * The set of arguments for constructing a Timestamp resource.
+ */
+export interface TimestampArgs {
+    /**
+     * Arbitrary values that, when changed, cause the time to be recorded anew.
+     */
+    readonly triggers?: Input<{[key: string]: Input<any>}>;
+}
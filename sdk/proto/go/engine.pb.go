@@ -51,7 +51,7 @@ func (x LogSeverity) String() string {
 	return proto.EnumName(LogSeverity_name, int32(x))
 }
 func (LogSeverity) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_engine_8ab3bc277096818e, []int{0}
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{0}
 }
 
 type LogRequest struct {
@@ -80,7 +80,7 @@ func (m *LogRequest) Reset()         { *m = LogRequest{} }
 func (m *LogRequest) String() string { return proto.CompactTextString(m) }
 func (*LogRequest) ProtoMessage()    {}
 func (*LogRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_engine_8ab3bc277096818e, []int{0}
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{0}
 }
 func (m *LogRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LogRequest.Unmarshal(m, b)
@@ -145,7 +145,7 @@ func (m *GetRootResourceRequest) Reset()         { *m = GetRootResourceRequest{}
 func (m *GetRootResourceRequest) String() string { return proto.CompactTextString(m) }
 func (*GetRootResourceRequest) ProtoMessage()    {}
 func (*GetRootResourceRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_engine_8ab3bc277096818e, []int{1}
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{1}
 }
 func (m *GetRootResourceRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetRootResourceRequest.Unmarshal(m, b)
@@ -177,7 +177,7 @@ func (m *GetRootResourceResponse) Reset()         { *m = GetRootResourceResponse
 func (m *GetRootResourceResponse) String() string { return proto.CompactTextString(m) }
 func (*GetRootResourceResponse) ProtoMessage()    {}
 func (*GetRootResourceResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_engine_8ab3bc277096818e, []int{2}
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{2}
 }
 func (m *GetRootResourceResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetRootResourceResponse.Unmarshal(m, b)
@@ -216,7 +216,7 @@ func (m *SetRootResourceRequest) Reset()         { *m = SetRootResourceRequest{}
 func (m *SetRootResourceRequest) String() string { return proto.CompactTextString(m) }
 func (*SetRootResourceRequest) ProtoMessage()    {}
 func (*SetRootResourceRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_engine_8ab3bc277096818e, []int{3}
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{3}
 }
 func (m *SetRootResourceRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SetRootResourceRequest.Unmarshal(m, b)
@@ -253,7 +253,7 @@ func (m *SetRootResourceResponse) Reset()         { *m = SetRootResourceResponse
 func (m *SetRootResourceResponse) String() string { return proto.CompactTextString(m) }
 func (*SetRootResourceResponse) ProtoMessage()    {}
 func (*SetRootResourceResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_engine_8ab3bc277096818e, []int{4}
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{4}
 }
 func (m *SetRootResourceResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SetRootResourceResponse.Unmarshal(m, b)
@@ -273,12 +273,70 @@ func (m *SetRootResourceResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_SetRootResourceResponse proto.InternalMessageInfo
 
+type ReportProgressRequest struct {
+	// the resource urn whose operation is progressing.
+	Urn string `protobuf:"bytes,1,opt,name=urn" json:"urn,omitempty"`
+	// the percentage of the operation that has completed, or a negative number if unknown.
+	Percent int32 `protobuf:"varint,2,opt,name=percent" json:"percent,omitempty"`
+	// a short description of what the provider is currently doing.
+	Message              string   `protobuf:"bytes,3,opt,name=message" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportProgressRequest) Reset()         { *m = ReportProgressRequest{} }
+func (m *ReportProgressRequest) String() string { return proto.CompactTextString(m) }
+func (*ReportProgressRequest) ProtoMessage()    {}
+func (*ReportProgressRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_engine_b0fc8fd2792f524d, []int{5}
+}
+func (m *ReportProgressRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportProgressRequest.Unmarshal(m, b)
+}
+func (m *ReportProgressRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportProgressRequest.Marshal(b, m, deterministic)
+}
+func (dst *ReportProgressRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportProgressRequest.Merge(dst, src)
+}
+func (m *ReportProgressRequest) XXX_Size() int {
+	return xxx_messageInfo_ReportProgressRequest.Size(m)
+}
+func (m *ReportProgressRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportProgressRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportProgressRequest proto.InternalMessageInfo
+
+func (m *ReportProgressRequest) GetUrn() string {
+	if m != nil {
+		return m.Urn
+	}
+	return ""
+}
+
+func (m *ReportProgressRequest) GetPercent() int32 {
+	if m != nil {
+		return m.Percent
+	}
+	return 0
+}
+
+func (m *ReportProgressRequest) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*LogRequest)(nil), "pulumirpc.LogRequest")
 	proto.RegisterType((*GetRootResourceRequest)(nil), "pulumirpc.GetRootResourceRequest")
 	proto.RegisterType((*GetRootResourceResponse)(nil), "pulumirpc.GetRootResourceResponse")
 	proto.RegisterType((*SetRootResourceRequest)(nil), "pulumirpc.SetRootResourceRequest")
 	proto.RegisterType((*SetRootResourceResponse)(nil), "pulumirpc.SetRootResourceResponse")
+	proto.RegisterType((*ReportProgressRequest)(nil), "pulumirpc.ReportProgressRequest")
 	proto.RegisterEnum("pulumirpc.LogSeverity", LogSeverity_name, LogSeverity_value)
 }
 
@@ -300,6 +358,8 @@ type EngineClient interface {
 	GetRootResource(ctx context.Context, in *GetRootResourceRequest, opts ...grpc.CallOption) (*GetRootResourceResponse, error)
 	// SetRootResource sets the URN of the root resource.
 	SetRootResource(ctx context.Context, in *SetRootResourceRequest, opts ...grpc.CallOption) (*SetRootResourceResponse, error)
+	// ReportProgress reports the progress of a long-running operation on a resource.
+	ReportProgress(ctx context.Context, in *ReportProgressRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 }
 
 type engineClient struct {
@@ -337,6 +397,15 @@ func (c *engineClient) SetRootResource(ctx context.Context, in *SetRootResourceR
 	return out, nil
 }
 
+func (c *engineClient) ReportProgress(ctx context.Context, in *ReportProgressRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := grpc.Invoke(ctx, "/pulumirpc.Engine/ReportProgress", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Engine service
 
 type EngineServer interface {
@@ -347,6 +416,8 @@ type EngineServer interface {
 	GetRootResource(context.Context, *GetRootResourceRequest) (*GetRootResourceResponse, error)
 	// SetRootResource sets the URN of the root resource.
 	SetRootResource(context.Context, *SetRootResourceRequest) (*SetRootResourceResponse, error)
+	// ReportProgress reports the progress of a long-running operation on a resource.
+	ReportProgress(context.Context, *ReportProgressRequest) (*empty.Empty, error)
 }
 
 func RegisterEngineServer(s *grpc.Server, srv EngineServer) {
@@ -407,6 +478,24 @@ func _Engine_SetRootResource_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Engine_ReportProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EngineServer).ReportProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.Engine/ReportProgress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EngineServer).ReportProgress(ctx, req.(*ReportProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Engine_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "pulumirpc.Engine",
 	HandlerType: (*EngineServer)(nil),
@@ -423,36 +512,42 @@ var _Engine_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetRootResource",
 			Handler:    _Engine_SetRootResource_Handler,
 		},
+		{
+			MethodName: "ReportProgress",
+			Handler:    _Engine_ReportProgress_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "engine.proto",
 }
 
-func init() { proto.RegisterFile("engine.proto", fileDescriptor_engine_8ab3bc277096818e) }
-
-var fileDescriptor_engine_8ab3bc277096818e = []byte{
-	// 356 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x91, 0x4f, 0x4b, 0xeb, 0x40,
-	0x14, 0xc5, 0x3b, 0x4d, 0xff, 0x24, 0xb7, 0x8f, 0xf7, 0xc2, 0xc0, 0x4b, 0xf3, 0xf2, 0x5c, 0xc4,
-	0xac, 0x42, 0x85, 0x14, 0x2a, 0xb8, 0x70, 0xa7, 0x18, 0x4b, 0xa1, 0xb4, 0x30, 0x41, 0x04, 0x77,
-	0x6d, 0xbd, 0xc6, 0x42, 0x93, 0x89, 0x99, 0x89, 0xd0, 0x2f, 0xe4, 0x67, 0x74, 0x29, 0x4d, 0xda,
-	0x58, 0x35, 0xd6, 0xdd, 0xcc, 0xbd, 0x87, 0x1f, 0xe7, 0x9e, 0x03, 0xbf, 0x30, 0x0e, 0x97, 0x31,
-	0x7a, 0x49, 0xca, 0x25, 0xa7, 0x5a, 0x92, 0xad, 0xb2, 0x68, 0x99, 0x26, 0x0b, 0xeb, 0x7f, 0xc8,
-	0x79, 0xb8, 0xc2, 0x7e, 0xbe, 0x98, 0x67, 0x0f, 0x7d, 0x8c, 0x12, 0xb9, 0x2e, 0x74, 0xce, 0x0b,
-	0x01, 0x18, 0xf3, 0x90, 0xe1, 0x53, 0x86, 0x42, 0xd2, 0x01, 0xa8, 0x02, 0x9f, 0x31, 0x5d, 0xca,
-	0xb5, 0x49, 0x6c, 0xe2, 0xfe, 0x1e, 0x18, 0x5e, 0x49, 0xf2, 0xc6, 0x3c, 0x0c, 0xb6, 0x5b, 0x56,
-	0xea, 0xa8, 0x09, 0xed, 0x08, 0x85, 0x98, 0x85, 0x68, 0xd6, 0x6d, 0xe2, 0x6a, 0x6c, 0xf7, 0xa5,
-	0x3a, 0x28, 0x59, 0x1a, 0x9b, 0x4a, 0x3e, 0xdd, 0x3c, 0xa9, 0x05, 0xaa, 0x90, 0x29, 0xce, 0xa2,
-	0xd1, 0xbd, 0xd9, 0xb0, 0x89, 0xdb, 0x64, 0xe5, 0x9f, 0x1e, 0x81, 0x86, 0xc9, 0x23, 0x46, 0x98,
-	0xce, 0x56, 0x66, 0xd3, 0x26, 0xae, 0xca, 0xde, 0x07, 0x8e, 0x09, 0xc6, 0x10, 0x25, 0xe3, 0x5c,
-	0x32, 0x14, 0x3c, 0x4b, 0x17, 0xb8, 0xf5, 0xec, 0x9c, 0x40, 0xf7, 0xcb, 0x46, 0x24, 0x3c, 0x16,
-	0xa5, 0x01, 0x52, 0x1a, 0x70, 0x7a, 0x60, 0x04, 0x95, 0x98, 0x0a, 0xed, 0x3f, 0xe8, 0x06, 0xd5,
-	0xe0, 0xde, 0x39, 0x74, 0xf6, 0xc2, 0xa0, 0x1a, 0x34, 0xaf, 0xfc, 0xcb, 0x9b, 0xa1, 0x5e, 0xa3,
-	0x2a, 0x34, 0x46, 0x93, 0xeb, 0xa9, 0x4e, 0x68, 0x07, 0xda, 0xb7, 0x17, 0x6c, 0x32, 0x9a, 0x0c,
-	0xf5, 0xfa, 0x46, 0xe1, 0x33, 0x36, 0x65, 0xba, 0x32, 0x78, 0x25, 0xd0, 0xf2, 0xf3, 0xae, 0xe8,
-	0x19, 0x28, 0x63, 0x1e, 0xd2, 0xbf, 0x1f, 0x33, 0xde, 0x3a, 0xb2, 0x0c, 0xaf, 0x68, 0xce, 0xdb,
-	0x35, 0xe7, 0xf9, 0x9b, 0xe6, 0x9c, 0x1a, 0xbd, 0x83, 0x3f, 0x9f, 0x4e, 0xa6, 0xc7, 0x7b, 0x8c,
-	0xea, 0xa0, 0x2c, 0xe7, 0x90, 0xa4, 0x38, 0xac, 0x60, 0x07, 0x07, 0xd8, 0xc1, 0xcf, 0xec, 0xe0,
-	0x3b, 0xf6, 0xbc, 0x95, 0x5f, 0x72, 0xfa, 0x16, 0x00, 0x00, 0xff, 0xff, 0x26, 0x3e, 0xcf, 0xd2,
-	0xac, 0x02, 0x00, 0x00,
+func init() { proto.RegisterFile("engine.proto", fileDescriptor_engine_b0fc8fd2792f524d) }
+
+var fileDescriptor_engine_b0fc8fd2792f524d = []byte{
+	// 394 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x85, 0x92, 0xcf, 0x4e, 0xc2, 0x40,
+	0x10, 0xc6, 0x29, 0xa5, 0xfc, 0x19, 0x0c, 0x36, 0x9b, 0x50, 0x2a, 0x7a, 0xc0, 0x9e, 0x0c, 0x26,
+	0x25, 0xc1, 0xc4, 0x83, 0x37, 0x8d, 0x95, 0x90, 0x10, 0x30, 0xdb, 0x18, 0x13, 0x13, 0x0f, 0x80,
+	0x63, 0x25, 0xa1, 0xdd, 0xba, 0xdd, 0x9a, 0xf0, 0x42, 0x3e, 0x8a, 0xcf, 0x65, 0x29, 0xa5, 0x82,
+	0x16, 0xbc, 0xed, 0xcc, 0x7c, 0xf3, 0xcb, 0xec, 0x37, 0x03, 0x07, 0xe8, 0x39, 0x33, 0x0f, 0x4d,
+	0x9f, 0x33, 0xc1, 0x48, 0xc5, 0x0f, 0xe7, 0xa1, 0x3b, 0xe3, 0xfe, 0xb4, 0x79, 0xec, 0x30, 0xe6,
+	0xcc, 0xb1, 0x13, 0x17, 0x26, 0xe1, 0x6b, 0x07, 0x5d, 0x5f, 0x2c, 0x56, 0x3a, 0xe3, 0x53, 0x02,
+	0x18, 0x30, 0x87, 0xe2, 0x7b, 0x88, 0x81, 0x20, 0x5d, 0x28, 0x07, 0xf8, 0x81, 0x7c, 0x26, 0x16,
+	0xba, 0xd4, 0x92, 0xce, 0x6a, 0x5d, 0xcd, 0x4c, 0x49, 0x66, 0x24, 0xb4, 0x93, 0x2a, 0x4d, 0x75,
+	0x44, 0x87, 0x92, 0x8b, 0x41, 0x30, 0x76, 0x50, 0xcf, 0x47, 0x2d, 0x15, 0xba, 0x0e, 0x89, 0x0a,
+	0x72, 0xc8, 0x3d, 0x5d, 0x8e, 0xb3, 0xcb, 0x27, 0x69, 0x46, 0x7c, 0xc1, 0x71, 0xec, 0xf6, 0x5f,
+	0xf4, 0x42, 0x94, 0x56, 0x68, 0x1a, 0x93, 0x13, 0xa8, 0xa0, 0xff, 0x86, 0x2e, 0xf2, 0xf1, 0x5c,
+	0x57, 0xa2, 0x62, 0x99, 0xfe, 0x24, 0x0c, 0x1d, 0xb4, 0x1e, 0x0a, 0xca, 0x98, 0xa0, 0x18, 0xb0,
+	0x90, 0x4f, 0x31, 0x99, 0xd9, 0x38, 0x87, 0xc6, 0x9f, 0x4a, 0xe0, 0x33, 0x2f, 0x48, 0x07, 0x90,
+	0xd2, 0x01, 0x8c, 0x36, 0x68, 0x76, 0x26, 0x26, 0x43, 0x7b, 0x04, 0x0d, 0x3b, 0x1b, 0x6c, 0x3c,
+	0x43, 0x9d, 0xa2, 0xcf, 0xb8, 0xb8, 0xe7, 0xcc, 0xe1, 0xd1, 0x77, 0x77, 0x52, 0x96, 0xf6, 0xf8,
+	0x18, 0xf5, 0x7a, 0x22, 0xb6, 0x47, 0xa1, 0xeb, 0x70, 0xd3, 0x38, 0x79, 0xcb, 0xb8, 0xf6, 0x15,
+	0x54, 0x37, 0xbc, 0x26, 0x15, 0x50, 0x6e, 0xad, 0x9b, 0x87, 0x9e, 0x9a, 0x23, 0x65, 0x28, 0xf4,
+	0x87, 0x77, 0x23, 0x55, 0x22, 0x55, 0x28, 0x3d, 0x5e, 0xd3, 0x61, 0x7f, 0xd8, 0x53, 0xf3, 0x4b,
+	0x85, 0x45, 0xe9, 0x88, 0xaa, 0x72, 0xf7, 0x2b, 0x0f, 0x45, 0x2b, 0x3e, 0x05, 0x72, 0x09, 0x72,
+	0x84, 0x21, 0xf5, 0xed, 0x15, 0x26, 0xa3, 0x36, 0x35, 0x73, 0x75, 0x18, 0xe6, 0xfa, 0x30, 0x4c,
+	0x6b, 0x79, 0x18, 0x46, 0x8e, 0x3c, 0xc1, 0xe1, 0x2f, 0x47, 0xc9, 0xe9, 0x06, 0x23, 0x7b, 0x0f,
+	0x4d, 0x63, 0x9f, 0x24, 0xf1, 0x2d, 0x66, 0xdb, 0x7b, 0xd8, 0xf6, 0xff, 0x6c, 0x7b, 0x27, 0x7b,
+	0x00, 0xb5, 0xed, 0xad, 0x90, 0xd6, 0x46, 0x5f, 0xe6, 0xc2, 0x76, 0xbb, 0x30, 0x29, 0xc6, 0x99,
+	0x8b, 0x6f, 0xc0, 0x71, 0x90, 0xa8, 0x59, 0x03, 0x00, 0x00,
 }
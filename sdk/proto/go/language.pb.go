@@ -37,7 +37,7 @@ func (m *GetRequiredPluginsRequest) Reset()         { *m = GetRequiredPluginsReq
 func (m *GetRequiredPluginsRequest) String() string { return proto.CompactTextString(m) }
 func (*GetRequiredPluginsRequest) ProtoMessage()    {}
 func (*GetRequiredPluginsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_language_aca56bed509f6786, []int{0}
+	return fileDescriptor_language_7e254f26a5c1b77e, []int{0}
 }
 func (m *GetRequiredPluginsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetRequiredPluginsRequest.Unmarshal(m, b)
@@ -89,7 +89,7 @@ func (m *GetRequiredPluginsResponse) Reset()         { *m = GetRequiredPluginsRe
 func (m *GetRequiredPluginsResponse) String() string { return proto.CompactTextString(m) }
 func (*GetRequiredPluginsResponse) ProtoMessage()    {}
 func (*GetRequiredPluginsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_language_aca56bed509f6786, []int{1}
+	return fileDescriptor_language_7e254f26a5c1b77e, []int{1}
 }
 func (m *GetRequiredPluginsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetRequiredPluginsResponse.Unmarshal(m, b)
@@ -128,6 +128,8 @@ type RunRequest struct {
 	Parallel             int32             `protobuf:"varint,8,opt,name=parallel" json:"parallel,omitempty"`
 	MonitorAddress       string            `protobuf:"bytes,9,opt,name=monitor_address,json=monitorAddress" json:"monitor_address,omitempty"`
 	QueryMode            bool              `protobuf:"varint,10,opt,name=queryMode" json:"queryMode,omitempty"`
+	HotReload            bool              `protobuf:"varint,11,opt,name=hotReload" json:"hotReload,omitempty"`
+	ChangedPaths         []string          `protobuf:"bytes,12,rep,name=changedPaths" json:"changedPaths,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -137,7 +139,7 @@ func (m *RunRequest) Reset()         { *m = RunRequest{} }
 func (m *RunRequest) String() string { return proto.CompactTextString(m) }
 func (*RunRequest) ProtoMessage()    {}
 func (*RunRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_language_aca56bed509f6786, []int{2}
+	return fileDescriptor_language_7e254f26a5c1b77e, []int{2}
 }
 func (m *RunRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RunRequest.Unmarshal(m, b)
@@ -227,6 +229,20 @@ func (m *RunRequest) GetQueryMode() bool {
 	return false
 }
 
+func (m *RunRequest) GetHotReload() bool {
+	if m != nil {
+		return m.HotReload
+	}
+	return false
+}
+
+func (m *RunRequest) GetChangedPaths() []string {
+	if m != nil {
+		return m.ChangedPaths
+	}
+	return nil
+}
+
 // RunResponse is the response back from the interpreter/source back to the monitor.
 type RunResponse struct {
 	// An unhandled error if any occurred.
@@ -244,7 +260,7 @@ func (m *RunResponse) Reset()         { *m = RunResponse{} }
 func (m *RunResponse) String() string { return proto.CompactTextString(m) }
 func (*RunResponse) ProtoMessage()    {}
 func (*RunResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_language_aca56bed509f6786, []int{3}
+	return fileDescriptor_language_7e254f26a5c1b77e, []int{3}
 }
 func (m *RunResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RunResponse.Unmarshal(m, b)
@@ -430,38 +446,40 @@ var _LanguageRuntime_serviceDesc = grpc.ServiceDesc{
 	Metadata: "language.proto",
 }
 
-func init() { proto.RegisterFile("language.proto", fileDescriptor_language_aca56bed509f6786) }
-
-var fileDescriptor_language_aca56bed509f6786 = []byte{
-	// 477 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x93, 0xdf, 0x6e, 0xd3, 0x30,
-	0x14, 0xc6, 0x97, 0x66, 0xfd, 0x77, 0x0a, 0x1b, 0xb2, 0xb6, 0xca, 0x64, 0x5c, 0x94, 0x08, 0x44,
-	0xaf, 0x32, 0x69, 0x88, 0x3f, 0xe3, 0x0a, 0x04, 0xd3, 0x84, 0x04, 0x12, 0x32, 0x0f, 0x80, 0xdc,
-	0xe4, 0x34, 0x0a, 0x4b, 0x6d, 0xcf, 0xb1, 0x41, 0x79, 0x68, 0x24, 0x1e, 0x01, 0xd9, 0x4e, 0xbb,
-	0xc2, 0x8a, 0x76, 0x77, 0xbe, 0x93, 0xef, 0x24, 0xbf, 0x7c, 0x3e, 0x86, 0x83, 0x9a, 0x8b, 0xd2,
-	0xf2, 0x12, 0x33, 0xa5, 0xa5, 0x91, 0x64, 0xac, 0x6c, 0x6d, 0x57, 0x95, 0x56, 0x79, 0x72, 0x4f,
-	0xd5, 0xb6, 0xac, 0x44, 0x78, 0x90, 0x9c, 0x94, 0x52, 0x96, 0x35, 0x9e, 0x7a, 0xb5, 0xb0, 0xcb,
-	0x53, 0x5c, 0x29, 0xd3, 0x86, 0x87, 0x29, 0x87, 0x87, 0x97, 0x68, 0x18, 0x5e, 0xdb, 0x4a, 0x63,
-	0xf1, 0xc5, 0xcf, 0x35, 0x4e, 0x62, 0x63, 0x08, 0x85, 0xa1, 0xd2, 0xf2, 0x3b, 0xe6, 0x86, 0x46,
-	0xb3, 0x68, 0x3e, 0x66, 0x6b, 0x49, 0x1e, 0x40, 0xac, 0x7e, 0x16, 0xb4, 0xe7, 0xbb, 0xae, 0xec,
-	0xbc, 0xa5, 0xe6, 0x2b, 0x1a, 0x6f, 0xbc, 0x4e, 0xa6, 0x5f, 0x21, 0xd9, 0xf5, 0x89, 0x46, 0x49,
-	0xd1, 0x20, 0x79, 0x01, 0xc3, 0x40, 0xdb, 0xd0, 0x68, 0x16, 0xcf, 0x27, 0x67, 0x27, 0xd9, 0xe6,
-	0x47, 0xb2, 0x60, 0xfe, 0x80, 0x0a, 0x45, 0x81, 0x22, 0x6f, 0xd9, 0xda, 0x9b, 0xfe, 0xea, 0x01,
-	0x30, 0x2b, 0xee, 0x26, 0x3d, 0x82, 0x7e, 0x63, 0x78, 0x7e, 0xd5, 0xb1, 0x06, 0xb1, 0xe6, 0x8f,
-	0x77, 0xf2, 0xef, 0xff, 0xc5, 0x4f, 0x08, 0xec, 0x73, 0x5d, 0x36, 0xb4, 0x3f, 0x8b, 0xe7, 0x63,
-	0xe6, 0x6b, 0x72, 0x0e, 0x83, 0x5c, 0x8a, 0x65, 0x55, 0xd2, 0x81, 0x87, 0x7e, 0xbc, 0x05, 0x7d,
-	0x83, 0x95, 0xbd, 0xf7, 0x9e, 0x0b, 0x61, 0x74, 0xcb, 0xba, 0x01, 0x32, 0x85, 0x41, 0xa1, 0x5b,
-	0x66, 0x05, 0x1d, 0xce, 0xa2, 0xf9, 0x88, 0x75, 0x8a, 0x24, 0x30, 0x52, 0x5c, 0xf3, 0xba, 0xc6,
-	0x9a, 0x8e, 0x66, 0xd1, 0xbc, 0xcf, 0x36, 0x9a, 0x3c, 0x83, 0xc3, 0x95, 0x14, 0x95, 0x91, 0xfa,
-	0x1b, 0x2f, 0x0a, 0x8d, 0x4d, 0x43, 0xc7, 0x1e, 0xf2, 0xa0, 0x6b, 0xbf, 0x0b, 0x5d, 0xf2, 0x08,
-	0xc6, 0xd7, 0x16, 0x75, 0xfb, 0x59, 0x16, 0x48, 0xc1, 0xbf, 0xff, 0xa6, 0x91, 0x9c, 0xc3, 0x64,
-	0x8b, 0xc8, 0x85, 0x70, 0x85, 0x6d, 0x17, 0x98, 0x2b, 0x5d, 0x58, 0x3f, 0x78, 0x6d, 0x71, 0x1d,
-	0x96, 0x17, 0x6f, 0x7a, 0xaf, 0xa3, 0xf4, 0x15, 0x4c, 0xfc, 0x7f, 0x75, 0xa7, 0x76, 0x04, 0x7d,
-	0xd4, 0x5a, 0xea, 0x6e, 0x38, 0x08, 0x97, 0xd4, 0x82, 0x57, 0xb5, 0x9f, 0x1e, 0x31, 0x5f, 0x9f,
-	0xfd, 0x8e, 0xe0, 0xf0, 0x53, 0xb7, 0xa9, 0xcc, 0x0a, 0x53, 0xad, 0x90, 0xe4, 0x40, 0x6e, 0x6f,
-	0x04, 0x79, 0xb2, 0x95, 0xe1, 0x7f, 0x77, 0x32, 0x79, 0x7a, 0x87, 0x2b, 0x00, 0xa6, 0x7b, 0xe4,
-	0x25, 0xc4, 0x2e, 0xd6, 0xe3, 0x9d, 0x27, 0x93, 0x4c, 0xff, 0x6d, 0x6f, 0xe6, 0xde, 0xc2, 0xfd,
-	0x4b, 0x34, 0xe1, 0x7d, 0x1f, 0xc5, 0x52, 0x92, 0x69, 0x16, 0x2e, 0x50, 0xb6, 0xbe, 0x40, 0xd9,
-	0x85, 0xbb, 0x40, 0xc9, 0xf1, 0xad, 0x45, 0x75, 0xf6, 0x74, 0x6f, 0x31, 0xf0, 0xc6, 0xe7, 0x7f,
-	0x02, 0x00, 0x00, 0xff, 0xff, 0x4e, 0xdb, 0x96, 0xaa, 0xa2, 0x03, 0x00, 0x00,
+func init() { proto.RegisterFile("language.proto", fileDescriptor_language_7e254f26a5c1b77e) }
+
+var fileDescriptor_language_7e254f26a5c1b77e = []byte{
+	// 498 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x85, 0x53, 0xdb, 0x6e, 0xd4, 0x30,
+	0x10, 0xed, 0x36, 0xdd, 0xed, 0x66, 0x76, 0x69, 0x91, 0x45, 0x57, 0x26, 0xe5, 0x01, 0x22, 0x10,
+	0x7d, 0x4a, 0xa5, 0x22, 0x2e, 0xe5, 0x09, 0x04, 0x15, 0x42, 0x02, 0x09, 0x85, 0x0f, 0x40, 0xde,
+	0xc4, 0x9b, 0x0d, 0x75, 0xec, 0x60, 0x3b, 0xa0, 0x7c, 0x15, 0xbf, 0xc6, 0x27, 0xe0, 0x4b, 0x92,
+	0x6e, 0xe9, 0xa2, 0xbe, 0xcd, 0x99, 0x39, 0x33, 0x73, 0x3c, 0x33, 0x86, 0x03, 0x46, 0x78, 0xd1,
+	0x90, 0x82, 0x26, 0xb5, 0x14, 0x5a, 0xa0, 0xb0, 0x6e, 0x58, 0x53, 0x95, 0xb2, 0xce, 0xa2, 0x79,
+	0xcd, 0x9a, 0xa2, 0xe4, 0x3e, 0x10, 0x1d, 0x17, 0x42, 0x14, 0x8c, 0x9e, 0x3a, 0xb4, 0x6c, 0x56,
+	0xa7, 0xb4, 0xaa, 0x75, 0xeb, 0x83, 0x31, 0x81, 0xfb, 0x1f, 0xa8, 0x4e, 0xe9, 0x8f, 0xa6, 0x94,
+	0x34, 0xff, 0xe2, 0xf2, 0x94, 0x85, 0x54, 0x69, 0x84, 0x61, 0xdf, 0xb0, 0xbe, 0xd3, 0x4c, 0xe3,
+	0xd1, 0xc3, 0xd1, 0x49, 0x98, 0xf6, 0x10, 0xdd, 0x85, 0xa0, 0xfe, 0x95, 0xe3, 0x5d, 0xe7, 0xb5,
+	0x66, 0xc7, 0x2d, 0x24, 0xa9, 0x70, 0x30, 0x70, 0x2d, 0x8c, 0xbf, 0x42, 0xb4, 0xad, 0x85, 0xaa,
+	0x05, 0x57, 0x14, 0x3d, 0x37, 0x79, 0xde, 0x65, 0x7a, 0x04, 0x27, 0xb3, 0xb3, 0xe3, 0x64, 0x78,
+	0x48, 0xe2, 0xc9, 0xef, 0x69, 0x4d, 0x79, 0x4e, 0x79, 0xd6, 0xa6, 0x3d, 0x37, 0xfe, 0x1d, 0x00,
+	0xa4, 0x0d, 0xbf, 0x5d, 0xe9, 0x3d, 0x18, 0x2b, 0x4d, 0xb2, 0xcb, 0x4e, 0xab, 0x07, 0xbd, 0xfe,
+	0x60, 0xab, 0xfe, 0xbd, 0x6b, 0xfa, 0x11, 0x82, 0x3d, 0x22, 0x0b, 0x85, 0xc7, 0x46, 0x5e, 0x98,
+	0x3a, 0x1b, 0x9d, 0xc3, 0x24, 0x13, 0x7c, 0x55, 0x16, 0x78, 0xe2, 0x44, 0x3f, 0xda, 0x10, 0x7d,
+	0x25, 0x2b, 0x79, 0xe7, 0x38, 0x17, 0x5c, 0xcb, 0x36, 0xed, 0x12, 0xd0, 0x02, 0x26, 0xb9, 0x81,
+	0x0d, 0xc7, 0xfb, 0xa6, 0xcf, 0x34, 0xed, 0x10, 0x8a, 0x60, 0x5a, 0x13, 0x49, 0x18, 0xa3, 0x0c,
+	0x4f, 0x4d, 0x64, 0x9c, 0x0e, 0x18, 0x3d, 0x85, 0xc3, 0x4a, 0xf0, 0x52, 0x0b, 0xf9, 0x8d, 0xe4,
+	0xb9, 0xa4, 0x4a, 0xe1, 0xd0, 0x89, 0x3c, 0xe8, 0xdc, 0x6f, 0xbd, 0x17, 0x3d, 0x80, 0xd0, 0x74,
+	0x96, 0xed, 0x67, 0x91, 0x53, 0x0c, 0xae, 0xfe, 0x95, 0xc3, 0x46, 0xd7, 0xc2, 0x6c, 0x82, 0x09,
+	0x92, 0xe3, 0x99, 0x8f, 0x0e, 0x0e, 0x14, 0xc3, 0x3c, 0x5b, 0x9b, 0x9b, 0x32, 0x3b, 0x22, 0x7a,
+	0xad, 0xf0, 0xdc, 0xbd, 0xf7, 0x9a, 0x2f, 0x3a, 0x87, 0xd9, 0xc6, 0x9b, 0xec, 0x18, 0x2f, 0x69,
+	0xdb, 0x8d, 0xdc, 0x9a, 0x76, 0xdc, 0x3f, 0x09, 0x6b, 0x68, 0x3f, 0x6e, 0x07, 0x5e, 0xef, 0xbe,
+	0x1a, 0xc5, 0x2f, 0x61, 0xe6, 0x26, 0xd3, 0xed, 0xdd, 0x10, 0xa9, 0x94, 0x42, 0x76, 0xc9, 0x1e,
+	0xd8, 0x59, 0x2f, 0x49, 0xc9, 0x5c, 0xf6, 0x34, 0x75, 0xf6, 0xd9, 0x9f, 0x11, 0x1c, 0x7e, 0xea,
+	0x6e, 0xdd, 0x54, 0xd0, 0x65, 0x45, 0x51, 0x06, 0xe8, 0xe6, 0x4d, 0xa1, 0xc7, 0x1b, 0x5b, 0xf8,
+	0xef, 0x55, 0x47, 0x4f, 0x6e, 0x61, 0x79, 0x81, 0xf1, 0x0e, 0x7a, 0x01, 0x81, 0x5d, 0xcc, 0xd1,
+	0xd6, 0xdd, 0x46, 0x8b, 0x7f, 0xdd, 0x43, 0xde, 0x1b, 0xb8, 0x63, 0xea, 0xfa, 0x7a, 0x1f, 0xf9,
+	0x4a, 0xa0, 0x45, 0xe2, 0xbf, 0x60, 0xd2, 0x7f, 0xc1, 0xe4, 0xc2, 0x7e, 0xc1, 0xe8, 0xe8, 0xc6,
+	0xa9, 0x5b, 0x7a, 0xbc, 0xb3, 0x9c, 0x38, 0xe2, 0xb3, 0xbf, 0xb4, 0x2e, 0xba, 0x99, 0xe4, 0x03,
+	0x00, 0x00,
 }
@@ -48,7 +48,7 @@ func (x DiffResponse_DiffChanges) String() string {
 	return proto.EnumName(DiffResponse_DiffChanges_name, int32(x))
 }
 func (DiffResponse_DiffChanges) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{9, 0}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{9, 0}
 }
 
 type ConfigureRequest struct {
@@ -64,7 +64,7 @@ func (m *ConfigureRequest) Reset()         { *m = ConfigureRequest{} }
 func (m *ConfigureRequest) String() string { return proto.CompactTextString(m) }
 func (*ConfigureRequest) ProtoMessage()    {}
 func (*ConfigureRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{0}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{0}
 }
 func (m *ConfigureRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConfigureRequest.Unmarshal(m, b)
@@ -116,7 +116,7 @@ func (m *ConfigureResponse) Reset()         { *m = ConfigureResponse{} }
 func (m *ConfigureResponse) String() string { return proto.CompactTextString(m) }
 func (*ConfigureResponse) ProtoMessage()    {}
 func (*ConfigureResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{1}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{1}
 }
 func (m *ConfigureResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConfigureResponse.Unmarshal(m, b)
@@ -155,7 +155,7 @@ func (m *ConfigureErrorMissingKeys) Reset()         { *m = ConfigureErrorMissing
 func (m *ConfigureErrorMissingKeys) String() string { return proto.CompactTextString(m) }
 func (*ConfigureErrorMissingKeys) ProtoMessage()    {}
 func (*ConfigureErrorMissingKeys) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{2}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{2}
 }
 func (m *ConfigureErrorMissingKeys) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConfigureErrorMissingKeys.Unmarshal(m, b)
@@ -194,7 +194,7 @@ func (m *ConfigureErrorMissingKeys_MissingKey) Reset()         { *m = ConfigureE
 func (m *ConfigureErrorMissingKeys_MissingKey) String() string { return proto.CompactTextString(m) }
 func (*ConfigureErrorMissingKeys_MissingKey) ProtoMessage()    {}
 func (*ConfigureErrorMissingKeys_MissingKey) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{2, 0}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{2, 0}
 }
 func (m *ConfigureErrorMissingKeys_MissingKey) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConfigureErrorMissingKeys_MissingKey.Unmarshal(m, b)
@@ -242,7 +242,7 @@ func (m *InvokeRequest) Reset()         { *m = InvokeRequest{} }
 func (m *InvokeRequest) String() string { return proto.CompactTextString(m) }
 func (*InvokeRequest) ProtoMessage()    {}
 func (*InvokeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{3}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{3}
 }
 func (m *InvokeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InvokeRequest.Unmarshal(m, b)
@@ -302,7 +302,7 @@ func (m *InvokeResponse) Reset()         { *m = InvokeResponse{} }
 func (m *InvokeResponse) String() string { return proto.CompactTextString(m) }
 func (*InvokeResponse) ProtoMessage()    {}
 func (*InvokeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{4}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{4}
 }
 func (m *InvokeResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InvokeResponse.Unmarshal(m, b)
@@ -349,7 +349,7 @@ func (m *CheckRequest) Reset()         { *m = CheckRequest{} }
 func (m *CheckRequest) String() string { return proto.CompactTextString(m) }
 func (*CheckRequest) ProtoMessage()    {}
 func (*CheckRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{5}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{5}
 }
 func (m *CheckRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CheckRequest.Unmarshal(m, b)
@@ -402,7 +402,7 @@ func (m *CheckResponse) Reset()         { *m = CheckResponse{} }
 func (m *CheckResponse) String() string { return proto.CompactTextString(m) }
 func (*CheckResponse) ProtoMessage()    {}
 func (*CheckResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{6}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{6}
 }
 func (m *CheckResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CheckResponse.Unmarshal(m, b)
@@ -448,7 +448,7 @@ func (m *CheckFailure) Reset()         { *m = CheckFailure{} }
 func (m *CheckFailure) String() string { return proto.CompactTextString(m) }
 func (*CheckFailure) ProtoMessage()    {}
 func (*CheckFailure) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{7}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{7}
 }
 func (m *CheckFailure) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CheckFailure.Unmarshal(m, b)
@@ -496,7 +496,7 @@ func (m *DiffRequest) Reset()         { *m = DiffRequest{} }
 func (m *DiffRequest) String() string { return proto.CompactTextString(m) }
 func (*DiffRequest) ProtoMessage()    {}
 func (*DiffRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{8}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{8}
 }
 func (m *DiffRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiffRequest.Unmarshal(m, b)
@@ -559,7 +559,7 @@ func (m *DiffResponse) Reset()         { *m = DiffResponse{} }
 func (m *DiffResponse) String() string { return proto.CompactTextString(m) }
 func (*DiffResponse) ProtoMessage()    {}
 func (*DiffResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{9}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{9}
 }
 func (m *DiffResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiffResponse.Unmarshal(m, b)
@@ -626,7 +626,7 @@ func (m *CreateRequest) Reset()         { *m = CreateRequest{} }
 func (m *CreateRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateRequest) ProtoMessage()    {}
 func (*CreateRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{10}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{10}
 }
 func (m *CreateRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateRequest.Unmarshal(m, b)
@@ -663,6 +663,7 @@ func (m *CreateRequest) GetProperties() *_struct.Struct {
 type CreateResponse struct {
 	Id                   string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Properties           *_struct.Struct `protobuf:"bytes,2,opt,name=properties" json:"properties,omitempty"`
+	Artifacts            []*Artifact     `protobuf:"bytes,3,rep,name=artifacts" json:"artifacts,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -672,7 +673,7 @@ func (m *CreateResponse) Reset()         { *m = CreateResponse{} }
 func (m *CreateResponse) String() string { return proto.CompactTextString(m) }
 func (*CreateResponse) ProtoMessage()    {}
 func (*CreateResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{11}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{11}
 }
 func (m *CreateResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateResponse.Unmarshal(m, b)
@@ -706,6 +707,13 @@ func (m *CreateResponse) GetProperties() *_struct.Struct {
 	return nil
 }
 
+func (m *CreateResponse) GetArtifacts() []*Artifact {
+	if m != nil {
+		return m.Artifacts
+	}
+	return nil
+}
+
 type ReadRequest struct {
 	Id                   string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Urn                  string          `protobuf:"bytes,2,opt,name=urn" json:"urn,omitempty"`
@@ -720,7 +728,7 @@ func (m *ReadRequest) Reset()         { *m = ReadRequest{} }
 func (m *ReadRequest) String() string { return proto.CompactTextString(m) }
 func (*ReadRequest) ProtoMessage()    {}
 func (*ReadRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{12}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{12}
 }
 func (m *ReadRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ReadRequest.Unmarshal(m, b)
@@ -781,7 +789,7 @@ func (m *ReadResponse) Reset()         { *m = ReadResponse{} }
 func (m *ReadResponse) String() string { return proto.CompactTextString(m) }
 func (*ReadResponse) ProtoMessage()    {}
 func (*ReadResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{13}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{13}
 }
 func (m *ReadResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ReadResponse.Unmarshal(m, b)
@@ -836,7 +844,7 @@ func (m *UpdateRequest) Reset()         { *m = UpdateRequest{} }
 func (m *UpdateRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateRequest) ProtoMessage()    {}
 func (*UpdateRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{14}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{14}
 }
 func (m *UpdateRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateRequest.Unmarshal(m, b)
@@ -886,6 +894,7 @@ func (m *UpdateRequest) GetNews() *_struct.Struct {
 
 type UpdateResponse struct {
 	Properties           *_struct.Struct `protobuf:"bytes,1,opt,name=properties" json:"properties,omitempty"`
+	Artifacts            []*Artifact     `protobuf:"bytes,2,rep,name=artifacts" json:"artifacts,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -895,7 +904,7 @@ func (m *UpdateResponse) Reset()         { *m = UpdateResponse{} }
 func (m *UpdateResponse) String() string { return proto.CompactTextString(m) }
 func (*UpdateResponse) ProtoMessage()    {}
 func (*UpdateResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{15}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{15}
 }
 func (m *UpdateResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateResponse.Unmarshal(m, b)
@@ -922,6 +931,13 @@ func (m *UpdateResponse) GetProperties() *_struct.Struct {
 	return nil
 }
 
+func (m *UpdateResponse) GetArtifacts() []*Artifact {
+	if m != nil {
+		return m.Artifacts
+	}
+	return nil
+}
+
 type DeleteRequest struct {
 	Id                   string          `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Urn                  string          `protobuf:"bytes,2,opt,name=urn" json:"urn,omitempty"`
@@ -935,7 +951,7 @@ func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
 func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteRequest) ProtoMessage()    {}
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{16}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{16}
 }
 func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
@@ -992,7 +1008,7 @@ func (m *ErrorResourceInitFailed) Reset()         { *m = ErrorResourceInitFailed
 func (m *ErrorResourceInitFailed) String() string { return proto.CompactTextString(m) }
 func (*ErrorResourceInitFailed) ProtoMessage()    {}
 func (*ErrorResourceInitFailed) Descriptor() ([]byte, []int) {
-	return fileDescriptor_provider_308463e06268e293, []int{17}
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{17}
 }
 func (m *ErrorResourceInitFailed) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ErrorResourceInitFailed.Unmarshal(m, b)
@@ -1040,6 +1056,391 @@ func (m *ErrorResourceInitFailed) GetInputs() *_struct.Struct {
 	return nil
 }
 
+// MigrateStateRequest asks a provider to upgrade the state of a resource last managed by an older version of it.
+type MigrateStateRequest struct {
+	Urn                  string          `protobuf:"bytes,1,opt,name=urn" json:"urn,omitempty"`
+	Id                   string          `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
+	Version              string          `protobuf:"bytes,3,opt,name=version" json:"version,omitempty"`
+	Inputs               *_struct.Struct `protobuf:"bytes,4,opt,name=inputs" json:"inputs,omitempty"`
+	Outputs              *_struct.Struct `protobuf:"bytes,5,opt,name=outputs" json:"outputs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *MigrateStateRequest) Reset()         { *m = MigrateStateRequest{} }
+func (m *MigrateStateRequest) String() string { return proto.CompactTextString(m) }
+func (*MigrateStateRequest) ProtoMessage()    {}
+func (*MigrateStateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{18}
+}
+func (m *MigrateStateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MigrateStateRequest.Unmarshal(m, b)
+}
+func (m *MigrateStateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MigrateStateRequest.Marshal(b, m, deterministic)
+}
+func (dst *MigrateStateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MigrateStateRequest.Merge(dst, src)
+}
+func (m *MigrateStateRequest) XXX_Size() int {
+	return xxx_messageInfo_MigrateStateRequest.Size(m)
+}
+func (m *MigrateStateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MigrateStateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MigrateStateRequest proto.InternalMessageInfo
+
+func (m *MigrateStateRequest) GetUrn() string {
+	if m != nil {
+		return m.Urn
+	}
+	return ""
+}
+
+func (m *MigrateStateRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *MigrateStateRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *MigrateStateRequest) GetInputs() *_struct.Struct {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *MigrateStateRequest) GetOutputs() *_struct.Struct {
+	if m != nil {
+		return m.Outputs
+	}
+	return nil
+}
+
+// MigrateStateResponse carries a resource's state as upgraded by the provider.
+type MigrateStateResponse struct {
+	Inputs               *_struct.Struct `protobuf:"bytes,1,opt,name=inputs" json:"inputs,omitempty"`
+	Outputs              *_struct.Struct `protobuf:"bytes,2,opt,name=outputs" json:"outputs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *MigrateStateResponse) Reset()         { *m = MigrateStateResponse{} }
+func (m *MigrateStateResponse) String() string { return proto.CompactTextString(m) }
+func (*MigrateStateResponse) ProtoMessage()    {}
+func (*MigrateStateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{19}
+}
+func (m *MigrateStateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MigrateStateResponse.Unmarshal(m, b)
+}
+func (m *MigrateStateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MigrateStateResponse.Marshal(b, m, deterministic)
+}
+func (dst *MigrateStateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MigrateStateResponse.Merge(dst, src)
+}
+func (m *MigrateStateResponse) XXX_Size() int {
+	return xxx_messageInfo_MigrateStateResponse.Size(m)
+}
+func (m *MigrateStateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MigrateStateResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MigrateStateResponse proto.InternalMessageInfo
+
+func (m *MigrateStateResponse) GetInputs() *_struct.Struct {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *MigrateStateResponse) GetOutputs() *_struct.Struct {
+	if m != nil {
+		return m.Outputs
+	}
+	return nil
+}
+
+// Artifact is a small file produced by a resource operation, e.g. a generated kubeconfig or certificate.
+type Artifact struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	ContentType          string   `protobuf:"bytes,2,opt,name=contentType" json:"contentType,omitempty"`
+	Contents             []byte   `protobuf:"bytes,3,opt,name=contents" json:"contents,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Artifact) Reset()         { *m = Artifact{} }
+func (m *Artifact) String() string { return proto.CompactTextString(m) }
+func (*Artifact) ProtoMessage()    {}
+func (*Artifact) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{20}
+}
+func (m *Artifact) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Artifact.Unmarshal(m, b)
+}
+func (m *Artifact) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Artifact.Marshal(b, m, deterministic)
+}
+func (dst *Artifact) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Artifact.Merge(dst, src)
+}
+func (m *Artifact) XXX_Size() int {
+	return xxx_messageInfo_Artifact.Size(m)
+}
+func (m *Artifact) XXX_DiscardUnknown() {
+	xxx_messageInfo_Artifact.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Artifact proto.InternalMessageInfo
+
+func (m *Artifact) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Artifact) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Artifact) GetContents() []byte {
+	if m != nil {
+		return m.Contents
+	}
+	return nil
+}
+
+// ExplainFailureRequest asks a provider to describe an error returned by an operation on a resource.
+type ExplainFailureRequest struct {
+	Urn                  string   `protobuf:"bytes,1,opt,name=urn" json:"urn,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExplainFailureRequest) Reset()         { *m = ExplainFailureRequest{} }
+func (m *ExplainFailureRequest) String() string { return proto.CompactTextString(m) }
+func (*ExplainFailureRequest) ProtoMessage()    {}
+func (*ExplainFailureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{21}
+}
+func (m *ExplainFailureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExplainFailureRequest.Unmarshal(m, b)
+}
+func (m *ExplainFailureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExplainFailureRequest.Marshal(b, m, deterministic)
+}
+func (dst *ExplainFailureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExplainFailureRequest.Merge(dst, src)
+}
+func (m *ExplainFailureRequest) XXX_Size() int {
+	return xxx_messageInfo_ExplainFailureRequest.Size(m)
+}
+func (m *ExplainFailureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExplainFailureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExplainFailureRequest proto.InternalMessageInfo
+
+func (m *ExplainFailureRequest) GetUrn() string {
+	if m != nil {
+		return m.Urn
+	}
+	return ""
+}
+
+func (m *ExplainFailureRequest) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// ExplainFailureResponse carries structured details about a failed operation.
+type ExplainFailureResponse struct {
+	Code                 string   `protobuf:"bytes,1,opt,name=code" json:"code,omitempty"`
+	Retryable            bool     `protobuf:"varint,2,opt,name=retryable" json:"retryable,omitempty"`
+	Remediation          []string `protobuf:"bytes,3,rep,name=remediation" json:"remediation,omitempty"`
+	DocsUrl              string   `protobuf:"bytes,4,opt,name=docsUrl" json:"docsUrl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExplainFailureResponse) Reset()         { *m = ExplainFailureResponse{} }
+func (m *ExplainFailureResponse) String() string { return proto.CompactTextString(m) }
+func (*ExplainFailureResponse) ProtoMessage()    {}
+func (*ExplainFailureResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{22}
+}
+func (m *ExplainFailureResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExplainFailureResponse.Unmarshal(m, b)
+}
+func (m *ExplainFailureResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExplainFailureResponse.Marshal(b, m, deterministic)
+}
+func (dst *ExplainFailureResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExplainFailureResponse.Merge(dst, src)
+}
+func (m *ExplainFailureResponse) XXX_Size() int {
+	return xxx_messageInfo_ExplainFailureResponse.Size(m)
+}
+func (m *ExplainFailureResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExplainFailureResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExplainFailureResponse proto.InternalMessageInfo
+
+func (m *ExplainFailureResponse) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *ExplainFailureResponse) GetRetryable() bool {
+	if m != nil {
+		return m.Retryable
+	}
+	return false
+}
+
+func (m *ExplainFailureResponse) GetRemediation() []string {
+	if m != nil {
+		return m.Remediation
+	}
+	return nil
+}
+
+func (m *ExplainFailureResponse) GetDocsUrl() string {
+	if m != nil {
+		return m.DocsUrl
+	}
+	return ""
+}
+
+// CheckHealthRequest asks a provider whether a resource is healthy.
+type CheckHealthRequest struct {
+	Urn                  string          `protobuf:"bytes,1,opt,name=urn" json:"urn,omitempty"`
+	Id                   string          `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
+	Properties           *_struct.Struct `protobuf:"bytes,3,opt,name=properties" json:"properties,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *CheckHealthRequest) Reset()         { *m = CheckHealthRequest{} }
+func (m *CheckHealthRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckHealthRequest) ProtoMessage()    {}
+func (*CheckHealthRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{23}
+}
+func (m *CheckHealthRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckHealthRequest.Unmarshal(m, b)
+}
+func (m *CheckHealthRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckHealthRequest.Marshal(b, m, deterministic)
+}
+func (dst *CheckHealthRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckHealthRequest.Merge(dst, src)
+}
+func (m *CheckHealthRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckHealthRequest.Size(m)
+}
+func (m *CheckHealthRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckHealthRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckHealthRequest proto.InternalMessageInfo
+
+func (m *CheckHealthRequest) GetUrn() string {
+	if m != nil {
+		return m.Urn
+	}
+	return ""
+}
+
+func (m *CheckHealthRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *CheckHealthRequest) GetProperties() *_struct.Struct {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
+// CheckHealthResponse reports the health of a resource.
+type CheckHealthResponse struct {
+	Healthy              bool     `protobuf:"varint,1,opt,name=healthy" json:"healthy,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckHealthResponse) Reset()         { *m = CheckHealthResponse{} }
+func (m *CheckHealthResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckHealthResponse) ProtoMessage()    {}
+func (*CheckHealthResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_provider_03e0cf4fb6a190ff, []int{24}
+}
+func (m *CheckHealthResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckHealthResponse.Unmarshal(m, b)
+}
+func (m *CheckHealthResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckHealthResponse.Marshal(b, m, deterministic)
+}
+func (dst *CheckHealthResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckHealthResponse.Merge(dst, src)
+}
+func (m *CheckHealthResponse) XXX_Size() int {
+	return xxx_messageInfo_CheckHealthResponse.Size(m)
+}
+func (m *CheckHealthResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckHealthResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckHealthResponse proto.InternalMessageInfo
+
+func (m *CheckHealthResponse) GetHealthy() bool {
+	if m != nil {
+		return m.Healthy
+	}
+	return false
+}
+
+func (m *CheckHealthResponse) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*ConfigureRequest)(nil), "pulumirpc.ConfigureRequest")
 	proto.RegisterMapType((map[string]string)(nil), "pulumirpc.ConfigureRequest.VariablesEntry")
@@ -1061,6 +1462,13 @@ func init() {
 	proto.RegisterType((*UpdateResponse)(nil), "pulumirpc.UpdateResponse")
 	proto.RegisterType((*DeleteRequest)(nil), "pulumirpc.DeleteRequest")
 	proto.RegisterType((*ErrorResourceInitFailed)(nil), "pulumirpc.ErrorResourceInitFailed")
+	proto.RegisterType((*MigrateStateRequest)(nil), "pulumirpc.MigrateStateRequest")
+	proto.RegisterType((*MigrateStateResponse)(nil), "pulumirpc.MigrateStateResponse")
+	proto.RegisterType((*Artifact)(nil), "pulumirpc.Artifact")
+	proto.RegisterType((*ExplainFailureRequest)(nil), "pulumirpc.ExplainFailureRequest")
+	proto.RegisterType((*ExplainFailureResponse)(nil), "pulumirpc.ExplainFailureResponse")
+	proto.RegisterType((*CheckHealthRequest)(nil), "pulumirpc.CheckHealthRequest")
+	proto.RegisterType((*CheckHealthResponse)(nil), "pulumirpc.CheckHealthResponse")
 	proto.RegisterEnum("pulumirpc.DiffResponse_DiffChanges", DiffResponse_DiffChanges_name, DiffResponse_DiffChanges_value)
 }
 
@@ -1105,6 +1513,15 @@ type ResourceProviderClient interface {
 	Cancel(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
 	// GetPluginInfo returns generic information about this plugin, like its version.
 	GetPluginInfo(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*PluginInfo, error)
+	// MigrateState upgrades the state of a resource that was last managed by an older version of this provider into
+	// the form this version expects. Providers that have no need to migrate state need not implement it.
+	MigrateState(ctx context.Context, in *MigrateStateRequest, opts ...grpc.CallOption) (*MigrateStateResponse, error)
+	// ExplainFailure returns structured details about an error returned by an operation on a resource, e.g. a code,
+	// remediation hints, and a link to documentation. Providers that have nothing to add need not implement it.
+	ExplainFailure(ctx context.Context, in *ExplainFailureRequest, opts ...grpc.CallOption) (*ExplainFailureResponse, error)
+	// CheckHealth reports whether a resource is healthy, e.g. ready to serve traffic. Providers that are unable to
+	// check the health of their resources need not implement it.
+	CheckHealth(ctx context.Context, in *CheckHealthRequest, opts ...grpc.CallOption) (*CheckHealthResponse, error)
 }
 
 type resourceProviderClient struct {
@@ -1223,6 +1640,33 @@ func (c *resourceProviderClient) GetPluginInfo(ctx context.Context, in *empty.Em
 	return out, nil
 }
 
+func (c *resourceProviderClient) MigrateState(ctx context.Context, in *MigrateStateRequest, opts ...grpc.CallOption) (*MigrateStateResponse, error) {
+	out := new(MigrateStateResponse)
+	err := grpc.Invoke(ctx, "/pulumirpc.ResourceProvider/MigrateState", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *resourceProviderClient) ExplainFailure(ctx context.Context, in *ExplainFailureRequest, opts ...grpc.CallOption) (*ExplainFailureResponse, error) {
+	out := new(ExplainFailureResponse)
+	err := grpc.Invoke(ctx, "/pulumirpc.ResourceProvider/ExplainFailure", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *resourceProviderClient) CheckHealth(ctx context.Context, in *CheckHealthRequest, opts ...grpc.CallOption) (*CheckHealthResponse, error) {
+	out := new(CheckHealthResponse)
+	err := grpc.Invoke(ctx, "/pulumirpc.ResourceProvider/CheckHealth", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for ResourceProvider service
 
 type ResourceProviderServer interface {
@@ -1256,6 +1700,15 @@ type ResourceProviderServer interface {
 	Cancel(context.Context, *empty.Empty) (*empty.Empty, error)
 	// GetPluginInfo returns generic information about this plugin, like its version.
 	GetPluginInfo(context.Context, *empty.Empty) (*PluginInfo, error)
+	// MigrateState upgrades the state of a resource that was last managed by an older version of this provider into
+	// the form this version expects. Providers that have no need to migrate state need not implement it.
+	MigrateState(context.Context, *MigrateStateRequest) (*MigrateStateResponse, error)
+	// ExplainFailure returns structured details about an error returned by an operation on a resource, e.g. a code,
+	// remediation hints, and a link to documentation. Providers that have nothing to add need not implement it.
+	ExplainFailure(context.Context, *ExplainFailureRequest) (*ExplainFailureResponse, error)
+	// CheckHealth reports whether a resource is healthy, e.g. ready to serve traffic. Providers that are unable to
+	// check the health of their resources need not implement it.
+	CheckHealth(context.Context, *CheckHealthRequest) (*CheckHealthResponse, error)
 }
 
 func RegisterResourceProviderServer(s *grpc.Server, srv ResourceProviderServer) {
@@ -1478,6 +1931,60 @@ func _ResourceProvider_GetPluginInfo_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ResourceProvider_MigrateState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrateStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResourceProviderServer).MigrateState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.ResourceProvider/MigrateState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResourceProviderServer).MigrateState(ctx, req.(*MigrateStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ResourceProvider_ExplainFailure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainFailureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResourceProviderServer).ExplainFailure(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.ResourceProvider/ExplainFailure",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResourceProviderServer).ExplainFailure(ctx, req.(*ExplainFailureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ResourceProvider_CheckHealth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckHealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ResourceProviderServer).CheckHealth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.ResourceProvider/CheckHealth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ResourceProviderServer).CheckHealth(ctx, req.(*CheckHealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ResourceProvider_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "pulumirpc.ResourceProvider",
 	HandlerType: (*ResourceProviderServer)(nil),
@@ -1530,76 +2037,105 @@ var _ResourceProvider_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetPluginInfo",
 			Handler:    _ResourceProvider_GetPluginInfo_Handler,
 		},
+		{
+			MethodName: "MigrateState",
+			Handler:    _ResourceProvider_MigrateState_Handler,
+		},
+		{
+			MethodName: "ExplainFailure",
+			Handler:    _ResourceProvider_ExplainFailure_Handler,
+		},
+		{
+			MethodName: "CheckHealth",
+			Handler:    _ResourceProvider_CheckHealth_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "provider.proto",
 }
 
-func init() { proto.RegisterFile("provider.proto", fileDescriptor_provider_308463e06268e293) }
-
-var fileDescriptor_provider_308463e06268e293 = []byte{
-	// 997 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xc4, 0x57, 0x4d, 0x6f, 0xdb, 0x46,
-	0x13, 0x16, 0x25, 0x59, 0xb6, 0x46, 0x1f, 0xd0, 0xbb, 0x6f, 0x6b, 0x2b, 0x4c, 0x0e, 0x02, 0xdb,
-	0x83, 0xd1, 0x02, 0x72, 0xe1, 0x1c, 0xda, 0x04, 0x09, 0x52, 0xd8, 0x96, 0x5b, 0x21, 0x88, 0x9c,
-	0xd2, 0x48, 0x8b, 0xf6, 0x52, 0xd0, 0xe4, 0x50, 0x61, 0x45, 0x93, 0xec, 0x72, 0xa9, 0xc2, 0x3d,
-	0xf7, 0xd0, 0x8f, 0x6b, 0x2f, 0xfd, 0x11, 0xbd, 0xf4, 0x7f, 0xf5, 0x3f, 0x14, 0xfb, 0x41, 0x8a,
-	0x6b, 0xc9, 0x8a, 0x6c, 0xa4, 0xe8, 0x8d, 0xa3, 0x99, 0xdd, 0x79, 0xe6, 0x99, 0x99, 0x87, 0x14,
-	0x74, 0x13, 0x1a, 0xcf, 0x03, 0x0f, 0xe9, 0x30, 0xa1, 0x31, 0x8b, 0x49, 0x33, 0xc9, 0xc2, 0xec,
-	0x32, 0xa0, 0x89, 0x6b, 0xb6, 0x93, 0x30, 0x9b, 0x06, 0x91, 0x74, 0x98, 0xf7, 0xa7, 0x71, 0x3c,
-	0x0d, 0xf1, 0x40, 0x58, 0x17, 0x99, 0x7f, 0x80, 0x97, 0x09, 0xbb, 0x52, 0xce, 0x07, 0xd7, 0x9d,
-	0x29, 0xa3, 0x99, 0xcb, 0xa4, 0xd7, 0xfa, 0xdb, 0x80, 0xde, 0x71, 0x1c, 0xf9, 0xc1, 0x34, 0xa3,
-	0x68, 0xe3, 0xf7, 0x19, 0xa6, 0x8c, 0x7c, 0x0e, 0xcd, 0xb9, 0x43, 0x03, 0xe7, 0x22, 0xc4, 0xb4,
-	0x6f, 0x0c, 0x6a, 0xfb, 0xad, 0xc3, 0x0f, 0x86, 0x45, 0xf2, 0xe1, 0xf5, 0xf8, 0xe1, 0x97, 0x79,
-	0xf0, 0x28, 0x62, 0xf4, 0xca, 0x5e, 0x1c, 0x26, 0x1f, 0x42, 0xdd, 0xa1, 0xd3, 0xb4, 0x5f, 0x1d,
-	0x18, 0xfb, 0xad, 0xc3, 0xbd, 0xa1, 0xc4, 0x32, 0xcc, 0xb1, 0x0c, 0xcf, 0x05, 0x16, 0x5b, 0x04,
-	0x91, 0xf7, 0xa1, 0xe3, 0xb8, 0x2e, 0x26, 0xec, 0x1c, 0x5d, 0x8a, 0x2c, 0xed, 0xd7, 0x06, 0xc6,
-	0xfe, 0x8e, 0xad, 0xff, 0x68, 0x3e, 0x81, 0xae, 0x9e, 0x8f, 0xf4, 0xa0, 0x36, 0xc3, 0xab, 0xbe,
-	0x31, 0x30, 0xf6, 0x9b, 0x36, 0x7f, 0x24, 0xef, 0xc0, 0xd6, 0xdc, 0x09, 0x33, 0x14, 0x79, 0x9b,
-	0xb6, 0x34, 0x1e, 0x57, 0x3f, 0x31, 0xac, 0x47, 0xf0, 0xbf, 0x12, 0xfc, 0x34, 0x89, 0xa3, 0x14,
-	0x97, 0x13, 0x1b, 0x2b, 0x12, 0x5b, 0x7f, 0x19, 0x70, 0xaf, 0x38, 0x3b, 0xa2, 0x34, 0xa6, 0x2f,
-	0x82, 0x34, 0x0d, 0xa2, 0xe9, 0x73, 0xbc, 0x4a, 0xc9, 0x17, 0xd0, 0xba, 0x5c, 0x98, 0x8a, 0xb5,
-	0x83, 0x55, 0xac, 0x5d, 0x3f, 0x3a, 0x5c, 0x3c, 0xdb, 0xe5, 0x3b, 0xcc, 0x23, 0x80, 0x85, 0x8b,
-	0x10, 0xa8, 0x47, 0xce, 0x25, 0xaa, 0x32, 0xc5, 0x33, 0x19, 0x40, 0xcb, 0xc3, 0xd4, 0xa5, 0x41,
-	0xc2, 0x82, 0x38, 0x52, 0xd5, 0x96, 0x7f, 0xb2, 0x7e, 0x32, 0xa0, 0x33, 0x8e, 0xe6, 0xf1, 0xac,
-	0x68, 0x6e, 0x0f, 0x6a, 0x2c, 0x9e, 0xe5, 0x6c, 0xb1, 0x78, 0x76, 0xbb, 0x26, 0x99, 0xb0, 0x93,
-	0x8f, 0xa5, 0xe8, 0x4f, 0xd3, 0x2e, 0x6c, 0xd2, 0x87, 0xed, 0x39, 0xd2, 0x94, 0x43, 0xa9, 0x0b,
-	0x57, 0x6e, 0x5a, 0x73, 0xe8, 0xe6, 0x28, 0x14, 0xe7, 0x07, 0xd0, 0xa0, 0xc8, 0x32, 0x1a, 0x09,
-	0x24, 0x6b, 0xd2, 0xaa, 0x30, 0xf2, 0x10, 0x76, 0x7c, 0x27, 0x08, 0x33, 0x8a, 0x1c, 0x69, 0x4d,
-	0x1c, 0x29, 0xb1, 0xfb, 0x1a, 0xdd, 0xd9, 0xa9, 0xf4, 0xdb, 0x45, 0xa0, 0xf5, 0x23, 0xb4, 0x85,
-	0xa7, 0x54, 0x7c, 0x9e, 0xb2, 0x69, 0xf3, 0x47, 0x5e, 0x7c, 0x1c, 0x7a, 0x6f, 0x2e, 0x9e, 0x07,
-	0xf1, 0xe0, 0x08, 0x7f, 0x90, 0x83, 0xb9, 0x2e, 0x98, 0x07, 0x59, 0x19, 0x74, 0x54, 0xee, 0x45,
-	0xc9, 0x41, 0x94, 0x64, 0x6a, 0xbe, 0xd6, 0x95, 0x2c, 0xc3, 0xee, 0x56, 0xf2, 0x91, 0x2a, 0x59,
-	0x79, 0x54, 0xc3, 0x12, 0xa4, 0x2c, 0x5f, 0x91, 0xc2, 0x26, 0xbb, 0xbc, 0x09, 0x4e, 0x5a, 0x8c,
-	0x8e, 0xb2, 0xac, 0x5f, 0x0c, 0x68, 0x9d, 0x04, 0xbe, 0x9f, 0xd3, 0xd6, 0x85, 0x6a, 0xe0, 0xa9,
-	0xd3, 0xd5, 0xc0, 0xcb, 0x69, 0xac, 0x2e, 0xd3, 0x58, 0xbb, 0x0d, 0x8d, 0xf5, 0x4d, 0x68, 0xfc,
-	0xb5, 0x0a, 0x6d, 0x89, 0x45, 0xd1, 0x68, 0xc2, 0x0e, 0xc5, 0x24, 0x74, 0x5c, 0x25, 0x4e, 0x4d,
-	0xbb, 0xb0, 0xf9, 0x04, 0xa6, 0x4c, 0xea, 0x56, 0x55, 0xb8, 0x72, 0x93, 0x7c, 0x04, 0xff, 0xf7,
-	0x30, 0x44, 0x86, 0x47, 0xe8, 0xc7, 0x7c, 0xf7, 0xc5, 0x09, 0x25, 0x31, 0xab, 0x5c, 0xe4, 0x29,
-	0x6c, 0xbb, 0xaf, 0x9d, 0x68, 0x8a, 0x12, 0x68, 0xf7, 0xf0, 0xbd, 0x12, 0xf9, 0x65, 0x44, 0xc2,
-	0x38, 0x96, 0xa1, 0x76, 0x7e, 0x86, 0x6b, 0x90, 0x17, 0xf8, 0x7e, 0xda, 0xdf, 0x12, 0x40, 0xa4,
-	0x61, 0x3d, 0x95, 0xc4, 0xaa, 0x68, 0xd2, 0x83, 0xf6, 0xc9, 0xf8, 0xf4, 0xf4, 0xdb, 0x57, 0x93,
-	0xe7, 0x93, 0xb3, 0xaf, 0x26, 0xbd, 0x0a, 0xe9, 0x40, 0x53, 0xfc, 0x32, 0x39, 0x9b, 0x8c, 0x7a,
-	0x46, 0x61, 0x9e, 0x9f, 0xbd, 0x18, 0xf5, 0xaa, 0xd6, 0x37, 0xd0, 0x39, 0xa6, 0xe8, 0x30, 0xbc,
-	0x79, 0xa0, 0x3f, 0x06, 0x50, 0xfd, 0x0d, 0xf0, 0x8d, 0x63, 0x5d, 0x0a, 0xb5, 0xbe, 0x86, 0x6e,
-	0x7e, 0xb7, 0x62, 0xfa, 0x7a, 0xdb, 0xef, 0x7c, 0xf5, 0x1f, 0x06, 0xb4, 0x6c, 0x74, 0xbc, 0xcd,
-	0xe7, 0x49, 0x4f, 0x55, 0xdb, 0x38, 0x55, 0x69, 0xc9, 0xea, 0x1b, 0x2d, 0x99, 0xf5, 0xb3, 0x01,
-	0x6d, 0x89, 0xed, 0x2d, 0x57, 0x5d, 0x82, 0x52, 0xdb, 0x0c, 0xca, 0x6f, 0x06, 0x74, 0x5e, 0x25,
-	0x5e, 0xa9, 0xbd, 0xff, 0xe5, 0xe2, 0x8d, 0xa1, 0x9b, 0x83, 0x51, 0xcc, 0xe8, 0x4c, 0x18, 0x9b,
-	0xf7, 0xff, 0x3b, 0xe8, 0x9c, 0x88, 0x0d, 0xfb, 0xf7, 0x07, 0xc0, 0xfa, 0xd3, 0x80, 0x3d, 0xf1,
-	0x8a, 0xb5, 0x31, 0x8d, 0x33, 0xea, 0xe2, 0x38, 0x0a, 0x18, 0x17, 0x43, 0xf4, 0xde, 0x5e, 0x6b,
-	0xfb, 0xb0, 0x2d, 0xa5, 0x92, 0x43, 0x13, 0x3a, 0xa3, 0xcc, 0x5b, 0xcf, 0xdf, 0xe1, 0xef, 0x0d,
-	0xe8, 0xe5, 0x50, 0x5f, 0xe6, 0x6f, 0xd2, 0x23, 0x68, 0x09, 0x11, 0x97, 0x1f, 0x0d, 0x64, 0x49,
-	0xf6, 0x15, 0x8f, 0x66, 0x7f, 0xd9, 0x21, 0x7b, 0x65, 0x55, 0xc8, 0x33, 0x00, 0x21, 0x35, 0xf2,
-	0x8a, 0xdd, 0x25, 0xf1, 0x92, 0x37, 0xec, 0xdd, 0x20, 0x6a, 0x56, 0x85, 0x7f, 0x06, 0x16, 0x1f,
-	0x2d, 0xe4, 0xfe, 0x9a, 0x0f, 0x40, 0xf3, 0xc1, 0x6a, 0x67, 0x09, 0x4a, 0x43, 0xbe, 0xfe, 0x49,
-	0x19, 0xb0, 0xf6, 0x5d, 0x62, 0xde, 0x5b, 0xe1, 0x29, 0x2e, 0x78, 0x02, 0x5b, 0xa2, 0xbc, 0xbb,
-	0x31, 0xf1, 0x08, 0xea, 0xbc, 0xb4, 0xbb, 0x70, 0xf0, 0x0c, 0x1a, 0x52, 0x14, 0x35, 0xe4, 0x9a,
-	0x06, 0x6b, 0xc8, 0x75, 0x05, 0x95, 0xb9, 0xb9, 0xba, 0x68, 0xb9, 0x4b, 0x52, 0xa8, 0xe5, 0x2e,
-	0xcb, 0x90, 0xcc, 0x2d, 0x17, 0x50, 0xcb, 0xad, 0x09, 0x84, 0x96, 0x5b, 0xdf, 0x56, 0xc1, 0x5a,
-	0x43, 0xae, 0x9d, 0x76, 0x81, 0xb6, 0x89, 0xe6, 0xee, 0xd2, 0x7c, 0x8e, 0xf8, 0x9f, 0x07, 0xab,
-	0x42, 0x1e, 0x43, 0xe3, 0xd8, 0x89, 0x5c, 0x0c, 0xc9, 0x0d, 0x31, 0x6b, 0xce, 0x7e, 0x0a, 0x9d,
-	0xcf, 0x90, 0xbd, 0x14, 0x7f, 0x52, 0xc6, 0x91, 0x1f, 0xdf, 0x78, 0xc5, 0xbb, 0x25, 0x60, 0x8b,
-	0x70, 0xab, 0x72, 0xd1, 0x10, 0x81, 0x0f, 0xff, 0x09, 0x00, 0x00, 0xff, 0xff, 0x51, 0x61, 0x5d,
-	0xad, 0x05, 0x0d, 0x00, 0x00,
+func init() { proto.RegisterFile("provider.proto", fileDescriptor_provider_03e0cf4fb6a190ff) }
+
+var fileDescriptor_provider_03e0cf4fb6a190ff = []byte{
+	// 1270 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0xc5, 0x58, 0x4b, 0x6f, 0xdb, 0x46,
+	0x10, 0x0e, 0x25, 0xd9, 0x96, 0xc6, 0x92, 0xa0, 0xac, 0x13, 0x5b, 0x61, 0xdc, 0xd4, 0x61, 0x7b,
+	0x08, 0x5a, 0x40, 0x6e, 0x9d, 0x43, 0x9b, 0x20, 0x41, 0x5a, 0xc7, 0x72, 0x62, 0x04, 0x96, 0x13,
+	0xba, 0x4e, 0x80, 0xa2, 0x40, 0x41, 0x93, 0x2b, 0x99, 0x35, 0x4d, 0xb2, 0xe4, 0x52, 0xad, 0x8a,
+	0x1e, 0x8b, 0xa2, 0xaf, 0x43, 0xaf, 0xfd, 0x11, 0xbd, 0x14, 0xe8, 0xcf, 0xea, 0x7f, 0xe8, 0x3e,
+	0x29, 0x52, 0xaf, 0xc8, 0x42, 0x8a, 0xde, 0x76, 0x76, 0x66, 0x67, 0xbe, 0x99, 0x9d, 0x9d, 0x19,
+	0x12, 0xea, 0x61, 0x14, 0xf4, 0x5d, 0x07, 0x47, 0x2d, 0xba, 0x20, 0x01, 0xaa, 0x84, 0x89, 0x97,
+	0x5c, 0xb8, 0x51, 0x68, 0xeb, 0xd5, 0xd0, 0x4b, 0x7a, 0xae, 0x2f, 0x18, 0xfa, 0xcd, 0x5e, 0x10,
+	0xf4, 0x3c, 0xbc, 0xcd, 0xa9, 0xd3, 0xa4, 0xbb, 0x8d, 0x2f, 0x42, 0x32, 0x90, 0xcc, 0xcd, 0x51,
+	0x66, 0x4c, 0xa2, 0xc4, 0x26, 0x82, 0x6b, 0xfc, 0xa3, 0x41, 0xe3, 0x71, 0xe0, 0x77, 0xdd, 0x5e,
+	0x12, 0x61, 0x13, 0x7f, 0x9d, 0xe0, 0x98, 0xa0, 0xa7, 0x50, 0xe9, 0x5b, 0x91, 0x6b, 0x9d, 0x7a,
+	0x38, 0x6e, 0x6a, 0x5b, 0xc5, 0x3b, 0xab, 0x3b, 0xef, 0xb5, 0x52, 0xe3, 0xad, 0x51, 0xf9, 0xd6,
+	0x4b, 0x25, 0xdc, 0xf6, 0x49, 0x34, 0x30, 0x87, 0x87, 0xd1, 0xfb, 0x50, 0xb2, 0xa2, 0x5e, 0xdc,
+	0x2c, 0x6c, 0x69, 0x54, 0xc9, 0x46, 0x4b, 0x60, 0x69, 0x29, 0x2c, 0xad, 0x63, 0x8e, 0xc5, 0xe4,
+	0x42, 0xe8, 0x5d, 0xa8, 0x59, 0xb6, 0x8d, 0x43, 0x72, 0x8c, 0xed, 0x08, 0x93, 0xb8, 0x59, 0xa4,
+	0xa7, 0xca, 0x66, 0x7e, 0x53, 0x7f, 0x00, 0xf5, 0xbc, 0x3d, 0xd4, 0x80, 0xe2, 0x39, 0x1e, 0x50,
+	0xa0, 0xda, 0x9d, 0x8a, 0xc9, 0x96, 0xe8, 0x1a, 0x2c, 0xf5, 0x2d, 0x2f, 0xc1, 0xdc, 0x6e, 0xc5,
+	0x14, 0xc4, 0xfd, 0xc2, 0xc7, 0x9a, 0x71, 0x0f, 0xae, 0x66, 0xe0, 0xc7, 0x61, 0xe0, 0xc7, 0x78,
+	0xdc, 0xb0, 0x36, 0xc1, 0xb0, 0xf1, 0x97, 0x06, 0x37, 0xd2, 0xb3, 0xed, 0x28, 0x0a, 0xa2, 0x43,
+	0x37, 0x8e, 0x5d, 0xbf, 0xf7, 0x0c, 0x0f, 0x62, 0xf4, 0x02, 0x56, 0x2f, 0x86, 0xa4, 0x8c, 0xda,
+	0xf6, 0xa4, 0xa8, 0x8d, 0x1e, 0x6d, 0x0d, 0xd7, 0x66, 0x56, 0x87, 0xbe, 0x0b, 0x30, 0x64, 0x21,
+	0x04, 0x25, 0xdf, 0xba, 0xc0, 0xd2, 0x4d, 0xbe, 0x46, 0x5b, 0xb0, 0xea, 0xe0, 0xd8, 0x8e, 0xdc,
+	0x90, 0xb8, 0x81, 0x2f, 0xbd, 0xcd, 0x6e, 0x19, 0x3f, 0x68, 0x50, 0x3b, 0xf0, 0xfb, 0xc1, 0x79,
+	0x7a, 0xb9, 0x34, 0x5a, 0x24, 0x38, 0x57, 0xd1, 0xa2, 0xcb, 0xcb, 0x5d, 0x92, 0x0e, 0x65, 0x95,
+	0x96, 0xfc, 0x7e, 0x2a, 0x66, 0x4a, 0xa3, 0x26, 0xac, 0xf4, 0x71, 0x14, 0x33, 0x28, 0x25, 0xce,
+	0x52, 0xa4, 0xd1, 0x87, 0xba, 0x42, 0x21, 0x63, 0xbe, 0x0d, 0xcb, 0x34, 0xaa, 0x49, 0xe4, 0x73,
+	0x24, 0x33, 0xcc, 0x4a, 0x31, 0x74, 0x17, 0xca, 0x5d, 0xcb, 0xf5, 0x68, 0x00, 0x19, 0xd2, 0x22,
+	0x3f, 0x92, 0x89, 0xee, 0x19, 0xb6, 0xcf, 0xf7, 0x05, 0xdf, 0x4c, 0x05, 0x8d, 0xef, 0xa0, 0xca,
+	0x39, 0x19, 0xe7, 0x95, 0x49, 0xea, 0x3c, 0x53, 0x4b, 0x9d, 0x0f, 0x3c, 0xe7, 0xf5, 0xce, 0x33,
+	0x21, 0x26, 0xec, 0xe3, 0x6f, 0x44, 0x62, 0xce, 0x12, 0x66, 0x42, 0x46, 0x02, 0x35, 0x69, 0x7b,
+	0xe8, 0xb2, 0xeb, 0x87, 0x89, 0xcc, 0xaf, 0x59, 0x2e, 0x0b, 0xb1, 0xc5, 0x5c, 0xde, 0x95, 0x2e,
+	0x4b, 0x8e, 0xbc, 0xb0, 0x10, 0x47, 0x44, 0x3d, 0x91, 0x94, 0x46, 0xeb, 0xec, 0x12, 0xac, 0x38,
+	0x4d, 0x1d, 0x49, 0x19, 0x3f, 0x6b, 0xb0, 0xba, 0xe7, 0x76, 0xbb, 0x2a, 0x6c, 0x75, 0x28, 0xb8,
+	0x8e, 0x3c, 0x4d, 0x57, 0x2a, 0x8c, 0x85, 0xf1, 0x30, 0x16, 0x2f, 0x13, 0xc6, 0xd2, 0x3c, 0x61,
+	0xfc, 0xa5, 0x00, 0x55, 0x81, 0x45, 0x86, 0x91, 0x3a, 0x14, 0xe1, 0xd0, 0xb3, 0x6c, 0x59, 0x9c,
+	0xa8, 0x43, 0x8a, 0x66, 0x19, 0x18, 0x13, 0x51, 0xb7, 0x0a, 0x9c, 0xa5, 0x48, 0xf4, 0x01, 0xac,
+	0x39, 0xd8, 0xc3, 0x04, 0xef, 0xe2, 0x6e, 0xc0, 0xde, 0x3e, 0x3f, 0x21, 0x4b, 0xcc, 0x24, 0x16,
+	0x7a, 0x08, 0x2b, 0xf6, 0x99, 0xe5, 0xf7, 0xb0, 0x00, 0x5a, 0xdf, 0x79, 0x27, 0x13, 0xfc, 0x2c,
+	0x22, 0x4e, 0x3c, 0x16, 0xa2, 0xa6, 0x3a, 0xc3, 0x6a, 0x90, 0x43, 0xf7, 0xe3, 0xe6, 0x12, 0x07,
+	0x22, 0x08, 0xe3, 0xa1, 0x08, 0xac, 0x94, 0xa6, 0x81, 0xac, 0xee, 0x1d, 0xec, 0xef, 0x7f, 0x79,
+	0xd2, 0x79, 0xd6, 0x39, 0x7a, 0xd5, 0x69, 0x5c, 0x41, 0x35, 0xa8, 0xf0, 0x9d, 0xce, 0x51, 0xa7,
+	0xdd, 0xd0, 0x52, 0xf2, 0xf8, 0xe8, 0xb0, 0xdd, 0x28, 0x18, 0x9f, 0xd3, 0x9c, 0xa2, 0x77, 0x44,
+	0xf0, 0xf4, 0x84, 0xfe, 0x08, 0x40, 0xde, 0xaf, 0x8b, 0x5f, 0x9b, 0xd6, 0x19, 0x51, 0xe3, 0x37,
+	0x0d, 0xea, 0x4a, 0xb9, 0x0c, 0xf5, 0xe8, 0xbd, 0x2f, 0xaa, 0x1b, 0x7d, 0x08, 0x15, 0x8b, 0xae,
+	0xba, 0x96, 0xcd, 0xcb, 0x3a, 0x4b, 0xe5, 0xb5, 0x4c, 0x34, 0x3f, 0x95, 0x3c, 0x73, 0x28, 0x65,
+	0xfc, 0x41, 0x73, 0xd0, 0xc4, 0x96, 0x33, 0x7f, 0x0e, 0xe6, 0xd1, 0x15, 0xe7, 0x47, 0x37, 0x7c,
+	0x98, 0xa5, 0xb9, 0x1e, 0xa6, 0xf1, 0x93, 0x06, 0x55, 0x81, 0xed, 0x4d, 0x07, 0x6a, 0x08, 0xa5,
+	0x38, 0x1f, 0x94, 0x5f, 0x69, 0x81, 0x3f, 0x09, 0x9d, 0x4c, 0x4a, 0xfc, 0x9f, 0x8f, 0xf5, 0x7b,
+	0xa8, 0x2b, 0x30, 0x32, 0x32, 0xf9, 0x48, 0x68, 0x0b, 0xa6, 0x4c, 0x61, 0xae, 0x94, 0xf9, 0x0a,
+	0x6a, 0x7b, 0xfc, 0x21, 0xff, 0xf7, 0x39, 0x63, 0xfc, 0xa9, 0xc1, 0x06, 0xef, 0xe4, 0xd4, 0xd3,
+	0x20, 0x89, 0x6c, 0x7c, 0xe0, 0xbb, 0x84, 0xd5, 0x5c, 0xec, 0xbc, 0xb9, 0x6c, 0xa0, 0xe5, 0x4c,
+	0x54, 0x64, 0xf1, 0x68, 0x68, 0x39, 0x93, 0xe4, 0xe5, 0x53, 0xf6, 0x6f, 0x0d, 0xd6, 0x0e, 0xdd,
+	0x5e, 0x44, 0xef, 0xe6, 0x98, 0xcc, 0x2c, 0x20, 0x02, 0x7d, 0x21, 0x45, 0x9f, 0xe9, 0xea, 0xc5,
+	0x5c, 0x57, 0xbf, 0x34, 0x08, 0x7a, 0xa7, 0x2b, 0x41, 0x42, 0xf8, 0x89, 0xa5, 0xd9, 0x27, 0x94,
+	0x1c, 0xed, 0xe0, 0xd7, 0xf2, 0xb0, 0x17, 0x6d, 0xa6, 0x19, 0xdb, 0x85, 0x39, 0x6d, 0x7f, 0x01,
+	0x65, 0x95, 0x66, 0xd3, 0xc6, 0x2f, 0x3b, 0xf0, 0x09, 0xf6, 0xc9, 0x67, 0x83, 0x50, 0x0d, 0x9b,
+	0xd9, 0x2d, 0xd6, 0xab, 0x24, 0x29, 0x92, 0xab, 0x6a, 0xa6, 0xb4, 0xf1, 0x08, 0xae, 0xb7, 0xbf,
+	0xa5, 0xad, 0xc6, 0xf5, 0x55, 0x13, 0x9f, 0x7a, 0x25, 0xb4, 0x97, 0x60, 0x96, 0x6b, 0x6a, 0x9e,
+	0xe5, 0x84, 0xf1, 0xa3, 0x06, 0xeb, 0xa3, 0x1a, 0x64, 0x74, 0x28, 0x5a, 0x3b, 0x70, 0x52, 0xb4,
+	0x6c, 0x8d, 0x36, 0xa1, 0x42, 0x47, 0xa9, 0x68, 0xc0, 0xfa, 0x21, 0x57, 0x54, 0x36, 0x87, 0x1b,
+	0xcc, 0x97, 0x08, 0x5f, 0x60, 0xc7, 0xb5, 0x88, 0xb8, 0x69, 0x96, 0x6e, 0xd9, 0x2d, 0x96, 0x07,
+	0x4e, 0x60, 0xc7, 0x27, 0x91, 0xa7, 0xa6, 0x3b, 0x49, 0x1a, 0x01, 0x20, 0x3e, 0x72, 0x3c, 0xc5,
+	0x96, 0x47, 0xce, 0xe6, 0xcf, 0xac, 0x85, 0x1f, 0xdf, 0x13, 0x58, 0xcb, 0x19, 0x94, 0x5e, 0x53,
+	0x84, 0x67, 0x7c, 0x67, 0x20, 0x27, 0x78, 0x45, 0x4e, 0x1b, 0x74, 0x76, 0x7e, 0x2f, 0x43, 0x43,
+	0x3d, 0xe0, 0xe7, 0x6a, 0x8c, 0xdd, 0x85, 0x55, 0xae, 0x5d, 0x4c, 0xec, 0x68, 0x6c, 0xe6, 0x92,
+	0x0e, 0xea, 0xcd, 0x71, 0x86, 0x00, 0x62, 0x5c, 0x41, 0x8f, 0x00, 0x78, 0x9f, 0x17, 0x2a, 0xd6,
+	0xc7, 0x26, 0x07, 0xa1, 0x61, 0x63, 0xca, 0x44, 0x41, 0x15, 0xd0, 0x6f, 0xb0, 0xf4, 0x8b, 0x01,
+	0xdd, 0x9c, 0xf1, 0xf5, 0xa5, 0x6f, 0x4e, 0x66, 0x66, 0xa0, 0x2c, 0x8b, 0xd9, 0x1b, 0x65, 0x01,
+	0xe7, 0x3e, 0x0a, 0xf4, 0x1b, 0x13, 0x38, 0xa9, 0x82, 0x07, 0xb0, 0xc4, 0xdd, 0x5b, 0x2c, 0x12,
+	0xf7, 0xa0, 0xc4, 0x5c, 0x5b, 0x24, 0x06, 0x14, 0xb9, 0x18, 0x48, 0x72, 0xc8, 0x73, 0x03, 0x50,
+	0x0e, 0x79, 0x7e, 0x7a, 0x11, 0xb6, 0x59, 0x9b, 0xce, 0xd9, 0xce, 0xcc, 0x14, 0x39, 0xdb, 0xd9,
+	0x7e, 0x2e, 0x6c, 0x8b, 0x4e, 0x96, 0xb3, 0x9d, 0xeb, 0xb4, 0x39, 0xdb, 0xf9, 0xb6, 0xc7, 0xa3,
+	0xb6, 0x2c, 0x9a, 0x51, 0x4e, 0x41, 0xae, 0x3f, 0xe9, 0xeb, 0x63, 0xc9, 0xde, 0x66, 0x5f, 0xee,
+	0xf4, 0xf4, 0x7d, 0xea, 0xba, 0xe5, 0xdb, 0xd8, 0x43, 0x53, 0x64, 0x66, 0x9c, 0xfd, 0x04, 0x6a,
+	0x4f, 0x30, 0x79, 0xce, 0xff, 0x10, 0x1c, 0xf8, 0xdd, 0x60, 0xaa, 0x8a, 0xeb, 0x19, 0x60, 0x43,
+	0x71, 0xaa, 0xe1, 0x05, 0x54, 0xb3, 0x45, 0x17, 0xdd, 0xca, 0x08, 0x4e, 0x68, 0x22, 0xfa, 0xdb,
+	0x53, 0xf9, 0x69, 0x38, 0x5e, 0x41, 0x3d, 0x5f, 0xab, 0xd0, 0x56, 0xe6, 0xd0, 0xc4, 0x42, 0xa8,
+	0xdf, 0x9e, 0x21, 0x91, 0x2a, 0xee, 0xc8, 0xd7, 0x2a, 0x6a, 0x01, 0x7a, 0x6b, 0x34, 0x15, 0x73,
+	0x45, 0x49, 0xbf, 0x35, 0x8d, 0xad, 0xf4, 0x9d, 0x2e, 0xf3, 0x20, 0xdd, 0xfd, 0x17, 0xff, 0x17,
+	0x79, 0x67, 0x7e, 0x11, 0x00, 0x00,
 }
@@ -38,7 +38,7 @@ func (m *SupportsFeatureRequest) Reset()         { *m = SupportsFeatureRequest{}
 func (m *SupportsFeatureRequest) String() string { return proto.CompactTextString(m) }
 func (*SupportsFeatureRequest) ProtoMessage()    {}
 func (*SupportsFeatureRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{0}
+	return fileDescriptor_resource_aac09545c7506de4, []int{0}
 }
 func (m *SupportsFeatureRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SupportsFeatureRequest.Unmarshal(m, b)
@@ -76,7 +76,7 @@ func (m *SupportsFeatureResponse) Reset()         { *m = SupportsFeatureResponse
 func (m *SupportsFeatureResponse) String() string { return proto.CompactTextString(m) }
 func (*SupportsFeatureResponse) ProtoMessage()    {}
 func (*SupportsFeatureResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{1}
+	return fileDescriptor_resource_aac09545c7506de4, []int{1}
 }
 func (m *SupportsFeatureResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SupportsFeatureResponse.Unmarshal(m, b)
@@ -125,7 +125,7 @@ func (m *ReadResourceRequest) Reset()         { *m = ReadResourceRequest{} }
 func (m *ReadResourceRequest) String() string { return proto.CompactTextString(m) }
 func (*ReadResourceRequest) ProtoMessage()    {}
 func (*ReadResourceRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{2}
+	return fileDescriptor_resource_aac09545c7506de4, []int{2}
 }
 func (m *ReadResourceRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ReadResourceRequest.Unmarshal(m, b)
@@ -235,7 +235,7 @@ func (m *ReadResourceResponse) Reset()         { *m = ReadResourceResponse{} }
 func (m *ReadResourceResponse) String() string { return proto.CompactTextString(m) }
 func (*ReadResourceResponse) ProtoMessage()    {}
 func (*ReadResourceResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{3}
+	return fileDescriptor_resource_aac09545c7506de4, []int{3}
 }
 func (m *ReadResourceResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ReadResourceResponse.Unmarshal(m, b)
@@ -286,6 +286,10 @@ type RegisterResourceRequest struct {
 	AcceptSecrets           bool                                                     `protobuf:"varint,13,opt,name=acceptSecrets" json:"acceptSecrets,omitempty"`
 	AdditionalSecretOutputs []string                                                 `protobuf:"bytes,14,rep,name=additionalSecretOutputs" json:"additionalSecretOutputs,omitempty"`
 	Aliases                 []string                                                 `protobuf:"bytes,15,rep,name=aliases" json:"aliases,omitempty"`
+	ValidateReplacement     bool                                                     `protobuf:"varint,16,opt,name=validateReplacement" json:"validateReplacement,omitempty"`
+	CustomTimeouts          *RegisterResourceRequest_CustomTimeouts                  `protobuf:"bytes,17,opt,name=customTimeouts" json:"customTimeouts,omitempty"`
+	ReplaceOnChanges        []string                                                 `protobuf:"bytes,18,rep,name=replaceOnChanges" json:"replaceOnChanges,omitempty"`
+	WaitFor                 []string                                                 `protobuf:"bytes,19,rep,name=waitFor" json:"waitFor,omitempty"`
 	XXX_NoUnkeyedLiteral    struct{}                                                 `json:"-"`
 	XXX_unrecognized        []byte                                                   `json:"-"`
 	XXX_sizecache           int32                                                    `json:"-"`
@@ -295,7 +299,7 @@ func (m *RegisterResourceRequest) Reset()         { *m = RegisterResourceRequest
 func (m *RegisterResourceRequest) String() string { return proto.CompactTextString(m) }
 func (*RegisterResourceRequest) ProtoMessage()    {}
 func (*RegisterResourceRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{4}
+	return fileDescriptor_resource_aac09545c7506de4, []int{4}
 }
 func (m *RegisterResourceRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterResourceRequest.Unmarshal(m, b)
@@ -420,6 +424,34 @@ func (m *RegisterResourceRequest) GetAliases() []string {
 	return nil
 }
 
+func (m *RegisterResourceRequest) GetValidateReplacement() bool {
+	if m != nil {
+		return m.ValidateReplacement
+	}
+	return false
+}
+
+func (m *RegisterResourceRequest) GetCustomTimeouts() *RegisterResourceRequest_CustomTimeouts {
+	if m != nil {
+		return m.CustomTimeouts
+	}
+	return nil
+}
+
+func (m *RegisterResourceRequest) GetReplaceOnChanges() []string {
+	if m != nil {
+		return m.ReplaceOnChanges
+	}
+	return nil
+}
+
+func (m *RegisterResourceRequest) GetWaitFor() []string {
+	if m != nil {
+		return m.WaitFor
+	}
+	return nil
+}
+
 // PropertyDependencies describes the resources that a particular property depends on.
 type RegisterResourceRequest_PropertyDependencies struct {
 	Urns                 []string `protobuf:"bytes,1,rep,name=urns" json:"urns,omitempty"`
@@ -436,7 +468,7 @@ func (m *RegisterResourceRequest_PropertyDependencies) String() string {
 }
 func (*RegisterResourceRequest_PropertyDependencies) ProtoMessage() {}
 func (*RegisterResourceRequest_PropertyDependencies) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{4, 0}
+	return fileDescriptor_resource_aac09545c7506de4, []int{4, 0}
 }
 func (m *RegisterResourceRequest_PropertyDependencies) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterResourceRequest_PropertyDependencies.Unmarshal(m, b)
@@ -463,6 +495,73 @@ func (m *RegisterResourceRequest_PropertyDependencies) GetUrns() []string {
 	return nil
 }
 
+// CustomTimeouts bounds the time the provider may take to operate on a resource.
+type RegisterResourceRequest_CustomTimeouts struct {
+	Create               string   `protobuf:"bytes,1,opt,name=create" json:"create,omitempty"`
+	Update               string   `protobuf:"bytes,2,opt,name=update" json:"update,omitempty"`
+	Delete               string   `protobuf:"bytes,3,opt,name=delete" json:"delete,omitempty"`
+	PollInterval         string   `protobuf:"bytes,4,opt,name=pollInterval" json:"pollInterval,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RegisterResourceRequest_CustomTimeouts) Reset() {
+	*m = RegisterResourceRequest_CustomTimeouts{}
+}
+func (m *RegisterResourceRequest_CustomTimeouts) String() string {
+	return proto.CompactTextString(m)
+}
+func (*RegisterResourceRequest_CustomTimeouts) ProtoMessage() {}
+func (*RegisterResourceRequest_CustomTimeouts) Descriptor() ([]byte, []int) {
+	return fileDescriptor_resource_aac09545c7506de4, []int{4, 2}
+}
+func (m *RegisterResourceRequest_CustomTimeouts) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterResourceRequest_CustomTimeouts.Unmarshal(m, b)
+}
+func (m *RegisterResourceRequest_CustomTimeouts) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterResourceRequest_CustomTimeouts.Marshal(b, m, deterministic)
+}
+func (dst *RegisterResourceRequest_CustomTimeouts) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterResourceRequest_CustomTimeouts.Merge(dst, src)
+}
+func (m *RegisterResourceRequest_CustomTimeouts) XXX_Size() int {
+	return xxx_messageInfo_RegisterResourceRequest_CustomTimeouts.Size(m)
+}
+func (m *RegisterResourceRequest_CustomTimeouts) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterResourceRequest_CustomTimeouts.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterResourceRequest_CustomTimeouts proto.InternalMessageInfo
+
+func (m *RegisterResourceRequest_CustomTimeouts) GetCreate() string {
+	if m != nil {
+		return m.Create
+	}
+	return ""
+}
+
+func (m *RegisterResourceRequest_CustomTimeouts) GetUpdate() string {
+	if m != nil {
+		return m.Update
+	}
+	return ""
+}
+
+func (m *RegisterResourceRequest_CustomTimeouts) GetDelete() string {
+	if m != nil {
+		return m.Delete
+	}
+	return ""
+}
+
+func (m *RegisterResourceRequest_CustomTimeouts) GetPollInterval() string {
+	if m != nil {
+		return m.PollInterval
+	}
+	return ""
+}
+
 // RegisterResourceResponse is returned by the engine after a resource has finished being initialized.  It includes the
 // auto-assigned URN, the provider-assigned ID, and any other properties initialized by the engine.
 type RegisterResourceResponse struct {
@@ -480,7 +579,7 @@ func (m *RegisterResourceResponse) Reset()         { *m = RegisterResourceRespon
 func (m *RegisterResourceResponse) String() string { return proto.CompactTextString(m) }
 func (*RegisterResourceResponse) ProtoMessage()    {}
 func (*RegisterResourceResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{5}
+	return fileDescriptor_resource_aac09545c7506de4, []int{5}
 }
 func (m *RegisterResourceResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterResourceResponse.Unmarshal(m, b)
@@ -548,7 +647,7 @@ func (m *RegisterResourceOutputsRequest) Reset()         { *m = RegisterResource
 func (m *RegisterResourceOutputsRequest) String() string { return proto.CompactTextString(m) }
 func (*RegisterResourceOutputsRequest) ProtoMessage()    {}
 func (*RegisterResourceOutputsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_resource_968947468bf03677, []int{6}
+	return fileDescriptor_resource_aac09545c7506de4, []int{6}
 }
 func (m *RegisterResourceOutputsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterResourceOutputsRequest.Unmarshal(m, b)
@@ -590,6 +689,7 @@ func init() {
 	proto.RegisterType((*RegisterResourceRequest)(nil), "pulumirpc.RegisterResourceRequest")
 	proto.RegisterMapType((map[string]*RegisterResourceRequest_PropertyDependencies)(nil), "pulumirpc.RegisterResourceRequest.PropertyDependenciesEntry")
 	proto.RegisterType((*RegisterResourceRequest_PropertyDependencies)(nil), "pulumirpc.RegisterResourceRequest.PropertyDependencies")
+	proto.RegisterType((*RegisterResourceRequest_CustomTimeouts)(nil), "pulumirpc.RegisterResourceRequest.CustomTimeouts")
 	proto.RegisterType((*RegisterResourceResponse)(nil), "pulumirpc.RegisterResourceResponse")
 	proto.RegisterType((*RegisterResourceOutputsRequest)(nil), "pulumirpc.RegisterResourceOutputsRequest")
 }
@@ -798,56 +898,62 @@ var _ResourceMonitor_serviceDesc = grpc.ServiceDesc{
 	Metadata: "resource.proto",
 }
 
-func init() { proto.RegisterFile("resource.proto", fileDescriptor_resource_968947468bf03677) }
-
-var fileDescriptor_resource_968947468bf03677 = []byte{
-	// 758 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9c, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
-	0x10, 0x36, 0x29, 0x9b, 0x92, 0x46, 0xb6, 0x6c, 0xac, 0x0d, 0x69, 0xcd, 0x16, 0xae, 0xca, 0xf6,
-	0xa0, 0xf6, 0x20, 0xb7, 0xee, 0xc1, 0x6e, 0x51, 0x20, 0x40, 0x12, 0x07, 0xc8, 0xc1, 0x48, 0x42,
-	0xdf, 0x02, 0x24, 0x00, 0x45, 0x8e, 0x65, 0xc6, 0x14, 0x77, 0xb3, 0xbb, 0x14, 0xa0, 0x5b, 0xae,
-	0x79, 0x8a, 0xbc, 0x4a, 0x9e, 0x29, 0x4f, 0x10, 0x70, 0x49, 0x2a, 0xa2, 0x48, 0x59, 0x4e, 0x6e,
-	0x3b, 0x3f, 0x3b, 0xdc, 0xfd, 0xbe, 0x6f, 0x66, 0x09, 0x5d, 0x81, 0x92, 0x25, 0xc2, 0xc7, 0x11,
-	0x17, 0x4c, 0x31, 0xd2, 0xe6, 0x49, 0x94, 0x4c, 0x43, 0xc1, 0x7d, 0xfb, 0xa7, 0x09, 0x63, 0x93,
-	0x08, 0x4f, 0x75, 0x60, 0x9c, 0xdc, 0x9c, 0xe2, 0x94, 0xab, 0x79, 0x96, 0x67, 0xff, 0xbc, 0x1a,
-	0x94, 0x4a, 0x24, 0xbe, 0xca, 0xa3, 0x5d, 0x2e, 0xd8, 0x2c, 0x0c, 0x50, 0x64, 0xb6, 0x33, 0x84,
-	0xde, 0x75, 0xc2, 0x39, 0x13, 0x4a, 0x3e, 0x43, 0x4f, 0x25, 0x02, 0x5d, 0x7c, 0x9f, 0xa0, 0x54,
-	0xa4, 0x0b, 0x66, 0x18, 0x50, 0x63, 0x60, 0x0c, 0xdb, 0xae, 0x19, 0x06, 0xce, 0xbf, 0xd0, 0xaf,
-	0x64, 0x4a, 0xce, 0x62, 0x89, 0xe4, 0x04, 0xe0, 0xd6, 0x93, 0x79, 0x54, 0x6f, 0x69, 0xb9, 0x4b,
-	0x1e, 0xe7, 0x8b, 0x09, 0x87, 0x2e, 0x7a, 0x81, 0x9b, 0xdf, 0x68, 0xcd, 0x27, 0x08, 0x81, 0x6d,
-	0x35, 0xe7, 0x48, 0x4d, 0xed, 0xd1, 0xeb, 0xd4, 0x17, 0x7b, 0x53, 0xa4, 0x8d, 0xcc, 0x97, 0xae,
-	0x49, 0x0f, 0x2c, 0xee, 0x09, 0x8c, 0x15, 0xdd, 0xd6, 0xde, 0xdc, 0x22, 0xe7, 0x00, 0x5c, 0x30,
-	0x8e, 0x42, 0x85, 0x28, 0xe9, 0xce, 0xc0, 0x18, 0x76, 0xce, 0xfa, 0xa3, 0x0c, 0x8f, 0x51, 0x81,
-	0xc7, 0xe8, 0x5a, 0xe3, 0xe1, 0x2e, 0xa5, 0x12, 0x07, 0x76, 0x03, 0xe4, 0x18, 0x07, 0x18, 0xfb,
-	0xe9, 0x56, 0x6b, 0xd0, 0x18, 0xb6, 0xdd, 0x92, 0x8f, 0xd8, 0xd0, 0x2a, 0xb0, 0xa3, 0x4d, 0xfd,
-	0xd9, 0x85, 0x4d, 0x28, 0x34, 0x67, 0x28, 0x64, 0xc8, 0x62, 0xda, 0xd2, 0xa1, 0xc2, 0x24, 0xbf,
-	0xc3, 0x9e, 0xe7, 0xfb, 0xc8, 0xd5, 0x35, 0xfa, 0x02, 0x95, 0xa4, 0x6d, 0x8d, 0x4e, 0xd9, 0x49,
-	0x2e, 0xa0, 0xef, 0x05, 0x41, 0xa8, 0x42, 0x16, 0x7b, 0x51, 0xe6, 0x7c, 0x91, 0x28, 0x9e, 0x28,
-	0x49, 0x41, 0x1f, 0x65, 0x5d, 0x38, 0xfd, 0xb2, 0x17, 0x85, 0x9e, 0x44, 0x49, 0x3b, 0x3a, 0xb3,
-	0x30, 0x1d, 0x0f, 0x8e, 0xca, 0x98, 0xe7, 0x64, 0x1d, 0x40, 0x23, 0x11, 0x71, 0x8e, 0x7a, 0xba,
-	0x5c, 0x81, 0xcd, 0x7c, 0x30, 0x6c, 0xce, 0x47, 0x0b, 0xfa, 0x2e, 0x4e, 0x42, 0xa9, 0x50, 0xac,
-	0x72, 0x5b, 0x70, 0x69, 0xd4, 0x70, 0x69, 0xd6, 0x72, 0xd9, 0x28, 0x71, 0xd9, 0x03, 0xcb, 0x4f,
-	0xa4, 0x62, 0x53, 0xcd, 0x71, 0xcb, 0xcd, 0x2d, 0x72, 0x0a, 0x16, 0x1b, 0xbf, 0x43, 0x5f, 0x6d,
-	0xe2, 0x37, 0x4f, 0x4b, 0x11, 0x4a, 0x43, 0xe9, 0x0e, 0x4b, 0x57, 0x2a, 0xcc, 0x0a, 0xeb, 0xcd,
-	0x0d, 0xac, 0xb7, 0x56, 0x58, 0xe7, 0x70, 0x94, 0x83, 0x31, 0x7f, 0xba, 0x5c, 0xa7, 0x3d, 0x68,
-	0x0c, 0x3b, 0x67, 0xff, 0x8f, 0x16, 0x0d, 0x3b, 0x5a, 0x03, 0xd2, 0xe8, 0x65, 0xcd, 0xf6, 0xcb,
-	0x58, 0x89, 0xb9, 0x5b, 0x5b, 0x99, 0xfc, 0x05, 0x87, 0x01, 0x46, 0xa8, 0xf0, 0x31, 0xde, 0xb0,
-	0xb4, 0x01, 0x79, 0xe4, 0xf9, 0x48, 0x41, 0xdf, 0xab, 0x2e, 0xb4, 0xac, 0xcc, 0x4e, 0x45, 0x99,
-	0xe1, 0x24, 0x66, 0x02, 0x9f, 0xdc, 0x7a, 0xf1, 0x04, 0x25, 0xdd, 0xd5, 0xd7, 0x2f, 0x3b, 0xab,
-	0xfa, 0xdd, 0xfb, 0x4e, 0xfd, 0x76, 0x1f, 0xac, 0xdf, 0xfd, 0x92, 0x7e, 0xed, 0x3f, 0xe1, 0xa8,
-	0x0e, 0x9e, 0x54, 0x44, 0x89, 0x88, 0x25, 0x35, 0x74, 0xba, 0x5e, 0xdb, 0x1f, 0x0c, 0x38, 0x5e,
-	0x8b, 0x65, 0xaa, 0xf8, 0x3b, 0x9c, 0x17, 0x8a, 0xbf, 0xc3, 0x39, 0xb9, 0x82, 0x9d, 0x99, 0x17,
-	0x25, 0x98, 0x8b, 0xfd, 0xfc, 0x07, 0xa9, 0x72, 0xb3, 0x2a, 0xff, 0x99, 0x17, 0x86, 0xf3, 0xc9,
-	0x00, 0x5a, 0xdd, 0xbb, 0xb6, 0xe7, 0xb2, 0xd1, 0x67, 0x2e, 0x46, 0xdf, 0x37, 0x59, 0x37, 0x1e,
-	0x26, 0xeb, 0x1e, 0x58, 0x52, 0x79, 0xe3, 0x08, 0x8b, 0xfe, 0xc8, 0xac, 0x14, 0xd0, 0x6c, 0x95,
-	0x0e, 0x40, 0x0d, 0x68, 0x6e, 0x3a, 0x08, 0x27, 0xab, 0x07, 0xcc, 0x59, 0x28, 0x7a, 0xb6, 0x7a,
-	0xcc, 0xbf, 0xa1, 0xc9, 0x72, 0x22, 0x37, 0xcc, 0x85, 0x22, 0xef, 0xec, 0x73, 0x03, 0xf6, 0x8b,
-	0xfa, 0x57, 0x2c, 0x0e, 0x15, 0x13, 0xe4, 0x35, 0xec, 0xaf, 0xbc, 0x1d, 0xe4, 0xd7, 0x25, 0xcc,
-	0xeb, 0x5f, 0x20, 0xdb, 0xb9, 0x2f, 0x25, 0x43, 0xd6, 0xd9, 0x22, 0x8f, 0xc0, 0x7a, 0x1e, 0xcf,
-	0xd8, 0x1d, 0x12, 0xba, 0x94, 0x9f, 0xb9, 0x8a, 0x4a, 0xc7, 0x35, 0x91, 0x45, 0x81, 0x57, 0xb0,
-	0xbb, 0x3c, 0x28, 0xc9, 0x49, 0x49, 0x0d, 0x95, 0x57, 0xcb, 0xfe, 0x65, 0x6d, 0x7c, 0x51, 0xf2,
-	0x0d, 0x1c, 0xac, 0x42, 0x4d, 0x9c, 0xcd, 0x22, 0xb3, 0x7f, 0xbb, 0x37, 0x67, 0x51, 0xfe, 0x6d,
-	0x75, 0xec, 0x16, 0xfd, 0xf4, 0xc7, 0x3d, 0x15, 0xca, 0x6c, 0xdb, 0xbd, 0x0a, 0x95, 0x97, 0xe9,
-	0x6f, 0x84, 0xb3, 0x35, 0xb6, 0xb4, 0xe7, 0x9f, 0xaf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x7d, 0xff,
-	0x47, 0xb3, 0x83, 0x08, 0x00, 0x00,
+func init() { proto.RegisterFile("resource.proto", fileDescriptor_resource_aac09545c7506de4) }
+
+var fileDescriptor_resource_aac09545c7506de4 = []byte{
+	// 862 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x9d, 0x56, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0x26, 0x09, 0xa4, 0xe9, 0xb4, 0xa4, 0x65, 0x5b, 0xb5, 0xc6, 0xa0, 0x02, 0x86, 0x03, 0x70,
+	0x48, 0xa1, 0x1c, 0x5a, 0x10, 0x12, 0x12, 0x7f, 0x12, 0x07, 0x04, 0xb8, 0x5c, 0x40, 0x02, 0xc9,
+	0xb5, 0x87, 0x60, 0x70, 0xbc, 0xcb, 0x7a, 0x5d, 0x14, 0x89, 0x03, 0x6f, 0xc2, 0xab, 0xf0, 0x1c,
+	0x3c, 0x06, 0x4f, 0xc0, 0xfe, 0x86, 0x38, 0x76, 0xda, 0xc2, 0xc9, 0xf3, 0xb7, 0xb3, 0x33, 0xf3,
+	0xcd, 0xcc, 0x1a, 0xfa, 0x1c, 0x0b, 0x5a, 0xf2, 0x18, 0x07, 0x8c, 0x53, 0x41, 0xc9, 0x22, 0x2b,
+	0xb3, 0x72, 0x94, 0x72, 0x16, 0xfb, 0x17, 0x86, 0x94, 0x0e, 0x33, 0xdc, 0xd6, 0x8a, 0x83, 0xf2,
+	0xc3, 0x36, 0x8e, 0x98, 0x18, 0x1b, 0x3b, 0xff, 0xe2, 0xac, 0xb2, 0x10, 0xbc, 0x8c, 0x85, 0xd5,
+	0xf6, 0xe5, 0xe7, 0x30, 0x4d, 0x90, 0x1b, 0x3e, 0xb8, 0x0e, 0x1b, 0xfb, 0x25, 0x63, 0x94, 0x8b,
+	0xe2, 0x29, 0x46, 0xa2, 0xe4, 0x18, 0xe2, 0x97, 0x12, 0x0b, 0x41, 0xfa, 0xd0, 0x4e, 0x13, 0xaf,
+	0x75, 0xb9, 0x75, 0x7d, 0x31, 0x94, 0x54, 0x70, 0x17, 0x36, 0x6b, 0x96, 0x05, 0xa3, 0x79, 0x81,
+	0x64, 0x0b, 0xe0, 0x63, 0x54, 0x58, 0xad, 0x3e, 0xd2, 0x0b, 0xa7, 0x24, 0xc1, 0xef, 0x36, 0xac,
+	0x85, 0x18, 0x25, 0xa1, 0xcd, 0x68, 0xce, 0x15, 0x84, 0xc0, 0x69, 0x31, 0x66, 0xe8, 0xb5, 0xb5,
+	0x44, 0xd3, 0x4a, 0x96, 0x47, 0x23, 0xf4, 0x3a, 0x46, 0xa6, 0x68, 0xb2, 0x01, 0x5d, 0x16, 0x71,
+	0xcc, 0x85, 0x77, 0x5a, 0x4b, 0x2d, 0x47, 0x76, 0x01, 0x64, 0x56, 0x0c, 0xb9, 0x48, 0xb1, 0xf0,
+	0xce, 0x48, 0xdd, 0xd2, 0xce, 0xe6, 0xc0, 0xd4, 0x63, 0xe0, 0xea, 0x31, 0xd8, 0xd7, 0xf5, 0x08,
+	0xa7, 0x4c, 0x49, 0x00, 0xcb, 0x09, 0x32, 0xcc, 0x13, 0xcc, 0x63, 0x75, 0xb4, 0x7b, 0xb9, 0x23,
+	0xdd, 0x56, 0x64, 0xc4, 0x87, 0x9e, 0xab, 0x9d, 0xb7, 0xa0, 0xaf, 0x9d, 0xf0, 0xc4, 0x83, 0x85,
+	0x43, 0xe4, 0x45, 0x4a, 0x73, 0xaf, 0xa7, 0x55, 0x8e, 0x25, 0xd7, 0xe0, 0x6c, 0x14, 0xc7, 0xc8,
+	0xc4, 0x3e, 0xc6, 0x1c, 0x45, 0xe1, 0x2d, 0xea, 0xea, 0x54, 0x85, 0x64, 0x0f, 0x36, 0xa3, 0x24,
+	0x49, 0x85, 0x3c, 0x11, 0x65, 0x46, 0xf8, 0xa2, 0x14, 0xac, 0x94, 0xf6, 0xa0, 0x43, 0x99, 0xa7,
+	0x56, 0x37, 0x47, 0x59, 0x1a, 0x15, 0x32, 0xe8, 0x25, 0x6d, 0xe9, 0xd8, 0x20, 0x82, 0xf5, 0x6a,
+	0xcd, 0x2d, 0x58, 0xab, 0xd0, 0x29, 0x79, 0x6e, 0xab, 0xae, 0xc8, 0x99, 0xb2, 0xb5, 0x4f, 0x5c,
+	0xb6, 0xe0, 0x57, 0x0f, 0x36, 0x43, 0x1c, 0xa6, 0x85, 0x40, 0x3e, 0x8b, 0xad, 0xc3, 0xb2, 0xd5,
+	0x80, 0x65, 0xbb, 0x11, 0xcb, 0x4e, 0x05, 0x4b, 0x29, 0x8f, 0xcb, 0x42, 0xd0, 0x91, 0xc6, 0xb8,
+	0x17, 0x5a, 0x8e, 0x6c, 0x43, 0x97, 0x1e, 0x7c, 0xc2, 0x58, 0x1c, 0x87, 0xaf, 0x35, 0x53, 0x15,
+	0x52, 0x2a, 0x75, 0xa2, 0xab, 0x3d, 0x39, 0xb6, 0x86, 0xfa, 0xc2, 0x31, 0xa8, 0xf7, 0x66, 0x50,
+	0x67, 0xb0, 0x6e, 0x8b, 0x31, 0x7e, 0x3c, 0xed, 0x67, 0x51, 0xfa, 0x59, 0xda, 0xb9, 0x3f, 0x98,
+	0x0c, 0xec, 0x60, 0x4e, 0x91, 0x06, 0x2f, 0x1b, 0x8e, 0x3f, 0xc9, 0x05, 0x1f, 0x87, 0x8d, 0x9e,
+	0xc9, 0x2d, 0x58, 0x4b, 0x30, 0x43, 0x81, 0x0f, 0xf1, 0x03, 0x55, 0x03, 0xc8, 0xb2, 0x28, 0x46,
+	0xd9, 0x23, 0x2a, 0xaf, 0x26, 0xd5, 0x74, 0x67, 0x2e, 0xd5, 0x3a, 0x33, 0x1d, 0xe6, 0xd2, 0xf4,
+	0xd1, 0xc7, 0x28, 0x1f, 0xca, 0xb0, 0x97, 0x75, 0xfa, 0x55, 0x61, 0xbd, 0x7f, 0xcf, 0xfe, 0x63,
+	0xff, 0xf6, 0x4f, 0xdc, 0xbf, 0x2b, 0x95, 0xfe, 0x55, 0xb9, 0x1e, 0x4a, 0x3a, 0x89, 0x84, 0x4b,
+	0x66, 0xa4, 0xba, 0x64, 0xd5, 0xe4, 0xda, 0xa0, 0x22, 0x6f, 0xa0, 0x6f, 0x9a, 0xe4, 0x75, 0x3a,
+	0x42, 0xaa, 0x2e, 0x3f, 0xa7, 0x5b, 0xe4, 0xf6, 0x09, 0x90, 0x78, 0x54, 0x39, 0x18, 0xce, 0x38,
+	0x22, 0x37, 0x61, 0x95, 0x9b, 0x9b, 0x5e, 0xe4, 0xae, 0x5e, 0x44, 0xc7, 0x5b, 0x93, 0xab, 0x94,
+	0xbe, 0x46, 0xa9, 0x78, 0x4a, 0xb9, 0xb7, 0x66, 0x52, 0xb2, 0xac, 0x7f, 0x13, 0xd6, 0x9b, 0x10,
+	0x57, 0x73, 0x21, 0xe7, 0xb0, 0x90, 0xb3, 0xa2, 0xcc, 0x35, 0xed, 0x7f, 0x6f, 0xc1, 0xf9, 0xb9,
+	0xed, 0xa1, 0x86, 0xf8, 0x33, 0x8e, 0xdd, 0x10, 0x4b, 0x92, 0x3c, 0x87, 0x33, 0xb2, 0x26, 0x25,
+	0xda, 0xf9, 0xdd, 0xfd, 0xcf, 0xee, 0x0b, 0x8d, 0x97, 0x7b, 0xed, 0xbd, 0x96, 0xff, 0x0d, 0xfa,
+	0xd5, 0xb2, 0xe8, 0xa1, 0xe4, 0x72, 0xf9, 0xbb, 0xb1, 0xb6, 0x9c, 0x92, 0x97, 0x4c, 0xc1, 0x61,
+	0x47, 0xdb, 0x72, 0x4a, 0x6e, 0x9a, 0xd2, 0x0d, 0xb7, 0xe1, 0xd4, 0xe4, 0x31, 0x9a, 0x65, 0xcf,
+	0x72, 0x19, 0x95, 0xbc, 0xcf, 0xae, 0xf1, 0x8a, 0x2c, 0xf8, 0xd1, 0x02, 0xaf, 0x1e, 0xf9, 0xdc,
+	0x25, 0x66, 0xde, 0x92, 0xf6, 0xe4, 0x2d, 0xf9, 0xbb, 0x27, 0x3a, 0x27, 0xdb, 0x13, 0x32, 0xd6,
+	0x42, 0x44, 0x07, 0x19, 0xba, 0x85, 0x63, 0x38, 0x05, 0xa7, 0xa1, 0xd4, 0x8b, 0xa2, 0xe1, 0xb4,
+	0x6c, 0x80, 0xb0, 0x35, 0x1b, 0xa0, 0x6d, 0x6b, 0xb7, 0x04, 0xeb, 0x61, 0xde, 0x86, 0x05, 0x6a,
+	0x27, 0xe3, 0x98, 0x45, 0xeb, 0xec, 0x76, 0x7e, 0x76, 0x60, 0xc5, 0xf9, 0x7f, 0x4e, 0xf3, 0x54,
+	0x50, 0x4e, 0xde, 0xc2, 0xca, 0xcc, 0x63, 0x4c, 0xae, 0x4c, 0x21, 0xde, 0xfc, 0xa4, 0xfb, 0xc1,
+	0x51, 0x26, 0xa6, 0xb2, 0xc1, 0x29, 0xf2, 0x00, 0xba, 0xcf, 0xf2, 0x43, 0xfa, 0x59, 0xa6, 0x3e,
+	0x65, 0x6f, 0x44, 0xce, 0xd3, 0xf9, 0x06, 0xcd, 0xc4, 0xc1, 0x2b, 0x58, 0x9e, 0x7e, 0x79, 0xc8,
+	0x56, 0xa5, 0x17, 0x6b, 0xbf, 0x01, 0xfe, 0xa5, 0xb9, 0xfa, 0x89, 0xcb, 0x77, 0xb0, 0x3a, 0x5b,
+	0x6a, 0x12, 0x1c, 0xdf, 0xe2, 0xfe, 0xd5, 0x23, 0x6d, 0x26, 0xee, 0xdf, 0xd7, 0xdf, 0x31, 0xb7,
+	0xa0, 0x6e, 0x1c, 0xe1, 0xa1, 0x8a, 0xb6, 0xbf, 0x51, 0x83, 0xf2, 0x89, 0xfa, 0x2f, 0x0b, 0x4e,
+	0x1d, 0x74, 0xb5, 0xe4, 0xce, 0x1f, 0x34, 0x1e, 0x7e, 0x34, 0xd4, 0x09, 0x00, 0x00,
 }